@@ -0,0 +1,635 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/features/api_usage"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/audit_archive"
+	"go-crm/internal/features/auth"
+	"go-crm/internal/features/automation"
+	"go-crm/internal/features/feature_flag"
+	"go-crm/internal/features/mobile"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/organization"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/resource"
+	"go-crm/internal/features/role"
+	"go-crm/internal/features/sandbox"
+	"go-crm/internal/features/sync"
+	"go-crm/internal/features/user"
+
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/fx"
+)
+
+// adminServices are what the admin commands work through; nothing here
+// writes to Mongo directly
+type adminServices struct {
+	fx.In
+
+	Auth         auth.AuthService
+	Users        user.UserService
+	Roles        role.RoleService
+	Orgs         organization.OrganizationRepository
+	Records      record.RecordService
+	Automations  automation.AutomationService
+	ModuleRepo   module.ModuleRepository
+	ResourceRepo resource.ResourceRepository
+	Sync         sync.SyncService
+	Sandboxes    sandbox.SandboxService
+	Flags        feature_flag.FeatureFlagService
+
+	ExternalIDRepo record.ExternalIDRepository
+	AuditRepo      audit.AuditRepository
+	DeviceRepo     mobile.DeviceRepository
+	UsageRepo      api_usage.UsageRepository
+	Usage          api_usage.UsageService
+	AuditArchives  audit_archive.ArchiveRepository
+	FlagRepo       feature_flag.FlagRepository
+}
+
+// newAdminCommand builds the admin CLI. The application graph is started
+// once the command line is parsed, so -h and usage errors need no database.
+func newAdminCommand(svc *adminServices, app **fx.App) *cobra.Command {
+	root := &cobra.Command{
+		Use:           "admin",
+		Short:         "Administer tenants, users and maintenance jobs",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Cobra checks required flags after this hook; check them first
+			if err := cmd.ValidateRequiredFlags(); err != nil {
+				return err
+			}
+			*app = fx.New(appProviders(), fx.NopLogger, fx.Populate(svc))
+			return (*app).Start(cmd.Context())
+		},
+	}
+
+	group := func(use, short string, children ...*cobra.Command) *cobra.Command {
+		cmd := &cobra.Command{Use: use, Short: short}
+		cmd.AddCommand(children...)
+		return cmd
+	}
+	root.AddCommand(
+		group("tenant", "Manage organizations", createTenantCommand(svc)),
+		group("user", "Manage users", createUserCommand(svc), resetPasswordCommand(svc)),
+		group("automation", "Run automations", rerunAutomationsCommand(svc)),
+		group("permissions", "Inspect permissions", checkPermissionsCommand(svc)),
+		group("indexes", "Maintain database indexes", rebuildIndexesCommand(svc)),
+		group("sync", "Run data syncs", runSyncCommand(svc)),
+		group("sandbox", "Maintain sandboxes", anonymizeSandboxCommand(svc)),
+		group("usage", "Report API usage", usageReportCommand(svc)),
+		group("flag", "Manage feature flags", listFlagsCommand(svc), setFlagCommand(svc), deleteFlagCommand(svc)),
+	)
+	return root
+}
+
+// runAdmin runs one admin command against the configured database and exits
+// non-zero when it fails
+func runAdmin(args []string) {
+	var svc adminServices
+	var app *fx.App
+	root := newAdminCommand(&svc, &app)
+	root.SetArgs(args)
+
+	ctx := context.Background()
+	err := root.ExecuteContext(ctx)
+	if app != nil {
+		app.Stop(ctx)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "admin: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// tenantFlag adds the --tenant flag most commands scope to
+func tenantFlag(cmd *cobra.Command, tenant *string, required bool) {
+	cmd.Flags().StringVar(tenant, "tenant", "", "Organization ID or name")
+	if required {
+		_ = cmd.MarkFlagRequired("tenant")
+	}
+}
+
+func requireFlags(cmd *cobra.Command, names ...string) {
+	for _, name := range names {
+		_ = cmd.MarkFlagRequired(name)
+	}
+}
+
+// withTenant scopes ctx to an organization given by ID or name
+func withTenant(ctx context.Context, svc *adminServices, tenant string) (context.Context, error) {
+	if tenant == "" {
+		return nil, errors.New("--tenant is required")
+	}
+	org, err := svc.Orgs.FindByID(ctx, tenant)
+	if err != nil {
+		org, err = svc.Orgs.FindByName(ctx, tenant)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("organization %q not found", tenant)
+	}
+	return context.WithValue(ctx, models.TenantIDKey, org.ID.Hex()), nil
+}
+
+func createTenantCommand(svc *adminServices) *cobra.Command {
+	var name, username, password, email string
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create an organization with its admin user",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			owner, err := svc.Auth.Register(ctx, username, password, email, name)
+			if err != nil {
+				return err
+			}
+
+			// Register gives new users the default role; the owner also gets admin
+			ctx = context.WithValue(ctx, models.TenantIDKey, owner.TenantID.Hex())
+			admin, err := svc.Roles.GetRoleByName(ctx, "admin")
+			if err != nil {
+				admin, err = svc.Roles.CreateRole(ctx, &role.Role{Name: "admin", Description: "Organization administrator", IsSystem: true})
+				if err != nil {
+					return err
+				}
+			}
+			roleIDs := []string{admin.ID.Hex()}
+			for _, id := range owner.Roles {
+				roleIDs = append(roleIDs, id.Hex())
+			}
+			if err := svc.Users.UpdateUserRoles(ctx, owner.ID.Hex(), roleIDs); err != nil {
+				return err
+			}
+
+			fmt.Printf("created organization %s (%s) with admin %s\n", name, owner.TenantID.Hex(), owner.Username)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "Organization name")
+	cmd.Flags().StringVar(&username, "username", "", "Admin username")
+	cmd.Flags().StringVar(&password, "password", "", "Admin password")
+	cmd.Flags().StringVar(&email, "email", "", "Admin email")
+	requireFlags(cmd, "name", "username", "password", "email")
+	return cmd
+}
+
+func createUserCommand(svc *adminServices) *cobra.Command {
+	var tenant, username, password, email string
+	var roles []string
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a user in an organization",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := withTenant(cmd.Context(), svc, tenant)
+			if err != nil {
+				return err
+			}
+
+			var roleIDs []primitive.ObjectID
+			for _, name := range roles {
+				if name = strings.TrimSpace(name); name == "" {
+					continue
+				}
+				r, err := svc.Roles.GetRoleByName(ctx, name)
+				if err != nil {
+					return fmt.Errorf("role %q not found", name)
+				}
+				roleIDs = append(roleIDs, r.ID)
+			}
+
+			tenantID, _ := primitive.ObjectIDFromHex(ctx.Value(models.TenantIDKey).(string))
+			u := &models.User{
+				TenantID: tenantID,
+				Username: username,
+				Password: password,
+				Email:    email,
+				Roles:    roleIDs,
+			}
+			if err := svc.Users.CreateUser(ctx, u); err != nil {
+				return err
+			}
+
+			fmt.Printf("created user %s (%s)\n", u.Username, u.ID.Hex())
+			return nil
+		},
+	}
+	tenantFlag(cmd, &tenant, true)
+	cmd.Flags().StringVar(&username, "username", "", "Username")
+	cmd.Flags().StringVar(&password, "password", "", "Password")
+	cmd.Flags().StringVar(&email, "email", "", "Email")
+	cmd.Flags().StringSliceVar(&roles, "roles", nil, "Comma-separated role names")
+	requireFlags(cmd, "username", "password", "email")
+	return cmd
+}
+
+func resetPasswordCommand(svc *adminServices) *cobra.Command {
+	var tenant, username, password string
+	cmd := &cobra.Command{
+		Use:   "reset-password",
+		Short: "Set a user's password",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := withTenant(cmd.Context(), svc, tenant)
+			if err != nil {
+				return err
+			}
+
+			u, err := svc.Users.GetUserByUsername(ctx, username)
+			if err != nil {
+				return fmt.Errorf("user %q not found", username)
+			}
+			if err := svc.Users.ResetPassword(ctx, u.ID.Hex(), password); err != nil {
+				return err
+			}
+
+			fmt.Printf("password reset for %s\n", u.Username)
+			return nil
+		},
+	}
+	tenantFlag(cmd, &tenant, true)
+	cmd.Flags().StringVar(&username, "username", "", "Username")
+	cmd.Flags().StringVar(&password, "password", "", "New password")
+	requireFlags(cmd, "username", "password")
+	return cmd
+}
+
+func rerunAutomationsCommand(svc *adminServices) *cobra.Command {
+	var tenant, moduleName, recordID, trigger, ruleID string
+	cmd := &cobra.Command{
+		Use:   "rerun",
+		Short: "Replay the automations of a record",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := withTenant(cmd.Context(), svc, tenant)
+			if err != nil {
+				return err
+			}
+
+			rec, err := svc.Records.GetRecord(ctx, moduleName, recordID, primitive.NilObjectID)
+			if err != nil {
+				return err
+			}
+			if ruleID != "" {
+				if err := svc.Automations.ExecuteRule(ctx, ruleID, moduleName, rec); err != nil {
+					return err
+				}
+				fmt.Printf("ran rule %s on %s/%s\n", ruleID, moduleName, recordID)
+				return nil
+			}
+			if err := svc.Automations.ExecuteFromTrigger(ctx, moduleName, rec, trigger); err != nil {
+				return err
+			}
+			fmt.Printf("ran %s automations on %s/%s\n", trigger, moduleName, recordID)
+			return nil
+		},
+	}
+	tenantFlag(cmd, &tenant, true)
+	cmd.Flags().StringVar(&moduleName, "module", "", "Module name")
+	cmd.Flags().StringVar(&recordID, "record", "", "Record ID")
+	cmd.Flags().StringVar(&trigger, "trigger", "update", "Trigger to replay, e.g. create or update")
+	cmd.Flags().StringVar(&ruleID, "rule", "", "Run only this rule, whatever its trigger")
+	requireFlags(cmd, "module", "record")
+	return cmd
+}
+
+// checkPermissionsCommand prints what a user may do in a module: module
+// actions, field-level security and the record filter applied to reads
+func checkPermissionsCommand(svc *adminServices) *cobra.Command {
+	var tenant, username, moduleName string
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Show a user's permissions in a module",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := withTenant(cmd.Context(), svc, tenant)
+			if err != nil {
+				return err
+			}
+
+			u, err := svc.Users.GetUserByUsername(ctx, username)
+			if err != nil {
+				return fmt.Errorf("user %q not found", username)
+			}
+			var roleNames []string
+			for _, id := range u.Roles {
+				if r, err := svc.Roles.GetRoleByID(ctx, id.Hex()); err == nil {
+					roleNames = append(roleNames, r.Name)
+				}
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintf(w, "user\t%s\n", u.Username)
+			fmt.Fprintf(w, "roles\t%s\n", strings.Join(roleNames, ", "))
+			for _, action := range []string{"read", "create", "update", "delete"} {
+				allowed, err := svc.Roles.CheckModulePermission(ctx, roleNames, moduleName, action)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(w, "%s\t%t\n", action, allowed)
+			}
+
+			fields, err := svc.Roles.GetFieldPermissions(ctx, u.ID, moduleName)
+			if err != nil {
+				return err
+			}
+			names := make([]string, 0, len(fields))
+			for name := range fields {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Fprintf(w, "field %s\t%s\n", name, fields[name])
+			}
+
+			filter, err := svc.Roles.GetAccessFilter(ctx, u.ID, moduleName, "read")
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "read filter\t%v\n", filter)
+			return w.Flush()
+		},
+	}
+	tenantFlag(cmd, &tenant, true)
+	cmd.Flags().StringVar(&username, "username", "", "Username")
+	cmd.Flags().StringVar(&moduleName, "module", "", "Module name")
+	requireFlags(cmd, "username", "module")
+	return cmd
+}
+
+func rebuildIndexesCommand(svc *adminServices) *cobra.Command {
+	return &cobra.Command{
+		Use:   "rebuild",
+		Short: "Create any missing indexes",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			if err := svc.ModuleRepo.EnsureIndexes(ctx); err != nil {
+				return fmt.Errorf("module indexes: %w", err)
+			}
+			if err := svc.ResourceRepo.EnsureIndexes(ctx); err != nil {
+				return fmt.Errorf("resource indexes: %w", err)
+			}
+			if err := svc.ExternalIDRepo.EnsureIndexes(ctx); err != nil {
+				return fmt.Errorf("external ID indexes: %w", err)
+			}
+			if err := svc.AuditRepo.EnsureIndexes(ctx); err != nil {
+				return fmt.Errorf("audit log indexes: %w", err)
+			}
+			if err := svc.DeviceRepo.EnsureIndexes(ctx); err != nil {
+				return fmt.Errorf("mobile device indexes: %w", err)
+			}
+			if err := svc.UsageRepo.EnsureIndexes(ctx); err != nil {
+				return fmt.Errorf("API usage indexes: %w", err)
+			}
+			if err := svc.AuditArchives.EnsureIndexes(ctx); err != nil {
+				return fmt.Errorf("audit archive indexes: %w", err)
+			}
+			if err := svc.FlagRepo.EnsureIndexes(ctx); err != nil {
+				return fmt.Errorf("feature flag indexes: %w", err)
+			}
+			fmt.Println("indexes rebuilt")
+			return nil
+		},
+	}
+}
+
+func runSyncCommand(svc *adminServices) *cobra.Command {
+	var tenant, id string
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Run an organization's syncs now",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := withTenant(cmd.Context(), svc, tenant)
+			if err != nil {
+				return err
+			}
+
+			ids := []string{id}
+			if id == "" {
+				settings, err := svc.Sync.ListSettings(ctx)
+				if err != nil {
+					return err
+				}
+				ids = ids[:0]
+				for _, s := range settings {
+					ids = append(ids, s.ID.Hex())
+				}
+			}
+
+			var failed int
+			for _, settingID := range ids {
+				if err := svc.Sync.RunSync(ctx, settingID); err != nil {
+					fmt.Printf("sync %s failed: %v\n", settingID, err)
+					failed++
+					continue
+				}
+				fmt.Printf("sync %s done\n", settingID)
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d syncs failed", failed, len(ids))
+			}
+			return nil
+		},
+	}
+	tenantFlag(cmd, &tenant, true)
+	cmd.Flags().StringVar(&id, "id", "", "Sync setting ID; all settings when empty")
+	return cmd
+}
+
+// anonymizeSandboxCommand rewrites the personal data of a sandbox's records,
+// for refreshing a staging sandbox with production data
+func anonymizeSandboxCommand(svc *adminServices) *cobra.Command {
+	var tenant string
+	cmd := &cobra.Command{
+		Use:   "anonymize",
+		Short: "Anonymize the records of a sandbox",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := withTenant(cmd.Context(), svc, tenant)
+			if err != nil {
+				return err
+			}
+			sandboxID, _ := primitive.ObjectIDFromHex(ctx.Value(models.TenantIDKey).(string))
+
+			job, err := svc.Sandboxes.RunAnonymize(ctx, sandboxID, "admin-cli")
+			if err != nil {
+				return err
+			}
+			modules := make([]string, 0, len(job.Records))
+			for name := range job.Records {
+				modules = append(modules, name)
+			}
+			sort.Strings(modules)
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "MODULE\tRECORDS")
+			for _, name := range modules {
+				fmt.Fprintf(w, "%s\t%d\n", name, job.Records[name])
+			}
+			w.Flush()
+			fmt.Printf("anonymized %d records\n", job.Processed)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&tenant, "tenant", "", "Sandbox organization ID or name")
+	requireFlags(cmd, "tenant")
+	return cmd
+}
+
+// usageReportCommand prints API usage across tenants, or of one tenant with
+// --tenant
+func usageReportCommand(svc *adminServices) *cobra.Command {
+	var tenant, by string
+	var hours, limit int
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Report API usage",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			q := api_usage.ReportQuery{From: time.Now().Add(-time.Duration(hours) * time.Hour), GroupBy: by, Limit: limit}
+			var report *api_usage.UsageReport
+			var err error
+			if tenant == "" {
+				if q.GroupBy == "" {
+					q.GroupBy = api_usage.GroupByTenant
+				}
+				report, err = svc.Usage.ReportAll(ctx, q)
+			} else {
+				ctx, err = withTenant(ctx, svc, tenant)
+				if err != nil {
+					return err
+				}
+				report, err = svc.Usage.Report(ctx, q)
+			}
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintf(w, "%s\trequests\t4xx\t5xx\terror rate\tavg ms\tp50\tp95\tp99\n", report.GroupBy)
+			printRow := func(key string, st api_usage.UsageStats) {
+				fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.2f%%\t%.0f\t%d\t%d\t%d\n", key, st.Requests, st.ClientErrors, st.Errors, st.ErrorRate*100, st.AvgMs, st.P50Ms, st.P95Ms, st.P99Ms)
+			}
+			for _, row := range report.Rows {
+				printRow(row.Key, row.UsageStats)
+			}
+			printRow("total", report.Totals)
+			return w.Flush()
+		},
+	}
+	cmd.Flags().StringVar(&tenant, "tenant", "", "Organization ID or name; every tenant when empty")
+	cmd.Flags().StringVar(&by, "by", "", "Breakdown: tenant, user, key or endpoint")
+	cmd.Flags().IntVar(&hours, "hours", 24, "Hours back from now")
+	cmd.Flags().IntVar(&limit, "limit", 20, "Rows")
+	return cmd
+}
+
+func listFlagsCommand(svc *adminServices) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List feature flags",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags, err := svc.Flags.All(cmd.Context())
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "key\tstate\ttenants\tusers\toverrides\tdescription")
+			for _, f := range flags {
+				state := "live"
+				if f.Killed {
+					state = "killed"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%d%%\t%d%%\t%d\t%s\n", f.Key, state, f.TenantPercent, f.UserPercent, len(f.Tenants), f.Description)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+// setFlagCommand creates a flag or changes the settings given, leaving the
+// others. Running instances pick the change up within
+// feature_flag.RefreshInterval.
+func setFlagCommand(svc *adminServices) *cobra.Command {
+	var key, description string
+	var tenants, users int
+	var kill bool
+	cmd := &cobra.Command{
+		Use:   "set",
+		Short: "Create or change a feature flag",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			f := &feature_flag.Flag{Key: key, UserPercent: 100}
+			flags, err := svc.Flags.All(ctx)
+			if err != nil {
+				return err
+			}
+			for i := range flags {
+				if flags[i].Key == key {
+					f = &flags[i]
+				}
+			}
+			changed := cmd.Flags().Changed
+			if changed("description") {
+				f.Description = description
+			}
+			if changed("tenants") {
+				f.TenantPercent = tenants
+			}
+			if changed("users") {
+				f.UserPercent = users
+			}
+			if changed("kill") {
+				f.Killed = kill
+			}
+			if err := svc.Flags.Save(ctx, f); err != nil {
+				return err
+			}
+			fmt.Printf("flag %s: killed=%t tenants=%d%% users=%d%%\n", f.Key, f.Killed, f.TenantPercent, f.UserPercent)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&key, "key", "", "Flag key")
+	cmd.Flags().StringVar(&description, "description", "", "What the flag gates")
+	cmd.Flags().IntVar(&tenants, "tenants", 0, "Share of tenants to roll out to, 0-100")
+	cmd.Flags().IntVar(&users, "users", 100, "Share of users within those tenants, 0-100")
+	cmd.Flags().BoolVar(&kill, "kill", false, "Turn the flag off everywhere; --kill=false restores the rollout")
+	requireFlags(cmd, "key")
+	return cmd
+}
+
+func deleteFlagCommand(svc *adminServices) *cobra.Command {
+	var key string
+	cmd := &cobra.Command{
+		Use:   "delete",
+		Short: "Delete a feature flag",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := svc.Flags.Delete(cmd.Context(), key); err != nil {
+				return err
+			}
+			fmt.Printf("flag %s deleted\n", key)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&key, "key", "", "Flag key")
+	requireFlags(cmd, "key")
+	return cmd
+}
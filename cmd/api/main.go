@@ -2,38 +2,77 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	common_api "go-crm/internal/common/api"
 	"go-crm/internal/config"
 	"go-crm/internal/database"
+	"go-crm/internal/features/access_review"
+	"go-crm/internal/features/account"
+	"go-crm/internal/features/accounting"
 	"go-crm/internal/features/activity"
+	"go-crm/internal/features/addin"
 	"go-crm/internal/features/admin"
+	"go-crm/internal/features/ai"
 	"go-crm/internal/features/analytics"
+	"go-crm/internal/features/api_usage"
 	"go-crm/internal/features/approval"
+	"go-crm/internal/features/archive"
+	"go-crm/internal/features/asset"
 	"go-crm/internal/features/audit"
+	"go-crm/internal/features/audit_archive"
 	"go-crm/internal/features/auth"
+	"go-crm/internal/features/authz"
 	"go-crm/internal/features/automation"
+	"go-crm/internal/features/blueprint"
 	"go-crm/internal/features/bulk_operation"
 	"go-crm/internal/features/chart"
+	"go-crm/internal/features/comment"
+	"go-crm/internal/features/contract"
 	cron_feature "go-crm/internal/features/cron"
+	"go-crm/internal/features/custom_action"
 	"go-crm/internal/features/dashboard"
+	"go-crm/internal/features/data_quality"
+	"go-crm/internal/features/dedupe"
 	"go-crm/internal/features/email"
 	"go-crm/internal/features/email_template"
+	"go-crm/internal/features/email_verification"
+	"go-crm/internal/features/esign"
+	"go-crm/internal/features/exchange"
+	"go-crm/internal/features/export"
 	"go-crm/internal/features/extension"
+	"go-crm/internal/features/feature_flag"
 	"go-crm/internal/features/file"
+	"go-crm/internal/features/follow"
+	"go-crm/internal/features/forecast"
+	"go-crm/internal/features/gql"
 	"go-crm/internal/features/group"
+	"go-crm/internal/features/impersonation"
 	import_feature "go-crm/internal/features/import"
+	"go-crm/internal/features/marketing"
+	"go-crm/internal/features/mobile"
 	"go-crm/internal/features/module"
 	"go-crm/internal/features/notification"
+	"go-crm/internal/features/opportunity"
 	"go-crm/internal/features/organization"
 	"go-crm/internal/features/permission"
+	"go-crm/internal/features/plugin"
+	"go-crm/internal/features/print_template"
+	"go-crm/internal/features/project"
+	"go-crm/internal/features/purchasing"
+	"go-crm/internal/features/quota"
+	"go-crm/internal/features/recalculation"
 	"go-crm/internal/features/record"
+	"go-crm/internal/features/record_template"
+	"go-crm/internal/features/reminder"
 	"go-crm/internal/features/report"
 	"go-crm/internal/features/resource"
 	"go-crm/internal/features/role"
+	"go-crm/internal/features/sandbox"
 	"go-crm/internal/features/saved_filter"
 	"go-crm/internal/features/search"
 	"go-crm/internal/features/settings"
+	"go-crm/internal/features/stage_gate"
 	"go-crm/internal/features/sync"
 	"go-crm/internal/features/system"
 	"go-crm/internal/features/ticket"
@@ -41,7 +80,9 @@ import (
 	"go-crm/internal/features/webhook"
 	"go-crm/internal/logger"
 	"go-crm/internal/middleware"
+	"go-crm/internal/migration"
 	"log"
+	"os"
 	"time"
 
 	_ "go-crm/docs" // Import swagger docs
@@ -53,29 +94,46 @@ import (
 )
 
 // NewFiberServer creates a new Fiber app instance
-func NewFiberServer() *fiber.App {
+func NewFiberServer(cfg *config.Config) *fiber.App {
 	app := fiber.New(fiber.Config{
 		DisableStartupMessage: true,
-		ErrorHandler: func(c *fiber.Ctx, err error) error {
-			code := fiber.StatusInternalServerError
-			if e, ok := err.(*fiber.Error); ok {
-				code = e.Code
-			}
-			return c.Status(code).JSON(fiber.Map{
-				"error": err.Error(),
-			})
-		},
+		ErrorHandler:          common_api.ErrorHandler,
 	})
 
-	// Use custom CORS middleware
-	app.Use(middleware.CORSMiddleware())
+	// Timed first so usage latency covers every other middleware
+	app.Use(middleware.UsageMiddleware())
+
+	// CORS and security headers from config
+	app.Use(middleware.CORSMiddleware(cfg))
+	app.Use(middleware.SecurityHeadersMiddleware(cfg))
 
 	// Add Product middleware to extract X-Rich-Product header
 	app.Use(middleware.ProductMiddleware())
 
+	// Write requests read from the primary when read offloading is on
+	app.Use(middleware.ReadConsistencyMiddleware())
+
+	configureAPIVersions(cfg)
+
 	return app
 }
 
+// configureAPIVersions applies the v1 deprecation schedule from config
+func configureAPIVersions(cfg *config.Config) {
+	if cfg.APIV1Sunset == "" && cfg.APIV1DeprecatedAt == "" {
+		return
+	}
+	parse := func(value string) time.Time {
+		for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t
+			}
+		}
+		return time.Time{}
+	}
+	common_api.DeprecateVersion(common_api.V1, parse(cfg.APIV1DeprecatedAt), parse(cfg.APIV1Sunset), cfg.APIDeprecationLink)
+}
+
 // AsRoute is a helper function to reduce boilerplate.
 // It tags the constructor so Fx knows to add it to the "routes" group.
 func AsRoute(f any) any {
@@ -124,7 +182,7 @@ func StartServer(lc fx.Lifecycle, app *fiber.App, cfg *config.Config) {
 }
 
 // InitializeIndexes ensures that necessary database indexes are created
-func InitializeIndexes(lc fx.Lifecycle, moduleRepo module.ModuleRepository, resourceRepo resource.ResourceRepository) {
+func InitializeIndexes(lc fx.Lifecycle, moduleRepo module.ModuleRepository, resourceRepo resource.ResourceRepository, externalIDRepo record.ExternalIDRepository, auditRepo audit.AuditRepository, deviceRepo mobile.DeviceRepository, usageRepo api_usage.UsageRepository, auditArchiveRepo audit_archive.ArchiveRepository, flagRepo feature_flag.FlagRepository) {
 	lc.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
 			go func() {
@@ -138,6 +196,24 @@ func InitializeIndexes(lc fx.Lifecycle, moduleRepo module.ModuleRepository, reso
 				if err := resourceRepo.EnsureIndexes(ctx); err != nil {
 					log.Printf("Failed to ensure resource indexes: %v", err)
 				}
+				if err := externalIDRepo.EnsureIndexes(ctx); err != nil {
+					log.Printf("Failed to ensure external ID indexes: %v", err)
+				}
+				if err := auditRepo.EnsureIndexes(ctx); err != nil {
+					log.Printf("Failed to ensure audit log indexes: %v", err)
+				}
+				if err := deviceRepo.EnsureIndexes(ctx); err != nil {
+					log.Printf("Failed to ensure mobile device indexes: %v", err)
+				}
+				if err := usageRepo.EnsureIndexes(ctx); err != nil {
+					log.Printf("Failed to ensure API usage indexes: %v", err)
+				}
+				if err := auditArchiveRepo.EnsureIndexes(ctx); err != nil {
+					log.Printf("Failed to ensure audit archive indexes: %v", err)
+				}
+				if err := flagRepo.EnsureIndexes(ctx); err != nil {
+					log.Printf("Failed to ensure feature flag indexes: %v", err)
+				}
 			}()
 			return nil
 		},
@@ -234,8 +310,11 @@ func (a *resourceServiceAdapter) DeleteResource(ctx context.Context, resourceID
 
 // @host            localhost:8000
 // @BasePath        /
-func main() {
-	app := fx.New(
+// appProviders builds every repository, service, controller and route. The
+// server and the admin commands share it; fx only constructs what an
+// invoke asks for.
+func appProviders() fx.Option {
+	return fx.Options(
 		fx.Provide(
 			// Load Config
 			config.LoadConfig,
@@ -248,6 +327,9 @@ func main() {
 
 			// Initialize Database
 			database.NewDatabase,
+			migration.NewMigrationRepository,
+			migration.NewSeeder,
+			migration.NewRunner,
 
 			// Initialize Repository
 			file.NewFileRepository,
@@ -256,15 +338,29 @@ func main() {
 			organization.NewOrganizationRepository,
 			user.NewUserRepository,
 			record.NewRecordRepository,
+			record.NewCounterRepository,
+			record.NewExternalIDRepository,
 			role.NewRoleRepository,
 			approval.NewApprovalRepository,
 			report.NewReportRepository,
+			addin.NewLoggedEmailRepository,
+			mobile.NewDeviceRepository,
+			api_usage.NewUsageRepository,
+			audit_archive.NewPolicyRepository,
+			audit_archive.NewArchiveRepository,
+			email_verification.NewSettingsRepository,
+			email_verification.NewRunRepository,
+			quota.NewUsageRepository,
+			recalculation.NewJobRepository,
 			automation.NewAutomationRepository,
 			settings.NewSettingsRepository,
 			ticket.NewTicketRepository,
 			ticket.NewSLAPolicyRepository,
 			ticket.NewTicketCommentRepository,
 			ticket.NewEscalationRuleRepository,
+			ticket.NewAgentAvailabilityRepository,
+			ticket.NewTicketSettingsRepository,
+			ticket.NewTicketSentimentRepository,
 			group.NewGroupRepository,
 			notification.NewNotificationRepository,
 			webhook.NewWebhookRepository,
@@ -272,20 +368,71 @@ func main() {
 			extension.NewExtensionRepository,
 			sync.NewSyncSettingRepository,
 			sync.NewSyncLogRepository,
+			sync.NewSyncStateRepository,
 			chart.NewChartRepository,
 			dashboard.NewDashboardRepository,
+			dashboard.NewSnapshotRepository,
 			email.NewEmailRepository,
+			email.NewSuppressionRepository,
 			email_template.NewEmailTemplateRepository,
 			bulk_operation.NewBulkOperationRepository,
 			saved_filter.NewSavedFilterRepository,
 			cron_feature.NewCronRepository,
 			import_feature.NewImportRepository,
+			import_feature.NewCRMImportRepository,
 			analytics.NewMetricRepository,
 			analytics.NewDataSourceRepository,
+			analytics.NewProductivityRepository,
 			resource.NewResourceRepository,
 			permission.NewPermissionRepository,
+			forecast.NewGoalRepository,
+			forecast.NewScoreRepository,
+			dedupe.NewDedupeRepository,
+			export.NewExportRepository,
+			print_template.NewPrintTemplateRepository,
+			print_template.NewDocumentTemplateRepository,
+			record_template.NewRecordTemplateRepository,
+			esign.NewSignatureRepository,
+			comment.NewCommentRepository,
+			comment.NewCommentSettingsRepository,
+			follow.NewFollowRepository,
+			follow.NewPreferencesRepository,
+			follow.NewOwnershipRepository,
+			reminder.NewReminderRepository,
+			activity.NewCalendarFeedRepository,
+			accounting.NewConnectionRepository,
+			accounting.NewMappingRepository,
+			marketing.NewConnectionRepository,
+			marketing.NewAudienceSyncRepository,
+			marketing.NewMemberStateRepository,
+			exchange.NewExchangeRepository,
+			exchange.NewRunRepository,
+			plugin.NewPluginRepository,
+			plugin.NewInvocationRepository,
+			data_quality.NewRuleRepository,
+			data_quality.NewViolationRepository,
+			data_quality.NewScoreRepository,
+			archive.NewPolicyRepository,
+			archive.NewArchiveRepository,
+			asset.NewAssetRepository,
+			asset.NewWarrantyEventRepository,
+			contract.NewContractRepository,
+			contract.NewReminderRepository,
+			project.NewTemplateRepository,
+			project.NewDependencyRepository,
+			stage_gate.NewStageGateRepository,
+			blueprint.NewBlueprintRepository,
+			impersonation.NewImpersonationRepository,
+			sandbox.NewSandboxRepository,
+			authz.NewVersionRepository,
+			feature_flag.NewFlagRepository,
+
+			// File storage backend and upload scanning
+			file.NewStorage,
+			file.NewVirusScanner,
 
 			audit.NewAuditService,
+			authz.NewVersionService,
 			auth.NewAuthService,
 			role.NewRoleService,
 			module.NewModuleService,
@@ -296,11 +443,21 @@ func main() {
 			approval.NewApprovalService,
 			settings.NewSettingsService,
 			report.NewReportService,
+			addin.NewAddInService,
+			mobile.NewMobileSyncService,
+			api_usage.NewUsageService,
+			audit_archive.NewAuditArchiveService,
+			email_verification.NewEmailVerificationService,
+			quota.NewQuotaService,
+			recalculation.NewRecalculationService,
+			access_review.NewAccessReviewService,
+			feature_flag.NewFeatureFlagService,
 			automation.NewActionExecutor,
 			automation.NewAutomationService,
 			ticket.NewTicketService,
 			ticket.NewSLAService,
 			ticket.NewEscalationService,
+			ticket.NewWorkloadService,
 			notification.NewNotificationService,
 			webhook.NewWebhookService,
 			extension.NewExtensionService,
@@ -317,13 +474,57 @@ func main() {
 			saved_filter.NewSavedFilterService,
 			analytics.NewAnalyticsService,
 			analytics.NewDataSourceService,
+			analytics.NewProductivityService,
 			resource.NewResourceService,
 			permission.NewPermissionService,
+			forecast.NewForecastService,
+			dedupe.NewDedupeService,
+			gql.NewGraphQLService,
+			export.NewExportService,
+			print_template.NewPrintTemplateService,
+			print_template.NewDocumentTemplateService,
+			record_template.NewRecordTemplateService,
+			ai.NewAIService,
+			esign.NewESignService,
+			comment.NewCommentService,
+			follow.NewFollowService,
+			follow.NewChangeNotifier,
+			follow.NewDigest,
+			reminder.NewReminderService,
+			reminder.NewDispatcher,
+			accounting.NewAccountingService,
+			marketing.NewMarketingService,
+			exchange.NewExchangeService,
+			plugin.NewPluginService,
+			custom_action.NewCustomActionService,
+			data_quality.NewDataQualityService,
+			archive.NewArchiveService,
+			asset.NewAssetService,
+			account.NewAccountService,
+			opportunity.NewOpportunityService,
+			contract.NewContractService,
+			purchasing.NewPurchasingService,
+			project.NewPlanner,
+			project.NewProjectService,
+			stage_gate.NewStageGateService,
+			blueprint.NewBlueprintService,
+			impersonation.NewImpersonationService,
+			sandbox.NewSandboxService,
+			func(n *follow.ChangeNotifier, d *reminder.Dispatcher, p *project.Planner, b blueprint.BlueprintService) record.ChangeListener {
+				return record.ChangeListeners{n, d, p, b}
+			},
+			record.NewChangeStream,
 
 			// Interface Adapters to break circular dependencies and satisfy Fx
 			func(s approval.ApprovalService) record.ApprovalTrigger { return s },
 			func(s automation.AutomationService) record.AutomationTrigger { return s },
+			func(s plugin.PluginService) record.RecordHooks { return s },
+			func(s stage_gate.StageGateService) record.StageValidator { return s },
+			func(s blueprint.BlueprintService) record.TransitionGuard { return s },
+			func(s settings.SettingsService) record.TimezoneResolver { return s },
+			func(s blueprint.BlueprintService) ticket.StatusMachine { return s },
 			func(s role.RoleService) middleware.RoleService { return s },
+			func(s role.RoleService) user.RoleGrants { return s },
 			func(r user.UserRepository) audit.UserFinder { return r },
 			func(s resource.ResourceService) interface {
 				CreateResource(ctx context.Context, resource interface{}) error
@@ -345,10 +546,20 @@ func main() {
 			system.NewWebSocketController,
 			approval.NewApprovalController,
 			report.NewReportController,
+			addin.NewAddInController,
+			mobile.NewMobileController,
+			api_usage.NewUsageController,
+			audit_archive.NewAuditArchiveController,
+			email_verification.NewEmailVerificationController,
+			quota.NewQuotaController,
+			recalculation.NewRecalculationController,
+			access_review.NewAccessReviewController,
+			feature_flag.NewFeatureFlagController,
 			automation.NewAutomationController,
 			settings.NewSettingsController,
 			ticket.NewTicketController,
 			ticket.NewSLAMetricsController,
+			ticket.NewWorkloadController,
 			group.NewGroupController,
 			notification.NewNotificationController,
 			webhook.NewWebhookController,
@@ -365,8 +576,39 @@ func main() {
 			cron_feature.NewCronController,
 			analytics.NewAnalyticsController,
 			analytics.NewDataSourceController,
+			analytics.NewProductivityController,
 			resource.NewResourceController,
 			permission.NewPermissionController,
+			forecast.NewForecastController,
+			dedupe.NewDedupeController,
+			gql.NewGraphQLController,
+			export.NewExportController,
+			print_template.NewPrintTemplateController,
+			print_template.NewDocumentTemplateController,
+			record_template.NewRecordTemplateController,
+			ai.NewAIController,
+			esign.NewESignController,
+			comment.NewCommentController,
+			follow.NewFollowController,
+			reminder.NewReminderController,
+			accounting.NewAccountingController,
+			marketing.NewMarketingController,
+			email.NewEmailController,
+			exchange.NewExchangeController,
+			plugin.NewPluginController,
+			custom_action.NewCustomActionController,
+			data_quality.NewDataQualityController,
+			archive.NewArchiveController,
+			asset.NewAssetController,
+			account.NewAccountController,
+			opportunity.NewOpportunityController,
+			contract.NewContractController,
+			purchasing.NewPurchasingController,
+			project.NewProjectController,
+			stage_gate.NewStageGateController,
+			blueprint.NewBlueprintController,
+			impersonation.NewImpersonationController,
+			sandbox.NewSandboxController,
 
 			// Initialize API Routes
 			AsRoute(admin.NewAdminApi),
@@ -381,6 +623,15 @@ func main() {
 			AsRoute(system.NewHealthApi),
 			AsRoute(approval.NewApprovalApi),
 			AsRoute(report.NewReportApi),
+			AsRoute(addin.NewAddInApi),
+			AsRoute(mobile.NewMobileApi),
+			AsRoute(api_usage.NewUsageApi),
+			AsRoute(audit_archive.NewAuditArchiveApi),
+			AsRoute(email_verification.NewEmailVerificationApi),
+			AsRoute(quota.NewQuotaApi),
+			AsRoute(recalculation.NewRecalculationApi),
+			AsRoute(access_review.NewAccessReviewApi),
+			AsRoute(feature_flag.NewFeatureFlagApi),
 			AsRoute(automation.NewAutomationApi),
 			AsRoute(settings.NewSettingsApi),
 			AsRoute(ticket.NewTicketApi),
@@ -401,10 +652,64 @@ func main() {
 			AsRoute(system.NewSwaggerApi),
 			AsRoute(analytics.NewAnalyticsApi),
 			AsRoute(analytics.NewDataSourceApi),
+			AsRoute(analytics.NewProductivityApi),
 			AsRoute(resource.NewResourceApi),
 			AsRoute(permission.NewPermissionApi),
+			AsRoute(forecast.NewForecastApi),
+			AsRoute(dedupe.NewDedupeApi),
+			AsRoute(gql.NewGraphQLApi),
+			AsRoute(export.NewExportApi),
+			AsRoute(print_template.NewPrintTemplateApi),
+			AsRoute(record_template.NewRecordTemplateApi),
+			AsRoute(ai.NewAIApi),
+			AsRoute(esign.NewESignApi),
+			AsRoute(comment.NewCommentApi),
+			AsRoute(follow.NewFollowApi),
+			AsRoute(reminder.NewReminderApi),
+			AsRoute(accounting.NewAccountingApi),
+			AsRoute(marketing.NewMarketingApi),
+			AsRoute(email.NewEmailApi),
+			AsRoute(exchange.NewExchangeApi),
+			AsRoute(plugin.NewPluginApi),
+			AsRoute(custom_action.NewCustomActionApi),
+			AsRoute(data_quality.NewDataQualityApi),
+			AsRoute(archive.NewArchiveApi),
+			AsRoute(asset.NewAssetApi),
+			AsRoute(account.NewAccountApi),
+			AsRoute(opportunity.NewOpportunityApi),
+			AsRoute(contract.NewContractApi),
+			AsRoute(purchasing.NewPurchasingApi),
+			AsRoute(project.NewProjectApi),
+			AsRoute(stage_gate.NewStageGateApi),
+			AsRoute(blueprint.NewBlueprintApi),
+			AsRoute(impersonation.NewImpersonationApi),
+			AsRoute(sandbox.NewSandboxApi),
 			AsRoute(system.NewWebSocketApi),
 		),
+	)
+}
+
+func main() {
+	// Timestamps are stored and served in UTC whatever the host's zone is;
+	// users' timezones are applied when dates are parsed and displayed
+	time.Local = time.UTC
+
+	migrate := flag.Bool("migrate", false, "Apply pending migrations and exit")
+	demo := flag.Bool("demo", false, "With -migrate, also load demo users")
+	status := flag.Bool("migrate-status", false, "List migrations and whether they are applied, then exit")
+	adminPassword := flag.String("admin-password", os.Getenv("ADMIN_PASSWORD"), "With -migrate, the first admin's password (default $ADMIN_PASSWORD)")
+	flag.Parse()
+	if *migrate || *status {
+		runMigrations(migration.Options{Demo: *demo, AdminPassword: *adminPassword}, *status)
+		return
+	}
+	if flag.Arg(0) == "admin" {
+		runAdmin(flag.Args()[1:])
+		return
+	}
+
+	app := fx.New(
+		appProviders(),
 		fx.WithLogger(func(log *zap.Logger) fxevent.Logger {
 			return &fxevent.ZapLogger{Logger: log}
 		}),
@@ -412,6 +717,96 @@ func main() {
 			// Register Routes & Start
 			RegisterAllRoutesWithAnnotation,
 			StartServer,
+			func(s impersonation.ImpersonationService) {
+				middleware.SetImpersonationValidator(s.IsActive)
+			},
+			func(v authz.VersionService, s role.RoleService) {
+				middleware.SetPermissionsVersionSource(v.Current)
+				v.SetDependents(s.ExtendingRoleIDs)
+			},
+			func(s settings.SettingsService) {
+				middleware.SetLocaleResolver(s.ResolveLocale)
+			},
+			func(lc fx.Lifecycle, s feature_flag.FeatureFlagService) {
+				middleware.SetFeatureResolver(s.EnabledKeys)
+				ctx, cancel := context.WithCancel(context.Background())
+				lc.Append(fx.Hook{
+					OnStart: func(startCtx context.Context) error {
+						// Load before serving so the first requests see the flags
+						if err := s.Refresh(startCtx); err != nil {
+							log.Printf("Failed to load feature flags: %v", err)
+						}
+						go s.Run(ctx)
+						return nil
+					},
+					OnStop: func(context.Context) error {
+						cancel()
+						return nil
+					},
+				})
+			},
+			func(lc fx.Lifecycle, s api_usage.UsageService) {
+				middleware.SetUsageRecorder(s.Record)
+				ctx, cancel := context.WithCancel(context.Background())
+				done := make(chan struct{})
+				lc.Append(fx.Hook{
+					OnStart: func(context.Context) error {
+						go func() {
+							defer close(done)
+							s.Run(ctx)
+						}()
+						return nil
+					},
+					OnStop: func(context.Context) error {
+						// Run writes what is still buffered before returning
+						cancel()
+						<-done
+						return nil
+					},
+				})
+			},
+			func(cronService cron_feature.CronService, d *reminder.Dispatcher) error {
+				return cronService.RegisterSystemJob("reminders", reminder.DispatchSchedule, d.Run)
+			},
+			func(cronService cron_feature.CronService, d *follow.Digest) error {
+				return cronService.RegisterSystemJob("ownership_digest", follow.DigestSchedule, d.Run)
+			},
+			func(cronService cron_feature.CronService, s accounting.AccountingService) error {
+				return cronService.RegisterSystemJob("accounting_sync", accounting.SyncSchedule, s.SyncAll)
+			},
+			func(cronService cron_feature.CronService, s data_quality.DataQualityService) error {
+				return cronService.RegisterSystemJob("data_quality", data_quality.EvaluationSchedule, s.EvaluateAll)
+			},
+			func(cronService cron_feature.CronService, s forecast.ForecastService) error {
+				return cronService.RegisterSystemJob("deal_scoring", forecast.ScoringSchedule, s.ScoreAll)
+			},
+			func(cronService cron_feature.CronService, s record.RecordService) error {
+				return cronService.RegisterSystemJob("record_counters", record.CounterRebuildSchedule, s.RebuildCounters)
+			},
+			func(cronService cron_feature.CronService, s archive.ArchiveService) error {
+				return cronService.RegisterSystemJob("record_archival", archive.ArchiveSchedule, s.RunAll)
+			},
+			func(cronService cron_feature.CronService, s audit_archive.AuditArchiveService) error {
+				return cronService.RegisterSystemJob("audit_retention", audit_archive.RetentionSchedule, s.RunAll)
+			},
+			func(cronService cron_feature.CronService, s email_verification.EmailVerificationService) error {
+				return cronService.RegisterSystemJob("email_verification", email_verification.VerificationSchedule, s.RunAll)
+			},
+			func(cronService cron_feature.CronService, s asset.AssetService) error {
+				return cronService.RegisterSystemJob("asset_warranty", asset.WarrantySchedule, s.CheckWarranties)
+			},
+			func(cronService cron_feature.CronService, s contract.ContractService) error {
+				return cronService.RegisterSystemJob("contract_renewals", contract.RenewalSchedule, s.RunRenewals)
+			},
+			func(cronService cron_feature.CronService, s ticket.TicketService) error {
+				return cronService.RegisterSystemJob("ticket_auto_close", ticket.AutoCloseSchedule, s.AutoCloseResolved)
+			},
+			func(cronService cron_feature.CronService, s ticket.TicketService) error {
+				return cronService.RegisterSystemJob("ticket_sla_notifications", ticket.SLANotificationSchedule, s.NotifySLA)
+			},
+			func(cronService cron_feature.CronService, s ticket.EscalationService) error {
+				return cronService.RegisterSystemJob("ticket_escalations", ticket.EscalationSchedule, s.ProcessEscalations)
+			},
 			func(lc fx.Lifecycle, cronService cron_feature.CronService) {
 				lc.Append(fx.Hook{
 					OnStart: func(ctx context.Context) error {
@@ -422,6 +817,32 @@ func main() {
 					},
 				})
 			},
+			func(lc fx.Lifecycle, cs *record.ChangeStream) {
+				ctx, cancel := context.WithCancel(context.Background())
+				lc.Append(fx.Hook{
+					OnStart: func(context.Context) error {
+						go cs.Run(ctx)
+						return nil
+					},
+					OnStop: func(context.Context) error {
+						cancel()
+						return nil
+					},
+				})
+			},
+			func(lc fx.Lifecycle, s recalculation.RecalculationService) {
+				ctx, cancel := context.WithCancel(context.Background())
+				lc.Append(fx.Hook{
+					OnStart: func(context.Context) error {
+						go s.ResumeInterrupted(ctx)
+						return nil
+					},
+					OnStop: func(context.Context) error {
+						cancel()
+						return nil
+					},
+				})
+			},
 			InitializeIndexes,
 		),
 	)
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"go-crm/internal/migration"
+
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxevent"
+	"go.uber.org/zap"
+)
+
+// runMigrations applies pending migrations, or only lists them, then exits
+func runMigrations(opts migration.Options, statusOnly bool) {
+	app := fx.New(
+		appProviders(),
+		fx.WithLogger(func(log *zap.Logger) fxevent.Logger {
+			return &fxevent.ZapLogger{Logger: log}
+		}),
+		fx.Invoke(func(lc fx.Lifecycle, runner *migration.Runner, logger *zap.Logger, shutdowner fx.Shutdowner) {
+			lc.Append(fx.Hook{
+				OnStart: func(ctx context.Context) error {
+					go func() {
+						code := 0
+						if err := applyMigrations(context.Background(), runner, opts, statusOnly); err != nil {
+							logger.Error("Migration failed", zap.Error(err))
+							code = 1
+						}
+						_ = shutdowner.Shutdown(fx.ExitCode(code))
+					}()
+					return nil
+				},
+			})
+		}),
+	)
+
+	if err := app.Start(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+	sig := <-app.Wait()
+	_ = app.Stop(context.Background())
+	if sig.ExitCode != 0 {
+		log.Fatalf("migrations did not complete")
+	}
+}
+
+func applyMigrations(ctx context.Context, runner *migration.Runner, opts migration.Options, statusOnly bool) error {
+	if statusOnly {
+		statuses, err := runner.Status(ctx, opts)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "applied"
+			if s.Pending && s.Applied != nil {
+				state = "changed"
+			} else if s.Pending {
+				state = "pending"
+			}
+			fmt.Printf("%5d  %-24s %-7s %s\n", s.Step.Version, s.Step.Name, s.Step.Kind, state)
+		}
+		return nil
+	}
+
+	applied, err := runner.Run(ctx, opts)
+	for _, rec := range applied {
+		fmt.Printf("applied %d %s (%dms)\n", rec.Version, rec.Name, rec.DurationMs)
+	}
+	if err == nil && len(applied) == 0 {
+		fmt.Println("database is up to date")
+	}
+	return err
+}
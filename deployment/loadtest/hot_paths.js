@@ -0,0 +1,101 @@
+// k6 load profile for the busiest endpoints: record list with lookups,
+// record create with automations, and the ticket inbox.
+//
+//   k6 run -e BASE_URL=http://localhost:42111 -e USERNAME=loadtest -e PASSWORD=... \
+//     deployment/loadtest/hot_paths.js
+//
+// The thresholds are the regression budgets from README.md; k6 exits non-zero
+// when one is exceeded, so the run can gate a deploy.
+import http from 'k6/http';
+import { check, fail } from 'k6';
+
+const BASE_URL = __ENV.BASE_URL || 'http://localhost:42111';
+const LIST_MODULE = __ENV.LIST_MODULE || 'deals';
+const CREATE_MODULE = __ENV.CREATE_MODULE || 'leads';
+
+export const options = {
+  scenarios: {
+    record_list: {
+      executor: 'constant-arrival-rate',
+      exec: 'recordList',
+      rate: 60,
+      timeUnit: '1s',
+      duration: '2m',
+      preAllocatedVUs: 30,
+    },
+    record_create: {
+      executor: 'constant-arrival-rate',
+      exec: 'recordCreate',
+      rate: 10,
+      timeUnit: '1s',
+      duration: '2m',
+      preAllocatedVUs: 10,
+    },
+    ticket_list: {
+      executor: 'constant-arrival-rate',
+      exec: 'ticketList',
+      rate: 30,
+      timeUnit: '1s',
+      duration: '2m',
+      preAllocatedVUs: 15,
+    },
+  },
+  thresholds: {
+    'http_req_failed{scenario:record_list}': ['rate<0.01'],
+    'http_req_failed{scenario:record_create}': ['rate<0.01'],
+    'http_req_failed{scenario:ticket_list}': ['rate<0.01'],
+    'http_req_duration{scenario:record_list}': ['p(95)<250', 'p(99)<500'],
+    'http_req_duration{scenario:record_create}': ['p(95)<300', 'p(99)<600'],
+    'http_req_duration{scenario:ticket_list}': ['p(95)<150', 'p(99)<300'],
+  },
+};
+
+export function setup() {
+  const res = http.post(`${BASE_URL}/api/login`, JSON.stringify({
+    username: __ENV.USERNAME,
+    password: __ENV.PASSWORD,
+  }), { headers: { 'Content-Type': 'application/json' } });
+  if (res.status !== 200) {
+    fail(`login failed: ${res.status} ${res.body}`);
+  }
+  return { token: res.json('token') };
+}
+
+function params(data) {
+  return {
+    headers: {
+      Authorization: `Bearer ${data.token}`,
+      'Content-Type': 'application/json',
+    },
+  };
+}
+
+export function recordList(data) {
+  const page = 1 + Math.floor(Math.random() * 5);
+  const res = http.get(
+    `${BASE_URL}/api/modules/${LIST_MODULE}/records?page=${page}&limit=50&sort_by=amount&sort_order=desc`,
+    Object.assign(params(data), { tags: { name: 'record_list' } }),
+  );
+  check(res, { 'record list 200': (r) => r.status === 200 });
+}
+
+export function recordCreate(data) {
+  const body = JSON.stringify({
+    name: `Load test lead ${__VU}-${__ITER}`,
+    amount: 500 + Math.floor(Math.random() * 10000),
+  });
+  const res = http.post(
+    `${BASE_URL}/api/modules/${CREATE_MODULE}/records`,
+    body,
+    Object.assign(params(data), { tags: { name: 'record_create' } }),
+  );
+  check(res, { 'record create 2xx': (r) => r.status === 200 || r.status === 201 });
+}
+
+export function ticketList(data) {
+  const res = http.get(
+    `${BASE_URL}/api/tickets?status=open&page=1&limit=50`,
+    Object.assign(params(data), { tags: { name: 'ticket_list' } }),
+  );
+  check(res, { 'ticket list 200': (r) => r.status === 200 });
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+
+	"go-crm/internal/common/apperr"
+	"go-crm/internal/common/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Fail writes err as an error response. Errors classified by apperr use the
+// status of their kind, and field validation errors are sent as 422 with
+// their field errors under "errors"; other errors use status. "error" always
+// carries a readable message and "code" a stable apperr code.
+func Fail(c *fiber.Ctx, status int, err error) error {
+	code := apperr.CodeOf(err)
+	if code == apperr.CodeInternal {
+		code = apperr.CodeForStatus(status)
+	} else {
+		status = apperr.Status(code)
+	}
+	body := fiber.Map{"error": err.Error(), "code": code}
+	if fieldErrs, ok := validation.As(err); ok {
+		body["errors"] = fieldErrs
+	}
+	return c.Status(status).JSON(body)
+}
+
+// Error writes err with the status its classification maps to, 500 for
+// unclassified errors
+func Error(c *fiber.Ctx, err error) error {
+	return Fail(c, fiber.StatusInternalServerError, err)
+}
+
+// ErrorHandler is the app's fiber error handler: errors handlers return
+// instead of answering are mapped here, as are fiber's own such as 404 for
+// unknown routes
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		return c.Status(fe.Code).JSON(fiber.Map{"error": fe.Message, "code": apperr.CodeForStatus(fe.Code)})
+	}
+	return Error(c, err)
+}
+
+// InvalidBody answers a request body that does not parse. A value of the
+// wrong JSON type, or a validation error from the parser, is reported
+// against its field.
+func InvalidBody(c *fiber.Ctx, err error) error {
+	fieldErrs := validation.Errors{{Code: validation.CodeInvalidBody, Message: "Invalid request body"}}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		fieldErrs = validation.Errors{{
+			Code:    validation.CodeInvalid,
+			Field:   typeErr.Field,
+			Message: "wrong type for " + typeErr.Field + ": got " + typeErr.Value,
+		}}
+	} else if parsed, ok := validation.As(err); ok {
+		fieldErrs = parsed
+	}
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error":  "Invalid request body",
+		"code":   apperr.CodeBadRequest,
+		"errors": fieldErrs,
+	})
+}
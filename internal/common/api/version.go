@@ -0,0 +1,149 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Version identifies a public REST API version, e.g. "v1"
+type Version string
+
+const (
+	V1 Version = "v1"
+	V2 Version = "v2"
+
+	// DefaultVersion is served when a request does not ask for a specific version.
+	// Unversioned /api/... routes behave like v1 so existing clients keep working.
+	DefaultVersion = V1
+	LatestVersion  = V2
+
+	versionLocalsKey = "api_version"
+)
+
+// VersionPolicy describes the lifecycle of a version. Deprecated versions keep
+// working but advertise their retirement through Deprecation and Sunset headers.
+type VersionPolicy struct {
+	Deprecated   bool
+	DeprecatedAt time.Time
+	Sunset       time.Time
+	Link         string // Migration guide, sent as Link rel="deprecation"
+}
+
+// VersionPolicies lists every supported version. Versions not listed here are rejected.
+var VersionPolicies = map[Version]VersionPolicy{
+	V1: {},
+	V2: {},
+}
+
+var acceptVersionPattern = regexp.MustCompile(`application/vnd\.crm\.(v\d+)\+json`)
+
+// DeprecateVersion marks a version as deprecated so its responses carry
+// Deprecation, Sunset and Link headers
+func DeprecateVersion(v Version, deprecatedAt, sunset time.Time, link string) {
+	VersionPolicies[v] = VersionPolicy{
+		Deprecated:   true,
+		DeprecatedAt: deprecatedAt,
+		Sunset:       sunset,
+		Link:         link,
+	}
+}
+
+// IsSupported reports whether the version is known
+func (v Version) IsSupported() bool {
+	_, ok := VersionPolicies[v]
+	return ok
+}
+
+// NegotiateVersion resolves the requested version from, in order, the path
+// prefix (/api/v2/...), the Accept vendor type (application/vnd.crm.v2+json)
+// and the API-Version header, falling back to DefaultVersion.
+func NegotiateVersion(c *fiber.Ctx) Version {
+	path := strings.TrimPrefix(c.Path(), "/api/")
+	if i := strings.Index(path, "/"); i > 0 {
+		if v := Version(path[:i]); v.IsSupported() {
+			return v
+		}
+	}
+
+	if m := acceptVersionPattern.FindStringSubmatch(c.Get(fiber.HeaderAccept)); len(m) == 2 {
+		if v := Version(m[1]); v.IsSupported() {
+			return v
+		}
+	}
+
+	if header := strings.ToLower(strings.TrimSpace(c.Get("API-Version"))); header != "" {
+		if !strings.HasPrefix(header, "v") {
+			header = "v" + header
+		}
+		if v := Version(header); v.IsSupported() {
+			return v
+		}
+	}
+
+	return DefaultVersion
+}
+
+// RequestVersion returns the version resolved for the current request
+func RequestVersion(c *fiber.Ctx) Version {
+	if v, ok := c.Locals(versionLocalsKey).(Version); ok {
+		return v
+	}
+	return NegotiateVersion(c)
+}
+
+// VersionMiddleware pins the request to a version and emits lifecycle headers
+func VersionMiddleware(v Version) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return pinVersion(c, v)
+	}
+}
+
+// NegotiatedVersionMiddleware is VersionMiddleware for the unversioned
+// /api/... routes: the version comes from NegotiateVersion, so legacy clients
+// get the lifecycle headers of the version they are actually served.
+func NegotiatedVersionMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return pinVersion(c, NegotiateVersion(c))
+	}
+}
+
+func pinVersion(c *fiber.Ctx, v Version) error {
+	c.Locals(versionLocalsKey, v)
+	c.Set("API-Version", string(v))
+
+	if policy := VersionPolicies[v]; policy.Deprecated {
+		// RFC 9745 uses a structured date (@epoch); "true" is accepted by older clients
+		if !policy.DeprecatedAt.IsZero() {
+			c.Set("Deprecation", fmt.Sprintf("@%d", policy.DeprecatedAt.Unix()))
+		} else {
+			c.Set("Deprecation", "true")
+		}
+		if !policy.Sunset.IsZero() {
+			c.Set("Sunset", policy.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if policy.Link != "" {
+			c.Append(fiber.HeaderLink, fmt.Sprintf(`<%s>; rel="deprecation"`, policy.Link))
+		}
+	}
+
+	return c.Next()
+}
+
+// VersionedGroup registers the same prefix under /api/<version> for each
+// version, letting one Setup serve several versions. The register callback
+// receives the version so handlers that changed shape can be swapped per version.
+//
+//	api.VersionedGroup(app, "/tickets", []api.Version{api.V1, api.V2}, func(r fiber.Router, v api.Version) {
+//		r.Get("/", h.controller.ListTickets)
+//	}, middleware.AuthMiddleware(h.config.SkipAuth))
+func VersionedGroup(app *fiber.App, prefix string, versions []Version, register func(r fiber.Router, v Version), handlers ...fiber.Handler) {
+	for _, v := range versions {
+		chain := append([]fiber.Handler{VersionMiddleware(v)}, handlers...)
+		register(app.Group("/api/"+string(v)+prefix, chain...), v)
+	}
+}
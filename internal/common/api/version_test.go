@@ -0,0 +1,111 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestNegotiateVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		accept string
+		header string
+		want   Version
+	}{
+		{name: "default", path: "/api/tickets", want: DefaultVersion},
+		{name: "path", path: "/api/v2/tickets", want: V2},
+		{name: "accept", path: "/api/tickets", accept: "application/vnd.crm.v2+json", want: V2},
+		{name: "header", path: "/api/tickets", header: "v2", want: V2},
+		{name: "header without prefix", path: "/api/tickets", header: " 2 ", want: V2},
+		{name: "path over header", path: "/api/v1/tickets", header: "v2", want: V1},
+		{name: "path over accept", path: "/api/v1/tickets", accept: "application/vnd.crm.v2+json", want: V1},
+		{name: "accept over header", path: "/api/tickets", accept: "application/vnd.crm.v1+json", header: "v2", want: V1},
+		{name: "unknown path version", path: "/api/v9/tickets", want: DefaultVersion},
+		{name: "unknown path falls through to header", path: "/api/v9/tickets", header: "v2", want: V2},
+		{name: "unknown accept version", path: "/api/tickets", accept: "application/vnd.crm.v9+json", want: DefaultVersion},
+		{name: "unknown header version", path: "/api/tickets", header: "v9", want: DefaultVersion},
+		{name: "malformed header", path: "/api/tickets", header: "latest", want: DefaultVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Version
+			app := fiber.New()
+			app.Use(func(c *fiber.Ctx) error {
+				got = NegotiateVersion(c)
+				return c.SendStatus(fiber.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(fiber.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set(fiber.HeaderAccept, tt.accept)
+			}
+			if tt.header != "" {
+				req.Header.Set("API-Version", tt.header)
+			}
+			if _, err := app.Test(req); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("NegotiateVersion = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNegotiatedVersionMiddlewareSendsDeprecationHeaders(t *testing.T) {
+	saved := VersionPolicies[V1]
+	defer func() { VersionPolicies[V1] = saved }()
+	deprecatedAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+	DeprecateVersion(V1, deprecatedAt, sunset, "https://example.com/migrate")
+
+	app := fiber.New()
+	app.Get("/api/tickets", NegotiatedVersionMiddleware(), func(c *fiber.Ctx) error {
+		return c.SendString(string(RequestVersion(c)))
+	})
+
+	tests := []struct {
+		name       string
+		header     string
+		want       Version
+		deprecated bool
+	}{
+		{name: "legacy route served as v1", want: V1, deprecated: true},
+		{name: "legacy route asking for v2", header: "v2", want: V2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, "/api/tickets", nil)
+			if tt.header != "" {
+				req.Header.Set("API-Version", tt.header)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resp.Header.Get("API-Version"); got != string(tt.want) {
+				t.Errorf("API-Version = %q, want %q", got, tt.want)
+			}
+			if !tt.deprecated {
+				if got := resp.Header.Get("Deprecation"); got != "" {
+					t.Errorf("Deprecation = %q, want none", got)
+				}
+				return
+			}
+			if got := resp.Header.Get("Deprecation"); got != "@1767225600" {
+				t.Errorf("Deprecation = %q, want @1767225600", got)
+			}
+			if got := resp.Header.Get("Sunset"); got != "Thu, 31 Dec 2026 00:00:00 GMT" {
+				t.Errorf("Sunset = %q", got)
+			}
+			if got := resp.Header.Get(fiber.HeaderLink); got != `<https://example.com/migrate>; rel="deprecation"` {
+				t.Errorf("Link = %q", got)
+			}
+		})
+	}
+}
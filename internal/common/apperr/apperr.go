@@ -0,0 +1,168 @@
+// Package apperr classifies service errors so the HTTP layer can answer them
+// with the right status and a stable code, without matching on messages
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go-crm/internal/common/validation"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Error codes clients can switch on. They are part of the API and must not change.
+const (
+	CodeNotFound         = "not_found"
+	CodePermissionDenied = "permission_denied"
+	CodeValidation       = "validation_failed"
+	CodeConflict         = "conflict"
+	CodeBadRequest       = "bad_request"
+	CodeUnauthorized     = "unauthorized"
+	CodeQuotaExceeded    = "quota_exceeded"
+	CodeInternal         = "internal"
+)
+
+// Error is a classified error. Message is what the client is shown; Err, when
+// set, is the underlying cause kept for logs and errors.Is.
+type Error struct {
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" && e.Err != nil {
+		return e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the kind sentinels, so errors.Is(err, apperr.ErrNotFound) holds
+// for every not-found error whatever its message
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Message == "" && t.Err == nil && t.Code == e.Code
+}
+
+// Kind sentinels for errors.Is
+var (
+	ErrNotFound         = &Error{Code: CodeNotFound}
+	ErrPermissionDenied = &Error{Code: CodePermissionDenied}
+	ErrValidation       = &Error{Code: CodeValidation}
+	ErrConflict         = &Error{Code: CodeConflict}
+	ErrBadRequest       = &Error{Code: CodeBadRequest}
+	ErrQuotaExceeded    = &Error{Code: CodeQuotaExceeded}
+)
+
+func newf(code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// NotFound reports something that does not exist or that the caller may not know exists
+func NotFound(format string, args ...any) *Error {
+	return newf(CodeNotFound, format, args...)
+}
+
+// PermissionDenied reports an action the caller is not allowed to take
+func PermissionDenied(format string, args ...any) *Error {
+	return newf(CodePermissionDenied, format, args...)
+}
+
+// Validation reports input that is well formed but not acceptable.
+// Field-level problems use validation.Errors, which classify the same way.
+func Validation(format string, args ...any) *Error {
+	return newf(CodeValidation, format, args...)
+}
+
+// Conflict reports a request that clashes with the current state, such as a
+// duplicate name or a job that is already running
+func Conflict(format string, args ...any) *Error {
+	return newf(CodeConflict, format, args...)
+}
+
+// BadRequest reports a request that cannot be understood
+func BadRequest(format string, args ...any) *Error {
+	return newf(CodeBadRequest, format, args...)
+}
+
+// QuotaExceeded reports a create the tenant's plan has no room left for
+func QuotaExceeded(format string, args ...any) *Error {
+	return newf(CodeQuotaExceeded, format, args...)
+}
+
+// Wrap classifies err under code, keeping its message
+func Wrap(code string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// CodeOf returns the code err is classified under. Field validation errors,
+// missing documents and duplicate keys are classified without wrapping;
+// anything else is internal.
+func CodeOf(err error) string {
+	var e *Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &e):
+		return e.Code
+	case errors.As(err, new(validation.Errors)):
+		return CodeValidation
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return CodeNotFound
+	case mongo.IsDuplicateKeyError(err):
+		return CodeConflict
+	}
+	return CodeInternal
+}
+
+// Status is the HTTP status for code
+func Status(code string) int {
+	switch code {
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodePermissionDenied:
+		return http.StatusForbidden
+	case CodeValidation:
+		return http.StatusUnprocessableEntity
+	case CodeConflict:
+		return http.StatusConflict
+	case CodeBadRequest:
+		return http.StatusBadRequest
+	case CodeUnauthorized:
+		return http.StatusUnauthorized
+	case CodeQuotaExceeded:
+		return http.StatusPaymentRequired
+	}
+	return http.StatusInternalServerError
+}
+
+// CodeForStatus is the code an unclassified error answered with status carries
+func CodeForStatus(status int) string {
+	switch status {
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusForbidden:
+		return CodePermissionDenied
+	case http.StatusUnprocessableEntity:
+		return CodeValidation
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusPaymentRequired:
+		return CodeQuotaExceeded
+	}
+	if status >= 400 && status < 500 {
+		return CodeBadRequest
+	}
+	return CodeInternal
+}
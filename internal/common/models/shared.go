@@ -3,6 +3,8 @@ package models
 import (
 	"time"
 
+	"go-crm/pkg/locale"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -30,6 +32,10 @@ const (
 	AuditActionReport     AuditAction = "REPORT"
 	AuditActionChart      AuditAction = "CHART"
 	AuditActionDashboard  AuditAction = "DASHBOARD"
+	AuditActionMerge      AuditAction = "MERGE"
+
+	AuditActionImpersonation AuditAction = "IMPERSONATION"
+	AuditActionArchive       AuditAction = "ARCHIVE"
 )
 
 type Change struct {
@@ -47,6 +53,13 @@ type AuditLog struct {
 	ActorName string             `bson:"-" json:"actor_name,omitempty"`              // Populated Name of the actor
 	Changes   map[string]Change  `bson:"changes,omitempty" json:"changes,omitempty"` // For updates: field -> {old, new}
 	Timestamp time.Time          `bson:"timestamp" json:"timestamp"`
+
+	// ImpersonatorID is the admin who acted as ActorID during an impersonation session
+	ImpersonatorID string `bson:"impersonator_id,omitempty" json:"impersonator_id,omitempty"`
+
+	// RestoredAt is set on entries brought back from an audit archive; they
+	// are removed again without being archived a second time
+	RestoredAt *time.Time `bson:"restored_at,omitempty" json:"restored_at,omitempty"`
 }
 
 // Product Types
@@ -80,8 +93,9 @@ const (
 )
 
 type SelectOptions struct {
-	Label string `json:"label" bson:"label"`
-	Value string `json:"value" bson:"value"`
+	Label        string            `json:"label" bson:"label"`
+	Value        string            `json:"value" bson:"value"`
+	Translations map[string]string `json:"translations,omitempty" bson:"translations,omitempty"` // Language code -> label
 }
 
 type LookupDef struct {
@@ -91,37 +105,115 @@ type LookupDef struct {
 }
 
 type ModuleField struct {
-	Name         string          `json:"name" bson:"name"`
-	Label        string          `json:"label" bson:"label"`
-	Type         FieldType       `json:"type" bson:"type"`
-	Required     bool            `json:"required" bson:"required"`
-	Options      []SelectOptions `json:"options,omitempty" bson:"options,omitempty"`
-	Lookup       *LookupDef      `json:"lookup,omitempty" bson:"lookup,omitempty"`
-	IsSystem     bool            `json:"is_system" bson:"is_system"`
-	Filterable   bool            `json:"filterable" bson:"filterable"`
-	Sortable     bool            `json:"sortable" bson:"sortable"`
-	Unique       bool            `json:"unique" bson:"unique"`
-	DefaultValue string          `json:"default_value" bson:"default_value"`
-	Placeholder  string          `json:"placeholder" bson:"placeholder"`
-	HelpText     string          `json:"help_text" bson:"help_text"`
-	Hidden       bool            `json:"hidden" bson:"hidden"`
+	Name         string            `json:"name" bson:"name"`
+	Label        string            `json:"label" bson:"label"`
+	Type         FieldType         `json:"type" bson:"type"`
+	Required     bool              `json:"required" bson:"required"`
+	Options      []SelectOptions   `json:"options,omitempty" bson:"options,omitempty"`
+	Lookup       *LookupDef        `json:"lookup,omitempty" bson:"lookup,omitempty"`
+	IsSystem     bool              `json:"is_system" bson:"is_system"`
+	Filterable   bool              `json:"filterable" bson:"filterable"`
+	Sortable     bool              `json:"sortable" bson:"sortable"`
+	Unique       bool              `json:"unique" bson:"unique"`
+	DefaultValue string            `json:"default_value" bson:"default_value"`
+	Placeholder  string            `json:"placeholder" bson:"placeholder"`
+	HelpText     string            `json:"help_text" bson:"help_text"`
+	Hidden       bool              `json:"hidden" bson:"hidden"`
+	Translations map[string]string `json:"translations,omitempty" bson:"translations,omitempty"` // Language code -> label
 }
 
 // Entity (formerly Module) - Metadata Definition
 type Entity struct {
-	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	TenantID  primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
-	Product   Product            `json:"product" bson:"product"`
-	Name      string             `json:"name" bson:"name"` // Slug/Internal Name
-	Label     string             `json:"label" bson:"label"`
-	Slug      string             `json:"slug" bson:"slug"`
-	Fields    []ModuleField      `json:"fields" bson:"fields"`
-	Indexes   []string           `json:"indexes" bson:"indexes"`
-	IsSystem  bool               `json:"is_system" bson:"is_system"`
-	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
-	DeletedAt *time.Time         `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
-	DeletedBy string             `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID     primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	Product      Product            `json:"product" bson:"product"`
+	Name         string             `json:"name" bson:"name"` // Slug/Internal Name
+	Label        string             `json:"label" bson:"label"`
+	Slug         string             `json:"slug" bson:"slug"`
+	Fields       []ModuleField      `json:"fields" bson:"fields"`
+	Indexes      []string           `json:"indexes" bson:"indexes"`
+	IsSystem     bool               `json:"is_system" bson:"is_system"`
+	Actions      []CustomAction     `json:"actions,omitempty" bson:"actions,omitempty"`
+	RelatedLists []RelatedList      `json:"related_lists,omitempty" bson:"related_lists,omitempty"`
+	Translations map[string]string  `json:"translations,omitempty" bson:"translations"` // Language code -> label; stored even when empty so removals persist
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at" bson:"updated_at"`
+	DeletedAt    *time.Time         `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	DeletedBy    string             `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+
+	// HistoryTracking picks the fields whose changes the audit log keeps; nil tracks every field
+	HistoryTracking *HistoryTracking `json:"history_tracking,omitempty" bson:"history_tracking,omitempty"`
+}
+
+type CustomActionTarget string
+
+const (
+	CustomActionAutomation CustomActionTarget = "automation"
+	CustomActionScript     CustomActionTarget = "script"
+	CustomActionWebhook    CustomActionTarget = "webhook"
+)
+
+// CustomAction is a button on a module's records, run through
+// POST /api/modules/:name/records/:id/actions/:action
+type CustomAction struct {
+	Name         string             `json:"name" bson:"name"` // Slug used in the URL
+	Label        string             `json:"label" bson:"label"`
+	Icon         string             `json:"icon,omitempty" bson:"icon,omitempty"`
+	RequiredRole string             `json:"required_role,omitempty" bson:"required_role,omitempty"` // Role name or ID; empty allows anyone who can edit the record
+	Confirmation string             `json:"confirmation,omitempty" bson:"confirmation,omitempty"`   // Prompt shown before running
+	Target       CustomActionTarget `json:"target" bson:"target"`
+	// AutomationRuleID is the rule run for automation targets
+	AutomationRuleID string `json:"automation_rule_id,omitempty" bson:"automation_rule_id,omitempty"`
+	// Config is the run_script ("script") or webhook ("url", "method", "headers") action config
+	Config map[string]interface{} `json:"config,omitempty" bson:"config,omitempty"`
+}
+
+// RelatedList declares the records of another module that point at this
+// module's records through a lookup, e.g. an account's contacts. They are
+// listed through GET /api/modules/:name/records/:id/related/:relation.
+type RelatedList struct {
+	Name   string `json:"name" bson:"name"` // Slug used in the URL
+	Label  string `json:"label" bson:"label"`
+	Module string `json:"module" bson:"module"` // Child module
+	Field  string `json:"field" bson:"field"`   // Lookup field of Module referencing this module
+	// Columns limits the listed fields; empty lists every readable field
+	Columns   []string `json:"columns,omitempty" bson:"columns,omitempty"`
+	SortBy    string   `json:"sort_by,omitempty" bson:"sort_by,omitempty"`
+	SortOrder string   `json:"sort_order,omitempty" bson:"sort_order,omitempty"`
+}
+
+type HistoryTrackingMode string
+
+const (
+	HistoryTrackAll      HistoryTrackingMode = "all"
+	HistoryTrackSelected HistoryTrackingMode = "selected"
+	HistoryTrackNone     HistoryTrackingMode = "none"
+)
+
+// HistoryTracking is the field history configuration of a module. Fields
+// lists the tracked fields in selected mode.
+type HistoryTracking struct {
+	Mode   HistoryTrackingMode `json:"mode" bson:"mode"`
+	Fields []string            `json:"fields,omitempty" bson:"fields,omitempty"`
+}
+
+// TracksHistory reports whether the audit log keeps the changes of a field
+func (e *Entity) TracksHistory(field string) bool {
+	if e.HistoryTracking == nil {
+		return true
+	}
+	switch e.HistoryTracking.Mode {
+	case HistoryTrackNone:
+		return false
+	case HistoryTrackSelected:
+		for _, f := range e.HistoryTracking.Fields {
+			if f == field {
+				return true
+			}
+		}
+		return false
+	}
+	return true
 }
 
 // EntityRecord - The actual data
@@ -138,6 +230,10 @@ type EntityRecord struct {
 	Deleted   bool                   `json:"__deleted" bson:"deleted"`
 	DeletedAt *time.Time             `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
 	DeletedBy string                 `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"` // User ID
+
+	// EventID is set by writes whose events the record service dispatched
+	// itself, so the change stream can skip them
+	EventID primitive.ObjectID `json:"-" bson:"event_id,omitempty"`
 }
 
 type Organization struct {
@@ -149,6 +245,9 @@ type Organization struct {
 	OwnerID         primitive.ObjectID `bson:"owner_id" json:"owner_id"`
 	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+
+	// Set on sandbox tenants cloned from another organization
+	SandboxOf *primitive.ObjectID `bson:"sandbox_of,omitempty" json:"sandbox_of,omitempty"`
 }
 
 type User struct {
@@ -165,6 +264,7 @@ type User struct {
 	Groups    []string             `bson:"groups,omitempty" json:"groups,omitempty"`         // User groups for ABAC (e.g., ["sales_team_west", "managers"])
 	ReportsTo *primitive.ObjectID  `bson:"reports_to,omitempty" json:"reports_to,omitempty"` // Manager ID
 	LastLogin *time.Time           `bson:"last_login,omitempty" json:"last_login,omitempty"`
+	Locale    *locale.Settings     `bson:"locale,omitempty" json:"locale,omitempty"` // Overrides the tenant's formatting preferences
 	CreatedAt time.Time            `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time            `bson:"updated_at" json:"updated_at"`
 }
@@ -213,6 +313,7 @@ type ApprovalRecordState struct {
 	CurrentStep int               `bson:"current_step" json:"current_step"`
 	WorkflowID  string            `bson:"workflow_id" json:"workflow_id"`
 	History     []ApprovalHistory `bson:"history" json:"history"`
+	SubmittedAt time.Time         `bson:"submitted_at,omitempty" json:"submitted_at,omitempty"`
 }
 
 type ApprovalHistory struct {
@@ -0,0 +1,64 @@
+// Package validation reports invalid request payloads field by field, so
+// clients can point at the fields to fix
+package validation
+
+import (
+	"errors"
+	"strings"
+)
+
+// Error codes clients can switch on
+const (
+	CodeRequired    = "required"
+	CodeInvalid     = "invalid"     // Wrong type or format
+	CodeNotAllowed  = "not_allowed" // Not one of the accepted values
+	CodeReadOnly    = "read_only"
+	CodeDuplicate   = "duplicate"
+	CodeInvalidBody = "invalid_body" // The body could not be parsed
+)
+
+// FieldError is one problem with a payload. Field is the JSON path of the
+// offending value, e.g. "actions.1.label", and empty for the whole body.
+type FieldError struct {
+	Code    string `json:"code"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Errors collects every problem found in a payload
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fe := range e {
+		messages[i] = fe.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Add records a problem with field
+func (e *Errors) Add(field, code, message string) {
+	*e = append(*e, FieldError{Code: code, Field: field, Message: message})
+}
+
+// Err returns the collected errors, or nil when there are none
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// New returns a single field error
+func New(field, code, message string) error {
+	return Errors{{Code: code, Field: field, Message: message}}
+}
+
+// As returns the field errors wrapped in err
+func As(err error) (Errors, bool) {
+	var errs Errors
+	if errors.As(err, &errs) {
+		return errs, true
+	}
+	return nil, false
+}
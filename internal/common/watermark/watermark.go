@@ -0,0 +1,62 @@
+// Package watermark labels exported files with who exported them and when,
+// so a file found outside the system can be traced to an export
+package watermark
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/common/session"
+)
+
+// Mark identifies an export
+type Mark struct {
+	UserID   string    `json:"user_id"`
+	Username string    `json:"username,omitempty"`
+	Email    string    `json:"email,omitempty"`
+	At       time.Time `json:"exported_at"`
+	// ImpersonatorID is the admin who exported while acting as the user
+	ImpersonatorID string `json:"impersonator_id,omitempty"`
+}
+
+// New marks an export by u now. u may be nil when the user could not be
+// loaded, in which case the mark carries only userID.
+func New(ctx context.Context, userID string, u *models.User) Mark {
+	m := Mark{UserID: userID, At: time.Now().UTC().Truncate(time.Second)}
+	if u != nil {
+		m.Username = u.Username
+		m.Email = u.Email
+	}
+	if rc := session.From(ctx); rc != nil {
+		m.ImpersonatorID = rc.ImpersonatorID
+	}
+	return m
+}
+
+// Author names the exporter for file metadata such as a workbook's creator
+func (m Mark) Author() string {
+	if m.Username != "" {
+		return m.Username
+	}
+	return m.UserID
+}
+
+// String is the line written into the file. Times are UTC so marks compare
+// across tenants and time zones.
+func (m Mark) String() string {
+	who := m.UserID
+	if m.Username != "" {
+		who = m.Username
+		if m.Email != "" {
+			who += " <" + m.Email + ">"
+		}
+		who += " [" + m.UserID + "]"
+	}
+	s := fmt.Sprintf("Exported by %s on %s", who, m.At.Format(time.RFC3339))
+	if m.ImpersonatorID != "" {
+		s += fmt.Sprintf(" (impersonated by %s)", m.ImpersonatorID)
+	}
+	return s
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -10,13 +12,83 @@ import (
 type Config struct {
 	Port        string
 	JWTSecret   string
-	MongoURI    string
+	MongoURI    string // A replica set or sharded cluster; transactions fail on a standalone mongod
 	DBName      string
 	SkipAuth    bool
 	Environment string
 	AppId       string
 	FSPath      string // Physical directory for file uploads
 	FSURL       string // URL path prefix for file access
+	ExportPath  string // Export artifacts with local storage, staging otherwise; kept outside FSPath so they are only reachable via signed links
+
+	// File storage backend: local (default), s3, gcs or azure
+	StorageBackend      string
+	SignedURLTTLMinutes int
+	S3Bucket            string
+	S3Region            string
+	S3Endpoint          string // Optional, for S3-compatible stores such as MinIO (path-style)
+	S3AccessKeyID       string
+	S3SecretAccessKey   string
+	S3SessionToken      string
+	GCSBucket           string
+	GCSCredentialsFile  string // Service account JSON key used for V4 signing
+	AzureAccount        string
+	AzureAccountKey     string
+	AzureContainer      string
+	ClamAVAddress       string // host:port of clamd; empty disables virus scanning
+	ThumbnailSizes      []int  // Bounding-box edge lengths (px) generated for uploaded images
+
+	PublicURL           string // Base URL for links sent outside the app, e.g. e-sign invitations
+	ESignCallbackSecret string // HMAC secret for provider callbacks; empty disables /api/esign/callback
+	EmailWebhookSecret  string // Token email providers pass to /api/email/events; empty disables it
+
+	// Accounting connectors; a provider is unavailable while its client ID is empty
+	QuickBooksClientID     string
+	QuickBooksClientSecret string
+	QuickBooksSandbox      bool
+	XeroClientID           string
+	XeroClientSecret       string
+
+	// API versioning: when APIV1Sunset is set (RFC3339 or YYYY-MM-DD), v1
+	// responses advertise deprecation and retirement headers
+	APIV1DeprecatedAt  string
+	APIV1Sunset        string
+	APIDeprecationLink string
+
+	// AutomationRatePerMinute caps automation rule runs per tenant
+	AutomationRatePerMinute int
+
+	// DefaultPlan is the plan organizations signing up are put on; empty
+	// leaves them unlimited
+	DefaultPlan string
+
+	// Read-heavy queries (lists, reports, exports, dashboards) use this read
+	// preference; "primary" (default) keeps every read on the primary
+	MongoReadPreference      string
+	MongoReadConcern         string // local (default), available or majority
+	MongoMaxStalenessSeconds int    // 0 for no limit, otherwise at least 90
+
+	// CORS; origins may use a wildcard subdomain such as
+	// https://*.example.com for tenant frontends
+	CORSAllowOrigins  []string
+	CORSAllowMethods  []string
+	CORSAllowHeaders  []string
+	CORSExposeHeaders []string
+
+	// Email client add-ins call /api/addin from their own pages in addition
+	// to CORSAllowOrigins, with bearer tokens rather than cookies
+	AddInAllowOrigins  []string
+	AddInTokenTTLHours int
+
+	// Security headers; HSTS is sent on HTTPS requests while HSTSMaxAge > 0
+	HSTSMaxAge            int
+	ContentSecurityPolicy string // Sent with every response; empty sends none
+	PortalPath            string // Path prefix of the customer portal
+	PortalCSP             string // Replaces ContentSecurityPolicy under PortalPath
+
+	// ChangeStreams feeds record writes made outside the API, e.g. by
+	// seeders or manual fixes, to listeners and webhooks. Needs a replica set.
+	ChangeStreams bool
 }
 
 // LoadConfig loads configuration from environment variables
@@ -27,16 +99,76 @@ func LoadConfig() (*Config, error) {
 		log.Println("Loaded .env file successfully")
 	}
 
+	environment := getEnv("ENVIRONMENT", "development")
+	defaultOrigins, defaultHSTS := []string{"http://localhost:3000", "http://localhost:3001", "http://localhost:3002", "http://localhost:8000"}, 0
+	if environment == "production" {
+		// Production origins must be configured explicitly
+		defaultOrigins, defaultHSTS = nil, 31536000
+	}
+
 	return &Config{
 		Port:        getEnv("PORT", "8080"),
 		JWTSecret:   getEnv("JWT_SECRET", "secret"),
 		MongoURI:    getEnv("MONGO_URI", "mongodb://localhost:27017"),
 		DBName:      getEnv("DB_NAME", "go-crm"),
 		SkipAuth:    getEnv("SKIP_AUTH", "false") == "true",
-		Environment: getEnv("ENVIRONMENT", "development"),
+		Environment: environment,
 		AppId:       getEnv("APP_ID", "go-crm"),
 		FSPath:      getEnv("FS_PATH", "./uploads"),
 		FSURL:       getEnv("FS_URL", "/fs/uploads"),
+		ExportPath:  getEnv("EXPORT_PATH", "./exports"),
+
+		StorageBackend:      getEnv("STORAGE_BACKEND", "local"),
+		SignedURLTTLMinutes: getEnvInt("SIGNED_URL_TTL_MINUTES", 15),
+		S3Bucket:            getEnv("S3_BUCKET", ""),
+		S3Region:            getEnv("S3_REGION", "us-east-1"),
+		S3Endpoint:          getEnv("S3_ENDPOINT", ""),
+		S3AccessKeyID:       getEnv("AWS_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey:   getEnv("AWS_SECRET_ACCESS_KEY", ""),
+		S3SessionToken:      getEnv("AWS_SESSION_TOKEN", ""),
+		GCSBucket:           getEnv("GCS_BUCKET", ""),
+		GCSCredentialsFile:  getEnv("GCS_CREDENTIALS_FILE", ""),
+		AzureAccount:        getEnv("AZURE_STORAGE_ACCOUNT", ""),
+		AzureAccountKey:     getEnv("AZURE_STORAGE_KEY", ""),
+		AzureContainer:      getEnv("AZURE_STORAGE_CONTAINER", ""),
+		ClamAVAddress:       getEnv("CLAMAV_ADDRESS", ""),
+		ThumbnailSizes:      getEnvIntList("THUMBNAIL_SIZES", []int{150, 600}),
+
+		PublicURL:           getEnv("PUBLIC_URL", "http://localhost:8080"),
+		ESignCallbackSecret: getEnv("ESIGN_CALLBACK_SECRET", ""),
+		EmailWebhookSecret:  getEnv("EMAIL_WEBHOOK_SECRET", ""),
+
+		QuickBooksClientID:     getEnv("QUICKBOOKS_CLIENT_ID", ""),
+		QuickBooksClientSecret: getEnv("QUICKBOOKS_CLIENT_SECRET", ""),
+		QuickBooksSandbox:      getEnv("QUICKBOOKS_SANDBOX", "true") == "true",
+		XeroClientID:           getEnv("XERO_CLIENT_ID", ""),
+		XeroClientSecret:       getEnv("XERO_CLIENT_SECRET", ""),
+
+		APIV1DeprecatedAt:  getEnv("API_V1_DEPRECATED_AT", ""),
+		APIV1Sunset:        getEnv("API_V1_SUNSET", ""),
+		APIDeprecationLink: getEnv("API_DEPRECATION_LINK", ""),
+
+		AutomationRatePerMinute: getEnvInt("AUTOMATION_RATE_PER_MINUTE", 600),
+		DefaultPlan:             getEnv("DEFAULT_PLAN", ""),
+
+		MongoReadPreference:      getEnv("MONGO_READ_PREFERENCE", "primary"),
+		MongoReadConcern:         getEnv("MONGO_READ_CONCERN", "local"),
+		MongoMaxStalenessSeconds: getEnvInt("MONGO_MAX_STALENESS_SECONDS", 0),
+
+		CORSAllowOrigins:  getEnvList("CORS_ALLOW_ORIGINS", defaultOrigins),
+		CORSAllowMethods:  getEnvList("CORS_ALLOW_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
+		CORSAllowHeaders:  getEnvList("CORS_ALLOW_HEADERS", []string{"Content-Type", "Authorization", "X-Requested-With", "X-Rich-Product", "API-Version", "X-Read-Consistency"}),
+		CORSExposeHeaders: getEnvList("CORS_EXPOSE_HEADERS", []string{"API-Version", "Deprecation", "Sunset", "Link"}),
+
+		AddInAllowOrigins:  getEnvList("ADDIN_ALLOW_ORIGINS", nil),
+		AddInTokenTTLHours: getEnvInt("ADDIN_TOKEN_TTL_HOURS", 720),
+
+		HSTSMaxAge:            getEnvInt("HSTS_MAX_AGE", defaultHSTS),
+		ContentSecurityPolicy: getEnv("CONTENT_SECURITY_POLICY", "default-src 'none'; frame-ancestors 'none'"),
+		PortalPath:            getEnv("PORTAL_PATH", "/portal"),
+		PortalCSP:             getEnv("PORTAL_CSP", "default-src 'self'; img-src 'self' data: https:; style-src 'self' 'unsafe-inline'; frame-ancestors 'self'; form-action 'self'"),
+
+		ChangeStreams: getEnv("CHANGE_STREAMS", "false") == "true",
 	}, nil
 }
 
@@ -46,3 +178,40 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvInt(key string, fallback int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+func getEnvIntList(key string, fallback []int) []int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	var out []int
+	for _, part := range strings.Split(value, ",") {
+		if n, err := strconv.Atoi(strings.TrimSpace(part)); err == nil && n > 0 {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func getEnvList(key string, fallback []string) []string {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	var out []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -30,6 +30,13 @@ func NewDatabase(lc fx.Lifecycle, cfg *config.Config) (*MongodbDB, error) {
 	log.Println("Connected to MongoDB!")
 
 	db := client.Database(cfg.DBName)
+	reads, err := readsDatabase(client, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if reads != nil {
+		log.Printf("Offloading read-heavy queries with read preference %s", cfg.MongoReadPreference)
+	}
 
 	// Register lifecycle hooks
 	lc.Append(fx.Hook{
@@ -39,5 +46,5 @@ func NewDatabase(lc fx.Lifecycle, cfg *config.Config) (*MongodbDB, error) {
 		},
 	})
 
-	return &MongodbDB{DB: db}, nil
+	return &MongodbDB{DB: db, Reads: reads}, nil
 }
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-crm/internal/config"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// ReadMode overrides where one operation reads from
+type ReadMode int
+
+const (
+	// ReadDefault leaves the choice to the repository method
+	ReadDefault ReadMode = iota
+	// ReadPrimary reads from the primary, e.g. right after a write
+	ReadPrimary
+	// ReadSecondary reads with the configured read preference even where
+	// the repository would use the primary
+	ReadSecondary
+)
+
+type readModeKey struct{}
+
+// WithReadMode sets the read mode for operations run with ctx. The latest
+// call wins, so a service can offload a report started by a write request.
+func WithReadMode(ctx context.Context, mode ReadMode) context.Context {
+	return context.WithValue(ctx, readModeKey{}, mode)
+}
+
+// ReadModeFrom returns the read mode set on ctx
+func ReadModeFrom(ctx context.Context) ReadMode {
+	mode, _ := ctx.Value(readModeKey{}).(ReadMode)
+	return mode
+}
+
+// Collections pairs a collection with a handle on it that reads with the
+// configured read preference and concern. Writes always use Primary.
+type Collections struct {
+	Primary *mongo.Collection
+	reads   *mongo.Collection
+}
+
+// Collections returns both handles on the named collection
+func (db *MongodbDB) Collections(name string) Collections {
+	reads := db.Reads
+	if reads == nil {
+		reads = db.DB
+	}
+	return Collections{Primary: db.DB.Collection(name), reads: reads.Collection(name)}
+}
+
+// Read picks the collection for a read. heavy marks list, report, export
+// and dashboard reads that can tolerate replication lag; the mode set on
+// ctx overrides it.
+func (c Collections) Read(ctx context.Context, heavy bool) *mongo.Collection {
+	switch ReadModeFrom(ctx) {
+	case ReadPrimary:
+		return c.Primary
+	case ReadSecondary:
+		return c.reads
+	}
+	if heavy {
+		return c.reads
+	}
+	return c.Primary
+}
+
+// readsDatabase returns the database handle for offloaded reads, or nil
+// when reads stay on the primary
+func readsDatabase(client *mongo.Client, cfg *config.Config) (*mongo.Database, error) {
+	if cfg.MongoReadPreference == "" || cfg.MongoReadPreference == "primary" {
+		return nil, nil
+	}
+	mode, err := readpref.ModeFromString(cfg.MongoReadPreference)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MONGO_READ_PREFERENCE %q", cfg.MongoReadPreference)
+	}
+	var prefOpts []readpref.Option
+	if cfg.MongoMaxStalenessSeconds > 0 {
+		// The server rejects values under 90 seconds
+		prefOpts = append(prefOpts, readpref.WithMaxStaleness(time.Duration(max(cfg.MongoMaxStalenessSeconds, 90))*time.Second))
+	}
+	pref, err := readpref.New(mode, prefOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var concern *readconcern.ReadConcern
+	switch cfg.MongoReadConcern {
+	case "", "local":
+		concern = readconcern.Local()
+	case "available":
+		concern = readconcern.Available()
+	case "majority":
+		concern = readconcern.Majority()
+	default:
+		return nil, fmt.Errorf("invalid MONGO_READ_CONCERN %q", cfg.MongoReadConcern)
+	}
+
+	return client.Database(cfg.DBName, options.Database().SetReadPreference(pref).SetReadConcern(concern)), nil
+}
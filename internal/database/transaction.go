@@ -0,0 +1,38 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrTransactionsUnsupported is returned when the server cannot run
+// multi-document transactions. Only replica set members and mongos can; a
+// standalone mongod has to be started as a single-node replica set.
+var ErrTransactionsUnsupported = errors.New("mongodb transactions require a replica set or sharded cluster; run mongod with --replSet, even for a single node")
+
+// illegalOperation is the server's code for a transaction sent to a standalone
+const illegalOperation = 20
+
+// WithTransaction runs fn in a transaction on db's client
+func WithTransaction(ctx context.Context, db *mongo.Database, fn func(ctx context.Context) error) error {
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	if transactionsUnsupported(err) {
+		return ErrTransactionsUnsupported
+	}
+	return err
+}
+
+func transactionsUnsupported(err error) bool {
+	var se mongo.ServerError
+	return errors.As(err, &se) && se.HasErrorCodeWithMessage(illegalOperation, "Transaction numbers are only allowed")
+}
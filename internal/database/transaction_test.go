@@ -0,0 +1,31 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestTransactionsUnsupported(t *testing.T) {
+	standalone := mongo.CommandError{Code: 20, Name: "IllegalOperation", Message: "Transaction numbers are only allowed on a replica set member or mongos"}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "standalone server", err: standalone, want: true},
+		{name: "wrapped by the change set", err: fmt.Errorf("records %q: %w", "lead-1", standalone), want: true},
+		{name: "other illegal operation", err: mongo.CommandError{Code: 20, Message: "cannot run on a view"}},
+		{name: "write conflict", err: mongo.CommandError{Code: 112, Message: "WriteConflict"}},
+		{name: "not a server error", err: errors.New("Transaction numbers are only allowed")},
+		{name: "no error"},
+	}
+	for _, tt := range tests {
+		if got := transactionsUnsupported(tt.err); got != tt.want {
+			t.Errorf("%s: transactionsUnsupported = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
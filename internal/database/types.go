@@ -4,4 +4,8 @@ import "go.mongodb.org/mongo-driver/mongo"
 
 type MongodbDB struct {
 	DB *mongo.Database
+
+	// Reads is DB with the configured read preference and concern, for
+	// read-heavy queries; nil when every read goes to the primary
+	Reads *mongo.Database
 }
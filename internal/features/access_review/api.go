@@ -0,0 +1,29 @@
+package access_review
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/features/role"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AccessReviewApi struct {
+	controller  *AccessReviewController
+	config      *config.Config
+	roleService role.RoleService
+}
+
+func NewAccessReviewApi(controller *AccessReviewController, config *config.Config, roleService role.RoleService) *AccessReviewApi {
+	return &AccessReviewApi{
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *AccessReviewApi) Setup(app *fiber.App) {
+	group := app.Group("/api/admin/access-review", middleware.AuthMiddleware(h.config.SkipAuth))
+	group.Get("/matrix", middleware.RequirePermission(h.roleService, "roles", "read"), h.controller.GetMatrix)
+	group.Get("/records/:module/:id", middleware.RequirePermission(h.roleService, "roles", "read"), h.controller.ExplainRecord)
+}
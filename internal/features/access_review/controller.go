@@ -0,0 +1,75 @@
+package access_review
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AccessReviewController struct {
+	Service AccessReviewService
+}
+
+func NewAccessReviewController(service AccessReviewService) *AccessReviewController {
+	return &AccessReviewController{Service: service}
+}
+
+// GetMatrix godoc
+// @Summary Permission matrix
+// @Description Every role and group against every module and action, with the conditions of conditional grants, plus non-module resources and field-level rules. With format=csv the matrix is one table whose field rule rows fill the field column instead of action.
+// @Tags access-review
+// @Produce json
+// @Produce text/csv
+// @Param format query string false "json (default) or csv"
+// @Success 200 {object} PermissionMatrix
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/admin/access-review/matrix [get]
+func (ctrl *AccessReviewController) GetMatrix(c *fiber.Ctx) error {
+	if c.Query("format") == "csv" {
+		data, filename, err := ctrl.Service.MatrixCSV(c.UserContext())
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		c.Set("Content-Type", "text/csv")
+		c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+		return c.Send(data)
+	}
+
+	matrix, err := ctrl.Service.Matrix(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"data": matrix})
+}
+
+// ExplainRecord godoc
+// @Summary Who can access a record
+// @Description List the users who can act on a record, each with their module permission, whether their access filter matches the record, the grants that match or not, and the fields hidden or read-only for them
+// @Tags access-review
+// @Produce json
+// @Param module path string true "Module name"
+// @Param id path string true "Record ID"
+// @Param action query string false "read (default), update or delete"
+// @Param user_id query string false "Explain one user"
+// @Param all query bool false "Include users without access"
+// @Success 200 {object} RecordAccess
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/admin/access-review/records/{module}/{id} [get]
+func (ctrl *AccessReviewController) ExplainRecord(c *fiber.Ctx) error {
+	result, err := ctrl.Service.ExplainRecord(c.UserContext(), ExplainQuery{
+		Module:   c.Params("module"),
+		RecordID: c.Params("id"),
+		Action:   c.Query("action"),
+		UserID:   c.Query("user_id"),
+		All:      c.QueryBool("all", false),
+	})
+	if errors.Is(err, ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"data": result})
+}
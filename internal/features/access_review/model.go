@@ -0,0 +1,96 @@
+package access_review
+
+import "time"
+
+// Principal kinds a permission is granted to
+const (
+	PrincipalRole  = "role"
+	PrincipalGroup = "group"
+)
+
+// Access levels of a matrix entry
+const (
+	AccessAllowed     = "allowed"
+	AccessConditional = "conditional" // Allowed on the records the conditions match
+	AccessDenied      = "denied"
+)
+
+// MatrixEntry is what one role or group may do with one resource
+type MatrixEntry struct {
+	PrincipalType string `json:"principal_type"`
+	Principal     string `json:"principal"`
+	Resource      string `json:"resource"` // Module name, or the resource ID of pages and settings
+	Action        string `json:"action"`
+	Access        string `json:"access"`
+	// Conditions is the access filter in readable form, e.g. owner eq $user.id
+	Conditions string `json:"conditions,omitempty"`
+	// GrantedBy is the permission key that grants the action, such as "*"
+	// for a wildcard permission or "admin" for the admin roles
+	GrantedBy string `json:"granted_by,omitempty"`
+}
+
+// FieldRule is a role's field-level access to a module field
+type FieldRule struct {
+	Role   string `json:"role"`
+	Module string `json:"module"`
+	Field  string `json:"field"`
+	Access string `json:"access"` // read_write, read_only or none
+}
+
+type PermissionMatrix struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Roles       []string  `json:"roles"`
+	Groups      []string  `json:"groups"`
+	// Inherits maps each role that extends another to its base role; the
+	// entries and field rules already include what is inherited
+	Inherits   map[string]string `json:"inherits"`
+	Modules    []string          `json:"modules"`
+	Actions    []string          `json:"actions"`
+	Entries    []MatrixEntry     `json:"entries"`
+	FieldRules []FieldRule       `json:"field_rules"`
+}
+
+// GrantCheck is one of a user's grants on the module and whether it covers the record
+type GrantCheck struct {
+	Source     string `json:"source"` // admin, role or group
+	Name       string `json:"name"`
+	Resource   string `json:"resource"`
+	Conditions string `json:"conditions,omitempty"`
+	Matches    bool   `json:"matches"`
+	Error      string `json:"error,omitempty"`
+}
+
+// UserAccess explains whether one user can act on the record
+type UserAccess struct {
+	UserID   string   `json:"user_id"`
+	Username string   `json:"username"`
+	Status   string   `json:"status"`
+	Roles    []string `json:"roles"`
+	// ModuleAccess is the module-level permission routes check
+	ModuleAccess bool `json:"module_access"`
+	// RecordAccess is whether the user's access filter matches the record
+	RecordAccess bool         `json:"record_access"`
+	Allowed      bool         `json:"allowed"`
+	Grants       []GrantCheck `json:"grants,omitempty"`
+	// Fields the user's roles hide or make read-only on this module
+	HiddenFields   []string `json:"hidden_fields,omitempty"`
+	ReadOnlyFields []string `json:"read_only_fields,omitempty"`
+}
+
+type ExplainQuery struct {
+	Module   string
+	RecordID string
+	Action   string // Default read
+	UserID   string // Explain one user only
+	// All includes users without access; by default only users who have it are listed
+	All bool
+}
+
+type RecordAccess struct {
+	Module      string       `json:"module"`
+	RecordID    string       `json:"record_id"`
+	Action      string       `json:"action"`
+	GeneratedAt time.Time    `json:"generated_at"`
+	Checked     int          `json:"checked"` // Users evaluated
+	Users       []UserAccess `json:"users"`
+}
@@ -0,0 +1,416 @@
+package access_review
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/group"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/role"
+	"go-crm/internal/features/user"
+	"go-crm/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxExplainUsers caps the users a record explanation evaluates
+const maxExplainUsers = 2000
+
+// crudActions are listed for every module; other actions appear when a
+// permission grants them
+var crudActions = []string{"create", "read", "update", "delete"}
+
+var ErrRecordNotFound = errors.New("record not found")
+
+type AccessReviewService interface {
+	// Matrix lists what every role and group may do with every module and
+	// other resource, with field rules and access filters
+	Matrix(ctx context.Context) (*PermissionMatrix, error)
+	// MatrixCSV is the matrix as one CSV table, field rules included
+	MatrixCSV(ctx context.Context) ([]byte, string, error)
+	// ExplainRecord reports which users can act on a record and why
+	ExplainRecord(ctx context.Context, q ExplainQuery) (*RecordAccess, error)
+}
+
+type AccessReviewServiceImpl struct {
+	RoleRepo     role.RoleRepository
+	RoleService  role.RoleService
+	GroupRepo    group.GroupRepository
+	ModuleRepo   module.ModuleRepository
+	UserRepo     user.UserRepository
+	RecordRepo   record.RecordRepository
+	AuditService audit.AuditService
+}
+
+func NewAccessReviewService(
+	roleRepo role.RoleRepository,
+	roleService role.RoleService,
+	groupRepo group.GroupRepository,
+	moduleRepo module.ModuleRepository,
+	userRepo user.UserRepository,
+	recordRepo record.RecordRepository,
+	auditService audit.AuditService,
+) AccessReviewService {
+	return &AccessReviewServiceImpl{
+		RoleRepo:     roleRepo,
+		RoleService:  roleService,
+		GroupRepo:    groupRepo,
+		ModuleRepo:   moduleRepo,
+		UserRepo:     userRepo,
+		RecordRepo:   recordRepo,
+		AuditService: auditService,
+	}
+}
+
+// resourceGrants holds a principal's action permissions by resource ID
+type resourceGrants map[string]map[string]common_models.ActionPermission
+
+type principal struct {
+	kind, name string
+	admin      bool
+	grants     resourceGrants
+}
+
+// decide resolves a principal's access to an action on a module. Grants on
+// the module, its "crm."-prefixed resource and "*" combine; an unconditional
+// grant wins over conditional ones.
+func decide(grants resourceGrants, keys []string, action string) (access, conditions, grantedBy string) {
+	access = AccessDenied
+	var conds, by []string
+	for _, key := range keys {
+		p, ok := grants[key][action]
+		if !ok || !p.Allowed {
+			continue
+		}
+		if p.Conditions == nil {
+			return AccessAllowed, "", key
+		}
+		conds = append(conds, DescribeConditions(p.Conditions))
+		by = append(by, key)
+	}
+	if len(conds) > 0 {
+		return AccessConditional, strings.Join(conds, " OR "), strings.Join(by, ",")
+	}
+	return access, "", ""
+}
+
+func (s *AccessReviewServiceImpl) Matrix(ctx context.Context) (*PermissionMatrix, error) {
+	roles, err := s.RoleRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	modules, err := s.ModuleRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.GroupRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+
+	moduleNames := make([]string, 0, len(modules))
+	moduleKeys := map[string]bool{}
+	for _, m := range modules {
+		moduleNames = append(moduleNames, m.Name)
+		moduleKeys[m.Name] = true
+		moduleKeys["crm."+m.Name] = true
+	}
+	sort.Strings(moduleNames)
+
+	roleNames := map[primitive.ObjectID]string{}
+	for _, r := range roles {
+		roleNames[r.ID] = r.Name
+	}
+
+	var principals []principal
+	matrix := &PermissionMatrix{GeneratedAt: time.Now(), Roles: []string{}, Groups: []string{}, Inherits: map[string]string{}, Modules: moduleNames, Entries: []MatrixEntry{}, FieldRules: []FieldRule{}}
+	for _, r := range roles {
+		grants := resourceGrants{}
+		perms, err := s.RoleService.EffectivePermissions(ctx, &r)
+		if err != nil {
+			return nil, fmt.Errorf("permissions of role %s: %w", r.Name, err)
+		}
+		fieldPermissions, err := s.RoleService.EffectiveFieldPermissions(ctx, &r)
+		if err != nil {
+			return nil, fmt.Errorf("field permissions of role %s: %w", r.Name, err)
+		}
+		for _, p := range perms {
+			if grants[p.Resource.ID] == nil {
+				grants[p.Resource.ID] = map[string]common_models.ActionPermission{}
+			}
+			for action, ap := range p.Actions {
+				grants[p.Resource.ID][action] = ap
+			}
+		}
+		principals = append(principals, principal{kind: PrincipalRole, name: r.Name, admin: role.IsAdminRole(r.Name), grants: grants})
+		matrix.Roles = append(matrix.Roles, r.Name)
+
+		if r.BaseRoleID != nil {
+			matrix.Inherits[r.Name] = roleNames[*r.BaseRoleID]
+		}
+		for moduleName, fields := range fieldPermissions {
+			for field, access := range fields {
+				matrix.FieldRules = append(matrix.FieldRules, FieldRule{Role: r.Name, Module: moduleName, Field: field, Access: access})
+			}
+		}
+	}
+	for _, g := range groups {
+		principals = append(principals, principal{kind: PrincipalGroup, name: g.Name, grants: resourceGrants(g.Permissions)})
+		matrix.Groups = append(matrix.Groups, g.Name)
+	}
+	sort.Slice(matrix.FieldRules, func(i, j int) bool {
+		a, b := matrix.FieldRules[i], matrix.FieldRules[j]
+		if a.Role != b.Role {
+			return a.Role < b.Role
+		}
+		if a.Module != b.Module {
+			return a.Module < b.Module
+		}
+		return a.Field < b.Field
+	})
+
+	// Actions beyond CRUD, and resources that are not modules, come from
+	// the permissions themselves
+	actions := slices.Clone(crudActions)
+	otherResources := map[string][]string{}
+	for _, p := range principals {
+		for resource, acts := range p.grants {
+			for action := range acts {
+				if !slices.Contains(actions, action) && (resource == "*" || moduleKeys[resource]) {
+					actions = append(actions, action)
+				}
+				if resource != "*" && !moduleKeys[resource] && !slices.Contains(otherResources[resource], action) {
+					otherResources[resource] = append(otherResources[resource], action)
+				}
+			}
+		}
+	}
+	sort.Strings(actions[len(crudActions):])
+	matrix.Actions = actions
+	resources := make([]string, 0, len(otherResources))
+	for resource := range otherResources {
+		resources = append(resources, resource)
+		sort.Strings(otherResources[resource])
+	}
+	sort.Strings(resources)
+
+	for _, p := range principals {
+		entry := func(resource, action string, keys []string) MatrixEntry {
+			e := MatrixEntry{PrincipalType: p.kind, Principal: p.name, Resource: resource, Action: action}
+			if p.admin {
+				e.Access, e.GrantedBy = AccessAllowed, "admin"
+				return e
+			}
+			e.Access, e.Conditions, e.GrantedBy = decide(p.grants, keys, action)
+			return e
+		}
+		for _, moduleName := range moduleNames {
+			keys := []string{moduleName, "crm." + moduleName, "*"}
+			for _, action := range actions {
+				matrix.Entries = append(matrix.Entries, entry(moduleName, action, keys))
+			}
+		}
+		for _, resource := range resources {
+			for _, action := range otherResources[resource] {
+				matrix.Entries = append(matrix.Entries, entry(resource, action, []string{resource, "*"}))
+			}
+		}
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionReport, "permission_matrix", "", map[string]common_models.Change{
+		"entries": {New: len(matrix.Entries)},
+	})
+
+	return matrix, nil
+}
+
+func (s *AccessReviewServiceImpl) MatrixCSV(ctx context.Context) ([]byte, string, error) {
+	matrix, err := s.Matrix(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write([]string{"principal_type", "principal", "resource", "action", "field", "access", "conditions", "granted_by"})
+	for _, e := range matrix.Entries {
+		_ = w.Write([]string{e.PrincipalType, e.Principal, e.Resource, e.Action, "", e.Access, e.Conditions, e.GrantedBy})
+	}
+	for _, f := range matrix.FieldRules {
+		_ = w.Write([]string{PrincipalRole, f.Role, f.Module, "", f.Field, f.Access, "", ""})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), fmt.Sprintf("permission_matrix_%s.csv", matrix.GeneratedAt.Format("20060102_150405")), nil
+}
+
+func (s *AccessReviewServiceImpl) ExplainRecord(ctx context.Context, q ExplainQuery) (*RecordAccess, error) {
+	if q.Action == "" {
+		q.Action = "read"
+	}
+	recordID, err := primitive.ObjectIDFromHex(q.RecordID)
+	if err != nil {
+		return nil, ErrRecordNotFound
+	}
+	if _, err := s.ModuleRepo.FindByName(ctx, q.Module); err != nil {
+		return nil, fmt.Errorf("module %q not found", q.Module)
+	}
+	byID := map[string]any{"_id": recordID}
+	n, err := s.RecordRepo.Count(ctx, q.Module, byID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	roles, err := s.RoleRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	roleNames := map[primitive.ObjectID]string{}
+	for _, r := range roles {
+		roleNames[r.ID] = r.Name
+	}
+
+	var users []common_models.User
+	if q.UserID != "" {
+		u, err := s.UserRepo.FindByID(ctx, q.UserID)
+		if err != nil || u == nil {
+			return nil, errors.New("user not found")
+		}
+		users = []common_models.User{*u}
+	} else {
+		users, _, err = s.UserRepo.List(ctx, map[string]interface{}{}, maxExplainUsers, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := &RecordAccess{Module: q.Module, RecordID: q.RecordID, Action: q.Action, GeneratedAt: time.Now(), Users: []UserAccess{}}
+	for _, u := range users {
+		access, err := s.explainUser(ctx, u, roleNames, q, byID)
+		if err != nil {
+			return nil, fmt.Errorf("user %s: %w", u.Username, err)
+		}
+		result.Checked++
+		if access.Allowed || q.All || q.UserID != "" {
+			result.Users = append(result.Users, access)
+		}
+	}
+	sort.SliceStable(result.Users, func(i, j int) bool {
+		if result.Users[i].Allowed != result.Users[j].Allowed {
+			return result.Users[i].Allowed
+		}
+		return result.Users[i].Username < result.Users[j].Username
+	})
+	return result, nil
+}
+
+func (s *AccessReviewServiceImpl) explainUser(ctx context.Context, u common_models.User, roleNames map[primitive.ObjectID]string, q ExplainQuery, byID map[string]any) (UserAccess, error) {
+	access := UserAccess{UserID: u.ID.Hex(), Username: u.Username, Status: u.Status, Roles: []string{}}
+	for _, id := range u.Roles {
+		if name, ok := roleNames[id]; ok {
+			access.Roles = append(access.Roles, name)
+		}
+	}
+
+	// Module permissions resolve group grants from the caller's claims, so
+	// the check runs as the user being explained
+	userCtx := context.WithValue(ctx, utils.UserClaimsKey, &utils.UserClaims{
+		UserID:   u.ID.Hex(),
+		TenantID: u.TenantID.Hex(),
+		Roles:    access.Roles,
+	})
+	moduleAccess, err := s.RoleService.CheckModulePermission(userCtx, access.Roles, q.Module, q.Action)
+	if err != nil {
+		return access, err
+	}
+	access.ModuleAccess = moduleAccess
+
+	filter, err := s.RoleService.GetAccessFilter(ctx, u.ID, q.Module, q.Action)
+	if err != nil {
+		return access, err
+	}
+	n, err := s.RecordRepo.Count(ctx, q.Module, byID, filter)
+	if err != nil {
+		return access, err
+	}
+	access.RecordAccess = n > 0
+
+	grants, err := s.RoleService.ExplainAccess(ctx, u.ID, q.Module, q.Action)
+	if err != nil {
+		return access, err
+	}
+	for _, g := range grants {
+		check := GrantCheck{Source: g.Source, Name: g.Name, Resource: g.Resource, Error: g.Error}
+		switch {
+		case g.Error != "":
+		case g.Conditions == nil:
+			check.Matches = true
+		default:
+			check.Conditions = DescribeConditions(g.Conditions)
+			n, err := s.RecordRepo.Count(ctx, q.Module, byID, g.Filter)
+			if err != nil {
+				return access, err
+			}
+			check.Matches = n > 0
+		}
+		access.Grants = append(access.Grants, check)
+	}
+
+	access.Allowed = access.ModuleAccess && access.RecordAccess && u.Status != "inactive" && u.Status != "suspended"
+	if access.Allowed {
+		fields, err := s.RoleService.GetFieldPermissions(ctx, u.ID, q.Module)
+		if err != nil {
+			return access, err
+		}
+		for field, rule := range fields {
+			switch rule {
+			case role.FieldPermNone:
+				access.HiddenFields = append(access.HiddenFields, field)
+			case role.FieldPermReadOnly:
+				access.ReadOnlyFields = append(access.ReadOnlyFields, field)
+			}
+		}
+		sort.Strings(access.HiddenFields)
+		sort.Strings(access.ReadOnlyFields)
+	}
+	return access, nil
+}
+
+// DescribeConditions renders an access filter for reviewers, e.g.
+// "owner eq $user.id AND (stage in [won lost])"
+func DescribeConditions(g *common_models.PermissionGroup) string {
+	if g == nil {
+		return ""
+	}
+	op := strings.ToUpper(g.Operator)
+	if op == "" {
+		op = "AND"
+	}
+	var parts []string
+	for _, r := range g.Rules {
+		parts = append(parts, fmt.Sprintf("%s %s %v", r.Field, r.Operator, r.Value))
+	}
+	for i := range g.Groups {
+		if nested := DescribeConditions(&g.Groups[i]); nested != "" {
+			parts = append(parts, "("+nested+")")
+		}
+	}
+	return strings.Join(parts, " "+op+" ")
+}
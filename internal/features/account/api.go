@@ -0,0 +1,26 @@
+package account
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AccountApi struct {
+	controller *AccountController
+	config     *config.Config
+}
+
+func NewAccountApi(controller *AccountController, config *config.Config) *AccountApi {
+	return &AccountApi{
+		controller: controller,
+		config:     config,
+	}
+}
+
+func (h *AccountApi) Setup(app *fiber.App) {
+	group := app.Group("/api/accounts", middleware.AuthMiddleware(h.config.SkipAuth))
+	group.Get("/:id/overview", h.controller.GetOverview)
+	group.Get("/:id/hierarchy", h.controller.GetHierarchy)
+}
@@ -0,0 +1,59 @@
+package account
+
+import (
+	common_api "go-crm/internal/common/api"
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AccountController struct {
+	Service AccountService
+}
+
+func NewAccountController(service AccountService) *AccountController {
+	return &AccountController{Service: service}
+}
+
+// GetOverview godoc
+// @Summary Account overview
+// @Description Customer 360 view of an account: the account record, open opportunities, open tickets and their SLA status, contracts, lifetime revenue (won opportunities), recent activities and upcoming tasks and meetings. Related records count only when the user may read them. With rollup, the figures cover the account and the subsidiaries below it.
+// @Tags accounts
+// @Produce json
+// @Param id path string true "Account record ID"
+// @Param rollup query bool false "Aggregate across the subsidiaries of the account"
+// @Success 200 {object} Overview
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/accounts/{id}/overview [get]
+func (c *AccountController) GetOverview(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	overview, err := c.Service.GetOverview(ctx.UserContext(), ctx.Params("id"), ctx.QueryBool("rollup"), userID)
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+	return ctx.JSON(overview)
+}
+
+// GetHierarchy godoc
+// @Summary Account hierarchy
+// @Description The corporate family of an account as a tree, from its top-level parent down through parent_account. Accounts the user may not read are shown without their name.
+// @Tags accounts
+// @Produce json
+// @Param id path string true "Account record ID"
+// @Success 200 {object} Hierarchy
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/accounts/{id}/hierarchy [get]
+func (c *AccountController) GetHierarchy(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	hierarchy, err := c.Service.GetHierarchy(ctx.UserContext(), ctx.Params("id"), userID)
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+	return ctx.JSON(hierarchy)
+}
@@ -0,0 +1,198 @@
+package account
+
+import (
+	"context"
+
+	"go-crm/internal/features/record"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetHierarchy returns the corporate family of an account, which the user
+// must be able to read
+func (s *AccountServiceImpl) GetHierarchy(ctx context.Context, id string, userID primitive.ObjectID) (*Hierarchy, error) {
+	if _, err := s.RecordService.GetRecord(ctx, ModuleName, id, userID); err != nil {
+		return nil, ErrAccountNotFound
+	}
+
+	root, err := s.rootOf(ctx, id)
+	if err != nil {
+		return nil, ErrAccountNotFound
+	}
+	rootID := recordID(root)
+	children, truncated, err := s.family(ctx, rootID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{rootID}
+	for _, recs := range children {
+		for _, rec := range recs {
+			ids = append(ids, recordID(rec))
+		}
+	}
+	readable, err := s.readableAccounts(ctx, ids, userID)
+	if err != nil {
+		return nil, err
+	}
+	showNames := !s.hidden(ctx, userID, ModuleName, "name")
+
+	var node func(rec map[string]interface{}) *HierarchyNode
+	node = func(rec map[string]interface{}) *HierarchyNode {
+		n := &HierarchyNode{ID: recordID(rec), Children: []*HierarchyNode{}}
+		if !readable[n.ID] {
+			n.Restricted = true
+		} else if showNames {
+			n.Name, _ = rec["name"].(string)
+		}
+		for _, child := range children[n.ID] {
+			n.Children = append(n.Children, node(child))
+		}
+		return n
+	}
+	return &Hierarchy{AccountID: id, Root: node(root), Accounts: len(ids), Truncated: truncated}, nil
+}
+
+// subsidiaries returns the IDs of the accounts below id the user may read
+func (s *AccountServiceImpl) subsidiaries(ctx context.Context, id string, userID primitive.ObjectID) ([]string, error) {
+	children, _, err := s.family(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, recs := range children {
+		for _, rec := range recs {
+			ids = append(ids, recordID(rec))
+		}
+	}
+	readable, err := s.readableAccounts(ctx, ids, userID)
+	if err != nil {
+		return nil, err
+	}
+	visible := make([]string, 0, len(ids))
+	for _, child := range ids {
+		if readable[child] {
+			visible = append(visible, child)
+		}
+	}
+	return visible, nil
+}
+
+// rootOf follows the parent chain up from id and returns the top-level
+// account. A parent that is missing or loops back ends the walk.
+func (s *AccountServiceImpl) rootOf(ctx context.Context, id string) (map[string]interface{}, error) {
+	rec, err := s.RecordRepo.Get(ctx, ModuleName, id)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{id: true}
+	for depth := 0; depth < record.MaxHierarchyDepth; depth++ {
+		parent := parentOf(rec)
+		if parent == "" || seen[parent] {
+			break
+		}
+		parentRec, err := s.RecordRepo.Get(ctx, ModuleName, parent)
+		if err != nil {
+			break
+		}
+		seen[parent] = true
+		rec = parentRec
+	}
+	return rec, nil
+}
+
+// family reads the accounts below root one level per query and returns the
+// children of each account in name order. truncated is set when familyLimit
+// accounts were read before reaching the bottom.
+func (s *AccountServiceImpl) family(ctx context.Context, root string) (map[string][]map[string]interface{}, bool, error) {
+	children := make(map[string][]map[string]interface{})
+	seen := map[string]bool{root: true}
+	level := []string{root}
+	read := 0
+	for depth := 0; len(level) > 0 && depth < record.MaxHierarchyDepth; depth++ {
+		if read >= familyLimit {
+			return children, true, nil
+		}
+		recs, err := s.RecordRepo.List(ctx, ModuleName, map[string]any{ParentField: bson.M{"$in": idValues(level)}}, nil, int64(familyLimit-read), 0, "name", 1)
+		if err != nil {
+			return nil, false, err
+		}
+		level = level[:0:0]
+		for _, rec := range recs {
+			id := recordID(rec)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			read++
+			parent := parentOf(rec)
+			children[parent] = append(children[parent], rec)
+			level = append(level, id)
+		}
+	}
+	return children, false, nil
+}
+
+// readableAccounts returns which of the accounts the user may read
+func (s *AccountServiceImpl) readableAccounts(ctx context.Context, ids []string, userID primitive.ObjectID) (map[string]bool, error) {
+	readable := make(map[string]bool, len(ids))
+	if len(ids) == 0 {
+		return readable, nil
+	}
+	accessFilter, err := s.RoleService.GetAccessFilter(ctx, userID, ModuleName, "read")
+	if err != nil {
+		return nil, err
+	}
+	if len(accessFilter) == 0 {
+		for _, id := range ids {
+			readable[id] = true
+		}
+		return readable, nil
+	}
+
+	oids := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+			oids = append(oids, oid)
+		}
+	}
+	recs, err := s.RecordRepo.List(ctx, ModuleName, map[string]any{"_id": bson.M{"$in": oids}}, accessFilter, int64(len(oids)), 0, "_id", 1)
+	if err != nil {
+		return nil, err
+	}
+	for _, rec := range recs {
+		readable[recordID(rec)] = true
+	}
+	return readable, nil
+}
+
+// idValues matches a lookup to the accounts either way it may be stored: as
+// an ObjectID, or as the hex of imported records
+func idValues(ids []string) bson.A {
+	values := make(bson.A, 0, 2*len(ids))
+	for _, id := range ids {
+		if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+			values = append(values, oid)
+		}
+		values = append(values, id)
+	}
+	return values
+}
+
+func parentOf(rec map[string]interface{}) string {
+	switch v := rec[ParentField].(type) {
+	case primitive.ObjectID:
+		return v.Hex()
+	case string:
+		return v
+	}
+	return ""
+}
+
+func recordID(rec map[string]interface{}) string {
+	if oid, ok := rec["_id"].(primitive.ObjectID); ok {
+		return oid.Hex()
+	}
+	return ""
+}
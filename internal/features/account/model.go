@@ -0,0 +1,105 @@
+package account
+
+import (
+	"time"
+
+	"go-crm/internal/features/ticket"
+)
+
+// Modules the overview reads; see internal/migration/data/modules.json
+const (
+	ModuleName          = "accounts"
+	ContactsModule      = "contacts"
+	OpportunitiesModule = "opportunities"
+	ContractsModule     = "contracts"
+	TasksModule         = "tasks"
+	CallsModule         = "calls"
+	MeetingsModule      = "meetings"
+)
+
+// Seeded select values the overview groups by
+const (
+	WonStage             = "Closed Won"
+	LostStage            = "Closed Lost"
+	ContractStatusActive = "active"
+	TaskStatusCompleted  = "completed"
+)
+
+// ParentField is the accounts lookup holding an account's parent company
+const ParentField = "parent_account"
+
+const (
+	// ActivityLimit bounds the recent and the upcoming activities listed
+	ActivityLimit = 10
+	// contactsLimit bounds the contacts whose activities and tickets count
+	contactsLimit = 1000
+	// familyLimit bounds the accounts of a hierarchy read or rolled up
+	familyLimit = 1000
+)
+
+// Overview is the customer 360 view of an account, computed in one call.
+// Amounts are nil when the user cannot see the field they come from. Rolled
+// up, the figures cover the account and the subsidiaries below it the user
+// may read; Accounts counts them.
+type Overview struct {
+	Account            map[string]interface{}        `json:"account"`
+	RolledUp           bool                          `json:"rolled_up"`
+	Accounts           int                           `json:"accounts"`
+	Contacts           int                           `json:"contacts"`
+	Opportunities      OpportunitySummary            `json:"opportunities"`
+	Tickets            *ticket.CustomerTicketSummary `json:"tickets"`
+	Contracts          ContractSummary               `json:"contracts"`
+	LifetimeRevenue    *float64                      `json:"lifetime_revenue"`
+	RecentActivities   []Activity                    `json:"recent_activities"`
+	UpcomingActivities []Activity                    `json:"upcoming_activities"`
+}
+
+// OpportunitySummary counts the account's opportunities by outcome
+type OpportunitySummary struct {
+	Open       int      `json:"open"`
+	OpenAmount *float64 `json:"open_amount"`
+	Won        int      `json:"won"`
+	Lost       int      `json:"lost"`
+}
+
+// ContractSummary is the state of the account's contracts. Status is active
+// while any contract is, none without contracts, and otherwise the status of
+// the contract ending last.
+type ContractSummary struct {
+	Status      string         `json:"status"`
+	Active      int            `json:"active"`
+	ActiveValue *float64       `json:"active_value"`
+	NextEndDate *time.Time     `json:"next_end_date,omitempty"`
+	ByStatus    map[string]int `json:"by_status"`
+}
+
+// Activity is a task, call or meeting logged against the account, one of its
+// contacts or one of its opportunities
+type Activity struct {
+	Module        string     `json:"module"`
+	ID            string     `json:"id"`
+	Subject       string     `json:"subject"`
+	Status        string     `json:"status,omitempty"`
+	At            *time.Time `json:"at,omitempty"`
+	RelatedModule string     `json:"related_module"`
+	RelatedID     string     `json:"related_id"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// HierarchyNode is an account of a corporate family. Accounts the user may
+// not read keep their place in the tree without their name.
+type HierarchyNode struct {
+	ID         string           `json:"id"`
+	Name       string           `json:"name,omitempty"`
+	Restricted bool             `json:"restricted,omitempty"`
+	Children   []*HierarchyNode `json:"children"`
+}
+
+// Hierarchy is the corporate family of an account, from its top-level parent
+// down. Truncated is set when the family is larger than what is read.
+type Hierarchy struct {
+	AccountID string         `json:"account_id"`
+	Root      *HierarchyNode `json:"root"`
+	Accounts  int            `json:"accounts"`
+	Truncated bool           `json:"truncated,omitempty"`
+}
@@ -0,0 +1,356 @@
+package account
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go-crm/internal/common/apperr"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/role"
+	"go-crm/internal/features/ticket"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var ErrAccountNotFound = apperr.NotFound("account not found")
+
+// activityDateFields is the field each activity module is scheduled by
+var activityDateFields = map[string]string{
+	TasksModule:    "due_date",
+	CallsModule:    "start_time",
+	MeetingsModule: "start_time",
+}
+
+type AccountService interface {
+	// GetOverview returns the customer 360 view of an account, with rollup
+	// across the subsidiaries below it
+	GetOverview(ctx context.Context, id string, rollup bool, userID primitive.ObjectID) (*Overview, error)
+	// GetHierarchy returns the corporate family tree an account belongs to
+	GetHierarchy(ctx context.Context, id string, userID primitive.ObjectID) (*Hierarchy, error)
+}
+
+type AccountServiceImpl struct {
+	RecordService record.RecordService
+	RecordRepo    record.RecordRepository
+	RoleService   role.RoleService
+	TicketRepo    ticket.TicketRepository
+}
+
+func NewAccountService(
+	recordService record.RecordService,
+	recordRepo record.RecordRepository,
+	roleService role.RoleService,
+	ticketRepo ticket.TicketRepository,
+) AccountService {
+	return &AccountServiceImpl{
+		RecordService: recordService,
+		RecordRepo:    recordRepo,
+		RoleService:   roleService,
+		TicketRepo:    ticketRepo,
+	}
+}
+
+// GetOverview reads the account with the user's access, then summarises its
+// opportunities, contracts and activities with one aggregation per module,
+// each limited to the records the user may read. Tickets are those filed
+// from the emails of the account's contacts.
+func (s *AccountServiceImpl) GetOverview(ctx context.Context, id string, rollup bool, userID primitive.ObjectID) (*Overview, error) {
+	acct, err := s.RecordService.GetRecord(ctx, ModuleName, id, userID)
+	if err != nil {
+		return nil, ErrAccountNotFound
+	}
+	ids := []string{id}
+	if rollup {
+		subsidiaries, err := s.subsidiaries(ctx, id, userID)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, subsidiaries...)
+	}
+	ofAccount := bson.M{"$in": idValues(ids)}
+	now := time.Now()
+
+	overview := &Overview{Account: acct, RolledUp: rollup, Accounts: len(ids)}
+
+	contactIDs, emails, err := s.contacts(ctx, ofAccount)
+	if err != nil {
+		return nil, err
+	}
+	contactsFilter, err := s.RoleService.GetAccessFilter(ctx, userID, ContactsModule, "read")
+	if err != nil {
+		return nil, err
+	}
+	contacts, err := s.RecordRepo.Count(ctx, ContactsModule, map[string]any{"account": ofAccount}, contactsFilter)
+	if err != nil {
+		return nil, err
+	}
+	overview.Contacts = int(contacts)
+
+	opportunityIDs, err := s.opportunities(ctx, overview, ofAccount, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.contracts(ctx, overview, ofAccount, userID); err != nil {
+		return nil, err
+	}
+	if overview.Tickets, err = s.TicketRepo.CustomerSummary(ctx, emails, now); err != nil {
+		return nil, err
+	}
+
+	related := bson.A{bson.M{"data.related_module": ModuleName, "data.related_id": bson.M{"$in": ids}}}
+	if len(contactIDs) > 0 {
+		related = append(related, bson.M{"data.related_module": ContactsModule, "data.related_id": bson.M{"$in": contactIDs}})
+	}
+	if len(opportunityIDs) > 0 {
+		related = append(related, bson.M{"data.related_module": OpportunitiesModule, "data.related_id": bson.M{"$in": opportunityIDs}})
+	}
+	if err := s.activities(ctx, overview, bson.M{"$or": related}, now, userID); err != nil {
+		return nil, err
+	}
+	return overview, nil
+}
+
+// readable limits match to the module records the user may read
+func (s *AccountServiceImpl) readable(ctx context.Context, userID primitive.ObjectID, module string, match bson.M) (bson.M, error) {
+	accessFilter, err := s.RoleService.GetAccessFilter(ctx, userID, module, "read")
+	if err != nil {
+		return nil, err
+	}
+	if len(accessFilter) == 0 {
+		return match, nil
+	}
+	return bson.M{"$and": bson.A{match, accessFilter}}, nil
+}
+
+// hidden reports whether the field of the module is hidden from the user
+func (s *AccountServiceImpl) hidden(ctx context.Context, userID primitive.ObjectID, module, field string) bool {
+	perms, err := s.RoleService.GetFieldPermissions(ctx, userID, module)
+	return err == nil && perms[field] == role.FieldPermNone
+}
+
+// aggregateOne runs a pipeline grouping to a single row and decodes it into
+// out, leaving out untouched without matching records
+func (s *AccountServiceImpl) aggregateOne(ctx context.Context, module string, pipeline mongo.Pipeline, out interface{}) error {
+	rows, err := s.RecordRepo.Aggregate(ctx, module, pipeline)
+	if err != nil || len(rows) == 0 {
+		return err
+	}
+	raw, err := bson.Marshal(rows[0])
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(raw, out)
+}
+
+// contacts returns the IDs and emails of the account's contacts, whoever may
+// read them: they only select the tickets and activities counted
+func (s *AccountServiceImpl) contacts(ctx context.Context, ofAccount bson.M) ([]string, []string, error) {
+	var row struct {
+		IDs    []string `bson:"ids"`
+		Emails []string `bson:"emails"`
+	}
+	err := s.aggregateOne(ctx, ContactsModule, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"data.account": ofAccount}}},
+		{{Key: "$limit", Value: contactsLimit}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    nil,
+			"ids":    bson.M{"$push": bson.M{"$toString": "$_id"}},
+			"emails": bson.M{"$addToSet": "$data.email"},
+		}}},
+	}, &row)
+
+	emails := make([]string, 0, len(row.Emails))
+	for _, e := range row.Emails {
+		if e != "" {
+			emails = append(emails, e)
+		}
+	}
+	return row.IDs, emails, err
+}
+
+// opportunities fills the opportunity summary and lifetime revenue, the won
+// amount, and returns the IDs of the opportunities counted
+func (s *AccountServiceImpl) opportunities(ctx context.Context, overview *Overview, ofAccount bson.M, userID primitive.ObjectID) ([]string, error) {
+	match, err := s.readable(ctx, userID, OpportunitiesModule, bson.M{"data.account": ofAccount})
+	if err != nil {
+		return nil, err
+	}
+	open := bson.M{"$not": bson.A{bson.M{"$in": bson.A{"$data.stage", bson.A{WonStage, LostStage}}}}}
+	won := bson.M{"$eq": bson.A{"$data.stage", WonStage}}
+
+	var row struct {
+		IDs        []string `bson:"ids"`
+		Open       int      `bson:"open"`
+		OpenAmount float64  `bson:"open_amount"`
+		Won        int      `bson:"won"`
+		WonAmount  float64  `bson:"won_amount"`
+		Lost       int      `bson:"lost"`
+	}
+	err = s.aggregateOne(ctx, OpportunitiesModule, mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         nil,
+			"ids":         bson.M{"$push": bson.M{"$toString": "$_id"}},
+			"open":        bson.M{"$sum": bson.M{"$cond": bson.A{open, 1, 0}}},
+			"open_amount": bson.M{"$sum": bson.M{"$cond": bson.A{open, "$data.amount", 0}}},
+			"won":         bson.M{"$sum": bson.M{"$cond": bson.A{won, 1, 0}}},
+			"won_amount":  bson.M{"$sum": bson.M{"$cond": bson.A{won, "$data.amount", 0}}},
+			"lost":        bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$data.stage", LostStage}}, 1, 0}}},
+		}}},
+	}, &row)
+	if err != nil {
+		return nil, err
+	}
+
+	overview.Opportunities = OpportunitySummary{Open: row.Open, Won: row.Won, Lost: row.Lost}
+	if !s.hidden(ctx, userID, OpportunitiesModule, "amount") {
+		overview.Opportunities.OpenAmount = &row.OpenAmount
+		overview.LifetimeRevenue = &row.WonAmount
+	}
+	return row.IDs, nil
+}
+
+// contracts fills the contract summary
+func (s *AccountServiceImpl) contracts(ctx context.Context, overview *Overview, ofAccount bson.M, userID primitive.ObjectID) error {
+	match, err := s.readable(ctx, userID, ContractsModule, bson.M{"data.account": ofAccount})
+	if err != nil {
+		return err
+	}
+	active := bson.M{"$eq": bson.A{"$data.status", ContractStatusActive}}
+
+	var row struct {
+		LastStatus  string     `bson:"last_status"`
+		Statuses    []string   `bson:"statuses"`
+		Active      int        `bson:"active"`
+		ActiveValue float64    `bson:"active_value"`
+		NextEndDate *time.Time `bson:"next_end_date"`
+	}
+	err = s.aggregateOne(ctx, ContractsModule, mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "data.end_date", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":           nil,
+			"last_status":   bson.M{"$first": "$data.status"},
+			"statuses":      bson.M{"$push": "$data.status"},
+			"active":        bson.M{"$sum": bson.M{"$cond": bson.A{active, 1, 0}}},
+			"active_value":  bson.M{"$sum": bson.M{"$cond": bson.A{active, "$data.value", 0}}},
+			"next_end_date": bson.M{"$min": bson.M{"$cond": bson.A{active, "$data.end_date", nil}}},
+		}}},
+	}, &row)
+	if err != nil {
+		return err
+	}
+
+	summary := ContractSummary{Status: "none", Active: row.Active, NextEndDate: row.NextEndDate, ByStatus: map[string]int{}}
+	for _, status := range row.Statuses {
+		summary.ByStatus[status]++
+	}
+	switch {
+	case row.Active > 0:
+		summary.Status = ContractStatusActive
+	case row.LastStatus != "":
+		summary.Status = row.LastStatus
+	}
+	if !s.hidden(ctx, userID, ContractsModule, "value") {
+		summary.ActiveValue = &row.ActiveValue
+	}
+	overview.Contracts = summary
+	return nil
+}
+
+// activityRow is an activity as the activities pipeline projects it
+type activityRow struct {
+	ID            primitive.ObjectID `bson:"_id"`
+	Subject       string             `bson:"subject"`
+	Status        string             `bson:"status"`
+	At            *time.Time         `bson:"at"`
+	RelatedModule string             `bson:"related_module"`
+	RelatedID     string             `bson:"related_id"`
+	CreatedAt     time.Time          `bson:"created_at"`
+}
+
+// activities lists the latest activities logged against the related records
+// and the tasks and meetings still to come, across the activity modules
+func (s *AccountServiceImpl) activities(ctx context.Context, overview *Overview, related bson.M, now time.Time, userID primitive.ObjectID) error {
+	overview.RecentActivities = []Activity{}
+	overview.UpcomingActivities = []Activity{}
+
+	for _, module := range []string{TasksModule, CallsModule, MeetingsModule} {
+		match, err := s.readable(ctx, userID, module, related)
+		if err != nil {
+			return err
+		}
+		date := "$data." + activityDateFields[module]
+		project := bson.D{{Key: "$project", Value: bson.M{
+			"subject":        "$data.subject",
+			"status":         "$data.status",
+			"at":             date,
+			"related_module": "$data.related_module",
+			"related_id":     "$data.related_id",
+			"created_at":     1,
+		}}}
+		facets := bson.M{
+			"recent": bson.A{
+				bson.D{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: -1}}}},
+				bson.D{{Key: "$limit", Value: ActivityLimit}},
+				project,
+			},
+		}
+		if module != CallsModule {
+			upcoming := bson.M{"data." + activityDateFields[module]: bson.M{"$gte": now}}
+			if module == TasksModule {
+				upcoming["data.status"] = bson.M{"$ne": TaskStatusCompleted}
+			}
+			facets["upcoming"] = bson.A{
+				bson.D{{Key: "$match", Value: upcoming}},
+				bson.D{{Key: "$sort", Value: bson.D{{Key: "data." + activityDateFields[module], Value: 1}}}},
+				bson.D{{Key: "$limit", Value: ActivityLimit}},
+				project,
+			}
+		}
+
+		var row struct {
+			Recent   []activityRow `bson:"recent"`
+			Upcoming []activityRow `bson:"upcoming"`
+		}
+		err = s.aggregateOne(ctx, module, mongo.Pipeline{
+			{{Key: "$match", Value: match}},
+			{{Key: "$facet", Value: facets}},
+		}, &row)
+		if err != nil {
+			return err
+		}
+		for _, r := range row.Recent {
+			overview.RecentActivities = append(overview.RecentActivities, r.activity(module))
+		}
+		for _, r := range row.Upcoming {
+			overview.UpcomingActivities = append(overview.UpcomingActivities, r.activity(module))
+		}
+	}
+
+	sort.SliceStable(overview.RecentActivities, func(i, j int) bool {
+		return overview.RecentActivities[i].CreatedAt.After(overview.RecentActivities[j].CreatedAt)
+	})
+	sort.SliceStable(overview.UpcomingActivities, func(i, j int) bool {
+		return overview.UpcomingActivities[i].At.Before(*overview.UpcomingActivities[j].At)
+	})
+	overview.RecentActivities = overview.RecentActivities[:min(len(overview.RecentActivities), ActivityLimit)]
+	overview.UpcomingActivities = overview.UpcomingActivities[:min(len(overview.UpcomingActivities), ActivityLimit)]
+	return nil
+}
+
+func (r activityRow) activity(module string) Activity {
+	return Activity{
+		Module:        module,
+		ID:            r.ID.Hex(),
+		Subject:       r.Subject,
+		Status:        r.Status,
+		At:            r.At,
+		RelatedModule: r.RelatedModule,
+		RelatedID:     r.RelatedID,
+		CreatedAt:     r.CreatedAt,
+	}
+}
@@ -0,0 +1,37 @@
+package accounting
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AccountingApi struct {
+	controller  *AccountingController
+	config      *config.Config
+	roleService middleware.RoleService
+}
+
+func NewAccountingApi(controller *AccountingController, config *config.Config, roleService middleware.RoleService) *AccountingApi {
+	return &AccountingApi{
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *AccountingApi) Setup(app *fiber.App) {
+	// The provider redirects the browser here; the signed state authenticates it
+	app.Get("/api/accounting/oauth/:provider/callback", h.controller.Callback)
+
+	conns := app.Group("/api/accounting/connections", middleware.AuthMiddleware(h.config.SkipAuth))
+	conns.Get("/", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListConnections)
+	conns.Post("/:provider/connect", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.Connect)
+	conns.Get("/:id", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.GetConnection)
+	conns.Put("/:id", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.UpdateConnection)
+	conns.Delete("/:id", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.Disconnect)
+	conns.Post("/:id/sync", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.RunSync)
+	conns.Get("/:id/logs", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListLogs)
+	conns.Get("/:id/mappings", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListMappings)
+}
@@ -0,0 +1,193 @@
+package accounting
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go-crm/internal/common/session"
+	"go-crm/internal/config"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AccountingController struct {
+	Service AccountingService
+	Config  *config.Config
+}
+
+func NewAccountingController(service AccountingService, cfg *config.Config) *AccountingController {
+	return &AccountingController{Service: service, Config: cfg}
+}
+
+// ListConnections godoc
+// @Summary List accounting connections
+// @Description List the tenant's QuickBooks and Xero connections and which providers this server supports
+// @Tags accounting
+// @Produce json
+// @Success 200 {array} Connection
+// @Router /api/accounting/connections [get]
+func (c *AccountingController) ListConnections(ctx *fiber.Ctx) error {
+	conns, err := c.Service.ListConnections(ctx.UserContext())
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": conns, "providers": c.Service.Providers()})
+}
+
+// Connect godoc
+// @Summary Start an accounting connection
+// @Description Return the provider consent URL; the provider redirects back to the OAuth callback
+// @Tags accounting
+// @Produce json
+// @Param provider path string true "quickbooks or xero"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/accounting/connections/{provider}/connect [post]
+func (c *AccountingController) Connect(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	authURL, err := c.Service.Connect(ctx.UserContext(), Provider(ctx.Params("provider")), userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"auth_url": authURL})
+}
+
+// Callback godoc
+// @Summary Accounting OAuth callback
+// @Description Complete the OAuth flow and redirect back to the accounting settings page
+// @Tags accounting
+// @Param provider path string true "quickbooks or xero"
+// @Param code query string false "Authorization code"
+// @Param state query string true "Signed state"
+// @Success 302
+// @Router /api/accounting/oauth/{provider}/callback [get]
+func (c *AccountingController) Callback(ctx *fiber.Ctx) error {
+	provider := Provider(ctx.Params("provider"))
+	params := ctx.Queries()
+
+	q := url.Values{}
+	q.Set("provider", string(provider))
+	if _, err := c.Service.HandleCallback(ctx.UserContext(), provider, params); err != nil {
+		if errors.Is(err, ErrInvalidState) {
+			return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
+		q.Set("status", "error")
+		q.Set("error", err.Error())
+	} else {
+		q.Set("status", "connected")
+	}
+	return ctx.Redirect(fmt.Sprintf("%s/dashboard/settings/accounting?%s", strings.TrimRight(c.Config.PublicURL, "/"), q.Encode()))
+}
+
+// GetConnection godoc
+// @Summary Get accounting connection
+// @Tags accounting
+// @Produce json
+// @Param id path string true "Connection ID"
+// @Success 200 {object} Connection
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/accounting/connections/{id} [get]
+func (c *AccountingController) GetConnection(ctx *fiber.Ctx) error {
+	conn, err := c.Service.GetConnection(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Connection not found"})
+	}
+	return ctx.JSON(fiber.Map{"data": conn})
+}
+
+// UpdateConnection godoc
+// @Summary Update accounting connection
+// @Description Set the module to document mappings, provider options and whether scheduled sync runs
+// @Tags accounting
+// @Accept json
+// @Produce json
+// @Param id path string true "Connection ID"
+// @Param request body UpdateConnectionRequest true "Connection settings"
+// @Success 200 {object} Connection
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/accounting/connections/{id} [put]
+func (c *AccountingController) UpdateConnection(ctx *fiber.Ctx) error {
+	var req UpdateConnectionRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	conn, err := c.Service.UpdateConnection(ctx.UserContext(), ctx.Params("id"), req)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": conn})
+}
+
+// Disconnect godoc
+// @Summary Disconnect accounting system
+// @Description Remove the connection and its record to document ID mappings
+// @Tags accounting
+// @Param id path string true "Connection ID"
+// @Success 204
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/accounting/connections/{id} [delete]
+func (c *AccountingController) Disconnect(ctx *fiber.Ctx) error {
+	if err := c.Service.Disconnect(ctx.UserContext(), ctx.Params("id")); err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// RunSync godoc
+// @Summary Run accounting sync
+// @Description Push changed invoices and sales orders and pull payment status now
+// @Tags accounting
+// @Produce json
+// @Param id path string true "Connection ID"
+// @Success 200 {object} sync.SyncLog
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/accounting/connections/{id}/sync [post]
+func (c *AccountingController) RunSync(ctx *fiber.Ctx) error {
+	run, err := c.Service.RunSync(ctx.UserContext(), ctx.Params("id"))
+	if errors.Is(err, ErrSyncRunning) {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	if run == nil && err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	// A failed run is still reported through its log
+	return ctx.JSON(fiber.Map{"data": run})
+}
+
+// ListLogs godoc
+// @Summary List accounting sync runs
+// @Tags accounting
+// @Produce json
+// @Param id path string true "Connection ID"
+// @Param limit query int false "Limit"
+// @Success 200 {array} sync.SyncLog
+// @Router /api/accounting/connections/{id}/logs [get]
+func (c *AccountingController) ListLogs(ctx *fiber.Ctx) error {
+	logs, err := c.Service.ListLogs(ctx.UserContext(), ctx.Params("id"), int64(ctx.QueryInt("limit", 20)))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": logs})
+}
+
+// ListMappings godoc
+// @Summary List record to document mappings
+// @Tags accounting
+// @Produce json
+// @Param id path string true "Connection ID"
+// @Param limit query int false "Limit"
+// @Param offset query int false "Offset"
+// @Success 200 {array} EntityMapping
+// @Router /api/accounting/connections/{id}/mappings [get]
+func (c *AccountingController) ListMappings(ctx *fiber.Ctx) error {
+	mappings, err := c.Service.ListMappings(ctx.UserContext(), ctx.Params("id"), int64(ctx.QueryInt("limit", 50)), int64(ctx.QueryInt("offset", 0)))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": mappings})
+}
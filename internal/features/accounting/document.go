@@ -0,0 +1,191 @@
+package accounting
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func validateMapping(m ModuleMapping) error {
+	if m.ModuleName == "" {
+		return errors.New("module_name is required")
+	}
+	if m.DocType != DocInvoice && m.DocType != DocSalesOrder {
+		return fmt.Errorf("%s: doc_type must be %q or %q", m.ModuleName, DocInvoice, DocSalesOrder)
+	}
+	if m.CustomerNameField == "" {
+		return fmt.Errorf("%s: customer_name_field is required", m.ModuleName)
+	}
+	if m.LineItemsField == "" && m.TotalField == "" {
+		return fmt.Errorf("%s: line_items_field or total_field is required", m.ModuleName)
+	}
+	if m.DocType == DocSalesOrder && (m.PaymentStatusField != "" || m.AmountPaidField != "") {
+		return fmt.Errorf("%s: payment fields only apply to invoices", m.ModuleName)
+	}
+	if (m.PushWhenField == "") != (m.PushWhenValue == "") {
+		return fmt.Errorf("%s: push_when_field and push_when_value go together", m.ModuleName)
+	}
+	return nil
+}
+
+// shouldPush applies the mapping's optional push condition
+func shouldPush(rec map[string]any, m ModuleMapping) bool {
+	if m.PushWhenField == "" {
+		return true
+	}
+	return stringValue(rec[m.PushWhenField]) == m.PushWhenValue
+}
+
+// buildDocument maps a flattened record onto the provider-neutral document
+func buildDocument(rec map[string]any, m ModuleMapping) (Document, error) {
+	doc := Document{
+		Type:          m.DocType,
+		CustomerName:  strings.TrimSpace(stringValue(rec[m.CustomerNameField])),
+		CustomerEmail: stringValue(rec[m.CustomerEmailField]),
+		Currency:      strings.ToUpper(stringValue(rec[m.CurrencyField])),
+	}
+	if doc.CustomerName == "" {
+		return doc, fmt.Errorf("field %s (customer name) is empty", m.CustomerNameField)
+	}
+	if m.NumberField != "" {
+		doc.Number = stringValue(rec[m.NumberField])
+	}
+	if m.DateField != "" {
+		doc.Date, _ = timeValue(rec[m.DateField])
+	}
+	if m.DueDateField != "" {
+		doc.DueDate, _ = timeValue(rec[m.DueDateField])
+	}
+
+	if m.LineItemsField != "" {
+		items, err := lineItems(rec[m.LineItemsField])
+		if err != nil {
+			return doc, fmt.Errorf("field %s: %v", m.LineItemsField, err)
+		}
+		doc.Lines = items
+	}
+	if len(doc.Lines) == 0 && m.TotalField != "" {
+		total, ok := numberValue(rec[m.TotalField])
+		if !ok {
+			return doc, fmt.Errorf("field %s (total) is not a number", m.TotalField)
+		}
+		description := doc.Number
+		if description == "" {
+			description = m.ModuleName
+		}
+		doc.Lines = []DocumentLine{{Description: description, Quantity: 1, UnitPrice: total}}
+	}
+	if len(doc.Lines) == 0 {
+		return doc, errors.New("document has no line items")
+	}
+	return doc, nil
+}
+
+func lineItems(v any) ([]DocumentLine, error) {
+	var raw []any
+	switch val := v.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		raw = val
+	case primitive.A:
+		raw = val
+	case []map[string]any:
+		for _, m := range val {
+			raw = append(raw, m)
+		}
+	default:
+		return nil, errors.New("expected a list of line items")
+	}
+
+	lines := make([]DocumentLine, 0, len(raw))
+	for i, item := range raw {
+		var m map[string]any
+		switch val := item.(type) {
+		case map[string]any:
+			m = val
+		case bson.M:
+			m = val
+		case bson.D:
+			m = val.Map()
+		default:
+			return nil, fmt.Errorf("line %d is not an object", i+1)
+		}
+		line := DocumentLine{Description: stringValue(m["description"]), Quantity: 1}
+		if q, ok := numberValue(m["quantity"]); ok {
+			line.Quantity = q
+		}
+		price, ok := numberValue(m["unit_price"])
+		if !ok {
+			return nil, fmt.Errorf("line %d has no unit_price", i+1)
+		}
+		line.UnitPrice = price
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// documentHash fingerprints what would be sent, so unchanged records are skipped
+func documentHash(doc Document) string {
+	b, _ := json.Marshal(doc)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func stringValue(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case primitive.ObjectID:
+		return val.Hex()
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func numberValue(v any) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case float32:
+		return float64(val), true
+	case int:
+		return float64(val), true
+	case int32:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	case primitive.Decimal128:
+		f, err := strconv.ParseFloat(val.String(), 64)
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func timeValue(v any) (time.Time, bool) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, !val.IsZero()
+	case primitive.DateTime:
+		return val.Time(), true
+	case string:
+		for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+			if t, err := time.Parse(layout, val); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,140 @@
+package accounting
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Provider string
+
+const (
+	ProviderQuickBooks Provider = "quickbooks"
+	ProviderXero       Provider = "xero"
+)
+
+// Document types that can be pushed
+const (
+	DocInvoice    = "invoice"
+	DocSalesOrder = "sales_order" // QuickBooks estimate / Xero quote
+)
+
+// Payment states written back to records
+const (
+	PaymentUnpaid  = "unpaid"
+	PaymentPartial = "partially_paid"
+	PaymentPaid    = "paid"
+	PaymentVoided  = "voided"
+)
+
+type ConnectionStatus string
+
+const (
+	StatusConnected    ConnectionStatus = "connected"
+	StatusError        ConnectionStatus = "error" // tokens rejected; reconnect required
+	StatusDisconnected ConnectionStatus = "disconnected"
+)
+
+// ModuleMapping says which records are pushed as which document and which
+// record fields hold the document data. Field values are record field names.
+type ModuleMapping struct {
+	ModuleName string `json:"module_name" bson:"module_name"`
+	DocType    string `json:"doc_type" bson:"doc_type"` // invoice, sales_order
+
+	NumberField        string `json:"number_field,omitempty" bson:"number_field,omitempty"`
+	CustomerNameField  string `json:"customer_name_field" bson:"customer_name_field"`
+	CustomerEmailField string `json:"customer_email_field,omitempty" bson:"customer_email_field,omitempty"`
+	DateField          string `json:"date_field,omitempty" bson:"date_field,omitempty"`
+	DueDateField       string `json:"due_date_field,omitempty" bson:"due_date_field,omitempty"`
+	CurrencyField      string `json:"currency_field,omitempty" bson:"currency_field,omitempty"`
+	// LineItemsField holds a list of {description, quantity, unit_price};
+	// without it the record becomes one line of TotalField
+	LineItemsField string `json:"line_items_field,omitempty" bson:"line_items_field,omitempty"`
+	TotalField     string `json:"total_field,omitempty" bson:"total_field,omitempty"`
+
+	// Written back when payment status is pulled (invoices only)
+	PaymentStatusField string `json:"payment_status_field,omitempty" bson:"payment_status_field,omitempty"`
+	AmountPaidField    string `json:"amount_paid_field,omitempty" bson:"amount_paid_field,omitempty"`
+	// Optional filter: only push records whose field equals the value
+	PushWhenField string `json:"push_when_field,omitempty" bson:"push_when_field,omitempty"`
+	PushWhenValue string `json:"push_when_value,omitempty" bson:"push_when_value,omitempty"`
+}
+
+// Connection is a tenant's OAuth connection to one accounting system
+type Connection struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	Provider Provider           `json:"provider" bson:"provider"`
+	Status   ConnectionStatus   `json:"status" bson:"status"`
+	IsActive bool               `json:"is_active" bson:"is_active"` // included in scheduled sync
+
+	// Company identifiers: QuickBooks realm ID or Xero tenant ID
+	CompanyID   string `json:"company_id" bson:"company_id"`
+	CompanyName string `json:"company_name,omitempty" bson:"company_name,omitempty"`
+
+	AccessToken  string    `json:"-" bson:"access_token"`
+	RefreshToken string    `json:"-" bson:"refresh_token"`
+	ExpiresAt    time.Time `json:"token_expires_at" bson:"expires_at"`
+
+	Modules []ModuleMapping `json:"modules" bson:"modules"`
+	// Provider-specific defaults: QuickBooks item_id, Xero account_code, Xero invoice_status
+	Options map[string]string `json:"options,omitempty" bson:"options,omitempty"`
+
+	LastSyncAt  time.Time          `json:"last_sync_at" bson:"last_sync_at"`
+	LastError   string             `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	ConnectedBy primitive.ObjectID `json:"connected_by" bson:"connected_by"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// EntityMapping links a CRM record to the document created for it
+type EntityMapping struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID     primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	ConnectionID primitive.ObjectID `json:"connection_id" bson:"connection_id"`
+	Provider     Provider           `json:"provider" bson:"provider"`
+	ModuleName   string             `json:"module_name" bson:"module_name"`
+	RecordID     string             `json:"record_id" bson:"record_id"`
+	DocType      string             `json:"doc_type" bson:"doc_type"`
+	ExternalID   string             `json:"external_id" bson:"external_id"`
+	SyncToken    string             `json:"-" bson:"sync_token,omitempty"` // QuickBooks optimistic concurrency token
+	Hash         string             `json:"-" bson:"hash"`                 // of the last pushed document
+	PushedAt     time.Time          `json:"pushed_at" bson:"pushed_at"`
+
+	PaymentStatus string    `json:"payment_status,omitempty" bson:"payment_status,omitempty"`
+	AmountPaid    float64   `json:"amount_paid" bson:"amount_paid"`
+	AmountDue     float64   `json:"amount_due" bson:"amount_due"`
+	CheckedAt     time.Time `json:"checked_at,omitempty" bson:"checked_at,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+type UpdateConnectionRequest struct {
+	Modules  []ModuleMapping   `json:"modules"`
+	Options  map[string]string `json:"options"`
+	IsActive *bool             `json:"is_active"`
+}
+
+// Document is the provider-neutral form of a pushed invoice or sales order
+type Document struct {
+	Type          string
+	Number        string
+	CustomerName  string
+	CustomerEmail string
+	Date          time.Time
+	DueDate       time.Time
+	Currency      string
+	Lines         []DocumentLine
+}
+
+type DocumentLine struct {
+	Description string  `json:"description"`
+	Quantity    float64 `json:"quantity"`
+	UnitPrice   float64 `json:"unit_price"`
+}
+
+// PaymentInfo is what a provider reports about a pushed invoice
+type PaymentInfo struct {
+	Status     string
+	AmountPaid float64
+	AmountDue  float64
+}
@@ -0,0 +1,202 @@
+package accounting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go-crm/internal/config"
+)
+
+// ErrAuthRejected means the provider refused the connection's credentials;
+// the tenant has to reconnect
+var ErrAuthRejected = errors.New("accounting provider rejected the connection credentials")
+
+// provider is implemented once per accounting system
+type provider interface {
+	AuthURL(state, redirectURI string) string
+	// Exchange trades the callback code for tokens and resolves the company
+	// the connection belongs to
+	Exchange(ctx context.Context, code, redirectURI string, callback map[string]string, conn *Connection) error
+	Refresh(ctx context.Context, conn *Connection) error
+	// Push creates the document, or updates it when mapping is not nil, and
+	// returns its provider ID and concurrency token
+	Push(ctx context.Context, conn *Connection, doc Document, mapping *EntityMapping) (string, string, error)
+	// PaymentUpdates returns the payment state of invoices changed since the
+	// given time, keyed by provider invoice ID
+	PaymentUpdates(ctx context.Context, conn *Connection, since time.Time) (map[string]PaymentInfo, error)
+}
+
+func newProviders(cfg *config.Config) map[Provider]provider {
+	providers := map[Provider]provider{}
+	client := &http.Client{Timeout: 30 * time.Second}
+	if cfg.QuickBooksClientID != "" {
+		providers[ProviderQuickBooks] = &quickBooks{
+			oauth: oauthClient{
+				clientID:     cfg.QuickBooksClientID,
+				clientSecret: cfg.QuickBooksClientSecret,
+				tokenURL:     "https://oauth.platform.intuit.com/oauth2/v1/tokens/bearer",
+				http:         client,
+			},
+			sandbox: cfg.QuickBooksSandbox,
+		}
+	}
+	if cfg.XeroClientID != "" {
+		providers[ProviderXero] = &xero{
+			oauth: oauthClient{
+				clientID:     cfg.XeroClientID,
+				clientSecret: cfg.XeroClientSecret,
+				tokenURL:     "https://identity.xero.com/connect/token",
+				http:         client,
+			},
+		}
+	}
+	return providers
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// oauthClient holds the authorization-code flow shared by both providers
+type oauthClient struct {
+	clientID     string
+	clientSecret string
+	tokenURL     string
+	http         *http.Client
+}
+
+func (o oauthClient) authURL(base, scope, state, redirectURI string) string {
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", o.clientID)
+	q.Set("redirect_uri", redirectURI)
+	q.Set("scope", scope)
+	q.Set("state", state)
+	return base + "?" + q.Encode()
+}
+
+func (o oauthClient) exchange(ctx context.Context, code, redirectURI string, conn *Connection) error {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURI)
+	return o.token(ctx, form, conn)
+}
+
+func (o oauthClient) refresh(ctx context.Context, conn *Connection) error {
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", conn.RefreshToken)
+	return o.token(ctx, form, conn)
+}
+
+func (o oauthClient) token(ctx context.Context, form url.Values, conn *Connection) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(o.clientID, o.clientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := o.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("token request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	// Both providers answer a revoked or expired refresh token with 400 invalid_grant
+	if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("%w: %s", ErrAuthRejected, strings.TrimSpace(string(body)))
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("token request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var tok tokenResponse
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return fmt.Errorf("invalid token response: %v", err)
+	}
+	conn.AccessToken = tok.AccessToken
+	if tok.RefreshToken != "" {
+		conn.RefreshToken = tok.RefreshToken
+	}
+	conn.ExpiresAt = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+	return nil
+}
+
+// doJSON sends an authenticated API call and decodes the JSON response into out
+func (o oauthClient) doJSON(ctx context.Context, method, endpoint string, headers map[string]string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := o.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return ErrAuthRejected
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s failed with status %d: %s", method, endpoint, resp.StatusCode, truncate(strings.TrimSpace(string(respBody)), 500))
+	}
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// paymentStatus derives the CRM payment state from invoice amounts
+func paymentStatus(total, due float64) string {
+	switch {
+	case due <= 0 && total > 0:
+		return PaymentPaid
+	case due < total:
+		return PaymentPartial
+	}
+	return PaymentUnpaid
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
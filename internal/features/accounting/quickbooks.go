@@ -0,0 +1,226 @@
+package accounting
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	quickBooksAuthURL    = "https://appcenter.intuit.com/connect/oauth2"
+	quickBooksScope      = "com.intuit.quickbooks.accounting"
+	quickBooksAPI        = "https://quickbooks.api.intuit.com"
+	quickBooksSandboxAPI = "https://sandbox-quickbooks.api.intuit.com"
+	quickBooksPageSize   = 1000
+)
+
+// quickBooks pushes invoices and estimates (sales orders) to QuickBooks Online
+type quickBooks struct {
+	oauth   oauthClient
+	sandbox bool
+}
+
+type qbRef struct {
+	Value string `json:"value"`
+	Name  string `json:"name,omitempty"`
+}
+
+type qbLine struct {
+	DetailType          string     `json:"DetailType"`
+	Amount              float64    `json:"Amount"`
+	Description         string     `json:"Description,omitempty"`
+	SalesItemLineDetail qbLineItem `json:"SalesItemLineDetail"`
+}
+
+type qbLineItem struct {
+	ItemRef   qbRef   `json:"ItemRef"`
+	Qty       float64 `json:"Qty"`
+	UnitPrice float64 `json:"UnitPrice"`
+}
+
+type qbTxn struct {
+	ID          string   `json:"Id,omitempty"`
+	SyncToken   string   `json:"SyncToken,omitempty"`
+	Sparse      bool     `json:"sparse,omitempty"`
+	CustomerRef qbRef    `json:"CustomerRef"`
+	DocNumber   string   `json:"DocNumber,omitempty"`
+	TxnDate     string   `json:"TxnDate,omitempty"`
+	DueDate     string   `json:"DueDate,omitempty"`
+	ExpDate     string   `json:"ExpirationDate,omitempty"`
+	CurrencyRef *qbRef   `json:"CurrencyRef,omitempty"`
+	Line        []qbLine `json:"Line"`
+
+	TotalAmt float64 `json:"TotalAmt,omitempty"`
+	Balance  float64 `json:"Balance,omitempty"`
+}
+
+func (q *quickBooks) baseURL(conn *Connection) string {
+	base := quickBooksAPI
+	if q.sandbox {
+		base = quickBooksSandboxAPI
+	}
+	return fmt.Sprintf("%s/v3/company/%s", base, conn.CompanyID)
+}
+
+func (q *quickBooks) headers(conn *Connection) map[string]string {
+	return map[string]string{"Authorization": "Bearer " + conn.AccessToken}
+}
+
+func (q *quickBooks) AuthURL(state, redirectURI string) string {
+	return q.oauth.authURL(quickBooksAuthURL, quickBooksScope, state, redirectURI)
+}
+
+func (q *quickBooks) Exchange(ctx context.Context, code, redirectURI string, callback map[string]string, conn *Connection) error {
+	// The company is chosen on Intuit's consent screen and returned as realmId
+	realmID := callback["realmId"]
+	if realmID == "" {
+		return fmt.Errorf("missing realmId in QuickBooks callback")
+	}
+	if err := q.oauth.exchange(ctx, code, redirectURI, conn); err != nil {
+		return err
+	}
+	conn.CompanyID = realmID
+
+	var info struct {
+		CompanyInfo struct {
+			CompanyName string `json:"CompanyName"`
+		} `json:"CompanyInfo"`
+	}
+	if err := q.oauth.doJSON(ctx, http.MethodGet, q.baseURL(conn)+"/companyinfo/"+realmID, q.headers(conn), nil, &info); err == nil {
+		conn.CompanyName = info.CompanyInfo.CompanyName
+	}
+	return nil
+}
+
+func (q *quickBooks) Refresh(ctx context.Context, conn *Connection) error {
+	return q.oauth.refresh(ctx, conn)
+}
+
+func (q *quickBooks) query(ctx context.Context, conn *Connection, statement string, out any) error {
+	endpoint := q.baseURL(conn) + "/query?" + url.Values{"query": {statement}}.Encode()
+	return q.oauth.doJSON(ctx, http.MethodGet, endpoint, q.headers(conn), nil, out)
+}
+
+// customerID finds the customer by display name, creating it when missing
+func (q *quickBooks) customerID(ctx context.Context, conn *Connection, name, email string) (string, error) {
+	var found struct {
+		QueryResponse struct {
+			Customer []struct {
+				ID string `json:"Id"`
+			} `json:"Customer"`
+		} `json:"QueryResponse"`
+	}
+	escaped := strings.ReplaceAll(name, "'", `\'`)
+	if err := q.query(ctx, conn, fmt.Sprintf("select Id from Customer where DisplayName = '%s'", escaped), &found); err != nil {
+		return "", err
+	}
+	if len(found.QueryResponse.Customer) > 0 {
+		return found.QueryResponse.Customer[0].ID, nil
+	}
+
+	customer := map[string]any{"DisplayName": name}
+	if email != "" {
+		customer["PrimaryEmailAddr"] = map[string]string{"Address": email}
+	}
+	var created struct {
+		Customer struct {
+			ID string `json:"Id"`
+		} `json:"Customer"`
+	}
+	if err := q.oauth.doJSON(ctx, http.MethodPost, q.baseURL(conn)+"/customer", q.headers(conn), customer, &created); err != nil {
+		return "", fmt.Errorf("failed to create customer: %w", err)
+	}
+	return created.Customer.ID, nil
+}
+
+func (q *quickBooks) Push(ctx context.Context, conn *Connection, doc Document, mapping *EntityMapping) (string, string, error) {
+	customerID, err := q.customerID(ctx, conn, doc.CustomerName, doc.CustomerEmail)
+	if err != nil {
+		return "", "", err
+	}
+
+	itemID := conn.Options["item_id"]
+	if itemID == "" {
+		itemID = "1" // "Services" in new QuickBooks companies
+	}
+	txn := qbTxn{
+		CustomerRef: qbRef{Value: customerID},
+		DocNumber:   doc.Number,
+	}
+	if !doc.Date.IsZero() {
+		txn.TxnDate = doc.Date.Format("2006-01-02")
+	}
+	if !doc.DueDate.IsZero() {
+		if doc.Type == DocSalesOrder {
+			txn.ExpDate = doc.DueDate.Format("2006-01-02")
+		} else {
+			txn.DueDate = doc.DueDate.Format("2006-01-02")
+		}
+	}
+	if doc.Currency != "" {
+		txn.CurrencyRef = &qbRef{Value: doc.Currency}
+	}
+	for _, l := range doc.Lines {
+		txn.Line = append(txn.Line, qbLine{
+			DetailType:  "SalesItemLineDetail",
+			Amount:      roundCents(l.Quantity * l.UnitPrice),
+			Description: l.Description,
+			SalesItemLineDetail: qbLineItem{
+				ItemRef:   qbRef{Value: itemID},
+				Qty:       l.Quantity,
+				UnitPrice: l.UnitPrice,
+			},
+		})
+	}
+	if mapping != nil {
+		txn.ID = mapping.ExternalID
+		txn.SyncToken = mapping.SyncToken
+		txn.Sparse = true
+	}
+
+	entity, key := "invoice", "Invoice"
+	if doc.Type == DocSalesOrder {
+		entity, key = "estimate", "Estimate"
+	}
+	var resp map[string]qbTxn
+	if err := q.oauth.doJSON(ctx, http.MethodPost, q.baseURL(conn)+"/"+entity, q.headers(conn), txn, &resp); err != nil {
+		return "", "", err
+	}
+	saved := resp[key]
+	return saved.ID, saved.SyncToken, nil
+}
+
+func (q *quickBooks) PaymentUpdates(ctx context.Context, conn *Connection, since time.Time) (map[string]PaymentInfo, error) {
+	updates := map[string]PaymentInfo{}
+	for start := 1; ; start += quickBooksPageSize {
+		var page struct {
+			QueryResponse struct {
+				Invoice []qbTxn `json:"Invoice"`
+			} `json:"QueryResponse"`
+		}
+		statement := fmt.Sprintf("select * from Invoice where Metadata.LastUpdatedTime > '%s' startposition %d maxresults %d",
+			since.UTC().Format(time.RFC3339), start, quickBooksPageSize)
+		if err := q.query(ctx, conn, statement, &page); err != nil {
+			return nil, err
+		}
+
+		for _, inv := range page.QueryResponse.Invoice {
+			info := PaymentInfo{
+				Status:     paymentStatus(inv.TotalAmt, inv.Balance),
+				AmountPaid: roundCents(inv.TotalAmt - inv.Balance),
+				AmountDue:  inv.Balance,
+			}
+			// Voiding zeroes the invoice in QuickBooks
+			if inv.TotalAmt == 0 && inv.Balance == 0 {
+				info.Status = PaymentVoided
+			}
+			updates[inv.ID] = info
+		}
+		if len(page.QueryResponse.Invoice) < quickBooksPageSize {
+			return updates, nil
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package accounting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ConnectionRepository interface {
+	Save(ctx context.Context, conn *Connection) error
+	Get(ctx context.Context, id string) (*Connection, error)
+	// GetByProvider returns nil without error when the tenant has no connection
+	GetByProvider(ctx context.Context, provider Provider) (*Connection, error)
+	List(ctx context.Context) ([]Connection, error)
+	Delete(ctx context.Context, id string) error
+
+	// ListActive returns connected, active connections across all tenants.
+	// Used by the scheduler.
+	ListActive(ctx context.Context) ([]Connection, error)
+}
+
+type ConnectionRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewConnectionRepository(db *database.MongodbDB) ConnectionRepository {
+	return &ConnectionRepositoryImpl{
+		collection: db.DB.Collection("accounting_connections"),
+	}
+}
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+func (r *ConnectionRepositoryImpl) Save(ctx context.Context, conn *Connection) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if conn.ID.IsZero() {
+		conn.ID = primitive.NewObjectID()
+		conn.CreatedAt = now
+	}
+	conn.TenantID = tenantID
+	conn.UpdatedAt = now
+
+	_, err = r.collection.ReplaceOne(ctx,
+		bson.M{"_id": conn.ID, "tenant_id": tenantID},
+		conn,
+		options.Replace().SetUpsert(true))
+	return err
+}
+
+func (r *ConnectionRepositoryImpl) Get(ctx context.Context, id string) (*Connection, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn Connection
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID}).Decode(&conn); err != nil {
+		return nil, err
+	}
+	return &conn, nil
+}
+
+func (r *ConnectionRepositoryImpl) GetByProvider(ctx context.Context, provider Provider) (*Connection, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn Connection
+	err = r.collection.FindOne(ctx, bson.M{"tenant_id": tenantID, "provider": provider}).Decode(&conn)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &conn, nil
+}
+
+func (r *ConnectionRepositoryImpl) List(ctx context.Context) ([]Connection, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"tenant_id": tenantID}, options.Find().SetSort(bson.M{"provider": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	conns := []Connection{}
+	if err := cursor.All(ctx, &conns); err != nil {
+		return nil, err
+	}
+	return conns, nil
+}
+
+func (r *ConnectionRepositoryImpl) Delete(ctx context.Context, id string) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID})
+	return err
+}
+
+func (r *ConnectionRepositoryImpl) ListActive(ctx context.Context) ([]Connection, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"is_active": true, "status": StatusConnected})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var conns []Connection
+	if err := cursor.All(ctx, &conns); err != nil {
+		return nil, err
+	}
+	return conns, nil
+}
+
+type MappingRepository interface {
+	// GetMany returns mappings keyed by record ID
+	GetMany(ctx context.Context, connectionID primitive.ObjectID, moduleName string, recordIDs []string) (map[string]EntityMapping, error)
+	Save(ctx context.Context, mapping *EntityMapping) error
+	ListByConnection(ctx context.Context, connectionID primitive.ObjectID, limit, offset int64) ([]EntityMapping, error)
+	// FindByExternalIDs returns the mappings of the given provider documents
+	FindByExternalIDs(ctx context.Context, connectionID primitive.ObjectID, externalIDs []string) ([]EntityMapping, error)
+	DeleteByConnection(ctx context.Context, connectionID primitive.ObjectID) error
+}
+
+type MappingRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewMappingRepository(db *database.MongodbDB) MappingRepository {
+	return &MappingRepositoryImpl{
+		collection: db.DB.Collection("accounting_entity_mappings"),
+	}
+}
+
+func (r *MappingRepositoryImpl) GetMany(ctx context.Context, connectionID primitive.ObjectID, moduleName string, recordIDs []string) (map[string]EntityMapping, error) {
+	result := map[string]EntityMapping{}
+	if len(recordIDs) == 0 {
+		return result, nil
+	}
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"tenant_id":     tenantID,
+		"connection_id": connectionID,
+		"module_name":   moduleName,
+		"record_id":     bson.M{"$in": recordIDs},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mappings []EntityMapping
+	if err := cursor.All(ctx, &mappings); err != nil {
+		return nil, err
+	}
+	for _, m := range mappings {
+		result[m.RecordID] = m
+	}
+	return result, nil
+}
+
+func (r *MappingRepositoryImpl) Save(ctx context.Context, mapping *EntityMapping) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if mapping.ID.IsZero() {
+		mapping.ID = primitive.NewObjectID()
+	}
+	mapping.TenantID = tenantID
+	mapping.UpdatedAt = time.Now()
+
+	_, err = r.collection.ReplaceOne(ctx,
+		bson.M{"tenant_id": tenantID, "connection_id": mapping.ConnectionID, "module_name": mapping.ModuleName, "record_id": mapping.RecordID},
+		mapping,
+		options.Replace().SetUpsert(true))
+	return err
+}
+
+func (r *MappingRepositoryImpl) ListByConnection(ctx context.Context, connectionID primitive.ObjectID, limit, offset int64) ([]EntityMapping, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.M{"updated_at": -1}).SetLimit(limit).SetSkip(offset)
+	cursor, err := r.collection.Find(ctx, bson.M{"tenant_id": tenantID, "connection_id": connectionID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	mappings := []EntityMapping{}
+	if err := cursor.All(ctx, &mappings); err != nil {
+		return nil, err
+	}
+	return mappings, nil
+}
+
+func (r *MappingRepositoryImpl) FindByExternalIDs(ctx context.Context, connectionID primitive.ObjectID, externalIDs []string) ([]EntityMapping, error) {
+	if len(externalIDs) == 0 {
+		return nil, nil
+	}
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"tenant_id":     tenantID,
+		"connection_id": connectionID,
+		"doc_type":      DocInvoice,
+		"external_id":   bson.M{"$in": externalIDs},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mappings []EntityMapping
+	if err := cursor.All(ctx, &mappings); err != nil {
+		return nil, err
+	}
+	return mappings, nil
+}
+
+func (r *MappingRepositoryImpl) DeleteByConnection(ctx context.Context, connectionID primitive.ObjectID) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.DeleteMany(ctx, bson.M{"tenant_id": tenantID, "connection_id": connectionID})
+	return err
+}
@@ -0,0 +1,584 @@
+package accounting
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/config"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/record"
+	data_sync "go-crm/internal/features/sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// SyncSchedule is how often active connections are synced incrementally
+	SyncSchedule = "*/15 * * * *"
+
+	stateTTL        = 10 * time.Minute
+	refreshMargin   = 5 * time.Minute
+	recordPageSize  = int64(200)
+	lookupBatchSize = 500
+	maxLoggedErrors = 200
+	// Provider clocks and our own are compared when pulling payment changes
+	pullOverlap = 5 * time.Minute
+)
+
+var (
+	ErrProviderUnavailable = errors.New("accounting provider is not configured")
+	ErrInvalidState        = errors.New("invalid or expired authorization state")
+	ErrSyncRunning         = apperr.Conflict("a sync is already running for this connection")
+)
+
+type AccountingService interface {
+	// Providers lists the providers configured on this server
+	Providers() []Provider
+	// Connect returns the provider consent URL that starts the OAuth flow
+	Connect(ctx context.Context, provider Provider, userID primitive.ObjectID) (string, error)
+	// HandleCallback completes the OAuth flow; the tenant comes from the signed state
+	HandleCallback(ctx context.Context, provider Provider, params map[string]string) (*Connection, error)
+
+	ListConnections(ctx context.Context) ([]Connection, error)
+	GetConnection(ctx context.Context, id string) (*Connection, error)
+	UpdateConnection(ctx context.Context, id string, req UpdateConnectionRequest) (*Connection, error)
+	Disconnect(ctx context.Context, id string) error
+
+	RunSync(ctx context.Context, id string) (*data_sync.SyncLog, error)
+	// SyncAll runs every active connection across tenants. Used by the scheduler.
+	SyncAll(ctx context.Context) error
+	ListLogs(ctx context.Context, id string, limit int64) ([]data_sync.SyncLog, error)
+	ListMappings(ctx context.Context, id string, limit, offset int64) ([]EntityMapping, error)
+}
+
+type AccountingServiceImpl struct {
+	ConnectionRepo ConnectionRepository
+	MappingRepo    MappingRepository
+	LogRepo        data_sync.SyncLogRepository
+	RecordRepo     record.RecordRepository
+	ModuleRepo     module.ModuleRepository
+	AuditService   audit.AuditService
+	Config         *config.Config
+
+	providers map[Provider]provider
+	running   sync.Map // connection ID -> struct{}
+}
+
+func NewAccountingService(
+	connectionRepo ConnectionRepository,
+	mappingRepo MappingRepository,
+	logRepo data_sync.SyncLogRepository,
+	recordRepo record.RecordRepository,
+	moduleRepo module.ModuleRepository,
+	auditService audit.AuditService,
+	cfg *config.Config,
+) AccountingService {
+	return &AccountingServiceImpl{
+		ConnectionRepo: connectionRepo,
+		MappingRepo:    mappingRepo,
+		LogRepo:        logRepo,
+		RecordRepo:     recordRepo,
+		ModuleRepo:     moduleRepo,
+		AuditService:   auditService,
+		Config:         cfg,
+		providers:      newProviders(cfg),
+	}
+}
+
+func (s *AccountingServiceImpl) Providers() []Provider {
+	out := []Provider{}
+	for _, p := range []Provider{ProviderQuickBooks, ProviderXero} {
+		if _, ok := s.providers[p]; ok {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func (s *AccountingServiceImpl) redirectURI(p Provider) string {
+	return fmt.Sprintf("%s/api/accounting/oauth/%s/callback", strings.TrimRight(s.Config.PublicURL, "/"), p)
+}
+
+func (s *AccountingServiceImpl) Connect(ctx context.Context, p Provider, userID primitive.ObjectID) (string, error) {
+	impl, ok := s.providers[p]
+	if !ok {
+		return "", ErrProviderUnavailable
+	}
+	tenantID, ok := ctx.Value(common_models.TenantIDKey).(string)
+	if !ok || tenantID == "" {
+		return "", errors.New("tenant ID not found in context")
+	}
+	return impl.AuthURL(s.signState(tenantID, userID.Hex(), p, time.Now()), s.redirectURI(p)), nil
+}
+
+// signState binds the OAuth round trip to the tenant and user that started it
+func (s *AccountingServiceImpl) signState(tenantID, userID string, p Provider, at time.Time) string {
+	payload := fmt.Sprintf("%s:%s:%s:%d", tenantID, userID, p, at.Unix())
+	mac := hmac.New(sha256.New, []byte(s.Config.JWTSecret))
+	fmt.Fprintf(mac, "accounting:%s", payload)
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *AccountingServiceImpl) verifyState(state string, p Provider) (string, primitive.ObjectID, error) {
+	encoded, _, ok := strings.Cut(state, ".")
+	if !ok {
+		return "", primitive.NilObjectID, ErrInvalidState
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", primitive.NilObjectID, ErrInvalidState
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 4 || parts[2] != string(p) {
+		return "", primitive.NilObjectID, ErrInvalidState
+	}
+	ts, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil || time.Since(time.Unix(ts, 0)) > stateTTL {
+		return "", primitive.NilObjectID, ErrInvalidState
+	}
+	expected := s.signState(parts[0], parts[1], p, time.Unix(ts, 0))
+	if !hmac.Equal([]byte(expected), []byte(state)) {
+		return "", primitive.NilObjectID, ErrInvalidState
+	}
+	if _, err := primitive.ObjectIDFromHex(parts[0]); err != nil {
+		return "", primitive.NilObjectID, ErrInvalidState
+	}
+	userID, _ := primitive.ObjectIDFromHex(parts[1])
+	return parts[0], userID, nil
+}
+
+func (s *AccountingServiceImpl) HandleCallback(ctx context.Context, p Provider, params map[string]string) (*Connection, error) {
+	impl, ok := s.providers[p]
+	if !ok {
+		return nil, ErrProviderUnavailable
+	}
+	tenantID, userID, err := s.verifyState(params["state"], p)
+	if err != nil {
+		return nil, err
+	}
+	if reason := params["error"]; reason != "" {
+		return nil, fmt.Errorf("authorization was not granted: %s", reason)
+	}
+	if params["code"] == "" {
+		return nil, errors.New("missing authorization code")
+	}
+	ctx = context.WithValue(ctx, common_models.TenantIDKey, tenantID)
+
+	conn, err := s.ConnectionRepo.GetByProvider(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if conn == nil {
+		conn = &Connection{Provider: p, IsActive: true, Modules: []ModuleMapping{}}
+	}
+	previousCompany := conn.CompanyID
+
+	if err := impl.Exchange(ctx, params["code"], s.redirectURI(p), params, conn); err != nil {
+		return nil, err
+	}
+	conn.Status = StatusConnected
+	conn.LastError = ""
+	conn.ConnectedBy = userID
+	if previousCompany != "" && previousCompany != conn.CompanyID {
+		// Document IDs of the old company mean nothing in the new one
+		if err := s.MappingRepo.DeleteByConnection(ctx, conn.ID); err != nil {
+			return nil, err
+		}
+		conn.LastSyncAt = time.Time{}
+	}
+	if err := s.ConnectionRepo.Save(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "accounting", string(p), map[string]common_models.Change{
+		"connection": {Old: previousCompany, New: conn.CompanyID},
+	})
+	return conn, nil
+}
+
+func (s *AccountingServiceImpl) ListConnections(ctx context.Context) ([]Connection, error) {
+	return s.ConnectionRepo.List(ctx)
+}
+
+func (s *AccountingServiceImpl) GetConnection(ctx context.Context, id string) (*Connection, error) {
+	return s.ConnectionRepo.Get(ctx, id)
+}
+
+func (s *AccountingServiceImpl) UpdateConnection(ctx context.Context, id string, req UpdateConnectionRequest) (*Connection, error) {
+	conn, err := s.ConnectionRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	old := *conn
+
+	if req.Modules != nil {
+		seen := map[string]bool{}
+		for _, m := range req.Modules {
+			if err := validateMapping(m); err != nil {
+				return nil, err
+			}
+			if seen[m.ModuleName] {
+				return nil, fmt.Errorf("module %s is mapped more than once", m.ModuleName)
+			}
+			seen[m.ModuleName] = true
+			if _, err := s.ModuleRepo.FindByName(ctx, m.ModuleName); err != nil {
+				return nil, fmt.Errorf("module %s not found", m.ModuleName)
+			}
+		}
+		conn.Modules = req.Modules
+	}
+	if req.Options != nil {
+		conn.Options = req.Options
+	}
+	if req.IsActive != nil {
+		conn.IsActive = *req.IsActive
+	}
+	if err := s.ConnectionRepo.Save(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "accounting", string(conn.Provider), map[string]common_models.Change{
+		"modules":   {Old: old.Modules, New: conn.Modules},
+		"options":   {Old: old.Options, New: conn.Options},
+		"is_active": {Old: old.IsActive, New: conn.IsActive},
+	})
+	return conn, nil
+}
+
+// Disconnect removes the connection and its ID mappings; documents already
+// in the accounting system are left untouched
+func (s *AccountingServiceImpl) Disconnect(ctx context.Context, id string) error {
+	conn, err := s.ConnectionRepo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.ConnectionRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	_ = s.MappingRepo.DeleteByConnection(ctx, conn.ID)
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "accounting", string(conn.Provider), map[string]common_models.Change{
+		"connection": {Old: conn.CompanyID, New: "DELETED"},
+	})
+	return nil
+}
+
+func (s *AccountingServiceImpl) ListLogs(ctx context.Context, id string, limit int64) ([]data_sync.SyncLog, error) {
+	conn, err := s.ConnectionRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	return s.LogRepo.List(ctx, conn.ID.Hex(), limit)
+}
+
+func (s *AccountingServiceImpl) ListMappings(ctx context.Context, id string, limit, offset int64) ([]EntityMapping, error) {
+	conn, err := s.ConnectionRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	return s.MappingRepo.ListByConnection(ctx, conn.ID, limit, offset)
+}
+
+func (s *AccountingServiceImpl) RunSync(ctx context.Context, id string) (*data_sync.SyncLog, error) {
+	conn, err := s.ConnectionRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if conn.Status != StatusConnected {
+		return nil, errors.New("connection needs to be reconnected")
+	}
+	return s.syncConnection(context.WithoutCancel(ctx), conn)
+}
+
+func (s *AccountingServiceImpl) SyncAll(ctx context.Context) error {
+	conns, err := s.ConnectionRepo.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range conns {
+		conn := &conns[i]
+		if _, ok := s.providers[conn.Provider]; !ok || len(conn.Modules) == 0 {
+			continue
+		}
+		tenantCtx := context.WithValue(ctx, common_models.TenantIDKey, conn.TenantID.Hex())
+		if _, err := s.syncConnection(tenantCtx, conn); err != nil && !errors.Is(err, ErrSyncRunning) {
+			log.Printf("accounting: sync of %s connection %s failed: %v", conn.Provider, conn.ID.Hex(), err)
+		}
+	}
+	return nil
+}
+
+// syncRun collects the per-record outcome of one run
+type syncRun struct {
+	conn *Connection
+	impl provider
+	log  *data_sync.SyncLog
+}
+
+func (r *syncRun) fail(module, recordID, direction, operation string, err error) {
+	r.log.Failed++
+	if len(r.log.Errors) < maxLoggedErrors {
+		r.log.Errors = append(r.log.Errors, data_sync.SyncRecordError{
+			Module:    module,
+			RecordID:  recordID,
+			Direction: direction,
+			Operation: operation,
+			Error:     err.Error(),
+		})
+	}
+}
+
+func (s *AccountingServiceImpl) syncConnection(ctx context.Context, conn *Connection) (*data_sync.SyncLog, error) {
+	impl, ok := s.providers[conn.Provider]
+	if !ok {
+		return nil, ErrProviderUnavailable
+	}
+	if _, busy := s.running.LoadOrStore(conn.ID, struct{}{}); busy {
+		return nil, ErrSyncRunning
+	}
+	defer s.running.Delete(conn.ID)
+
+	startedAt := time.Now()
+	syncLog := &data_sync.SyncLog{
+		SyncSettingID: conn.ID,
+		StartTime:     startedAt,
+		Status:        "in_progress",
+	}
+	_ = s.LogRepo.Create(ctx, syncLog)
+
+	var syncError error
+	defer func() {
+		syncLog.EndTime = time.Now()
+		syncLog.ProcessedCount = syncLog.Pushed + syncLog.Pulled
+		switch {
+		case syncError != nil:
+			syncLog.Status = "failed"
+			syncLog.Error = syncError.Error()
+		case syncLog.Failed > 0:
+			syncLog.Status = "partial"
+		default:
+			syncLog.Status = "success"
+		}
+		_ = s.LogRepo.Update(ctx, syncLog)
+
+		// As with database sync, only a clean run advances the change cursor;
+		// mapping hashes keep successfully pushed records from being resent
+		if syncError == nil && syncLog.Failed == 0 {
+			conn.LastSyncAt = startedAt
+			conn.LastError = ""
+		} else if syncError != nil {
+			conn.LastError = syncError.Error()
+		}
+		if errors.Is(syncError, ErrAuthRejected) {
+			conn.Status = StatusError
+		}
+		_ = s.ConnectionRepo.Save(ctx, conn)
+
+		_ = s.AuditService.LogChange(ctx, common_models.AuditActionSync, "accounting", string(conn.Provider), map[string]common_models.Change{
+			"status": {New: syncLog.Status},
+			"pushed": {New: syncLog.Pushed},
+			"pulled": {New: syncLog.Pulled},
+			"failed": {New: syncLog.Failed},
+			"error":  {New: syncLog.Error},
+		})
+	}()
+
+	if time.Until(conn.ExpiresAt) < refreshMargin {
+		if syncError = impl.Refresh(ctx, conn); syncError != nil {
+			return syncLog, syncError
+		}
+		// Refresh tokens rotate, so the new pair is stored before it is used
+		if syncError = s.ConnectionRepo.Save(ctx, conn); syncError != nil {
+			return syncLog, syncError
+		}
+	}
+
+	run := &syncRun{conn: conn, impl: impl, log: syncLog}
+	for _, m := range conn.Modules {
+		if syncError = s.pushModule(ctx, run, m); syncError != nil {
+			syncError = fmt.Errorf("%s: %w", m.ModuleName, syncError)
+			return syncLog, syncError
+		}
+	}
+	if syncError = s.pullPayments(ctx, run, startedAt); syncError != nil {
+		return syncLog, syncError
+	}
+	return syncLog, nil
+}
+
+// pushModule sends records changed since the last clean run. A record whose
+// document is unchanged since its last push is skipped.
+func (s *AccountingServiceImpl) pushModule(ctx context.Context, run *syncRun, m ModuleMapping) error {
+	filters := bson.M{"updated_at": bson.M{"$gt": run.conn.LastSyncAt}}
+	for page := int64(0); ; page++ {
+		records, err := s.RecordRepo.List(ctx, m.ModuleName, filters, nil, recordPageSize, page*recordPageSize, "updated_at", 1)
+		if err != nil {
+			return fmt.Errorf("failed to fetch records on page %d: %v", page+1, err)
+		}
+
+		ids := make([]string, 0, len(records))
+		for _, rec := range records {
+			ids = append(ids, stringValue(rec["id"]))
+		}
+		mappings, err := s.MappingRepo.GetMany(ctx, run.conn.ID, m.ModuleName, ids)
+		if err != nil {
+			return err
+		}
+
+		for i, rec := range records {
+			if err := s.pushRecord(ctx, run, m, ids[i], rec, mappings); err != nil {
+				return err
+			}
+		}
+		if len(records) < int(recordPageSize) {
+			return nil
+		}
+	}
+}
+
+// pushRecord returns an error only when the whole run has to stop
+func (s *AccountingServiceImpl) pushRecord(ctx context.Context, run *syncRun, m ModuleMapping, id string, rec map[string]any, mappings map[string]EntityMapping) error {
+	if !shouldPush(rec, m) {
+		return nil
+	}
+	var existing *EntityMapping
+	operation := "create"
+	if mapping, ok := mappings[id]; ok {
+		existing = &mapping
+		operation = "update"
+	}
+
+	doc, err := buildDocument(rec, m)
+	if err != nil {
+		run.fail(m.ModuleName, id, data_sync.DirectionPush, operation, err)
+		return nil
+	}
+	hash := documentHash(doc)
+	if existing != nil && existing.Hash == hash {
+		run.log.Skipped++
+		return nil
+	}
+
+	externalID, syncToken, err := run.impl.Push(ctx, run.conn, doc, existing)
+	if errors.Is(err, ErrAuthRejected) {
+		return err
+	}
+	if err != nil {
+		run.fail(m.ModuleName, id, data_sync.DirectionPush, operation, err)
+		return nil
+	}
+
+	mapping := EntityMapping{
+		ConnectionID:  run.conn.ID,
+		Provider:      run.conn.Provider,
+		ModuleName:    m.ModuleName,
+		RecordID:      id,
+		DocType:       m.DocType,
+		PaymentStatus: PaymentUnpaid,
+	}
+	if existing != nil {
+		mapping = *existing
+	}
+	mapping.ExternalID = externalID
+	mapping.SyncToken = syncToken
+	mapping.Hash = hash
+	mapping.PushedAt = time.Now()
+	if err := s.MappingRepo.Save(ctx, &mapping); err != nil {
+		run.fail(m.ModuleName, id, data_sync.DirectionPush, operation, fmt.Errorf("pushed as %s but the mapping was not saved: %v", externalID, err))
+		return nil
+	}
+	run.log.Pushed++
+	return nil
+}
+
+// pullPayments writes the payment state of invoices changed in the
+// accounting system back to their records
+func (s *AccountingServiceImpl) pullPayments(ctx context.Context, run *syncRun, startedAt time.Time) error {
+	modules := map[string]ModuleMapping{}
+	for _, m := range run.conn.Modules {
+		if m.DocType == DocInvoice {
+			modules[m.ModuleName] = m
+		}
+	}
+	if len(modules) == 0 {
+		return nil
+	}
+
+	// On the first run every invoice was just pushed and is still unpaid
+	since := run.conn.LastSyncAt
+	if since.IsZero() {
+		since = startedAt
+	}
+	updates, err := run.impl.PaymentUpdates(ctx, run.conn, since.Add(-pullOverlap))
+	if err != nil {
+		return fmt.Errorf("failed to fetch payment updates: %w", err)
+	}
+
+	externalIDs := make([]string, 0, len(updates))
+	for id := range updates {
+		externalIDs = append(externalIDs, id)
+	}
+	for start := 0; start < len(externalIDs); start += lookupBatchSize {
+		end := min(start+lookupBatchSize, len(externalIDs))
+		mappings, err := s.MappingRepo.FindByExternalIDs(ctx, run.conn.ID, externalIDs[start:end])
+		if err != nil {
+			return err
+		}
+		for i := range mappings {
+			mapping := &mappings[i]
+			m, ok := modules[mapping.ModuleName]
+			if !ok {
+				continue
+			}
+			info := updates[mapping.ExternalID]
+			if info.Status == mapping.PaymentStatus && info.AmountPaid == mapping.AmountPaid && info.AmountDue == mapping.AmountDue {
+				continue
+			}
+
+			fields := map[string]any{}
+			if m.PaymentStatusField != "" {
+				fields[m.PaymentStatusField] = info.Status
+			}
+			if m.AmountPaidField != "" {
+				fields[m.AmountPaidField] = info.AmountPaid
+			}
+			if len(fields) > 0 {
+				if err := s.RecordRepo.Update(ctx, m.ModuleName, mapping.RecordID, fields); err != nil {
+					run.fail(m.ModuleName, mapping.RecordID, data_sync.DirectionPull, "payment", err)
+					continue
+				}
+			}
+
+			mapping.PaymentStatus = info.Status
+			mapping.AmountPaid = info.AmountPaid
+			mapping.AmountDue = info.AmountDue
+			mapping.CheckedAt = time.Now()
+			if err := s.MappingRepo.Save(ctx, mapping); err != nil {
+				run.fail(m.ModuleName, mapping.RecordID, data_sync.DirectionPull, "payment", err)
+				continue
+			}
+			run.log.Pulled++
+		}
+	}
+	return nil
+}
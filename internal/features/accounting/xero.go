@@ -0,0 +1,251 @@
+package accounting
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	xeroAuthURL        = "https://login.xero.com/identity/connect/authorize"
+	xeroScope          = "openid profile email accounting.transactions accounting.contacts offline_access"
+	xeroConnectionsURL = "https://api.xero.com/connections"
+	xeroAPI            = "https://api.xero.com/api.xro/2.0"
+	xeroPageSize       = 100
+)
+
+// xero pushes invoices and quotes (sales orders) to Xero
+type xero struct {
+	oauth oauthClient
+}
+
+type xeroContact struct {
+	ContactID    string `json:"ContactID,omitempty"`
+	Name         string `json:"Name,omitempty"`
+	EmailAddress string `json:"EmailAddress,omitempty"`
+}
+
+type xeroLine struct {
+	Description string  `json:"Description,omitempty"`
+	Quantity    float64 `json:"Quantity"`
+	UnitAmount  float64 `json:"UnitAmount"`
+	AccountCode string  `json:"AccountCode,omitempty"`
+}
+
+type xeroInvoice struct {
+	InvoiceID     string      `json:"InvoiceID,omitempty"`
+	Type          string      `json:"Type,omitempty"`
+	Contact       xeroContact `json:"Contact"`
+	InvoiceNumber string      `json:"InvoiceNumber,omitempty"`
+	Date          string      `json:"Date,omitempty"`
+	DueDate       string      `json:"DueDate,omitempty"`
+	CurrencyCode  string      `json:"CurrencyCode,omitempty"`
+	Status        string      `json:"Status,omitempty"`
+	LineItems     []xeroLine  `json:"LineItems,omitempty"`
+
+	Total      float64 `json:"Total,omitempty"`
+	AmountDue  float64 `json:"AmountDue,omitempty"`
+	AmountPaid float64 `json:"AmountPaid,omitempty"`
+}
+
+type xeroQuote struct {
+	QuoteID      string      `json:"QuoteID,omitempty"`
+	Contact      xeroContact `json:"Contact"`
+	QuoteNumber  string      `json:"QuoteNumber,omitempty"`
+	Date         string      `json:"Date,omitempty"`
+	ExpiryDate   string      `json:"ExpiryDate,omitempty"`
+	CurrencyCode string      `json:"CurrencyCode,omitempty"`
+	Status       string      `json:"Status,omitempty"`
+	LineItems    []xeroLine  `json:"LineItems,omitempty"`
+}
+
+func (x *xero) headers(conn *Connection) map[string]string {
+	return map[string]string{
+		"Authorization":  "Bearer " + conn.AccessToken,
+		"xero-tenant-id": conn.CompanyID,
+	}
+}
+
+func (x *xero) AuthURL(state, redirectURI string) string {
+	return x.oauth.authURL(xeroAuthURL, xeroScope, state, redirectURI)
+}
+
+func (x *xero) Exchange(ctx context.Context, code, redirectURI string, callback map[string]string, conn *Connection) error {
+	if err := x.oauth.exchange(ctx, code, redirectURI, conn); err != nil {
+		return err
+	}
+
+	// Xero returns the organisations the user authorised; the first one is the
+	// one just connected since consent is requested per organisation
+	var orgs []struct {
+		TenantID   string `json:"tenantId"`
+		TenantName string `json:"tenantName"`
+		TenantType string `json:"tenantType"`
+	}
+	headers := map[string]string{"Authorization": "Bearer " + conn.AccessToken}
+	if err := x.oauth.doJSON(ctx, http.MethodGet, xeroConnectionsURL, headers, nil, &orgs); err != nil {
+		return fmt.Errorf("failed to list Xero organisations: %w", err)
+	}
+	for _, org := range orgs {
+		if org.TenantType == "" || org.TenantType == "ORGANISATION" {
+			conn.CompanyID = org.TenantID
+			conn.CompanyName = org.TenantName
+			return nil
+		}
+	}
+	return fmt.Errorf("no Xero organisation was authorised")
+}
+
+func (x *xero) Refresh(ctx context.Context, conn *Connection) error {
+	return x.oauth.refresh(ctx, conn)
+}
+
+// contact finds the contact by name, creating it when missing. Xero rejects
+// a second contact with the same name.
+func (x *xero) contact(ctx context.Context, conn *Connection, name, email string) (xeroContact, error) {
+	var found struct {
+		Contacts []xeroContact `json:"Contacts"`
+	}
+	where := fmt.Sprintf(`Name=="%s"`, strings.ReplaceAll(name, `"`, `\"`))
+	endpoint := xeroAPI + "/Contacts?" + url.Values{"where": {where}}.Encode()
+	if err := x.oauth.doJSON(ctx, http.MethodGet, endpoint, x.headers(conn), nil, &found); err != nil {
+		return xeroContact{}, err
+	}
+	if len(found.Contacts) > 0 {
+		return xeroContact{ContactID: found.Contacts[0].ContactID}, nil
+	}
+
+	var created struct {
+		Contacts []xeroContact `json:"Contacts"`
+	}
+	body := map[string][]xeroContact{"Contacts": {{Name: name, EmailAddress: email}}}
+	if err := x.oauth.doJSON(ctx, http.MethodPost, xeroAPI+"/Contacts", x.headers(conn), body, &created); err != nil {
+		return xeroContact{}, fmt.Errorf("failed to create contact: %w", err)
+	}
+	if len(created.Contacts) == 0 {
+		return xeroContact{}, fmt.Errorf("failed to create contact: empty response")
+	}
+	return xeroContact{ContactID: created.Contacts[0].ContactID}, nil
+}
+
+func (x *xero) Push(ctx context.Context, conn *Connection, doc Document, mapping *EntityMapping) (string, string, error) {
+	contact, err := x.contact(ctx, conn, doc.CustomerName, doc.CustomerEmail)
+	if err != nil {
+		return "", "", err
+	}
+
+	lines := make([]xeroLine, 0, len(doc.Lines))
+	for _, l := range doc.Lines {
+		lines = append(lines, xeroLine{
+			Description: l.Description,
+			Quantity:    l.Quantity,
+			UnitAmount:  l.UnitPrice,
+			AccountCode: conn.Options["account_code"],
+		})
+	}
+	date, due := "", ""
+	if !doc.Date.IsZero() {
+		date = doc.Date.Format("2006-01-02")
+	}
+	if !doc.DueDate.IsZero() {
+		due = doc.DueDate.Format("2006-01-02")
+	}
+	externalID := ""
+	if mapping != nil {
+		externalID = mapping.ExternalID
+	}
+
+	// POST updates the document when its ID is set and creates it otherwise
+	if doc.Type == DocSalesOrder {
+		quote := xeroQuote{
+			QuoteID:      externalID,
+			Contact:      contact,
+			QuoteNumber:  doc.Number,
+			Date:         date,
+			ExpiryDate:   due,
+			CurrencyCode: doc.Currency,
+			LineItems:    lines,
+		}
+		if externalID == "" {
+			quote.Status = "DRAFT"
+		}
+		var resp struct {
+			Quotes []xeroQuote `json:"Quotes"`
+		}
+		if err := x.oauth.doJSON(ctx, http.MethodPost, xeroAPI+"/Quotes", x.headers(conn), map[string][]xeroQuote{"Quotes": {quote}}, &resp); err != nil {
+			return "", "", err
+		}
+		if len(resp.Quotes) == 0 {
+			return "", "", fmt.Errorf("empty response from Xero")
+		}
+		return resp.Quotes[0].QuoteID, "", nil
+	}
+
+	invoice := xeroInvoice{
+		InvoiceID:     externalID,
+		Type:          "ACCREC",
+		Contact:       contact,
+		InvoiceNumber: doc.Number,
+		Date:          date,
+		DueDate:       due,
+		CurrencyCode:  doc.Currency,
+		LineItems:     lines,
+	}
+	if externalID == "" {
+		// Only authorised invoices can take payments
+		invoice.Status = conn.Options["invoice_status"]
+		if invoice.Status == "" {
+			invoice.Status = "AUTHORISED"
+		}
+	}
+	var resp struct {
+		Invoices []xeroInvoice `json:"Invoices"`
+	}
+	if err := x.oauth.doJSON(ctx, http.MethodPost, xeroAPI+"/Invoices", x.headers(conn), map[string][]xeroInvoice{"Invoices": {invoice}}, &resp); err != nil {
+		return "", "", err
+	}
+	if len(resp.Invoices) == 0 {
+		return "", "", fmt.Errorf("empty response from Xero")
+	}
+	return resp.Invoices[0].InvoiceID, "", nil
+}
+
+func (x *xero) PaymentUpdates(ctx context.Context, conn *Connection, since time.Time) (map[string]PaymentInfo, error) {
+	updates := map[string]PaymentInfo{}
+	headers := x.headers(conn)
+	headers["If-Modified-Since"] = since.UTC().Format("2006-01-02T15:04:05")
+
+	for page := 1; ; page++ {
+		q := url.Values{}
+		q.Set("where", `Type=="ACCREC"`)
+		q.Set("page", fmt.Sprint(page))
+		q.Set("summaryOnly", "true")
+		var resp struct {
+			Invoices []xeroInvoice `json:"Invoices"`
+		}
+		if err := x.oauth.doJSON(ctx, http.MethodGet, xeroAPI+"/Invoices?"+q.Encode(), headers, nil, &resp); err != nil {
+			return nil, err
+		}
+
+		for _, inv := range resp.Invoices {
+			info := PaymentInfo{
+				Status:     paymentStatus(inv.Total, inv.AmountDue),
+				AmountPaid: inv.AmountPaid,
+				AmountDue:  inv.AmountDue,
+			}
+			switch inv.Status {
+			case "PAID":
+				info.Status = PaymentPaid
+			case "VOIDED", "DELETED":
+				info.Status = PaymentVoided
+			}
+			updates[inv.InvoiceID] = info
+		}
+		if len(resp.Invoices) < xeroPageSize {
+			return updates, nil
+		}
+	}
+}
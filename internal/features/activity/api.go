@@ -23,6 +23,13 @@ func NewActivityApi(activityController *ActivityController, roleService role.Rol
 }
 
 func (api *ActivityApi) Setup(app *fiber.App) {
+	// Calendar clients authenticate with the feed token only
+	app.Get("/api/activities/feeds/:token", api.ActivityController.CalendarFeed)
+
 	group := app.Group("/api/activities", middleware.AuthMiddleware(api.Config.SkipAuth))
 	group.Get("/calendar", api.ActivityController.GetCalendarEvents)
+	group.Get("/calendar/feed", api.ActivityController.GetCalendarFeed)
+	group.Put("/calendar/feed", api.ActivityController.UpdateCalendarFeed)
+	group.Post("/calendar/feed/rotate", api.ActivityController.RotateCalendarFeed)
+	group.Delete("/calendar/feed", api.ActivityController.RevokeCalendarFeed)
 }
@@ -1,8 +1,12 @@
 package activity
 
 import (
+	"errors"
+	"strings"
 	"time"
 
+	"go-crm/internal/common/session"
+
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -51,3 +55,119 @@ func (c *ActivityController) GetCalendarEvents(ctx *fiber.Ctx) error {
 
 	return ctx.JSON(events)
 }
+
+// GetCalendarFeed godoc
+// @Summary      Get calendar feed
+// @Description  Subscription URL of the current user's iCal feed of tasks, calls and meetings; created on first request
+// @Tags         activity
+// @Produce      json
+// @Success      200    {object}  CalendarFeedInfo
+// @Router       /api/activities/calendar/feed [get]
+func (c *ActivityController) GetCalendarFeed(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	info, err := c.ActivityService.GetCalendarFeed(ctx.UserContext(), userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(info)
+}
+
+// UpdateCalendarFeed godoc
+// @Summary      Update calendar feed
+// @Description  Set the IANA timezone calendar clients should display the feed in
+// @Tags         activity
+// @Accept       json
+// @Produce      json
+// @Param        request  body      CalendarFeedRequest  true  "Feed settings"
+// @Success      200      {object}  CalendarFeedInfo
+// @Failure      400      {object}  map[string]string
+// @Router       /api/activities/calendar/feed [put]
+func (c *ActivityController) UpdateCalendarFeed(ctx *fiber.Ctx) error {
+	var req CalendarFeedRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	info, err := c.ActivityService.UpdateCalendarFeed(ctx.UserContext(), req, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(info)
+}
+
+// RotateCalendarFeed godoc
+// @Summary      Rotate calendar feed URL
+// @Description  Issue a new feed token; existing subscriptions stop working
+// @Tags         activity
+// @Produce      json
+// @Success      200    {object}  CalendarFeedInfo
+// @Router       /api/activities/calendar/feed/rotate [post]
+func (c *ActivityController) RotateCalendarFeed(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	info, err := c.ActivityService.RotateCalendarFeed(ctx.UserContext(), userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(info)
+}
+
+// RevokeCalendarFeed godoc
+// @Summary      Revoke calendar feed
+// @Tags         activity
+// @Success      200    {object}  map[string]string
+// @Router       /api/activities/calendar/feed [delete]
+func (c *ActivityController) RevokeCalendarFeed(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if err := c.ActivityService.RevokeCalendarFeed(ctx.UserContext(), userID); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Calendar feed revoked"})
+}
+
+// CalendarFeed godoc
+// @Summary      iCal feed
+// @Description  Public, token-authenticated iCalendar feed for calendar subscriptions. Tasks are VTODOs unless tasks=events.
+// @Tags         activity
+// @Produce      text/calendar
+// @Param        token  path      string  true   "Feed token (optionally with .ics)"
+// @Param        tasks  query     string  false  "Set to events to emit tasks as VEVENTs"
+// @Success      200    {string}  string
+// @Failure      404    {object}  map[string]string
+// @Router       /api/activities/feeds/{token} [get]
+func (c *ActivityController) CalendarFeed(ctx *fiber.Ctx) error {
+	token := strings.TrimSuffix(ctx.Params("token"), ".ics")
+
+	body, err := c.ActivityService.RenderCalendarFeed(ctx.UserContext(), token, ctx.Query("tasks") == "events")
+	if errors.Is(err, ErrFeedNotFound) {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to build calendar feed"})
+	}
+
+	ctx.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	ctx.Set(fiber.HeaderContentDisposition, `inline; filename="calendar.ics"`)
+	ctx.Set(fiber.HeaderCacheControl, "private, max-age=300")
+	return ctx.Send(body)
+}
@@ -0,0 +1,129 @@
+package activity
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// calendarItem is one task, call or meeting as it appears in a feed
+type calendarItem struct {
+	UID         string
+	Todo        bool // VTODO instead of VEVENT
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time // DUE for todos
+	End         time.Time // zero for todos and point-in-time events
+	AllDay      bool
+	Status      string
+	Priority    int
+	URL         string
+}
+
+const icalTimeLayout = "20060102T150405Z"
+const icalDateLayout = "20060102"
+
+// renderICal writes an RFC 5545 calendar. Timed values are emitted in UTC so
+// no VTIMEZONE definitions are needed; X-WR-TIMEZONE tells clients which zone
+// to display the feed in. All-day values are floating dates.
+func renderICal(name, timezone string, items []calendarItem, now time.Time) []byte {
+	w := &icalWriter{}
+	w.prop("BEGIN", "VCALENDAR")
+	w.prop("VERSION", "2.0")
+	w.prop("PRODID", "-//go-crm//Calendar Feed//EN")
+	w.prop("CALSCALE", "GREGORIAN")
+	w.prop("METHOD", "PUBLISH")
+	w.prop("X-WR-CALNAME", escapeText(name))
+	if timezone != "" {
+		w.prop("X-WR-TIMEZONE", timezone)
+	}
+	w.prop("REFRESH-INTERVAL;VALUE=DURATION", "PT15M")
+	w.prop("X-PUBLISHED-TTL", "PT15M")
+
+	stamp := now.UTC().Format(icalTimeLayout)
+	for _, item := range items {
+		component := "VEVENT"
+		if item.Todo {
+			component = "VTODO"
+		}
+		w.prop("BEGIN", component)
+		w.prop("UID", item.UID)
+		w.prop("DTSTAMP", stamp)
+		if item.Todo {
+			w.dateProp("DUE", item.Start, item.AllDay)
+		} else {
+			w.dateProp("DTSTART", item.Start, item.AllDay)
+			if !item.End.IsZero() && item.End.After(item.Start) {
+				w.dateProp("DTEND", item.End, item.AllDay)
+			}
+		}
+		w.prop("SUMMARY", escapeText(item.Summary))
+		if item.Description != "" {
+			w.prop("DESCRIPTION", escapeText(item.Description))
+		}
+		if item.Location != "" {
+			w.prop("LOCATION", escapeText(item.Location))
+		}
+		if item.Status != "" {
+			w.prop("STATUS", item.Status)
+		}
+		if item.Priority > 0 {
+			w.prop("PRIORITY", strconv.Itoa(item.Priority))
+		}
+		if item.URL != "" {
+			w.prop("URL", item.URL)
+		}
+		w.prop("END", component)
+	}
+	w.prop("END", "VCALENDAR")
+	return []byte(w.b.String())
+}
+
+type icalWriter struct {
+	b strings.Builder
+}
+
+func (w *icalWriter) dateProp(name string, t time.Time, allDay bool) {
+	if allDay {
+		w.prop(name+";VALUE=DATE", t.Format(icalDateLayout))
+		return
+	}
+	w.prop(name, t.UTC().Format(icalTimeLayout))
+}
+
+// prop writes one content line, folded at 75 octets as RFC 5545 requires
+// without splitting a UTF-8 sequence
+func (w *icalWriter) prop(name, value string) {
+	line := name + ":" + value
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8Start(line[cut]) {
+			cut--
+		}
+		w.b.WriteString(line[:cut])
+		w.b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74 // continuation lines start with a space
+	}
+	w.b.WriteString(line)
+	w.b.WriteString("\r\n")
+}
+
+func utf8Start(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
@@ -0,0 +1,32 @@
+package activity
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CalendarFeed is a user's subscribable iCal feed. The token is the only
+// credential calendar clients send, so it is random and can be rotated.
+type CalendarFeed struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID   primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	UserID     primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Token      string             `json:"-" bson:"token"`
+	Timezone   string             `json:"timezone" bson:"timezone"` // IANA name, e.g. Europe/Berlin
+	LastPolled *time.Time         `json:"last_polled,omitempty" bson:"last_polled,omitempty"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// CalendarFeedInfo is what the owner sees; URL embeds the token
+type CalendarFeedInfo struct {
+	URL        string     `json:"url"`
+	Timezone   string     `json:"timezone"`
+	LastPolled *time.Time `json:"last_polled,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type CalendarFeedRequest struct {
+	Timezone string `json:"timezone"`
+}
@@ -0,0 +1,105 @@
+package activity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type CalendarFeedRepository interface {
+	// GetByUser returns nil without error when the user has no feed
+	GetByUser(ctx context.Context, userID primitive.ObjectID) (*CalendarFeed, error)
+	Save(ctx context.Context, feed *CalendarFeed) error
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) error
+
+	// FindByToken looks the feed up across tenants; calendar clients carry no
+	// tenant context. Returns nil without error when the token is unknown.
+	FindByToken(ctx context.Context, token string) (*CalendarFeed, error)
+	TouchPolled(ctx context.Context, id primitive.ObjectID) error
+}
+
+type CalendarFeedRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewCalendarFeedRepository(db *database.MongodbDB) CalendarFeedRepository {
+	return &CalendarFeedRepositoryImpl{
+		collection: db.DB.Collection("calendar_feeds"),
+	}
+}
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+func (r *CalendarFeedRepositoryImpl) GetByUser(ctx context.Context, userID primitive.ObjectID) (*CalendarFeed, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var feed CalendarFeed
+	err = r.collection.FindOne(ctx, bson.M{"tenant_id": tenantID, "user_id": userID}).Decode(&feed)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+func (r *CalendarFeedRepositoryImpl) Save(ctx context.Context, feed *CalendarFeed) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	feed.TenantID = tenantID
+	feed.UpdatedAt = now
+	if feed.ID.IsZero() {
+		feed.ID = primitive.NewObjectID()
+		feed.CreatedAt = now
+		_, err = r.collection.InsertOne(ctx, feed)
+		return err
+	}
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": feed.ID, "tenant_id": tenantID}, feed)
+	return err
+}
+
+func (r *CalendarFeedRepositoryImpl) DeleteByUser(ctx context.Context, userID primitive.ObjectID) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.DeleteMany(ctx, bson.M{"tenant_id": tenantID, "user_id": userID})
+	return err
+}
+
+func (r *CalendarFeedRepositoryImpl) FindByToken(ctx context.Context, token string) (*CalendarFeed, error) {
+	var feed CalendarFeed
+	err := r.collection.FindOne(ctx, bson.M{"token": token}).Decode(&feed)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+func (r *CalendarFeedRepositoryImpl) TouchPolled(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"last_polled": time.Now()}})
+	return err
+}
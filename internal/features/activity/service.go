@@ -2,23 +2,57 @@ package activity
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/config"
 	"go-crm/internal/features/record"
+	"go-crm/internal/features/role"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+var ErrFeedNotFound = errors.New("calendar feed not found")
+
+// Feeds cover a rolling window so subscriptions stay small
+const (
+	feedPastDays   = 30
+	feedFutureDays = 365
+)
+
 type ActivityService interface {
 	GetCalendarEvents(ctx context.Context, start, end time.Time) ([]map[string]interface{}, error)
+
+	// Calendar feed management for the current user; the feed is created on first use
+	GetCalendarFeed(ctx context.Context, userID primitive.ObjectID) (*CalendarFeedInfo, error)
+	UpdateCalendarFeed(ctx context.Context, req CalendarFeedRequest, userID primitive.ObjectID) (*CalendarFeedInfo, error)
+	RotateCalendarFeed(ctx context.Context, userID primitive.ObjectID) (*CalendarFeedInfo, error)
+	RevokeCalendarFeed(ctx context.Context, userID primitive.ObjectID) error
+
+	// RenderCalendarFeed authenticates by feed token and returns the iCal body.
+	// tasksAsEvents emits tasks as VEVENTs for clients that ignore VTODO.
+	RenderCalendarFeed(ctx context.Context, token string, tasksAsEvents bool) ([]byte, error)
 }
 
 type ActivityServiceImpl struct {
-	RecordRepo record.RecordRepository
+	RecordRepo  record.RecordRepository
+	FeedRepo    CalendarFeedRepository
+	RoleService role.RoleService
+	Config      *config.Config
 }
 
-func NewActivityService(recordRepo record.RecordRepository) ActivityService {
-	return &ActivityServiceImpl{RecordRepo: recordRepo}
+func NewActivityService(recordRepo record.RecordRepository, feedRepo CalendarFeedRepository, roleService role.RoleService, cfg *config.Config) ActivityService {
+	return &ActivityServiceImpl{
+		RecordRepo:  recordRepo,
+		FeedRepo:    feedRepo,
+		RoleService: roleService,
+		Config:      cfg,
+	}
 }
 
 func (s *ActivityServiceImpl) GetCalendarEvents(ctx context.Context, start, end time.Time) ([]map[string]interface{}, error) {
@@ -106,3 +140,260 @@ func toTime(v interface{}) time.Time {
 	}
 	return time.Time{}
 }
+
+func (s *ActivityServiceImpl) GetCalendarFeed(ctx context.Context, userID primitive.ObjectID) (*CalendarFeedInfo, error) {
+	feed, err := s.FeedRepo.GetByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if feed == nil {
+		feed = &CalendarFeed{UserID: userID, Token: newFeedToken()}
+		if err := s.FeedRepo.Save(ctx, feed); err != nil {
+			return nil, err
+		}
+	}
+	return s.feedInfo(feed), nil
+}
+
+func (s *ActivityServiceImpl) UpdateCalendarFeed(ctx context.Context, req CalendarFeedRequest, userID primitive.ObjectID) (*CalendarFeedInfo, error) {
+	tz := strings.TrimSpace(req.Timezone)
+	if tz != "" {
+		if _, err := time.LoadLocation(tz); err != nil {
+			return nil, fmt.Errorf("unknown timezone '%s'", tz)
+		}
+	}
+
+	feed, err := s.FeedRepo.GetByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if feed == nil {
+		feed = &CalendarFeed{UserID: userID, Token: newFeedToken()}
+	}
+	feed.Timezone = tz
+	if err := s.FeedRepo.Save(ctx, feed); err != nil {
+		return nil, err
+	}
+	return s.feedInfo(feed), nil
+}
+
+func (s *ActivityServiceImpl) RotateCalendarFeed(ctx context.Context, userID primitive.ObjectID) (*CalendarFeedInfo, error) {
+	feed, err := s.FeedRepo.GetByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if feed == nil {
+		feed = &CalendarFeed{UserID: userID}
+	}
+	feed.Token = newFeedToken()
+	feed.LastPolled = nil
+	if err := s.FeedRepo.Save(ctx, feed); err != nil {
+		return nil, err
+	}
+	return s.feedInfo(feed), nil
+}
+
+func (s *ActivityServiceImpl) RevokeCalendarFeed(ctx context.Context, userID primitive.ObjectID) error {
+	return s.FeedRepo.DeleteByUser(ctx, userID)
+}
+
+func (s *ActivityServiceImpl) RenderCalendarFeed(ctx context.Context, token string, tasksAsEvents bool) ([]byte, error) {
+	if token == "" {
+		return nil, ErrFeedNotFound
+	}
+	feed, err := s.FeedRepo.FindByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if feed == nil {
+		return nil, ErrFeedNotFound
+	}
+	ctx = context.WithValue(ctx, common_models.TenantIDKey, feed.TenantID.Hex())
+
+	now := time.Now()
+	start := now.AddDate(0, 0, -feedPastDays)
+	end := now.AddDate(0, 0, feedFutureDays)
+
+	items := []calendarItem{}
+	items = append(items, s.feedTasks(ctx, feed.UserID, start, end, !tasksAsEvents)...)
+	items = append(items, s.feedCalls(ctx, feed.UserID, start, end)...)
+	items = append(items, s.feedMeetings(ctx, feed.UserID, start, end)...)
+
+	_ = s.FeedRepo.TouchPolled(ctx, feed.ID)
+	return renderICal("CRM Activities", feed.Timezone, items, now), nil
+}
+
+// listMine returns the user's own or assigned records of a module, within
+// the range and the user's read access
+func (s *ActivityServiceImpl) listMine(ctx context.Context, moduleName, dateField string, userID primitive.ObjectID, start, end time.Time) []map[string]interface{} {
+	accessFilter, err := s.RoleService.GetAccessFilter(ctx, userID, moduleName, "read")
+	if err != nil {
+		return nil
+	}
+	filter := bson.M{
+		dateField: bson.M{"$gte": start, "$lte": end},
+		"$or": []bson.M{
+			{"owner": userID},
+			{"assigned_to": userID},
+			{"assigned_to": userID.Hex()},
+		},
+	}
+	records, err := s.RecordRepo.List(ctx, moduleName, filter, accessFilter, 1000, 0, dateField, 1)
+	if err != nil {
+		return nil
+	}
+	return records
+}
+
+func (s *ActivityServiceImpl) feedTasks(ctx context.Context, userID primitive.ObjectID, start, end time.Time, asTodo bool) []calendarItem {
+	items := []calendarItem{}
+	for _, t := range s.listMine(ctx, "tasks", "due_date", userID, start, end) {
+		due := toTime(t["due_date"])
+		if due.IsZero() {
+			continue
+		}
+		items = append(items, calendarItem{
+			UID:         s.itemUID("tasks", t),
+			Todo:        asTodo,
+			Summary:     stringValue(t["subject"]),
+			Description: stringValue(t["description"]),
+			Start:       due,
+			AllDay:      isDateOnly(due),
+			Status:      taskStatus(stringValue(t["status"]), asTodo),
+			Priority:    taskPriority(stringValue(t["priority"])),
+			URL:         s.recordURL("tasks", t),
+		})
+	}
+	return items
+}
+
+func (s *ActivityServiceImpl) feedCalls(ctx context.Context, userID primitive.ObjectID, start, end time.Time) []calendarItem {
+	items := []calendarItem{}
+	for _, c := range s.listMine(ctx, "calls", "start_time", userID, start, end) {
+		startT := toTime(c["start_time"])
+		if startT.IsZero() {
+			continue
+		}
+		duration := 30
+		switch d := c["duration"].(type) {
+		case int32:
+			duration = int(d)
+		case int64:
+			duration = int(d)
+		case float64:
+			duration = int(d)
+		}
+		items = append(items, calendarItem{
+			UID:         s.itemUID("calls", c),
+			Summary:     stringValue(c["subject"]),
+			Description: stringValue(c["description"]),
+			Start:       startT,
+			End:         startT.Add(time.Duration(duration) * time.Minute),
+			URL:         s.recordURL("calls", c),
+		})
+	}
+	return items
+}
+
+func (s *ActivityServiceImpl) feedMeetings(ctx context.Context, userID primitive.ObjectID, start, end time.Time) []calendarItem {
+	items := []calendarItem{}
+	for _, m := range s.listMine(ctx, "meetings", "start_time", userID, start, end) {
+		startT := toTime(m["start_time"])
+		if startT.IsZero() {
+			continue
+		}
+		item := calendarItem{
+			UID:         s.itemUID("meetings", m),
+			Summary:     stringValue(m["subject"]),
+			Description: stringValue(m["description"]),
+			Location:    stringValue(m["location"]),
+			Start:       startT,
+			End:         toTime(m["end_time"]),
+			URL:         s.recordURL("meetings", m),
+		}
+		if strings.EqualFold(stringValue(m["status"]), "cancelled") {
+			item.Status = "CANCELLED"
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func (s *ActivityServiceImpl) feedInfo(feed *CalendarFeed) *CalendarFeedInfo {
+	return &CalendarFeedInfo{
+		URL:        fmt.Sprintf("%s/api/activities/feeds/%s.ics", strings.TrimRight(s.Config.PublicURL, "/"), feed.Token),
+		Timezone:   feed.Timezone,
+		LastPolled: feed.LastPolled,
+		CreatedAt:  feed.CreatedAt,
+	}
+}
+
+// itemUID stays stable across polls so clients update events in place
+func (s *ActivityServiceImpl) itemUID(moduleName string, rec map[string]interface{}) string {
+	host := strings.TrimPrefix(strings.TrimPrefix(s.Config.PublicURL, "https://"), "http://")
+	host = strings.TrimRight(host, "/")
+	return fmt.Sprintf("%s-%s@%s", moduleName, recordID(rec), host)
+}
+
+func (s *ActivityServiceImpl) recordURL(moduleName string, rec map[string]interface{}) string {
+	return fmt.Sprintf("%s/dashboard/modules/%s/%s", strings.TrimRight(s.Config.PublicURL, "/"), moduleName, recordID(rec))
+}
+
+func newFeedToken() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func recordID(rec map[string]interface{}) string {
+	if oid, ok := rec["_id"].(primitive.ObjectID); ok {
+		return oid.Hex()
+	}
+	return fmt.Sprintf("%v", rec["_id"])
+}
+
+func stringValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return ""
+}
+
+// isDateOnly treats values stored at UTC midnight as all-day dates, which
+// is how date fields without a time component are saved
+func isDateOnly(t time.Time) bool {
+	t = t.UTC()
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0
+}
+
+func taskStatus(status string, asTodo bool) string {
+	status = strings.ToLower(status)
+	if !asTodo {
+		if status == "cancelled" {
+			return "CANCELLED"
+		}
+		return ""
+	}
+	switch status {
+	case "completed", "done", "closed":
+		return "COMPLETED"
+	case "in_progress", "in progress":
+		return "IN-PROCESS"
+	case "cancelled":
+		return "CANCELLED"
+	}
+	return "NEEDS-ACTION"
+}
+
+// taskPriority maps to the RFC 5545 scale where 1 is highest
+func taskPriority(priority string) int {
+	switch strings.ToLower(priority) {
+	case "urgent", "high":
+		return 1
+	case "medium", "normal":
+		return 5
+	case "low":
+		return 9
+	}
+	return 0
+}
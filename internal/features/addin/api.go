@@ -0,0 +1,32 @@
+package addin
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AddInApi struct {
+	controller *AddInController
+	config     *config.Config
+}
+
+func NewAddInApi(controller *AddInController, config *config.Config) *AddInApi {
+	return &AddInApi{
+		controller: controller,
+		config:     config,
+	}
+}
+
+// Setup registers the email add-in routes. CORS runs before auth so
+// preflights from add-in pages, which carry no token, succeed.
+func (h *AddInApi) Setup(app *fiber.App) {
+	addin := app.Group(middleware.AddInPath, middleware.AddInCORSMiddleware(h.config), middleware.AuthMiddleware(h.config.SkipAuth))
+
+	addin.Post("/token", h.controller.IssueToken)
+	addin.Get("/lookup", h.controller.LookupEmail)
+	addin.Post("/records", h.controller.CreateFromSignature)
+	addin.Get("/records/:module/:id/summary", h.controller.GetRecordSummary)
+	addin.Post("/emails", h.controller.LogEmail)
+}
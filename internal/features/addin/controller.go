@@ -0,0 +1,171 @@
+package addin
+
+import (
+	"errors"
+
+	"go-crm/internal/common/session"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/record"
+	"go-crm/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type AddInController struct {
+	Service AddInService
+}
+
+func NewAddInController(service AddInService) *AddInController {
+	return &AddInController{Service: service}
+}
+
+func currentUser(c *fiber.Ctx) (primitive.ObjectID, error) {
+	userID, ok := session.UserID(c.UserContext())
+	if !ok {
+		return primitive.NilObjectID, fiber.NewError(fiber.StatusUnauthorized, "User ID not found")
+	}
+	return userID, nil
+}
+
+func serviceError(c *fiber.Ctx, err error) error {
+	var fiberErr *fiber.Error
+	switch {
+	case errors.As(err, &fiberErr):
+		return c.Status(fiberErr.Code).JSON(fiber.Map{"error": fiberErr.Message})
+	case errors.Is(err, ErrEmailRequired), errors.Is(err, ErrRecordRequired), errors.Is(err, ErrUnsupportedModule),
+		errors.Is(err, ErrInvalidDirection), errors.Is(err, ErrNothingToCreate):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, module.ErrAccessDenied), errors.Is(err, record.ErrAccessDenied):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, record.ErrRecordNotFound), errors.Is(err, mongo.ErrNoDocuments):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Record not found"})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+}
+
+// IssueToken godoc
+// @Summary Issue an add-in token
+// @Description Exchange a session token for a longer-lived token that only works on /api/addin. Refresh it at /api/token/refresh.
+// @Tags addin
+// @Produce json
+// @Success 201 {object} TokenResponse
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/addin/token [post]
+func (ctrl *AddInController) IssueToken(c *fiber.Ctx) error {
+	claims, _ := c.Locals(utils.UserClaimsKey).(*utils.UserClaims)
+	resp, err := ctrl.Service.IssueToken(c.UserContext(), claims)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// LookupEmail godoc
+// @Summary Look up an email address
+// @Description Find the contacts and leads with the address
+// @Tags addin
+// @Produce json
+// @Param email query string true "Email address"
+// @Success 200 {object} LookupResult
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/addin/lookup [get]
+func (ctrl *AddInController) LookupEmail(c *fiber.Ctx) error {
+	userID, err := currentUser(c)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	result, err := ctrl.Service.LookupEmail(c.UserContext(), c.Query("email"), userID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	return c.JSON(result)
+}
+
+// CreateFromSignature godoc
+// @Summary Create a lead or contact from an email
+// @Description Parse the sender's signature into a lead or contact. Returns 409 with the existing record when the address is known.
+// @Tags addin
+// @Accept json
+// @Produce json
+// @Param request body SignatureRequest true "Message and sender"
+// @Success 201 {object} SignatureResult
+// @Success 200 {object} SignatureResult "Dry run"
+// @Failure 409 {object} SignatureResult
+// @Router /api/addin/records [post]
+func (ctrl *AddInController) CreateFromSignature(c *fiber.Ctx) error {
+	userID, err := currentUser(c)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	var req SignatureRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	result, err := ctrl.Service.CreateFromSignature(c.UserContext(), req, userID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	switch {
+	case result.Existing != nil:
+		return c.Status(fiber.StatusConflict).JSON(result)
+	case result.Record != nil:
+		return c.Status(fiber.StatusCreated).JSON(result)
+	}
+	return c.JSON(result)
+}
+
+// LogEmail godoc
+// @Summary Log an email against a record
+// @Description File the open message on a record. A message already logged there by Message-ID returns 200 with the existing entry.
+// @Tags addin
+// @Accept json
+// @Produce json
+// @Param request body LogEmailRequest true "Message"
+// @Success 201 {object} LoggedEmail
+// @Success 200 {object} LoggedEmail
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/addin/emails [post]
+func (ctrl *AddInController) LogEmail(c *fiber.Ctx) error {
+	userID, err := currentUser(c)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	var req LogEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	email, created, err := ctrl.Service.LogEmail(c.UserContext(), req, userID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	if created {
+		return c.Status(fiber.StatusCreated).JSON(email)
+	}
+	return c.JSON(email)
+}
+
+// GetRecordSummary godoc
+// @Summary Compact record summary
+// @Description Title, key fields and recently logged emails of a record
+// @Tags addin
+// @Produce json
+// @Param module path string true "Module name"
+// @Param id path string true "Record ID"
+// @Success 200 {object} RecordSummary
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/addin/records/{module}/{id}/summary [get]
+func (ctrl *AddInController) GetRecordSummary(c *fiber.Ctx) error {
+	userID, err := currentUser(c)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	summary, err := ctrl.Service.GetRecordSummary(c.UserContext(), c.Params("module"), c.Params("id"), userID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	return c.JSON(summary)
+}
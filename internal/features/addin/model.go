@@ -0,0 +1,122 @@
+package addin
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Modules an email address is looked up in, in order
+var LookupModules = []string{"contacts", "leads"}
+
+const (
+	DirectionInbound  = "inbound"
+	DirectionOutbound = "outbound"
+)
+
+// LoggedEmail is a message a user filed against a record from their mail client
+type LoggedEmail struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID   primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	ModuleName string             `json:"module_name" bson:"module_name"`
+	RecordID   string             `json:"record_id" bson:"record_id"`
+	// MessageID is the Internet Message-ID; the same message is logged once per record
+	MessageID string             `json:"message_id,omitempty" bson:"message_id,omitempty"`
+	Direction string             `json:"direction" bson:"direction"` // inbound, outbound
+	Subject   string             `json:"subject" bson:"subject"`
+	From      string             `json:"from" bson:"from"`
+	To        []string           `json:"to,omitempty" bson:"to,omitempty"`
+	Cc        []string           `json:"cc,omitempty" bson:"cc,omitempty"`
+	Body      string             `json:"body,omitempty" bson:"body,omitempty"` // Plain text, truncated
+	SentAt    time.Time          `json:"sent_at" bson:"sent_at"`
+	LoggedBy  primitive.ObjectID `json:"logged_by" bson:"logged_by"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type LogEmailRequest struct {
+	Module    string    `json:"module"`
+	RecordID  string    `json:"record_id"`
+	MessageID string    `json:"message_id"`
+	Direction string    `json:"direction"`
+	Subject   string    `json:"subject"`
+	From      string    `json:"from"`
+	To        []string  `json:"to"`
+	Cc        []string  `json:"cc"`
+	Body      string    `json:"body"` // Text or HTML
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// RecordCard is the few fields an add-in shows for a record
+type RecordCard struct {
+	Module   string     `json:"module"`
+	ID       string     `json:"id"`
+	Title    string     `json:"title"`
+	Subtitle string     `json:"subtitle,omitempty"`
+	Email    string     `json:"email,omitempty"`
+	Phone    string     `json:"phone,omitempty"`
+	Account  *RecordRef `json:"account,omitempty"`
+	Owner    string     `json:"owner,omitempty"`
+}
+
+type RecordRef struct {
+	Module string `json:"module"`
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+}
+
+type LookupResult struct {
+	Email   string       `json:"email"`
+	Matches []RecordCard `json:"matches"`
+}
+
+// Signature holds what was read from an email signature
+type Signature struct {
+	FirstName string `json:"first_name,omitempty"`
+	LastName  string `json:"last_name,omitempty"`
+	Email     string `json:"email,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Company   string `json:"company,omitempty"`
+	Phone     string `json:"phone,omitempty"`
+	Mobile    string `json:"mobile,omitempty"`
+	Website   string `json:"website,omitempty"`
+}
+
+type SignatureRequest struct {
+	Module    string `json:"module"` // leads (default) or contacts
+	FromName  string `json:"from_name"`
+	FromEmail string `json:"from_email"`
+	// Body is the message or just its signature block, text or HTML
+	Body string `json:"body"`
+	// Fields override or add to what the signature yields
+	Fields map[string]any `json:"fields"`
+	// DryRun returns the parsed signature and record data without creating it
+	DryRun bool `json:"dry_run"`
+}
+
+type SignatureResult struct {
+	Parsed Signature      `json:"parsed"`
+	Data   map[string]any `json:"data"`
+	// Record is set once created; Existing when the address already has one
+	Record   *RecordCard `json:"record,omitempty"`
+	Existing *RecordCard `json:"existing,omitempty"`
+}
+
+type SummaryField struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// RecordSummary is a compact view of a record for a task pane
+type RecordSummary struct {
+	RecordCard
+	ModuleLabel  string         `json:"module_label"`
+	Fields       []SummaryField `json:"fields"`
+	UpdatedAt    string         `json:"updated_at,omitempty"`
+	RecentEmails []LoggedEmail  `json:"recent_emails"`
+}
+
+type TokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,103 @@
+package addin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type LoggedEmailRepository interface {
+	Create(ctx context.Context, email *LoggedEmail) error
+	// FindByMessageID returns the message logged against the record, if any
+	FindByMessageID(ctx context.Context, moduleName, recordID, messageID string) (*LoggedEmail, error)
+	// ListForRecord returns the record's emails, newest first
+	ListForRecord(ctx context.Context, moduleName, recordID string, limit int64) ([]LoggedEmail, error)
+}
+
+type LoggedEmailRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewLoggedEmailRepository(db *database.MongodbDB) LoggedEmailRepository {
+	return &LoggedEmailRepositoryImpl{
+		collection: db.DB.Collection("logged_emails"),
+	}
+}
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	tenantID, err := primitive.ObjectIDFromHex(tenantIDStr)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("invalid tenant ID: %v", err)
+	}
+	return tenantID, nil
+}
+
+func (r *LoggedEmailRepositoryImpl) Create(ctx context.Context, email *LoggedEmail) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	email.TenantID = tenantID
+	if email.ID.IsZero() {
+		email.ID = primitive.NewObjectID()
+	}
+	email.CreatedAt = time.Now()
+
+	_, err = r.collection.InsertOne(ctx, email)
+	return err
+}
+
+func (r *LoggedEmailRepositoryImpl) FindByMessageID(ctx context.Context, moduleName, recordID, messageID string) (*LoggedEmail, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var email LoggedEmail
+	err = r.collection.FindOne(ctx, bson.M{
+		"tenant_id":   tenantID,
+		"module_name": moduleName,
+		"record_id":   recordID,
+		"message_id":  messageID,
+	}).Decode(&email)
+	if err != nil {
+		return nil, err
+	}
+	return &email, nil
+}
+
+func (r *LoggedEmailRepositoryImpl) ListForRecord(ctx context.Context, moduleName, recordID string, limit int64) ([]LoggedEmail, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "sent_at", Value: -1}}).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"tenant_id":   tenantID,
+		"module_name": moduleName,
+		"record_id":   recordID,
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	emails := []LoggedEmail{}
+	if err := cursor.All(ctx, &emails); err != nil {
+		return nil, err
+	}
+	return emails, nil
+}
@@ -0,0 +1,361 @@
+package addin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/auth"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/settings"
+	"go-crm/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	maxLookupMatches = 5
+	maxSummaryFields = 8
+	recentEmailCount = 5
+	maxLoggedBody    = 20000
+)
+
+var (
+	ErrEmailRequired      = errors.New("email is required")
+	ErrRecordRequired     = errors.New("module and record_id are required")
+	ErrUnsupportedModule  = errors.New("records can only be created in contacts or leads")
+	ErrInvalidDirection   = errors.New("direction must be inbound or outbound")
+	ErrNothingToCreate    = errors.New("no record data found in the signature")
+	errUnexpectedCreateID = errors.New("record created without an id")
+)
+
+// Fields shown on the card and left out of a summary's field list
+var cardFields = []string{"name", "first_name", "last_name", "email", "phone", "mobile", "title", "job_title", "company", "account", "owner"}
+
+type AddInService interface {
+	// LookupEmail finds the contacts and leads the user can read with the address
+	LookupEmail(ctx context.Context, email string, userID primitive.ObjectID) (*LookupResult, error)
+	CreateFromSignature(ctx context.Context, req SignatureRequest, userID primitive.ObjectID) (*SignatureResult, error)
+	// LogEmail files a message against a record; logging the same message twice returns the first entry
+	LogEmail(ctx context.Context, req LogEmailRequest, userID primitive.ObjectID) (*LoggedEmail, bool, error)
+	GetRecordSummary(ctx context.Context, moduleName, id string, userID primitive.ObjectID) (*RecordSummary, error)
+	IssueToken(ctx context.Context, claims *utils.UserClaims) (*TokenResponse, error)
+}
+
+type AddInServiceImpl struct {
+	Repo            LoggedEmailRepository
+	RecordService   record.RecordService
+	ModuleService   module.ModuleService
+	SettingsService settings.SettingsService
+	AuthService     auth.AuthService
+}
+
+func NewAddInService(repo LoggedEmailRepository, recordService record.RecordService, moduleService module.ModuleService, settingsService settings.SettingsService, authService auth.AuthService) AddInService {
+	return &AddInServiceImpl{
+		Repo:            repo,
+		RecordService:   recordService,
+		ModuleService:   moduleService,
+		SettingsService: settingsService,
+		AuthService:     authService,
+	}
+}
+
+func (s *AddInServiceImpl) LookupEmail(ctx context.Context, email string, userID primitive.ObjectID) (*LookupResult, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil, ErrEmailRequired
+	}
+
+	result := &LookupResult{Email: email, Matches: []RecordCard{}}
+	for _, moduleName := range LookupModules {
+		// Modules the user cannot read are skipped
+		records, err := s.findByEmail(ctx, moduleName, email, userID)
+		if err != nil {
+			continue
+		}
+		for _, rec := range records {
+			result.Matches = append(result.Matches, s.card(ctx, moduleName, rec, userID))
+		}
+	}
+	return result, nil
+}
+
+// findByEmail matches the address as given and lower-cased, since filters
+// compare exactly
+func (s *AddInServiceImpl) findByEmail(ctx context.Context, moduleName, email string, userID primitive.ObjectID) ([]map[string]any, error) {
+	filters := []common_models.Filter{{Field: "email", Operator: "eq", Value: email}}
+	records, _, err := s.RecordService.ListRecords(ctx, moduleName, filters, 1, maxLookupMatches, "updated_at", "desc", userID)
+	if err != nil || len(records) > 0 {
+		return records, err
+	}
+	if lower := strings.ToLower(email); lower != email {
+		filters[0].Value = lower
+		records, _, err = s.RecordService.ListRecords(ctx, moduleName, filters, 1, maxLookupMatches, "updated_at", "desc", userID)
+	}
+	return records, err
+}
+
+func (s *AddInServiceImpl) CreateFromSignature(ctx context.Context, req SignatureRequest, userID primitive.ObjectID) (*SignatureResult, error) {
+	moduleName := req.Module
+	if moduleName == "" {
+		moduleName = "leads"
+	}
+	if !slices.Contains(LookupModules, moduleName) {
+		return nil, ErrUnsupportedModule
+	}
+	mod, err := s.ModuleService.GetModuleByName(ctx, moduleName, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := ParseSignature(req.Body, req.FromName, req.FromEmail)
+	result := &SignatureResult{Parsed: parsed, Data: signatureData(parsed, mod)}
+	for k, v := range req.Fields {
+		result.Data[k] = v
+	}
+	if len(result.Data) == 0 {
+		return nil, ErrNothingToCreate
+	}
+
+	if email, _ := result.Data["email"].(string); email != "" {
+		if existing, err := s.findByEmail(ctx, moduleName, email, userID); err == nil && len(existing) > 0 {
+			card := s.card(ctx, moduleName, existing[0], userID)
+			result.Existing = &card
+			return result, nil
+		}
+	}
+	if req.DryRun {
+		return result, nil
+	}
+
+	created, err := s.RecordService.CreateRecord(ctx, moduleName, result.Data, userID)
+	if err != nil {
+		return nil, err
+	}
+	id, ok := created.(primitive.ObjectID)
+	if !ok {
+		return nil, errUnexpectedCreateID
+	}
+	rec, err := s.RecordService.GetRecord(ctx, moduleName, id.Hex(), userID)
+	if err != nil {
+		rec = map[string]any{"_id": id}
+		for k, v := range result.Data {
+			rec[k] = v
+		}
+	}
+	card := s.card(ctx, moduleName, rec, userID)
+	result.Record = &card
+	return result, nil
+}
+
+// signatureData maps a signature onto the fields the module has
+func signatureData(sig Signature, mod *common_models.Entity) map[string]any {
+	fullName := strings.TrimSpace(sig.FirstName + " " + sig.LastName)
+	candidates := map[string]string{
+		"first_name": sig.FirstName,
+		"last_name":  sig.LastName,
+		"name":       fullName,
+		"email":      sig.Email,
+		"phone":      sig.Phone,
+		"mobile":     sig.Mobile,
+		"title":      sig.Title,
+		"job_title":  sig.Title,
+		"company":    sig.Company,
+		"website":    sig.Website,
+	}
+	if sig.Phone == "" {
+		candidates["phone"] = sig.Mobile
+	}
+
+	data := map[string]any{}
+	for _, f := range mod.Fields {
+		if v := candidates[f.Name]; v != "" && !f.IsSystem {
+			data[f.Name] = v
+		}
+	}
+	return data
+}
+
+func (s *AddInServiceImpl) LogEmail(ctx context.Context, req LogEmailRequest, userID primitive.ObjectID) (*LoggedEmail, bool, error) {
+	if req.Module == "" || req.RecordID == "" {
+		return nil, false, ErrRecordRequired
+	}
+	switch req.Direction {
+	case "":
+		req.Direction = DirectionInbound
+	case DirectionInbound, DirectionOutbound:
+	default:
+		return nil, false, ErrInvalidDirection
+	}
+	// Filing needs the same access as reading the record
+	if _, err := s.RecordService.GetRecord(ctx, req.Module, req.RecordID, userID); err != nil {
+		return nil, false, err
+	}
+
+	messageID := strings.Trim(strings.TrimSpace(req.MessageID), "<>")
+	if messageID != "" {
+		existing, err := s.Repo.FindByMessageID(ctx, req.Module, req.RecordID, messageID)
+		if err == nil {
+			return existing, false, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return nil, false, err
+		}
+	}
+
+	body := strings.TrimSpace(htmlToText(req.Body))
+	if r := []rune(body); len(r) > maxLoggedBody {
+		body = string(r[:maxLoggedBody])
+	}
+	sentAt := req.SentAt
+	if sentAt.IsZero() {
+		sentAt = time.Now()
+	}
+
+	email := &LoggedEmail{
+		ModuleName: req.Module,
+		RecordID:   req.RecordID,
+		MessageID:  messageID,
+		Direction:  req.Direction,
+		Subject:    strings.TrimSpace(req.Subject),
+		From:       strings.TrimSpace(req.From),
+		To:         req.To,
+		Cc:         req.Cc,
+		Body:       body,
+		SentAt:     sentAt.UTC(),
+		LoggedBy:   userID,
+	}
+	if err := s.Repo.Create(ctx, email); err != nil {
+		return nil, false, err
+	}
+	return email, true, nil
+}
+
+func (s *AddInServiceImpl) GetRecordSummary(ctx context.Context, moduleName, id string, userID primitive.ObjectID) (*RecordSummary, error) {
+	mod, err := s.ModuleService.GetModuleByName(ctx, moduleName, userID)
+	if err != nil {
+		return nil, err
+	}
+	rec, err := s.RecordService.GetRecord(ctx, moduleName, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	lf := s.SettingsService.Formatter(ctx, userID.Hex())
+	summary := &RecordSummary{
+		RecordCard:   s.card(ctx, moduleName, rec, userID),
+		ModuleLabel:  mod.Label,
+		Fields:       []SummaryField{},
+		RecentEmails: []LoggedEmail{},
+	}
+	if v, ok := rec["updated_at"]; ok {
+		summary.UpdatedAt = lf.Field(string(common_models.FieldTypeDate), v)
+	}
+
+	for _, f := range mod.Fields {
+		if len(summary.Fields) == maxSummaryFields {
+			break
+		}
+		if f.IsSystem || f.Hidden || slices.Contains(cardFields, f.Name) {
+			continue
+		}
+		switch f.Type {
+		case common_models.FieldTypeFile, common_models.FieldTypeImage, common_models.FieldTypeTextArea:
+			continue
+		}
+		v, ok := rec[f.Name]
+		if !ok || v == nil || v == "" {
+			continue
+		}
+		summary.Fields = append(summary.Fields, SummaryField{Name: f.Name, Label: f.Label, Value: lf.Field(string(f.Type), v)})
+	}
+
+	if emails, err := s.Repo.ListForRecord(ctx, moduleName, id, recentEmailCount); err == nil {
+		for i := range emails {
+			// The pane lists subjects; the full text stays on the record
+			emails[i].Body = ""
+		}
+		summary.RecentEmails = emails
+	}
+	return summary, nil
+}
+
+func (s *AddInServiceImpl) IssueToken(ctx context.Context, claims *utils.UserClaims) (*TokenResponse, error) {
+	token, expiresAt, err := s.AuthService.IssueAddInToken(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenResponse{Token: token, ExpiresAt: expiresAt}, nil
+}
+
+// card picks a record's display fields; the account name is read with the
+// user's access and left out when they cannot see it
+func (s *AddInServiceImpl) card(ctx context.Context, moduleName string, rec map[string]any, userID primitive.ObjectID) RecordCard {
+	card := RecordCard{
+		Module: moduleName,
+		ID:     idString(rec["_id"]),
+		Title:  recordTitle(rec),
+		Email:  stringField(rec, "email"),
+		Phone:  stringField(rec, "phone", "mobile"),
+		Owner:  idString(rec["owner"]),
+	}
+	card.Subtitle = stringField(rec, "title", "job_title")
+	if company := stringField(rec, "company"); company != "" {
+		if card.Subtitle != "" {
+			card.Subtitle += ", "
+		}
+		card.Subtitle += company
+	}
+
+	if accountID := idString(rec["account"]); accountID != "" {
+		if account, err := s.RecordService.GetRecord(ctx, "accounts", accountID, userID); err == nil {
+			card.Account = &RecordRef{Module: "accounts", ID: accountID, Title: recordTitle(account)}
+		}
+	}
+	return card
+}
+
+func recordTitle(rec map[string]any) string {
+	if name := stringField(rec, "name"); name != "" {
+		return name
+	}
+	if name := strings.TrimSpace(stringField(rec, "first_name") + " " + stringField(rec, "last_name")); name != "" {
+		return name
+	}
+	if title := stringField(rec, "subject", "title", "email"); title != "" {
+		return title
+	}
+	return idString(rec["_id"])
+}
+
+// stringField returns the first non-empty string among keys
+func stringField(rec map[string]any, keys ...string) string {
+	for _, k := range keys {
+		if v, ok := rec[k].(string); ok && strings.TrimSpace(v) != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+func idString(v any) string {
+	switch id := v.(type) {
+	case primitive.ObjectID:
+		return id.Hex()
+	case string:
+		return id
+	case map[string]any:
+		return idString(id["id"])
+	case primitive.M:
+		return idString(id["id"])
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
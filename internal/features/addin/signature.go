@@ -0,0 +1,235 @@
+package addin
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// maxSignatureLines is how far past the sign-off a signature is read
+const maxSignatureLines = 10
+
+var (
+	htmlBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|tr|li|h[1-6])>`)
+	htmlTagPattern   = regexp.MustCompile(`(?s)<[^>]*>`)
+
+	emailPattern   = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	phonePattern   = regexp.MustCompile(`\+?\(?\d[\d\s().\-]{6,}\d`)
+	websitePattern = regexp.MustCompile(`(?i)\b(https?://[^\s<>|]+|www\.[^\s<>|]+)`)
+	mobilePattern  = regexp.MustCompile(`(?i)\b(m|mob|mobile|cell|cellular)\b\s*[:.]?`)
+
+	signOffs = []string{"regards", "best", "thanks", "thank you", "cheers", "sincerely", "kind regards", "best regards", "warm regards", "all the best"}
+
+	companySuffixes = []string{"inc", "inc.", "llc", "ltd", "ltd.", "limited", "gmbh", "corp", "corp.", "corporation", "co.", "company", "group", "plc", "ag", "sa", "s.a.", "bv", "pty", "technologies", "solutions", "partners", "labs"}
+
+	titleWords = []string{"ceo", "cto", "cfo", "coo", "cmo", "vp", "svp", "evp", "president", "founder", "co-founder", "owner", "director", "head", "manager", "lead", "engineer", "developer", "consultant", "specialist", "executive", "officer", "analyst", "architect", "representative", "associate", "coordinator", "administrator", "partner", "sales", "marketing", "account", "designer"}
+
+	// Free mail domains say nothing about the sender's company
+	freeMailDomains = map[string]bool{"gmail.com": true, "googlemail.com": true, "yahoo.com": true, "outlook.com": true, "hotmail.com": true, "live.com": true, "icloud.com": true, "me.com": true, "aol.com": true, "proton.me": true, "protonmail.com": true, "gmx.com": true, "gmx.de": true, "yandex.com": true, "mail.com": true}
+)
+
+// ParseSignature reads contact details from the end of an email. fromName
+// and fromEmail come from the message headers and win over the signature.
+func ParseSignature(body, fromName, fromEmail string) Signature {
+	lines := signatureLines(htmlToText(body))
+	var sig Signature
+
+	nameLine := -1
+	for i, line := range lines {
+		if sig.Email == "" {
+			sig.Email = emailPattern.FindString(line)
+		}
+		if sig.Website == "" {
+			if m := websitePattern.FindString(line); m != "" {
+				sig.Website = strings.TrimRight(m, ".,;")
+			}
+		}
+		for _, part := range splitSignatureLine(line) {
+			if emailPattern.MatchString(part) || websitePattern.MatchString(part) {
+				continue
+			}
+			if phone := phonePattern.FindString(part); phone != "" && digitCount(phone) >= 7 {
+				phone = strings.TrimSpace(phone)
+				if mobilePattern.MatchString(part) {
+					if sig.Mobile == "" {
+						sig.Mobile = phone
+					}
+				} else if sig.Phone == "" {
+					sig.Phone = phone
+				}
+				continue
+			}
+			switch {
+			case sig.Company == "" && looksLikeCompany(part):
+				sig.Company = part
+			case sig.Title == "" && looksLikeTitle(part):
+				sig.Title = part
+			case nameLine < 0 && looksLikeName(part):
+				nameLine = i
+				sig.FirstName, sig.LastName = splitName(part)
+			}
+		}
+	}
+
+	if name := strings.TrimSpace(fromName); name != "" && !strings.Contains(name, "@") {
+		sig.FirstName, sig.LastName = splitName(name)
+	}
+	if email := strings.TrimSpace(fromEmail); email != "" {
+		sig.Email = strings.ToLower(email)
+	}
+	if sig.Company == "" {
+		sig.Company = companyFromDomain(sig.Email)
+	}
+	return sig
+}
+
+// signatureLines returns the lines after the signature delimiter or the
+// last sign-off, or the last few lines when there is neither
+func signatureLines(text string) []string {
+	var all []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		// Quoted replies belong to someone else
+		if strings.HasPrefix(line, ">") {
+			continue
+		}
+		all = append(all, line)
+	}
+
+	start := -1
+	for i, line := range all {
+		if line == "--" || line == "-- " || line == "—" {
+			start = i + 1
+			break
+		}
+	}
+	if start < 0 {
+		for i := len(all) - 1; i >= 0; i-- {
+			lower := strings.TrimRight(strings.ToLower(all[i]), ",.!")
+			for _, s := range signOffs {
+				if lower == s {
+					start = i + 1
+					break
+				}
+			}
+			if start >= 0 {
+				break
+			}
+		}
+	}
+	if start < 0 {
+		start = max(len(all)-maxSignatureLines, 0)
+	}
+
+	var lines []string
+	for _, line := range all[start:] {
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+		if len(lines) == maxSignatureLines {
+			break
+		}
+	}
+	return lines
+}
+
+func htmlToText(s string) string {
+	if !strings.Contains(s, "<") {
+		return s
+	}
+	s = htmlBreakPattern.ReplaceAllString(s, "\n")
+	return html.UnescapeString(htmlTagPattern.ReplaceAllString(s, ""))
+}
+
+// splitSignatureLine splits "Sales Director | Acme Inc" style lines
+func splitSignatureLine(line string) []string {
+	var parts []string
+	for _, p := range strings.FieldsFunc(line, func(r rune) bool { return r == '|' || r == '•' || r == '·' }) {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	// "Jane Doe, Head of Sales" names the title after a comma
+	if len(parts) == 1 {
+		if name, rest, ok := strings.Cut(parts[0], ","); ok && looksLikeName(strings.TrimSpace(name)) && looksLikeTitle(strings.TrimSpace(rest)) {
+			return []string{strings.TrimSpace(name), strings.TrimSpace(rest)}
+		}
+	}
+	return parts
+}
+
+func looksLikeName(s string) bool {
+	words := strings.Fields(s)
+	if len(words) < 2 || len(words) > 4 {
+		return false
+	}
+	for _, w := range words {
+		r := []rune(w)
+		if !unicode.IsUpper(r[0]) {
+			return false
+		}
+		for _, c := range r {
+			if !unicode.IsLetter(c) && c != '-' && c != '\'' && c != '.' {
+				return false
+			}
+		}
+	}
+	return !looksLikeTitle(s) && !looksLikeCompany(s)
+}
+
+func looksLikeTitle(s string) bool {
+	return hasWord(s, titleWords)
+}
+
+func looksLikeCompany(s string) bool {
+	return hasWord(s, companySuffixes)
+}
+
+func hasWord(s string, words []string) bool {
+	for _, w := range strings.Fields(strings.ToLower(s)) {
+		w = strings.Trim(w, ",()")
+		for _, candidate := range words {
+			if w == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func splitName(name string) (string, string) {
+	first, last, _ := strings.Cut(strings.TrimSpace(name), " ")
+	return first, strings.TrimSpace(last)
+}
+
+func companyFromDomain(email string) string {
+	_, domain, ok := strings.Cut(email, "@")
+	if !ok || freeMailDomains[strings.ToLower(domain)] {
+		return ""
+	}
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return ""
+	}
+	name := labels[len(labels)-2]
+	// example.co.uk
+	if len(labels) > 2 && len(name) <= 3 {
+		name = labels[len(labels)-3]
+	}
+	if name == "" {
+		return ""
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func digitCount(s string) int {
+	n := 0
+	for _, r := range s {
+		if unicode.IsDigit(r) {
+			n++
+		}
+	}
+	return n
+}
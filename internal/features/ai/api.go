@@ -0,0 +1,29 @@
+package ai
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AIApi struct {
+	controller *AIController
+	config     *config.Config
+}
+
+func NewAIApi(controller *AIController, config *config.Config) *AIApi {
+	return &AIApi{
+		controller: controller,
+		config:     config,
+	}
+}
+
+// Setup registers the AI routes. Record and comment access is checked by
+// the services the notes are read from and written to.
+func (h *AIApi) Setup(app *fiber.App) {
+	group := app.Group("/api/ai", middleware.AuthMiddleware(h.config.SkipAuth))
+	group.Post("/tickets/:id/summary", h.controller.SummarizeTicket)
+	group.Post("/tickets/:id/reply-draft", h.controller.SuggestReply)
+	group.Post("/modules/:name/records/:id/action-items", h.controller.ExtractActionItems)
+}
@@ -0,0 +1,100 @@
+package ai
+
+import (
+	"errors"
+
+	common_api "go-crm/internal/common/api"
+	"go-crm/internal/common/session"
+	"go-crm/internal/features/comment"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AIController struct {
+	Service AIService
+}
+
+func NewAIController(service AIService) *AIController {
+	return &AIController{Service: service}
+}
+
+func (ctrl *AIController) respond(c *fiber.Ctx, note *comment.Comment, err error) error {
+	if err != nil {
+		status := fiber.StatusBadRequest
+		switch {
+		case errors.Is(err, ErrProvider):
+			status = fiber.StatusBadGateway
+		case errors.Is(err, ErrNotConfigured):
+			status = fiber.StatusConflict
+		}
+		return common_api.Fail(c, status, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"data": note})
+}
+
+// SummarizeTicket godoc
+// @Summary Summarize ticket
+// @Description Summarize the ticket thread with the tenant's AI provider and store the summary as an internal, AI-generated comment
+// @Tags ai
+// @Produce json
+// @Param id path string true "Ticket ID"
+// @Success 201 {object} comment.Comment
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 502 {object} map[string]interface{}
+// @Router /api/ai/tickets/{id}/summary [post]
+func (ctrl *AIController) SummarizeTicket(c *fiber.Ctx) error {
+	userID, ok := session.UserID(c.UserContext())
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	note, err := ctrl.Service.SummarizeTicket(c.UserContext(), c.Params("id"), userID)
+	return ctrl.respond(c, note, err)
+}
+
+// SuggestReply godoc
+// @Summary Suggest ticket reply
+// @Description Draft a reply to the customer in the style of the ticket email templates and store it as an internal, AI-generated comment
+// @Tags ai
+// @Produce json
+// @Param id path string true "Ticket ID"
+// @Success 201 {object} comment.Comment
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 502 {object} map[string]interface{}
+// @Router /api/ai/tickets/{id}/reply-draft [post]
+func (ctrl *AIController) SuggestReply(c *fiber.Ctx) error {
+	userID, ok := session.UserID(c.UserContext())
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	note, err := ctrl.Service.SuggestReply(c.UserContext(), c.Params("id"), userID)
+	return ctrl.respond(c, note, err)
+}
+
+// ExtractActionItems godoc
+// @Summary Extract action items
+// @Description Extract action items from meeting notes, given inline or read from a field of the record, and store them as an internal, AI-generated comment on the record
+// @Tags ai
+// @Accept json
+// @Produce json
+// @Param name path string true "Module Name"
+// @Param id path string true "Record ID"
+// @Param request body ActionItemsRequest true "Notes or the field holding them"
+// @Success 201 {object} comment.Comment
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 502 {object} map[string]interface{}
+// @Router /api/ai/modules/{name}/records/{id}/action-items [post]
+func (ctrl *AIController) ExtractActionItems(c *fiber.Ctx) error {
+	userID, ok := session.UserID(c.UserContext())
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var req ActionItemsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return common_api.InvalidBody(c, err)
+	}
+	note, err := ctrl.Service.ExtractActionItems(c.UserContext(), c.Params("name"), c.Params("id"), req, userID)
+	return ctrl.respond(c, note, err)
+}
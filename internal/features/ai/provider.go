@@ -0,0 +1,181 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"go-crm/internal/features/settings"
+)
+
+// Prompt is one completion request to a provider
+type Prompt struct {
+	System    string
+	User      string
+	MaxTokens int
+}
+
+// Provider turns a prompt into text with a tenant's credentials
+type Provider interface {
+	Complete(ctx context.Context, prompt Prompt) (string, error)
+}
+
+// ProviderFactory builds a provider from a tenant's AI settings
+type ProviderFactory func(cfg settings.AIConfig, client *http.Client) Provider
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]ProviderFactory{
+		"openai":    newOpenAIProvider,
+		"anthropic": newAnthropicProvider,
+	}
+)
+
+// RegisterProvider adds or replaces a provider selectable in the AI settings
+func RegisterProvider(name string, factory ProviderFactory) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[name] = factory
+}
+
+func lookupProvider(name string) (ProviderFactory, bool) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	factory, ok := providers[name]
+	return factory, ok
+}
+
+// postJSON sends body and decodes a JSON reply, reporting the provider's
+// message on error statuses
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			return fmt.Errorf("status %d: %s", resp.StatusCode, apiErr.Error.Message)
+		}
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return json.Unmarshal(data, out)
+}
+
+func baseURL(cfg settings.AIConfig, fallback string) string {
+	if cfg.BaseURL != "" {
+		return strings.TrimRight(cfg.BaseURL, "/")
+	}
+	return fallback
+}
+
+// openAIProvider uses the chat completions API
+type openAIProvider struct {
+	cfg    settings.AIConfig
+	client *http.Client
+}
+
+func newOpenAIProvider(cfg settings.AIConfig, client *http.Client) Provider {
+	if cfg.Model == "" {
+		cfg.Model = "gpt-4o-mini"
+	}
+	return &openAIProvider{cfg: cfg, client: client}
+}
+
+func (p *openAIProvider) Complete(ctx context.Context, prompt Prompt) (string, error) {
+	body := map[string]interface{}{
+		"model":      p.cfg.Model,
+		"max_tokens": prompt.MaxTokens,
+		"messages": []map[string]string{
+			{"role": "system", "content": prompt.System},
+			{"role": "user", "content": prompt.User},
+		},
+	}
+	var out struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	url := baseURL(p.cfg, "https://api.openai.com") + "/v1/chat/completions"
+	if err := postJSON(ctx, p.client, url, map[string]string{"Authorization": "Bearer " + p.cfg.APIKey}, body, &out); err != nil {
+		return "", err
+	}
+	if len(out.Choices) == 0 {
+		return "", fmt.Errorf("empty completion")
+	}
+	return out.Choices[0].Message.Content, nil
+}
+
+// anthropicProvider uses the messages API
+type anthropicProvider struct {
+	cfg    settings.AIConfig
+	client *http.Client
+}
+
+func newAnthropicProvider(cfg settings.AIConfig, client *http.Client) Provider {
+	if cfg.Model == "" {
+		cfg.Model = "claude-3-5-haiku-latest"
+	}
+	return &anthropicProvider{cfg: cfg, client: client}
+}
+
+func (p *anthropicProvider) Complete(ctx context.Context, prompt Prompt) (string, error) {
+	body := map[string]interface{}{
+		"model":      p.cfg.Model,
+		"max_tokens": prompt.MaxTokens,
+		"system":     prompt.System,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt.User},
+		},
+	}
+	var out struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	headers := map[string]string{"x-api-key": p.cfg.APIKey, "anthropic-version": "2023-06-01"}
+	url := baseURL(p.cfg, "https://api.anthropic.com") + "/v1/messages"
+	if err := postJSON(ctx, p.client, url, headers, body, &out); err != nil {
+		return "", err
+	}
+	var text strings.Builder
+	for _, part := range out.Content {
+		if part.Type == "text" {
+			text.WriteString(part.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("empty completion")
+	}
+	return text.String(), nil
+}
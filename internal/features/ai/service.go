@@ -0,0 +1,250 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go-crm/internal/features/comment"
+	"go-crm/internal/features/email_template"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/settings"
+	"go-crm/internal/features/ticket"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrNotConfigured is returned while the tenant has no AI provider enabled
+	ErrNotConfigured = errors.New("AI is not configured for this organization")
+	// ErrProvider wraps failures of the provider call
+	ErrProvider = errors.New("AI provider request failed")
+)
+
+const (
+	// maxInputChars caps the text sent per request; long threads keep their
+	// latest part
+	maxInputChars = 30000
+	// maxOutputChars keeps artifacts under the comment length limit
+	maxOutputChars = 9000
+	maxTokens      = 1024
+	styleExamples  = 3
+)
+
+// ActionItemsRequest names the meeting notes to read: Notes itself, or the
+// record field holding them
+type ActionItemsRequest struct {
+	Notes string `json:"notes"`
+	Field string `json:"field"`
+}
+
+type AIService interface {
+	// SummarizeTicket stores a summary of the ticket thread as an internal note
+	SummarizeTicket(ctx context.Context, ticketID string, userID primitive.ObjectID) (*comment.Comment, error)
+	// SuggestReply stores a reply draft, written in the style of the ticket
+	// email templates, as an internal note for the agent to edit and send
+	SuggestReply(ctx context.Context, ticketID string, userID primitive.ObjectID) (*comment.Comment, error)
+	// ExtractActionItems stores the action items of meeting notes as an
+	// internal note on the record
+	ExtractActionItems(ctx context.Context, moduleName, recordID string, req ActionItemsRequest, userID primitive.ObjectID) (*comment.Comment, error)
+}
+
+type AIServiceImpl struct {
+	SettingsService settings.SettingsService
+	TicketService   ticket.TicketService
+	CommentService  comment.CommentService
+	RecordService   record.RecordService
+	TemplateService email_template.EmailTemplateService
+	Client          *http.Client
+}
+
+func NewAIService(
+	settingsService settings.SettingsService,
+	ticketService ticket.TicketService,
+	commentService comment.CommentService,
+	recordService record.RecordService,
+	templateService email_template.EmailTemplateService,
+) AIService {
+	return &AIServiceImpl{
+		SettingsService: settingsService,
+		TicketService:   ticketService,
+		CommentService:  commentService,
+		RecordService:   recordService,
+		TemplateService: templateService,
+		Client:          &http.Client{Timeout: 90 * time.Second},
+	}
+}
+
+func (s *AIServiceImpl) SummarizeTicket(ctx context.Context, ticketID string, userID primitive.ObjectID) (*comment.Comment, error) {
+	thread, err := s.ticketThread(ctx, ticketID, userID)
+	if err != nil {
+		return nil, err
+	}
+	text, err := s.complete(ctx, Prompt{
+		System: "You summarize customer support tickets for the agents working on them. " +
+			"Write a short overview of the issue, what has been tried, the current state and any open question, " +
+			"as plain text with a few bullet points. Do not invent facts.",
+		User: thread,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.TicketService.AddComment(ctx, ticketID, ticket.TicketCommentRequest{CreateCommentRequest: artifact("AI summary", text)}, userID)
+}
+
+func (s *AIServiceImpl) SuggestReply(ctx context.Context, ticketID string, userID primitive.ObjectID) (*comment.Comment, error) {
+	thread, err := s.ticketThread(ctx, ticketID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	system := "You draft replies to customers for support agents. Answer the customer's latest message politely and concisely. " +
+		"Only promise what the thread supports; leave a [placeholder] where the agent must fill in details. Return the reply text only."
+	if examples := s.styleExamples(ctx); examples != "" {
+		system += "\n\nMatch the tone and structure of these canned responses:\n\n" + examples
+	}
+	text, err := s.complete(ctx, Prompt{System: system, User: thread})
+	if err != nil {
+		return nil, err
+	}
+	return s.TicketService.AddComment(ctx, ticketID, ticket.TicketCommentRequest{CreateCommentRequest: artifact("AI reply draft", text)}, userID)
+}
+
+func (s *AIServiceImpl) ExtractActionItems(ctx context.Context, moduleName, recordID string, req ActionItemsRequest, userID primitive.ObjectID) (*comment.Comment, error) {
+	notes := strings.TrimSpace(req.Notes)
+	if notes == "" {
+		if req.Field == "" {
+			return nil, errors.New("notes or field is required")
+		}
+		rec, err := s.RecordService.GetRecord(ctx, moduleName, recordID, userID)
+		if err != nil {
+			return nil, err
+		}
+		value, _ := rec[req.Field].(string)
+		if notes = strings.TrimSpace(value); notes == "" {
+			return nil, fmt.Errorf("field '%s' holds no notes", req.Field)
+		}
+	}
+
+	text, err := s.complete(ctx, Prompt{
+		System: "You extract action items from meeting notes. List each as '- [owner] task (due date)', " +
+			"leaving out owner or due date when the notes do not say. If there are none, answer 'No action items.'",
+		User: tail(notes, maxInputChars),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.CommentService.AddComment(ctx, moduleName, recordID, artifact("AI action items", text), userID)
+}
+
+// complete runs a prompt with the tenant's provider
+func (s *AIServiceImpl) complete(ctx context.Context, prompt Prompt) (string, error) {
+	cfg, err := s.SettingsService.GetAIConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if cfg == nil || !cfg.Enabled || cfg.APIKey == "" {
+		return "", ErrNotConfigured
+	}
+	factory, ok := lookupProvider(cfg.Provider)
+	if !ok {
+		return "", fmt.Errorf("%w: unknown provider '%s'", ErrNotConfigured, cfg.Provider)
+	}
+	if prompt.MaxTokens == 0 {
+		prompt.MaxTokens = maxTokens
+	}
+	text, err := factory(*cfg, s.Client).Complete(ctx, prompt)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrProvider, err)
+	}
+	return strings.TrimSpace(text), nil
+}
+
+// ticketThread renders a ticket and its comments, oldest first, leaving
+// out earlier AI notes
+func (s *AIServiceImpl) ticketThread(ctx context.Context, ticketID string, userID primitive.ObjectID) (string, error) {
+	t, err := s.TicketService.GetTicket(ctx, ticketID)
+	if err != nil {
+		return "", err
+	}
+	comments, err := s.TicketService.ListComments(ctx, ticketID, userID)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Ticket %s: %s\nStatus: %s, priority: %s\n\n", t.TicketNumber, t.Subject, t.Status, t.Priority)
+	fmt.Fprintf(&b, "[%s] Customer:\n%s\n", t.CreatedAt.Format(time.RFC3339), t.Description)
+	for _, c := range comments {
+		if c.AIGenerated || c.IsDeleted {
+			continue
+		}
+		author := "Agent"
+		if t.CustomerID != nil && c.CreatedBy == *t.CustomerID {
+			author = "Customer"
+		}
+		if c.IsInternal {
+			author += " (internal note)"
+		}
+		fmt.Fprintf(&b, "\n[%s] %s:\n%s\n", c.CreatedAt.Format(time.RFC3339), author, c.Content)
+	}
+	return tail(b.String(), maxInputChars), nil
+}
+
+// styleExamples returns a few active ticket email templates as examples
+func (s *AIServiceImpl) styleExamples(ctx context.Context) string {
+	if s.TemplateService == nil {
+		return ""
+	}
+	templates, err := s.TemplateService.ListTemplates(ctx, ticket.CommentModuleName, false)
+	if err != nil {
+		return ""
+	}
+	var examples []string
+	for _, tpl := range templates {
+		if !tpl.IsActive || strings.TrimSpace(tpl.Body) == "" {
+			continue
+		}
+		examples = append(examples, tail(tpl.Body, 2000))
+		if len(examples) == styleExamples {
+			break
+		}
+	}
+	return strings.Join(examples, "\n\n---\n\n")
+}
+
+// artifact is the internal note storing generated text
+func artifact(title, text string) comment.CreateCommentRequest {
+	if len(text) > maxOutputChars {
+		cut := maxOutputChars
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut] + "…"
+	}
+	return comment.CreateCommentRequest{
+		Content:     title + ":\n\n" + text,
+		IsInternal:  true,
+		AIGenerated: true,
+	}
+}
+
+// tail keeps the last n bytes of s, cut at a line start where possible
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	start := len(s) - n
+	for start < len(s) && !utf8.RuneStart(s[start]) {
+		start++
+	}
+	s = s[start:]
+	if i := strings.IndexByte(s, '\n'); i >= 0 && i < 200 {
+		s = s[i+1:]
+	}
+	return s
+}
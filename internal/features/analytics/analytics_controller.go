@@ -2,7 +2,8 @@ package analytics
 
 import (
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"go-crm/internal/common/session"
 )
 
 type AnalyticsController struct {
@@ -27,10 +28,7 @@ func (c *AnalyticsController) CreateMetric(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	// Get user ID from context
-	if userID, ok := ctx.Locals("user_id").(primitive.ObjectID); ok {
-		metric.CreatedBy = userID
-	}
+	metric.CreatedBy, _ = session.UserID(ctx.UserContext())
 
 	if err := c.Service.CreateMetric(ctx.UserContext(), &metric); err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
@@ -1,10 +1,10 @@
 package analytics
 
 import (
+	"go-crm/internal/common/session"
 	"go-crm/internal/connectors"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type DataSourceController struct {
@@ -29,10 +29,7 @@ func (c *DataSourceController) CreateDataSource(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	// Get user ID from context
-	if userID, ok := ctx.Locals("user_id").(primitive.ObjectID); ok {
-		dataSource.CreatedBy = userID
-	}
+	dataSource.CreatedBy, _ = session.UserID(ctx.UserContext())
 
 	if err := c.Service.CreateDataSource(ctx.UserContext(), &dataSource); err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
@@ -65,3 +65,61 @@ type TimeRange struct {
 	End      time.Time `json:"end"`
 	Interval string    `json:"interval"` // "day", "week", "month"
 }
+
+// ActivityCounts is one user's (or a team's) activity over a period
+type ActivityCounts struct {
+	RecordsCreated  int64 `json:"records_created"`
+	RecordsUpdated  int64 `json:"records_updated"`
+	CallsLogged     int64 `json:"calls_logged"`
+	MeetingsLogged  int64 `json:"meetings_logged"`
+	TasksCompleted  int64 `json:"tasks_completed"`
+	TicketsResolved int64 `json:"tickets_resolved"`
+	EmailsSent      int64 `json:"emails_sent"`
+}
+
+// ProductivityPeriod is one bucket of a timeline
+type ProductivityPeriod struct {
+	Start  time.Time      `json:"start"`
+	Counts ActivityCounts `json:"counts"`
+}
+
+// UserProductivity compares a user's period with the previous period of equal
+// length and with the team average
+type UserProductivity struct {
+	UserID    primitive.ObjectID   `json:"user_id"`
+	Name      string               `json:"name"`
+	Totals    ActivityCounts       `json:"totals"`
+	Previous  ActivityCounts       `json:"previous"`
+	Change    map[string]*float64  `json:"change"`      // % vs previous period; nil when previous is 0
+	VsTeamAvg map[string]*float64  `json:"vs_team_avg"` // % vs team average; nil when the average is 0
+	Timeline  []ProductivityPeriod `json:"timeline"`
+}
+
+// TeamProductivity rolls the included users up
+type TeamProductivity struct {
+	Members  int                  `json:"members"`
+	Totals   ActivityCounts       `json:"totals"`
+	Previous ActivityCounts       `json:"previous"`
+	Average  map[string]float64   `json:"average"` // per member
+	Change   map[string]*float64  `json:"change"`
+	Timeline []ProductivityPeriod `json:"timeline"`
+}
+
+type ProductivityReport struct {
+	Range         TimeRange          `json:"range"`
+	PreviousRange TimeRange          `json:"previous_range"`
+	Timezone      string             `json:"timezone"`
+	Team          TeamProductivity   `json:"team"`
+	Users         []UserProductivity `json:"users"`
+}
+
+// ProductivityQuery selects the period and the team. The team is the union of
+// UserIDs, the members of GroupID and the direct reports of ManagerID; when
+// all are empty every active user is included.
+type ProductivityQuery struct {
+	Range     TimeRange
+	Timezone  string
+	UserIDs   []primitive.ObjectID
+	GroupID   string
+	ManagerID string
+}
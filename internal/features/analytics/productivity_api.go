@@ -0,0 +1,28 @@
+package analytics
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/features/role"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ProductivityApi struct {
+	Controller  *ProductivityController
+	RoleService role.RoleService
+	Config      *config.Config
+}
+
+func NewProductivityApi(controller *ProductivityController, roleService role.RoleService, config *config.Config) *ProductivityApi {
+	return &ProductivityApi{
+		Controller:  controller,
+		RoleService: roleService,
+		Config:      config,
+	}
+}
+
+func (a *ProductivityApi) Setup(app *fiber.App) {
+	group := app.Group("/api/analytics/productivity", middleware.AuthMiddleware(a.Config.SkipAuth))
+	group.Get("/", middleware.RequirePermission(a.RoleService, "reports", "read"), a.Controller.GetProductivity)
+}
@@ -0,0 +1,89 @@
+package analytics
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ProductivityController struct {
+	Service ProductivityService
+}
+
+func NewProductivityController(service ProductivityService) *ProductivityController {
+	return &ProductivityController{Service: service}
+}
+
+// GetProductivity aggregates user activity per period
+// @Summary Get productivity report
+// @Description Records created/updated, calls and meetings logged, tasks completed, tickets resolved and emails sent per user per period, with a team rollup and comparison against the previous period of equal length
+// @Tags analytics
+// @Produce json
+// @Param start query string true "Start date (YYYY-MM-DD or RFC3339)"
+// @Param end query string true "End date (YYYY-MM-DD, inclusive, or RFC3339)"
+// @Param interval query string false "day, week or month (default day)"
+// @Param timezone query string false "IANA timezone used for period boundaries (default UTC)"
+// @Param user_ids query string false "Comma-separated user IDs"
+// @Param group_id query string false "Include members of this group"
+// @Param manager_id query string false "Include direct reports of this user"
+// @Success 200 {object} ProductivityReport
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/analytics/productivity [get]
+func (c *ProductivityController) GetProductivity(ctx *fiber.Ctx) error {
+	q := ProductivityQuery{
+		Timezone:  ctx.Query("timezone"),
+		GroupID:   ctx.Query("group_id"),
+		ManagerID: ctx.Query("manager_id"),
+	}
+	q.Range.Interval = ctx.Query("interval")
+
+	loc := time.UTC
+	if q.Timezone != "" {
+		l, err := time.LoadLocation(q.Timezone)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown timezone"})
+		}
+		loc = l
+	}
+
+	start, _, err := parseReportTime(ctx.Query("start"), loc)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid start (YYYY-MM-DD or RFC3339)"})
+	}
+	end, dateOnly, err := parseReportTime(ctx.Query("end"), loc)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid end (YYYY-MM-DD or RFC3339)"})
+	}
+	if dateOnly {
+		end = end.AddDate(0, 0, 1)
+	}
+	q.Range.Start, q.Range.End = start, end
+
+	if ids := ctx.Query("user_ids"); ids != "" {
+		for _, raw := range strings.Split(ids, ",") {
+			oid, err := primitive.ObjectIDFromHex(strings.TrimSpace(raw))
+			if err != nil {
+				return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID: " + raw})
+			}
+			q.UserIDs = append(q.UserIDs, oid)
+		}
+	}
+
+	report, err := c.Service.GetProductivity(ctx.UserContext(), q)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(report)
+}
+
+// parseReportTime accepts a date in loc or an RFC3339 timestamp
+func parseReportTime(value string, loc *time.Location) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, loc); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, false, err
+}
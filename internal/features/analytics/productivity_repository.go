@@ -0,0 +1,179 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// activityBucket is one aggregated row: how often an actor did one kind of
+// thing in one period
+type activityBucket struct {
+	Actor  string
+	Period time.Time
+	Action common_models.AuditAction
+	Module string
+	Status interface{} // new value of changes.status, if any
+	Count  int64
+}
+
+// ProductivityRepository aggregates user activity from the audit log and the
+// sent email log
+type ProductivityRepository interface {
+	AuditActivity(ctx context.Context, start, end time.Time, interval, timezone string, actorIDs []string) ([]activityBucket, error)
+	EmailsSent(ctx context.Context, start, end time.Time, interval, timezone string, senders []primitive.ObjectID) ([]activityBucket, error)
+}
+
+type ProductivityRepositoryImpl struct {
+	auditLogs database.Collections
+	emails    database.Collections
+}
+
+func NewProductivityRepository(db *database.MongodbDB) ProductivityRepository {
+	return &ProductivityRepositoryImpl{
+		auditLogs: db.Collections("audit_logs"),
+		emails:    db.Collections("emails"),
+	}
+}
+
+func productivityTenant(ctx context.Context) (primitive.ObjectID, error) {
+	tenantID, ok := ctx.Value(common_models.TenantIDKey).(string)
+	if !ok || tenantID == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantID)
+}
+
+// periodExpr truncates a date field to the start of its bucket in timezone
+func periodExpr(field, interval, timezone string) bson.M {
+	trunc := bson.M{"date": field, "unit": interval, "timezone": timezone}
+	if interval == "week" {
+		trunc["startOfWeek"] = "monday"
+	}
+	return bson.M{"$dateTrunc": trunc}
+}
+
+func (r *ProductivityRepositoryImpl) AuditActivity(ctx context.Context, start, end time.Time, interval, timezone string, actorIDs []string) ([]activityBucket, error) {
+	tenantID, err := productivityTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	match := bson.M{
+		"tenant_id": tenantID,
+		"timestamp": bson.M{"$gte": start, "$lt": end},
+		"action":    bson.M{"$in": []common_models.AuditAction{common_models.AuditActionCreate, common_models.AuditActionUpdate}},
+	}
+	if len(actorIDs) > 0 {
+		match["actor_id"] = bson.M{"$in": actorIDs}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"actor":  "$actor_id",
+				"period": periodExpr("$timestamp", interval, timezone),
+				"action": "$action",
+				"module": "$module",
+				"status": "$changes.status.new",
+			},
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+
+	cursor, err := r.auditLogs.Read(ctx, true).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		ID struct {
+			Actor  string                    `bson:"actor"`
+			Period time.Time                 `bson:"period"`
+			Action common_models.AuditAction `bson:"action"`
+			Module string                    `bson:"module"`
+			Status interface{}               `bson:"status"`
+		} `bson:"_id"`
+		Count int64 `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	buckets := make([]activityBucket, 0, len(rows))
+	for _, row := range rows {
+		buckets = append(buckets, activityBucket{
+			Actor:  row.ID.Actor,
+			Period: row.ID.Period,
+			Action: row.ID.Action,
+			Module: row.ID.Module,
+			Status: row.ID.Status,
+			Count:  row.Count,
+		})
+	}
+	return buckets, nil
+}
+
+func (r *ProductivityRepositoryImpl) EmailsSent(ctx context.Context, start, end time.Time, interval, timezone string, senders []primitive.ObjectID) ([]activityBucket, error) {
+	tenantID, err := productivityTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	match := bson.M{
+		"orgId":  tenantID,
+		"status": "sent",
+		"sentAt": bson.M{"$gte": start, "$lt": end},
+		"sentBy": bson.M{"$exists": true},
+	}
+	if len(senders) > 0 {
+		match["sentBy"] = bson.M{"$in": senders}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"sender": "$sentBy",
+				"period": periodExpr("$sentAt", interval, timezone),
+			},
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+
+	cursor, err := r.emails.Read(ctx, true).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		ID struct {
+			Sender primitive.ObjectID `bson:"sender"`
+			Period time.Time          `bson:"period"`
+		} `bson:"_id"`
+		Count int64 `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	buckets := make([]activityBucket, 0, len(rows))
+	for _, row := range rows {
+		buckets = append(buckets, activityBucket{
+			Actor:  row.ID.Sender.Hex(),
+			Period: row.ID.Period,
+			Count:  row.Count,
+		})
+	}
+	return buckets, nil
+}
@@ -0,0 +1,340 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/group"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/user"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxProductivityBuckets keeps a report to a chartable number of periods
+const maxProductivityBuckets = 400
+
+type ProductivityService interface {
+	GetProductivity(ctx context.Context, q ProductivityQuery) (*ProductivityReport, error)
+}
+
+type ProductivityServiceImpl struct {
+	Repo         ProductivityRepository
+	ModuleRepo   module.ModuleRepository
+	UserRepo     user.UserRepository
+	GroupService group.GroupService
+}
+
+func NewProductivityService(repo ProductivityRepository, moduleRepo module.ModuleRepository, userRepo user.UserRepository, groupService group.GroupService) ProductivityService {
+	return &ProductivityServiceImpl{
+		Repo:         repo,
+		ModuleRepo:   moduleRepo,
+		UserRepo:     userRepo,
+		GroupService: groupService,
+	}
+}
+
+func (s *ProductivityServiceImpl) GetProductivity(ctx context.Context, q ProductivityQuery) (*ProductivityReport, error) {
+	if q.Timezone == "" {
+		q.Timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(q.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone '%s'", q.Timezone)
+	}
+	if q.Range.Interval == "" {
+		q.Range.Interval = "day"
+	}
+	if q.Range.Interval != "day" && q.Range.Interval != "week" && q.Range.Interval != "month" {
+		return nil, errors.New("interval must be day, week or month")
+	}
+	if q.Range.Start.IsZero() || q.Range.End.IsZero() || !q.Range.End.After(q.Range.Start) {
+		return nil, errors.New("a valid start and end are required")
+	}
+
+	periods := periodStarts(q.Range.Start, q.Range.End, q.Range.Interval, loc)
+	if len(periods) > maxProductivityBuckets {
+		return nil, fmt.Errorf("range spans more than %d %ss; use a larger interval", maxProductivityBuckets, q.Range.Interval)
+	}
+
+	users, err := s.team(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	recordModules := map[string]bool{}
+	if modules, err := s.ModuleRepo.List(ctx); err == nil {
+		for _, m := range modules {
+			recordModules[m.Name] = true
+		}
+	}
+
+	length := q.Range.End.Sub(q.Range.Start)
+	previous := TimeRange{Start: q.Range.Start.Add(-length), End: q.Range.Start, Interval: q.Range.Interval}
+
+	current, err := s.collect(ctx, q.Range, q.Timezone, users, recordModules)
+	if err != nil {
+		return nil, err
+	}
+	before, err := s.collect(ctx, previous, q.Timezone, users, recordModules)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ProductivityReport{
+		Range:         q.Range,
+		PreviousRange: previous,
+		Timezone:      q.Timezone,
+		Users:         make([]UserProductivity, 0, len(users)),
+	}
+
+	teamTimeline := map[int64]*ActivityCounts{}
+	for _, u := range users {
+		id := u.ID.Hex()
+		up := UserProductivity{
+			UserID:   u.ID,
+			Name:     displayName(u),
+			Totals:   current.totals[id],
+			Previous: before.totals[id],
+			Timeline: make([]ProductivityPeriod, 0, len(periods)),
+		}
+		up.Change = percentChange(up.Totals.values(), up.Previous.values())
+		for _, p := range periods {
+			counts := current.timeline[id][p.Unix()]
+			up.Timeline = append(up.Timeline, ProductivityPeriod{Start: p, Counts: counts})
+			if teamTimeline[p.Unix()] == nil {
+				teamTimeline[p.Unix()] = &ActivityCounts{}
+			}
+			teamTimeline[p.Unix()].add(counts)
+		}
+		report.Team.Totals.add(up.Totals)
+		report.Team.Previous.add(up.Previous)
+		report.Users = append(report.Users, up)
+	}
+
+	report.Team.Members = len(users)
+	report.Team.Change = percentChange(report.Team.Totals.values(), report.Team.Previous.values())
+	report.Team.Average = map[string]float64{}
+	for k, v := range report.Team.Totals.values() {
+		if len(users) > 0 {
+			report.Team.Average[k] = v / float64(len(users))
+		}
+	}
+	report.Team.Timeline = make([]ProductivityPeriod, 0, len(periods))
+	for _, p := range periods {
+		counts := ActivityCounts{}
+		if c := teamTimeline[p.Unix()]; c != nil {
+			counts = *c
+		}
+		report.Team.Timeline = append(report.Team.Timeline, ProductivityPeriod{Start: p, Counts: counts})
+	}
+
+	for i := range report.Users {
+		report.Users[i].VsTeamAvg = percentChange(report.Users[i].Totals.values(), report.Team.Average)
+	}
+	sort.SliceStable(report.Users, func(i, j int) bool {
+		return report.Users[i].Totals.sum() > report.Users[j].Totals.sum()
+	})
+
+	return report, nil
+}
+
+// team resolves the users the report covers
+func (s *ProductivityServiceImpl) team(ctx context.Context, q ProductivityQuery) ([]common_models.User, error) {
+	if len(q.UserIDs) == 0 && q.GroupID == "" && q.ManagerID == "" {
+		users, _, err := s.UserRepo.List(ctx, map[string]interface{}{"status": bson.M{"$ne": "inactive"}}, 0, 0)
+		return users, err
+	}
+
+	ids := append([]primitive.ObjectID{}, q.UserIDs...)
+	if q.GroupID != "" {
+		groupID, err := primitive.ObjectIDFromHex(q.GroupID)
+		if err != nil {
+			return nil, errors.New("invalid group_id")
+		}
+		g, err := s.GroupService.GetGroupByID(ctx, groupID)
+		if err != nil || g == nil {
+			return nil, errors.New("group not found")
+		}
+		ids = append(ids, g.Members...)
+	}
+
+	filters := []bson.M{}
+	if len(ids) > 0 {
+		filters = append(filters, bson.M{"_id": bson.M{"$in": ids}})
+	}
+	if q.ManagerID != "" {
+		managerID, err := primitive.ObjectIDFromHex(q.ManagerID)
+		if err != nil {
+			return nil, errors.New("invalid manager_id")
+		}
+		filters = append(filters, bson.M{"reports_to": managerID})
+	}
+	if len(filters) == 0 {
+		return []common_models.User{}, nil
+	}
+
+	users, _, err := s.UserRepo.List(ctx, map[string]interface{}{"$or": filters}, 0, 0)
+	return users, err
+}
+
+type activitySet struct {
+	totals   map[string]ActivityCounts
+	timeline map[string]map[int64]ActivityCounts
+}
+
+func (a *activitySet) add(actor string, period time.Time, apply func(c *ActivityCounts)) {
+	t := a.totals[actor]
+	apply(&t)
+	a.totals[actor] = t
+
+	if a.timeline[actor] == nil {
+		a.timeline[actor] = map[int64]ActivityCounts{}
+	}
+	p := a.timeline[actor][period.Unix()]
+	apply(&p)
+	a.timeline[actor][period.Unix()] = p
+}
+
+func (s *ProductivityServiceImpl) collect(ctx context.Context, r TimeRange, timezone string, users []common_models.User, recordModules map[string]bool) (*activitySet, error) {
+	set := &activitySet{totals: map[string]ActivityCounts{}, timeline: map[string]map[int64]ActivityCounts{}}
+	if len(users) == 0 {
+		return set, nil
+	}
+
+	actorIDs := make([]string, 0, len(users))
+	senders := make([]primitive.ObjectID, 0, len(users))
+	for _, u := range users {
+		actorIDs = append(actorIDs, u.ID.Hex())
+		senders = append(senders, u.ID)
+	}
+
+	audit, err := s.Repo.AuditActivity(ctx, r.Start, r.End, r.Interval, timezone, actorIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range audit {
+		b := b
+		set.add(b.Actor, b.Period, func(c *ActivityCounts) { c.apply(b, recordModules) })
+	}
+
+	emails, err := s.Repo.EmailsSent(ctx, r.Start, r.End, r.Interval, timezone, senders)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range emails {
+		count := b.Count
+		set.add(b.Actor, b.Period, func(c *ActivityCounts) { c.EmailsSent += count })
+	}
+	return set, nil
+}
+
+// apply counts an audit bucket toward the metrics it represents
+func (c *ActivityCounts) apply(b activityBucket, recordModules map[string]bool) {
+	status := strings.ToLower(fmt.Sprintf("%v", b.Status))
+	switch b.Action {
+	case common_models.AuditActionCreate:
+		if recordModules[b.Module] {
+			c.RecordsCreated += b.Count
+		}
+		switch b.Module {
+		case "calls":
+			c.CallsLogged += b.Count
+		case "meetings":
+			c.MeetingsLogged += b.Count
+		}
+	case common_models.AuditActionUpdate:
+		if recordModules[b.Module] {
+			c.RecordsUpdated += b.Count
+		}
+		switch {
+		case b.Module == "tasks" && (status == "completed" || status == "done"):
+			c.TasksCompleted += b.Count
+		case b.Module == "tickets" && status == "resolved":
+			c.TicketsResolved += b.Count
+		}
+	}
+}
+
+func (c *ActivityCounts) add(o ActivityCounts) {
+	c.RecordsCreated += o.RecordsCreated
+	c.RecordsUpdated += o.RecordsUpdated
+	c.CallsLogged += o.CallsLogged
+	c.MeetingsLogged += o.MeetingsLogged
+	c.TasksCompleted += o.TasksCompleted
+	c.TicketsResolved += o.TicketsResolved
+	c.EmailsSent += o.EmailsSent
+}
+
+func (c ActivityCounts) values() map[string]float64 {
+	return map[string]float64{
+		"records_created":  float64(c.RecordsCreated),
+		"records_updated":  float64(c.RecordsUpdated),
+		"calls_logged":     float64(c.CallsLogged),
+		"meetings_logged":  float64(c.MeetingsLogged),
+		"tasks_completed":  float64(c.TasksCompleted),
+		"tickets_resolved": float64(c.TicketsResolved),
+		"emails_sent":      float64(c.EmailsSent),
+	}
+}
+
+func (c ActivityCounts) sum() int64 {
+	return c.RecordsCreated + c.RecordsUpdated + c.CallsLogged + c.MeetingsLogged + c.TasksCompleted + c.TicketsResolved + c.EmailsSent
+}
+
+func percentChange(current, baseline map[string]float64) map[string]*float64 {
+	out := make(map[string]*float64, len(current))
+	for k, v := range current {
+		base := baseline[k]
+		if base == 0 {
+			out[k] = nil
+			continue
+		}
+		pct := (v - base) / base * 100
+		out[k] = &pct
+	}
+	return out
+}
+
+// periodStarts lists bucket starts covering [start, end) in loc, matching
+// the $dateTrunc boundaries used by the repository
+func periodStarts(start, end time.Time, interval string, loc *time.Location) []time.Time {
+	t := start.In(loc)
+	switch interval {
+	case "month":
+		t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	case "week":
+		t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		t = t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+	default:
+		t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+
+	var periods []time.Time
+	for t.Before(end) && len(periods) <= maxProductivityBuckets {
+		periods = append(periods, t.UTC())
+		switch interval {
+		case "month":
+			t = t.AddDate(0, 1, 0)
+		case "week":
+			t = t.AddDate(0, 0, 7)
+		default:
+			t = t.AddDate(0, 0, 1)
+		}
+	}
+	return periods
+}
+
+func displayName(u common_models.User) string {
+	name := strings.TrimSpace(u.FirstName + " " + u.LastName)
+	if name == "" {
+		return u.Username
+	}
+	return name
+}
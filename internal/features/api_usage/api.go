@@ -0,0 +1,28 @@
+package api_usage
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/features/role"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type UsageApi struct {
+	controller  *UsageController
+	config      *config.Config
+	roleService role.RoleService
+}
+
+func NewUsageApi(controller *UsageController, config *config.Config, roleService role.RoleService) *UsageApi {
+	return &UsageApi{
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *UsageApi) Setup(app *fiber.App) {
+	group := app.Group("/api/admin/api-usage", middleware.AuthMiddleware(h.config.SkipAuth))
+	group.Get("/", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.GetReport)
+}
@@ -0,0 +1,68 @@
+package api_usage
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type UsageController struct {
+	Service UsageService
+}
+
+func NewUsageController(service UsageService) *UsageController {
+	return &UsageController{Service: service}
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// GetReport godoc
+// @Summary API usage report
+// @Description Request counts, error rates and latency percentiles of the tenant, broken down by user, key (a fingerprint of the token used) or endpoint, with the busiest endpoints. Counts are written about once a minute.
+// @Tags admin
+// @Produce json
+// @Param from query string false "Start, RFC 3339 or YYYY-MM-DD (default 24 hours ago)"
+// @Param to query string false "End (default now)"
+// @Param group_by query string false "user, key or endpoint (default)"
+// @Param limit query int false "Rows (default 20, max 200)"
+// @Success 200 {object} UsageReport
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/admin/api-usage [get]
+func (ctrl *UsageController) GetReport(c *fiber.Ctx) error {
+	from, err := parseTime(c.Query("from"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid from"})
+	}
+	to, err := parseTime(c.Query("to"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid to"})
+	}
+	groupBy := c.Query("group_by")
+	// Other tenants are not visible to a tenant admin
+	if groupBy == GroupByTenant {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "group_by must be user, key or endpoint"})
+	}
+
+	report, err := ctrl.Service.Report(c.UserContext(), ReportQuery{
+		From:    from,
+		To:      to,
+		GroupBy: groupBy,
+		Limit:   c.QueryInt("limit", 0),
+	})
+	if err != nil {
+		if errors.Is(err, ErrInvalidGroupBy) || errors.Is(err, ErrInvalidRange) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(report)
+}
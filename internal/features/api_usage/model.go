@@ -0,0 +1,73 @@
+package api_usage
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Dimensions a report can be broken down by
+const (
+	GroupByTenant   = "tenant"
+	GroupByUser     = "user"
+	GroupByKey      = "key"
+	GroupByEndpoint = "endpoint"
+)
+
+// LatencyBuckets are the upper bounds, in milliseconds, of the latency
+// histogram kept per rollup; slower requests fall in a last, open bucket
+var LatencyBuckets = []int64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// Rollup counts the requests of one tenant, user, key and endpoint in an hour
+type Rollup struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID     primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	Hour         time.Time          `json:"hour" bson:"hour"`
+	UserID       string             `json:"user_id" bson:"user_id"`
+	KeyID        string             `json:"key_id" bson:"key_id"`
+	Method       string             `json:"method" bson:"method"`
+	Route        string             `json:"route" bson:"route"`
+	Requests     int64              `json:"requests" bson:"requests"`
+	ClientErrors int64              `json:"client_errors" bson:"client_errors"` // 4xx
+	Errors       int64              `json:"errors" bson:"errors"`               // 5xx
+	TotalMs      int64              `json:"total_ms" bson:"total_ms"`
+	// Latency holds request counts per LatencyBuckets entry, plus the open
+	// bucket. It is stored as an object keyed by index.
+	Latency []int64 `json:"latency" bson:"-"`
+}
+
+// ReportQuery selects the rollups of a report
+type ReportQuery struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	GroupBy string    `json:"group_by"`
+	Limit   int       `json:"limit"`
+}
+
+// UsageStats are the figures reported for a group of requests
+type UsageStats struct {
+	Requests     int64   `json:"requests"`
+	ClientErrors int64   `json:"client_errors"`
+	Errors       int64   `json:"errors"`
+	ErrorRate    float64 `json:"error_rate"` // Share of requests answered with 5xx
+	AvgMs        float64 `json:"avg_ms"`
+	// Percentiles are estimated from the histogram as the bucket's upper
+	// bound; requests past the last bound report that bound
+	P50Ms int64 `json:"p50_ms"`
+	P95Ms int64 `json:"p95_ms"`
+	P99Ms int64 `json:"p99_ms"`
+}
+
+type UsageRow struct {
+	Key string `json:"key"` // Tenant, user or key ID, or "METHOD route"
+	UsageStats
+}
+
+type UsageReport struct {
+	From         time.Time  `json:"from"`
+	To           time.Time  `json:"to"`
+	GroupBy      string     `json:"group_by"`
+	Totals       UsageStats `json:"totals"`
+	Rows         []UsageRow `json:"rows"`
+	TopEndpoints []UsageRow `json:"top_endpoints"`
+}
@@ -0,0 +1,179 @@
+package api_usage
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// rollupRetention is how long hourly rollups are kept
+const rollupRetention = 90 * 24 * time.Hour
+
+// usageGroup is a rollup summed over a report dimension
+type usageGroup struct {
+	Key          string
+	Requests     int64
+	ClientErrors int64
+	Errors       int64
+	TotalMs      int64
+	Latency      []int64
+}
+
+type UsageRepository interface {
+	// Increment adds the counts of each rollup to the stored one for its hour
+	Increment(ctx context.Context, rollups []*Rollup) error
+	// Aggregate sums the rollups of [from, to) by groupBy, busiest first. A
+	// nil tenant covers every tenant; an empty groupBy returns one total.
+	Aggregate(ctx context.Context, tenantID primitive.ObjectID, from, to time.Time, groupBy string, limit int) ([]usageGroup, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type UsageRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewUsageRepository(db *database.MongodbDB) UsageRepository {
+	return &UsageRepositoryImpl{
+		collection: db.DB.Collection("api_usage_rollups"),
+	}
+}
+
+func (r *UsageRepositoryImpl) Increment(ctx context.Context, rollups []*Rollup) error {
+	if len(rollups) == 0 {
+		return nil
+	}
+	models := make([]mongo.WriteModel, 0, len(rollups))
+	for _, ru := range rollups {
+		inc := bson.M{
+			"requests":      ru.Requests,
+			"client_errors": ru.ClientErrors,
+			"errors":        ru.Errors,
+			"total_ms":      ru.TotalMs,
+		}
+		for i, n := range ru.Latency {
+			if n > 0 {
+				inc["latency."+strconv.Itoa(i)] = n
+			}
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{
+				"tenant_id": ru.TenantID,
+				"hour":      ru.Hour,
+				"user_id":   ru.UserID,
+				"key_id":    ru.KeyID,
+				"method":    ru.Method,
+				"route":     ru.Route,
+			}).
+			SetUpdate(bson.M{"$inc": inc}).
+			SetUpsert(true))
+	}
+	_, err := r.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+func (r *UsageRepositoryImpl) Aggregate(ctx context.Context, tenantID primitive.ObjectID, from, to time.Time, groupBy string, limit int) ([]usageGroup, error) {
+	match := bson.M{"hour": bson.M{"$gte": from, "$lt": to}}
+	if !tenantID.IsZero() {
+		match["tenant_id"] = tenantID
+	}
+
+	var key any
+	switch groupBy {
+	case GroupByTenant:
+		key = bson.M{"$toString": "$tenant_id"}
+	case GroupByUser:
+		key = "$user_id"
+	case GroupByKey:
+		key = "$key_id"
+	case GroupByEndpoint:
+		key = bson.M{"$concat": bson.A{"$method", " ", "$route"}}
+	}
+	group := bson.M{
+		"_id":           key,
+		"requests":      bson.M{"$sum": "$requests"},
+		"client_errors": bson.M{"$sum": "$client_errors"},
+		"errors":        bson.M{"$sum": "$errors"},
+		"total_ms":      bson.M{"$sum": "$total_ms"},
+	}
+	// The histogram is stored as an object keyed by bucket index so upserts can $inc it
+	for i := 0; i <= len(LatencyBuckets); i++ {
+		group["l"+strconv.Itoa(i)] = bson.M{"$sum": "$latency." + strconv.Itoa(i)}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: group}},
+		{{Key: "$sort", Value: bson.D{{Key: "requests", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+	if limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: limit}})
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []bson.M
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	groups := make([]usageGroup, 0, len(docs))
+	for _, doc := range docs {
+		key, _ := doc["_id"].(string)
+		g := usageGroup{
+			Key:          key,
+			Requests:     toInt64(doc["requests"]),
+			ClientErrors: toInt64(doc["client_errors"]),
+			Errors:       toInt64(doc["errors"]),
+			TotalMs:      toInt64(doc["total_ms"]),
+			Latency:      make([]int64, len(LatencyBuckets)+1),
+		}
+		for i := range g.Latency {
+			g.Latency[i] = toInt64(doc["l"+strconv.Itoa(i)])
+		}
+		groups = append(groups, g)
+	}
+	return groups, nil
+}
+
+func toInt64(v any) int64 {
+	switch n := v.(type) {
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	case float64:
+		return int64(n)
+	}
+	return 0
+}
+
+func (r *UsageRepositoryImpl) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "hour", Value: 1},
+				{Key: "user_id", Value: 1},
+				{Key: "key_id", Value: 1},
+				{Key: "method", Value: 1},
+				{Key: "route", Value: 1},
+			},
+			Options: options.Index().SetName("idx_rollup").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "hour", Value: 1}},
+			Options: options.Index().SetName("idx_rollup_ttl").SetExpireAfterSeconds(int32(rollupRetention.Seconds())),
+		},
+	})
+	return err
+}
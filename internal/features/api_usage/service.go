@@ -0,0 +1,282 @@
+package api_usage
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/middleware"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// FlushInterval is how often buffered counts are written
+	FlushInterval = time.Minute
+
+	defaultReportRange = 24 * time.Hour
+	maxReportRange     = rollupRetention
+	defaultReportRows  = 20
+	maxReportRows      = 200
+	topEndpointCount   = 10
+)
+
+var (
+	ErrInvalidGroupBy = errors.New("group_by must be tenant, user, key or endpoint")
+	ErrInvalidRange   = errors.New("from must be before to and at most 90 days earlier")
+)
+
+type UsageService interface {
+	// Record counts a request; it only buffers, so it is safe on the request path
+	Record(event middleware.UsageEvent)
+	// Flush writes the buffered counts
+	Flush(ctx context.Context) error
+	// Run flushes every FlushInterval until ctx is done, then flushes once more
+	Run(ctx context.Context)
+	// Report covers the caller's tenant
+	Report(ctx context.Context, q ReportQuery) (*UsageReport, error)
+	// ReportAll covers every tenant, for operators
+	ReportAll(ctx context.Context, q ReportQuery) (*UsageReport, error)
+}
+
+type rollupKey struct {
+	tenantID primitive.ObjectID
+	hour     time.Time
+	userID   string
+	keyID    string
+	method   string
+	route    string
+}
+
+type UsageServiceImpl struct {
+	Repo UsageRepository
+
+	mu      sync.Mutex
+	pending map[rollupKey]*Rollup
+}
+
+func NewUsageService(repo UsageRepository) UsageService {
+	return &UsageServiceImpl{
+		Repo:    repo,
+		pending: map[rollupKey]*Rollup{},
+	}
+}
+
+func (s *UsageServiceImpl) Record(e middleware.UsageEvent) {
+	tenantID, err := primitive.ObjectIDFromHex(e.TenantID)
+	if err != nil {
+		return
+	}
+	key := rollupKey{
+		tenantID: tenantID,
+		hour:     e.At.UTC().Truncate(time.Hour),
+		userID:   e.UserID,
+		keyID:    e.KeyID,
+		method:   e.Method,
+		route:    e.Route,
+	}
+	ms := e.Duration.Milliseconds()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ru, ok := s.pending[key]
+	if !ok {
+		ru = &Rollup{
+			TenantID: key.tenantID,
+			Hour:     key.hour,
+			UserID:   key.userID,
+			KeyID:    key.keyID,
+			Method:   key.method,
+			Route:    key.route,
+			Latency:  make([]int64, len(LatencyBuckets)+1),
+		}
+		s.pending[key] = ru
+	}
+	ru.Requests++
+	switch {
+	case e.Status >= 500:
+		ru.Errors++
+	case e.Status >= 400:
+		ru.ClientErrors++
+	}
+	ru.TotalMs += ms
+	ru.Latency[latencyBucket(ms)]++
+}
+
+func latencyBucket(ms int64) int {
+	for i, bound := range LatencyBuckets {
+		if ms <= bound {
+			return i
+		}
+	}
+	return len(LatencyBuckets)
+}
+
+func (s *UsageServiceImpl) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = map[rollupKey]*Rollup{}
+	s.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	rollups := make([]*Rollup, 0, len(pending))
+	for _, ru := range pending {
+		rollups = append(rollups, ru)
+	}
+	if err := s.Repo.Increment(ctx, rollups); err != nil {
+		// Put the counts back for the next flush rather than lose them
+		s.mu.Lock()
+		for key, ru := range pending {
+			if cur, ok := s.pending[key]; ok {
+				mergeRollup(cur, ru)
+			} else {
+				s.pending[key] = ru
+			}
+		}
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func mergeRollup(into, from *Rollup) {
+	into.Requests += from.Requests
+	into.ClientErrors += from.ClientErrors
+	into.Errors += from.Errors
+	into.TotalMs += from.TotalMs
+	for i := range into.Latency {
+		into.Latency[i] += from.Latency[i]
+	}
+}
+
+func (s *UsageServiceImpl) Run(ctx context.Context) {
+	ticker := time.NewTicker(FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Flush(ctx); err != nil {
+				log.Printf("Failed to flush API usage: %v", err)
+			}
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if err := s.Flush(flushCtx); err != nil {
+				log.Printf("Failed to flush API usage: %v", err)
+			}
+			cancel()
+			return
+		}
+	}
+}
+
+func (s *UsageServiceImpl) Report(ctx context.Context, q ReportQuery) (*UsageReport, error) {
+	tenantIDStr, _ := ctx.Value(models.TenantIDKey).(string)
+	tenantID, err := primitive.ObjectIDFromHex(tenantIDStr)
+	if err != nil {
+		return nil, errors.New("tenant ID not found in context")
+	}
+	return s.report(ctx, tenantID, q)
+}
+
+func (s *UsageServiceImpl) ReportAll(ctx context.Context, q ReportQuery) (*UsageReport, error) {
+	return s.report(ctx, primitive.NilObjectID, q)
+}
+
+func (s *UsageServiceImpl) report(ctx context.Context, tenantID primitive.ObjectID, q ReportQuery) (*UsageReport, error) {
+	if q.To.IsZero() {
+		q.To = time.Now()
+	}
+	if q.From.IsZero() {
+		q.From = q.To.Add(-defaultReportRange)
+	}
+	if !q.From.Before(q.To) || q.To.Sub(q.From) > maxReportRange {
+		return nil, ErrInvalidRange
+	}
+	switch q.GroupBy {
+	case "":
+		q.GroupBy = GroupByEndpoint
+	case GroupByTenant, GroupByUser, GroupByKey, GroupByEndpoint:
+	default:
+		return nil, ErrInvalidGroupBy
+	}
+	if q.Limit < 1 {
+		q.Limit = defaultReportRows
+	}
+	q.Limit = min(q.Limit, maxReportRows)
+
+	// Rollups are hourly, so the range is widened to whole hours
+	from := q.From.UTC().Truncate(time.Hour)
+	to := q.To.UTC().Truncate(time.Hour)
+	if to.Before(q.To) {
+		to = to.Add(time.Hour)
+	}
+
+	report := &UsageReport{From: from, To: to, GroupBy: q.GroupBy, Rows: []UsageRow{}, TopEndpoints: []UsageRow{}}
+	totals, err := s.Repo.Aggregate(ctx, tenantID, from, to, "", 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(totals) > 0 {
+		report.Totals = stats(totals[0])
+	}
+
+	groups, err := s.Repo.Aggregate(ctx, tenantID, from, to, q.GroupBy, q.Limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		report.Rows = append(report.Rows, UsageRow{Key: g.Key, UsageStats: stats(g)})
+	}
+
+	if q.GroupBy == GroupByEndpoint {
+		report.TopEndpoints = report.Rows[:min(len(report.Rows), topEndpointCount)]
+		return report, nil
+	}
+	endpoints, err := s.Repo.Aggregate(ctx, tenantID, from, to, GroupByEndpoint, topEndpointCount)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range endpoints {
+		report.TopEndpoints = append(report.TopEndpoints, UsageRow{Key: g.Key, UsageStats: stats(g)})
+	}
+	return report, nil
+}
+
+func stats(g usageGroup) UsageStats {
+	st := UsageStats{Requests: g.Requests, ClientErrors: g.ClientErrors, Errors: g.Errors}
+	if g.Requests == 0 {
+		return st
+	}
+	st.ErrorRate = float64(g.Errors) / float64(g.Requests)
+	st.AvgMs = float64(g.TotalMs) / float64(g.Requests)
+	st.P50Ms = percentile(g.Latency, 0.50)
+	st.P95Ms = percentile(g.Latency, 0.95)
+	st.P99Ms = percentile(g.Latency, 0.99)
+	return st
+}
+
+// percentile returns the bound of the histogram bucket holding the p-th request
+func percentile(latency []int64, p float64) int64 {
+	var total int64
+	for _, n := range latency {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(p * float64(total)))
+	var seen int64
+	for i, n := range latency {
+		seen += n
+		if seen >= rank {
+			return LatencyBuckets[min(i, len(LatencyBuckets)-1)]
+		}
+	}
+	return LatencyBuckets[len(LatencyBuckets)-1]
+}
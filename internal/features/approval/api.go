@@ -36,6 +36,12 @@ func (h *ApprovalApi) Setup(app *fiber.App) {
 	// Group: /approvals
 	approvals := app.Group("/api/approvals", middleware.AuthMiddleware(h.config.SkipAuth))
 
+	// Inbox
+	approvals.Get("/pending", h.controller.ListPending)
+	approvals.Get("/pending/count", h.controller.CountPending)
+	approvals.Post("/bulk", h.controller.BulkDecide)
+	approvals.Get("/:module/:id/history", h.controller.GetRecordApproval)
+
 	// Approval Actions
 	approvals.Post("/:module/:id/approve", h.controller.ApproveRecord)
 	approvals.Post("/:module/:id/reject", h.controller.RejectRecord)
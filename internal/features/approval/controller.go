@@ -222,3 +222,82 @@ func (c *ApprovalController) RejectRecord(ctx *fiber.Ctx) error {
 
 	return ctx.JSON(fiber.Map{"message": "Record rejected successfully"})
 }
+
+// ListPending godoc
+// @Summary List pending approvals
+// @Description Records in every module waiting on the current user's approval, oldest first, with the workflow's context fields
+// @Tags approvals
+// @Produce json
+// @Success 200 {array} PendingApproval
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/approvals/pending [get]
+func (c *ApprovalController) ListPending(ctx *fiber.Ctx) error {
+	userClaims := ctx.Locals(utils.UserClaimsKey).(*utils.UserClaims)
+
+	pending, err := c.Service.ListPending(ctx.UserContext(), userClaims.UserID, userClaims.RoleIDs)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": pending})
+}
+
+// CountPending godoc
+// @Summary Count pending approvals
+// @Description Badge count of records waiting on the current user, in total and per module
+// @Tags approvals
+// @Produce json
+// @Success 200 {object} PendingCount
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/approvals/pending/count [get]
+func (c *ApprovalController) CountPending(ctx *fiber.Ctx) error {
+	userClaims := ctx.Locals(utils.UserClaimsKey).(*utils.UserClaims)
+
+	count, err := c.Service.CountPending(ctx.UserContext(), userClaims.UserID, userClaims.RoleIDs)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": count})
+}
+
+// GetRecordApproval godoc
+// @Summary Get a record's approval history
+// @Description Workflow, current step and every approve or reject decision on the record
+// @Tags approvals
+// @Produce json
+// @Param module path string true "Module Name"
+// @Param id path string true "Record ID"
+// @Success 200 {object} RecordApproval
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /api/approvals/{module}/{id}/history [get]
+func (c *ApprovalController) GetRecordApproval(ctx *fiber.Ctx) error {
+	approval, err := c.Service.GetRecordApproval(ctx.UserContext(), ctx.Params("module"), ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": approval})
+}
+
+// BulkDecide godoc
+// @Summary Bulk approve or reject
+// @Description Approve or reject several records with one comment. Each record is checked and decided on its own; the response lists the outcome per record.
+// @Tags approvals
+// @Accept json
+// @Produce json
+// @Param body body BulkDecisionRequest true "Decision"
+// @Success 200 {array} BulkDecisionResult
+// @Failure 400 {object} map[string]string "Invalid request body"
+// @Router /api/approvals/bulk [post]
+func (c *ApprovalController) BulkDecide(ctx *fiber.Ctx) error {
+	var req BulkDecisionRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	userClaims := ctx.Locals(utils.UserClaimsKey).(*utils.UserClaims)
+
+	results, err := c.Service.BulkDecide(ctx.UserContext(), req, userClaims.UserID, userClaims.RoleIDs)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": results})
+}
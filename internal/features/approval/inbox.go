@@ -0,0 +1,251 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxBulkDecisions caps how many records one bulk approve or reject can touch
+const MaxBulkDecisions = 100
+
+func isApprover(step ApprovalStep, userID string, userRoleIDs []string) bool {
+	if slices.Contains(step.ApproverUsers, userID) {
+		return true
+	}
+	for _, roleID := range userRoleIDs {
+		if slices.Contains(step.ApproverRoles, roleID) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordTitle picks a human name for a record in the inbox
+func recordTitle(mod *common_models.Entity, rec map[string]interface{}) string {
+	for _, key := range []string{"name", "title", "subject", "po_number"} {
+		if v, ok := rec[key].(string); ok && v != "" {
+			return v
+		}
+	}
+	first, _ := rec["first_name"].(string)
+	last, _ := rec["last_name"].(string)
+	if name := strings.TrimSpace(first + " " + last); name != "" {
+		return name
+	}
+	for _, f := range mod.Fields {
+		if f.Type != common_models.FieldTypeText {
+			continue
+		}
+		if v, ok := rec[f.Name].(string); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func contextFields(wf *ApprovalWorkflow, mod *common_models.Entity) []string {
+	if len(wf.ContextFields) > 0 {
+		return wf.ContextFields
+	}
+	var fields []string
+	for _, f := range mod.Fields {
+		if f.Required && len(fields) < 4 {
+			fields = append(fields, f.Name)
+		}
+	}
+	return fields
+}
+
+func pendingSince(state *common_models.ApprovalRecordState, rec map[string]interface{}) time.Time {
+	if n := len(state.History); n > 0 {
+		return state.History[n-1].Timestamp
+	}
+	if !state.SubmittedAt.IsZero() {
+		return state.SubmittedAt
+	}
+	if t, ok := rec["created_at"].(time.Time); ok {
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *ApprovalServiceImpl) ListPending(ctx context.Context, userID string, userRoleIDs []string) ([]PendingApproval, error) {
+	workflows, err := s.Repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	modules, err := s.ModuleRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*common_models.Entity, len(modules))
+	for i := range modules {
+		byID[modules[i].ID.Hex()] = &modules[i]
+	}
+
+	pending := []PendingApproval{}
+	for i := range workflows {
+		wf := &workflows[i]
+		mod, ok := byID[wf.ModuleID]
+		if !ok {
+			continue
+		}
+		var steps []int
+		for idx, step := range wf.Steps {
+			if isApprover(step, userID, userRoleIDs) {
+				steps = append(steps, idx)
+			}
+		}
+		if len(steps) == 0 {
+			continue
+		}
+
+		// Records keep their workflow after it is deactivated, so inactive
+		// workflows are still searched
+		filter := map[string]any{
+			"_approval.status":       common_models.ApprovalStatusPending,
+			"_approval.workflow_id":  wf.ID.Hex(),
+			"_approval.current_step": bson.M{"$in": steps},
+		}
+		records, err := s.RecordRepo.List(ctx, mod.Name, filter, nil, 0, 0, "created_at", 1)
+		if err != nil {
+			return nil, err
+		}
+
+		fields := contextFields(wf, mod)
+		for _, rec := range records {
+			state := s.extractApprovalState(rec)
+			if state == nil || state.CurrentStep >= len(wf.Steps) {
+				continue
+			}
+			values := make(map[string]interface{}, len(fields))
+			for _, f := range fields {
+				if v, ok := rec[f]; ok {
+					values[f] = v
+				}
+			}
+			pending = append(pending, PendingApproval{
+				ModuleName:   mod.Name,
+				ModuleLabel:  mod.Label,
+				RecordID:     rec["_id"].(primitive.ObjectID).Hex(),
+				Title:        recordTitle(mod, rec),
+				WorkflowID:   wf.ID.Hex(),
+				WorkflowName: wf.Name,
+				StepName:     wf.Steps[state.CurrentStep].Name,
+				PendingSince: pendingSince(state, rec),
+				Fields:       values,
+			})
+		}
+	}
+
+	// Oldest first so nothing sits at the bottom of the inbox
+	slices.SortStableFunc(pending, func(a, b PendingApproval) int {
+		return a.PendingSince.Compare(b.PendingSince)
+	})
+	return pending, nil
+}
+
+func (s *ApprovalServiceImpl) CountPending(ctx context.Context, userID string, userRoleIDs []string) (*PendingCount, error) {
+	pending, err := s.ListPending(ctx, userID, userRoleIDs)
+	if err != nil {
+		return nil, err
+	}
+	count := &PendingCount{Total: len(pending), ByModule: map[string]int{}}
+	for _, p := range pending {
+		count.ByModule[p.ModuleName]++
+	}
+	return count, nil
+}
+
+func (s *ApprovalServiceImpl) GetRecordApproval(ctx context.Context, moduleName string, recordID string) (*RecordApproval, error) {
+	rec, err := s.RecordRepo.Get(ctx, moduleName, recordID)
+	if err != nil {
+		return nil, err
+	}
+	state := s.extractApprovalState(rec)
+	if state == nil {
+		return nil, errors.New("record has no approval history")
+	}
+
+	out := &RecordApproval{
+		Status:      state.Status,
+		WorkflowID:  state.WorkflowID,
+		SubmittedAt: state.SubmittedAt,
+		History:     make([]HistoryEntry, 0, len(state.History)),
+	}
+	if wf, err := s.Repo.GetByID(ctx, state.WorkflowID); err == nil {
+		out.WorkflowName = wf.Name
+		if state.Status == common_models.ApprovalStatusPending && state.CurrentStep < len(wf.Steps) {
+			out.CurrentStep = wf.Steps[state.CurrentStep].Name
+		}
+	}
+
+	var actorIDs []string
+	for _, h := range state.History {
+		if h.ActorID != "" && !slices.Contains(actorIDs, h.ActorID) {
+			actorIDs = append(actorIDs, h.ActorID)
+		}
+	}
+	names := map[string]string{}
+	if len(actorIDs) > 0 {
+		if users, err := s.UserRepo.FindByIDs(ctx, actorIDs); err == nil {
+			for _, u := range users {
+				name := strings.TrimSpace(u.FirstName + " " + u.LastName)
+				if name == "" {
+					name = u.Username
+				}
+				names[u.ID.Hex()] = name
+			}
+		}
+	}
+	for _, h := range state.History {
+		out.History = append(out.History, HistoryEntry{ApprovalHistory: h, ActorName: names[h.ActorID]})
+	}
+	return out, nil
+}
+
+func (s *ApprovalServiceImpl) BulkDecide(ctx context.Context, req BulkDecisionRequest, userID string, userRoleIDs []string) ([]BulkDecisionResult, error) {
+	if req.Action != "approve" && req.Action != "reject" {
+		return nil, errors.New("action must be approve or reject")
+	}
+	if len(req.Records) == 0 {
+		return nil, errors.New("no records given")
+	}
+	if len(req.Records) > MaxBulkDecisions {
+		return nil, fmt.Errorf("at most %d records can be decided at once", MaxBulkDecisions)
+	}
+
+	results := make([]BulkDecisionResult, 0, len(req.Records))
+	for _, r := range req.Records {
+		result := BulkDecisionResult{Module: r.Module, ID: r.ID}
+		allowed, err := s.CanApprove(ctx, r.Module, r.ID, userID, userRoleIDs)
+		switch {
+		case err != nil:
+			result.Error = err.Error()
+		case !allowed:
+			result.Error = "You are not authorized to " + req.Action + " this step"
+		default:
+			if req.Action == "approve" {
+				err = s.ApproveRecord(ctx, r.Module, r.ID, userID, req.Comment)
+			} else {
+				err = s.RejectRecord(ctx, r.Module, r.ID, userID, req.Comment)
+			}
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Success = true
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
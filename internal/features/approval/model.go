@@ -3,27 +3,35 @@ package approval
 import (
 	"time"
 
+	common_models "go-crm/internal/common/models"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// ApprovalWorkflow defines the rules for approving records in a module
+// RuleCondition matches a record field. Operators: equals, not_equals and
+// the numeric gt, gte, lt, lte.
 type RuleCondition struct {
 	Field    string      `json:"field" bson:"field"`
 	Operator string      `json:"operator" bson:"operator"`
 	Value    interface{} `json:"value" bson:"value"`
 }
 
+// ApprovalWorkflow defines the rules for approving records in a module
+
 type ApprovalWorkflow struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	TenantID  primitive.ObjectID `bson:"tenant_id" json:"tenant_id"`
-	ModuleID  string             `bson:"module_id" json:"module_id"` // The module this workflow applies to
-	Name      string             `bson:"name" json:"name"`
-	Active    bool               `bson:"active" json:"active"`
-	Priority  int                `bson:"priority" json:"priority"` // Evaluation order (0 = highest)
-	Criteria  []RuleCondition    `bson:"criteria" json:"criteria"`
-	Steps     []ApprovalStep     `bson:"steps" json:"steps"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID primitive.ObjectID `bson:"tenant_id" json:"tenant_id"`
+	ModuleID string             `bson:"module_id" json:"module_id"` // The module this workflow applies to
+	Name     string             `bson:"name" json:"name"`
+	Active   bool               `bson:"active" json:"active"`
+	Priority int                `bson:"priority" json:"priority"` // Evaluation order (0 = highest)
+	Criteria []RuleCondition    `bson:"criteria" json:"criteria"`
+	Steps    []ApprovalStep     `bson:"steps" json:"steps"`
+	// ContextFields are shown with pending records in the approvals inbox;
+	// empty uses the module's required fields
+	ContextFields []string  `bson:"context_fields,omitempty" json:"context_fields,omitempty"`
+	CreatedAt     time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time `bson:"updated_at" json:"updated_at"`
 }
 
 // ApprovalStep defines a single step in the approval process
@@ -33,4 +41,60 @@ type ApprovalStep struct {
 	Order         int      `bson:"order" json:"order"`                   // Sequence number
 	ApproverRoles []string `bson:"approver_roles" json:"approver_roles"` // Role IDs allowed to approve
 	ApproverUsers []string `bson:"approver_users" json:"approver_users"` // User IDs allowed to approve
+	// Criteria limit the step to matching records, e.g. net_amount gt 10000
+	// for a VP step. Evaluated against the record when the step is reached.
+	Criteria []RuleCondition `bson:"criteria,omitempty" json:"criteria,omitempty"`
+}
+
+// PendingApproval is a record waiting on the current user's approval
+type PendingApproval struct {
+	ModuleName   string                 `json:"module_name"`
+	ModuleLabel  string                 `json:"module_label"`
+	RecordID     string                 `json:"record_id"`
+	Title        string                 `json:"title"`
+	WorkflowID   string                 `json:"workflow_id"`
+	WorkflowName string                 `json:"workflow_name"`
+	StepName     string                 `json:"step_name"`
+	PendingSince time.Time              `json:"pending_since"`
+	Fields       map[string]interface{} `json:"fields"`
+}
+
+// PendingCount is the inbox badge: total and per module
+type PendingCount struct {
+	Total    int            `json:"total"`
+	ByModule map[string]int `json:"by_module"`
+}
+
+// HistoryEntry is an approval history entry with the actor resolved
+type HistoryEntry struct {
+	common_models.ApprovalHistory
+	ActorName string `json:"actor_name,omitempty"`
+}
+
+// RecordApproval is the approval timeline of one record
+type RecordApproval struct {
+	Status       common_models.ApprovalStatus `json:"status"`
+	WorkflowID   string                       `json:"workflow_id"`
+	WorkflowName string                       `json:"workflow_name,omitempty"`
+	CurrentStep  string                       `json:"current_step,omitempty"`
+	SubmittedAt  time.Time                    `json:"submitted_at,omitempty"`
+	History      []HistoryEntry               `json:"history"`
+}
+
+// BulkDecisionRequest approves or rejects several records at once
+type BulkDecisionRequest struct {
+	Action  string `json:"action"` // approve or reject
+	Comment string `json:"comment"`
+	Records []struct {
+		Module string `json:"module"`
+		ID     string `json:"id"`
+	} `json:"records"`
+}
+
+// BulkDecisionResult is the outcome for one record of a bulk decision
+type BulkDecisionResult struct {
+	Module  string `json:"module"`
+	ID      string `json:"id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
 }
@@ -10,6 +10,7 @@ import (
 	"go-crm/internal/features/record"
 	"go-crm/internal/features/user"
 	"slices"
+	"strconv"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -33,6 +34,12 @@ type ApprovalService interface {
 
 	// Helper to initialize approval state for a new record
 	InitializeApproval(ctx context.Context, moduleName string, record map[string]interface{}) (*common_models.ApprovalRecordState, error)
+
+	// Inbox
+	ListPending(ctx context.Context, userID string, userRoleIDs []string) ([]PendingApproval, error)
+	CountPending(ctx context.Context, userID string, userRoleIDs []string) (*PendingCount, error)
+	GetRecordApproval(ctx context.Context, moduleName string, recordID string) (*RecordApproval, error)
+	BulkDecide(ctx context.Context, req BulkDecisionRequest, userID string, userRoleIDs []string) ([]BulkDecisionResult, error)
 }
 
 type ApprovalServiceImpl struct {
@@ -166,23 +173,7 @@ func (s *ApprovalServiceImpl) InitializeApproval(ctx context.Context, moduleName
 			break
 		}
 
-		match := true
-		for _, cond := range wf.Criteria {
-			val, exists := rec[cond.Field]
-			if !exists {
-				match = false
-				break
-			}
-
-			strVal := fmt.Sprintf("%v", val)
-			strCond := fmt.Sprintf("%v", cond.Value)
-
-			if cond.Operator == "equals" && strVal != strCond {
-				match = false
-				break
-			}
-		}
-		if match {
+		if matchesCriteria(rec, wf.Criteria) {
 			matchedWorkflow = &wf
 			break
 		}
@@ -192,14 +183,91 @@ func (s *ApprovalServiceImpl) InitializeApproval(ctx context.Context, moduleName
 		return nil, nil
 	}
 
+	first := nextStep(matchedWorkflow, rec, 0)
+	if first >= len(matchedWorkflow.Steps) {
+		// No step applies to this record
+		return nil, nil
+	}
+
 	return &common_models.ApprovalRecordState{
 		Status:      common_models.ApprovalStatusPending,
-		CurrentStep: 0,
+		CurrentStep: first,
 		WorkflowID:  matchedWorkflow.ID.Hex(),
 		History:     []common_models.ApprovalHistory{},
+		SubmittedAt: time.Now(),
 	}, nil
 }
 
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func matchesCondition(rec map[string]interface{}, cond RuleCondition) bool {
+	val, exists := rec[cond.Field]
+	if !exists {
+		return false
+	}
+
+	switch cond.Operator {
+	case "equals":
+		return fmt.Sprintf("%v", val) == fmt.Sprintf("%v", cond.Value)
+	case "not_equals":
+		return fmt.Sprintf("%v", val) != fmt.Sprintf("%v", cond.Value)
+	case "gt", "gte", "lt", "lte":
+		a, ok1 := toFloat(val)
+		b, ok2 := toFloat(cond.Value)
+		if !ok1 || !ok2 {
+			return false
+		}
+		switch cond.Operator {
+		case "gt":
+			return a > b
+		case "gte":
+			return a >= b
+		case "lt":
+			return a < b
+		default:
+			return a <= b
+		}
+	}
+	return true
+}
+
+func matchesCriteria(rec map[string]interface{}, criteria []RuleCondition) bool {
+	for _, cond := range criteria {
+		if !matchesCondition(rec, cond) {
+			return false
+		}
+	}
+	return true
+}
+
+// nextStep returns the index of the first step from `from` whose criteria
+// match the record, or len(Steps) when none is left
+func nextStep(workflow *ApprovalWorkflow, rec map[string]interface{}, from int) int {
+	for i := from; i < len(workflow.Steps); i++ {
+		if matchesCriteria(rec, workflow.Steps[i].Criteria) {
+			return i
+		}
+	}
+	return len(workflow.Steps)
+}
+
 func (s *ApprovalServiceImpl) ApproveRecord(ctx context.Context, moduleName string, recordID string, actorID string, comment string) error {
 	rec, err := s.RecordRepo.Get(ctx, moduleName, recordID)
 	if err != nil {
@@ -231,8 +299,8 @@ func (s *ApprovalServiceImpl) ApproveRecord(ctx context.Context, moduleName stri
 	}
 	state.History = append(state.History, history)
 
-	if state.CurrentStep < len(workflow.Steps)-1 {
-		state.CurrentStep++
+	if next := nextStep(workflow, rec, state.CurrentStep+1); next < len(workflow.Steps) {
+		state.CurrentStep = next
 	} else {
 		state.Status = common_models.ApprovalStatusApproved
 	}
@@ -333,19 +401,7 @@ func (s *ApprovalServiceImpl) CanApprove(ctx context.Context, moduleName string,
 		return false, nil
 	}
 
-	step := workflow.Steps[state.CurrentStep]
-
-	if slices.Contains(step.ApproverUsers, userID) {
-		return true, nil
-	}
-
-	for _, roleID := range userRoleIDs {
-		if slices.Contains(step.ApproverRoles, roleID) {
-			return true, nil
-		}
-	}
-
-	return false, nil
+	return isApprover(workflow.Steps[state.CurrentStep], userID, userRoleIDs), nil
 }
 
 func (s *ApprovalServiceImpl) extractApprovalState(rec map[string]any) *common_models.ApprovalRecordState {
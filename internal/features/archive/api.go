@@ -0,0 +1,36 @@
+package archive
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ArchiveApi struct {
+	controller  *ArchiveController
+	config      *config.Config
+	roleService middleware.RoleService
+}
+
+func NewArchiveApi(controller *ArchiveController, config *config.Config, roleService middleware.RoleService) *ArchiveApi {
+	return &ArchiveApi{
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *ArchiveApi) Setup(app *fiber.App) {
+	group := app.Group("/api/archive", middleware.AuthMiddleware(h.config.SkipAuth))
+
+	group.Get("/policies", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListPolicies)
+	group.Post("/policies", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.CreatePolicy)
+	group.Get("/policies/:id", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.GetPolicy)
+	group.Put("/policies/:id", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.UpdatePolicy)
+	group.Delete("/policies/:id", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.DeletePolicy)
+	group.Post("/policies/:id/run", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.RunPolicy)
+
+	group.Get("/records/:module", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.SearchArchive)
+	group.Post("/records/:module/:id/restore", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.RestoreRecord)
+}
@@ -0,0 +1,179 @@
+package archive
+
+import (
+	"encoding/json"
+	"errors"
+
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ArchiveController struct {
+	Service ArchiveService
+}
+
+func NewArchiveController(service ArchiveService) *ArchiveController {
+	return &ArchiveController{Service: service}
+}
+
+// CreatePolicy godoc
+// @Summary Create archival policy
+// @Description Archive a module's records once date_field is older than older_than_days
+// @Tags archive
+// @Accept json
+// @Produce json
+// @Param policy body Policy true "Policy"
+// @Success 201 {object} Policy
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/archive/policies [post]
+func (c *ArchiveController) CreatePolicy(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var policy Policy
+	if err := ctx.BodyParser(&policy); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := c.Service.CreatePolicy(ctx.UserContext(), &policy, userID); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{"data": policy})
+}
+
+// ListPolicies godoc
+// @Summary List archival policies
+// @Tags archive
+// @Produce json
+// @Param module query string false "Module Name"
+// @Success 200 {array} Policy
+// @Router /api/archive/policies [get]
+func (c *ArchiveController) ListPolicies(ctx *fiber.Ctx) error {
+	policies, err := c.Service.ListPolicies(ctx.UserContext(), ctx.Query("module"))
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": policies})
+}
+
+// GetPolicy godoc
+// @Summary Get archival policy
+// @Tags archive
+// @Produce json
+// @Param id path string true "Policy ID"
+// @Success 200 {object} Policy
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/archive/policies/{id} [get]
+func (c *ArchiveController) GetPolicy(ctx *fiber.Ctx) error {
+	policy, err := c.Service.GetPolicy(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Policy not found"})
+	}
+	return ctx.JSON(fiber.Map{"data": policy})
+}
+
+// UpdatePolicy godoc
+// @Summary Update archival policy
+// @Tags archive
+// @Accept json
+// @Produce json
+// @Param id path string true "Policy ID"
+// @Param policy body Policy true "Policy"
+// @Success 200 {object} Policy
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/archive/policies/{id} [put]
+func (c *ArchiveController) UpdatePolicy(ctx *fiber.Ctx) error {
+	var policy Policy
+	if err := ctx.BodyParser(&policy); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	id, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid policy ID"})
+	}
+	policy.ID = id
+	if err := c.Service.UpdatePolicy(ctx.UserContext(), &policy); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": policy})
+}
+
+// DeletePolicy godoc
+// @Summary Delete archival policy
+// @Description Archived records stay in the archive
+// @Tags archive
+// @Param id path string true "Policy ID"
+// @Success 204
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/archive/policies/{id} [delete]
+func (c *ArchiveController) DeletePolicy(ctx *fiber.Ctx) error {
+	if err := c.Service.DeletePolicy(ctx.UserContext(), ctx.Params("id")); err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// RunPolicy godoc
+// @Summary Run archival policy
+// @Description Archive matching records now instead of waiting for the nightly job
+// @Tags archive
+// @Produce json
+// @Param id path string true "Policy ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/archive/policies/{id}/run [post]
+func (c *ArchiveController) RunPolicy(ctx *fiber.Ctx) error {
+	archived, err := c.Service.RunPolicy(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error(), "archived": archived})
+	}
+	return ctx.JSON(fiber.Map{"archived": archived})
+}
+
+// SearchArchive godoc
+// @Summary Search archived records
+// @Tags archive
+// @Produce json
+// @Param module path string true "Module Name"
+// @Param filter query string false "JSON filter on record fields"
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Success 200 {array} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/archive/records/{module} [get]
+func (c *ArchiveController) SearchArchive(ctx *fiber.Ctx) error {
+	filter := map[string]any{}
+	if raw := ctx.Query("filter"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &filter); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid filter"})
+		}
+	}
+	records, total, err := c.Service.Search(ctx.UserContext(), ctx.Params("module"), filter, int64(ctx.QueryInt("page", 1)), int64(ctx.QueryInt("limit", 50)))
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": records, "total": total})
+}
+
+// RestoreRecord godoc
+// @Summary Restore archived record
+// @Tags archive
+// @Produce json
+// @Param module path string true "Module Name"
+// @Param id path string true "Record ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/archive/records/{module}/{id}/restore [post]
+func (c *ArchiveController) RestoreRecord(ctx *fiber.Ctx) error {
+	userID, _ := session.UserID(ctx.UserContext())
+	record, err := c.Service.Restore(ctx.UserContext(), ctx.Params("module"), ctx.Params("id"), userID)
+	if errors.Is(err, ErrNotArchived) {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": record})
+}
@@ -0,0 +1,45 @@
+package archive
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ConditionOperator string
+
+const (
+	OperatorEquals    ConditionOperator = "equals"
+	OperatorNotEquals ConditionOperator = "not_equals"
+	OperatorIn        ConditionOperator = "in"
+	OperatorNotIn     ConditionOperator = "not_in"
+)
+
+// PolicyCondition narrows a policy to records whose Field matches Value
+type PolicyCondition struct {
+	Field    string            `json:"field" bson:"field"`
+	Operator ConditionOperator `json:"operator" bson:"operator"`
+	Value    interface{}       `json:"value" bson:"value"`
+}
+
+// Policy moves a module's records to the archive once DateField is older
+// than OlderThanDays, e.g. closed tickets not updated for two years
+type Policy struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID    primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	ModuleName  string             `json:"module_name" bson:"module_name"`
+	Name        string             `json:"name" bson:"name"`
+	Description string             `json:"description,omitempty" bson:"description,omitempty"`
+	IsActive    bool               `json:"is_active" bson:"is_active"`
+
+	DateField     string            `json:"date_field,omitempty" bson:"date_field,omitempty"` // Default: updated_at
+	OlderThanDays int               `json:"older_than_days" bson:"older_than_days"`
+	Conditions    []PolicyCondition `json:"conditions,omitempty" bson:"conditions,omitempty"`
+
+	LastRunAt    *time.Time `json:"last_run_at,omitempty" bson:"last_run_at,omitempty"`
+	LastArchived int        `json:"last_archived" bson:"last_archived"` // Records moved by the last run
+
+	CreatedBy primitive.ObjectID `json:"created_by" bson:"created_by"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
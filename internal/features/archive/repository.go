@@ -0,0 +1,345 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrNotArchived is returned when a restore names a record not in the archive
+var ErrNotArchived = errors.New("record not found in archive")
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+type PolicyRepository interface {
+	Create(ctx context.Context, policy *Policy) error
+	Get(ctx context.Context, id string) (*Policy, error)
+	// List returns the tenant's policies, optionally for one module
+	List(ctx context.Context, moduleName string) ([]Policy, error)
+	Update(ctx context.Context, policy *Policy) error
+	Delete(ctx context.Context, id string) error
+	SetLastRun(ctx context.Context, id primitive.ObjectID, at time.Time, archived int) error
+
+	// ListAllActive returns active policies of every tenant for the scheduled run
+	ListAllActive(ctx context.Context) ([]Policy, error)
+}
+
+type PolicyRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewPolicyRepository(db *database.MongodbDB) PolicyRepository {
+	return &PolicyRepositoryImpl{
+		collection: db.DB.Collection("archive_policies"),
+	}
+}
+
+func (r *PolicyRepositoryImpl) Create(ctx context.Context, policy *Policy) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	policy.ID = primitive.NewObjectID()
+	policy.TenantID = tenantID
+	policy.CreatedAt = time.Now()
+	policy.UpdatedAt = policy.CreatedAt
+
+	_, err = r.collection.InsertOne(ctx, policy)
+	return err
+}
+
+func (r *PolicyRepositoryImpl) Get(ctx context.Context, id string) (*Policy, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var policy Policy
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID}).Decode(&policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (r *PolicyRepositoryImpl) List(ctx context.Context, moduleName string) ([]Policy, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{"tenant_id": tenantID}
+	if moduleName != "" {
+		filter["module_name"] = moduleName
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "module_name", Value: 1}, {Key: "name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	policies := []Policy{}
+	if err := cursor.All(ctx, &policies); err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+func (r *PolicyRepositoryImpl) Update(ctx context.Context, policy *Policy) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	policy.UpdatedAt = time.Now()
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": policy.ID, "tenant_id": tenantID}, policy)
+	return err
+}
+
+func (r *PolicyRepositoryImpl) Delete(ctx context.Context, id string) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID})
+	return err
+}
+
+func (r *PolicyRepositoryImpl) SetLastRun(ctx context.Context, id primitive.ObjectID, at time.Time, archived int) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"last_run_at":   at,
+		"last_archived": archived,
+	}})
+	return err
+}
+
+func (r *PolicyRepositoryImpl) ListAllActive(ctx context.Context) ([]Policy, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "tenant_id", Value: 1}, {Key: "module_name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"is_active": true}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var policies []Policy
+	if err := cursor.All(ctx, &policies); err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// ArchiveRepository moves records between entity_records and
+// archived_records. Archived documents keep their stored shape, plus
+// archived_at and the policy or user that archived them, so a restore puts
+// back exactly what was there.
+type ArchiveRepository interface {
+	// Move archives up to limit records of the tenant matching query, which
+	// is written against entity_records documents
+	Move(ctx context.Context, query bson.M, limit int, archivedBy bson.M) (int, error)
+	Search(ctx context.Context, moduleName string, filter bson.M, limit, offset int64) ([]map[string]any, int64, error)
+	Restore(ctx context.Context, moduleName, id string) (map[string]any, error)
+}
+
+type ArchiveRepositoryImpl struct {
+	records  *mongo.Collection
+	archived *mongo.Collection
+}
+
+func NewArchiveRepository(db *database.MongodbDB) ArchiveRepository {
+	return &ArchiveRepositoryImpl{
+		records:  db.DB.Collection("entity_records"),
+		archived: db.DB.Collection("archived_records"),
+	}
+}
+
+// moveBatch is how many records are copied and removed per round trip
+const moveBatch = 500
+
+func (r *ArchiveRepositoryImpl) Move(ctx context.Context, query bson.M, limit int, archivedBy bson.M) (int, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	scoped := bson.M{"$and": bson.A{bson.M{"tenant_id": tenantID}, query}}
+
+	moved := 0
+	for moved < limit {
+		batch := min(moveBatch, limit-moved)
+		cursor, err := r.records.Find(ctx, scoped, options.Find().SetLimit(int64(batch)))
+		if err != nil {
+			return moved, err
+		}
+		var docs []bson.M
+		if err := cursor.All(ctx, &docs); err != nil {
+			return moved, err
+		}
+		if len(docs) == 0 {
+			break
+		}
+
+		now := time.Now()
+		ids := make(bson.A, len(docs))
+		inserts := make([]interface{}, len(docs))
+		for i, doc := range docs {
+			ids[i] = doc["_id"]
+			doc["archived_at"] = now
+			for k, v := range archivedBy {
+				doc[k] = v
+			}
+			inserts[i] = doc
+		}
+
+		// A failed earlier run may have copied some already; those collide.
+		// Only records confirmed in the archive are removed.
+		if _, err := r.archived.InsertMany(ctx, inserts, options.InsertMany().SetOrdered(false)); err != nil && !mongo.IsDuplicateKeyError(err) {
+			return moved, err
+		}
+		cursor, err = r.archived.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, options.Find().SetProjection(bson.M{"_id": 1}))
+		if err != nil {
+			return moved, err
+		}
+		var copied []struct {
+			ID any `bson:"_id"`
+		}
+		if err := cursor.All(ctx, &copied); err != nil {
+			return moved, err
+		}
+		copiedIDs := make(bson.A, len(copied))
+		for i, c := range copied {
+			copiedIDs[i] = c.ID
+		}
+		if len(copiedIDs) < len(ids) {
+			return moved, fmt.Errorf("archived %d of %d records in batch", len(copiedIDs), len(ids))
+		}
+		if _, err := r.records.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": copiedIDs}, "tenant_id": tenantID}); err != nil {
+			return moved, err
+		}
+		moved += len(docs)
+		if len(docs) < batch {
+			break
+		}
+	}
+	return moved, nil
+}
+
+func (r *ArchiveRepositoryImpl) Search(ctx context.Context, moduleName string, filter bson.M, limit, offset int64) ([]map[string]any, int64, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := bson.M{"$and": bson.A{bson.M{"tenant_id": tenantID, "entity": moduleName}, toDataQuery(filter)}}
+
+	total, err := r.archived.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "archived_at", Value: -1}}).SetSkip(offset).SetLimit(limit)
+	cursor, err := r.archived.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	var docs []bson.M
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, 0, err
+	}
+
+	results := make([]map[string]any, len(docs))
+	for i, doc := range docs {
+		results[i] = flatten(doc)
+	}
+	return results, total, nil
+}
+
+func (r *ArchiveRepositoryImpl) Restore(ctx context.Context, moduleName, id string) (map[string]any, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	key := bson.M{"_id": oid, "tenant_id": tenantID, "entity": moduleName}
+
+	var doc bson.M
+	if err := r.archived.FindOne(ctx, key).Decode(&doc); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotArchived
+		}
+		return nil, err
+	}
+	for k := range archiveFields {
+		delete(doc, k)
+	}
+
+	// A collision means an earlier restore got as far as the insert
+	if _, err := r.records.InsertOne(ctx, doc); err != nil && !mongo.IsDuplicateKeyError(err) {
+		return nil, err
+	}
+	if _, err := r.archived.DeleteOne(ctx, key); err != nil {
+		return nil, err
+	}
+	return flatten(doc), nil
+}
+
+// archiveFields are added on archive and stripped on restore
+var archiveFields = map[string]bool{"archived_at": true, "archive_policy_id": true, "archived_by": true}
+
+// toDataQuery maps flattened record fields to their stored "data." paths
+func toDataQuery(filter bson.M) bson.M {
+	query := bson.M{}
+	for k, v := range filter {
+		switch k {
+		case "_id", "created_at", "updated_at", "created_by", "archived_at", "archive_policy_id", "archived_by":
+			query[k] = v
+		default:
+			query["data."+k] = v
+		}
+	}
+	return query
+}
+
+// flatten returns an archived document in the shape record endpoints use
+func flatten(doc bson.M) map[string]any {
+	flat := map[string]any{}
+	switch data := doc["data"].(type) {
+	case bson.M:
+		for k, v := range data {
+			flat[k] = v
+		}
+	case bson.D:
+		for _, e := range data {
+			flat[e.Key] = e.Value
+		}
+	}
+	for _, k := range []string{"_id", "created_at", "updated_at", "created_by", "updated_by", "archived_at", "archive_policy_id", "archived_by"} {
+		if v, ok := doc[k]; ok {
+			flat[k] = v
+		}
+	}
+	flat["id"] = doc["_id"]
+	return flat
+}
@@ -0,0 +1,284 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/module"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ArchiveSchedule runs every active policy nightly
+const ArchiveSchedule = "30 3 * * *"
+
+// maxPerRun caps the records one policy moves per run so a first run on a
+// large tenant is spread over several nights
+const maxPerRun = 50000
+
+// minOlderThanDays keeps a policy from archiving live records
+const minOlderThanDays = 30
+
+type ArchiveService interface {
+	CreatePolicy(ctx context.Context, policy *Policy, userID primitive.ObjectID) error
+	GetPolicy(ctx context.Context, id string) (*Policy, error)
+	ListPolicies(ctx context.Context, moduleName string) ([]Policy, error)
+	UpdatePolicy(ctx context.Context, policy *Policy) error
+	DeletePolicy(ctx context.Context, id string) error
+
+	// RunPolicy archives the records the policy currently matches
+	RunPolicy(ctx context.Context, id string) (int, error)
+	// RunAll runs every tenant's active policies; registered as a system job
+	RunAll(ctx context.Context) error
+
+	// Search lists a module's archived records matching filter, newest
+	// archived first
+	Search(ctx context.Context, moduleName string, filter map[string]any, page, limit int64) ([]map[string]any, int64, error)
+	// Restore moves an archived record back into its module
+	Restore(ctx context.Context, moduleName, id string, userID primitive.ObjectID) (map[string]any, error)
+}
+
+type ArchiveServiceImpl struct {
+	PolicyRepo   PolicyRepository
+	ArchiveRepo  ArchiveRepository
+	ModuleRepo   module.ModuleRepository
+	AuditService audit.AuditService
+}
+
+func NewArchiveService(
+	policyRepo PolicyRepository,
+	archiveRepo ArchiveRepository,
+	moduleRepo module.ModuleRepository,
+	auditService audit.AuditService,
+) ArchiveService {
+	return &ArchiveServiceImpl{
+		PolicyRepo:   policyRepo,
+		ArchiveRepo:  archiveRepo,
+		ModuleRepo:   moduleRepo,
+		AuditService: auditService,
+	}
+}
+
+func (s *ArchiveServiceImpl) validate(ctx context.Context, policy *Policy) error {
+	if policy.Name == "" {
+		return errors.New("name is required")
+	}
+	if policy.OlderThanDays < minOlderThanDays {
+		return fmt.Errorf("older_than_days must be at least %d", minOlderThanDays)
+	}
+	m, err := s.ModuleRepo.FindByName(ctx, policy.ModuleName)
+	if err != nil || m == nil {
+		return fmt.Errorf("module '%s' not found", policy.ModuleName)
+	}
+
+	fields := map[string]common_models.FieldType{}
+	for _, f := range m.Fields {
+		fields[f.Name] = f.Type
+	}
+	switch policy.DateField {
+	case "", "created_at", "updated_at":
+	default:
+		if fields[policy.DateField] != common_models.FieldTypeDate {
+			return fmt.Errorf("date_field '%s' is not a date field of %s", policy.DateField, policy.ModuleName)
+		}
+	}
+	for _, c := range policy.Conditions {
+		if _, ok := fields[c.Field]; !ok {
+			return fmt.Errorf("unknown field '%s'", c.Field)
+		}
+		switch c.Operator {
+		case OperatorEquals, OperatorNotEquals:
+		case OperatorIn, OperatorNotIn:
+			if _, ok := c.Value.([]interface{}); !ok {
+				return fmt.Errorf("'%s' needs a list of values for %s", c.Field, c.Operator)
+			}
+		default:
+			return fmt.Errorf("unsupported operator '%s'", c.Operator)
+		}
+	}
+	return nil
+}
+
+func (s *ArchiveServiceImpl) CreatePolicy(ctx context.Context, policy *Policy, userID primitive.ObjectID) error {
+	if err := s.validate(ctx, policy); err != nil {
+		return err
+	}
+	policy.CreatedBy = userID
+	policy.LastRunAt = nil
+	policy.LastArchived = 0
+	if err := s.PolicyRepo.Create(ctx, policy); err != nil {
+		return err
+	}
+	s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "archive_policy", policy.ID.Hex(), map[string]common_models.Change{
+		"policy": {New: policy},
+	})
+	return nil
+}
+
+func (s *ArchiveServiceImpl) GetPolicy(ctx context.Context, id string) (*Policy, error) {
+	return s.PolicyRepo.Get(ctx, id)
+}
+
+func (s *ArchiveServiceImpl) ListPolicies(ctx context.Context, moduleName string) ([]Policy, error) {
+	return s.PolicyRepo.List(ctx, moduleName)
+}
+
+func (s *ArchiveServiceImpl) UpdatePolicy(ctx context.Context, policy *Policy) error {
+	existing, err := s.PolicyRepo.Get(ctx, policy.ID.Hex())
+	if err != nil {
+		return errors.New("policy not found")
+	}
+	if err := s.validate(ctx, policy); err != nil {
+		return err
+	}
+	policy.TenantID = existing.TenantID
+	policy.CreatedBy = existing.CreatedBy
+	policy.CreatedAt = existing.CreatedAt
+	policy.LastRunAt = existing.LastRunAt
+	policy.LastArchived = existing.LastArchived
+	if err := s.PolicyRepo.Update(ctx, policy); err != nil {
+		return err
+	}
+	s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "archive_policy", policy.ID.Hex(), map[string]common_models.Change{
+		"policy": {Old: existing, New: policy},
+	})
+	return nil
+}
+
+func (s *ArchiveServiceImpl) DeletePolicy(ctx context.Context, id string) error {
+	existing, err := s.PolicyRepo.Get(ctx, id)
+	if err != nil {
+		return errors.New("policy not found")
+	}
+	if err := s.PolicyRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "archive_policy", id, map[string]common_models.Change{
+		"policy": {Old: existing, New: "DELETED"},
+	})
+	return nil
+}
+
+func (s *ArchiveServiceImpl) RunPolicy(ctx context.Context, id string) (int, error) {
+	policy, err := s.PolicyRepo.Get(ctx, id)
+	if err != nil {
+		return 0, errors.New("policy not found")
+	}
+	return s.run(ctx, policy)
+}
+
+func (s *ArchiveServiceImpl) RunAll(ctx context.Context) error {
+	policies, err := s.PolicyRepo.ListAllActive(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range policies {
+		p := &policies[i]
+		tenantCtx := context.WithValue(ctx, common_models.TenantIDKey, p.TenantID.Hex())
+		if _, err := s.run(tenantCtx, p); err != nil {
+			log.Printf("archive: policy %s for tenant %s failed: %v", p.Name, p.TenantID.Hex(), err)
+		}
+	}
+	return nil
+}
+
+func (s *ArchiveServiceImpl) run(ctx context.Context, policy *Policy) (int, error) {
+	now := time.Now()
+	moved, err := s.ArchiveRepo.Move(ctx, policyQuery(policy, now), maxPerRun, bson.M{"archive_policy_id": policy.ID})
+	if setErr := s.PolicyRepo.SetLastRun(ctx, policy.ID, now, moved); setErr != nil {
+		log.Printf("archive: failed to record run of policy %s: %v", policy.ID.Hex(), setErr)
+	}
+	if moved > 0 {
+		s.AuditService.LogChange(ctx, common_models.AuditActionArchive, policy.ModuleName, policy.ID.Hex(), map[string]common_models.Change{
+			"archived": {New: moved},
+			"policy":   {New: policy.Name},
+		})
+	}
+	return moved, err
+}
+
+// policyQuery matches the live records of the policy's module that are past
+// the cutoff and meet its conditions
+func policyQuery(policy *Policy, now time.Time) bson.M {
+	dateField := policy.DateField
+	if dateField == "" {
+		dateField = "updated_at"
+	}
+	if dateField != "created_at" && dateField != "updated_at" {
+		dateField = "data." + dateField
+	}
+
+	cutoff := now.AddDate(0, 0, -policy.OlderThanDays)
+	query := bson.M{
+		"entity":  policy.ModuleName,
+		"deleted": bson.M{"$ne": true},
+		dateField: bson.M{"$lt": cutoff},
+	}
+	if dateField != "created_at" && dateField != "updated_at" {
+		// Date fields saved from forms hold ISO strings, which sort by date
+		delete(query, dateField)
+		query["$or"] = bson.A{
+			bson.M{dateField: bson.M{"$lt": cutoff}},
+			bson.M{dateField: bson.M{"$lt": cutoff.UTC().Format("2006-01-02")}},
+		}
+	}
+	for _, c := range policy.Conditions {
+		var match interface{}
+		switch c.Operator {
+		case OperatorEquals:
+			match = c.Value
+		case OperatorNotEquals:
+			match = bson.M{"$ne": c.Value}
+		case OperatorIn:
+			match = bson.M{"$in": c.Value}
+		case OperatorNotIn:
+			match = bson.M{"$nin": c.Value}
+		default:
+			continue
+		}
+		key := "data." + c.Field
+		if existing, ok := query[key]; ok {
+			// Two conditions on one field must both hold
+			query["$and"] = append(asList(query["$and"]), bson.M{key: existing}, bson.M{key: match})
+			delete(query, key)
+			continue
+		}
+		query[key] = match
+	}
+	return query
+}
+
+func asList(v interface{}) bson.A {
+	if list, ok := v.(bson.A); ok {
+		return list
+	}
+	return bson.A{}
+}
+
+func (s *ArchiveServiceImpl) Search(ctx context.Context, moduleName string, filter map[string]any, page, limit int64) ([]map[string]any, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	return s.ArchiveRepo.Search(ctx, moduleName, bson.M(filter), limit, (page-1)*limit)
+}
+
+func (s *ArchiveServiceImpl) Restore(ctx context.Context, moduleName, id string, userID primitive.ObjectID) (map[string]any, error) {
+	restored, err := s.ArchiveRepo.Restore(ctx, moduleName, id)
+	if err != nil {
+		return nil, err
+	}
+	s.AuditService.LogChange(ctx, common_models.AuditActionArchive, moduleName, id, map[string]common_models.Change{
+		"restored":    {Old: "archived", New: "active"},
+		"restored_by": {New: userID.Hex()},
+	})
+	return restored, nil
+}
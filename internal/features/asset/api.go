@@ -0,0 +1,27 @@
+package asset
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AssetApi struct {
+	controller *AssetController
+	config     *config.Config
+}
+
+func NewAssetApi(controller *AssetController, config *config.Config) *AssetApi {
+	return &AssetApi{
+		controller: controller,
+		config:     config,
+	}
+}
+
+func (h *AssetApi) Setup(app *fiber.App) {
+	group := app.Group("/api/assets", middleware.AuthMiddleware(h.config.SkipAuth))
+	group.Get("/:id/tickets", h.controller.GetHistory)
+	group.Post("/:id/tickets", h.controller.LinkTicket)
+	group.Delete("/:id/tickets/:ticketId", h.controller.UnlinkTicket)
+}
@@ -0,0 +1,91 @@
+package asset
+
+import (
+	"errors"
+
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AssetController struct {
+	Service AssetService
+}
+
+func NewAssetController(service AssetService) *AssetController {
+	return &AssetController{Service: service}
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrAssetNotFound), errors.Is(err, ErrTicketNotFound):
+		return fiber.StatusNotFound
+	default:
+		return fiber.StatusInternalServerError
+	}
+}
+
+// GetHistory godoc
+// @Summary Asset history
+// @Description The asset record with every ticket filed against it, newest first
+// @Tags assets
+// @Produce json
+// @Param id path string true "Asset record ID"
+// @Success 200 {object} History
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/assets/{id}/tickets [get]
+func (c *AssetController) GetHistory(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	h, err := c.Service.GetHistory(ctx.UserContext(), ctx.Params("id"), userID)
+	if err != nil {
+		return ctx.Status(errorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": h})
+}
+
+// LinkTicket godoc
+// @Summary Link ticket to asset
+// @Tags assets
+// @Accept json
+// @Produce json
+// @Param id path string true "Asset record ID"
+// @Param body body LinkRequest true "Ticket"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/assets/{id}/tickets [post]
+func (c *AssetController) LinkTicket(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var req LinkRequest
+	if err := ctx.BodyParser(&req); err != nil || req.TicketID == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ticket_id is required"})
+	}
+	if err := c.Service.LinkTicket(ctx.UserContext(), ctx.Params("id"), req.TicketID, userID); err != nil {
+		return ctx.Status(errorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"message": "Ticket linked"})
+}
+
+// UnlinkTicket godoc
+// @Summary Unlink ticket from asset
+// @Tags assets
+// @Param id path string true "Asset record ID"
+// @Param ticketId path string true "Ticket ID"
+// @Success 204
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/assets/{id}/tickets/{ticketId} [delete]
+func (c *AssetController) UnlinkTicket(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	if err := c.Service.UnlinkTicket(ctx.UserContext(), ctx.Params("id"), ctx.Params("ticketId"), userID); err != nil {
+		return ctx.Status(errorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,45 @@
+package asset
+
+import (
+	"time"
+
+	"go-crm/internal/features/ticket"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ModuleName is the module assets are stored in; see internal/migration/data/modules.json
+const ModuleName = "assets"
+
+// Automation trigger types fired by the warranty job on the assets module
+const (
+	TriggerWarrantyExpiring = "warranty_expiring"
+	TriggerWarrantyExpired  = "warranty_expired"
+)
+
+// WarrantyNoticeDays is how long before warranty_end the expiring trigger fires
+const WarrantyNoticeDays = 30
+
+// WarrantyEvent records that a warranty trigger fired for an asset, so each
+// fires once per warranty end date. Extending the warranty fires again.
+type WarrantyEvent struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID    primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	AssetID     primitive.ObjectID `json:"asset_id" bson:"asset_id"`
+	Trigger     string             `json:"trigger" bson:"trigger"`
+	WarrantyEnd time.Time          `json:"warranty_end" bson:"warranty_end"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// History is an asset with every ticket filed against it
+type History struct {
+	Asset         map[string]interface{} `json:"asset"`
+	UnderWarranty bool                   `json:"under_warranty"`
+	OpenTickets   int                    `json:"open_tickets"`
+	Tickets       []ticket.Ticket        `json:"tickets"`
+}
+
+// LinkRequest links a ticket to an asset
+type LinkRequest struct {
+	TicketID string `json:"ticket_id"`
+}
@@ -0,0 +1,84 @@
+package asset
+
+import (
+	"context"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AssetRepository reads asset records across tenants for the warranty job
+type AssetRepository interface {
+	// ListWarrantyEndingBefore returns a page of assets, in every tenant, whose
+	// warranty ends before the given time, ordered by ID after afterID
+	ListWarrantyEndingBefore(ctx context.Context, before time.Time, afterID primitive.ObjectID, limit int64) ([]models.EntityRecord, error)
+}
+
+type AssetRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewAssetRepository(db *database.MongodbDB) AssetRepository {
+	return &AssetRepositoryImpl{
+		collection: db.DB.Collection("entity_records"),
+	}
+}
+
+func (r *AssetRepositoryImpl) ListWarrantyEndingBefore(ctx context.Context, before time.Time, afterID primitive.ObjectID, limit int64) ([]models.EntityRecord, error) {
+	filter := bson.M{
+		"entity":            ModuleName,
+		"deleted":           bson.M{"$ne": true},
+		"data.warranty_end": bson.M{"$type": "date", "$lt": before},
+	}
+	if !afterID.IsZero() {
+		filter["_id"] = bson.M{"$gt": afterID}
+	}
+	opts := options.Find().SetSort(bson.M{"_id": 1}).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []models.EntityRecord
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+type WarrantyEventRepository interface {
+	// Claim records a warranty event and reports false when it already fired
+	Claim(ctx context.Context, e *WarrantyEvent) (bool, error)
+}
+
+type WarrantyEventRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewWarrantyEventRepository(db *database.MongodbDB) WarrantyEventRepository {
+	return &WarrantyEventRepositoryImpl{
+		collection: db.DB.Collection("asset_warranty_events"),
+	}
+}
+
+func (r *WarrantyEventRepositoryImpl) Claim(ctx context.Context, e *WarrantyEvent) (bool, error) {
+	e.CreatedAt = time.Now()
+	filter := bson.M{
+		"tenant_id":    e.TenantID,
+		"asset_id":     e.AssetID,
+		"trigger":      e.Trigger,
+		"warranty_end": e.WarrantyEnd,
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$setOnInsert": bson.M{"created_at": e.CreatedAt}}, options.Update().SetUpsert(true))
+	if err != nil {
+		return false, err
+	}
+	return result.UpsertedCount > 0, nil
+}
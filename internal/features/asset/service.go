@@ -0,0 +1,187 @@
+package asset
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/ticket"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WarrantySchedule runs the warranty job daily
+const WarrantySchedule = "0 6 * * *"
+
+const warrantyPageSize = 500
+
+var (
+	ErrAssetNotFound  = apperr.NotFound("asset not found")
+	ErrTicketNotFound = apperr.NotFound("ticket not found")
+)
+
+type AssetService interface {
+	LinkTicket(ctx context.Context, assetID, ticketID string, userID primitive.ObjectID) error
+	UnlinkTicket(ctx context.Context, assetID, ticketID string, userID primitive.ObjectID) error
+	// GetHistory returns the asset and every ticket filed against it
+	GetHistory(ctx context.Context, assetID string, userID primitive.ObjectID) (*History, error)
+	// CheckWarranties is the system job firing the warranty automation triggers
+	CheckWarranties(ctx context.Context) error
+}
+
+type AssetServiceImpl struct {
+	AssetRepo         AssetRepository
+	EventRepo         WarrantyEventRepository
+	TicketRepo        ticket.TicketRepository
+	RecordService     record.RecordService
+	AutomationService record.AutomationTrigger
+	AuditService      audit.AuditService
+}
+
+func NewAssetService(
+	assetRepo AssetRepository,
+	eventRepo WarrantyEventRepository,
+	ticketRepo ticket.TicketRepository,
+	recordService record.RecordService,
+	automationService record.AutomationTrigger,
+	auditService audit.AuditService,
+) AssetService {
+	return &AssetServiceImpl{
+		AssetRepo:         assetRepo,
+		EventRepo:         eventRepo,
+		TicketRepo:        ticketRepo,
+		RecordService:     recordService,
+		AutomationService: automationService,
+		AuditService:      auditService,
+	}
+}
+
+// warrantyEnd reads the warranty_end field of a record
+func warrantyEnd(rec map[string]interface{}) (time.Time, bool) {
+	switch v := rec["warranty_end"].(type) {
+	case time.Time:
+		return v, true
+	case primitive.DateTime:
+		return v.Time(), true
+	}
+	return time.Time{}, false
+}
+
+// resolve checks the user can see the asset and that the ticket exists
+func (s *AssetServiceImpl) resolve(ctx context.Context, assetID, ticketID string, userID primitive.ObjectID) (primitive.ObjectID, primitive.ObjectID, error) {
+	if _, err := s.RecordService.GetRecord(ctx, ModuleName, assetID, userID); err != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, ErrAssetNotFound
+	}
+	assetOID, _ := primitive.ObjectIDFromHex(assetID)
+	ticketOID, err := primitive.ObjectIDFromHex(ticketID)
+	if err != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, ErrTicketNotFound
+	}
+	if _, err := s.TicketRepo.FindByID(ctx, ticketOID); err != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, ErrTicketNotFound
+	}
+	return assetOID, ticketOID, nil
+}
+
+func (s *AssetServiceImpl) LinkTicket(ctx context.Context, assetID, ticketID string, userID primitive.ObjectID) error {
+	assetOID, ticketOID, err := s.resolve(ctx, assetID, ticketID, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.TicketRepo.AddAsset(ctx, ticketOID, assetOID); err != nil {
+		return err
+	}
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionUpdate, "tickets", ticketID, map[string]common_models.Change{
+		"asset_ids": {New: assetID},
+	})
+	return nil
+}
+
+func (s *AssetServiceImpl) UnlinkTicket(ctx context.Context, assetID, ticketID string, userID primitive.ObjectID) error {
+	assetOID, ticketOID, err := s.resolve(ctx, assetID, ticketID, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.TicketRepo.RemoveAsset(ctx, ticketOID, assetOID); err != nil {
+		return err
+	}
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionUpdate, "tickets", ticketID, map[string]common_models.Change{
+		"asset_ids": {Old: assetID},
+	})
+	return nil
+}
+
+func (s *AssetServiceImpl) GetHistory(ctx context.Context, assetID string, userID primitive.ObjectID) (*History, error) {
+	asset, err := s.RecordService.GetRecord(ctx, ModuleName, assetID, userID)
+	if err != nil {
+		return nil, ErrAssetNotFound
+	}
+	assetOID, _ := primitive.ObjectIDFromHex(assetID)
+	tickets, err := s.TicketRepo.FindByAsset(ctx, assetOID)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &History{Asset: asset, Tickets: tickets}
+	if end, ok := warrantyEnd(asset); ok {
+		h.UnderWarranty = time.Now().Before(end)
+	}
+	for _, t := range tickets {
+		if t.Status != ticket.TicketStatusResolved && t.Status != ticket.TicketStatusClosed {
+			h.OpenTickets++
+		}
+	}
+	return h, nil
+}
+
+// CheckWarranties fires warranty_expiring on assets whose warranty ends
+// within WarrantyNoticeDays and warranty_expired once it has ended. Each
+// trigger fires once per asset and warranty end date.
+func (s *AssetServiceImpl) CheckWarranties(ctx context.Context) error {
+	now := time.Now()
+	before := now.AddDate(0, 0, WarrantyNoticeDays)
+
+	var lastID primitive.ObjectID
+	for {
+		assets, err := s.AssetRepo.ListWarrantyEndingBefore(ctx, before, lastID, warrantyPageSize)
+		if err != nil {
+			return err
+		}
+		for _, a := range assets {
+			lastID = a.ID
+			end, ok := warrantyEnd(a.Data)
+			if !ok {
+				continue
+			}
+			trigger := TriggerWarrantyExpiring
+			if !end.After(now) {
+				trigger = TriggerWarrantyExpired
+			}
+			fired, err := s.EventRepo.Claim(ctx, &WarrantyEvent{TenantID: a.TenantID, AssetID: a.ID, Trigger: trigger, WarrantyEnd: end})
+			if err != nil {
+				return err
+			}
+			if !fired {
+				continue
+			}
+
+			rec := make(map[string]interface{}, len(a.Data)+2)
+			for k, v := range a.Data {
+				rec[k] = v
+			}
+			rec["_id"] = a.ID
+			rec["id"] = a.ID
+			tenantCtx := context.WithValue(ctx, common_models.TenantIDKey, a.TenantID.Hex())
+			if err := s.AutomationService.ExecuteFromTrigger(tenantCtx, ModuleName, rec, trigger); err != nil {
+				log.Printf("assets: %s trigger for %s failed: %v", trigger, a.ID.Hex(), err)
+			}
+		}
+		if len(assets) < warrantyPageSize {
+			return nil
+		}
+	}
+}
@@ -2,6 +2,10 @@ package audit
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
+
 	common_models "go-crm/internal/common/models"
 	"go-crm/internal/database"
 
@@ -14,6 +18,18 @@ import (
 type AuditRepository interface {
 	Create(ctx context.Context, log common_models.AuditLog) error
 	List(ctx context.Context, filters map[string]interface{}, limit, offset int64) ([]common_models.AuditLog, error)
+	// ListSince returns a module's entries with the given actions after the
+	// entry ID after and written before until, oldest first
+	ListSince(ctx context.Context, module string, actions []common_models.AuditAction, after primitive.ObjectID, until time.Time, limit int64) ([]common_models.AuditLog, error)
+	// ListBefore returns the tenant's entries written before the time,
+	// oldest first, leaving out restored ones
+	ListBefore(ctx context.Context, before time.Time, limit int64) ([]common_models.AuditLog, error)
+	DeleteByIDs(ctx context.Context, ids []primitive.ObjectID) (int64, error)
+	// DeleteRestoredBefore removes entries restored from an archive before the time
+	DeleteRestoredBefore(ctx context.Context, before time.Time) (int64, error)
+	// InsertRestored puts archived entries back, skipping those already present
+	InsertRestored(ctx context.Context, logs []common_models.AuditLog) (int, error)
+	EnsureIndexes(ctx context.Context) error
 }
 
 type AuditRepositoryImpl struct {
@@ -72,3 +88,139 @@ func (r *AuditRepositoryImpl) List(ctx context.Context, filters map[string]inter
 	}
 	return logs, nil
 }
+
+func (r *AuditRepositoryImpl) ListSince(ctx context.Context, module string, actions []common_models.AuditAction, after primitive.ObjectID, until time.Time, limit int64) ([]common_models.AuditLog, error) {
+	tenantID, ok := ctx.Value(common_models.TenantIDKey).(string)
+	if !ok || tenantID == "" {
+		return nil, fmt.Errorf("organization context missing")
+	}
+	oid, err := primitive.ObjectIDFromHex(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	// IDs embed their creation second, so they bound the time as well
+	idRange := bson.M{"$lt": primitive.NewObjectIDFromTimestamp(until)}
+	if !after.IsZero() {
+		idRange["$gt"] = after
+	}
+	query := bson.M{
+		"tenant_id": oid,
+		"module":    module,
+		"action":    bson.M{"$in": actions},
+		"_id":       idRange,
+	}
+	opts := options.Find().SetLimit(limit).SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	cursor, err := r.Collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	var logs []common_models.AuditLog
+	if err = cursor.All(ctx, &logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantID, ok := ctx.Value(common_models.TenantIDKey).(string)
+	if !ok || tenantID == "" {
+		return primitive.NilObjectID, fmt.Errorf("organization context missing")
+	}
+	return primitive.ObjectIDFromHex(tenantID)
+}
+
+func (r *AuditRepositoryImpl) ListBefore(ctx context.Context, before time.Time, limit int64) ([]common_models.AuditLog, error) {
+	oid, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := bson.M{
+		"tenant_id":   oid,
+		"timestamp":   bson.M{"$lt": before},
+		"restored_at": bson.M{"$exists": false},
+	}
+	opts := options.Find().SetLimit(limit).SetSort(bson.D{{Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := r.Collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	var logs []common_models.AuditLog
+	if err = cursor.All(ctx, &logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+func (r *AuditRepositoryImpl) DeleteByIDs(ctx context.Context, ids []primitive.ObjectID) (int64, error) {
+	oid, err := tenantFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	res, err := r.Collection.DeleteMany(ctx, bson.M{"tenant_id": oid, "_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+func (r *AuditRepositoryImpl) DeleteRestoredBefore(ctx context.Context, before time.Time) (int64, error) {
+	oid, err := tenantFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	res, err := r.Collection.DeleteMany(ctx, bson.M{"tenant_id": oid, "restored_at": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+func (r *AuditRepositoryImpl) InsertRestored(ctx context.Context, logs []common_models.AuditLog) (int, error) {
+	oid, err := tenantFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(logs) == 0 {
+		return 0, nil
+	}
+	docs := make([]interface{}, 0, len(logs))
+	for _, l := range logs {
+		l.TenantID = oid
+		docs = append(docs, l)
+	}
+
+	_, err = r.Collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) {
+		for _, we := range bulkErr.WriteErrors {
+			if !mongo.IsDuplicateKeyError(we) {
+				return 0, err
+			}
+		}
+		// The rest are still here, or were restored by an earlier request
+		return len(docs) - len(bulkErr.WriteErrors), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(docs), nil
+}
+
+func (r *AuditRepositoryImpl) EnsureIndexes(ctx context.Context) error {
+	_, err := r.Collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "module", Value: 1}, {Key: "_id", Value: 1}},
+			Options: options.Index().SetName("idx_tenant_module_id"),
+		},
+		{
+			// Retention reads a tenant's oldest entries
+			Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "timestamp", Value: 1}},
+			Options: options.Index().SetName("idx_tenant_timestamp"),
+		},
+	})
+	return err
+}
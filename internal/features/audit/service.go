@@ -16,6 +16,7 @@ type UserFinder interface {
 type AuditService interface {
 	LogChange(ctx context.Context, action common_models.AuditAction, module string, recordID string, changes map[string]common_models.Change) error
 	ListLogs(ctx context.Context, filters map[string]interface{}, page, limit int64) ([]common_models.AuditLog, error)
+	ListSince(ctx context.Context, module string, actions []common_models.AuditAction, after primitive.ObjectID, until time.Time, limit int64) ([]common_models.AuditLog, error)
 }
 
 type AuditServiceImpl struct {
@@ -33,8 +34,10 @@ func NewAuditService(repo AuditRepository, userRepo UserFinder) AuditService {
 func (s *AuditServiceImpl) LogChange(ctx context.Context, action common_models.AuditAction, module string, recordID string, changes map[string]common_models.Change) error {
 	// Extract Actor from Context
 	actorID := "system"
+	impersonatorID := ""
 	if claims, ok := ctx.Value(utils.UserClaimsKey).(*utils.UserClaims); ok {
 		actorID = claims.UserID
+		impersonatorID = claims.ImpersonatorID
 	}
 
 	log := common_models.AuditLog{
@@ -45,6 +48,8 @@ func (s *AuditServiceImpl) LogChange(ctx context.Context, action common_models.A
 		ActorID:   actorID,
 		Changes:   changes,
 		Timestamp: time.Now(),
+
+		ImpersonatorID: impersonatorID,
 	}
 
 	return s.Repo.Create(ctx, log)
@@ -101,3 +106,8 @@ func (s *AuditServiceImpl) ListLogs(ctx context.Context, filters map[string]inte
 
 	return logs, nil
 }
+
+// ListSince pages through a module's entries in the order they were written
+func (s *AuditServiceImpl) ListSince(ctx context.Context, module string, actions []common_models.AuditAction, after primitive.ObjectID, until time.Time, limit int64) ([]common_models.AuditLog, error) {
+	return s.Repo.ListSince(ctx, module, actions, after, until, limit)
+}
@@ -0,0 +1,34 @@
+package audit_archive
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AuditArchiveApi struct {
+	controller  *AuditArchiveController
+	config      *config.Config
+	roleService middleware.RoleService
+}
+
+func NewAuditArchiveApi(controller *AuditArchiveController, config *config.Config, roleService middleware.RoleService) *AuditArchiveApi {
+	return &AuditArchiveApi{
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *AuditArchiveApi) Setup(app *fiber.App) {
+	group := app.Group("/api/audit-archive", middleware.AuthMiddleware(h.config.SkipAuth))
+
+	group.Get("/policy", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.GetPolicy)
+	group.Put("/policy", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.SavePolicy)
+	group.Post("/run", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.Run)
+
+	group.Get("/archives", middleware.RequirePermission(h.roleService, "crm.settings_audit_logs", "read"), h.controller.ListArchives)
+	group.Get("/entries", middleware.RequirePermission(h.roleService, "crm.settings_audit_logs", "read"), h.controller.QueryEntries)
+	group.Post("/restore", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.Restore)
+}
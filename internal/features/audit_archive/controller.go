@@ -0,0 +1,184 @@
+package audit_archive
+
+import (
+	"errors"
+	"time"
+
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AuditArchiveController struct {
+	Service AuditArchiveService
+}
+
+func NewAuditArchiveController(service AuditArchiveService) *AuditArchiveController {
+	return &AuditArchiveController{Service: service}
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+func queryRange(ctx *fiber.Ctx) (time.Time, time.Time, error) {
+	from, err := parseTime(ctx.Query("from"))
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid from")
+	}
+	to, err := parseTime(ctx.Query("to"))
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid to")
+	}
+	return from, to, nil
+}
+
+func readError(ctx *fiber.Ctx, err error) error {
+	if errors.Is(err, ErrRangeRequired) || errors.Is(err, ErrTooManyFiles) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+}
+
+// GetPolicy godoc
+// @Summary Get audit retention policy
+// @Tags audit
+// @Produce json
+// @Success 200 {object} RetentionPolicy
+// @Router /api/audit-archive/policy [get]
+func (c *AuditArchiveController) GetPolicy(ctx *fiber.Ctx) error {
+	policy, err := c.Service.GetPolicy(ctx.UserContext())
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": policy})
+}
+
+// SavePolicy godoc
+// @Summary Save audit retention policy
+// @Description Keep audit entries for keep_days; older ones are exported to compressed files nightly and removed
+// @Tags audit
+// @Accept json
+// @Produce json
+// @Param policy body RetentionPolicy true "Policy"
+// @Success 200 {object} RetentionPolicy
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/audit-archive/policy [put]
+func (c *AuditArchiveController) SavePolicy(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var policy RetentionPolicy
+	if err := ctx.BodyParser(&policy); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	saved, err := c.Service.SavePolicy(ctx.UserContext(), &policy, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": saved})
+}
+
+// Run godoc
+// @Summary Run audit retention now
+// @Description Archive and remove expired entries without waiting for the nightly run
+// @Tags audit
+// @Produce json
+// @Success 200 {object} RunResult
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/audit-archive/run [post]
+func (c *AuditArchiveController) Run(ctx *fiber.Ctx) error {
+	result, err := c.Service.Run(ctx.UserContext())
+	if errors.Is(err, ErrNoPolicy) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		// Batches stored before the failure stay archived
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error(), "data": result})
+	}
+	return ctx.JSON(fiber.Map{"data": result})
+}
+
+// ListArchives godoc
+// @Summary List audit archives
+// @Tags audit
+// @Produce json
+// @Param from query string false "Start, RFC 3339 or YYYY-MM-DD"
+// @Param to query string false "End"
+// @Success 200 {array} Archive
+// @Router /api/audit-archive/archives [get]
+func (c *AuditArchiveController) ListArchives(ctx *fiber.Ctx) error {
+	from, to, err := queryRange(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	archives, err := c.Service.ListArchives(ctx.UserContext(), from, to)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": archives})
+}
+
+// QueryEntries godoc
+// @Summary Search archived audit entries
+// @Description Read entries of a range from the archives without restoring them
+// @Tags audit
+// @Produce json
+// @Param from query string true "Start, RFC 3339 or YYYY-MM-DD"
+// @Param to query string true "End"
+// @Param module query string false "Module"
+// @Param record_id query string false "Record ID"
+// @Param actor_id query string false "User ID"
+// @Param action query string false "Action"
+// @Param limit query int false "Entries (default 100, max 1000)"
+// @Success 200 {array} models.AuditLog
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/audit-archive/entries [get]
+func (c *AuditArchiveController) QueryEntries(ctx *fiber.Ctx) error {
+	from, to, err := queryRange(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	entries, err := c.Service.QueryEntries(ctx.UserContext(), EntryQuery{
+		From:     from,
+		To:       to,
+		Module:   ctx.Query("module"),
+		RecordID: ctx.Query("record_id"),
+		ActorID:  ctx.Query("actor_id"),
+		Action:   ctx.Query("action"),
+		Limit:    ctx.QueryInt("limit", 0),
+	})
+	if err != nil {
+		return readError(ctx, err)
+	}
+	return ctx.JSON(fiber.Map{"data": entries})
+}
+
+// Restore godoc
+// @Summary Restore archived audit entries
+// @Description Put the archived entries of a range back into the audit log for 30 days
+// @Tags audit
+// @Accept json
+// @Produce json
+// @Param request body RestoreRequest true "Range"
+// @Success 200 {object} RestoreResult
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/audit-archive/restore [post]
+func (c *AuditArchiveController) Restore(ctx *fiber.Ctx) error {
+	var req RestoreRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	result, err := c.Service.Restore(ctx.UserContext(), req)
+	if err != nil {
+		return readError(ctx, err)
+	}
+	return ctx.JSON(fiber.Map{"data": result})
+}
@@ -0,0 +1,68 @@
+package audit_archive
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RetentionPolicy is how long a tenant keeps audit entries in the database.
+// Older entries are written to an archive file and removed; tenants without
+// an active policy keep everything.
+type RetentionPolicy struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	IsActive bool               `json:"is_active" bson:"is_active"`
+	KeepDays int                `json:"keep_days" bson:"keep_days"` // e.g. 548 for 18 months
+	// SkipArchive deletes expired entries without exporting them first
+	SkipArchive bool `json:"skip_archive" bson:"skip_archive"`
+
+	LastRunAt    *time.Time `json:"last_run_at,omitempty" bson:"last_run_at,omitempty"`
+	LastArchived int64      `json:"last_archived" bson:"last_archived"` // Entries removed by the last run
+
+	UpdatedBy primitive.ObjectID `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// Archive is one gzipped NDJSON file of audit entries in file storage
+type Archive struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID   primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	From       time.Time          `json:"from" bson:"from"` // Timestamp of the oldest entry
+	To         time.Time          `json:"to" bson:"to"`     // Timestamp of the newest entry
+	Count      int                `json:"count" bson:"count"`
+	Size       int64              `json:"size" bson:"size"` // Compressed bytes
+	Storage    string             `json:"storage" bson:"storage"`
+	StorageKey string             `json:"-" bson:"storage_key"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type RunResult struct {
+	Archived int64 `json:"archived"` // Entries exported and removed
+	Deleted  int64 `json:"deleted"`  // Entries removed without export
+	Expired  int64 `json:"expired"`  // Restored entries removed again
+	Files    int   `json:"files"`
+}
+
+// EntryQuery filters archived entries read on demand
+type EntryQuery struct {
+	From     time.Time
+	To       time.Time
+	Module   string
+	RecordID string
+	ActorID  string
+	Action   string
+	Limit    int
+}
+
+type RestoreRequest struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+type RestoreResult struct {
+	Files     int       `json:"files"`
+	Restored  int       `json:"restored"`
+	ExpiresAt time.Time `json:"expires_at"` // When the restored entries are removed again
+}
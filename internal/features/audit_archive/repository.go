@@ -0,0 +1,168 @@
+package audit_archive
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+type PolicyRepository interface {
+	// Get returns the tenant's policy
+	Get(ctx context.Context) (*RetentionPolicy, error)
+	Save(ctx context.Context, policy *RetentionPolicy) error
+	SetLastRun(ctx context.Context, at time.Time, archived int64) error
+	// ListAllActive returns active policies of every tenant for the scheduled run
+	ListAllActive(ctx context.Context) ([]RetentionPolicy, error)
+}
+
+type PolicyRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewPolicyRepository(db *database.MongodbDB) PolicyRepository {
+	return &PolicyRepositoryImpl{
+		collection: db.DB.Collection("audit_retention_policies"),
+	}
+}
+
+func (r *PolicyRepositoryImpl) Get(ctx context.Context) (*RetentionPolicy, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var policy RetentionPolicy
+	if err := r.collection.FindOne(ctx, bson.M{"tenant_id": tenantID}).Decode(&policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (r *PolicyRepositoryImpl) Save(ctx context.Context, policy *RetentionPolicy) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	policy.TenantID = tenantID
+	policy.UpdatedAt = now
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	return r.collection.FindOneAndUpdate(ctx, bson.M{"tenant_id": tenantID}, bson.M{
+		"$set": bson.M{
+			"is_active":    policy.IsActive,
+			"keep_days":    policy.KeepDays,
+			"skip_archive": policy.SkipArchive,
+			"updated_by":   policy.UpdatedBy,
+			"updated_at":   now,
+		},
+		"$setOnInsert": bson.M{"created_at": now, "last_archived": 0},
+	}, opts).Decode(policy)
+}
+
+func (r *PolicyRepositoryImpl) SetLastRun(ctx context.Context, at time.Time, archived int64) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.UpdateOne(ctx, bson.M{"tenant_id": tenantID}, bson.M{
+		"$set": bson.M{"last_run_at": at, "last_archived": archived},
+	})
+	return err
+}
+
+func (r *PolicyRepositoryImpl) ListAllActive(ctx context.Context) ([]RetentionPolicy, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"is_active": true})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var policies []RetentionPolicy
+	if err := cursor.All(ctx, &policies); err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+type ArchiveRepository interface {
+	Create(ctx context.Context, archive *Archive) error
+	// ListOverlapping returns the tenant's archives holding entries of [from, to], oldest first
+	ListOverlapping(ctx context.Context, from, to time.Time) ([]Archive, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type ArchiveRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewArchiveRepository(db *database.MongodbDB) ArchiveRepository {
+	return &ArchiveRepositoryImpl{
+		collection: db.DB.Collection("audit_archives"),
+	}
+}
+
+func (r *ArchiveRepositoryImpl) Create(ctx context.Context, archive *Archive) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	archive.TenantID = tenantID
+	if archive.ID.IsZero() {
+		archive.ID = primitive.NewObjectID()
+	}
+	archive.CreatedAt = time.Now()
+
+	_, err = r.collection.InsertOne(ctx, archive)
+	return err
+}
+
+func (r *ArchiveRepositoryImpl) ListOverlapping(ctx context.Context, from, to time.Time) ([]Archive, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := bson.M{"tenant_id": tenantID}
+	if !to.IsZero() {
+		query["from"] = bson.M{"$lte": to}
+	}
+	if !from.IsZero() {
+		query["to"] = bson.M{"$gte": from}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "from", Value: 1}})
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	archives := []Archive{}
+	if err := cursor.All(ctx, &archives); err != nil {
+		return nil, err
+	}
+	return archives, nil
+}
+
+func (r *ArchiveRepositoryImpl) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "from", Value: 1}},
+		Options: options.Index().SetName("idx_tenant_from"),
+	})
+	return err
+}
@@ -0,0 +1,357 @@
+package audit_archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/file"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RetentionSchedule runs every active policy nightly, after record archival
+const RetentionSchedule = "15 4 * * *"
+
+const (
+	minKeepDays = 30
+	// batchSize is the number of entries per archive file
+	batchSize = 5000
+	// maxPerRun spreads a first run on a large tenant over several nights
+	maxPerRun = 200000
+	// restoreDays is how long restored entries stay before removal
+	restoreDays = 30
+	// maxReadFiles caps the archives one query or restore reads
+	maxReadFiles      = 50
+	defaultEntryLimit = 100
+	maxEntryLimit     = 1000
+	// maxLineSize bounds one archived entry, whose changes may hold large values
+	maxLineSize = 16 << 20
+)
+
+var (
+	ErrNoPolicy      = errors.New("no active audit retention policy")
+	ErrRangeRequired = errors.New("from and to are required, with from before to")
+	ErrTooManyFiles  = fmt.Errorf("range spans more than %d archives, narrow it", maxReadFiles)
+)
+
+type AuditArchiveService interface {
+	// GetPolicy returns the tenant's policy, or an inactive one when none is saved
+	GetPolicy(ctx context.Context) (*RetentionPolicy, error)
+	SavePolicy(ctx context.Context, policy *RetentionPolicy, userID primitive.ObjectID) (*RetentionPolicy, error)
+	// Run archives and removes the tenant's expired entries now
+	Run(ctx context.Context) (*RunResult, error)
+	// RunAll runs every tenant's active policy; registered as a system job
+	RunAll(ctx context.Context) error
+
+	ListArchives(ctx context.Context, from, to time.Time) ([]Archive, error)
+	// QueryEntries reads matching entries from the archives, oldest first
+	QueryEntries(ctx context.Context, q EntryQuery) ([]common_models.AuditLog, error)
+	// Restore puts the archived entries of a range back for restoreDays
+	Restore(ctx context.Context, req RestoreRequest) (*RestoreResult, error)
+}
+
+type AuditArchiveServiceImpl struct {
+	PolicyRepo   PolicyRepository
+	ArchiveRepo  ArchiveRepository
+	AuditRepo    audit.AuditRepository
+	AuditService audit.AuditService
+	Storage      file.Storage
+}
+
+func NewAuditArchiveService(
+	policyRepo PolicyRepository,
+	archiveRepo ArchiveRepository,
+	auditRepo audit.AuditRepository,
+	auditService audit.AuditService,
+	storage file.Storage,
+) AuditArchiveService {
+	return &AuditArchiveServiceImpl{
+		PolicyRepo:   policyRepo,
+		ArchiveRepo:  archiveRepo,
+		AuditRepo:    auditRepo,
+		AuditService: auditService,
+		Storage:      storage,
+	}
+}
+
+func (s *AuditArchiveServiceImpl) GetPolicy(ctx context.Context) (*RetentionPolicy, error) {
+	policy, err := s.PolicyRepo.Get(ctx)
+	if err == mongo.ErrNoDocuments {
+		return &RetentionPolicy{}, nil
+	}
+	return policy, err
+}
+
+func (s *AuditArchiveServiceImpl) SavePolicy(ctx context.Context, policy *RetentionPolicy, userID primitive.ObjectID) (*RetentionPolicy, error) {
+	if policy.IsActive && policy.KeepDays < minKeepDays {
+		return nil, fmt.Errorf("keep_days must be at least %d", minKeepDays)
+	}
+	existing, err := s.GetPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	policy.UpdatedBy = userID
+	if err := s.PolicyRepo.Save(ctx, policy); err != nil {
+		return nil, err
+	}
+	s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "audit_retention", policy.ID.Hex(), map[string]common_models.Change{
+		"policy": {Old: existing, New: policy},
+	})
+	return policy, nil
+}
+
+func (s *AuditArchiveServiceImpl) Run(ctx context.Context) (*RunResult, error) {
+	policy, err := s.PolicyRepo.Get(ctx)
+	if err == mongo.ErrNoDocuments || (err == nil && !policy.IsActive) {
+		return nil, ErrNoPolicy
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.run(ctx, policy)
+}
+
+func (s *AuditArchiveServiceImpl) RunAll(ctx context.Context) error {
+	policies, err := s.PolicyRepo.ListAllActive(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range policies {
+		p := &policies[i]
+		tenantCtx := context.WithValue(ctx, common_models.TenantIDKey, p.TenantID.Hex())
+		if _, err := s.run(tenantCtx, p); err != nil {
+			log.Printf("audit retention: tenant %s failed: %v", p.TenantID.Hex(), err)
+		}
+	}
+	return nil
+}
+
+// run exports and removes entries older than the policy keeps, a file per
+// batch. A batch is only removed once its file is stored.
+func (s *AuditArchiveServiceImpl) run(ctx context.Context, policy *RetentionPolicy) (*RunResult, error) {
+	now := time.Now()
+	cutoff := now.AddDate(0, 0, -policy.KeepDays)
+	result := &RunResult{}
+
+	var runErr error
+	for result.Archived+result.Deleted < maxPerRun {
+		logs, err := s.AuditRepo.ListBefore(ctx, cutoff, batchSize)
+		if err != nil {
+			runErr = err
+			break
+		}
+		if len(logs) == 0 {
+			break
+		}
+		if !policy.SkipArchive {
+			if _, err := s.writeArchive(ctx, logs); err != nil {
+				runErr = err
+				break
+			}
+			result.Files++
+		}
+
+		ids := make([]primitive.ObjectID, len(logs))
+		for i, l := range logs {
+			ids[i] = l.ID
+		}
+		n, err := s.AuditRepo.DeleteByIDs(ctx, ids)
+		if policy.SkipArchive {
+			result.Deleted += n
+		} else {
+			result.Archived += n
+		}
+		if err != nil {
+			runErr = err
+			break
+		}
+		if len(logs) < batchSize {
+			break
+		}
+	}
+
+	if runErr == nil {
+		result.Expired, runErr = s.AuditRepo.DeleteRestoredBefore(ctx, now.AddDate(0, 0, -restoreDays))
+	}
+	if err := s.PolicyRepo.SetLastRun(ctx, now, result.Archived+result.Deleted); err != nil {
+		log.Printf("audit retention: failed to record run for tenant %s: %v", policy.TenantID.Hex(), err)
+	}
+	if result.Archived+result.Deleted > 0 {
+		s.AuditService.LogChange(ctx, common_models.AuditActionArchive, "audit_retention", policy.ID.Hex(), map[string]common_models.Change{
+			"archived": {New: result.Archived},
+			"deleted":  {New: result.Deleted},
+			"files":    {New: result.Files},
+		})
+	}
+	return result, runErr
+}
+
+// writeArchive stores entries as gzipped NDJSON, one Extended JSON document
+// per line so types survive a restore
+func (s *AuditArchiveServiceImpl) writeArchive(ctx context.Context, logs []common_models.AuditLog) (*Archive, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	for _, l := range logs {
+		line, err := bson.MarshalExtJSON(l, false, false)
+		if err != nil {
+			return nil, err
+		}
+		zw.Write(line)
+		zw.Write([]byte{'\n'})
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	archive := &Archive{
+		ID:      primitive.NewObjectID(),
+		From:    logs[0].Timestamp,
+		To:      logs[len(logs)-1].Timestamp,
+		Count:   len(logs),
+		Size:    int64(buf.Len()),
+		Storage: s.Storage.Name(),
+	}
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	archive.StorageKey = fmt.Sprintf("audit-archives/%s/%s-%s.ndjson.gz", tenantID.Hex(), archive.From.UTC().Format("20060102T150405Z"), archive.ID.Hex())
+
+	if err := s.Storage.Put(ctx, archive.StorageKey, bytes.NewReader(buf.Bytes()), archive.Size, "application/gzip"); err != nil {
+		return nil, fmt.Errorf("store audit archive: %w", err)
+	}
+	if err := s.ArchiveRepo.Create(ctx, archive); err != nil {
+		// Without its record the file could not be found again
+		s.Storage.Delete(ctx, archive.StorageKey)
+		return nil, err
+	}
+	return archive, nil
+}
+
+// readArchive calls fn with each entry of an archive until it returns false
+func (s *AuditArchiveServiceImpl) readArchive(ctx context.Context, archive *Archive, fn func(common_models.AuditLog) bool) error {
+	rc, err := s.Storage.Open(ctx, archive.StorageKey)
+	if err != nil {
+		return fmt.Errorf("open audit archive %s: %w", archive.ID.Hex(), err)
+	}
+	defer rc.Close()
+	zr, err := gzip.NewReader(rc)
+	if err != nil {
+		return fmt.Errorf("read audit archive %s: %w", archive.ID.Hex(), err)
+	}
+	defer zr.Close()
+
+	scanner := bufio.NewScanner(zr)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		var entry common_models.AuditLog
+		if err := bson.UnmarshalExtJSON(scanner.Bytes(), false, &entry); err != nil {
+			return fmt.Errorf("read audit archive %s: %w", archive.ID.Hex(), err)
+		}
+		if !fn(entry) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+func (s *AuditArchiveServiceImpl) ListArchives(ctx context.Context, from, to time.Time) ([]Archive, error) {
+	return s.ArchiveRepo.ListOverlapping(ctx, from, to)
+}
+
+// archivesFor returns the archives of a range a query or restore reads
+func (s *AuditArchiveServiceImpl) archivesFor(ctx context.Context, from, to time.Time) ([]Archive, error) {
+	if from.IsZero() || to.IsZero() || !from.Before(to) {
+		return nil, ErrRangeRequired
+	}
+	archives, err := s.ArchiveRepo.ListOverlapping(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if len(archives) > maxReadFiles {
+		return nil, ErrTooManyFiles
+	}
+	return archives, nil
+}
+
+func (s *AuditArchiveServiceImpl) QueryEntries(ctx context.Context, q EntryQuery) ([]common_models.AuditLog, error) {
+	archives, err := s.archivesFor(ctx, q.From, q.To)
+	if err != nil {
+		return nil, err
+	}
+	if q.Limit < 1 {
+		q.Limit = defaultEntryLimit
+	}
+	q.Limit = min(q.Limit, maxEntryLimit)
+
+	entries := []common_models.AuditLog{}
+	for i := range archives {
+		err := s.readArchive(ctx, &archives[i], func(e common_models.AuditLog) bool {
+			if inRange(e, q.From, q.To) && matches(e, q) {
+				entries = append(entries, e)
+			}
+			return len(entries) < q.Limit
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) >= q.Limit {
+			break
+		}
+	}
+	return entries, nil
+}
+
+func inRange(e common_models.AuditLog, from, to time.Time) bool {
+	return !e.Timestamp.Before(from) && !e.Timestamp.After(to)
+}
+
+func matches(e common_models.AuditLog, q EntryQuery) bool {
+	return (q.Module == "" || e.Module == q.Module) &&
+		(q.RecordID == "" || e.RecordID == q.RecordID) &&
+		(q.ActorID == "" || e.ActorID == q.ActorID) &&
+		(q.Action == "" || string(e.Action) == q.Action)
+}
+
+func (s *AuditArchiveServiceImpl) Restore(ctx context.Context, req RestoreRequest) (*RestoreResult, error) {
+	archives, err := s.archivesFor(ctx, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	result := &RestoreResult{Files: len(archives), ExpiresAt: now.AddDate(0, 0, restoreDays)}
+
+	for i := range archives {
+		var batch []common_models.AuditLog
+		err := s.readArchive(ctx, &archives[i], func(e common_models.AuditLog) bool {
+			if inRange(e, req.From, req.To) {
+				e.RestoredAt = &now
+				batch = append(batch, e)
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		n, err := s.AuditRepo.InsertRestored(ctx, batch)
+		result.Restored += n
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "audit_retention", "", map[string]common_models.Change{
+		"restored": {New: map[string]any{"from": req.From, "to": req.To, "entries": result.Restored}},
+	})
+	return result, nil
+}
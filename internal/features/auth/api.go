@@ -24,6 +24,8 @@ func (h *AuthApi) Setup(app *fiber.App) {
 	// Public routes
 	app.Post("/api/register", h.controller.Register)
 	app.Post("/api/login", h.controller.Login)
+	// Accepts tokens rejected for a stale permissions version
+	app.Post("/api/token/refresh", h.controller.RefreshToken)
 
 	// Protected route example
 	app.Get("/api/protected", middleware.AuthMiddleware(h.config.SkipAuth), h.protectedRoute)
@@ -89,3 +89,30 @@ func (ctrl *AuthController) Login(c *fiber.Ctx) error {
 
 	return c.JSON(AuthResponse{Token: token})
 }
+
+// RefreshToken godoc
+// @Summary      Refresh token
+// @Description  Reissue the bearer token with current roles, e.g. after a 401 with code permissions_changed
+// @Tags         auth
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Success      200  {object} AuthResponse
+// @Failure      401  {string} string "Invalid token"
+// @Router       /api/token/refresh [post]
+func (ctrl *AuthController) RefreshToken(c *fiber.Ctx) error {
+	authHeader := c.Get("Authorization")
+	if len(authHeader) < 7 || authHeader[:7] != "Bearer " {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid authorization header format",
+		})
+	}
+
+	token, err := ctrl.AuthService.RefreshToken(c.Context(), authHeader[7:])
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(AuthResponse{Token: token})
+}
@@ -6,7 +6,9 @@ import (
 	"time"
 
 	"go-crm/internal/common/models"
+	"go-crm/internal/config"
 	"go-crm/internal/features/audit"
+	"go-crm/internal/features/authz"
 	"go-crm/internal/features/role"
 	"go-crm/internal/features/user"
 	"go-crm/pkg/utils"
@@ -21,6 +23,12 @@ import (
 type AuthService interface {
 	Register(ctx context.Context, username, password, email, orgName string) (*models.User, error)
 	Login(ctx context.Context, username, password string) (string, error)
+	// RefreshToken reissues a signed, unexpired token with the user's current
+	// roles and permissions version
+	RefreshToken(ctx context.Context, token string) (string, error)
+	// IssueAddInToken signs a longer-lived token that only works on the email
+	// add-in endpoints, for clients that keep it in their own storage
+	IssueAddInToken(ctx context.Context, claims *utils.UserClaims) (string, time.Time, error)
 }
 
 type AuthServiceImpl struct {
@@ -28,14 +36,18 @@ type AuthServiceImpl struct {
 	RoleRepo         role.RoleRepository
 	OrganizationRepo organization.OrganizationRepository
 	AuditService     audit.AuditService
+	Versions         authz.VersionService
+	Config           *config.Config
 }
 
-func NewAuthService(userRepo user.UserRepository, roleRepo role.RoleRepository, orgRepo organization.OrganizationRepository, auditService audit.AuditService) AuthService {
+func NewAuthService(userRepo user.UserRepository, roleRepo role.RoleRepository, orgRepo organization.OrganizationRepository, auditService audit.AuditService, versions authz.VersionService, cfg *config.Config) AuthService {
 	return &AuthServiceImpl{
 		UserRepo:         userRepo,
 		RoleRepo:         roleRepo,
 		OrganizationRepo: orgRepo,
 		AuditService:     auditService,
+		Versions:         versions,
+		Config:           cfg,
 	}
 }
 
@@ -55,6 +67,9 @@ func (s *AuthServiceImpl) Register(ctx context.Context, username, password, emai
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
+	if s.Config != nil {
+		newOrg.Plan = s.Config.DefaultPlan
+	}
 	// Note: OwnerID will be set after user creation, or perform transaction?
 	// For simplicity, generate UserID first.
 	newUserID := primitive.NewObjectID()
@@ -132,12 +147,100 @@ func (s *AuthServiceImpl) Login(ctx context.Context, username, password string)
 		return "", errors.New("invalid credentials")
 	}
 
+	return s.issueToken(ctx, usr)
+}
+
+func (s *AuthServiceImpl) RefreshToken(ctx context.Context, token string) (string, error) {
+	claims, err := utils.ValidateToken(token)
+	if err != nil {
+		return "", errors.New("invalid token")
+	}
+	if claims.ImpersonationID != "" {
+		return "", errors.New("impersonation tokens cannot be refreshed")
+	}
+
+	ctx = context.WithValue(ctx, models.TenantIDKey, claims.TenantID)
+	usr, err := s.UserRepo.FindByID(ctx, claims.UserID)
+	if err != nil || usr == nil {
+		return "", errors.New("invalid token")
+	}
+	// Add-in tokens stay add-in tokens
+	if claims.Scope == utils.ScopeAddIn {
+		token, _, err := s.issueAddInToken(ctx, usr)
+		return token, err
+	}
+	return s.issueToken(ctx, usr)
+}
+
+func (s *AuthServiceImpl) IssueAddInToken(ctx context.Context, claims *utils.UserClaims) (string, time.Time, error) {
+	if claims == nil {
+		return "", time.Time{}, errors.New("invalid token")
+	}
+	if claims.ImpersonationID != "" {
+		return "", time.Time{}, errors.New("impersonation tokens cannot issue add-in tokens")
+	}
+	if claims.Scope != "" {
+		return "", time.Time{}, errors.New("scoped tokens cannot issue add-in tokens")
+	}
+
+	ctx = context.WithValue(ctx, models.TenantIDKey, claims.TenantID)
+	usr, err := s.UserRepo.FindByID(ctx, claims.UserID)
+	if err != nil || usr == nil {
+		return "", time.Time{}, errors.New("invalid token")
+	}
+	token, expiresAt, err := s.issueAddInToken(ctx, usr)
+	if err == nil {
+		_ = s.AuditService.LogChange(ctx, models.AuditActionLogin, "user", usr.ID.Hex(), map[string]models.Change{
+			"addin_token": {New: expiresAt},
+		})
+	}
+	return token, expiresAt, err
+}
+
+func (s *AuthServiceImpl) issueAddInToken(ctx context.Context, usr *models.User) (string, time.Time, error) {
+	ttl := 30 * 24 * time.Hour
+	if s.Config != nil && s.Config.AddInTokenTTLHours > 0 {
+		ttl = time.Duration(s.Config.AddInTokenTTLHours) * time.Hour
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	roleNames, roleIDs, groups, version, err := s.tokenClaims(ctx, usr)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	token, err := utils.GenerateScopedToken(usr.ID, usr.TenantID, roleNames, roleIDs, groups, utils.ScopeAddIn, expiresAt, version)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// issueToken checks the account can sign in and signs a token with its
+// current roles, groups and permissions version
+func (s *AuthServiceImpl) issueToken(ctx context.Context, usr *models.User) (string, error) {
+	roleNames, roleIDs, userGroups, version, err := s.tokenClaims(ctx, usr)
+	if err != nil {
+		return "", err
+	}
+
+	// Generate JWT with user groups
+	token, err := utils.GenerateToken(usr.ID, usr.TenantID, roleNames, roleIDs, userGroups, version)
+
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// tokenClaims checks the account can sign in and collects what its tokens carry
+func (s *AuthServiceImpl) tokenClaims(ctx context.Context, usr *models.User) ([]string, []string, []string, int64, error) {
 	// Check user status
 	if usr.Status == "suspended" {
-		return "", errors.New("account suspended")
+		return nil, nil, nil, 0, errors.New("account suspended")
 	}
 	if usr.Status == "inactive" {
-		return "", errors.New("account inactive")
+		return nil, nil, nil, 0, errors.New("account inactive")
 	}
 
 	// Set Organization Context for subsequent calls (e.g. Roles)
@@ -163,18 +266,16 @@ func (s *AuthServiceImpl) Login(ctx context.Context, username, password string)
 		roleIDs = []string{}
 	}
 
-	// Generate JWT with user groups
+	var version int64
+	if s.Versions != nil {
+		version = s.Versions.Current(ctx, append([]string{usr.ID.Hex()}, roleIDs...)...)
+	}
+
 	userGroups := usr.Groups
 	if userGroups == nil {
 		userGroups = []string{}
 	}
-	token, err := utils.GenerateToken(usr.ID, usr.TenantID, roleNames, roleIDs, userGroups)
-
-	if err != nil {
-		return "", err
-	}
-
-	return token, nil
+	return roleNames, roleIDs, userGroups, version, nil
 }
 
 // Helper function to check if slice contains string
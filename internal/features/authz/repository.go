@@ -0,0 +1,70 @@
+package authz
+
+import (
+	"context"
+	"time"
+
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// versionDoc holds one subject's permissions version; subjects are the users
+// and roles whose grants tokens depend on
+type versionDoc struct {
+	SubjectID primitive.ObjectID `bson:"_id"`
+	Version   int64              `bson:"version"`
+	UpdatedAt time.Time          `bson:"updated_at"`
+}
+
+type VersionRepository interface {
+	// Get returns the versions of the subjects; subjects whose permissions
+	// never changed are left out
+	Get(ctx context.Context, subjectIDs []primitive.ObjectID) (map[primitive.ObjectID]int64, error)
+	Bump(ctx context.Context, subjectIDs []primitive.ObjectID) error
+}
+
+type VersionRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewVersionRepository(db *database.MongodbDB) VersionRepository {
+	return &VersionRepositoryImpl{
+		collection: db.DB.Collection("permission_versions"),
+	}
+}
+
+func (r *VersionRepositoryImpl) Get(ctx context.Context, subjectIDs []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": subjectIDs}})
+	if err != nil {
+		return nil, err
+	}
+	var docs []versionDoc
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	versions := make(map[primitive.ObjectID]int64, len(docs))
+	for _, d := range docs {
+		versions[d.SubjectID] = d.Version
+	}
+	return versions, nil
+}
+
+func (r *VersionRepositoryImpl) Bump(ctx context.Context, subjectIDs []primitive.ObjectID) error {
+	if len(subjectIDs) == 0 {
+		return nil
+	}
+	update := bson.M{
+		"$inc": bson.M{"version": 1},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+	models := make([]mongo.WriteModel, len(subjectIDs))
+	for i, id := range subjectIDs {
+		models[i] = mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": id}).SetUpdate(update).SetUpsert(true)
+	}
+	_, err := r.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
@@ -0,0 +1,151 @@
+package authz
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// versionTTL bounds how long another instance's bump can go unnoticed
+const versionTTL = 30 * time.Second
+
+// maxVersions triggers a sweep of expired versions
+const maxVersions = 100000
+
+// maxDependents bounds how far a bump follows dependents, in case of cycles
+const maxDependents = 1000
+
+// VersionService tracks permissions versions per user and per role. Tokens
+// carry the sum of their user's and roles' versions when issued; changing a
+// user's roles, groups or status bumps the user, and changing a role or its
+// permissions bumps the role, so only tokens depending on the change must be
+// refreshed and only their cached authorization decisions stop matching.
+type VersionService interface {
+	// Current sums the versions of the subjects, a user and their role IDs
+	Current(ctx context.Context, subjectIDs ...string) int64
+	// Bump increments the versions of the subjects and of their dependents
+	Bump(ctx context.Context, subjectIDs ...string)
+	// SetDependents installs the lookup of subjects deriving their grants from
+	// another, such as the roles extending a role
+	SetDependents(fn DependentsFunc)
+}
+
+// DependentsFunc lists the IDs of the subjects whose grants derive from subjectID
+type DependentsFunc func(ctx context.Context, subjectID string) []string
+
+type cachedVersion struct {
+	version   int64
+	fetchedAt time.Time
+}
+
+type VersionServiceImpl struct {
+	Repo VersionRepository
+
+	mu         sync.RWMutex
+	versions   map[string]cachedVersion
+	dependents DependentsFunc
+}
+
+func NewVersionService(repo VersionRepository) VersionService {
+	return &VersionServiceImpl{
+		Repo:     repo,
+		versions: map[string]cachedVersion{},
+	}
+}
+
+func (s *VersionServiceImpl) Current(ctx context.Context, subjectIDs ...string) int64 {
+	var total int64
+	var missing []primitive.ObjectID
+	stale := map[primitive.ObjectID]int64{}
+	now := time.Now()
+
+	s.mu.RLock()
+	for _, id := range subjectIDs {
+		cached, ok := s.versions[id]
+		if ok && now.Sub(cached.fetchedAt) < versionTTL {
+			total += cached.version
+			continue
+		}
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			continue
+		}
+		missing = append(missing, oid)
+		stale[oid] = cached.version
+	}
+	s.mu.RUnlock()
+	if len(missing) == 0 {
+		return total
+	}
+
+	versions, err := s.Repo.Get(ctx, missing)
+	if err != nil {
+		// Keep serving the last known versions while the database is unavailable
+		for _, v := range stale {
+			total += v
+		}
+		return total
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.versions) >= maxVersions {
+		for k, v := range s.versions {
+			if now.Sub(v.fetchedAt) >= versionTTL {
+				delete(s.versions, k)
+			}
+		}
+	}
+	for _, oid := range missing {
+		v := versions[oid]
+		s.versions[oid.Hex()] = cachedVersion{version: v, fetchedAt: now}
+		total += v
+	}
+	return total
+}
+
+func (s *VersionServiceImpl) Bump(ctx context.Context, subjectIDs ...string) {
+	s.mu.RLock()
+	dependents := s.dependents
+	s.mu.RUnlock()
+
+	seen := map[string]bool{}
+	var oids []primitive.ObjectID
+	queue := append([]string(nil), subjectIDs...)
+	for len(queue) > 0 && len(oids) < maxDependents {
+		id := queue[0]
+		queue = queue[1:]
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			continue
+		}
+		oids = append(oids, oid)
+		if dependents != nil {
+			queue = append(queue, dependents(ctx, id)...)
+		}
+	}
+	if len(oids) == 0 {
+		return
+	}
+	if err := s.Repo.Bump(ctx, oids); err != nil {
+		return
+	}
+
+	// Forget the bumped versions so this instance sees the change at once
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, oid := range oids {
+		delete(s.versions, oid.Hex())
+	}
+}
+
+func (s *VersionServiceImpl) SetDependents(fn DependentsFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dependents = fn
+}
@@ -0,0 +1,87 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryVersions struct {
+	versions map[primitive.ObjectID]int64
+	gets     int
+}
+
+func (r *memoryVersions) Get(ctx context.Context, subjectIDs []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	r.gets++
+	found := map[primitive.ObjectID]int64{}
+	for _, id := range subjectIDs {
+		if v, ok := r.versions[id]; ok {
+			found[id] = v
+		}
+	}
+	return found, nil
+}
+
+func (r *memoryVersions) Bump(ctx context.Context, subjectIDs []primitive.ObjectID) error {
+	for _, id := range subjectIDs {
+		r.versions[id]++
+	}
+	return nil
+}
+
+func TestBumpOnlyInvalidatesAffectedSubjects(t *testing.T) {
+	alice, bob := primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex()
+	base, extending, other := primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex()
+	repo := &memoryVersions{versions: map[primitive.ObjectID]int64{}}
+	svc := NewVersionService(repo)
+	svc.SetDependents(func(ctx context.Context, subjectID string) []string {
+		if subjectID == base {
+			return []string{extending}
+		}
+		// A cycle must not loop forever
+		if subjectID == extending {
+			return []string{base}
+		}
+		return nil
+	})
+	ctx := context.Background()
+
+	aliceToken := svc.Current(ctx, alice, extending)
+	bobToken := svc.Current(ctx, bob, other)
+
+	svc.Bump(ctx, bob)
+	if svc.Current(ctx, alice, extending) != aliceToken {
+		t.Error("Expected bumping one user to leave the others' version alone")
+	}
+	if svc.Current(ctx, bob, other) <= bobToken {
+		t.Error("Expected the bumped user's version to increase")
+	}
+
+	bobToken = svc.Current(ctx, bob, other)
+	svc.Bump(ctx, base)
+	if svc.Current(ctx, alice, extending) <= aliceToken {
+		t.Error("Expected bumping a base role to invalidate users of the roles extending it")
+	}
+	if svc.Current(ctx, bob, other) != bobToken {
+		t.Error("Expected bumping a role to leave users without it alone")
+	}
+}
+
+func TestCurrentCachesVersions(t *testing.T) {
+	user := primitive.NewObjectID().Hex()
+	repo := &memoryVersions{versions: map[primitive.ObjectID]int64{}}
+	svc := NewVersionService(repo)
+	ctx := context.Background()
+
+	svc.Current(ctx, user)
+	svc.Current(ctx, user)
+	if repo.gets != 1 {
+		t.Errorf("Expected one lookup while the version is cached, got %d", repo.gets)
+	}
+
+	svc.Bump(ctx, user)
+	if v := svc.Current(ctx, user); v != 1 {
+		t.Errorf("Expected a bump to be seen at once, got version %d", v)
+	}
+}
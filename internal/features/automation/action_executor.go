@@ -1,6 +1,7 @@
 package automation
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -16,6 +17,7 @@ import (
 	"time"
 
 	"github.com/d5/tengo/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // ActionExecutor provides centralized action execution for all automation features
@@ -65,7 +67,7 @@ func (e *ActionExecutorImpl) ExecuteActions(ctx context.Context, actions []RuleA
 func (e *ActionExecutorImpl) ExecuteAction(ctx context.Context, action RuleAction, moduleName string, record map[string]interface{}) error {
 	switch action.Type {
 	case ActionSendEmail:
-		return e.executeSendEmail(ctx, action.Config, record)
+		return e.executeSendEmail(ctx, action.Config, moduleName, record)
 
 	case ActionUpdateField:
 		return e.executeUpdateField(ctx, action.Config, moduleName, record)
@@ -96,7 +98,7 @@ func (e *ActionExecutorImpl) ExecuteAction(ctx context.Context, action RuleActio
 	}
 }
 
-func (e *ActionExecutorImpl) executeSendEmail(ctx context.Context, config map[string]interface{}, rec map[string]interface{}) error {
+func (e *ActionExecutorImpl) executeSendEmail(ctx context.Context, config map[string]interface{}, moduleName string, rec map[string]interface{}) error {
 	to, _ := config["to"].(string)
 	subject, _ := config["subject"].(string)
 	body, _ := config["body"].(string)
@@ -111,8 +113,8 @@ func (e *ActionExecutorImpl) executeSendEmail(ctx context.Context, config map[st
 		subject = renderedSubject
 		body = renderedBody
 	} else {
-		subject = e.replacePlaceholders(subject, rec)
-		body = e.replacePlaceholders(body, rec)
+		subject = e.emailTemplateService.RenderText(ctx, moduleName, subject, rec)
+		body = e.emailTemplateService.RenderText(ctx, moduleName, body, rec)
 	}
 
 	if to == "" {
@@ -140,7 +142,11 @@ func (e *ActionExecutorImpl) executeUpdateField(ctx context.Context, config map[
 		return fmt.Errorf("record ID not found")
 	}
 
+	// Stored records carry an ObjectID, whose %v form is not its hex
 	recordIDStr := fmt.Sprintf("%v", recordID)
+	if oid, ok := recordID.(primitive.ObjectID); ok {
+		recordIDStr = oid.Hex()
+	}
 
 	updateData := map[string]interface{}{
 		field: value,
@@ -154,7 +160,7 @@ func (e *ActionExecutorImpl) executeUpdateField(ctx context.Context, config map[
 	return nil
 }
 
-func (e *ActionExecutorImpl) executeWebhook(_ context.Context, config map[string]interface{}, moduleName string, rec map[string]interface{}) error {
+func (e *ActionExecutorImpl) executeWebhook(ctx context.Context, config map[string]interface{}, moduleName string, rec map[string]interface{}) error {
 	url, _ := config["url"].(string)
 	method, _ := config["method"].(string)
 
@@ -177,7 +183,7 @@ func (e *ActionExecutorImpl) executeWebhook(_ context.Context, config map[string
 		return fmt.Errorf("failed to marshal webhook payload: %w", err)
 	}
 
-	req, err := http.NewRequest(method, url, nil)
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(payloadBytes))
 	if err != nil {
 		return fmt.Errorf("failed to create webhook request: %w", err)
 	}
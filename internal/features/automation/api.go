@@ -26,6 +26,7 @@ func (h *AutomationApi) Setup(app *fiber.App) {
 	group.Get("/rules", h.controller.ListRules)
 	group.Get("/rules/:id", h.controller.GetRule)
 	group.Post("/rules", h.controller.CreateRule)
+	group.Post("/rules/validate", h.controller.ValidateConditions)
 	group.Put("/rules/:id", h.controller.UpdateRule)
 	group.Delete("/rules/:id", h.controller.DeleteRule)
 }
@@ -0,0 +1,416 @@
+package automation
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	OperatorGreaterOrEqual ValidationOperator = "gte"
+	OperatorLessOrEqual    ValidationOperator = "lte"
+	OperatorIn             ValidationOperator = "in"
+	OperatorIsEmpty        ValidationOperator = "is_empty"
+	OperatorIsNotEmpty     ValidationOperator = "is_not_empty"
+	// Relative date windows: Value is an amount of Unit (hours, days,
+	// weeks, months), e.g. close_date within_next 7 days
+	OperatorWithinNext ValidationOperator = "within_next"
+	OperatorWithinLast ValidationOperator = "within_last"
+	// Change predicates, evaluated against the record before an update
+	OperatorChanged     ValidationOperator = "changed"
+	OperatorChangedFrom ValidationOperator = "changed_from"
+	OperatorChangedTo   ValidationOperator = "changed_to"
+)
+
+// ConditionGroup combines conditions and nested groups. Operator is AND
+// (the default) or OR.
+type ConditionGroup struct {
+	Operator   string           `json:"operator" bson:"operator"`
+	Conditions []RuleCondition  `json:"conditions" bson:"conditions"`
+	Groups     []ConditionGroup `json:"groups,omitempty" bson:"groups,omitempty"`
+}
+
+// conditionContext is what conditions are evaluated against
+type conditionContext struct {
+	record   map[string]interface{}
+	previous map[string]interface{} // nil outside update triggers
+	now      time.Time
+}
+
+func (g *ConditionGroup) matches(c conditionContext) bool {
+	or := strings.EqualFold(g.Operator, "OR")
+	if len(g.Conditions) == 0 && len(g.Groups) == 0 {
+		return true
+	}
+	for _, cond := range g.Conditions {
+		if cond.matches(c) == or {
+			return or
+		}
+	}
+	for i := range g.Groups {
+		if g.Groups[i].matches(c) == or {
+			return or
+		}
+	}
+	return !or
+}
+
+func (cond RuleCondition) matches(c conditionContext) bool {
+	val, exists := c.record[cond.Field]
+
+	switch cond.Operator {
+	case OperatorIsEmpty:
+		return isEmpty(val)
+	case OperatorIsNotEmpty:
+		return !isEmpty(val)
+	case OperatorChanged, OperatorChangedFrom, OperatorChangedTo:
+		if c.previous == nil {
+			return false
+		}
+		old := c.previous[cond.Field]
+		if sameValue(old, val) {
+			return false
+		}
+		switch cond.Operator {
+		case OperatorChangedFrom:
+			return sameValue(old, cond.Value)
+		case OperatorChangedTo:
+			return sameValue(val, cond.Value)
+		}
+		return true
+	}
+
+	if !exists {
+		return false
+	}
+
+	switch cond.Operator {
+	case OperatorWithinNext, OperatorWithinLast:
+		t, ok := toTime(val)
+		if !ok {
+			return false
+		}
+		window, err := relativeSpan(cond.Value, cond.Unit)
+		if err != nil {
+			return false
+		}
+		if cond.Operator == OperatorWithinNext {
+			return !t.Before(c.now) && !t.After(addSpan(c.now, window))
+		}
+		return !t.After(c.now) && !t.Before(addSpan(c.now, window.neg()))
+	}
+
+	target := cond.Value
+	if cond.ValueField != "" {
+		target = c.record[cond.ValueField]
+	} else if s, ok := target.(string); ok {
+		if t, ok := resolveDateExpression(s, c.now); ok {
+			target = t
+		}
+	}
+
+	switch cond.Operator {
+	case OperatorEquals:
+		return sameValue(val, target)
+	case OperatorNotEquals:
+		return !sameValue(val, target)
+	case OperatorContains:
+		return strings.Contains(fmt.Sprintf("%v", val), fmt.Sprintf("%v", target))
+	case OperatorIn:
+		items := reflect.ValueOf(target)
+		if items.Kind() != reflect.Slice {
+			return false
+		}
+		for i := 0; i < items.Len(); i++ {
+			if sameValue(val, items.Index(i).Interface()) {
+				return true
+			}
+		}
+		return false
+	case OperatorGreaterThan, OperatorLessThan, OperatorGreaterOrEqual, OperatorLessOrEqual:
+		cmp, ok := compareValues(val, target)
+		if !ok {
+			return false
+		}
+		switch cond.Operator {
+		case OperatorGreaterThan:
+			return cmp > 0
+		case OperatorLessThan:
+			return cmp < 0
+		case OperatorGreaterOrEqual:
+			return cmp >= 0
+		default:
+			return cmp <= 0
+		}
+	}
+	return false
+}
+
+func isEmpty(val interface{}) bool {
+	if val == nil {
+		return true
+	}
+	if s, ok := val.(string); ok {
+		return strings.TrimSpace(s) == ""
+	}
+	v := reflect.ValueOf(val)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	}
+	return false
+}
+
+func sameValue(a, b interface{}) bool {
+	if cmp, ok := compareValues(a, b); ok {
+		return cmp == 0
+	}
+	if a == nil || b == nil {
+		return isEmpty(a) && isEmpty(b)
+	}
+	return fmt.Sprintf("%v", a) == fmt.Sprintf("%v", b)
+}
+
+// compareValues orders numbers and dates; other values are not ordered
+func compareValues(a, b interface{}) (int, bool) {
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	if x, ok := toTime(a); ok {
+		if y, ok := toTime(b); ok {
+			return x.Compare(y), true
+		}
+	}
+	return 0, false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func toTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case primitive.DateTime:
+		return t.Time(), true
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"} {
+			if parsed, err := time.Parse(layout, t); err == nil {
+				return parsed, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// span is a calendar-aware duration: days and months are added with
+// AddDate so they keep the wall clock across daylight saving changes
+type span struct {
+	months int
+	days   int
+	d      time.Duration
+}
+
+func (s span) neg() span { return span{months: -s.months, days: -s.days, d: -s.d} }
+
+func addSpan(t time.Time, s span) time.Time {
+	return t.AddDate(0, s.months, s.days).Add(s.d)
+}
+
+// relativeSpan reads the amount and unit of a relative date condition
+func relativeSpan(value interface{}, unit string) (span, error) {
+	amount, ok := toFloat(value)
+	if !ok || amount < 0 {
+		return span{}, fmt.Errorf("relative date amount must be a non-negative number")
+	}
+	n := int(amount)
+	switch strings.ToLower(unit) {
+	case "hour", "hours", "h":
+		return span{d: time.Duration(n) * time.Hour}, nil
+	case "", "day", "days", "d":
+		return span{days: n}, nil
+	case "week", "weeks", "w":
+		return span{days: n * 7}, nil
+	case "month", "months", "m":
+		return span{months: n}, nil
+	}
+	return span{}, fmt.Errorf("unknown relative date unit: %s", unit)
+}
+
+// resolveDateExpression evaluates "now" or "today" with an optional offset
+// such as "today+7d", "now-2h" or "today-1m" (units h, d, w, m)
+func resolveDateExpression(expr string, now time.Time) (time.Time, bool) {
+	expr = strings.ToLower(strings.ReplaceAll(expr, " ", ""))
+	var base time.Time
+	switch {
+	case strings.HasPrefix(expr, "today"):
+		y, m, d := now.Date()
+		base = time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+		expr = strings.TrimPrefix(expr, "today")
+	case strings.HasPrefix(expr, "now"):
+		base = now
+		expr = strings.TrimPrefix(expr, "now")
+	default:
+		return time.Time{}, false
+	}
+	if expr == "" {
+		return base, true
+	}
+	if len(expr) < 3 || (expr[0] != '+' && expr[0] != '-') {
+		return time.Time{}, false
+	}
+	s, err := relativeSpan(expr[1:len(expr)-1], expr[len(expr)-1:])
+	if err != nil {
+		return time.Time{}, false
+	}
+	if expr[0] == '-' {
+		s = s.neg()
+	}
+	return addSpan(base, s), true
+}
+
+// ConditionProblem is one issue found when checking conditions against a
+// module schema. Path locates the condition, e.g. "condition_group.groups[0].conditions[1]".
+type ConditionProblem struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ConditionError rejects a rule whose conditions do not fit its module
+type ConditionError struct {
+	Problems []ConditionProblem
+}
+
+func (e *ConditionError) Error() string {
+	if len(e.Problems) == 0 {
+		return "invalid conditions"
+	}
+	return fmt.Sprintf("invalid conditions: %s: %s", e.Problems[0].Path, e.Problems[0].Message)
+}
+
+var (
+	orderedTypes = map[common_models.FieldType]bool{
+		common_models.FieldTypeNumber:   true,
+		common_models.FieldTypeCurrency: true,
+		common_models.FieldTypeDate:     true,
+	}
+	systemDateFields = map[string]bool{"created_at": true, "updated_at": true}
+)
+
+// validateConditions checks fields, operators and values against the module
+// fields. Change predicates are only meaningful on update triggers.
+func validateConditions(rule *AutomationRule, fields []common_models.ModuleField) []ConditionProblem {
+	types := make(map[string]common_models.FieldType, len(fields))
+	for _, f := range fields {
+		types[f.Name] = f.Type
+	}
+	for name := range systemDateFields {
+		types[name] = common_models.FieldTypeDate
+	}
+
+	var problems []ConditionProblem
+	check := func(path string, cond RuleCondition) {
+		add := func(format string, args ...interface{}) {
+			problems = append(problems, ConditionProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+		}
+		fieldType, ok := types[cond.Field]
+		if !ok {
+			add("unknown field %q", cond.Field)
+			return
+		}
+		if cond.ValueField != "" {
+			other, ok := types[cond.ValueField]
+			if !ok {
+				add("unknown value_field %q", cond.ValueField)
+			} else if orderedTypes[fieldType] != orderedTypes[other] {
+				add("cannot compare %s field %q with %s field %q", fieldType, cond.Field, other, cond.ValueField)
+			}
+		}
+
+		switch cond.Operator {
+		case OperatorEquals, OperatorNotEquals, OperatorContains, OperatorIsEmpty, OperatorIsNotEmpty:
+		case OperatorIn:
+			if cond.ValueField == "" && reflect.ValueOf(cond.Value).Kind() != reflect.Slice {
+				add("in requires a list value")
+			}
+		case OperatorGreaterThan, OperatorLessThan, OperatorGreaterOrEqual, OperatorLessOrEqual:
+			if !orderedTypes[fieldType] {
+				add("%s requires a number, currency or date field", cond.Operator)
+			} else if fieldType == common_models.FieldTypeDate && cond.ValueField == "" {
+				if s, ok := cond.Value.(string); !ok || !validDateValue(s) {
+					add("date comparisons need a date, or an expression like today+7d")
+				}
+			}
+		case OperatorWithinNext, OperatorWithinLast:
+			if fieldType != common_models.FieldTypeDate {
+				add("%s requires a date field", cond.Operator)
+			} else if _, err := relativeSpan(cond.Value, cond.Unit); err != nil {
+				add("%v", err)
+			}
+		case OperatorChanged, OperatorChangedFrom, OperatorChangedTo:
+			if rule.TriggerType != "update" {
+				add("%s only applies to update triggers", cond.Operator)
+			}
+		default:
+			add("unknown operator %q", cond.Operator)
+		}
+	}
+
+	for i, cond := range rule.Conditions {
+		check(fmt.Sprintf("conditions[%d]", i), cond)
+	}
+	var walk func(path string, g *ConditionGroup)
+	walk = func(path string, g *ConditionGroup) {
+		if op := strings.ToUpper(g.Operator); op != "" && op != "AND" && op != "OR" {
+			problems = append(problems, ConditionProblem{Path: path, Message: "operator must be AND or OR"})
+		}
+		for i, cond := range g.Conditions {
+			check(fmt.Sprintf("%s.conditions[%d]", path, i), cond)
+		}
+		for i := range g.Groups {
+			walk(fmt.Sprintf("%s.groups[%d]", path, i), &g.Groups[i])
+		}
+	}
+	if rule.ConditionGroup != nil {
+		walk("condition_group", rule.ConditionGroup)
+	}
+	return problems
+}
+
+func validDateValue(s string) bool {
+	if _, ok := resolveDateExpression(s, time.Now()); ok {
+		return true
+	}
+	_, ok := toTime(s)
+	return ok
+}
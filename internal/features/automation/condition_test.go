@@ -0,0 +1,324 @@
+package automation
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// probe counts how often a condition reads it, to observe short-circuiting
+type probe struct{ reads *int }
+
+func (p probe) String() string {
+	*p.reads++
+	return "probe"
+}
+
+func TestResolveDateExpression(t *testing.T) {
+	now := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+	today := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want time.Time
+		ok   bool
+	}{
+		{expr: "now", want: now, ok: true},
+		{expr: "today", want: today, ok: true},
+		{expr: "today+7d", want: today.AddDate(0, 0, 7), ok: true},
+		{expr: "Today + 7 D", want: today.AddDate(0, 0, 7), ok: true},
+		{expr: "today+12d", want: today.AddDate(0, 0, 12), ok: true},
+		{expr: "today-1d", want: today.AddDate(0, 0, -1), ok: true},
+		{expr: "today+2w", want: today.AddDate(0, 0, 14), ok: true},
+		{expr: "today-1m", want: time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC), ok: true},
+		{expr: "now-2h", want: now.Add(-2 * time.Hour), ok: true},
+		{expr: "now+0d", want: now, ok: true},
+		{expr: "today+1.9d", want: today.AddDate(0, 0, 1), ok: true}, // Fractions are truncated
+		{expr: ""},
+		{expr: "tomorrow"},
+		{expr: "nowish"},
+		{expr: "2024-03-15"},
+		{expr: "today+"},
+		{expr: "today+7"},
+		{expr: "today+d"},
+		{expr: "today7d"},
+		{expr: "today*7d"},
+		{expr: "today+7y"},
+		{expr: "today+xd"},
+		{expr: "today+-7d"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, ok := resolveDateExpression(tt.expr, now)
+			if ok != tt.ok {
+				t.Fatalf("resolveDateExpression(%q) ok = %v, want %v", tt.expr, ok, tt.ok)
+			}
+			if ok && !got.Equal(tt.want) {
+				t.Errorf("resolveDateExpression(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveDateExpressionTimezones(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sydney := time.FixedZone("AEST", 10*60*60)
+	bogota := time.FixedZone("COT", -5*60*60)
+
+	tests := []struct {
+		name string
+		expr string
+		now  time.Time
+		want time.Time
+	}{
+		{
+			name: "today is the local date ahead of UTC",
+			expr: "today",
+			now:  time.Date(2024, 3, 15, 20, 0, 0, 0, time.UTC).In(sydney),
+			want: time.Date(2024, 3, 16, 0, 0, 0, 0, sydney),
+		},
+		{
+			name: "today is the local date behind UTC",
+			expr: "today",
+			now:  time.Date(2024, 3, 16, 3, 0, 0, 0, time.UTC).In(bogota),
+			want: time.Date(2024, 3, 15, 0, 0, 0, 0, bogota),
+		},
+		{
+			name: "one second before midnight",
+			expr: "today+1d",
+			now:  time.Date(2024, 3, 15, 23, 59, 59, 0, bogota),
+			want: time.Date(2024, 3, 16, 0, 0, 0, 0, bogota),
+		},
+		{
+			name: "exactly midnight",
+			expr: "today",
+			now:  time.Date(2024, 3, 16, 0, 0, 0, 0, bogota),
+			want: time.Date(2024, 3, 16, 0, 0, 0, 0, bogota),
+		},
+		{
+			name: "days keep midnight across the spring change",
+			expr: "today+2d",
+			now:  time.Date(2024, 3, 9, 12, 0, 0, 0, newYork),
+			want: time.Date(2024, 3, 11, 0, 0, 0, 0, newYork),
+		},
+		{
+			name: "days keep the wall clock across the autumn change",
+			expr: "now+1d",
+			now:  time.Date(2024, 11, 2, 12, 0, 0, 0, newYork),
+			want: time.Date(2024, 11, 3, 12, 0, 0, 0, newYork),
+		},
+		{
+			name: "hours are elapsed time across the spring change",
+			expr: "now+24h",
+			now:  time.Date(2024, 3, 9, 12, 0, 0, 0, newYork),
+			want: time.Date(2024, 3, 10, 13, 0, 0, 0, newYork),
+		},
+		{
+			name: "months past a short month overflow",
+			expr: "today+1m",
+			now:  time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := resolveDateExpression(tt.expr, tt.now)
+			if !ok {
+				t.Fatalf("resolveDateExpression(%q) failed", tt.expr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("resolveDateExpression(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDateConditions(t *testing.T) {
+	now := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+	week := 7 * 24 * time.Hour
+
+	tests := []struct {
+		name  string
+		cond  RuleCondition
+		value interface{}
+		want  bool
+	}{
+		{name: "equals today", cond: RuleCondition{Operator: OperatorEquals, Value: "today"}, value: time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), want: true},
+		{name: "gte today at midnight", cond: RuleCondition{Operator: OperatorGreaterOrEqual, Value: "today"}, value: "2024-03-15", want: true},
+		{name: "lt today a second before midnight", cond: RuleCondition{Operator: OperatorLessThan, Value: "today"}, value: "2024-03-14T23:59:59Z", want: true},
+		{name: "lte today later that day", cond: RuleCondition{Operator: OperatorLessOrEqual, Value: "today"}, value: "2024-03-15T00:00:01Z", want: false},
+		{name: "lt today+7d", cond: RuleCondition{Operator: OperatorLessThan, Value: "today+7d"}, value: "2024-03-21", want: true},
+		{name: "lt today+7d on the boundary", cond: RuleCondition{Operator: OperatorLessThan, Value: "today+7d"}, value: "2024-03-22", want: false},
+		{name: "gt now-2h", cond: RuleCondition{Operator: OperatorGreaterThan, Value: "now-2h"}, value: now.Add(-time.Hour), want: true},
+
+		{name: "within_next now", cond: RuleCondition{Operator: OperatorWithinNext, Value: 7, Unit: "days"}, value: now, want: true},
+		{name: "within_next end of the window", cond: RuleCondition{Operator: OperatorWithinNext, Value: 7, Unit: "days"}, value: now.Add(week), want: true},
+		{name: "within_next past the window", cond: RuleCondition{Operator: OperatorWithinNext, Value: 7, Unit: "days"}, value: now.Add(week + time.Second), want: false},
+		{name: "within_next in the past", cond: RuleCondition{Operator: OperatorWithinNext, Value: 7, Unit: "days"}, value: now.Add(-time.Second), want: false},
+		{name: "within_next default unit is days", cond: RuleCondition{Operator: OperatorWithinNext, Value: 1}, value: now.Add(23 * time.Hour), want: true},
+		{name: "within_next hours", cond: RuleCondition{Operator: OperatorWithinNext, Value: 24, Unit: "hours"}, value: "2024-03-16T10:30:00Z", want: true},
+		{name: "within_next weeks", cond: RuleCondition{Operator: OperatorWithinNext, Value: 2, Unit: "w"}, value: "2024-03-29", want: true},
+		{name: "within_next months", cond: RuleCondition{Operator: OperatorWithinNext, Value: 1, Unit: "month"}, value: time.Date(2024, 4, 15, 10, 30, 0, 0, time.UTC), want: true},
+		{name: "within_next amount as text", cond: RuleCondition{Operator: OperatorWithinNext, Value: "7", Unit: "days"}, value: now.Add(week), want: true},
+		{name: "within_next stored datetime", cond: RuleCondition{Operator: OperatorWithinNext, Value: 7, Unit: "days"}, value: primitive.NewDateTimeFromTime(now.Add(time.Hour)), want: true},
+		{name: "within_last start of the window", cond: RuleCondition{Operator: OperatorWithinLast, Value: 7, Unit: "days"}, value: now.Add(-week), want: true},
+		{name: "within_last before the window", cond: RuleCondition{Operator: OperatorWithinLast, Value: 7, Unit: "days"}, value: now.Add(-week - time.Second), want: false},
+		{name: "within_last in the future", cond: RuleCondition{Operator: OperatorWithinLast, Value: 7, Unit: "days"}, value: now.Add(time.Second), want: false},
+		{name: "within_last months", cond: RuleCondition{Operator: OperatorWithinLast, Value: 1, Unit: "m"}, value: "2024-02-15T10:30:00Z", want: true},
+		{name: "within_next not a date", cond: RuleCondition{Operator: OperatorWithinNext, Value: 7, Unit: "days"}, value: "soon", want: false},
+		{name: "within_next missing value", cond: RuleCondition{Operator: OperatorWithinNext, Value: 7, Unit: "days"}, value: nil, want: false},
+		{name: "within_next negative amount", cond: RuleCondition{Operator: OperatorWithinNext, Value: -7, Unit: "days"}, value: now, want: false},
+		{name: "within_next unknown unit", cond: RuleCondition{Operator: OperatorWithinNext, Value: 1, Unit: "years"}, value: now, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := map[string]interface{}{}
+			if tt.value != nil {
+				record["close_date"] = tt.value
+			}
+			tt.cond.Field = "close_date"
+			if got := tt.cond.matches(conditionContext{record: record, now: now}); got != tt.want {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangeConditions(t *testing.T) {
+	tests := []struct {
+		name     string
+		cond     RuleCondition
+		record   map[string]interface{}
+		previous map[string]interface{}
+		want     bool
+	}{
+		{name: "not an update", cond: RuleCondition{Operator: OperatorChanged}, record: map[string]interface{}{"stage": "Won"}, want: false},
+		{name: "set where there was none", cond: RuleCondition{Operator: OperatorChanged}, record: map[string]interface{}{"stage": "Won"}, previous: map[string]interface{}{}, want: true},
+		{name: "absent before and after", cond: RuleCondition{Operator: OperatorChanged}, record: map[string]interface{}{}, previous: map[string]interface{}{}, want: false},
+		{name: "absent to empty", cond: RuleCondition{Operator: OperatorChanged}, record: map[string]interface{}{"stage": ""}, previous: map[string]interface{}{}, want: false},
+		{name: "cleared", cond: RuleCondition{Operator: OperatorChanged}, record: map[string]interface{}{}, previous: map[string]interface{}{"stage": "Open"}, want: true},
+		{name: "same number in another type", cond: RuleCondition{Field: "amount", Operator: OperatorChanged}, record: map[string]interface{}{"amount": "100"}, previous: map[string]interface{}{"amount": 100}, want: false},
+		{name: "changed_from absent", cond: RuleCondition{Operator: OperatorChangedFrom, Value: nil}, record: map[string]interface{}{"stage": "Won"}, previous: map[string]interface{}{}, want: true},
+		{name: "changed_from empty matches absent", cond: RuleCondition{Operator: OperatorChangedFrom, Value: ""}, record: map[string]interface{}{"stage": "Won"}, previous: map[string]interface{}{}, want: true},
+		{name: "changed_from a value that was absent", cond: RuleCondition{Operator: OperatorChangedFrom, Value: "Open"}, record: map[string]interface{}{"stage": "Won"}, previous: map[string]interface{}{}, want: false},
+		{name: "changed_to from absent", cond: RuleCondition{Operator: OperatorChangedTo, Value: "Won"}, record: map[string]interface{}{"stage": "Won"}, previous: map[string]interface{}{}, want: true},
+		{name: "changed_to absent", cond: RuleCondition{Operator: OperatorChangedTo, Value: nil}, record: map[string]interface{}{}, previous: map[string]interface{}{"stage": "Open"}, want: true},
+		{name: "changed_from", cond: RuleCondition{Operator: OperatorChangedFrom, Value: "Open"}, record: map[string]interface{}{"stage": "Won"}, previous: map[string]interface{}{"stage": "Open"}, want: true},
+		{name: "changed_to another value", cond: RuleCondition{Operator: OperatorChangedTo, Value: "Lost"}, record: map[string]interface{}{"stage": "Won"}, previous: map[string]interface{}{"stage": "Open"}, want: false},
+		{name: "changed_to unchanged", cond: RuleCondition{Operator: OperatorChangedTo, Value: "Won"}, record: map[string]interface{}{"stage": "Won"}, previous: map[string]interface{}{"stage": "Won"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.cond.Field == "" {
+				tt.cond.Field = "stage"
+			}
+			c := conditionContext{record: tt.record, previous: tt.previous, now: time.Now()}
+			if got := tt.cond.matches(c); got != tt.want {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConditionGroupMatches(t *testing.T) {
+	won := RuleCondition{Field: "stage", Operator: OperatorEquals, Value: "Won"}
+	lost := RuleCondition{Field: "stage", Operator: OperatorEquals, Value: "Lost"}
+	large := RuleCondition{Field: "amount", Operator: OperatorGreaterThan, Value: 500}
+	medium := RuleCondition{Field: "amount", Operator: OperatorGreaterThan, Value: 50}
+	probed := RuleCondition{Field: "probe", Operator: OperatorEquals, Value: "probe"}
+
+	tests := []struct {
+		name      string
+		group     ConditionGroup
+		want      bool
+		wantReads int
+	}{
+		{name: "empty", group: ConditionGroup{}, want: true},
+		{name: "and", group: ConditionGroup{Conditions: []RuleCondition{won, medium}}, want: true},
+		{name: "and with a false condition", group: ConditionGroup{Operator: "AND", Conditions: []RuleCondition{won, large}}, want: false},
+		{name: "or", group: ConditionGroup{Operator: "OR", Conditions: []RuleCondition{lost, won}}, want: true},
+		{name: "or without a true condition", group: ConditionGroup{Operator: "OR", Conditions: []RuleCondition{lost, large}}, want: false},
+		{name: "operator ignores case", group: ConditionGroup{Operator: "or", Conditions: []RuleCondition{lost, won}}, want: true},
+		{name: "and stops at the first false condition", group: ConditionGroup{Conditions: []RuleCondition{lost, probed}}, want: false},
+		{name: "or stops at the first true condition", group: ConditionGroup{Operator: "OR", Conditions: []RuleCondition{won, probed}}, want: true},
+		{
+			name: "and skips groups after a false condition",
+			group: ConditionGroup{
+				Conditions: []RuleCondition{lost},
+				Groups:     []ConditionGroup{{Conditions: []RuleCondition{probed}}},
+			},
+			want: false,
+		},
+		{
+			name: "or skips groups after a true condition",
+			group: ConditionGroup{
+				Operator:   "OR",
+				Conditions: []RuleCondition{won},
+				Groups:     []ConditionGroup{{Conditions: []RuleCondition{probed}}},
+			},
+			want: true,
+		},
+		{
+			name: "or skips later groups after a true group",
+			group: ConditionGroup{
+				Operator: "OR",
+				Groups: []ConditionGroup{
+					{Conditions: []RuleCondition{won}},
+					{Conditions: []RuleCondition{probed}},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "or falls through to a nested group",
+			group: ConditionGroup{
+				Operator:   "OR",
+				Conditions: []RuleCondition{lost},
+				Groups:     []ConditionGroup{{Conditions: []RuleCondition{won, medium}}},
+			},
+			want: true,
+		},
+		{
+			name: "and with a false nested group",
+			group: ConditionGroup{
+				Conditions: []RuleCondition{won},
+				Groups:     []ConditionGroup{{Operator: "OR", Conditions: []RuleCondition{lost, large}}},
+			},
+			want: false,
+		},
+		{
+			name: "nested condition read only when needed",
+			group: ConditionGroup{
+				Conditions: []RuleCondition{won},
+				Groups: []ConditionGroup{{
+					Operator:   "OR",
+					Conditions: []RuleCondition{large},
+					Groups:     []ConditionGroup{{Conditions: []RuleCondition{medium, probed}}},
+				}},
+			},
+			want:      true,
+			wantReads: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reads := 0
+			record := map[string]interface{}{"stage": "Won", "amount": 100, "probe": probe{reads: &reads}}
+			if got := tt.group.matches(conditionContext{record: record, now: time.Now()}); got != tt.want {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+			if reads != tt.wantReads {
+				t.Errorf("probe read %d times, want %d", reads, tt.wantReads)
+			}
+		})
+	}
+}
@@ -1,17 +1,44 @@
 package automation
 
 import (
+	"errors"
+
+	common_api "go-crm/internal/common/api"
+	"go-crm/internal/common/session"
+	"go-crm/internal/features/role"
+	"go-crm/internal/middleware"
+
 	"github.com/gofiber/fiber/v2"
 )
 
 type AutomationController struct {
 	Service AutomationService
+	Scopes  middleware.AdminScopeChecker
 }
 
-func NewAutomationController(service AutomationService) *AutomationController {
+func NewAutomationController(service AutomationService, roleService role.RoleService) *AutomationController {
 	return &AutomationController{
 		Service: service,
+		Scopes:  roleService,
+	}
+}
+
+// canManage checks the automations admin scope for the rule's module
+func (ctrl *AutomationController) canManage(c *fiber.Ctx, moduleName string) bool {
+	return middleware.HasAdminScope(c, ctrl.Scopes, role.AdminScopeAutomations, moduleName)
+}
+
+func scopeDenied(moduleName string) fiber.Map {
+	return fiber.Map{"error": "Access denied: automations admin scope required for " + moduleName}
+}
+
+// saveError answers 400 with the problems for invalid conditions
+func saveError(c *fiber.Ctx, err error) error {
+	var invalid *ConditionError
+	if errors.As(err, &invalid) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error(), "problems": invalid.Problems})
 	}
+	return common_api.Error(c, err)
 }
 
 // CreateRule godoc
@@ -23,6 +50,7 @@ func NewAutomationController(service AutomationService) *AutomationController {
 // @Param rule body AutomationRule true "Automation Rule"
 // @Success 201 {object} AutomationRule
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/automation/rules [post]
 func (ctrl *AutomationController) CreateRule(c *fiber.Ctx) error {
@@ -30,9 +58,13 @@ func (ctrl *AutomationController) CreateRule(c *fiber.Ctx) error {
 	if err := c.BodyParser(&rule); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
 	}
+	if !ctrl.canManage(c, rule.ModuleID) {
+		return c.Status(fiber.StatusForbidden).JSON(scopeDenied(rule.ModuleID))
+	}
+	rule.CreatedBy, _ = session.UserID(c.UserContext())
 
 	if err := ctrl.Service.CreateRule(c.UserContext(), &rule); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return saveError(c, err)
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(rule)
@@ -84,6 +116,7 @@ func (ctrl *AutomationController) ListRules(c *fiber.Ctx) error {
 // @Param rule body AutomationRule true "Automation Rule"
 // @Success 200 {object} AutomationRule
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/automation/rules/{id} [put]
 func (ctrl *AutomationController) UpdateRule(c *fiber.Ctx) error {
@@ -92,10 +125,21 @@ func (ctrl *AutomationController) UpdateRule(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
 	}
 
+	// A module admin can neither edit another module's rule nor move a rule out of their module
+	existing, err := ctrl.Service.GetRule(c.UserContext(), rule.ID.Hex())
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Rule not found"})
+	}
+	for _, m := range []string{existing.ModuleID, rule.ModuleID} {
+		if !ctrl.canManage(c, m) {
+			return c.Status(fiber.StatusForbidden).JSON(scopeDenied(m))
+		}
+	}
+
 	// Ensure ID is set from path
 	// (Assuming ID is string or ObjectID)
 	if err := ctrl.Service.UpdateRule(c.UserContext(), &rule); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return saveError(c, err)
 	}
 
 	return c.JSON(rule)
@@ -107,12 +151,45 @@ func (ctrl *AutomationController) UpdateRule(c *fiber.Ctx) error {
 // @Tags automation
 // @Param id path string true "Rule ID"
 // @Success 204 {object} nil
+// @Failure 403 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/automation/rules/{id} [delete]
 func (ctrl *AutomationController) DeleteRule(c *fiber.Ctx) error {
 	id := c.Params("id")
+	existing, err := ctrl.Service.GetRule(c.UserContext(), id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Rule not found"})
+	}
+	if !ctrl.canManage(c, existing.ModuleID) {
+		return c.Status(fiber.StatusForbidden).JSON(scopeDenied(existing.ModuleID))
+	}
 	if err := ctrl.Service.DeleteRule(c.UserContext(), id); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 	return c.SendStatus(fiber.StatusNoContent)
 }
+
+// ValidateConditions godoc
+// @Summary Validate automation conditions
+// @Description Check a rule's conditions and condition group against the module schema without saving it
+// @Tags automation
+// @Accept json
+// @Produce json
+// @Param rule body AutomationRule true "Rule with module_id, trigger_type and conditions"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/automation/rules/validate [post]
+func (ctrl *AutomationController) ValidateConditions(c *fiber.Ctx) error {
+	var rule AutomationRule
+	if err := c.BodyParser(&rule); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	problems, err := ctrl.Service.ValidateConditions(c.UserContext(), &rule)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if problems == nil {
+		problems = []ConditionProblem{}
+	}
+	return c.JSON(fiber.Map{"valid": len(problems) == 0, "problems": problems})
+}
@@ -0,0 +1,133 @@
+package automation
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// maxExecutionDepth stops chains where a rule's actions trigger rules
+	// that trigger rules again
+	maxExecutionDepth = 5
+	// defaultCooldown keeps a rule from firing twice for one record in quick
+	// succession, which is how loops through external systems show up
+	defaultCooldown = 10 * time.Second
+	// breakerThreshold failures or loop detections within breakerWindow
+	// disable the rule
+	breakerThreshold = 5
+	breakerWindow    = 10 * time.Minute
+	// maxGuardEntries triggers a sweep of stale cooldown entries
+	maxGuardEntries = 10000
+)
+
+// Reasons a run is skipped
+var (
+	errDepthExceeded = fmt.Errorf("automation chain deeper than %d runs", maxExecutionDepth)
+	errCoolingDown   = fmt.Errorf("rule fired for this record moments ago")
+	errRateLimited   = fmt.Errorf("tenant automation rate limit reached")
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// executionGuard holds the in-process state behind loop protection: record
+// cooldowns, per-tenant token buckets and per-rule failure counts
+type executionGuard struct {
+	mu        sync.Mutex
+	perMinute int
+	cooldowns map[string]time.Time   // tenant|rule|record -> last run
+	buckets   map[string]*bucket     // tenant -> tokens
+	failures  map[string][]time.Time // rule -> recent failures and loops
+}
+
+func newExecutionGuard(perMinute int) *executionGuard {
+	return &executionGuard{
+		perMinute: perMinute,
+		cooldowns: map[string]time.Time{},
+		buckets:   map[string]*bucket{},
+		failures:  map[string][]time.Time{},
+	}
+}
+
+// admit decides whether the rule may run for the record now and, if so,
+// records the run for cooldown and rate accounting
+func (g *executionGuard) admit(rule *AutomationRule, recordID string, depth int, now time.Time) error {
+	if depth >= maxExecutionDepth {
+		return errDepthExceeded
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	tenant := rule.TenantID.Hex()
+	key := tenant + "|" + rule.ID.Hex() + "|" + recordID
+	if recordID != "" {
+		cooldown := defaultCooldown
+		if rule.CooldownSeconds > 0 {
+			cooldown = time.Duration(rule.CooldownSeconds) * time.Second
+		}
+		if last, ok := g.cooldowns[key]; ok && now.Sub(last) < cooldown {
+			return errCoolingDown
+		}
+	}
+
+	if g.perMinute > 0 {
+		b, ok := g.buckets[tenant]
+		if !ok {
+			b = &bucket{tokens: float64(g.perMinute), last: now}
+			g.buckets[tenant] = b
+		}
+		b.tokens += now.Sub(b.last).Minutes() * float64(g.perMinute)
+		if b.tokens > float64(g.perMinute) {
+			b.tokens = float64(g.perMinute)
+		}
+		b.last = now
+		if b.tokens < 1 {
+			return errRateLimited
+		}
+		b.tokens--
+	}
+
+	if recordID != "" {
+		if len(g.cooldowns) >= maxGuardEntries {
+			for k, last := range g.cooldowns {
+				if now.Sub(last) > time.Hour {
+					delete(g.cooldowns, k)
+				}
+			}
+		}
+		g.cooldowns[key] = now
+	}
+	return nil
+}
+
+// fail counts a failed or looping run and reports whether the rule has
+// reached the breaker threshold
+func (g *executionGuard) fail(ruleID string, now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	recent := g.failures[ruleID][:0]
+	for _, at := range g.failures[ruleID] {
+		if now.Sub(at) < breakerWindow {
+			recent = append(recent, at)
+		}
+	}
+	recent = append(recent, now)
+	if len(recent) >= breakerThreshold {
+		delete(g.failures, ruleID)
+		return true
+	}
+	g.failures[ruleID] = recent
+	return false
+}
+
+// succeed clears the failure count; the breaker only trips on failures
+// that keep coming
+func (g *executionGuard) succeed(ruleID string) {
+	g.mu.Lock()
+	delete(g.failures, ruleID)
+	g.mu.Unlock()
+}
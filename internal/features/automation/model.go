@@ -35,6 +35,11 @@ type RuleCondition struct {
 	Field    string             `json:"field" bson:"field"`
 	Operator ValidationOperator `json:"operator" bson:"operator"`
 	Value    interface{}        `json:"value" bson:"value"`
+
+	// ValueField compares against another field of the record instead of Value
+	ValueField string `json:"value_field,omitempty" bson:"value_field,omitempty"`
+	// Unit of Value for within_next/within_last: hours, days, weeks, months
+	Unit string `json:"unit,omitempty" bson:"unit,omitempty"`
 }
 
 type RuleAction struct {
@@ -53,4 +58,21 @@ type AutomationRule struct {
 	Actions     []RuleAction       `json:"actions" bson:"actions"`
 	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+
+	// ConditionGroup holds AND/OR condition trees. It must match as well as
+	// every entry in Conditions.
+	ConditionGroup *ConditionGroup `json:"condition_group,omitempty" bson:"condition_group,omitempty"`
+
+	// CooldownSeconds is how long the rule waits before firing again for the
+	// same record; 0 uses the default
+	CooldownSeconds int                `json:"cooldown_seconds,omitempty" bson:"cooldown_seconds,omitempty"`
+	CreatedBy       primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"` // Alerted when the circuit opens
+	// Circuit is set when the rule was disabled for failing or looping
+	Circuit *RuleCircuit `json:"circuit,omitempty" bson:"circuit,omitempty"`
+}
+
+// RuleCircuit records why a rule was switched off automatically
+type RuleCircuit struct {
+	OpenedAt time.Time `json:"opened_at" bson:"opened_at"`
+	Reason   string    `json:"reason" bson:"reason"`
 }
@@ -18,6 +18,8 @@ type AutomationRepository interface {
 	Update(ctx context.Context, rule *AutomationRule) error
 	Delete(ctx context.Context, id string) error
 	Enable(ctx context.Context, id string, active bool) error
+	// SetCircuit disables the rule with the circuit, or clears it when nil
+	SetCircuit(ctx context.Context, id primitive.ObjectID, circuit *RuleCircuit) error
 }
 
 type AutomationRepositoryImpl struct {
@@ -103,3 +105,12 @@ func (r *AutomationRepositoryImpl) Enable(ctx context.Context, id string, active
 	_, err = r.Collection.UpdateOne(ctx, bson.M{"_id": oid}, bson.M{"$set": bson.M{"active": active, "updated_at": time.Now()}})
 	return err
 }
+
+func (r *AutomationRepositoryImpl) SetCircuit(ctx context.Context, id primitive.ObjectID, circuit *RuleCircuit) error {
+	update := bson.M{"$unset": bson.M{"circuit": ""}, "$set": bson.M{"updated_at": time.Now()}}
+	if circuit != nil {
+		update = bson.M{"$set": bson.M{"active": false, "circuit": circuit, "updated_at": time.Now()}}
+	}
+	_, err := r.Collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
@@ -2,10 +2,19 @@ package automation
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	common_models "go-crm/internal/common/models"
+	"go-crm/internal/config"
 	"go-crm/internal/features/audit"
-	"strings"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/notification"
+	"go-crm/internal/features/quota"
+	"go-crm/internal/features/record"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type AutomationService interface {
@@ -17,23 +26,81 @@ type AutomationService interface {
 
 	// Core Logic
 	ExecuteFromTrigger(ctx context.Context, moduleName string, record map[string]interface{}, triggerType string) error
+	// ExecuteRule runs a single rule on demand, e.g. from a custom record action.
+	// Unlike triggered runs, action failures are returned to the caller.
+	ExecuteRule(ctx context.Context, ruleID string, moduleName string, record map[string]interface{}) error
+	// ValidateConditions checks a rule's conditions against its module schema
+	ValidateConditions(ctx context.Context, rule *AutomationRule) ([]ConditionProblem, error)
 }
 
 type AutomationServiceImpl struct {
-	Repo           AutomationRepository
-	ActionExecutor ActionExecutor
-	AuditService   audit.AuditService
+	Repo                AutomationRepository
+	ActionExecutor      ActionExecutor
+	AuditService        audit.AuditService
+	ModuleRepo          module.ModuleRepository
+	NotificationService notification.NotificationService
+	Quotas              quota.QuotaService
+
+	guard *executionGuard
 }
 
-func NewAutomationService(repo AutomationRepository, actionExecutor ActionExecutor, auditService audit.AuditService) AutomationService {
+func NewAutomationService(
+	repo AutomationRepository,
+	actionExecutor ActionExecutor,
+	auditService audit.AuditService,
+	moduleRepo module.ModuleRepository,
+	notificationService notification.NotificationService,
+	quotas quota.QuotaService,
+	cfg *config.Config,
+) AutomationService {
 	return &AutomationServiceImpl{
-		Repo:           repo,
-		ActionExecutor: actionExecutor,
-		AuditService:   auditService,
+		Repo:                repo,
+		ActionExecutor:      actionExecutor,
+		AuditService:        auditService,
+		ModuleRepo:          moduleRepo,
+		NotificationService: notificationService,
+		Quotas:              quotas,
+		guard:               newExecutionGuard(cfg.AutomationRatePerMinute),
+	}
+}
+
+func (s *AutomationServiceImpl) ValidateConditions(ctx context.Context, rule *AutomationRule) ([]ConditionProblem, error) {
+	m, err := s.ModuleRepo.FindByName(ctx, rule.ModuleID)
+	if err != nil || m == nil {
+		return nil, fmt.Errorf("module '%s' not found", rule.ModuleID)
 	}
+	return validateConditions(rule, m.Fields), nil
+}
+
+// checkConditions rejects rules whose conditions do not fit the module.
+// Rules on modules without a stored schema are not checked.
+func (s *AutomationServiceImpl) checkConditions(ctx context.Context, rule *AutomationRule) error {
+	if s.ModuleRepo == nil {
+		return nil
+	}
+	m, err := s.ModuleRepo.FindByName(ctx, rule.ModuleID)
+	if err != nil || m == nil {
+		return nil
+	}
+	if problems := validateConditions(rule, m.Fields); len(problems) > 0 {
+		return &ConditionError{Problems: problems}
+	}
+	return nil
 }
 
 func (s *AutomationServiceImpl) CreateRule(ctx context.Context, rule *AutomationRule) error {
+	if err := s.checkConditions(ctx, rule); err != nil {
+		return err
+	}
+	if s.Quotas != nil {
+		if err := s.Quotas.CheckAutomation(ctx); err != nil {
+			return err
+		}
+	}
+	// Rules count toward the quota of the tenant creating them
+	if tenantID, ok := ctx.Value(common_models.TenantIDKey).(string); ok && rule.TenantID.IsZero() {
+		rule.TenantID, _ = primitive.ObjectIDFromHex(tenantID)
+	}
 	err := s.Repo.Create(ctx, rule)
 	if err == nil {
 		s.AuditService.LogChange(ctx, common_models.AuditActionAutomation, "automation", rule.ID.Hex(), map[string]common_models.Change{
@@ -58,8 +125,25 @@ func (s *AutomationServiceImpl) UpdateRule(ctx context.Context, rule *Automation
 	// Get old rule for audit
 	oldRule, _ := s.GetRule(ctx, rule.ID.Hex())
 
+	if err := s.checkConditions(ctx, rule); err != nil {
+		return err
+	}
+	if oldRule != nil {
+		rule.CreatedBy = oldRule.CreatedBy
+		rule.TenantID = oldRule.TenantID
+	}
+	// Switching a tripped rule back on closes its circuit
+	if rule.Active {
+		rule.Circuit = nil
+	} else if oldRule != nil {
+		rule.Circuit = oldRule.Circuit
+	}
 	err := s.Repo.Update(ctx, rule)
 	if err == nil {
+		if rule.Active && oldRule != nil && oldRule.Circuit != nil {
+			_ = s.Repo.SetCircuit(ctx, rule.ID, nil)
+			s.guard.succeed(rule.ID.Hex())
+		}
 		s.AuditService.LogChange(ctx, common_models.AuditActionAutomation, "automation", rule.ID.Hex(), map[string]common_models.Change{
 			"rule": {Old: oldRule, New: rule},
 		})
@@ -84,6 +168,10 @@ func (s *AutomationServiceImpl) DeleteRule(ctx context.Context, id string) error
 	return err
 }
 
+// ExecuteFromTrigger runs the matching rules for a record event. Runs are
+// skipped past the maximum chain depth, within a rule's cooldown for the
+// record, or over the tenant's rate limit; rules that keep failing or
+// looping are switched off.
 func (s *AutomationServiceImpl) ExecuteFromTrigger(ctx context.Context, moduleName string, record map[string]interface{}, triggerType string) error {
 	rules, err := s.Repo.GetByModule(ctx, moduleName)
 	if err != nil {
@@ -95,8 +183,8 @@ func (s *AutomationServiceImpl) ExecuteFromTrigger(ctx context.Context, moduleNa
 			continue
 		}
 
-		if s.evaluateConditions(rule.Conditions, record) {
-			if err := s.executeActions(ctx, rule.Actions, moduleName, record); err != nil {
+		if s.evaluateConditions(ctx, &rule, record) {
+			if err := s.run(ctx, &rule, moduleName, record, true); err != nil {
 				fmt.Printf("Error executing automation rule '%s': %v\n", rule.Name, err)
 			}
 		}
@@ -104,32 +192,124 @@ func (s *AutomationServiceImpl) ExecuteFromTrigger(ctx context.Context, moduleNa
 	return nil
 }
 
-func (s *AutomationServiceImpl) evaluateConditions(conditions []RuleCondition, record map[string]interface{}) bool {
-	for _, cond := range conditions {
-		val, exists := record[cond.Field]
-		if !exists {
-			return false
+func (s *AutomationServiceImpl) ExecuteRule(ctx context.Context, ruleID string, moduleName string, record map[string]interface{}) error {
+	rule, err := s.Repo.GetByID(ctx, ruleID)
+	if err != nil {
+		return fmt.Errorf("automation rule not found")
+	}
+	if rule.ModuleID != moduleName {
+		return fmt.Errorf("automation rule '%s' does not belong to module '%s'", rule.Name, moduleName)
+	}
+	if !rule.Active {
+		return fmt.Errorf("automation rule '%s' is inactive", rule.Name)
+	}
+	if !s.evaluateConditions(ctx, rule, record) {
+		return fmt.Errorf("record does not meet the conditions of '%s'", rule.Name)
+	}
+	return s.run(ctx, rule, moduleName, record, false)
+}
+
+// run executes the rule's actions under the execution guard. Actions run one
+// level deeper than the trigger, and field updates they make fire the
+// module's update rules at that depth. Cooldowns only apply to triggered
+// runs, so users can re-run a rule on demand.
+func (s *AutomationServiceImpl) run(ctx context.Context, rule *AutomationRule, moduleName string, data map[string]interface{}, triggered bool) error {
+	depth := record.AutomationDepth(ctx)
+	var recordID string
+	if id, ok := data["_id"]; ok {
+		recordID = fmt.Sprintf("%v", id)
+	}
+
+	cooldownKey := ""
+	if triggered {
+		cooldownKey = recordID
+	}
+	if err := s.guard.admit(rule, cooldownKey, depth, time.Now()); err != nil {
+		if !errors.Is(err, errRateLimited) {
+			s.reportFailure(ctx, rule, err)
 		}
+		return err
+	}
 
-		match := false
-		switch cond.Operator {
-		case OperatorEquals:
-			match = fmt.Sprintf("%v", val) == fmt.Sprintf("%v", cond.Value)
-		case OperatorNotEquals:
-			match = fmt.Sprintf("%v", val) != fmt.Sprintf("%v", cond.Value)
-		case OperatorContains:
-			match = strings.Contains(fmt.Sprintf("%v", val), fmt.Sprintf("%v", cond.Value))
-		default:
-			match = false
+	actionCtx := record.WithAutomationDepth(ctx, depth+1)
+	updated := map[string]interface{}{}
+	var firstErr error
+	for i, action := range rule.Actions {
+		if err := s.ActionExecutor.ExecuteAction(actionCtx, action, moduleName, data); err != nil {
+			log.Printf("Failed to execute action %d (type: %s): %v", i, action.Type, err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("action %d (%s) failed: %w", i+1, action.Type, err)
+			}
+			continue
 		}
+		if action.Type == ActionUpdateField {
+			if field, _ := action.Config["field"].(string); field != "" {
+				updated[field] = action.Config["value"]
+			}
+		}
+	}
 
-		if !match {
-			return false
+	if firstErr != nil {
+		s.reportFailure(ctx, rule, firstErr)
+		return firstErr
+	}
+	s.guard.succeed(rule.ID.Hex())
+
+	if len(updated) > 0 && recordID != "" {
+		merged := make(map[string]interface{}, len(data)+len(updated))
+		for k, v := range data {
+			merged[k] = v
+		}
+		for k, v := range updated {
+			merged[k] = v
 		}
+		_ = s.ExecuteFromTrigger(record.WithPrevious(actionCtx, data), moduleName, merged, "update")
+	}
+	return nil
+}
+
+// reportFailure counts a failed or blocked run and opens the rule's circuit
+// once the breaker threshold is reached
+func (s *AutomationServiceImpl) reportFailure(ctx context.Context, rule *AutomationRule, cause error) {
+	if !s.guard.fail(rule.ID.Hex(), time.Now()) {
+		return
+	}
+
+	circuit := &RuleCircuit{
+		OpenedAt: time.Now(),
+		Reason:   fmt.Sprintf("disabled after %d failures in %s; last: %v", breakerThreshold, breakerWindow, cause),
+	}
+	if err := s.Repo.SetCircuit(ctx, rule.ID, circuit); err != nil {
+		log.Printf("Failed to open circuit for automation rule '%s': %v", rule.Name, err)
+		return
+	}
+	log.Printf("Automation rule '%s' disabled: %s", rule.Name, circuit.Reason)
+
+	s.AuditService.LogChange(ctx, common_models.AuditActionAutomation, "automation", rule.ID.Hex(), map[string]common_models.Change{
+		"active":  {Old: true, New: false},
+		"circuit": {New: circuit},
+	})
+
+	if s.NotificationService != nil && !rule.CreatedBy.IsZero() {
+		_ = s.NotificationService.CreateNotification(ctx, rule.CreatedBy,
+			"Automation rule disabled",
+			fmt.Sprintf("'%s' was switched off: %s", rule.Name, circuit.Reason),
+			notification.NotificationTypeWarning,
+			"/automation/rules/"+rule.ID.Hex())
 	}
-	return true
 }
 
-func (s *AutomationServiceImpl) executeActions(ctx context.Context, actions []RuleAction, moduleName string, record map[string]interface{}) error {
-	return s.ActionExecutor.ExecuteActions(ctx, actions, moduleName, record)
+// evaluateConditions matches the flat conditions and the condition group.
+// On update triggers the pre-update record comes from the context.
+func (s *AutomationServiceImpl) evaluateConditions(ctx context.Context, rule *AutomationRule, data map[string]interface{}) bool {
+	c := conditionContext{record: data, now: time.Now()}
+	if previous, ok := record.PreviousRecord(ctx); ok {
+		c.previous = previous
+	}
+	for _, cond := range rule.Conditions {
+		if !cond.matches(c) {
+			return false
+		}
+	}
+	return rule.ConditionGroup == nil || rule.ConditionGroup.matches(c)
 }
@@ -0,0 +1,35 @@
+package blueprint
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type BlueprintApi struct {
+	controller  *BlueprintController
+	config      *config.Config
+	roleService middleware.RoleService
+}
+
+func NewBlueprintApi(controller *BlueprintController, config *config.Config, roleService middleware.RoleService) *BlueprintApi {
+	return &BlueprintApi{
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *BlueprintApi) Setup(app *fiber.App) {
+	group := app.Group("/api/blueprints", middleware.AuthMiddleware(h.config.SkipAuth))
+
+	// Anyone who can see a record may ask which moves are open to them
+	group.Get("/transitions", h.controller.AvailableTransitions)
+
+	group.Get("/", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListBlueprints)
+	group.Post("/", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.CreateBlueprint)
+	group.Get("/:id", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.GetBlueprint)
+	group.Put("/:id", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.UpdateBlueprint)
+	group.Delete("/:id", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.DeleteBlueprint)
+}
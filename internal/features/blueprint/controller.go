@@ -0,0 +1,143 @@
+package blueprint
+
+import (
+	"errors"
+
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type BlueprintController struct {
+	Service BlueprintService
+}
+
+func NewBlueprintController(service BlueprintService) *BlueprintController {
+	return &BlueprintController{Service: service}
+}
+
+func notFoundOr(err error, status int) int {
+	if errors.Is(err, ErrBlueprintNotFound) {
+		return fiber.StatusNotFound
+	}
+	return status
+}
+
+// CreateBlueprint godoc
+// @Summary Create blueprint
+// @Description Define a state machine over a select field: its states, allowed transitions, inputs each transition requires, roles that may make it and actions to run after it. Use module_name tickets and field status for ticket statuses.
+// @Tags blueprints
+// @Accept json
+// @Produce json
+// @Param blueprint body Blueprint true "Blueprint"
+// @Success 201 {object} Blueprint
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/blueprints [post]
+func (c *BlueprintController) CreateBlueprint(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var b Blueprint
+	if err := ctx.BodyParser(&b); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := c.Service.CreateBlueprint(ctx.UserContext(), &b, userID); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{"data": b})
+}
+
+// ListBlueprints godoc
+// @Summary List blueprints
+// @Tags blueprints
+// @Produce json
+// @Success 200 {array} Blueprint
+// @Router /api/blueprints [get]
+func (c *BlueprintController) ListBlueprints(ctx *fiber.Ctx) error {
+	blueprints, err := c.Service.ListBlueprints(ctx.UserContext())
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": blueprints})
+}
+
+// GetBlueprint godoc
+// @Summary Get blueprint
+// @Tags blueprints
+// @Produce json
+// @Param id path string true "Blueprint ID"
+// @Success 200 {object} Blueprint
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/blueprints/{id} [get]
+func (c *BlueprintController) GetBlueprint(ctx *fiber.Ctx) error {
+	b, err := c.Service.GetBlueprint(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(notFoundOr(err, fiber.StatusBadRequest)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": b})
+}
+
+// UpdateBlueprint godoc
+// @Summary Update blueprint
+// @Description The module and field cannot be changed
+// @Tags blueprints
+// @Accept json
+// @Produce json
+// @Param id path string true "Blueprint ID"
+// @Param blueprint body Blueprint true "Blueprint"
+// @Success 200 {object} Blueprint
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/blueprints/{id} [put]
+func (c *BlueprintController) UpdateBlueprint(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var in Blueprint
+	if err := ctx.BodyParser(&in); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	b, err := c.Service.UpdateBlueprint(ctx.UserContext(), ctx.Params("id"), &in, userID)
+	if err != nil {
+		return ctx.Status(notFoundOr(err, fiber.StatusBadRequest)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": b})
+}
+
+// DeleteBlueprint godoc
+// @Summary Delete blueprint
+// @Tags blueprints
+// @Param id path string true "Blueprint ID"
+// @Success 204
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/blueprints/{id} [delete]
+func (c *BlueprintController) DeleteBlueprint(ctx *fiber.Ctx) error {
+	if err := c.Service.DeleteBlueprint(ctx.UserContext(), ctx.Params("id")); err != nil {
+		return ctx.Status(notFoundOr(err, fiber.StatusBadRequest)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// AvailableTransitions godoc
+// @Summary List available transitions
+// @Description Transitions the current user can make from a state, with the inputs each one requires
+// @Tags blueprints
+// @Produce json
+// @Param module query string true "Module name, or tickets"
+// @Param field query string true "Field name"
+// @Param from query string true "Current state"
+// @Success 200 {array} Transition
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/blueprints/transitions [get]
+func (c *BlueprintController) AvailableTransitions(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	transitions, err := c.Service.AvailableTransitions(ctx.UserContext(), ctx.Query("module"), ctx.Query("field"), ctx.Query("from"), userID)
+	if err != nil {
+		return ctx.Status(notFoundOr(err, fiber.StatusInternalServerError)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": transitions})
+}
@@ -0,0 +1,105 @@
+package blueprint
+
+import (
+	"time"
+
+	"go-crm/internal/features/automation"
+	"go-crm/internal/features/ticket"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TicketsModule is the module name blueprints use for tickets, which are
+// not module records
+const TicketsModule = ticket.StageGateModuleName
+
+// State is one value of the blueprint's field
+type State struct {
+	Value string `json:"value" bson:"value"`
+	Label string `json:"label,omitempty" bson:"label,omitempty"`
+	// Initial states are the only ones a record can be created in. When no
+	// state is initial, records can start anywhere.
+	Initial bool `json:"initial,omitempty" bson:"initial,omitempty"`
+	// Final states can only be left by transitions that name them in From
+	Final bool `json:"final,omitempty" bson:"final,omitempty"`
+}
+
+// Transition allows moving the field from any of From to To
+type Transition struct {
+	Name string `json:"name" bson:"name"`
+	// From is empty to allow the move from any state that is not final
+	From []string `json:"from,omitempty" bson:"from,omitempty"`
+	To   string   `json:"to" bson:"to"`
+	// RequiredInputs are fields that must be submitted with the change,
+	// e.g. a lost reason when a deal moves to Closed Lost
+	RequiredInputs []string `json:"required_inputs,omitempty" bson:"required_inputs,omitempty"`
+	// Roles are the role IDs allowed to make the transition; empty means anyone
+	Roles []string `json:"roles,omitempty" bson:"roles,omitempty"`
+	// Actions run after the transition is saved
+	Actions []automation.RuleAction `json:"actions,omitempty" bson:"actions,omitempty"`
+}
+
+// Blueprint is a state machine over one select field of a module
+type Blueprint struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID    primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	Name        string             `json:"name" bson:"name"`
+	ModuleName  string             `json:"module_name" bson:"module_name"`
+	Field       string             `json:"field" bson:"field"`
+	Active      bool               `json:"active" bson:"active"`
+	States      []State            `json:"states" bson:"states"`
+	Transitions []Transition       `json:"transitions" bson:"transitions"`
+	UpdatedBy   primitive.ObjectID `json:"updated_by" bson:"updated_by"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+func (b *Blueprint) state(value string) *State {
+	for i := range b.States {
+		if b.States[i].Value == value {
+			return &b.States[i]
+		}
+	}
+	return nil
+}
+
+func (b *Blueprint) initialStates() []string {
+	var states []string
+	for _, s := range b.States {
+		if s.Initial {
+			states = append(states, s.Value)
+		}
+	}
+	return states
+}
+
+// leaves reports whether t can start from the given state
+func (b *Blueprint) leaves(t Transition, from string) bool {
+	if len(t.From) == 0 {
+		s := b.state(from)
+		return s == nil || !s.Final
+	}
+	for _, f := range t.From {
+		if f == from {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultTicketBlueprint is used for ticket statuses until a tenant defines
+// its own: every status can move to every other one
+func defaultTicketBlueprint() *Blueprint {
+	b := &Blueprint{Name: "Ticket status", ModuleName: TicketsModule, Field: "status", Active: true}
+	for _, status := range []ticket.TicketStatus{
+		ticket.TicketStatusNew,
+		ticket.TicketStatusOpen,
+		ticket.TicketStatusPending,
+		ticket.TicketStatusResolved,
+		ticket.TicketStatusClosed,
+	} {
+		b.States = append(b.States, State{Value: string(status)})
+		b.Transitions = append(b.Transitions, Transition{Name: string(status), To: string(status)})
+	}
+	return b
+}
@@ -0,0 +1,158 @@
+package blueprint
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+type BlueprintRepository interface {
+	Create(ctx context.Context, b *Blueprint) error
+	Get(ctx context.Context, id string) (*Blueprint, error)
+	List(ctx context.Context) ([]Blueprint, error)
+	// ListByModule returns the module's active blueprints
+	ListByModule(ctx context.Context, moduleName string) ([]Blueprint, error)
+	// FindByField returns the blueprint of a module field, or nil when it has none
+	FindByField(ctx context.Context, moduleName, field string) (*Blueprint, error)
+	Update(ctx context.Context, b *Blueprint) error
+	Delete(ctx context.Context, id string) error
+}
+
+type BlueprintRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewBlueprintRepository(db *database.MongodbDB) BlueprintRepository {
+	return &BlueprintRepositoryImpl{
+		collection: db.DB.Collection("blueprints"),
+	}
+}
+
+func (r *BlueprintRepositoryImpl) Create(ctx context.Context, b *Blueprint) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	b.ID = primitive.NewObjectID()
+	b.TenantID = tenantID
+	b.CreatedAt = time.Now()
+	b.UpdatedAt = b.CreatedAt
+
+	_, err = r.collection.InsertOne(ctx, b)
+	return err
+}
+
+func (r *BlueprintRepositoryImpl) Get(ctx context.Context, id string) (*Blueprint, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var b Blueprint
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID}).Decode(&b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (r *BlueprintRepositoryImpl) List(ctx context.Context) ([]Blueprint, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "module_name", Value: 1}, {Key: "field", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"tenant_id": tenantID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	blueprints := []Blueprint{}
+	if err := cursor.All(ctx, &blueprints); err != nil {
+		return nil, err
+	}
+	return blueprints, nil
+}
+
+func (r *BlueprintRepositoryImpl) ListByModule(ctx context.Context, moduleName string) ([]Blueprint, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"tenant_id": tenantID, "module_name": moduleName, "active": true})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var blueprints []Blueprint
+	if err := cursor.All(ctx, &blueprints); err != nil {
+		return nil, err
+	}
+	return blueprints, nil
+}
+
+func (r *BlueprintRepositoryImpl) FindByField(ctx context.Context, moduleName, field string) (*Blueprint, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var b Blueprint
+	err = r.collection.FindOne(ctx, bson.M{"tenant_id": tenantID, "module_name": moduleName, "field": field}).Decode(&b)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (r *BlueprintRepositoryImpl) Update(ctx context.Context, b *Blueprint) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	b.UpdatedAt = time.Now()
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": b.ID, "tenant_id": tenantID}, b)
+	return err
+}
+
+func (r *BlueprintRepositoryImpl) Delete(ctx context.Context, id string) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID})
+	if err == nil && res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return err
+}
@@ -0,0 +1,433 @@
+package blueprint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/automation"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/role"
+	"go-crm/internal/features/user"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var ErrBlueprintNotFound = errors.New("blueprint not found")
+
+type BlueprintService interface {
+	CreateBlueprint(ctx context.Context, b *Blueprint, userID primitive.ObjectID) error
+	GetBlueprint(ctx context.Context, id string) (*Blueprint, error)
+	ListBlueprints(ctx context.Context) ([]Blueprint, error)
+	UpdateBlueprint(ctx context.Context, id string, b *Blueprint, userID primitive.ObjectID) (*Blueprint, error)
+	DeleteBlueprint(ctx context.Context, id string) error
+	// AvailableTransitions lists the transitions the user can make from a state
+	AvailableTransitions(ctx context.Context, moduleName, field, from string, userID primitive.ObjectID) ([]Transition, error)
+
+	// CheckTransition implements record.TransitionGuard
+	CheckTransition(ctx context.Context, moduleName string, previous, input map[string]interface{}, userID primitive.ObjectID) error
+	// RecordChanged runs the actions of the transitions a saved change made
+	RecordChanged(ctx context.Context, change record.RecordChange)
+}
+
+type BlueprintServiceImpl struct {
+	Repo           BlueprintRepository
+	ModuleRepo     module.ModuleRepository
+	UserRepo       user.UserRepository
+	RoleRepo       role.RoleRepository
+	ActionExecutor automation.ActionExecutor
+	AuditService   audit.AuditService
+}
+
+func NewBlueprintService(
+	repo BlueprintRepository,
+	moduleRepo module.ModuleRepository,
+	userRepo user.UserRepository,
+	roleRepo role.RoleRepository,
+	actionExecutor automation.ActionExecutor,
+	auditService audit.AuditService,
+) BlueprintService {
+	return &BlueprintServiceImpl{
+		Repo:           repo,
+		ModuleRepo:     moduleRepo,
+		UserRepo:       userRepo,
+		RoleRepo:       roleRepo,
+		ActionExecutor: actionExecutor,
+		AuditService:   auditService,
+	}
+}
+
+// fieldOptions returns the values the blueprint's field accepts
+func (s *BlueprintServiceImpl) fieldOptions(ctx context.Context, moduleName, field string) ([]string, error) {
+	if moduleName == TicketsModule {
+		if field != "status" {
+			return nil, errors.New("ticket blueprints apply to the status field")
+		}
+		var statuses []string
+		for _, st := range defaultTicketBlueprint().States {
+			statuses = append(statuses, st.Value)
+		}
+		return statuses, nil
+	}
+	m, err := s.ModuleRepo.FindByName(ctx, moduleName)
+	if err != nil {
+		return nil, errors.New("module not found")
+	}
+	for _, f := range m.Fields {
+		if f.Name != field {
+			continue
+		}
+		if f.Type != common_models.FieldTypeSelect {
+			return nil, fmt.Errorf("field %s is not a select field", field)
+		}
+		options := make([]string, 0, len(f.Options))
+		for _, o := range f.Options {
+			options = append(options, o.Value)
+		}
+		return options, nil
+	}
+	return nil, fmt.Errorf("field %s not found in %s", field, moduleName)
+}
+
+func (s *BlueprintServiceImpl) validate(ctx context.Context, b *Blueprint) error {
+	if b.ModuleName == "" || b.Field == "" {
+		return errors.New("module_name and field are required")
+	}
+	if b.Name == "" {
+		b.Name = b.ModuleName + " " + b.Field
+	}
+	options, err := s.fieldOptions(ctx, b.ModuleName, b.Field)
+	if err != nil {
+		return err
+	}
+	if len(b.States) == 0 {
+		return errors.New("at least one state is required")
+	}
+	seen := map[string]bool{}
+	for _, st := range b.States {
+		if !slices.Contains(options, st.Value) {
+			return fmt.Errorf("unknown state %q; expected one of %s", st.Value, strings.Join(options, ", "))
+		}
+		if seen[st.Value] {
+			return fmt.Errorf("state %s is listed twice", st.Value)
+		}
+		seen[st.Value] = true
+	}
+	for _, t := range b.Transitions {
+		if !seen[t.To] {
+			return fmt.Errorf("transition %s: unknown state %q", t.Name, t.To)
+		}
+		for _, from := range t.From {
+			if !seen[from] {
+				return fmt.Errorf("transition %s: unknown state %q", t.Name, from)
+			}
+		}
+		for _, roleID := range t.Roles {
+			if _, err := primitive.ObjectIDFromHex(roleID); err != nil {
+				return fmt.Errorf("transition %s: invalid role id %q", t.Name, roleID)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *BlueprintServiceImpl) CreateBlueprint(ctx context.Context, b *Blueprint, userID primitive.ObjectID) error {
+	if err := s.validate(ctx, b); err != nil {
+		return err
+	}
+	existing, err := s.Repo.FindByField(ctx, b.ModuleName, b.Field)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("%s.%s already has a blueprint", b.ModuleName, b.Field)
+	}
+	b.UpdatedBy = userID
+	if err := s.Repo.Create(ctx, b); err != nil {
+		return err
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "blueprints", b.ModuleName+"."+b.Field, map[string]common_models.Change{
+		"blueprint": {New: b},
+	})
+	return nil
+}
+
+func (s *BlueprintServiceImpl) GetBlueprint(ctx context.Context, id string) (*Blueprint, error) {
+	b, err := s.Repo.Get(ctx, id)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrBlueprintNotFound
+	}
+	return b, err
+}
+
+func (s *BlueprintServiceImpl) ListBlueprints(ctx context.Context) ([]Blueprint, error) {
+	return s.Repo.List(ctx)
+}
+
+func (s *BlueprintServiceImpl) UpdateBlueprint(ctx context.Context, id string, in *Blueprint, userID primitive.ObjectID) (*Blueprint, error) {
+	existing, err := s.GetBlueprint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	old := *existing
+
+	// The module and field are fixed; create a new blueprint to move them
+	existing.Name = in.Name
+	existing.Active = in.Active
+	existing.States = in.States
+	existing.Transitions = in.Transitions
+	existing.UpdatedBy = userID
+	if err := s.validate(ctx, existing); err != nil {
+		return nil, err
+	}
+	if err := s.Repo.Update(ctx, existing); err != nil {
+		return nil, err
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "blueprints", existing.ModuleName+"."+existing.Field, map[string]common_models.Change{
+		"blueprint": {Old: old, New: existing},
+	})
+	return existing, nil
+}
+
+func (s *BlueprintServiceImpl) DeleteBlueprint(ctx context.Context, id string) error {
+	b, err := s.GetBlueprint(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.Repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "blueprints", b.ModuleName+"."+b.Field, map[string]common_models.Change{
+		"blueprint": {Old: b.Name, New: "DELETED"},
+	})
+	return nil
+}
+
+// blueprints returns the module's active blueprints. Tickets fall back to
+// the built-in status blueprint when the tenant has not defined one.
+func (s *BlueprintServiceImpl) blueprints(ctx context.Context, moduleName string) ([]Blueprint, error) {
+	blueprints, err := s.Repo.ListByModule(ctx, moduleName)
+	if err != nil {
+		return nil, err
+	}
+	if moduleName == TicketsModule && !slices.ContainsFunc(blueprints, func(b Blueprint) bool { return b.Field == "status" }) {
+		if custom, err := s.Repo.FindByField(ctx, TicketsModule, "status"); err != nil || custom == nil {
+			blueprints = append(blueprints, *defaultTicketBlueprint())
+		}
+	}
+	return blueprints, nil
+}
+
+// actor resolves the user's roles once per check
+type actor struct {
+	roles []string
+	admin bool
+}
+
+func (s *BlueprintServiceImpl) loadActor(ctx context.Context, userID primitive.ObjectID) *actor {
+	a := &actor{}
+	if userID.IsZero() {
+		// System writes are not restricted by role
+		a.admin = true
+		return a
+	}
+	u, err := s.UserRepo.FindByID(ctx, userID.Hex())
+	if err != nil {
+		return a
+	}
+	for _, roleID := range u.Roles {
+		a.roles = append(a.roles, roleID.Hex())
+		if r, err := s.RoleRepo.FindByID(ctx, roleID.Hex()); err == nil && role.IsAdminRole(r.Name) {
+			a.admin = true
+		}
+	}
+	return a
+}
+
+func (a *actor) may(t Transition) bool {
+	if a.admin || len(t.Roles) == 0 {
+		return true
+	}
+	for _, r := range t.Roles {
+		if slices.Contains(a.roles, r) {
+			return true
+		}
+	}
+	return false
+}
+
+func stringValue(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case primitive.ObjectID:
+		return t.Hex()
+	}
+	return fmt.Sprint(v)
+}
+
+func isEmpty(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(t) == ""
+	case primitive.ObjectID:
+		return t.IsZero()
+	case []interface{}:
+		return len(t) == 0
+	case primitive.A:
+		return len(t) == 0
+	}
+	return false
+}
+
+func (s *BlueprintServiceImpl) labels(ctx context.Context, moduleName string) map[string]string {
+	labels := map[string]string{}
+	if moduleName == TicketsModule {
+		return labels
+	}
+	if m, err := s.ModuleRepo.FindByName(ctx, moduleName); err == nil {
+		for _, f := range m.Fields {
+			labels[f.Name] = f.Label
+		}
+	}
+	return labels
+}
+
+func (s *BlueprintServiceImpl) CheckTransition(ctx context.Context, moduleName string, previous, input map[string]interface{}, userID primitive.ObjectID) error {
+	if _, ok := ctx.Value(common_models.TenantIDKey).(string); !ok {
+		// System writes without a tenant are not checked
+		return nil
+	}
+	blueprints, err := s.blueprints(ctx, moduleName)
+	if err != nil {
+		return err
+	}
+
+	var who *actor
+	for i := range blueprints {
+		b := &blueprints[i]
+		val, submitted := input[b.Field]
+		to := stringValue(val)
+		if !submitted || to == "" {
+			continue
+		}
+		if b.state(to) == nil {
+			return fmt.Errorf("%s is not a valid %s", to, b.Field)
+		}
+
+		from := stringValue(previous[b.Field])
+		if from == to {
+			continue
+		}
+		if from == "" {
+			if initial := b.initialStates(); len(initial) > 0 && !slices.Contains(initial, to) {
+				return &record.StageTransitionError{Field: b.Field, To: to, Allowed: initial}
+			}
+			continue
+		}
+
+		var candidates []Transition
+		for _, t := range b.Transitions {
+			if t.To == to && b.leaves(t, from) {
+				candidates = append(candidates, t)
+			}
+		}
+		if len(candidates) == 0 {
+			allowed := []string{}
+			for _, t := range b.Transitions {
+				if b.leaves(t, from) && t.To != from && !slices.Contains(allowed, t.To) {
+					allowed = append(allowed, t.To)
+				}
+			}
+			return &record.StageTransitionError{Field: b.Field, From: from, To: to, Allowed: allowed}
+		}
+
+		if who == nil {
+			who = s.loadActor(ctx, userID)
+		}
+		i := slices.IndexFunc(candidates, who.may)
+		if i < 0 {
+			return &record.StageTransitionError{Field: b.Field, From: from, To: to, Unmet: []string{"your role is not allowed to make this transition"}}
+		}
+
+		labels := s.labels(ctx, moduleName)
+		terr := &record.StageTransitionError{Field: b.Field, From: from, To: to}
+		for _, field := range candidates[i].RequiredInputs {
+			if isEmpty(input[field]) {
+				label := labels[field]
+				if label == "" {
+					label = field
+				}
+				terr.Missing = append(terr.Missing, label)
+			}
+		}
+		if len(terr.Missing) > 0 {
+			return terr
+		}
+	}
+	return nil
+}
+
+func (s *BlueprintServiceImpl) AvailableTransitions(ctx context.Context, moduleName, field, from string, userID primitive.ObjectID) ([]Transition, error) {
+	blueprints, err := s.blueprints(ctx, moduleName)
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(blueprints, func(b Blueprint) bool { return b.Field == field })
+	if i < 0 {
+		return nil, ErrBlueprintNotFound
+	}
+	b := &blueprints[i]
+	who := s.loadActor(ctx, userID)
+
+	transitions := []Transition{}
+	for _, t := range b.Transitions {
+		if t.To != from && b.leaves(t, from) && who.may(t) {
+			transitions = append(transitions, t)
+		}
+	}
+	return transitions, nil
+}
+
+func (s *BlueprintServiceImpl) RecordChanged(ctx context.Context, change record.RecordChange) {
+	if change.Created {
+		return
+	}
+	blueprints, err := s.blueprints(ctx, change.ModuleName)
+	if err != nil {
+		return
+	}
+	for i := range blueprints {
+		b := &blueprints[i]
+		c, ok := change.Changes[b.Field]
+		if !ok {
+			continue
+		}
+		from, to := stringValue(c.Old), stringValue(c.New)
+		if from == to {
+			continue
+		}
+		for _, t := range b.Transitions {
+			if t.To != to || !b.leaves(t, from) || len(t.Actions) == 0 {
+				continue
+			}
+			if err := s.ActionExecutor.ExecuteActions(ctx, t.Actions, change.ModuleName, change.Record); err != nil {
+				log.Printf("Blueprint %s: transition %s actions failed: %v", b.Name, t.Name, err)
+			}
+			break
+		}
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"strings"
 
 	common_models "go-crm/internal/common/models"
+	"go-crm/internal/common/session"
+	"go-crm/internal/features/record"
 
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -42,11 +44,10 @@ func (c *BulkOperationController) PreviewBulkOperation(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
 	}
 
-	userIDStr, ok := ctx.Locals("user_id").(string)
+	userID, ok := session.UserID(ctx.UserContext())
 	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
-	userID, _ := primitive.ObjectIDFromHex(userIDStr)
 
 	records, total, err := c.BulkService.PreviewBulkOperation(ctx.UserContext(), req.ModuleName, req.Filters, userID)
 	if err != nil {
@@ -61,7 +62,7 @@ func (c *BulkOperationController) PreviewBulkOperation(ctx *fiber.Ctx) error {
 
 // CreateBulkOperation godoc
 // @Summary Create bulk operation
-// @Description Create a new bulk operation job
+// @Description Create a new bulk operation job. triggers.suppress_automations skips automation rules for its records; triggers.batch_webhooks sends their webhooks in batches of up to 100 records.
 // @Tags bulk_operations
 // @Accept json
 // @Produce json
@@ -76,6 +77,7 @@ func (c *BulkOperationController) CreateBulkOperation(ctx *fiber.Ctx) error {
 		Type       BulkOperationType      `json:"type"`
 		Filters    interface{}            `json:"filters"`
 		Updates    map[string]interface{} `json:"updates"`
+		Triggers   record.TriggerOptions  `json:"triggers"`
 	}
 
 	var req CreateBulkOpRequest
@@ -123,13 +125,13 @@ func (c *BulkOperationController) CreateBulkOperation(ctx *fiber.Ctx) error {
 		Type:       req.Type,
 		Updates:    req.Updates,
 		Filters:    filters,
+		Triggers:   req.Triggers,
 	}
 
-	userIDStr, ok := ctx.Locals("user_id").(string)
+	userID, ok := session.UserID(ctx.UserContext())
 	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
-	userID, _ := primitive.ObjectIDFromHex(userIDStr)
 	op.UserID = userID
 
 	tenantIDStr, ok := ctx.Locals("tenant_id").(string)
@@ -164,11 +166,10 @@ func (c *BulkOperationController) CreateBulkOperation(ctx *fiber.Ctx) error {
 func (c *BulkOperationController) ExecuteBulkOperation(ctx *fiber.Ctx) error {
 	opID := ctx.Params("id")
 
-	userIDStr, ok := ctx.Locals("user_id").(string)
+	userID, ok := session.UserID(ctx.UserContext())
 	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
-	userID, _ := primitive.ObjectIDFromHex(userIDStr)
 
 	go func() {
 		bgCtx := context.Background()
@@ -208,11 +209,10 @@ func (c *BulkOperationController) GetBulkOperation(ctx *fiber.Ctx) error {
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/bulk/operations [get]
 func (c *BulkOperationController) ListBulkOperations(ctx *fiber.Ctx) error {
-	userIDStr, ok := ctx.Locals("user_id").(string)
+	userID, ok := session.UserID(ctx.UserContext())
 	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
-	userID, _ := primitive.ObjectIDFromHex(userIDStr)
 
 	ops, err := c.BulkService.GetUserOperations(ctx.UserContext(), userID)
 	if err != nil {
@@ -2,6 +2,7 @@ package bulk_operation
 
 import (
 	"go-crm/internal/common/models"
+	"go-crm/internal/features/record"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -33,6 +34,7 @@ type BulkOperation struct {
 	Type           BulkOperationType      `json:"type" bson:"type"`
 	Filters        []models.Filter        `json:"filters" bson:"filters"`
 	Updates        map[string]interface{} `json:"updates" bson:"updates"`
+	Triggers       record.TriggerOptions  `json:"triggers" bson:"triggers"`
 	Status         BulkOperationStatus    `json:"status" bson:"status"`
 	TotalRecords   int                    `json:"total_records" bson:"total_records"`
 	ProcessedCount int                    `json:"processed_count" bson:"processed_count"`
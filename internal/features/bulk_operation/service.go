@@ -71,6 +71,7 @@ func (s *BulkOperationServiceImpl) ExecuteBulkOperation(ctx context.Context, opI
 	// Inject tenant context
 	ctx = context.WithValue(ctx, models.TenantIDKey, op.TenantID.Hex())
 	s.BulkRepo.UpdateStatus(ctx, opID, BulkStatusProcessing)
+	ctx, batch := record.WithTriggerBatch(ctx, op.Triggers, opID)
 
 	records, total, err := s.RecordService.ListRecords(ctx, op.ModuleName, op.Filters, 1, 10000, "created_at", "desc", userID)
 	if err != nil {
@@ -124,6 +125,8 @@ func (s *BulkOperationServiceImpl) ExecuteBulkOperation(ctx context.Context, opI
 		}
 	}
 
+	batch.Flush(ctx)
+
 	op.SuccessCount = successCount
 	op.ErrorCount = errorCount
 	op.Errors = errs
@@ -3,14 +3,17 @@ package chart
 import (
 	"context"
 	"fmt"
+	"go-crm/internal/common/apperr"
 	common_models "go-crm/internal/common/models"
 	"go-crm/internal/features/audit"
 	"go-crm/internal/features/module"
 	"go-crm/internal/features/record"
+	"go-crm/internal/features/role"
 	"sort"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -21,20 +24,25 @@ type ChartService interface {
 	UpdateChart(ctx context.Context, id string, chart *Chart) error
 	DeleteChart(ctx context.Context, id string) error
 	GetChartData(ctx context.Context, id string) ([]map[string]interface{}, error)
+	// GetChartDataFor aggregates only the records userID may read, and
+	// refuses charts over fields hidden from them
+	GetChartDataFor(ctx context.Context, id string, userID primitive.ObjectID) ([]map[string]interface{}, error)
 }
 
 type ChartServiceImpl struct {
 	ChartRepo    ChartRepository
 	RecordRepo   record.RecordRepository
 	ModuleRepo   module.ModuleRepository
+	RoleService  role.RoleService
 	AuditService audit.AuditService
 }
 
-func NewChartService(chartRepo ChartRepository, recordRepo record.RecordRepository, moduleRepo module.ModuleRepository, auditService audit.AuditService) ChartService {
+func NewChartService(chartRepo ChartRepository, recordRepo record.RecordRepository, moduleRepo module.ModuleRepository, roleService role.RoleService, auditService audit.AuditService) ChartService {
 	return &ChartServiceImpl{
 		ChartRepo:    chartRepo,
 		RecordRepo:   recordRepo,
 		ModuleRepo:   moduleRepo,
+		RoleService:  roleService,
 		AuditService: auditService,
 	}
 }
@@ -93,12 +101,45 @@ func (s *ChartServiceImpl) GetChartData(ctx context.Context, id string) ([]map[s
 	if err != nil {
 		return nil, err
 	}
+	return s.aggregate(ctx, chart, nil)
+}
+
+func (s *ChartServiceImpl) GetChartDataFor(ctx context.Context, id string, userID primitive.ObjectID) ([]map[string]interface{}, error) {
+	chart, err := s.ChartRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 
+	perms, err := s.RoleService.GetFieldPermissions(ctx, userID, chart.ModuleID)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range []string{chart.XAxisField, chart.YAxisField} {
+		if field != "" && perms[field] == role.FieldPermNone {
+			return nil, apperr.PermissionDenied("field '%s' is hidden from you", field)
+		}
+	}
+
+	accessFilter, err := s.RoleService.GetAccessFilter(ctx, userID, chart.ModuleID, "read")
+	if err != nil {
+		return nil, err
+	}
+	return s.aggregate(ctx, chart, accessFilter)
+}
+
+// aggregate groups the chart's module records, limited to those matching
+// accessFilter when given
+func (s *ChartServiceImpl) aggregate(ctx context.Context, chart *Chart, accessFilter bson.M) ([]map[string]interface{}, error) {
 	moduleName := chart.ModuleID
 	pipeline := mongo.Pipeline{}
+	if len(accessFilter) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: accessFilter}})
+	}
 
 	mod, err := s.ModuleRepo.FindByName(ctx, moduleName)
-	var groupID any = "$" + chart.XAxisField
+	xField := recordFieldPath(chart.XAxisField)
+	yField := recordFieldPath(chart.YAxisField)
+	var groupID any = xField
 
 	if err == nil {
 		for _, f := range mod.Fields {
@@ -107,7 +148,7 @@ func (s *ChartServiceImpl) GetChartData(ctx context.Context, id string) ([]map[s
 					groupID = bson.M{
 						"$dateToString": bson.M{
 							"format": "%Y-%m-%d",
-							"date":   "$" + chart.XAxisField,
+							"date":   xField,
 						},
 					}
 				}
@@ -121,13 +162,13 @@ func (s *ChartServiceImpl) GetChartData(ctx context.Context, id string) ([]map[s
 	case AggregationTypeCount:
 		accumulator = bson.M{"$sum": 1}
 	case AggregationTypeSum:
-		accumulator = bson.M{"$sum": "$" + chart.YAxisField}
+		accumulator = bson.M{"$sum": yField}
 	case AggregationTypeAvg:
-		accumulator = bson.M{"$avg": "$" + chart.YAxisField}
+		accumulator = bson.M{"$avg": yField}
 	case AggregationTypeMin:
-		accumulator = bson.M{"$min": "$" + chart.YAxisField}
+		accumulator = bson.M{"$min": yField}
 	case AggregationTypeMax:
-		accumulator = bson.M{"$max": "$" + chart.YAxisField}
+		accumulator = bson.M{"$max": yField}
 	default:
 		accumulator = bson.M{"$sum": 1}
 	}
@@ -204,3 +245,13 @@ func (s *ChartServiceImpl) GetChartData(ctx context.Context, id string) ([]map[s
 
 	return formatted, nil
 }
+
+// recordFieldPath maps a module field name to its aggregation path inside the
+// unified records collection, where user fields are nested under "data".
+func recordFieldPath(field string) string {
+	switch field {
+	case "_id", "created_at", "updated_at", "created_by", "updated_by":
+		return "$" + field
+	}
+	return "$data." + field
+}
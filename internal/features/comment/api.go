@@ -0,0 +1,39 @@
+package comment
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/features/role"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type CommentApi struct {
+	controller  *CommentController
+	config      *config.Config
+	roleService role.RoleService
+}
+
+func NewCommentApi(controller *CommentController, config *config.Config, roleService role.RoleService) *CommentApi {
+	return &CommentApi{
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *CommentApi) Setup(app *fiber.App) {
+	comments := app.Group("/api/comments", middleware.AuthMiddleware(h.config.SkipAuth))
+	comments.Get("/settings", h.controller.ListSettings)
+	comments.Get("/settings/:module", h.controller.GetSettings)
+	comments.Put("/settings/:module", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.UpdateSettings)
+	comments.Get("/:id", h.controller.Get)
+	comments.Put("/:id", h.controller.Update)
+	comments.Delete("/:id", h.controller.Delete)
+	comments.Post("/:id/reactions/:reaction", h.controller.React)
+
+	// Record read access is enforced by the service when the thread is loaded
+	records := app.Group("/api/modules", middleware.AuthMiddleware(h.config.SkipAuth))
+	records.Get("/:module/records/:id/comments", h.controller.List)
+	records.Post("/:module/records/:id/comments", h.controller.Create)
+}
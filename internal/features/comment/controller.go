@@ -0,0 +1,247 @@
+package comment
+
+import (
+	common_api "go-crm/internal/common/api"
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type CommentController struct {
+	Service CommentService
+}
+
+func NewCommentController(service CommentService) *CommentController {
+	return &CommentController{Service: service}
+}
+
+// writeError answers a failed comment operation; errors the service does not
+// classify are the caller's input
+func writeError(ctx *fiber.Ctx, err error) error {
+	return common_api.Fail(ctx, fiber.StatusBadRequest, err)
+}
+
+// List godoc
+// @Summary List record comments
+// @Description List comment threads on a record. Internal comments are only returned to users who can update the record. Pass flat=true for a flat list.
+// @Tags comments
+// @Produce json
+// @Param module path string true "Module Name"
+// @Param id path string true "Record ID"
+// @Param flat query bool false "Return a flat list instead of threads"
+// @Success 200 {array} Comment
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/modules/{module}/records/{id}/comments [get]
+func (c *CommentController) List(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if ctx.QueryBool("flat") {
+		comments, err := c.Service.ListCommentsFlat(ctx.UserContext(), ctx.Params("module"), ctx.Params("id"), userID)
+		if err != nil {
+			return writeError(ctx, err)
+		}
+		return ctx.JSON(comments)
+	}
+
+	threads, err := c.Service.ListComments(ctx.UserContext(), ctx.Params("module"), ctx.Params("id"), userID)
+	if err != nil {
+		return writeError(ctx, err)
+	}
+	return ctx.JSON(threads)
+}
+
+// Create godoc
+// @Summary Add comment
+// @Description Add a comment or a reply (parent_id) to a record
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param module path string true "Module Name"
+// @Param id path string true "Record ID"
+// @Param comment body CreateCommentRequest true "Comment"
+// @Success 201 {object} Comment
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/modules/{module}/records/{id}/comments [post]
+func (c *CommentController) Create(ctx *fiber.Ctx) error {
+	var req CreateCommentRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	comment, err := c.Service.AddComment(ctx.UserContext(), ctx.Params("module"), ctx.Params("id"), req, userID)
+	if err != nil {
+		return writeError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(comment)
+}
+
+// Get godoc
+// @Summary Get comment
+// @Description Get a comment including its edit history
+// @Tags comments
+// @Produce json
+// @Param id path string true "Comment ID"
+// @Success 200 {object} Comment
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/comments/{id} [get]
+func (c *CommentController) Get(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	comment, err := c.Service.GetComment(ctx.UserContext(), ctx.Params("id"), userID)
+	if err != nil {
+		return writeError(ctx, err)
+	}
+
+	return ctx.JSON(comment)
+}
+
+// Update godoc
+// @Summary Edit comment
+// @Description Edit your own comment; the previous content is kept in edit_history
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param id path string true "Comment ID"
+// @Param comment body map[string]string true "New content"
+// @Success 200 {object} Comment
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/comments/{id} [put]
+func (c *CommentController) Update(ctx *fiber.Ctx) error {
+	var input struct {
+		Content string `json:"content"`
+	}
+	if err := ctx.BodyParser(&input); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	comment, err := c.Service.EditComment(ctx.UserContext(), ctx.Params("id"), input.Content, userID)
+	if err != nil {
+		return writeError(ctx, err)
+	}
+
+	return ctx.JSON(comment)
+}
+
+// Delete godoc
+// @Summary Delete comment
+// @Description Delete your own comment. Replies stay in the thread under a deleted placeholder.
+// @Tags comments
+// @Param id path string true "Comment ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/comments/{id} [delete]
+func (c *CommentController) Delete(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if err := c.Service.DeleteComment(ctx.UserContext(), ctx.Params("id"), userID); err != nil {
+		return writeError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Comment deleted"})
+}
+
+// React godoc
+// @Summary Toggle reaction
+// @Description Add the reaction for the current user, or remove it if already present
+// @Tags comments
+// @Produce json
+// @Param id path string true "Comment ID"
+// @Param reaction path string true "Reaction (thumbs_up, thumbs_down, heart, laugh, celebrate, eyes)"
+// @Success 200 {object} Comment
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/comments/{id}/reactions/{reaction} [post]
+func (c *CommentController) React(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	comment, err := c.Service.ToggleReaction(ctx.UserContext(), ctx.Params("id"), ctx.Params("reaction"), userID)
+	if err != nil {
+		return writeError(ctx, err)
+	}
+
+	return ctx.JSON(comment)
+}
+
+// ListSettings godoc
+// @Summary List comment settings
+// @Description List per-module comment settings. Modules not listed use the defaults (everything enabled).
+// @Tags comments
+// @Produce json
+// @Success 200 {array} CommentSettings
+// @Router /api/comments/settings [get]
+func (c *CommentController) ListSettings(ctx *fiber.Ctx) error {
+	settings, err := c.Service.ListSettings(ctx.UserContext())
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(settings)
+}
+
+// GetSettings godoc
+// @Summary Get comment settings for a module
+// @Tags comments
+// @Produce json
+// @Param module path string true "Module Name"
+// @Success 200 {object} CommentSettings
+// @Router /api/comments/settings/{module} [get]
+func (c *CommentController) GetSettings(ctx *fiber.Ctx) error {
+	settings, err := c.Service.GetSettings(ctx.UserContext(), ctx.Params("module"))
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(settings)
+}
+
+// UpdateSettings godoc
+// @Summary Update comment settings for a module
+// @Description Enable or disable comments, internal comments and reactions on a module
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param module path string true "Module Name"
+// @Param settings body CommentSettings true "Settings"
+// @Success 200 {object} CommentSettings
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/comments/settings/{module} [put]
+func (c *CommentController) UpdateSettings(ctx *fiber.Ctx) error {
+	var settings CommentSettings
+	if err := ctx.BodyParser(&settings); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	settings.ModuleName = ctx.Params("module")
+
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if err := c.Service.UpdateSettings(ctx.UserContext(), &settings, userID); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(settings)
+}
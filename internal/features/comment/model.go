@@ -0,0 +1,71 @@
+package comment
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reactions accepted on comments; the names double as bson keys
+var AllowedReactions = []string{"thumbs_up", "thumbs_down", "heart", "laugh", "celebrate", "eyes"}
+
+// Comment is a note on any module record. Replies point at their parent and
+// share the RootID of the top-level comment so a whole thread loads at once.
+type Comment struct {
+	ID          primitive.ObjectID              `json:"id" bson:"_id,omitempty"`
+	TenantID    primitive.ObjectID              `json:"tenant_id" bson:"tenant_id"`
+	ModuleName  string                          `json:"module_name" bson:"module_name"`
+	RecordID    string                          `json:"record_id" bson:"record_id"`
+	ParentID    *primitive.ObjectID             `json:"parent_id,omitempty" bson:"parent_id,omitempty"`
+	RootID      *primitive.ObjectID             `json:"root_id,omitempty" bson:"root_id,omitempty"`
+	Content     string                          `json:"content" bson:"content"`
+	IsInternal  bool                            `json:"is_internal" bson:"is_internal"` // hidden from users without update access
+	Attachments []primitive.ObjectID            `json:"attachments,omitempty" bson:"attachments,omitempty"`
+	Reactions   map[string][]primitive.ObjectID `json:"reactions,omitempty" bson:"reactions,omitempty"`
+	EditHistory []CommentEdit                   `json:"edit_history,omitempty" bson:"edit_history,omitempty"`
+	IsDeleted   bool                            `json:"is_deleted" bson:"is_deleted"`
+	DeletedAt   *time.Time                      `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	CreatedBy   primitive.ObjectID              `json:"created_by" bson:"created_by"`
+	CreatedAt   time.Time                       `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time                       `json:"updated_at" bson:"updated_at"`
+
+	// AIGenerated marks notes written by the AI assistant
+	AIGenerated bool `json:"ai_generated,omitempty" bson:"ai_generated,omitempty"`
+
+	// Replies is filled when a thread is returned as a tree
+	Replies []*Comment `json:"replies,omitempty" bson:"-"`
+}
+
+// CommentEdit keeps the content a comment had before an edit
+type CommentEdit struct {
+	Content  string             `json:"content" bson:"content"`
+	EditedBy primitive.ObjectID `json:"edited_by" bson:"edited_by"`
+	EditedAt time.Time          `json:"edited_at" bson:"edited_at"`
+}
+
+// CommentSettings toggles comment features per module. Modules without a
+// settings document use DefaultSettings.
+type CommentSettings struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID       primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	ModuleName     string             `json:"module_name" bson:"module_name"`
+	Enabled        bool               `json:"enabled" bson:"enabled"`
+	AllowInternal  bool               `json:"allow_internal" bson:"allow_internal"`
+	AllowReactions bool               `json:"allow_reactions" bson:"allow_reactions"`
+	UpdatedBy      primitive.ObjectID `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	UpdatedAt      time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+func DefaultSettings(moduleName string) *CommentSettings {
+	return &CommentSettings{ModuleName: moduleName, Enabled: true, AllowInternal: true, AllowReactions: true}
+}
+
+type CreateCommentRequest struct {
+	Content     string               `json:"content"`
+	IsInternal  bool                 `json:"is_internal"`
+	ParentID    string               `json:"parent_id"`
+	Attachments []primitive.ObjectID `json:"attachments"`
+
+	// AIGenerated is set by the server only
+	AIGenerated bool `json:"-"`
+}
@@ -0,0 +1,217 @@
+package comment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CommentRepository interface {
+	Create(ctx context.Context, comment *Comment) error
+	Get(ctx context.Context, id string) (*Comment, error)
+	ListByRecord(ctx context.Context, moduleName, recordID string, includeInternal bool) ([]Comment, error)
+	UpdateContent(ctx context.Context, id primitive.ObjectID, content string, previous CommentEdit) error
+	SoftDelete(ctx context.Context, id primitive.ObjectID) error
+	AddReaction(ctx context.Context, id primitive.ObjectID, reaction string, userID primitive.ObjectID) error
+	RemoveReaction(ctx context.Context, id primitive.ObjectID, reaction string, userID primitive.ObjectID) error
+}
+
+type CommentRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewCommentRepository(db *database.MongodbDB) CommentRepository {
+	return &CommentRepositoryImpl{
+		collection: db.DB.Collection("comments"),
+	}
+}
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+func (r *CommentRepositoryImpl) Create(ctx context.Context, comment *Comment) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if comment.ID.IsZero() {
+		comment.ID = primitive.NewObjectID()
+	}
+	comment.TenantID = tenantID
+	comment.CreatedAt = time.Now()
+	comment.UpdatedAt = time.Now()
+
+	_, err = r.collection.InsertOne(ctx, comment)
+	return err
+}
+
+func (r *CommentRepositoryImpl) Get(ctx context.Context, id string) (*Comment, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var comment Comment
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID}).Decode(&comment); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+func (r *CommentRepositoryImpl) ListByRecord(ctx context.Context, moduleName, recordID string, includeInternal bool) ([]Comment, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{"tenant_id": tenantID, "module_name": moduleName, "record_id": recordID}
+	if !includeInternal {
+		filter["is_internal"] = false
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	comments := []Comment{}
+	if err := cursor.All(ctx, &comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (r *CommentRepositoryImpl) UpdateContent(ctx context.Context, id primitive.ObjectID, content string, previous CommentEdit) error {
+	return r.update(ctx, id, bson.M{
+		"$set":  bson.M{"content": content, "updated_at": time.Now()},
+		"$push": bson.M{"edit_history": previous},
+	})
+}
+
+func (r *CommentRepositoryImpl) SoftDelete(ctx context.Context, id primitive.ObjectID) error {
+	now := time.Now()
+	// Content goes but the document stays so replies keep their parent
+	return r.update(ctx, id, bson.M{
+		"$set":   bson.M{"is_deleted": true, "deleted_at": now, "content": "", "updated_at": now},
+		"$unset": bson.M{"edit_history": "", "attachments": "", "reactions": ""},
+	})
+}
+
+func (r *CommentRepositoryImpl) AddReaction(ctx context.Context, id primitive.ObjectID, reaction string, userID primitive.ObjectID) error {
+	return r.update(ctx, id, bson.M{"$addToSet": bson.M{"reactions." + reaction: userID}})
+}
+
+func (r *CommentRepositoryImpl) RemoveReaction(ctx context.Context, id primitive.ObjectID, reaction string, userID primitive.ObjectID) error {
+	return r.update(ctx, id, bson.M{"$pull": bson.M{"reactions." + reaction: userID}})
+}
+
+func (r *CommentRepositoryImpl) update(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrCommentNotFound
+	}
+	return nil
+}
+
+type CommentSettingsRepository interface {
+	Get(ctx context.Context, moduleName string) (*CommentSettings, error)
+	List(ctx context.Context) ([]CommentSettings, error)
+	Upsert(ctx context.Context, settings *CommentSettings) error
+}
+
+type CommentSettingsRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewCommentSettingsRepository(db *database.MongodbDB) CommentSettingsRepository {
+	return &CommentSettingsRepositoryImpl{
+		collection: db.DB.Collection("comment_settings"),
+	}
+}
+
+// Get returns nil without error when the module has no settings yet
+func (r *CommentSettingsRepositoryImpl) Get(ctx context.Context, moduleName string) (*CommentSettings, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var settings CommentSettings
+	err = r.collection.FindOne(ctx, bson.M{"tenant_id": tenantID, "module_name": moduleName}).Decode(&settings)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (r *CommentSettingsRepositoryImpl) List(ctx context.Context) ([]CommentSettings, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"tenant_id": tenantID}, options.Find().SetSort(bson.M{"module_name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	settings := []CommentSettings{}
+	if err := cursor.All(ctx, &settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (r *CommentSettingsRepositoryImpl) Upsert(ctx context.Context, settings *CommentSettings) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	settings.TenantID = tenantID
+	settings.UpdatedAt = time.Now()
+
+	_, err = r.collection.UpdateOne(ctx,
+		bson.M{"tenant_id": tenantID, "module_name": settings.ModuleName},
+		bson.M{
+			"$set": bson.M{
+				"enabled":         settings.Enabled,
+				"allow_internal":  settings.AllowInternal,
+				"allow_reactions": settings.AllowReactions,
+				"updated_by":      settings.UpdatedBy,
+				"updated_at":      settings.UpdatedAt,
+			},
+			"$setOnInsert": bson.M{"_id": primitive.NewObjectID()},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
@@ -0,0 +1,362 @@
+package comment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/notification"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/role"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const maxCommentLength = 10000
+
+var (
+	ErrCommentsDisabled = apperr.PermissionDenied("comments are disabled for this module")
+	ErrNotAuthor        = apperr.PermissionDenied("only the author can change this comment")
+	ErrCommentNotFound  = apperr.NotFound("comment not found")
+	ErrRecordNotFound   = apperr.NotFound("record not found")
+)
+
+// RecordResolver checks that a record outside the dynamic modules (e.g. a
+// ticket) exists and is readable. Staff who can read such records also see
+// internal comments on them.
+type RecordResolver func(ctx context.Context, recordID string, userID primitive.ObjectID) error
+
+type CommentService interface {
+	AddComment(ctx context.Context, moduleName, recordID string, req CreateCommentRequest, userID primitive.ObjectID) (*Comment, error)
+	ListComments(ctx context.Context, moduleName, recordID string, userID primitive.ObjectID) ([]*Comment, error)
+	ListCommentsFlat(ctx context.Context, moduleName, recordID string, userID primitive.ObjectID) ([]Comment, error)
+	GetComment(ctx context.Context, id string, userID primitive.ObjectID) (*Comment, error)
+	EditComment(ctx context.Context, id, content string, userID primitive.ObjectID) (*Comment, error)
+	DeleteComment(ctx context.Context, id string, userID primitive.ObjectID) error
+	ToggleReaction(ctx context.Context, id, reaction string, userID primitive.ObjectID) (*Comment, error)
+
+	GetSettings(ctx context.Context, moduleName string) (*CommentSettings, error)
+	ListSettings(ctx context.Context) ([]CommentSettings, error)
+	UpdateSettings(ctx context.Context, settings *CommentSettings, userID primitive.ObjectID) error
+
+	RegisterRecordResolver(moduleName string, resolver RecordResolver)
+}
+
+type CommentServiceImpl struct {
+	Repo                CommentRepository
+	SettingsRepo        CommentSettingsRepository
+	RecordService       record.RecordService
+	RoleService         role.RoleService
+	AuditService        audit.AuditService
+	NotificationService notification.NotificationService
+
+	mu        sync.RWMutex
+	resolvers map[string]RecordResolver
+}
+
+func NewCommentService(
+	repo CommentRepository,
+	settingsRepo CommentSettingsRepository,
+	recordService record.RecordService,
+	roleService role.RoleService,
+	auditService audit.AuditService,
+	notificationService notification.NotificationService,
+) CommentService {
+	return &CommentServiceImpl{
+		Repo:                repo,
+		SettingsRepo:        settingsRepo,
+		RecordService:       recordService,
+		RoleService:         roleService,
+		AuditService:        auditService,
+		NotificationService: notificationService,
+		resolvers:           map[string]RecordResolver{},
+	}
+}
+
+func (s *CommentServiceImpl) RegisterRecordResolver(moduleName string, resolver RecordResolver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolvers[moduleName] = resolver
+}
+
+// access verifies the user can read the record and reports whether internal
+// comments are visible to them
+func (s *CommentServiceImpl) access(ctx context.Context, moduleName, recordID string, userID primitive.ObjectID) (bool, error) {
+	s.mu.RLock()
+	resolver, ok := s.resolvers[moduleName]
+	s.mu.RUnlock()
+	if ok {
+		if err := resolver(ctx, recordID, userID); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	if _, err := s.RecordService.GetRecord(ctx, moduleName, recordID, userID); err != nil {
+		return false, ErrRecordNotFound
+	}
+	// Read-only users only see customer-visible comments
+	filter, err := s.RoleService.GetAccessFilter(ctx, userID, moduleName, "update")
+	if err != nil {
+		return false, nil
+	}
+	if v, denied := filter["_id"]; denied && v == -1 {
+		return false, nil
+	}
+	return true, nil
+}
+
+func (s *CommentServiceImpl) GetSettings(ctx context.Context, moduleName string) (*CommentSettings, error) {
+	settings, err := s.SettingsRepo.Get(ctx, moduleName)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		return DefaultSettings(moduleName), nil
+	}
+	return settings, nil
+}
+
+func (s *CommentServiceImpl) ListSettings(ctx context.Context) ([]CommentSettings, error) {
+	return s.SettingsRepo.List(ctx)
+}
+
+func (s *CommentServiceImpl) UpdateSettings(ctx context.Context, settings *CommentSettings, userID primitive.ObjectID) error {
+	if settings.ModuleName == "" {
+		return errors.New("module_name is required")
+	}
+	old, _ := s.GetSettings(ctx, settings.ModuleName)
+	settings.UpdatedBy = userID
+	if err := s.SettingsRepo.Upsert(ctx, settings); err != nil {
+		return err
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "comment_settings", settings.ModuleName, map[string]common_models.Change{
+		"settings": {Old: old, New: settings},
+	})
+	return nil
+}
+
+func (s *CommentServiceImpl) AddComment(ctx context.Context, moduleName, recordID string, req CreateCommentRequest, userID primitive.ObjectID) (*Comment, error) {
+	content := strings.TrimSpace(req.Content)
+	if content == "" {
+		return nil, errors.New("content is required")
+	}
+	if len(content) > maxCommentLength {
+		return nil, fmt.Errorf("content exceeds %d characters", maxCommentLength)
+	}
+
+	settings, err := s.GetSettings(ctx, moduleName)
+	if err != nil {
+		return nil, err
+	}
+	if !settings.Enabled {
+		return nil, ErrCommentsDisabled
+	}
+
+	canInternal, err := s.access(ctx, moduleName, recordID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.IsInternal && (!settings.AllowInternal || !canInternal) {
+		return nil, errors.New("internal comments are not allowed here")
+	}
+
+	c := &Comment{
+		ModuleName:  moduleName,
+		RecordID:    recordID,
+		Content:     content,
+		IsInternal:  req.IsInternal,
+		Attachments: req.Attachments,
+		CreatedBy:   userID,
+		AIGenerated: req.AIGenerated,
+	}
+
+	var parent *Comment
+	if req.ParentID != "" {
+		parent, err = s.Repo.Get(ctx, req.ParentID)
+		if err != nil || parent.ModuleName != moduleName || parent.RecordID != recordID {
+			return nil, errors.New("parent comment not found")
+		}
+		if parent.IsInternal && !canInternal {
+			return nil, errors.New("parent comment not found")
+		}
+		c.ParentID = &parent.ID
+		rootID := parent.ID
+		if parent.RootID != nil {
+			rootID = *parent.RootID
+		}
+		c.RootID = &rootID
+		// A reply to an internal note stays internal
+		c.IsInternal = c.IsInternal || parent.IsInternal
+	}
+
+	if err := s.Repo.Create(ctx, c); err != nil {
+		return nil, err
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionUpdate, moduleName, recordID, map[string]common_models.Change{
+		"comment_added": {Old: nil, New: c.ID.Hex()},
+	})
+
+	if parent != nil && parent.CreatedBy != userID && !parent.IsDeleted {
+		_ = s.NotificationService.CreateNotification(ctx, parent.CreatedBy, "New reply",
+			truncate(c.Content, 140), notification.NotificationTypeInfo,
+			fmt.Sprintf("/dashboard/modules/%s/%s", moduleName, recordID))
+	}
+
+	return c, nil
+}
+
+// ListComments returns threads as trees of top-level comments and their replies
+func (s *CommentServiceImpl) ListComments(ctx context.Context, moduleName, recordID string, userID primitive.ObjectID) ([]*Comment, error) {
+	flat, err := s.ListCommentsFlat(ctx, moduleName, recordID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[primitive.ObjectID]*Comment, len(flat))
+	for i := range flat {
+		byID[flat[i].ID] = &flat[i]
+	}
+	roots := []*Comment{}
+	for i := range flat {
+		c := &flat[i]
+		if c.ParentID != nil {
+			if parent, ok := byID[*c.ParentID]; ok {
+				parent.Replies = append(parent.Replies, c)
+				continue
+			}
+			// Parent hidden from this user; the reply is hidden with it
+			continue
+		}
+		roots = append(roots, c)
+	}
+	return roots, nil
+}
+
+// ListCommentsFlat returns the visible comments in creation order
+func (s *CommentServiceImpl) ListCommentsFlat(ctx context.Context, moduleName, recordID string, userID primitive.ObjectID) ([]Comment, error) {
+	canInternal, err := s.access(ctx, moduleName, recordID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Repo.ListByRecord(ctx, moduleName, recordID, canInternal)
+}
+
+func (s *CommentServiceImpl) GetComment(ctx context.Context, id string, userID primitive.ObjectID) (*Comment, error) {
+	c, err := s.Repo.Get(ctx, id)
+	if err != nil {
+		return nil, ErrCommentNotFound
+	}
+	canInternal, err := s.access(ctx, c.ModuleName, c.RecordID, userID)
+	if err != nil || (c.IsInternal && !canInternal) {
+		return nil, ErrCommentNotFound
+	}
+	return c, nil
+}
+
+func (s *CommentServiceImpl) EditComment(ctx context.Context, id, content string, userID primitive.ObjectID) (*Comment, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, errors.New("content is required")
+	}
+	if len(content) > maxCommentLength {
+		return nil, fmt.Errorf("content exceeds %d characters", maxCommentLength)
+	}
+
+	c, err := s.GetComment(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if c.IsDeleted {
+		return nil, errors.New("comment has been deleted")
+	}
+	if c.CreatedBy != userID {
+		return nil, ErrNotAuthor
+	}
+	if c.Content == content {
+		return c, nil
+	}
+
+	previous := CommentEdit{Content: c.Content, EditedBy: userID, EditedAt: time.Now()}
+	if err := s.Repo.UpdateContent(ctx, c.ID, content, previous); err != nil {
+		return nil, err
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionUpdate, c.ModuleName, c.RecordID, map[string]common_models.Change{
+		"comment_edited": {Old: c.Content, New: content},
+	})
+
+	c.EditHistory = append(c.EditHistory, previous)
+	c.Content = content
+	c.UpdatedAt = previous.EditedAt
+	return c, nil
+}
+
+func (s *CommentServiceImpl) DeleteComment(ctx context.Context, id string, userID primitive.ObjectID) error {
+	c, err := s.GetComment(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if c.IsDeleted {
+		return nil
+	}
+	if c.CreatedBy != userID {
+		return ErrNotAuthor
+	}
+	if err := s.Repo.SoftDelete(ctx, c.ID); err != nil {
+		return err
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionUpdate, c.ModuleName, c.RecordID, map[string]common_models.Change{
+		"comment_deleted": {Old: c.ID.Hex(), New: nil},
+	})
+	return nil
+}
+
+// ToggleReaction adds the user's reaction, or removes it when already present
+func (s *CommentServiceImpl) ToggleReaction(ctx context.Context, id, reaction string, userID primitive.ObjectID) (*Comment, error) {
+	if !slices.Contains(AllowedReactions, reaction) {
+		return nil, fmt.Errorf("unsupported reaction: %s", reaction)
+	}
+	c, err := s.GetComment(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if c.IsDeleted {
+		return nil, errors.New("comment has been deleted")
+	}
+	settings, err := s.GetSettings(ctx, c.ModuleName)
+	if err != nil {
+		return nil, err
+	}
+	if !settings.Enabled || !settings.AllowReactions {
+		return nil, errors.New("reactions are disabled for this module")
+	}
+
+	if slices.Contains(c.Reactions[reaction], userID) {
+		err = s.Repo.RemoveReaction(ctx, c.ID, reaction, userID)
+	} else {
+		err = s.Repo.AddReaction(ctx, c.ID, reaction, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.Repo.Get(ctx, id)
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
@@ -0,0 +1,31 @@
+package contract
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ContractApi struct {
+	controller *ContractController
+	config     *config.Config
+}
+
+func NewContractApi(controller *ContractController, config *config.Config) *ContractApi {
+	return &ContractApi{
+		controller: controller,
+		config:     config,
+	}
+}
+
+// Setup registers contract routes. Module permissions are checked by the
+// record service on each read and write.
+func (h *ContractApi) Setup(app *fiber.App) {
+	group := app.Group("/api/contracts", middleware.AuthMiddleware(h.config.SkipAuth))
+
+	group.Get("/renewals", h.controller.GetRenewalPipeline)
+	group.Get("/revenue", h.controller.GetRevenueSummary)
+	group.Patch("/:id/renewal-stage", h.controller.UpdateRenewalStage)
+	group.Post("/:id/renew", h.controller.Renew)
+}
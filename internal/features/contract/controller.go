@@ -0,0 +1,134 @@
+package contract
+
+import (
+	"errors"
+	"time"
+
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ContractController struct {
+	Service ContractService
+}
+
+func NewContractController(service ContractService) *ContractController {
+	return &ContractController{Service: service}
+}
+
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// GetRenewalPipeline godoc
+// @Summary Contract renewal pipeline
+// @Description Active contracts ending within the window, grouped by renewal stage
+// @Tags contracts
+// @Produce json
+// @Param days query int false "Days ahead (default 90, max 365)"
+// @Success 200 {object} RenewalPipeline
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/contracts/renewals [get]
+func (c *ContractController) GetRenewalPipeline(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	pipeline, err := c.Service.GetRenewalPipeline(ctx.UserContext(), ctx.QueryInt("days", 90), userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": pipeline})
+}
+
+// UpdateRenewalStage godoc
+// @Summary Move contract in renewal pipeline
+// @Tags contracts
+// @Accept json
+// @Produce json
+// @Param id path string true "Contract ID"
+// @Param stage body StageRequest true "Stage"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/contracts/{id}/renewal-stage [patch]
+func (c *ContractController) UpdateRenewalStage(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var req StageRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := c.Service.UpdateRenewalStage(ctx.UserContext(), ctx.Params("id"), req.Stage, userID); err != nil {
+		if errors.Is(err, ErrContractNotFound) {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"message": "Renewal stage updated"})
+}
+
+// Renew godoc
+// @Summary Renew contract
+// @Description Create the next term of the contract with its products and mark this one renewed
+// @Tags contracts
+// @Produce json
+// @Param id path string true "Contract ID"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/contracts/{id}/renew [post]
+func (c *ContractController) Renew(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	id, err := c.Service.Renew(ctx.UserContext(), ctx.Params("id"), userID)
+	if err != nil {
+		if errors.Is(err, ErrContractNotFound) {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{"data": fiber.Map{"id": id}})
+}
+
+// GetRevenueSummary godoc
+// @Summary Contract revenue recognition
+// @Description Contract value recognised evenly over each term, per period
+// @Tags contracts
+// @Produce json
+// @Param start_date query string false "Start (YYYY-MM-DD or RFC3339, default one year before end)"
+// @Param end_date query string false "End, exclusive (default start of next year)"
+// @Param interval query string false "month, quarter or year"
+// @Success 200 {object} RevenueSummary
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/contracts/revenue [get]
+func (c *ContractController) GetRevenueSummary(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	start, err := parseDate(ctx.Query("start_date"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid start_date"})
+	}
+	end, err := parseDate(ctx.Query("end_date"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid end_date"})
+	}
+	summary, err := c.Service.GetRevenueSummary(ctx.UserContext(), start, end, ctx.Query("interval"), userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": summary})
+}
@@ -0,0 +1,94 @@
+package contract
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Modules contracts and their line items are stored in; see internal/migration/data/modules.json
+const (
+	ModuleName      = "contracts"
+	ItemsModuleName = "contract_products"
+)
+
+// Contract statuses
+const (
+	StatusDraft     = "draft"
+	StatusActive    = "active"
+	StatusRenewed   = "renewed"
+	StatusExpired   = "expired"
+	StatusCancelled = "cancelled"
+)
+
+// Renewal stages, in pipeline order
+var RenewalStages = []string{"not_started", "contacted", "negotiating", "won", "lost"}
+
+const (
+	// DefaultReminderDays applies when a contract has no renewal_reminder_days
+	DefaultReminderDays = 30
+	// MaxReminderDays bounds how far ahead the renewal job looks
+	MaxReminderDays = 365
+)
+
+// Reminder records that the renewal task for a contract term was created
+type Reminder struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID   primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	ContractID primitive.ObjectID `json:"contract_id" bson:"contract_id"`
+	EndDate    time.Time          `json:"end_date" bson:"end_date"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// RenewalItem is one contract in the renewal pipeline
+type RenewalItem struct {
+	ID        string      `json:"id"`
+	Name      string      `json:"name"`
+	Account   interface{} `json:"account,omitempty"`
+	EndDate   time.Time   `json:"end_date"`
+	DaysLeft  int         `json:"days_left"`
+	Value     float64     `json:"value"`
+	AutoRenew bool        `json:"auto_renew"`
+	Owner     interface{} `json:"owner,omitempty"`
+}
+
+// RenewalStage groups the pipeline by renewal_stage
+type RenewalStage struct {
+	Stage     string        `json:"stage"`
+	Count     int           `json:"count"`
+	Value     float64       `json:"value"`
+	Contracts []RenewalItem `json:"contracts"`
+}
+
+type RenewalPipeline struct {
+	Days   int            `json:"days"`
+	Count  int            `json:"count"`
+	Value  float64        `json:"value"`
+	Stages []RenewalStage `json:"stages"`
+}
+
+// RevenuePeriod is the revenue recognised in one period
+type RevenuePeriod struct {
+	Period     time.Time `json:"period"`
+	Recognized float64   `json:"recognized"`
+	Contracts  int       `json:"contracts"` // contracts contributing to the period
+}
+
+// RevenueSummary recognises contract value straight-line over each contract
+// term, by day
+type RevenueSummary struct {
+	Start      time.Time       `json:"start"`
+	End        time.Time       `json:"end"`
+	Interval   string          `json:"interval"`
+	Periods    []RevenuePeriod `json:"periods"`
+	Recognized float64         `json:"recognized"`
+	// Booked is the value of contracts starting in the range
+	Booked float64 `json:"booked"`
+	// Deferred is the value of contracts in the range still to be recognised after End
+	Deferred float64 `json:"deferred"`
+}
+
+// StageRequest moves a contract in the renewal pipeline
+type StageRequest struct {
+	Stage string `json:"stage"`
+}
@@ -0,0 +1,84 @@
+package contract
+
+import (
+	"context"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ContractRepository reads contract records across tenants for the renewal job
+type ContractRepository interface {
+	// ListActiveEndingBefore returns a page of active contracts, in every
+	// tenant, ending before the given time, ordered by ID after afterID
+	ListActiveEndingBefore(ctx context.Context, before time.Time, afterID primitive.ObjectID, limit int64) ([]models.EntityRecord, error)
+}
+
+type ContractRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewContractRepository(db *database.MongodbDB) ContractRepository {
+	return &ContractRepositoryImpl{
+		collection: db.DB.Collection("entity_records"),
+	}
+}
+
+func (r *ContractRepositoryImpl) ListActiveEndingBefore(ctx context.Context, before time.Time, afterID primitive.ObjectID, limit int64) ([]models.EntityRecord, error) {
+	filter := bson.M{
+		"entity":        ModuleName,
+		"deleted":       bson.M{"$ne": true},
+		"data.status":   StatusActive,
+		"data.end_date": bson.M{"$type": "date", "$lt": before},
+	}
+	if !afterID.IsZero() {
+		filter["_id"] = bson.M{"$gt": afterID}
+	}
+	opts := options.Find().SetSort(bson.M{"_id": 1}).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []models.EntityRecord
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+type ReminderRepository interface {
+	// Claim records a renewal reminder and reports false when it was already sent
+	Claim(ctx context.Context, r *Reminder) (bool, error)
+}
+
+type ReminderRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewReminderRepository(db *database.MongodbDB) ReminderRepository {
+	return &ReminderRepositoryImpl{
+		collection: db.DB.Collection("contract_renewal_reminders"),
+	}
+}
+
+func (r *ReminderRepositoryImpl) Claim(ctx context.Context, rem *Reminder) (bool, error) {
+	rem.CreatedAt = time.Now()
+	filter := bson.M{
+		"tenant_id":   rem.TenantID,
+		"contract_id": rem.ContractID,
+		"end_date":    rem.EndDate,
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$setOnInsert": bson.M{"created_at": rem.CreatedAt}}, options.Update().SetUpsert(true))
+	if err != nil {
+		return false, err
+	}
+	return result.UpsertedCount > 0, nil
+}
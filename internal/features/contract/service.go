@@ -0,0 +1,516 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/notification"
+	"go-crm/internal/features/record"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RenewalSchedule runs the renewal job daily
+const RenewalSchedule = "30 5 * * *"
+
+const (
+	renewalPageSize = 500
+	maxRevenueDays  = 5 * 366
+	day             = 24 * time.Hour
+)
+
+var (
+	ErrContractNotFound = apperr.NotFound("contract not found")
+	ErrNotRenewable     = errors.New("only active or expired contracts can be renewed")
+)
+
+type ContractService interface {
+	// GetRenewalPipeline groups active contracts ending within days by renewal stage
+	GetRenewalPipeline(ctx context.Context, days int, userID primitive.ObjectID) (*RenewalPipeline, error)
+	UpdateRenewalStage(ctx context.Context, id, stage string, userID primitive.ObjectID) error
+	// Renew creates the next term of a contract with its products and
+	// returns the new contract ID
+	Renew(ctx context.Context, id string, userID primitive.ObjectID) (string, error)
+	GetRevenueSummary(ctx context.Context, start, end time.Time, interval string, userID primitive.ObjectID) (*RevenueSummary, error)
+	// RunRenewals is the system job creating renewal reminder tasks and
+	// renewing or expiring ended contracts
+	RunRenewals(ctx context.Context) error
+}
+
+type ContractServiceImpl struct {
+	ContractRepo        ContractRepository
+	ReminderRepo        ReminderRepository
+	RecordService       record.RecordService
+	RecordRepo          record.RecordRepository
+	ModuleRepo          module.ModuleRepository
+	NotificationService notification.NotificationService
+}
+
+func NewContractService(
+	contractRepo ContractRepository,
+	reminderRepo ReminderRepository,
+	recordService record.RecordService,
+	recordRepo record.RecordRepository,
+	moduleRepo module.ModuleRepository,
+	notificationService notification.NotificationService,
+) ContractService {
+	return &ContractServiceImpl{
+		ContractRepo:        contractRepo,
+		ReminderRepo:        reminderRepo,
+		RecordService:       recordService,
+		RecordRepo:          recordRepo,
+		ModuleRepo:          moduleRepo,
+		NotificationService: notificationService,
+	}
+}
+
+func dateValue(rec map[string]interface{}, field string) (time.Time, bool) {
+	switch v := rec[field].(type) {
+	case time.Time:
+		return v, true
+	case primitive.DateTime:
+		return v.Time(), true
+	}
+	return time.Time{}, false
+}
+
+func numberValue(rec map[string]interface{}, field string) float64 {
+	switch v := rec[field].(type) {
+	case float64:
+		return v
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case int:
+		return float64(v)
+	}
+	return 0
+}
+
+func recordID(rec map[string]interface{}) string {
+	if oid, ok := rec["_id"].(primitive.ObjectID); ok {
+		return oid.Hex()
+	}
+	return fmt.Sprint(rec["id"])
+}
+
+var systemFields = map[string]bool{
+	"_id": true, "id": true, "created_at": true, "updated_at": true, "created_by": true, "updated_by": true,
+}
+
+// copyData returns the record's data fields without system fields
+func copyData(rec map[string]interface{}) map[string]interface{} {
+	data := make(map[string]interface{}, len(rec))
+	for k, v := range rec {
+		if !systemFields[k] {
+			data[k] = v
+		}
+	}
+	return data
+}
+
+// toInput converts stored values back to the form CreateRecord accepts
+func toInput(data map[string]interface{}) map[string]interface{} {
+	in := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		switch t := v.(type) {
+		case time.Time:
+			in[k] = t.Format(time.RFC3339)
+		case primitive.DateTime:
+			in[k] = t.Time().UTC().Format(time.RFC3339)
+		case primitive.ObjectID:
+			in[k] = t.Hex()
+		case int32:
+			in[k] = float64(t)
+		default:
+			in[k] = v
+		}
+	}
+	return in
+}
+
+// successor builds the next term of a contract: same length, starting the
+// day after it ends
+func successor(rec map[string]interface{}) (map[string]interface{}, error) {
+	start, ok1 := dateValue(rec, "start_date")
+	end, ok2 := dateValue(rec, "end_date")
+	if !ok1 || !ok2 || !end.After(start) {
+		return nil, errors.New("contract needs a start_date before its end_date to be renewed")
+	}
+	data := copyData(rec)
+	nextStart := end.AddDate(0, 0, 1)
+	data["start_date"] = nextStart
+	data["end_date"] = nextStart.Add(end.Sub(start))
+	data["status"] = StatusActive
+	data["renewal_stage"] = RenewalStages[0]
+	data["renewed_from"] = rec["_id"]
+	return data, nil
+}
+
+func (s *ContractServiceImpl) GetRenewalPipeline(ctx context.Context, days int, userID primitive.ObjectID) (*RenewalPipeline, error) {
+	if days == 0 {
+		days = 90
+	}
+	if days < 1 || days > MaxReminderDays {
+		return nil, fmt.Errorf("days must be between 1 and %d", MaxReminderDays)
+	}
+	now := time.Now()
+	window := now.Format(time.RFC3339) + "," + now.AddDate(0, 0, days).Format(time.RFC3339)
+
+	byStage := map[string]*RenewalStage{}
+	for _, stage := range RenewalStages {
+		byStage[stage] = &RenewalStage{Stage: stage, Contracts: []RenewalItem{}}
+	}
+	pipeline := &RenewalPipeline{Days: days}
+	err := s.RecordService.StreamRecords(ctx, ModuleName, []common_models.Filter{{Field: "end_date", Operator: "between", Value: window}}, nil, renewalPageSize, userID, func(batch []map[string]any) error {
+		for _, rec := range batch {
+			if rec["status"] != StatusActive {
+				continue
+			}
+			end, _ := dateValue(rec, "end_date")
+			autoRenew, _ := rec["auto_renew"].(bool)
+			name, _ := rec["name"].(string)
+			item := RenewalItem{
+				ID:        recordID(rec),
+				Name:      name,
+				Account:   rec["account"],
+				EndDate:   end,
+				DaysLeft:  int(math.Ceil(end.Sub(now).Hours() / 24)),
+				Value:     numberValue(rec, "value"),
+				AutoRenew: autoRenew,
+				Owner:     rec["owner"],
+			}
+			stage, _ := rec["renewal_stage"].(string)
+			group, ok := byStage[stage]
+			if !ok {
+				group = byStage[RenewalStages[0]]
+			}
+			group.Contracts = append(group.Contracts, item)
+			group.Count++
+			group.Value += item.Value
+			pipeline.Count++
+			pipeline.Value += item.Value
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stage := range RenewalStages {
+		group := byStage[stage]
+		sort.Slice(group.Contracts, func(i, j int) bool { return group.Contracts[i].EndDate.Before(group.Contracts[j].EndDate) })
+		pipeline.Stages = append(pipeline.Stages, *group)
+	}
+	return pipeline, nil
+}
+
+func (s *ContractServiceImpl) UpdateRenewalStage(ctx context.Context, id, stage string, userID primitive.ObjectID) error {
+	valid := false
+	for _, st := range RenewalStages {
+		valid = valid || st == stage
+	}
+	if !valid {
+		return fmt.Errorf("unknown renewal stage '%s'", stage)
+	}
+	if _, err := s.RecordService.GetRecord(ctx, ModuleName, id, userID); err != nil {
+		return ErrContractNotFound
+	}
+	return s.RecordService.UpdateRecord(ctx, ModuleName, id, map[string]interface{}{"renewal_stage": stage}, userID)
+}
+
+func (s *ContractServiceImpl) Renew(ctx context.Context, id string, userID primitive.ObjectID) (string, error) {
+	rec, err := s.RecordService.GetRecord(ctx, ModuleName, id, userID)
+	if err != nil {
+		return "", ErrContractNotFound
+	}
+	if rec["status"] != StatusActive && rec["status"] != StatusExpired {
+		return "", ErrNotRenewable
+	}
+	data, err := successor(rec)
+	if err != nil {
+		return "", err
+	}
+	created, err := s.RecordService.CreateRecord(ctx, ModuleName, toInput(data), userID)
+	if err != nil {
+		return "", err
+	}
+	newID := fmt.Sprint(created)
+	if oid, ok := created.(primitive.ObjectID); ok {
+		newID = oid.Hex()
+	}
+
+	err = s.RecordService.StreamRecords(ctx, ItemsModuleName, []common_models.Filter{{Field: "contract", Operator: "eq", Value: id}}, nil, renewalPageSize, userID, func(batch []map[string]any) error {
+		for _, item := range batch {
+			data := copyData(item)
+			data["contract"] = newID
+			if _, err := s.RecordService.CreateRecord(ctx, ItemsModuleName, toInput(data), userID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return newID, err
+	}
+
+	if err := s.RecordService.UpdateRecord(ctx, ModuleName, id, map[string]interface{}{"status": StatusRenewed, "renewal_stage": "won"}, userID); err != nil {
+		return newID, err
+	}
+	return newID, nil
+}
+
+// periodStart truncates t to the start of its month, quarter or year in UTC
+func periodStart(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	switch interval {
+	case "year":
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	case "quarter":
+		return time.Date(t.Year(), t.Month()-(t.Month()-1)%3, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+func nextPeriod(t time.Time, interval string) time.Time {
+	switch interval {
+	case "year":
+		return t.AddDate(1, 0, 0)
+	case "quarter":
+		return t.AddDate(0, 3, 0)
+	default:
+		return t.AddDate(0, 1, 0)
+	}
+}
+
+// overlapDays is the number of days [a1, a2) and [b1, b2) share
+func overlapDays(a1, a2, b1, b2 time.Time) float64 {
+	if b1.After(a1) {
+		a1 = b1
+	}
+	if b2.Before(a2) {
+		a2 = b2
+	}
+	if !a2.After(a1) {
+		return 0
+	}
+	return a2.Sub(a1).Hours() / 24
+}
+
+func round2(v float64) float64 { return math.Round(v*100) / 100 }
+
+// GetRevenueSummary recognises the value of active, renewed and expired
+// contracts evenly over every day of their term, from start_date to
+// end_date inclusive
+func (s *ContractServiceImpl) GetRevenueSummary(ctx context.Context, start, end time.Time, interval string, userID primitive.ObjectID) (*RevenueSummary, error) {
+	switch interval {
+	case "":
+		interval = "month"
+	case "month", "quarter", "year":
+	default:
+		return nil, errors.New("interval must be month, quarter or year")
+	}
+	if end.IsZero() {
+		end = periodStart(time.Now(), "year").AddDate(1, 0, 0)
+	}
+	if start.IsZero() {
+		start = end.AddDate(-1, 0, 0)
+	}
+	if !end.After(start) {
+		return nil, errors.New("end must be after start")
+	}
+	if end.Sub(start) > maxRevenueDays*day {
+		return nil, errors.New("range cannot exceed 5 years")
+	}
+
+	summary := &RevenueSummary{Start: start, End: end, Interval: interval}
+	for p := periodStart(start, interval); p.Before(end); p = nextPeriod(p, interval) {
+		summary.Periods = append(summary.Periods, RevenuePeriod{Period: p})
+	}
+
+	filters := []common_models.Filter{
+		{Field: "start_date", Operator: "lt", Value: end.Format(time.RFC3339)},
+		{Field: "end_date", Operator: "gte", Value: start.Format(time.RFC3339)},
+	}
+	err := s.RecordService.StreamRecords(ctx, ModuleName, filters, nil, renewalPageSize, userID, func(batch []map[string]any) error {
+		for _, rec := range batch {
+			switch rec["status"] {
+			case StatusActive, StatusRenewed, StatusExpired:
+			default:
+				continue
+			}
+			cStart, ok1 := dateValue(rec, "start_date")
+			cEnd, ok2 := dateValue(rec, "end_date")
+			if !ok1 || !ok2 || cEnd.Before(cStart) {
+				continue
+			}
+			cEnd = cEnd.Add(day) // end_date is the last day of the term
+			value := numberValue(rec, "value")
+			perDay := value / (cEnd.Sub(cStart).Hours() / 24)
+
+			if !cStart.Before(start) && cStart.Before(end) {
+				summary.Booked += value
+			}
+			summary.Deferred += perDay * overlapDays(cStart, cEnd, end, cEnd)
+			for i := range summary.Periods {
+				p := &summary.Periods[i]
+				pEnd := nextPeriod(p.Period, interval)
+				if pEnd.After(end) {
+					pEnd = end
+				}
+				pStart := p.Period
+				if pStart.Before(start) {
+					pStart = start
+				}
+				if d := overlapDays(cStart, cEnd, pStart, pEnd); d > 0 {
+					p.Recognized += perDay * d
+					p.Contracts++
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range summary.Periods {
+		summary.Recognized += summary.Periods[i].Recognized
+		summary.Periods[i].Recognized = round2(summary.Periods[i].Recognized)
+	}
+	summary.Recognized = round2(summary.Recognized)
+	summary.Booked = round2(summary.Booked)
+	summary.Deferred = round2(summary.Deferred)
+	return summary, nil
+}
+
+// RunRenewals walks active contracts ending within MaxReminderDays. Ended
+// contracts are renewed when auto_renew is set and expired otherwise; the
+// rest get a renewal task for their owner once inside their reminder window.
+func (s *ContractServiceImpl) RunRenewals(ctx context.Context) error {
+	now := time.Now()
+	var lastID primitive.ObjectID
+	for {
+		contracts, err := s.ContractRepo.ListActiveEndingBefore(ctx, now.AddDate(0, 0, MaxReminderDays), lastID, renewalPageSize)
+		if err != nil {
+			return err
+		}
+		for _, c := range contracts {
+			lastID = c.ID
+			tenantCtx := context.WithValue(ctx, common_models.TenantIDKey, c.TenantID.Hex())
+			rec := make(map[string]interface{}, len(c.Data)+1)
+			for k, v := range c.Data {
+				rec[k] = v
+			}
+			rec["_id"] = c.ID
+
+			end, ok := dateValue(rec, "end_date")
+			if !ok {
+				continue
+			}
+			if !end.After(now) {
+				if err := s.expire(tenantCtx, c, rec); err != nil {
+					log.Printf("contracts: ending %s failed: %v", c.ID.Hex(), err)
+				}
+				continue
+			}
+
+			reminderDays := int(numberValue(rec, "renewal_reminder_days"))
+			if reminderDays <= 0 {
+				reminderDays = DefaultReminderDays
+			}
+			if now.Before(end.AddDate(0, 0, -reminderDays)) {
+				continue
+			}
+			claimed, err := s.ReminderRepo.Claim(ctx, &Reminder{TenantID: c.TenantID, ContractID: c.ID, EndDate: end})
+			if err != nil {
+				return err
+			}
+			if claimed {
+				s.remind(tenantCtx, c, rec, end)
+			}
+		}
+		if len(contracts) < renewalPageSize {
+			return nil
+		}
+	}
+}
+
+func (s *ContractServiceImpl) product(ctx context.Context, moduleName string) common_models.Product {
+	if m, err := s.ModuleRepo.FindByName(ctx, moduleName); err == nil && m.Product != "" {
+		return m.Product
+	}
+	return common_models.ProductCRM
+}
+
+// expire renews an ended auto-renew contract or marks it expired
+func (s *ContractServiceImpl) expire(ctx context.Context, c common_models.EntityRecord, rec map[string]interface{}) error {
+	if autoRenew, _ := rec["auto_renew"].(bool); !autoRenew {
+		return s.RecordRepo.Update(ctx, ModuleName, c.ID.Hex(), map[string]interface{}{"status": StatusExpired})
+	}
+
+	data, err := successor(rec)
+	if err != nil {
+		_ = s.RecordRepo.Update(ctx, ModuleName, c.ID.Hex(), map[string]interface{}{"status": StatusExpired})
+		return err
+	}
+	created, err := s.RecordRepo.Create(ctx, ModuleName, s.product(ctx, ModuleName), data)
+	if err != nil {
+		return err
+	}
+	newID, _ := created.(primitive.ObjectID)
+
+	items, err := s.RecordRepo.List(ctx, ItemsModuleName, map[string]any{"contract": c.ID}, nil, 1000, 0, "", 0)
+	if err != nil {
+		return err
+	}
+	itemProduct := s.product(ctx, ItemsModuleName)
+	for _, item := range items {
+		data := copyData(item)
+		data["contract"] = newID
+		if _, err := s.RecordRepo.Create(ctx, ItemsModuleName, itemProduct, data); err != nil {
+			return err
+		}
+	}
+	return s.RecordRepo.Update(ctx, ModuleName, c.ID.Hex(), map[string]interface{}{"status": StatusRenewed, "renewal_stage": "won"})
+}
+
+// remind creates a renewal task for the contract owner and notifies them
+func (s *ContractServiceImpl) remind(ctx context.Context, c common_models.EntityRecord, rec map[string]interface{}, end time.Time) {
+	name, _ := rec["name"].(string)
+	subject := fmt.Sprintf("Renew contract %s", name)
+	description := fmt.Sprintf("Contract %s ends on %s.", name, end.Format("2006-01-02"))
+	if autoRenew, _ := rec["auto_renew"].(bool); autoRenew {
+		description += " It renews automatically unless cancelled."
+	}
+
+	task := map[string]interface{}{
+		"subject":        subject,
+		"description":    description,
+		"status":         "pending",
+		"due_date":       end,
+		"related_module": ModuleName,
+		"related_id":     c.ID.Hex(),
+		"created_at":     time.Now(),
+	}
+	owner, hasOwner := rec["owner"].(primitive.ObjectID)
+	if hasOwner {
+		task["assigned_to"] = owner.Hex()
+	}
+	if _, err := s.RecordRepo.Create(ctx, "tasks", s.product(ctx, "tasks"), task); err != nil {
+		log.Printf("contracts: renewal task for %s failed: %v", c.ID.Hex(), err)
+	}
+	if hasOwner {
+		_ = s.NotificationService.CreateNotification(ctx, owner, "Contract renewal due", description, notification.NotificationTypeTask, fmt.Sprintf("/dashboard/modules/%s/%s", ModuleName, c.ID.Hex()))
+	}
+}
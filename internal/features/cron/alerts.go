@@ -0,0 +1,109 @@
+package cron_feature
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var alertHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+func validateAlerts(cronJob *CronJob) error {
+	a := cronJob.Alerts
+	if a == nil {
+		return nil
+	}
+	if a.ConsecutiveFailures < 0 || a.MaxDurationSeconds < 0 {
+		return fmt.Errorf("alert thresholds cannot be negative")
+	}
+	if a.WebhookURL != "" {
+		u, err := url.Parse(a.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid alert webhook URL")
+		}
+	}
+	if (a.ConsecutiveFailures > 0 || a.MaxDurationSeconds > 0) && len(a.Emails) == 0 && a.WebhookURL == "" {
+		return fmt.Errorf("alerts need an email address or a webhook URL")
+	}
+	return nil
+}
+
+// checkAlerts raises an alert when the finished run is the Nth failure in a
+// row or went over the duration threshold. A failure streak alerts once, when
+// it reaches the threshold; the next alert needs a success in between.
+func (s *CronServiceImpl) checkAlerts(ctx context.Context, cronJob *CronJob, logEntry *CronJobLog) {
+	a := cronJob.Alerts
+	if a == nil || logEntry.EndTime == nil {
+		return
+	}
+
+	if a.MaxDurationSeconds > 0 {
+		if took := logEntry.EndTime.Sub(logEntry.StartTime); took > time.Duration(a.MaxDurationSeconds)*time.Second {
+			s.sendAlert(ctx, cronJob, logEntry, fmt.Sprintf("took %s, over the %ds threshold", took.Round(time.Second), a.MaxDurationSeconds))
+		}
+	}
+
+	if a.ConsecutiveFailures > 0 && logEntry.Status == "failed" {
+		logs, err := s.repo.GetLogs(ctx, cronJob.ID.Hex(), a.ConsecutiveFailures+50)
+		if err != nil {
+			log.Printf("Failed to load logs for cron job alert %s: %v", cronJob.ID.Hex(), err)
+			return
+		}
+		streak := 0
+		for _, l := range logs {
+			if l.Status == "skipped" || l.Status == "running" {
+				continue
+			}
+			if l.Status != "failed" {
+				break
+			}
+			streak++
+		}
+		if streak == a.ConsecutiveFailures {
+			s.sendAlert(ctx, cronJob, logEntry, fmt.Sprintf("failed %d times in a row; last error: %s", streak, logEntry.Error))
+		}
+	}
+}
+
+func (s *CronServiceImpl) sendAlert(ctx context.Context, cronJob *CronJob, logEntry *CronJobLog, problem string) {
+	subject := fmt.Sprintf("Cron job %q needs attention", cronJob.Name)
+	body := fmt.Sprintf("<p>Cron job <b>%s</b> %s.</p><p>Run started %s on %s.</p>",
+		html.EscapeString(cronJob.Name), html.EscapeString(problem), logEntry.StartTime.UTC().Format(time.RFC1123), logEntry.Instance)
+
+	if len(cronJob.Alerts.Emails) > 0 && s.emailService != nil {
+		if err := s.emailService.SendEmail(ctx, cronJob.Alerts.Emails, subject, body); err != nil {
+			log.Printf("Failed to email alert for cron job %s: %v", cronJob.ID.Hex(), err)
+		}
+	}
+
+	if cronJob.Alerts.WebhookURL != "" {
+		payload, _ := json.Marshal(map[string]interface{}{
+			"event":       "cron_job.alert",
+			"cron_job_id": cronJob.ID.Hex(),
+			"name":        cronJob.Name,
+			"problem":     problem,
+			"log":         logEntry,
+		})
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, cronJob.Alerts.WebhookURL, bytes.NewReader(payload))
+		if err != nil {
+			log.Printf("Failed to build alert webhook for cron job %s: %v", cronJob.ID.Hex(), err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := alertHTTPClient.Do(req)
+		if err != nil {
+			log.Printf("Alert webhook for cron job %s failed: %v", cronJob.ID.Hex(), err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			log.Printf("Alert webhook for cron job %s returned %s", cronJob.ID.Hex(), resp.Status)
+		}
+	}
+}
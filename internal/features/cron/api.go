@@ -31,10 +31,15 @@ func (h *CronApi) Setup(app *fiber.App) {
 
 	cronJobs.Post("/", middleware.RequirePermission(h.roleService, "automation", "create"), h.cronController.CreateCronJob)
 	cronJobs.Get("/", middleware.RequirePermission(h.roleService, "automation", "read"), h.cronController.ListCronJobs)
+	cronJobs.Get("/stats", middleware.RequirePermission(h.roleService, "automation", "read"), h.cronController.GetCronJobStats)
 	cronJobs.Get("/:id", middleware.RequirePermission(h.roleService, "automation", "read"), h.cronController.GetCronJob)
 	cronJobs.Put("/:id", middleware.RequirePermission(h.roleService, "automation", "update"), h.cronController.UpdateCronJob)
 	cronJobs.Delete("/:id", middleware.RequirePermission(h.roleService, "automation", "delete"), h.cronController.DeleteCronJob)
 
 	cronJobs.Post("/:id/execute", middleware.RequirePermission(h.roleService, "automation", "update"), h.cronController.ExecuteCronJob)
+	cronJobs.Post("/:id/run", middleware.RequirePermission(h.roleService, "automation", "update"), h.cronController.RunCronJob)
+	cronJobs.Post("/:id/pause", middleware.RequirePermission(h.roleService, "automation", "update"), h.cronController.PauseCronJob)
+	cronJobs.Post("/:id/resume", middleware.RequirePermission(h.roleService, "automation", "update"), h.cronController.ResumeCronJob)
 	cronJobs.Get("/:id/logs", middleware.RequirePermission(h.roleService, "automation", "read"), h.cronController.GetCronJobLogs)
+	cronJobs.Get("/:id/stats", middleware.RequirePermission(h.roleService, "automation", "read"), h.cronController.GetCronJobStats)
 }
@@ -2,8 +2,11 @@ package cron_feature
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"go-crm/internal/common/session"
+
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -159,21 +162,138 @@ func (c *CronController) DeleteCronJob(ctx *fiber.Ctx) error {
 // @Produce json
 // @Param id path string true "Job ID"
 // @Success 200 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/cron/jobs/{id}/execute [post]
 func (c *CronController) ExecuteCronJob(ctx *fiber.Ctx) error {
 	id := ctx.Params("id")
 
+	userID, _ := session.UserID(ctx.UserContext())
 	ctxt, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	if err := c.Service.ExecuteCronJob(ctxt, id); err != nil {
+	if _, err := c.Service.RunCronJob(ctxt, id, userID, nil); err != nil {
+		if errors.Is(err, ErrJobRunning) {
+			return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	return ctx.JSON(fiber.Map{"message": "Cron job executed successfully"})
 }
 
+// RunCronJob godoc
+// @Summary Run cron job with overrides
+// @Description Run a cron job now, overriding its parameters for this run only
+// @Tags cron
+// @Accept json
+// @Produce json
+// @Param id path string true "Job ID"
+// @Param body body map[string]interface{} false "parameters: overrides for this run"
+// @Success 200 {object} CronJobLog
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/cron/jobs/{id}/run [post]
+func (c *CronController) RunCronJob(ctx *fiber.Ctx) error {
+	var body struct {
+		Parameters map[string]interface{} `json:"parameters"`
+	}
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&body); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+
+	userID, _ := session.UserID(ctx.UserContext())
+	ctxt, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	logEntry, err := c.Service.RunCronJob(ctxt, ctx.Params("id"), userID, body.Parameters)
+	if errors.Is(err, ErrJobRunning) {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	if logEntry == nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	// A failed run still has a log entry carrying the error
+	return ctx.JSON(logEntry)
+}
+
+// PauseCronJob godoc
+// @Summary Pause cron job
+// @Description Stop a cron job firing until it is resumed
+// @Tags cron
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} CronJob
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/cron/jobs/{id}/pause [post]
+func (c *CronController) PauseCronJob(ctx *fiber.Ctx) error {
+	userID, _ := session.UserID(ctx.UserContext())
+	ctxt, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cronJob, err := c.Service.PauseCronJob(ctxt, ctx.Params("id"), userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(cronJob)
+}
+
+// ResumeCronJob godoc
+// @Summary Resume cron job
+// @Description Resume a paused cron job from its next scheduled time
+// @Tags cron
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} CronJob
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/cron/jobs/{id}/resume [post]
+func (c *CronController) ResumeCronJob(ctx *fiber.Ctx) error {
+	ctxt, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cronJob, err := c.Service.ResumeCronJob(ctxt, ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(cronJob)
+}
+
+// GetCronJobStats godoc
+// @Summary Get cron job statistics
+// @Description Success rate, durations and last failure per job over recent days
+// @Tags cron
+// @Produce json
+// @Param days query int false "Days to look back (default 7)"
+// @Success 200 {array} CronJobStats
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/cron/jobs/stats [get]
+func (c *CronController) GetCronJobStats(ctx *fiber.Ctx) error {
+	days := ctx.QueryInt("days", 7)
+	if days <= 0 || days > 365 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "days must be between 1 and 365"})
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	ctxt, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stats, err := c.Service.GetCronJobStats(ctxt, since, ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if ctx.Params("id") != "" {
+		if len(stats) == 0 {
+			return ctx.JSON(fiber.Map{"runs": 0})
+		}
+		return ctx.JSON(stats[0])
+	}
+	return ctx.JSON(stats)
+}
+
 // GetCronJobLogs godoc
 // @Summary Get cron job logs
 // @Description Get execution logs for a cron job
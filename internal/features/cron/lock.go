@@ -0,0 +1,230 @@
+package cron_feature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// leaseTTL bounds how long a crashed instance blocks a job; running
+	// jobs renew the lease well before it lapses
+	leaseTTL        = 2 * time.Minute
+	leaseRenewEvery = 30 * time.Second
+	// queued runs give up if the previous run is still going after queueWait
+	queueWait = time.Hour
+	queuePoll = 5 * time.Second
+	// maxMisfireRuns caps catch-up runs per job for the run_all policy
+	maxMisfireRuns = 24
+)
+
+// ErrJobRunning is returned when a run is refused because another is in
+// progress under the skip policy
+var ErrJobRunning = errors.New("cron job is already running")
+
+// instanceID identifies this process as a lease owner
+var instanceID = func() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d:%s", host, os.Getpid(), primitive.NewObjectID().Hex()[16:])
+}()
+
+func validatePolicies(cronJob *CronJob) error {
+	switch cronJob.ConcurrencyPolicy {
+	case "", ConcurrencySkip, ConcurrencyQueue, ConcurrencyAllow:
+	default:
+		return fmt.Errorf("unknown concurrency policy %q", cronJob.ConcurrencyPolicy)
+	}
+	switch cronJob.MisfirePolicy {
+	case "", MisfireSkip, MisfireRunOnce, MisfireRunAll:
+	default:
+		return fmt.Errorf("unknown misfire policy %q", cronJob.MisfirePolicy)
+	}
+	return nil
+}
+
+func jobKey(cronJob *CronJob) string {
+	return "job:" + cronJob.ID.Hex()
+}
+
+func systemJobKey(name string) string {
+	return "system:" + name
+}
+
+// leaseExpiry is when a lease taken or renewed at now lapses
+func leaseExpiry(now time.Time, ttl time.Duration) time.Time {
+	return now.Add(ttl)
+}
+
+// leaseFree reports whether owner may take a lease that holder has until
+// the given time: it is unset, has lapsed, or is owner's already. A lease
+// is still live at the instant it expires.
+func leaseFree(holder string, until time.Time, owner string, now time.Time) bool {
+	return until.IsZero() || until.Before(now) || holder == owner
+}
+
+// runTick runs a scheduled tick if this instance is the first to claim it.
+// Every instance fires the same ticks; the claim makes one of them run it.
+func (s *CronServiceImpl) runTick(ctx context.Context, key string, tick time.Time, policy ConcurrencyPolicy, run func(ctx context.Context) error) (bool, error) {
+	claimed, err := s.repo.ClaimTick(ctx, key, tick)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim tick: %w", err)
+	}
+	if !claimed {
+		return false, nil
+	}
+	return true, s.runExclusive(ctx, key, policy, run)
+}
+
+// runExclusive applies the concurrency policy across instances, holding a
+// renewed lease for the length of the run
+func (s *CronServiceImpl) runExclusive(ctx context.Context, key string, policy ConcurrencyPolicy, run func(ctx context.Context) error) error {
+	if policy == ConcurrencyAllow {
+		return run(ctx)
+	}
+
+	deadline := time.Now().Add(queueWait)
+	for {
+		acquired, err := s.repo.AcquireLease(ctx, key, instanceID, leaseTTL)
+		if err != nil {
+			return fmt.Errorf("failed to acquire lease: %w", err)
+		}
+		if acquired {
+			break
+		}
+		if policy != ConcurrencyQueue || time.Now().After(deadline) {
+			return ErrJobRunning
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(queuePoll):
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		until := leaseExpiry(time.Now(), leaseTTL)
+		ticker := time.NewTicker(leaseRenewEvery)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				now := time.Now()
+				if err := s.repo.RenewLease(context.Background(), key, instanceID, leaseTTL); err != nil {
+					log.Printf("Failed to renew cron lease %s: %v", key, err)
+					if leaseFree(instanceID, until, "", now) {
+						log.Printf("Cron lease %s lapsed; another instance may start the same job", key)
+					}
+					continue
+				}
+				until = leaseExpiry(now, leaseTTL)
+			}
+		}
+	}()
+	defer func() {
+		close(done)
+		if err := s.repo.ReleaseLease(context.Background(), key, instanceID); err != nil {
+			log.Printf("Failed to release cron lease %s: %v", key, err)
+		}
+	}()
+
+	return run(ctx)
+}
+
+// runScheduledJob runs a stored job for a tick, logging ticks dropped by
+// the skip policy
+func (s *CronServiceImpl) runScheduledJob(ctx context.Context, cronJob *CronJob, tick time.Time, trigger string) {
+	_, err := s.runTick(ctx, jobKey(cronJob), tick, cronJob.ConcurrencyPolicy, func(ctx context.Context) error {
+		_, err := s.executeCronJobInternal(ctx, cronJob, jobRun{trigger: trigger})
+		return err
+	})
+	if errors.Is(err, ErrJobRunning) {
+		s.logSkipped(ctx, cronJob, "previous run still in progress")
+		return
+	}
+	if err != nil {
+		log.Printf("Cron job %s did not run: %v", cronJob.ID.Hex(), err)
+	}
+}
+
+func (s *CronServiceImpl) logSkipped(ctx context.Context, cronJob *CronJob, reason string) {
+	now := time.Now()
+	if err := s.repo.CreateLog(ctx, &CronJobLog{
+		CronJobID:   cronJob.ID,
+		CronJobName: cronJob.Name,
+		StartTime:   now,
+		EndTime:     &now,
+		Status:      "skipped",
+		Error:       reason,
+		Instance:    instanceID,
+		Trigger:     TriggerSchedule,
+	}); err != nil {
+		log.Printf("Failed to log skipped run of cron job %s: %v", cronJob.ID.Hex(), err)
+	}
+}
+
+// catchUpMisfires runs ticks missed while no instance was up, according to
+// each job's misfire policy. Ticks another instance already handled fail
+// to claim, so starting several instances at once runs each tick once.
+func (s *CronServiceImpl) catchUpMisfires(ctx context.Context, cronJobs []CronJob) {
+	now := time.Now()
+	for i := range cronJobs {
+		cronJob := &cronJobs[i]
+		missed := misfireRuns(cronJob, now)
+		if len(missed) == 0 {
+			continue
+		}
+
+		log.Printf("Cron job %s missed %d run(s); catching up", cronJob.Name, len(missed))
+		for _, tick := range missed {
+			s.runScheduledJob(ctx, cronJob, tick, TriggerMisfire)
+		}
+	}
+}
+
+// misfireRuns picks the missed ticks of a job to run at now: none under the
+// skip policy, the latest under run_once and at most maxMisfireRuns of the
+// latest under run_all. A tick due exactly at now is left to the scheduler.
+func misfireRuns(cronJob *CronJob, now time.Time) []time.Time {
+	if cronJob.Paused || cronJob.NextRun == nil || !cronJob.NextRun.Before(now) {
+		return nil
+	}
+	if cronJob.MisfirePolicy != MisfireRunOnce && cronJob.MisfirePolicy != MisfireRunAll {
+		return nil
+	}
+	schedule, err := cron.ParseStandard(scheduleSpec(cronJob))
+	if err != nil {
+		return nil
+	}
+
+	missed := missedTicks(schedule, *cronJob.NextRun, now)
+	if cronJob.MisfirePolicy == MisfireRunOnce {
+		missed = missed[len(missed)-1:]
+	}
+	if len(missed) > maxMisfireRuns {
+		missed = missed[len(missed)-maxMisfireRuns:]
+	}
+	return missed
+}
+
+// missedTicks lists the scheduled times from first, the stored next run, up
+// to now
+func missedTicks(schedule cron.Schedule, first, now time.Time) []time.Time {
+	ticks := []time.Time{first}
+	for t := schedule.Next(first); t.Before(now); t = schedule.Next(t) {
+		ticks = append(ticks, t)
+		// Keep only the most recent runs of very frequent schedules
+		if len(ticks) > 2*maxMisfireRuns {
+			ticks = ticks[len(ticks)-maxMisfireRuns:]
+		}
+	}
+	return ticks
+}
@@ -0,0 +1,127 @@
+package cron_feature
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// every lists n times from start, step apart
+func every(start time.Time, step time.Duration, n int) []time.Time {
+	ticks := make([]time.Time, n)
+	for i := range ticks {
+		ticks[i] = start.Add(time.Duration(i) * step)
+	}
+	return ticks
+}
+
+func TestMisfireRuns(t *testing.T) {
+	next := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	hourly := func(policy MisfirePolicy) CronJob {
+		return CronJob{Schedule: "0 * * * *", Timezone: "UTC", NextRun: &next, MisfirePolicy: policy}
+	}
+	paused := hourly(MisfireRunAll)
+	paused.Paused = true
+	notScheduled := hourly(MisfireRunAll)
+	notScheduled.NextRun = nil
+	invalid := hourly(MisfireRunAll)
+	invalid.Schedule = "every hour"
+
+	fiveMinutes := hourly(MisfireRunAll)
+	fiveMinutes.Schedule = "*/5 * * * *"
+	everyMinute := hourly(MisfireRunOnce)
+	everyMinute.Schedule = "* * * * *"
+
+	// 09:00 in New York is 14:00 UTC before the clocks go forward on the
+	// 10th and 13:00 UTC after
+	nyNext := time.Date(2024, 3, 9, 14, 0, 0, 0, time.UTC)
+	newYork := CronJob{Schedule: "0 9 * * *", Timezone: "America/New_York", NextRun: &nyNext, MisfirePolicy: MisfireRunAll}
+
+	tests := []struct {
+		name string
+		job  CronJob
+		now  time.Time
+		want []time.Time
+	}{
+		{name: "not scheduled", job: notScheduled, now: next.Add(5 * time.Hour)},
+		{name: "paused", job: paused, now: next.Add(5 * time.Hour)},
+		{name: "next run still ahead", job: hourly(MisfireRunAll), now: next.Add(-time.Second)},
+		{name: "tick exactly on the boundary", job: hourly(MisfireRunAll), now: next},
+		{name: "just past the boundary", job: hourly(MisfireRunAll), now: next.Add(time.Second), want: []time.Time{next}},
+		{name: "skip after downtime", job: hourly(MisfireSkip), now: next.Add(5*time.Hour + 30*time.Minute)},
+		{name: "no policy after downtime", job: hourly(""), now: next.Add(5*time.Hour + 30*time.Minute)},
+		{name: "run once after downtime", job: hourly(MisfireRunOnce), now: next.Add(5*time.Hour + 30*time.Minute), want: []time.Time{next.Add(5 * time.Hour)}},
+		{name: "run all after downtime", job: hourly(MisfireRunAll), now: next.Add(5*time.Hour + 30*time.Minute), want: every(next, time.Hour, 6)},
+		{name: "run all leaves a tick due now", job: hourly(MisfireRunAll), now: next.Add(5 * time.Hour), want: every(next, time.Hour, 5)},
+		{
+			name: "run all keeps the latest after long downtime",
+			job:  fiveMinutes,
+			now:  next.Add(72*time.Hour + 2*time.Minute),
+			want: every(next.Add(72*time.Hour-(maxMisfireRuns-1)*5*time.Minute), 5*time.Minute, maxMisfireRuns),
+		},
+		{name: "run once after long downtime", job: everyMinute, now: next.Add(72*time.Hour + 30*time.Second), want: []time.Time{next.Add(72 * time.Hour)}},
+		{
+			name: "run all across a daylight saving change",
+			job:  newYork,
+			now:  time.Date(2024, 3, 12, 12, 0, 0, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2024, 3, 9, 14, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 10, 13, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 11, 13, 0, 0, 0, time.UTC),
+			},
+		},
+		{name: "invalid schedule", job: invalid, now: next.Add(5 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := misfireRuns(&tt.job, tt.now)
+			for i := range got {
+				got[i] = got[i].UTC()
+			}
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("misfireRuns = %v\nwant           %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeaseFree(t *testing.T) {
+	acquired := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	until := leaseExpiry(acquired, leaseTTL)
+
+	tests := []struct {
+		name   string
+		holder string
+		until  time.Time
+		now    time.Time
+		want   bool
+	}{
+		{name: "never taken", now: acquired, want: true},
+		{name: "live lease of another instance", holder: "b", until: until, now: acquired.Add(time.Minute), want: false},
+		{name: "expires on the boundary", holder: "b", until: until, now: acquired.Add(leaseTTL), want: false},
+		{name: "expired after a crash", holder: "b", until: until, now: acquired.Add(leaseTTL + time.Nanosecond), want: true},
+		{name: "own live lease", holder: "a", until: until, now: acquired.Add(time.Minute), want: true},
+		{name: "own expired lease", holder: "a", until: until, now: acquired.Add(time.Hour), want: true},
+		{
+			name:   "renewed on schedule outlives the first expiry",
+			holder: "b",
+			until:  leaseExpiry(acquired.Add(4*leaseRenewEvery), leaseTTL),
+			now:    acquired.Add(leaseTTL + time.Second),
+			want:   false,
+		},
+		{name: "live after three missed renewals", holder: "b", until: until, now: acquired.Add(3 * leaseRenewEvery), want: false},
+		{name: "expired once renewals stop for the TTL", holder: "b", until: until, now: acquired.Add(4*leaseRenewEvery + time.Second), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := leaseFree(tt.holder, tt.until, "a", tt.now); got != tt.want {
+				t.Errorf("leaseFree = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
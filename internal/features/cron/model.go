@@ -19,16 +19,19 @@ const (
 type ActionType string
 
 const (
-	ActionSendEmail        ActionType = "send_email"
-	ActionCreateTask       ActionType = "create_task"
-	ActionUpdateField      ActionType = "update_field"
-	ActionWebhook          ActionType = "webhook"
-	ActionRunScript        ActionType = "run_script"
-	ActionSendNotification ActionType = "send_notification"
-	ActionSendSMS          ActionType = "send_sms"
-	ActionGeneratePDF      ActionType = "generate_pdf"
-	ActionDataSync         ActionType = "data_sync"
-	ActionSendReport       ActionType = "send_report"
+	ActionSendEmail         ActionType = "send_email"
+	ActionCreateTask        ActionType = "create_task"
+	ActionUpdateField       ActionType = "update_field"
+	ActionWebhook           ActionType = "webhook"
+	ActionRunScript         ActionType = "run_script"
+	ActionSendNotification  ActionType = "send_notification"
+	ActionSendSMS           ActionType = "send_sms"
+	ActionGeneratePDF       ActionType = "generate_pdf"
+	ActionDataSync          ActionType = "data_sync"
+	ActionSendReport        ActionType = "send_report"
+	ActionMarketingSync     ActionType = "marketing_sync"
+	ActionSFTPExchange      ActionType = "sftp_exchange"
+	ActionDashboardSnapshot ActionType = "dashboard_snapshot"
 )
 
 type RuleCondition struct {
@@ -42,12 +45,32 @@ type RuleAction struct {
 	Config map[string]interface{} `json:"config" bson:"config"`
 }
 
+// ConcurrencyPolicy decides what happens when a job is due while a previous
+// run, on any instance, is still going
+type ConcurrencyPolicy string
+
+const (
+	ConcurrencySkip  ConcurrencyPolicy = "skip"  // Drop the tick (default)
+	ConcurrencyQueue ConcurrencyPolicy = "queue" // Run once the previous run ends
+	ConcurrencyAllow ConcurrencyPolicy = "allow" // Run alongside it
+)
+
+// MisfirePolicy decides what happens to runs missed while no instance was up
+type MisfirePolicy string
+
+const (
+	MisfireSkip    MisfirePolicy = "skip"     // Wait for the next tick (default)
+	MisfireRunOnce MisfirePolicy = "run_once" // Run once on startup
+	MisfireRunAll  MisfirePolicy = "run_all"  // Run once per missed tick, up to maxMisfireRuns
+)
+
 // CronJob represents a scheduled automation job
 type CronJob struct {
 	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	Name        string             `json:"name" bson:"name"`
 	Description string             `json:"description,omitempty" bson:"description,omitempty"`
 	Schedule    string             `json:"schedule" bson:"schedule"`
+	Timezone    string             `json:"timezone,omitempty" bson:"timezone"` // IANA zone the schedule runs in; empty is UTC
 	ModuleID    string             `json:"module_id,omitempty" bson:"module_id,omitempty"`
 	Conditions  []RuleCondition    `json:"conditions,omitempty" bson:"conditions,omitempty"`
 	Actions     []RuleAction       `json:"actions" bson:"actions"`
@@ -57,8 +80,54 @@ type CronJob struct {
 	CreatedBy   primitive.ObjectID `json:"created_by" bson:"created_by"`
 	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+
+	ConcurrencyPolicy ConcurrencyPolicy `json:"concurrency_policy,omitempty" bson:"concurrency_policy,omitempty"`
+	MisfirePolicy     MisfirePolicy     `json:"misfire_policy,omitempty" bson:"misfire_policy,omitempty"`
+
+	// Parameters are substituted for {{param.name}} in action configs and
+	// condition values; manual runs can override them
+	Parameters map[string]interface{} `json:"parameters,omitempty" bson:"parameters,omitempty"`
+
+	Alerts *JobAlerts `json:"alerts,omitempty" bson:"alerts,omitempty"`
+
+	// A paused job keeps its schedule but does not fire until resumed
+	Paused   bool               `json:"paused" bson:"paused"`
+	PausedAt *time.Time         `json:"paused_at,omitempty" bson:"paused_at,omitempty"`
+	PausedBy primitive.ObjectID `json:"paused_by,omitempty" bson:"paused_by,omitempty"`
+}
+
+// JobAlerts notifies people when a job keeps failing or runs too long.
+// Thresholds left at 0 are off.
+type JobAlerts struct {
+	ConsecutiveFailures int      `json:"consecutive_failures,omitempty" bson:"consecutive_failures,omitempty"`
+	MaxDurationSeconds  int      `json:"max_duration_seconds,omitempty" bson:"max_duration_seconds,omitempty"`
+	Emails              []string `json:"emails,omitempty" bson:"emails,omitempty"`
+	WebhookURL          string   `json:"webhook_url,omitempty" bson:"webhook_url,omitempty"`
+}
+
+// CronJobStats summarises a job's runs over a period
+type CronJobStats struct {
+	CronJobID         primitive.ObjectID `json:"cron_job_id" bson:"_id"`
+	CronJobName       string             `json:"cron_job_name" bson:"cron_job_name"`
+	Runs              int                `json:"runs" bson:"runs"` // Every logged run, skipped ones included
+	Successes         int                `json:"successes" bson:"successes"`
+	Failures          int                `json:"failures" bson:"failures"`
+	Skipped           int                `json:"skipped" bson:"skipped"`
+	SuccessRate       float64            `json:"success_rate" bson:"-"` // Successes over finished runs, 0-1
+	AvgDurationMs     float64            `json:"avg_duration_ms" bson:"avg_duration_ms"`
+	MaxDurationMs     float64            `json:"max_duration_ms" bson:"max_duration_ms"`
+	LastRun           *time.Time         `json:"last_run,omitempty" bson:"last_run,omitempty"`
+	LastFailure       *time.Time         `json:"last_failure,omitempty" bson:"-"`
+	LastFailureReason string             `json:"last_failure_reason,omitempty" bson:"-"`
 }
 
+// What started a run
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+	TriggerMisfire  = "misfire" // Catch-up run for a missed tick
+)
+
 // CronJobLog represents a single execution of a cron job
 type CronJobLog struct {
 	ID               primitive.ObjectID `json:"id" bson:"_id,omitempty"`
@@ -66,10 +135,15 @@ type CronJobLog struct {
 	CronJobName      string             `json:"cron_job_name" bson:"cron_job_name"`
 	StartTime        time.Time          `json:"start_time" bson:"start_time"`
 	EndTime          *time.Time         `json:"end_time,omitempty" bson:"end_time,omitempty"`
-	Status           string             `json:"status" bson:"status"` // "success", "failed", "running"
+	Status           string             `json:"status" bson:"status"` // "success", "failed", "running", "skipped"
 	RecordsProcessed int                `json:"records_processed" bson:"records_processed"`
 	RecordsAffected  int                `json:"records_affected" bson:"records_affected"`
 	Error            string             `json:"error,omitempty" bson:"error,omitempty"`
 	Output           string             `json:"output,omitempty" bson:"output,omitempty"`
 	CreatedAt        time.Time          `json:"created_at" bson:"created_at"`
+
+	Instance    string                 `json:"instance,omitempty" bson:"instance,omitempty"` // Host that ran it
+	Trigger     string                 `json:"trigger,omitempty" bson:"trigger,omitempty"`
+	TriggeredBy primitive.ObjectID     `json:"triggered_by,omitempty" bson:"triggered_by,omitempty"` // User behind a manual run
+	Overrides   map[string]interface{} `json:"overrides,omitempty" bson:"overrides,omitempty"`       // Parameters changed for this run
 }
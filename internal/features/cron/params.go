@@ -0,0 +1,85 @@
+package cron_feature
+
+import (
+	"fmt"
+	"regexp"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var paramPattern = regexp.MustCompile(`\{\{\s*param\.([A-Za-z0-9_]+)\s*\}\}`)
+
+// withParameters returns a copy of the job with {{param.name}} replaced in
+// condition values and action configs. Overrides take precedence over the
+// job's stored parameters. A value that is only a placeholder takes the
+// parameter as is, so numbers and dates keep their type.
+func withParameters(cronJob *CronJob, overrides map[string]interface{}) *CronJob {
+	params := make(map[string]interface{}, len(cronJob.Parameters)+len(overrides))
+	for k, v := range cronJob.Parameters {
+		params[k] = v
+	}
+	for k, v := range overrides {
+		params[k] = v
+	}
+	if len(params) == 0 {
+		return cronJob
+	}
+
+	job := *cronJob
+	job.Conditions = make([]RuleCondition, len(cronJob.Conditions))
+	for i, c := range cronJob.Conditions {
+		c.Value = substituteParams(c.Value, params)
+		job.Conditions[i] = c
+	}
+	job.Actions = make([]RuleAction, len(cronJob.Actions))
+	for i, a := range cronJob.Actions {
+		config, _ := substituteParams(a.Config, params).(map[string]interface{})
+		job.Actions[i] = RuleAction{Type: a.Type, Config: config}
+	}
+	return &job
+}
+
+func substituteParams(value interface{}, params map[string]interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if m := paramPattern.FindStringSubmatch(v); m != nil && m[0] == v {
+			if p, ok := params[m[1]]; ok {
+				return p
+			}
+			return v
+		}
+		return paramPattern.ReplaceAllStringFunc(v, func(match string) string {
+			name := paramPattern.FindStringSubmatch(match)[1]
+			if p, ok := params[name]; ok {
+				return fmt.Sprintf("%v", p)
+			}
+			return match
+		})
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = substituteParams(item, params)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = substituteParams(item, params)
+		}
+		return out
+	// Nested documents and arrays decoded from Mongo
+	case primitive.D:
+		out := make(primitive.D, len(v))
+		for i, e := range v {
+			out[i] = primitive.E{Key: e.Key, Value: substituteParams(e.Value, params)}
+		}
+		return out
+	case primitive.A:
+		out := make(primitive.A, len(v))
+		for i, item := range v {
+			out[i] = substituteParams(item, params)
+		}
+		return out
+	}
+	return value
+}
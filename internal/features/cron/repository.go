@@ -19,22 +19,38 @@ type CronRepository interface {
 	Delete(ctx context.Context, id string) error
 	GetActive(ctx context.Context) ([]CronJob, error)
 	UpdateLastRun(ctx context.Context, id string, lastRun time.Time, nextRun *time.Time) error
+	// SetPaused records who paused the job, or clears the pause; nextRun is
+	// stored when given
+	SetPaused(ctx context.Context, id string, paused bool, pausedBy primitive.ObjectID, nextRun *time.Time) error
 
 	// Log operations
 	CreateLog(ctx context.Context, log *CronJobLog) error
 	GetLogs(ctx context.Context, cronJobID string, limit int) ([]CronJobLog, error)
 	UpdateLog(ctx context.Context, log *CronJobLog) error
+	// Stats aggregates the logs started since the given time, per job, or
+	// for one job when cronJobID is set
+	Stats(ctx context.Context, since time.Time, cronJobID *primitive.ObjectID) ([]CronJobStats, error)
+
+	// Locks shared by all instances, keyed by job.
+	// ClaimTick succeeds for one caller per key and scheduled time.
+	ClaimTick(ctx context.Context, key string, tick time.Time) (bool, error)
+	// AcquireLease succeeds when no other owner holds an unexpired lease
+	AcquireLease(ctx context.Context, key, owner string, ttl time.Duration) (bool, error)
+	RenewLease(ctx context.Context, key, owner string, ttl time.Duration) error
+	ReleaseLease(ctx context.Context, key, owner string) error
 }
 
 type CronRepositoryImpl struct {
-	collection    *mongo.Collection
-	logCollection *mongo.Collection
+	collection     *mongo.Collection
+	logCollection  *mongo.Collection
+	lockCollection *mongo.Collection
 }
 
 func NewCronRepository(db *database.MongodbDB) CronRepository {
 	return &CronRepositoryImpl{
-		collection:    db.DB.Collection("cron_jobs"),
-		logCollection: db.DB.Collection("cron_job_logs"),
+		collection:     db.DB.Collection("cron_jobs"),
+		logCollection:  db.DB.Collection("cron_job_logs"),
+		lockCollection: db.DB.Collection("cron_locks"),
 	}
 }
 
@@ -126,6 +142,28 @@ func (r *CronRepositoryImpl) UpdateLastRun(ctx context.Context, id string, lastR
 	return err
 }
 
+func (r *CronRepositoryImpl) SetPaused(ctx context.Context, id string, paused bool, pausedBy primitive.ObjectID, nextRun *time.Time) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	set := bson.M{"paused": paused, "updated_at": time.Now()}
+	if nextRun != nil {
+		set["next_run"] = nextRun
+	}
+	update := bson.M{"$set": set}
+	if paused {
+		set["paused_at"] = time.Now()
+		set["paused_by"] = pausedBy
+	} else {
+		update["$unset"] = bson.M{"paused_at": "", "paused_by": ""}
+	}
+
+	_, err = r.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	return err
+}
+
 func (r *CronRepositoryImpl) CreateLog(ctx context.Context, log *CronJobLog) error {
 	log.ID = primitive.NewObjectID()
 	log.CreatedAt = time.Now()
@@ -170,3 +208,131 @@ func (r *CronRepositoryImpl) UpdateLog(ctx context.Context, log *CronJobLog) err
 	_, err := r.logCollection.UpdateOne(ctx, filter, update)
 	return err
 }
+
+func (r *CronRepositoryImpl) Stats(ctx context.Context, since time.Time, cronJobID *primitive.ObjectID) ([]CronJobStats, error) {
+	match := bson.M{"start_time": bson.M{"$gte": since}}
+	if cronJobID != nil {
+		match["cron_job_id"] = *cronJobID
+	}
+	status := func(value string) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$status", value}}, 1, 0}}}
+	}
+	duration := bson.M{"$cond": bson.A{
+		bson.M{"$and": bson.A{"$end_time", bson.M{"$ne": bson.A{"$status", "skipped"}}}},
+		bson.M{"$subtract": bson.A{"$end_time", "$start_time"}},
+		nil,
+	}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.M{"start_time": 1}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":             "$cron_job_id",
+			"cron_job_name":   bson.M{"$last": "$cron_job_name"},
+			"runs":            bson.M{"$sum": 1},
+			"successes":       status("success"),
+			"failures":        status("failed"),
+			"skipped":         status("skipped"),
+			"avg_duration_ms": bson.M{"$avg": duration},
+			"max_duration_ms": bson.M{"$max": duration},
+			"last_run":        bson.M{"$last": "$start_time"},
+		}}},
+		{{Key: "$sort", Value: bson.M{"cron_job_name": 1}}},
+	}
+	cursor, err := r.logCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	stats := []CronJobStats{}
+	if err := cursor.All(ctx, &stats); err != nil {
+		return nil, err
+	}
+
+	// The most recent failure per job, looked back over all time
+	failureMatch := bson.M{"status": "failed"}
+	if cronJobID != nil {
+		failureMatch["cron_job_id"] = *cronJobID
+	}
+	cursor, err = r.logCollection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: failureMatch}},
+		{{Key: "$sort", Value: bson.M{"start_time": -1}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$cron_job_id",
+			"at":    bson.M{"$first": "$start_time"},
+			"error": bson.M{"$first": "$error"},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var failures []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		At    time.Time          `bson:"at"`
+		Error string             `bson:"error"`
+	}
+	if err := cursor.All(ctx, &failures); err != nil {
+		return nil, err
+	}
+	lastFailure := make(map[primitive.ObjectID]int, len(failures))
+	for i, f := range failures {
+		lastFailure[f.ID] = i
+	}
+
+	for i := range stats {
+		st := &stats[i]
+		if finished := st.Successes + st.Failures; finished > 0 {
+			st.SuccessRate = float64(st.Successes) / float64(finished)
+		}
+		if j, ok := lastFailure[st.CronJobID]; ok {
+			at := failures[j].At
+			st.LastFailure = &at
+			st.LastFailureReason = failures[j].Error
+		}
+	}
+	return stats, nil
+}
+
+func (r *CronRepositoryImpl) ClaimTick(ctx context.Context, key string, tick time.Time) (bool, error) {
+	// Only a lock that has not seen this tick matches; when another instance
+	// got there first the upsert collides on _id
+	filter := bson.M{"_id": key, "$or": []bson.M{
+		{"last_tick": bson.M{"$exists": false}},
+		{"last_tick": bson.M{"$lt": tick}},
+	}}
+	update := bson.M{"$set": bson.M{"last_tick": tick}}
+	_, err := r.lockCollection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *CronRepositoryImpl) AcquireLease(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	// leaseFree as a query, so the check and the takeover are one update
+	filter := bson.M{"_id": key, "$or": []bson.M{
+		{"lease_until": bson.M{"$exists": false}},
+		{"lease_until": bson.M{"$lt": now}},
+		{"lease_owner": owner},
+	}}
+	update := bson.M{"$set": bson.M{"lease_owner": owner, "lease_until": leaseExpiry(now, ttl)}}
+	_, err := r.lockCollection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *CronRepositoryImpl) RenewLease(ctx context.Context, key, owner string, ttl time.Duration) error {
+	_, err := r.lockCollection.UpdateOne(ctx,
+		bson.M{"_id": key, "lease_owner": owner},
+		bson.M{"$set": bson.M{"lease_until": leaseExpiry(time.Now(), ttl)}})
+	return err
+}
+
+func (r *CronRepositoryImpl) ReleaseLease(ctx context.Context, key, owner string) error {
+	_, err := r.lockCollection.UpdateOne(ctx,
+		bson.M{"_id": key, "lease_owner": owner},
+		bson.M{"$unset": bson.M{"lease_owner": "", "lease_until": ""}})
+	return err
+}
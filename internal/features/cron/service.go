@@ -2,11 +2,15 @@ package cron_feature
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	common_models "go-crm/internal/common/models"
 	"go-crm/internal/features/audit"
 	"go-crm/internal/features/automation"
+	"go-crm/internal/features/dashboard"
 	"go-crm/internal/features/email"
+	"go-crm/internal/features/exchange"
+	"go-crm/internal/features/marketing"
 	"go-crm/internal/features/record"
 	sync_feature "go-crm/internal/features/sync"
 	"log"
@@ -14,6 +18,7 @@ import (
 	"time"
 
 	"github.com/robfig/cron/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type CronService interface {
@@ -23,23 +28,45 @@ type CronService interface {
 	UpdateCronJob(ctx context.Context, cronJob *CronJob) error
 	DeleteCronJob(ctx context.Context, id string) error
 	ExecuteCronJob(ctx context.Context, id string) error
+	// RunCronJob runs a job now, with overrides layered over its parameters
+	// for this run only
+	RunCronJob(ctx context.Context, id string, triggeredBy primitive.ObjectID, overrides map[string]interface{}) (*CronJobLog, error)
+	// PauseCronJob stops a job firing without deactivating or deleting it
+	PauseCronJob(ctx context.Context, id string, pausedBy primitive.ObjectID) (*CronJob, error)
+	ResumeCronJob(ctx context.Context, id string) (*CronJob, error)
 	GetCronJobLogs(ctx context.Context, cronJobID string, limit int) ([]CronJobLog, error)
+	// GetCronJobStats summarises runs since the given time, for every job or
+	// the one with cronJobID
+	GetCronJobStats(ctx context.Context, since time.Time, cronJobID string) ([]CronJobStats, error)
 	InitializeScheduler(ctx context.Context) error
 	StopScheduler() error
 	RegisterJob(cronJob *CronJob) error
 	UnregisterJob(id string) error
+	// RegisterSystemJob schedules an in-process job owned by another feature.
+	// System jobs are not stored and start with the scheduler.
+	RegisterSystemJob(name, schedule string, run func(ctx context.Context) error) error
+}
+
+type systemJob struct {
+	name     string
+	schedule string
+	run      func(ctx context.Context) error
 }
 
 type CronServiceImpl struct {
-	repo           CronRepository
-	recordRepo     record.RecordRepository
-	actionExecutor automation.ActionExecutor
-	auditService   audit.AuditService
-	syncService    sync_feature.SyncService
-	emailService   email.EmailService
+	repo             CronRepository
+	recordRepo       record.RecordRepository
+	actionExecutor   automation.ActionExecutor
+	auditService     audit.AuditService
+	syncService      sync_feature.SyncService
+	emailService     email.EmailService
+	marketingService marketing.MarketingService
+	exchangeService  exchange.ExchangeService
+	dashboardService dashboard.DashboardService
 
 	scheduler  *cron.Cron
 	jobEntries map[string]cron.EntryID
+	systemJobs []systemJob
 	mu         sync.RWMutex
 }
 
@@ -50,29 +77,63 @@ func NewCronService(
 	auditService audit.AuditService,
 	syncService sync_feature.SyncService,
 	emailService email.EmailService,
+	marketingService marketing.MarketingService,
+	exchangeService exchange.ExchangeService,
+	dashboardService dashboard.DashboardService,
 ) CronService {
 	return &CronServiceImpl{
-		repo:           repo,
-		recordRepo:     recordRepo,
-		actionExecutor: actionExecutor,
-		auditService:   auditService,
-		syncService:    syncService,
-		emailService:   emailService,
-		jobEntries:     make(map[string]cron.EntryID),
+		repo:             repo,
+		recordRepo:       recordRepo,
+		actionExecutor:   actionExecutor,
+		auditService:     auditService,
+		syncService:      syncService,
+		emailService:     emailService,
+		marketingService: marketingService,
+		exchangeService:  exchangeService,
+		dashboardService: dashboardService,
+		jobEntries:       make(map[string]cron.EntryID),
+	}
+}
+
+// scheduleSpec prefixes the schedule with the job's timezone, so "0 9 * * *"
+// fires at 09:00 where the job owner is rather than on the server clock
+func scheduleSpec(cronJob *CronJob) string {
+	if cronJob.Timezone == "" {
+		return cronJob.Schedule
+	}
+	return "CRON_TZ=" + cronJob.Timezone + " " + cronJob.Schedule
+}
+
+func validateSchedule(cronJob *CronJob) (cron.Schedule, error) {
+	if cronJob.Timezone != "" {
+		if _, err := time.LoadLocation(cronJob.Timezone); err != nil {
+			return nil, fmt.Errorf("unknown timezone %q", cronJob.Timezone)
+		}
+	}
+	schedule, err := cron.ParseStandard(scheduleSpec(cronJob))
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression: %w", err)
+	}
+	if err := validatePolicies(cronJob); err != nil {
+		return nil, err
+	}
+	if err := validateAlerts(cronJob); err != nil {
+		return nil, err
 	}
+	return schedule, nil
 }
 
 func (s *CronServiceImpl) CreateCronJob(ctx context.Context, cronJob *CronJob) error {
-	if _, err := cron.ParseStandard(cronJob.Schedule); err != nil {
-		return fmt.Errorf("invalid cron expression: %w", err)
+	schedule, err := validateSchedule(cronJob)
+	if err != nil {
+		return err
 	}
 
 	now := time.Now()
 	cronJob.CreatedAt = now
 	cronJob.UpdatedAt = now
 
-	schedule, _ := cron.ParseStandard(cronJob.Schedule)
-	nextRun := schedule.Next(now)
+	nextRun := schedule.Next(now).UTC()
 	cronJob.NextRun = &nextRun
 
 	if err := s.repo.Create(ctx, cronJob); err != nil {
@@ -83,7 +144,7 @@ func (s *CronServiceImpl) CreateCronJob(ctx context.Context, cronJob *CronJob) e
 		"cron_job": {New: cronJob},
 	})
 
-	if cronJob.Active && s.scheduler != nil {
+	if cronJob.Active && !cronJob.Paused && s.scheduler != nil {
 		if err := s.RegisterJob(cronJob); err != nil {
 			log.Printf("Failed to register cron job %s: %v", cronJob.ID.Hex(), err)
 		}
@@ -101,15 +162,20 @@ func (s *CronServiceImpl) ListCronJobs(ctx context.Context, filter map[string]in
 }
 
 func (s *CronServiceImpl) UpdateCronJob(ctx context.Context, cronJob *CronJob) error {
-	if _, err := cron.ParseStandard(cronJob.Schedule); err != nil {
-		return fmt.Errorf("invalid cron expression: %w", err)
+	schedule, err := validateSchedule(cronJob)
+	if err != nil {
+		return err
 	}
 
-	schedule, _ := cron.ParseStandard(cronJob.Schedule)
-	nextRun := schedule.Next(time.Now())
+	nextRun := schedule.Next(time.Now()).UTC()
 	cronJob.NextRun = &nextRun
 
 	oldJob, _ := s.GetCronJob(ctx, cronJob.ID.Hex())
+	// Pausing goes through PauseCronJob and ResumeCronJob only
+	cronJob.Paused, cronJob.PausedAt, cronJob.PausedBy = false, nil, primitive.NilObjectID
+	if oldJob != nil {
+		cronJob.Paused, cronJob.PausedAt, cronJob.PausedBy = oldJob.Paused, oldJob.PausedAt, oldJob.PausedBy
+	}
 
 	if err := s.repo.Update(ctx, cronJob); err != nil {
 		return err
@@ -121,7 +187,7 @@ func (s *CronServiceImpl) UpdateCronJob(ctx context.Context, cronJob *CronJob) e
 
 	s.UnregisterJob(cronJob.ID.Hex())
 
-	if cronJob.Active && s.scheduler != nil {
+	if cronJob.Active && !cronJob.Paused && s.scheduler != nil {
 		if err := s.RegisterJob(cronJob); err != nil {
 			log.Printf("Failed to register updated cron job %s: %v", cronJob.ID.Hex(), err)
 		}
@@ -143,18 +209,101 @@ func (s *CronServiceImpl) DeleteCronJob(ctx context.Context, id string) error {
 }
 
 func (s *CronServiceImpl) ExecuteCronJob(ctx context.Context, id string) error {
+	_, err := s.RunCronJob(ctx, id, primitive.NilObjectID, nil)
+	return err
+}
+
+func (s *CronServiceImpl) RunCronJob(ctx context.Context, id string, triggeredBy primitive.ObjectID, overrides map[string]interface{}) (*CronJobLog, error) {
 	cronJob, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if cronJob == nil {
+		return nil, fmt.Errorf("cron job not found")
+	}
+
+	// Manual runs share the lease with scheduled ones
+	var logEntry *CronJobLog
+	err = s.runExclusive(ctx, jobKey(cronJob), cronJob.ConcurrencyPolicy, func(ctx context.Context) error {
+		var runErr error
+		logEntry, runErr = s.executeCronJobInternal(ctx, cronJob, jobRun{
+			trigger:     TriggerManual,
+			triggeredBy: triggeredBy,
+			overrides:   overrides,
+		})
+		return runErr
+	})
+	return logEntry, err
+}
+
+func (s *CronServiceImpl) PauseCronJob(ctx context.Context, id string, pausedBy primitive.ObjectID) (*CronJob, error) {
+	cronJob, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
 	}
 	if cronJob == nil {
-		return fmt.Errorf("cron job not found")
+		return nil, fmt.Errorf("cron job not found")
+	}
+	if cronJob.Paused {
+		return cronJob, nil
+	}
+
+	now := time.Now()
+	if err := s.repo.SetPaused(ctx, id, true, pausedBy, nil); err != nil {
+		return nil, err
+	}
+	s.UnregisterJob(id)
+	cronJob.Paused, cronJob.PausedAt, cronJob.PausedBy = true, &now, pausedBy
+
+	s.auditService.LogChange(ctx, common_models.AuditActionCron, "cron", id, map[string]common_models.Change{
+		"paused": {Old: false, New: true},
+	})
+	return cronJob, nil
+}
+
+// ResumeCronJob picks the schedule up from now; ticks that fell inside the
+// pause are not caught up
+func (s *CronServiceImpl) ResumeCronJob(ctx context.Context, id string) (*CronJob, error) {
+	cronJob, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if cronJob == nil {
+		return nil, fmt.Errorf("cron job not found")
+	}
+	if !cronJob.Paused {
+		return cronJob, nil
+	}
+
+	if schedule, err := cron.ParseStandard(scheduleSpec(cronJob)); err == nil {
+		nextRun := schedule.Next(time.Now()).UTC()
+		cronJob.NextRun = &nextRun
+	}
+	if err := s.repo.SetPaused(ctx, id, false, primitive.NilObjectID, cronJob.NextRun); err != nil {
+		return nil, err
+	}
+	cronJob.Paused, cronJob.PausedAt, cronJob.PausedBy = false, nil, primitive.NilObjectID
+
+	if cronJob.Active && s.scheduler != nil {
+		if err := s.RegisterJob(cronJob); err != nil {
+			log.Printf("Failed to register resumed cron job %s: %v", id, err)
+		}
 	}
 
-	return s.executeCronJobInternal(ctx, cronJob)
+	s.auditService.LogChange(ctx, common_models.AuditActionCron, "cron", id, map[string]common_models.Change{
+		"paused": {Old: true, New: false},
+	})
+	return cronJob, nil
+}
+
+// jobRun describes what started a run and the parameters it overrides
+type jobRun struct {
+	trigger     string
+	triggeredBy primitive.ObjectID
+	overrides   map[string]interface{}
 }
 
-func (s *CronServiceImpl) executeCronJobInternal(ctx context.Context, cronJob *CronJob) error {
+func (s *CronServiceImpl) executeCronJobInternal(ctx context.Context, cronJob *CronJob, run jobRun) (*CronJobLog, error) {
 	startTime := time.Now()
 
 	logEntry := &CronJobLog{
@@ -162,12 +311,18 @@ func (s *CronServiceImpl) executeCronJobInternal(ctx context.Context, cronJob *C
 		CronJobName: cronJob.Name,
 		StartTime:   startTime,
 		Status:      "running",
+		Instance:    instanceID,
+		Trigger:     run.trigger,
+		TriggeredBy: run.triggeredBy,
+		Overrides:   run.overrides,
 	}
 
 	if err := s.repo.CreateLog(ctx, logEntry); err != nil {
 		log.Printf("Failed to create log entry for cron job %s: %v", cronJob.ID.Hex(), err)
 	}
 
+	cronJob = withParameters(cronJob, run.overrides)
+
 	s.auditService.LogChange(ctx, common_models.AuditActionCron, "cron", cronJob.ID.Hex(), map[string]common_models.Change{
 		"status":   {New: "started"},
 		"job_name": {New: cronJob.Name},
@@ -198,6 +353,7 @@ func (s *CronServiceImpl) executeCronJobInternal(ctx context.Context, cronJob *C
 	if err := s.repo.UpdateLog(ctx, logEntry); err != nil {
 		log.Printf("Failed to update log entry for cron job %s: %v", cronJob.ID.Hex(), err)
 	}
+	s.checkAlerts(ctx, cronJob, logEntry)
 
 	auditStatus := "success"
 	if execError != nil {
@@ -209,13 +365,13 @@ func (s *CronServiceImpl) executeCronJobInternal(ctx context.Context, cronJob *C
 		"error":    {New: logEntry.Error},
 	})
 
-	schedule, _ := cron.ParseStandard(cronJob.Schedule)
-	nextRun := schedule.Next(time.Now())
+	schedule, _ := cron.ParseStandard(scheduleSpec(cronJob))
+	nextRun := schedule.Next(time.Now()).UTC()
 	if err := s.repo.UpdateLastRun(ctx, cronJob.ID.Hex(), startTime, &nextRun); err != nil {
 		log.Printf("Failed to update last run for cron job %s: %v", cronJob.ID.Hex(), err)
 	}
 
-	return execError
+	return logEntry, execError
 }
 
 func (s *CronServiceImpl) executeRecordBasedJob(ctx context.Context, cronJob *CronJob) (int, int, error) {
@@ -286,6 +442,33 @@ func (s *CronServiceImpl) executeNonRecordBasedJob(ctx context.Context, cronJob
 				return recordsAffected, err
 			}
 			recordsAffected++
+		case ActionMarketingSync:
+			audienceSyncID, ok := action.Config["audience_sync_id"].(string)
+			if !ok {
+				return recordsAffected, fmt.Errorf("audience_sync_id missing in action config")
+			}
+			if err := s.marketingService.RunScheduled(ctx, audienceSyncID); err != nil {
+				return recordsAffected, err
+			}
+			recordsAffected++
+		case ActionSFTPExchange:
+			exchangeID, ok := action.Config["exchange_id"].(string)
+			if !ok {
+				return recordsAffected, fmt.Errorf("exchange_id missing in action config")
+			}
+			if err := s.exchangeService.RunScheduled(ctx, exchangeID); err != nil {
+				return recordsAffected, err
+			}
+			recordsAffected++
+		case ActionDashboardSnapshot:
+			snapshotID, ok := action.Config["snapshot_id"].(string)
+			if !ok {
+				return recordsAffected, fmt.Errorf("snapshot_id missing in action config")
+			}
+			if err := s.dashboardService.RunScheduled(ctx, snapshotID); err != nil {
+				return recordsAffected, err
+			}
+			recordsAffected++
 		default:
 			log.Printf("Action type %s not supported for non-record based jobs", action.Type)
 		}
@@ -301,21 +484,45 @@ func (s *CronServiceImpl) GetCronJobLogs(ctx context.Context, cronJobID string,
 	return s.repo.GetLogs(ctx, cronJobID, limit)
 }
 
+func (s *CronServiceImpl) GetCronJobStats(ctx context.Context, since time.Time, cronJobID string) ([]CronJobStats, error) {
+	if cronJobID == "" {
+		return s.repo.Stats(ctx, since, nil)
+	}
+	id, err := primitive.ObjectIDFromHex(cronJobID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron job id")
+	}
+	return s.repo.Stats(ctx, since, &id)
+}
+
 func (s *CronServiceImpl) InitializeScheduler(ctx context.Context) error {
 	log.Println("Initializing cron scheduler...")
-	s.scheduler = cron.New()
+	// Jobs without a timezone run on UTC whatever the host's zone is
+	s.scheduler = cron.New(cron.WithLocation(time.UTC))
 	cronJobs, err := s.repo.GetActive(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to load active cron jobs: %w", err)
 	}
 
 	for i := range cronJobs {
+		if cronJobs[i].Paused {
+			continue
+		}
 		if err := s.RegisterJob(&cronJobs[i]); err != nil {
 			log.Printf("Failed to register cron job %s: %v", cronJobs[i].ID.Hex(), err)
 		}
 	}
 
+	s.mu.Lock()
+	for _, job := range s.systemJobs {
+		if err := s.addSystemJob(job); err != nil {
+			log.Printf("Failed to register system job %s: %v", job.name, err)
+		}
+	}
+	s.mu.Unlock()
+
 	s.scheduler.Start()
+	go s.catchUpMisfires(context.Background(), cronJobs)
 	return nil
 }
 
@@ -337,15 +544,18 @@ func (s *CronServiceImpl) RegisterJob(cronJob *CronJob) error {
 
 	cronJobID := cronJob.ID.Hex()
 	jobFunc := func() {
+		// Schedules have minute resolution, so every instance derives the
+		// same tick however far their clocks drift within the minute
+		tick := time.Now().Truncate(time.Minute)
 		ctx := context.Background()
 		latestCronJob, err := s.repo.GetByID(ctx, cronJobID)
-		if err != nil || latestCronJob == nil || !latestCronJob.Active {
+		if err != nil || latestCronJob == nil || !latestCronJob.Active || latestCronJob.Paused {
 			return
 		}
-		s.executeCronJobInternal(ctx, latestCronJob)
+		s.runScheduledJob(ctx, latestCronJob, tick, TriggerSchedule)
 	}
 
-	entryID, err := s.scheduler.AddFunc(cronJob.Schedule, jobFunc)
+	entryID, err := s.scheduler.AddFunc(scheduleSpec(cronJob), jobFunc)
 	if err != nil {
 		return fmt.Errorf("failed to add cron job to scheduler: %w", err)
 	}
@@ -364,3 +574,33 @@ func (s *CronServiceImpl) UnregisterJob(id string) error {
 	}
 	return nil
 }
+
+func (s *CronServiceImpl) RegisterSystemJob(name, schedule string, run func(ctx context.Context) error) error {
+	if _, err := cron.ParseStandard(schedule); err != nil {
+		return fmt.Errorf("invalid schedule for %s: %w", name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job := systemJob{name: name, schedule: schedule, run: run}
+	s.systemJobs = append(s.systemJobs, job)
+	if s.scheduler == nil {
+		// Added when the scheduler starts
+		return nil
+	}
+	return s.addSystemJob(job)
+}
+
+// addSystemJob must be called with s.mu held. System jobs run on one
+// instance per tick and skip a tick rather than overlap a slow run.
+func (s *CronServiceImpl) addSystemJob(job systemJob) error {
+	_, err := s.scheduler.AddFunc(job.schedule, func() {
+		tick := time.Now().Truncate(time.Minute)
+		_, err := s.runTick(context.Background(), systemJobKey(job.name), tick, ConcurrencySkip, job.run)
+		if err != nil && !errors.Is(err, ErrJobRunning) {
+			log.Printf("System job %s failed: %v", job.name, err)
+		}
+	})
+	return err
+}
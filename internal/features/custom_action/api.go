@@ -0,0 +1,26 @@
+package custom_action
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type CustomActionApi struct {
+	controller *CustomActionController
+	config     *config.Config
+}
+
+func NewCustomActionApi(controller *CustomActionController, config *config.Config) *CustomActionApi {
+	return &CustomActionApi{
+		controller: controller,
+		config:     config,
+	}
+}
+
+func (h *CustomActionApi) Setup(app *fiber.App) {
+	records := app.Group("/api/modules", middleware.AuthMiddleware(h.config.SkipAuth))
+	records.Get("/:module/actions", h.controller.ListActions)
+	records.Post("/:module/records/:id/actions/:action", h.controller.ExecuteAction)
+}
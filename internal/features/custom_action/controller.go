@@ -0,0 +1,84 @@
+package custom_action
+
+import (
+	"errors"
+
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type CustomActionController struct {
+	Service CustomActionService
+}
+
+func NewCustomActionController(service CustomActionService) *CustomActionController {
+	return &CustomActionController{Service: service}
+}
+
+// ListActions godoc
+// @Summary List custom actions
+// @Description List the module's custom actions available to the current user
+// @Tags custom-actions
+// @Produce json
+// @Param module path string true "Module Name"
+// @Success 200 {array} models.CustomAction
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/modules/{module}/actions [get]
+func (c *CustomActionController) ListActions(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	actions, err := c.Service.ListActions(ctx.UserContext(), ctx.Params("module"), userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": actions})
+}
+
+// ExecuteAction godoc
+// @Summary Run custom action
+// @Description Run a custom action on a record. Actions with a confirmation prompt need {"confirmed": true}.
+// @Tags custom-actions
+// @Accept json
+// @Produce json
+// @Param module path string true "Module Name"
+// @Param id path string true "Record ID"
+// @Param action path string true "Action Name"
+// @Param request body ExecuteRequest false "Confirmation"
+// @Success 200 {object} ActionResult
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/modules/{module}/records/{id}/actions/{action} [post]
+func (c *CustomActionController) ExecuteAction(ctx *fiber.Ctx) error {
+	var req ExecuteRequest
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&req); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	result, err := c.Service.ExecuteAction(ctx.UserContext(), ctx.Params("module"), ctx.Params("id"), ctx.Params("action"), req.Confirmed, userID)
+	if err != nil {
+		status := fiber.StatusBadRequest
+		switch {
+		case errors.Is(err, ErrActionNotFound), errors.Is(err, ErrRecordNotFound):
+			status = fiber.StatusNotFound
+		case errors.Is(err, ErrForbidden):
+			status = fiber.StatusForbidden
+		case errors.Is(err, ErrConfirmationRequired):
+			status = fiber.StatusConflict
+		}
+		return ctx.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": result})
+}
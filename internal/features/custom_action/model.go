@@ -0,0 +1,22 @@
+package custom_action
+
+import (
+	"time"
+
+	common_models "go-crm/internal/common/models"
+)
+
+// ExecuteRequest is the optional body of an action call
+type ExecuteRequest struct {
+	// Confirmed must be true for actions that define a confirmation prompt
+	Confirmed bool `json:"confirmed"`
+}
+
+// ActionResult reports a completed custom action
+type ActionResult struct {
+	Action     string                           `json:"action"`
+	Label      string                           `json:"label"`
+	Target     common_models.CustomActionTarget `json:"target"`
+	RecordID   string                           `json:"record_id"`
+	ExecutedAt time.Time                        `json:"executed_at"`
+}
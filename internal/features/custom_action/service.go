@@ -0,0 +1,166 @@
+package custom_action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/automation"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/role"
+	"go-crm/internal/features/user"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrActionNotFound       = apperr.NotFound("action not found")
+	ErrRecordNotFound       = apperr.NotFound("record not found")
+	ErrForbidden            = apperr.PermissionDenied("you do not have the role required for this action")
+	ErrConfirmationRequired = errors.New("this action requires confirmation")
+)
+
+type CustomActionService interface {
+	// ListActions returns the module's actions the user is allowed to run
+	ListActions(ctx context.Context, moduleName string, userID primitive.ObjectID) ([]common_models.CustomAction, error)
+	ExecuteAction(ctx context.Context, moduleName, recordID, actionName string, confirmed bool, userID primitive.ObjectID) (*ActionResult, error)
+}
+
+type CustomActionServiceImpl struct {
+	ModuleRepo        module.ModuleRepository
+	RecordService     record.RecordService
+	UserRepo          user.UserRepository
+	RoleRepo          role.RoleRepository
+	AutomationService automation.AutomationService
+	ActionExecutor    automation.ActionExecutor
+	AuditService      audit.AuditService
+}
+
+func NewCustomActionService(
+	moduleRepo module.ModuleRepository,
+	recordService record.RecordService,
+	userRepo user.UserRepository,
+	roleRepo role.RoleRepository,
+	automationService automation.AutomationService,
+	actionExecutor automation.ActionExecutor,
+	auditService audit.AuditService,
+) CustomActionService {
+	return &CustomActionServiceImpl{
+		ModuleRepo:        moduleRepo,
+		RecordService:     recordService,
+		UserRepo:          userRepo,
+		RoleRepo:          roleRepo,
+		AutomationService: automationService,
+		ActionExecutor:    actionExecutor,
+		AuditService:      auditService,
+	}
+}
+
+func (s *CustomActionServiceImpl) ListActions(ctx context.Context, moduleName string, userID primitive.ObjectID) ([]common_models.CustomAction, error) {
+	m, err := s.ModuleRepo.FindByName(ctx, moduleName)
+	if err != nil {
+		return nil, errors.New("module not found")
+	}
+
+	roles, err := s.userRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	actions := []common_models.CustomAction{}
+	for _, a := range m.Actions {
+		if hasRole(roles, a.RequiredRole) {
+			actions = append(actions, a)
+		}
+	}
+	return actions, nil
+}
+
+func (s *CustomActionServiceImpl) ExecuteAction(ctx context.Context, moduleName, recordID, actionName string, confirmed bool, userID primitive.ObjectID) (*ActionResult, error) {
+	m, err := s.ModuleRepo.FindByName(ctx, moduleName)
+	if err != nil {
+		return nil, errors.New("module not found")
+	}
+
+	var action *common_models.CustomAction
+	for i := range m.Actions {
+		if m.Actions[i].Name == actionName {
+			action = &m.Actions[i]
+			break
+		}
+	}
+	if action == nil {
+		return nil, ErrActionNotFound
+	}
+
+	if action.RequiredRole != "" {
+		roles, err := s.userRoles(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !hasRole(roles, action.RequiredRole) {
+			return nil, ErrForbidden
+		}
+	}
+	if action.Confirmation != "" && !confirmed {
+		return nil, ErrConfirmationRequired
+	}
+
+	// The caller must be able to see the record
+	rec, err := s.RecordService.GetRecord(ctx, moduleName, recordID, userID)
+	if err != nil {
+		return nil, ErrRecordNotFound
+	}
+
+	switch action.Target {
+	case common_models.CustomActionAutomation:
+		err = s.AutomationService.ExecuteRule(ctx, action.AutomationRuleID, moduleName, rec)
+	case common_models.CustomActionScript:
+		err = s.ActionExecutor.ExecuteAction(ctx, automation.RuleAction{Type: automation.ActionRunScript, Config: action.Config}, moduleName, rec)
+	case common_models.CustomActionWebhook:
+		err = s.ActionExecutor.ExecuteAction(ctx, automation.RuleAction{Type: automation.ActionWebhook, Config: action.Config}, moduleName, rec)
+	default:
+		err = fmt.Errorf("unknown action target '%s'", action.Target)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", action.Label, err)
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionAutomation, moduleName, recordID, map[string]common_models.Change{
+		"action": {New: action.Name},
+	})
+
+	return &ActionResult{
+		Action:     action.Name,
+		Label:      action.Label,
+		Target:     action.Target,
+		RecordID:   recordID,
+		ExecutedAt: time.Now(),
+	}, nil
+}
+
+// userRoles returns the names and IDs of the user's roles
+func (s *CustomActionServiceImpl) userRoles(ctx context.Context, userID primitive.ObjectID) (map[string]bool, error) {
+	u, err := s.UserRepo.FindByID(ctx, userID.Hex())
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	roles := make(map[string]bool, len(u.Roles)*2)
+	for _, roleID := range u.Roles {
+		roles[roleID.Hex()] = true
+		if r, err := s.RoleRepo.FindByID(ctx, roleID.Hex()); err == nil {
+			roles[r.Name] = true
+		}
+	}
+	return roles, nil
+}
+
+// hasRole reports whether required, a role name or ID, is among the user's
+// roles. An empty requirement allows everyone.
+func hasRole(roles map[string]bool, required string) bool {
+	return required == "" || roles[required]
+}
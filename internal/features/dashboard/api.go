@@ -33,4 +33,10 @@ func (api *DashboardApi) Setup(app *fiber.App) {
 
 	group.Post("/:id/set-default", api.DashboardController.SetDefaultDashboard)
 	group.Get("/:id/data", api.DashboardController.GetDashboardData)
+
+	group.Get("/:id/snapshots", api.DashboardController.ListSnapshots)
+	group.Post("/:id/snapshots", api.DashboardController.CreateSnapshot)
+	group.Put("/:id/snapshots/:snapshotId", api.DashboardController.UpdateSnapshot)
+	group.Delete("/:id/snapshots/:snapshotId", api.DashboardController.DeleteSnapshot)
+	group.Post("/:id/snapshots/:snapshotId/send", api.DashboardController.SendSnapshot)
 }
@@ -2,7 +2,8 @@ package dashboard
 
 import (
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"go-crm/internal/common/session"
 )
 
 type DashboardController struct {
@@ -34,16 +35,11 @@ func (ctrl *DashboardController) CreateDashboard(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	userIDStr := ctx.Locals("user_id")
-	if userIDStr == nil {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
 	}
 
-	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
-	if err != nil {
-		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid user ID"})
-	}
-
 	if err := ctrl.DashboardService.CreateDashboard(ctx.UserContext(), &dashboard, userID); err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -62,16 +58,11 @@ func (ctrl *DashboardController) CreateDashboard(ctx *fiber.Ctx) error {
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/dashboards [get]
 func (ctrl *DashboardController) ListDashboards(ctx *fiber.Ctx) error {
-	userIDStr := ctx.Locals("user_id")
-	if userIDStr == nil {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
 	}
 
-	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
-	if err != nil {
-		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid user ID"})
-	}
-
 	dashboards, err := ctrl.DashboardService.ListUserDashboards(ctx.UserContext(), userID)
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
@@ -94,16 +85,11 @@ func (ctrl *DashboardController) ListDashboards(ctx *fiber.Ctx) error {
 func (ctrl *DashboardController) GetDashboard(ctx *fiber.Ctx) error {
 	id := ctx.Params("id")
 
-	userIDStr := ctx.Locals("user_id")
-	if userIDStr == nil {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
 	}
 
-	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
-	if err != nil {
-		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid user ID"})
-	}
-
 	dashboard, err := ctrl.DashboardService.GetDashboard(ctx.UserContext(), id, userID)
 	if err != nil {
 		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
@@ -134,16 +120,11 @@ func (ctrl *DashboardController) UpdateDashboard(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	userIDStr := ctx.Locals("user_id")
-	if userIDStr == nil {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
 	}
 
-	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
-	if err != nil {
-		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid user ID"})
-	}
-
 	if err := ctrl.DashboardService.UpdateDashboard(ctx.UserContext(), id, &dashboard, userID); err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -164,16 +145,11 @@ func (ctrl *DashboardController) UpdateDashboard(ctx *fiber.Ctx) error {
 func (ctrl *DashboardController) DeleteDashboard(ctx *fiber.Ctx) error {
 	id := ctx.Params("id")
 
-	userIDStr := ctx.Locals("user_id")
-	if userIDStr == nil {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
 	}
 
-	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
-	if err != nil {
-		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid user ID"})
-	}
-
 	if err := ctrl.DashboardService.DeleteDashboard(ctx.UserContext(), id, userID); err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -195,16 +171,11 @@ func (ctrl *DashboardController) DeleteDashboard(ctx *fiber.Ctx) error {
 func (ctrl *DashboardController) SetDefaultDashboard(ctx *fiber.Ctx) error {
 	id := ctx.Params("id")
 
-	userIDStr := ctx.Locals("user_id")
-	if userIDStr == nil {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
 	}
 
-	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
-	if err != nil {
-		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid user ID"})
-	}
-
 	if err := ctrl.DashboardService.SetDefaultDashboard(ctx.UserContext(), id, userID); err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -226,16 +197,11 @@ func (ctrl *DashboardController) SetDefaultDashboard(ctx *fiber.Ctx) error {
 func (ctrl *DashboardController) GetDashboardData(ctx *fiber.Ctx) error {
 	id := ctx.Params("id")
 
-	userIDStr := ctx.Locals("user_id")
-	if userIDStr == nil {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
 	}
 
-	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
-	if err != nil {
-		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid user ID"})
-	}
-
 	data, err := ctrl.DashboardService.GetDashboardData(ctx.UserContext(), id, userID)
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
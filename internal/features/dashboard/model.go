@@ -35,3 +35,31 @@ type DashboardConfig struct {
 	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
 }
+
+// Snapshot formats
+const (
+	SnapshotInline = "inline" // KPIs in the body, charts as inline images
+	SnapshotPDF    = "pdf"    // KPIs in the body, everything in an attached PDF
+)
+
+// SnapshotSubscription emails a dashboard's KPI values and charts to users.
+// Schedule it with a cron job using the dashboard_snapshot action. Each
+// recipient is sent the dashboard as they see it, with their own record
+// access and field permissions.
+type SnapshotSubscription struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID    primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	DashboardID primitive.ObjectID `json:"dashboard_id" bson:"dashboard_id"`
+	// Subject defaults to the dashboard name
+	Subject string `json:"subject,omitempty" bson:"subject,omitempty"`
+	// Recipients are user IDs; permissions are evaluated per user, so outside addresses cannot subscribe
+	Recipients []primitive.ObjectID `json:"recipients" bson:"recipients"`
+	Format     string               `json:"format" bson:"format"`
+	IsActive   bool                 `json:"is_active" bson:"is_active"`
+	LastSentAt *time.Time           `json:"last_sent_at,omitempty" bson:"last_sent_at,omitempty"`
+	// LastError lists the recipients the last run could not be sent to
+	LastError string             `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	CreatedBy primitive.ObjectID `json:"created_by" bson:"created_by"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
@@ -0,0 +1,318 @@
+package dashboard
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Snapshot charts are drawn server side as plain raster images, so they show
+// in mail clients that run no scripts and embed in PDFs as they are.
+
+const (
+	chartWidth     = 640
+	chartHeight    = 320
+	maxChartPoints = 20
+	glyphWidth     = 7 // basicfont.Face7x13
+)
+
+var (
+	chartPalette = []color.RGBA{
+		{0x4e, 0x79, 0xa7, 0xff}, {0xf2, 0x8e, 0x2b, 0xff}, {0xe1, 0x57, 0x59, 0xff},
+		{0x76, 0xb7, 0xb2, 0xff}, {0x59, 0xa1, 0x4f, 0xff}, {0xed, 0xc9, 0x48, 0xff},
+		{0xb0, 0x7a, 0xa1, 0xff}, {0xff, 0x9d, 0xa7, 0xff}, {0x9c, 0x75, 0x5f, 0xff},
+		{0xba, 0xb0, 0xac, 0xff},
+	}
+	inkColor  = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	gridColor = color.RGBA{0xdd, 0xdd, 0xdd, 0xff}
+	areaColor = color.RGBA{0xc6, 0xd5, 0xe6, 0xff}
+)
+
+type chartPoint struct {
+	label string
+	value float64
+}
+
+// chartPoints reads the name/value series returned for chart widgets, both
+// for saved charts and for ones configured on the widget
+func chartPoints(data interface{}) []chartPoint {
+	var rows []map[string]interface{}
+	switch d := data.(type) {
+	case []map[string]interface{}:
+		rows = d
+	case map[string]interface{}:
+		rows, _ = d["data"].([]map[string]interface{})
+	}
+	points := make([]chartPoint, 0, len(rows))
+	for _, row := range rows {
+		v, _ := numeric(row["value"])
+		points = append(points, chartPoint{label: fmt.Sprintf("%v", row["name"]), value: v})
+	}
+	if len(points) > maxChartPoints {
+		points = points[:maxChartPoints]
+	}
+	return points
+}
+
+func numeric(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// renderChart draws points as the chart type's closest plain form: pie and
+// donut charts as such, line and area charts as lines, anything else as bars
+func renderChart(chartType string, points []chartPoint) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, chartWidth, chartHeight))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	if len(points) == 0 {
+		drawText(img, chartWidth/2-3*glyphWidth, chartHeight/2, inkColor, "No data")
+		return img
+	}
+
+	switch chartType {
+	case "pie", "donut":
+		drawPie(img, points, chartType == "donut")
+	case "line", "area", "stacked_area":
+		drawLine(img, points, chartType != "line")
+	default:
+		drawBars(img, points)
+	}
+	return img
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// plot is the value axis of bar and line charts, with gridlines and labels
+type plot struct {
+	left, top, right, bottom int
+	min, max                 float64
+}
+
+func newPlot(img *image.RGBA, points []chartPoint) plot {
+	p := plot{left: 64, top: 16, right: chartWidth - 16, bottom: chartHeight - 40}
+	for _, pt := range points {
+		p.min = math.Min(p.min, pt.value)
+		p.max = math.Max(p.max, pt.value)
+	}
+	if p.max == p.min {
+		p.max = p.min + 1
+	}
+
+	const ticks = 4
+	for i := 0; i <= ticks; i++ {
+		v := p.min + (p.max-p.min)*float64(i)/ticks
+		y := p.y(v)
+		fillRect(img, image.Rect(p.left, y, p.right, y+1), gridColor)
+		label := shortNumber(v)
+		drawText(img, p.left-8-len(label)*glyphWidth, y+4, inkColor, label)
+	}
+	return p
+}
+
+func (p plot) y(v float64) int {
+	return p.bottom - int(math.Round((v-p.min)/(p.max-p.min)*float64(p.bottom-p.top)))
+}
+
+func drawBars(img *image.RGBA, points []chartPoint) {
+	p := newPlot(img, points)
+	slot := float64(p.right-p.left) / float64(len(points))
+	zero := p.y(0)
+	for i, pt := range points {
+		x0 := p.left + int(float64(i)*slot+slot*0.15)
+		x1 := p.left + int(float64(i+1)*slot-slot*0.15)
+		top, bottom := p.y(pt.value), zero
+		if top > bottom {
+			top, bottom = bottom, top
+		}
+		fillRect(img, image.Rect(x0, top, max(x1, x0+1), max(bottom, top+1)), chartPalette[0])
+		drawCategory(img, p, slot, i, pt.label)
+	}
+}
+
+func drawLine(img *image.RGBA, points []chartPoint, fill bool) {
+	p := newPlot(img, points)
+	slot := float64(p.right-p.left) / float64(len(points))
+	xs := make([]int, len(points))
+	ys := make([]int, len(points))
+	for i, pt := range points {
+		xs[i] = p.left + int(float64(i)*slot+slot/2)
+		ys[i] = p.y(pt.value)
+		drawCategory(img, p, slot, i, pt.label)
+	}
+
+	if fill {
+		zero := p.y(0)
+		for i := 1; i < len(points); i++ {
+			for x := xs[i-1]; x <= xs[i]; x++ {
+				t := float64(x-xs[i-1]) / float64(max(xs[i]-xs[i-1], 1))
+				y := int(math.Round(float64(ys[i-1]) + t*float64(ys[i]-ys[i-1])))
+				top, bottom := min(y, zero), max(y, zero)
+				fillRect(img, image.Rect(x, top, x+1, bottom), areaColor)
+			}
+		}
+	}
+	for i := 1; i < len(points); i++ {
+		drawSegment(img, xs[i-1], ys[i-1], xs[i], ys[i], chartPalette[0])
+	}
+	for i := range points {
+		fillRect(img, image.Rect(xs[i]-3, ys[i]-3, xs[i]+3, ys[i]+3), chartPalette[0])
+	}
+}
+
+// drawCategory writes a point's label under its slot, cut to fit
+func drawCategory(img *image.RGBA, p plot, slot float64, i int, label string) {
+	fit := max(int(slot)/glyphWidth-1, 1)
+	if r := []rune(label); len(r) > fit {
+		label = string(r[:max(fit-1, 1)]) + "."
+	}
+	x := p.left + int(float64(i)*slot+slot/2) - len([]rune(label))*glyphWidth/2
+	drawText(img, x, p.bottom+18, inkColor, label)
+}
+
+func drawPie(img *image.RGBA, points []chartPoint, donut bool) {
+	total := 0.0
+	for _, pt := range points {
+		total += math.Max(pt.value, 0)
+	}
+	if total == 0 {
+		drawText(img, chartWidth/2-3*glyphWidth, chartHeight/2, inkColor, "No data")
+		return
+	}
+
+	// Slice i covers the turn fractions ends[i-1] to ends[i], clockwise from
+	// twelve o'clock
+	ends := make([]float64, len(points))
+	acc := 0.0
+	for i, pt := range points {
+		acc += math.Max(pt.value, 0) / total
+		ends[i] = acc
+	}
+
+	const cx, cy, radius = 160, chartHeight / 2, 130
+	inner := 0.0
+	if donut {
+		inner = radius * 0.55
+	}
+	for y := cy - radius; y <= cy+radius; y++ {
+		for x := cx - radius; x <= cx+radius; x++ {
+			dx, dy := float64(x-cx), float64(y-cy)
+			d := math.Hypot(dx, dy)
+			if d > radius || d < inner {
+				continue
+			}
+			turn := math.Atan2(dx, -dy) / (2 * math.Pi)
+			if turn < 0 {
+				turn++
+			}
+			slice := len(ends) - 1
+			for i, end := range ends {
+				if turn <= end {
+					slice = i
+					break
+				}
+			}
+			img.SetRGBA(x, y, chartPalette[slice%len(chartPalette)])
+		}
+	}
+
+	// Legend
+	rowHeight := min(22, (chartHeight-32)/len(points))
+	for i, pt := range points {
+		y := 16 + i*rowHeight
+		fillRect(img, image.Rect(320, y, 332, y+12), chartPalette[i%len(chartPalette)])
+		label := fmt.Sprintf("%s  %s (%.0f%%)", pt.label, shortNumber(pt.value), math.Max(pt.value, 0)/total*100)
+		if r := []rune(label); len(r) > 40 {
+			label = string(r[:39]) + "."
+		}
+		drawText(img, 340, y+11, inkColor, label)
+	}
+}
+
+func fillRect(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+// drawSegment draws a two pixel wide line from (x0, y0) to (x1, y1)
+func drawSegment(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
+	steps := max(abs(x1-x0), abs(y1-y0), 1)
+	for i := 0; i <= steps; i++ {
+		x := x0 + (x1-x0)*i/steps
+		y := y0 + (y1-y0)*i/steps
+		fillRect(img, image.Rect(x-1, y-1, x+1, y+1), c)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// drawText writes s with its baseline at y. The built-in face covers ASCII;
+// other characters are drawn as '?'.
+func drawText(img *image.RGBA, x, y int, c color.RGBA, s string) {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return '?'
+		}
+		return r
+	}, s)
+	d := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(s)
+}
+
+// shortNumber formats axis and legend values compactly, e.g. 12.5k
+func shortNumber(v float64) string {
+	a := math.Abs(v)
+	switch {
+	case a >= 1e9:
+		return trimZero(fmt.Sprintf("%.1f", v/1e9)) + "B"
+	case a >= 1e6:
+		return trimZero(fmt.Sprintf("%.1f", v/1e6)) + "M"
+	case a >= 1e4:
+		return trimZero(fmt.Sprintf("%.1f", v/1e3)) + "k"
+	case a == math.Trunc(a):
+		return fmt.Sprintf("%.0f", v)
+	default:
+		return trimZero(fmt.Sprintf("%.2f", v))
+	}
+}
+
+func trimZero(s string) string {
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
@@ -3,13 +3,16 @@ package dashboard
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
+	"go-crm/internal/common/models"
 	"go-crm/internal/database"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type DashboardRepository interface {
@@ -192,3 +195,130 @@ func (r *DashboardRepositoryImpl) SetDefault(ctx context.Context, userID string,
 
 	return nil
 }
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+type SnapshotRepository interface {
+	Create(ctx context.Context, sub *SnapshotSubscription) error
+	Get(ctx context.Context, id string) (*SnapshotSubscription, error)
+	ListByDashboard(ctx context.Context, dashboardID primitive.ObjectID) ([]SnapshotSubscription, error)
+	Update(ctx context.Context, sub *SnapshotSubscription) error
+	Delete(ctx context.Context, id string) error
+	// DeleteByDashboard removes the subscriptions of a deleted dashboard
+	DeleteByDashboard(ctx context.Context, dashboardID primitive.ObjectID) error
+
+	// GetForRun loads a subscription regardless of tenant. Used by scheduled
+	// jobs, which run without a tenant context.
+	GetForRun(ctx context.Context, id string) (*SnapshotSubscription, error)
+}
+
+type SnapshotRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewSnapshotRepository(db *database.MongodbDB) SnapshotRepository {
+	return &SnapshotRepositoryImpl{
+		collection: db.DB.Collection("dashboard_snapshots"),
+	}
+}
+
+func (r *SnapshotRepositoryImpl) Create(ctx context.Context, sub *SnapshotSubscription) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	sub.ID = primitive.NewObjectID()
+	sub.TenantID = tenantID
+	sub.CreatedAt = time.Now()
+	sub.UpdatedAt = sub.CreatedAt
+
+	_, err = r.collection.InsertOne(ctx, sub)
+	return err
+}
+
+func (r *SnapshotRepositoryImpl) Get(ctx context.Context, id string) (*SnapshotSubscription, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var sub SnapshotSubscription
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID}).Decode(&sub); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (r *SnapshotRepositoryImpl) GetForRun(ctx context.Context, id string) (*SnapshotSubscription, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var sub SnapshotSubscription
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&sub); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (r *SnapshotRepositoryImpl) ListByDashboard(ctx context.Context, dashboardID primitive.ObjectID) ([]SnapshotSubscription, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"tenant_id": tenantID, "dashboard_id": dashboardID}, options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	subs := []SnapshotSubscription{}
+	if err := cursor.All(ctx, &subs); err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+func (r *SnapshotRepositoryImpl) Update(ctx context.Context, sub *SnapshotSubscription) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	sub.UpdatedAt = time.Now()
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": sub.ID, "tenant_id": tenantID}, sub)
+	return err
+}
+
+func (r *SnapshotRepositoryImpl) Delete(ctx context.Context, id string) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID})
+	return err
+}
+
+func (r *SnapshotRepositoryImpl) DeleteByDashboard(ctx context.Context, dashboardID primitive.ObjectID) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.DeleteMany(ctx, bson.M{"tenant_id": tenantID, "dashboard_id": dashboardID})
+	return err
+}
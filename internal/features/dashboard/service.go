@@ -7,10 +7,14 @@ import (
 	"strings"
 
 	common_models "go-crm/internal/common/models"
+	"go-crm/internal/database"
 	"go-crm/internal/features/audit"
 	"go-crm/internal/features/chart"
+	"go-crm/internal/features/email"
 	"go-crm/internal/features/module"
 	"go-crm/internal/features/record"
+	"go-crm/internal/features/settings"
+	"go-crm/internal/features/user"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -23,29 +27,53 @@ type DashboardService interface {
 	DeleteDashboard(ctx context.Context, id string, userID primitive.ObjectID) error
 	SetDefaultDashboard(ctx context.Context, dashboardID string, userID primitive.ObjectID) error
 	GetDashboardData(ctx context.Context, dashboardID string, userID primitive.ObjectID) (map[string]interface{}, error)
+
+	// Snapshot subscriptions of a dashboard the user can see. Only their
+	// creator or the dashboard owner may change, delete or send them.
+	CreateSnapshot(ctx context.Context, dashboardID string, sub *SnapshotSubscription, userID primitive.ObjectID) error
+	ListSnapshots(ctx context.Context, dashboardID string, userID primitive.ObjectID) ([]SnapshotSubscription, error)
+	UpdateSnapshot(ctx context.Context, dashboardID, id string, sub *SnapshotSubscription, userID primitive.ObjectID) (*SnapshotSubscription, error)
+	DeleteSnapshot(ctx context.Context, dashboardID, id string, userID primitive.ObjectID) error
+	// SendSnapshot emails the subscription now
+	SendSnapshot(ctx context.Context, dashboardID, id string, userID primitive.ObjectID) (*SnapshotSubscription, error)
+	// RunScheduled sends a subscription from a cron job, which carries no
+	// tenant; inactive subscriptions are skipped
+	RunScheduled(ctx context.Context, id string) error
 }
 
 type DashboardServiceImpl struct {
-	DashboardRepo DashboardRepository
-	RecordService record.RecordService
-	ModuleRepo    module.ModuleRepository
-	ChartService  chart.ChartService
-	AuditService  audit.AuditService
+	DashboardRepo   DashboardRepository
+	SnapshotRepo    SnapshotRepository
+	RecordService   record.RecordService
+	ModuleRepo      module.ModuleRepository
+	ChartService    chart.ChartService
+	UserRepo        user.UserRepository
+	EmailService    email.EmailService
+	SettingsService settings.SettingsService
+	AuditService    audit.AuditService
 }
 
 func NewDashboardService(
 	dashboardRepo DashboardRepository,
+	snapshotRepo SnapshotRepository,
 	recordService record.RecordService,
 	moduleRepo module.ModuleRepository,
 	chartService chart.ChartService,
+	userRepo user.UserRepository,
+	emailService email.EmailService,
+	settingsService settings.SettingsService,
 	auditService audit.AuditService,
 ) DashboardService {
 	return &DashboardServiceImpl{
-		DashboardRepo: dashboardRepo,
-		RecordService: recordService,
-		ModuleRepo:    moduleRepo,
-		ChartService:  chartService,
-		AuditService:  auditService,
+		DashboardRepo:   dashboardRepo,
+		SnapshotRepo:    snapshotRepo,
+		RecordService:   recordService,
+		ModuleRepo:      moduleRepo,
+		ChartService:    chartService,
+		UserRepo:        userRepo,
+		EmailService:    emailService,
+		SettingsService: settingsService,
+		AuditService:    auditService,
 	}
 }
 
@@ -117,6 +145,7 @@ func (s *DashboardServiceImpl) DeleteDashboard(ctx context.Context, id string, u
 
 	err = s.DashboardRepo.Delete(ctx, id)
 	if err == nil {
+		_ = s.SnapshotRepo.DeleteByDashboard(ctx, existing.ID)
 		_ = s.AuditService.LogChange(ctx, common_models.AuditActionDashboard, "dashboards", existing.Name, map[string]common_models.Change{
 			"dashboard": {Old: existing, New: "DELETED"},
 		})
@@ -173,6 +202,8 @@ func (s *DashboardServiceImpl) validateWidgets(ctx context.Context, widgets []Da
 }
 
 func (s *DashboardServiceImpl) getWidgetData(ctx context.Context, widget DashboardWidget, userID primitive.ObjectID) (interface{}, error) {
+	// Widgets read from secondaries when read offloading is on
+	ctx = database.WithReadMode(ctx, database.ReadSecondary)
 	switch widget.Type {
 	case "metric":
 		return s.getMetricData(ctx, widget, userID)
@@ -227,7 +258,7 @@ func (s *DashboardServiceImpl) getMetricData(ctx context.Context, widget Dashboa
 func (s *DashboardServiceImpl) getChartData(ctx context.Context, widget DashboardWidget, userID primitive.ObjectID) (interface{}, error) {
 	chartID, _ := widget.Config["chart_id"].(string)
 	if chartID != "" {
-		return s.ChartService.GetChartData(ctx, chartID)
+		return s.ChartService.GetChartDataFor(ctx, chartID, userID)
 	}
 
 	chartType, _ := widget.Config["chart_type"].(string)
@@ -0,0 +1,385 @@
+package dashboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/common/session"
+	"go-crm/internal/features/email"
+	"go-crm/internal/features/print_template"
+	"go-crm/pkg/locale"
+	"go-crm/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const maxSnapshotRecipients = 50
+
+var (
+	ErrSnapshotNotFound = apperr.NotFound("snapshot subscription not found")
+	ErrSnapshotOwner    = apperr.PermissionDenied("only the creator or the dashboard owner can change a snapshot subscription")
+)
+
+// snapshotWidget is one widget as a recipient sees it: a KPI value or a
+// rendered chart
+type snapshotWidget struct {
+	title  string
+	value  string
+	chart  []byte // PNG
+	figure print_template.Figure
+}
+
+func (s *DashboardServiceImpl) CreateSnapshot(ctx context.Context, dashboardID string, sub *SnapshotSubscription, userID primitive.ObjectID) error {
+	dashboard, err := s.visibleDashboard(ctx, dashboardID, userID)
+	if err != nil {
+		return err
+	}
+	sub.DashboardID = dashboard.ID
+	sub.CreatedBy = userID
+	sub.LastSentAt, sub.LastError = nil, ""
+	if err := s.validateSnapshot(ctx, dashboard, sub); err != nil {
+		return err
+	}
+
+	if err := s.SnapshotRepo.Create(ctx, sub); err != nil {
+		return err
+	}
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionDashboard, "dashboard_snapshots", sub.ID.Hex(), map[string]common_models.Change{
+		"snapshot": {New: sub},
+	})
+	return nil
+}
+
+func (s *DashboardServiceImpl) ListSnapshots(ctx context.Context, dashboardID string, userID primitive.ObjectID) ([]SnapshotSubscription, error) {
+	dashboard, err := s.visibleDashboard(ctx, dashboardID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.SnapshotRepo.ListByDashboard(ctx, dashboard.ID)
+}
+
+func (s *DashboardServiceImpl) UpdateSnapshot(ctx context.Context, dashboardID, id string, in *SnapshotSubscription, userID primitive.ObjectID) (*SnapshotSubscription, error) {
+	dashboard, sub, err := s.ownSnapshot(ctx, dashboardID, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	old := *sub
+	sub.Subject = in.Subject
+	sub.Recipients = in.Recipients
+	sub.Format = in.Format
+	sub.IsActive = in.IsActive
+	if err := s.validateSnapshot(ctx, dashboard, sub); err != nil {
+		return nil, err
+	}
+
+	if err := s.SnapshotRepo.Update(ctx, sub); err != nil {
+		return nil, err
+	}
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionDashboard, "dashboard_snapshots", sub.ID.Hex(), map[string]common_models.Change{
+		"snapshot": {Old: old, New: sub},
+	})
+	return sub, nil
+}
+
+func (s *DashboardServiceImpl) DeleteSnapshot(ctx context.Context, dashboardID, id string, userID primitive.ObjectID) error {
+	_, sub, err := s.ownSnapshot(ctx, dashboardID, id, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.SnapshotRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionDashboard, "dashboard_snapshots", sub.ID.Hex(), map[string]common_models.Change{
+		"snapshot": {Old: sub, New: "DELETED"},
+	})
+	return nil
+}
+
+func (s *DashboardServiceImpl) SendSnapshot(ctx context.Context, dashboardID, id string, userID primitive.ObjectID) (*SnapshotSubscription, error) {
+	_, sub, err := s.ownSnapshot(ctx, dashboardID, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	err = s.sendSnapshot(context.WithoutCancel(ctx), sub)
+	return sub, err
+}
+
+func (s *DashboardServiceImpl) RunScheduled(ctx context.Context, id string) error {
+	sub, err := s.SnapshotRepo.GetForRun(ctx, id)
+	if err != nil {
+		return fmt.Errorf("dashboard snapshot %s not found", id)
+	}
+	if !sub.IsActive {
+		return nil
+	}
+	ctx = context.WithValue(ctx, common_models.TenantIDKey, sub.TenantID.Hex())
+	return s.sendSnapshot(ctx, sub)
+}
+
+// visibleDashboard loads a dashboard the user owns or that is shared
+func (s *DashboardServiceImpl) visibleDashboard(ctx context.Context, dashboardID string, userID primitive.ObjectID) (*DashboardConfig, error) {
+	dashboard, err := s.GetDashboard(ctx, dashboardID, userID)
+	if err != nil {
+		return nil, apperr.NotFound("dashboard not found")
+	}
+	return dashboard, nil
+}
+
+// ownSnapshot loads a subscription of the dashboard that the user may change
+func (s *DashboardServiceImpl) ownSnapshot(ctx context.Context, dashboardID, id string, userID primitive.ObjectID) (*DashboardConfig, *SnapshotSubscription, error) {
+	dashboard, err := s.visibleDashboard(ctx, dashboardID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	sub, err := s.SnapshotRepo.Get(ctx, id)
+	if err != nil || sub.DashboardID != dashboard.ID {
+		return nil, nil, ErrSnapshotNotFound
+	}
+	if sub.CreatedBy != userID && dashboard.UserID != userID {
+		return nil, nil, ErrSnapshotOwner
+	}
+	return dashboard, sub, nil
+}
+
+func (s *DashboardServiceImpl) validateSnapshot(ctx context.Context, dashboard *DashboardConfig, sub *SnapshotSubscription) error {
+	if sub.Format == "" {
+		sub.Format = SnapshotInline
+	}
+	if sub.Format != SnapshotInline && sub.Format != SnapshotPDF {
+		return apperr.Validation("format must be %s or %s", SnapshotInline, SnapshotPDF)
+	}
+
+	seen := map[primitive.ObjectID]bool{}
+	recipients := sub.Recipients[:0]
+	for _, id := range sub.Recipients {
+		if !seen[id] {
+			seen[id] = true
+			recipients = append(recipients, id)
+		}
+	}
+	sub.Recipients = recipients
+	if len(sub.Recipients) == 0 {
+		return apperr.Validation("at least one recipient is required")
+	}
+	if len(sub.Recipients) > maxSnapshotRecipients {
+		return apperr.Validation("at most %d recipients are allowed", maxSnapshotRecipients)
+	}
+
+	for _, id := range sub.Recipients {
+		if _, err := s.UserRepo.FindByID(ctx, id.Hex()); err != nil {
+			return apperr.Validation("recipient %s is not a user of this organization", id.Hex())
+		}
+		if !dashboard.IsShared && id != dashboard.UserID {
+			return apperr.Validation("the dashboard is not shared, so only its owner can receive snapshots")
+		}
+	}
+	return nil
+}
+
+// sendSnapshot mails every recipient their own view of the dashboard and
+// records the outcome on the subscription
+func (s *DashboardServiceImpl) sendSnapshot(ctx context.Context, sub *SnapshotSubscription) error {
+	dashboard, err := s.DashboardRepo.Get(ctx, sub.DashboardID.Hex())
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, id := range sub.Recipients {
+		if err := s.sendSnapshotTo(ctx, sub, dashboard, id); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", id.Hex(), err))
+		}
+	}
+
+	now := time.Now()
+	sub.LastSentAt = &now
+	sub.LastError = strings.Join(failed, "; ")
+	_ = s.SnapshotRepo.Update(ctx, sub)
+
+	if len(failed) > 0 {
+		return fmt.Errorf("snapshot not sent to %d of %d recipients: %s", len(failed), len(sub.Recipients), sub.LastError)
+	}
+	return nil
+}
+
+func (s *DashboardServiceImpl) sendSnapshotTo(ctx context.Context, sub *SnapshotSubscription, dashboard *DashboardConfig, userID primitive.ObjectID) error {
+	u, err := s.UserRepo.FindByID(ctx, userID.Hex())
+	if err != nil {
+		return errors.New("user not found")
+	}
+	// Deactivated users keep their place on the list but get nothing
+	if u.Status != "" && u.Status != "active" {
+		return nil
+	}
+	if u.Email == "" {
+		return errors.New("user has no email address")
+	}
+	// Sharing can be turned off after the subscription was made
+	if dashboard.UserID != u.ID && !dashboard.IsShared {
+		return errors.New("dashboard is no longer shared")
+	}
+
+	rctx := s.recipientContext(ctx, u)
+	lf := s.SettingsService.Formatter(rctx, u.ID.Hex())
+	widgets := s.snapshotWidgets(rctx, dashboard, u.ID, lf)
+
+	subject := sub.Subject
+	if subject == "" {
+		subject = dashboard.Name + " snapshot"
+	}
+	takenAt := lf.DateTime(time.Now())
+
+	var body strings.Builder
+	body.WriteString(`<html><body style="font-family:Arial,Helvetica,sans-serif;color:#333">`)
+	fmt.Fprintf(&body, "<h2>%s</h2><p>As of %s</p>", html.EscapeString(dashboard.Name), html.EscapeString(takenAt))
+
+	var kpis [][2]string
+	for _, w := range widgets {
+		if w.chart == nil {
+			kpis = append(kpis, [2]string{w.title, w.value})
+		}
+	}
+	if len(kpis) > 0 {
+		body.WriteString(`<table cellpadding="6" style="border-collapse:collapse">`)
+		for _, kv := range kpis {
+			fmt.Fprintf(&body, `<tr><td style="border-bottom:1px solid #ddd">%s</td><td style="border-bottom:1px solid #ddd;font-weight:bold;text-align:right">%s</td></tr>`,
+				html.EscapeString(kv[0]), html.EscapeString(kv[1]))
+		}
+		body.WriteString("</table>")
+	}
+
+	var attachments []email.Attachment
+	switch sub.Format {
+	case SnapshotPDF:
+		var figures []print_template.Figure
+		for _, w := range widgets {
+			if w.chart != nil {
+				figures = append(figures, w.figure)
+			}
+		}
+		pdf, err := print_template.RenderSummaryPDF(dashboard.Name, "As of "+takenAt,
+			[]print_template.SummarySection{{Heading: "Key figures", Pairs: kpis}}, figures)
+		if err != nil {
+			return err
+		}
+		attachments = append(attachments, email.Attachment{Filename: snapshotFilename(dashboard.Name), Data: pdf})
+		body.WriteString("<p>The full snapshot, charts included, is attached.</p>")
+	default:
+		for i, w := range widgets {
+			if w.chart == nil {
+				continue
+			}
+			cid := fmt.Sprintf("chart-%d", i+1)
+			attachments = append(attachments, email.Attachment{Filename: cid + ".png", Data: w.chart, ContentID: cid})
+			fmt.Fprintf(&body, `<h3>%s</h3><img src="cid:%s" width="%d" height="%d" alt="%s">`,
+				html.EscapeString(w.title), cid, chartWidth, chartHeight, html.EscapeString(w.title))
+		}
+	}
+	body.WriteString("</body></html>")
+
+	return s.EmailService.SendEmailWithAttachments(rctx, []string{u.Email}, subject, body.String(), attachments)
+}
+
+// recipientContext acts as u, the way AuthMiddleware would for their own
+// requests, so the widgets only read what u may read
+func (s *DashboardServiceImpl) recipientContext(ctx context.Context, u *common_models.User) context.Context {
+	tenantID, _ := ctx.Value(common_models.TenantIDKey).(string)
+	roleIDs := make([]string, len(u.Roles))
+	for i, id := range u.Roles {
+		roleIDs[i] = id.Hex()
+	}
+	claims := &utils.UserClaims{UserID: u.ID.Hex(), TenantID: tenantID, RoleIDs: roleIDs, Groups: u.Groups}
+	ctx = context.WithValue(ctx, utils.UserClaimsKey, claims)
+
+	tid, _ := primitive.ObjectIDFromHex(tenantID)
+	rc := &session.RequestContext{UserID: u.ID, TenantID: tid, RoleIDs: roleIDs, Groups: u.Groups}
+	rc.WithLocale(func() locale.Settings { return s.SettingsService.Formatter(ctx, u.ID.Hex()).Settings() })
+	return session.With(ctx, rc)
+}
+
+// snapshotWidgets evaluates the metric, chart and table widgets in layout
+// order. Widgets the recipient cannot read are left out.
+func (s *DashboardServiceImpl) snapshotWidgets(ctx context.Context, dashboard *DashboardConfig, userID primitive.ObjectID, lf *locale.Formatter) []snapshotWidget {
+	ordered := append([]DashboardWidget(nil), dashboard.Widgets...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].Position.Y != ordered[j].Position.Y {
+			return ordered[i].Position.Y < ordered[j].Position.Y
+		}
+		return ordered[i].Position.X < ordered[j].Position.X
+	})
+
+	var widgets []snapshotWidget
+	for _, widget := range ordered {
+		data, err := s.getWidgetData(ctx, widget, userID)
+		if err != nil {
+			continue
+		}
+		w := snapshotWidget{title: widget.Title}
+		switch widget.Type {
+		case "metric":
+			m, _ := data.(map[string]interface{})
+			v, _ := numeric(m["value"])
+			decimals := 0
+			if v != float64(int64(v)) {
+				decimals = 2
+			}
+			w.value = lf.Number(v, decimals)
+		case "chart":
+			chartType := s.snapshotChartType(ctx, widget)
+			img := renderChart(chartType, chartPoints(data))
+			png, err := encodePNG(img)
+			if err != nil {
+				continue
+			}
+			w.chart = png
+			w.figure = print_template.Figure{Caption: widget.Title, Image: img}
+		default:
+			m, _ := data.(map[string]interface{})
+			total, _ := numeric(m["total"])
+			w.value = lf.Number(total, 0) + " records"
+		}
+		widgets = append(widgets, w)
+	}
+	return widgets
+}
+
+// snapshotChartType is the chart type of a widget, from its saved chart when
+// it shows one
+func (s *DashboardServiceImpl) snapshotChartType(ctx context.Context, widget DashboardWidget) string {
+	if chartID, _ := widget.Config["chart_id"].(string); chartID != "" {
+		if c, err := s.ChartService.GetChart(ctx, chartID); err == nil {
+			return string(c.ChartType)
+		}
+	}
+	chartType, _ := widget.Config["chart_type"].(string)
+	return chartType
+}
+
+// snapshotFilename names the PDF after the dashboard and the day it was taken
+func snapshotFilename(name string) string {
+	slug := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return '_'
+	}, name)
+	for strings.Contains(slug, "__") {
+		slug = strings.ReplaceAll(slug, "__", "_")
+	}
+	slug = strings.Trim(slug, "_")
+	if slug == "" {
+		slug = "dashboard"
+	}
+	return fmt.Sprintf("%s_%s.pdf", slug, time.Now().Format("20060102"))
+}
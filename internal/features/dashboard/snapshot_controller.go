@@ -0,0 +1,130 @@
+package dashboard
+
+import (
+	common_api "go-crm/internal/common/api"
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CreateSnapshot godoc
+// @Summary Create dashboard snapshot subscription
+// @Description Email the dashboard's KPI values and charts to users, inline or as a PDF. Schedule it with a cron job using the dashboard_snapshot action and config {"snapshot_id": "..."}. Each recipient gets the dashboard with their own data permissions, so recipients are user IDs and the dashboard must be shared to send it to anyone but its owner.
+// @Tags dashboard
+// @Accept json
+// @Produce json
+// @Param id path string true "Dashboard ID"
+// @Param snapshot body SnapshotSubscription true "Snapshot subscription"
+// @Success 201 {object} SnapshotSubscription
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/dashboards/{id}/snapshots [post]
+func (ctrl *DashboardController) CreateSnapshot(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var sub SnapshotSubscription
+	if err := ctx.BodyParser(&sub); err != nil {
+		return common_api.InvalidBody(ctx, err)
+	}
+	if err := ctrl.DashboardService.CreateSnapshot(ctx.UserContext(), ctx.Params("id"), &sub, userID); err != nil {
+		return common_api.Error(ctx, err)
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(sub)
+}
+
+// ListSnapshots godoc
+// @Summary List dashboard snapshot subscriptions
+// @Tags dashboard
+// @Produce json
+// @Param id path string true "Dashboard ID"
+// @Success 200 {array} SnapshotSubscription
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/dashboards/{id}/snapshots [get]
+func (ctrl *DashboardController) ListSnapshots(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	subs, err := ctrl.DashboardService.ListSnapshots(ctx.UserContext(), ctx.Params("id"), userID)
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+	return ctx.JSON(subs)
+}
+
+// UpdateSnapshot godoc
+// @Summary Update dashboard snapshot subscription
+// @Description Change the subject, recipients, format or active flag. Only the creator or the dashboard owner can.
+// @Tags dashboard
+// @Accept json
+// @Produce json
+// @Param id path string true "Dashboard ID"
+// @Param snapshotId path string true "Snapshot subscription ID"
+// @Param snapshot body SnapshotSubscription true "Snapshot subscription"
+// @Success 200 {object} SnapshotSubscription
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/dashboards/{id}/snapshots/{snapshotId} [put]
+func (ctrl *DashboardController) UpdateSnapshot(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var in SnapshotSubscription
+	if err := ctx.BodyParser(&in); err != nil {
+		return common_api.InvalidBody(ctx, err)
+	}
+	sub, err := ctrl.DashboardService.UpdateSnapshot(ctx.UserContext(), ctx.Params("id"), ctx.Params("snapshotId"), &in, userID)
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+	return ctx.JSON(sub)
+}
+
+// DeleteSnapshot godoc
+// @Summary Delete dashboard snapshot subscription
+// @Description Cron jobs sending it start failing
+// @Tags dashboard
+// @Param id path string true "Dashboard ID"
+// @Param snapshotId path string true "Snapshot subscription ID"
+// @Success 204
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/dashboards/{id}/snapshots/{snapshotId} [delete]
+func (ctrl *DashboardController) DeleteSnapshot(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	if err := ctrl.DashboardService.DeleteSnapshot(ctx.UserContext(), ctx.Params("id"), ctx.Params("snapshotId"), userID); err != nil {
+		return common_api.Error(ctx, err)
+	}
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// SendSnapshot godoc
+// @Summary Send dashboard snapshot now
+// @Description Email every recipient now, whatever the schedule, and return the subscription with the outcome
+// @Tags dashboard
+// @Produce json
+// @Param id path string true "Dashboard ID"
+// @Param snapshotId path string true "Snapshot subscription ID"
+// @Success 200 {object} SnapshotSubscription
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/dashboards/{id}/snapshots/{snapshotId}/send [post]
+func (ctrl *DashboardController) SendSnapshot(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	sub, err := ctrl.DashboardService.SendSnapshot(ctx.UserContext(), ctx.Params("id"), ctx.Params("snapshotId"), userID)
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+	return ctx.JSON(sub)
+}
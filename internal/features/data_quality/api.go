@@ -0,0 +1,41 @@
+package data_quality
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type DataQualityApi struct {
+	controller  *DataQualityController
+	config      *config.Config
+	roleService middleware.RoleService
+}
+
+func NewDataQualityApi(controller *DataQualityController, config *config.Config, roleService middleware.RoleService) *DataQualityApi {
+	return &DataQualityApi{
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *DataQualityApi) Setup(app *fiber.App) {
+	group := app.Group("/api/data-quality", middleware.AuthMiddleware(h.config.SkipAuth))
+
+	group.Get("/rules", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListRules)
+	group.Post("/rules", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.CreateRule)
+	group.Get("/rules/:id", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.GetRule)
+	group.Put("/rules/:id", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.UpdateRule)
+	group.Delete("/rules/:id", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.DeleteRule)
+
+	group.Get("/scores", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListScores)
+	group.Get("/modules/:module/scores", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ScoreHistory)
+	group.Post("/modules/:module/evaluate", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.Evaluate)
+
+	// Record owners work their own list; the service checks assignment on update
+	group.Get("/violations/mine", h.controller.MyViolations)
+	group.Get("/violations", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListViolations)
+	group.Put("/violations/:id", h.controller.UpdateViolation)
+}
@@ -0,0 +1,263 @@
+package data_quality
+
+import (
+	"errors"
+
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DataQualityController struct {
+	Service DataQualityService
+}
+
+func NewDataQualityController(service DataQualityService) *DataQualityController {
+	return &DataQualityController{Service: service}
+}
+
+// CreateRule godoc
+// @Summary Create data quality rule
+// @Description Types: required_for_stage, format, reference, stale
+// @Tags data-quality
+// @Accept json
+// @Produce json
+// @Param rule body Rule true "Rule"
+// @Success 201 {object} Rule
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/data-quality/rules [post]
+func (c *DataQualityController) CreateRule(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var rule Rule
+	if err := ctx.BodyParser(&rule); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := c.Service.CreateRule(ctx.UserContext(), &rule, userID); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{"data": rule})
+}
+
+// ListRules godoc
+// @Summary List data quality rules
+// @Tags data-quality
+// @Produce json
+// @Param module query string false "Module Name"
+// @Success 200 {array} Rule
+// @Router /api/data-quality/rules [get]
+func (c *DataQualityController) ListRules(ctx *fiber.Ctx) error {
+	rules, err := c.Service.ListRules(ctx.UserContext(), ctx.Query("module"))
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": rules})
+}
+
+// GetRule godoc
+// @Summary Get data quality rule
+// @Tags data-quality
+// @Produce json
+// @Param id path string true "Rule ID"
+// @Success 200 {object} Rule
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/data-quality/rules/{id} [get]
+func (c *DataQualityController) GetRule(ctx *fiber.Ctx) error {
+	rule, err := c.Service.GetRule(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Rule not found"})
+	}
+	return ctx.JSON(fiber.Map{"data": rule})
+}
+
+// UpdateRule godoc
+// @Summary Update data quality rule
+// @Description Deactivating a rule, or changing its type or module, clears its violations
+// @Tags data-quality
+// @Accept json
+// @Produce json
+// @Param id path string true "Rule ID"
+// @Param rule body Rule true "Rule"
+// @Success 200 {object} Rule
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/data-quality/rules/{id} [put]
+func (c *DataQualityController) UpdateRule(ctx *fiber.Ctx) error {
+	var in Rule
+	if err := ctx.BodyParser(&in); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	rule, err := c.Service.UpdateRule(ctx.UserContext(), ctx.Params("id"), &in)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": rule})
+}
+
+// DeleteRule godoc
+// @Summary Delete data quality rule
+// @Tags data-quality
+// @Param id path string true "Rule ID"
+// @Success 204
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/data-quality/rules/{id} [delete]
+func (c *DataQualityController) DeleteRule(ctx *fiber.Ctx) error {
+	if err := c.Service.DeleteRule(ctx.UserContext(), ctx.Params("id")); err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// Evaluate godoc
+// @Summary Evaluate module data quality
+// @Description Run the module's active rules now instead of waiting for the nightly job
+// @Tags data-quality
+// @Produce json
+// @Param module path string true "Module Name"
+// @Success 200 {object} Score
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/data-quality/modules/{module}/evaluate [post]
+func (c *DataQualityController) Evaluate(ctx *fiber.Ctx) error {
+	score, err := c.Service.Evaluate(ctx.UserContext(), ctx.Params("module"))
+	if errors.Is(err, ErrEvaluationRunning) {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": score})
+}
+
+// ListScores godoc
+// @Summary Data quality scores
+// @Description Latest quality score of each evaluated module
+// @Tags data-quality
+// @Produce json
+// @Success 200 {array} Score
+// @Router /api/data-quality/scores [get]
+func (c *DataQualityController) ListScores(ctx *fiber.Ctx) error {
+	scores, err := c.Service.ListScores(ctx.UserContext())
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": scores})
+}
+
+// ScoreHistory godoc
+// @Summary Data quality score history
+// @Tags data-quality
+// @Produce json
+// @Param module path string true "Module Name"
+// @Param limit query int false "Limit"
+// @Success 200 {array} Score
+// @Router /api/data-quality/modules/{module}/scores [get]
+func (c *DataQualityController) ScoreHistory(ctx *fiber.Ctx) error {
+	scores, err := c.Service.ScoreHistory(ctx.UserContext(), ctx.Params("module"), int64(ctx.QueryInt("limit", 30)))
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": scores})
+}
+
+// ListViolations godoc
+// @Summary List data quality violations
+// @Tags data-quality
+// @Produce json
+// @Param module query string false "Module Name"
+// @Param rule_id query string false "Rule ID"
+// @Param status query string false "open, resolved or ignored"
+// @Param assigned_to query string false "User ID"
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Success 200 {array} Violation
+// @Router /api/data-quality/violations [get]
+func (c *DataQualityController) ListViolations(ctx *fiber.Ctx) error {
+	filter := violationFilter(ctx)
+	if assignee := ctx.Query("assigned_to"); assignee != "" {
+		oid, err := primitive.ObjectIDFromHex(assignee)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid assigned_to"})
+		}
+		filter.AssignedTo = &oid
+	}
+	return c.listViolations(ctx, filter)
+}
+
+// MyViolations godoc
+// @Summary List my data quality violations
+// @Description Violations on records owned by the current user; open ones by default
+// @Tags data-quality
+// @Produce json
+// @Param module query string false "Module Name"
+// @Param status query string false "open, resolved or ignored"
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Success 200 {array} Violation
+// @Router /api/data-quality/violations/mine [get]
+func (c *DataQualityController) MyViolations(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	filter := violationFilter(ctx)
+	filter.AssignedTo = &userID
+	if filter.Status == "" {
+		filter.Status = ViolationOpen
+	}
+	return c.listViolations(ctx, filter)
+}
+
+func violationFilter(ctx *fiber.Ctx) ViolationFilter {
+	return ViolationFilter{
+		ModuleName: ctx.Query("module"),
+		RuleID:     ctx.Query("rule_id"),
+		Status:     ViolationStatus(ctx.Query("status")),
+	}
+}
+
+func (c *DataQualityController) listViolations(ctx *fiber.Ctx, filter ViolationFilter) error {
+	violations, total, err := c.Service.ListViolations(ctx.UserContext(), filter, int64(ctx.QueryInt("page", 1)), int64(ctx.QueryInt("limit", 50)))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": violations, "total": total})
+}
+
+// UpdateViolation godoc
+// @Summary Update violation status
+// @Description Resolve, ignore or reopen a violation. Ignored violations stay ignored on later runs; resolved ones reopen if the record still fails.
+// @Tags data-quality
+// @Accept json
+// @Produce json
+// @Param id path string true "Violation ID"
+// @Param request body UpdateViolationRequest true "Status"
+// @Success 200 {object} Violation
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/data-quality/violations/{id} [put]
+func (c *DataQualityController) UpdateViolation(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var req UpdateViolationRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	v, err := c.Service.UpdateViolation(ctx.UserContext(), ctx.Params("id"), req.Status, userID)
+	if err != nil {
+		status := fiber.StatusBadRequest
+		switch {
+		case errors.Is(err, ErrViolationNotFound):
+			status = fiber.StatusNotFound
+		case errors.Is(err, ErrNotAssigned):
+			status = fiber.StatusForbidden
+		}
+		return ctx.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": v})
+}
@@ -0,0 +1,301 @@
+package data_quality
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"go-crm/internal/features/notification"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const evaluationPageSize = int64(500)
+
+// check is a rule prepared for one evaluation run
+type check struct {
+	rule    Rule
+	pattern *regexp.Regexp
+	stages  map[string]bool
+	cutoff  time.Time
+}
+
+func newCheck(rule Rule, now time.Time) (*check, error) {
+	c := &check{rule: rule}
+	switch rule.Type {
+	case RuleFormat:
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		c.pattern = pattern
+	case RuleRequiredForStage:
+		c.stages = make(map[string]bool, len(rule.Stages))
+		for _, st := range rule.Stages {
+			c.stages[st] = true
+		}
+	case RuleStale:
+		c.cutoff = now.AddDate(0, 0, -rule.StaleDays)
+	}
+	return c, nil
+}
+
+// evaluate runs the rules over every record of the module. Findings are
+// upserted as violations; violations not found again are resolved.
+func (s *DataQualityServiceImpl) evaluate(ctx context.Context, moduleName string, rules []Rule) (*Score, error) {
+	seenAt := time.Now()
+	checks := make([]*check, 0, len(rules))
+	ruleIDs := make([]primitive.ObjectID, 0, len(rules))
+	for _, r := range rules {
+		c, err := newCheck(r, seenAt)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+		checks = append(checks, c)
+		ruleIDs = append(ruleIDs, r.ID)
+	}
+
+	score := &Score{ModuleName: moduleName, ByRule: map[string]int{}}
+	failing := map[primitive.ObjectID]bool{}
+	newByOwner := map[primitive.ObjectID]int{}
+
+	var lastID primitive.ObjectID
+	for {
+		filter := bson.M{}
+		if !lastID.IsZero() {
+			filter["_id"] = bson.M{"$gt": lastID}
+		}
+		records, err := s.RecordRepo.List(ctx, moduleName, filter, nil, evaluationPageSize, 0, "_id", 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			break
+		}
+		if oid, ok := records[len(records)-1]["_id"].(primitive.ObjectID); ok {
+			lastID = oid
+		}
+		score.RecordsChecked += len(records)
+
+		byID := make(map[primitive.ObjectID]map[string]any, len(records))
+		for _, rec := range records {
+			if oid, ok := rec["_id"].(primitive.ObjectID); ok {
+				byID[oid] = rec
+			}
+		}
+
+		for _, c := range checks {
+			findings, err := s.runCheck(ctx, c, records)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: %w", c.rule.Name, err)
+			}
+			for recordID, message := range findings {
+				v := &Violation{
+					RuleID:     c.rule.ID,
+					RuleName:   c.rule.Name,
+					ModuleName: moduleName,
+					RecordID:   recordID,
+					Field:      c.rule.Field,
+					Message:    message,
+					Severity:   c.rule.Severity,
+					AssignedTo: ownerOf(byID[recordID]),
+				}
+				status, opened, err := s.ViolationRepo.Record(ctx, v, seenAt)
+				if err != nil {
+					return nil, err
+				}
+				if status != ViolationOpen {
+					continue
+				}
+				failing[recordID] = true
+				score.Violations++
+				score.ByRule[c.rule.ID.Hex()]++
+				if opened && v.AssignedTo != nil {
+					newByOwner[*v.AssignedTo]++
+				}
+			}
+		}
+
+		if int64(len(records)) < evaluationPageSize {
+			break
+		}
+	}
+
+	if err := s.ViolationRepo.ResolveUnseen(ctx, ruleIDs, seenAt); err != nil {
+		return nil, err
+	}
+
+	score.RecordsFailing = len(failing)
+	score.Score = 100
+	if score.RecordsChecked > 0 {
+		passing := float64(score.RecordsChecked-score.RecordsFailing) / float64(score.RecordsChecked)
+		score.Score = math.Round(passing*1000) / 10
+	}
+	score.EvaluatedAt = time.Now()
+	if err := s.ScoreRepo.Create(ctx, score); err != nil {
+		return nil, err
+	}
+
+	for owner, count := range newByOwner {
+		title := fmt.Sprintf("%d new data quality issue(s) in %s", count, moduleName)
+		if err := s.NotificationService.CreateNotification(ctx, owner, title,
+			"Records you own failed data quality checks and need cleanup.",
+			notification.NotificationTypeWarning, "/data-quality/violations?module="+moduleName); err != nil {
+			log.Printf("data quality: notifying %s failed: %v", owner.Hex(), err)
+		}
+	}
+	return score, nil
+}
+
+// runCheck returns a message per failing record ID
+func (s *DataQualityServiceImpl) runCheck(ctx context.Context, c *check, records []map[string]any) (map[primitive.ObjectID]string, error) {
+	findings := map[primitive.ObjectID]string{}
+	rule := c.rule
+
+	switch rule.Type {
+	case RuleRequiredForStage:
+		for _, rec := range records {
+			stage := fmt.Sprintf("%v", rec[rule.StageField])
+			if c.stages[stage] && isEmpty(rec[rule.Field]) {
+				findings[recordID(rec)] = fmt.Sprintf("%s is required at stage %s", rule.Field, stage)
+			}
+		}
+
+	case RuleFormat:
+		for _, rec := range records {
+			val := rec[rule.Field]
+			if !isEmpty(val) && !c.pattern.MatchString(fmt.Sprintf("%v", val)) {
+				findings[recordID(rec)] = fmt.Sprintf("%s does not match the expected format", rule.Field)
+			}
+		}
+
+	case RuleReference:
+		refs := map[primitive.ObjectID][]primitive.ObjectID{} // referenced -> referencing records
+		for _, rec := range records {
+			val := rec[rule.Field]
+			if isEmpty(val) {
+				continue
+			}
+			ref, ok := toObjectID(val)
+			if !ok {
+				findings[recordID(rec)] = fmt.Sprintf("%s is not a valid reference", rule.Field)
+				continue
+			}
+			refs[ref] = append(refs[ref], recordID(rec))
+		}
+		if len(refs) == 0 {
+			break
+		}
+		ids := make([]primitive.ObjectID, 0, len(refs))
+		for id := range refs {
+			ids = append(ids, id)
+		}
+		found, err := s.RecordRepo.List(ctx, rule.ReferenceModule, bson.M{"_id": bson.M{"$in": ids}}, nil, int64(len(ids)), 0, "_id", 1)
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range found {
+			delete(refs, recordID(rec))
+		}
+		for _, referencing := range refs {
+			for _, id := range referencing {
+				findings[id] = fmt.Sprintf("%s points to a missing %s record", rule.Field, rule.ReferenceModule)
+			}
+		}
+
+	case RuleStale:
+		message := fmt.Sprintf("No activity in %d days", rule.StaleDays)
+		for _, rec := range records {
+			last := toTime(rec[rule.Field])
+			if last.IsZero() || !last.Before(c.cutoff) {
+				continue
+			}
+			id := recordID(rec)
+			active, err := s.hasRecentActivity(ctx, rule, id, c.cutoff)
+			if err != nil {
+				return nil, err
+			}
+			if !active {
+				findings[id] = message
+			}
+		}
+	}
+	return findings, nil
+}
+
+func (s *DataQualityServiceImpl) hasRecentActivity(ctx context.Context, rule Rule, id primitive.ObjectID, cutoff time.Time) (bool, error) {
+	for _, activityModule := range rule.ActivityModules {
+		found, err := s.RecordRepo.List(ctx, activityModule, bson.M{
+			rule.ActivityField: bson.M{"$in": []interface{}{id, id.Hex()}},
+			"created_at":       bson.M{"$gte": cutoff},
+		}, nil, 1, 0, "_id", 1)
+		if err != nil {
+			return false, err
+		}
+		if len(found) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func recordID(rec map[string]any) primitive.ObjectID {
+	oid, _ := rec["_id"].(primitive.ObjectID)
+	return oid
+}
+
+func ownerOf(rec map[string]any) *primitive.ObjectID {
+	if rec == nil {
+		return nil
+	}
+	if oid, ok := toObjectID(rec["owner"]); ok {
+		return &oid
+	}
+	return nil
+}
+
+func toObjectID(v interface{}) (primitive.ObjectID, bool) {
+	switch val := v.(type) {
+	case primitive.ObjectID:
+		return val, !val.IsZero()
+	case string:
+		oid, err := primitive.ObjectIDFromHex(val)
+		return oid, err == nil
+	}
+	return primitive.NilObjectID, false
+}
+
+func toTime(v interface{}) time.Time {
+	switch val := v.(type) {
+	case time.Time:
+		return val
+	case primitive.DateTime:
+		return val.Time()
+	case string:
+		if t, err := time.Parse(time.RFC3339, val); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func isEmpty(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(val) == ""
+	case []interface{}:
+		return len(val) == 0
+	case primitive.A:
+		return len(val) == 0
+	case primitive.ObjectID:
+		return val.IsZero()
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package data_quality
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RuleType string
+
+const (
+	// RuleRequiredForStage requires Field once StageField reaches one of Stages
+	RuleRequiredForStage RuleType = "required_for_stage"
+	// RuleFormat requires non-empty values of Field to match Pattern
+	RuleFormat RuleType = "format"
+	// RuleReference requires the lookup in Field to point at an existing record
+	RuleReference RuleType = "reference"
+	// RuleStale flags records whose DateField and activity are older than StaleDays
+	RuleStale RuleType = "stale"
+)
+
+type Severity string
+
+const (
+	SeverityLow    Severity = "low"
+	SeverityMedium Severity = "medium"
+	SeverityHigh   Severity = "high"
+)
+
+type ViolationStatus string
+
+const (
+	ViolationOpen     ViolationStatus = "open"
+	ViolationResolved ViolationStatus = "resolved"
+	// ViolationIgnored is set by users and survives re-evaluation
+	ViolationIgnored ViolationStatus = "ignored"
+)
+
+// Rule is one data quality check on a module
+type Rule struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID    primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	ModuleName  string             `json:"module_name" bson:"module_name"`
+	Name        string             `json:"name" bson:"name"`
+	Description string             `json:"description,omitempty" bson:"description,omitempty"`
+	Type        RuleType           `json:"type" bson:"type"`
+	Severity    Severity           `json:"severity" bson:"severity"`
+	IsActive    bool               `json:"is_active" bson:"is_active"`
+
+	// Field is the checked field; for stale rules it is the date field and
+	// defaults to updated_at
+	Field string `json:"field,omitempty" bson:"field,omitempty"`
+
+	// required_for_stage
+	StageField string   `json:"stage_field,omitempty" bson:"stage_field,omitempty"` // Default: stage
+	Stages     []string `json:"stages,omitempty" bson:"stages,omitempty"`
+
+	// format
+	Pattern string `json:"pattern,omitempty" bson:"pattern,omitempty"`
+
+	// reference; defaults to the lookup module of Field
+	ReferenceModule string `json:"reference_module,omitempty" bson:"reference_module,omitempty"`
+
+	// stale. When ActivityModules is set, a record is only stale if none of
+	// their records created since the cutoff reference it through ActivityField.
+	StaleDays       int      `json:"stale_days,omitempty" bson:"stale_days,omitempty"`
+	ActivityModules []string `json:"activity_modules,omitempty" bson:"activity_modules,omitempty"`
+	ActivityField   string   `json:"activity_field,omitempty" bson:"activity_field,omitempty"`
+
+	CreatedBy primitive.ObjectID `json:"created_by" bson:"created_by"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// Violation is a record failing a rule, assigned to the record owner
+type Violation struct {
+	ID          primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	TenantID    primitive.ObjectID  `json:"tenant_id" bson:"tenant_id"`
+	RuleID      primitive.ObjectID  `json:"rule_id" bson:"rule_id"`
+	RuleName    string              `json:"rule_name" bson:"rule_name"`
+	ModuleName  string              `json:"module_name" bson:"module_name"`
+	RecordID    primitive.ObjectID  `json:"record_id" bson:"record_id"`
+	Field       string              `json:"field,omitempty" bson:"field,omitempty"`
+	Message     string              `json:"message" bson:"message"`
+	Severity    Severity            `json:"severity" bson:"severity"`
+	AssignedTo  *primitive.ObjectID `json:"assigned_to,omitempty" bson:"assigned_to,omitempty"`
+	Status      ViolationStatus     `json:"status" bson:"status"`
+	FirstSeenAt time.Time           `json:"first_seen_at" bson:"first_seen_at"`
+	LastSeenAt  time.Time           `json:"last_seen_at" bson:"last_seen_at"`
+	ResolvedAt  *time.Time          `json:"resolved_at,omitempty" bson:"resolved_at,omitempty"`
+}
+
+type ViolationFilter struct {
+	ModuleName string
+	RuleID     string
+	Status     ViolationStatus
+	AssignedTo *primitive.ObjectID
+}
+
+// Score is the outcome of one module evaluation. Score is the percentage of
+// checked records without open violations.
+type Score struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID       primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	ModuleName     string             `json:"module_name" bson:"module_name"`
+	Score          float64            `json:"score" bson:"score"`
+	RecordsChecked int                `json:"records_checked" bson:"records_checked"`
+	RecordsFailing int                `json:"records_failing" bson:"records_failing"`
+	Violations     int                `json:"violations" bson:"violations"`
+	ByRule         map[string]int     `json:"by_rule" bson:"by_rule"` // rule ID -> open violations
+	EvaluatedAt    time.Time          `json:"evaluated_at" bson:"evaluated_at"`
+}
+
+type UpdateViolationRequest struct {
+	Status ViolationStatus `json:"status"`
+}
@@ -0,0 +1,381 @@
+package data_quality
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+type RuleRepository interface {
+	Create(ctx context.Context, rule *Rule) error
+	Get(ctx context.Context, id string) (*Rule, error)
+	// List returns the tenant's rules, optionally for one module
+	List(ctx context.Context, moduleName string) ([]Rule, error)
+	Update(ctx context.Context, rule *Rule) error
+	Delete(ctx context.Context, id string) error
+
+	// ListAllActive returns active rules of every tenant for the scheduled run
+	ListAllActive(ctx context.Context) ([]Rule, error)
+}
+
+type RuleRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewRuleRepository(db *database.MongodbDB) RuleRepository {
+	return &RuleRepositoryImpl{
+		collection: db.DB.Collection("data_quality_rules"),
+	}
+}
+
+func (r *RuleRepositoryImpl) Create(ctx context.Context, rule *Rule) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	rule.ID = primitive.NewObjectID()
+	rule.TenantID = tenantID
+	rule.CreatedAt = time.Now()
+	rule.UpdatedAt = rule.CreatedAt
+
+	_, err = r.collection.InsertOne(ctx, rule)
+	return err
+}
+
+func (r *RuleRepositoryImpl) Get(ctx context.Context, id string) (*Rule, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var rule Rule
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID}).Decode(&rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *RuleRepositoryImpl) List(ctx context.Context, moduleName string) ([]Rule, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{"tenant_id": tenantID}
+	if moduleName != "" {
+		filter["module_name"] = moduleName
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "module_name", Value: 1}, {Key: "name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rules := []Rule{}
+	if err := cursor.All(ctx, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (r *RuleRepositoryImpl) Update(ctx context.Context, rule *Rule) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	rule.UpdatedAt = time.Now()
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": rule.ID, "tenant_id": tenantID}, rule)
+	return err
+}
+
+func (r *RuleRepositoryImpl) Delete(ctx context.Context, id string) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID})
+	return err
+}
+
+func (r *RuleRepositoryImpl) ListAllActive(ctx context.Context) ([]Rule, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "tenant_id", Value: 1}, {Key: "module_name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"is_active": true}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rules []Rule
+	if err := cursor.All(ctx, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+type ViolationRepository interface {
+	// Record stores a violation found at seenAt. New and previously resolved
+	// violations are (re)opened; ignored ones stay ignored. It returns the
+	// resulting status and whether the violation was newly opened.
+	Record(ctx context.Context, v *Violation, seenAt time.Time) (ViolationStatus, bool, error)
+	// ResolveUnseen resolves open violations of the given rules not seen since seenAt
+	ResolveUnseen(ctx context.Context, ruleIDs []primitive.ObjectID, seenAt time.Time) error
+	Get(ctx context.Context, id string) (*Violation, error)
+	List(ctx context.Context, filter ViolationFilter, limit, offset int64) ([]Violation, int64, error)
+	SetStatus(ctx context.Context, id primitive.ObjectID, status ViolationStatus) error
+	DeleteByRule(ctx context.Context, ruleID primitive.ObjectID) error
+}
+
+type ViolationRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewViolationRepository(db *database.MongodbDB) ViolationRepository {
+	return &ViolationRepositoryImpl{
+		collection: db.DB.Collection("data_quality_violations"),
+	}
+}
+
+func (r *ViolationRepositoryImpl) Record(ctx context.Context, v *Violation, seenAt time.Time) (ViolationStatus, bool, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	key := bson.M{"tenant_id": tenantID, "rule_id": v.RuleID, "record_id": v.RecordID}
+
+	var existing Violation
+	err = r.collection.FindOne(ctx, key).Decode(&existing)
+	if err == mongo.ErrNoDocuments {
+		v.ID = primitive.NewObjectID()
+		v.TenantID = tenantID
+		v.Status = ViolationOpen
+		v.FirstSeenAt = seenAt
+		v.LastSeenAt = seenAt
+		v.ResolvedAt = nil
+		_, err = r.collection.InsertOne(ctx, v)
+		return ViolationOpen, err == nil, err
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	set := bson.M{
+		"rule_name":    v.RuleName,
+		"field":        v.Field,
+		"message":      v.Message,
+		"severity":     v.Severity,
+		"assigned_to":  v.AssignedTo,
+		"last_seen_at": seenAt,
+	}
+	update := bson.M{"$set": set}
+	status := existing.Status
+	reopened := false
+	if existing.Status == ViolationResolved {
+		status = ViolationOpen
+		reopened = true
+		set["status"] = ViolationOpen
+		set["first_seen_at"] = seenAt
+		update["$unset"] = bson.M{"resolved_at": ""}
+	}
+	_, err = r.collection.UpdateOne(ctx, bson.M{"_id": existing.ID}, update)
+	return status, reopened, err
+}
+
+func (r *ViolationRepositoryImpl) ResolveUnseen(ctx context.Context, ruleIDs []primitive.ObjectID, seenAt time.Time) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.UpdateMany(ctx, bson.M{
+		"tenant_id":    tenantID,
+		"rule_id":      bson.M{"$in": ruleIDs},
+		"status":       ViolationOpen,
+		"last_seen_at": bson.M{"$lt": seenAt},
+	}, bson.M{"$set": bson.M{"status": ViolationResolved, "resolved_at": time.Now()}})
+	return err
+}
+
+func (r *ViolationRepositoryImpl) Get(ctx context.Context, id string) (*Violation, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var v Violation
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID}).Decode(&v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *ViolationRepositoryImpl) List(ctx context.Context, filter ViolationFilter, limit, offset int64) ([]Violation, int64, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := bson.M{"tenant_id": tenantID}
+	if filter.ModuleName != "" {
+		query["module_name"] = filter.ModuleName
+	}
+	if filter.RuleID != "" {
+		ruleID, err := primitive.ObjectIDFromHex(filter.RuleID)
+		if err != nil {
+			return nil, 0, err
+		}
+		query["rule_id"] = ruleID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.AssignedTo != nil {
+		query["assigned_to"] = *filter.AssignedTo
+	}
+
+	total, err := r.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "last_seen_at", Value: -1}, {Key: "_id", Value: 1}}).
+		SetLimit(limit).
+		SetSkip(offset)
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	violations := []Violation{}
+	if err := cursor.All(ctx, &violations); err != nil {
+		return nil, 0, err
+	}
+	return violations, total, nil
+}
+
+func (r *ViolationRepositoryImpl) SetStatus(ctx context.Context, id primitive.ObjectID, status ViolationStatus) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	update := bson.M{"$set": bson.M{"status": status}}
+	if status == ViolationResolved {
+		update["$set"].(bson.M)["resolved_at"] = time.Now()
+	} else {
+		update["$unset"] = bson.M{"resolved_at": ""}
+	}
+	_, err = r.collection.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}, update)
+	return err
+}
+
+func (r *ViolationRepositoryImpl) DeleteByRule(ctx context.Context, ruleID primitive.ObjectID) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.DeleteMany(ctx, bson.M{"tenant_id": tenantID, "rule_id": ruleID})
+	return err
+}
+
+type ScoreRepository interface {
+	Create(ctx context.Context, score *Score) error
+	// Latest returns the most recent score of each module
+	Latest(ctx context.Context) ([]Score, error)
+	History(ctx context.Context, moduleName string, limit int64) ([]Score, error)
+}
+
+type ScoreRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewScoreRepository(db *database.MongodbDB) ScoreRepository {
+	return &ScoreRepositoryImpl{
+		collection: db.DB.Collection("data_quality_scores"),
+	}
+}
+
+func (r *ScoreRepositoryImpl) Create(ctx context.Context, score *Score) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	score.ID = primitive.NewObjectID()
+	score.TenantID = tenantID
+
+	_, err = r.collection.InsertOne(ctx, score)
+	return err
+}
+
+func (r *ScoreRepositoryImpl) Latest(ctx context.Context) ([]Score, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"tenant_id": tenantID}}},
+		{{Key: "$sort", Value: bson.M{"evaluated_at": -1}}},
+		{{Key: "$group", Value: bson.M{"_id": "$module_name", "doc": bson.M{"$first": "$$ROOT"}}}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$doc"}}},
+		{{Key: "$sort", Value: bson.M{"module_name": 1}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	scores := []Score{}
+	if err := cursor.All(ctx, &scores); err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
+
+func (r *ScoreRepositoryImpl) History(ctx context.Context, moduleName string, limit int64) ([]Score, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.M{"evaluated_at": -1}).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, bson.M{"tenant_id": tenantID, "module_name": moduleName}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	scores := []Score{}
+	if err := cursor.All(ctx, &scores); err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
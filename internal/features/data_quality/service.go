@@ -0,0 +1,327 @@
+package data_quality
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/notification"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/role"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EvaluationSchedule runs every active rule nightly
+const EvaluationSchedule = "0 2 * * *"
+
+var (
+	ErrEvaluationRunning = apperr.Conflict("an evaluation is already running for this module")
+	ErrViolationNotFound = apperr.NotFound("violation not found")
+	ErrNotAssigned       = apperr.PermissionDenied("violation is not assigned to you")
+)
+
+// Fields every record has without being declared on the module
+var systemFields = map[string]bool{"created_at": true, "updated_at": true, "created_by": true, "updated_by": true, "owner": true}
+
+type DataQualityService interface {
+	CreateRule(ctx context.Context, rule *Rule, userID primitive.ObjectID) error
+	GetRule(ctx context.Context, id string) (*Rule, error)
+	ListRules(ctx context.Context, moduleName string) ([]Rule, error)
+	UpdateRule(ctx context.Context, id string, rule *Rule) (*Rule, error)
+	DeleteRule(ctx context.Context, id string) error
+
+	// Evaluate runs the module's active rules now and returns the new score
+	Evaluate(ctx context.Context, moduleName string) (*Score, error)
+	// EvaluateAll runs every tenant's active rules; registered as a system job
+	EvaluateAll(ctx context.Context) error
+
+	ListScores(ctx context.Context) ([]Score, error)
+	ScoreHistory(ctx context.Context, moduleName string, limit int64) ([]Score, error)
+	ListViolations(ctx context.Context, filter ViolationFilter, page, limit int64) ([]Violation, int64, error)
+	// UpdateViolation resolves, ignores or reopens a violation. Assignees may
+	// update their own; anyone else needs settings update permission.
+	UpdateViolation(ctx context.Context, id string, status ViolationStatus, userID primitive.ObjectID) (*Violation, error)
+}
+
+type DataQualityServiceImpl struct {
+	RuleRepo            RuleRepository
+	ViolationRepo       ViolationRepository
+	ScoreRepo           ScoreRepository
+	ModuleRepo          module.ModuleRepository
+	RecordRepo          record.RecordRepository
+	RoleService         role.RoleService
+	NotificationService notification.NotificationService
+	AuditService        audit.AuditService
+
+	running sync.Map // tenant/module -> struct{}
+}
+
+func NewDataQualityService(
+	ruleRepo RuleRepository,
+	violationRepo ViolationRepository,
+	scoreRepo ScoreRepository,
+	moduleRepo module.ModuleRepository,
+	recordRepo record.RecordRepository,
+	roleService role.RoleService,
+	notificationService notification.NotificationService,
+	auditService audit.AuditService,
+) DataQualityService {
+	return &DataQualityServiceImpl{
+		RuleRepo:            ruleRepo,
+		ViolationRepo:       violationRepo,
+		ScoreRepo:           scoreRepo,
+		ModuleRepo:          moduleRepo,
+		RecordRepo:          recordRepo,
+		RoleService:         roleService,
+		NotificationService: notificationService,
+		AuditService:        auditService,
+	}
+}
+
+func (s *DataQualityServiceImpl) validate(ctx context.Context, rule *Rule) error {
+	if rule.Name == "" || rule.ModuleName == "" {
+		return errors.New("name and module_name are required")
+	}
+	m, err := s.ModuleRepo.FindByName(ctx, rule.ModuleName)
+	if err != nil {
+		return fmt.Errorf("module '%s' not found", rule.ModuleName)
+	}
+	fields := make(map[string]common_models.ModuleField, len(m.Fields))
+	for _, f := range m.Fields {
+		fields[f.Name] = f
+	}
+	hasField := func(name string) bool {
+		_, ok := fields[name]
+		return ok || systemFields[name]
+	}
+
+	switch rule.Severity {
+	case "":
+		rule.Severity = SeverityMedium
+	case SeverityLow, SeverityMedium, SeverityHigh:
+	default:
+		return errors.New("severity must be low, medium or high")
+	}
+
+	switch rule.Type {
+	case RuleRequiredForStage:
+		if rule.StageField == "" {
+			rule.StageField = "stage"
+		}
+		if len(rule.Stages) == 0 {
+			return errors.New("stages are required")
+		}
+		if !hasField(rule.StageField) {
+			return fmt.Errorf("unknown field '%s'", rule.StageField)
+		}
+	case RuleFormat:
+		if rule.Pattern == "" {
+			return errors.New("pattern is required")
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	case RuleReference:
+		if rule.ReferenceModule == "" {
+			if f, ok := fields[rule.Field]; ok && f.Lookup != nil {
+				rule.ReferenceModule = f.Lookup.LookupModule
+			}
+		}
+		if rule.ReferenceModule == "" {
+			return errors.New("reference_module is required when field is not a lookup")
+		}
+	case RuleStale:
+		if rule.Field == "" {
+			rule.Field = "updated_at"
+		}
+		if rule.StaleDays <= 0 {
+			return errors.New("stale_days must be positive")
+		}
+		if len(rule.ActivityModules) > 0 && rule.ActivityField == "" {
+			return errors.New("activity_field is required with activity_modules")
+		}
+	default:
+		return fmt.Errorf("unknown rule type: %s", rule.Type)
+	}
+
+	if rule.Field == "" {
+		return errors.New("field is required")
+	}
+	if !hasField(rule.Field) {
+		return fmt.Errorf("unknown field '%s'", rule.Field)
+	}
+	return nil
+}
+
+func (s *DataQualityServiceImpl) CreateRule(ctx context.Context, rule *Rule, userID primitive.ObjectID) error {
+	if err := s.validate(ctx, rule); err != nil {
+		return err
+	}
+	rule.CreatedBy = userID
+	if err := s.RuleRepo.Create(ctx, rule); err != nil {
+		return err
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "data_quality", rule.Name, map[string]common_models.Change{
+		"rule": {New: rule},
+	})
+	return nil
+}
+
+func (s *DataQualityServiceImpl) GetRule(ctx context.Context, id string) (*Rule, error) {
+	return s.RuleRepo.Get(ctx, id)
+}
+
+func (s *DataQualityServiceImpl) ListRules(ctx context.Context, moduleName string) ([]Rule, error) {
+	return s.RuleRepo.List(ctx, moduleName)
+}
+
+func (s *DataQualityServiceImpl) UpdateRule(ctx context.Context, id string, in *Rule) (*Rule, error) {
+	existing, err := s.RuleRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	old := *existing
+
+	in.ID = existing.ID
+	in.TenantID = existing.TenantID
+	in.CreatedBy = existing.CreatedBy
+	in.CreatedAt = existing.CreatedAt
+	if err := s.validate(ctx, in); err != nil {
+		return nil, err
+	}
+	if err := s.RuleRepo.Update(ctx, in); err != nil {
+		return nil, err
+	}
+	// Findings of a different check, or of a disabled one, no longer apply
+	if !in.IsActive || in.Type != old.Type || in.ModuleName != old.ModuleName {
+		_ = s.ViolationRepo.DeleteByRule(ctx, in.ID)
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "data_quality", in.Name, map[string]common_models.Change{
+		"rule": {Old: old, New: in},
+	})
+	return in, nil
+}
+
+func (s *DataQualityServiceImpl) DeleteRule(ctx context.Context, id string) error {
+	rule, err := s.RuleRepo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.RuleRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	_ = s.ViolationRepo.DeleteByRule(ctx, rule.ID)
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "data_quality", rule.Name, map[string]common_models.Change{
+		"rule": {Old: rule.Name, New: "DELETED"},
+	})
+	return nil
+}
+
+func (s *DataQualityServiceImpl) Evaluate(ctx context.Context, moduleName string) (*Score, error) {
+	rules, err := s.RuleRepo.List(ctx, moduleName)
+	if err != nil {
+		return nil, err
+	}
+	active := rules[:0]
+	for _, r := range rules {
+		if r.IsActive {
+			active = append(active, r)
+		}
+	}
+	if len(active) == 0 {
+		return nil, fmt.Errorf("module '%s' has no active data quality rules", moduleName)
+	}
+	tenantID, _ := tenantFromContext(ctx)
+	return s.evaluateLocked(ctx, tenantID, moduleName, active)
+}
+
+func (s *DataQualityServiceImpl) EvaluateAll(ctx context.Context) error {
+	rules, err := s.RuleRepo.ListAllActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Rules arrive sorted by tenant and module
+	for start := 0; start < len(rules); {
+		end := start + 1
+		for end < len(rules) && rules[end].TenantID == rules[start].TenantID && rules[end].ModuleName == rules[start].ModuleName {
+			end++
+		}
+		tenantID, moduleName := rules[start].TenantID, rules[start].ModuleName
+		tenantCtx := context.WithValue(ctx, common_models.TenantIDKey, tenantID.Hex())
+		if _, err := s.evaluateLocked(tenantCtx, tenantID, moduleName, rules[start:end]); err != nil && !errors.Is(err, ErrEvaluationRunning) {
+			log.Printf("data quality: evaluation of %s for tenant %s failed: %v", moduleName, tenantID.Hex(), err)
+		}
+		start = end
+	}
+	return nil
+}
+
+func (s *DataQualityServiceImpl) evaluateLocked(ctx context.Context, tenantID primitive.ObjectID, moduleName string, rules []Rule) (*Score, error) {
+	key := tenantID.Hex() + "/" + moduleName
+	if _, busy := s.running.LoadOrStore(key, struct{}{}); busy {
+		return nil, ErrEvaluationRunning
+	}
+	defer s.running.Delete(key)
+	return s.evaluate(ctx, moduleName, rules)
+}
+
+func (s *DataQualityServiceImpl) ListScores(ctx context.Context) ([]Score, error) {
+	return s.ScoreRepo.Latest(ctx)
+}
+
+func (s *DataQualityServiceImpl) ScoreHistory(ctx context.Context, moduleName string, limit int64) ([]Score, error) {
+	if limit <= 0 {
+		limit = 30
+	}
+	return s.ScoreRepo.History(ctx, moduleName, limit)
+}
+
+func (s *DataQualityServiceImpl) ListViolations(ctx context.Context, filter ViolationFilter, page, limit int64) ([]Violation, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	return s.ViolationRepo.List(ctx, filter, limit, (page-1)*limit)
+}
+
+func (s *DataQualityServiceImpl) UpdateViolation(ctx context.Context, id string, status ViolationStatus, userID primitive.ObjectID) (*Violation, error) {
+	switch status {
+	case ViolationOpen, ViolationResolved, ViolationIgnored:
+	default:
+		return nil, errors.New("status must be open, resolved or ignored")
+	}
+	v, err := s.ViolationRepo.Get(ctx, id)
+	if err != nil {
+		return nil, ErrViolationNotFound
+	}
+	if v.AssignedTo == nil || *v.AssignedTo != userID {
+		allowed, err := s.RoleService.CheckPermission(ctx, userID, "settings", "update")
+		if err != nil || !allowed {
+			return nil, ErrNotAssigned
+		}
+	}
+	if err := s.ViolationRepo.SetStatus(ctx, v.ID, status); err != nil {
+		return nil, err
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionUpdate, v.ModuleName, v.RecordID.Hex(), map[string]common_models.Change{
+		"data_quality": {Old: strings.Join([]string{v.RuleName, string(v.Status)}, ": "), New: strings.Join([]string{v.RuleName, string(status)}, ": ")},
+	})
+	v.Status = status
+	return v, nil
+}
@@ -0,0 +1,36 @@
+package dedupe
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/features/role"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type DedupeApi struct {
+	controller  *DedupeController
+	config      *config.Config
+	roleService role.RoleService
+}
+
+func NewDedupeApi(controller *DedupeController, config *config.Config, roleService role.RoleService) *DedupeApi {
+	return &DedupeApi{
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *DedupeApi) Setup(app *fiber.App) {
+	dedupe := app.Group("/api/dedupe", middleware.AuthMiddleware(h.config.SkipAuth))
+
+	dedupe.Post("/jobs", middleware.RequirePermission(h.roleService, "dedupe", "create"), h.controller.StartJob)
+	dedupe.Get("/jobs", middleware.RequirePermission(h.roleService, "dedupe", "read"), h.controller.ListJobs)
+	dedupe.Get("/jobs/:id", middleware.RequirePermission(h.roleService, "dedupe", "read"), h.controller.GetJob)
+
+	dedupe.Get("/sets", middleware.RequirePermission(h.roleService, "dedupe", "read"), h.controller.ListSets)
+	dedupe.Get("/sets/:id", middleware.RequirePermission(h.roleService, "dedupe", "read"), h.controller.GetSet)
+	dedupe.Post("/sets/:id/merge", middleware.RequirePermission(h.roleService, "dedupe", "update"), h.controller.MergeSet)
+	dedupe.Post("/sets/:id/dismiss", middleware.RequirePermission(h.roleService, "dedupe", "update"), h.controller.DismissSet)
+}
@@ -0,0 +1,210 @@
+package dedupe
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"go-crm/internal/common/session"
+)
+
+type DedupeController struct {
+	DedupeService DedupeService
+}
+
+func NewDedupeController(dedupeService DedupeService) *DedupeController {
+	return &DedupeController{
+		DedupeService: dedupeService,
+	}
+}
+
+// StartJob godoc
+// @Summary Start dedupe job
+// @Description Scan an entire module for duplicates using the given match rules
+// @Tags dedupe
+// @Accept json
+// @Produce json
+// @Param job body DedupeJob true "Job Details (module_name, rules, min_confidence)"
+// @Success 202 {object} DedupeJob
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/dedupe/jobs [post]
+func (c *DedupeController) StartJob(ctx *fiber.Ctx) error {
+	var req struct {
+		ModuleName    string      `json:"module_name"`
+		Rules         []MatchRule `json:"rules"`
+		MinConfidence float64     `json:"min_confidence"`
+	}
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	job := DedupeJob{
+		UserID:        userID,
+		ModuleName:    req.ModuleName,
+		Rules:         req.Rules,
+		MinConfidence: req.MinConfidence,
+	}
+	if err := c.DedupeService.StartJob(ctx.UserContext(), &job); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.Status(fiber.StatusAccepted).JSON(job)
+}
+
+// ListJobs godoc
+// @Summary List dedupe jobs
+// @Description List recent dedupe jobs, optionally for a single module
+// @Tags dedupe
+// @Produce json
+// @Param module query string false "Module name"
+// @Success 200 {array} DedupeJob
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/dedupe/jobs [get]
+func (c *DedupeController) ListJobs(ctx *fiber.Ctx) error {
+	jobs, err := c.DedupeService.ListJobs(ctx.UserContext(), ctx.Query("module"))
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(jobs)
+}
+
+// GetJob godoc
+// @Summary Get dedupe job
+// @Description Get progress and result counts of a dedupe job
+// @Tags dedupe
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} DedupeJob
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/dedupe/jobs/{id} [get]
+func (c *DedupeController) GetJob(ctx *fiber.Ctx) error {
+	job, err := c.DedupeService.GetJob(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Job not found"})
+	}
+	return ctx.JSON(job)
+}
+
+// ListSets godoc
+// @Summary List duplicate sets
+// @Description List candidate duplicate sets ordered by confidence
+// @Tags dedupe
+// @Produce json
+// @Param module query string false "Module name"
+// @Param job_id query string false "Job ID"
+// @Param status query string false "Status (pending, merged, dismissed)"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/dedupe/sets [get]
+func (c *DedupeController) ListSets(ctx *fiber.Ctx) error {
+	filter := make(map[string]interface{})
+	if moduleName := ctx.Query("module"); moduleName != "" {
+		filter["module_name"] = moduleName
+	}
+	if jobID := ctx.Query("job_id"); jobID != "" {
+		oid, err := primitive.ObjectIDFromHex(jobID)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid job ID"})
+		}
+		filter["job_id"] = oid
+	}
+	if status := ctx.Query("status", string(DuplicateSetPending)); status != "all" {
+		filter["status"] = status
+	}
+
+	page := int64(ctx.QueryInt("page", 1))
+	limit := int64(ctx.QueryInt("limit", 20))
+
+	sets, total, err := c.DedupeService.ListSets(ctx.UserContext(), filter, page, limit)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"data":  sets,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// GetSet godoc
+// @Summary Get duplicate set
+// @Description Get a duplicate set with the current data of each record for review
+// @Tags dedupe
+// @Produce json
+// @Param id path string true "Set ID"
+// @Success 200 {object} DuplicateSet
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/dedupe/sets/{id} [get]
+func (c *DedupeController) GetSet(ctx *fiber.Ctx) error {
+	set, err := c.DedupeService.GetSet(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Duplicate set not found"})
+	}
+	return ctx.JSON(set)
+}
+
+// MergeSet godoc
+// @Summary Merge duplicate set
+// @Description Merge all records in a set into the chosen master record
+// @Tags dedupe
+// @Accept json
+// @Produce json
+// @Param id path string true "Set ID"
+// @Param request body MergeRequest true "Merge options"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/dedupe/sets/{id}/merge [post]
+func (c *DedupeController) MergeSet(ctx *fiber.Ctx) error {
+	var req MergeRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	master, err := c.DedupeService.MergeSet(ctx.UserContext(), ctx.Params("id"), req, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "Records merged successfully",
+		"record":  master,
+	})
+}
+
+// DismissSet godoc
+// @Summary Dismiss duplicate set
+// @Description Mark a candidate set as not duplicates
+// @Tags dedupe
+// @Produce json
+// @Param id path string true "Set ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/dedupe/sets/{id}/dismiss [post]
+func (c *DedupeController) DismissSet(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if err := c.DedupeService.DismissSet(ctx.UserContext(), ctx.Params("id"), userID); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Duplicate set dismissed"})
+}
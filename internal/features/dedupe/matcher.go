@@ -0,0 +1,280 @@
+package dedupe
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// candidate is a record prepared for matching with all rule values pre-normalized
+type candidate struct {
+	ID       string
+	Values   []string         // Normalized value per rule, "" when missing
+	Trigrams []map[string]int // Trigram sets per rule, only populated for fuzzy rules
+}
+
+// pairScore is the weighted similarity between two candidates
+type pairScore struct {
+	A, B       int
+	Confidence float64
+	MatchedOn  []string
+}
+
+// matchGroup is a connected component of candidates linked by high-confidence pairs
+type matchGroup struct {
+	Members    []int
+	Confidence float64
+	MatchedOn  []string
+}
+
+// maxBlockSize skips blocking keys shared by too many records (e.g. a placeholder
+// phone number), which would otherwise explode the number of compared pairs
+const maxBlockSize = 500
+
+func normalizeValue(t MatchType, v any) string {
+	if v == nil {
+		return ""
+	}
+	s := strings.TrimSpace(fmt.Sprint(v))
+	switch t {
+	case MatchTypeEmail:
+		s = strings.ToLower(s)
+		at := strings.LastIndex(s, "@")
+		if at <= 0 {
+			return s
+		}
+		local, domain := s[:at], s[at+1:]
+		if plus := strings.Index(local, "+"); plus > 0 {
+			local = local[:plus]
+		}
+		return local + "@" + domain
+	case MatchTypePhone:
+		var b strings.Builder
+		for _, r := range s {
+			if unicode.IsDigit(r) {
+				b.WriteRune(r)
+			}
+		}
+		digits := b.String()
+		if len(digits) < 7 {
+			return ""
+		}
+		if len(digits) > 10 {
+			digits = digits[len(digits)-10:]
+		}
+		return digits
+	case MatchTypeFuzzy:
+		var b strings.Builder
+		space := false
+		for _, r := range strings.ToLower(s) {
+			switch {
+			case unicode.IsLetter(r) || unicode.IsDigit(r):
+				b.WriteRune(r)
+				space = false
+			case !space && b.Len() > 0:
+				b.WriteRune(' ')
+				space = true
+			}
+		}
+		return strings.TrimSpace(b.String())
+	default:
+		return strings.ToLower(s)
+	}
+}
+
+func trigrams(s string) map[string]int {
+	grams := make(map[string]int)
+	if s == "" {
+		return grams
+	}
+	padded := []rune("  " + s + " ")
+	for i := 0; i+3 <= len(padded); i++ {
+		grams[string(padded[i:i+3])]++
+	}
+	return grams
+}
+
+// trigramSimilarity returns the Jaccard similarity of two trigram sets
+func trigramSimilarity(a, b map[string]int) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for g := range a {
+		if _, ok := b[g]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+func buildCandidates(records []map[string]any, rules []MatchRule) []candidate {
+	candidates := make([]candidate, 0, len(records))
+	for _, rec := range records {
+		id, ok := rec["_id"].(primitive.ObjectID)
+		if !ok {
+			continue
+		}
+		c := candidate{
+			ID:       id.Hex(),
+			Values:   make([]string, len(rules)),
+			Trigrams: make([]map[string]int, len(rules)),
+		}
+		for i, rule := range rules {
+			c.Values[i] = normalizeValue(rule.Type, rec[rule.Field])
+			if rule.Type == MatchTypeFuzzy {
+				c.Trigrams[i] = trigrams(c.Values[i])
+			}
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates
+}
+
+// candidatePairs uses blocking so that only records sharing a normalized value
+// (or, for fuzzy rules, a trigram) are ever compared
+func candidatePairs(candidates []candidate, rules []MatchRule) map[[2]int]struct{} {
+	pairs := make(map[[2]int]struct{})
+	addBlock := func(members []int) {
+		if len(members) < 2 || len(members) > maxBlockSize {
+			return
+		}
+		for i := 0; i < len(members); i++ {
+			for j := i + 1; j < len(members); j++ {
+				pairs[[2]int{members[i], members[j]}] = struct{}{}
+			}
+		}
+	}
+
+	for r, rule := range rules {
+		blocks := make(map[string][]int)
+		for i, c := range candidates {
+			if c.Values[r] == "" {
+				continue
+			}
+			if rule.Type == MatchTypeFuzzy {
+				for g := range c.Trigrams[r] {
+					blocks[g] = append(blocks[g], i)
+				}
+			} else {
+				blocks[c.Values[r]] = append(blocks[c.Values[r]], i)
+			}
+		}
+		for _, members := range blocks {
+			addBlock(members)
+		}
+	}
+	return pairs
+}
+
+func scorePair(a, b *candidate, rules []MatchRule) (float64, []string) {
+	var score, totalWeight float64
+	var matched []string
+
+	for r, rule := range rules {
+		if a.Values[r] == "" || b.Values[r] == "" {
+			continue
+		}
+		weight := rule.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		totalWeight += weight
+
+		var sim float64
+		if rule.Type == MatchTypeFuzzy {
+			sim = trigramSimilarity(a.Trigrams[r], b.Trigrams[r])
+			threshold := rule.Threshold
+			if threshold <= 0 {
+				threshold = DefaultFuzzyThreshold
+			}
+			if sim < threshold {
+				sim = 0
+			}
+		} else if a.Values[r] == b.Values[r] {
+			sim = 1
+		}
+
+		if sim > 0 {
+			score += weight * sim
+			matched = append(matched, rule.Field)
+		}
+	}
+
+	if totalWeight == 0 {
+		return 0, nil
+	}
+	return score / totalWeight, matched
+}
+
+// findDuplicateGroups scores candidate pairs and unions those above minConfidence
+func findDuplicateGroups(candidates []candidate, rules []MatchRule, minConfidence float64) []matchGroup {
+	parent := make([]int, len(candidates))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	var links []pairScore
+	for pair := range candidatePairs(candidates, rules) {
+		confidence, matched := scorePair(&candidates[pair[0]], &candidates[pair[1]], rules)
+		if confidence < minConfidence {
+			continue
+		}
+		links = append(links, pairScore{A: pair[0], B: pair[1], Confidence: confidence, MatchedOn: matched})
+		if ra, rb := find(pair[0]), find(pair[1]); ra != rb {
+			parent[rb] = ra
+		}
+	}
+
+	type accumulator struct {
+		members map[int]struct{}
+		total   float64
+		links   int
+		fields  map[string]struct{}
+	}
+	byRoot := make(map[int]*accumulator)
+	for _, link := range links {
+		root := find(link.A)
+		acc, ok := byRoot[root]
+		if !ok {
+			acc = &accumulator{members: map[int]struct{}{}, fields: map[string]struct{}{}}
+			byRoot[root] = acc
+		}
+		acc.members[link.A] = struct{}{}
+		acc.members[link.B] = struct{}{}
+		acc.total += link.Confidence
+		acc.links++
+		for _, f := range link.MatchedOn {
+			acc.fields[f] = struct{}{}
+		}
+	}
+
+	groups := make([]matchGroup, 0, len(byRoot))
+	for _, acc := range byRoot {
+		g := matchGroup{Confidence: acc.total / float64(acc.links)}
+		for m := range acc.members {
+			g.Members = append(g.Members, m)
+		}
+		for f := range acc.fields {
+			g.MatchedOn = append(g.MatchedOn, f)
+		}
+		sort.Ints(g.Members)
+		sort.Strings(g.MatchedOn)
+		groups = append(groups, g)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Confidence > groups[j].Confidence
+	})
+	return groups
+}
@@ -0,0 +1,93 @@
+package dedupe
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MatchType defines how a field is compared between two records
+type MatchType string
+
+const (
+	MatchTypeExact MatchType = "exact" // Case-insensitive, trimmed equality
+	MatchTypeEmail MatchType = "email" // Normalized email (lowercase, "+tag" removed)
+	MatchTypePhone MatchType = "phone" // Digits only, compared on the last 10 digits
+	MatchTypeFuzzy MatchType = "fuzzy" // Trigram similarity, typically for names
+)
+
+const (
+	DefaultFuzzyThreshold = 0.6
+	DefaultMinConfidence  = 0.7
+	MaxScanRecords        = 50000
+)
+
+// MatchRule configures one field comparison used when scoring candidate pairs
+type MatchRule struct {
+	Field     string    `json:"field" bson:"field"`
+	Type      MatchType `json:"type" bson:"type"`
+	Weight    float64   `json:"weight,omitempty" bson:"weight,omitempty"`       // Defaults to 1
+	Threshold float64   `json:"threshold,omitempty" bson:"threshold,omitempty"` // Fuzzy only, defaults to DefaultFuzzyThreshold
+}
+
+type DedupeJobStatus string
+
+const (
+	DedupeJobPending    DedupeJobStatus = "pending"
+	DedupeJobProcessing DedupeJobStatus = "processing"
+	DedupeJobCompleted  DedupeJobStatus = "completed"
+	DedupeJobFailed     DedupeJobStatus = "failed"
+)
+
+// DedupeJob scans an entire module and produces candidate duplicate sets
+type DedupeJob struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID       primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	UserID         primitive.ObjectID `json:"user_id" bson:"user_id"`
+	ModuleName     string             `json:"module_name" bson:"module_name"`
+	Rules          []MatchRule        `json:"rules" bson:"rules"`
+	MinConfidence  float64            `json:"min_confidence" bson:"min_confidence"`
+	Status         DedupeJobStatus    `json:"status" bson:"status"`
+	TotalRecords   int                `json:"total_records" bson:"total_records"`
+	ProcessedCount int                `json:"processed_count" bson:"processed_count"`
+	SetCount       int                `json:"set_count" bson:"set_count"`
+	Error          string             `json:"error,omitempty" bson:"error,omitempty"`
+	CreatedAt      time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at" bson:"updated_at"`
+	CompletedAt    *time.Time         `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+}
+
+type DuplicateSetStatus string
+
+const (
+	DuplicateSetPending   DuplicateSetStatus = "pending"
+	DuplicateSetMerged    DuplicateSetStatus = "merged"
+	DuplicateSetDismissed DuplicateSetStatus = "dismissed"
+)
+
+// DuplicateSet is a group of records believed to describe the same entity
+type DuplicateSet struct {
+	ID         primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	TenantID   primitive.ObjectID  `json:"tenant_id" bson:"tenant_id"`
+	JobID      primitive.ObjectID  `json:"job_id" bson:"job_id"`
+	ModuleName string              `json:"module_name" bson:"module_name"`
+	RecordIDs  []string            `json:"record_ids" bson:"record_ids"`
+	Confidence float64             `json:"confidence" bson:"confidence"` // 0-1, average of the linking pair scores
+	MatchedOn  []string            `json:"matched_on" bson:"matched_on"`
+	Status     DuplicateSetStatus  `json:"status" bson:"status"`
+	MasterID   string              `json:"master_id,omitempty" bson:"master_id,omitempty"`
+	ResolvedBy *primitive.ObjectID `json:"resolved_by,omitempty" bson:"resolved_by,omitempty"`
+	ResolvedAt *time.Time          `json:"resolved_at,omitempty" bson:"resolved_at,omitempty"`
+	CreatedAt  time.Time           `json:"created_at" bson:"created_at"`
+
+	// Populated on read for review, not persisted
+	Records []map[string]any `json:"records,omitempty" bson:"-"`
+}
+
+// MergeRequest resolves a duplicate set into a single master record
+type MergeRequest struct {
+	MasterID string `json:"master_id"`
+	// Optional per-field source: field name -> record ID whose value should win.
+	// Fields not listed keep the master value, falling back to the first non-empty duplicate value.
+	FieldSources map[string]string `json:"field_sources,omitempty"`
+}
@@ -0,0 +1,205 @@
+package dedupe
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type DedupeRepository interface {
+	CreateJob(ctx context.Context, job *DedupeJob) error
+	GetJob(ctx context.Context, id string) (*DedupeJob, error)
+	UpdateJob(ctx context.Context, job *DedupeJob) error
+	ListJobs(ctx context.Context, moduleName string, limit int64) ([]DedupeJob, error)
+
+	CreateSets(ctx context.Context, sets []DuplicateSet) error
+	DeletePendingSets(ctx context.Context, moduleName string) error
+	GetSet(ctx context.Context, id string) (*DuplicateSet, error)
+	ListSets(ctx context.Context, filter bson.M, limit, offset int64) ([]DuplicateSet, int64, error)
+	UpdateSet(ctx context.Context, set *DuplicateSet) error
+}
+
+type DedupeRepositoryImpl struct {
+	jobs *mongo.Collection
+	sets *mongo.Collection
+}
+
+func NewDedupeRepository(db *database.MongodbDB) DedupeRepository {
+	return &DedupeRepositoryImpl{
+		jobs: db.DB.Collection("dedupe_jobs"),
+		sets: db.DB.Collection("duplicate_sets"),
+	}
+}
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+func (r *DedupeRepositoryImpl) CreateJob(ctx context.Context, job *DedupeJob) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if job.ID.IsZero() {
+		job.ID = primitive.NewObjectID()
+	}
+	job.TenantID = tenantID
+	job.Status = DedupeJobPending
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = time.Now()
+
+	_, err = r.jobs.InsertOne(ctx, job)
+	return err
+}
+
+func (r *DedupeRepositoryImpl) GetJob(ctx context.Context, id string) (*DedupeJob, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var job DedupeJob
+	if err := r.jobs.FindOne(ctx, bson.M{"_id": objID, "tenant_id": tenantID}).Decode(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *DedupeRepositoryImpl) UpdateJob(ctx context.Context, job *DedupeJob) error {
+	job.UpdatedAt = time.Now()
+	_, err := r.jobs.ReplaceOne(ctx, bson.M{"_id": job.ID, "tenant_id": job.TenantID}, job)
+	return err
+}
+
+func (r *DedupeRepositoryImpl) ListJobs(ctx context.Context, moduleName string, limit int64) ([]DedupeJob, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"tenant_id": tenantID}
+	if moduleName != "" {
+		filter["module_name"] = moduleName
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+	cursor, err := r.jobs.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	jobs := []DedupeJob{}
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (r *DedupeRepositoryImpl) CreateSets(ctx context.Context, sets []DuplicateSet) error {
+	if len(sets) == 0 {
+		return nil
+	}
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	docs := make([]interface{}, len(sets))
+	for i := range sets {
+		if sets[i].ID.IsZero() {
+			sets[i].ID = primitive.NewObjectID()
+		}
+		sets[i].TenantID = tenantID
+		sets[i].CreatedAt = time.Now()
+		docs[i] = sets[i]
+	}
+
+	_, err = r.sets.InsertMany(ctx, docs)
+	return err
+}
+
+// DeletePendingSets clears unresolved sets from earlier runs so a new scan replaces them
+func (r *DedupeRepositoryImpl) DeletePendingSets(ctx context.Context, moduleName string) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.sets.DeleteMany(ctx, bson.M{
+		"tenant_id":   tenantID,
+		"module_name": moduleName,
+		"status":      DuplicateSetPending,
+	})
+	return err
+}
+
+func (r *DedupeRepositoryImpl) GetSet(ctx context.Context, id string) (*DuplicateSet, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var set DuplicateSet
+	if err := r.sets.FindOne(ctx, bson.M{"_id": objID, "tenant_id": tenantID}).Decode(&set); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+func (r *DedupeRepositoryImpl) ListSets(ctx context.Context, filter bson.M, limit, offset int64) ([]DuplicateSet, int64, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := bson.M{"tenant_id": tenantID}
+	for k, v := range filter {
+		query[k] = v
+	}
+
+	total, err := r.sets.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "confidence", Value: -1}, {Key: "created_at", Value: -1}}).
+		SetLimit(limit).
+		SetSkip(offset)
+	cursor, err := r.sets.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	sets := []DuplicateSet{}
+	if err := cursor.All(ctx, &sets); err != nil {
+		return nil, 0, err
+	}
+	return sets, total, nil
+}
+
+func (r *DedupeRepositoryImpl) UpdateSet(ctx context.Context, set *DuplicateSet) error {
+	_, err := r.sets.ReplaceOne(ctx, bson.M{"_id": set.ID, "tenant_id": set.TenantID}, set)
+	return err
+}
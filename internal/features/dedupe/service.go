@@ -0,0 +1,367 @@
+package dedupe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/record"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DedupeService interface {
+	StartJob(ctx context.Context, job *DedupeJob) error
+	GetJob(ctx context.Context, id string) (*DedupeJob, error)
+	ListJobs(ctx context.Context, moduleName string) ([]DedupeJob, error)
+
+	ListSets(ctx context.Context, filter map[string]interface{}, page, limit int64) ([]DuplicateSet, int64, error)
+	GetSet(ctx context.Context, id string) (*DuplicateSet, error)
+	MergeSet(ctx context.Context, id string, req MergeRequest, userID primitive.ObjectID) (map[string]any, error)
+	DismissSet(ctx context.Context, id string, userID primitive.ObjectID) error
+}
+
+type DedupeServiceImpl struct {
+	DedupeRepo    DedupeRepository
+	ModuleRepo    module.ModuleRepository
+	RecordRepo    record.RecordRepository
+	RecordService record.RecordService
+	AuditService  audit.AuditService
+}
+
+func NewDedupeService(
+	dedupeRepo DedupeRepository,
+	moduleRepo module.ModuleRepository,
+	recordRepo record.RecordRepository,
+	recordService record.RecordService,
+	auditService audit.AuditService,
+) DedupeService {
+	return &DedupeServiceImpl{
+		DedupeRepo:    dedupeRepo,
+		ModuleRepo:    moduleRepo,
+		RecordRepo:    recordRepo,
+		RecordService: recordService,
+		AuditService:  auditService,
+	}
+}
+
+// StartJob validates the rules, persists the job and scans the module in the background
+func (s *DedupeServiceImpl) StartJob(ctx context.Context, job *DedupeJob) error {
+	m, err := s.ModuleRepo.FindByName(ctx, job.ModuleName)
+	if err != nil {
+		return fmt.Errorf("module '%s' not found", job.ModuleName)
+	}
+
+	if len(job.Rules) == 0 {
+		return errors.New("at least one match rule is required")
+	}
+	fields := make(map[string]bool, len(m.Fields))
+	for _, f := range m.Fields {
+		fields[f.Name] = true
+	}
+	for i, rule := range job.Rules {
+		if !fields[rule.Field] {
+			return fmt.Errorf("field '%s' does not exist in module '%s'", rule.Field, job.ModuleName)
+		}
+		switch rule.Type {
+		case MatchTypeExact, MatchTypeEmail, MatchTypePhone, MatchTypeFuzzy:
+		case "":
+			job.Rules[i].Type = MatchTypeExact
+		default:
+			return fmt.Errorf("unsupported match type '%s'", rule.Type)
+		}
+	}
+	if job.MinConfidence <= 0 || job.MinConfidence > 1 {
+		job.MinConfidence = DefaultMinConfidence
+	}
+
+	if err := s.DedupeRepo.CreateJob(ctx, job); err != nil {
+		return err
+	}
+
+	// Detach from the request but keep the tenant so repositories stay scoped
+	bgCtx := context.WithValue(context.Background(), common_models.TenantIDKey, job.TenantID.Hex())
+	go s.runJob(bgCtx, *job)
+
+	return nil
+}
+
+func (s *DedupeServiceImpl) runJob(ctx context.Context, job DedupeJob) {
+	job.Status = DedupeJobProcessing
+	_ = s.DedupeRepo.UpdateJob(ctx, &job)
+
+	fail := func(err error) {
+		log.Printf("Dedupe job %s failed: %v", job.ID.Hex(), err)
+		job.Status = DedupeJobFailed
+		job.Error = err.Error()
+		now := time.Now()
+		job.CompletedAt = &now
+		_ = s.DedupeRepo.UpdateJob(ctx, &job)
+	}
+
+	total, err := s.RecordRepo.Count(ctx, job.ModuleName, nil, nil)
+	if err != nil {
+		fail(err)
+		return
+	}
+	job.TotalRecords = int(total)
+	if job.TotalRecords > MaxScanRecords {
+		job.TotalRecords = MaxScanRecords
+	}
+
+	// 1. Load the module in pages, keeping only normalized match values in memory
+	const pageSize = 1000
+	var candidates []candidate
+	for offset := int64(0); offset < int64(job.TotalRecords); offset += pageSize {
+		records, err := s.RecordRepo.List(ctx, job.ModuleName, nil, nil, pageSize, offset, "created_at", 1)
+		if err != nil {
+			fail(err)
+			return
+		}
+		candidates = append(candidates, buildCandidates(records, job.Rules)...)
+
+		job.ProcessedCount += len(records)
+		_ = s.DedupeRepo.UpdateJob(ctx, &job)
+
+		if len(records) < pageSize {
+			break
+		}
+	}
+
+	// 2. Score and group
+	groups := findDuplicateGroups(candidates, job.Rules, job.MinConfidence)
+
+	sets := make([]DuplicateSet, 0, len(groups))
+	for _, g := range groups {
+		ids := make([]string, len(g.Members))
+		for i, m := range g.Members {
+			ids[i] = candidates[m].ID
+		}
+		sets = append(sets, DuplicateSet{
+			JobID:      job.ID,
+			ModuleName: job.ModuleName,
+			RecordIDs:  ids,
+			Confidence: round2(g.Confidence),
+			MatchedOn:  g.MatchedOn,
+			Status:     DuplicateSetPending,
+		})
+	}
+
+	// 3. Replace unresolved sets from earlier runs
+	if err := s.DedupeRepo.DeletePendingSets(ctx, job.ModuleName); err != nil {
+		fail(err)
+		return
+	}
+	if err := s.DedupeRepo.CreateSets(ctx, sets); err != nil {
+		fail(err)
+		return
+	}
+
+	job.SetCount = len(sets)
+	job.Status = DedupeJobCompleted
+	now := time.Now()
+	job.CompletedAt = &now
+	_ = s.DedupeRepo.UpdateJob(ctx, &job)
+}
+
+func (s *DedupeServiceImpl) GetJob(ctx context.Context, id string) (*DedupeJob, error) {
+	return s.DedupeRepo.GetJob(ctx, id)
+}
+
+func (s *DedupeServiceImpl) ListJobs(ctx context.Context, moduleName string) ([]DedupeJob, error) {
+	return s.DedupeRepo.ListJobs(ctx, moduleName, 50)
+}
+
+func (s *DedupeServiceImpl) ListSets(ctx context.Context, filter map[string]interface{}, page, limit int64) ([]DuplicateSet, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+	return s.DedupeRepo.ListSets(ctx, filter, limit, (page-1)*limit)
+}
+
+// GetSet returns a duplicate set with its current record data attached for side-by-side review
+func (s *DedupeServiceImpl) GetSet(ctx context.Context, id string) (*DuplicateSet, error) {
+	set, err := s.DedupeRepo.GetSet(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	set.Records = make([]map[string]any, 0, len(set.RecordIDs))
+	for _, recordID := range set.RecordIDs {
+		rec, err := s.RecordRepo.Get(ctx, set.ModuleName, recordID)
+		if err != nil {
+			continue // Deleted since the scan
+		}
+		set.Records = append(set.Records, rec)
+	}
+	return set, nil
+}
+
+// MergeSet folds every duplicate into the master record: empty master fields are
+// filled from duplicates (or taken from explicit field sources), lookups in other
+// modules are re-pointed at the master, and the duplicates are soft-deleted.
+func (s *DedupeServiceImpl) MergeSet(ctx context.Context, id string, req MergeRequest, userID primitive.ObjectID) (map[string]any, error) {
+	set, err := s.DedupeRepo.GetSet(ctx, id)
+	if err != nil {
+		return nil, errors.New("duplicate set not found")
+	}
+	if set.Status != DuplicateSetPending {
+		return nil, fmt.Errorf("duplicate set is already %s", set.Status)
+	}
+
+	masterID := req.MasterID
+	if masterID == "" {
+		masterID = set.RecordIDs[0]
+	}
+
+	records := make(map[string]map[string]any)
+	var duplicateIDs []string
+	inSet := false
+	for _, recordID := range set.RecordIDs {
+		rec, err := s.RecordRepo.Get(ctx, set.ModuleName, recordID)
+		if err != nil {
+			continue
+		}
+		records[recordID] = rec
+		if recordID == masterID {
+			inSet = true
+		} else {
+			duplicateIDs = append(duplicateIDs, recordID)
+		}
+	}
+	if !inSet {
+		return nil, errors.New("master record must be a member of the duplicate set")
+	}
+	if len(duplicateIDs) == 0 {
+		return nil, errors.New("no remaining duplicates to merge")
+	}
+
+	m, err := s.ModuleRepo.FindByName(ctx, set.ModuleName)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. Build surviving values
+	master := records[masterID]
+	updates := make(map[string]interface{})
+	for _, field := range m.Fields {
+		if field.IsSystem {
+			continue
+		}
+		if sourceID, ok := req.FieldSources[field.Name]; ok {
+			if source, ok := records[sourceID]; ok && sourceID != masterID {
+				updates[field.Name] = source[field.Name]
+			}
+			continue
+		}
+		if !isEmptyValue(master[field.Name]) {
+			continue
+		}
+		for _, dupID := range duplicateIDs {
+			if v := records[dupID][field.Name]; !isEmptyValue(v) {
+				updates[field.Name] = v
+				break
+			}
+		}
+	}
+
+	if len(updates) > 0 {
+		if err := s.RecordService.UpdateRecord(ctx, set.ModuleName, masterID, updates, userID); err != nil {
+			return nil, fmt.Errorf("failed to update master record: %v", err)
+		}
+	}
+
+	// 2. Re-point lookups in related modules
+	masterOID, _ := primitive.ObjectIDFromHex(masterID)
+	if related, err := s.ModuleRepo.FindUsingLookup(ctx, set.ModuleName); err == nil {
+		for _, rm := range related {
+			for _, field := range rm.Fields {
+				if field.Lookup == nil || field.Lookup.LookupModule != set.ModuleName {
+					continue
+				}
+				for _, dupID := range duplicateIDs {
+					dupOID, err := primitive.ObjectIDFromHex(dupID)
+					if err != nil {
+						continue
+					}
+					refs, err := s.RecordRepo.List(ctx, rm.Name, map[string]any{field.Name: dupOID}, nil, 0, 0, "created_at", 1)
+					if err != nil {
+						continue
+					}
+					for _, ref := range refs {
+						if refID, ok := ref["_id"].(primitive.ObjectID); ok {
+							_ = s.RecordRepo.Update(ctx, rm.Name, refID.Hex(), map[string]any{field.Name: masterOID})
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// 3. Remove duplicates
+	for _, dupID := range duplicateIDs {
+		if err := s.RecordService.DeleteRecord(ctx, set.ModuleName, dupID, userID); err != nil {
+			return nil, fmt.Errorf("failed to delete duplicate %s: %v", dupID, err)
+		}
+	}
+
+	now := time.Now()
+	set.Status = DuplicateSetMerged
+	set.MasterID = masterID
+	set.ResolvedBy = &userID
+	set.ResolvedAt = &now
+	if err := s.DedupeRepo.UpdateSet(ctx, set); err != nil {
+		return nil, err
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionMerge, set.ModuleName, masterID, map[string]common_models.Change{
+		"merged_records": {Old: duplicateIDs, New: masterID},
+		"fields":         {New: updates},
+	})
+
+	return s.RecordRepo.Get(ctx, set.ModuleName, masterID)
+}
+
+func (s *DedupeServiceImpl) DismissSet(ctx context.Context, id string, userID primitive.ObjectID) error {
+	set, err := s.DedupeRepo.GetSet(ctx, id)
+	if err != nil {
+		return errors.New("duplicate set not found")
+	}
+	if set.Status != DuplicateSetPending {
+		return fmt.Errorf("duplicate set is already %s", set.Status)
+	}
+
+	now := time.Now()
+	set.Status = DuplicateSetDismissed
+	set.ResolvedBy = &userID
+	set.ResolvedAt = &now
+	return s.DedupeRepo.UpdateSet(ctx, set)
+}
+
+func isEmptyValue(v any) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case string:
+		return val == ""
+	case primitive.ObjectID:
+		return val.IsZero()
+	case []interface{}:
+		return len(val) == 0
+	case primitive.A:
+		return len(val) == 0
+	}
+	return false
+}
+
+func round2(v float64) float64 {
+	return float64(int(v*100+0.5)) / 100
+}
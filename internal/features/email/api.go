@@ -0,0 +1,35 @@
+package email
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type EmailApi struct {
+	controller  *EmailController
+	config      *config.Config
+	roleService middleware.RoleService
+}
+
+func NewEmailApi(controller *EmailController, config *config.Config, roleService middleware.RoleService) *EmailApi {
+	return &EmailApi{
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *EmailApi) Setup(app *fiber.App) {
+	// Provider webhooks authenticate with EMAIL_WEBHOOK_SECRET, so they are
+	// registered ahead of the authenticated group
+	app.Post("/api/email/events/:provider", h.controller.Events)
+
+	group := app.Group("/api/email", middleware.AuthMiddleware(h.config.SkipAuth))
+	group.Get("/log", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListLog)
+	group.Get("/domain", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.CheckDomain)
+	group.Get("/suppressions", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListSuppressions)
+	group.Post("/suppressions", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.AddSuppression)
+	group.Delete("/suppressions/:email", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.RemoveSuppression)
+}
@@ -0,0 +1,165 @@
+package email
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type EmailController struct {
+	Service EmailService
+}
+
+func NewEmailController(service EmailService) *EmailController {
+	return &EmailController{Service: service}
+}
+
+func pagination(ctx *fiber.Ctx) (int64, int64) {
+	page, _ := strconv.ParseInt(ctx.Query("page", "1"), 10, 64)
+	limit, _ := strconv.ParseInt(ctx.Query("limit", "20"), 10, 64)
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return page, limit
+}
+
+// ListLog godoc
+// @Summary List sent mail
+// @Description Sent-mail log of the tenant with delivery status, provider and fallback attempts
+// @Tags email
+// @Produce json
+// @Param status query string false "queued, sent, failed, bounced or complained"
+// @Param to query string false "Recipient address"
+// @Param from query string false "Created at or after (RFC3339)"
+// @Param until query string false "Created before (RFC3339)"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/email/log [get]
+func (c *EmailController) ListLog(ctx *fiber.Ctx) error {
+	q := EmailLogQuery{Status: EmailStatus(ctx.Query("status")), To: ctx.Query("to")}
+	q.Page, q.Limit = pagination(ctx)
+	for param, dst := range map[string]**time.Time{"from": &q.From, "until": &q.Until} {
+		if v := ctx.Query(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid " + param + " time"})
+			}
+			*dst = &t
+		}
+	}
+
+	emails, total, err := c.Service.ListLog(ctx.UserContext(), q)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{
+		"data": emails,
+		"meta": fiber.Map{"total": total, "page": q.Page, "limit": q.Limit},
+	})
+}
+
+// ListSuppressions godoc
+// @Summary List suppressed addresses
+// @Description Addresses mail is no longer sent to after bounces, complaints or manual entry
+// @Tags email
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/email/suppressions [get]
+func (c *EmailController) ListSuppressions(ctx *fiber.Ctx) error {
+	page, limit := pagination(ctx)
+	suppressions, total, err := c.Service.ListSuppressions(ctx.UserContext(), page, limit)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{
+		"data": suppressions,
+		"meta": fiber.Map{"total": total, "page": page, "limit": limit},
+	})
+}
+
+// AddSuppression godoc
+// @Summary Suppress an address
+// @Tags email
+// @Accept json
+// @Produce json
+// @Param suppression body map[string]string true "email and optional detail"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/email/suppressions [post]
+func (c *EmailController) AddSuppression(ctx *fiber.Ctx) error {
+	var req struct {
+		Email  string `json:"email"`
+		Detail string `json:"detail"`
+	}
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := c.Service.AddSuppression(ctx.UserContext(), req.Email, req.Detail); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Address suppressed"})
+}
+
+// RemoveSuppression godoc
+// @Summary Remove a suppressed address
+// @Tags email
+// @Param email path string true "Email address"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/email/suppressions/{email} [delete]
+func (c *EmailController) RemoveSuppression(ctx *fiber.Ctx) error {
+	if err := c.Service.RemoveSuppression(ctx.UserContext(), ctx.Params("email")); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Address is not suppressed"})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"message": "Suppression removed"})
+}
+
+// CheckDomain godoc
+// @Summary Check sending domain
+// @Description Look up the SPF, DKIM and DMARC records of the configured sending domain
+// @Tags email
+// @Produce json
+// @Success 200 {object} DomainStatus
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/email/domain [get]
+func (c *EmailController) CheckDomain(ctx *fiber.Ctx) error {
+	status, err := c.Service.CheckDomain(ctx.UserContext())
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": status})
+}
+
+// Events godoc
+// @Summary Provider delivery events
+// @Description Bounce and complaint webhook for SendGrid (Event Webhook) and SES (SNS); authenticated by the token query parameter
+// @Tags email
+// @Accept json
+// @Param provider path string true "sendgrid or ses"
+// @Param token query string true "EMAIL_WEBHOOK_SECRET"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/email/events/{provider} [post]
+func (c *EmailController) Events(ctx *fiber.Ctx) error {
+	if err := c.Service.HandleEvents(ctx.UserContext(), ctx.Params("provider"), ctx.Query("token"), ctx.Body()); err != nil {
+		if errors.Is(err, ErrInvalidEventToken) {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"message": "Events accepted"})
+}
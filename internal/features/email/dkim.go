@@ -0,0 +1,199 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"go-crm/internal/features/settings"
+)
+
+// Headers covered by the DKIM signature when present
+var dkimHeaders = []string{"from", "to", "subject", "date", "message-id", "mime-version", "content-type"}
+
+// dkimDomain is the signing domain: the sending domain when set, else the
+// domain of the from address
+func dkimDomain(config *settings.EmailConfig, from string) string {
+	if config.SendingDomain != "" {
+		return config.SendingDomain
+	}
+	return from[strings.LastIndex(from, "@")+1:]
+}
+
+// dkimSign prepends an RFC 6376 rsa-sha256 signature using relaxed/relaxed
+// canonicalization
+func dkimSign(raw []byte, domain, selector, privateKeyPEM string, now time.Time) ([]byte, error) {
+	key, err := parseDKIMKey(privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	split := bytes.Index(raw, []byte("\r\n\r\n"))
+	if split < 0 {
+		return nil, errors.New("dkim: message has no body separator")
+	}
+	headerBlock, body := string(raw[:split+2]), raw[split+4:]
+
+	bodyHash := sha256.Sum256(relaxedBody(body))
+	headers := parseHeaders(headerBlock)
+
+	var signed []string
+	var hashed strings.Builder
+	for _, name := range dkimHeaders {
+		if value, ok := headers[name]; ok {
+			signed = append(signed, name)
+			hashed.WriteString(relaxedHeader(name, value))
+			hashed.WriteString("\r\n")
+		}
+	}
+
+	sig := fmt.Sprintf("v=1; a=rsa-sha256; c=relaxed/relaxed; d=%s; s=%s; t=%d; h=%s; bh=%s; b=",
+		domain, selector, now.Unix(), strings.Join(signed, ":"), base64.StdEncoding.EncodeToString(bodyHash[:]))
+	hashed.WriteString(relaxedHeader("dkim-signature", sig))
+
+	digest := sha256.Sum256([]byte(hashed.String()))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("dkim: %w", err)
+	}
+
+	out := make([]byte, 0, len(raw)+512)
+	out = append(out, "DKIM-Signature: "+sig+base64.StdEncoding.EncodeToString(signature)+"\r\n"...)
+	return append(out, raw...), nil
+}
+
+func parseDKIMKey(privateKeyPEM string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privateKeyPEM))
+	if block == nil {
+		return nil, errors.New("dkim: private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("dkim: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("dkim: private key is not RSA")
+	}
+	return key, nil
+}
+
+// parseHeaders maps lowercased names to unfolded values; later duplicates
+// are ignored since the signature covers the first occurrence
+func parseHeaders(block string) map[string]string {
+	headers := map[string]string{}
+	var name, value string
+	flush := func() {
+		if _, seen := headers[name]; name != "" && !seen {
+			headers[name] = value
+		}
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(block, "\r\n"), "\r\n") {
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			value += line
+			continue
+		}
+		flush()
+		if i := strings.Index(line, ":"); i > 0 {
+			name, value = strings.ToLower(strings.TrimSpace(line[:i])), line[i+1:]
+		} else {
+			name, value = "", ""
+		}
+	}
+	flush()
+	return headers
+}
+
+func relaxedHeader(name, value string) string {
+	return name + ":" + strings.Join(strings.Fields(value), " ")
+}
+
+func relaxedBody(body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		lines[i] = strings.Join(strings.FieldsFunc(line, func(r rune) bool { return r == ' ' || r == '\t' }), " ")
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			lines[i] = " " + lines[i]
+		}
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+// DomainStatus reports the DNS authentication records of a sending domain
+type DomainStatus struct {
+	Domain   string   `json:"domain"`
+	Selector string   `json:"selector,omitempty"`
+	SPF      string   `json:"spf,omitempty"`      // v=spf1 record
+	DKIM     bool     `json:"dkim"`               // <selector>._domainkey record found
+	DMARC    string   `json:"dmarc,omitempty"`    // _dmarc record
+	Problems []string `json:"problems,omitempty"` // Missing records that hurt deliverability
+}
+
+// checkDomain looks up SPF, DKIM and DMARC records for the sending domain
+func checkDomain(ctx context.Context, config *settings.EmailConfig) *DomainStatus {
+	status := &DomainStatus{Domain: config.SendingDomain, Selector: config.DKIMSelector}
+	if status.Domain == "" {
+		status.Problems = append(status.Problems, "no sending domain configured")
+		return status
+	}
+	resolver := net.DefaultResolver
+
+	if records, err := resolver.LookupTXT(ctx, status.Domain); err == nil {
+		for _, r := range records {
+			if strings.HasPrefix(strings.ToLower(r), "v=spf1") {
+				status.SPF = r
+			}
+		}
+	}
+	if status.SPF == "" {
+		status.Problems = append(status.Problems, "no SPF record")
+	}
+
+	if status.Selector == "" {
+		if config.Provider == "" || config.Provider == ProviderSMTP {
+			status.Problems = append(status.Problems, "no DKIM selector configured")
+		}
+	} else if records, err := resolver.LookupTXT(ctx, status.Selector+"._domainkey."+status.Domain); err == nil {
+		for _, r := range records {
+			if strings.Contains(r, "p=") {
+				status.DKIM = true
+			}
+		}
+	}
+	if status.Selector != "" && !status.DKIM {
+		status.Problems = append(status.Problems, "no DKIM record for selector "+status.Selector)
+	}
+
+	if records, err := resolver.LookupTXT(ctx, "_dmarc."+status.Domain); err == nil {
+		for _, r := range records {
+			if strings.HasPrefix(strings.ToLower(r), "v=dmarc1") {
+				status.DMARC = r
+			}
+		}
+	}
+	if status.DMARC == "" {
+		status.Problems = append(status.Problems, "no DMARC record")
+	}
+	return status
+}
@@ -12,6 +12,10 @@ const (
 	EmailQueued EmailStatus = "queued"
 	EmailSent   EmailStatus = "sent"
 	EmailFailed EmailStatus = "failed"
+
+	// Set from provider events after delivery was accepted
+	EmailBounced    EmailStatus = "bounced"
+	EmailComplained EmailStatus = "complained"
 )
 
 type Email struct {
@@ -28,6 +32,30 @@ type Email struct {
 	EntityType string             `bson:"entityType,omitempty" json:"entityType,omitempty"`
 	EntityID   primitive.ObjectID `bson:"entityId,omitempty" json:"entityId,omitempty"`
 	ErrorMsg   string             `bson:"errorMessage,omitempty" json:"errorMessage,omitempty"`
+	SentBy     primitive.ObjectID `bson:"sentBy,omitempty" json:"sentBy,omitempty"` // Acting user, empty for system mail
 	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
 	SentAt     *time.Time         `bson:"sentAt,omitempty" json:"sentAt,omitempty"`
+
+	Provider          string   `bson:"provider,omitempty" json:"provider,omitempty"`                   // Provider that accepted the message
+	ProviderMessageID string   `bson:"providerMessageId,omitempty" json:"providerMessageId,omitempty"` // ID in the provider's events
+	Attempts          []string `bson:"attempts,omitempty" json:"attempts,omitempty"`                   // "provider: error" for each failed provider
+	Suppressed        []string `bson:"suppressed,omitempty" json:"suppressed,omitempty"`               // Recipients skipped by the suppression list
+}
+
+// Suppression reasons
+const (
+	SuppressionBounce    = "bounce"
+	SuppressionComplaint = "complaint"
+	SuppressionManual    = "manual"
+)
+
+// Suppression blocks sending to an address within a tenant
+type Suppression struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrgID     primitive.ObjectID `bson:"orgId" json:"orgId"`
+	Email     string             `bson:"email" json:"email"` // Lowercased
+	Reason    string             `bson:"reason" json:"reason"`
+	Detail    string             `bson:"detail,omitempty" json:"detail,omitempty"`
+	EmailID   primitive.ObjectID `bson:"emailId,omitempty" json:"emailId,omitempty"` // Message that triggered it
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
 }
@@ -0,0 +1,286 @@
+package email
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	common_models "go-crm/internal/common/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidEventToken rejects webhook calls without the configured secret
+var ErrInvalidEventToken = errors.New("invalid event token")
+
+// deliveryEvent is a bounce or complaint for one recipient, normalised
+// across providers
+type deliveryEvent struct {
+	EmailID           string // Log ID sent with the message
+	ProviderMessageID string
+	Recipient         string
+	Complaint         bool
+	Permanent         bool // Hard bounce; soft bounces are only recorded
+	Detail            string
+}
+
+// HandleEvents records bounces and complaints against the sent-mail log.
+// Hard bounces and complaints suppress the address for the tenant and mark
+// records holding it as having an invalid email.
+func (s *EmailServiceImpl) HandleEvents(ctx context.Context, provider, token string, body []byte) error {
+	if s.Config == nil || s.Config.EmailWebhookSecret == "" {
+		return errors.New("email events are not enabled")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.Config.EmailWebhookSecret)) != 1 {
+		return ErrInvalidEventToken
+	}
+
+	var events []deliveryEvent
+	var err error
+	switch provider {
+	case ProviderSendGrid:
+		events, err = parseSendGridEvents(body)
+	case ProviderSES:
+		events, err = s.parseSESEvents(ctx, body)
+	default:
+		return fmt.Errorf("unsupported provider: %s", provider)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, evt := range events {
+		if err := s.applyEvent(ctx, provider, evt); err != nil {
+			log.Printf("Email event for %s not applied: %v", evt.Recipient, err)
+		}
+	}
+	return nil
+}
+
+func (s *EmailServiceImpl) applyEvent(ctx context.Context, provider string, evt deliveryEvent) error {
+	var sent *Email
+	if id, err := primitive.ObjectIDFromHex(evt.EmailID); err == nil {
+		sent, _ = s.Repo.FindByID(ctx, id)
+	}
+	if sent == nil && evt.ProviderMessageID != "" {
+		sent, _ = s.Repo.FindByProviderMessageID(ctx, provider, evt.ProviderMessageID)
+	}
+	if sent == nil || sent.OrgID.IsZero() {
+		return errors.New("message not found")
+	}
+	ctx = context.WithValue(ctx, common_models.TenantIDKey, sent.OrgID.Hex())
+
+	status := EmailBounced
+	reason := SuppressionBounce
+	if evt.Complaint {
+		status = EmailComplained
+		reason = SuppressionComplaint
+	}
+	if err := s.Repo.UpdateStatus(ctx, sent.ID, status, evt.Recipient+": "+evt.Detail); err != nil {
+		return err
+	}
+	if !evt.Complaint && !evt.Permanent {
+		return nil
+	}
+
+	if err := s.Suppressions.Add(ctx, &Suppression{
+		OrgID:   sent.OrgID,
+		Email:   evt.Recipient,
+		Reason:  reason,
+		Detail:  evt.Detail,
+		EmailID: sent.ID,
+	}); err != nil {
+		return err
+	}
+	return s.invalidateContacts(ctx, evt.Recipient, reason)
+}
+
+// invalidateContacts flags records whose email fields hold the address, so
+// users see why mail to them stopped
+func (s *EmailServiceImpl) invalidateContacts(ctx context.Context, address, reason string) error {
+	if s.ModuleRepo == nil || s.RecordRepo == nil {
+		return nil
+	}
+	modules, err := s.ModuleRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+	match := map[string]any{"$regex": "^" + regexp.QuoteMeta(address) + "$", "$options": "i"}
+	for _, m := range modules {
+		var clauses []any
+		for _, f := range m.Fields {
+			if f.Type == common_models.FieldTypeEmail {
+				clauses = append(clauses, map[string]any{f.Name: match})
+			}
+		}
+		if len(clauses) == 0 {
+			continue
+		}
+		records, err := s.RecordRepo.List(ctx, m.Name, map[string]any{"$or": clauses}, nil, 100, 0, "", 0)
+		if err != nil {
+			return err
+		}
+		for _, rec := range records {
+			id, ok := rec["_id"].(primitive.ObjectID)
+			if !ok {
+				continue
+			}
+			if err := s.RecordRepo.Update(ctx, m.Name, id.Hex(), map[string]any{
+				"email_invalid":        true,
+				"email_invalid_reason": reason,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseSendGridEvents reads the Event Webhook batch; bounce and spamreport
+// are the only events acted on
+func parseSendGridEvents(body []byte) ([]deliveryEvent, error) {
+	var batch []struct {
+		Email       string `json:"email"`
+		Event       string `json:"event"`
+		Type        string `json:"type"`
+		Reason      string `json:"reason"`
+		SGMessageID string `json:"sg_message_id"`
+		EmailID     string `json:"email_id"`
+	}
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return nil, fmt.Errorf("invalid event payload: %w", err)
+	}
+
+	var events []deliveryEvent
+	for _, e := range batch {
+		// sg_message_id is the X-Message-Id followed by a filter suffix
+		messageID := strings.SplitN(e.SGMessageID, ".", 2)[0]
+		switch e.Event {
+		case "bounce":
+			events = append(events, deliveryEvent{
+				EmailID:           e.EmailID,
+				ProviderMessageID: messageID,
+				Recipient:         e.Email,
+				Permanent:         e.Type != "blocked",
+				Detail:            e.Reason,
+			})
+		case "spamreport":
+			events = append(events, deliveryEvent{
+				EmailID:           e.EmailID,
+				ProviderMessageID: messageID,
+				Recipient:         e.Email,
+				Complaint:         true,
+				Detail:            "spam report",
+			})
+		}
+	}
+	return events, nil
+}
+
+// parseSESEvents reads an SNS delivery of an SES notification, confirming
+// the topic subscription when SNS asks for it
+func (s *EmailServiceImpl) parseSESEvents(ctx context.Context, body []byte) ([]deliveryEvent, error) {
+	var envelope struct {
+		Type         string
+		Message      string
+		SubscribeURL string
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid event payload: %w", err)
+	}
+	switch envelope.Type {
+	case "SubscriptionConfirmation":
+		return nil, confirmSNSSubscription(ctx, envelope.SubscribeURL)
+	case "Notification":
+	default:
+		return nil, nil
+	}
+
+	var n struct {
+		NotificationType string `json:"notificationType"`
+		EventType        string `json:"eventType"`
+		Mail             struct {
+			MessageID string              `json:"messageId"`
+			Tags      map[string][]string `json:"tags"`
+		} `json:"mail"`
+		Bounce struct {
+			BounceType        string `json:"bounceType"`
+			BouncedRecipients []struct {
+				EmailAddress   string `json:"emailAddress"`
+				DiagnosticCode string `json:"diagnosticCode"`
+			} `json:"bouncedRecipients"`
+		} `json:"bounce"`
+		Complaint struct {
+			ComplainedRecipients []struct {
+				EmailAddress string `json:"emailAddress"`
+			} `json:"complainedRecipients"`
+			ComplaintFeedbackType string `json:"complaintFeedbackType"`
+		} `json:"complaint"`
+	}
+	if err := json.Unmarshal([]byte(envelope.Message), &n); err != nil {
+		return nil, fmt.Errorf("invalid SES notification: %w", err)
+	}
+
+	var emailID string
+	if ids := n.Mail.Tags["email_id"]; len(ids) > 0 {
+		emailID = ids[0]
+	}
+	kind := n.NotificationType
+	if kind == "" {
+		kind = n.EventType
+	}
+
+	var events []deliveryEvent
+	switch kind {
+	case "Bounce":
+		for _, r := range n.Bounce.BouncedRecipients {
+			events = append(events, deliveryEvent{
+				EmailID:           emailID,
+				ProviderMessageID: n.Mail.MessageID,
+				Recipient:         r.EmailAddress,
+				Permanent:         n.Bounce.BounceType == "Permanent",
+				Detail:            r.DiagnosticCode,
+			})
+		}
+	case "Complaint":
+		for _, r := range n.Complaint.ComplainedRecipients {
+			events = append(events, deliveryEvent{
+				EmailID:           emailID,
+				ProviderMessageID: n.Mail.MessageID,
+				Recipient:         r.EmailAddress,
+				Complaint:         true,
+				Detail:            n.Complaint.ComplaintFeedbackType,
+			})
+		}
+	}
+	return events, nil
+}
+
+// confirmSNSSubscription visits the confirmation link, which must point at
+// an AWS endpoint so the webhook cannot be used to make arbitrary requests
+func confirmSNSSubscription(ctx context.Context, subscribeURL string) error {
+	u, err := url.Parse(subscribeURL)
+	if err != nil || u.Scheme != "https" || !strings.HasSuffix(u.Hostname(), ".amazonaws.com") {
+		return errors.New("invalid subscription URL")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := providerHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("subscription confirmation failed: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/mail"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go-crm/internal/features/settings"
+)
+
+// Delivery providers
+const (
+	ProviderSMTP     = "smtp"
+	ProviderSendGrid = "sendgrid"
+	ProviderSES      = "ses"
+)
+
+// Message is one outbound email, independent of the provider sending it
+type Message struct {
+	ID          string // Log ID, passed to providers so events can be matched
+	From        string
+	FromName    string
+	To          []string
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+type Attachment struct {
+	Filename string
+	Data     []byte
+	// ContentID marks an image the HTML body shows inline, as <img src="cid:...">
+	ContentID string
+}
+
+func (a Attachment) disposition() string {
+	if a.ContentID != "" {
+		return "inline"
+	}
+	return "attachment"
+}
+
+func (a Attachment) contentType() string {
+	if ct := mime.TypeByExtension(filepath.Ext(a.Filename)); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+// Provider delivers messages. Send returns the provider's message ID.
+type Provider interface {
+	Name() string
+	Send(ctx context.Context, msg *Message) (string, error)
+}
+
+// providerChain returns the configured primary provider followed by its
+// fallbacks, skipping any without credentials
+func providerChain(config *settings.EmailConfig) []Provider {
+	names := append([]string{config.Provider}, config.FallbackProviders...)
+	seen := map[string]bool{}
+	var chain []Provider
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			name = ProviderSMTP
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if p := newProvider(name, config); p != nil {
+			chain = append(chain, p)
+		}
+	}
+	return chain
+}
+
+func newProvider(name string, config *settings.EmailConfig) Provider {
+	switch name {
+	case ProviderSMTP:
+		if config.SMTPHost == "" || config.SMTPPort == 0 {
+			return nil
+		}
+		return &smtpProvider{config: config}
+	case ProviderSendGrid:
+		if config.SendGridAPIKey == "" {
+			return nil
+		}
+		return &sendGridProvider{apiKey: config.SendGridAPIKey}
+	case ProviderSES:
+		if config.SESRegion == "" || config.SESAccessKeyID == "" || config.SESSecretKey == "" {
+			return nil
+		}
+		return &sesProvider{region: config.SESRegion, accessKey: config.SESAccessKeyID, secretKey: config.SESSecretKey}
+	}
+	return nil
+}
+
+// isHTML tells HTML bodies from plain text ones; callers pass either
+func isHTML(body string) bool {
+	lower := strings.ToLower(body)
+	return strings.Contains(lower, "<html") || strings.Contains(lower, "<body") ||
+		strings.Contains(lower, "<p>") || strings.Contains(lower, "<br") || strings.Contains(lower, "</div>")
+}
+
+func bodyContentType(body string) string {
+	if isHTML(body) {
+		return "text/html; charset=\"utf-8\""
+	}
+	return "text/plain; charset=\"utf-8\""
+}
+
+// buildMIME renders the message as RFC 5322 bytes with CRLF line endings
+func buildMIME(msg *Message, now time.Time) []byte {
+	var buf bytes.Buffer
+	from := (&mail.Address{Name: msg.FromName, Address: msg.From}).String()
+	domain := msg.From[strings.LastIndex(msg.From, "@")+1:]
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", msg.ID, domain)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	if len(msg.Attachments) == 0 {
+		fmt.Fprintf(&buf, "Content-Type: %s\r\n", bodyContentType(msg.Body))
+		buf.WriteString("\r\n")
+		buf.WriteString(msg.Body)
+		buf.WriteString("\r\n")
+		return buf.Bytes()
+	}
+
+	// Clients show the images of a related body in place rather than as files
+	multipart := "related"
+	for _, a := range msg.Attachments {
+		if a.ContentID == "" {
+			multipart = "mixed"
+		}
+	}
+	marker := "ACRMarker" + msg.ID
+	fmt.Fprintf(&buf, "Content-Type: multipart/%s; boundary=%s\r\n", multipart, marker)
+	buf.WriteString("\r\n")
+
+	fmt.Fprintf(&buf, "--%s\r\n", marker)
+	fmt.Fprintf(&buf, "Content-Type: %s\r\n", bodyContentType(msg.Body))
+	buf.WriteString("\r\n")
+	buf.WriteString(msg.Body)
+	buf.WriteString("\r\n")
+
+	for _, a := range msg.Attachments {
+		fmt.Fprintf(&buf, "--%s\r\n", marker)
+		fmt.Fprintf(&buf, "Content-Type: %s; name=\"%s\"\r\n", a.contentType(), a.Filename)
+		buf.WriteString("Content-Transfer-Encoding: base64\r\n")
+		fmt.Fprintf(&buf, "Content-Disposition: %s; filename=\"%s\"\r\n", a.disposition(), a.Filename)
+		if a.ContentID != "" {
+			fmt.Fprintf(&buf, "Content-ID: <%s>\r\n", a.ContentID)
+		}
+		buf.WriteString("\r\n")
+
+		encoded := base64.StdEncoding.EncodeToString(a.Data)
+		for len(encoded) > 76 {
+			buf.WriteString(encoded[:76])
+			buf.WriteString("\r\n")
+			encoded = encoded[76:]
+		}
+		buf.WriteString(encoded)
+		buf.WriteString("\r\n")
+	}
+	fmt.Fprintf(&buf, "--%s--\r\n", marker)
+	return buf.Bytes()
+}
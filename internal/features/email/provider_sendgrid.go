@@ -0,0 +1,95 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const sendGridURL = "https://api.sendgrid.com/v3/mail/send"
+
+var providerHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+type sendGridProvider struct {
+	apiKey string
+}
+
+func (p *sendGridProvider) Name() string { return ProviderSendGrid }
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridAttachment struct {
+	Content     string `json:"content"`
+	Filename    string `json:"filename"`
+	Type        string `json:"type"`
+	Disposition string `json:"disposition"`
+	ContentID   string `json:"content_id,omitempty"`
+}
+
+// Send posts to the v3 mail API. The log ID travels as a custom arg, which
+// SendGrid echoes on every event for the message.
+func (p *sendGridProvider) Send(ctx context.Context, msg *Message) (string, error) {
+	to := make([]sendGridAddress, len(msg.To))
+	for i, addr := range msg.To {
+		to[i] = sendGridAddress{Email: addr}
+	}
+	contentType := "text/plain"
+	if isHTML(msg.Body) {
+		contentType = "text/html"
+	}
+	payload := map[string]any{
+		"personalizations": []map[string]any{{"to": to}},
+		"from":             sendGridAddress{Email: msg.From, Name: msg.FromName},
+		"subject":          msg.Subject,
+		"content":          []sendGridContent{{Type: contentType, Value: msg.Body}},
+		"custom_args":      map[string]string{"email_id": msg.ID},
+	}
+	if len(msg.Attachments) > 0 {
+		attachments := make([]sendGridAttachment, len(msg.Attachments))
+		for i, a := range msg.Attachments {
+			attachments[i] = sendGridAttachment{
+				Content:     base64.StdEncoding.EncodeToString(a.Data),
+				Filename:    a.Filename,
+				Type:        a.contentType(),
+				Disposition: a.disposition(),
+				ContentID:   a.ContentID,
+			}
+		}
+		payload["attachments"] = attachments
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sendGridURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := providerHTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("sendgrid: %s: %s", resp.Status, bytes.TrimSpace(detail))
+	}
+	return resp.Header.Get("X-Message-Id"), nil
+}
@@ -0,0 +1,110 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// sesProvider sends raw MIME through the SES v2 API, signing requests with
+// AWS Signature V4
+type sesProvider struct {
+	region    string
+	accessKey string
+	secretKey string
+}
+
+func (p *sesProvider) Name() string { return ProviderSES }
+
+// Send tags the message with the log ID, which SES includes in the bounce
+// and complaint notifications it publishes
+func (p *sesProvider) Send(ctx context.Context, msg *Message) (string, error) {
+	now := time.Now().UTC()
+	payload := map[string]any{
+		"FromEmailAddress": msg.From,
+		"Destination":      map[string]any{"ToAddresses": msg.To},
+		"Content":          map[string]any{"Raw": map[string]any{"Data": buildMIME(msg, now)}},
+		"EmailTags":        []map[string]string{{"Name": "email_id", "Value": msg.ID}},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	host := "email." + p.region + ".amazonaws.com"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://"+host+"/v2/email/outbound-emails", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	p.sign(req, host, body, now)
+
+	resp, err := providerHTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("ses: %s: %s", resp.Status, bytes.TrimSpace(respBody))
+	}
+	var out struct {
+		MessageId string
+	}
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return "", fmt.Errorf("ses: invalid response: %w", err)
+	}
+	return out.MessageId, nil
+}
+
+func (p *sesProvider) sign(req *http.Request, host string, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	scope := fmt.Sprintf("%s/%s/ses/aws4_request", date, p.region)
+	payloadHash := sha256.Sum256(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+
+	signedHeaders := "content-type;host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := strings.Join([]string{
+		"content-type:" + req.Header.Get("Content-Type"),
+		"host:" + host,
+		"x-amz-content-sha256:" + hex.EncodeToString(payloadHash[:]),
+		"x-amz-date:" + amzDate,
+	}, "\n") + "\n"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		"",
+		canonicalHeaders,
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(hash[:])}, "\n")
+
+	key := sesHMAC([]byte("AWS4"+p.secretKey), date)
+	key = sesHMAC(key, p.region)
+	key = sesHMAC(key, "ses")
+	key = sesHMAC(key, "aws4_request")
+	signature := hex.EncodeToString(sesHMAC(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		p.accessKey, scope, signedHeaders, signature))
+}
+
+func sesHMAC(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,37 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"time"
+
+	"go-crm/internal/features/settings"
+)
+
+type smtpProvider struct {
+	config *settings.EmailConfig
+}
+
+func (p *smtpProvider) Name() string { return ProviderSMTP }
+
+// Send relays the message through the tenant's SMTP server, DKIM-signing it
+// when a key is configured. SMTP has no provider message ID, so the
+// Message-ID header stands in.
+func (p *smtpProvider) Send(ctx context.Context, msg *Message) (string, error) {
+	raw := buildMIME(msg, time.Now())
+	if p.config.DKIMSelector != "" && p.config.DKIMPrivateKey != "" {
+		signed, err := dkimSign(raw, dkimDomain(p.config, msg.From), p.config.DKIMSelector, p.config.DKIMPrivateKey, time.Now())
+		if err != nil {
+			return "", err
+		}
+		raw = signed
+	}
+
+	auth := smtp.PlainAuth("", p.config.SMTPUser, p.config.SMTPPassword, p.config.SMTPHost)
+	addr := fmt.Sprintf("%s:%d", p.config.SMTPHost, p.config.SMTPPort)
+	if err := smtp.SendMail(addr, auth, msg.From, msg.To, raw); err != nil {
+		return "", err
+	}
+	return msg.ID, nil
+}
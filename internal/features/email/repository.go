@@ -2,6 +2,7 @@ package email
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"go-crm/internal/database"
@@ -9,6 +10,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type EmailRepository struct {
@@ -45,3 +47,174 @@ func (r *EmailRepository) UpdateStatus(
 	_, err := r.col.UpdateByID(ctx, id, update)
 	return err
 }
+
+// UpdateDelivery saves the outcome of a send attempt
+func (r *EmailRepository) UpdateDelivery(ctx context.Context, email *Email) error {
+	set := bson.M{
+		"status":            email.Status,
+		"errorMessage":      email.ErrorMsg,
+		"provider":          email.Provider,
+		"providerMessageId": email.ProviderMessageID,
+		"attempts":          email.Attempts,
+		"suppressed":        email.Suppressed,
+	}
+	if email.SentAt != nil {
+		set["sentAt"] = email.SentAt
+	}
+	_, err := r.col.UpdateByID(ctx, email.ID, bson.M{"$set": set})
+	return err
+}
+
+// FindByID looks a message up across tenants; provider events only carry
+// the message ID
+func (r *EmailRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*Email, error) {
+	var email Email
+	if err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&email); err != nil {
+		return nil, err
+	}
+	return &email, nil
+}
+
+// FindByProviderMessageID looks a message up by the ID its provider assigned
+func (r *EmailRepository) FindByProviderMessageID(ctx context.Context, provider, messageID string) (*Email, error) {
+	var email Email
+	if err := r.col.FindOne(ctx, bson.M{"provider": provider, "providerMessageId": messageID}).Decode(&email); err != nil {
+		return nil, err
+	}
+	return &email, nil
+}
+
+// EmailLogQuery filters the sent-mail log
+type EmailLogQuery struct {
+	Status EmailStatus
+	To     string
+	From   *time.Time
+	Until  *time.Time
+	Page   int64
+	Limit  int64
+}
+
+// List returns a tenant's sent mail, newest first
+func (r *EmailRepository) List(ctx context.Context, orgID primitive.ObjectID, q EmailLogQuery) ([]Email, int64, error) {
+	filter := bson.M{"orgId": orgID}
+	if q.Status != "" {
+		filter["status"] = q.Status
+	}
+	if q.To != "" {
+		filter["to"] = q.To
+	}
+	if q.From != nil || q.Until != nil {
+		created := bson.M{}
+		if q.From != nil {
+			created["$gte"] = *q.From
+		}
+		if q.Until != nil {
+			created["$lt"] = *q.Until
+		}
+		filter["createdAt"] = created
+	}
+
+	total, err := r.col.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetSkip((q.Page - 1) * q.Limit).
+		SetLimit(q.Limit)
+	cursor, err := r.col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	emails := []Email{}
+	if err := cursor.All(ctx, &emails); err != nil {
+		return nil, 0, err
+	}
+	return emails, total, nil
+}
+
+type SuppressionRepository struct {
+	col *mongo.Collection
+}
+
+func NewSuppressionRepository(db *database.MongodbDB) *SuppressionRepository {
+	return &SuppressionRepository{
+		col: db.DB.Collection("email_suppressions"),
+	}
+}
+
+// Add suppresses an address, keeping the first reason it was suppressed for
+func (r *SuppressionRepository) Add(ctx context.Context, s *Suppression) error {
+	s.Email = strings.ToLower(strings.TrimSpace(s.Email))
+	s.CreatedAt = time.Now()
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"orgId": s.OrgID, "email": s.Email},
+		bson.M{"$setOnInsert": bson.M{
+			"reason":    s.Reason,
+			"detail":    s.Detail,
+			"emailId":   s.EmailID,
+			"createdAt": s.CreatedAt,
+		}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (r *SuppressionRepository) Remove(ctx context.Context, orgID primitive.ObjectID, email string) error {
+	res, err := r.col.DeleteOne(ctx, bson.M{"orgId": orgID, "email": strings.ToLower(strings.TrimSpace(email))})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *SuppressionRepository) List(ctx context.Context, orgID primitive.ObjectID, page, limit int64) ([]Suppression, int64, error) {
+	filter := bson.M{"orgId": orgID}
+	total, err := r.col.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+	cursor, err := r.col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	suppressions := []Suppression{}
+	if err := cursor.All(ctx, &suppressions); err != nil {
+		return nil, 0, err
+	}
+	return suppressions, total, nil
+}
+
+// Suppressed returns which of the addresses are suppressed, lowercased
+func (r *SuppressionRepository) Suppressed(ctx context.Context, orgID primitive.ObjectID, emails []string) (map[string]bool, error) {
+	lower := make([]string, len(emails))
+	for i, e := range emails {
+		lower[i] = strings.ToLower(strings.TrimSpace(e))
+	}
+	cursor, err := r.col.Find(ctx, bson.M{"orgId": orgID, "email": bson.M{"$in": lower}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var found []Suppression
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+	suppressed := make(map[string]bool, len(found))
+	for _, s := range found {
+		suppressed[s.Email] = true
+	}
+	return suppressed, nil
+}
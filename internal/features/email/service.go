@@ -1,38 +1,134 @@
 package email
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
 	"errors"
 	"fmt"
-	"go-crm/internal/features/settings"
 	"log"
-	"mime"
-	"net/smtp"
-	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/config"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/settings"
+	"go-crm/pkg/utils"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// ErrAllSuppressed is returned when every recipient is on the suppression list
+var ErrAllSuppressed = errors.New("all recipients are suppressed")
+
 type EmailService interface {
 	SendEmail(ctx context.Context, to []string, subject, body string) error
 	SendEmailWithAttachment(ctx context.Context, to []string, subject, body string, attachmentName string, attachmentData []byte) error
+	// SendEmailWithAttachments sends any number of files, including images
+	// the body shows inline
+	SendEmailWithAttachments(ctx context.Context, to []string, subject, body string, attachments []Attachment) error
+
+	// Sent-mail log and suppression list of the tenant in ctx
+	ListLog(ctx context.Context, q EmailLogQuery) ([]Email, int64, error)
+	ListSuppressions(ctx context.Context, page, limit int64) ([]Suppression, int64, error)
+	AddSuppression(ctx context.Context, email, detail string) error
+	RemoveSuppression(ctx context.Context, email string) error
+	// CheckDomain reports the SPF, DKIM and DMARC records of the sending domain
+	CheckDomain(ctx context.Context) (*DomainStatus, error)
+	// HandleEvents ingests a provider's bounce and complaint webhook
+	HandleEvents(ctx context.Context, provider, token string, body []byte) error
 }
 
 type EmailServiceImpl struct {
 	SettingsService settings.SettingsService
 	Repo            *EmailRepository
+	Suppressions    *SuppressionRepository
+	ModuleRepo      module.ModuleRepository
+	RecordRepo      record.RecordRepository
+	Config          *config.Config
+
+	health providerHealth
 }
 
-func NewEmailService(settingsService settings.SettingsService, repo *EmailRepository) EmailService {
+func NewEmailService(
+	settingsService settings.SettingsService,
+	repo *EmailRepository,
+	suppressions *SuppressionRepository,
+	moduleRepo module.ModuleRepository,
+	recordRepo record.RecordRepository,
+	cfg *config.Config,
+) EmailService {
 	return &EmailServiceImpl{
 		SettingsService: settingsService,
 		Repo:            repo,
+		Suppressions:    suppressions,
+		ModuleRepo:      moduleRepo,
+		RecordRepo:      recordRepo,
+		Config:          cfg,
 	}
 }
 
+// providerCooldown is how long a failed provider moves to the back of the
+// chain, so a provider outage does not add a timeout to every send
+const providerCooldown = 2 * time.Minute
+
+type providerHealth struct {
+	mu     sync.Mutex
+	failed map[string]time.Time
+}
+
+// order puts providers that failed recently after the healthy ones
+func (h *providerHealth) order(chain []Provider) []Provider {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	healthy := make([]Provider, 0, len(chain))
+	var cooling []Provider
+	for _, p := range chain {
+		if at, ok := h.failed[p.Name()]; ok && time.Since(at) < providerCooldown {
+			cooling = append(cooling, p)
+			continue
+		}
+		healthy = append(healthy, p)
+	}
+	return append(healthy, cooling...)
+}
+
+func (h *providerHealth) report(name string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err == nil {
+		delete(h.failed, name)
+		return
+	}
+	if h.failed == nil {
+		h.failed = map[string]time.Time{}
+	}
+	h.failed[name] = time.Now()
+}
+
 func (s *EmailServiceImpl) SendEmail(ctx context.Context, to []string, subject, body string) error {
+	return s.send(ctx, to, subject, body, nil)
+}
+
+func (s *EmailServiceImpl) SendEmailWithAttachment(ctx context.Context, to []string, subject, body string, attachmentName string, attachmentData []byte) error {
+	var attachments []Attachment
+	if len(attachmentData) > 0 {
+		attachments = []Attachment{{Filename: attachmentName, Data: attachmentData}}
+	}
+	return s.send(ctx, to, subject, body, attachments)
+}
+
+func (s *EmailServiceImpl) SendEmailWithAttachments(ctx context.Context, to []string, subject, body string, attachments []Attachment) error {
+	return s.send(ctx, to, subject, body, attachments)
+}
+
+// send logs the message, drops suppressed recipients and hands it to the
+// configured providers in turn until one accepts it
+func (s *EmailServiceImpl) send(ctx context.Context, to []string, subject, body string, attachments []Attachment) error {
+	if len(to) == 0 {
+		return errors.New("no recipients")
+	}
 	config, err := s.SettingsService.GetEmailConfig(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to fetch email config: %v", err)
@@ -41,32 +137,28 @@ func (s *EmailServiceImpl) SendEmail(ctx context.Context, to []string, subject,
 		return errors.New("email configuration not found")
 	}
 
-	if config.SMTPHost == "" || config.SMTPPort == 0 {
-		return errors.New("invalid email configuration: missing host or port")
+	chain := providerChain(config)
+	if len(chain) == 0 {
+		return errors.New("invalid email configuration: no provider configured")
 	}
 
-	auth := smtp.PlainAuth("", config.SMTPUser, config.SMTPPassword, config.SMTPHost)
-
-	addr := fmt.Sprintf("%s:%d", config.SMTPHost, config.SMTPPort)
 	from := config.FromEmail
 	if from == "" {
 		from = config.SMTPUser
 	}
+	if from == "" && config.SendingDomain != "" {
+		from = "noreply@" + config.SendingDomain
+	}
+	if config.SendingDomain != "" && !inDomain(from, config.SendingDomain) {
+		return fmt.Errorf("from address %s is outside the sending domain %s", from, config.SendingDomain)
+	}
 
-	// Try to get OrgID from context
-	var orgID primitive.ObjectID
-	if val := ctx.Value("orgId"); val != nil {
-		if id, ok := val.(primitive.ObjectID); ok {
-			orgID = id
-		} else if idStr, ok := val.(string); ok {
-			// Try parsing string
-			if oid, err := primitive.ObjectIDFromHex(idStr); err == nil {
-				orgID = oid
-			}
-		}
+	orgID := orgFromContext(ctx)
+	var sentBy primitive.ObjectID
+	if claims, ok := ctx.Value(utils.UserClaimsKey).(*utils.UserClaims); ok {
+		sentBy, _ = primitive.ObjectIDFromHex(claims.UserID)
 	}
 
-	// Create email record
 	emailRecord := &Email{
 		ID:       primitive.NewObjectID(),
 		OrgID:    orgID,
@@ -75,101 +167,147 @@ func (s *EmailServiceImpl) SendEmail(ctx context.Context, to []string, subject,
 		Subject:  subject,
 		HtmlBody: body,
 		Status:   EmailQueued,
+		SentBy:   sentBy,
+	}
+
+	recipients := to
+	if s.Suppressions != nil && !orgID.IsZero() {
+		if suppressed, err := s.Suppressions.Suppressed(ctx, orgID, to); err == nil && len(suppressed) > 0 {
+			recipients = nil
+			for _, addr := range to {
+				if suppressed[strings.ToLower(strings.TrimSpace(addr))] {
+					emailRecord.Suppressed = append(emailRecord.Suppressed, addr)
+				} else {
+					recipients = append(recipients, addr)
+				}
+			}
+		}
 	}
 
 	if s.Repo != nil {
 		_ = s.Repo.Create(ctx, emailRecord)
 	}
 
-	msg := []byte(fmt.Sprintf("To: %s\r\n"+
-		"Subject: %s\r\n"+
-		"\r\n"+
-		"%s\r\n", to[0], subject, body))
-
-	log.Printf("Sending email to %v via %s...", to, addr)
-	err = smtp.SendMail(addr, auth, from, to, msg)
-
-	status := EmailSent
-	errMsg := ""
-	if err != nil {
-		status = EmailFailed
-		errMsg = err.Error()
+	if len(recipients) == 0 {
+		emailRecord.Status = EmailFailed
+		emailRecord.ErrorMsg = ErrAllSuppressed.Error()
+		s.saveDelivery(ctx, emailRecord)
+		return ErrAllSuppressed
 	}
 
-	if s.Repo != nil {
-		_ = s.Repo.UpdateStatus(ctx, emailRecord.ID, status, errMsg)
+	msg := &Message{
+		ID:          emailRecord.ID.Hex(),
+		From:        from,
+		FromName:    config.FromName,
+		To:          recipients,
+		Subject:     subject,
+		Body:        body,
+		Attachments: attachments,
 	}
 
-	if err != nil {
-		return fmt.Errorf("failed to send email: %v", err)
+	for _, provider := range s.health.order(chain) {
+		log.Printf("Sending email to %v via %s...", recipients, provider.Name())
+		messageID, err := provider.Send(ctx, msg)
+		s.health.report(provider.Name(), err)
+		if err != nil {
+			log.Printf("Email provider %s failed: %v", provider.Name(), err)
+			emailRecord.Attempts = append(emailRecord.Attempts, provider.Name()+": "+err.Error())
+			continue
+		}
+
+		now := time.Now()
+		emailRecord.Status = EmailSent
+		emailRecord.ErrorMsg = ""
+		emailRecord.Provider = provider.Name()
+		emailRecord.ProviderMessageID = messageID
+		emailRecord.SentAt = &now
+		s.saveDelivery(ctx, emailRecord)
+		log.Println("Email sent successfully")
+		return nil
 	}
 
-	log.Println("Email sent successfully")
-	return nil
+	emailRecord.Status = EmailFailed
+	emailRecord.ErrorMsg = strings.Join(emailRecord.Attempts, "; ")
+	s.saveDelivery(ctx, emailRecord)
+	return fmt.Errorf("failed to send email: %s", emailRecord.ErrorMsg)
 }
 
-func (s *EmailServiceImpl) SendEmailWithAttachment(ctx context.Context, to []string, subject, body string, attachmentName string, attachmentData []byte) error {
-	config, err := s.SettingsService.GetEmailConfig(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to fetch email config: %v", err)
-	}
-	if config == nil {
-		return errors.New("email configuration not found")
+func (s *EmailServiceImpl) saveDelivery(ctx context.Context, email *Email) {
+	if s.Repo != nil {
+		_ = s.Repo.UpdateDelivery(ctx, email)
 	}
+}
 
-	if config.SMTPHost == "" || config.SMTPPort == 0 {
-		return errors.New("invalid email configuration: missing host or port")
+// orgFromContext reads the tenant of the sending request
+func orgFromContext(ctx context.Context) primitive.ObjectID {
+	var orgID primitive.ObjectID
+	if val := ctx.Value("orgId"); val != nil {
+		if id, ok := val.(primitive.ObjectID); ok {
+			orgID = id
+		} else if idStr, ok := val.(string); ok {
+			// Try parsing string
+			if oid, err := primitive.ObjectIDFromHex(idStr); err == nil {
+				orgID = oid
+			}
+		}
 	}
-
-	auth := smtp.PlainAuth("", config.SMTPUser, config.SMTPPassword, config.SMTPHost)
-
-	addr := fmt.Sprintf("%s:%d", config.SMTPHost, config.SMTPPort)
-	from := config.FromEmail
-	if from == "" {
-		from = config.SMTPUser
+	if orgID.IsZero() {
+		if tenantID, ok := ctx.Value(common_models.TenantIDKey).(string); ok {
+			orgID, _ = primitive.ObjectIDFromHex(tenantID)
+		}
 	}
+	return orgID
+}
 
-	marker := "ACRMarker"
-	var buf bytes.Buffer
-	buf.WriteString(fmt.Sprintf("From: %s\r\n", from))
-	buf.WriteString(fmt.Sprintf("To: %s\r\n", to[0]))
-	buf.WriteString(fmt.Sprintf("Subject: %s\r\n", subject))
-	buf.WriteString("MIME-Version: 1.0\r\n")
-	buf.WriteString(fmt.Sprintf("Content-Type: multipart/mixed; boundary=%s\r\n", marker))
-	buf.WriteString("\r\n")
+// inDomain reports whether the address belongs to domain or a subdomain
+func inDomain(address, domain string) bool {
+	host := strings.ToLower(address[strings.LastIndex(address, "@")+1:])
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
 
-	buf.WriteString(fmt.Sprintf("--%s\r\n", marker))
-	buf.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
-	buf.WriteString("\r\n")
-	buf.WriteString(body)
-	buf.WriteString("\r\n")
+func (s *EmailServiceImpl) ListLog(ctx context.Context, q EmailLogQuery) ([]Email, int64, error) {
+	orgID := orgFromContext(ctx)
+	if orgID.IsZero() {
+		return nil, 0, errors.New("organization context missing")
+	}
+	return s.Repo.List(ctx, orgID, q)
+}
 
-	if len(attachmentData) > 0 {
-		buf.WriteString(fmt.Sprintf("--%s\r\n", marker))
-		ext := filepath.Ext(attachmentName)
-		contentType := mime.TypeByExtension(ext)
-		if contentType == "" {
-			contentType = "application/octet-stream"
-		}
-		buf.WriteString(fmt.Sprintf("Content-Type: %s; name=\"%s\"\r\n", contentType, attachmentName))
-		buf.WriteString("Content-Transfer-Encoding: base64\r\n")
-		buf.WriteString(fmt.Sprintf("Content-Disposition: attachment; filename=\"%s\"\r\n", attachmentName))
-		buf.WriteString("\r\n")
+func (s *EmailServiceImpl) ListSuppressions(ctx context.Context, page, limit int64) ([]Suppression, int64, error) {
+	orgID := orgFromContext(ctx)
+	if orgID.IsZero() {
+		return nil, 0, errors.New("organization context missing")
+	}
+	return s.Suppressions.List(ctx, orgID, page, limit)
+}
 
-		b := make([]byte, base64.StdEncoding.EncodedLen(len(attachmentData)))
-		base64.StdEncoding.Encode(b, attachmentData)
-		buf.Write(b)
-		buf.WriteString("\r\n")
+func (s *EmailServiceImpl) AddSuppression(ctx context.Context, email, detail string) error {
+	orgID := orgFromContext(ctx)
+	if orgID.IsZero() {
+		return errors.New("organization context missing")
 	}
+	if !strings.Contains(email, "@") {
+		return errors.New("invalid email address")
+	}
+	return s.Suppressions.Add(ctx, &Suppression{OrgID: orgID, Email: email, Reason: SuppressionManual, Detail: detail})
+}
 
-	buf.WriteString(fmt.Sprintf("--%s--", marker))
+func (s *EmailServiceImpl) RemoveSuppression(ctx context.Context, email string) error {
+	orgID := orgFromContext(ctx)
+	if orgID.IsZero() {
+		return errors.New("organization context missing")
+	}
+	return s.Suppressions.Remove(ctx, orgID, email)
+}
 
-	log.Printf("Sending email with attachment to %v via %s...", to, addr)
-	err = smtp.SendMail(addr, auth, from, to, buf.Bytes())
+func (s *EmailServiceImpl) CheckDomain(ctx context.Context) (*DomainStatus, error) {
+	config, err := s.SettingsService.GetEmailConfig(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to send email with attachment: %v", err)
+		return nil, err
 	}
-
-	log.Println("Email with attachment sent successfully")
-	return nil
+	if config == nil {
+		return nil, errors.New("email configuration not found")
+	}
+	return checkDomain(ctx, config), nil
 }
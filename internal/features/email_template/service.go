@@ -9,6 +9,8 @@ import (
 	"go-crm/internal/features/audit"
 	"go-crm/internal/features/email"
 	"go-crm/internal/features/module"
+	"go-crm/internal/features/settings"
+	"go-crm/pkg/locale"
 	"strings"
 )
 
@@ -20,14 +22,17 @@ type EmailTemplateService interface {
 	DeleteTemplate(ctx context.Context, id string) error
 	GetModuleFields(ctx context.Context, moduleName string) ([]models.ModuleField, error)
 	RenderTemplate(ctx context.Context, templateID string, record map[string]interface{}) (string, string, error)
+	// RenderText fills {{field}} placeholders of ad-hoc text, formatting values in the tenant's locale
+	RenderText(ctx context.Context, moduleName string, text string, record map[string]interface{}) string
 	SendTestEmail(ctx context.Context, templateID string, to string, testData map[string]interface{}) error
 }
 
 type EmailTemplateServiceImpl struct {
-	Repo         EmailTemplateRepository
-	ModuleRepo   module.ModuleRepository
-	AuditService audit.AuditService
-	EmailService email.EmailService
+	Repo            EmailTemplateRepository
+	ModuleRepo      module.ModuleRepository
+	AuditService    audit.AuditService
+	EmailService    email.EmailService
+	SettingsService settings.SettingsService
 }
 
 func NewEmailTemplateService(
@@ -35,12 +40,14 @@ func NewEmailTemplateService(
 	moduleRepo module.ModuleRepository,
 	auditService audit.AuditService,
 	emailService email.EmailService,
+	settingsService settings.SettingsService,
 ) EmailTemplateService {
 	return &EmailTemplateServiceImpl{
-		Repo:         repo,
-		ModuleRepo:   moduleRepo,
-		AuditService: auditService,
-		EmailService: emailService,
+		Repo:            repo,
+		ModuleRepo:      moduleRepo,
+		AuditService:    auditService,
+		EmailService:    emailService,
+		SettingsService: settingsService,
 	}
 }
 
@@ -129,17 +136,30 @@ func (s *EmailTemplateServiceImpl) RenderTemplate(ctx context.Context, templateI
 		return "", "", err
 	}
 
-	subject := s.replacePlaceholders(template.Subject, record)
-	body := s.replacePlaceholders(template.Body, record)
+	subject := s.RenderText(ctx, template.ModuleName, template.Subject, record)
+	body := s.RenderText(ctx, template.ModuleName, template.Body, record)
 
 	return subject, body, nil
 }
 
-func (s *EmailTemplateServiceImpl) replacePlaceholders(text string, record map[string]interface{}) string {
+func (s *EmailTemplateServiceImpl) RenderText(ctx context.Context, moduleName string, text string, record map[string]interface{}) string {
+	// Emails go out on behalf of the tenant, so its locale applies rather than a user's
+	lf := locale.Default()
+	if s.SettingsService != nil {
+		lf = s.SettingsService.Formatter(ctx, "")
+	}
+	fieldTypes := map[string]string{}
+	if moduleName != "" {
+		if mod, err := s.ModuleRepo.FindByName(ctx, moduleName); err == nil && mod != nil {
+			for _, f := range mod.Fields {
+				fieldTypes[f.Name] = string(f.Type)
+			}
+		}
+	}
+
 	for key, value := range record {
 		placeholder := fmt.Sprintf("{{%s}}", key)
-		replacement := fmt.Sprintf("%v", value)
-		text = strings.ReplaceAll(text, placeholder, replacement)
+		text = strings.ReplaceAll(text, placeholder, lf.Field(fieldTypes[key], value))
 	}
 	return text
 }
@@ -0,0 +1,32 @@
+package email_verification
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type EmailVerificationApi struct {
+	controller  *EmailVerificationController
+	config      *config.Config
+	roleService middleware.RoleService
+}
+
+func NewEmailVerificationApi(controller *EmailVerificationController, config *config.Config, roleService middleware.RoleService) *EmailVerificationApi {
+	return &EmailVerificationApi{
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *EmailVerificationApi) Setup(app *fiber.App) {
+	group := app.Group("/api/email-verification", middleware.AuthMiddleware(h.config.SkipAuth))
+
+	group.Get("/settings", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.GetSettings)
+	group.Put("/settings", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.SaveSettings)
+	group.Post("/run", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.Run)
+	group.Get("/runs", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListRuns)
+	group.Get("/runs/:id", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.GetRun)
+}
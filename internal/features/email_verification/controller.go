@@ -0,0 +1,104 @@
+package email_verification
+
+import (
+	common_api "go-crm/internal/common/api"
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type EmailVerificationController struct {
+	Service EmailVerificationService
+}
+
+func NewEmailVerificationController(service EmailVerificationService) *EmailVerificationController {
+	return &EmailVerificationController{Service: service}
+}
+
+// GetSettings godoc
+// @Summary Get email verification settings
+// @Tags email-verification
+// @Produce json
+// @Success 200 {object} Settings
+// @Router /api/email-verification/settings [get]
+func (c *EmailVerificationController) GetSettings(ctx *fiber.Ctx) error {
+	settings, err := c.Service.GetSettings(ctx.UserContext())
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+	return ctx.JSON(fiber.Map{"data": settings})
+}
+
+// SaveSettings godoc
+// @Summary Save email verification settings
+// @Description Choose the modules whose emails are verified, whether domains must accept mail (MX check) and an optional verification provider. Active settings run weekly.
+// @Tags email-verification
+// @Accept json
+// @Produce json
+// @Param settings body Settings true "Settings"
+// @Success 200 {object} Settings
+// @Failure 400 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{} "Unknown module or invalid provider URL"
+// @Router /api/email-verification/settings [put]
+func (c *EmailVerificationController) SaveSettings(ctx *fiber.Ctx) error {
+	var settings Settings
+	if err := ctx.BodyParser(&settings); err != nil {
+		return common_api.InvalidBody(ctx, err)
+	}
+	userID, _ := session.UserID(ctx.UserContext())
+	saved, err := c.Service.SaveSettings(ctx.UserContext(), &settings, userID)
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+	return ctx.JSON(fiber.Map{"data": saved})
+}
+
+// Run godoc
+// @Summary Run email verification
+// @Description Start verifying the tenant's emails now: syntax, MX and provider checks, normalization and flagging of case and alias duplicates. Records get their verdict in email_status. Poll the returned run for its report.
+// @Tags email-verification
+// @Produce json
+// @Success 202 {object} Run
+// @Failure 409 {object} map[string]interface{} "A run is already going"
+// @Router /api/email-verification/run [post]
+func (c *EmailVerificationController) Run(ctx *fiber.Ctx) error {
+	userID, _ := session.UserID(ctx.UserContext())
+	run, err := c.Service.Run(ctx.UserContext(), userID)
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+	return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{"data": run})
+}
+
+// ListRuns godoc
+// @Summary List email verification runs
+// @Description Past runs, newest first, with their counts per module but without findings
+// @Tags email-verification
+// @Produce json
+// @Param limit query int false "Runs to return (default 20, max 100)"
+// @Success 200 {array} Run
+// @Router /api/email-verification/runs [get]
+func (c *EmailVerificationController) ListRuns(ctx *fiber.Ctx) error {
+	runs, err := c.Service.ListRuns(ctx.UserContext(), int64(ctx.QueryInt("limit")))
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+	return ctx.JSON(fiber.Map{"data": runs})
+}
+
+// GetRun godoc
+// @Summary Get email verification run
+// @Description A run's report: counts per module and the records flagged or normalized
+// @Tags email-verification
+// @Produce json
+// @Param id path string true "Run ID"
+// @Success 200 {object} Run
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/email-verification/runs/{id} [get]
+func (c *EmailVerificationController) GetRun(ctx *fiber.Ctx) error {
+	run, err := c.Service.GetRun(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+	return ctx.JSON(fiber.Map{"data": run})
+}
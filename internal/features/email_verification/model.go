@@ -0,0 +1,110 @@
+package email_verification
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Verdicts written to StatusField of each checked record
+const (
+	StatusValid     = "valid"
+	StatusInvalid   = "invalid"
+	StatusRisky     = "risky"     // The provider would not vouch for it, e.g. catch-all or disposable
+	StatusUnknown   = "unknown"   // The domain or provider could not be reached this run
+	StatusDuplicate = "duplicate" // Another record of the module has the same address
+)
+
+// StatusField is the select field of contacts and leads holding the verdict;
+// see internal/migration/data/modules.json
+const StatusField = "email_status"
+
+// Reasons a finding gives for its verdict
+const (
+	ReasonSyntax   = "syntax"
+	ReasonNoMX     = "no_mx"     // The domain does not accept mail
+	ReasonDNS      = "dns_error" // The lookup failed, not answered
+	ReasonProvider = "provider"  // Verdict of the verification provider
+	ReasonAlias    = "alias"     // Same mailbox as DuplicateOf, up to case, dots or a +tag
+)
+
+// What started a run
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+const (
+	RunStatusRunning   = "running"
+	RunStatusCompleted = "completed"
+	RunStatusFailed    = "failed"
+)
+
+// Settings is how a tenant verifies the emails of its records. Modules
+// default to contacts and leads; each is checked through its first email
+// field.
+type Settings struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	IsActive bool               `json:"is_active" bson:"is_active"` // Run nightly
+	Modules  []string           `json:"modules,omitempty" bson:"modules,omitempty"`
+	CheckMX  bool               `json:"check_mx" bson:"check_mx"`
+	Provider *Provider          `json:"provider,omitempty" bson:"provider,omitempty"`
+
+	LastRunAt *time.Time `json:"last_run_at,omitempty" bson:"last_run_at,omitempty"`
+
+	UpdatedBy primitive.ObjectID `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// Provider is a third-party verification API. Each address is POSTed as
+// {"email": ...} with the key as a bearer token, and the answer read as
+// {"status": "valid" | "invalid" | "risky" | "unknown", "reason": ...}.
+type Provider struct {
+	URL    string `json:"url" bson:"url"`
+	APIKey string `json:"api_key,omitempty" bson:"api_key"` // Write-only; blank when read
+}
+
+// Run is the report of one verification pass over a tenant's modules.
+// Findings list the records that were not valid or were normalized, up to
+// maxFindings.
+type Run struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID    primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	Trigger     string             `json:"trigger" bson:"trigger"`
+	TriggeredBy primitive.ObjectID `json:"triggered_by,omitempty" bson:"triggered_by,omitempty"`
+	Status      string             `json:"status" bson:"status"`
+	Error       string             `json:"error,omitempty" bson:"error,omitempty"`
+	Modules     []ModuleResult     `json:"modules" bson:"modules"`
+	Findings    []Finding          `json:"findings" bson:"findings"`
+	Truncated   bool               `json:"truncated,omitempty" bson:"truncated,omitempty"` // More findings than listed
+	StartedAt   time.Time          `json:"started_at" bson:"started_at"`
+	FinishedAt  *time.Time         `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+}
+
+// ModuleResult counts the verdicts of a module's records. Records without
+// an email are not checked.
+type ModuleResult struct {
+	Module     string `json:"module" bson:"module"`
+	Field      string `json:"field" bson:"field"`
+	Checked    int    `json:"checked" bson:"checked"`
+	Valid      int    `json:"valid" bson:"valid"`
+	Invalid    int    `json:"invalid" bson:"invalid"`
+	Risky      int    `json:"risky" bson:"risky"`
+	Unknown    int    `json:"unknown" bson:"unknown"`
+	Duplicates int    `json:"duplicates" bson:"duplicates"`
+	Normalized int    `json:"normalized" bson:"normalized"` // Emails rewritten in normal form
+	Updated    int    `json:"updated" bson:"updated"`       // Records written
+}
+
+// Finding is a record whose email was flagged or rewritten
+type Finding struct {
+	Module      string `json:"module" bson:"module"`
+	RecordID    string `json:"record_id" bson:"record_id"`
+	Email       string `json:"email" bson:"email"`
+	Normalized  string `json:"normalized,omitempty" bson:"normalized,omitempty"`
+	Status      string `json:"status" bson:"status"`
+	Reason      string `json:"reason,omitempty" bson:"reason,omitempty"`
+	DuplicateOf string `json:"duplicate_of,omitempty" bson:"duplicate_of,omitempty"`
+}
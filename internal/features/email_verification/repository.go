@@ -0,0 +1,180 @@
+package email_verification
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+type SettingsRepository interface {
+	// Get returns the tenant's settings
+	Get(ctx context.Context) (*Settings, error)
+	Save(ctx context.Context, settings *Settings) error
+	SetLastRun(ctx context.Context, at time.Time) error
+	// ListAllActive returns active settings of every tenant for the scheduled run
+	ListAllActive(ctx context.Context) ([]Settings, error)
+}
+
+type SettingsRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewSettingsRepository(db *database.MongodbDB) SettingsRepository {
+	return &SettingsRepositoryImpl{
+		collection: db.DB.Collection("email_verification_settings"),
+	}
+}
+
+func (r *SettingsRepositoryImpl) Get(ctx context.Context) (*Settings, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var settings Settings
+	if err := r.collection.FindOne(ctx, bson.M{"tenant_id": tenantID}).Decode(&settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (r *SettingsRepositoryImpl) Save(ctx context.Context, settings *Settings) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	settings.TenantID = tenantID
+	settings.UpdatedAt = now
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	return r.collection.FindOneAndUpdate(ctx, bson.M{"tenant_id": tenantID}, bson.M{
+		"$set": bson.M{
+			"is_active":  settings.IsActive,
+			"modules":    settings.Modules,
+			"check_mx":   settings.CheckMX,
+			"provider":   settings.Provider,
+			"updated_by": settings.UpdatedBy,
+			"updated_at": now,
+		},
+		"$setOnInsert": bson.M{"created_at": now},
+	}, opts).Decode(settings)
+}
+
+func (r *SettingsRepositoryImpl) SetLastRun(ctx context.Context, at time.Time) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.UpdateOne(ctx, bson.M{"tenant_id": tenantID}, bson.M{
+		"$set": bson.M{"last_run_at": at},
+	})
+	return err
+}
+
+func (r *SettingsRepositoryImpl) ListAllActive(ctx context.Context) ([]Settings, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"is_active": true})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var settings []Settings
+	if err := cursor.All(ctx, &settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+type RunRepository interface {
+	Create(ctx context.Context, run *Run) error
+	Update(ctx context.Context, run *Run) error
+	Get(ctx context.Context, id string) (*Run, error)
+	// List returns the tenant's runs, newest first, without their findings
+	List(ctx context.Context, limit int64) ([]Run, error)
+}
+
+type RunRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewRunRepository(db *database.MongodbDB) RunRepository {
+	return &RunRepositoryImpl{
+		collection: db.DB.Collection("email_verification_runs"),
+	}
+}
+
+func (r *RunRepositoryImpl) Create(ctx context.Context, run *Run) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	run.TenantID = tenantID
+	if run.ID.IsZero() {
+		run.ID = primitive.NewObjectID()
+	}
+	_, err = r.collection.InsertOne(ctx, run)
+	return err
+}
+
+func (r *RunRepositoryImpl) Update(ctx context.Context, run *Run) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": run.ID, "tenant_id": tenantID}, run)
+	return err
+}
+
+func (r *RunRepositoryImpl) Get(ctx context.Context, id string) (*Run, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, mongo.ErrNoDocuments
+	}
+	var run Run
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID}).Decode(&run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (r *RunRepositoryImpl) List(ctx context.Context, limit int64) ([]Run, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "started_at", Value: -1}}).
+		SetLimit(limit).
+		SetProjection(bson.M{"findings": 0})
+	cursor, err := r.collection.Find(ctx, bson.M{"tenant_id": tenantID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	runs := []Run{}
+	if err := cursor.All(ctx, &runs); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
@@ -0,0 +1,405 @@
+package email_verification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/common/validation"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/record"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// VerificationSchedule runs every active tenant weekly, early on Sunday
+const VerificationSchedule = "30 3 * * 0"
+
+const (
+	// pageSize is the number of records read per query
+	pageSize = 500
+	// maxFindings bounds the findings a run report lists
+	maxFindings       = 1000
+	defaultRunsLimit  = 20
+	maxRunsLimit      = 100
+	domainCheckBudget = 5 * time.Second
+)
+
+// DefaultModules are verified when the settings name none
+var DefaultModules = []string{"contacts", "leads"}
+
+var (
+	ErrRunRunning  = apperr.Conflict("an email verification run is already going for this tenant")
+	ErrRunNotFound = apperr.NotFound("email verification run not found")
+)
+
+type EmailVerificationService interface {
+	// GetSettings returns the tenant's settings, or inactive defaults when
+	// none are saved. The provider key is never returned.
+	GetSettings(ctx context.Context) (*Settings, error)
+	// SaveSettings replaces the settings; a blank provider key keeps the
+	// saved one
+	SaveSettings(ctx context.Context, settings *Settings, userID primitive.ObjectID) (*Settings, error)
+	// Run starts a verification of the tenant's modules in the background
+	// and returns its report, which fills in as it goes
+	Run(ctx context.Context, userID primitive.ObjectID) (*Run, error)
+	// RunAll verifies every active tenant; registered as a system job
+	RunAll(ctx context.Context) error
+	ListRuns(ctx context.Context, limit int64) ([]Run, error)
+	GetRun(ctx context.Context, id string) (*Run, error)
+}
+
+type EmailVerificationServiceImpl struct {
+	SettingsRepo SettingsRepository
+	RunRepo      RunRepository
+	ModuleRepo   module.ModuleRepository
+	RecordRepo   record.RecordRepository
+	AuditService audit.AuditService
+
+	resolver resolver
+	running  sync.Map // tenant -> struct{}
+}
+
+func NewEmailVerificationService(
+	settingsRepo SettingsRepository,
+	runRepo RunRepository,
+	moduleRepo module.ModuleRepository,
+	recordRepo record.RecordRepository,
+	auditService audit.AuditService,
+) EmailVerificationService {
+	return &EmailVerificationServiceImpl{
+		SettingsRepo: settingsRepo,
+		RunRepo:      runRepo,
+		ModuleRepo:   moduleRepo,
+		RecordRepo:   recordRepo,
+		AuditService: auditService,
+		resolver:     net.DefaultResolver,
+	}
+}
+
+func (s *EmailVerificationServiceImpl) GetSettings(ctx context.Context) (*Settings, error) {
+	settings, err := s.SettingsRepo.Get(ctx)
+	if err == mongo.ErrNoDocuments {
+		return &Settings{Modules: DefaultModules, CheckMX: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if settings.Provider != nil {
+		settings.Provider.APIKey = ""
+	}
+	return settings, nil
+}
+
+func (s *EmailVerificationServiceImpl) SaveSettings(ctx context.Context, settings *Settings, userID primitive.ObjectID) (*Settings, error) {
+	var errs validation.Errors
+	for i, name := range settings.Modules {
+		if _, err := s.emailField(ctx, name); err != nil {
+			errs.Add(fmt.Sprintf("modules.%d", i), validation.CodeInvalid, err.Error())
+		}
+	}
+	if p := settings.Provider; p != nil {
+		if u, err := url.Parse(p.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			errs.Add("provider.url", validation.CodeInvalid, "provider url must be an http or https URL")
+		}
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.SettingsRepo.Get(ctx)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	if p := settings.Provider; p != nil && p.APIKey == "" && existing != nil && existing.Provider != nil {
+		p.APIKey = existing.Provider.APIKey
+	}
+
+	settings.UpdatedBy = userID
+	if err := s.SettingsRepo.Save(ctx, settings); err != nil {
+		return nil, err
+	}
+	if settings.Provider != nil {
+		settings.Provider.APIKey = ""
+	}
+	if existing != nil && existing.Provider != nil {
+		existing.Provider.APIKey = ""
+	}
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "email_verification", settings.ID.Hex(), map[string]common_models.Change{
+		"settings": {Old: existing, New: settings},
+	})
+	return settings, nil
+}
+
+func (s *EmailVerificationServiceImpl) Run(ctx context.Context, userID primitive.ObjectID) (*Run, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := s.SettingsRepo.Get(ctx)
+	if err == mongo.ErrNoDocuments {
+		settings = &Settings{TenantID: tenantID, Modules: DefaultModules, CheckMX: true}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if _, busy := s.running.LoadOrStore(tenantID.Hex(), struct{}{}); busy {
+		return nil, ErrRunRunning
+	}
+	run := &Run{Trigger: TriggerManual, TriggeredBy: userID, Status: RunStatusRunning, Modules: []ModuleResult{}, Findings: []Finding{}, StartedAt: time.Now()}
+	if err := s.RunRepo.Create(ctx, run); err != nil {
+		s.running.Delete(tenantID.Hex())
+		return nil, err
+	}
+
+	// Background run keeps the tenant so record reads stay scoped
+	bgCtx := context.WithValue(context.Background(), common_models.TenantIDKey, tenantID.Hex())
+	report := *run
+	go func() {
+		defer s.running.Delete(tenantID.Hex())
+		s.run(bgCtx, settings, &report)
+	}()
+	return run, nil
+}
+
+func (s *EmailVerificationServiceImpl) RunAll(ctx context.Context) error {
+	all, err := s.SettingsRepo.ListAllActive(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range all {
+		settings := &all[i]
+		key := settings.TenantID.Hex()
+		if _, busy := s.running.LoadOrStore(key, struct{}{}); busy {
+			continue
+		}
+		tenantCtx := context.WithValue(ctx, common_models.TenantIDKey, key)
+		run := &Run{Trigger: TriggerSchedule, Status: RunStatusRunning, Modules: []ModuleResult{}, Findings: []Finding{}, StartedAt: time.Now()}
+		if err := s.RunRepo.Create(tenantCtx, run); err != nil {
+			log.Printf("email verification: tenant %s failed: %v", key, err)
+		} else {
+			s.run(tenantCtx, settings, run)
+		}
+		s.running.Delete(key)
+	}
+	return nil
+}
+
+func (s *EmailVerificationServiceImpl) ListRuns(ctx context.Context, limit int64) ([]Run, error) {
+	if limit <= 0 || limit > maxRunsLimit {
+		limit = defaultRunsLimit
+	}
+	return s.RunRepo.List(ctx, limit)
+}
+
+func (s *EmailVerificationServiceImpl) GetRun(ctx context.Context, id string) (*Run, error) {
+	run, err := s.RunRepo.Get(ctx, id)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrRunNotFound
+	}
+	return run, err
+}
+
+// run verifies each module in turn and saves the report as it goes
+func (s *EmailVerificationServiceImpl) run(ctx context.Context, settings *Settings, run *Run) {
+	modules := settings.Modules
+	if len(modules) == 0 {
+		modules = DefaultModules
+	}
+	v := &verifier{settings: settings, resolver: s.resolver, domains: make(map[string][2]string)}
+
+	var failed []string
+	for _, name := range modules {
+		result, err := s.verifyModule(ctx, v, name, run)
+		if result != nil {
+			run.Modules = append(run.Modules, *result)
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", name, err))
+		}
+		if err := s.RunRepo.Update(ctx, run); err != nil {
+			log.Printf("email verification: saving run %s failed: %v", run.ID.Hex(), err)
+		}
+	}
+
+	now := time.Now()
+	run.FinishedAt = &now
+	run.Status = RunStatusCompleted
+	if len(failed) > 0 {
+		run.Status = RunStatusFailed
+		run.Error = strings.Join(failed, "; ")
+	}
+	if err := s.RunRepo.Update(ctx, run); err != nil {
+		log.Printf("email verification: saving run %s failed: %v", run.ID.Hex(), err)
+	}
+	_ = s.SettingsRepo.SetLastRun(ctx, now)
+}
+
+// verifyModule pages through the module's records oldest first, so the
+// first record of a mailbox keeps its verdict and later aliases are
+// duplicates of it
+func (s *EmailVerificationServiceImpl) verifyModule(ctx context.Context, v *verifier, name string, run *Run) (*ModuleResult, error) {
+	field, err := s.emailField(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	result := &ModuleResult{Module: name, Field: field}
+	firstOf := make(map[string]string) // canonical address -> record ID
+
+	var lastID primitive.ObjectID
+	for {
+		filter := map[string]any{}
+		if !lastID.IsZero() {
+			filter["_id"] = bson.M{"$gt": lastID}
+		}
+		recs, err := s.RecordRepo.List(ctx, name, filter, nil, pageSize, 0, "_id", 1)
+		if err != nil {
+			return result, err
+		}
+		for _, rec := range recs {
+			lastID, _ = rec["_id"].(primitive.ObjectID)
+			raw, _ := rec[field].(string)
+			if strings.TrimSpace(raw) == "" {
+				continue
+			}
+			id := lastID.Hex()
+			result.Checked++
+
+			addr, status, reason := v.verify(ctx, raw)
+			finding := Finding{Module: name, RecordID: id, Email: raw, Status: status, Reason: reason}
+			if status != StatusInvalid {
+				key := Canonical(addr)
+				if first, seen := firstOf[key]; seen {
+					finding.Status, finding.Reason, finding.DuplicateOf = StatusDuplicate, ReasonAlias, first
+				} else {
+					firstOf[key] = id
+				}
+			}
+			result.count(finding.Status)
+
+			update := map[string]any{}
+			if addr != raw && status != StatusInvalid {
+				update[field] = addr
+				finding.Normalized = addr
+				result.Normalized++
+			}
+			if current, _ := rec[StatusField].(string); current != finding.Status {
+				update[StatusField] = finding.Status
+			}
+			if len(update) > 0 {
+				if err := s.RecordRepo.Update(ctx, name, id, update); err != nil {
+					return result, err
+				}
+				changes := make(map[string]common_models.Change, len(update))
+				for k, val := range update {
+					changes[k] = common_models.Change{Old: rec[k], New: val}
+				}
+				_ = s.AuditService.LogChange(ctx, common_models.AuditActionUpdate, name, id, changes)
+				result.Updated++
+			}
+
+			if finding.Status != StatusValid || finding.Normalized != "" {
+				if len(run.Findings) < maxFindings {
+					run.Findings = append(run.Findings, finding)
+				} else {
+					run.Truncated = true
+				}
+			}
+		}
+		if len(recs) < pageSize {
+			return result, nil
+		}
+	}
+}
+
+// emailField returns the name of the module's first email field
+func (s *EmailVerificationServiceImpl) emailField(ctx context.Context, name string) (string, error) {
+	m, err := s.ModuleRepo.FindByName(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("module '%s' not found", name)
+	}
+	for _, f := range m.Fields {
+		if f.Type == common_models.FieldTypeEmail {
+			return f.Name, nil
+		}
+	}
+	return "", fmt.Errorf("module '%s' has no email field", name)
+}
+
+func (r *ModuleResult) count(status string) {
+	switch status {
+	case StatusValid:
+		r.Valid++
+	case StatusInvalid:
+		r.Invalid++
+	case StatusRisky:
+		r.Risky++
+	case StatusDuplicate:
+		r.Duplicates++
+	default:
+		r.Unknown++
+	}
+}
+
+// verifier checks addresses for one run, looking each domain up once
+type verifier struct {
+	settings *Settings
+	resolver resolver
+	domains  map[string][2]string // domain -> status, reason
+
+	providerDown bool
+}
+
+// verify returns the normalized address and its verdict: the syntax, then
+// the domain's mail servers when CheckMX is set, then the provider if any.
+// Once the provider fails, the rest of the run goes without it.
+func (v *verifier) verify(ctx context.Context, raw string) (string, string, string) {
+	addr, ok := Normalize(raw)
+	if !ok {
+		return addr, StatusInvalid, ReasonSyntax
+	}
+
+	if v.settings.CheckMX {
+		domain := addr[strings.LastIndexByte(addr, '@')+1:]
+		verdict, checked := v.domains[domain]
+		if !checked {
+			lookupCtx, cancel := context.WithTimeout(ctx, domainCheckBudget)
+			verdict[0], verdict[1] = checkDomain(lookupCtx, v.resolver, domain)
+			cancel()
+			v.domains[domain] = verdict
+		}
+		if verdict[0] != StatusValid {
+			return addr, verdict[0], verdict[1]
+		}
+	}
+
+	if p := v.settings.Provider; p != nil && p.URL != "" && !v.providerDown {
+		status, reason, err := verifyWithProvider(ctx, p, addr)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				log.Printf("email verification: provider failed, continuing without it: %v", err)
+			}
+			v.providerDown = true
+			return addr, StatusUnknown, ReasonProvider
+		}
+		if reason == "" {
+			reason = ReasonProvider
+		}
+		if status == StatusValid {
+			reason = ""
+		}
+		return addr, status, reason
+	}
+	return addr, StatusValid, ""
+}
@@ -0,0 +1,158 @@
+package email_verification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+var providerHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// Normalize returns the address in the form it is stored in: trimmed,
+// without a mailto: prefix or angle brackets, and lower case. ok is false
+// when what is left is not a plain address.
+func Normalize(raw string) (string, bool) {
+	addr := strings.TrimSpace(raw)
+	if len(addr) >= len("mailto:") && strings.EqualFold(addr[:len("mailto:")], "mailto:") {
+		addr = addr[len("mailto:"):]
+	}
+	addr = strings.ToLower(strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(addr, "<"), ">")))
+	if addr == "" || len(addr) > 254 {
+		return addr, false
+	}
+	parsed, err := mail.ParseAddress(addr)
+	if err != nil || parsed.Name != "" || parsed.Address != addr {
+		return addr, false
+	}
+	at := strings.LastIndexByte(addr, '@')
+	if at < 1 || at > 64 || !validDomain(addr[at+1:]) {
+		return addr, false
+	}
+	return addr, true
+}
+
+// validDomain accepts host names with a top-level domain of two letters or more
+func validDomain(domain string) bool {
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 || len(domain) > 253 {
+		return false
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	tld := labels[len(labels)-1]
+	return len(tld) >= 2 && strings.Trim(tld, "abcdefghijklmnopqrstuvwxyz") == ""
+}
+
+// Canonical maps the aliases of a mailbox to one key: the +tag is dropped,
+// and so are the dots Gmail ignores. Normalized addresses only.
+func Canonical(addr string) string {
+	at := strings.LastIndexByte(addr, '@')
+	if at < 0 {
+		return addr
+	}
+	local, domain := addr[:at], addr[at+1:]
+	if plus := strings.IndexByte(local, '+'); plus > 0 {
+		local = local[:plus]
+	}
+	if domain == "googlemail.com" {
+		domain = "gmail.com"
+	}
+	if domain == "gmail.com" {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+	return local + "@" + domain
+}
+
+// resolver is the part of net.Resolver the MX check uses
+type resolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// checkDomain returns whether the domain accepts mail: it has MX records
+// other than the null MX, or failing those an address record to deliver to.
+// Lookups that fail without an answer are unknown.
+func checkDomain(ctx context.Context, r resolver, domain string) (string, string) {
+	mxs, err := r.LookupMX(ctx, domain)
+	if err == nil {
+		if len(mxs) == 1 && mxs[0].Host == "." {
+			return StatusInvalid, ReasonNoMX
+		}
+		if len(mxs) > 0 {
+			return StatusValid, ""
+		}
+	} else if !notFound(err) {
+		return StatusUnknown, ReasonDNS
+	}
+
+	if _, err := r.LookupHost(ctx, domain); err != nil {
+		if notFound(err) {
+			return StatusInvalid, ReasonNoMX
+		}
+		return StatusUnknown, ReasonDNS
+	}
+	return StatusValid, ""
+}
+
+func notFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+type providerResponse struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+// verifyWithProvider asks the tenant's provider about the address. Answers
+// outside the known verdicts count as unknown.
+func verifyWithProvider(ctx context.Context, p *Provider, addr string) (string, string, error) {
+	body, err := json.Marshal(map[string]string{"email": addr})
+	if err != nil {
+		return "", "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+
+	resp, err := providerHTTPClient.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+		return "", "", fmt.Errorf("verification provider returned %d", resp.StatusCode)
+	}
+
+	var out providerResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&out); err != nil {
+		return "", "", fmt.Errorf("verification provider: %w", err)
+	}
+	switch out.Status {
+	case StatusValid, StatusInvalid, StatusRisky:
+		return out.Status, out.Reason, nil
+	}
+	return StatusUnknown, out.Reason, nil
+}
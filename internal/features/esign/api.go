@@ -0,0 +1,40 @@
+package esign
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ESignApi struct {
+	controller *ESignController
+	config     *config.Config
+}
+
+func NewESignApi(controller *ESignController, config *config.Config) *ESignApi {
+	return &ESignApi{
+		controller: controller,
+		config:     config,
+	}
+}
+
+func (h *ESignApi) Setup(app *fiber.App) {
+	// Signer links and provider callbacks authenticate with their own tokens,
+	// so they are registered ahead of the authenticated groups
+	app.Post("/api/esign/callback", h.controller.Callback)
+	app.Get("/api/esign/:id/signers/:signer", h.controller.View)
+	app.Get("/api/esign/:id/signers/:signer/document", h.controller.Document)
+	app.Post("/api/esign/:id/signers/:signer/sign", h.controller.Sign)
+	app.Post("/api/esign/:id/signers/:signer/decline", h.controller.Decline)
+
+	requests := app.Group("/api/esign/requests", middleware.AuthMiddleware(h.config.SkipAuth))
+	requests.Get("/:id", h.controller.Get)
+	requests.Post("/:id/void", h.controller.Void)
+	requests.Post("/:id/remind", h.controller.Remind)
+
+	// Record read access is enforced by RecordService when the record is loaded
+	records := app.Group("/api/modules", middleware.AuthMiddleware(h.config.SkipAuth))
+	records.Post("/:module/records/:id/signature-requests", h.controller.Create)
+	records.Get("/:module/records/:id/signature-requests", h.controller.ListForRecord)
+}
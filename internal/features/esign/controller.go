@@ -0,0 +1,268 @@
+package esign
+
+import (
+	"errors"
+
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ESignController struct {
+	Service ESignService
+}
+
+func NewESignController(service ESignService) *ESignController {
+	return &ESignController{Service: service}
+}
+
+// signerError maps signer-facing failures to status codes
+func signerError(ctx *fiber.Ctx, err error) error {
+	status := fiber.StatusBadRequest
+	switch {
+	case errors.Is(err, ErrInvalidToken):
+		status = fiber.StatusForbidden
+	case errors.Is(err, ErrRequestClosed), errors.Is(err, ErrRequestExpired), errors.Is(err, ErrConcurrentUpdate):
+		status = fiber.StatusConflict
+	}
+	return ctx.Status(status).JSON(fiber.Map{"error": err.Error()})
+}
+
+// Create godoc
+// @Summary Send record document for signature
+// @Description Render the record with a print template, store it as an attachment and email a signing link to each signer
+// @Tags esign
+// @Accept json
+// @Produce json
+// @Param module path string true "Module Name"
+// @Param id path string true "Record ID"
+// @Param request body CreateRequest true "Signature request"
+// @Success 201 {object} SignatureRequest
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/modules/{module}/records/{id}/signature-requests [post]
+func (c *ESignController) Create(ctx *fiber.Ctx) error {
+	var input CreateRequest
+	if err := ctx.BodyParser(&input); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	req, err := c.Service.CreateRequest(ctx.UserContext(), ctx.Params("module"), ctx.Params("id"), input, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(req)
+}
+
+// ListForRecord godoc
+// @Summary List signature requests for a record
+// @Tags esign
+// @Produce json
+// @Param module path string true "Module Name"
+// @Param id path string true "Record ID"
+// @Success 200 {array} SignatureRequest
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/modules/{module}/records/{id}/signature-requests [get]
+func (c *ESignController) ListForRecord(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	requests, err := c.Service.ListForRecord(ctx.UserContext(), ctx.Params("module"), ctx.Params("id"), userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(requests)
+}
+
+// Get godoc
+// @Summary Get signature request
+// @Description Get a signature request with per-signer status and its event trail
+// @Tags esign
+// @Produce json
+// @Param id path string true "Signature Request ID"
+// @Success 200 {object} SignatureRequest
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/esign/requests/{id} [get]
+func (c *ESignController) Get(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	req, err := c.Service.GetRequest(ctx.UserContext(), ctx.Params("id"), userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(req)
+}
+
+// Void godoc
+// @Summary Void signature request
+// @Description Cancel an open signature request; its signing links stop working
+// @Tags esign
+// @Param id path string true "Signature Request ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/esign/requests/{id}/void [post]
+func (c *ESignController) Void(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if err := c.Service.VoidRequest(ctx.UserContext(), ctx.Params("id"), userID); err != nil {
+		return signerError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Signature request voided"})
+}
+
+// Remind godoc
+// @Summary Remind pending signers
+// @Description Re-send the signing link to signers who have not signed yet
+// @Tags esign
+// @Param id path string true "Signature Request ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/esign/requests/{id}/remind [post]
+func (c *ESignController) Remind(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if err := c.Service.RemindSigners(ctx.UserContext(), ctx.Params("id"), userID); err != nil {
+		return signerError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Reminders sent"})
+}
+
+// View godoc
+// @Summary Open signing link
+// @Description Public endpoint behind the emailed link; marks the signer as having viewed the document
+// @Tags esign
+// @Produce json
+// @Param id path string true "Signature Request ID"
+// @Param signer path string true "Signer ID"
+// @Param tenant query string true "Tenant ID"
+// @Param token query string true "Signing token"
+// @Success 200 {object} SignerView
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/esign/{id}/signers/{signer} [get]
+func (c *ESignController) View(ctx *fiber.Ctx) error {
+	view, err := c.Service.ViewAsSigner(ctx.UserContext(), ctx.Params("id"), ctx.Params("signer"),
+		ctx.Query("tenant"), ctx.Query("token"), ctx.IP(), ctx.Get(fiber.HeaderUserAgent))
+	if err != nil {
+		return signerError(ctx, err)
+	}
+
+	return ctx.JSON(view)
+}
+
+// Document godoc
+// @Summary Download document from signing link
+// @Description Redirects to the document (or the signed copy once completed)
+// @Tags esign
+// @Param id path string true "Signature Request ID"
+// @Param signer path string true "Signer ID"
+// @Param tenant query string true "Tenant ID"
+// @Param token query string true "Signing token"
+// @Success 302
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/esign/{id}/signers/{signer}/document [get]
+func (c *ESignController) Document(ctx *fiber.Ctx) error {
+	url, err := c.Service.SignerDocumentURL(ctx.UserContext(), ctx.Params("id"), ctx.Params("signer"),
+		ctx.Query("tenant"), ctx.Query("token"))
+	if err != nil {
+		return signerError(ctx, err)
+	}
+
+	return ctx.Redirect(url, fiber.StatusFound)
+}
+
+// Sign godoc
+// @Summary Sign document
+// @Description Record the signer's typed signature and consent. The last signature completes the request.
+// @Tags esign
+// @Accept json
+// @Produce json
+// @Param id path string true "Signature Request ID"
+// @Param signer path string true "Signer ID"
+// @Param tenant query string true "Tenant ID"
+// @Param token query string true "Signing token"
+// @Param input body SignInput true "Signature"
+// @Success 200 {object} SignerView
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/esign/{id}/signers/{signer}/sign [post]
+func (c *ESignController) Sign(ctx *fiber.Ctx) error {
+	var input SignInput
+	if err := ctx.BodyParser(&input); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	view, err := c.Service.Sign(ctx.UserContext(), ctx.Params("id"), ctx.Params("signer"),
+		ctx.Query("tenant"), ctx.Query("token"), input, ctx.IP(), ctx.Get(fiber.HeaderUserAgent))
+	if err != nil {
+		return signerError(ctx, err)
+	}
+
+	return ctx.JSON(view)
+}
+
+// Decline godoc
+// @Summary Decline to sign
+// @Tags esign
+// @Accept json
+// @Param id path string true "Signature Request ID"
+// @Param signer path string true "Signer ID"
+// @Param tenant query string true "Tenant ID"
+// @Param token query string true "Signing token"
+// @Param input body map[string]string false "Reason"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/esign/{id}/signers/{signer}/decline [post]
+func (c *ESignController) Decline(ctx *fiber.Ctx) error {
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&input); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	if err := c.Service.Decline(ctx.UserContext(), ctx.Params("id"), ctx.Params("signer"),
+		ctx.Query("tenant"), ctx.Query("token"), input.Reason, ctx.IP(), ctx.Get(fiber.HeaderUserAgent)); err != nil {
+		return signerError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Signature declined"})
+}
+
+// Callback godoc
+// @Summary Signing provider callback
+// @Description Accepts viewed/signed/declined events. The body must be signed with ESIGN_CALLBACK_SECRET (hex HMAC-SHA256 in X-Esign-Signature).
+// @Tags esign
+// @Accept json
+// @Param event body CallbackEvent true "Event"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/esign/callback [post]
+func (c *ESignController) Callback(ctx *fiber.Ctx) error {
+	if err := c.Service.HandleCallback(ctx.UserContext(), ctx.Body(), ctx.Get("X-Esign-Signature")); err != nil {
+		return signerError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Event accepted"})
+}
@@ -0,0 +1,130 @@
+package esign
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RequestStatus string
+
+const (
+	StatusSent            RequestStatus = "sent"
+	StatusViewed          RequestStatus = "viewed"
+	StatusPartiallySigned RequestStatus = "partially_signed"
+	StatusCompleted       RequestStatus = "completed"
+	StatusDeclined        RequestStatus = "declined"
+	StatusVoided          RequestStatus = "voided"
+	StatusExpired         RequestStatus = "expired"
+)
+
+type SignerStatus string
+
+const (
+	SignerPending  SignerStatus = "pending"
+	SignerViewed   SignerStatus = "viewed"
+	SignerSigned   SignerStatus = "signed"
+	SignerDeclined SignerStatus = "declined"
+)
+
+// Event types accepted from signing links and provider callbacks
+const (
+	EventViewed   = "viewed"
+	EventSigned   = "signed"
+	EventDeclined = "declined"
+)
+
+type Signer struct {
+	ID            primitive.ObjectID `json:"id" bson:"id"`
+	Name          string             `json:"name" bson:"name"`
+	Email         string             `json:"email" bson:"email"`
+	Status        SignerStatus       `json:"status" bson:"status"`
+	ViewedAt      *time.Time         `json:"viewed_at,omitempty" bson:"viewed_at,omitempty"`
+	SignedAt      *time.Time         `json:"signed_at,omitempty" bson:"signed_at,omitempty"`
+	DeclinedAt    *time.Time         `json:"declined_at,omitempty" bson:"declined_at,omitempty"`
+	DeclineReason string             `json:"decline_reason,omitempty" bson:"decline_reason,omitempty"`
+	SignatureName string             `json:"signature_name,omitempty" bson:"signature_name,omitempty"` // typed signature
+	IPAddress     string             `json:"ip_address,omitempty" bson:"ip_address,omitempty"`
+	UserAgent     string             `json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+}
+
+type SignatureEvent struct {
+	Type      string             `json:"type" bson:"type"`
+	SignerID  primitive.ObjectID `json:"signer_id,omitempty" bson:"signer_id,omitempty"`
+	Source    string             `json:"source" bson:"source"` // user, link, callback, system
+	Detail    string             `json:"detail,omitempty" bson:"detail,omitempty"`
+	IPAddress string             `json:"ip_address,omitempty" bson:"ip_address,omitempty"`
+	At        time.Time          `json:"at" bson:"at"`
+}
+
+// CompletionAction runs once every signer has signed
+type CompletionAction struct {
+	// FieldUpdates are applied to the source record, e.g. {"stage": "Closed Won"}
+	FieldUpdates map[string]interface{} `json:"field_updates,omitempty" bson:"field_updates,omitempty"`
+	// TriggerAutomation fires automation rules with trigger type "esign_completed"
+	TriggerAutomation bool `json:"trigger_automation" bson:"trigger_automation"`
+}
+
+type SignatureRequest struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID       primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	ModuleName     string             `json:"module_name" bson:"module_name"`
+	RecordID       string             `json:"record_id" bson:"record_id"`
+	TemplateID     string             `json:"template_id,omitempty" bson:"template_id,omitempty"`
+	Title          string             `json:"title" bson:"title"`
+	Message        string             `json:"message,omitempty" bson:"message,omitempty"`
+	Status         RequestStatus      `json:"status" bson:"status"`
+	Signers        []Signer           `json:"signers" bson:"signers"`
+	DocumentFileID primitive.ObjectID `json:"document_file_id" bson:"document_file_id"`
+	DocumentHash   string             `json:"document_hash" bson:"document_hash"` // SHA-256 of the unsigned PDF
+	SignedFileID   primitive.ObjectID `json:"signed_file_id,omitempty" bson:"signed_file_id,omitempty"`
+	OnComplete     CompletionAction   `json:"on_complete" bson:"on_complete"`
+	Events         []SignatureEvent   `json:"events" bson:"events"`
+	ExpiresAt      time.Time          `json:"expires_at" bson:"expires_at"`
+	CreatedBy      primitive.ObjectID `json:"created_by" bson:"created_by"`
+	CreatedAt      time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at" bson:"updated_at"`
+	CompletedAt    *time.Time         `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+}
+
+type SignerInput struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type CreateRequest struct {
+	TemplateID    string           `json:"template_id"`
+	Title         string           `json:"title"`
+	Message       string           `json:"message"`
+	Signers       []SignerInput    `json:"signers"`
+	ExpiresInDays int              `json:"expires_in_days"`
+	OnComplete    CompletionAction `json:"on_complete"`
+}
+
+// SignInput is submitted by a signer from the signing link
+type SignInput struct {
+	SignatureName string `json:"signature_name"`
+	Consent       bool   `json:"consent"`
+}
+
+// CallbackEvent is the payload accepted on /api/esign/callback from an external signing provider
+type CallbackEvent struct {
+	RequestID     string `json:"request_id"`
+	TenantID      string `json:"tenant_id"`
+	SignerID      string `json:"signer_id"`
+	Event         string `json:"event"` // viewed, signed, declined
+	SignatureName string `json:"signature_name,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+	IPAddress     string `json:"ip_address,omitempty"`
+}
+
+// SignerView is what a signer sees when opening their link
+type SignerView struct {
+	RequestID   string        `json:"request_id"`
+	Title       string        `json:"title"`
+	Message     string        `json:"message,omitempty"`
+	Status      RequestStatus `json:"status"`
+	Signer      Signer        `json:"signer"`
+	ExpiresAt   time.Time     `json:"expires_at"`
+	DocumentURL string        `json:"document_url"`
+}
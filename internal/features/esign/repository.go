@@ -0,0 +1,110 @@
+package esign
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type SignatureRepository interface {
+	Create(ctx context.Context, req *SignatureRequest) error
+	Get(ctx context.Context, id string) (*SignatureRequest, error)
+	Update(ctx context.Context, req *SignatureRequest) error
+	ListByRecord(ctx context.Context, moduleName, recordID string) ([]SignatureRequest, error)
+}
+
+type SignatureRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewSignatureRepository(db *database.MongodbDB) SignatureRepository {
+	return &SignatureRepositoryImpl{
+		collection: db.DB.Collection("signature_requests"),
+	}
+}
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+func (r *SignatureRepositoryImpl) Create(ctx context.Context, req *SignatureRequest) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if req.ID.IsZero() {
+		req.ID = primitive.NewObjectID()
+	}
+	req.TenantID = tenantID
+	// Millisecond precision matches what Mongo stores, keeping the Update guard exact
+	req.CreatedAt = time.Now().Truncate(time.Millisecond)
+	req.UpdatedAt = req.CreatedAt
+
+	_, err = r.collection.InsertOne(ctx, req)
+	return err
+}
+
+func (r *SignatureRepositoryImpl) Get(ctx context.Context, id string) (*SignatureRequest, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var req SignatureRequest
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID}).Decode(&req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// Update replaces the request only if it has not changed since it was read,
+// so concurrent signers cannot overwrite each other's status
+func (r *SignatureRepositoryImpl) Update(ctx context.Context, req *SignatureRequest) error {
+	previous := req.UpdatedAt
+	req.UpdatedAt = time.Now().Truncate(time.Millisecond)
+
+	res, err := r.collection.ReplaceOne(ctx, bson.M{"_id": req.ID, "tenant_id": req.TenantID, "updated_at": previous}, req)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrConcurrentUpdate
+	}
+	return nil
+}
+
+func (r *SignatureRepositoryImpl) ListByRecord(ctx context.Context, moduleName, recordID string) ([]SignatureRequest, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"tenant_id": tenantID, "module_name": moduleName, "record_id": recordID}
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	requests := []SignatureRequest{}
+	if err := cursor.All(ctx, &requests); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
@@ -0,0 +1,690 @@
+package esign
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/config"
+	"go-crm/internal/features/email"
+	"go-crm/internal/features/file"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/notification"
+	"go-crm/internal/features/print_template"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/webhook"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultExpiryDays = 14
+	maxExpiryDays     = 90
+	maxSigners        = 10
+	updateRetries     = 3
+
+	// AutomationTriggerCompleted is the automation trigger type fired on completion
+	AutomationTriggerCompleted = "esign_completed"
+)
+
+var (
+	ErrInvalidToken     = errors.New("invalid signing link")
+	ErrRequestClosed    = errors.New("signature request is no longer open")
+	ErrRequestExpired   = errors.New("signature request has expired")
+	ErrConcurrentUpdate = apperr.Conflict("signature request was modified concurrently")
+)
+
+type ESignService interface {
+	CreateRequest(ctx context.Context, moduleName, recordID string, req CreateRequest, userID primitive.ObjectID) (*SignatureRequest, error)
+	GetRequest(ctx context.Context, id string, userID primitive.ObjectID) (*SignatureRequest, error)
+	ListForRecord(ctx context.Context, moduleName, recordID string, userID primitive.ObjectID) ([]SignatureRequest, error)
+	VoidRequest(ctx context.Context, id string, userID primitive.ObjectID) error
+	RemindSigners(ctx context.Context, id string, userID primitive.ObjectID) error
+
+	// Signer-facing operations authenticate with the per-signer link token
+	ViewAsSigner(ctx context.Context, id, signerID, tenantID, token, ip, userAgent string) (*SignerView, error)
+	SignerDocumentURL(ctx context.Context, id, signerID, tenantID, token string) (string, error)
+	Sign(ctx context.Context, id, signerID, tenantID, token string, input SignInput, ip, userAgent string) (*SignerView, error)
+	Decline(ctx context.Context, id, signerID, tenantID, token, reason, ip, userAgent string) error
+
+	// HandleCallback applies a status event posted by an external signing provider
+	HandleCallback(ctx context.Context, body []byte, signature string) error
+}
+
+type ESignServiceImpl struct {
+	Repo                 SignatureRepository
+	ModuleRepo           module.ModuleRepository
+	RecordService        record.RecordService
+	PrintTemplateService print_template.PrintTemplateService
+	FileService          file.FileService
+	FileRepo             file.FileRepository
+	EmailService         email.EmailService
+	NotificationService  notification.NotificationService
+	WebhookService       webhook.WebhookService
+	AutomationService    record.AutomationTrigger
+	Config               *config.Config
+}
+
+func NewESignService(
+	repo SignatureRepository,
+	moduleRepo module.ModuleRepository,
+	recordService record.RecordService,
+	printTemplateService print_template.PrintTemplateService,
+	fileService file.FileService,
+	fileRepo file.FileRepository,
+	emailService email.EmailService,
+	notificationService notification.NotificationService,
+	webhookService webhook.WebhookService,
+	automationService record.AutomationTrigger,
+	cfg *config.Config,
+) ESignService {
+	return &ESignServiceImpl{
+		Repo:                 repo,
+		ModuleRepo:           moduleRepo,
+		RecordService:        recordService,
+		PrintTemplateService: printTemplateService,
+		FileService:          fileService,
+		FileRepo:             fileRepo,
+		EmailService:         emailService,
+		NotificationService:  notificationService,
+		WebhookService:       webhookService,
+		AutomationService:    automationService,
+		Config:               cfg,
+	}
+}
+
+func (s *ESignServiceImpl) CreateRequest(ctx context.Context, moduleName, recordID string, in CreateRequest, userID primitive.ObjectID) (*SignatureRequest, error) {
+	if len(in.Signers) == 0 {
+		return nil, errors.New("at least one signer is required")
+	}
+	if len(in.Signers) > maxSigners {
+		return nil, fmt.Errorf("at most %d signers are allowed", maxSigners)
+	}
+	signers := make([]Signer, 0, len(in.Signers))
+	for _, si := range in.Signers {
+		addr, err := mail.ParseAddress(si.Email)
+		if err != nil {
+			return nil, fmt.Errorf("invalid signer email: %s", si.Email)
+		}
+		name := strings.TrimSpace(si.Name)
+		if name == "" {
+			name = addr.Address
+		}
+		signers = append(signers, Signer{ID: primitive.NewObjectID(), Name: name, Email: addr.Address, Status: SignerPending})
+	}
+
+	days := in.ExpiresInDays
+	if days <= 0 {
+		days = defaultExpiryDays
+	}
+	if days > maxExpiryDays {
+		return nil, fmt.Errorf("expires_in_days cannot exceed %d", maxExpiryDays)
+	}
+
+	mod, err := s.ModuleRepo.FindByName(ctx, moduleName)
+	if err != nil || mod == nil {
+		return nil, errors.New("module not found")
+	}
+	for field := range in.OnComplete.FieldUpdates {
+		if !moduleHasField(mod, field) {
+			return nil, fmt.Errorf("on_complete: unknown field '%s'", field)
+		}
+	}
+
+	// Rendering goes through GetRecord, so the requester must be able to read the record
+	pdf, filename, err := s.PrintTemplateService.RenderRecordPDF(ctx, moduleName, recordID, in.TemplateID, userID)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(pdf)
+
+	title := strings.TrimSpace(in.Title)
+	if title == "" {
+		title = strings.TrimSuffix(filename, ".pdf")
+	}
+
+	doc := &file.File{
+		OriginalFilename: filename,
+		MimeType:         "application/pdf",
+		ModuleName:       moduleName,
+		RecordID:         recordID,
+		UploadedBy:       userID,
+		Description:      "Document sent for signature: " + title,
+	}
+	if err := s.FileService.StoreGenerated(ctx, pdf, doc); err != nil {
+		return nil, err
+	}
+
+	req := &SignatureRequest{
+		ModuleName:     moduleName,
+		RecordID:       recordID,
+		TemplateID:     in.TemplateID,
+		Title:          title,
+		Message:        in.Message,
+		Status:         StatusSent,
+		Signers:        signers,
+		DocumentFileID: doc.ID,
+		DocumentHash:   hex.EncodeToString(hash[:]),
+		OnComplete:     in.OnComplete,
+		ExpiresAt:      time.Now().AddDate(0, 0, days),
+		CreatedBy:      userID,
+		Events:         []SignatureEvent{{Type: "created", Source: "user", At: time.Now()}},
+	}
+	if err := s.Repo.Create(ctx, req); err != nil {
+		return nil, err
+	}
+
+	for i := range req.Signers {
+		req.Events = append(req.Events, s.sendInvitation(ctx, req, &req.Signers[i], false))
+	}
+	if err := s.Repo.Update(ctx, req); err != nil {
+		log.Printf("esign: failed to record invitation events for %s: %v", req.ID.Hex(), err)
+	}
+
+	s.publish(req, "esign.sent", nil)
+	return req, nil
+}
+
+func (s *ESignServiceImpl) GetRequest(ctx context.Context, id string, userID primitive.ObjectID) (*SignatureRequest, error) {
+	req, err := s.Repo.Get(ctx, id)
+	if err != nil {
+		return nil, errors.New("signature request not found")
+	}
+	// Visibility follows the source record
+	if _, err := s.RecordService.GetRecord(ctx, req.ModuleName, req.RecordID, userID); err != nil {
+		return nil, errors.New("signature request not found")
+	}
+	return req, nil
+}
+
+func (s *ESignServiceImpl) ListForRecord(ctx context.Context, moduleName, recordID string, userID primitive.ObjectID) ([]SignatureRequest, error) {
+	if _, err := s.RecordService.GetRecord(ctx, moduleName, recordID, userID); err != nil {
+		return nil, err
+	}
+	return s.Repo.ListByRecord(ctx, moduleName, recordID)
+}
+
+func (s *ESignServiceImpl) VoidRequest(ctx context.Context, id string, userID primitive.ObjectID) error {
+	req, err := s.GetRequest(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if isClosed(req.Status) {
+		return ErrRequestClosed
+	}
+	req.Status = StatusVoided
+	req.Events = append(req.Events, SignatureEvent{Type: "voided", Source: "user", At: time.Now()})
+	if err := s.Repo.Update(ctx, req); err != nil {
+		return err
+	}
+	s.publish(req, "esign.voided", nil)
+	return nil
+}
+
+func (s *ESignServiceImpl) RemindSigners(ctx context.Context, id string, userID primitive.ObjectID) error {
+	req, err := s.GetRequest(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if isClosed(req.Status) {
+		return ErrRequestClosed
+	}
+	for i := range req.Signers {
+		if req.Signers[i].Status == SignerPending || req.Signers[i].Status == SignerViewed {
+			req.Events = append(req.Events, s.sendInvitation(ctx, req, &req.Signers[i], true))
+		}
+	}
+	return s.Repo.Update(ctx, req)
+}
+
+func (s *ESignServiceImpl) ViewAsSigner(ctx context.Context, id, signerID, tenantID, token, ip, userAgent string) (*SignerView, error) {
+	ctx, err := s.verifyToken(ctx, id, signerID, tenantID, token)
+	if err != nil {
+		return nil, err
+	}
+	req, err := s.applyEvent(ctx, id, signerID, EventViewed, "link", eventMeta{ip: ip, userAgent: userAgent})
+	if err != nil && !errors.Is(err, ErrRequestClosed) {
+		return nil, err
+	}
+	if req == nil {
+		if req, err = s.Repo.Get(ctx, id); err != nil {
+			return nil, ErrInvalidToken
+		}
+	}
+	return s.signerView(req, signerID, tenantID, token)
+}
+
+func (s *ESignServiceImpl) SignerDocumentURL(ctx context.Context, id, signerID, tenantID, token string) (string, error) {
+	ctx, err := s.verifyToken(ctx, id, signerID, tenantID, token)
+	if err != nil {
+		return "", err
+	}
+	req, err := s.Repo.Get(ctx, id)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+
+	// Completed requests serve the signed copy with its certificate page
+	fileID := req.DocumentFileID
+	if req.Status == StatusCompleted && !req.SignedFileID.IsZero() {
+		fileID = req.SignedFileID
+	}
+	doc, err := s.FileRepo.Get(ctx, fileID.Hex())
+	if err != nil {
+		return "", errors.New("document not found")
+	}
+	return s.FileService.DownloadURL(ctx, doc)
+}
+
+func (s *ESignServiceImpl) Sign(ctx context.Context, id, signerID, tenantID, token string, input SignInput, ip, userAgent string) (*SignerView, error) {
+	if !input.Consent {
+		return nil, errors.New("consent to sign electronically is required")
+	}
+	if strings.TrimSpace(input.SignatureName) == "" {
+		return nil, errors.New("signature_name is required")
+	}
+
+	ctx, err := s.verifyToken(ctx, id, signerID, tenantID, token)
+	if err != nil {
+		return nil, err
+	}
+	req, err := s.applyEvent(ctx, id, signerID, EventSigned, "link", eventMeta{ip: ip, userAgent: userAgent, signatureName: input.SignatureName})
+	if err != nil {
+		return nil, err
+	}
+	return s.signerView(req, signerID, tenantID, token)
+}
+
+func (s *ESignServiceImpl) Decline(ctx context.Context, id, signerID, tenantID, token, reason, ip, userAgent string) error {
+	ctx, err := s.verifyToken(ctx, id, signerID, tenantID, token)
+	if err != nil {
+		return err
+	}
+	_, err = s.applyEvent(ctx, id, signerID, EventDeclined, "link", eventMeta{ip: ip, userAgent: userAgent, reason: reason})
+	return err
+}
+
+func (s *ESignServiceImpl) HandleCallback(ctx context.Context, body []byte, signature string) error {
+	if s.Config.ESignCallbackSecret == "" {
+		return errors.New("callbacks are not enabled")
+	}
+	mac := hmac.New(sha256.New, []byte(s.Config.ESignCallbackSecret))
+	mac.Write(body)
+	if !hmac.Equal([]byte(hex.EncodeToString(mac.Sum(nil))), []byte(strings.ToLower(signature))) {
+		return ErrInvalidToken
+	}
+
+	var evt CallbackEvent
+	if err := json.Unmarshal(body, &evt); err != nil {
+		return fmt.Errorf("invalid callback payload: %w", err)
+	}
+	switch evt.Event {
+	case EventViewed, EventSigned, EventDeclined:
+	default:
+		return fmt.Errorf("unsupported event: %s", evt.Event)
+	}
+	if _, err := primitive.ObjectIDFromHex(evt.TenantID); err != nil {
+		return errors.New("invalid tenant_id")
+	}
+
+	ctx = context.WithValue(ctx, common_models.TenantIDKey, evt.TenantID)
+	_, err := s.applyEvent(ctx, evt.RequestID, evt.SignerID, evt.Event, "callback", eventMeta{
+		ip:            evt.IPAddress,
+		signatureName: evt.SignatureName,
+		reason:        evt.Reason,
+	})
+	return err
+}
+
+type eventMeta struct {
+	ip            string
+	userAgent     string
+	signatureName string
+	reason        string
+}
+
+// applyEvent is the single state transition path for links and callbacks.
+// Updates are guarded by updated_at, so the request is re-read and the event
+// reapplied when another signer wrote in between.
+func (s *ESignServiceImpl) applyEvent(ctx context.Context, id, signerID, eventType, source string, meta eventMeta) (*SignatureRequest, error) {
+	for attempt := 0; attempt < updateRetries; attempt++ {
+		req, err := s.Repo.Get(ctx, id)
+		if err != nil {
+			return nil, errors.New("signature request not found")
+		}
+		idx := signerIndex(req, signerID)
+		if idx < 0 {
+			return nil, errors.New("signer not found")
+		}
+
+		if isClosed(req.Status) {
+			return req, ErrRequestClosed
+		}
+		now := time.Now()
+		if now.After(req.ExpiresAt) {
+			req.Status = StatusExpired
+			req.Events = append(req.Events, SignatureEvent{Type: "expired", Source: "system", At: now})
+			_ = s.Repo.Update(ctx, req)
+			return req, ErrRequestExpired
+		}
+
+		signer := &req.Signers[idx]
+		changed := true
+		switch eventType {
+		case EventViewed:
+			if signer.Status != SignerPending {
+				changed = signer.ViewedAt == nil
+			}
+			if signer.ViewedAt == nil {
+				signer.ViewedAt = &now
+			}
+			if signer.Status == SignerPending {
+				signer.Status = SignerViewed
+			}
+			if req.Status == StatusSent {
+				req.Status = StatusViewed
+			}
+		case EventSigned:
+			if signer.Status == SignerSigned {
+				return req, nil
+			}
+			if signer.Status == SignerDeclined {
+				return req, ErrRequestClosed
+			}
+			if signer.ViewedAt == nil {
+				signer.ViewedAt = &now
+			}
+			signer.Status = SignerSigned
+			signer.SignedAt = &now
+			signer.SignatureName = strings.TrimSpace(meta.signatureName)
+			if signer.SignatureName == "" {
+				signer.SignatureName = signer.Name
+			}
+			signer.IPAddress = meta.ip
+			signer.UserAgent = meta.userAgent
+			req.Status = StatusPartiallySigned
+		case EventDeclined:
+			signer.Status = SignerDeclined
+			signer.DeclinedAt = &now
+			signer.DeclineReason = meta.reason
+			signer.IPAddress = meta.ip
+			signer.UserAgent = meta.userAgent
+			req.Status = StatusDeclined
+		default:
+			return nil, fmt.Errorf("unsupported event: %s", eventType)
+		}
+		if !changed {
+			return req, nil
+		}
+
+		req.Events = append(req.Events, SignatureEvent{
+			Type:      eventType,
+			SignerID:  signer.ID,
+			Source:    source,
+			Detail:    meta.reason,
+			IPAddress: meta.ip,
+			At:        now,
+		})
+
+		completed := eventType == EventSigned && allSigned(req)
+		if completed {
+			if err := s.attachSignedDocument(ctx, req); err != nil {
+				log.Printf("esign: failed to finalize %s: %v", req.ID.Hex(), err)
+				req.Events = append(req.Events, SignatureEvent{Type: "finalize_failed", Source: "system", Detail: err.Error(), At: now})
+			}
+			req.Status = StatusCompleted
+			req.CompletedAt = &now
+		}
+
+		if err := s.Repo.Update(ctx, req); err != nil {
+			if errors.Is(err, ErrConcurrentUpdate) {
+				if completed && !req.SignedFileID.IsZero() {
+					// Another writer won; drop the signed copy produced for this attempt
+					_ = s.FileService.DeleteFile(ctx, req.SignedFileID.Hex(), req.CreatedBy)
+				}
+				continue
+			}
+			return nil, err
+		}
+
+		s.publish(req, "esign."+eventType, signer)
+		switch {
+		case completed:
+			s.onCompleted(ctx, req)
+		case eventType == EventDeclined:
+			_ = s.NotificationService.CreateNotification(ctx, req.CreatedBy, "Signature declined",
+				fmt.Sprintf("%s declined to sign \"%s\"", signer.Name, req.Title), notification.NotificationTypeWarning,
+				fmt.Sprintf("/dashboard/modules/%s/%s", req.ModuleName, req.RecordID))
+		}
+		return req, nil
+	}
+	return nil, ErrConcurrentUpdate
+}
+
+// attachSignedDocument stores the original PDF plus a signature certificate
+// page as a new attachment on the source record
+func (s *ESignServiceImpl) attachSignedDocument(ctx context.Context, req *SignatureRequest) error {
+	original, err := s.FileRepo.Get(ctx, req.DocumentFileID.Hex())
+	if err != nil {
+		return fmt.Errorf("original document not found: %w", err)
+	}
+	rc, err := s.FileService.OpenFile(ctx, original)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.Sum256(data)
+	if hex.EncodeToString(hash[:]) != req.DocumentHash {
+		return errors.New("original document hash mismatch")
+	}
+
+	now := time.Now().UTC()
+	sections := []print_template.SummarySection{{
+		Heading: "Document",
+		Pairs: [][2]string{
+			{"Title", req.Title},
+			{"Request ID", req.ID.Hex()},
+			{"Original SHA-256", req.DocumentHash},
+			{"Completed", now.Format(time.RFC3339)},
+		},
+	}}
+	for _, signer := range req.Signers {
+		pairs := [][2]string{
+			{"Email", signer.Email},
+			{"Signed as", signer.SignatureName},
+		}
+		if signer.ViewedAt != nil {
+			pairs = append(pairs, [2]string{"Viewed", signer.ViewedAt.UTC().Format(time.RFC3339)})
+		}
+		if signer.SignedAt != nil {
+			pairs = append(pairs, [2]string{"Signed", signer.SignedAt.UTC().Format(time.RFC3339)})
+		}
+		if signer.IPAddress != "" {
+			pairs = append(pairs, [2]string{"IP address", signer.IPAddress})
+		}
+		if signer.UserAgent != "" {
+			pairs = append(pairs, [2]string{"User agent", signer.UserAgent})
+		}
+		sections = append(sections, print_template.SummarySection{Heading: "Signer: " + signer.Name, Pairs: pairs})
+	}
+
+	signed, err := print_template.AppendSummaryPage(data, "Signature Certificate", sections)
+	if err != nil {
+		return err
+	}
+
+	signedFile := &file.File{
+		OriginalFilename: strings.TrimSuffix(original.OriginalFilename, ".pdf") + "_signed.pdf",
+		MimeType:         "application/pdf",
+		ModuleName:       req.ModuleName,
+		RecordID:         req.RecordID,
+		UploadedBy:       req.CreatedBy,
+		Description:      "Signed document: " + req.Title,
+	}
+	if err := s.FileService.StoreGenerated(ctx, signed, signedFile); err != nil {
+		return err
+	}
+	req.SignedFileID = signedFile.ID
+	return nil
+}
+
+// onCompleted applies the request's completion actions as the requester
+func (s *ESignServiceImpl) onCompleted(ctx context.Context, req *SignatureRequest) {
+	if len(req.OnComplete.FieldUpdates) > 0 {
+		if err := s.RecordService.UpdateRecord(ctx, req.ModuleName, req.RecordID, req.OnComplete.FieldUpdates, req.CreatedBy); err != nil {
+			log.Printf("esign: failed to apply completion updates for %s: %v", req.ID.Hex(), err)
+		}
+	}
+
+	if req.OnComplete.TriggerAutomation && s.AutomationService != nil {
+		rec, err := s.RecordService.GetRecord(ctx, req.ModuleName, req.RecordID, req.CreatedBy)
+		if err == nil {
+			rec["esign_request_id"] = req.ID.Hex()
+			if err := s.AutomationService.ExecuteFromTrigger(ctx, req.ModuleName, rec, AutomationTriggerCompleted); err != nil {
+				log.Printf("esign: completion automation failed for %s: %v", req.ID.Hex(), err)
+			}
+		}
+	}
+
+	_ = s.NotificationService.CreateNotification(ctx, req.CreatedBy, "Document signed",
+		fmt.Sprintf("All signers have signed \"%s\"", req.Title), notification.NotificationTypeSuccess,
+		fmt.Sprintf("/dashboard/modules/%s/%s", req.ModuleName, req.RecordID))
+}
+
+func (s *ESignServiceImpl) sendInvitation(ctx context.Context, req *SignatureRequest, signer *Signer, reminder bool) SignatureEvent {
+	link := s.signingLink(req, signer)
+	subject := "Signature requested: " + req.Title
+	if reminder {
+		subject = "Reminder: " + subject
+	}
+	body := fmt.Sprintf("Hello %s,\n\nYou have been asked to sign \"%s\".\n\n", signer.Name, req.Title)
+	if req.Message != "" {
+		body += req.Message + "\n\n"
+	}
+	body += fmt.Sprintf("Review and sign: %s\n\nThis link expires on %s.", link, req.ExpiresAt.Format("2006-01-02"))
+
+	evt := SignatureEvent{Type: "sent", SignerID: signer.ID, Source: "system", At: time.Now()}
+	if reminder {
+		evt.Type = "reminded"
+	}
+	if err := s.EmailService.SendEmail(ctx, []string{signer.Email}, subject, body); err != nil {
+		evt.Type = "email_failed"
+		evt.Detail = err.Error()
+	}
+	return evt
+}
+
+func (s *ESignServiceImpl) signingLink(req *SignatureRequest, signer *Signer) string {
+	q := url.Values{}
+	q.Set("tenant", req.TenantID.Hex())
+	q.Set("token", s.token(req.ID.Hex(), signer.ID.Hex(), req.TenantID.Hex()))
+	return fmt.Sprintf("%s/api/esign/%s/signers/%s?%s", strings.TrimRight(s.Config.PublicURL, "/"), req.ID.Hex(), signer.ID.Hex(), q.Encode())
+}
+
+// token is the per-signer credential embedded in signing links. It does not
+// expire by itself; the request's ExpiresAt bounds its use.
+func (s *ESignServiceImpl) token(requestID, signerID, tenantID string) string {
+	mac := hmac.New(sha256.New, []byte(s.Config.JWTSecret))
+	fmt.Fprintf(mac, "esign:%s:%s:%s", requestID, signerID, tenantID)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *ESignServiceImpl) verifyToken(ctx context.Context, id, signerID, tenantID, token string) (context.Context, error) {
+	if _, err := primitive.ObjectIDFromHex(tenantID); err != nil {
+		return ctx, ErrInvalidToken
+	}
+	if !hmac.Equal([]byte(s.token(id, signerID, tenantID)), []byte(token)) {
+		return ctx, ErrInvalidToken
+	}
+	return context.WithValue(ctx, common_models.TenantIDKey, tenantID), nil
+}
+
+func (s *ESignServiceImpl) signerView(req *SignatureRequest, signerID, tenantID, token string) (*SignerView, error) {
+	idx := signerIndex(req, signerID)
+	if idx < 0 {
+		return nil, ErrInvalidToken
+	}
+	signer := req.Signers[idx]
+	// Other signers' network details are not exposed to each other
+	signer.IPAddress, signer.UserAgent = "", ""
+
+	q := url.Values{}
+	q.Set("tenant", tenantID)
+	q.Set("token", token)
+	return &SignerView{
+		RequestID:   req.ID.Hex(),
+		Title:       req.Title,
+		Message:     req.Message,
+		Status:      req.Status,
+		Signer:      signer,
+		ExpiresAt:   req.ExpiresAt,
+		DocumentURL: fmt.Sprintf("/api/esign/%s/signers/%s/document?%s", req.ID.Hex(), signerID, q.Encode()),
+	}, nil
+}
+
+func (s *ESignServiceImpl) publish(req *SignatureRequest, event string, signer *Signer) {
+	extra := map[string]any{"signature_request_id": req.ID.Hex(), "status": req.Status}
+	if signer != nil {
+		extra["signer_email"] = signer.Email
+	}
+	s.WebhookService.Trigger(context.Background(), event, common_models.WebhookPayload{
+		Event:     event,
+		Module:    req.ModuleName,
+		RecordID:  req.RecordID,
+		Data:      req,
+		Timestamp: time.Now(),
+		Extra:     extra,
+	})
+}
+
+func signerIndex(req *SignatureRequest, signerID string) int {
+	for i, sg := range req.Signers {
+		if sg.ID.Hex() == signerID {
+			return i
+		}
+	}
+	return -1
+}
+
+func allSigned(req *SignatureRequest) bool {
+	for _, sg := range req.Signers {
+		if sg.Status != SignerSigned {
+			return false
+		}
+	}
+	return true
+}
+
+func isClosed(status RequestStatus) bool {
+	switch status {
+	case StatusCompleted, StatusDeclined, StatusVoided, StatusExpired:
+		return true
+	}
+	return false
+}
+
+func moduleHasField(mod *common_models.Entity, name string) bool {
+	for _, f := range mod.Fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package exchange
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ExchangeApi struct {
+	controller  *ExchangeController
+	config      *config.Config
+	roleService middleware.RoleService
+}
+
+func NewExchangeApi(controller *ExchangeController, config *config.Config, roleService middleware.RoleService) *ExchangeApi {
+	return &ExchangeApi{
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *ExchangeApi) Setup(app *fiber.App) {
+	group := app.Group("/api/exchanges", middleware.AuthMiddleware(h.config.SkipAuth))
+
+	group.Get("/", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListExchanges)
+	group.Post("/", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.CreateExchange)
+	group.Get("/:id", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.GetExchange)
+	group.Put("/:id", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.UpdateExchange)
+	group.Delete("/:id", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.DeleteExchange)
+	group.Post("/:id/test", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.TestConnection)
+	group.Post("/:id/run", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.RunExchange)
+	group.Get("/:id/runs", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListRuns)
+}
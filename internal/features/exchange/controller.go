@@ -0,0 +1,162 @@
+package exchange
+
+import (
+	"errors"
+
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ExchangeController struct {
+	Service ExchangeService
+}
+
+func NewExchangeController(service ExchangeService) *ExchangeController {
+	return &ExchangeController{Service: service}
+}
+
+// CreateExchange godoc
+// @Summary Create SFTP exchange
+// @Description Configure a scheduled CSV export to, or import from, an SFTP directory. Schedule it with a cron job using the sftp_exchange action.
+// @Tags exchanges
+// @Accept json
+// @Produce json
+// @Param exchange body Exchange true "Exchange"
+// @Success 201 {object} Exchange
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/exchanges [post]
+func (c *ExchangeController) CreateExchange(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var ex Exchange
+	if err := ctx.BodyParser(&ex); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := c.Service.CreateExchange(ctx.UserContext(), &ex, userID); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{"data": ex})
+}
+
+// ListExchanges godoc
+// @Summary List SFTP exchanges
+// @Tags exchanges
+// @Produce json
+// @Success 200 {array} Exchange
+// @Router /api/exchanges [get]
+func (c *ExchangeController) ListExchanges(ctx *fiber.Ctx) error {
+	exchanges, err := c.Service.ListExchanges(ctx.UserContext())
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": exchanges})
+}
+
+// GetExchange godoc
+// @Summary Get SFTP exchange
+// @Tags exchanges
+// @Produce json
+// @Param id path string true "Exchange ID"
+// @Success 200 {object} Exchange
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/exchanges/{id} [get]
+func (c *ExchangeController) GetExchange(ctx *fiber.Ctx) error {
+	ex, err := c.Service.GetExchange(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Exchange not found"})
+	}
+	return ctx.JSON(fiber.Map{"data": ex})
+}
+
+// UpdateExchange godoc
+// @Summary Update SFTP exchange
+// @Description The direction cannot be changed. Omit password and private_key to keep the stored credentials.
+// @Tags exchanges
+// @Accept json
+// @Produce json
+// @Param id path string true "Exchange ID"
+// @Param exchange body Exchange true "Exchange"
+// @Success 200 {object} Exchange
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/exchanges/{id} [put]
+func (c *ExchangeController) UpdateExchange(ctx *fiber.Ctx) error {
+	var in Exchange
+	if err := ctx.BodyParser(&in); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	ex, err := c.Service.UpdateExchange(ctx.UserContext(), ctx.Params("id"), &in)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": ex})
+}
+
+// DeleteExchange godoc
+// @Summary Delete SFTP exchange
+// @Description Deletes the exchange and its run logs; cron jobs using it start failing
+// @Tags exchanges
+// @Param id path string true "Exchange ID"
+// @Success 204
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/exchanges/{id} [delete]
+func (c *ExchangeController) DeleteExchange(ctx *fiber.Ctx) error {
+	if err := c.Service.DeleteExchange(ctx.UserContext(), ctx.Params("id")); err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// TestConnection godoc
+// @Summary Test SFTP exchange connection
+// @Description Log in with the stored credentials and check the remote path
+// @Tags exchanges
+// @Produce json
+// @Param id path string true "Exchange ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/exchanges/{id}/test [post]
+func (c *ExchangeController) TestConnection(ctx *fiber.Ctx) error {
+	if err := c.Service.TestConnection(ctx.UserContext(), ctx.Params("id")); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"message": "Connection successful"})
+}
+
+// RunExchange godoc
+// @Summary Run SFTP exchange
+// @Description Run the export or import now and return its log
+// @Tags exchanges
+// @Produce json
+// @Param id path string true "Exchange ID"
+// @Success 200 {object} Run
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/exchanges/{id}/run [post]
+func (c *ExchangeController) RunExchange(ctx *fiber.Ctx) error {
+	run, err := c.Service.RunExchange(ctx.UserContext(), ctx.Params("id"))
+	if errors.Is(err, ErrExchangeRunning) {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	if run == nil && err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": run})
+}
+
+// ListRuns godoc
+// @Summary List SFTP exchange runs
+// @Tags exchanges
+// @Produce json
+// @Param id path string true "Exchange ID"
+// @Param limit query int false "Limit"
+// @Success 200 {array} Run
+// @Router /api/exchanges/{id}/runs [get]
+func (c *ExchangeController) ListRuns(ctx *fiber.Ctx) error {
+	runs, err := c.Service.ListRuns(ctx.UserContext(), ctx.Params("id"), int64(ctx.QueryInt("limit", 20)))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": runs})
+}
@@ -0,0 +1,94 @@
+package exchange
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Direction string
+
+const (
+	// DirectionExport writes the module's matching records as a CSV to the remote path
+	DirectionExport Direction = "export"
+	// DirectionImport ingests CSVs dropped in the remote path through the import pipeline
+	DirectionImport Direction = "import"
+)
+
+// Run statuses
+const (
+	RunInProgress = "in_progress"
+	RunSuccess    = "success"
+	RunPartial    = "partial"
+	RunFailed     = "failed"
+)
+
+// Exchange is a scheduled CSV transfer with one SFTP server. Schedule it
+// with a cron job using the sftp_exchange action.
+type Exchange struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID   primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	Name       string             `json:"name" bson:"name"`
+	Direction  Direction          `json:"direction" bson:"direction"`
+	ModuleName string             `json:"module_name" bson:"module_name"`
+
+	Host     string `json:"host" bson:"host"`
+	Port     int    `json:"port" bson:"port"`
+	Username string `json:"username" bson:"username"`
+	// Password and PrivateKey are write-only; cleared before responses
+	Password   string `json:"password,omitempty" bson:"password,omitempty"`
+	PrivateKey string `json:"private_key,omitempty" bson:"private_key,omitempty"`
+	// HostKeyFingerprint pins the server key, e.g. "SHA256:..." as printed by ssh-keygen -l
+	HostKeyFingerprint string `json:"host_key_fingerprint" bson:"host_key_fingerprint"`
+
+	// RemotePath is the directory files are written to (export) or picked up from (import)
+	RemotePath string `json:"remote_path" bson:"remote_path"`
+
+	// Export: records matching the saved filter, or all records when empty
+	SavedFilterID string   `json:"saved_filter_id,omitempty" bson:"saved_filter_id,omitempty"`
+	Columns       []string `json:"columns,omitempty" bson:"columns,omitempty"` // Defaults to every module field
+	// FileName is a Go time layout for the uploaded file, e.g. "leads_20060102.csv"
+	FileName string `json:"file_name,omitempty" bson:"file_name,omitempty"`
+
+	// Import: FilePattern selects files to ingest (path.Match syntax, default "*.csv")
+	FilePattern string `json:"file_pattern,omitempty" bson:"file_pattern,omitempty"`
+	// ColumnMapping maps CSV headers to module fields; empty maps each header to the field of the same name
+	ColumnMapping map[string]string `json:"column_mapping,omitempty" bson:"column_mapping,omitempty"`
+	// ArchivePath receives imported files; when empty they are deleted
+	ArchivePath string `json:"archive_path,omitempty" bson:"archive_path,omitempty"`
+
+	// AlertEmails are notified when a run fails, in addition to the creator
+	AlertEmails []string `json:"alert_emails,omitempty" bson:"alert_emails,omitempty"`
+
+	IsActive   bool               `json:"is_active" bson:"is_active"`
+	LastRunAt  time.Time          `json:"last_run_at" bson:"last_run_at"`
+	LastStatus string             `json:"last_status,omitempty" bson:"last_status,omitempty"`
+	CreatedBy  primitive.ObjectID `json:"created_by" bson:"created_by"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// Run is the log of one exchange execution
+type Run struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID   primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	ExchangeID primitive.ObjectID `json:"exchange_id" bson:"exchange_id"`
+	Direction  Direction          `json:"direction" bson:"direction"`
+	Status     string             `json:"status" bson:"status"`
+	Files      []RunFile          `json:"files" bson:"files"`
+	Records    int                `json:"records" bson:"records"`
+	Failed     int                `json:"failed" bson:"failed"`
+	Error      string             `json:"error,omitempty" bson:"error,omitempty"`
+	StartedAt  time.Time          `json:"started_at" bson:"started_at"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+}
+
+// RunFile is one file written or ingested during a run
+type RunFile struct {
+	Name        string             `json:"name" bson:"name"`
+	Size        int64              `json:"size" bson:"size"`
+	Records     int                `json:"records" bson:"records"`
+	Failed      int                `json:"failed" bson:"failed"`
+	ImportJobID primitive.ObjectID `json:"import_job_id,omitempty" bson:"import_job_id,omitempty"`
+	Error       string             `json:"error,omitempty" bson:"error,omitempty"`
+}
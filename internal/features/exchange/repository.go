@@ -0,0 +1,198 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+type ExchangeRepository interface {
+	Create(ctx context.Context, ex *Exchange) error
+	Get(ctx context.Context, id string) (*Exchange, error)
+	List(ctx context.Context) ([]Exchange, error)
+	Update(ctx context.Context, ex *Exchange) error
+	Delete(ctx context.Context, id string) error
+
+	// GetForRun loads an exchange regardless of tenant. Used by scheduled
+	// jobs, which run without a tenant context.
+	GetForRun(ctx context.Context, id string) (*Exchange, error)
+}
+
+type ExchangeRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewExchangeRepository(db *database.MongodbDB) ExchangeRepository {
+	return &ExchangeRepositoryImpl{
+		collection: db.DB.Collection("sftp_exchanges"),
+	}
+}
+
+func (r *ExchangeRepositoryImpl) Create(ctx context.Context, ex *Exchange) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	ex.ID = primitive.NewObjectID()
+	ex.TenantID = tenantID
+	ex.CreatedAt = time.Now()
+	ex.UpdatedAt = ex.CreatedAt
+
+	_, err = r.collection.InsertOne(ctx, ex)
+	return err
+}
+
+func (r *ExchangeRepositoryImpl) Get(ctx context.Context, id string) (*Exchange, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var ex Exchange
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID}).Decode(&ex); err != nil {
+		return nil, err
+	}
+	return &ex, nil
+}
+
+func (r *ExchangeRepositoryImpl) GetForRun(ctx context.Context, id string) (*Exchange, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var ex Exchange
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&ex); err != nil {
+		return nil, err
+	}
+	return &ex, nil
+}
+
+func (r *ExchangeRepositoryImpl) List(ctx context.Context) ([]Exchange, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"tenant_id": tenantID}, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	exchanges := []Exchange{}
+	if err := cursor.All(ctx, &exchanges); err != nil {
+		return nil, err
+	}
+	return exchanges, nil
+}
+
+func (r *ExchangeRepositoryImpl) Update(ctx context.Context, ex *Exchange) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	ex.UpdatedAt = time.Now()
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": ex.ID, "tenant_id": tenantID}, ex)
+	return err
+}
+
+func (r *ExchangeRepositoryImpl) Delete(ctx context.Context, id string) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID})
+	return err
+}
+
+type RunRepository interface {
+	Create(ctx context.Context, run *Run) error
+	Update(ctx context.Context, run *Run) error
+	ListByExchange(ctx context.Context, exchangeID primitive.ObjectID, limit int64) ([]Run, error)
+	DeleteByExchange(ctx context.Context, exchangeID primitive.ObjectID) error
+}
+
+type RunRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewRunRepository(db *database.MongodbDB) RunRepository {
+	return &RunRepositoryImpl{
+		collection: db.DB.Collection("sftp_exchange_runs"),
+	}
+}
+
+func (r *RunRepositoryImpl) Create(ctx context.Context, run *Run) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	run.ID = primitive.NewObjectID()
+	run.TenantID = tenantID
+
+	_, err = r.collection.InsertOne(ctx, run)
+	return err
+}
+
+func (r *RunRepositoryImpl) Update(ctx context.Context, run *Run) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": run.ID, "tenant_id": tenantID}, run)
+	return err
+}
+
+func (r *RunRepositoryImpl) ListByExchange(ctx context.Context, exchangeID primitive.ObjectID, limit int64) ([]Run, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.M{"started_at": -1}).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, bson.M{"tenant_id": tenantID, "exchange_id": exchangeID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	runs := []Run{}
+	if err := cursor.All(ctx, &runs); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+func (r *RunRepositoryImpl) DeleteByExchange(ctx context.Context, exchangeID primitive.ObjectID) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.DeleteMany(ctx, bson.M{"tenant_id": tenantID, "exchange_id": exchangeID})
+	return err
+}
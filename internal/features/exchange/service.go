@@ -0,0 +1,404 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/config"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/email"
+	import_feature "go-crm/internal/features/import"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/notification"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/saved_filter"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const maxLoggedFiles = 200
+
+var ErrExchangeRunning = errors.New("this exchange is already running")
+
+type ExchangeService interface {
+	CreateExchange(ctx context.Context, ex *Exchange, userID primitive.ObjectID) error
+	GetExchange(ctx context.Context, id string) (*Exchange, error)
+	ListExchanges(ctx context.Context) ([]Exchange, error)
+	UpdateExchange(ctx context.Context, id string, ex *Exchange) (*Exchange, error)
+	DeleteExchange(ctx context.Context, id string) error
+	// TestConnection logs in and checks that the remote path is a directory
+	TestConnection(ctx context.Context, id string) error
+
+	RunExchange(ctx context.Context, id string) (*Run, error)
+	// RunScheduled runs an exchange from a cron job, which carries no tenant;
+	// the tenant is taken from the exchange itself
+	RunScheduled(ctx context.Context, id string) error
+	ListRuns(ctx context.Context, id string, limit int64) ([]Run, error)
+}
+
+type ExchangeServiceImpl struct {
+	ExchangeRepo        ExchangeRepository
+	RunRepo             RunRepository
+	RecordRepo          record.RecordRepository
+	ModuleRepo          module.ModuleRepository
+	SavedFilterService  saved_filter.SavedFilterService
+	ImportService       import_feature.ImportService
+	NotificationService notification.NotificationService
+	EmailService        email.EmailService
+	AuditService        audit.AuditService
+	Config              *config.Config
+
+	running sync.Map // exchange ID -> struct{}
+}
+
+func NewExchangeService(
+	exchangeRepo ExchangeRepository,
+	runRepo RunRepository,
+	recordRepo record.RecordRepository,
+	moduleRepo module.ModuleRepository,
+	savedFilterService saved_filter.SavedFilterService,
+	importService import_feature.ImportService,
+	notificationService notification.NotificationService,
+	emailService email.EmailService,
+	auditService audit.AuditService,
+	cfg *config.Config,
+) ExchangeService {
+	return &ExchangeServiceImpl{
+		ExchangeRepo:        exchangeRepo,
+		RunRepo:             runRepo,
+		RecordRepo:          recordRepo,
+		ModuleRepo:          moduleRepo,
+		SavedFilterService:  savedFilterService,
+		ImportService:       importService,
+		NotificationService: notificationService,
+		EmailService:        emailService,
+		AuditService:        auditService,
+		Config:              cfg,
+	}
+}
+
+// redact clears the credentials before an exchange leaves the service
+func redact(ex *Exchange) {
+	ex.Password = ""
+	ex.PrivateKey = ""
+}
+
+func (s *ExchangeServiceImpl) validate(ctx context.Context, ex *Exchange) error {
+	if ex.Name == "" || ex.ModuleName == "" || ex.Host == "" || ex.Username == "" || ex.RemotePath == "" {
+		return errors.New("name, module_name, host, username and remote_path are required")
+	}
+	if ex.Port < 0 || ex.Port > 65535 {
+		return errors.New("invalid port")
+	}
+	if ex.Password == "" && ex.PrivateKey == "" {
+		return errors.New("password or private_key is required")
+	}
+	if !strings.HasPrefix(ex.HostKeyFingerprint, "SHA256:") {
+		return errors.New("host_key_fingerprint must be a SHA256 fingerprint, e.g. SHA256:...")
+	}
+	for _, addr := range ex.AlertEmails {
+		if _, err := mail.ParseAddress(addr); err != nil {
+			return fmt.Errorf("invalid alert email: %s", addr)
+		}
+	}
+
+	m, err := s.ModuleRepo.FindByName(ctx, ex.ModuleName)
+	if err != nil {
+		return fmt.Errorf("module %s not found", ex.ModuleName)
+	}
+
+	switch ex.Direction {
+	case DirectionExport:
+		known := map[string]bool{"id": true, "created_at": true, "updated_at": true, "created_by": true}
+		for _, f := range m.Fields {
+			known[f.Name] = true
+		}
+		for _, col := range ex.Columns {
+			if !known[col] {
+				return fmt.Errorf("unknown column: %s", col)
+			}
+		}
+		if ex.SavedFilterID != "" {
+			filter, err := s.loadFilter(ctx, ex.SavedFilterID)
+			if err != nil {
+				return err
+			}
+			if filter.ModuleName != ex.ModuleName {
+				return fmt.Errorf("saved filter belongs to module %s", filter.ModuleName)
+			}
+		}
+		if ex.FileName != "" && strings.ContainsAny(ex.FileName, "/\\") {
+			return errors.New("file_name must not contain a path")
+		}
+	case DirectionImport:
+		if ex.FilePattern == "" {
+			ex.FilePattern = "*.csv"
+		}
+		if _, err := path.Match(ex.FilePattern, ""); err != nil {
+			return fmt.Errorf("invalid file_pattern: %w", err)
+		}
+		if ex.ArchivePath != "" && path.Clean(ex.ArchivePath) == path.Clean(ex.RemotePath) {
+			return errors.New("archive_path must differ from remote_path")
+		}
+	default:
+		return errors.New("direction must be export or import")
+	}
+	return nil
+}
+
+func (s *ExchangeServiceImpl) loadFilter(ctx context.Context, id string) (*saved_filter.SavedFilter, error) {
+	filter, err := s.SavedFilterService.GetFilter(ctx, id)
+	if err != nil || filter == nil {
+		return nil, errors.New("saved filter not found")
+	}
+	tenantID, _ := tenantFromContext(ctx)
+	if !filter.TenantID.IsZero() && filter.TenantID != tenantID {
+		return nil, errors.New("saved filter not found")
+	}
+	return filter, nil
+}
+
+func (s *ExchangeServiceImpl) CreateExchange(ctx context.Context, ex *Exchange, userID primitive.ObjectID) error {
+	if err := s.validate(ctx, ex); err != nil {
+		return err
+	}
+	ex.CreatedBy = userID
+	ex.LastRunAt = time.Time{}
+	ex.LastStatus = ""
+	if err := s.ExchangeRepo.Create(ctx, ex); err != nil {
+		return err
+	}
+	redact(ex)
+
+	_ = s.AuditService.LogChange(ctx, models.AuditActionSettings, "exchange", ex.Name, map[string]models.Change{
+		"exchange": {New: ex},
+	})
+	return nil
+}
+
+func (s *ExchangeServiceImpl) GetExchange(ctx context.Context, id string) (*Exchange, error) {
+	ex, err := s.ExchangeRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	redact(ex)
+	return ex, nil
+}
+
+func (s *ExchangeServiceImpl) ListExchanges(ctx context.Context) ([]Exchange, error) {
+	exchanges, err := s.ExchangeRepo.List(ctx)
+	for i := range exchanges {
+		redact(&exchanges[i])
+	}
+	return exchanges, err
+}
+
+func (s *ExchangeServiceImpl) UpdateExchange(ctx context.Context, id string, in *Exchange) (*Exchange, error) {
+	existing, err := s.ExchangeRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	old := *existing
+	redact(&old)
+
+	existing.Name = in.Name
+	existing.ModuleName = in.ModuleName
+	existing.Host = in.Host
+	existing.Port = in.Port
+	existing.Username = in.Username
+	existing.HostKeyFingerprint = in.HostKeyFingerprint
+	existing.RemotePath = in.RemotePath
+	existing.SavedFilterID = in.SavedFilterID
+	existing.Columns = in.Columns
+	existing.FileName = in.FileName
+	existing.FilePattern = in.FilePattern
+	existing.ColumnMapping = in.ColumnMapping
+	existing.ArchivePath = in.ArchivePath
+	existing.AlertEmails = in.AlertEmails
+	existing.IsActive = in.IsActive
+	// Credentials are only replaced when sent; the direction is fixed
+	if in.Password != "" {
+		existing.Password = in.Password
+	}
+	if in.PrivateKey != "" {
+		existing.PrivateKey = in.PrivateKey
+	}
+	if err := s.validate(ctx, existing); err != nil {
+		return nil, err
+	}
+	if err := s.ExchangeRepo.Update(ctx, existing); err != nil {
+		return nil, err
+	}
+	redact(existing)
+
+	_ = s.AuditService.LogChange(ctx, models.AuditActionSettings, "exchange", existing.Name, map[string]models.Change{
+		"exchange": {Old: old, New: existing},
+	})
+	return existing, nil
+}
+
+func (s *ExchangeServiceImpl) DeleteExchange(ctx context.Context, id string) error {
+	ex, err := s.ExchangeRepo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.ExchangeRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	_ = s.RunRepo.DeleteByExchange(ctx, ex.ID)
+
+	_ = s.AuditService.LogChange(ctx, models.AuditActionSettings, "exchange", ex.Name, map[string]models.Change{
+		"exchange": {Old: ex.Host + ":" + ex.RemotePath, New: "DELETED"},
+	})
+	return nil
+}
+
+func (s *ExchangeServiceImpl) TestConnection(ctx context.Context, id string) error {
+	ex, err := s.ExchangeRepo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	client, err := dialSFTP(ex)
+	if err != nil {
+		return fmt.Errorf("could not connect: %w", err)
+	}
+	defer client.Close()
+
+	info, err := client.Stat(ex.RemotePath)
+	if err != nil {
+		return fmt.Errorf("remote path %s: %w", ex.RemotePath, err)
+	}
+	if !info.IsDir {
+		return fmt.Errorf("remote path %s is not a directory", ex.RemotePath)
+	}
+	return nil
+}
+
+func (s *ExchangeServiceImpl) ListRuns(ctx context.Context, id string, limit int64) ([]Run, error) {
+	ex, err := s.ExchangeRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	return s.RunRepo.ListByExchange(ctx, ex.ID, limit)
+}
+
+func (s *ExchangeServiceImpl) RunExchange(ctx context.Context, id string) (*Run, error) {
+	ex, err := s.ExchangeRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.run(context.WithoutCancel(ctx), ex)
+}
+
+func (s *ExchangeServiceImpl) RunScheduled(ctx context.Context, id string) error {
+	ex, err := s.ExchangeRepo.GetForRun(ctx, id)
+	if err != nil {
+		return fmt.Errorf("exchange %s not found", id)
+	}
+	if !ex.IsActive {
+		return nil
+	}
+	ctx = context.WithValue(ctx, models.TenantIDKey, ex.TenantID.Hex())
+	_, err = s.run(ctx, ex)
+	return err
+}
+
+func (s *ExchangeServiceImpl) run(ctx context.Context, ex *Exchange) (*Run, error) {
+	if _, busy := s.running.LoadOrStore(ex.ID, struct{}{}); busy {
+		return nil, ErrExchangeRunning
+	}
+	defer s.running.Delete(ex.ID)
+
+	run := &Run{
+		ExchangeID: ex.ID,
+		Direction:  ex.Direction,
+		Status:     RunInProgress,
+		Files:      []RunFile{},
+		StartedAt:  time.Now(),
+	}
+	_ = s.RunRepo.Create(ctx, run)
+
+	var runError error
+	defer func() {
+		now := time.Now()
+		run.FinishedAt = &now
+		switch {
+		case runError != nil:
+			run.Status = RunFailed
+			run.Error = runError.Error()
+		case run.Failed > 0:
+			run.Status = RunPartial
+		default:
+			run.Status = RunSuccess
+		}
+		_ = s.RunRepo.Update(ctx, run)
+
+		ex.LastRunAt = run.StartedAt
+		ex.LastStatus = run.Status
+		_ = s.ExchangeRepo.Update(ctx, ex)
+
+		_ = s.AuditService.LogChange(ctx, models.AuditActionSync, "exchange", ex.Name, map[string]models.Change{
+			"status":  {New: run.Status},
+			"files":   {New: len(run.Files)},
+			"records": {New: run.Records},
+			"failed":  {New: run.Failed},
+			"error":   {New: run.Error},
+		})
+
+		if run.Status != RunSuccess {
+			s.alert(ctx, ex, run)
+		}
+	}()
+
+	client, err := dialSFTP(ex)
+	if err != nil {
+		runError = fmt.Errorf("could not connect to %s: %w", ex.Host, err)
+		return run, runError
+	}
+	defer client.Close()
+
+	if ex.Direction == DirectionExport {
+		runError = s.exportRecords(ctx, client, ex, run)
+	} else {
+		runError = s.importFiles(ctx, client, ex, run)
+	}
+	return run, runError
+}
+
+// alert tells the creator and the alert recipients about a failed or
+// partially failed run
+func (s *ExchangeServiceImpl) alert(ctx context.Context, ex *Exchange, run *Run) {
+	title := fmt.Sprintf("SFTP exchange %s failed", ex.Name)
+	message := run.Error
+	if run.Status == RunPartial {
+		title = fmt.Sprintf("SFTP exchange %s completed with errors", ex.Name)
+		message = fmt.Sprintf("%d records or files failed", run.Failed)
+	}
+	for _, f := range run.Files {
+		if f.Error != "" {
+			message += fmt.Sprintf("\n%s: %s", f.Name, f.Error)
+		}
+	}
+
+	if !ex.CreatedBy.IsZero() {
+		_ = s.NotificationService.CreateNotification(ctx, ex.CreatedBy, title, message,
+			notification.NotificationTypeError, "/dashboard/settings/exchanges/"+ex.ID.Hex())
+	}
+	if len(ex.AlertEmails) > 0 {
+		body := fmt.Sprintf("%s\n\n%s\n\nRun started %s.", title, message, run.StartedAt.Format(time.RFC1123))
+		if err := s.EmailService.SendEmail(ctx, ex.AlertEmails, title, body); err != nil {
+			log.Printf("exchange %s: failed to send alert: %v", ex.ID.Hex(), err)
+		}
+	}
+}
@@ -0,0 +1,488 @@
+package exchange
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// A minimal SFTP (protocol version 3) client covering what exchanges need:
+// listing a directory, streaming files in and out, renaming and removing.
+// Requests are sent one at a time.
+
+const (
+	sftpInit     = 1
+	sftpVersion  = 2
+	sftpOpen     = 3
+	sftpClose    = 4
+	sftpRead     = 5
+	sftpWrite    = 6
+	sftpOpenDir  = 11
+	sftpReadDir  = 12
+	sftpRemove   = 13
+	sftpMkdir    = 14
+	sftpStat     = 17
+	sftpRename   = 18
+	sftpStatus   = 101
+	sftpHandle   = 102
+	sftpData     = 103
+	sftpName     = 104
+	sftpAttrs    = 105
+	sftpProtocol = 3
+
+	sftpFlagRead   = 0x01
+	sftpFlagWrite  = 0x02
+	sftpFlagCreate = 0x08
+	sftpFlagTrunc  = 0x10
+
+	sftpOK         = 0
+	sftpEOF        = 1
+	sftpNoSuchFile = 2
+
+	sftpAttrSize        = 0x01
+	sftpAttrUIDGID      = 0x02
+	sftpAttrPermissions = 0x04
+	sftpAttrTimes       = 0x08
+	sftpAttrExtended    = 0x80000000
+
+	// Stay well under the 34000 byte packet size every server must accept
+	sftpChunkSize   = 32 * 1024
+	sftpMaxPacket   = 256 * 1024
+	sftpDialTimeout = 30 * time.Second
+)
+
+var errSFTPNotFound = errors.New("no such file")
+
+// sftpStatusError is a non-OK status returned by the server
+type sftpStatusError struct {
+	Code    uint32
+	Message string
+}
+
+func (e *sftpStatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("sftp: %s (code %d)", e.Message, e.Code)
+	}
+	return fmt.Sprintf("sftp: status code %d", e.Code)
+}
+
+func (e *sftpStatusError) Is(target error) bool {
+	return target == errSFTPNotFound && e.Code == sftpNoSuchFile
+}
+
+// remoteFile is a directory entry on the server
+type remoteFile struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+type sftpClient struct {
+	ssh     *ssh.Client
+	session *ssh.Session
+	in      io.WriteCloser
+	out     io.Reader
+
+	mu     sync.Mutex
+	nextID uint32
+}
+
+// dialSFTP connects to the exchange's server. The host key must match the
+// configured SHA256 fingerprint.
+func dialSFTP(ex *Exchange) (*sftpClient, error) {
+	auth := []ssh.AuthMethod{}
+	if ex.PrivateKey != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(ex.PrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if ex.Password != "" {
+		auth = append(auth, ssh.Password(ex.Password))
+	}
+
+	config := &ssh.ClientConfig{
+		User: ex.Username,
+		Auth: auth,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			if got := ssh.FingerprintSHA256(key); got != ex.HostKeyFingerprint {
+				return fmt.Errorf("host key mismatch: server presented %s", got)
+			}
+			return nil
+		},
+		Timeout: sftpDialTimeout,
+	}
+
+	port := ex.Port
+	if port == 0 {
+		port = 22
+	}
+	conn, err := ssh.Dial("tcp", net.JoinHostPort(ex.Host, strconv.Itoa(port)), config)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &sftpClient{ssh: conn}
+	if err := c.start(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *sftpClient) start() error {
+	session, err := c.ssh.NewSession()
+	if err != nil {
+		return err
+	}
+	c.session = session
+	if c.in, err = session.StdinPipe(); err != nil {
+		return err
+	}
+	if c.out, err = session.StdoutPipe(); err != nil {
+		return err
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		return fmt.Errorf("sftp subsystem unavailable: %w", err)
+	}
+
+	// INIT carries the version where a request ID would be
+	init := binary.BigEndian.AppendUint32(nil, sftpProtocol)
+	if err := c.writePacket(sftpInit, init); err != nil {
+		return err
+	}
+	typ, _, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	if typ != sftpVersion {
+		return fmt.Errorf("sftp: unexpected packet %d during handshake", typ)
+	}
+	return nil
+}
+
+func (c *sftpClient) Close() error {
+	if c.session != nil {
+		c.session.Close()
+	}
+	return c.ssh.Close()
+}
+
+func (c *sftpClient) writePacket(typ byte, payload []byte) error {
+	buf := make([]byte, 0, 5+len(payload))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(1+len(payload)))
+	buf = append(buf, typ)
+	buf = append(buf, payload...)
+	_, err := c.in.Write(buf)
+	return err
+}
+
+func (c *sftpClient) readPacket() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(c.out, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length < 1 || length > sftpMaxPacket {
+		return 0, nil, fmt.Errorf("sftp: invalid packet length %d", length)
+	}
+	payload := make([]byte, length-1)
+	if _, err := io.ReadFull(c.out, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[4], payload, nil
+}
+
+// request sends one request and returns the reply with its ID stripped
+func (c *sftpClient) request(typ byte, body []byte) (byte, []byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextID++
+	id := c.nextID
+	payload := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(body)), id)
+	payload = append(payload, body...)
+	if err := c.writePacket(typ, payload); err != nil {
+		return 0, nil, err
+	}
+
+	replyType, reply, err := c.readPacket()
+	if err != nil {
+		return 0, nil, err
+	}
+	r := &sftpReader{buf: reply}
+	if got := r.uint32(); got != id || r.err != nil {
+		return 0, nil, fmt.Errorf("sftp: reply for request %d, expected %d", got, id)
+	}
+	if replyType == sftpStatus {
+		code := r.uint32()
+		msg := r.string()
+		if code == sftpOK {
+			return replyType, nil, nil
+		}
+		return replyType, nil, &sftpStatusError{Code: code, Message: msg}
+	}
+	return replyType, r.buf, nil
+}
+
+// expectStatus runs a request whose only successful reply is STATUS OK
+func (c *sftpClient) expectStatus(typ byte, body []byte) error {
+	replyType, _, err := c.request(typ, body)
+	if err != nil {
+		return err
+	}
+	if replyType != sftpStatus {
+		return fmt.Errorf("sftp: unexpected reply %d", replyType)
+	}
+	return nil
+}
+
+func (c *sftpClient) handle(typ byte, body []byte) (string, error) {
+	replyType, reply, err := c.request(typ, body)
+	if err != nil {
+		return "", err
+	}
+	if replyType != sftpHandle {
+		return "", fmt.Errorf("sftp: unexpected reply %d", replyType)
+	}
+	r := &sftpReader{buf: reply}
+	h := r.string()
+	return h, r.err
+}
+
+func (c *sftpClient) closeHandle(h string) error {
+	return c.expectStatus(sftpClose, appendString(nil, h))
+}
+
+// ReadDir lists a directory, leaving out "." and ".."
+func (c *sftpClient) ReadDir(dir string) ([]remoteFile, error) {
+	h, err := c.handle(sftpOpenDir, appendString(nil, dir))
+	if err != nil {
+		return nil, err
+	}
+	defer c.closeHandle(h)
+
+	var files []remoteFile
+	for {
+		replyType, reply, err := c.request(sftpReadDir, appendString(nil, h))
+		var status *sftpStatusError
+		if errors.As(err, &status) && status.Code == sftpEOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if replyType != sftpName {
+			return nil, fmt.Errorf("sftp: unexpected reply %d", replyType)
+		}
+		r := &sftpReader{buf: reply}
+		count := r.uint32()
+		for i := uint32(0); i < count && r.err == nil; i++ {
+			name := r.string()
+			r.string() // long name
+			f := r.attrs()
+			if name == "." || name == ".." {
+				continue
+			}
+			f.Name = name
+			files = append(files, f)
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+	}
+}
+
+// Stat reports errSFTPNotFound (via errors.Is) for missing paths
+func (c *sftpClient) Stat(p string) (remoteFile, error) {
+	replyType, reply, err := c.request(sftpStat, appendString(nil, p))
+	if err != nil {
+		return remoteFile{}, err
+	}
+	if replyType != sftpAttrs {
+		return remoteFile{}, fmt.Errorf("sftp: unexpected reply %d", replyType)
+	}
+	r := &sftpReader{buf: reply}
+	f := r.attrs()
+	return f, r.err
+}
+
+func (c *sftpClient) Mkdir(p string) error {
+	body := appendString(nil, p)
+	body = binary.BigEndian.AppendUint32(body, 0) // no attributes
+	return c.expectStatus(sftpMkdir, body)
+}
+
+func (c *sftpClient) Remove(p string) error {
+	return c.expectStatus(sftpRemove, appendString(nil, p))
+}
+
+// Rename fails on most servers when the target exists
+func (c *sftpClient) Rename(from, to string) error {
+	return c.expectStatus(sftpRename, appendString(appendString(nil, from), to))
+}
+
+// Download copies a remote file into w
+func (c *sftpClient) Download(p string, w io.Writer) (int64, error) {
+	body := appendString(nil, p)
+	body = binary.BigEndian.AppendUint32(body, sftpFlagRead)
+	body = binary.BigEndian.AppendUint32(body, 0)
+	h, err := c.handle(sftpOpen, body)
+	if err != nil {
+		return 0, err
+	}
+	defer c.closeHandle(h)
+
+	var offset uint64
+	for {
+		req := appendString(nil, h)
+		req = binary.BigEndian.AppendUint64(req, offset)
+		req = binary.BigEndian.AppendUint32(req, sftpChunkSize)
+		replyType, reply, err := c.request(sftpRead, req)
+		var status *sftpStatusError
+		if errors.As(err, &status) && status.Code == sftpEOF {
+			return int64(offset), nil
+		}
+		if err != nil {
+			return int64(offset), err
+		}
+		if replyType != sftpData {
+			return int64(offset), fmt.Errorf("sftp: unexpected reply %d", replyType)
+		}
+		r := &sftpReader{buf: reply}
+		data := r.bytes()
+		if r.err != nil {
+			return int64(offset), r.err
+		}
+		if _, err := w.Write(data); err != nil {
+			return int64(offset), err
+		}
+		offset += uint64(len(data))
+	}
+}
+
+// Create opens a remote file for writing, truncating it if it exists
+func (c *sftpClient) Create(p string) (*sftpWriter, error) {
+	body := appendString(nil, p)
+	body = binary.BigEndian.AppendUint32(body, sftpFlagWrite|sftpFlagCreate|sftpFlagTrunc)
+	body = binary.BigEndian.AppendUint32(body, 0)
+	h, err := c.handle(sftpOpen, body)
+	if err != nil {
+		return nil, err
+	}
+	return &sftpWriter{client: c, handle: h}, nil
+}
+
+// sftpWriter writes sequentially to an open remote file
+type sftpWriter struct {
+	client *sftpClient
+	handle string
+	offset uint64
+}
+
+func (w *sftpWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > sftpChunkSize {
+			chunk = chunk[:sftpChunkSize]
+		}
+		body := appendString(nil, w.handle)
+		body = binary.BigEndian.AppendUint64(body, w.offset)
+		body = appendString(body, string(chunk))
+		if err := w.client.expectStatus(sftpWrite, body); err != nil {
+			return written, err
+		}
+		w.offset += uint64(len(chunk))
+		written += len(chunk)
+		p = p[len(chunk):]
+	}
+	return written, nil
+}
+
+func (w *sftpWriter) Close() error {
+	return w.client.closeHandle(w.handle)
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(s)))
+	return append(buf, s...)
+}
+
+// sftpReader decodes reply payloads; the first short read sticks in err
+type sftpReader struct {
+	buf []byte
+	err error
+}
+
+func (r *sftpReader) uint32() uint32 {
+	if r.err != nil || len(r.buf) < 4 {
+		r.err = errors.New("sftp: short packet")
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.buf)
+	r.buf = r.buf[4:]
+	return v
+}
+
+func (r *sftpReader) uint64() uint64 {
+	if r.err != nil || len(r.buf) < 8 {
+		r.err = errors.New("sftp: short packet")
+		return 0
+	}
+	v := binary.BigEndian.Uint64(r.buf)
+	r.buf = r.buf[8:]
+	return v
+}
+
+func (r *sftpReader) bytes() []byte {
+	n := r.uint32()
+	if r.err != nil || uint32(len(r.buf)) < n {
+		r.err = errors.New("sftp: short packet")
+		return nil
+	}
+	v := r.buf[:n]
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *sftpReader) string() string {
+	return string(r.bytes())
+}
+
+func (r *sftpReader) attrs() remoteFile {
+	var f remoteFile
+	flags := r.uint32()
+	if flags&sftpAttrSize != 0 {
+		f.Size = int64(r.uint64())
+	}
+	if flags&sftpAttrUIDGID != 0 {
+		r.uint32()
+		r.uint32()
+	}
+	if flags&sftpAttrPermissions != 0 {
+		// S_IFDIR in the file type bits
+		f.IsDir = r.uint32()&0o170000 == 0o040000
+	}
+	if flags&sftpAttrTimes != 0 {
+		r.uint32() // atime
+		f.ModTime = time.Unix(int64(r.uint32()), 0)
+	}
+	if flags&sftpAttrExtended != 0 {
+		n := r.uint32()
+		for i := uint32(0); i < n && r.err == nil; i++ {
+			r.string()
+			r.string()
+		}
+	}
+	return f
+}
@@ -0,0 +1,292 @@
+package exchange
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	import_feature "go-crm/internal/features/import"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const recordPageSize = int64(500)
+
+func (r *Run) addFile(f RunFile) {
+	if len(r.Files) < maxLoggedFiles {
+		r.Files = append(r.Files, f)
+	}
+}
+
+// exportRecords writes the matching records to a temporary ".part" file and
+// renames it into place once complete, so the other side never picks up a
+// partial file
+func (s *ExchangeServiceImpl) exportRecords(ctx context.Context, client *sftpClient, ex *Exchange, run *Run) error {
+	columns, err := s.exportColumns(ctx, ex)
+	if err != nil {
+		return err
+	}
+	query := map[string]any{}
+	if ex.SavedFilterID != "" {
+		filter, err := s.loadFilter(ctx, ex.SavedFilterID)
+		if err != nil {
+			return err
+		}
+		query = s.SavedFilterService.BuildQueryFromCriteria(filter.Criteria)
+	}
+
+	layout := ex.FileName
+	if layout == "" {
+		layout = ex.ModuleName + "_20060102_150405.csv"
+	}
+	name := run.StartedAt.Format(layout)
+	target := path.Join(ex.RemotePath, name)
+	partial := target + ".part"
+
+	remote, err := client.Create(partial)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", partial, err)
+	}
+	buffered := bufio.NewWriterSize(remote, sftpChunkSize)
+	w := csv.NewWriter(buffered)
+
+	file := RunFile{Name: name}
+	err = w.Write(columns)
+	for page := int64(0); err == nil; page++ {
+		var records []map[string]any
+		records, err = s.RecordRepo.List(ctx, ex.ModuleName, query, nil, recordPageSize, page*recordPageSize, "created_at", 1)
+		if err != nil {
+			err = fmt.Errorf("failed to fetch records on page %d: %v", page+1, err)
+			break
+		}
+		for _, rec := range records {
+			row := make([]string, len(columns))
+			for i, col := range columns {
+				row[i] = cellString(rec[col])
+			}
+			if err = w.Write(row); err != nil {
+				break
+			}
+			file.Records++
+		}
+		if len(records) < int(recordPageSize) {
+			break
+		}
+	}
+	if err == nil {
+		w.Flush()
+		err = w.Error()
+	}
+	if err == nil {
+		err = buffered.Flush()
+	}
+	file.Size = int64(remote.offset)
+	if closeErr := remote.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		// SFTP v3 rename does not overwrite
+		if rmErr := client.Remove(target); rmErr != nil && !errors.Is(rmErr, errSFTPNotFound) {
+			err = rmErr
+		}
+	}
+	if err == nil {
+		err = client.Rename(partial, target)
+	}
+	if err != nil {
+		_ = client.Remove(partial)
+		file.Error = err.Error()
+		run.addFile(file)
+		return fmt.Errorf("failed to upload %s: %w", name, err)
+	}
+
+	run.addFile(file)
+	run.Records += file.Records
+	return nil
+}
+
+// exportColumns defaults to every module field
+func (s *ExchangeServiceImpl) exportColumns(ctx context.Context, ex *Exchange) ([]string, error) {
+	if len(ex.Columns) > 0 {
+		return ex.Columns, nil
+	}
+	m, err := s.ModuleRepo.FindByName(ctx, ex.ModuleName)
+	if err != nil {
+		return nil, fmt.Errorf("module %s not found", ex.ModuleName)
+	}
+	columns := []string{"id"}
+	for _, f := range m.Fields {
+		columns = append(columns, f.Name)
+	}
+	return append(columns, "created_at", "updated_at"), nil
+}
+
+// importFiles hands every matching file in the watched directory to the import
+// pipeline, oldest name first. Imported files are archived (or deleted); files
+// the pipeline rejects are renamed with an ".error" suffix so they are not
+// picked up again.
+func (s *ExchangeServiceImpl) importFiles(ctx context.Context, client *sftpClient, ex *Exchange, run *Run) error {
+	entries, err := client.ReadDir(ex.RemotePath)
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", ex.RemotePath, err)
+	}
+	pattern := ex.FilePattern
+	if pattern == "" {
+		pattern = "*.csv"
+	}
+	var names []string
+	for _, e := range entries {
+		if ok, _ := path.Match(pattern, e.Name); ok && !e.IsDir {
+			names = append(names, e.Name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+
+	dir := filepath.Join(s.Config.FSPath, "exchanges", ex.TenantID.Hex())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if ex.ArchivePath != "" {
+		if _, err := client.Stat(ex.ArchivePath); errors.Is(err, errSFTPNotFound) {
+			if err := client.Mkdir(ex.ArchivePath); err != nil {
+				return fmt.Errorf("failed to create archive path %s: %w", ex.ArchivePath, err)
+			}
+		}
+	}
+
+	for _, name := range names {
+		remote := path.Join(ex.RemotePath, name)
+		file, err := s.importFile(ctx, client, ex, remote, filepath.Join(dir, run.ID.Hex()+"_"+filepath.Base(name)))
+		file.Name = name
+		run.Records += file.Records
+		run.Failed += file.Failed
+		if err != nil {
+			run.Failed++
+			file.Error = err.Error()
+			if renameErr := client.Rename(remote, remote+".error"); renameErr != nil {
+				file.Error += "; " + renameErr.Error()
+			}
+			run.addFile(file)
+			continue
+		}
+
+		if ex.ArchivePath != "" {
+			err = client.Rename(remote, path.Join(ex.ArchivePath, run.StartedAt.Format("20060102_150405_")+name))
+		} else {
+			err = client.Remove(remote)
+		}
+		if err != nil {
+			// The records are in; a leftover file would be imported twice
+			run.Failed++
+			file.Error = fmt.Sprintf("imported but could not be moved: %v", err)
+			run.addFile(file)
+			return fmt.Errorf("failed to archive %s: %w", name, err)
+		}
+		run.addFile(file)
+	}
+	return nil
+}
+
+func (s *ExchangeServiceImpl) importFile(ctx context.Context, client *sftpClient, ex *Exchange, remote, local string) (RunFile, error) {
+	var file RunFile
+	out, err := os.Create(local)
+	if err != nil {
+		return file, err
+	}
+	file.Size, err = client.Download(remote, out)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(local)
+		return file, fmt.Errorf("download failed: %w", err)
+	}
+
+	mapping := ex.ColumnMapping
+	if len(mapping) == 0 {
+		if mapping, err = identityMapping(local); err != nil {
+			return file, err
+		}
+	}
+
+	job := &import_feature.ImportJob{
+		UserID:        ex.CreatedBy,
+		ModuleName:    ex.ModuleName,
+		FileName:      path.Base(remote),
+		FilePath:      local,
+		ColumnMapping: mapping,
+	}
+	if err := s.ImportService.CreateJob(ctx, job); err != nil {
+		return file, err
+	}
+	file.ImportJobID = job.ID
+	if err := s.ImportService.ProcessImport(ctx, job.ID.Hex(), ex.CreatedBy); err != nil {
+		return file, err
+	}
+
+	result, err := s.ImportService.GetJob(ctx, job.ID.Hex())
+	if err != nil {
+		return file, err
+	}
+	file.Records = result.SuccessCount
+	file.Failed = result.ErrorCount
+	if result.ErrorCount > 0 && len(result.Errors) > 0 {
+		file.Error = fmt.Sprintf("row %d: %s", result.Errors[0].Row, result.Errors[0].Message)
+	}
+	return file, nil
+}
+
+// identityMapping maps each CSV header to the field of the same name
+func identityMapping(local string) (map[string]string, error) {
+	f, err := os.Open(local)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	headers, err := csv.NewReader(f).Read()
+	if err == io.EOF {
+		return nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+	mapping := make(map[string]string, len(headers))
+	for _, h := range headers {
+		// Keys must match the raw headers the pipeline reads, BOM included
+		mapping[h] = strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))
+	}
+	return mapping, nil
+}
+
+func cellString(val any) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case primitive.DateTime:
+		return v.Time().Format(time.RFC3339)
+	case primitive.ObjectID:
+		return v.Hex()
+	case map[string]any:
+		if name, ok := v["name"]; ok {
+			return fmt.Sprint(name)
+		}
+	}
+	return fmt.Sprint(val)
+}
@@ -0,0 +1,38 @@
+package export
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/features/role"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ExportApi struct {
+	controller  *ExportController
+	config      *config.Config
+	roleService role.RoleService
+}
+
+func NewExportApi(controller *ExportController, config *config.Config, roleService role.RoleService) *ExportApi {
+	return &ExportApi{
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *ExportApi) Setup(app *fiber.App) {
+	// Exporting needs module read like the record routes; record access is
+	// then enforced per user by RecordService while streaming
+	app.Post("/api/modules/:module/export", middleware.AuthMiddleware(h.config.SkipAuth),
+		middleware.RequireModuleAction(h.roleService, middleware.ModuleParam("module"), "read"), h.controller.StartExport)
+
+	// Signed links are the credential for downloads, so this route skips AuthMiddleware.
+	// It must be registered before the authenticated group below, which matches the same prefix.
+	app.Get("/api/exports/:id/download", h.controller.DownloadExport)
+
+	exports := app.Group("/api/exports", middleware.AuthMiddleware(h.config.SkipAuth))
+	exports.Get("/", h.controller.ListExports)
+	exports.Get("/:id", h.controller.GetExport)
+}
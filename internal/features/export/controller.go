@@ -0,0 +1,130 @@
+package export
+
+import (
+	"errors"
+
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ExportController struct {
+	ExportService ExportService
+}
+
+func NewExportController(exportService ExportService) *ExportController {
+	return &ExportController{
+		ExportService: exportService,
+	}
+}
+
+// StartExport godoc
+// @Summary Start bulk export
+// @Description Export module records matching filters to CSV, XLSX or JSON in the background. The requester is notified with a signed download link when the file is ready.
+// @Tags exports
+// @Accept json
+// @Produce json
+// @Param module path string true "Module Name"
+// @Param request body ExportRequest true "Export options"
+// @Success 202 {object} ExportJob
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/modules/{module}/export [post]
+func (c *ExportController) StartExport(ctx *fiber.Ctx) error {
+	var req ExportRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	job, err := c.ExportService.StartExport(ctx.UserContext(), ctx.Params("module"), req, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.Status(fiber.StatusAccepted).JSON(job)
+}
+
+// ListExports godoc
+// @Summary List exports
+// @Description List the current user's recent export jobs
+// @Tags exports
+// @Produce json
+// @Success 200 {array} ExportJob
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/exports [get]
+func (c *ExportController) ListExports(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	jobs, err := c.ExportService.ListJobs(ctx.UserContext(), userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(jobs)
+}
+
+// GetExport godoc
+// @Summary Get export status
+// @Description Get an export job; completed jobs include a fresh signed download_url
+// @Tags exports
+// @Produce json
+// @Param id path string true "Export Job ID"
+// @Success 200 {object} ExportJob
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/exports/{id} [get]
+func (c *ExportController) GetExport(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	job, err := c.ExportService.GetJob(ctx.UserContext(), ctx.Params("id"), userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Export not found"})
+	}
+
+	return ctx.JSON(job)
+}
+
+// DownloadExport godoc
+// @Summary Download export
+// @Description Download a completed export using a signed link. No bearer token is required.
+// @Tags exports
+// @Produce octet-stream
+// @Param id path string true "Export Job ID"
+// @Param tenant query string true "Tenant ID"
+// @Param expires query int true "Link expiry (unix seconds)"
+// @Param signature query string true "Link signature"
+// @Success 200 {file} file
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/exports/{id}/download [get]
+func (c *ExportController) DownloadExport(ctx *fiber.Ctx) error {
+	artifact, body, err := c.ExportService.ResolveDownload(ctx.UserContext(), ctx.Params("id"), ctx.Query("tenant"), ctx.Query("expires"), ctx.Query("signature"))
+	if err != nil {
+		if errors.Is(err, ErrInvalidSignature) {
+			return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Export not found"})
+	}
+
+	// The artifact may live in object storage, so stream it rather than
+	// serving a local path; SendStream closes body once it is sent
+	ctx.Attachment(artifact.OriginalFilename)
+	if artifact.MimeType != "" {
+		ctx.Set(fiber.HeaderContentType, artifact.MimeType)
+	}
+	if artifact.Size <= 0 {
+		return ctx.SendStream(body)
+	}
+	return ctx.SendStream(body, int(artifact.Size))
+}
@@ -0,0 +1,60 @@
+package export
+
+import (
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/common/watermark"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ExportFormat string
+
+const (
+	ExportFormatCSV  ExportFormat = "csv"
+	ExportFormatXLSX ExportFormat = "xlsx"
+	ExportFormatJSON ExportFormat = "json"
+)
+
+type ExportStatus string
+
+const (
+	ExportStatusPending    ExportStatus = "pending"
+	ExportStatusProcessing ExportStatus = "processing"
+	ExportStatusCompleted  ExportStatus = "completed"
+	ExportStatusFailed     ExportStatus = "failed"
+)
+
+// ExportRequest is the body of POST /api/modules/:module/export
+type ExportRequest struct {
+	Format  ExportFormat    `json:"format"`
+	Filters []models.Filter `json:"filters"`
+	Filter  string          `json:"filter,omitempty"`  // OData-style $filter expression
+	Columns []string        `json:"columns,omitempty"` // Defaults to every readable module field
+}
+
+// ExportJob tracks a background export. The artifact is stored as a File and
+// served through a signed download link once the job completes.
+type ExportJob struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID       primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	ModuleName     string             `json:"module_name" bson:"module_name"`
+	Format         ExportFormat       `json:"format" bson:"format"`
+	Filters        []models.Filter    `json:"filters,omitempty" bson:"filters,omitempty"`
+	Filter         string             `json:"filter,omitempty" bson:"filter,omitempty"`
+	Columns        []string           `json:"columns" bson:"columns"`
+	Status         ExportStatus       `json:"status" bson:"status"`
+	ProcessedCount int64              `json:"processed_count" bson:"processed_count"`
+	FileID         primitive.ObjectID `json:"file_id,omitempty" bson:"file_id,omitempty"`
+	FileSize       int64              `json:"file_size,omitempty" bson:"file_size,omitempty"`
+	DownloadURL    string             `json:"download_url,omitempty" bson:"-"`
+	Error          string             `json:"error,omitempty" bson:"error,omitempty"`
+	RequestedBy    primitive.ObjectID `json:"requested_by" bson:"requested_by"`
+	// Watermark is written into the file; it is taken when the export is
+	// requested, so an impersonating admin is named
+	Watermark   watermark.Mark `json:"watermark" bson:"watermark"`
+	CreatedAt   time.Time      `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at" bson:"updated_at"`
+	CompletedAt *time.Time     `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+}
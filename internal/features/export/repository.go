@@ -0,0 +1,100 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ExportRepository interface {
+	Create(ctx context.Context, job *ExportJob) error
+	Get(ctx context.Context, id string) (*ExportJob, error)
+	Update(ctx context.Context, job *ExportJob) error
+	ListByUser(ctx context.Context, userID primitive.ObjectID, limit int64) ([]ExportJob, error)
+}
+
+type ExportRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewExportRepository(db *database.MongodbDB) ExportRepository {
+	return &ExportRepositoryImpl{
+		collection: db.DB.Collection("export_jobs"),
+	}
+}
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+func (r *ExportRepositoryImpl) Create(ctx context.Context, job *ExportJob) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if job.ID.IsZero() {
+		job.ID = primitive.NewObjectID()
+	}
+	job.TenantID = tenantID
+	job.Status = ExportStatusPending
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = time.Now()
+
+	_, err = r.collection.InsertOne(ctx, job)
+	return err
+}
+
+func (r *ExportRepositoryImpl) Get(ctx context.Context, id string) (*ExportJob, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var job ExportJob
+	if err := r.collection.FindOne(ctx, bson.M{"_id": objID, "tenant_id": tenantID}).Decode(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *ExportRepositoryImpl) Update(ctx context.Context, job *ExportJob) error {
+	job.UpdatedAt = time.Now()
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": job.ID, "tenant_id": job.TenantID}, job)
+	return err
+}
+
+func (r *ExportRepositoryImpl) ListByUser(ctx context.Context, userID primitive.ObjectID, limit int64) ([]ExportJob, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, bson.M{"tenant_id": tenantID, "requested_by": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	jobs := []ExportJob{}
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
@@ -0,0 +1,356 @@
+package export
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/common/watermark"
+	"go-crm/internal/config"
+	"go-crm/internal/database"
+	"go-crm/internal/features/file"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/notification"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/role"
+	"go-crm/internal/features/settings"
+	"go-crm/internal/features/user"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	exportBatchSize = 500
+	downloadLinkTTL = 24 * time.Hour
+)
+
+var ErrInvalidSignature = errors.New("invalid or expired download link")
+
+type ExportService interface {
+	StartExport(ctx context.Context, moduleName string, req ExportRequest, userID primitive.ObjectID) (*ExportJob, error)
+	GetJob(ctx context.Context, id string, userID primitive.ObjectID) (*ExportJob, error)
+	ListJobs(ctx context.Context, userID primitive.ObjectID) ([]ExportJob, error)
+	// ResolveDownload verifies a signed link and opens the artifact's content;
+	// the caller closes the returned reader
+	ResolveDownload(ctx context.Context, jobID, tenantID, expires, signature string) (*file.File, io.ReadCloser, error)
+}
+
+type ExportServiceImpl struct {
+	ExportRepo          ExportRepository
+	FileRepo            file.FileRepository
+	FileService         file.FileService
+	ModuleRepo          module.ModuleRepository
+	RecordService       record.RecordService
+	RoleService         role.RoleService
+	NotificationService notification.NotificationService
+	SettingsService     settings.SettingsService
+	UserRepo            user.UserRepository
+	Config              *config.Config
+}
+
+func NewExportService(
+	exportRepo ExportRepository,
+	fileRepo file.FileRepository,
+	fileService file.FileService,
+	moduleRepo module.ModuleRepository,
+	recordService record.RecordService,
+	roleService role.RoleService,
+	notificationService notification.NotificationService,
+	settingsService settings.SettingsService,
+	userRepo user.UserRepository,
+	cfg *config.Config,
+) ExportService {
+	return &ExportServiceImpl{
+		ExportRepo:          exportRepo,
+		FileRepo:            fileRepo,
+		FileService:         fileService,
+		ModuleRepo:          moduleRepo,
+		RecordService:       recordService,
+		RoleService:         roleService,
+		NotificationService: notificationService,
+		SettingsService:     settingsService,
+		UserRepo:            userRepo,
+		Config:              cfg,
+	}
+}
+
+func (s *ExportServiceImpl) StartExport(ctx context.Context, moduleName string, req ExportRequest, userID primitive.ObjectID) (*ExportJob, error) {
+	if req.Format == "" {
+		req.Format = ExportFormatCSV
+	}
+	switch req.Format {
+	case ExportFormatCSV, ExportFormatXLSX, ExportFormatJSON:
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", req.Format)
+	}
+
+	m, err := s.ModuleRepo.FindByName(ctx, moduleName)
+	if err != nil {
+		return nil, errors.New("module not found")
+	}
+
+	// Validate the expression up front so syntax errors fail the request, not the job
+	if req.Filter != "" {
+		if _, err := record.ParseFilterExpression(req.Filter); err != nil {
+			return nil, err
+		}
+	}
+
+	columns, err := s.resolveColumns(ctx, m, req.Columns, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &ExportJob{
+		ModuleName:  moduleName,
+		Format:      req.Format,
+		Filters:     req.Filters,
+		Filter:      req.Filter,
+		Columns:     columns,
+		RequestedBy: userID,
+		Watermark:   s.watermark(ctx, userID),
+	}
+	if err := s.ExportRepo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+
+	// Detach from the request but keep the tenant so repositories stay scoped
+	bgCtx := context.WithValue(context.Background(), common_models.TenantIDKey, job.TenantID.Hex())
+	go s.runExport(bgCtx, *job)
+
+	return job, nil
+}
+
+// resolveColumns defaults to every module field and drops fields the user cannot read
+func (s *ExportServiceImpl) resolveColumns(ctx context.Context, m *common_models.Entity, requested []string, userID primitive.ObjectID) ([]string, error) {
+	known := map[string]bool{"id": true, "created_at": true, "updated_at": true, "created_by": true}
+	for _, f := range m.Fields {
+		known[f.Name] = true
+	}
+
+	columns := requested
+	if len(columns) == 0 {
+		columns = []string{"id"}
+		for _, f := range m.Fields {
+			columns = append(columns, f.Name)
+		}
+		columns = append(columns, "created_at", "updated_at")
+	}
+
+	for _, col := range columns {
+		if !known[col] {
+			return nil, fmt.Errorf("unknown column: %s", col)
+		}
+	}
+	perms, _ := s.RoleService.GetFieldPermissions(ctx, userID, m.Name)
+	return role.VisibleColumns(perms, columns), nil
+}
+
+// watermark names the exporting user; a user that cannot be loaded is named by ID
+func (s *ExportServiceImpl) watermark(ctx context.Context, userID primitive.ObjectID) watermark.Mark {
+	u, _ := s.UserRepo.FindByID(ctx, userID.Hex())
+	return watermark.New(ctx, userID.Hex(), u)
+}
+
+func (s *ExportServiceImpl) runExport(ctx context.Context, job ExportJob) {
+	// Exports read the records from secondaries when read offloading is on
+	reads := database.WithReadMode(ctx, database.ReadSecondary)
+	job.Status = ExportStatusProcessing
+	_ = s.ExportRepo.Update(ctx, &job)
+
+	fail := func(err error) {
+		log.Printf("export %s failed: %v", job.ID.Hex(), err)
+		now := time.Now()
+		job.Status = ExportStatusFailed
+		job.Error = err.Error()
+		job.CompletedAt = &now
+		_ = s.ExportRepo.Update(ctx, &job)
+		_ = s.NotificationService.CreateNotification(ctx, job.RequestedBy, "Export failed",
+			fmt.Sprintf("Your %s export could not be completed: %v", job.ModuleName, err),
+			notification.NotificationTypeError, "")
+	}
+
+	var expr *record.FilterExpr
+	if job.Filter != "" {
+		parsed, err := record.ParseFilterExpression(job.Filter)
+		if err != nil {
+			fail(err)
+			return
+		}
+		expr = parsed
+	}
+
+	dir := filepath.Join(s.Config.ExportPath, job.TenantID.Hex())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fail(err)
+		return
+	}
+	filename := fmt.Sprintf("%s_%s.%s", job.ModuleName, time.Now().Format("20060102_150405"), job.Format)
+	path := filepath.Join(dir, job.ID.Hex()+"."+string(job.Format))
+
+	// Field permissions may have changed while the job was queued
+	perms, err := s.RoleService.GetFieldPermissions(ctx, job.RequestedBy, job.ModuleName)
+	if err != nil {
+		fail(err)
+		return
+	}
+	job.Columns = role.VisibleColumns(perms, job.Columns)
+
+	mod, _ := s.ModuleRepo.FindByName(ctx, job.ModuleName)
+	cells := newCellFormatter(s.SettingsService.Formatter(ctx, job.RequestedBy.Hex()), mod)
+
+	w, err := newRowWriter(job.Format, path, cells, job.Watermark)
+	if err != nil {
+		fail(err)
+		return
+	}
+	if err := w.WriteHeader(job.Columns); err != nil {
+		w.Close()
+		fail(err)
+		return
+	}
+
+	err = s.RecordService.StreamRecords(reads, job.ModuleName, job.Filters, expr, exportBatchSize, job.RequestedBy, func(batch []map[string]any) error {
+		for _, rec := range batch {
+			if err := w.WriteRow(rec); err != nil {
+				return err
+			}
+		}
+		job.ProcessedCount += int64(len(batch))
+		return s.ExportRepo.Update(ctx, &job)
+	})
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		fail(err)
+		return
+	}
+
+	artifact := &file.File{
+		OriginalFilename: filename,
+		MimeType:         mimeTypeFor(job.Format),
+		ModuleName:       job.ModuleName,
+		UploadedBy:       job.RequestedBy,
+		Description:      fmt.Sprintf("Export of %s (%d records)", job.ModuleName, job.ProcessedCount),
+		CreatedAt:        time.Now(),
+	}
+	if err := s.storeArtifact(ctx, path, artifact); err != nil {
+		fail(err)
+		return
+	}
+
+	now := time.Now()
+	job.Status = ExportStatusCompleted
+	job.FileID = artifact.ID
+	job.FileSize = artifact.Size
+	job.CompletedAt = &now
+	_ = s.ExportRepo.Update(ctx, &job)
+
+	_ = s.NotificationService.CreateNotification(ctx, job.RequestedBy, "Export ready",
+		fmt.Sprintf("Your %s export with %d records is ready to download", job.ModuleName, job.ProcessedCount),
+		notification.NotificationTypeSuccess, s.signedDownloadURL(&job, time.Now().Add(downloadLinkTTL)))
+}
+
+func (s *ExportServiceImpl) GetJob(ctx context.Context, id string, userID primitive.ObjectID) (*ExportJob, error) {
+	job, err := s.ExportRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.RequestedBy != userID {
+		return nil, errors.New("export not found")
+	}
+	if job.Status == ExportStatusCompleted {
+		job.DownloadURL = s.signedDownloadURL(job, time.Now().Add(downloadLinkTTL))
+	}
+	return job, nil
+}
+
+func (s *ExportServiceImpl) ListJobs(ctx context.Context, userID primitive.ObjectID) ([]ExportJob, error) {
+	return s.ExportRepo.ListByUser(ctx, userID, 50)
+}
+
+// storeArtifact moves the staged export into object storage so any instance
+// can serve the download. Local storage is served statically from FSPath, so
+// there the export stays in ExportPath where only the signed link reaches it.
+func (s *ExportServiceImpl) storeArtifact(ctx context.Context, path string, artifact *file.File) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(s.Config.StorageBackend) {
+	case "", file.StorageLocal:
+		artifact.Path = path
+		artifact.Size = info.Size()
+		artifact.StorageType = file.StorageLocal
+		return s.FileRepo.Save(ctx, artifact)
+	}
+
+	defer os.Remove(path)
+	return s.FileService.StoreGeneratedStream(ctx, f, info.Size(), artifact)
+}
+
+// ResolveDownload verifies a signed link and opens the artifact. The link
+// itself is the credential, so the tenant travels in the signed payload.
+func (s *ExportServiceImpl) ResolveDownload(ctx context.Context, jobID, tenantID, expires, signature string) (*file.File, io.ReadCloser, error) {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return nil, nil, ErrInvalidSignature
+	}
+	expected := s.sign(jobID, tenantID, exp)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return nil, nil, ErrInvalidSignature
+	}
+
+	ctx = context.WithValue(ctx, common_models.TenantIDKey, tenantID)
+	job, err := s.ExportRepo.Get(ctx, jobID)
+	if err != nil || job.Status != ExportStatusCompleted {
+		return nil, nil, errors.New("export not found")
+	}
+	artifact, err := s.FileRepo.Get(ctx, job.FileID.Hex())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var body io.ReadCloser
+	if artifact.StorageKey == "" {
+		// Kept in ExportPath by a local storage backend
+		body, err = os.Open(artifact.Path)
+	} else {
+		body, err = s.FileService.OpenFile(ctx, artifact)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return artifact, body, nil
+}
+
+func (s *ExportServiceImpl) signedDownloadURL(job *ExportJob, expiresAt time.Time) string {
+	exp := expiresAt.Unix()
+	return fmt.Sprintf("/api/exports/%s/download?tenant=%s&expires=%d&signature=%s",
+		job.ID.Hex(), job.TenantID.Hex(), exp, s.sign(job.ID.Hex(), job.TenantID.Hex(), exp))
+}
+
+func (s *ExportServiceImpl) sign(jobID, tenantID string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(s.Config.JWTSecret))
+	fmt.Fprintf(mac, "export:%s:%s:%d", jobID, tenantID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,132 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-crm/internal/config"
+	"go-crm/internal/features/file"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memStorage is an object storage backend kept in memory
+type memStorage struct {
+	file.Storage
+	objects map[string][]byte
+}
+
+func (s *memStorage) Name() string { return file.StorageS3 }
+
+func (s *memStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	data, err := io.ReadAll(r)
+	s.objects[key] = data
+	return err
+}
+
+func (s *memStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, errors.New("object not found")
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+type memFileRepo struct {
+	file.FileRepository
+	files map[primitive.ObjectID]*file.File
+}
+
+func (r *memFileRepo) Save(ctx context.Context, f *file.File) error {
+	if f.ID.IsZero() {
+		f.ID = primitive.NewObjectID()
+	}
+	r.files[f.ID] = f
+	return nil
+}
+
+func (r *memFileRepo) Get(ctx context.Context, id string) (*file.File, error) {
+	oid, _ := primitive.ObjectIDFromHex(id)
+	if f, ok := r.files[oid]; ok {
+		return f, nil
+	}
+	return nil, errors.New("file not found")
+}
+
+type memExportRepo struct {
+	ExportRepository
+	job *ExportJob
+}
+
+func (r *memExportRepo) Get(ctx context.Context, id string) (*ExportJob, error) {
+	if r.job.ID.Hex() != id {
+		return nil, errors.New("export not found")
+	}
+	return r.job, nil
+}
+
+func TestExportArtifactDownload(t *testing.T) {
+	tests := []struct {
+		backend    string
+		wantStaged bool
+	}{
+		// Local artifacts stay outside the statically served FSPath
+		{backend: "", wantStaged: true},
+		{backend: file.StorageS3},
+	}
+
+	for _, tt := range tests {
+		t.Run("backend "+tt.backend, func(t *testing.T) {
+			cfg := &config.Config{JWTSecret: "secret", StorageBackend: tt.backend}
+			storage := &memStorage{objects: map[string][]byte{}}
+			files := &memFileRepo{files: map[primitive.ObjectID]*file.File{}}
+			job := &ExportJob{ID: primitive.NewObjectID(), TenantID: primitive.NewObjectID(), Status: ExportStatusCompleted}
+			s := &ExportServiceImpl{
+				ExportRepo:  &memExportRepo{job: job},
+				FileRepo:    files,
+				FileService: &file.FileServiceImpl{FileRepo: files, Storage: storage, Config: cfg},
+				Config:      cfg,
+			}
+
+			staged := filepath.Join(t.TempDir(), job.ID.Hex()+".csv")
+			if err := os.WriteFile(staged, []byte("name\nAcme\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			artifact := &file.File{OriginalFilename: "deals.csv", MimeType: "text/csv"}
+			if err := s.storeArtifact(context.Background(), staged, artifact); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(staged); (err == nil) != tt.wantStaged {
+				t.Errorf("staged file kept = %v, want %v", err == nil, tt.wantStaged)
+			}
+			if tt.wantStaged == (len(storage.objects) > 0) {
+				t.Errorf("objects in storage = %d", len(storage.objects))
+			}
+			job.FileID = artifact.ID
+
+			link, _ := url.Parse(s.signedDownloadURL(job, time.Now().Add(time.Minute)))
+			q := link.Query()
+			got, body, err := s.ResolveDownload(context.Background(), job.ID.Hex(), q.Get("tenant"), q.Get("expires"), q.Get("signature"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer body.Close()
+			data, _ := io.ReadAll(body)
+			if string(data) != "name\nAcme\n" || got.Size != int64(len(data)) {
+				t.Errorf("downloaded %q (size %d)", data, got.Size)
+			}
+
+			if _, _, err := s.ResolveDownload(context.Background(), job.ID.Hex(), q.Get("tenant"), q.Get("expires"), strings.Repeat("0", 64)); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("tampered link: err = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/common/watermark"
+	"go-crm/pkg/locale"
+
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// rowWriter streams rows into an artifact on disk without buffering the whole
+// export. Each format carries the export's watermark ahead of the rows.
+type rowWriter interface {
+	WriteHeader(columns []string) error
+	WriteRow(record map[string]any) error
+	Close() error
+}
+
+// cellFormatter renders values in the requesting user's locale, using the
+// module's field types to tell currency amounts and dates apart
+type cellFormatter struct {
+	locale     *locale.Formatter
+	fieldTypes map[string]string
+}
+
+func newCellFormatter(f *locale.Formatter, m *common_models.Entity) cellFormatter {
+	if f == nil {
+		f = locale.Default()
+	}
+	types := map[string]string{}
+	if m != nil {
+		for _, field := range m.Fields {
+			types[field.Name] = string(field.Type)
+		}
+	}
+	return cellFormatter{locale: f, fieldTypes: types}
+}
+
+func (c cellFormatter) display(col string, val any) string {
+	return c.locale.Field(c.fieldTypes[col], val)
+}
+
+func newRowWriter(format ExportFormat, path string, cells cellFormatter, mark watermark.Mark) (rowWriter, error) {
+	switch format {
+	case ExportFormatCSV:
+		return newCSVWriter(path, cells, mark)
+	case ExportFormatJSON:
+		return newJSONWriter(path, mark)
+	case ExportFormatXLSX:
+		return newXLSXWriter(path, cells, mark)
+	}
+	return nil, fmt.Errorf("unsupported format: %s", format)
+}
+
+func mimeTypeFor(format ExportFormat) string {
+	switch format {
+	case ExportFormatCSV:
+		return "text/csv"
+	case ExportFormatJSON:
+		return "application/json"
+	case ExportFormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "application/octet-stream"
+}
+
+// --- CSV ---
+
+type csvWriter struct {
+	file    *os.File
+	w       *csv.Writer
+	cells   cellFormatter
+	mark    watermark.Mark
+	columns []string
+}
+
+func newCSVWriter(path string, cells cellFormatter, mark watermark.Mark) (*csvWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &csvWriter{file: f, w: csv.NewWriter(f), cells: cells, mark: mark}, nil
+}
+
+// WriteHeader puts the watermark on a "#" line above the column names
+func (c *csvWriter) WriteHeader(columns []string) error {
+	c.columns = columns
+	if err := c.w.Write([]string{"# " + c.mark.String()}); err != nil {
+		return err
+	}
+	return c.w.Write(columns)
+}
+
+func (c *csvWriter) WriteRow(record map[string]any) error {
+	row := make([]string, len(c.columns))
+	for i, col := range c.columns {
+		row[i] = c.cells.display(col, record[col])
+	}
+	return c.w.Write(row)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		c.file.Close()
+		return err
+	}
+	return c.file.Close()
+}
+
+// --- JSON ({"watermark": {...}, "records": [objects]}) ---
+
+type jsonWriter struct {
+	file    *os.File
+	buf     *bufio.Writer
+	mark    watermark.Mark
+	columns []string
+	count   int
+}
+
+func newJSONWriter(path string, mark watermark.Mark) (*jsonWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonWriter{file: f, buf: bufio.NewWriter(f), mark: mark}, nil
+}
+
+func (j *jsonWriter) WriteHeader(columns []string) error {
+	j.columns = columns
+	mark, err := json.Marshal(j.mark)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(j.buf, "{\"watermark\":%s,\n\"records\":[", mark)
+	return err
+}
+
+func (j *jsonWriter) WriteRow(record map[string]any) error {
+	row := make(map[string]any, len(j.columns))
+	for _, col := range j.columns {
+		row[col] = jsonValue(record[col])
+	}
+	data, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	if j.count > 0 {
+		if _, err := j.buf.WriteString(",\n"); err != nil {
+			return err
+		}
+	}
+	j.count++
+	_, err = j.buf.Write(data)
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	if _, err := j.buf.WriteString("]}\n"); err != nil {
+		j.file.Close()
+		return err
+	}
+	if err := j.buf.Flush(); err != nil {
+		j.file.Close()
+		return err
+	}
+	return j.file.Close()
+}
+
+// --- XLSX (excelize stream writer spills rows to a temp file) ---
+
+type xlsxWriter struct {
+	path    string
+	file    *excelize.File
+	stream  *excelize.StreamWriter
+	cells   cellFormatter
+	mark    watermark.Mark
+	columns []string
+	row     int
+}
+
+func newXLSXWriter(path string, cells cellFormatter, mark watermark.Mark) (*xlsxWriter, error) {
+	f := excelize.NewFile()
+	if err := setWatermarkProps(f, mark); err != nil {
+		f.Close()
+		return nil, err
+	}
+	sw, err := f.NewStreamWriter("Sheet1")
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &xlsxWriter{path: path, file: f, stream: sw, cells: cells, mark: mark, row: 1}, nil
+}
+
+// WriteHeader puts the watermark in the first row, above the column names
+func (x *xlsxWriter) WriteHeader(columns []string) error {
+	x.columns = columns
+	if err := x.nextRow([]interface{}{x.mark.String()}); err != nil {
+		return err
+	}
+	header := make([]interface{}, len(columns))
+	for i, col := range columns {
+		header[i] = col
+	}
+	return x.nextRow(header)
+}
+
+func (x *xlsxWriter) WriteRow(record map[string]any) error {
+	row := make([]interface{}, len(x.columns))
+	for i, col := range x.columns {
+		// Numbers stay numeric so spreadsheets can sum them
+		switch v := record[col].(type) {
+		case float64, int, int32, int64, bool:
+			row[i] = v
+		default:
+			row[i] = x.cells.display(col, v)
+		}
+	}
+	return x.nextRow(row)
+}
+
+func (x *xlsxWriter) nextRow(values []interface{}) error {
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+	x.row++
+	return x.stream.SetRow(cell, values)
+}
+
+func (x *xlsxWriter) Close() error {
+	defer x.file.Close()
+	if err := x.stream.Flush(); err != nil {
+		return err
+	}
+	return x.file.SaveAs(x.path)
+}
+
+// setWatermarkProps records the watermark in the workbook's document
+// properties, which survive the first row being deleted
+func setWatermarkProps(f *excelize.File, mark watermark.Mark) error {
+	return f.SetDocProps(&excelize.DocProperties{
+		Creator:     mark.Author(),
+		Created:     mark.At.Format(time.RFC3339),
+		Description: mark.String(),
+	})
+}
+
+func jsonValue(val any) any {
+	switch v := val.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case primitive.ObjectID:
+		return v.Hex()
+	}
+	return val
+}
@@ -0,0 +1,32 @@
+package feature_flag
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/features/role"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type FeatureFlagApi struct {
+	controller  *FeatureFlagController
+	config      *config.Config
+	roleService role.RoleService
+}
+
+func NewFeatureFlagApi(controller *FeatureFlagController, config *config.Config, roleService role.RoleService) *FeatureFlagApi {
+	return &FeatureFlagApi{
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *FeatureFlagApi) Setup(app *fiber.App) {
+	app.Get("/api/feature-flags", middleware.AuthMiddleware(h.config.SkipAuth), h.controller.ListMyFlags)
+
+	group := app.Group("/api/admin/feature-flags", middleware.AuthMiddleware(h.config.SkipAuth))
+	group.Get("/", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListFlags)
+	group.Put("/:key/tenant", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.SetTenantOverride)
+	group.Put("/:key/users/:userId", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.SetUserOverride)
+}
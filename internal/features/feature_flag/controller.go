@@ -0,0 +1,99 @@
+package feature_flag
+
+import (
+	common_api "go-crm/internal/common/api"
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type FeatureFlagController struct {
+	Service FeatureFlagService
+}
+
+func NewFeatureFlagController(service FeatureFlagService) *FeatureFlagController {
+	return &FeatureFlagController{Service: service}
+}
+
+// OverrideRequest sets or clears an override; a null or missing enabled
+// returns the flag to its rollout
+type OverrideRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// ListFlags godoc
+// @Summary List feature flags
+// @Description Every flag with its rollout and the tenant's overrides. Flags are defined and rolled out by operators; a killed flag is off whatever the overrides.
+// @Tags admin
+// @Produce json
+// @Success 200 {array} FlagState
+// @Router /api/admin/feature-flags [get]
+func (ctrl *FeatureFlagController) ListFlags(c *fiber.Ctx) error {
+	states, err := ctrl.Service.List(c.UserContext())
+	if err != nil {
+		return common_api.Error(c, err)
+	}
+	return c.JSON(fiber.Map{"data": states})
+}
+
+// SetTenantOverride godoc
+// @Summary Override a feature flag for the tenant
+// @Description Turns the flag on or off for every user of the tenant, or with enabled null returns it to the rollout. Takes effect on all instances within 30 seconds.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param key path string true "Flag key"
+// @Param body body OverrideRequest true "Override"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/admin/feature-flags/{key}/tenant [put]
+func (ctrl *FeatureFlagController) SetTenantOverride(c *fiber.Ctx) error {
+	var req OverrideRequest
+	if err := c.BodyParser(&req); err != nil {
+		return common_api.InvalidBody(c, err)
+	}
+	if err := ctrl.Service.SetTenantOverride(c.UserContext(), c.Params("key"), req.Enabled); err != nil {
+		return common_api.Error(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "Override saved"})
+}
+
+// SetUserOverride godoc
+// @Summary Override a feature flag for a user
+// @Description Turns the flag on or off for one user of the tenant, ahead of the tenant override and the rollout; enabled null clears it
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param key path string true "Flag key"
+// @Param userId path string true "User ID"
+// @Param body body OverrideRequest true "Override"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/admin/feature-flags/{key}/users/{userId} [put]
+func (ctrl *FeatureFlagController) SetUserOverride(c *fiber.Ctx) error {
+	var req OverrideRequest
+	if err := c.BodyParser(&req); err != nil {
+		return common_api.InvalidBody(c, err)
+	}
+	if err := ctrl.Service.SetUserOverride(c.UserContext(), c.Params("key"), c.Params("userId"), req.Enabled); err != nil {
+		return common_api.Error(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "Override saved"})
+}
+
+// ListMyFlags godoc
+// @Summary Feature flags enabled for the current user
+// @Description Keys of the flags on for the caller, for clients to gate their own features
+// @Tags auth
+// @Produce json
+// @Success 200 {array} string
+// @Router /api/feature-flags [get]
+func (ctrl *FeatureFlagController) ListMyFlags(c *fiber.Ctx) error {
+	keys := []string{}
+	if rc := session.From(c.UserContext()); rc != nil {
+		if enabled := rc.Features(); enabled != nil {
+			keys = enabled
+		}
+	}
+	return c.JSON(fiber.Map{"data": keys})
+}
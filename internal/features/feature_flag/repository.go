@@ -0,0 +1,114 @@
+package feature_flag
+
+import (
+	"context"
+	"time"
+
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FlagRepository stores the flags of every tenant in one collection; it is
+// not tenant-scoped
+type FlagRepository interface {
+	List(ctx context.Context) ([]Flag, error)
+	FindByKey(ctx context.Context, key string) (*Flag, error)
+	// Save creates or updates a flag's definition and rollout, leaving its
+	// tenant overrides as they are
+	Save(ctx context.Context, flag *Flag) error
+	Delete(ctx context.Context, key string) error
+	// SetOverride sets the override at path under the flag's tenants, or
+	// clears it when value is nil. It returns mongo.ErrNoDocuments for an
+	// unknown flag.
+	SetOverride(ctx context.Context, key, path string, value *bool) error
+	EnsureIndexes(ctx context.Context) error
+}
+
+type FlagRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewFlagRepository(db *database.MongodbDB) FlagRepository {
+	return &FlagRepositoryImpl{
+		collection: db.DB.Collection("feature_flags"),
+	}
+}
+
+func (r *FlagRepositoryImpl) List(ctx context.Context) ([]Flag, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"key": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var flags []Flag
+	if err := cursor.All(ctx, &flags); err != nil {
+		return nil, err
+	}
+	return flags, nil
+}
+
+func (r *FlagRepositoryImpl) FindByKey(ctx context.Context, key string) (*Flag, error) {
+	var flag Flag
+	if err := r.collection.FindOne(ctx, bson.M{"key": key}).Decode(&flag); err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
+
+func (r *FlagRepositoryImpl) Save(ctx context.Context, flag *Flag) error {
+	now := time.Now()
+	flag.UpdatedAt = now
+	update := bson.M{
+		"$set": bson.M{
+			"description":    flag.Description,
+			"killed":         flag.Killed,
+			"tenant_percent": flag.TenantPercent,
+			"user_percent":   flag.UserPercent,
+			"updated_at":     now,
+		},
+		"$setOnInsert": bson.M{"created_at": now},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	return r.collection.FindOneAndUpdate(ctx, bson.M{"key": flag.Key}, update, opts).Decode(flag)
+}
+
+func (r *FlagRepositoryImpl) Delete(ctx context.Context, key string) error {
+	res, err := r.collection.DeleteOne(ctx, bson.M{"key": key})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *FlagRepositoryImpl) SetOverride(ctx context.Context, key, path string, value *bool) error {
+	field := "tenants." + path
+	update := bson.M{"$set": bson.M{"updated_at": time.Now()}}
+	if value == nil {
+		update["$unset"] = bson.M{field: ""}
+	} else {
+		update["$set"].(bson.M)[field] = *value
+	}
+	res, err := r.collection.UpdateOne(ctx, bson.M{"key": key}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *FlagRepositoryImpl) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "key", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
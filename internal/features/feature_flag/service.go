@@ -0,0 +1,248 @@
+package feature_flag
+
+import (
+	"context"
+	"errors"
+	"log"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/common/session"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/user"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RefreshInterval bounds how long a flag change made on another instance,
+// or with the admin CLI, takes to reach this one
+const RefreshInterval = 30 * time.Second
+
+var keyPattern = regexp.MustCompile(`^[a-z][a-z0-9_.-]{0,63}$`)
+
+var (
+	ErrFlagNotFound = apperr.NotFound("feature flag not found")
+	ErrInvalidKey   = apperr.Validation("key must be lower case letters, digits, '.', '_' or '-', starting with a letter")
+	ErrInvalidShare = apperr.Validation("tenant_percent and user_percent must be between 0 and 100")
+	ErrNoTenant     = apperr.BadRequest("tenant context missing")
+)
+
+type FeatureFlagService interface {
+	// Enabled reports whether flag key is on for the user and tenant of ctx.
+	// Unknown flags are off. It reads the in-memory copy, so it is cheap
+	// enough for any request path.
+	Enabled(ctx context.Context, key string) bool
+	// EnabledKeys lists the flags on for a user of a tenant; it backs the
+	// feature flags of the request context
+	EnabledKeys(ctx context.Context, tenantID, userID string) []string
+	// List returns every flag as the tenant of ctx sees it
+	List(ctx context.Context) ([]FlagState, error)
+	// SetTenantOverride forces key on or off for the tenant of ctx; nil
+	// returns it to the rollout
+	SetTenantOverride(ctx context.Context, key string, enabled *bool) error
+	// SetUserOverride forces key on or off for a user of the tenant of ctx
+	SetUserOverride(ctx context.Context, key, userID string, enabled *bool) error
+
+	// All returns the stored flags, for operators
+	All(ctx context.Context) ([]Flag, error)
+	// Save defines a flag or changes its rollout or kill switch, for operators
+	Save(ctx context.Context, flag *Flag) error
+	// Delete removes a flag, for operators. Code still checking it sees it off.
+	Delete(ctx context.Context, key string) error
+
+	// Refresh reloads the flags from the database
+	Refresh(ctx context.Context) error
+	// Run refreshes every RefreshInterval until ctx is done
+	Run(ctx context.Context)
+}
+
+type FeatureFlagServiceImpl struct {
+	Repo         FlagRepository
+	UserRepo     user.UserRepository
+	AuditService audit.AuditService
+
+	mu     sync.RWMutex
+	flags  map[string]*Flag
+	loaded bool
+}
+
+func NewFeatureFlagService(repo FlagRepository, userRepo user.UserRepository, auditService audit.AuditService) FeatureFlagService {
+	return &FeatureFlagServiceImpl{
+		Repo:         repo,
+		UserRepo:     userRepo,
+		AuditService: auditService,
+	}
+}
+
+// snapshot returns the cached flags, loading them on first use
+func (s *FeatureFlagServiceImpl) snapshot(ctx context.Context) map[string]*Flag {
+	s.mu.RLock()
+	flags, loaded := s.flags, s.loaded
+	s.mu.RUnlock()
+	if loaded {
+		return flags
+	}
+	if err := s.Refresh(ctx); err != nil {
+		log.Printf("feature flags: load failed, treating all as off: %v", err)
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.flags
+}
+
+func (s *FeatureFlagServiceImpl) Refresh(ctx context.Context) error {
+	list, err := s.Repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	flags := make(map[string]*Flag, len(list))
+	for i := range list {
+		flags[list[i].Key] = &list[i]
+	}
+	s.mu.Lock()
+	s.flags, s.loaded = flags, true
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *FeatureFlagServiceImpl) Run(ctx context.Context) {
+	ticker := time.NewTicker(RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Refresh(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("feature flags: refresh failed: %v", err)
+			}
+		}
+	}
+}
+
+func (s *FeatureFlagServiceImpl) Enabled(ctx context.Context, key string) bool {
+	f, ok := s.snapshot(ctx)[key]
+	if !ok {
+		return false
+	}
+	tenantID, _ := ctx.Value(common_models.TenantIDKey).(string)
+	userID := ""
+	if id, ok := session.UserID(ctx); ok {
+		userID = id.Hex()
+	}
+	return f.EnabledFor(tenantID, userID)
+}
+
+func (s *FeatureFlagServiceImpl) EnabledKeys(ctx context.Context, tenantID, userID string) []string {
+	var keys []string
+	for key, f := range s.snapshot(ctx) {
+		if f.EnabledFor(tenantID, userID) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *FeatureFlagServiceImpl) List(ctx context.Context) ([]FlagState, error) {
+	tenantID, ok := ctx.Value(common_models.TenantIDKey).(string)
+	if !ok || tenantID == "" {
+		return nil, ErrNoTenant
+	}
+	flags, err := s.Repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	states := make([]FlagState, 0, len(flags))
+	for i := range flags {
+		states = append(states, stateFor(&flags[i], tenantID))
+	}
+	return states, nil
+}
+
+func (s *FeatureFlagServiceImpl) SetTenantOverride(ctx context.Context, key string, enabled *bool) error {
+	tenantID, ok := ctx.Value(common_models.TenantIDKey).(string)
+	if !ok || tenantID == "" {
+		return ErrNoTenant
+	}
+	return s.setOverride(ctx, key, tenantID+".enabled", enabled)
+}
+
+func (s *FeatureFlagServiceImpl) SetUserOverride(ctx context.Context, key, userID string, enabled *bool) error {
+	tenantID, ok := ctx.Value(common_models.TenantIDKey).(string)
+	if !ok || tenantID == "" {
+		return ErrNoTenant
+	}
+	// The lookup is tenant-scoped, so only the tenant's own users can be set
+	if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+		return apperr.BadRequest("invalid user ID")
+	}
+	if _, err := s.UserRepo.FindByID(ctx, userID); err != nil {
+		return apperr.NotFound("user not found")
+	}
+	return s.setOverride(ctx, key, tenantID+".users."+userID, enabled)
+}
+
+func (s *FeatureFlagServiceImpl) setOverride(ctx context.Context, key, path string, enabled *bool) error {
+	if err := s.Repo.SetOverride(ctx, key, path, enabled); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrFlagNotFound
+		}
+		return err
+	}
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "feature_flags", key, map[string]common_models.Change{
+		"tenants." + path: {New: enabled},
+	})
+	return s.Refresh(ctx)
+}
+
+func (s *FeatureFlagServiceImpl) All(ctx context.Context) ([]Flag, error) {
+	return s.Repo.List(ctx)
+}
+
+func (s *FeatureFlagServiceImpl) Save(ctx context.Context, flag *Flag) error {
+	if !keyPattern.MatchString(flag.Key) {
+		return ErrInvalidKey
+	}
+	if flag.TenantPercent < 0 || flag.TenantPercent > 100 || flag.UserPercent < 0 || flag.UserPercent > 100 {
+		return ErrInvalidShare
+	}
+
+	old, err := s.Repo.FindByKey(ctx, flag.Key)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+	if err := s.Repo.Save(ctx, flag); err != nil {
+		return err
+	}
+
+	action, changes := common_models.AuditActionCreate, map[string]common_models.Change{
+		"killed":         {New: flag.Killed},
+		"tenant_percent": {New: flag.TenantPercent},
+		"user_percent":   {New: flag.UserPercent},
+	}
+	if old != nil {
+		action = common_models.AuditActionUpdate
+		changes["killed"] = common_models.Change{Old: old.Killed, New: flag.Killed}
+		changes["tenant_percent"] = common_models.Change{Old: old.TenantPercent, New: flag.TenantPercent}
+		changes["user_percent"] = common_models.Change{Old: old.UserPercent, New: flag.UserPercent}
+	}
+	_ = s.AuditService.LogChange(ctx, action, "feature_flags", flag.Key, changes)
+	return s.Refresh(ctx)
+}
+
+func (s *FeatureFlagServiceImpl) Delete(ctx context.Context, key string) error {
+	if err := s.Repo.Delete(ctx, key); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrFlagNotFound
+		}
+		return err
+	}
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionDelete, "feature_flags", key, nil)
+	return s.Refresh(ctx)
+}
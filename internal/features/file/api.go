@@ -20,6 +20,9 @@ func NewFileApi(controller *FileController, config *config.Config) *FileApi {
 }
 
 func (h *FileApi) Setup(app *fiber.App) {
+	// Signed links authenticate through their signature, not a bearer token
+	app.Get("/api/files/signed", h.controller.DownloadSigned)
+
 	app.Post("/api/upload", middleware.AuthMiddleware(h.config.SkipAuth), h.controller.UploadFile)
 	app.Get("/api/files/:module/:recordId", middleware.AuthMiddleware(h.config.SkipAuth), h.controller.GetFilesByRecord)
 	app.Get("/api/files/shared", middleware.AuthMiddleware(h.config.SkipAuth), h.controller.GetSharedFiles)
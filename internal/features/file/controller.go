@@ -1,16 +1,15 @@
 package file
 
 import (
-	"fmt"
+	"errors"
 	"os"
 	"path/filepath"
-	"strings"
-	"time"
 
+	common_api "go-crm/internal/common/api"
+	"go-crm/internal/common/session"
 	"go-crm/internal/config"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type FileController struct {
@@ -47,9 +46,8 @@ func NewFileController(fileService FileService, cfg *config.Config) *FileControl
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/files/upload [post]
 func (ctrl *FileController) UploadFile(c *fiber.Ctx) error {
-	userIDStr := c.Locals("user_id").(string)
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
+	userID, ok := session.UserID(c.UserContext())
+	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user ID"})
 	}
 
@@ -60,49 +58,29 @@ func (ctrl *FileController) UploadFile(c *fiber.Ctx) error {
 		})
 	}
 
-	moduleName := c.FormValue("module_name")
-	recordID := c.FormValue("record_id")
-	isShared := c.FormValue("is_shared") == "true"
-	description := c.FormValue("description")
-
-	if err := ctrl.FileService.ValidateUpload(c.UserContext(), moduleName, recordID, file.Size, file.Header.Get("Content-Type")); err != nil {
+	src, err := file.Open()
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": err.Error(),
-		})
-	}
-
-	originalName := filepath.Base(file.Filename)
-	timestamp := time.Now().UnixNano()
-	uniqueName := fmt.Sprintf("%d_%s", timestamp, originalName)
-	uniqueName = strings.ReplaceAll(uniqueName, " ", "_")
-
-	dstPath := filepath.Join(ctrl.UploadDir, uniqueName)
-
-	if err := c.SaveFile(file, dstPath); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error saving file to disk",
+			"error": "Error reading file",
 		})
 	}
+	defer src.Close()
 
 	fileRecord := &File{
-		OriginalFilename: originalName,
-		Path:             dstPath,
-		URL:              "/api/files/download/" + uniqueName, // Basic URL, should ideally be configurable
-		Size:             file.Size,
+		OriginalFilename: filepath.Base(file.Filename),
 		MimeType:         file.Header.Get("Content-Type"),
-		ModuleName:       moduleName,
-		RecordID:         recordID,
+		ModuleName:       c.FormValue("module_name"),
+		RecordID:         c.FormValue("record_id"),
 		UploadedBy:       userID,
-		IsShared:         isShared,
-		StorageType:      "local",
-		Description:      description,
-		CreatedAt:        time.Now(),
+		IsShared:         c.FormValue("is_shared") == "true",
+		Description:      c.FormValue("description"),
 	}
 
-	if err := ctrl.FileService.SaveFile(c.UserContext(), fileRecord); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error saving file metadata",
-		})
+	if err := ctrl.FileService.Upload(c.UserContext(), src, file.Size, fileRecord); err != nil {
+		if errors.Is(err, ErrInfectedFile) || errors.Is(err, ErrInvalidImage) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+		}
+		return common_api.Fail(c, fiber.StatusBadRequest, err)
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fileRecord)
@@ -169,7 +147,42 @@ func (ctrl *FileController) DownloadFile(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.Download(file.Path, file.OriginalFilename)
+	if file.StorageType == StorageLocal || file.StorageType == "" {
+		return c.Download(file.Path, file.OriginalFilename)
+	}
+
+	signedURL, err := ctrl.FileService.DownloadURL(c.UserContext(), file)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error generating download link",
+		})
+	}
+	return c.Redirect(signedURL, fiber.StatusFound)
+}
+
+// DownloadSigned godoc
+// @Summary Download file via signed URL
+// @Description Serve a locally stored file using the time-limited link returned in record file fields
+// @Tags files
+// @Param key query string true "Storage key"
+// @Param expires query int true "Expiry (unix seconds)"
+// @Param filename query string false "Download filename"
+// @Param signature query string true "HMAC signature"
+// @Success 200 {file} file "File content"
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/files/signed [get]
+func (ctrl *FileController) DownloadSigned(c *fiber.Ctx) error {
+	filename := c.Query("filename")
+	path, err := ctrl.FileService.ResolveSigned(c.Query("key"), c.Query("expires"), filename, c.Query("signature"))
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if filename == "" {
+		filename = filepath.Base(path)
+	}
+	return c.Download(path, filename)
 }
 
 // DeleteFile godoc
@@ -184,9 +197,8 @@ func (ctrl *FileController) DownloadFile(c *fiber.Ctx) error {
 func (ctrl *FileController) DeleteFile(c *fiber.Ctx) error {
 	fileID := c.Params("id")
 
-	userIDStr := c.Locals("user_id").(string)
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
+	userID, ok := session.UserID(c.UserContext())
+	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user ID"})
 	}
 
@@ -0,0 +1,315 @@
+package file
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"strings"
+)
+
+var ErrInvalidImage = errors.New("invalid image")
+
+// maxImagePixels bounds decoded dimensions so a small compressed upload cannot
+// expand into gigabytes of pixels
+const maxImagePixels = 50_000_000
+
+// processedImage is an upload after metadata stripping plus its thumbnails,
+// keyed by bounding-box size
+type processedImage struct {
+	Data       []byte
+	Thumbnails map[int][]byte
+	ThumbExt   string
+	ThumbMime  string
+}
+
+// IsImageMime reports whether the MIME type is acceptable for an image field
+func IsImageMime(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "image/")
+}
+
+// canProcessImage reports whether the format can be decoded with the standard library
+func canProcessImage(mimeType string) bool {
+	switch mimeType {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	}
+	return false
+}
+
+// processImage validates the image, strips EXIF/text metadata and renders
+// thumbnails that fit within each of sizes
+func processImage(data []byte, mimeType string, sizes []int) (*processedImage, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if "image/"+format != mimeType {
+		return nil, fmt.Errorf("%w: content is %s, not %s", ErrInvalidImage, format, mimeType)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxImagePixels {
+		return nil, fmt.Errorf("%w: dimensions %dx%d not allowed", ErrInvalidImage, cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	out := &processedImage{Thumbnails: map[int][]byte{}, ThumbExt: "png", ThumbMime: "image/png"}
+
+	switch format {
+	case "jpeg":
+		out.ThumbExt, out.ThumbMime = "jpg", "image/jpeg"
+		// Dropping EXIF also drops the orientation tag, so bake it into the pixels
+		if o := jpegOrientation(data); o > 1 {
+			img = orient(toNRGBA(img), o)
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+				return nil, err
+			}
+			out.Data = buf.Bytes()
+		} else {
+			if out.Data, err = stripJPEGMetadata(data); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+			}
+		}
+	case "png":
+		if out.Data, err = stripPNGMetadata(data); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+		}
+	default:
+		// GIF carries no EXIF; re-encoding would lose animation frames
+		out.Data = data
+	}
+
+	src := toNRGBA(img)
+	for _, size := range sizes {
+		thumb := resizeToFit(src, size)
+		var buf bytes.Buffer
+		if out.ThumbMime == "image/jpeg" {
+			err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80})
+		} else {
+			err = png.Encode(&buf, thumb)
+		}
+		if err != nil {
+			return nil, err
+		}
+		out.Thumbnails[size] = buf.Bytes()
+	}
+
+	return out, nil
+}
+
+func toNRGBA(img image.Image) *image.NRGBA {
+	if n, ok := img.(*image.NRGBA); ok && n.Rect.Min == (image.Point{}) {
+		return n
+	}
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
+
+// resizeToFit downsamples with an alpha-weighted box filter so the longest edge
+// is at most size. Images already within bounds are returned unchanged.
+func resizeToFit(src *image.NRGBA, size int) *image.NRGBA {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	if w <= size && h <= size {
+		return src
+	}
+	tw, th := size, size
+	if w >= h {
+		th = max(1, h*size/w)
+	} else {
+		tw = max(1, w*size/h)
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, tw, th))
+	for ty := 0; ty < th; ty++ {
+		y0, y1 := ty*h/th, max((ty+1)*h/th, ty*h/th+1)
+		for tx := 0; tx < tw; tx++ {
+			x0, x1 := tx*w/tw, max((tx+1)*w/tw, tx*w/tw+1)
+
+			var r, g, b, a, n uint64
+			for y := y0; y < y1; y++ {
+				row := src.Pix[y*src.Stride:]
+				for x := x0; x < x1; x++ {
+					p := row[x*4 : x*4+4]
+					pa := uint64(p[3])
+					r += uint64(p[0]) * pa
+					g += uint64(p[1]) * pa
+					b += uint64(p[2]) * pa
+					a += pa
+					n++
+				}
+			}
+
+			o := dst.Pix[ty*dst.Stride+tx*4:]
+			if a > 0 {
+				o[0] = uint8(r / a)
+				o[1] = uint8(g / a)
+				o[2] = uint8(b / a)
+			}
+			o[3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// orient applies an EXIF orientation (2-8) to the pixels
+func orient(src *image.NRGBA, o int) *image.NRGBA {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := w, h
+	if o >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch o {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			default:
+				dx, dy = x, y
+			}
+			copy(dst.Pix[dy*dst.Stride+dx*4:dy*dst.Stride+dx*4+4], src.Pix[y*src.Stride+x*4:y*src.Stride+x*4+4])
+		}
+	}
+	return dst
+}
+
+// jpegOrientation reads the EXIF orientation tag (0x0112) from IFD0, returning 1 when absent
+func jpegOrientation(data []byte) int {
+	for _, seg := range jpegSegments(data) {
+		if seg.marker != 0xE1 || !bytes.HasPrefix(seg.payload, []byte("Exif\x00\x00")) {
+			continue
+		}
+		tiff := seg.payload[6:]
+		if len(tiff) < 8 {
+			return 1
+		}
+		var order binary.ByteOrder
+		switch string(tiff[:2]) {
+		case "II":
+			order = binary.LittleEndian
+		case "MM":
+			order = binary.BigEndian
+		default:
+			return 1
+		}
+		ifd := int(order.Uint32(tiff[4:8]))
+		if ifd+2 > len(tiff) {
+			return 1
+		}
+		count := int(order.Uint16(tiff[ifd:]))
+		for i := 0; i < count; i++ {
+			entry := ifd + 2 + i*12
+			if entry+12 > len(tiff) {
+				return 1
+			}
+			if order.Uint16(tiff[entry:]) == 0x0112 {
+				if v := int(order.Uint16(tiff[entry+8:])); v >= 1 && v <= 8 {
+					return v
+				}
+				return 1
+			}
+		}
+	}
+	return 1
+}
+
+type jpegSegment struct {
+	marker  byte
+	raw     []byte // marker, length and payload as found in the file
+	payload []byte
+}
+
+// jpegSegments splits the header segments preceding the scan data
+func jpegSegments(data []byte) []jpegSegment {
+	var segs []jpegSegment
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil
+	}
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return segs
+		}
+		marker := data[i+1]
+		if marker == 0xDA { // start of scan
+			return segs
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return segs
+		}
+		segs = append(segs, jpegSegment{marker: marker, raw: data[i : i+2+length], payload: data[i+4 : i+2+length]})
+		i += 2 + length
+	}
+	return segs
+}
+
+// stripJPEGMetadata drops APP1 (EXIF/XMP) and APP13 (IPTC) segments without re-encoding
+func stripJPEGMetadata(data []byte) ([]byte, error) {
+	segs := jpegSegments(data)
+	if segs == nil {
+		return nil, errors.New("malformed jpeg")
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+	consumed := 2
+	for _, s := range segs {
+		consumed += len(s.raw)
+		if s.marker == 0xE1 || s.marker == 0xED {
+			continue
+		}
+		out = append(out, s.raw...)
+	}
+	return append(out, data[consumed:]...), nil
+}
+
+// stripPNGMetadata drops eXIf, textual and timestamp chunks; chunk CRCs are
+// per-chunk so the remaining ones need no rewriting
+func stripPNGMetadata(data []byte) ([]byte, error) {
+	const sig = "\x89PNG\r\n\x1a\n"
+	if !bytes.HasPrefix(data, []byte(sig)) {
+		return nil, errors.New("malformed png")
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, sig...)
+	i := len(sig)
+	for i+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, errors.New("malformed png chunk")
+		}
+		switch string(data[i+4 : i+8]) {
+		case "eXIf", "tEXt", "zTXt", "iTXt", "tIME":
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return out, nil
+}
@@ -8,6 +8,7 @@ import (
 
 type File struct {
 	ID               primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID         primitive.ObjectID `json:"-" bson:"tenant_id,omitempty"` // Files stored before quotas were metered have none
 	OriginalFilename string             `json:"original_filename" bson:"original_filename"`
 	URL              string             `json:"url" bson:"url"`
 	Path             string             `json:"path" bson:"path"`
@@ -18,6 +19,8 @@ type File struct {
 	UploadedBy       primitive.ObjectID `json:"uploaded_by" bson:"uploaded_by"`
 	IsShared         bool               `json:"is_shared" bson:"is_shared"`
 	StorageType      string             `json:"storage_type" bson:"storage_type"` // local, s3, etc.
+	StorageKey       string             `json:"-" bson:"storage_key,omitempty"`
+	Thumbnails       map[string]string  `json:"-" bson:"thumbnails,omitempty"` // size (px) -> storage key
 	Description      string             `json:"description,omitempty" bson:"description,omitempty"`
 	CreatedAt        time.Time          `json:"created_at" bson:"created_at"`
 }
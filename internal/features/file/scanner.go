@@ -0,0 +1,97 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"go-crm/internal/config"
+)
+
+var ErrInfectedFile = errors.New("file rejected by virus scanner")
+
+// VirusScanner is called on every upload before it reaches storage
+type VirusScanner interface {
+	Scan(ctx context.Context, r io.Reader) error
+}
+
+// NewVirusScanner returns a clamd scanner when CLAMAV_ADDRESS is set, otherwise a no-op
+func NewVirusScanner(cfg *config.Config) VirusScanner {
+	if cfg.ClamAVAddress == "" {
+		return NoopScanner{}
+	}
+	return &ClamAVScanner{Address: cfg.ClamAVAddress, Timeout: 2 * time.Minute}
+}
+
+type NoopScanner struct{}
+
+func (NoopScanner) Scan(ctx context.Context, r io.Reader) error { return nil }
+
+// ClamAVScanner streams content to clamd using the INSTREAM command
+type ClamAVScanner struct {
+	Address string // host:port
+	Timeout time.Duration
+}
+
+func (s *ClamAVScanner) Scan(ctx context.Context, r io.Reader) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.Address)
+	if err != nil {
+		return fmt.Errorf("virus scanner unavailable: %w", err)
+	}
+	defer conn.Close()
+	if s.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(s.Timeout))
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return fmt.Errorf("virus scanner unavailable: %w", err)
+	}
+
+	buf := make([]byte, 32<<10)
+	size := make([]byte, 4)
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := conn.Write(size); err != nil {
+				return fmt.Errorf("virus scan failed: %w", err)
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return fmt.Errorf("virus scan failed: %w", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+
+	// Zero-length chunk ends the stream
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err := conn.Write(size); err != nil {
+		return fmt.Errorf("virus scan failed: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("virus scan failed: %w", err)
+	}
+	reply = strings.TrimRight(reply, "\x00\n")
+
+	if strings.HasSuffix(reply, "OK") {
+		return nil
+	}
+	if strings.HasSuffix(reply, "FOUND") {
+		return fmt.Errorf("%w: %s", ErrInfectedFile, strings.TrimSpace(strings.TrimPrefix(reply, "stream:")))
+	}
+	return fmt.Errorf("virus scan failed: %s", reply)
+}
@@ -1,10 +1,19 @@
 package file
 
 import (
+	"bytes"
 	"context"
 	"fmt"
-	"os"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
+	"go-crm/internal/common/models"
+	"go-crm/internal/config"
+	"go-crm/internal/features/quota"
 	"go-crm/internal/features/settings"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -17,17 +26,40 @@ type FileService interface {
 	DeleteFile(ctx context.Context, fileID string, userID primitive.ObjectID) error
 	ValidateUpload(ctx context.Context, moduleName string, recordID string, fileSize int64, mimeType string) error
 	SaveFile(ctx context.Context, file *File) error
+	// Upload validates, scans and stores src, then saves the metadata in file
+	Upload(ctx context.Context, src io.ReadSeeker, size int64, file *File) error
+	// StoreGenerated stores a system-produced document (exports, rendered PDFs).
+	// Upload policy checks do not apply since the content is not user supplied.
+	StoreGenerated(ctx context.Context, data []byte, file *File) error
+	// StoreGeneratedStream is StoreGenerated for documents too large to buffer
+	StoreGeneratedStream(ctx context.Context, r io.Reader, size int64, file *File) error
+	// OpenFile streams the stored bytes of a file
+	OpenFile(ctx context.Context, file *File) (io.ReadCloser, error)
+	// DownloadURL returns a time-limited signed URL for the file
+	DownloadURL(ctx context.Context, file *File) (string, error)
+	// ThumbnailURLs returns signed URLs for generated thumbnails keyed by size
+	ThumbnailURLs(ctx context.Context, file *File) (map[string]string, error)
+	// ResolveSigned verifies a local signed URL and returns the path to serve
+	ResolveSigned(key, expires, filename, signature string) (string, error)
 }
 
 type FileServiceImpl struct {
 	FileRepo     FileRepository
 	SettingsRepo settings.SettingsRepository
+	Storage      Storage
+	Scanner      VirusScanner
+	Config       *config.Config
+	Quotas       quota.QuotaService
 }
 
-func NewFileService(fileRepo FileRepository, settingsRepo settings.SettingsRepository) FileService {
+func NewFileService(fileRepo FileRepository, settingsRepo settings.SettingsRepository, storage Storage, scanner VirusScanner, cfg *config.Config, quotas quota.QuotaService) FileService {
 	return &FileServiceImpl{
 		FileRepo:     fileRepo,
 		SettingsRepo: settingsRepo,
+		Storage:      storage,
+		Scanner:      scanner,
+		Config:       cfg,
+		Quotas:       quotas,
 	}
 }
 
@@ -57,13 +89,197 @@ func (s *FileServiceImpl) DeleteFile(ctx context.Context, fileID string, userID
 		return fmt.Errorf("unauthorized: you can only delete your own files")
 	}
 
-	if err := os.Remove(file.Path); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to delete file from disk: %w", err)
+	if err := s.Storage.Delete(ctx, s.storageKey(file)); err != nil {
+		return fmt.Errorf("failed to delete file from storage: %w", err)
+	}
+	for _, thumbKey := range file.Thumbnails {
+		s.Storage.Delete(ctx, thumbKey)
 	}
 
 	return s.FileRepo.Delete(ctx, fileID)
 }
 
+func (s *FileServiceImpl) Upload(ctx context.Context, src io.ReadSeeker, size int64, file *File) error {
+	mimeType, err := sniffMimeType(src, file.MimeType)
+	if err != nil {
+		return err
+	}
+	file.MimeType = mimeType
+
+	if err := s.ValidateUpload(ctx, file.ModuleName, file.RecordID, size, mimeType); err != nil {
+		return err
+	}
+	if s.Quotas != nil {
+		if err := s.Quotas.CheckStorage(ctx, size); err != nil {
+			return err
+		}
+	}
+
+	if err := s.Scanner.Scan(ctx, src); err != nil {
+		return err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	var img *processedImage
+	if canProcessImage(mimeType) {
+		data, err := io.ReadAll(src)
+		if err != nil {
+			return err
+		}
+		if img, err = processImage(data, mimeType, s.Config.ThumbnailSizes); err != nil {
+			return err
+		}
+		src = bytes.NewReader(img.Data)
+		size = int64(len(img.Data))
+	}
+
+	key := newStorageKey(file.OriginalFilename)
+	if err := s.Storage.Put(ctx, key, src, size, mimeType); err != nil {
+		return fmt.Errorf("failed to store file: %w", err)
+	}
+
+	if img != nil && len(img.Thumbnails) > 0 {
+		file.Thumbnails = make(map[string]string, len(img.Thumbnails))
+		for px, data := range img.Thumbnails {
+			thumbKey := fmt.Sprintf("%s.thumb_%d.%s", key, px, img.ThumbExt)
+			if err := s.Storage.Put(ctx, thumbKey, bytes.NewReader(data), int64(len(data)), img.ThumbMime); err != nil {
+				s.deleteObjects(ctx, key, file.Thumbnails)
+				return fmt.Errorf("failed to store thumbnail: %w", err)
+			}
+			file.Thumbnails[strconv.Itoa(px)] = thumbKey
+		}
+	}
+
+	if err := s.saveMetadata(ctx, file, key, size); err != nil {
+		s.deleteObjects(ctx, key, file.Thumbnails)
+		return err
+	}
+	return nil
+}
+
+func (s *FileServiceImpl) StoreGenerated(ctx context.Context, data []byte, file *File) error {
+	return s.StoreGeneratedStream(ctx, bytes.NewReader(data), int64(len(data)), file)
+}
+
+func (s *FileServiceImpl) StoreGeneratedStream(ctx context.Context, r io.Reader, size int64, file *File) error {
+	key := newStorageKey(file.OriginalFilename)
+	if err := s.Storage.Put(ctx, key, r, size, file.MimeType); err != nil {
+		return fmt.Errorf("failed to store file: %w", err)
+	}
+
+	if err := s.saveMetadata(ctx, file, key, size); err != nil {
+		s.Storage.Delete(ctx, key)
+		return err
+	}
+	return nil
+}
+
+func (s *FileServiceImpl) saveMetadata(ctx context.Context, file *File, key string, size int64) error {
+	file.StorageKey = key
+	file.StorageType = s.Storage.Name()
+	file.Size = size
+	if tenantID, ok := ctx.Value(models.TenantIDKey).(string); ok && file.TenantID.IsZero() {
+		file.TenantID, _ = primitive.ObjectIDFromHex(tenantID)
+	}
+	if local, ok := s.Storage.(*LocalStorage); ok {
+		file.Path, _ = local.Path(key)
+	}
+	if file.ID.IsZero() {
+		file.ID = primitive.NewObjectID()
+	}
+	file.URL = "/api/files/" + file.ID.Hex() + "/download"
+	if file.CreatedAt.IsZero() {
+		file.CreatedAt = time.Now()
+	}
+	return s.FileRepo.Save(ctx, file)
+}
+
+// newStorageKey namespaces objects by month so local directories stay small
+func newStorageKey(filename string) string {
+	name := strings.ReplaceAll(filepath.Base(filename), " ", "_")
+	now := time.Now()
+	return fmt.Sprintf("%s/%d_%s", now.Format("2006/01"), now.UnixNano(), name)
+}
+
+func (s *FileServiceImpl) deleteObjects(ctx context.Context, key string, thumbnails map[string]string) {
+	s.Storage.Delete(ctx, key)
+	for _, thumbKey := range thumbnails {
+		s.Storage.Delete(ctx, thumbKey)
+	}
+}
+
+func (s *FileServiceImpl) OpenFile(ctx context.Context, file *File) (io.ReadCloser, error) {
+	return s.Storage.Open(ctx, s.storageKey(file))
+}
+
+func (s *FileServiceImpl) DownloadURL(ctx context.Context, file *File) (string, error) {
+	return s.Storage.SignedURL(ctx, s.storageKey(file), s.signedURLTTL(), file.OriginalFilename)
+}
+
+func (s *FileServiceImpl) signedURLTTL() time.Duration {
+	if s.Config.SignedURLTTLMinutes <= 0 {
+		return 15 * time.Minute
+	}
+	return time.Duration(s.Config.SignedURLTTLMinutes) * time.Minute
+}
+
+func (s *FileServiceImpl) ThumbnailURLs(ctx context.Context, file *File) (map[string]string, error) {
+	urls := make(map[string]string, len(file.Thumbnails))
+	for px, thumbKey := range file.Thumbnails {
+		signed, err := s.Storage.SignedURL(ctx, thumbKey, s.signedURLTTL(), "")
+		if err != nil {
+			return nil, err
+		}
+		urls[px] = signed
+	}
+	return urls, nil
+}
+
+func (s *FileServiceImpl) ResolveSigned(key, expires, filename, signature string) (string, error) {
+	local, ok := s.Storage.(*LocalStorage)
+	if !ok {
+		return "", ErrInvalidSignedURL
+	}
+	return local.Verify(key, expires, filename, signature)
+}
+
+// storageKey falls back to the on-disk name for files uploaded before
+// pluggable storage, which were written flat into FSPath
+func (s *FileServiceImpl) storageKey(file *File) string {
+	if file.StorageKey != "" {
+		return file.StorageKey
+	}
+	return filepath.Base(file.Path)
+}
+
+// sniffMimeType detects the content type from the first 512 bytes and rewinds
+// src. The client-declared type is only kept when detection is inconclusive,
+// e.g. Office documents which sniff as zip archives.
+func sniffMimeType(src io.ReadSeeker, declared string) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	detected := http.DetectContentType(head[:n])
+	if i := strings.Index(detected, ";"); i >= 0 {
+		detected = detected[:i]
+	}
+	switch detected {
+	case "application/octet-stream", "application/zip", "text/plain":
+		if declared != "" {
+			return declared, nil
+		}
+	}
+	return detected, nil
+}
+
 func (s *FileServiceImpl) ValidateUpload(ctx context.Context, moduleName string, recordID string, fileSize int64, mimeType string) error {
 	settingsObj, err := s.SettingsRepo.GetByType(ctx, settings.SettingsTypeFileSharing)
 	if err != nil {
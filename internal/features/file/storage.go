@@ -0,0 +1,163 @@
+package file
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-crm/internal/config"
+)
+
+const (
+	StorageLocal = "local"
+	StorageS3    = "s3"
+	StorageGCS   = "gcs"
+	StorageAzure = "azure"
+)
+
+var ErrInvalidSignedURL = errors.New("invalid or expired signed URL")
+
+// Storage abstracts where file bytes live. Metadata stays in FileRepository;
+// File.StorageKey addresses the object inside the backend.
+type Storage interface {
+	Name() string
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a time-limited download URL; filename sets the
+	// Content-Disposition of the response where the backend supports it
+	SignedURL(ctx context.Context, key string, ttl time.Duration, filename string) (string, error)
+}
+
+// NewStorage builds the backend selected by STORAGE_BACKEND
+func NewStorage(cfg *config.Config) (Storage, error) {
+	switch strings.ToLower(cfg.StorageBackend) {
+	case "", StorageLocal:
+		return NewLocalStorage(cfg.FSPath, cfg.JWTSecret), nil
+	case StorageS3:
+		return NewS3Storage(cfg)
+	case StorageGCS:
+		return NewGCSStorage(cfg)
+	case StorageAzure:
+		return NewAzureStorage(cfg)
+	}
+	return nil, fmt.Errorf("unknown storage backend: %s", cfg.StorageBackend)
+}
+
+// LocalStorage keeps files under FSPath. Signed URLs point at
+// /api/files/signed, which verifies an HMAC instead of a bearer token.
+type LocalStorage struct {
+	root   string
+	secret []byte
+}
+
+func NewLocalStorage(root, secret string) *LocalStorage {
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		os.MkdirAll(root, 0755)
+	}
+	return &LocalStorage{root: root, secret: []byte(secret)}
+}
+
+func (s *LocalStorage) Name() string { return StorageLocal }
+
+// Path resolves a key to a location under root, rejecting traversal
+func (s *LocalStorage) Path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" {
+		return "", fmt.Errorf("invalid storage key")
+	}
+	return filepath.Join(s.root, clean), nil
+}
+
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	path, err := s.Path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	dst, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, r); err != nil {
+		dst.Close()
+		os.Remove(path)
+		return err
+	}
+	return dst.Close()
+}
+
+func (s *LocalStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.Path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	path, err := s.Path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStorage) SignedURL(ctx context.Context, key string, ttl time.Duration, filename string) (string, error) {
+	expires := time.Now().Add(ttl).Unix()
+	q := url.Values{}
+	q.Set("key", key)
+	q.Set("expires", strconv.FormatInt(expires, 10))
+	if filename != "" {
+		q.Set("filename", filename)
+	}
+	q.Set("signature", s.sign(key, expires, filename))
+	return "/api/files/signed?" + q.Encode(), nil
+}
+
+// Verify checks a signed URL's parameters and returns the file path to serve
+func (s *LocalStorage) Verify(key, expires, filename, signature string) (string, error) {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return "", ErrInvalidSignedURL
+	}
+	if !hmac.Equal([]byte(s.sign(key, exp, filename)), []byte(signature)) {
+		return "", ErrInvalidSignedURL
+	}
+	return s.Path(key)
+}
+
+func (s *LocalStorage) sign(key string, expires int64, filename string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "file:%s:%d:%s", key, expires, filename)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// contentDisposition builds an attachment header value safe for non-ASCII names
+func contentDisposition(filename string) string {
+	if filename == "" {
+		return ""
+	}
+	ascii := strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, filename)
+	return fmt.Sprintf("attachment; filename=\"%s\"; filename*=UTF-8''%s", ascii, url.PathEscape(filename))
+}
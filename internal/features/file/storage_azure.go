@@ -0,0 +1,112 @@
+package file
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go-crm/internal/config"
+)
+
+const azureSASVersion = "2020-02-10"
+
+// AzureStorage authorizes blob operations with service SAS tokens signed by the account key
+type AzureStorage struct {
+	account   string
+	container string
+	key       []byte
+}
+
+func NewAzureStorage(cfg *config.Config) (*AzureStorage, error) {
+	if cfg.AzureAccount == "" || cfg.AzureAccountKey == "" || cfg.AzureContainer == "" {
+		return nil, fmt.Errorf("azure storage requires AZURE_STORAGE_ACCOUNT, AZURE_STORAGE_KEY and AZURE_STORAGE_CONTAINER")
+	}
+	key, err := base64.StdEncoding.DecodeString(cfg.AzureAccountKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AZURE_STORAGE_KEY: %w", err)
+	}
+	return &AzureStorage{account: cfg.AzureAccount, container: cfg.AzureContainer, key: key}, nil
+}
+
+func (s *AzureStorage) Name() string { return StorageAzure }
+
+func (s *AzureStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	signed := s.sasURL(key, "cw", 15*time.Minute, "")
+	resp, err := doPresigned(ctx, http.MethodPut, signed, r, size, map[string]string{
+		"Content-Type":   contentType,
+		"x-ms-blob-type": "BlockBlob",
+		"x-ms-version":   azureSASVersion,
+	})
+	if err != nil {
+		return err
+	}
+	return drain(resp)
+}
+
+func (s *AzureStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := doPresigned(ctx, http.MethodGet, s.sasURL(key, "r", 15*time.Minute, ""), nil, 0, map[string]string{"x-ms-version": azureSASVersion})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *AzureStorage) Delete(ctx context.Context, key string) error {
+	resp, err := doPresigned(ctx, http.MethodDelete, s.sasURL(key, "d", 15*time.Minute, ""), nil, 0, map[string]string{"x-ms-version": azureSASVersion})
+	if err != nil {
+		return err
+	}
+	return drain(resp)
+}
+
+func (s *AzureStorage) SignedURL(ctx context.Context, key string, ttl time.Duration, filename string) (string, error) {
+	return s.sasURL(key, "r", ttl, contentDisposition(filename)), nil
+}
+
+// sasURL builds a blob service SAS (sr=b) for the given permissions
+func (s *AzureStorage) sasURL(key, permissions string, ttl time.Duration, disposition string) string {
+	expiry := time.Now().UTC().Add(ttl).Format("2006-01-02T15:04:05Z")
+	canonicalResource := fmt.Sprintf("/blob/%s/%s/%s", s.account, s.container, key)
+
+	// Field order is fixed by the SAS spec for this version; unused fields stay empty
+	stringToSign := strings.Join([]string{
+		permissions,       // sp
+		"",                // st
+		expiry,            // se
+		canonicalResource, // canonicalized resource
+		"",                // si
+		"",                // sip
+		"https",           // spr
+		azureSASVersion,   // sv
+		"b",               // sr
+		"",                // snapshot time
+		"",                // rscc
+		disposition,       // rscd
+		"",                // rsce
+		"",                // rscl
+		"",                // rsct
+	}, "\n")
+
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(stringToSign))
+
+	q := url.Values{}
+	q.Set("sp", permissions)
+	q.Set("se", expiry)
+	q.Set("spr", "https")
+	q.Set("sv", azureSASVersion)
+	q.Set("sr", "b")
+	if disposition != "" {
+		q.Set("rscd", disposition)
+	}
+	q.Set("sig", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+
+	return fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s?%s", s.account, s.container, uriEncode(key, false), q.Encode())
+}
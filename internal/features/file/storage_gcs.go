@@ -0,0 +1,158 @@
+package file
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-crm/internal/config"
+)
+
+const gcsHost = "storage.googleapis.com"
+
+// GCSStorage signs requests with GCS V4 signatures using a service account key
+type GCSStorage struct {
+	bucket      string
+	clientEmail string
+	privateKey  *rsa.PrivateKey
+}
+
+func NewGCSStorage(cfg *config.Config) (*GCSStorage, error) {
+	if cfg.GCSBucket == "" || cfg.GCSCredentialsFile == "" {
+		return nil, fmt.Errorf("gcs storage requires GCS_BUCKET and GCS_CREDENTIALS_FILE")
+	}
+
+	raw, err := os.ReadFile(cfg.GCSCredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GCS credentials: %w", err)
+	}
+	var creds struct {
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+	}
+	if err := json.Unmarshal(raw, &creds); err != nil {
+		return nil, fmt.Errorf("invalid GCS credentials: %w", err)
+	}
+
+	block, _ := pem.Decode([]byte(creds.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("invalid GCS credentials: private_key is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if pkcs1, err1 := x509.ParsePKCS1PrivateKey(block.Bytes); err1 == nil {
+			parsed = pkcs1
+		} else {
+			return nil, fmt.Errorf("invalid GCS private key: %w", err)
+		}
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid GCS private key: expected RSA")
+	}
+
+	return &GCSStorage{bucket: cfg.GCSBucket, clientEmail: creds.ClientEmail, privateKey: key}, nil
+}
+
+func (s *GCSStorage) Name() string { return StorageGCS }
+
+func (s *GCSStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	signed, err := s.presign(http.MethodPut, key, 15*time.Minute, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := doPresigned(ctx, http.MethodPut, signed, r, size, map[string]string{"Content-Type": contentType})
+	if err != nil {
+		return err
+	}
+	return drain(resp)
+}
+
+func (s *GCSStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	signed, err := s.presign(http.MethodGet, key, 15*time.Minute, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := doPresigned(ctx, http.MethodGet, signed, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *GCSStorage) Delete(ctx context.Context, key string) error {
+	signed, err := s.presign(http.MethodDelete, key, 15*time.Minute, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := doPresigned(ctx, http.MethodDelete, signed, nil, 0, nil)
+	if err != nil {
+		return err
+	}
+	return drain(resp)
+}
+
+func (s *GCSStorage) SignedURL(ctx context.Context, key string, ttl time.Duration, filename string) (string, error) {
+	extra := url.Values{}
+	if cd := contentDisposition(filename); cd != "" {
+		extra.Set("response-content-disposition", cd)
+	}
+	return s.presign(http.MethodGet, key, ttl, extra)
+}
+
+func (s *GCSStorage) presign(method, key string, ttl time.Duration, extra url.Values) (string, error) {
+	now := time.Now().UTC()
+	googDate := now.Format("20060102T150405Z")
+	scope := now.Format("20060102") + "/auto/storage/goog4_request"
+	path := "/" + uriEncode(s.bucket, true) + "/" + uriEncode(key, false)
+
+	q := url.Values{}
+	for k, v := range extra {
+		q[k] = v
+	}
+	q.Set("X-Goog-Algorithm", "GOOG4-RSA-SHA256")
+	q.Set("X-Goog-Credential", s.clientEmail+"/"+scope)
+	q.Set("X-Goog-Date", googDate)
+	q.Set("X-Goog-Expires", strconv.Itoa(int(ttl.Seconds())))
+	q.Set("X-Goog-SignedHeaders", "host")
+
+	query := canonicalQuery(q)
+	canonicalRequest := strings.Join([]string{
+		method,
+		path,
+		query,
+		"host:" + gcsHost + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"GOOG4-RSA-SHA256",
+		googDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	digest := sha256.Sum256([]byte(stringToSign))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("https://%s%s?%s&X-Goog-Signature=%s", gcsHost, path, query, hex.EncodeToString(sig)), nil
+}
@@ -0,0 +1,160 @@
+package file
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-crm/internal/config"
+)
+
+// S3Storage signs requests with AWS Signature V4 (query-string auth). Setting
+// S3_ENDPOINT switches to path-style addressing for S3-compatible stores.
+type S3Storage struct {
+	bucket       string
+	region       string
+	endpoint     string // scheme://host, without bucket
+	pathStyle    bool
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+func NewS3Storage(cfg *config.Config) (*S3Storage, error) {
+	if cfg.S3Bucket == "" || cfg.S3AccessKeyID == "" || cfg.S3SecretAccessKey == "" {
+		return nil, fmt.Errorf("s3 storage requires S3_BUCKET, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	s := &S3Storage{
+		bucket:       cfg.S3Bucket,
+		region:       cfg.S3Region,
+		accessKey:    cfg.S3AccessKeyID,
+		secretKey:    cfg.S3SecretAccessKey,
+		sessionToken: cfg.S3SessionToken,
+	}
+	if cfg.S3Endpoint != "" {
+		s.endpoint = strings.TrimRight(cfg.S3Endpoint, "/")
+		s.pathStyle = true
+	} else {
+		s.endpoint = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", cfg.S3Bucket, cfg.S3Region)
+	}
+	return s, nil
+}
+
+func (s *S3Storage) Name() string { return StorageS3 }
+
+func (s *S3Storage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	signed, err := s.presign(http.MethodPut, key, 15*time.Minute, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := doPresigned(ctx, http.MethodPut, signed, r, size, map[string]string{"Content-Type": contentType})
+	if err != nil {
+		return err
+	}
+	return drain(resp)
+}
+
+func (s *S3Storage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	signed, err := s.presign(http.MethodGet, key, 15*time.Minute, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := doPresigned(ctx, http.MethodGet, signed, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	signed, err := s.presign(http.MethodDelete, key, 15*time.Minute, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := doPresigned(ctx, http.MethodDelete, signed, nil, 0, nil)
+	if err != nil {
+		return err
+	}
+	return drain(resp)
+}
+
+func (s *S3Storage) SignedURL(ctx context.Context, key string, ttl time.Duration, filename string) (string, error) {
+	extra := url.Values{}
+	if cd := contentDisposition(filename); cd != "" {
+		extra.Set("response-content-disposition", cd)
+	}
+	return s.presign(http.MethodGet, key, ttl, extra)
+}
+
+func (s *S3Storage) presign(method, key string, ttl time.Duration, extra url.Values) (string, error) {
+	return s.presignAt(time.Now().UTC(), method, key, ttl, extra)
+}
+
+func (s *S3Storage) presignAt(now time.Time, method, key string, ttl time.Duration, extra url.Values) (string, error) {
+	base, err := url.Parse(s.endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	path := "/" + uriEncode(key, false)
+	if s.pathStyle {
+		path = "/" + uriEncode(s.bucket, true) + path
+	}
+
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, s.region)
+
+	q := url.Values{}
+	for k, v := range extra {
+		q[k] = v
+	}
+	q.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	q.Set("X-Amz-Credential", s.accessKey+"/"+scope)
+	q.Set("X-Amz-Date", amzDate)
+	q.Set("X-Amz-Expires", strconv.Itoa(int(ttl.Seconds())))
+	q.Set("X-Amz-SignedHeaders", "host")
+	if s.sessionToken != "" {
+		q.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	query := canonicalQuery(q)
+	canonicalRequest := strings.Join([]string{
+		method,
+		path,
+		query,
+		"host:" + base.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	signingKey = hmacSHA256(signingKey, s.region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	return fmt.Sprintf("%s://%s%s?%s&X-Amz-Signature=%s", base.Scheme, base.Host, path, query, signature), nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,33 @@
+package follow
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type FollowApi struct {
+	controller *FollowController
+	config     *config.Config
+}
+
+func NewFollowApi(controller *FollowController, config *config.Config) *FollowApi {
+	return &FollowApi{
+		controller: controller,
+		config:     config,
+	}
+}
+
+func (h *FollowApi) Setup(app *fiber.App) {
+	follows := app.Group("/api/follows", middleware.AuthMiddleware(h.config.SkipAuth))
+	follows.Get("/", h.controller.ListFollowing)
+	follows.Get("/preferences", h.controller.GetPreferences)
+	follows.Put("/preferences", h.controller.UpdatePreferences)
+
+	records := app.Group("/api/modules", middleware.AuthMiddleware(h.config.SkipAuth))
+	records.Get("/:module/records/:id/follow", h.controller.GetStatus)
+	records.Put("/:module/records/:id/follow", h.controller.Follow)
+	records.Delete("/:module/records/:id/follow", h.controller.Unfollow)
+	records.Get("/:module/records/:id/followers", h.controller.ListFollowers)
+}
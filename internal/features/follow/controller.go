@@ -0,0 +1,190 @@
+package follow
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"go-crm/internal/common/session"
+)
+
+type FollowController struct {
+	Service FollowService
+}
+
+func NewFollowController(service FollowService) *FollowController {
+	return &FollowController{Service: service}
+}
+
+// Follow godoc
+// @Summary Follow record
+// @Description Follow a record, or change the notification level of an existing follow (all or status)
+// @Tags follows
+// @Accept json
+// @Produce json
+// @Param module path string true "Module Name"
+// @Param id path string true "Record ID"
+// @Param request body FollowRequest false "Notification level"
+// @Success 200 {object} FollowStatus
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/modules/{module}/records/{id}/follow [put]
+func (c *FollowController) Follow(ctx *fiber.Ctx) error {
+	var req FollowRequest
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&req); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	status, err := c.Service.Follow(ctx.UserContext(), ctx.Params("module"), ctx.Params("id"), req.Level, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(status)
+}
+
+// Unfollow godoc
+// @Summary Unfollow record
+// @Description Stop change notifications for a record, including ones followed automatically
+// @Tags follows
+// @Param module path string true "Module Name"
+// @Param id path string true "Record ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/modules/{module}/records/{id}/follow [delete]
+func (c *FollowController) Unfollow(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if err := c.Service.Unfollow(ctx.UserContext(), ctx.Params("module"), ctx.Params("id"), userID); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Unfollowed"})
+}
+
+// GetStatus godoc
+// @Summary Get follow status
+// @Description Whether the current user follows the record and at which level
+// @Tags follows
+// @Produce json
+// @Param module path string true "Module Name"
+// @Param id path string true "Record ID"
+// @Success 200 {object} FollowStatus
+// @Router /api/modules/{module}/records/{id}/follow [get]
+func (c *FollowController) GetStatus(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	status, err := c.Service.GetStatus(ctx.UserContext(), ctx.Params("module"), ctx.Params("id"), userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(status)
+}
+
+// ListFollowers godoc
+// @Summary List record followers
+// @Tags follows
+// @Produce json
+// @Param module path string true "Module Name"
+// @Param id path string true "Record ID"
+// @Success 200 {array} Follow
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/modules/{module}/records/{id}/followers [get]
+func (c *FollowController) ListFollowers(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	follows, err := c.Service.ListFollowers(ctx.UserContext(), ctx.Params("module"), ctx.Params("id"), userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(follows)
+}
+
+// ListFollowing godoc
+// @Summary List followed records
+// @Description Records the current user follows, optionally filtered by module
+// @Tags follows
+// @Produce json
+// @Param module query string false "Filter by module"
+// @Success 200 {array} Follow
+// @Router /api/follows [get]
+func (c *FollowController) ListFollowing(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	follows, err := c.Service.ListFollowing(ctx.UserContext(), ctx.Query("module"), userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(follows)
+}
+
+// GetPreferences godoc
+// @Summary Get follow preferences
+// @Tags follows
+// @Produce json
+// @Success 200 {object} Preferences
+// @Router /api/follows/preferences [get]
+func (c *FollowController) GetPreferences(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	prefs, err := c.Service.GetPreferences(ctx.UserContext(), userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(prefs)
+}
+
+// UpdatePreferences godoc
+// @Summary Update follow preferences
+// @Description Default notification level for new follows, whether owned/assigned records are followed automatically, assignment emails and the daily ownership digest. Omitted fields are unchanged.
+// @Tags follows
+// @Accept json
+// @Produce json
+// @Param preferences body Preferences true "Preferences"
+// @Success 200 {object} Preferences
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/follows/preferences [put]
+func (c *FollowController) UpdatePreferences(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	// Fields missing from the body keep their current values
+	prefs, err := c.Service.GetPreferences(ctx.UserContext(), userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := ctx.BodyParser(prefs); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := c.Service.UpdatePreferences(ctx.UserContext(), prefs, userID); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(prefs)
+}
@@ -0,0 +1,82 @@
+package follow
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Level controls which changes on a followed record notify the follower
+type Level string
+
+const (
+	LevelAll    Level = "all"    // every field change
+	LevelStatus Level = "status" // status/stage changes only
+	LevelNone   Level = "none"   // unfollowed; kept so auto-follow does not re-subscribe
+)
+
+// Source records why the user follows the record
+type Source string
+
+const (
+	SourceManual   Source = "manual"
+	SourceOwner    Source = "owner"
+	SourceAssigned Source = "assigned"
+)
+
+type Follow struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID   primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	UserID     primitive.ObjectID `json:"user_id" bson:"user_id"`
+	ModuleName string             `json:"module_name" bson:"module_name"`
+	RecordID   string             `json:"record_id" bson:"record_id"`
+	Level      Level              `json:"level" bson:"level"`
+	Source     Source             `json:"source" bson:"source"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// Preferences are a user's defaults for new follows and ownership notices
+type Preferences struct {
+	ID                 primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID           primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	UserID             primitive.ObjectID `json:"user_id" bson:"user_id"`
+	DefaultLevel       Level              `json:"default_level" bson:"default_level"`
+	AutoFollowOwned    bool               `json:"auto_follow_owned" bson:"auto_follow_owned"`
+	AutoFollowAssigned bool               `json:"auto_follow_assigned" bson:"auto_follow_assigned"`
+	// EmailOnAssignment also emails the user when a record is assigned to them
+	EmailOnAssignment bool `json:"email_on_assignment" bson:"email_on_assignment"`
+	// DailyDigest sends the daily summary of ownership changes
+	DailyDigest bool      `json:"daily_digest" bson:"daily_digest"`
+	UpdatedAt   time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+func DefaultPreferences(userID primitive.ObjectID) *Preferences {
+	return &Preferences{UserID: userID, DefaultLevel: LevelAll, AutoFollowOwned: true, AutoFollowAssigned: true, DailyDigest: true}
+}
+
+type FollowRequest struct {
+	Level Level `json:"level"`
+}
+
+// FollowStatus is returned for the current user on a record
+type FollowStatus struct {
+	Following bool   `json:"following"`
+	Level     Level  `json:"level"`
+	Source    Source `json:"source,omitempty"`
+}
+
+// OwnershipChange is kept for the daily digest when a record changes owner
+type OwnershipChange struct {
+	ID            primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	TenantID      primitive.ObjectID  `json:"tenant_id" bson:"tenant_id"`
+	ModuleName    string              `json:"module_name" bson:"module_name"`
+	ModuleLabel   string              `json:"module_label" bson:"module_label"`
+	RecordID      string              `json:"record_id" bson:"record_id"`
+	RecordName    string              `json:"record_name" bson:"record_name"`
+	NewOwner      primitive.ObjectID  `json:"new_owner" bson:"new_owner"`
+	PreviousOwner *primitive.ObjectID `json:"previous_owner,omitempty" bson:"previous_owner,omitempty"`
+	ChangedBy     primitive.ObjectID  `json:"changed_by" bson:"changed_by"`
+	CreatedAt     time.Time           `json:"created_at" bson:"created_at"`
+	DigestedAt    *time.Time          `json:"digested_at,omitempty" bson:"digested_at,omitempty"`
+}
@@ -0,0 +1,304 @@
+package follow
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/email"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/notification"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/role"
+	"go-crm/internal/features/user"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const maxSummaryFields = 5
+
+// ChangeNotifier auto-follows owners and assignees, tells new owners about
+// their records and tells followers what changed. It is the
+// record.ChangeListener; it does not depend on RecordService so the two can
+// be wired without a cycle.
+type ChangeNotifier struct {
+	Repo                FollowRepository
+	PrefsRepo           PreferencesRepository
+	OwnershipRepo       OwnershipRepository
+	ModuleRepo          module.ModuleRepository
+	UserRepo            user.UserRepository
+	RoleService         role.RoleService
+	NotificationService notification.NotificationService
+	EmailService        email.EmailService
+}
+
+func NewChangeNotifier(
+	repo FollowRepository,
+	prefsRepo PreferencesRepository,
+	ownershipRepo OwnershipRepository,
+	moduleRepo module.ModuleRepository,
+	userRepo user.UserRepository,
+	roleService role.RoleService,
+	notificationService notification.NotificationService,
+	emailService email.EmailService,
+) *ChangeNotifier {
+	return &ChangeNotifier{
+		Repo:                repo,
+		PrefsRepo:           prefsRepo,
+		OwnershipRepo:       ownershipRepo,
+		ModuleRepo:          moduleRepo,
+		UserRepo:            userRepo,
+		RoleService:         roleService,
+		NotificationService: notificationService,
+		EmailService:        emailService,
+	}
+}
+
+func (n *ChangeNotifier) RecordChanged(ctx context.Context, change record.RecordChange) {
+	mod, err := n.ModuleRepo.FindByName(ctx, change.ModuleName)
+	if err != nil || mod == nil {
+		return
+	}
+
+	n.autoFollow(ctx, mod, change)
+	n.ownerChanged(ctx, mod, change)
+	if change.Created {
+		return
+	}
+
+	changed := changedFields(change.Changes)
+	if len(changed) == 0 {
+		return
+	}
+	followers, err := n.Repo.ListFollowers(ctx, change.ModuleName, change.RecordID)
+	if err != nil {
+		log.Printf("follow: failed to load followers for %s/%s: %v", change.ModuleName, change.RecordID, err)
+		return
+	}
+
+	actor := n.userName(ctx, change.ActorID)
+	title := fmt.Sprintf("%s updated: %s", moduleLabel(mod), recordName(change.Record, change.RecordID))
+	link := fmt.Sprintf("/dashboard/modules/%s/%s", change.ModuleName, change.RecordID)
+
+	for _, f := range followers {
+		if f.UserID == change.ActorID {
+			continue
+		}
+
+		// Followers only hear about fields they are allowed to see
+		visible := changed
+		if perms, err := n.RoleService.GetFieldPermissions(ctx, f.UserID, change.ModuleName); err == nil && perms != nil {
+			visible = make([]string, 0, len(changed))
+			for _, name := range changed {
+				if perms[name] != role.FieldPermNone {
+					visible = append(visible, name)
+				}
+			}
+		}
+		if f.Level == LevelStatus {
+			statusOnly := make([]string, 0, len(visible))
+			for _, name := range visible {
+				if isStatusField(name) {
+					statusOnly = append(statusOnly, name)
+				}
+			}
+			visible = statusOnly
+		}
+		if len(visible) == 0 {
+			continue
+		}
+
+		message := fmt.Sprintf("%s changed %s", actor, summarize(mod, visible, change.Changes))
+		_ = n.NotificationService.CreateNotification(ctx, f.UserID, title, message, notification.NotificationTypeInfo, link)
+	}
+}
+
+// autoFollow subscribes the owner and users referenced by assignment fields
+func (n *ChangeNotifier) autoFollow(ctx context.Context, mod *common_models.Entity, change record.RecordChange) {
+	candidates := map[primitive.ObjectID]Source{}
+
+	if _, touched := change.Changes["owner"]; touched || change.Created {
+		if id, ok := userIDFrom(change.Record["owner"]); ok {
+			candidates[id] = SourceOwner
+		}
+	}
+	for _, field := range mod.Fields {
+		if !isAssignmentField(field) {
+			continue
+		}
+		if _, touched := change.Changes[field.Name]; !touched {
+			continue
+		}
+		if id, ok := userIDFrom(change.Record[field.Name]); ok {
+			if _, isOwner := candidates[id]; !isOwner {
+				candidates[id] = SourceAssigned
+			}
+		}
+	}
+
+	for userID, source := range candidates {
+		prefs := n.preferences(ctx, userID)
+		if (source == SourceOwner && !prefs.AutoFollowOwned) || (source == SourceAssigned && !prefs.AutoFollowAssigned) {
+			continue
+		}
+		err := n.Repo.EnsureExists(ctx, &Follow{
+			UserID:     userID,
+			ModuleName: change.ModuleName,
+			RecordID:   change.RecordID,
+			Level:      prefs.DefaultLevel,
+			Source:     source,
+		})
+		if err != nil {
+			log.Printf("follow: auto-follow failed for %s on %s/%s: %v", userID.Hex(), change.ModuleName, change.RecordID, err)
+		}
+	}
+}
+
+func (n *ChangeNotifier) preferences(ctx context.Context, userID primitive.ObjectID) *Preferences {
+	prefs, err := n.PrefsRepo.Get(ctx, userID)
+	if err != nil || prefs == nil {
+		return DefaultPreferences(userID)
+	}
+	return prefs
+}
+
+func (n *ChangeNotifier) userName(ctx context.Context, userID primitive.ObjectID) string {
+	if userID.IsZero() {
+		return "Someone"
+	}
+	u, err := n.UserRepo.FindByID(ctx, userID.Hex())
+	if err != nil || u == nil {
+		return "Someone"
+	}
+	if name := strings.TrimSpace(u.FirstName + " " + u.LastName); name != "" {
+		return name
+	}
+	return u.Username
+}
+
+// changedFields lists user-facing fields in a stable order
+func changedFields(changes map[string]common_models.Change) []string {
+	names := make([]string, 0, len(changes))
+	for name := range changes {
+		if strings.HasPrefix(name, "_") {
+			continue
+		}
+		switch name {
+		case "updated_at", "updated_by", "created_at", "created_by", "deleted", "deleted_at", "deleted_by":
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// Status changes lead the summary
+	sort.SliceStable(names, func(i, j int) bool {
+		return isStatusField(names[i]) && !isStatusField(names[j])
+	})
+	return names
+}
+
+func summarize(mod *common_models.Entity, names []string, changes map[string]common_models.Change) string {
+	parts := make([]string, 0, maxSummaryFields)
+	for i, name := range names {
+		if i == maxSummaryFields {
+			parts = append(parts, fmt.Sprintf("and %d more", len(names)-maxSummaryFields))
+			break
+		}
+		label := fieldLabel(mod, name)
+		c := changes[name]
+		oldVal, oldOK := displayValue(c.Old)
+		newVal, newOK := displayValue(c.New)
+		if oldOK && newOK {
+			parts = append(parts, fmt.Sprintf("%s (%s → %s)", label, oldVal, newVal))
+		} else {
+			parts = append(parts, label)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// displayValue renders scalars for summaries; references and structures are
+// left to the record page
+func displayValue(v interface{}) (string, bool) {
+	var s string
+	switch val := v.(type) {
+	case nil:
+		return "empty", true
+	case string:
+		s = val
+	case bool, int, int32, int64, float32, float64:
+		s = fmt.Sprint(val)
+	case time.Time:
+		s = val.Format("2006-01-02")
+	case primitive.DateTime:
+		s = val.Time().Format("2006-01-02")
+	default:
+		return "", false
+	}
+	if s == "" {
+		return "empty", true
+	}
+	if r := []rune(s); len(r) > 40 {
+		s = string(r[:40]) + "…"
+	}
+	return s, true
+}
+
+func isStatusField(name string) bool {
+	name = strings.ToLower(name)
+	return name == "status" || name == "stage" || strings.HasSuffix(name, "_status") || strings.HasSuffix(name, "_stage")
+}
+
+func isAssignmentField(field common_models.ModuleField) bool {
+	if field.Type == common_models.FieldTypeLookup && field.Lookup != nil && field.Lookup.LookupModule == "users" {
+		return true
+	}
+	return field.Name == "assigned_to"
+}
+
+func userIDFrom(v interface{}) (primitive.ObjectID, bool) {
+	switch val := v.(type) {
+	case primitive.ObjectID:
+		return val, !val.IsZero()
+	case string:
+		id, err := primitive.ObjectIDFromHex(val)
+		return id, err == nil
+	case map[string]interface{}:
+		return userIDFrom(val["id"])
+	case primitive.M:
+		return userIDFrom(val["id"])
+	}
+	return primitive.NilObjectID, false
+}
+
+func fieldLabel(mod *common_models.Entity, name string) string {
+	for _, f := range mod.Fields {
+		if f.Name == name && f.Label != "" {
+			return f.Label
+		}
+	}
+	if name == "owner" {
+		return "Owner"
+	}
+	return name
+}
+
+func moduleLabel(mod *common_models.Entity) string {
+	if mod.Label != "" {
+		return mod.Label
+	}
+	return mod.Name
+}
+
+func recordName(rec map[string]interface{}, id string) string {
+	for _, key := range []string{"name", "title", "subject"} {
+		if s, ok := rec[key].(string); ok && s != "" {
+			return s
+		}
+	}
+	return id
+}
@@ -0,0 +1,198 @@
+package follow
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/notification"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/role"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DigestSchedule sends the ownership digest every morning
+const DigestSchedule = "0 7 * * *"
+
+// maxDigestItems bounds each section of a digest email
+const maxDigestItems = 50
+
+// ownerChanged notifies the new owner of a record, in-app and optionally by
+// email, and keeps the change for the daily digest. Owners who assign a
+// record to themselves are not notified.
+func (n *ChangeNotifier) ownerChanged(ctx context.Context, mod *common_models.Entity, change record.RecordChange) {
+	// On create the owner is the creator unless one was given
+	var previous *primitive.ObjectID
+	if !change.Created {
+		c, touched := change.Changes["owner"]
+		if !touched {
+			return
+		}
+		if id, ok := userIDFrom(c.Old); ok {
+			previous = &id
+		}
+	}
+	newOwner, ok := userIDFrom(change.Record["owner"])
+	if !ok || newOwner == change.ActorID || (previous != nil && *previous == newOwner) {
+		return
+	}
+
+	name := recordName(change.Record, change.RecordID)
+	err := n.OwnershipRepo.Create(ctx, &OwnershipChange{
+		ModuleName:    change.ModuleName,
+		ModuleLabel:   moduleLabel(mod),
+		RecordID:      change.RecordID,
+		RecordName:    name,
+		NewOwner:      newOwner,
+		PreviousOwner: previous,
+		ChangedBy:     change.ActorID,
+	})
+	if err != nil {
+		log.Printf("follow: failed to store ownership change for %s/%s: %v", change.ModuleName, change.RecordID, err)
+	}
+
+	title := fmt.Sprintf("%s assigned to you: %s", moduleLabel(mod), name)
+	message := fmt.Sprintf("%s made you the owner", n.userName(ctx, change.ActorID))
+	if summary := n.recordSummary(ctx, mod, change.Record, newOwner); summary != "" {
+		message += ". " + summary
+	}
+	link := fmt.Sprintf("/dashboard/modules/%s/%s", change.ModuleName, change.RecordID)
+	_ = n.NotificationService.CreateNotification(ctx, newOwner, title, message, notification.NotificationTypeTask, link)
+
+	if !n.preferences(ctx, newOwner).EmailOnAssignment {
+		return
+	}
+	u, err := n.UserRepo.FindByID(ctx, newOwner.Hex())
+	if err != nil || u == nil || u.Email == "" {
+		return
+	}
+	if err := n.EmailService.SendEmail(ctx, []string{u.Email}, title, message+"\n\nOpen: "+link); err != nil {
+		log.Printf("follow: assignment email to %s failed: %v", newOwner.Hex(), err)
+	}
+}
+
+// recordSummary lists a few filled-in fields the owner may see
+func (n *ChangeNotifier) recordSummary(ctx context.Context, mod *common_models.Entity, rec map[string]interface{}, userID primitive.ObjectID) string {
+	perms, err := n.RoleService.GetFieldPermissions(ctx, userID, mod.Name)
+	if err != nil {
+		perms = nil
+	}
+	parts := make([]string, 0, maxSummaryFields)
+	for _, f := range mod.Fields {
+		if len(parts) == maxSummaryFields {
+			break
+		}
+		switch f.Name {
+		case "owner", "name", "title", "subject":
+			continue
+		}
+		if perms != nil && perms[f.Name] == role.FieldPermNone {
+			continue
+		}
+		val, ok := displayValue(rec[f.Name])
+		if !ok || val == "empty" {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s: %s", fieldLabel(mod, f.Name), val))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Digest emails each user a daily summary of records assigned to them and
+// taken away from them. Changes are consumed whether or not the user opted
+// in, so turning the digest on does not replay old changes.
+type Digest struct {
+	OwnershipRepo OwnershipRepository
+	Notifier      *ChangeNotifier
+}
+
+func NewDigest(ownershipRepo OwnershipRepository, notifier *ChangeNotifier) *Digest {
+	return &Digest{OwnershipRepo: ownershipRepo, Notifier: notifier}
+}
+
+type digestKey struct {
+	tenantID primitive.ObjectID
+	userID   primitive.ObjectID
+}
+
+type digestEntry struct {
+	gained []OwnershipChange
+	lost   []OwnershipChange
+}
+
+func (d *Digest) Run(ctx context.Context) error {
+	changes, err := d.OwnershipRepo.ListUndigested(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	entries := map[digestKey]*digestEntry{}
+	entry := func(tenantID, userID primitive.ObjectID) *digestEntry {
+		key := digestKey{tenantID, userID}
+		if entries[key] == nil {
+			entries[key] = &digestEntry{}
+		}
+		return entries[key]
+	}
+	ids := make([]primitive.ObjectID, 0, len(changes))
+	for _, c := range changes {
+		ids = append(ids, c.ID)
+		entry(c.TenantID, c.NewOwner).gained = append(entry(c.TenantID, c.NewOwner).gained, c)
+		if c.PreviousOwner != nil && *c.PreviousOwner != c.ChangedBy {
+			entry(c.TenantID, *c.PreviousOwner).lost = append(entry(c.TenantID, *c.PreviousOwner).lost, c)
+		}
+	}
+
+	for key, e := range entries {
+		tenantCtx := context.WithValue(ctx, common_models.TenantIDKey, key.tenantID.Hex())
+		if err := d.send(tenantCtx, key.userID, e); err != nil {
+			log.Printf("follow: digest for %s failed: %v", key.userID.Hex(), err)
+		}
+	}
+	return d.OwnershipRepo.MarkDigested(ctx, ids)
+}
+
+func (d *Digest) send(ctx context.Context, userID primitive.ObjectID, e *digestEntry) error {
+	n := d.Notifier
+	if !n.preferences(ctx, userID).DailyDigest {
+		return nil
+	}
+	u, err := n.UserRepo.FindByID(ctx, userID.Hex())
+	if err != nil || u == nil || u.Email == "" {
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString("Here is what changed in your record ownership since the last digest.\n")
+	section := func(heading string, items []OwnershipChange, by func(OwnershipChange) string) {
+		if len(items) == 0 {
+			return
+		}
+		sort.SliceStable(items, func(i, j int) bool { return items[i].ModuleLabel < items[j].ModuleLabel })
+		fmt.Fprintf(&b, "\n%s (%d)\n", heading, len(items))
+		for i, c := range items {
+			if i == maxDigestItems {
+				fmt.Fprintf(&b, "  … and %d more\n", len(items)-maxDigestItems)
+				break
+			}
+			fmt.Fprintf(&b, "  - %s: %s (%s)\n", c.ModuleLabel, c.RecordName, by(c))
+		}
+	}
+	section("Assigned to you", e.gained, func(c OwnershipChange) string {
+		return "by " + n.userName(ctx, c.ChangedBy)
+	})
+	section("Reassigned to someone else", e.lost, func(c OwnershipChange) string {
+		return "now owned by " + n.userName(ctx, c.NewOwner)
+	})
+
+	subject := fmt.Sprintf("Ownership digest: %d assigned, %d reassigned", len(e.gained), len(e.lost))
+	return n.EmailService.SendEmail(ctx, []string{u.Email}, subject, b.String())
+}
@@ -0,0 +1,260 @@
+package follow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type FollowRepository interface {
+	Get(ctx context.Context, userID primitive.ObjectID, moduleName, recordID string) (*Follow, error)
+	// Set creates or replaces the user's follow on a record
+	Set(ctx context.Context, follow *Follow) error
+	// EnsureExists creates the follow only when the user has none on the record,
+	// so an explicit unfollow or level choice survives auto-follow
+	EnsureExists(ctx context.Context, follow *Follow) error
+	ListByUser(ctx context.Context, userID primitive.ObjectID, moduleName string) ([]Follow, error)
+	ListFollowers(ctx context.Context, moduleName, recordID string) ([]Follow, error)
+}
+
+type FollowRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewFollowRepository(db *database.MongodbDB) FollowRepository {
+	return &FollowRepositoryImpl{
+		collection: db.DB.Collection("record_follows"),
+	}
+}
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+func (r *FollowRepositoryImpl) Get(ctx context.Context, userID primitive.ObjectID, moduleName, recordID string) (*Follow, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var follow Follow
+	err = r.collection.FindOne(ctx, bson.M{
+		"tenant_id":   tenantID,
+		"user_id":     userID,
+		"module_name": moduleName,
+		"record_id":   recordID,
+	}).Decode(&follow)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &follow, nil
+}
+
+func (r *FollowRepositoryImpl) Set(ctx context.Context, follow *Follow) error {
+	return r.upsert(ctx, follow, false)
+}
+
+func (r *FollowRepositoryImpl) EnsureExists(ctx context.Context, follow *Follow) error {
+	return r.upsert(ctx, follow, true)
+}
+
+func (r *FollowRepositoryImpl) upsert(ctx context.Context, follow *Follow, insertOnly bool) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	follow.TenantID = tenantID
+	follow.UpdatedAt = now
+
+	filter := bson.M{
+		"tenant_id":   tenantID,
+		"user_id":     follow.UserID,
+		"module_name": follow.ModuleName,
+		"record_id":   follow.RecordID,
+	}
+	fields := bson.M{"level": follow.Level, "source": follow.Source, "updated_at": now}
+
+	update := bson.M{"$setOnInsert": bson.M{"_id": primitive.NewObjectID(), "created_at": now}}
+	if insertOnly {
+		for k, v := range fields {
+			update["$setOnInsert"].(bson.M)[k] = v
+		}
+	} else {
+		update["$set"] = fields
+	}
+
+	_, err = r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (r *FollowRepositoryImpl) ListByUser(ctx context.Context, userID primitive.ObjectID, moduleName string) ([]Follow, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{"tenant_id": tenantID, "user_id": userID, "level": bson.M{"$ne": LevelNone}}
+	if moduleName != "" {
+		filter["module_name"] = moduleName
+	}
+	return r.find(ctx, filter)
+}
+
+func (r *FollowRepositoryImpl) ListFollowers(ctx context.Context, moduleName, recordID string) ([]Follow, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.find(ctx, bson.M{
+		"tenant_id":   tenantID,
+		"module_name": moduleName,
+		"record_id":   recordID,
+		"level":       bson.M{"$ne": LevelNone},
+	})
+}
+
+func (r *FollowRepositoryImpl) find(ctx context.Context, filter bson.M) ([]Follow, error) {
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	follows := []Follow{}
+	if err := cursor.All(ctx, &follows); err != nil {
+		return nil, err
+	}
+	return follows, nil
+}
+
+type PreferencesRepository interface {
+	Get(ctx context.Context, userID primitive.ObjectID) (*Preferences, error)
+	Upsert(ctx context.Context, prefs *Preferences) error
+}
+
+type PreferencesRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewPreferencesRepository(db *database.MongodbDB) PreferencesRepository {
+	return &PreferencesRepositoryImpl{
+		collection: db.DB.Collection("follow_preferences"),
+	}
+}
+
+// Get returns nil without error when the user has not saved preferences
+func (r *PreferencesRepositoryImpl) Get(ctx context.Context, userID primitive.ObjectID) (*Preferences, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var prefs Preferences
+	err = r.collection.FindOne(ctx, bson.M{"tenant_id": tenantID, "user_id": userID}).Decode(&prefs)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+func (r *PreferencesRepositoryImpl) Upsert(ctx context.Context, prefs *Preferences) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	prefs.TenantID = tenantID
+	prefs.UpdatedAt = time.Now()
+
+	_, err = r.collection.UpdateOne(ctx,
+		bson.M{"tenant_id": tenantID, "user_id": prefs.UserID},
+		bson.M{
+			"$set": bson.M{
+				"default_level":        prefs.DefaultLevel,
+				"auto_follow_owned":    prefs.AutoFollowOwned,
+				"auto_follow_assigned": prefs.AutoFollowAssigned,
+				"email_on_assignment":  prefs.EmailOnAssignment,
+				"daily_digest":         prefs.DailyDigest,
+				"updated_at":           prefs.UpdatedAt,
+			},
+			"$setOnInsert": bson.M{"_id": primitive.NewObjectID()},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+type OwnershipRepository interface {
+	Create(ctx context.Context, change *OwnershipChange) error
+	// ListUndigested returns changes of every tenant not yet sent in a digest,
+	// oldest first
+	ListUndigested(ctx context.Context, before time.Time) ([]OwnershipChange, error)
+	MarkDigested(ctx context.Context, ids []primitive.ObjectID) error
+}
+
+type OwnershipRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewOwnershipRepository(db *database.MongodbDB) OwnershipRepository {
+	return &OwnershipRepositoryImpl{
+		collection: db.DB.Collection("ownership_changes"),
+	}
+}
+
+func (r *OwnershipRepositoryImpl) Create(ctx context.Context, change *OwnershipChange) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	change.ID = primitive.NewObjectID()
+	change.TenantID = tenantID
+	change.CreatedAt = time.Now()
+
+	_, err = r.collection.InsertOne(ctx, change)
+	return err
+}
+
+func (r *OwnershipRepositoryImpl) ListUndigested(ctx context.Context, before time.Time) ([]OwnershipChange, error) {
+	filter := bson.M{
+		"digested_at": bson.M{"$exists": false},
+		"created_at":  bson.M{"$lt": before},
+	}
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var changes []OwnershipChange
+	if err := cursor.All(ctx, &changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func (r *OwnershipRepositoryImpl) MarkDigested(ctx context.Context, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := r.collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, bson.M{"$set": bson.M{"digested_at": time.Now()}})
+	return err
+}
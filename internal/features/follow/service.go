@@ -0,0 +1,124 @@
+package follow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go-crm/internal/features/record"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type FollowService interface {
+	Follow(ctx context.Context, moduleName, recordID string, level Level, userID primitive.ObjectID) (*FollowStatus, error)
+	Unfollow(ctx context.Context, moduleName, recordID string, userID primitive.ObjectID) error
+	GetStatus(ctx context.Context, moduleName, recordID string, userID primitive.ObjectID) (*FollowStatus, error)
+	ListFollowing(ctx context.Context, moduleName string, userID primitive.ObjectID) ([]Follow, error)
+	ListFollowers(ctx context.Context, moduleName, recordID string, userID primitive.ObjectID) ([]Follow, error)
+	GetPreferences(ctx context.Context, userID primitive.ObjectID) (*Preferences, error)
+	UpdatePreferences(ctx context.Context, prefs *Preferences, userID primitive.ObjectID) error
+}
+
+type FollowServiceImpl struct {
+	Repo          FollowRepository
+	PrefsRepo     PreferencesRepository
+	RecordService record.RecordService
+}
+
+func NewFollowService(repo FollowRepository, prefsRepo PreferencesRepository, recordService record.RecordService) FollowService {
+	return &FollowServiceImpl{
+		Repo:          repo,
+		PrefsRepo:     prefsRepo,
+		RecordService: recordService,
+	}
+}
+
+func validLevel(level Level) bool {
+	return level == LevelAll || level == LevelStatus
+}
+
+func (s *FollowServiceImpl) Follow(ctx context.Context, moduleName, recordID string, level Level, userID primitive.ObjectID) (*FollowStatus, error) {
+	if _, err := s.RecordService.GetRecord(ctx, moduleName, recordID, userID); err != nil {
+		return nil, errors.New("record not found")
+	}
+	if level == "" {
+		prefs, err := s.GetPreferences(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		level = prefs.DefaultLevel
+	}
+	if !validLevel(level) {
+		return nil, fmt.Errorf("invalid level: %s", level)
+	}
+
+	// Changing the level of an auto-follow keeps its source
+	source := SourceManual
+	if existing, err := s.Repo.Get(ctx, userID, moduleName, recordID); err == nil && existing != nil && existing.Level != LevelNone {
+		source = existing.Source
+	}
+
+	f := &Follow{UserID: userID, ModuleName: moduleName, RecordID: recordID, Level: level, Source: source}
+	if err := s.Repo.Set(ctx, f); err != nil {
+		return nil, err
+	}
+	return &FollowStatus{Following: true, Level: level, Source: source}, nil
+}
+
+func (s *FollowServiceImpl) Unfollow(ctx context.Context, moduleName, recordID string, userID primitive.ObjectID) error {
+	existing, err := s.Repo.Get(ctx, userID, moduleName, recordID)
+	if err != nil {
+		return err
+	}
+	source := SourceManual
+	if existing != nil {
+		source = existing.Source
+	}
+	// Kept as level none so later owner/assignee changes do not re-follow
+	return s.Repo.Set(ctx, &Follow{UserID: userID, ModuleName: moduleName, RecordID: recordID, Level: LevelNone, Source: source})
+}
+
+func (s *FollowServiceImpl) GetStatus(ctx context.Context, moduleName, recordID string, userID primitive.ObjectID) (*FollowStatus, error) {
+	f, err := s.Repo.Get(ctx, userID, moduleName, recordID)
+	if err != nil {
+		return nil, err
+	}
+	if f == nil || f.Level == LevelNone {
+		return &FollowStatus{Following: false, Level: LevelNone}, nil
+	}
+	return &FollowStatus{Following: true, Level: f.Level, Source: f.Source}, nil
+}
+
+func (s *FollowServiceImpl) ListFollowing(ctx context.Context, moduleName string, userID primitive.ObjectID) ([]Follow, error) {
+	return s.Repo.ListByUser(ctx, userID, moduleName)
+}
+
+func (s *FollowServiceImpl) ListFollowers(ctx context.Context, moduleName, recordID string, userID primitive.ObjectID) ([]Follow, error) {
+	if _, err := s.RecordService.GetRecord(ctx, moduleName, recordID, userID); err != nil {
+		return nil, errors.New("record not found")
+	}
+	return s.Repo.ListFollowers(ctx, moduleName, recordID)
+}
+
+func (s *FollowServiceImpl) GetPreferences(ctx context.Context, userID primitive.ObjectID) (*Preferences, error) {
+	prefs, err := s.PrefsRepo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if prefs == nil {
+		return DefaultPreferences(userID), nil
+	}
+	return prefs, nil
+}
+
+func (s *FollowServiceImpl) UpdatePreferences(ctx context.Context, prefs *Preferences, userID primitive.ObjectID) error {
+	if prefs.DefaultLevel == "" {
+		prefs.DefaultLevel = LevelAll
+	}
+	if !validLevel(prefs.DefaultLevel) {
+		return fmt.Errorf("invalid default_level: %s", prefs.DefaultLevel)
+	}
+	prefs.UserID = userID
+	return s.PrefsRepo.Upsert(ctx, prefs)
+}
@@ -0,0 +1,42 @@
+package forecast
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/features/role"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ForecastApi struct {
+	controller  *ForecastController
+	config      *config.Config
+	roleService role.RoleService
+}
+
+func NewForecastApi(controller *ForecastController, config *config.Config, roleService role.RoleService) *ForecastApi {
+	return &ForecastApi{
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *ForecastApi) Setup(app *fiber.App) {
+	goals := app.Group("/api/goals", middleware.AuthMiddleware(h.config.SkipAuth))
+
+	goals.Post("/", middleware.RequirePermission(h.roleService, "goals", "create"), h.controller.CreateGoal)
+	goals.Get("/", middleware.RequirePermission(h.roleService, "goals", "read"), h.controller.ListGoals)
+	goals.Get("/attainment", middleware.RequirePermission(h.roleService, "goals", "read"), h.controller.GetAttainmentSummary)
+	goals.Get("/:id", middleware.RequirePermission(h.roleService, "goals", "read"), h.controller.GetGoal)
+	goals.Put("/:id", middleware.RequirePermission(h.roleService, "goals", "update"), h.controller.UpdateGoal)
+	goals.Delete("/:id", middleware.RequirePermission(h.roleService, "goals", "delete"), h.controller.DeleteGoal)
+	goals.Get("/:id/attainment", middleware.RequirePermission(h.roleService, "goals", "read"), h.controller.GetAttainment)
+
+	scores := app.Group("/api/deal-scores", middleware.AuthMiddleware(h.config.SkipAuth))
+
+	scores.Get("/", middleware.RequirePermission(h.roleService, DefaultGoalModule, "read"), h.controller.ListDealScores)
+	scores.Get("/model", middleware.RequirePermission(h.roleService, DefaultGoalModule, "read"), h.controller.GetDealScoreModel)
+	scores.Post("/refresh", middleware.RequirePermission(h.roleService, DefaultGoalModule, "update"), h.controller.RefreshDealScores)
+	scores.Get("/:id", middleware.RequirePermission(h.roleService, DefaultGoalModule, "read"), h.controller.GetDealScore)
+}
@@ -0,0 +1,293 @@
+package forecast
+
+import (
+	"errors"
+	"time"
+
+	common_api "go-crm/internal/common/api"
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ForecastController struct {
+	ForecastService ForecastService
+}
+
+func NewForecastController(forecastService ForecastService) *ForecastController {
+	return &ForecastController{
+		ForecastService: forecastService,
+	}
+}
+
+// CreateGoal godoc
+// @Summary Create sales goal
+// @Description Create a quota for a user or team over a period
+// @Tags goals
+// @Accept json
+// @Produce json
+// @Param goal body SalesGoal true "Goal Details"
+// @Success 201 {object} SalesGoal
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/goals [post]
+func (c *ForecastController) CreateGoal(ctx *fiber.Ctx) error {
+	var goal SalesGoal
+	if err := ctx.BodyParser(&goal); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	goal.CreatedBy = userID
+
+	if err := c.ForecastService.CreateGoal(ctx.UserContext(), &goal); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(goal)
+}
+
+// ListGoals godoc
+// @Summary List sales goals
+// @Description List sales goals, optionally filtered by owner
+// @Tags goals
+// @Produce json
+// @Param owner_type query string false "Owner type (user or team)"
+// @Param owner_id query string false "Owner ID"
+// @Success 200 {array} SalesGoal
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/goals [get]
+func (c *ForecastController) ListGoals(ctx *fiber.Ctx) error {
+	filter := make(map[string]interface{})
+	if ownerType := ctx.Query("owner_type"); ownerType != "" {
+		filter["owner_type"] = ownerType
+	}
+	if ownerID := ctx.Query("owner_id"); ownerID != "" {
+		oid, err := primitive.ObjectIDFromHex(ownerID)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid owner ID"})
+		}
+		filter["owner_id"] = oid
+	}
+
+	goals, err := c.ForecastService.ListGoals(ctx.UserContext(), filter)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(goals)
+}
+
+// GetGoal godoc
+// @Summary Get sales goal
+// @Description Get a sales goal by ID
+// @Tags goals
+// @Produce json
+// @Param id path string true "Goal ID"
+// @Success 200 {object} SalesGoal
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/goals/{id} [get]
+func (c *ForecastController) GetGoal(ctx *fiber.Ctx) error {
+	goal, err := c.ForecastService.GetGoal(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Goal not found"})
+	}
+
+	return ctx.JSON(goal)
+}
+
+// UpdateGoal godoc
+// @Summary Update sales goal
+// @Description Update an existing sales goal
+// @Tags goals
+// @Accept json
+// @Produce json
+// @Param id path string true "Goal ID"
+// @Param goal body SalesGoal true "Goal Details"
+// @Success 200 {object} SalesGoal
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/goals/{id} [put]
+func (c *ForecastController) UpdateGoal(ctx *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	var goal SalesGoal
+	if err := ctx.BodyParser(&goal); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	goal.ID = id
+
+	if err := c.ForecastService.UpdateGoal(ctx.UserContext(), &goal); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(goal)
+}
+
+// DeleteGoal godoc
+// @Summary Delete sales goal
+// @Description Delete a sales goal
+// @Tags goals
+// @Param id path string true "Goal ID"
+// @Success 204
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/goals/{id} [delete]
+func (c *ForecastController) DeleteGoal(ctx *fiber.Ctx) error {
+	if err := c.ForecastService.DeleteGoal(ctx.UserContext(), ctx.Params("id")); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// GetAttainment godoc
+// @Summary Get goal attainment
+// @Description Compare closed-won revenue against the goal target, with trend and run-rate projection
+// @Tags goals
+// @Produce json
+// @Param id path string true "Goal ID"
+// @Success 200 {object} GoalAttainment
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/goals/{id}/attainment [get]
+func (c *ForecastController) GetAttainment(ctx *fiber.Ctx) error {
+	attainment, err := c.ForecastService.GetAttainment(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+
+	return ctx.JSON(attainment)
+}
+
+// GetAttainmentSummary godoc
+// @Summary Get attainment summary
+// @Description Attainment for every goal active at the given date, ranked for manager dashboards
+// @Tags goals
+// @Produce json
+// @Param date query string false "Reference date (YYYY-MM-DD), defaults to today"
+// @Param owner_type query string false "Owner type (user or team)"
+// @Success 200 {array} GoalAttainment
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/goals/attainment [get]
+func (c *ForecastController) GetAttainmentSummary(ctx *fiber.Ctx) error {
+	at := time.Now()
+	if dateStr := ctx.Query("date"); dateStr != "" {
+		parsed, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid date format, expected YYYY-MM-DD"})
+		}
+		at = parsed
+	}
+
+	results, err := c.ForecastService.GetAttainmentSummary(ctx.UserContext(), at, GoalOwnerType(ctx.Query("owner_type")))
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(results)
+}
+
+// ListDealScores godoc
+// @Summary List deal scores
+// @Description Win probability of open opportunities, most likely first, with the factors behind each score
+// @Tags deal-scores
+// @Produce json
+// @Param limit query int false "Page size" default(50)
+// @Param offset query int false "Offset"
+// @Success 200 {array} DealScore
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/deal-scores [get]
+func (c *ForecastController) ListDealScores(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	limit := int64(ctx.QueryInt("limit", 50))
+	offset := int64(ctx.QueryInt("offset", 0))
+
+	scores, err := c.ForecastService.ListDealScores(ctx.UserContext(), limit, offset, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(scores)
+}
+
+// GetDealScore godoc
+// @Summary Get deal score
+// @Description Win probability of an open opportunity and its top contributing factors
+// @Tags deal-scores
+// @Produce json
+// @Param id path string true "Opportunity ID"
+// @Success 200 {object} DealScore
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/deal-scores/{id} [get]
+func (c *ForecastController) GetDealScore(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	score, err := c.ForecastService.GetDealScore(ctx.UserContext(), ctx.Params("id"), userID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Deal score not found"})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(score)
+}
+
+// GetDealScoreModel godoc
+// @Summary Get deal scoring model
+// @Description The tenant's current win-probability model and how it was trained
+// @Tags deal-scores
+// @Produce json
+// @Success 200 {object} ScoreModel
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/deal-scores/model [get]
+func (c *ForecastController) GetDealScoreModel(ctx *fiber.Ctx) error {
+	model, err := c.ForecastService.GetDealScoreModel(ctx.UserContext())
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No scoring model has been trained yet"})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(model)
+}
+
+// RefreshDealScores godoc
+// @Summary Refresh deal scores
+// @Description Retrain the scoring model on closed opportunities and rescore the open ones now instead of at the nightly run
+// @Tags deal-scores
+// @Produce json
+// @Success 200 {object} ScoreModel
+// @Failure 409 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/deal-scores/refresh [post]
+func (c *ForecastController) RefreshDealScores(ctx *fiber.Ctx) error {
+	model, err := c.ForecastService.RefreshDealScores(ctx.UserContext())
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrScoringRunning):
+			return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, ErrNotEnoughHistory):
+			return ctx.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(model)
+}
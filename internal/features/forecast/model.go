@@ -0,0 +1,82 @@
+package forecast
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GoalOwnerType identifies who a sales goal is assigned to
+type GoalOwnerType string
+
+const (
+	GoalOwnerUser GoalOwnerType = "user"
+	GoalOwnerTeam GoalOwnerType = "team"
+)
+
+// GoalPeriod is the cadence a quota is measured over
+type GoalPeriod string
+
+const (
+	GoalPeriodMonthly   GoalPeriod = "monthly"
+	GoalPeriodQuarterly GoalPeriod = "quarterly"
+	GoalPeriodYearly    GoalPeriod = "yearly"
+	GoalPeriodCustom    GoalPeriod = "custom"
+)
+
+// Default opportunity mapping used when a goal does not override it
+const (
+	DefaultGoalModule      = "opportunities"
+	DefaultGoalAmountField = "amount"
+	DefaultGoalStageField  = "stage"
+	DefaultGoalWonStage    = "Closed Won"
+	DefaultGoalDateField   = "close_date"
+)
+
+// SalesGoal represents a quota for a user or team over a period
+type SalesGoal struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID     primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	Name         string             `json:"name" bson:"name"`
+	OwnerType    GoalOwnerType      `json:"owner_type" bson:"owner_type"`
+	OwnerID      primitive.ObjectID `json:"owner_id" bson:"owner_id"` // User ID or Group ID depending on OwnerType
+	Period       GoalPeriod         `json:"period" bson:"period"`
+	PeriodStart  time.Time          `json:"period_start" bson:"period_start"`
+	PeriodEnd    time.Time          `json:"period_end" bson:"period_end"`
+	TargetAmount float64            `json:"target_amount" bson:"target_amount"`
+
+	// Opportunity mapping (defaults to opportunities/amount/stage/close_date)
+	Module      string `json:"module,omitempty" bson:"module,omitempty"`
+	AmountField string `json:"amount_field,omitempty" bson:"amount_field,omitempty"`
+	StageField  string `json:"stage_field,omitempty" bson:"stage_field,omitempty"`
+	WonStage    string `json:"won_stage,omitempty" bson:"won_stage,omitempty"`
+	DateField   string `json:"date_field,omitempty" bson:"date_field,omitempty"`
+
+	CreatedBy primitive.ObjectID `json:"created_by" bson:"created_by"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// TrendPoint is a bucket of closed-won revenue used for charting attainment over time
+type TrendPoint struct {
+	PeriodStart time.Time `json:"period_start"`
+	Amount      float64   `json:"amount"`
+	Cumulative  float64   `json:"cumulative"`
+	Deals       int       `json:"deals"`
+}
+
+// GoalAttainment compares closed-won revenue against a goal's target
+type GoalAttainment struct {
+	Goal             *SalesGoal   `json:"goal"`
+	Achieved         float64      `json:"achieved"`
+	DealsWon         int          `json:"deals_won"`
+	Remaining        float64      `json:"remaining"`
+	AttainmentPct    float64      `json:"attainment_pct"`
+	OpenPipeline     float64      `json:"open_pipeline"`
+	ElapsedPct       float64      `json:"elapsed_pct"`
+	ProjectedAmount  float64      `json:"projected_amount"`
+	ProjectedPct     float64      `json:"projected_pct"`
+	OnTrack          bool         `json:"on_track"`
+	Trend            []TrendPoint `json:"trend"`
+	TrendGranularity string       `json:"trend_granularity"`
+}
@@ -0,0 +1,132 @@
+package forecast
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type GoalRepository interface {
+	Create(ctx context.Context, goal *SalesGoal) error
+	Get(ctx context.Context, id string) (*SalesGoal, error)
+	List(ctx context.Context, filter bson.M) ([]SalesGoal, error)
+	Update(ctx context.Context, goal *SalesGoal) error
+	Delete(ctx context.Context, id string) error
+}
+
+type GoalRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewGoalRepository(db *database.MongodbDB) GoalRepository {
+	return &GoalRepositoryImpl{
+		collection: db.DB.Collection("sales_goals"),
+	}
+}
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	tenantID, err := primitive.ObjectIDFromHex(tenantIDStr)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("invalid tenant ID: %v", err)
+	}
+	return tenantID, nil
+}
+
+func (r *GoalRepositoryImpl) Create(ctx context.Context, goal *SalesGoal) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if goal.ID.IsZero() {
+		goal.ID = primitive.NewObjectID()
+	}
+	goal.TenantID = tenantID
+	goal.CreatedAt = time.Now()
+	goal.UpdatedAt = time.Now()
+
+	_, err = r.collection.InsertOne(ctx, goal)
+	return err
+}
+
+func (r *GoalRepositoryImpl) Get(ctx context.Context, id string) (*SalesGoal, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var goal SalesGoal
+	if err := r.collection.FindOne(ctx, bson.M{"_id": objID, "tenant_id": tenantID}).Decode(&goal); err != nil {
+		return nil, err
+	}
+	return &goal, nil
+}
+
+func (r *GoalRepositoryImpl) List(ctx context.Context, filter bson.M) ([]SalesGoal, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := bson.M{"tenant_id": tenantID}
+	for k, v := range filter {
+		query[k] = v
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "period_start", Value: -1}})
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	goals := []SalesGoal{}
+	if err := cursor.All(ctx, &goals); err != nil {
+		return nil, err
+	}
+	return goals, nil
+}
+
+func (r *GoalRepositoryImpl) Update(ctx context.Context, goal *SalesGoal) error {
+	existing, err := r.Get(ctx, goal.ID.Hex())
+	if err != nil {
+		return err
+	}
+
+	// Preserve immutable fields
+	goal.TenantID = existing.TenantID
+	goal.CreatedAt = existing.CreatedAt
+	goal.CreatedBy = existing.CreatedBy
+	goal.UpdatedAt = time.Now()
+
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": goal.ID, "tenant_id": existing.TenantID}, goal)
+	return err
+}
+
+func (r *GoalRepositoryImpl) Delete(ctx context.Context, id string) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": objID, "tenant_id": tenantID})
+	return err
+}
@@ -0,0 +1,136 @@
+package forecast
+
+import (
+	"context"
+
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ScoreRepository interface {
+	// SaveModel replaces the tenant's model of the module
+	SaveModel(ctx context.Context, model *ScoreModel) error
+	GetModel(ctx context.Context, module string) (*ScoreModel, error)
+	// ReplaceScores swaps the module's scores for those of a new run
+	ReplaceScores(ctx context.Context, module string, scores []DealScore) error
+	GetScore(ctx context.Context, module, recordID string) (*DealScore, error)
+	// ListScores returns scores by descending probability
+	ListScores(ctx context.Context, module string, limit int64) ([]DealScore, error)
+}
+
+type ScoreRepositoryImpl struct {
+	models *mongo.Collection
+	scores *mongo.Collection
+}
+
+func NewScoreRepository(db *database.MongodbDB) ScoreRepository {
+	return &ScoreRepositoryImpl{
+		models: db.DB.Collection("deal_score_models"),
+		scores: db.DB.Collection("deal_scores"),
+	}
+}
+
+func (r *ScoreRepositoryImpl) SaveModel(ctx context.Context, model *ScoreModel) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	model.TenantID = tenantID
+
+	filter := bson.M{"tenant_id": tenantID, "module": model.Module}
+	var existing ScoreModel
+	if err := r.models.FindOne(ctx, filter).Decode(&existing); err == nil {
+		model.ID = existing.ID
+	} else if err != mongo.ErrNoDocuments {
+		return err
+	}
+	if model.ID.IsZero() {
+		model.ID = primitive.NewObjectID()
+	}
+
+	_, err = r.models.ReplaceOne(ctx, filter, model, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (r *ScoreRepositoryImpl) GetModel(ctx context.Context, module string) (*ScoreModel, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var model ScoreModel
+	if err := r.models.FindOne(ctx, bson.M{"tenant_id": tenantID, "module": module}).Decode(&model); err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+func (r *ScoreRepositoryImpl) ReplaceScores(ctx context.Context, module string, scores []DealScore) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Insert the new run first so readers never see an empty list, then drop
+	// what earlier runs left
+	ids := make([]primitive.ObjectID, len(scores))
+	if len(scores) > 0 {
+		docs := make([]interface{}, len(scores))
+		for i := range scores {
+			scores[i].ID = primitive.NewObjectID()
+			scores[i].TenantID = tenantID
+			scores[i].Module = module
+			ids[i] = scores[i].ID
+			docs[i] = scores[i]
+		}
+		if _, err := r.scores.InsertMany(ctx, docs); err != nil {
+			return err
+		}
+	}
+	_, err = r.scores.DeleteMany(ctx, bson.M{
+		"tenant_id": tenantID,
+		"module":    module,
+		"_id":       bson.M{"$nin": ids},
+	})
+	return err
+}
+
+func (r *ScoreRepositoryImpl) GetScore(ctx context.Context, module, recordID string) (*DealScore, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var score DealScore
+	opts := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}})
+	if err := r.scores.FindOne(ctx, bson.M{"tenant_id": tenantID, "module": module, "record_id": recordID}, opts).Decode(&score); err != nil {
+		return nil, err
+	}
+	return &score, nil
+}
+
+func (r *ScoreRepositoryImpl) ListScores(ctx context.Context, module string, limit int64) ([]DealScore, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "probability", Value: -1}, {Key: "_id", Value: 1}}).
+		SetLimit(limit)
+	cursor, err := r.scores.Find(ctx, bson.M{"tenant_id": tenantID, "module": module}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	scores := []DealScore{}
+	if err := cursor.All(ctx, &scores); err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
@@ -0,0 +1,609 @@
+package forecast
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/role"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ScoringSchedule retrains and rescores nightly
+const ScoringSchedule = "45 2 * * *"
+
+// DefaultLostStage closes an opportunity as lost
+const DefaultLostStage = "Closed Lost"
+
+const (
+	// minTrainingDeals is the closed history needed, with both outcomes
+	// present, before a model is trained
+	minTrainingDeals = 20
+	maxTrainingDeals = 5000
+	maxScoredDeals   = 5000
+	trainIterations  = 500
+	learningRate     = 0.1
+	l2Penalty        = 0.01
+	topFactors       = 3
+)
+
+// Scoring features, in model order
+const (
+	FeatureAmount       = "amount"
+	FeatureStage        = "stage"
+	FeatureStageChanges = "stage_changes"
+	FeatureRegressions  = "stage_regressions"
+	FeatureAgeDays      = "age_days"
+	FeatureActivities   = "activities"
+)
+
+var scoringFeatures = []string{FeatureAmount, FeatureStage, FeatureStageChanges, FeatureRegressions, FeatureAgeDays, FeatureActivities}
+
+var featureLabels = map[string]string{
+	FeatureAmount:       "Deal amount",
+	FeatureStage:        "Pipeline stage",
+	FeatureStageChanges: "Stage changes",
+	FeatureRegressions:  "Moves back to an earlier stage",
+	FeatureAgeDays:      "Days open",
+	FeatureActivities:   "Logged tasks, calls and meetings",
+}
+
+// ScoreModel is a tenant's logistic regression over standardized features
+type ScoreModel struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID  primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	Module    string             `json:"module" bson:"module"`
+	Features  []string           `json:"features" bson:"features"`
+	Weights   []float64          `json:"weights" bson:"weights"`
+	Bias      float64            `json:"bias" bson:"bias"`
+	Means     []float64          `json:"means" bson:"means"`
+	Scales    []float64          `json:"scales" bson:"scales"`
+	Samples   int                `json:"samples" bson:"samples"`
+	WinRate   float64            `json:"win_rate" bson:"win_rate"`
+	Accuracy  float64            `json:"accuracy" bson:"accuracy"` // On the training deals
+	TrainedAt time.Time          `json:"trained_at" bson:"trained_at"`
+}
+
+// ScoreFactor is one feature's pull on a deal's score. Impact is in
+// log-odds; positive values raise the win probability.
+type ScoreFactor struct {
+	Feature string  `json:"feature" bson:"feature"`
+	Label   string  `json:"label" bson:"label"`
+	Value   float64 `json:"value" bson:"value"`
+	Impact  float64 `json:"impact" bson:"impact"`
+}
+
+// DealScore is the win probability of an open opportunity
+type DealScore struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID    primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	Module      string             `json:"module" bson:"module"`
+	RecordID    string             `json:"record_id" bson:"record_id"`
+	Name        string             `json:"name,omitempty" bson:"name,omitempty"`
+	Probability float64            `json:"probability" bson:"probability"`
+	Factors     []ScoreFactor      `json:"factors" bson:"factors"`
+	ModelID     primitive.ObjectID `json:"model_id" bson:"model_id"`
+	ScoredAt    time.Time          `json:"scored_at" bson:"scored_at"`
+}
+
+// trainModel fits weights by gradient descent on the log loss with an L2
+// penalty. rows are raw feature vectors; won marks the positive outcomes.
+func trainModel(rows [][]float64, won []bool) *ScoreModel {
+	n, k := len(rows), len(scoringFeatures)
+	m := &ScoreModel{
+		Features: scoringFeatures,
+		Weights:  make([]float64, k),
+		Means:    make([]float64, k),
+		Scales:   make([]float64, k),
+		Samples:  n,
+	}
+
+	for j := 0; j < k; j++ {
+		var sum float64
+		for _, r := range rows {
+			sum += r[j]
+		}
+		m.Means[j] = sum / float64(n)
+		var sq float64
+		for _, r := range rows {
+			d := r[j] - m.Means[j]
+			sq += d * d
+		}
+		m.Scales[j] = math.Sqrt(sq / float64(n))
+		if m.Scales[j] == 0 {
+			m.Scales[j] = 1
+		}
+	}
+	z := make([][]float64, n)
+	for i, r := range rows {
+		z[i] = m.standardize(r)
+	}
+
+	wins := 0
+	for _, w := range won {
+		if w {
+			wins++
+		}
+	}
+	m.WinRate = float64(wins) / float64(n)
+	// Start from the base rate so few steps already give sensible odds
+	m.Bias = math.Log(m.WinRate / (1 - m.WinRate))
+
+	grad := make([]float64, k)
+	for it := 0; it < trainIterations; it++ {
+		for j := range grad {
+			grad[j] = l2Penalty * m.Weights[j]
+		}
+		var gradBias float64
+		for i := range z {
+			p := sigmoid(m.logit(z[i]))
+			y := 0.0
+			if won[i] {
+				y = 1
+			}
+			d := (p - y) / float64(n)
+			for j, v := range z[i] {
+				grad[j] += d * v
+			}
+			gradBias += d
+		}
+		for j := range m.Weights {
+			m.Weights[j] -= learningRate * grad[j]
+		}
+		m.Bias -= learningRate * gradBias
+	}
+
+	correct := 0
+	for i := range z {
+		if (sigmoid(m.logit(z[i])) >= 0.5) == won[i] {
+			correct++
+		}
+	}
+	m.Accuracy = float64(correct) / float64(n)
+	return m
+}
+
+func (m *ScoreModel) standardize(row []float64) []float64 {
+	z := make([]float64, len(row))
+	for j, v := range row {
+		z[j] = (v - m.Means[j]) / m.Scales[j]
+	}
+	return z
+}
+
+func (m *ScoreModel) logit(z []float64) float64 {
+	sum := m.Bias
+	for j, v := range z {
+		sum += m.Weights[j] * v
+	}
+	return sum
+}
+
+// predict returns the win probability of a deal and the features that
+// moved it most from an average deal
+func (m *ScoreModel) predict(row []float64) (float64, []ScoreFactor) {
+	z := m.standardize(row)
+	factors := make([]ScoreFactor, len(z))
+	for j, v := range z {
+		factors[j] = ScoreFactor{
+			Feature: m.Features[j],
+			Label:   featureLabels[m.Features[j]],
+			Value:   row[j],
+			Impact:  round2(m.Weights[j] * v),
+		}
+	}
+	sort.SliceStable(factors, func(a, b int) bool {
+		return math.Abs(factors[a].Impact) > math.Abs(factors[b].Impact)
+	})
+	if len(factors) > topFactors {
+		factors = factors[:topFactors]
+	}
+	return round2(sigmoid(m.logit(z)) * 100), factors
+}
+
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+var (
+	// ErrNotEnoughHistory is returned while too few opportunities have closed
+	// as won and lost to learn from
+	ErrNotEnoughHistory = errors.New("not enough closed opportunities to train a scoring model")
+	// ErrScoringRunning is returned while the tenant's scores are being refreshed
+	ErrScoringRunning = apperr.Conflict("deal scoring is already running")
+)
+
+// activityModules hold the tasks, calls and meetings logged against deals
+// through related_module and related_id
+var activityModules = []string{"tasks", "calls", "meetings"}
+
+// scoringBatch bounds the IDs sent in one $in query
+const scoringBatch = 500
+
+// stageMove is one stage change from the audit log
+type stageMove struct {
+	from, to string
+	at       time.Time
+}
+
+// scoredDeal is an opportunity with its raw features
+type scoredDeal struct {
+	id       string
+	name     string
+	features []float64
+	won      bool
+}
+
+// ScoreAll retrains and rescores every tenant. Tenants without enough
+// closed history keep their last scores.
+func (s *ForecastServiceImpl) ScoreAll(ctx context.Context) error {
+	ids, err := s.OrgRepo.ListIDs(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		tenantCtx := context.WithValue(ctx, common_models.TenantIDKey, id.Hex())
+		if _, err := s.RefreshDealScores(tenantCtx); err != nil && !errors.Is(err, ErrNotEnoughHistory) && !errors.Is(err, ErrScoringRunning) {
+			log.Printf("deal scoring: refresh for tenant %s failed: %v", id.Hex(), err)
+		}
+	}
+	return nil
+}
+
+// RefreshDealScores trains the tenant's model on its recently closed
+// opportunities and rescores the open ones
+func (s *ForecastServiceImpl) RefreshDealScores(ctx context.Context) (*ScoreModel, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key := tenantID.Hex()
+	if _, busy := s.scoring.LoadOrStore(key, struct{}{}); busy {
+		return nil, ErrScoringRunning
+	}
+	defer s.scoring.Delete(key)
+
+	module := DefaultGoalModule
+	stages := s.openStages(ctx, module)
+	now := time.Now()
+
+	closed, err := s.RecordRepo.List(ctx, module, map[string]any{
+		DefaultGoalStageField: bson.M{"$in": []string{DefaultGoalWonStage, DefaultLostStage}},
+	}, nil, maxTrainingDeals, 0, "updated_at", -1)
+	if err != nil {
+		return nil, err
+	}
+	training, err := s.dealFeatures(ctx, module, closed, stages, now)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([][]float64, len(training))
+	won := make([]bool, len(training))
+	wins := 0
+	for i, d := range training {
+		rows[i], won[i] = d.features, d.won
+		if d.won {
+			wins++
+		}
+	}
+	if len(training) < minTrainingDeals || wins == 0 || wins == len(training) {
+		return nil, ErrNotEnoughHistory
+	}
+
+	model := trainModel(rows, won)
+	model.Module = module
+	model.TrainedAt = now
+	if err := s.ScoreRepo.SaveModel(ctx, model); err != nil {
+		return nil, err
+	}
+
+	open, err := s.RecordRepo.List(ctx, module, map[string]any{
+		DefaultGoalStageField: bson.M{"$nin": []string{DefaultGoalWonStage, DefaultLostStage}},
+	}, nil, maxScoredDeals, 0, "updated_at", -1)
+	if err != nil {
+		return nil, err
+	}
+	deals, err := s.dealFeatures(ctx, module, open, stages, now)
+	if err != nil {
+		return nil, err
+	}
+	scores := make([]DealScore, 0, len(deals))
+	for _, d := range deals {
+		probability, factors := model.predict(d.features)
+		scores = append(scores, DealScore{
+			RecordID:    d.id,
+			Name:        d.name,
+			Probability: probability,
+			Factors:     factors,
+			ModelID:     model.ID,
+			ScoredAt:    now,
+		})
+	}
+	if err := s.ScoreRepo.ReplaceScores(ctx, module, scores); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+func (s *ForecastServiceImpl) GetDealScoreModel(ctx context.Context) (*ScoreModel, error) {
+	return s.ScoreRepo.GetModel(ctx, DefaultGoalModule)
+}
+
+// GetDealScore returns the score of an open opportunity the user can read
+func (s *ForecastServiceImpl) GetDealScore(ctx context.Context, recordID string, userID primitive.ObjectID) (*DealScore, error) {
+	score, err := s.ScoreRepo.GetScore(ctx, DefaultGoalModule, recordID)
+	if err != nil {
+		return nil, err
+	}
+	visible, err := s.visibleScores(ctx, []DealScore{*score}, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(visible) == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &visible[0], nil
+}
+
+// ListDealScores returns the scores of the open opportunities the user can
+// read, most likely to win first
+func (s *ForecastServiceImpl) ListDealScores(ctx context.Context, limit, offset int64, userID primitive.ObjectID) ([]DealScore, error) {
+	scores, err := s.ScoreRepo.ListScores(ctx, DefaultGoalModule, maxScoredDeals)
+	if err != nil {
+		return nil, err
+	}
+	visible, err := s.visibleScores(ctx, scores, userID)
+	if err != nil {
+		return nil, err
+	}
+	if offset >= int64(len(visible)) {
+		return []DealScore{}, nil
+	}
+	visible = visible[offset:]
+	if limit > 0 && limit < int64(len(visible)) {
+		visible = visible[:limit]
+	}
+	return visible, nil
+}
+
+// visibleScores drops scores of records outside the user's record access
+// and factors revealing fields hidden from them
+func (s *ForecastServiceImpl) visibleScores(ctx context.Context, scores []DealScore, userID primitive.ObjectID) ([]DealScore, error) {
+	accessFilter, err := s.RoleService.GetAccessFilter(ctx, userID, DefaultGoalModule, "read")
+	if err != nil {
+		return nil, err
+	}
+	if len(accessFilter) > 0 {
+		readable := make(map[string]bool, len(scores))
+		for start := 0; start < len(scores); start += scoringBatch {
+			end := min(start+scoringBatch, len(scores))
+			ids := make([]primitive.ObjectID, 0, end-start)
+			for _, sc := range scores[start:end] {
+				if oid, err := primitive.ObjectIDFromHex(sc.RecordID); err == nil {
+					ids = append(ids, oid)
+				}
+			}
+			records, err := s.RecordRepo.List(ctx, DefaultGoalModule, map[string]any{"_id": bson.M{"$in": ids}}, accessFilter, int64(len(ids)), 0, "_id", 1)
+			if err != nil {
+				return nil, err
+			}
+			for _, rec := range records {
+				if oid, ok := rec["_id"].(primitive.ObjectID); ok {
+					readable[oid.Hex()] = true
+				}
+			}
+		}
+		kept := scores[:0]
+		for _, sc := range scores {
+			if readable[sc.RecordID] {
+				kept = append(kept, sc)
+			}
+		}
+		scores = kept
+	}
+
+	perms, _ := s.RoleService.GetFieldPermissions(ctx, userID, DefaultGoalModule)
+	hidden := map[string]bool{
+		FeatureAmount: perms[DefaultGoalAmountField] == role.FieldPermNone,
+		FeatureStage:  perms[DefaultGoalStageField] == role.FieldPermNone,
+	}
+	if hidden[FeatureAmount] || hidden[FeatureStage] {
+		for i := range scores {
+			factors := []ScoreFactor{}
+			for _, f := range scores[i].Factors {
+				if !hidden[f.Feature] {
+					factors = append(factors, f)
+				}
+			}
+			scores[i].Factors = factors
+		}
+	}
+	return scores, nil
+}
+
+// openStages returns the module's open stage values in pipeline order
+func (s *ForecastServiceImpl) openStages(ctx context.Context, module string) []string {
+	var stages []string
+	mod, err := s.ModuleRepo.FindByName(ctx, module)
+	if err != nil {
+		return stages
+	}
+	for _, f := range mod.Fields {
+		if f.Name != DefaultGoalStageField {
+			continue
+		}
+		for _, opt := range f.Options {
+			if !isClosedStage(opt.Value) {
+				stages = append(stages, opt.Value)
+			}
+		}
+	}
+	return stages
+}
+
+func isClosedStage(stage string) bool {
+	return stage == DefaultGoalWonStage || stage == DefaultLostStage
+}
+
+// dealFeatures computes the raw features of the given opportunities. A
+// closed deal is described as it stood before closing: its stage is the one
+// it closed from and its age runs to the close.
+func (s *ForecastServiceImpl) dealFeatures(ctx context.Context, module string, records []map[string]any, stages []string, now time.Time) ([]scoredDeal, error) {
+	ids := make([]string, 0, len(records))
+	for _, rec := range records {
+		if oid, ok := rec["_id"].(primitive.ObjectID); ok {
+			ids = append(ids, oid.Hex())
+		}
+	}
+	moves, err := s.stageHistory(ctx, module, ids)
+	if err != nil {
+		return nil, err
+	}
+	activities, err := s.activityCounts(ctx, module, ids)
+	if err != nil {
+		return nil, err
+	}
+	rank := make(map[string]int, len(stages))
+	for i, stage := range stages {
+		rank[stage] = i
+	}
+
+	deals := make([]scoredDeal, 0, len(records))
+	for _, rec := range records {
+		oid, ok := rec["_id"].(primitive.ObjectID)
+		if !ok {
+			continue
+		}
+		id := oid.Hex()
+		stage, _ := rec[DefaultGoalStageField].(string)
+		name, _ := rec["name"].(string)
+		created, _ := toTime(rec["created_at"])
+
+		history := moves[id]
+		regressions := 0
+		for _, m := range history {
+			from, okFrom := rank[m.from]
+			to, okTo := rank[m.to]
+			if okFrom && okTo && to < from {
+				regressions++
+			}
+		}
+
+		end := now
+		current := stage
+		if isClosedStage(stage) {
+			// Stage before the close; without history the first stage
+			current = ""
+			if t, ok := toTime(rec[DefaultGoalDateField]); ok {
+				end = t
+			} else if t, ok := toTime(rec["updated_at"]); ok {
+				end = t
+			}
+			for i := len(history) - 1; i >= 0; i-- {
+				if isClosedStage(history[i].to) {
+					current, end = history[i].from, history[i].at
+					break
+				}
+			}
+		}
+		ageDays := 0.0
+		if !created.IsZero() && end.After(created) {
+			ageDays = end.Sub(created).Hours() / 24
+		}
+
+		deals = append(deals, scoredDeal{
+			id:   id,
+			name: name,
+			won:  stage == DefaultGoalWonStage,
+			features: []float64{
+				math.Log1p(math.Max(toFloat(rec[DefaultGoalAmountField]), 0)),
+				float64(rank[current]),
+				float64(len(history)),
+				float64(regressions),
+				ageDays,
+				float64(activities[id]),
+			},
+		})
+	}
+	return deals, nil
+}
+
+// stageHistory returns each record's stage changes, oldest first
+func (s *ForecastServiceImpl) stageHistory(ctx context.Context, module string, ids []string) (map[string][]stageMove, error) {
+	moves := make(map[string][]stageMove, len(ids))
+	for start := 0; start < len(ids); start += scoringBatch {
+		batch := ids[start:min(start+scoringBatch, len(ids))]
+		logs, err := s.AuditService.ListLogs(ctx, map[string]interface{}{
+			"module":                           module,
+			"action":                           common_models.AuditActionUpdate,
+			"record_id":                        bson.M{"$in": batch},
+			"changes." + DefaultGoalStageField: bson.M{"$exists": true},
+		}, 1, int64(len(batch))*50)
+		if err != nil {
+			return nil, err
+		}
+		// Logs arrive newest first
+		for i := len(logs) - 1; i >= 0; i-- {
+			change := logs[i].Changes[DefaultGoalStageField]
+			from, _ := change.Old.(string)
+			to, _ := change.New.(string)
+			if from == to {
+				continue
+			}
+			moves[logs[i].RecordID] = append(moves[logs[i].RecordID], stageMove{from: from, to: to, at: logs[i].Timestamp})
+		}
+	}
+	return moves, nil
+}
+
+// activityCounts counts the activities logged against each record
+func (s *ForecastServiceImpl) activityCounts(ctx context.Context, module string, ids []string) (map[string]int, error) {
+	counts := make(map[string]int, len(ids))
+	for start := 0; start < len(ids); start += scoringBatch {
+		batch := ids[start:min(start+scoringBatch, len(ids))]
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{
+				"data.related_module": module,
+				"data.related_id":     bson.M{"$in": batch},
+			}}},
+			{{Key: "$group", Value: bson.M{"_id": "$data.related_id", "count": bson.M{"$sum": 1}}}},
+		}
+		for _, activityModule := range activityModules {
+			rows, err := s.RecordRepo.Aggregate(ctx, activityModule, pipeline)
+			if err != nil {
+				return nil, err
+			}
+			for _, row := range rows {
+				if id, ok := row["_id"].(string); ok {
+					counts[id] += int(toFloat(row["count"]))
+				}
+			}
+		}
+	}
+	return counts, nil
+}
+
+func toTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, !t.IsZero()
+	case primitive.DateTime:
+		return t.Time(), true
+	case string:
+		if parsed, err := time.Parse(time.RFC3339, t); err == nil {
+			return parsed, true
+		}
+		if parsed, err := time.Parse("2006-01-02", t); err == nil {
+			return parsed, true
+		}
+	}
+	return time.Time{}, false
+}
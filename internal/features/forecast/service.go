@@ -0,0 +1,413 @@
+package forecast
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/group"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/organization"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/role"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var ErrGoalNotFound = apperr.NotFound("goal not found")
+
+type ForecastService interface {
+	CreateGoal(ctx context.Context, goal *SalesGoal) error
+	GetGoal(ctx context.Context, id string) (*SalesGoal, error)
+	ListGoals(ctx context.Context, filter map[string]interface{}) ([]SalesGoal, error)
+	UpdateGoal(ctx context.Context, goal *SalesGoal) error
+	DeleteGoal(ctx context.Context, id string) error
+
+	GetAttainment(ctx context.Context, id string) (*GoalAttainment, error)
+	GetAttainmentSummary(ctx context.Context, at time.Time, ownerType GoalOwnerType) ([]GoalAttainment, error)
+
+	// RefreshDealScores retrains the tenant's win-probability model and
+	// rescores its open opportunities
+	RefreshDealScores(ctx context.Context) (*ScoreModel, error)
+	// ScoreAll refreshes the deal scores of every tenant
+	ScoreAll(ctx context.Context) error
+	GetDealScoreModel(ctx context.Context) (*ScoreModel, error)
+	GetDealScore(ctx context.Context, recordID string, userID primitive.ObjectID) (*DealScore, error)
+	ListDealScores(ctx context.Context, limit, offset int64, userID primitive.ObjectID) ([]DealScore, error)
+}
+
+type ForecastServiceImpl struct {
+	GoalRepo     GoalRepository
+	RecordRepo   record.RecordRepository
+	GroupRepo    group.GroupRepository
+	AuditService audit.AuditService
+
+	ScoreRepo   ScoreRepository
+	ModuleRepo  module.ModuleRepository
+	OrgRepo     organization.OrganizationRepository
+	RoleService role.RoleService
+
+	scoring sync.Map // Tenants whose deal scores are being refreshed
+}
+
+func NewForecastService(
+	goalRepo GoalRepository,
+	recordRepo record.RecordRepository,
+	groupRepo group.GroupRepository,
+	auditService audit.AuditService,
+	scoreRepo ScoreRepository,
+	moduleRepo module.ModuleRepository,
+	orgRepo organization.OrganizationRepository,
+	roleService role.RoleService,
+) ForecastService {
+	return &ForecastServiceImpl{
+		GoalRepo:     goalRepo,
+		RecordRepo:   recordRepo,
+		GroupRepo:    groupRepo,
+		AuditService: auditService,
+		ScoreRepo:    scoreRepo,
+		ModuleRepo:   moduleRepo,
+		OrgRepo:      orgRepo,
+		RoleService:  roleService,
+	}
+}
+
+func (s *ForecastServiceImpl) CreateGoal(ctx context.Context, goal *SalesGoal) error {
+	if err := s.normalizeGoal(goal); err != nil {
+		return err
+	}
+
+	if err := s.GoalRepo.Create(ctx, goal); err != nil {
+		return err
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionCreate, "sales_goals", goal.ID.Hex(), map[string]common_models.Change{
+		"goal": {New: goal},
+	})
+	return nil
+}
+
+func (s *ForecastServiceImpl) GetGoal(ctx context.Context, id string) (*SalesGoal, error) {
+	return s.GoalRepo.Get(ctx, id)
+}
+
+func (s *ForecastServiceImpl) ListGoals(ctx context.Context, filter map[string]interface{}) ([]SalesGoal, error) {
+	return s.GoalRepo.List(ctx, filter)
+}
+
+func (s *ForecastServiceImpl) UpdateGoal(ctx context.Context, goal *SalesGoal) error {
+	if err := s.normalizeGoal(goal); err != nil {
+		return err
+	}
+
+	oldGoal, _ := s.GoalRepo.Get(ctx, goal.ID.Hex())
+	if err := s.GoalRepo.Update(ctx, goal); err != nil {
+		return err
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionUpdate, "sales_goals", goal.ID.Hex(), map[string]common_models.Change{
+		"goal": {Old: oldGoal, New: goal},
+	})
+	return nil
+}
+
+func (s *ForecastServiceImpl) DeleteGoal(ctx context.Context, id string) error {
+	oldGoal, _ := s.GoalRepo.Get(ctx, id)
+	if err := s.GoalRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionDelete, "sales_goals", id, map[string]common_models.Change{
+		"goal": {Old: oldGoal, New: "DELETED"},
+	})
+	return nil
+}
+
+func (s *ForecastServiceImpl) GetAttainment(ctx context.Context, id string) (*GoalAttainment, error) {
+	goal, err := s.GoalRepo.Get(ctx, id)
+	if err != nil {
+		return nil, ErrGoalNotFound
+	}
+	return s.calculateAttainment(ctx, goal, time.Now())
+}
+
+// GetAttainmentSummary calculates attainment for every goal whose period contains the given time
+func (s *ForecastServiceImpl) GetAttainmentSummary(ctx context.Context, at time.Time, ownerType GoalOwnerType) ([]GoalAttainment, error) {
+	filter := bson.M{
+		"period_start": bson.M{"$lte": at},
+		"period_end":   bson.M{"$gt": at},
+	}
+	if ownerType != "" {
+		filter["owner_type"] = ownerType
+	}
+
+	goals, err := s.GoalRepo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]GoalAttainment, 0, len(goals))
+	for i := range goals {
+		attainment, err := s.calculateAttainment(ctx, &goals[i], at)
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate attainment for goal '%s': %v", goals[i].Name, err)
+		}
+		results = append(results, *attainment)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].AttainmentPct > results[j].AttainmentPct
+	})
+
+	return results, nil
+}
+
+func (s *ForecastServiceImpl) normalizeGoal(goal *SalesGoal) error {
+	if goal.Name == "" {
+		return errors.New("goal name is required")
+	}
+	if goal.OwnerType != GoalOwnerUser && goal.OwnerType != GoalOwnerTeam {
+		return errors.New("owner_type must be 'user' or 'team'")
+	}
+	if goal.OwnerID.IsZero() {
+		return errors.New("owner_id is required")
+	}
+	if goal.TargetAmount <= 0 {
+		return errors.New("target_amount must be greater than zero")
+	}
+	if goal.PeriodStart.IsZero() {
+		return errors.New("period_start is required")
+	}
+
+	if goal.PeriodEnd.IsZero() {
+		switch goal.Period {
+		case GoalPeriodMonthly:
+			goal.PeriodEnd = goal.PeriodStart.AddDate(0, 1, 0)
+		case GoalPeriodQuarterly:
+			goal.PeriodEnd = goal.PeriodStart.AddDate(0, 3, 0)
+		case GoalPeriodYearly:
+			goal.PeriodEnd = goal.PeriodStart.AddDate(1, 0, 0)
+		default:
+			return errors.New("period_end is required for custom periods")
+		}
+	}
+	if goal.Period == "" {
+		goal.Period = GoalPeriodCustom
+	}
+	if !goal.PeriodEnd.After(goal.PeriodStart) {
+		return errors.New("period_end must be after period_start")
+	}
+
+	if goal.Module == "" {
+		goal.Module = DefaultGoalModule
+	}
+	if goal.AmountField == "" {
+		goal.AmountField = DefaultGoalAmountField
+	}
+	if goal.StageField == "" {
+		goal.StageField = DefaultGoalStageField
+	}
+	if goal.WonStage == "" {
+		goal.WonStage = DefaultGoalWonStage
+	}
+	if goal.DateField == "" {
+		goal.DateField = DefaultGoalDateField
+	}
+	return nil
+}
+
+// resolveOwners returns the user IDs whose opportunities count toward a goal
+func (s *ForecastServiceImpl) resolveOwners(ctx context.Context, goal *SalesGoal) ([]primitive.ObjectID, error) {
+	if goal.OwnerType == GoalOwnerTeam {
+		g, err := s.GroupRepo.FindByID(ctx, goal.OwnerID)
+		if err != nil {
+			return nil, fmt.Errorf("team not found: %v", err)
+		}
+		return g.Members, nil
+	}
+	return []primitive.ObjectID{goal.OwnerID}, nil
+}
+
+func (s *ForecastServiceImpl) calculateAttainment(ctx context.Context, goal *SalesGoal, now time.Time) (*GoalAttainment, error) {
+	_ = s.normalizeGoal(goal)
+
+	owners, err := s.resolveOwners(ctx, goal)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GoalAttainment{
+		Goal:  goal,
+		Trend: []TrendPoint{},
+	}
+
+	granularity := "week"
+	if goal.PeriodEnd.Sub(goal.PeriodStart) > 100*24*time.Hour {
+		granularity = "month"
+	}
+	result.TrendGranularity = granularity
+
+	// Elapsed share of the period drives the run-rate projection
+	total := goal.PeriodEnd.Sub(goal.PeriodStart)
+	elapsed := now.Sub(goal.PeriodStart)
+	result.ElapsedPct = clamp(float64(elapsed)/float64(total), 0, 1) * 100
+
+	if len(owners) == 0 {
+		result.Remaining = goal.TargetAmount
+		return result, nil
+	}
+
+	stagePath := "data." + goal.StageField
+	datePath := "data." + goal.DateField
+	amountPath := "$data." + goal.AmountField
+
+	// 1. Closed-won revenue per day within the period
+	wonPipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			stagePath:    goal.WonStage,
+			datePath:     bson.M{"$gte": goal.PeriodStart, "$lt": goal.PeriodEnd},
+			"data.owner": bson.M{"$in": owners},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"$dateToString": bson.M{
+				"format": "%Y-%m-%d",
+				"date":   "$" + datePath,
+			}},
+			"amount": bson.M{"$sum": amountPath},
+			"deals":  bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	daily, err := s.RecordRepo.Aggregate(ctx, goal.Module, wonPipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make(map[time.Time]*TrendPoint)
+	for _, row := range daily {
+		day, ok := row["_id"].(string)
+		if !ok {
+			continue
+		}
+		t, err := time.Parse("2006-01-02", day)
+		if err != nil {
+			continue
+		}
+		amount := toFloat(row["amount"])
+		deals := int(toFloat(row["deals"]))
+
+		result.Achieved += amount
+		result.DealsWon += deals
+
+		key := bucketStart(t, granularity)
+		if _, ok := buckets[key]; !ok {
+			buckets[key] = &TrendPoint{PeriodStart: key}
+		}
+		buckets[key].Amount += amount
+		buckets[key].Deals += deals
+	}
+
+	// Zero-fill buckets from period start through now (or period end) for a continuous series
+	last := now
+	if last.After(goal.PeriodEnd) {
+		last = goal.PeriodEnd
+	}
+	cumulative := 0.0
+	for b := bucketStart(goal.PeriodStart.UTC(), granularity); !b.After(last); b = nextBucket(b, granularity) {
+		point := TrendPoint{PeriodStart: b}
+		if existing, ok := buckets[b]; ok {
+			point = *existing
+		}
+		cumulative += point.Amount
+		point.Cumulative = cumulative
+		result.Trend = append(result.Trend, point)
+	}
+
+	// 2. Open pipeline closing within the period
+	openPipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			stagePath:    bson.M{"$ne": goal.WonStage, "$not": primitive.Regex{Pattern: "^closed", Options: "i"}},
+			datePath:     bson.M{"$gte": goal.PeriodStart, "$lt": goal.PeriodEnd},
+			"data.owner": bson.M{"$in": owners},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    nil,
+			"amount": bson.M{"$sum": amountPath},
+		}}},
+	}
+	if open, err := s.RecordRepo.Aggregate(ctx, goal.Module, openPipeline); err == nil && len(open) > 0 {
+		result.OpenPipeline = toFloat(open[0]["amount"])
+	}
+
+	result.Remaining = math.Max(goal.TargetAmount-result.Achieved, 0)
+	result.AttainmentPct = round2(result.Achieved / goal.TargetAmount * 100)
+
+	// 3. Linear run-rate projection to period end
+	switch {
+	case result.ElapsedPct >= 100:
+		result.ProjectedAmount = result.Achieved
+	case result.ElapsedPct > 0:
+		result.ProjectedAmount = result.Achieved / (result.ElapsedPct / 100)
+	}
+	result.ProjectedAmount = round2(result.ProjectedAmount)
+	result.ProjectedPct = round2(result.ProjectedAmount / goal.TargetAmount * 100)
+	result.OnTrack = result.ProjectedAmount >= goal.TargetAmount
+	result.ElapsedPct = round2(result.ElapsedPct)
+
+	return result, nil
+}
+
+func bucketStart(t time.Time, granularity string) time.Time {
+	t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if granularity == "month" {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	// Weeks start on Monday
+	offset := (int(t.Weekday()) + 6) % 7
+	return t.AddDate(0, 0, -offset)
+}
+
+func nextBucket(t time.Time, granularity string) time.Time {
+	if granularity == "month" {
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 7)
+}
+
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	case int:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case primitive.Decimal128:
+		f, _ := strconv.ParseFloat(n.String(), 64)
+		return f
+	}
+	return 0
+}
+
+func clamp(v, min, max float64) float64 {
+	return math.Min(math.Max(v, min), max)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
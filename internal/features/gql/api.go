@@ -0,0 +1,29 @@
+package gql
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type GraphQLApi struct {
+	controller *GraphQLController
+	config     *config.Config
+}
+
+func NewGraphQLApi(controller *GraphQLController, config *config.Config) *GraphQLApi {
+	return &GraphQLApi{
+		controller: controller,
+		config:     config,
+	}
+}
+
+// Setup registers the GraphQL endpoint. Permissions are enforced per module
+// inside the resolvers, the same way as the REST record routes.
+func (h *GraphQLApi) Setup(app *fiber.App) {
+	graphql := app.Group("/api/graphql", middleware.AuthMiddleware(h.config.SkipAuth))
+
+	graphql.Post("/", h.controller.Execute)
+	graphql.Get("/schema", h.controller.Schema)
+}
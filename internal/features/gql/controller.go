@@ -0,0 +1,71 @@
+package gql
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"go-crm/internal/common/session"
+)
+
+type GraphQLController struct {
+	GraphQLService GraphQLService
+}
+
+func NewGraphQLController(graphQLService GraphQLService) *GraphQLController {
+	return &GraphQLController{
+		GraphQLService: graphQLService,
+	}
+}
+
+// Execute godoc
+// @Summary Execute GraphQL query
+// @Description Run a GraphQL query against the schema generated from module definitions. Lookup fields resolve to nested records.
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Param request body Request true "GraphQL request"
+// @Success 200 {object} Response
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/graphql [post]
+func (c *GraphQLController) Execute(ctx *fiber.Ctx) error {
+	var req Request
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	resp, err := c.GraphQLService.Execute(ctx.UserContext(), userID, req)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(resp)
+}
+
+// Schema godoc
+// @Summary Get GraphQL schema
+// @Description Returns the schema generated for the current tenant in SDL form
+// @Tags graphql
+// @Produce plain
+// @Success 200 {string} string
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/graphql/schema [get]
+func (c *GraphQLController) Schema(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	sdl, err := c.GraphQLService.SDL(ctx.UserContext(), userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	ctx.Set(fiber.HeaderContentType, "text/plain; charset=utf-8")
+	return ctx.SendString(sdl)
+}
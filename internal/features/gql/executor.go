@@ -0,0 +1,681 @@
+package gql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/vektah/gqlparser/v2/validator"
+)
+
+// Queries are parsed and validated by gqlparser against the schema's SDL,
+// which also backs introspection; the executor below resolves the validated
+// document, batching lookups per depth.
+
+// Thunk defers a resolver's result. Resolvers that go through a Loader return
+// a Thunk so every key requested at one depth is collected before the first
+// Thunk triggers the batch fetch.
+type Thunk func() (any, error)
+
+type ResolveParams struct {
+	Ctx    context.Context
+	Source any
+	Args   map[string]any
+}
+
+type ResolveFunc func(p ResolveParams) (any, error)
+
+// TypeRef points at a named type, optionally wrapped in a list
+type TypeRef struct {
+	Name    string
+	List    bool
+	NonNull bool
+}
+
+func (t TypeRef) String() string {
+	s := t.Name
+	if t.List {
+		s = "[" + s + "]"
+	}
+	if t.NonNull {
+		s += "!"
+	}
+	return s
+}
+
+type Argument struct {
+	Name string
+	Type TypeRef
+}
+
+type FieldDef struct {
+	Name        string
+	Description string
+	Type        TypeRef
+	Args        []Argument
+	Resolve     ResolveFunc
+}
+
+type ObjectType struct {
+	Name        string
+	Description string
+	Fields      []*FieldDef
+	fieldIndex  map[string]*FieldDef
+}
+
+func (o *ObjectType) AddField(f *FieldDef) {
+	if o.fieldIndex == nil {
+		o.fieldIndex = make(map[string]*FieldDef)
+	}
+	if _, exists := o.fieldIndex[f.Name]; exists {
+		return
+	}
+	o.fieldIndex[f.Name] = f
+	o.Fields = append(o.Fields, f)
+}
+
+func (o *ObjectType) Field(name string) *FieldDef {
+	return o.fieldIndex[name]
+}
+
+// ScalarType serializes resolved Go values into JSON-friendly output
+type ScalarType struct {
+	Name        string
+	Description string
+	Serialize   func(v any) (any, error)
+}
+
+// InputType is only described in the SDL; input values reach resolvers as plain maps
+type InputType struct {
+	Name   string
+	Fields []Argument
+}
+
+type Schema struct {
+	Query   *ObjectType
+	Objects map[string]*ObjectType
+	Scalars map[string]*ScalarType
+	Inputs  map[string]*InputType
+
+	// MaxDepth rejects queries nesting deeper than this many selection sets
+	// (0 = unlimited). Introspection is bounded by gqlparser's own rule.
+	MaxDepth int
+
+	compileOnce sync.Once
+	compiled    *ast.Schema
+	compileErr  error
+}
+
+func NewSchema(query *ObjectType) *Schema {
+	s := &Schema{
+		Query:   query,
+		Objects: map[string]*ObjectType{query.Name: query},
+		Scalars: make(map[string]*ScalarType),
+		Inputs:  make(map[string]*InputType),
+	}
+	for _, scalar := range builtinScalars() {
+		s.Scalars[scalar.Name] = scalar
+	}
+	addIntrospection(s)
+	return s
+}
+
+func (s *Schema) AddObject(o *ObjectType) {
+	s.Objects[o.Name] = o
+}
+
+func (s *Schema) AddInput(i *InputType) {
+	s.Inputs[i.Name] = i
+}
+
+// isIntrospection reports whether a type or field name is reserved for
+// introspection, which the SDL leaves out
+func isIntrospection(name string) bool {
+	return strings.HasPrefix(name, "__")
+}
+
+// describe renders a description as a GraphQL string literal
+func describe(desc string) string {
+	quoted, _ := json.Marshal(desc)
+	return string(quoted)
+}
+
+// SDL renders the schema in GraphQL schema definition language
+func (s *Schema) SDL() string {
+	var b strings.Builder
+	for _, name := range sortedKeys(s.Scalars) {
+		if isBuiltinScalar(name) || isIntrospection(name) {
+			continue
+		}
+		if desc := s.Scalars[name].Description; desc != "" {
+			fmt.Fprintf(&b, "%s\n", describe(desc))
+		}
+		fmt.Fprintf(&b, "scalar %s\n\n", name)
+	}
+
+	for _, name := range sortedKeys(s.Inputs) {
+		fmt.Fprintf(&b, "input %s {\n", name)
+		for _, f := range s.Inputs[name].Fields {
+			fmt.Fprintf(&b, "  %s: %s\n", f.Name, f.Type.String())
+		}
+		b.WriteString("}\n\n")
+	}
+
+	writeObject := func(o *ObjectType) {
+		if o.Description != "" {
+			fmt.Fprintf(&b, "%s\n", describe(o.Description))
+		}
+		fmt.Fprintf(&b, "type %s {\n", o.Name)
+		for _, f := range o.Fields {
+			if isIntrospection(f.Name) {
+				continue
+			}
+			if f.Description != "" {
+				fmt.Fprintf(&b, "  %s\n", describe(f.Description))
+			}
+			b.WriteString("  " + f.Name)
+			if len(f.Args) > 0 {
+				args := make([]string, len(f.Args))
+				for i, a := range f.Args {
+					args[i] = a.Name + ": " + a.Type.String()
+				}
+				b.WriteString("(" + strings.Join(args, ", ") + ")")
+			}
+			b.WriteString(": " + f.Type.String() + "\n")
+		}
+		b.WriteString("}\n\n")
+	}
+
+	writeObject(s.Query)
+	for _, name := range sortedKeys(s.Objects) {
+		if name != s.Query.Name && !isIntrospection(name) {
+			writeObject(s.Objects[name])
+		}
+	}
+	return strings.TrimSpace(b.String()) + "\n"
+}
+
+// compile loads the SDL into the gqlparser schema queries are validated and
+// introspected against. Types must not change once the schema has executed.
+func (s *Schema) compile() (*ast.Schema, error) {
+	s.compileOnce.Do(func() {
+		// gqlparser rejects a Query type without fields of its own
+		queryable := false
+		for _, f := range s.Query.Fields {
+			queryable = queryable || !isIntrospection(f.Name)
+		}
+		if !queryable {
+			s.compileErr = fmt.Errorf("the schema has no queryable types")
+			return
+		}
+		s.compiled, s.compileErr = gqlparser.LoadSchema(&ast.Source{Name: "schema.graphql", Input: s.SDL()})
+	})
+	return s.compiled, s.compileErr
+}
+
+// Request is the standard GraphQL-over-HTTP request body
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+type Error struct {
+	Message   string     `json:"message"`
+	Locations []Location `json:"locations,omitempty"`
+	Path      []any      `json:"path,omitempty"`
+}
+
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type Response struct {
+	Data   any     `json:"data"`
+	Errors []Error `json:"errors,omitempty"`
+}
+
+// Execute parses, validates and runs a query document against the schema
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	schema, err := s.compile()
+	if err != nil {
+		return errorResponse(err)
+	}
+	doc, errs := gqlparser.LoadQueryWithRules(schema, req.Query, nil)
+	if len(errs) > 0 {
+		return &Response{Errors: fromGQLErrors(errs)}
+	}
+
+	op, err := selectOperation(doc, req.OperationName)
+	if err != nil {
+		return errorResponse(err)
+	}
+	if op.Operation != ast.Query {
+		return errorResponse(fmt.Errorf("%s operations are not supported", op.Operation))
+	}
+
+	vars, err := validator.VariableValues(schema, op, req.Variables)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	e := &executor{schema: s, vars: vars}
+	if s.MaxDepth > 0 {
+		if depth := e.depth(op.SelectionSet, map[string]bool{}); depth > s.MaxDepth {
+			return errorResponse(fmt.Errorf("query depth %d exceeds the maximum of %d", depth, s.MaxDepth))
+		}
+	}
+	results := e.executeSelectionSet(ctx, s.Query, []any{nil}, op.SelectionSet, nil)
+
+	resp := &Response{Errors: e.errors}
+	if results != nil {
+		resp.Data = results[0]
+	}
+	return resp
+}
+
+func errorResponse(err error) *Response {
+	if gqlErr, ok := err.(*gqlerror.Error); ok {
+		return &Response{Errors: fromGQLErrors(gqlerror.List{gqlErr})}
+	}
+	return &Response{Errors: []Error{{Message: err.Error()}}}
+}
+
+func fromGQLErrors(errs gqlerror.List) []Error {
+	out := make([]Error, len(errs))
+	for i, err := range errs {
+		out[i] = Error{Message: err.Message}
+		for _, loc := range err.Locations {
+			out[i].Locations = append(out[i].Locations, Location{Line: loc.Line, Column: loc.Column})
+		}
+		for _, p := range err.Path {
+			switch el := p.(type) {
+			case ast.PathName:
+				out[i].Path = append(out[i].Path, string(el))
+			case ast.PathIndex:
+				out[i].Path = append(out[i].Path, int(el))
+			}
+		}
+	}
+	return out
+}
+
+func selectOperation(doc *ast.QueryDocument, name string) (*ast.OperationDefinition, error) {
+	if name == "" {
+		if len(doc.Operations) > 1 {
+			return nil, fmt.Errorf("operationName is required when the document contains multiple operations")
+		}
+		return doc.Operations[0], nil
+	}
+	if op := doc.Operations.ForName(name); op != nil {
+		return op, nil
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+type executor struct {
+	schema *Schema
+	vars   map[string]any
+	errors []Error
+}
+
+type collectedField struct {
+	key    string
+	fields []*ast.Field
+}
+
+func (e *executor) addError(path []any, format string, args ...any) {
+	e.errors = append(e.errors, Error{Message: fmt.Sprintf(format, args...), Path: append([]any{}, path...)})
+}
+
+// executeSelectionSet resolves the selection for every source object of the
+// same type at once, so nested lookups across all sources share one batch.
+// paths holds the response path of each source, used for error reporting.
+func (e *executor) executeSelectionSet(ctx context.Context, typ *ObjectType, sources []any, selections ast.SelectionSet, paths [][]any) []*OrderedMap {
+	if paths == nil {
+		paths = make([][]any, len(sources))
+	}
+
+	fields := e.collectFields(typ, selections, nil, map[string]bool{})
+	results := make([]*OrderedMap, len(sources))
+	for i := range results {
+		results[i] = NewOrderedMap()
+	}
+
+	type pending struct {
+		def    *FieldDef
+		values []any
+	}
+	resolved := make([]pending, len(fields))
+
+	// Phase 1: invoke resolvers for every field of every source. Loader-backed
+	// resolvers only queue keys here.
+	for fi, cf := range fields {
+		node := cf.fields[0]
+		if node.Name == "__typename" {
+			continue
+		}
+		def := typ.Field(node.Name)
+		if def == nil {
+			e.addError(nil, "Cannot query field %q on type %q", node.Name, typ.Name)
+			continue
+		}
+		args := node.ArgumentMap(e.vars)
+
+		values := make([]any, len(sources))
+		for i, src := range sources {
+			v, err := def.Resolve(ResolveParams{Ctx: ctx, Source: src, Args: args})
+			if err != nil {
+				e.addError(append(paths[i], cf.key), "%s", err.Error())
+				continue
+			}
+			values[i] = v
+		}
+		resolved[fi] = pending{def: def, values: values}
+	}
+
+	// Phase 2: force thunks; the first one of each loader fetches the whole batch
+	for fi, cf := range fields {
+		for i, v := range resolved[fi].values {
+			if thunk, ok := v.(Thunk); ok {
+				val, err := thunk()
+				if err != nil {
+					e.addError(append(paths[i], cf.key), "%s", err.Error())
+					val = nil
+				}
+				resolved[fi].values[i] = val
+			}
+		}
+	}
+
+	// Phase 3: complete values, recursing once per field across all sources
+	for fi, cf := range fields {
+		node := cf.fields[0]
+		if node.Name == "__typename" {
+			for _, r := range results {
+				r.Set(cf.key, typ.Name)
+			}
+			continue
+		}
+		p := resolved[fi]
+		if p.def == nil {
+			continue
+		}
+		completed := e.completeValues(ctx, p.def.Type, p.values, cf, paths)
+		for i, r := range results {
+			r.Set(cf.key, completed[i])
+		}
+	}
+
+	return results
+}
+
+func (e *executor) completeValues(ctx context.Context, ref TypeRef, values []any, cf collectedField, paths [][]any) []any {
+	out := make([]any, len(values))
+	subSelection := mergeSelectionSets(cf.fields)
+
+	if scalar, ok := e.schema.Scalars[ref.Name]; ok {
+		if len(subSelection) > 0 {
+			e.addError(nil, "Field %q of type %q must not have a selection", cf.fields[0].Name, ref.String())
+			return out
+		}
+		for i, v := range values {
+			out[i] = e.serializeScalar(scalar, ref, v, append(paths[i], cf.key))
+		}
+		return out
+	}
+
+	objType, ok := e.schema.Objects[ref.Name]
+	if !ok {
+		e.addError(nil, "Unknown type %q", ref.Name)
+		return out
+	}
+	if len(subSelection) == 0 {
+		e.addError(nil, "Field %q of type %q must have a selection of subfields", cf.fields[0].Name, ref.String())
+		return out
+	}
+
+	// Flatten every non-null object (and list element) into one batch
+	var children []any
+	var childPaths [][]any
+	type slot struct {
+		parent int
+		index  int // -1 for non-list
+	}
+	var slots []slot
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		if ref.List {
+			items := toSlice(v)
+			list := make([]any, len(items))
+			out[i] = list
+			for j, item := range items {
+				if item == nil {
+					continue
+				}
+				children = append(children, item)
+				childPaths = append(childPaths, append(append([]any{}, paths[i]...), cf.key, j))
+				slots = append(slots, slot{parent: i, index: j})
+			}
+			continue
+		}
+		children = append(children, v)
+		childPaths = append(childPaths, append(append([]any{}, paths[i]...), cf.key))
+		slots = append(slots, slot{parent: i, index: -1})
+	}
+	if len(children) == 0 {
+		return out
+	}
+
+	childResults := e.executeSelectionSet(ctx, objType, children, subSelection, childPaths)
+	for k, s := range slots {
+		if s.index < 0 {
+			out[s.parent] = childResults[k]
+		} else {
+			out[s.parent].([]any)[s.index] = childResults[k]
+		}
+	}
+	return out
+}
+
+func (e *executor) serializeScalar(scalar *ScalarType, ref TypeRef, v any, path []any) any {
+	if v == nil {
+		return nil
+	}
+	if ref.List {
+		items := toSlice(v)
+		list := make([]any, len(items))
+		for i, item := range items {
+			list[i] = e.serializeScalar(scalar, TypeRef{Name: ref.Name}, item, append(append([]any{}, path...), i))
+		}
+		return list
+	}
+	out, err := scalar.Serialize(v)
+	if err != nil {
+		e.addError(path, "%s", err.Error())
+		return nil
+	}
+	return out
+}
+
+// collectFields groups fields by response key, expanding fragments whose type
+// condition matches and honouring @skip/@include
+func (e *executor) collectFields(typ *ObjectType, selections ast.SelectionSet, into []collectedField, visited map[string]bool) []collectedField {
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *ast.Field:
+			if !e.shouldInclude(s.Directives) {
+				continue
+			}
+			key := s.Alias
+			if key == "" {
+				key = s.Name
+			}
+			found := false
+			for i := range into {
+				if into[i].key == key {
+					into[i].fields = append(into[i].fields, s)
+					found = true
+					break
+				}
+			}
+			if !found {
+				into = append(into, collectedField{key: key, fields: []*ast.Field{s}})
+			}
+		case *ast.InlineFragment:
+			if !e.shouldInclude(s.Directives) || (s.TypeCondition != "" && s.TypeCondition != typ.Name) {
+				continue
+			}
+			into = e.collectFields(typ, s.SelectionSet, into, visited)
+		case *ast.FragmentSpread:
+			if !e.shouldInclude(s.Directives) || visited[s.Name] {
+				continue
+			}
+			frag := s.Definition
+			if frag == nil {
+				e.addError(nil, "Unknown fragment %q", s.Name)
+				continue
+			}
+			if frag.TypeCondition != typ.Name {
+				continue
+			}
+			visited[s.Name] = true
+			into = e.collectFields(typ, frag.SelectionSet, into, visited)
+		}
+	}
+	return into
+}
+
+// depth counts the nested selection sets of data fields; introspection is
+// bounded by gqlparser's MaxIntrospectionDepth rule instead
+func (e *executor) depth(selections ast.SelectionSet, visited map[string]bool) int {
+	max := 0
+	for _, sel := range selections {
+		d := 0
+		switch s := sel.(type) {
+		case *ast.Field:
+			if len(s.SelectionSet) > 0 && !isIntrospection(s.Name) {
+				d = 1 + e.depth(s.SelectionSet, visited)
+			}
+		case *ast.InlineFragment:
+			d = e.depth(s.SelectionSet, visited)
+		case *ast.FragmentSpread:
+			if s.Definition != nil && !visited[s.Name] {
+				visited[s.Name] = true
+				d = e.depth(s.Definition.SelectionSet, visited)
+				delete(visited, s.Name)
+			}
+		}
+		if d > max {
+			max = d
+		}
+	}
+	return max
+}
+
+func (e *executor) shouldInclude(directives ast.DirectiveList) bool {
+	for _, d := range directives {
+		cond, _ := d.ArgumentMap(e.vars)["if"].(bool)
+		switch d.Name {
+		case "skip":
+			if cond {
+				return false
+			}
+		case "include":
+			if !cond {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func mergeSelectionSets(fields []*ast.Field) ast.SelectionSet {
+	if len(fields) == 1 {
+		return fields[0].SelectionSet
+	}
+	var merged ast.SelectionSet
+	for _, f := range fields {
+		merged = append(merged, f.SelectionSet...)
+	}
+	return merged
+}
+
+func toSlice(v any) []any {
+	switch list := v.(type) {
+	case []any:
+		return list
+	case []map[string]any:
+		out := make([]any, len(list))
+		for i, item := range list {
+			out[i] = item
+		}
+		return out
+	case []string:
+		out := make([]any, len(list))
+		for i, item := range list {
+			out[i] = item
+		}
+		return out
+	}
+	return []any{v}
+}
+
+// OrderedMap keeps response keys in selection order when marshalled to JSON
+type OrderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+func NewOrderedMap() *OrderedMap {
+	return &OrderedMap{values: make(map[string]any)}
+}
+
+func (m *OrderedMap) Set(key string, value any) {
+	if _, exists := m.values[key]; !exists {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *OrderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		val, err := json.Marshal(m.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,261 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// testSchema has a self-referencing Person type whose friend resolves through
+// a Thunk, so deferred values and depth can be checked
+func testSchema() *Schema {
+	person := &ObjectType{Name: "Person", Description: "A \"person\""}
+	person.AddField(&FieldDef{Name: "name", Type: TypeRef{Name: ScalarString}, Resolve: mapField("name")})
+	person.AddField(&FieldDef{Name: "age", Type: TypeRef{Name: ScalarInt}, Resolve: mapField("age")})
+	person.AddField(&FieldDef{Name: "friend", Type: TypeRef{Name: "Person"}, Resolve: func(p ResolveParams) (any, error) {
+		name, _ := sourceValue(p.Source, "name").(string)
+		return Thunk(func() (any, error) {
+			return map[string]any{"name": name + "'s friend", "age": 30}, nil
+		}), nil
+	}})
+
+	query := &ObjectType{Name: "Query"}
+	query.AddField(&FieldDef{Name: "people", Type: TypeRef{Name: "Person", List: true}, Args: []Argument{{Name: "limit", Type: TypeRef{Name: ScalarInt}}}, Resolve: func(p ResolveParams) (any, error) {
+		people := []any{map[string]any{"name": "Ada", "age": 36}, map[string]any{"name": "Alan", "age": 41}}
+		if limit := intArg(p.Args, "limit", int64(len(people))); limit < int64(len(people)) {
+			people = people[:limit]
+		}
+		return people, nil
+	}})
+
+	schema := NewSchema(query)
+	schema.AddObject(person)
+	schema.MaxDepth = 3
+	return schema
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]any
+		operation string
+		want      string
+		wantError string
+	}{
+		{
+			name:  "fields and arguments",
+			query: `{ people(limit: 1) { name age } }`,
+			want:  `{"people":[{"name":"Ada","age":36}]}`,
+		},
+		{
+			name:  "aliases and typename",
+			query: `{ first: people(limit: 1) { who: name __typename } }`,
+			want:  `{"first":[{"who":"Ada","__typename":"Person"}]}`,
+		},
+		{
+			name:  "fragments",
+			query: `query { people(limit: 1) { ...Basics ... on Person { age } } } fragment Basics on Person { name }`,
+			want:  `{"people":[{"name":"Ada","age":36}]}`,
+		},
+		{
+			name:      "skip and include",
+			query:     `query($hide: Boolean!) { people(limit: 1) { name @skip(if: $hide) age @include(if: $hide) } }`,
+			variables: map[string]any{"hide": true},
+			want:      `{"people":[{"age":36}]}`,
+		},
+		{
+			name:      "variables",
+			query:     `query($n: Int) { people(limit: $n) { name } }`,
+			variables: map[string]any{"n": 1},
+			want:      `{"people":[{"name":"Ada"}]}`,
+		},
+		{
+			name:      "selected operation",
+			query:     `query A { people(limit: 1) { name } } query B { people { age } }`,
+			operation: "B",
+			want:      `{"people":[{"age":36},{"age":41}]}`,
+		},
+		{
+			name:  "nested thunks",
+			query: `{ people { friend { name } } }`,
+			want:  `{"people":[{"friend":{"name":"Ada's friend"}},{"friend":{"name":"Alan's friend"}}]}`,
+		},
+		{
+			name:      "syntax error",
+			query:     `{ people { name }`,
+			wantError: "Expected Name",
+		},
+		{
+			name:      "unknown field",
+			query:     `{ people { email } }`,
+			wantError: `Cannot query field "email" on type "Person"`,
+		},
+		{
+			name:      "missing selection",
+			query:     `{ people }`,
+			wantError: `must have a selection of subfields`,
+		},
+		{
+			name:      "wrong argument type",
+			query:     `{ people(limit: "one") { name } }`,
+			wantError: "Int cannot represent non-integer value",
+		},
+		{
+			name:      "missing variable",
+			query:     `query($hide: Boolean!) { people { name @skip(if: $hide) } }`,
+			wantError: "must be defined",
+		},
+		{
+			name:      "ambiguous operation",
+			query:     `query A { people { name } } query B { people { age } }`,
+			wantError: "operationName is required",
+		},
+		{
+			name:      "unknown operation",
+			query:     `query A { people { name } }`,
+			operation: "B",
+			wantError: `unknown operation "B"`,
+		},
+		{
+			name:      "mutation",
+			query:     `mutation { people { name } }`,
+			wantError: `does not support operation type "mutation"`,
+		},
+		{
+			name:  "depth at the limit",
+			query: `{ people { friend { friend { name } } } }`,
+			want:  `{"people":[{"friend":{"friend":{"name":"Ada's friend's friend"}}},{"friend":{"friend":{"name":"Alan's friend's friend"}}}]}`,
+		},
+		{
+			name:      "depth over the limit",
+			query:     `{ people { friend { friend { friend { name } } } } }`,
+			wantError: "query depth 4 exceeds the maximum of 3",
+		},
+		{
+			name:      "depth through fragments",
+			query:     `{ people { ...F } } fragment F on Person { friend { friend { friend { name } } } }`,
+			wantError: "query depth 4 exceeds the maximum of 3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := testSchema().Execute(context.Background(), Request{Query: tt.query, Variables: tt.variables, OperationName: tt.operation})
+			if tt.wantError != "" {
+				if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, tt.wantError) {
+					t.Fatalf("errors = %+v, want %q", resp.Errors, tt.wantError)
+				}
+				if resp.Data != nil {
+					t.Errorf("data = %v, want none for a rejected query", resp.Data)
+				}
+				return
+			}
+			if len(resp.Errors) > 0 {
+				t.Fatalf("unexpected errors: %+v", resp.Errors)
+			}
+			got, err := json.Marshal(resp.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("data = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteReportsErrorLocations(t *testing.T) {
+	resp := testSchema().Execute(context.Background(), Request{Query: "{\n  people { email }\n}"})
+	if len(resp.Errors) != 1 {
+		t.Fatalf("errors = %+v, want one", resp.Errors)
+	}
+	if locs := resp.Errors[0].Locations; len(locs) != 1 || locs[0].Line != 2 || locs[0].Column != 12 {
+		t.Errorf("locations = %+v, want line 2 column 12", locs)
+	}
+}
+
+func TestExecuteRejectsEmptySchema(t *testing.T) {
+	resp := NewSchema(&ObjectType{Name: "Query"}).Execute(context.Background(), Request{Query: `{ __typename }`})
+	if len(resp.Errors) != 1 || resp.Errors[0].Message != "the schema has no queryable types" {
+		t.Errorf("errors = %+v", resp.Errors)
+	}
+}
+
+func TestIntrospection(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "query type",
+			query: `{ __schema { queryType { name kind } } }`,
+			want:  `{"__schema":{"queryType":{"name":"Query","kind":"OBJECT"}}}`,
+		},
+		{
+			name:  "type fields",
+			query: `{ __type(name: "Person") { kind description fields { name type { kind name ofType { name } } } } }`,
+			want:  `{"__type":{"kind":"OBJECT","description":"A \"person\"","fields":[{"name":"name","type":{"kind":"SCALAR","name":"String","ofType":null}},{"name":"age","type":{"kind":"SCALAR","name":"Int","ofType":null}},{"name":"friend","type":{"kind":"OBJECT","name":"Person","ofType":null}}]}}`,
+		},
+		{
+			name:  "query fields hide introspection",
+			query: `{ __type(name: "Query") { fields { name args { name type { name } } type { kind ofType { name } } } } }`,
+			want:  `{"__type":{"fields":[{"name":"people","args":[{"name":"limit","type":{"name":"Int"}}],"type":{"kind":"LIST","ofType":{"name":"Person"}}}]}}`,
+		},
+		{
+			name:  "wrapped types",
+			query: `{ __type(name: "__Schema") { fields(includeDeprecated: true) { name type { kind ofType { kind ofType { kind ofType { name } } } } } } }`,
+			want:  `{"__type":{"fields":[{"name":"description","type":{"kind":"SCALAR","ofType":null}},{"name":"types","type":{"kind":"NON_NULL","ofType":{"kind":"LIST","ofType":{"kind":"NON_NULL","ofType":{"name":"__Type"}}}}},{"name":"queryType","type":{"kind":"NON_NULL","ofType":{"kind":"OBJECT","ofType":null}}},{"name":"mutationType","type":{"kind":"OBJECT","ofType":null}},{"name":"subscriptionType","type":{"kind":"OBJECT","ofType":null}},{"name":"directives","type":{"kind":"NON_NULL","ofType":{"kind":"LIST","ofType":{"kind":"NON_NULL","ofType":{"name":"__Directive"}}}}}]}}`,
+		},
+		{
+			name:  "enum values",
+			query: `{ __type(name: "__TypeKind") { kind enumValues { name } } }`,
+			want:  `{"__type":{"kind":"ENUM","enumValues":[{"name":"SCALAR"},{"name":"OBJECT"},{"name":"INTERFACE"},{"name":"UNION"},{"name":"ENUM"},{"name":"INPUT_OBJECT"},{"name":"LIST"},{"name":"NON_NULL"}]}}`,
+		},
+		{
+			name:  "unknown type",
+			query: `{ __type(name: "Missing") { name } }`,
+			want:  `{"__type":null}`,
+		},
+		{
+			name:  "directives",
+			query: `{ __schema { directives { name locations args { name defaultValue } } } }`,
+			want:  `{"__schema":{"directives":[{"name":"defer","locations":["FRAGMENT_SPREAD","INLINE_FRAGMENT"],"args":[{"name":"if","defaultValue":"true"},{"name":"label","defaultValue":null}]},{"name":"deprecated","locations":["FIELD_DEFINITION","ARGUMENT_DEFINITION","INPUT_FIELD_DEFINITION","ENUM_VALUE"],"args":[{"name":"reason","defaultValue":"\"No longer supported\""}]},{"name":"include","locations":["FIELD","FRAGMENT_SPREAD","INLINE_FRAGMENT"],"args":[{"name":"if","defaultValue":null}]},{"name":"oneOf","locations":["INPUT_OBJECT"],"args":[]},{"name":"skip","locations":["FIELD","FRAGMENT_SPREAD","INLINE_FRAGMENT"],"args":[{"name":"if","defaultValue":null}]},{"name":"specifiedBy","locations":["SCALAR"],"args":[{"name":"url","defaultValue":null}]}]}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := testSchema().Execute(context.Background(), Request{Query: tt.query})
+			if len(resp.Errors) > 0 {
+				t.Fatalf("unexpected errors: %+v", resp.Errors)
+			}
+			got, err := json.Marshal(resp.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("data = %s\nwant   %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntrospectionIsNotCountedTowardsDepth(t *testing.T) {
+	resp := testSchema().Execute(context.Background(), Request{Query: `{ __schema { types { fields { type { ofType { name } } } } } }`})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("unexpected errors: %+v", resp.Errors)
+	}
+}
+
+func TestSDLEscapesDescriptions(t *testing.T) {
+	sdl := testSchema().SDL()
+	if !strings.Contains(sdl, `"A \"person\""`) {
+		t.Errorf("description not escaped in:\n%s", sdl)
+	}
+	if strings.Contains(sdl, "__") {
+		t.Errorf("SDL contains introspection types:\n%s", sdl)
+	}
+}
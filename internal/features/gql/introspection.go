@@ -0,0 +1,329 @@
+package gql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// Introspection resolves __schema and __type from the compiled gqlparser
+// schema, which holds the spec's introspection types alongside ours.
+
+// introType is a named type or a LIST/NON_NULL wrapper around one
+type introType struct {
+	def    *ast.Definition
+	kind   string
+	ofType *introType
+}
+
+func namedType(def *ast.Definition) any {
+	if def == nil {
+		return nil
+	}
+	return &introType{def: def, kind: string(def.Kind)}
+}
+
+func typeOf(schema *ast.Schema, t *ast.Type) *introType {
+	var it *introType
+	if t.Elem != nil {
+		it = &introType{kind: "LIST", ofType: typeOf(schema, t.Elem)}
+	} else {
+		def := schema.Types[t.NamedType]
+		it = &introType{def: def, kind: string(def.Kind)}
+	}
+	if t.NonNull {
+		it = &introType{kind: "NON_NULL", ofType: it}
+	}
+	return it
+}
+
+// deprecation returns whether the directives deprecate their element and why
+func deprecation(directives ast.DirectiveList) (bool, any) {
+	d := directives.ForName("deprecated")
+	if d == nil {
+		return false, nil
+	}
+	if arg := d.Arguments.ForName("reason"); arg != nil && arg.Value != nil {
+		return true, arg.Value.Raw
+	}
+	return true, "No longer supported"
+}
+
+func includeDeprecated(p ResolveParams, directives ast.DirectiveList) bool {
+	deprecated, _ := deprecation(directives)
+	include, _ := p.Args["includeDeprecated"].(bool)
+	return include || !deprecated
+}
+
+func optionalString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func addIntrospection(s *Schema) {
+	str := TypeRef{Name: ScalarString}
+	nonNullStr := TypeRef{Name: ScalarString, NonNull: true}
+	nonNullBool := TypeRef{Name: ScalarBoolean, NonNull: true}
+	typeRef := TypeRef{Name: "__Type"}
+	nonNullType := TypeRef{Name: "__Type", NonNull: true}
+	typeList := TypeRef{Name: "__Type", List: true}
+	includeArg := []Argument{{Name: "includeDeprecated", Type: TypeRef{Name: ScalarBoolean}}}
+
+	for _, name := range []string{"__TypeKind", "__DirectiveLocation"} {
+		s.Scalars[name] = &ScalarType{Name: name, Serialize: serializeString}
+	}
+
+	schemaType := &ObjectType{Name: "__Schema"}
+	typeType := &ObjectType{Name: "__Type"}
+	fieldType := &ObjectType{Name: "__Field"}
+	inputValueType := &ObjectType{Name: "__InputValue"}
+	enumValueType := &ObjectType{Name: "__EnumValue"}
+	directiveType := &ObjectType{Name: "__Directive"}
+
+	schemaType.AddField(&FieldDef{Name: "description", Type: str, Resolve: func(p ResolveParams) (any, error) {
+		return optionalString(p.Source.(*ast.Schema).Description), nil
+	}})
+	schemaType.AddField(&FieldDef{Name: "types", Type: TypeRef{Name: "__Type", List: true, NonNull: true}, Resolve: func(p ResolveParams) (any, error) {
+		schema := p.Source.(*ast.Schema)
+		var types []any
+		for _, name := range sortedKeys(schema.Types) {
+			types = append(types, namedType(schema.Types[name]))
+		}
+		return types, nil
+	}})
+	schemaType.AddField(&FieldDef{Name: "queryType", Type: nonNullType, Resolve: func(p ResolveParams) (any, error) {
+		return namedType(p.Source.(*ast.Schema).Query), nil
+	}})
+	for _, name := range []string{"mutationType", "subscriptionType"} {
+		schemaType.AddField(&FieldDef{Name: name, Type: typeRef, Resolve: func(p ResolveParams) (any, error) {
+			return nil, nil
+		}})
+	}
+	schemaType.AddField(&FieldDef{Name: "directives", Type: TypeRef{Name: "__Directive", List: true, NonNull: true}, Resolve: func(p ResolveParams) (any, error) {
+		schema := p.Source.(*ast.Schema)
+		var directives []any
+		for _, name := range sortedKeys(schema.Directives) {
+			directives = append(directives, schema.Directives[name])
+		}
+		return directives, nil
+	}})
+
+	typeType.AddField(&FieldDef{Name: "kind", Type: TypeRef{Name: "__TypeKind", NonNull: true}, Resolve: func(p ResolveParams) (any, error) {
+		return p.Source.(*introType).kind, nil
+	}})
+	typeType.AddField(&FieldDef{Name: "name", Type: str, Resolve: func(p ResolveParams) (any, error) {
+		if t := p.Source.(*introType); t.def != nil {
+			return t.def.Name, nil
+		}
+		return nil, nil
+	}})
+	typeType.AddField(&FieldDef{Name: "description", Type: str, Resolve: func(p ResolveParams) (any, error) {
+		if t := p.Source.(*introType); t.def != nil {
+			return optionalString(t.def.Description), nil
+		}
+		return nil, nil
+	}})
+	typeType.AddField(&FieldDef{Name: "specifiedByURL", Type: str, Resolve: func(p ResolveParams) (any, error) {
+		if t := p.Source.(*introType); t.def != nil {
+			if d := t.def.Directives.ForName("specifiedBy"); d != nil {
+				if arg := d.Arguments.ForName("url"); arg != nil {
+					return arg.Value.Raw, nil
+				}
+			}
+		}
+		return nil, nil
+	}})
+	typeType.AddField(&FieldDef{Name: "fields", Type: TypeRef{Name: "__Field", List: true}, Args: includeArg, Resolve: func(p ResolveParams) (any, error) {
+		t := p.Source.(*introType)
+		if t.def == nil || (t.def.Kind != ast.Object && t.def.Kind != ast.Interface) {
+			return nil, nil
+		}
+		fields := []any{}
+		for _, f := range t.def.Fields {
+			if !isIntrospection(f.Name) && includeDeprecated(p, f.Directives) {
+				fields = append(fields, f)
+			}
+		}
+		return fields, nil
+	}})
+	typeType.AddField(&FieldDef{Name: "interfaces", Type: typeList, Resolve: func(p ResolveParams) (any, error) {
+		t := p.Source.(*introType)
+		if t.def == nil || (t.def.Kind != ast.Object && t.def.Kind != ast.Interface) {
+			return nil, nil
+		}
+		interfaces := []any{}
+		for _, name := range t.def.Interfaces {
+			interfaces = append(interfaces, namedType(s.compiled.Types[name]))
+		}
+		return interfaces, nil
+	}})
+	typeType.AddField(&FieldDef{Name: "possibleTypes", Type: typeList, Resolve: func(p ResolveParams) (any, error) {
+		t := p.Source.(*introType)
+		if t.def == nil || !t.def.IsAbstractType() {
+			return nil, nil
+		}
+		types := []any{}
+		for _, def := range s.compiled.GetPossibleTypes(t.def) {
+			types = append(types, namedType(def))
+		}
+		return types, nil
+	}})
+	typeType.AddField(&FieldDef{Name: "enumValues", Type: TypeRef{Name: "__EnumValue", List: true}, Args: includeArg, Resolve: func(p ResolveParams) (any, error) {
+		t := p.Source.(*introType)
+		if t.def == nil || t.def.Kind != ast.Enum {
+			return nil, nil
+		}
+		values := []any{}
+		for _, v := range t.def.EnumValues {
+			if includeDeprecated(p, v.Directives) {
+				values = append(values, v)
+			}
+		}
+		return values, nil
+	}})
+	typeType.AddField(&FieldDef{Name: "inputFields", Type: TypeRef{Name: "__InputValue", List: true}, Args: includeArg, Resolve: func(p ResolveParams) (any, error) {
+		t := p.Source.(*introType)
+		if t.def == nil || t.def.Kind != ast.InputObject {
+			return nil, nil
+		}
+		fields := []any{}
+		for _, f := range t.def.Fields {
+			if includeDeprecated(p, f.Directives) {
+				fields = append(fields, &ast.ArgumentDefinition{
+					Name:         f.Name,
+					Description:  f.Description,
+					Type:         f.Type,
+					DefaultValue: f.DefaultValue,
+					Directives:   f.Directives,
+				})
+			}
+		}
+		return fields, nil
+	}})
+	typeType.AddField(&FieldDef{Name: "ofType", Type: typeRef, Resolve: func(p ResolveParams) (any, error) {
+		if t := p.Source.(*introType); t.ofType != nil {
+			return t.ofType, nil
+		}
+		return nil, nil
+	}})
+	typeType.AddField(&FieldDef{Name: "isOneOf", Type: TypeRef{Name: ScalarBoolean}, Resolve: func(p ResolveParams) (any, error) {
+		t := p.Source.(*introType)
+		if t.def == nil || t.def.Kind != ast.InputObject {
+			return nil, nil
+		}
+		return t.def.Directives.ForName("oneOf") != nil, nil
+	}})
+
+	args := func(defs ast.ArgumentDefinitionList, p ResolveParams) []any {
+		out := []any{}
+		for _, a := range defs {
+			if includeDeprecated(p, a.Directives) {
+				out = append(out, a)
+			}
+		}
+		return out
+	}
+	argsField := &FieldDef{Name: "args", Type: TypeRef{Name: "__InputValue", List: true, NonNull: true}, Args: includeArg}
+
+	fieldType.AddField(&FieldDef{Name: "name", Type: nonNullStr, Resolve: func(p ResolveParams) (any, error) {
+		return p.Source.(*ast.FieldDefinition).Name, nil
+	}})
+	fieldType.AddField(&FieldDef{Name: "description", Type: str, Resolve: func(p ResolveParams) (any, error) {
+		return optionalString(p.Source.(*ast.FieldDefinition).Description), nil
+	}})
+	fieldArgs := *argsField
+	fieldArgs.Resolve = func(p ResolveParams) (any, error) {
+		return args(p.Source.(*ast.FieldDefinition).Arguments, p), nil
+	}
+	fieldType.AddField(&fieldArgs)
+	fieldType.AddField(&FieldDef{Name: "type", Type: nonNullType, Resolve: func(p ResolveParams) (any, error) {
+		return typeOf(s.compiled, p.Source.(*ast.FieldDefinition).Type), nil
+	}})
+	fieldType.AddField(&FieldDef{Name: "isDeprecated", Type: nonNullBool, Resolve: func(p ResolveParams) (any, error) {
+		deprecated, _ := deprecation(p.Source.(*ast.FieldDefinition).Directives)
+		return deprecated, nil
+	}})
+	fieldType.AddField(&FieldDef{Name: "deprecationReason", Type: str, Resolve: func(p ResolveParams) (any, error) {
+		_, reason := deprecation(p.Source.(*ast.FieldDefinition).Directives)
+		return reason, nil
+	}})
+
+	inputValueType.AddField(&FieldDef{Name: "name", Type: nonNullStr, Resolve: func(p ResolveParams) (any, error) {
+		return p.Source.(*ast.ArgumentDefinition).Name, nil
+	}})
+	inputValueType.AddField(&FieldDef{Name: "description", Type: str, Resolve: func(p ResolveParams) (any, error) {
+		return optionalString(p.Source.(*ast.ArgumentDefinition).Description), nil
+	}})
+	inputValueType.AddField(&FieldDef{Name: "type", Type: nonNullType, Resolve: func(p ResolveParams) (any, error) {
+		return typeOf(s.compiled, p.Source.(*ast.ArgumentDefinition).Type), nil
+	}})
+	inputValueType.AddField(&FieldDef{Name: "defaultValue", Type: str, Resolve: func(p ResolveParams) (any, error) {
+		if v := p.Source.(*ast.ArgumentDefinition).DefaultValue; v != nil {
+			return v.String(), nil
+		}
+		return nil, nil
+	}})
+	inputValueType.AddField(&FieldDef{Name: "isDeprecated", Type: nonNullBool, Resolve: func(p ResolveParams) (any, error) {
+		deprecated, _ := deprecation(p.Source.(*ast.ArgumentDefinition).Directives)
+		return deprecated, nil
+	}})
+	inputValueType.AddField(&FieldDef{Name: "deprecationReason", Type: str, Resolve: func(p ResolveParams) (any, error) {
+		_, reason := deprecation(p.Source.(*ast.ArgumentDefinition).Directives)
+		return reason, nil
+	}})
+
+	enumValueType.AddField(&FieldDef{Name: "name", Type: nonNullStr, Resolve: func(p ResolveParams) (any, error) {
+		return p.Source.(*ast.EnumValueDefinition).Name, nil
+	}})
+	enumValueType.AddField(&FieldDef{Name: "description", Type: str, Resolve: func(p ResolveParams) (any, error) {
+		return optionalString(p.Source.(*ast.EnumValueDefinition).Description), nil
+	}})
+	enumValueType.AddField(&FieldDef{Name: "isDeprecated", Type: nonNullBool, Resolve: func(p ResolveParams) (any, error) {
+		deprecated, _ := deprecation(p.Source.(*ast.EnumValueDefinition).Directives)
+		return deprecated, nil
+	}})
+	enumValueType.AddField(&FieldDef{Name: "deprecationReason", Type: str, Resolve: func(p ResolveParams) (any, error) {
+		_, reason := deprecation(p.Source.(*ast.EnumValueDefinition).Directives)
+		return reason, nil
+	}})
+
+	directiveType.AddField(&FieldDef{Name: "name", Type: nonNullStr, Resolve: func(p ResolveParams) (any, error) {
+		return p.Source.(*ast.DirectiveDefinition).Name, nil
+	}})
+	directiveType.AddField(&FieldDef{Name: "description", Type: str, Resolve: func(p ResolveParams) (any, error) {
+		return optionalString(p.Source.(*ast.DirectiveDefinition).Description), nil
+	}})
+	directiveType.AddField(&FieldDef{Name: "isRepeatable", Type: nonNullBool, Resolve: func(p ResolveParams) (any, error) {
+		return p.Source.(*ast.DirectiveDefinition).IsRepeatable, nil
+	}})
+	directiveType.AddField(&FieldDef{Name: "locations", Type: TypeRef{Name: "__DirectiveLocation", List: true, NonNull: true}, Resolve: func(p ResolveParams) (any, error) {
+		var locations []any
+		for _, l := range p.Source.(*ast.DirectiveDefinition).Locations {
+			locations = append(locations, string(l))
+		}
+		return locations, nil
+	}})
+	directiveArgs := *argsField
+	directiveArgs.Resolve = func(p ResolveParams) (any, error) {
+		return args(p.Source.(*ast.DirectiveDefinition).Arguments, p), nil
+	}
+	directiveType.AddField(&directiveArgs)
+
+	for _, o := range []*ObjectType{schemaType, typeType, fieldType, inputValueType, enumValueType, directiveType} {
+		s.AddObject(o)
+	}
+
+	s.Query.AddField(&FieldDef{Name: "__schema", Type: TypeRef{Name: "__Schema", NonNull: true}, Resolve: func(p ResolveParams) (any, error) {
+		return s.compiled, nil
+	}})
+	s.Query.AddField(&FieldDef{Name: "__type", Type: typeRef, Args: []Argument{{Name: "name", Type: nonNullStr}}, Resolve: func(p ResolveParams) (any, error) {
+		name, _ := p.Args["name"].(string)
+		if strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("name is required")
+		}
+		return namedType(s.compiled.Types[name]), nil
+	}})
+}
@@ -0,0 +1,117 @@
+package gql
+
+import (
+	"context"
+	"sync"
+
+	"go-crm/internal/features/role"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// recordLoader batches lookup traversals into one query per module and depth.
+// Records are filtered with the caller's read access filter and stripped of
+// hidden fields, matching what RecordService returns for the same user.
+type recordLoader struct {
+	service *GraphQLServiceImpl
+	ctx     context.Context
+	userID  primitive.ObjectID
+	module  string
+
+	mu      sync.Mutex
+	pending []string
+	done    map[string]bool
+	cache   map[string]map[string]any
+	err     error
+}
+
+func newRecordLoader(ctx context.Context, service *GraphQLServiceImpl, userID primitive.ObjectID, module string) *recordLoader {
+	return &recordLoader{
+		service: service,
+		ctx:     ctx,
+		userID:  userID,
+		module:  module,
+		done:    make(map[string]bool),
+		cache:   make(map[string]map[string]any),
+	}
+}
+
+// Load queues an ID and returns a Thunk that yields the record, or nil when it
+// does not exist or the user cannot read it
+func (l *recordLoader) Load(id string) Thunk {
+	l.mu.Lock()
+	if !l.done[id] {
+		queued := false
+		for _, p := range l.pending {
+			if p == id {
+				queued = true
+				break
+			}
+		}
+		if !queued {
+			l.pending = append(l.pending, id)
+		}
+	}
+	l.mu.Unlock()
+
+	return func() (any, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if !l.done[id] {
+			l.dispatch()
+		}
+		if l.err != nil {
+			return nil, l.err
+		}
+		if rec, ok := l.cache[id]; ok {
+			return rec, nil
+		}
+		return nil, nil
+	}
+}
+
+// dispatch fetches every pending ID in one query; callers hold l.mu
+func (l *recordLoader) dispatch() {
+	keys := l.pending
+	l.pending = nil
+	for _, k := range keys {
+		l.done[k] = true
+	}
+
+	oids := make([]primitive.ObjectID, 0, len(keys))
+	for _, k := range keys {
+		if oid, err := primitive.ObjectIDFromHex(k); err == nil {
+			oids = append(oids, oid)
+		}
+	}
+	if len(oids) == 0 {
+		return
+	}
+
+	accessFilter, err := l.service.RoleService.GetAccessFilter(l.ctx, l.userID, l.module, "read")
+	if err != nil {
+		l.err = err
+		return
+	}
+
+	records, err := l.service.RecordRepo.List(l.ctx, l.module, bson.M{"_id": bson.M{"$in": oids}}, accessFilter, int64(len(oids)), 0, "", 0)
+	if err != nil {
+		l.err = err
+		return
+	}
+
+	perms, err := l.service.RoleService.GetFieldPermissions(l.ctx, l.userID, l.module)
+	for _, rec := range records {
+		if err == nil && perms != nil {
+			for field, p := range perms {
+				if p == role.FieldPermNone {
+					delete(rec, field)
+				}
+			}
+		}
+		if oid, ok := rec["_id"].(primitive.ObjectID); ok {
+			l.cache[oid.Hex()] = rec
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/role"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type loaderModuleRepo struct {
+	module.ModuleRepository
+	modules []common_models.Entity
+}
+
+func (r *loaderModuleRepo) List(ctx context.Context) ([]common_models.Entity, error) {
+	return r.modules, nil
+}
+
+// loaderRecordService lists the deals; other calls are not expected
+type loaderRecordService struct {
+	record.RecordService
+	deals []map[string]any
+}
+
+func (s *loaderRecordService) ListRecordsWithExpression(ctx context.Context, moduleName string, filters []common_models.Filter, expr *record.FilterExpr, page, limit int64, sortBy string, sortOrder string, userID primitive.ObjectID) ([]map[string]any, int64, error) {
+	return s.deals, int64(len(s.deals)), nil
+}
+
+// loaderRecordRepo returns the contacts matching the $in filter and records
+// each query
+type loaderRecordRepo struct {
+	record.RecordRepository
+	contacts map[primitive.ObjectID]map[string]any
+	queries  []map[string]any
+	access   []map[string]any
+}
+
+func (r *loaderRecordRepo) List(ctx context.Context, moduleName string, filter map[string]any, accessFilter map[string]any, limit, offset int64, sortBy string, sortOrder int) ([]map[string]any, error) {
+	r.queries = append(r.queries, filter)
+	r.access = append(r.access, accessFilter)
+	var out []map[string]any
+	for _, id := range filter["_id"].(bson.M)["$in"].([]primitive.ObjectID) {
+		if c, ok := r.contacts[id]; ok {
+			copied := make(map[string]any, len(c))
+			for k, v := range c {
+				copied[k] = v
+			}
+			out = append(out, copied)
+		}
+	}
+	return out, nil
+}
+
+// loaderRoleService hides the contact's ssn field
+type loaderRoleService struct {
+	role.RoleService
+}
+
+func (s *loaderRoleService) GetAccessFilter(ctx context.Context, userID primitive.ObjectID, moduleName string, action string) (bson.M, error) {
+	return bson.M{"owner": userID}, nil
+}
+
+func (s *loaderRoleService) GetFieldPermissions(ctx context.Context, userID primitive.ObjectID, moduleName string) (map[string]string, error) {
+	if moduleName == "contact" {
+		return map[string]string{"ssn": role.FieldPermNone, "name": role.FieldPermReadOnly}, nil
+	}
+	return nil, nil
+}
+
+func TestRecordLoaderBatchesAndStripsHiddenFields(t *testing.T) {
+	ada, alan, missing := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	repo := &loaderRecordRepo{contacts: map[primitive.ObjectID]map[string]any{
+		ada:  {"_id": ada, "name": "Ada", "ssn": "123"},
+		alan: {"_id": alan, "name": "Alan", "ssn": "456"},
+	}}
+	service := &GraphQLServiceImpl{
+		ModuleRepo: &loaderModuleRepo{modules: []common_models.Entity{
+			{Name: "contact", Fields: []common_models.ModuleField{
+				{Name: "name", Type: common_models.FieldTypeText},
+				{Name: "ssn", Type: common_models.FieldTypeText},
+			}},
+			{Name: "deal", Fields: []common_models.ModuleField{
+				{Name: "name", Type: common_models.FieldTypeText},
+				{Name: "contact", Type: common_models.FieldTypeLookup, Lookup: &common_models.LookupDef{LookupModule: "contact"}},
+			}},
+		}},
+		RecordRepo: repo,
+		RecordService: &loaderRecordService{deals: []map[string]any{
+			{"name": "First", "contact": ada},
+			{"name": "Second", "contact": map[string]any{"id": alan.Hex(), "name": "Alan"}},
+			{"name": "Third", "contact": ada},
+			{"name": "Orphan", "contact": missing},
+		}},
+		RoleService: &loaderRoleService{},
+	}
+
+	userID := primitive.NewObjectID()
+	resp, err := service.Execute(context.Background(), userID, Request{Query: `{ deal_list { data { name contact { name ssn } } } }`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Errors) > 0 {
+		t.Fatalf("unexpected errors: %+v", resp.Errors)
+	}
+
+	got, _ := json.Marshal(resp.Data)
+	want := `{"deal_list":{"data":[{"name":"First","contact":{"name":"Ada","ssn":null}},{"name":"Second","contact":{"name":"Alan","ssn":null}},{"name":"Third","contact":{"name":"Ada","ssn":null}},{"name":"Orphan","contact":null}]}}`
+	if string(got) != want {
+		t.Errorf("data = %s\nwant   %s", got, want)
+	}
+
+	if len(repo.queries) != 1 {
+		t.Fatalf("contact queries = %d, want one batched query", len(repo.queries))
+	}
+	if ids := repo.queries[0]["_id"].(bson.M)["$in"].([]primitive.ObjectID); len(ids) != 3 {
+		t.Errorf("batched ids = %v, want the three distinct contacts", ids)
+	}
+	if owner := repo.access[0]["owner"]; owner != userID {
+		t.Errorf("access filter = %v, want the caller's read filter", repo.access[0])
+	}
+}
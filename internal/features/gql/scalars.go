@@ -0,0 +1,126 @@
+package gql
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ScalarID       = "ID"
+	ScalarString   = "String"
+	ScalarInt      = "Int"
+	ScalarFloat    = "Float"
+	ScalarBoolean  = "Boolean"
+	ScalarDateTime = "DateTime"
+	ScalarJSON     = "JSON"
+)
+
+func isBuiltinScalar(name string) bool {
+	switch name {
+	case ScalarID, ScalarString, ScalarInt, ScalarFloat, ScalarBoolean:
+		return true
+	}
+	return false
+}
+
+func builtinScalars() []*ScalarType {
+	return []*ScalarType{
+		{Name: ScalarID, Serialize: serializeString},
+		{Name: ScalarString, Serialize: serializeString},
+		{Name: ScalarInt, Serialize: serializeInt},
+		{Name: ScalarFloat, Serialize: serializeFloat},
+		{Name: ScalarBoolean, Serialize: serializeBoolean},
+		{Name: ScalarDateTime, Description: "RFC3339 timestamp", Serialize: serializeDateTime},
+		{Name: ScalarJSON, Description: "Arbitrary JSON value", Serialize: func(v any) (any, error) { return v, nil }},
+	}
+}
+
+func serializeString(v any) (any, error) {
+	switch val := v.(type) {
+	case string:
+		return val, nil
+	case primitive.ObjectID:
+		return val.Hex(), nil
+	case time.Time:
+		return val.Format(time.RFC3339), nil
+	case primitive.DateTime:
+		return val.Time().UTC().Format(time.RFC3339), nil
+	case map[string]any:
+		// Populated lookups ({id, name}) read as their display name
+		if name, ok := val["name"]; ok && name != nil {
+			return fmt.Sprint(name), nil
+		}
+		if id, ok := val["id"]; ok {
+			return serializeString(id)
+		}
+	}
+	return fmt.Sprint(v), nil
+}
+
+func serializeInt(v any) (any, error) {
+	f, err := serializeFloat(v)
+	if err != nil {
+		return nil, err
+	}
+	return int64(f.(float64)), nil
+}
+
+func serializeFloat(v any) (any, error) {
+	switch val := v.(type) {
+	case float64:
+		return val, nil
+	case float32:
+		return float64(val), nil
+	case int:
+		return float64(val), nil
+	case int32:
+		return float64(val), nil
+	case int64:
+		return float64(val), nil
+	case primitive.Decimal128:
+		f, err := strconv.ParseFloat(val.String(), 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot represent %v as a number", v)
+		}
+		return f, nil
+	case string:
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot represent %q as a number", val)
+		}
+		return f, nil
+	}
+	return nil, fmt.Errorf("cannot represent %v as a number", v)
+}
+
+func serializeBoolean(v any) (any, error) {
+	switch val := v.(type) {
+	case bool:
+		return val, nil
+	case string:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("cannot represent %q as a boolean", val)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("cannot represent %v as a boolean", v)
+}
+
+func serializeDateTime(v any) (any, error) {
+	switch val := v.(type) {
+	case time.Time:
+		if val.IsZero() {
+			return nil, nil
+		}
+		return val.UTC().Format(time.RFC3339), nil
+	case primitive.DateTime:
+		return val.Time().UTC().Format(time.RFC3339), nil
+	case string:
+		return val, nil
+	}
+	return nil, fmt.Errorf("cannot represent %v as a DateTime", v)
+}
@@ -0,0 +1,378 @@
+package gql
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/role"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const maxQueryDepth = 8
+
+type GraphQLService interface {
+	Execute(ctx context.Context, userID primitive.ObjectID, req Request) (*Response, error)
+	SDL(ctx context.Context, userID primitive.ObjectID) (string, error)
+}
+
+type GraphQLServiceImpl struct {
+	ModuleRepo    module.ModuleRepository
+	RecordRepo    record.RecordRepository
+	RecordService record.RecordService
+	RoleService   role.RoleService
+}
+
+func NewGraphQLService(
+	moduleRepo module.ModuleRepository,
+	recordRepo record.RecordRepository,
+	recordService record.RecordService,
+	roleService role.RoleService,
+) GraphQLService {
+	return &GraphQLServiceImpl{
+		ModuleRepo:    moduleRepo,
+		RecordRepo:    recordRepo,
+		RecordService: recordService,
+		RoleService:   roleService,
+	}
+}
+
+func (s *GraphQLServiceImpl) Execute(ctx context.Context, userID primitive.ObjectID, req Request) (*Response, error) {
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	schema, err := s.buildSchema(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return schema.Execute(ctx, req), nil
+}
+
+func (s *GraphQLServiceImpl) SDL(ctx context.Context, userID primitive.ObjectID) (string, error) {
+	schema, err := s.buildSchema(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	return schema.SDL(), nil
+}
+
+// buildSchema generates one object type per module of the tenant. The schema
+// is rebuilt per request so module changes apply immediately and loaders never
+// share cached records between users.
+func (s *GraphQLServiceImpl) buildSchema(ctx context.Context, userID primitive.ObjectID) (*Schema, error) {
+	modules, err := s.ModuleRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := &ObjectType{Name: "Query"}
+	schema := NewSchema(query)
+	schema.MaxDepth = maxQueryDepth
+	schema.AddInput(&InputType{Name: "FilterInput", Fields: []Argument{
+		{Name: "field", Type: TypeRef{Name: ScalarString, NonNull: true}},
+		{Name: "operator", Type: TypeRef{Name: ScalarString, NonNull: true}},
+		{Name: "value", Type: TypeRef{Name: ScalarJSON}},
+	}})
+
+	loaders := make(map[string]*recordLoader)
+	loaderFor := func(moduleName string) *recordLoader {
+		if l, ok := loaders[moduleName]; ok {
+			return l
+		}
+		l := newRecordLoader(ctx, s, userID, moduleName)
+		loaders[moduleName] = l
+		return l
+	}
+
+	// Register every type before adding fields so lookups can reference any module
+	types := make(map[string]*ObjectType, len(modules))
+	for _, m := range modules {
+		t := &ObjectType{Name: typeName(m.Name), Description: m.Label}
+		types[m.Name] = t
+		schema.AddObject(t)
+	}
+
+	for _, m := range modules {
+		t := types[m.Name]
+		addSystemFields(t)
+		for _, field := range m.Fields {
+			s.addModuleField(t, field, types, loaderFor)
+		}
+
+		page := &ObjectType{Name: t.Name + "Page"}
+		page.AddField(&FieldDef{Name: "data", Type: TypeRef{Name: t.Name, List: true, NonNull: true}, Resolve: mapField("data")})
+		page.AddField(&FieldDef{Name: "total", Type: TypeRef{Name: ScalarInt, NonNull: true}, Resolve: mapField("total")})
+		page.AddField(&FieldDef{Name: "page", Type: TypeRef{Name: ScalarInt, NonNull: true}, Resolve: mapField("page")})
+		page.AddField(&FieldDef{Name: "limit", Type: TypeRef{Name: ScalarInt, NonNull: true}, Resolve: mapField("limit")})
+		schema.AddObject(page)
+
+		s.addRootFields(query, m, t, page, userID)
+	}
+
+	return schema, nil
+}
+
+func addSystemFields(t *ObjectType) {
+	t.AddField(&FieldDef{Name: "id", Type: TypeRef{Name: ScalarID, NonNull: true}, Resolve: mapField("id")})
+	t.AddField(&FieldDef{Name: "created_at", Type: TypeRef{Name: ScalarDateTime}, Resolve: mapField("created_at")})
+	t.AddField(&FieldDef{Name: "updated_at", Type: TypeRef{Name: ScalarDateTime}, Resolve: mapField("updated_at")})
+	t.AddField(&FieldDef{Name: "created_by", Type: TypeRef{Name: ScalarString}, Resolve: mapField("created_by")})
+	t.AddField(&FieldDef{Name: "updated_by", Type: TypeRef{Name: ScalarString}, Resolve: mapField("updated_by")})
+}
+
+func (s *GraphQLServiceImpl) addModuleField(t *ObjectType, field common_models.ModuleField, types map[string]*ObjectType, loaderFor func(string) *recordLoader) {
+	name := fieldName(field.Name)
+	if name == "" {
+		return
+	}
+	key := field.Name
+
+	if field.Type == common_models.FieldTypeLookup && field.Lookup != nil {
+		t.AddField(&FieldDef{
+			Name:        name + "_id",
+			Description: "ID of the referenced " + field.Lookup.LookupModule + " record",
+			Type:        TypeRef{Name: ScalarID},
+			Resolve: func(p ResolveParams) (any, error) {
+				if id := lookupID(sourceValue(p.Source, key)); id != "" {
+					return id, nil
+				}
+				return nil, nil
+			},
+		})
+
+		target, ok := types[field.Lookup.LookupModule]
+		if !ok {
+			// Target module is not part of this tenant's schema; expose the reference only
+			t.AddField(&FieldDef{Name: name, Type: TypeRef{Name: ScalarJSON}, Resolve: mapField(key)})
+			return
+		}
+		targetModule := field.Lookup.LookupModule
+		t.AddField(&FieldDef{
+			Name:        name,
+			Description: field.Label,
+			Type:        TypeRef{Name: target.Name},
+			Resolve: func(p ResolveParams) (any, error) {
+				id := lookupID(sourceValue(p.Source, key))
+				if id == "" {
+					return nil, nil
+				}
+				return loaderFor(targetModule).Load(id), nil
+			},
+		})
+		return
+	}
+
+	t.AddField(&FieldDef{
+		Name:        name,
+		Description: field.Label,
+		Type:        scalarTypeFor(field.Type),
+		Resolve:     mapField(key),
+	})
+}
+
+func (s *GraphQLServiceImpl) addRootFields(query *ObjectType, m common_models.Entity, t, page *ObjectType, userID primitive.ObjectID) {
+	name := fieldName(m.Name)
+	moduleName := m.Name
+
+	query.AddField(&FieldDef{
+		Name:        name,
+		Description: "Fetch a single " + m.Label + " record",
+		Type:        TypeRef{Name: t.Name},
+		Args:        []Argument{{Name: "id", Type: TypeRef{Name: ScalarID, NonNull: true}}},
+		Resolve: func(p ResolveParams) (any, error) {
+			id, _ := p.Args["id"].(string)
+			if id == "" {
+				return nil, fmt.Errorf("argument \"id\" is required")
+			}
+			rec, err := s.RecordService.GetRecord(p.Ctx, moduleName, id, userID)
+			if err != nil {
+				return nil, err
+			}
+			return rec, nil
+		},
+	})
+
+	query.AddField(&FieldDef{
+		Name:        name + "_list",
+		Description: "List " + m.Label + " records",
+		Type:        TypeRef{Name: page.Name, NonNull: true},
+		Args: []Argument{
+			{Name: "page", Type: TypeRef{Name: ScalarInt}},
+			{Name: "limit", Type: TypeRef{Name: ScalarInt}},
+			{Name: "sort_by", Type: TypeRef{Name: ScalarString}},
+			{Name: "sort_order", Type: TypeRef{Name: ScalarString}},
+			{Name: "filters", Type: TypeRef{Name: "FilterInput", List: true}},
+			{Name: "filter", Type: TypeRef{Name: ScalarString}},
+		},
+		Resolve: func(p ResolveParams) (any, error) {
+			pageNum := intArg(p.Args, "page", 1)
+			limit := intArg(p.Args, "limit", 10)
+			sortBy, _ := p.Args["sort_by"].(string)
+			sortOrder, _ := p.Args["sort_order"].(string)
+
+			filters, err := filterArgs(p.Args["filters"])
+			if err != nil {
+				return nil, err
+			}
+
+			// Same OData-style expression as the REST $filter parameter
+			var expr *record.FilterExpr
+			if filter, _ := p.Args["filter"].(string); filter != "" {
+				if expr, err = record.ParseFilterExpression(filter); err != nil {
+					return nil, err
+				}
+			}
+
+			records, total, err := s.RecordService.ListRecordsWithExpression(p.Ctx, moduleName, filters, expr, pageNum, limit, sortBy, sortOrder, userID)
+			if err != nil {
+				return nil, err
+			}
+
+			// ListRecords clamps page and limit; report the values actually applied
+			if pageNum < 1 {
+				pageNum = 1
+			}
+			if limit < 1 {
+				limit = 10
+			}
+			if limit > 100 {
+				limit = 100
+			}
+			return map[string]any{
+				"data":  records,
+				"total": total,
+				"page":  pageNum,
+				"limit": limit,
+			}, nil
+		},
+	})
+}
+
+func scalarTypeFor(t common_models.FieldType) TypeRef {
+	switch t {
+	case common_models.FieldTypeNumber, common_models.FieldTypeCurrency:
+		return TypeRef{Name: ScalarFloat}
+	case common_models.FieldTypeBoolean:
+		return TypeRef{Name: ScalarBoolean}
+	case common_models.FieldTypeDate:
+		return TypeRef{Name: ScalarDateTime}
+	case common_models.FieldTypeMultiSelect:
+		return TypeRef{Name: ScalarString, List: true}
+	case common_models.FieldTypeFile, common_models.FieldTypeImage:
+		return TypeRef{Name: ScalarJSON}
+	}
+	return TypeRef{Name: ScalarString}
+}
+
+func mapField(key string) ResolveFunc {
+	return func(p ResolveParams) (any, error) {
+		return sourceValue(p.Source, key), nil
+	}
+}
+
+func sourceValue(source any, key string) any {
+	switch src := source.(type) {
+	case map[string]any:
+		return src[key]
+	case primitive.M:
+		return src[key]
+	}
+	return nil
+}
+
+// lookupID accepts the raw ObjectID stored on a record as well as the
+// {id, name} shape produced by RecordService.populateLookups
+func lookupID(v any) string {
+	switch val := v.(type) {
+	case primitive.ObjectID:
+		return val.Hex()
+	case string:
+		return val
+	case map[string]any:
+		return lookupID(val["id"])
+	}
+	return ""
+}
+
+func intArg(args map[string]any, name string, fallback int64) int64 {
+	switch v := args[name].(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	case int:
+		return int64(v)
+	}
+	return fallback
+}
+
+func filterArgs(v any) ([]common_models.Filter, error) {
+	if v == nil {
+		return nil, nil
+	}
+	items, ok := v.([]any)
+	if !ok {
+		items = []any{v}
+	}
+	filters := make([]common_models.Filter, 0, len(items))
+	for _, item := range items {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("filters must be a list of {field, operator, value} objects")
+		}
+		field, _ := obj["field"].(string)
+		operator, _ := obj["operator"].(string)
+		if field == "" {
+			return nil, fmt.Errorf("filter field is required")
+		}
+		if operator == "" {
+			operator = "eq"
+		}
+		filters = append(filters, common_models.Filter{Field: field, Operator: operator, Value: obj["value"]})
+	}
+	return filters, nil
+}
+
+var invalidNameChars = regexp.MustCompile(`[^_0-9A-Za-z]`)
+
+// fieldName turns a module or field name into a valid GraphQL name
+func fieldName(name string) string {
+	n := invalidNameChars.ReplaceAllString(name, "_")
+	if n == "" {
+		return ""
+	}
+	if n[0] >= '0' && n[0] <= '9' {
+		n = "_" + n
+	}
+	// Names starting with "__" are reserved for introspection
+	if strings.HasPrefix(n, "__") {
+		n = "f" + n
+	}
+	return n
+}
+
+// typeName derives the object type name, e.g. price_lists -> PriceLists
+// reservedTypeNames are the schema's own types, which module types must not shadow
+var reservedTypeNames = map[string]bool{
+	"Query": true, "FilterInput": true,
+	ScalarID: true, ScalarString: true, ScalarInt: true, ScalarFloat: true,
+	ScalarBoolean: true, ScalarDateTime: true, ScalarJSON: true,
+}
+
+func typeName(moduleName string) string {
+	parts := strings.FieldsFunc(fieldName(moduleName), func(r rune) bool { return r == '_' })
+	var b strings.Builder
+	for _, p := range parts {
+		b.WriteString(strings.ToUpper(p[:1]) + p[1:])
+	}
+	name := b.String()
+	if name == "" || reservedTypeNames[name] || (name[0] >= '0' && name[0] <= '9') {
+		name = "Module" + name
+	}
+	return name
+}
@@ -0,0 +1,19 @@
+package gql
+
+import "testing"
+
+func TestTypeName(t *testing.T) {
+	tests := map[string]string{
+		"deal":         "Deal",
+		"sales_orders": "SalesOrders",
+		"query":        "ModuleQuery",
+		"string":       "ModuleString",
+		"date_time":    "ModuleDateTime",
+		"1st_module":   "Module1stModule",
+	}
+	for in, want := range tests {
+		if got := typeName(in); got != want {
+			t.Errorf("typeName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
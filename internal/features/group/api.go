@@ -8,27 +8,34 @@ import (
 )
 
 type GroupApi struct {
-	controller *GroupController
-	config     *config.Config
+	controller  *GroupController
+	config      *config.Config
+	roleService middleware.RoleService
 }
 
-func NewGroupApi(controller *GroupController, config *config.Config) *GroupApi {
+func NewGroupApi(controller *GroupController, config *config.Config, roleService middleware.RoleService) *GroupApi {
 	return &GroupApi{
-		controller: controller,
-		config:     config,
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
 	}
 }
 
 func (h *GroupApi) Setup(app *fiber.App) {
 	groups := app.Group("/api/groups", middleware.AuthMiddleware(h.config.SkipAuth))
 
-	groups.Post("/", h.controller.CreateGroup)
-	groups.Get("/", h.controller.GetAllGroups)
-	groups.Get("/:id", h.controller.GetGroup)
-	groups.Put("/:id", h.controller.UpdateGroup)
-	groups.Delete("/:id", h.controller.DeleteGroup)
+	// Groups are part of user management and grant permissions, so they need "users" permissions
+	read := middleware.RequirePermission(h.roleService, "users", "read")
+	manage := middleware.RequirePermission(h.roleService, "users", "update")
+
+	groups.Post("/", manage, h.controller.CreateGroup)
+	groups.Get("/", read, h.controller.GetAllGroups)
+	groups.Get("/members/:user_id", read, h.controller.GetMemberGroups)
+	groups.Get("/:id", read, h.controller.GetGroup)
+	groups.Put("/:id", manage, h.controller.UpdateGroup)
+	groups.Delete("/:id", manage, h.controller.DeleteGroup)
 
 	// Member management
-	groups.Post("/:id/members", h.controller.AddMember)
-	groups.Delete("/:id/members/:userId", h.controller.RemoveMember)
+	groups.Post("/:id/members", manage, h.controller.AddMember)
+	groups.Delete("/:id/members/:user_id", manage, h.controller.RemoveMember)
 }
@@ -239,3 +239,30 @@ func (c *GroupController) RemoveMember(ctx *fiber.Ctx) error {
 		"message": "Member removed successfully",
 	})
 }
+
+// GetMemberGroups godoc
+// @Summary List a user's groups
+// @Description List the groups a user is a member of
+// @Tags groups
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Success 200 {array} Group
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/groups/members/{user_id} [get]
+func (c *GroupController) GetMemberGroups(ctx *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(ctx.Params("user_id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	groups, err := c.Service.GetUserGroups(ctx.UserContext(), userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.JSON(groups)
+}
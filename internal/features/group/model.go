@@ -8,9 +8,11 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// Group represents a user group with permissions
+// Group represents a user group. Its permissions (resource -> action) are
+// granted to every member on top of their roles.
 type Group struct {
 	ID          primitive.ObjectID                            `json:"id" bson:"_id,omitempty"`
+	TenantID    primitive.ObjectID                            `json:"tenant_id" bson:"tenant_id,omitempty"`
 	Name        string                                        `json:"name" bson:"name"`
 	Description string                                        `json:"description" bson:"description"`
 	Permissions map[string]map[string]models.ActionPermission `json:"permissions" bson:"permissions"`
@@ -2,6 +2,7 @@ package group
 
 import (
 	"context"
+	"fmt"
 	"go-crm/internal/common/models"
 	"go-crm/internal/database"
 	"time"
@@ -32,7 +33,30 @@ func NewGroupRepository(db *database.MongodbDB) GroupRepository {
 	}
 }
 
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+// scoped adds the caller's tenant to a filter
+func scoped(ctx context.Context, filter bson.M) (bson.M, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter["tenant_id"] = tenantID
+	return filter, nil
+}
+
 func (r *GroupRepositoryImpl) Create(ctx context.Context, group *Group) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	group.TenantID = tenantID
 	group.CreatedAt = time.Now()
 	group.UpdatedAt = time.Now()
 
@@ -53,7 +77,11 @@ func (r *GroupRepositoryImpl) Create(ctx context.Context, group *Group) error {
 }
 
 func (r *GroupRepositoryImpl) FindAll(ctx context.Context) ([]Group, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{})
+	filter, err := scoped(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := r.collection.Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -69,7 +97,11 @@ func (r *GroupRepositoryImpl) FindAll(ctx context.Context) ([]Group, error) {
 
 func (r *GroupRepositoryImpl) FindByID(ctx context.Context, id primitive.ObjectID) (*Group, error) {
 	var group Group
-	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&group)
+	filter, err := scoped(ctx, bson.M{"_id": id})
+	if err != nil {
+		return nil, err
+	}
+	err = r.collection.FindOne(ctx, filter).Decode(&group)
 	if err != nil {
 		return nil, err
 	}
@@ -89,12 +121,20 @@ func (r *GroupRepositoryImpl) Update(ctx context.Context, id primitive.ObjectID,
 		},
 	}
 
-	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	filter, err := scoped(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.UpdateOne(ctx, filter, update)
 	return err
 }
 
 func (r *GroupRepositoryImpl) Delete(ctx context.Context, id primitive.ObjectID) error {
-	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "is_system": false})
+	filter, err := scoped(ctx, bson.M{"_id": id, "is_system": false})
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.DeleteOne(ctx, filter)
 	return err
 }
 
@@ -103,7 +143,11 @@ func (r *GroupRepositoryImpl) AddMember(ctx context.Context, groupID, userID pri
 		"$addToSet": bson.M{"members": userID},
 		"$set":      bson.M{"updated_at": time.Now()},
 	}
-	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": groupID}, update)
+	filter, err := scoped(ctx, bson.M{"_id": groupID})
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.UpdateOne(ctx, filter, update)
 	return err
 }
 
@@ -112,12 +156,20 @@ func (r *GroupRepositoryImpl) RemoveMember(ctx context.Context, groupID, userID
 		"$pull": bson.M{"members": userID},
 		"$set":  bson.M{"updated_at": time.Now()},
 	}
-	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": groupID}, update)
+	filter, err := scoped(ctx, bson.M{"_id": groupID})
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.UpdateOne(ctx, filter, update)
 	return err
 }
 
 func (r *GroupRepositoryImpl) FindByMember(ctx context.Context, userID primitive.ObjectID) ([]Group, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{"members": userID})
+	filter, err := scoped(ctx, bson.M{"members": userID})
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := r.collection.Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	common_models "go-crm/internal/common/models"
 	"go-crm/internal/features/audit"
+	"go-crm/internal/features/authz"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -23,15 +24,30 @@ type GroupService interface {
 type GroupServiceImpl struct {
 	repo         GroupRepository
 	auditService audit.AuditService
+	versions     authz.VersionService
 }
 
-func NewGroupService(repo GroupRepository, auditService audit.AuditService) GroupService {
+func NewGroupService(repo GroupRepository, auditService audit.AuditService, versions authz.VersionService) GroupService {
 	return &GroupServiceImpl{
 		repo:         repo,
 		auditService: auditService,
+		versions:     versions,
 	}
 }
 
+// bumpVersion refreshes the members' tokens and cached decisions once group
+// grants or membership change
+func (s *GroupServiceImpl) bumpVersion(ctx context.Context, members ...primitive.ObjectID) {
+	if s.versions == nil {
+		return
+	}
+	ids := make([]string, len(members))
+	for i, id := range members {
+		ids[i] = id.Hex()
+	}
+	s.versions.Bump(ctx, ids...)
+}
+
 func (s *GroupServiceImpl) CreateGroup(ctx context.Context, group *Group) error {
 	if group.Name == "" {
 		return errors.New("group name is required")
@@ -72,6 +88,7 @@ func (s *GroupServiceImpl) UpdateGroup(ctx context.Context, id primitive.ObjectI
 		_ = s.auditService.LogChange(ctx, common_models.AuditActionGroup, "groups", id.Hex(), map[string]common_models.Change{
 			"group": {Old: existing, New: group},
 		})
+		s.bumpVersion(ctx, append(existing.Members, group.Members...)...)
 	}
 	return err
 }
@@ -91,16 +108,21 @@ func (s *GroupServiceImpl) DeleteGroup(ctx context.Context, id primitive.ObjectI
 		_ = s.auditService.LogChange(ctx, common_models.AuditActionGroup, "groups", id.Hex(), map[string]common_models.Change{
 			"group": {Old: existing, New: "DELETED"},
 		})
+		s.bumpVersion(ctx, existing.Members...)
 	}
 	return err
 }
 
 func (s *GroupServiceImpl) AddMember(ctx context.Context, groupID, userID primitive.ObjectID) error {
+	if _, err := s.repo.FindByID(ctx, groupID); err != nil {
+		return errors.New("group not found")
+	}
 	err := s.repo.AddMember(ctx, groupID, userID)
 	if err == nil {
 		_ = s.auditService.LogChange(ctx, common_models.AuditActionGroup, "groups", groupID.Hex(), map[string]common_models.Change{
 			"member_added": {New: userID.Hex()},
 		})
+		s.bumpVersion(ctx, userID)
 	}
 	return err
 }
@@ -111,6 +133,7 @@ func (s *GroupServiceImpl) RemoveMember(ctx context.Context, groupID, userID pri
 		_ = s.auditService.LogChange(ctx, common_models.AuditActionGroup, "groups", groupID.Hex(), map[string]common_models.Change{
 			"member_removed": {Old: userID.Hex()},
 		})
+		s.bumpVersion(ctx, userID)
 	}
 	return err
 }
@@ -0,0 +1,31 @@
+package impersonation
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/features/role"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ImpersonationApi struct {
+	controller  *ImpersonationController
+	config      *config.Config
+	roleService role.RoleService
+}
+
+func NewImpersonationApi(controller *ImpersonationController, config *config.Config, roleService role.RoleService) *ImpersonationApi {
+	return &ImpersonationApi{
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *ImpersonationApi) Setup(app *fiber.App) {
+	group := app.Group("/api/impersonation", middleware.AuthMiddleware(h.config.SkipAuth))
+	group.Post("/", h.controller.Start)
+	// Open to impersonation tokens so the admin can end the session from inside it
+	group.Post("/stop", h.controller.Stop)
+	group.Get("/sessions", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListSessions)
+}
@@ -0,0 +1,82 @@
+package impersonation
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+type ImpersonationController struct {
+	Service ImpersonationService
+}
+
+func NewImpersonationController(service ImpersonationService) *ImpersonationController {
+	return &ImpersonationController{Service: service}
+}
+
+// Start godoc
+// @Summary Start impersonating a user
+// @Description Issue a time-limited token that acts as another user of the organization. Admins only; every request made with it is audited and flagged with the X-Impersonated-By header.
+// @Tags impersonation
+// @Accept json
+// @Produce json
+// @Param request body StartRequest true "Target user"
+// @Success 201 {object} StartResult
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/impersonation [post]
+func (c *ImpersonationController) Start(ctx *fiber.Ctx) error {
+	var req StartRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	result, err := c.Service.Start(ctx.UserContext(), req)
+	if err != nil {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{"data": result})
+}
+
+// Stop godoc
+// @Summary Stop impersonating
+// @Description End an impersonation session. Called with the impersonation token, the session id can be omitted.
+// @Tags impersonation
+// @Accept json
+// @Produce json
+// @Param request body StopRequest false "Session to stop"
+// @Success 200 {object} Session
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/impersonation/stop [post]
+func (c *ImpersonationController) Stop(ctx *fiber.Ctx) error {
+	var req StopRequest
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&req); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+
+	session, err := c.Service.Stop(ctx.UserContext(), req.SessionID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"data": session})
+}
+
+// ListSessions godoc
+// @Summary List impersonation sessions
+// @Description List the organization's impersonation sessions, newest first
+// @Tags impersonation
+// @Produce json
+// @Param active query bool false "Only running sessions"
+// @Success 200 {array} Session
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/impersonation/sessions [get]
+func (c *ImpersonationController) ListSessions(ctx *fiber.Ctx) error {
+	sessions, err := c.Service.List(ctx.UserContext(), ctx.QueryBool("active"))
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"data": sessions})
+}
@@ -0,0 +1,42 @@
+package impersonation
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session is one admin acting as another user. The token issued for it
+// stops working once EndedAt is set or ExpiresAt passes.
+type Session struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID       primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	ImpersonatorID primitive.ObjectID `json:"impersonator_id" bson:"impersonator_id"`
+	TargetUserID   primitive.ObjectID `json:"target_user_id" bson:"target_user_id"`
+	Reason         string             `json:"reason" bson:"reason"`
+	StartedAt      time.Time          `json:"started_at" bson:"started_at"`
+	ExpiresAt      time.Time          `json:"expires_at" bson:"expires_at"`
+	EndedAt        *time.Time         `json:"ended_at,omitempty" bson:"ended_at,omitempty"`
+	EndedBy        string             `json:"ended_by,omitempty" bson:"ended_by,omitempty"`
+}
+
+// Active reports whether the session's token is still accepted at now
+func (s *Session) Active(now time.Time) bool {
+	return s.EndedAt == nil && now.Before(s.ExpiresAt)
+}
+
+type StartRequest struct {
+	UserID  string `json:"user_id"`
+	Reason  string `json:"reason"`
+	Minutes int    `json:"minutes"` // Defaults to and is capped at the tenant's max_minutes
+}
+
+type StopRequest struct {
+	SessionID string `json:"session_id"` // Optional when stopping with the impersonation token itself
+}
+
+// StartResult carries the token the admin uses while impersonating
+type StartResult struct {
+	Token   string   `json:"token"`
+	Session *Session `json:"session"`
+}
@@ -0,0 +1,117 @@
+package impersonation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ImpersonationRepository interface {
+	Create(ctx context.Context, session *Session) error
+	FindByID(ctx context.Context, id string) (*Session, error)
+	// End marks a running session as ended; sessions already ended are left alone
+	End(ctx context.Context, id string, endedBy string, endedAt time.Time) error
+	List(ctx context.Context, activeOnly bool, limit int64) ([]Session, error)
+}
+
+type ImpersonationRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewImpersonationRepository(db *database.MongodbDB) ImpersonationRepository {
+	return &ImpersonationRepositoryImpl{
+		collection: db.DB.Collection("impersonation_sessions"),
+	}
+}
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+func (r *ImpersonationRepositoryImpl) Create(ctx context.Context, session *Session) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	session.TenantID = tenantID
+	if session.ID.IsZero() {
+		session.ID = primitive.NewObjectID()
+	}
+	_, err = r.collection.InsertOne(ctx, session)
+	return err
+}
+
+func (r *ImpersonationRepositoryImpl) FindByID(ctx context.Context, id string) (*Session, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var session Session
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID}).Decode(&session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *ImpersonationRepositoryImpl) End(ctx context.Context, id string, endedBy string, endedAt time.Time) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.UpdateOne(ctx,
+		bson.M{"_id": oid, "tenant_id": tenantID, "ended_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"ended_at": endedAt, "ended_by": endedBy}},
+	)
+	return err
+}
+
+func (r *ImpersonationRepositoryImpl) List(ctx context.Context, activeOnly bool, limit int64) ([]Session, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"tenant_id": tenantID}
+	if activeOnly {
+		filter["ended_at"] = bson.M{"$exists": false}
+		filter["expires_at"] = bson.M{"$gt": time.Now()}
+	}
+	opts := options.Find().SetSort(bson.M{"started_at": -1})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	sessions := []Session{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
@@ -0,0 +1,190 @@
+package impersonation
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/authz"
+	"go-crm/internal/features/role"
+	"go-crm/internal/features/settings"
+	"go-crm/internal/features/user"
+	"go-crm/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ImpersonationService interface {
+	// Start issues a time-limited token acting as another user of the tenant
+	Start(ctx context.Context, req StartRequest) (*StartResult, error)
+	// Stop ends a session; its token is rejected from then on
+	Stop(ctx context.Context, sessionID string) (*Session, error)
+	List(ctx context.Context, activeOnly bool) ([]Session, error)
+	// IsActive is installed as AuthMiddleware's impersonation validator
+	IsActive(ctx context.Context, sessionID string) bool
+}
+
+type ImpersonationServiceImpl struct {
+	Repo            ImpersonationRepository
+	UserRepo        user.UserRepository
+	RoleRepo        role.RoleRepository
+	SettingsService settings.SettingsService
+	AuditService    audit.AuditService
+	Versions        authz.VersionService
+}
+
+func NewImpersonationService(repo ImpersonationRepository, userRepo user.UserRepository, roleRepo role.RoleRepository, settingsService settings.SettingsService, auditService audit.AuditService, versions authz.VersionService) ImpersonationService {
+	return &ImpersonationServiceImpl{
+		Repo:            repo,
+		UserRepo:        userRepo,
+		RoleRepo:        roleRepo,
+		SettingsService: settingsService,
+		AuditService:    auditService,
+		Versions:        versions,
+	}
+}
+
+func (s *ImpersonationServiceImpl) Start(ctx context.Context, req StartRequest) (*StartResult, error) {
+	claims, ok := ctx.Value(utils.UserClaimsKey).(*utils.UserClaims)
+	if !ok {
+		return nil, errors.New("unauthorized")
+	}
+	if claims.ImpersonationID != "" {
+		return nil, errors.New("cannot start impersonation while impersonating")
+	}
+	if !role.HasAdminRole(claims.Roles) {
+		return nil, errors.New("only admins can impersonate users")
+	}
+	if req.UserID == "" {
+		return nil, errors.New("user_id is required")
+	}
+	if req.UserID == claims.UserID {
+		return nil, errors.New("cannot impersonate yourself")
+	}
+
+	config, err := s.SettingsService.GetImpersonationConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !config.Enabled {
+		return nil, errors.New("impersonation is disabled for this organization")
+	}
+	minutes := req.Minutes
+	if minutes <= 0 || minutes > config.MaxMinutes {
+		minutes = config.MaxMinutes
+	}
+
+	// Tenant-scoped lookup, so users of other organizations are not found
+	target, err := s.UserRepo.FindByID(ctx, req.UserID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if target.Status == "suspended" || target.Status == "inactive" {
+		return nil, errors.New("cannot impersonate an inactive user")
+	}
+	impersonatorID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+
+	roleNames := []string{}
+	roleIDs := []string{}
+	for _, roleID := range target.Roles {
+		r, err := s.RoleRepo.FindByID(ctx, roleID.Hex())
+		if err == nil {
+			roleNames = append(roleNames, r.Name)
+			roleIDs = append(roleIDs, roleID.Hex())
+		}
+	}
+	groups := target.Groups
+	if groups == nil {
+		groups = []string{}
+	}
+
+	now := time.Now()
+	session := &Session{
+		ID:             primitive.NewObjectID(),
+		ImpersonatorID: impersonatorID,
+		TargetUserID:   target.ID,
+		Reason:         req.Reason,
+		StartedAt:      now,
+		ExpiresAt:      now.Add(time.Duration(minutes) * time.Minute),
+	}
+	if err := s.Repo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	var version int64
+	if s.Versions != nil {
+		version = s.Versions.Current(ctx, append([]string{target.ID.Hex()}, roleIDs...)...)
+	}
+	token, err := utils.GenerateImpersonationToken(target.ID, target.TenantID, roleNames, roleIDs, groups, claims.UserID, session.ID.Hex(), session.ExpiresAt, version)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionImpersonation, "impersonation", session.ID.Hex(), map[string]common_models.Change{
+		"status":         {New: "started"},
+		"target_user_id": {New: target.ID.Hex()},
+		"expires_at":     {New: session.ExpiresAt},
+		"reason":         {New: req.Reason},
+	})
+
+	return &StartResult{Token: token, Session: session}, nil
+}
+
+func (s *ImpersonationServiceImpl) Stop(ctx context.Context, sessionID string) (*Session, error) {
+	claims, ok := ctx.Value(utils.UserClaimsKey).(*utils.UserClaims)
+	if !ok {
+		return nil, errors.New("unauthorized")
+	}
+	if sessionID == "" {
+		sessionID = claims.ImpersonationID
+	}
+	if sessionID == "" {
+		return nil, errors.New("session_id is required")
+	}
+
+	session, err := s.Repo.FindByID(ctx, sessionID)
+	if err != nil {
+		return nil, errors.New("impersonation session not found")
+	}
+	// The impersonating admin (with either token) or any other admin may stop it
+	endedBy := claims.UserID
+	if claims.ImpersonationID == sessionID {
+		endedBy = claims.ImpersonatorID
+	} else if session.ImpersonatorID.Hex() != claims.UserID && !role.HasAdminRole(claims.Roles) {
+		return nil, errors.New("access denied")
+	}
+	if session.EndedAt != nil {
+		return session, nil
+	}
+
+	now := time.Now()
+	if err := s.Repo.End(ctx, sessionID, endedBy, now); err != nil {
+		return nil, err
+	}
+	session.EndedAt = &now
+	session.EndedBy = endedBy
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionImpersonation, "impersonation", sessionID, map[string]common_models.Change{
+		"status":         {Old: "started", New: "stopped"},
+		"target_user_id": {New: session.TargetUserID.Hex()},
+	})
+
+	return session, nil
+}
+
+func (s *ImpersonationServiceImpl) List(ctx context.Context, activeOnly bool) ([]Session, error) {
+	return s.Repo.List(ctx, activeOnly, 200)
+}
+
+func (s *ImpersonationServiceImpl) IsActive(ctx context.Context, sessionID string) bool {
+	session, err := s.Repo.FindByID(ctx, sessionID)
+	if err != nil {
+		return false
+	}
+	return session.Active(time.Now())
+}
@@ -30,4 +30,10 @@ func (api *ImportApi) Setup(app *fiber.App) {
 	group.Get("/jobs", api.ImportController.ListImportJobs)
 	group.Get("/jobs/:id", api.ImportController.GetImportJob)
 	group.Post("/jobs/:id/execute", api.ImportController.ExecuteImport)
+
+	// CRM migrations
+	group.Get("/crm/mappings/:source", api.ImportController.GetCRMMapping)
+	group.Post("/crm/jobs", api.ImportController.StartCRMImport)
+	group.Get("/crm/jobs", api.ImportController.ListCRMImportJobs)
+	group.Get("/crm/jobs/:id", api.ImportController.GetCRMImportJob)
 }
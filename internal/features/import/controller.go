@@ -4,16 +4,26 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"go-crm/internal/common/session"
 	"go-crm/internal/config"
+	"go-crm/internal/features/record"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// triggerOptionsFromForm reads how an import job's records reach automations
+// and webhooks
+func triggerOptionsFromForm(ctx *fiber.Ctx) record.TriggerOptions {
+	return record.TriggerOptions{
+		SuppressAutomations: ctx.FormValue("suppress_automations") == "true",
+		BatchWebhooks:       ctx.FormValue("batch_webhooks") == "true",
+	}
+}
+
 type ImportController struct {
 	ImportService ImportService
 	UploadDir     string
@@ -77,6 +87,8 @@ func (c *ImportController) UploadAndPreview(ctx *fiber.Ctx) error {
 // @Param file formData file true "Import File"
 // @Param module formData string true "Module Name"
 // @Param mapping formData string true "Column Mapping JSON"
+// @Param suppress_automations formData bool false "Skip automation rules for the imported records"
+// @Param batch_webhooks formData bool false "Send record webhooks in batches of up to 100 records"
 // @Success 201 {object} ImportJob
 // @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
@@ -90,11 +102,10 @@ func (c *ImportController) CreateImportJob(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "module and mapping required"})
 	}
 
-	userIDStr, ok := ctx.Locals("user_id").(string)
+	userID, ok := session.UserID(ctx.UserContext())
 	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User ID not found"})
 	}
-	userID, _ := primitive.ObjectIDFromHex(userIDStr)
 
 	fileHeader, err := ctx.FormFile("file")
 	if err != nil {
@@ -132,6 +143,7 @@ func (c *ImportController) CreateImportJob(ctx *fiber.Ctx) error {
 		FilePath:      dstPath,
 		ColumnMapping: mapping,
 		TotalRecords:  totalRows,
+		Triggers:      triggerOptionsFromForm(ctx),
 	}
 
 	if err := c.ImportService.CreateJob(ctx.UserContext(), job); err != nil {
@@ -153,11 +165,10 @@ func (c *ImportController) CreateImportJob(ctx *fiber.Ctx) error {
 func (c *ImportController) ExecuteImport(ctx *fiber.Ctx) error {
 	id := ctx.Params("id")
 
-	userIDStr, ok := ctx.Locals("user_id").(string)
+	userID, ok := session.UserID(ctx.UserContext())
 	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User ID not found"})
 	}
-	userID, _ := primitive.ObjectIDFromHex(userIDStr)
 
 	go func() {
 		bgCtx := context.Background()
@@ -197,11 +208,10 @@ func (c *ImportController) GetImportJob(ctx *fiber.Ctx) error {
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/import/jobs [get]
 func (c *ImportController) ListImportJobs(ctx *fiber.Ctx) error {
-	userIDStr, ok := ctx.Locals("user_id").(string)
+	userID, ok := session.UserID(ctx.UserContext())
 	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User ID not found"})
 	}
-	userID, _ := primitive.ObjectIDFromHex(userIDStr)
 
 	jobs, err := c.ImportService.GetUserJobs(ctx.UserContext(), userID)
 	if err != nil {
@@ -0,0 +1,142 @@
+package import_feature
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetCRMMapping godoc
+// @Summary Get CRM field mapping
+// @Description Get the pre-built field mapping used to import exports from another CRM
+// @Tags import
+// @Produce json
+// @Param source path string true "CRM source (salesforce, hubspot, zoho)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/import/crm/mappings/{source} [get]
+func (c *ImportController) GetCRMMapping(ctx *fiber.Ctx) error {
+	mapping, err := c.ImportService.GetCRMMapping(CRMSource(ctx.Params("source")))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(mapping)
+}
+
+// StartCRMImport godoc
+// @Summary Start CRM migration
+// @Description Import Salesforce, HubSpot or Zoho exports (CSV, XLSX or JSON) into accounts, contacts, deals and activities, preserving relationships
+// @Tags import
+// @Accept multipart/form-data
+// @Produce json
+// @Param source formData string true "CRM source (salesforce, hubspot, zoho)"
+// @Param accounts formData file false "Accounts/companies export"
+// @Param contacts formData file false "Contacts export"
+// @Param deals formData file false "Deals/opportunities export"
+// @Param activities formData file false "Tasks/activities export"
+// @Param modules formData string false "Target module overrides JSON, e.g. {\"activities\":\"calls\"}"
+// @Param suppress_automations formData bool false "Skip automation rules for the imported records"
+// @Param batch_webhooks formData bool false "Send record webhooks in batches of up to 100 records"
+// @Success 202 {object} CRMImportJob
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/import/crm/jobs [post]
+func (c *ImportController) StartCRMImport(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User ID not found"})
+	}
+
+	job := &CRMImportJob{
+		UserID:   userID,
+		Source:   CRMSource(strings.ToLower(ctx.FormValue("source"))),
+		Triggers: triggerOptionsFromForm(ctx),
+	}
+
+	if modulesJSON := ctx.FormValue("modules"); modulesJSON != "" {
+		if err := json.Unmarshal([]byte(modulesJSON), &job.Modules); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid modules JSON"})
+		}
+	}
+
+	// Staged exports belong to the job once it is queued; until then every
+	// failed request removes what it saved
+	queued := false
+	defer func() {
+		if queued {
+			return
+		}
+		for _, f := range job.Files {
+			os.Remove(f.FilePath)
+		}
+	}()
+
+	for _, object := range CRMObjectOrder {
+		fileHeader, err := ctx.FormFile(object)
+		if err != nil {
+			continue
+		}
+
+		originalName := filepath.Base(fileHeader.Filename)
+		uniqueName := strings.ReplaceAll(fmt.Sprintf("%d_%s_%s", time.Now().UnixNano(), object, originalName), " ", "_")
+		dstPath := filepath.Join(c.UploadDir, uniqueName)
+
+		if err := ctx.SaveFile(fileHeader, dstPath); err != nil {
+			os.Remove(dstPath)
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error saving file"})
+		}
+
+		job.Files = append(job.Files, CRMImportFile{
+			Object:   object,
+			FileName: originalName,
+			FilePath: dstPath,
+		})
+	}
+
+	if err := c.ImportService.StartCRMImport(ctx.UserContext(), job); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	queued = true
+
+	return ctx.Status(fiber.StatusAccepted).JSON(job)
+}
+
+// GetCRMImportJob godoc
+// @Summary Get CRM migration
+// @Description Get progress and the summary report of a CRM migration job
+// @Tags import
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} CRMImportJob
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/import/crm/jobs/{id} [get]
+func (c *ImportController) GetCRMImportJob(ctx *fiber.Ctx) error {
+	job, err := c.ImportService.GetCRMImportJob(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Job not found"})
+	}
+	return ctx.JSON(job)
+}
+
+// ListCRMImportJobs godoc
+// @Summary List CRM migrations
+// @Description List recent CRM migration jobs
+// @Tags import
+// @Produce json
+// @Success 200 {array} CRMImportJob
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/import/crm/jobs [get]
+func (c *ImportController) ListCRMImportJobs(ctx *fiber.Ctx) error {
+	jobs, err := c.ImportService.ListCRMImportJobs(ctx.UserContext())
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(jobs)
+}
@@ -0,0 +1,73 @@
+package import_feature
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// crmImportService fails or accepts StartCRMImport
+type crmImportService struct {
+	ImportService
+	err error
+}
+
+func (s *crmImportService) StartCRMImport(ctx context.Context, job *CRMImportJob) error {
+	return s.err
+}
+
+func TestStartCRMImportRemovesStagedFilesOnError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantFiles  int
+	}{
+		{name: "rejected", err: errors.New("target module 'accounts' for accounts not found"), wantStatus: fiber.StatusBadRequest},
+		{name: "queued", wantStatus: fiber.StatusAccepted, wantFiles: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			controller := &ImportController{ImportService: &crmImportService{err: tt.err}, UploadDir: dir}
+			app := fiber.New()
+			app.Post("/jobs", func(c *fiber.Ctx) error {
+				c.SetUserContext(session.With(c.UserContext(), &session.RequestContext{UserID: primitive.NewObjectID()}))
+				return c.Next()
+			}, controller.StartCRMImport)
+
+			var body bytes.Buffer
+			form := multipart.NewWriter(&body)
+			form.WriteField("source", "salesforce")
+			for _, object := range []string{"accounts", "contacts"} {
+				part, _ := form.CreateFormFile(object, object+".csv")
+				part.Write([]byte("Id,Name\n1,Acme\n"))
+			}
+			form.Close()
+
+			req := httptest.NewRequest(fiber.MethodPost, "/jobs", &body)
+			req.Header.Set(fiber.HeaderContentType, form.FormDataContentType())
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			entries, _ := os.ReadDir(dir)
+			if len(entries) != tt.wantFiles {
+				t.Errorf("staged files = %d, want %d", len(entries), tt.wantFiles)
+			}
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package import_feature
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldTransform converts a source CRM value into the representation our record validation expects
+type FieldTransform string
+
+const (
+	TransformNone   FieldTransform = ""
+	TransformDate   FieldTransform = "date"
+	TransformNumber FieldTransform = "number"
+	TransformStage  FieldTransform = "stage"
+)
+
+// FieldMapping copies the first non-empty source column into a target field.
+// Several source names are listed because each CRM names columns differently
+// in its UI CSV export and its API/JSON export.
+type FieldMapping struct {
+	Sources   []string       `json:"sources"`
+	Target    string         `json:"target"`
+	Transform FieldTransform `json:"transform,omitempty"`
+}
+
+// RelationMapping resolves a source foreign key to a record created earlier in the same job
+type RelationMapping struct {
+	Sources    []string `json:"sources"`
+	Target     string   `json:"target"`
+	RefObjects []string `json:"ref_objects"` // Checked in order; Salesforce WhatId can point at several objects
+}
+
+// ObjectMapping describes how one exported CRM object becomes module records
+type ObjectMapping struct {
+	Module      string            `json:"module"`
+	ExternalID  []string          `json:"external_id"`
+	Fields      []FieldMapping    `json:"fields"`
+	Relations   []RelationMapping `json:"relations,omitempty"`
+	StageValues map[string]string `json:"stage_values,omitempty"`
+}
+
+var CRMMappings = map[CRMSource]map[string]ObjectMapping{
+	CRMSourceSalesforce: {
+		CRMObjectAccounts: {
+			Module:     "accounts",
+			ExternalID: []string{"Id", "ID"},
+			Fields: []FieldMapping{
+				{Sources: []string{"Name"}, Target: "name"},
+				{Sources: []string{"Industry"}, Target: "industry"},
+				{Sources: []string{"Website"}, Target: "website"},
+				{Sources: []string{"Phone"}, Target: "phone"},
+				{Sources: []string{"Type"}, Target: "type"},
+			},
+		},
+		CRMObjectContacts: {
+			Module:     "contacts",
+			ExternalID: []string{"Id", "ID"},
+			Fields: []FieldMapping{
+				{Sources: []string{"FirstName"}, Target: "first_name"},
+				{Sources: []string{"LastName"}, Target: "last_name"},
+				{Sources: []string{"Email"}, Target: "email"},
+				{Sources: []string{"Phone", "MobilePhone"}, Target: "phone"},
+				{Sources: []string{"Title"}, Target: "title"},
+			},
+			Relations: []RelationMapping{
+				{Sources: []string{"AccountId"}, Target: "account", RefObjects: []string{CRMObjectAccounts}},
+			},
+		},
+		CRMObjectDeals: {
+			Module:     "opportunities",
+			ExternalID: []string{"Id", "ID"},
+			Fields: []FieldMapping{
+				{Sources: []string{"Name"}, Target: "name"},
+				{Sources: []string{"Amount"}, Target: "amount", Transform: TransformNumber},
+				{Sources: []string{"StageName"}, Target: "stage", Transform: TransformStage},
+				{Sources: []string{"CloseDate"}, Target: "close_date", Transform: TransformDate},
+			},
+			Relations: []RelationMapping{
+				{Sources: []string{"AccountId"}, Target: "account", RefObjects: []string{CRMObjectAccounts}},
+			},
+		},
+		CRMObjectActivities: {
+			Module:     "tasks",
+			ExternalID: []string{"Id", "ID"},
+			Fields: []FieldMapping{
+				{Sources: []string{"Subject"}, Target: "subject"},
+				{Sources: []string{"ActivityDate"}, Target: "due_date", Transform: TransformDate},
+				{Sources: []string{"Status"}, Target: "status"},
+				{Sources: []string{"Description"}, Target: "description"},
+			},
+			Relations: []RelationMapping{
+				{Sources: []string{"WhoId"}, Target: "contact", RefObjects: []string{CRMObjectContacts}},
+				{Sources: []string{"WhatId"}, Target: "account", RefObjects: []string{CRMObjectAccounts}},
+				{Sources: []string{"WhatId"}, Target: "opportunity", RefObjects: []string{CRMObjectDeals}},
+			},
+		},
+	},
+	CRMSourceHubSpot: {
+		CRMObjectAccounts: {
+			Module:     "accounts",
+			ExternalID: []string{"Record ID", "hs_object_id", "id"},
+			Fields: []FieldMapping{
+				{Sources: []string{"Company name", "name"}, Target: "name"},
+				{Sources: []string{"Industry", "industry"}, Target: "industry"},
+				{Sources: []string{"Website URL", "website", "domain"}, Target: "website"},
+				{Sources: []string{"Phone Number", "phone"}, Target: "phone"},
+				{Sources: []string{"Type", "type"}, Target: "type"},
+			},
+		},
+		CRMObjectContacts: {
+			Module:     "contacts",
+			ExternalID: []string{"Record ID", "hs_object_id", "id"},
+			Fields: []FieldMapping{
+				{Sources: []string{"First Name", "firstname"}, Target: "first_name"},
+				{Sources: []string{"Last Name", "lastname"}, Target: "last_name"},
+				{Sources: []string{"Email", "email"}, Target: "email"},
+				{Sources: []string{"Phone Number", "phone", "mobilephone"}, Target: "phone"},
+				{Sources: []string{"Job Title", "jobtitle"}, Target: "title"},
+			},
+			Relations: []RelationMapping{
+				{Sources: []string{"Associated Company IDs", "Associated Company ID", "associatedcompanyid"}, Target: "account", RefObjects: []string{CRMObjectAccounts}},
+			},
+		},
+		CRMObjectDeals: {
+			Module:     "opportunities",
+			ExternalID: []string{"Record ID", "hs_object_id", "id"},
+			Fields: []FieldMapping{
+				{Sources: []string{"Deal Name", "dealname"}, Target: "name"},
+				{Sources: []string{"Amount", "amount"}, Target: "amount", Transform: TransformNumber},
+				{Sources: []string{"Deal Stage", "dealstage"}, Target: "stage", Transform: TransformStage},
+				{Sources: []string{"Close Date", "closedate"}, Target: "close_date", Transform: TransformDate},
+			},
+			Relations: []RelationMapping{
+				{Sources: []string{"Associated Company IDs", "Associated Company ID", "associatedcompanyid"}, Target: "account", RefObjects: []string{CRMObjectAccounts}},
+			},
+			StageValues: map[string]string{
+				"appointmentscheduled":     "Prospecting",
+				"qualifiedtobuy":           "Prospecting",
+				"presentationscheduled":    "Prospecting",
+				"decisionmakerboughtin":    "Negotiation",
+				"contractsent":             "Negotiation",
+				"closedwon":                "Closed Won",
+				"closedlost":               "Closed Lost",
+				"appointment scheduled":    "Prospecting",
+				"qualified to buy":         "Prospecting",
+				"presentation scheduled":   "Prospecting",
+				"decision maker bought-in": "Negotiation",
+				"contract sent":            "Negotiation",
+				"closed won":               "Closed Won",
+				"closed lost":              "Closed Lost",
+			},
+		},
+		CRMObjectActivities: {
+			Module:     "tasks",
+			ExternalID: []string{"Record ID", "hs_object_id", "id"},
+			Fields: []FieldMapping{
+				{Sources: []string{"Task Title", "hs_task_subject"}, Target: "subject"},
+				{Sources: []string{"Due Date", "hs_timestamp"}, Target: "due_date", Transform: TransformDate},
+				{Sources: []string{"Task Status", "hs_task_status"}, Target: "status"},
+				{Sources: []string{"Notes", "hs_task_body"}, Target: "description"},
+			},
+			Relations: []RelationMapping{
+				{Sources: []string{"Associated Contact IDs", "Associated Contact ID"}, Target: "contact", RefObjects: []string{CRMObjectContacts}},
+				{Sources: []string{"Associated Company IDs", "Associated Company ID"}, Target: "account", RefObjects: []string{CRMObjectAccounts}},
+				{Sources: []string{"Associated Deal IDs", "Associated Deal ID"}, Target: "opportunity", RefObjects: []string{CRMObjectDeals}},
+			},
+		},
+	},
+	CRMSourceZoho: {
+		CRMObjectAccounts: {
+			Module:     "accounts",
+			ExternalID: []string{"Record Id", "id"},
+			Fields: []FieldMapping{
+				{Sources: []string{"Account Name", "Account_Name"}, Target: "name"},
+				{Sources: []string{"Industry"}, Target: "industry"},
+				{Sources: []string{"Website"}, Target: "website"},
+				{Sources: []string{"Phone"}, Target: "phone"},
+				{Sources: []string{"Account Type", "Account_Type"}, Target: "type"},
+			},
+		},
+		CRMObjectContacts: {
+			Module:     "contacts",
+			ExternalID: []string{"Record Id", "id"},
+			Fields: []FieldMapping{
+				{Sources: []string{"First Name", "First_Name"}, Target: "first_name"},
+				{Sources: []string{"Last Name", "Last_Name"}, Target: "last_name"},
+				{Sources: []string{"Email"}, Target: "email"},
+				{Sources: []string{"Phone", "Mobile"}, Target: "phone"},
+				{Sources: []string{"Title"}, Target: "title"},
+			},
+			Relations: []RelationMapping{
+				{Sources: []string{"Account Name.id", "Account_Name.id"}, Target: "account", RefObjects: []string{CRMObjectAccounts}},
+			},
+		},
+		CRMObjectDeals: {
+			Module:     "opportunities",
+			ExternalID: []string{"Record Id", "id"},
+			Fields: []FieldMapping{
+				{Sources: []string{"Deal Name", "Deal_Name"}, Target: "name"},
+				{Sources: []string{"Amount"}, Target: "amount", Transform: TransformNumber},
+				{Sources: []string{"Stage"}, Target: "stage", Transform: TransformStage},
+				{Sources: []string{"Closing Date", "Closing_Date"}, Target: "close_date", Transform: TransformDate},
+			},
+			Relations: []RelationMapping{
+				{Sources: []string{"Account Name.id", "Account_Name.id"}, Target: "account", RefObjects: []string{CRMObjectAccounts}},
+			},
+			StageValues: map[string]string{
+				"qualification":              "Prospecting",
+				"needs analysis":             "Prospecting",
+				"value proposition":          "Prospecting",
+				"id. decision makers":        "Negotiation",
+				"proposal/price quote":       "Negotiation",
+				"negotiation/review":         "Negotiation",
+				"closed won":                 "Closed Won",
+				"closed lost":                "Closed Lost",
+				"closed-lost to competition": "Closed Lost",
+			},
+		},
+		CRMObjectActivities: {
+			Module:     "tasks",
+			ExternalID: []string{"Record Id", "id"},
+			Fields: []FieldMapping{
+				{Sources: []string{"Subject"}, Target: "subject"},
+				{Sources: []string{"Due Date", "Due_Date"}, Target: "due_date", Transform: TransformDate},
+				{Sources: []string{"Status"}, Target: "status"},
+				{Sources: []string{"Description"}, Target: "description"},
+			},
+			Relations: []RelationMapping{
+				{Sources: []string{"Contact Name.id", "Who_Id.id"}, Target: "contact", RefObjects: []string{CRMObjectContacts}},
+				{Sources: []string{"Related To.id", "What_Id.id"}, Target: "account", RefObjects: []string{CRMObjectAccounts}},
+				{Sources: []string{"Related To.id", "What_Id.id"}, Target: "opportunity", RefObjects: []string{CRMObjectDeals}},
+			},
+		},
+	},
+}
+
+// firstValue returns the first non-empty value among the candidate columns
+func firstValue(row map[string]interface{}, sources []string) string {
+	for _, src := range sources {
+		if v, ok := row[src]; ok && v != nil {
+			s := strings.TrimSpace(fmt.Sprint(v))
+			if s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// firstReference returns the first ID from a possibly multi-valued association column
+func firstReference(row map[string]interface{}, sources []string) string {
+	val := firstValue(row, sources)
+	if i := strings.IndexAny(val, ";,"); i >= 0 {
+		val = strings.TrimSpace(val[:i])
+	}
+	return val
+}
+
+var crmDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05.000Z0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"01/02/2006 15:04",
+	"01/02/2006",
+	"1/2/2006",
+	"Jan 2, 2006",
+}
+
+func applyTransform(mapping ObjectMapping, transform FieldTransform, value string) (interface{}, error) {
+	switch transform {
+	case TransformDate:
+		// HubSpot API exports timestamps as epoch milliseconds
+		if ms, err := strconv.ParseInt(value, 10, 64); err == nil && len(value) >= 12 {
+			return time.UnixMilli(ms).UTC().Format(time.RFC3339), nil
+		}
+		for _, layout := range crmDateLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t.UTC().Format(time.RFC3339), nil
+			}
+		}
+		return nil, fmt.Errorf("unrecognized date '%s'", value)
+	case TransformNumber:
+		cleaned := strings.NewReplacer(",", "", "$", "", "€", "", "£", "", " ", "").Replace(value)
+		f, err := strconv.ParseFloat(cleaned, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", value)
+		}
+		return f, nil
+	case TransformStage:
+		if mapped, ok := mapping.StageValues[strings.ToLower(value)]; ok {
+			return mapped, nil
+		}
+		return value, nil
+	}
+	return value, nil
+}
+
+// flattenExportRow turns nested API export objects into dotted column names,
+// e.g. Zoho {"Account_Name": {"id": "1"}} becomes "Account_Name.id" and HubSpot
+// {"properties": {...}} is lifted to the top level.
+func flattenExportRow(prefix string, in map[string]interface{}, out map[string]interface{}) {
+	for k, v := range in {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if nested, ok := v.(map[string]interface{}); ok {
+			if prefix == "" && k == "properties" {
+				flattenExportRow("", nested, out)
+			} else {
+				flattenExportRow(key, nested, out)
+			}
+			continue
+		}
+		out[key] = v
+	}
+}
@@ -0,0 +1,99 @@
+package import_feature
+
+import (
+	"context"
+	"fmt"
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CRMImportRepository interface {
+	Create(ctx context.Context, job *CRMImportJob) error
+	Get(ctx context.Context, id string) (*CRMImportJob, error)
+	Update(ctx context.Context, job *CRMImportJob) error
+	List(ctx context.Context, limit int64) ([]CRMImportJob, error)
+}
+
+type CRMImportRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewCRMImportRepository(db *database.MongodbDB) CRMImportRepository {
+	return &CRMImportRepositoryImpl{
+		collection: db.DB.Collection("crm_import_jobs"),
+	}
+}
+
+func (r *CRMImportRepositoryImpl) tenantID(ctx context.Context) (primitive.ObjectID, error) {
+	tenantID, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantID == "" {
+		return primitive.NilObjectID, fmt.Errorf("organization context missing")
+	}
+	return primitive.ObjectIDFromHex(tenantID)
+}
+
+func (r *CRMImportRepositoryImpl) Create(ctx context.Context, job *CRMImportJob) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+	if job.ID.IsZero() {
+		job.ID = primitive.NewObjectID()
+	}
+	job.TenantID = tenantID
+	job.Status = ImportStatusPending
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = time.Now()
+
+	_, err = r.collection.InsertOne(ctx, job)
+	return err
+}
+
+func (r *CRMImportRepositoryImpl) Get(ctx context.Context, id string) (*CRMImportJob, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var job CRMImportJob
+	if err := r.collection.FindOne(ctx, bson.M{"_id": objID, "tenant_id": tenantID}).Decode(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *CRMImportRepositoryImpl) Update(ctx context.Context, job *CRMImportJob) error {
+	job.UpdatedAt = time.Now()
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": job.ID, "tenant_id": job.TenantID}, job)
+	return err
+}
+
+func (r *CRMImportRepositoryImpl) List(ctx context.Context, limit int64) ([]CRMImportJob, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, bson.M{"tenant_id": tenantID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	jobs := []CRMImportJob{}
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
@@ -0,0 +1,256 @@
+package import_feature
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/record"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (s *ImportServiceImpl) GetCRMMapping(source CRMSource) (map[string]ObjectMapping, error) {
+	mapping, ok := CRMMappings[source]
+	if !ok {
+		return nil, fmt.Errorf("unsupported CRM source '%s'", source)
+	}
+	return mapping, nil
+}
+
+func (s *ImportServiceImpl) GetCRMImportJob(ctx context.Context, id string) (*CRMImportJob, error) {
+	return s.CRMImportRepo.Get(ctx, id)
+}
+
+func (s *ImportServiceImpl) ListCRMImportJobs(ctx context.Context) ([]CRMImportJob, error) {
+	return s.CRMImportRepo.List(ctx, 50)
+}
+
+// StartCRMImport validates the uploaded files, persists the job and runs the migration in the background
+func (s *ImportServiceImpl) StartCRMImport(ctx context.Context, job *CRMImportJob) error {
+	mappings, err := s.GetCRMMapping(job.Source)
+	if err != nil {
+		return err
+	}
+	if len(job.Files) == 0 {
+		return fmt.Errorf("at least one export file is required")
+	}
+
+	if job.Modules == nil {
+		job.Modules = make(map[string]string)
+	}
+	for _, f := range job.Files {
+		mapping, ok := mappings[f.Object]
+		if !ok {
+			return fmt.Errorf("unsupported object '%s'", f.Object)
+		}
+		if job.Modules[f.Object] == "" {
+			job.Modules[f.Object] = mapping.Module
+		}
+		if _, err := s.ModuleService.GetModuleByName(ctx, job.Modules[f.Object], primitive.NilObjectID); err != nil {
+			return fmt.Errorf("target module '%s' for %s not found", job.Modules[f.Object], f.Object)
+		}
+	}
+
+	job.Summary = make(map[string]*CRMObjectSummary)
+	if err := s.CRMImportRepo.Create(ctx, job); err != nil {
+		return err
+	}
+
+	// Background run keeps the tenant so record creation stays scoped
+	bgCtx := context.WithValue(context.Background(), common_models.TenantIDKey, job.TenantID.Hex())
+	go s.runCRMImport(bgCtx, *job)
+
+	return nil
+}
+
+func (s *ImportServiceImpl) runCRMImport(ctx context.Context, job CRMImportJob) {
+	job.Status = ImportStatusProcessing
+	_ = s.CRMImportRepo.Update(ctx, &job)
+	ctx, batch := record.WithTriggerBatch(ctx, job.Triggers, job.ID.Hex())
+
+	files := make(map[string]CRMImportFile, len(job.Files))
+	for _, f := range job.Files {
+		files[f.Object] = f
+	}
+
+	// External CRM ID -> our record ID, per object, for relationship preservation
+	idMap := make(map[string]map[string]string)
+	mappings := CRMMappings[job.Source]
+
+	for _, object := range CRMObjectOrder {
+		f, ok := files[object]
+		if !ok {
+			continue
+		}
+		idMap[object] = make(map[string]string)
+
+		summary := &CRMObjectSummary{Module: job.Modules[object]}
+		job.Summary[object] = summary
+
+		rows, err := s.readExportFile(f)
+		if err != nil {
+			job.Errors = append(job.Errors, CRMImportError{Object: object, Message: err.Error()})
+			continue
+		}
+		summary.TotalRows = len(rows)
+
+		mapping := mappings[object]
+		for i, row := range rows {
+			externalID := firstValue(row, mapping.ExternalID)
+			rec, rowErr := s.mapCRMRow(mapping, row, idMap, summary)
+			if rowErr != nil {
+				summary.Failed++
+				job.Errors = append(job.Errors, CRMImportError{Object: object, Row: i + 1, ExternalID: externalID, Message: rowErr.Error()})
+				continue
+			}
+			if len(rec) == 0 {
+				summary.Skipped++
+				continue
+			}
+
+			res, err := s.RecordService.CreateRecord(ctx, summary.Module, rec, job.UserID)
+			if err != nil {
+				summary.Failed++
+				job.Errors = append(job.Errors, CRMImportError{Object: object, Row: i + 1, ExternalID: externalID, Message: err.Error()})
+				continue
+			}
+			summary.Created++
+
+			if oid, ok := res.(primitive.ObjectID); ok && externalID != "" {
+				idMap[object][externalID] = oid.Hex()
+			}
+
+			if (i+1)%100 == 0 {
+				_ = s.CRMImportRepo.Update(ctx, &job)
+			}
+		}
+	}
+
+	batch.Flush(ctx)
+
+	// Uploaded exports are no longer needed once processed
+	for _, f := range job.Files {
+		os.Remove(f.FilePath)
+	}
+
+	job.Status = ImportStatusCompleted
+	now := time.Now()
+	job.CompletedAt = &now
+	if err := s.CRMImportRepo.Update(ctx, &job); err != nil {
+		log.Printf("Failed to save CRM import job %s: %v", job.ID.Hex(), err)
+	}
+}
+
+func (s *ImportServiceImpl) mapCRMRow(mapping ObjectMapping, row map[string]interface{}, idMap map[string]map[string]string, summary *CRMObjectSummary) (map[string]interface{}, error) {
+	rec := make(map[string]interface{})
+	for _, fm := range mapping.Fields {
+		value := firstValue(row, fm.Sources)
+		if value == "" {
+			continue
+		}
+		converted, err := applyTransform(mapping, fm.Transform, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", fm.Target, err)
+		}
+		rec[fm.Target] = converted
+	}
+
+	// A reference column may feed several relations (e.g. Salesforce WhatId), so it
+	// only counts as unresolved when none of them could link it
+	resolved := make(map[string]bool)
+	for _, rel := range mapping.Relations {
+		ref := firstReference(row, rel.Sources)
+		if ref == "" {
+			continue
+		}
+		if _, seen := resolved[ref]; !seen {
+			resolved[ref] = false
+		}
+		for _, refObject := range rel.RefObjects {
+			if newID, ok := idMap[refObject][ref]; ok {
+				rec[rel.Target] = newID
+				resolved[ref] = true
+				break
+			}
+		}
+	}
+	for _, ok := range resolved {
+		if ok {
+			summary.LinkedRelations++
+		} else {
+			summary.UnresolvedRelations++
+		}
+	}
+
+	return rec, nil
+}
+
+func (s *ImportServiceImpl) readExportFile(f CRMImportFile) ([]map[string]interface{}, error) {
+	file, err := os.Open(f.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	name := strings.ToLower(f.FileName)
+	switch {
+	case strings.HasSuffix(name, ".csv"):
+		_, rows, _, err := s.parseCSVFull(file)
+		return rows, err
+	case strings.HasSuffix(name, ".xlsx"):
+		_, rows, _, err := s.parseExcelFull(file)
+		return rows, err
+	case strings.HasSuffix(name, ".json"):
+		return parseJSONExport(file)
+	}
+	return nil, fmt.Errorf("unsupported file format")
+}
+
+// parseJSONExport accepts a top-level array or the paged envelopes used by CRM
+// APIs ({"records": [...]}, {"results": [...]}, {"data": [...]})
+func parseJSONExport(r io.Reader) ([]map[string]interface{}, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	var raw interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	var items []interface{}
+	switch v := raw.(type) {
+	case []interface{}:
+		items = v
+	case map[string]interface{}:
+		for _, key := range []string{"records", "results", "data"} {
+			if list, ok := v[key].([]interface{}); ok {
+				items = list
+				break
+			}
+		}
+		if items == nil {
+			return nil, fmt.Errorf("JSON export must be an array or contain records/results/data")
+		}
+	default:
+		return nil, fmt.Errorf("JSON export must be an array or contain records/results/data")
+	}
+
+	rows := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		row := make(map[string]interface{})
+		flattenExportRow("", obj, row)
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
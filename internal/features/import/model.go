@@ -2,6 +2,7 @@ package import_feature
 
 import (
 	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/record"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -33,6 +34,8 @@ type ImportJob struct {
 	CreatedAt        time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt        time.Time          `json:"updated_at" bson:"updated_at"`
 	CompletedAt      *time.Time         `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+
+	Triggers record.TriggerOptions `json:"triggers" bson:"triggers"`
 }
 
 // ImportError represents an error during import
@@ -44,8 +47,71 @@ type ImportError struct {
 
 // ImportPreview represents a preview of import data
 type ImportPreview struct {
-	Headers      []string                 `json:"headers"`
-	SampleData   []map[string]interface{} `json:"sample_data"`
-	TotalRows    int                      `json:"total_rows"`
-	ModuleFields []common_models.ModuleField     `json:"module_fields"`
+	Headers      []string                    `json:"headers"`
+	SampleData   []map[string]interface{}    `json:"sample_data"`
+	TotalRows    int                         `json:"total_rows"`
+	ModuleFields []common_models.ModuleField `json:"module_fields"`
+}
+
+// CRMSource identifies the system a migration export came from
+type CRMSource string
+
+const (
+	CRMSourceSalesforce CRMSource = "salesforce"
+	CRMSourceHubSpot    CRMSource = "hubspot"
+	CRMSourceZoho       CRMSource = "zoho"
+)
+
+// CRM objects in dependency order; parents are imported first so child
+// records can resolve their relationships to already-created records.
+const (
+	CRMObjectAccounts   = "accounts"
+	CRMObjectContacts   = "contacts"
+	CRMObjectDeals      = "deals"
+	CRMObjectActivities = "activities"
+)
+
+var CRMObjectOrder = []string{CRMObjectAccounts, CRMObjectContacts, CRMObjectDeals, CRMObjectActivities}
+
+// CRMImportJob migrates a set of export files from another CRM in one run
+type CRMImportJob struct {
+	ID          primitive.ObjectID           `json:"id" bson:"_id,omitempty"`
+	TenantID    primitive.ObjectID           `json:"tenant_id" bson:"tenant_id"`
+	UserID      primitive.ObjectID           `json:"user_id" bson:"user_id"`
+	Source      CRMSource                    `json:"source" bson:"source"`
+	Files       []CRMImportFile              `json:"files" bson:"files"`
+	Modules     map[string]string            `json:"modules" bson:"modules"` // CRM object -> target module
+	Status      ImportStatus                 `json:"status" bson:"status"`
+	Summary     map[string]*CRMObjectSummary `json:"summary" bson:"summary"`
+	Errors      []CRMImportError             `json:"errors,omitempty" bson:"errors,omitempty"`
+	Triggers    record.TriggerOptions        `json:"triggers" bson:"triggers"`
+	CreatedAt   time.Time                    `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time                    `json:"updated_at" bson:"updated_at"`
+	CompletedAt *time.Time                   `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+}
+
+// CRMImportFile is one uploaded export file for a CRM object
+type CRMImportFile struct {
+	Object   string `json:"object" bson:"object"`
+	FileName string `json:"file_name" bson:"file_name"`
+	FilePath string `json:"-" bson:"file_path"`
+}
+
+// CRMObjectSummary reports the outcome of importing one CRM object
+type CRMObjectSummary struct {
+	Module              string `json:"module" bson:"module"`
+	TotalRows           int    `json:"total_rows" bson:"total_rows"`
+	Created             int    `json:"created" bson:"created"`
+	Failed              int    `json:"failed" bson:"failed"`
+	Skipped             int    `json:"skipped" bson:"skipped"`
+	LinkedRelations     int    `json:"linked_relations" bson:"linked_relations"`
+	UnresolvedRelations int    `json:"unresolved_relations" bson:"unresolved_relations"`
+}
+
+// CRMImportError represents a row that could not be imported
+type CRMImportError struct {
+	Object     string `json:"object" bson:"object"`
+	Row        int    `json:"row" bson:"row"`
+	ExternalID string `json:"external_id,omitempty" bson:"external_id,omitempty"`
+	Message    string `json:"message" bson:"message"`
 }
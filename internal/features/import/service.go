@@ -22,21 +22,30 @@ type ImportService interface {
 	PreviewFile(ctx context.Context, file io.Reader, filename string, moduleName string) (*ImportPreview, error)
 	ProcessImport(ctx context.Context, jobID string, userID primitive.ObjectID) error
 	ProcessImportWithData(ctx context.Context, data []map[string]interface{}, columnMapping map[string]string, moduleName string, userID primitive.ObjectID, jobID string) error
+
+	// CRM migrations
+	GetCRMMapping(source CRMSource) (map[string]ObjectMapping, error)
+	StartCRMImport(ctx context.Context, job *CRMImportJob) error
+	GetCRMImportJob(ctx context.Context, id string) (*CRMImportJob, error)
+	ListCRMImportJobs(ctx context.Context) ([]CRMImportJob, error)
 }
 
 type ImportServiceImpl struct {
 	ImportRepo    ImportRepository
+	CRMImportRepo CRMImportRepository
 	RecordService record.RecordService
 	ModuleService module.ModuleService
 }
 
 func NewImportService(
 	importRepo ImportRepository,
+	crmImportRepo CRMImportRepository,
 	recordService record.RecordService,
 	moduleService module.ModuleService,
 ) ImportService {
 	return &ImportServiceImpl{
 		ImportRepo:    importRepo,
+		CRMImportRepo: crmImportRepo,
 		RecordService: recordService,
 		ModuleService: moduleService,
 	}
@@ -169,6 +178,7 @@ func (s *ImportServiceImpl) ProcessImport(ctx context.Context, jobID string, use
 	}
 
 	s.ImportRepo.UpdateStatus(ctx, jobID, ImportStatusProcessing)
+	ctx, batch := record.WithTriggerBatch(ctx, job.Triggers, jobID)
 
 	file, err := os.Open(job.FilePath)
 	if err != nil {
@@ -237,6 +247,8 @@ func (s *ImportServiceImpl) ProcessImport(ctx context.Context, jobID string, use
 		}
 	}
 
+	batch.Flush(ctx)
+
 	job.ProcessedRecords = len(allData)
 	job.SuccessCount = successCount
 	job.ErrorCount = errorCount
@@ -322,6 +334,7 @@ func (s *ImportServiceImpl) ProcessImportWithData(ctx context.Context, data []ma
 	}
 
 	s.ImportRepo.UpdateStatus(ctx, jobID, ImportStatusProcessing)
+	ctx, batch := record.WithTriggerBatch(ctx, job.Triggers, jobID)
 
 	var successCount, errorCount int
 	var errs []ImportError
@@ -351,6 +364,7 @@ func (s *ImportServiceImpl) ProcessImportWithData(ctx context.Context, data []ma
 		job.ErrorCount = errorCount
 		job.Errors = errs
 	}
+	batch.Flush(ctx)
 
 	job.Status = ImportStatusCompleted
 	now := time.Now()
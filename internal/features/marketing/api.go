@@ -0,0 +1,39 @@
+package marketing
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type MarketingApi struct {
+	controller  *MarketingController
+	config      *config.Config
+	roleService middleware.RoleService
+}
+
+func NewMarketingApi(controller *MarketingController, config *config.Config, roleService middleware.RoleService) *MarketingApi {
+	return &MarketingApi{
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *MarketingApi) Setup(app *fiber.App) {
+	group := app.Group("/api/marketing", middleware.AuthMiddleware(h.config.SkipAuth))
+
+	group.Get("/connections", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListConnections)
+	group.Post("/connections", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.CreateConnection)
+	group.Delete("/connections/:id", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.DeleteConnection)
+	group.Get("/connections/:id/lists", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListAudiences)
+
+	group.Get("/audiences", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListAudienceSyncs)
+	group.Post("/audiences", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.CreateAudienceSync)
+	group.Get("/audiences/:id", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.GetAudienceSync)
+	group.Put("/audiences/:id", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.UpdateAudienceSync)
+	group.Delete("/audiences/:id", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.DeleteAudienceSync)
+	group.Post("/audiences/:id/run", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.RunAudienceSync)
+	group.Get("/audiences/:id/runs", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListRuns)
+}
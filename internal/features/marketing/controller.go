@@ -0,0 +1,221 @@
+package marketing
+
+import (
+	"errors"
+
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type MarketingController struct {
+	Service MarketingService
+}
+
+func NewMarketingController(service MarketingService) *MarketingController {
+	return &MarketingController{Service: service}
+}
+
+// CreateConnection godoc
+// @Summary Connect a marketing platform
+// @Description Store a Mailchimp API key or HubSpot private app token after verifying it
+// @Tags marketing
+// @Accept json
+// @Produce json
+// @Param connection body Connection true "Provider, name and api_key"
+// @Success 201 {object} Connection
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/marketing/connections [post]
+func (c *MarketingController) CreateConnection(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var conn Connection
+	if err := ctx.BodyParser(&conn); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := c.Service.CreateConnection(ctx.UserContext(), &conn, userID); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{"data": conn})
+}
+
+// ListConnections godoc
+// @Summary List marketing connections
+// @Tags marketing
+// @Produce json
+// @Success 200 {array} Connection
+// @Router /api/marketing/connections [get]
+func (c *MarketingController) ListConnections(ctx *fiber.Ctx) error {
+	conns, err := c.Service.ListConnections(ctx.UserContext())
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": conns})
+}
+
+// DeleteConnection godoc
+// @Summary Delete marketing connection
+// @Description Fails while audience syncs still use the connection
+// @Tags marketing
+// @Param id path string true "Connection ID"
+// @Success 204
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/marketing/connections/{id} [delete]
+func (c *MarketingController) DeleteConnection(ctx *fiber.Ctx) error {
+	err := c.Service.DeleteConnection(ctx.UserContext(), ctx.Params("id"))
+	if errors.Is(err, ErrConnectionInUse) {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// ListAudiences godoc
+// @Summary List platform audiences
+// @Description List the Mailchimp audiences or HubSpot static lists of a connection
+// @Tags marketing
+// @Produce json
+// @Param id path string true "Connection ID"
+// @Success 200 {array} Audience
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/marketing/connections/{id}/lists [get]
+func (c *MarketingController) ListAudiences(ctx *fiber.Ctx) error {
+	lists, err := c.Service.ListAudiences(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": lists})
+}
+
+// CreateAudienceSync godoc
+// @Summary Create audience sync
+// @Description Sync the records matching a saved filter to a platform list
+// @Tags marketing
+// @Accept json
+// @Produce json
+// @Param sync body AudienceSync true "Audience sync"
+// @Success 201 {object} AudienceSync
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/marketing/audiences [post]
+func (c *MarketingController) CreateAudienceSync(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var a AudienceSync
+	if err := ctx.BodyParser(&a); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := c.Service.CreateAudienceSync(ctx.UserContext(), &a, userID); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{"data": a})
+}
+
+// ListAudienceSyncs godoc
+// @Summary List audience syncs
+// @Tags marketing
+// @Produce json
+// @Success 200 {array} AudienceSync
+// @Router /api/marketing/audiences [get]
+func (c *MarketingController) ListAudienceSyncs(ctx *fiber.Ctx) error {
+	syncs, err := c.Service.ListAudienceSyncs(ctx.UserContext())
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": syncs})
+}
+
+// GetAudienceSync godoc
+// @Summary Get audience sync
+// @Tags marketing
+// @Produce json
+// @Param id path string true "Audience sync ID"
+// @Success 200 {object} AudienceSync
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/marketing/audiences/{id} [get]
+func (c *MarketingController) GetAudienceSync(ctx *fiber.Ctx) error {
+	a, err := c.Service.GetAudienceSync(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Audience sync not found"})
+	}
+	return ctx.JSON(fiber.Map{"data": a})
+}
+
+// UpdateAudienceSync godoc
+// @Summary Update audience sync
+// @Description The connection and list cannot be changed
+// @Tags marketing
+// @Accept json
+// @Produce json
+// @Param id path string true "Audience sync ID"
+// @Param sync body AudienceSync true "Audience sync"
+// @Success 200 {object} AudienceSync
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/marketing/audiences/{id} [put]
+func (c *MarketingController) UpdateAudienceSync(ctx *fiber.Ctx) error {
+	var in AudienceSync
+	if err := ctx.BodyParser(&in); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	a, err := c.Service.UpdateAudienceSync(ctx.UserContext(), ctx.Params("id"), &in)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": a})
+}
+
+// DeleteAudienceSync godoc
+// @Summary Delete audience sync
+// @Description Members already on the platform list are left in place
+// @Tags marketing
+// @Param id path string true "Audience sync ID"
+// @Success 204
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/marketing/audiences/{id} [delete]
+func (c *MarketingController) DeleteAudienceSync(ctx *fiber.Ctx) error {
+	if err := c.Service.DeleteAudienceSync(ctx.UserContext(), ctx.Params("id")); err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// RunAudienceSync godoc
+// @Summary Run audience sync
+// @Description Push the segment to the list and pull engagement events now. Use a cron job with the marketing_sync action to schedule it.
+// @Tags marketing
+// @Produce json
+// @Param id path string true "Audience sync ID"
+// @Success 200 {object} sync.SyncLog
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/marketing/audiences/{id}/run [post]
+func (c *MarketingController) RunAudienceSync(ctx *fiber.Ctx) error {
+	run, err := c.Service.RunAudienceSync(ctx.UserContext(), ctx.Params("id"))
+	if errors.Is(err, ErrSyncRunning) {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	if run == nil && err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": run})
+}
+
+// ListRuns godoc
+// @Summary List audience sync runs
+// @Tags marketing
+// @Produce json
+// @Param id path string true "Audience sync ID"
+// @Param limit query int false "Limit"
+// @Success 200 {array} sync.SyncLog
+// @Router /api/marketing/audiences/{id}/runs [get]
+func (c *MarketingController) ListRuns(ctx *fiber.Ctx) error {
+	runs, err := c.Service.ListRuns(ctx.UserContext(), ctx.Params("id"), int64(ctx.QueryInt("limit", 20)))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": runs})
+}
@@ -0,0 +1,218 @@
+package marketing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	hubSpotAPI       = "https://api.hubapi.com"
+	hubSpotBatchSize = 100
+)
+
+// hubSpot syncs contacts into static (manual) lists with a private app
+// token. Unsubscribing removes the contact from the list; HubSpot keeps
+// email consent on the contact's subscription types.
+type hubSpot struct {
+	http *http.Client
+}
+
+func (h *hubSpot) call(ctx context.Context, conn *Connection, method, path string, in, out any) error {
+	auth := func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+conn.APIKey) }
+	return doJSON(ctx, h.http, method, hubSpotAPI+path, auth, in, out)
+}
+
+func (h *hubSpot) Verify(ctx context.Context, conn *Connection) (string, error) {
+	var account struct {
+		PortalID int64 `json:"portalId"`
+	}
+	if err := h.call(ctx, conn, http.MethodGet, "/account-info/v3/details", nil, &account); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("HubSpot portal %d", account.PortalID), nil
+}
+
+func (h *hubSpot) Lists(ctx context.Context, conn *Connection) ([]Audience, error) {
+	lists := []Audience{}
+	for offset := 0; ; {
+		body := map[string]any{
+			"count":           250,
+			"offset":          offset,
+			"processingTypes": []string{"MANUAL", "SNAPSHOT"},
+		}
+		var resp struct {
+			Lists []struct {
+				ListID string `json:"listId"`
+				Name   string `json:"name"`
+			} `json:"lists"`
+			HasMore bool `json:"hasMore"`
+			Offset  int  `json:"offset"`
+		}
+		if err := h.call(ctx, conn, http.MethodPost, "/crm/v3/lists/search", body, &resp); err != nil {
+			return nil, err
+		}
+		for _, l := range resp.Lists {
+			lists = append(lists, Audience{ID: l.ListID, Name: l.Name})
+		}
+		if !resp.HasMore || resp.Offset <= offset {
+			return lists, nil
+		}
+		offset = resp.Offset
+	}
+}
+
+func (h *hubSpot) Upsert(ctx context.Context, conn *Connection, listID string, members []*Member) ([]error, error) {
+	errs := make([]error, len(members))
+	offset := 0
+	for _, chunk := range batches(members, hubSpotBatchSize) {
+		inputs := make([]map[string]any, 0, len(chunk))
+		for _, member := range chunk {
+			props := map[string]any{"email": member.Email}
+			if member.FirstName != "" {
+				props["firstname"] = member.FirstName
+			}
+			if member.LastName != "" {
+				props["lastname"] = member.LastName
+			}
+			for k, v := range member.Fields {
+				props[k] = v
+			}
+			inputs = append(inputs, map[string]any{"idProperty": "email", "id": member.Email, "properties": props})
+		}
+
+		var resp struct {
+			Results []struct {
+				ID         string            `json:"id"`
+				Properties map[string]string `json:"properties"`
+			} `json:"results"`
+		}
+		err := h.call(ctx, conn, http.MethodPost, "/crm/v3/objects/contacts/batch/upsert", map[string]any{"inputs": inputs}, &resp)
+		if errors.Is(err, ErrAuthRejected) {
+			return nil, err
+		}
+		if err != nil {
+			fillErrors(errs, offset, len(chunk), err)
+			offset += len(chunk)
+			continue
+		}
+
+		ids := map[string]string{}
+		for _, r := range resp.Results {
+			ids[strings.ToLower(r.Properties["email"])] = r.ID
+		}
+		recordIDs := []string{}
+		for i, member := range chunk {
+			id, ok := ids[strings.ToLower(member.Email)]
+			if !ok {
+				errs[offset+i] = errors.New("contact was not returned by HubSpot")
+				continue
+			}
+			member.ExternalID = id
+			recordIDs = append(recordIDs, id)
+		}
+
+		if len(recordIDs) > 0 {
+			err := h.call(ctx, conn, http.MethodPut, "/crm/v3/lists/"+url.PathEscape(listID)+"/memberships/add", recordIDs, nil)
+			if errors.Is(err, ErrAuthRejected) {
+				return nil, err
+			}
+			if err != nil {
+				for i, member := range chunk {
+					if member.ExternalID != "" && errs[offset+i] == nil {
+						errs[offset+i] = fmt.Errorf("failed to add to list: %w", err)
+					}
+				}
+			}
+		}
+		offset += len(chunk)
+	}
+	return errs, nil
+}
+
+func (h *hubSpot) Unsubscribe(ctx context.Context, conn *Connection, listID string, members []*Member) ([]error, error) {
+	errs := make([]error, len(members))
+	offset := 0
+	for _, chunk := range batches(members, hubSpotBatchSize) {
+		recordIDs := []string{}
+		for i, member := range chunk {
+			if member.ExternalID == "" {
+				errs[offset+i] = errors.New("HubSpot contact ID is unknown")
+				continue
+			}
+			recordIDs = append(recordIDs, member.ExternalID)
+		}
+		if len(recordIDs) > 0 {
+			err := h.call(ctx, conn, http.MethodPut, "/crm/v3/lists/"+url.PathEscape(listID)+"/memberships/remove", recordIDs, nil)
+			if errors.Is(err, ErrAuthRejected) {
+				return nil, err
+			}
+			if err != nil {
+				for i := range chunk {
+					if errs[offset+i] == nil {
+						errs[offset+i] = err
+					}
+				}
+			}
+		}
+		offset += len(chunk)
+	}
+	return errs, nil
+}
+
+// Events reads the portal's email event stream. It is not scoped to a list;
+// the caller keeps only events for known members.
+func (h *hubSpot) Events(ctx context.Context, conn *Connection, listID string, since time.Time) ([]EngagementEvent, error) {
+	types := map[string]string{
+		"OPEN":       EventOpen,
+		"CLICK":      EventClick,
+		"BOUNCE":     EventBounce,
+		"SPAMREPORT": EventSpamReport,
+	}
+
+	var events []EngagementEvent
+	offset := ""
+	for {
+		q := url.Values{}
+		q.Set("startTimestamp", fmt.Sprint(since.UnixMilli()+1))
+		q.Set("limit", "1000")
+		if offset != "" {
+			q.Set("offset", offset)
+		}
+		var page struct {
+			Events []struct {
+				Type            string `json:"type"`
+				Recipient       string `json:"recipient"`
+				Created         int64  `json:"created"`
+				URL             string `json:"url"`
+				EmailCampaignID int64  `json:"emailCampaignId"`
+			} `json:"events"`
+			HasMore bool   `json:"hasMore"`
+			Offset  string `json:"offset"`
+		}
+		if err := h.call(ctx, conn, http.MethodGet, "/email/public/v1/events?"+q.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+		for _, e := range page.Events {
+			eventType := types[e.Type]
+			if eventType == "" {
+				continue
+			}
+			events = append(events, EngagementEvent{
+				Email:      strings.ToLower(e.Recipient),
+				Type:       eventType,
+				OccurredAt: time.UnixMilli(e.Created),
+				Campaign:   fmt.Sprintf("HubSpot campaign %d", e.EmailCampaignID),
+				URL:        e.URL,
+			})
+		}
+		if !page.HasMore || page.Offset == "" || page.Offset == offset {
+			return events, nil
+		}
+		offset = page.Offset
+	}
+}
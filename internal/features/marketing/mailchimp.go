@@ -0,0 +1,212 @@
+package marketing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	mailchimpBatchSize = 500
+	mailchimpPageSize  = 1000
+	// Campaigns sent this long before the cursor can still collect engagement
+	mailchimpCampaignWindow = 30 * 24 * time.Hour
+)
+
+// mailchimp syncs audience members through the Marketing API. API keys end
+// in the account's datacenter, e.g. "...-us21".
+type mailchimp struct {
+	http *http.Client
+}
+
+func (m *mailchimp) baseURL(conn *Connection) (string, error) {
+	i := strings.LastIndex(conn.APIKey, "-")
+	if i < 0 || i == len(conn.APIKey)-1 {
+		return "", errors.New("Mailchimp API key must end with the datacenter, e.g. -us21")
+	}
+	dc := conn.APIKey[i+1:]
+	for _, c := range dc {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return "", errors.New("invalid Mailchimp datacenter in API key")
+		}
+	}
+	return fmt.Sprintf("https://%s.api.mailchimp.com/3.0", dc), nil
+}
+
+func (m *mailchimp) call(ctx context.Context, conn *Connection, method, path string, in, out any) error {
+	base, err := m.baseURL(conn)
+	if err != nil {
+		return err
+	}
+	auth := func(req *http.Request) { req.SetBasicAuth("crm", conn.APIKey) }
+	return doJSON(ctx, m.http, method, base+path, auth, in, out)
+}
+
+func (m *mailchimp) Verify(ctx context.Context, conn *Connection) (string, error) {
+	var account struct {
+		AccountName string `json:"account_name"`
+	}
+	if err := m.call(ctx, conn, http.MethodGet, "/", nil, &account); err != nil {
+		return "", err
+	}
+	return account.AccountName, nil
+}
+
+func (m *mailchimp) Lists(ctx context.Context, conn *Connection) ([]Audience, error) {
+	var resp struct {
+		Lists []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"lists"`
+	}
+	if err := m.call(ctx, conn, http.MethodGet, "/lists?count=1000&fields=lists.id,lists.name", nil, &resp); err != nil {
+		return nil, err
+	}
+	lists := make([]Audience, 0, len(resp.Lists))
+	for _, l := range resp.Lists {
+		lists = append(lists, Audience{ID: l.ID, Name: l.Name})
+	}
+	return lists, nil
+}
+
+type mailchimpMember struct {
+	EmailAddress string         `json:"email_address"`
+	Status       string         `json:"status,omitempty"`
+	StatusIfNew  string         `json:"status_if_new,omitempty"`
+	MergeFields  map[string]any `json:"merge_fields,omitempty"`
+}
+
+type mailchimpBatchResponse struct {
+	Errors []struct {
+		EmailAddress string `json:"email_address"`
+		Error        string `json:"error"`
+	} `json:"errors"`
+}
+
+// batch sends members through the batch subscribe endpoint, which reports
+// failures per address
+func (m *mailchimp) batch(ctx context.Context, conn *Connection, listID string, members []*Member, build func(*Member) mailchimpMember) ([]error, error) {
+	errs := make([]error, len(members))
+	offset := 0
+	for _, chunk := range batches(members, mailchimpBatchSize) {
+		body := map[string]any{"update_existing": true}
+		payload := make([]mailchimpMember, 0, len(chunk))
+		index := map[string]int{}
+		for i, member := range chunk {
+			payload = append(payload, build(member))
+			index[strings.ToLower(member.Email)] = offset + i
+		}
+		body["members"] = payload
+
+		var resp mailchimpBatchResponse
+		err := m.call(ctx, conn, http.MethodPost, "/lists/"+url.PathEscape(listID), body, &resp)
+		if errors.Is(err, ErrAuthRejected) {
+			return nil, err
+		}
+		if err != nil {
+			fillErrors(errs, offset, len(chunk), err)
+		}
+		for _, e := range resp.Errors {
+			if i, ok := index[strings.ToLower(e.EmailAddress)]; ok {
+				errs[i] = errors.New(e.Error)
+			}
+		}
+		offset += len(chunk)
+	}
+	return errs, nil
+}
+
+// Upsert never resubscribes: existing members keep their status, so
+// people who unsubscribed on their own stay unsubscribed
+func (m *mailchimp) Upsert(ctx context.Context, conn *Connection, listID string, members []*Member) ([]error, error) {
+	return m.batch(ctx, conn, listID, members, func(member *Member) mailchimpMember {
+		merge := map[string]any{}
+		if member.FirstName != "" {
+			merge["FNAME"] = member.FirstName
+		}
+		if member.LastName != "" {
+			merge["LNAME"] = member.LastName
+		}
+		for k, v := range member.Fields {
+			merge[strings.ToUpper(k)] = v
+		}
+		return mailchimpMember{EmailAddress: member.Email, StatusIfNew: "subscribed", MergeFields: merge}
+	})
+}
+
+func (m *mailchimp) Unsubscribe(ctx context.Context, conn *Connection, listID string, members []*Member) ([]error, error) {
+	return m.batch(ctx, conn, listID, members, func(member *Member) mailchimpMember {
+		return mailchimpMember{EmailAddress: member.Email, Status: "unsubscribed"}
+	})
+}
+
+func (m *mailchimp) Events(ctx context.Context, conn *Connection, listID string, since time.Time) ([]EngagementEvent, error) {
+	q := url.Values{}
+	q.Set("list_id", listID)
+	q.Set("status", "sent")
+	q.Set("since_send_time", since.Add(-mailchimpCampaignWindow).UTC().Format(time.RFC3339))
+	q.Set("count", "1000")
+	q.Set("fields", "campaigns.id,campaigns.settings.title,campaigns.settings.subject_line")
+	var campaigns struct {
+		Campaigns []struct {
+			ID       string `json:"id"`
+			Settings struct {
+				Title       string `json:"title"`
+				SubjectLine string `json:"subject_line"`
+			} `json:"settings"`
+		} `json:"campaigns"`
+	}
+	if err := m.call(ctx, conn, http.MethodGet, "/campaigns?"+q.Encode(), nil, &campaigns); err != nil {
+		return nil, err
+	}
+
+	var events []EngagementEvent
+	for _, c := range campaigns.Campaigns {
+		title := c.Settings.Title
+		if title == "" {
+			title = c.Settings.SubjectLine
+		}
+		for offset := 0; ; offset += mailchimpPageSize {
+			q := url.Values{}
+			q.Set("since", since.UTC().Format(time.RFC3339))
+			q.Set("count", fmt.Sprint(mailchimpPageSize))
+			q.Set("offset", fmt.Sprint(offset))
+			var page struct {
+				Emails []struct {
+					EmailAddress string `json:"email_address"`
+					Activity     []struct {
+						Action    string    `json:"action"`
+						Timestamp time.Time `json:"timestamp"`
+						URL       string    `json:"url"`
+					} `json:"activity"`
+				} `json:"emails"`
+			}
+			if err := m.call(ctx, conn, http.MethodGet, "/reports/"+url.PathEscape(c.ID)+"/email-activity?"+q.Encode(), nil, &page); err != nil {
+				return nil, err
+			}
+			for _, e := range page.Emails {
+				for _, a := range e.Activity {
+					eventType := map[string]string{"open": EventOpen, "click": EventClick, "bounce": EventBounce}[a.Action]
+					if eventType == "" || !a.Timestamp.After(since) {
+						continue
+					}
+					events = append(events, EngagementEvent{
+						Email:      strings.ToLower(e.EmailAddress),
+						Type:       eventType,
+						OccurredAt: a.Timestamp,
+						Campaign:   title,
+						URL:        a.URL,
+					})
+				}
+			}
+			if len(page.Emails) < mailchimpPageSize {
+				break
+			}
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,111 @@
+package marketing
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Provider string
+
+const (
+	ProviderMailchimp Provider = "mailchimp"
+	ProviderHubSpot   Provider = "hubspot"
+)
+
+// Member states kept per audience
+const (
+	MemberSubscribed   = "subscribed"
+	MemberUnsubscribed = "unsubscribed"
+)
+
+// Engagement types written to activity records
+const (
+	EventOpen        = "email_open"
+	EventClick       = "email_click"
+	EventBounce      = "email_bounce"
+	EventSpamReport  = "email_spam_report"
+	EventUnsubscribe = "email_unsubscribe"
+)
+
+// Connection holds a tenant's credentials for one marketing platform: a
+// Mailchimp API key or a HubSpot private app token
+type Connection struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID    primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	Provider    Provider           `json:"provider" bson:"provider"`
+	Name        string             `json:"name" bson:"name"`
+	APIKey      string             `json:"api_key,omitempty" bson:"api_key"` // write-only; cleared before responses
+	AccountName string             `json:"account_name" bson:"account_name"`
+	CreatedBy   primitive.ObjectID `json:"created_by" bson:"created_by"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// AudienceSync keeps a platform list in step with the records matching a
+// saved filter
+type AudienceSync struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID      primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	ConnectionID  primitive.ObjectID `json:"connection_id" bson:"connection_id"`
+	Name          string             `json:"name" bson:"name"`
+	ModuleName    string             `json:"module_name" bson:"module_name"`
+	SavedFilterID string             `json:"saved_filter_id" bson:"saved_filter_id"`
+	ListID        string             `json:"list_id" bson:"list_id"` // Mailchimp audience ID or HubSpot list ID
+
+	EmailField     string `json:"email_field" bson:"email_field"`
+	FirstNameField string `json:"first_name_field,omitempty" bson:"first_name_field,omitempty"`
+	LastNameField  string `json:"last_name_field,omitempty" bson:"last_name_field,omitempty"`
+	// Extra platform fields: Mailchimp merge tag or HubSpot property -> CRM field
+	FieldMapping map[string]string `json:"field_mapping,omitempty" bson:"field_mapping,omitempty"`
+
+	// UnsubscribeRemoved unsubscribes members whose record left the segment
+	UnsubscribeRemoved bool `json:"unsubscribe_removed" bson:"unsubscribe_removed"`
+	// ActivityModule receives engagement events as records; empty disables the pull
+	ActivityModule string `json:"activity_module,omitempty" bson:"activity_module,omitempty"`
+
+	IsActive     bool               `json:"is_active" bson:"is_active"`
+	LastRunAt    time.Time          `json:"last_run_at" bson:"last_run_at"`
+	EventsCursor time.Time          `json:"events_cursor" bson:"events_cursor"` // newest engagement event imported
+	CreatedBy    primitive.ObjectID `json:"created_by" bson:"created_by"`
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// MemberState remembers what was last sent for a record so unchanged
+// members are skipped and departures can be unsubscribed
+type MemberState struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	TenantID       primitive.ObjectID `bson:"tenant_id"`
+	AudienceSyncID primitive.ObjectID `bson:"audience_sync_id"`
+	RecordID       string             `bson:"record_id"`
+	Email          string             `bson:"email"`
+	ExternalID     string             `bson:"external_id,omitempty"` // HubSpot contact ID
+	Hash           string             `bson:"hash"`
+	Status         string             `bson:"status"`
+	UpdatedAt      time.Time          `bson:"updated_at"`
+}
+
+// Audience is a list on the platform that members can be synced to
+type Audience struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Member is the platform-neutral form of a synced record
+type Member struct {
+	Email      string
+	FirstName  string
+	LastName   string
+	Fields     map[string]any
+	ExternalID string // set by the platform on upsert when it has its own IDs
+}
+
+// EngagementEvent is an email interaction reported by the platform
+type EngagementEvent struct {
+	Email      string
+	Type       string
+	OccurredAt time.Time
+	Campaign   string
+	URL        string
+}
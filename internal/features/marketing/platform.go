@@ -0,0 +1,97 @@
+package marketing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrAuthRejected means the platform refused the connection's credentials
+var ErrAuthRejected = errors.New("marketing platform rejected the connection credentials")
+
+// platform is implemented once per marketing system
+type platform interface {
+	// Verify checks the credentials and returns the account name
+	Verify(ctx context.Context, conn *Connection) (string, error)
+	Lists(ctx context.Context, conn *Connection) ([]Audience, error)
+	// Upsert creates or updates members on the list and returns one error
+	// slot per member
+	Upsert(ctx context.Context, conn *Connection, listID string, members []*Member) ([]error, error)
+	Unsubscribe(ctx context.Context, conn *Connection, listID string, members []*Member) ([]error, error)
+	// Events returns engagement on emails sent to the list after since
+	Events(ctx context.Context, conn *Connection, listID string, since time.Time) ([]EngagementEvent, error)
+}
+
+func newPlatforms() map[Provider]platform {
+	client := &http.Client{Timeout: 30 * time.Second}
+	return map[Provider]platform{
+		ProviderMailchimp: &mailchimp{http: client},
+		ProviderHubSpot:   &hubSpot{http: client},
+	}
+}
+
+// doJSON sends an API call and decodes the JSON response into out
+func doJSON(ctx context.Context, client *http.Client, method, endpoint string, auth func(*http.Request), in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	auth(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 20<<20))
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return ErrAuthRejected
+	}
+	if resp.StatusCode >= 300 {
+		msg := strings.TrimSpace(string(respBody))
+		if len(msg) > 500 {
+			msg = msg[:500] + "..."
+		}
+		return fmt.Errorf("%s %s failed with status %d: %s", method, endpoint, resp.StatusCode, msg)
+	}
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+// batches splits members into chunks of at most size
+func batches(members []*Member, size int) [][]*Member {
+	var out [][]*Member
+	for start := 0; start < len(members); start += size {
+		out = append(out, members[start:min(start+size, len(members))])
+	}
+	return out
+}
+
+// fillErrors gives every member the same error, for batch calls that fail as a whole
+func fillErrors(errs []error, offset, n int, err error) {
+	for i := offset; i < offset+n; i++ {
+		errs[i] = err
+	}
+}
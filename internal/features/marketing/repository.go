@@ -0,0 +1,287 @@
+package marketing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+type ConnectionRepository interface {
+	Create(ctx context.Context, conn *Connection) error
+	Get(ctx context.Context, id string) (*Connection, error)
+	List(ctx context.Context) ([]Connection, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type ConnectionRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewConnectionRepository(db *database.MongodbDB) ConnectionRepository {
+	return &ConnectionRepositoryImpl{
+		collection: db.DB.Collection("marketing_connections"),
+	}
+}
+
+func (r *ConnectionRepositoryImpl) Create(ctx context.Context, conn *Connection) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	conn.ID = primitive.NewObjectID()
+	conn.TenantID = tenantID
+	conn.CreatedAt = time.Now()
+	conn.UpdatedAt = conn.CreatedAt
+
+	_, err = r.collection.InsertOne(ctx, conn)
+	return err
+}
+
+func (r *ConnectionRepositoryImpl) Get(ctx context.Context, id string) (*Connection, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn Connection
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID}).Decode(&conn); err != nil {
+		return nil, err
+	}
+	return &conn, nil
+}
+
+func (r *ConnectionRepositoryImpl) List(ctx context.Context) ([]Connection, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"tenant_id": tenantID}, options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	conns := []Connection{}
+	if err := cursor.All(ctx, &conns); err != nil {
+		return nil, err
+	}
+	return conns, nil
+}
+
+func (r *ConnectionRepositoryImpl) Delete(ctx context.Context, id string) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID})
+	return err
+}
+
+type AudienceSyncRepository interface {
+	Create(ctx context.Context, sync *AudienceSync) error
+	Get(ctx context.Context, id string) (*AudienceSync, error)
+	List(ctx context.Context) ([]AudienceSync, error)
+	Update(ctx context.Context, sync *AudienceSync) error
+	Delete(ctx context.Context, id string) error
+	CountByConnection(ctx context.Context, connectionID primitive.ObjectID) (int64, error)
+
+	// GetForRun loads an audience sync regardless of tenant. Used by
+	// scheduled jobs, which run without a tenant context.
+	GetForRun(ctx context.Context, id string) (*AudienceSync, error)
+}
+
+type AudienceSyncRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewAudienceSyncRepository(db *database.MongodbDB) AudienceSyncRepository {
+	return &AudienceSyncRepositoryImpl{
+		collection: db.DB.Collection("marketing_audience_syncs"),
+	}
+}
+
+func (r *AudienceSyncRepositoryImpl) Create(ctx context.Context, sync *AudienceSync) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	sync.ID = primitive.NewObjectID()
+	sync.TenantID = tenantID
+	sync.CreatedAt = time.Now()
+	sync.UpdatedAt = sync.CreatedAt
+
+	_, err = r.collection.InsertOne(ctx, sync)
+	return err
+}
+
+func (r *AudienceSyncRepositoryImpl) Get(ctx context.Context, id string) (*AudienceSync, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var sync AudienceSync
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID}).Decode(&sync); err != nil {
+		return nil, err
+	}
+	return &sync, nil
+}
+
+func (r *AudienceSyncRepositoryImpl) GetForRun(ctx context.Context, id string) (*AudienceSync, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var sync AudienceSync
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&sync); err != nil {
+		return nil, err
+	}
+	return &sync, nil
+}
+
+func (r *AudienceSyncRepositoryImpl) List(ctx context.Context) ([]AudienceSync, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"tenant_id": tenantID}, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	syncs := []AudienceSync{}
+	if err := cursor.All(ctx, &syncs); err != nil {
+		return nil, err
+	}
+	return syncs, nil
+}
+
+func (r *AudienceSyncRepositoryImpl) Update(ctx context.Context, sync *AudienceSync) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	sync.UpdatedAt = time.Now()
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": sync.ID, "tenant_id": tenantID}, sync)
+	return err
+}
+
+func (r *AudienceSyncRepositoryImpl) Delete(ctx context.Context, id string) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID})
+	return err
+}
+
+func (r *AudienceSyncRepositoryImpl) CountByConnection(ctx context.Context, connectionID primitive.ObjectID) (int64, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return r.collection.CountDocuments(ctx, bson.M{"tenant_id": tenantID, "connection_id": connectionID})
+}
+
+type MemberStateRepository interface {
+	// ListBySync returns every member state of an audience keyed by record ID
+	ListBySync(ctx context.Context, audienceSyncID primitive.ObjectID) (map[string]MemberState, error)
+	Save(ctx context.Context, state *MemberState) error
+	DeleteBySync(ctx context.Context, audienceSyncID primitive.ObjectID) error
+}
+
+type MemberStateRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewMemberStateRepository(db *database.MongodbDB) MemberStateRepository {
+	return &MemberStateRepositoryImpl{
+		collection: db.DB.Collection("marketing_member_states"),
+	}
+}
+
+func (r *MemberStateRepositoryImpl) ListBySync(ctx context.Context, audienceSyncID primitive.ObjectID) (map[string]MemberState, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"tenant_id": tenantID, "audience_sync_id": audienceSyncID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	states := map[string]MemberState{}
+	for cursor.Next(ctx) {
+		var state MemberState
+		if err := cursor.Decode(&state); err != nil {
+			return nil, err
+		}
+		states[state.RecordID] = state
+	}
+	return states, cursor.Err()
+}
+
+func (r *MemberStateRepositoryImpl) Save(ctx context.Context, state *MemberState) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if state.ID.IsZero() {
+		state.ID = primitive.NewObjectID()
+	}
+	state.TenantID = tenantID
+	state.UpdatedAt = time.Now()
+
+	_, err = r.collection.ReplaceOne(ctx,
+		bson.M{"tenant_id": tenantID, "audience_sync_id": state.AudienceSyncID, "record_id": state.RecordID},
+		state,
+		options.Replace().SetUpsert(true))
+	return err
+}
+
+func (r *MemberStateRepositoryImpl) DeleteBySync(ctx context.Context, audienceSyncID primitive.ObjectID) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.DeleteMany(ctx, bson.M{"tenant_id": tenantID, "audience_sync_id": audienceSyncID})
+	return err
+}
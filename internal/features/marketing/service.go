@@ -0,0 +1,615 @@
+package marketing
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/saved_filter"
+	data_sync "go-crm/internal/features/sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	recordPageSize  = int64(500)
+	maxLoggedErrors = 200
+)
+
+var (
+	ErrUnknownProvider = errors.New("unsupported marketing provider")
+	ErrSyncRunning     = apperr.Conflict("this audience is already being synced")
+	ErrConnectionInUse = apperr.Conflict("connection is used by audience syncs")
+)
+
+type MarketingService interface {
+	CreateConnection(ctx context.Context, conn *Connection, userID primitive.ObjectID) error
+	ListConnections(ctx context.Context) ([]Connection, error)
+	DeleteConnection(ctx context.Context, id string) error
+	// ListAudiences returns the lists available on the connected account
+	ListAudiences(ctx context.Context, connectionID string) ([]Audience, error)
+
+	CreateAudienceSync(ctx context.Context, sync *AudienceSync, userID primitive.ObjectID) error
+	GetAudienceSync(ctx context.Context, id string) (*AudienceSync, error)
+	ListAudienceSyncs(ctx context.Context) ([]AudienceSync, error)
+	UpdateAudienceSync(ctx context.Context, id string, sync *AudienceSync) (*AudienceSync, error)
+	DeleteAudienceSync(ctx context.Context, id string) error
+
+	RunAudienceSync(ctx context.Context, id string) (*data_sync.SyncLog, error)
+	// RunScheduled runs an audience sync from a cron job, which carries no
+	// tenant; the tenant is taken from the audience sync itself
+	RunScheduled(ctx context.Context, id string) error
+	ListRuns(ctx context.Context, id string, limit int64) ([]data_sync.SyncLog, error)
+}
+
+type MarketingServiceImpl struct {
+	ConnectionRepo     ConnectionRepository
+	AudienceRepo       AudienceSyncRepository
+	StateRepo          MemberStateRepository
+	LogRepo            data_sync.SyncLogRepository
+	RecordRepo         record.RecordRepository
+	ModuleRepo         module.ModuleRepository
+	SavedFilterService saved_filter.SavedFilterService
+	AuditService       audit.AuditService
+
+	platforms map[Provider]platform
+	running   sync.Map // audience sync ID -> struct{}
+}
+
+func NewMarketingService(
+	connectionRepo ConnectionRepository,
+	audienceRepo AudienceSyncRepository,
+	stateRepo MemberStateRepository,
+	logRepo data_sync.SyncLogRepository,
+	recordRepo record.RecordRepository,
+	moduleRepo module.ModuleRepository,
+	savedFilterService saved_filter.SavedFilterService,
+	auditService audit.AuditService,
+) MarketingService {
+	return &MarketingServiceImpl{
+		ConnectionRepo:     connectionRepo,
+		AudienceRepo:       audienceRepo,
+		StateRepo:          stateRepo,
+		LogRepo:            logRepo,
+		RecordRepo:         recordRepo,
+		ModuleRepo:         moduleRepo,
+		SavedFilterService: savedFilterService,
+		AuditService:       auditService,
+		platforms:          newPlatforms(),
+	}
+}
+
+func (s *MarketingServiceImpl) CreateConnection(ctx context.Context, conn *Connection, userID primitive.ObjectID) error {
+	impl, ok := s.platforms[conn.Provider]
+	if !ok {
+		return ErrUnknownProvider
+	}
+	conn.APIKey = strings.TrimSpace(conn.APIKey)
+	if conn.APIKey == "" {
+		return errors.New("api_key is required")
+	}
+
+	account, err := impl.Verify(ctx, conn)
+	if err != nil {
+		return fmt.Errorf("could not verify credentials: %w", err)
+	}
+	conn.AccountName = account
+	if conn.Name == "" {
+		conn.Name = account
+	}
+	conn.CreatedBy = userID
+	if err := s.ConnectionRepo.Create(ctx, conn); err != nil {
+		return err
+	}
+	conn.APIKey = ""
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "marketing", conn.Name, map[string]common_models.Change{
+		"connection": {New: conn},
+	})
+	return nil
+}
+
+func (s *MarketingServiceImpl) ListConnections(ctx context.Context) ([]Connection, error) {
+	conns, err := s.ConnectionRepo.List(ctx)
+	for i := range conns {
+		conns[i].APIKey = ""
+	}
+	return conns, err
+}
+
+func (s *MarketingServiceImpl) DeleteConnection(ctx context.Context, id string) error {
+	conn, err := s.ConnectionRepo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	n, err := s.AudienceRepo.CountByConnection(ctx, conn.ID)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrConnectionInUse
+	}
+	if err := s.ConnectionRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "marketing", conn.Name, map[string]common_models.Change{
+		"connection": {Old: conn.AccountName, New: "DELETED"},
+	})
+	return nil
+}
+
+func (s *MarketingServiceImpl) ListAudiences(ctx context.Context, connectionID string) ([]Audience, error) {
+	conn, err := s.ConnectionRepo.Get(ctx, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	impl, ok := s.platforms[conn.Provider]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return impl.Lists(ctx, conn)
+}
+
+func (s *MarketingServiceImpl) validateAudienceSync(ctx context.Context, a *AudienceSync) error {
+	if a.Name == "" || a.ListID == "" || a.EmailField == "" {
+		return errors.New("name, list_id and email_field are required")
+	}
+	if _, err := s.ConnectionRepo.Get(ctx, a.ConnectionID.Hex()); err != nil {
+		return errors.New("connection not found")
+	}
+	filter, err := s.loadFilter(ctx, a.SavedFilterID)
+	if err != nil {
+		return err
+	}
+	if a.ModuleName == "" {
+		a.ModuleName = filter.ModuleName
+	}
+	if filter.ModuleName != a.ModuleName {
+		return fmt.Errorf("saved filter belongs to module %s", filter.ModuleName)
+	}
+	if _, err := s.ModuleRepo.FindByName(ctx, a.ModuleName); err != nil {
+		return fmt.Errorf("module %s not found", a.ModuleName)
+	}
+	if a.ActivityModule != "" {
+		if _, err := s.ModuleRepo.FindByName(ctx, a.ActivityModule); err != nil {
+			return fmt.Errorf("activity module %s not found", a.ActivityModule)
+		}
+	}
+	return nil
+}
+
+func (s *MarketingServiceImpl) loadFilter(ctx context.Context, id string) (*saved_filter.SavedFilter, error) {
+	filter, err := s.SavedFilterService.GetFilter(ctx, id)
+	if err != nil || filter == nil {
+		return nil, errors.New("saved filter not found")
+	}
+	tenantID, _ := tenantFromContext(ctx)
+	if !filter.TenantID.IsZero() && filter.TenantID != tenantID {
+		return nil, errors.New("saved filter not found")
+	}
+	return filter, nil
+}
+
+func (s *MarketingServiceImpl) CreateAudienceSync(ctx context.Context, a *AudienceSync, userID primitive.ObjectID) error {
+	if err := s.validateAudienceSync(ctx, a); err != nil {
+		return err
+	}
+	a.CreatedBy = userID
+	a.LastRunAt = time.Time{}
+	a.EventsCursor = time.Now()
+	if err := s.AudienceRepo.Create(ctx, a); err != nil {
+		return err
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "marketing", a.Name, map[string]common_models.Change{
+		"audience_sync": {New: a},
+	})
+	return nil
+}
+
+func (s *MarketingServiceImpl) GetAudienceSync(ctx context.Context, id string) (*AudienceSync, error) {
+	return s.AudienceRepo.Get(ctx, id)
+}
+
+func (s *MarketingServiceImpl) ListAudienceSyncs(ctx context.Context) ([]AudienceSync, error) {
+	return s.AudienceRepo.List(ctx)
+}
+
+func (s *MarketingServiceImpl) UpdateAudienceSync(ctx context.Context, id string, in *AudienceSync) (*AudienceSync, error) {
+	existing, err := s.AudienceRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	old := *existing
+
+	existing.Name = in.Name
+	existing.SavedFilterID = in.SavedFilterID
+	existing.ModuleName = in.ModuleName
+	existing.EmailField = in.EmailField
+	existing.FirstNameField = in.FirstNameField
+	existing.LastNameField = in.LastNameField
+	existing.FieldMapping = in.FieldMapping
+	existing.UnsubscribeRemoved = in.UnsubscribeRemoved
+	existing.ActivityModule = in.ActivityModule
+	existing.IsActive = in.IsActive
+	// The list and connection are fixed; member state refers to them
+	if err := s.validateAudienceSync(ctx, existing); err != nil {
+		return nil, err
+	}
+	if err := s.AudienceRepo.Update(ctx, existing); err != nil {
+		return nil, err
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "marketing", existing.Name, map[string]common_models.Change{
+		"audience_sync": {Old: old, New: existing},
+	})
+	return existing, nil
+}
+
+func (s *MarketingServiceImpl) DeleteAudienceSync(ctx context.Context, id string) error {
+	a, err := s.AudienceRepo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.AudienceRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	_ = s.StateRepo.DeleteBySync(ctx, a.ID)
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "marketing", a.Name, map[string]common_models.Change{
+		"audience_sync": {Old: a, New: "DELETED"},
+	})
+	return nil
+}
+
+func (s *MarketingServiceImpl) ListRuns(ctx context.Context, id string, limit int64) ([]data_sync.SyncLog, error) {
+	a, err := s.AudienceRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	return s.LogRepo.List(ctx, a.ID.Hex(), limit)
+}
+
+func (s *MarketingServiceImpl) RunAudienceSync(ctx context.Context, id string) (*data_sync.SyncLog, error) {
+	a, err := s.AudienceRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.run(context.WithoutCancel(ctx), a)
+}
+
+func (s *MarketingServiceImpl) RunScheduled(ctx context.Context, id string) error {
+	a, err := s.AudienceRepo.GetForRun(ctx, id)
+	if err != nil {
+		return fmt.Errorf("audience sync %s not found", id)
+	}
+	if !a.IsActive {
+		return nil
+	}
+	ctx = context.WithValue(ctx, common_models.TenantIDKey, a.TenantID.Hex())
+	_, err = s.run(ctx, a)
+	return err
+}
+
+// audienceRun collects the per-member outcome of one run
+type audienceRun struct {
+	sync   *AudienceSync
+	conn   *Connection
+	impl   platform
+	log    *data_sync.SyncLog
+	states map[string]MemberState
+}
+
+func (r *audienceRun) fail(recordID, direction, operation string, err error) {
+	r.log.Failed++
+	if len(r.log.Errors) < maxLoggedErrors {
+		r.log.Errors = append(r.log.Errors, data_sync.SyncRecordError{
+			Module:    r.sync.ModuleName,
+			RecordID:  recordID,
+			Direction: direction,
+			Operation: operation,
+			Error:     err.Error(),
+		})
+	}
+}
+
+func (s *MarketingServiceImpl) run(ctx context.Context, a *AudienceSync) (*data_sync.SyncLog, error) {
+	if _, busy := s.running.LoadOrStore(a.ID, struct{}{}); busy {
+		return nil, ErrSyncRunning
+	}
+	defer s.running.Delete(a.ID)
+
+	conn, err := s.ConnectionRepo.Get(ctx, a.ConnectionID.Hex())
+	if err != nil {
+		return nil, errors.New("connection not found")
+	}
+	impl, ok := s.platforms[conn.Provider]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	startedAt := time.Now()
+	runLog := &data_sync.SyncLog{
+		SyncSettingID: a.ID,
+		StartTime:     startedAt,
+		Status:        "in_progress",
+	}
+	_ = s.LogRepo.Create(ctx, runLog)
+
+	var runError error
+	defer func() {
+		runLog.EndTime = time.Now()
+		runLog.ProcessedCount = runLog.Pushed + runLog.Deleted + runLog.Pulled
+		switch {
+		case runError != nil:
+			runLog.Status = "failed"
+			runLog.Error = runError.Error()
+		case runLog.Failed > 0:
+			runLog.Status = "partial"
+		default:
+			runLog.Status = "success"
+		}
+		_ = s.LogRepo.Update(ctx, runLog)
+
+		a.LastRunAt = startedAt
+		_ = s.AudienceRepo.Update(ctx, a)
+
+		_ = s.AuditService.LogChange(ctx, common_models.AuditActionSync, "marketing", a.Name, map[string]common_models.Change{
+			"status":       {New: runLog.Status},
+			"upserted":     {New: runLog.Pushed},
+			"unsubscribed": {New: runLog.Deleted},
+			"events":       {New: runLog.Pulled},
+			"failed":       {New: runLog.Failed},
+			"error":        {New: runLog.Error},
+		})
+	}()
+
+	states, err := s.StateRepo.ListBySync(ctx, a.ID)
+	if err != nil {
+		runError = err
+		return runLog, runError
+	}
+	run := &audienceRun{sync: a, conn: conn, impl: impl, log: runLog, states: states}
+
+	if runError = s.pushSegment(ctx, run); runError != nil {
+		return runLog, runError
+	}
+	if a.ActivityModule != "" {
+		if runError = s.pullEvents(ctx, run); runError != nil {
+			return runLog, runError
+		}
+	}
+	return runLog, nil
+}
+
+// pushSegment upserts members whose record matches the saved filter and,
+// when enabled, unsubscribes members whose record no longer does
+func (s *MarketingServiceImpl) pushSegment(ctx context.Context, run *audienceRun) error {
+	filter, err := s.loadFilter(ctx, run.sync.SavedFilterID)
+	if err != nil {
+		return err
+	}
+	query := s.SavedFilterService.BuildQueryFromCriteria(filter.Criteria)
+
+	var upserts []*Member
+	var upsertStates []MemberState
+	var unsubscribes []*Member
+	var unsubscribeStates []MemberState
+	inSegment := map[string]bool{}
+
+	for page := int64(0); ; page++ {
+		records, err := s.RecordRepo.List(ctx, run.sync.ModuleName, query, nil, recordPageSize, page*recordPageSize, "created_at", 1)
+		if err != nil {
+			return fmt.Errorf("failed to fetch records on page %d: %v", page+1, err)
+		}
+		for _, rec := range records {
+			id := fieldString(rec["id"])
+			member, ok := buildMember(rec, run.sync)
+			if !ok {
+				continue // no usable email
+			}
+			inSegment[id] = true
+
+			state, known := run.states[id]
+			hash := memberHash(member)
+			if known && state.Email != member.Email && state.Status == MemberSubscribed {
+				// The address changed: the old one leaves the list
+				old := &Member{Email: state.Email, ExternalID: state.ExternalID}
+				unsubscribes = append(unsubscribes, old)
+				unsubscribeStates = append(unsubscribeStates, state)
+			}
+			if known && state.Email == member.Email && state.Hash == hash && state.Status == MemberSubscribed {
+				run.log.Skipped++
+				continue
+			}
+			upserts = append(upserts, member)
+			upsertStates = append(upsertStates, MemberState{
+				ID:             state.ID,
+				AudienceSyncID: run.sync.ID,
+				RecordID:       id,
+				Email:          member.Email,
+				ExternalID:     state.ExternalID,
+				Hash:           hash,
+				Status:         MemberSubscribed,
+			})
+		}
+		if len(records) < int(recordPageSize) {
+			break
+		}
+	}
+
+	if run.sync.UnsubscribeRemoved {
+		for id, state := range run.states {
+			if !inSegment[id] && state.Status == MemberSubscribed {
+				unsubscribes = append(unsubscribes, &Member{Email: state.Email, ExternalID: state.ExternalID})
+				unsubscribeStates = append(unsubscribeStates, state)
+			}
+		}
+	}
+
+	if len(upserts) > 0 {
+		errs, err := run.impl.Upsert(ctx, run.conn, run.sync.ListID, upserts)
+		if err != nil {
+			return err
+		}
+		for i, member := range upserts {
+			state := upsertStates[i]
+			if errs[i] != nil {
+				run.fail(state.RecordID, data_sync.DirectionPush, "upsert", fmt.Errorf("%s: %v", member.Email, errs[i]))
+				continue
+			}
+			if member.ExternalID != "" {
+				state.ExternalID = member.ExternalID
+			}
+			if err := s.StateRepo.Save(ctx, &state); err != nil {
+				run.fail(state.RecordID, data_sync.DirectionPush, "upsert", err)
+				continue
+			}
+			run.states[state.RecordID] = state
+			run.log.Pushed++
+		}
+	}
+
+	if len(unsubscribes) > 0 {
+		errs, err := run.impl.Unsubscribe(ctx, run.conn, run.sync.ListID, unsubscribes)
+		if err != nil {
+			return err
+		}
+		for i, member := range unsubscribes {
+			state := unsubscribeStates[i]
+			if errs[i] != nil {
+				run.fail(state.RecordID, data_sync.DirectionPush, "unsubscribe", fmt.Errorf("%s: %v", member.Email, errs[i]))
+				continue
+			}
+			if inSegment[state.RecordID] {
+				// Old address of a record that is still a member; its new
+				// state was saved with the upsert
+				continue
+			}
+			state.Status = MemberUnsubscribed
+			if err := s.StateRepo.Save(ctx, &state); err != nil {
+				run.fail(state.RecordID, data_sync.DirectionPush, "unsubscribe", err)
+				continue
+			}
+			run.states[state.RecordID] = state
+			run.log.Deleted++
+		}
+	}
+	return nil
+}
+
+// pullEvents records engagement on list emails as activities linked to the
+// member's record
+func (s *MarketingServiceImpl) pullEvents(ctx context.Context, run *audienceRun) error {
+	events, err := run.impl.Events(ctx, run.conn, run.sync.ListID, run.sync.EventsCursor)
+	if err != nil {
+		return fmt.Errorf("failed to fetch engagement events: %w", err)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].OccurredAt.Before(events[j].OccurredAt) })
+
+	byEmail := map[string]MemberState{}
+	for _, state := range run.states {
+		byEmail[state.Email] = state
+	}
+
+	product := common_models.ProductCRM
+	if mod, err := s.ModuleRepo.FindByName(ctx, run.sync.ActivityModule); err == nil && mod != nil {
+		product = mod.Product
+	}
+
+	for _, e := range events {
+		state, ok := byEmail[e.Email]
+		if !ok {
+			continue
+		}
+		data := map[string]any{
+			"subject":           activitySubject(e),
+			"activity_type":     e.Type,
+			"email":             e.Email,
+			"campaign":          e.Campaign,
+			"occurred_at":       e.OccurredAt,
+			"source":            string(run.conn.Provider),
+			"related_module":    run.sync.ModuleName,
+			"related_record_id": state.RecordID,
+		}
+		if e.URL != "" {
+			data["url"] = e.URL
+		}
+		if _, err := s.RecordRepo.Create(ctx, run.sync.ActivityModule, product, data); err != nil {
+			// Stop at the first failure so the cursor does not skip past it
+			run.fail(state.RecordID, data_sync.DirectionPull, "create", err)
+			return nil
+		}
+		run.sync.EventsCursor = e.OccurredAt
+		run.log.Pulled++
+	}
+	return nil
+}
+
+func activitySubject(e EngagementEvent) string {
+	verb := map[string]string{
+		EventOpen:        "Opened email",
+		EventClick:       "Clicked link in email",
+		EventBounce:      "Email bounced",
+		EventSpamReport:  "Marked email as spam",
+		EventUnsubscribe: "Unsubscribed",
+	}[e.Type]
+	if e.Campaign == "" {
+		return verb
+	}
+	return verb + ": " + e.Campaign
+}
+
+func buildMember(rec map[string]any, a *AudienceSync) (*Member, bool) {
+	email := strings.ToLower(strings.TrimSpace(fieldString(rec[a.EmailField])))
+	if _, err := mail.ParseAddress(email); err != nil || email == "" {
+		return nil, false
+	}
+	member := &Member{
+		Email:     email,
+		FirstName: fieldString(rec[a.FirstNameField]),
+		LastName:  fieldString(rec[a.LastNameField]),
+		Fields:    map[string]any{},
+	}
+	for target, field := range a.FieldMapping {
+		if v, ok := rec[field]; ok && v != nil {
+			member.Fields[target] = v
+		}
+	}
+	return member, true
+}
+
+func memberHash(m *Member) string {
+	b, _ := json.Marshal(m)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func fieldString(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case primitive.ObjectID:
+		return val.Hex()
+	}
+	return fmt.Sprint(v)
+}
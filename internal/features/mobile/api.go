@@ -0,0 +1,32 @@
+package mobile
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type MobileApi struct {
+	controller *MobileController
+	config     *config.Config
+}
+
+func NewMobileApi(controller *MobileController, config *config.Config) *MobileApi {
+	return &MobileApi{
+		controller: controller,
+		config:     config,
+	}
+}
+
+// Setup registers the mobile sync routes. Record access is checked per
+// module by the record service, so no module permission is required here.
+func (h *MobileApi) Setup(app *fiber.App) {
+	mobile := app.Group("/api/mobile", middleware.AuthMiddleware(h.config.SkipAuth))
+
+	mobile.Post("/devices", h.controller.RegisterDevice)
+	mobile.Get("/devices", h.controller.ListDevices)
+	mobile.Delete("/devices/:id", h.controller.RevokeDevice)
+	mobile.Post("/sync/pull", h.controller.Pull)
+	mobile.Post("/sync/push", h.controller.Push)
+}
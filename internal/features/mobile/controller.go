@@ -0,0 +1,167 @@
+package mobile
+
+import (
+	"errors"
+
+	"go-crm/internal/common/session"
+	"go-crm/internal/common/validation"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/record"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MobileController struct {
+	Service MobileSyncService
+}
+
+func NewMobileController(service MobileSyncService) *MobileController {
+	return &MobileController{Service: service}
+}
+
+func currentUser(c *fiber.Ctx) (primitive.ObjectID, error) {
+	userID, ok := session.UserID(c.UserContext())
+	if !ok {
+		return primitive.NilObjectID, fiber.NewError(fiber.StatusUnauthorized, "User ID not found")
+	}
+	return userID, nil
+}
+
+func serviceError(c *fiber.Ctx, err error) error {
+	var fiberErr *fiber.Error
+	switch {
+	case errors.As(err, &fiberErr):
+		return c.Status(fiberErr.Code).JSON(fiber.Map{"error": fiberErr.Message})
+	case errors.Is(err, ErrDeviceRequired), errors.Is(err, ErrNoModules), errors.Is(err, ErrTooManyModules), errors.Is(err, ErrTooManyItems):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrDeviceRevoked), errors.Is(err, module.ErrAccessDenied), errors.Is(err, record.ErrAccessDenied):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrDeviceNotFound), errors.Is(err, mongo.ErrNoDocuments):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Device not found"})
+	}
+	if verrs, ok := validation.As(err); ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error(), "errors": verrs})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+}
+
+// RegisterDevice godoc
+// @Summary Register a mobile device
+// @Description Register the app installation before it syncs. Registering a known device updates its details; a revoked one is allowed to sync again.
+// @Tags mobile
+// @Accept json
+// @Produce json
+// @Param request body DeviceRequest true "Device"
+// @Success 200 {object} Device
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/mobile/devices [post]
+func (ctrl *MobileController) RegisterDevice(c *fiber.Ctx) error {
+	userID, err := currentUser(c)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	var req DeviceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	device, err := ctrl.Service.RegisterDevice(c.UserContext(), req, userID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	return c.JSON(device)
+}
+
+// ListDevices godoc
+// @Summary List my mobile devices
+// @Description The current user's devices and their sync state per module
+// @Tags mobile
+// @Produce json
+// @Success 200 {array} Device
+// @Router /api/mobile/devices [get]
+func (ctrl *MobileController) ListDevices(c *fiber.Ctx) error {
+	userID, err := currentUser(c)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	devices, err := ctrl.Service.ListDevices(c.UserContext(), userID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	return c.JSON(devices)
+}
+
+// RevokeDevice godoc
+// @Summary Revoke a mobile device
+// @Description Stop a lost or retired device from syncing
+// @Tags mobile
+// @Param id path string true "Device ID"
+// @Success 204
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/mobile/devices/{id} [delete]
+func (ctrl *MobileController) RevokeDevice(c *fiber.Ctx) error {
+	userID, err := currentUser(c)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	if err := ctrl.Service.RevokeDevice(c.UserContext(), c.Params("id"), userID); err != nil {
+		return serviceError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Pull godoc
+// @Summary Pull changed records
+// @Description Fetch a page of changes for each module since its cursor. File and image fields are returned as references to download separately. Pass each module's cursor as since on the next pull, once its changes are stored.
+// @Tags mobile
+// @Accept json
+// @Produce json
+// @Param request body PullRequest true "Device and module cursors"
+// @Success 200 {object} PullResponse
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/mobile/sync/pull [post]
+func (ctrl *MobileController) Pull(c *fiber.Ctx) error {
+	userID, err := currentUser(c)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	var req PullRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	resp, err := ctrl.Service.Pull(c.UserContext(), req, userID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	return c.JSON(resp)
+}
+
+// Push godoc
+// @Summary Push offline edits
+// @Description Apply queued creates, updates and deletes in order. Each item is accepted, rejected, or reported as a conflict with the server's current values; resending an accepted client_id returns its first result.
+// @Tags mobile
+// @Accept json
+// @Produce json
+// @Param request body PushRequest true "Device and queued edits"
+// @Success 200 {object} PushResponse
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/mobile/sync/push [post]
+func (ctrl *MobileController) Push(c *fiber.Ctx) error {
+	userID, err := currentUser(c)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	var req PushRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	resp, err := ctrl.Service.Push(c.UserContext(), req, userID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	return c.JSON(resp)
+}
@@ -0,0 +1,152 @@
+package mobile
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Push operations
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+// Push item outcomes
+const (
+	StatusAccepted = "accepted"
+	StatusConflict = "conflict"
+	StatusRejected = "rejected"
+)
+
+// LocalFilePrefix marks a file field value that names a file still on the
+// device. The field is left out of the push until the file is uploaded.
+const LocalFilePrefix = "local:"
+
+// Device is one installation of the mobile app and how far it has synced
+type Device struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID   primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	UserID     primitive.ObjectID `json:"user_id" bson:"user_id"`
+	DeviceID   string             `json:"device_id" bson:"device_id"` // Chosen by the app at install
+	Name       string             `json:"name,omitempty" bson:"name,omitempty"`
+	Platform   string             `json:"platform,omitempty" bson:"platform,omitempty"` // ios, android
+	AppVersion string             `json:"app_version,omitempty" bson:"app_version,omitempty"`
+	// Modules holds the sync state per module name
+	Modules    map[string]ModuleState `json:"modules" bson:"modules"`
+	LastPullAt *time.Time             `json:"last_pull_at,omitempty" bson:"last_pull_at,omitempty"`
+	LastPushAt *time.Time             `json:"last_push_at,omitempty" bson:"last_push_at,omitempty"`
+	Pushed     int64                  `json:"pushed" bson:"pushed"`
+	Conflicts  int64                  `json:"conflicts" bson:"conflicts"`
+	Revoked    bool                   `json:"revoked" bson:"revoked"`
+	CreatedAt  time.Time              `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time              `json:"updated_at" bson:"updated_at"`
+}
+
+// ModuleState tracks a device's position in a module's changes feed.
+// Checkpoint is the cursor the device last pulled from, which it only sends
+// once the page before it is stored; Served is the newest cursor handed out.
+type ModuleState struct {
+	Checkpoint string    `json:"checkpoint" bson:"checkpoint"`
+	Served     string    `json:"served" bson:"served"`
+	PulledAt   time.Time `json:"pulled_at" bson:"pulled_at"`
+}
+
+type DeviceRequest struct {
+	DeviceID   string `json:"device_id"`
+	Name       string `json:"name"`
+	Platform   string `json:"platform"`
+	AppVersion string `json:"app_version"`
+}
+
+type PullModule struct {
+	Module string `json:"module"`
+	Since  string `json:"since"` // Cursor from the last pull; empty for a full sync
+	// Fields limits the record data returned; empty returns every readable field
+	Fields []string `json:"fields,omitempty"`
+}
+
+type PullRequest struct {
+	DeviceID string       `json:"device_id"`
+	Modules  []PullModule `json:"modules"`
+	Limit    int64        `json:"limit"` // Changes per module
+}
+
+// FileRef is a file a record points at; the app downloads it when needed
+type FileRef struct {
+	Field  string `json:"field"`
+	FileID string `json:"file_id"`
+}
+
+type PulledChange struct {
+	ID    string         `json:"id"`
+	Op    string         `json:"op"`
+	At    time.Time      `json:"at"`
+	Data  map[string]any `json:"data,omitempty"`
+	Files []FileRef      `json:"files,omitempty"`
+}
+
+type PulledModule struct {
+	Module  string         `json:"module"`
+	Changes []PulledChange `json:"changes"`
+	Cursor  string         `json:"cursor"`
+	HasMore bool           `json:"has_more"`
+	Error   string         `json:"error,omitempty"`
+}
+
+type PullResponse struct {
+	ServerTime time.Time      `json:"server_time"`
+	Modules    []PulledModule `json:"modules"`
+}
+
+// PushItem is one offline edit. RecordID of a create may be a temporary ID
+// the app made up; later items of the batch may use it in RecordID or as a
+// field value and get the server ID instead.
+type PushItem struct {
+	ClientID string         `json:"client_id"` // Unique per device; replays return the first result
+	Module   string         `json:"module"`
+	Op       string         `json:"op"`
+	RecordID string         `json:"record_id"`
+	Data     map[string]any `json:"data,omitempty"`
+	// Base holds the values the app last saw for the fields it changed. A
+	// field another user changed since is a conflict; without Base any
+	// server change after BaseUpdatedAt is.
+	Base          map[string]any `json:"base,omitempty"`
+	BaseUpdatedAt *time.Time     `json:"base_updated_at,omitempty"`
+	// Force applies the edit over a conflict
+	Force bool `json:"force,omitempty"`
+}
+
+type PushRequest struct {
+	DeviceID string     `json:"device_id"`
+	Items    []PushItem `json:"items"`
+}
+
+type PushResult struct {
+	ClientID string `json:"client_id" bson:"client_id"`
+	Status   string `json:"status" bson:"status"`
+	RecordID string `json:"record_id,omitempty" bson:"record_id,omitempty"`
+	// Conflicts names the fields changed on the server, whose current values are in Server
+	Conflicts []string       `json:"conflicts,omitempty" bson:"conflicts,omitempty"`
+	Server    map[string]any `json:"server,omitempty" bson:"server,omitempty"`
+	// Deferred names file fields left out until their files are uploaded
+	Deferred []string `json:"deferred,omitempty" bson:"deferred,omitempty"`
+	Error    string   `json:"error,omitempty" bson:"error,omitempty"`
+	Replayed bool     `json:"replayed,omitempty" bson:"replayed,omitempty"`
+}
+
+type PushResponse struct {
+	Results []PushResult `json:"results"`
+}
+
+// pushLog remembers the result of a push item so a retried batch is not applied twice
+type pushLog struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	TenantID  primitive.ObjectID `bson:"tenant_id"`
+	DeviceID  string             `bson:"device_id"`
+	ClientID  string             `bson:"client_id"`
+	TempID    string             `bson:"temp_id,omitempty"`
+	Result    PushResult         `bson:"result"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
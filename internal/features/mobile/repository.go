@@ -0,0 +1,219 @@
+package mobile
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// pushLogRetention is how long a push result is kept for replays
+const pushLogRetention = 30 * 24 * time.Hour
+
+type DeviceRepository interface {
+	// Register creates the user's device or updates its details
+	Register(ctx context.Context, device *Device) (*Device, error)
+	Get(ctx context.Context, userID primitive.ObjectID, deviceID string) (*Device, error)
+	List(ctx context.Context, userID primitive.ObjectID) ([]Device, error)
+	SetRevoked(ctx context.Context, userID primitive.ObjectID, deviceID string, revoked bool) error
+	RecordPull(ctx context.Context, id primitive.ObjectID, states map[string]ModuleState, at time.Time) error
+	RecordPush(ctx context.Context, id primitive.ObjectID, pushed, conflicts int64, at time.Time) error
+
+	// FindPush returns the stored result of an item the device pushed before
+	FindPush(ctx context.Context, deviceID, clientID string) (*pushLog, error)
+	SavePush(ctx context.Context, entry *pushLog) error
+
+	EnsureIndexes(ctx context.Context) error
+}
+
+type DeviceRepositoryImpl struct {
+	devices *mongo.Collection
+	pushes  *mongo.Collection
+}
+
+func NewDeviceRepository(db *database.MongodbDB) DeviceRepository {
+	return &DeviceRepositoryImpl{
+		devices: db.DB.Collection("mobile_devices"),
+		pushes:  db.DB.Collection("mobile_push_log"),
+	}
+}
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	tenantID, err := primitive.ObjectIDFromHex(tenantIDStr)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("invalid tenant ID: %v", err)
+	}
+	return tenantID, nil
+}
+
+func (r *DeviceRepositoryImpl) Register(ctx context.Context, device *Device) (*Device, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	filter := bson.M{"tenant_id": tenantID, "user_id": device.UserID, "device_id": device.DeviceID}
+	update := bson.M{
+		"$set": bson.M{
+			"name":        device.Name,
+			"platform":    device.Platform,
+			"app_version": device.AppVersion,
+			"updated_at":  now,
+		},
+		"$setOnInsert": bson.M{
+			"_id":        primitive.NewObjectID(),
+			"modules":    bson.M{},
+			"pushed":     0,
+			"conflicts":  0,
+			"revoked":    false,
+			"created_at": now,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var saved Device
+	if err := r.devices.FindOneAndUpdate(ctx, filter, update, opts).Decode(&saved); err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+func (r *DeviceRepositoryImpl) Get(ctx context.Context, userID primitive.ObjectID, deviceID string) (*Device, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var device Device
+	err = r.devices.FindOne(ctx, bson.M{"tenant_id": tenantID, "user_id": userID, "device_id": deviceID}).Decode(&device)
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+func (r *DeviceRepositoryImpl) List(ctx context.Context, userID primitive.ObjectID) ([]Device, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}})
+	cursor, err := r.devices.Find(ctx, bson.M{"tenant_id": tenantID, "user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	devices := []Device{}
+	if err := cursor.All(ctx, &devices); err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+func (r *DeviceRepositoryImpl) SetRevoked(ctx context.Context, userID primitive.ObjectID, deviceID string, revoked bool) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.devices.UpdateOne(ctx,
+		bson.M{"tenant_id": tenantID, "user_id": userID, "device_id": deviceID},
+		bson.M{"$set": bson.M{"revoked": revoked, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *DeviceRepositoryImpl) RecordPull(ctx context.Context, id primitive.ObjectID, states map[string]ModuleState, at time.Time) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	set := bson.M{"last_pull_at": at, "updated_at": at}
+	for module, state := range states {
+		set["modules."+module] = state
+	}
+	_, err = r.devices.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}, bson.M{"$set": set})
+	return err
+}
+
+func (r *DeviceRepositoryImpl) RecordPush(ctx context.Context, id primitive.ObjectID, pushed, conflicts int64, at time.Time) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.devices.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}, bson.M{
+		"$set": bson.M{"last_push_at": at, "updated_at": at},
+		"$inc": bson.M{"pushed": pushed, "conflicts": conflicts},
+	})
+	return err
+}
+
+func (r *DeviceRepositoryImpl) FindPush(ctx context.Context, deviceID, clientID string) (*pushLog, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry pushLog
+	err = r.pushes.FindOne(ctx, bson.M{"tenant_id": tenantID, "device_id": deviceID, "client_id": clientID}).Decode(&entry)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *DeviceRepositoryImpl) SavePush(ctx context.Context, entry *pushLog) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	entry.TenantID = tenantID
+	entry.ID = primitive.NewObjectID()
+	entry.CreatedAt = time.Now()
+
+	_, err = r.pushes.InsertOne(ctx, entry)
+	return err
+}
+
+func (r *DeviceRepositoryImpl) EnsureIndexes(ctx context.Context) error {
+	_, err := r.devices.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "user_id", Value: 1}, {Key: "device_id", Value: 1}},
+		Options: options.Index().SetName("idx_device").SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.pushes.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "device_id", Value: 1}, {Key: "client_id", Value: 1}},
+			Options: options.Index().SetName("idx_push_item").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetName("idx_push_ttl").SetExpireAfterSeconds(int32(pushLogRetention.Seconds())),
+		},
+	})
+	return err
+}
@@ -0,0 +1,494 @@
+package mobile
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/record"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	defaultPullLimit = 200
+	maxPullLimit     = 500
+	maxPullModules   = 20
+	maxPushItems     = 200
+)
+
+var (
+	ErrDeviceRequired = errors.New("device_id is required")
+	ErrDeviceNotFound = apperr.NotFound("device is not registered")
+	ErrDeviceRevoked  = errors.New("device has been revoked")
+	ErrNoModules      = errors.New("at least one module is required")
+	ErrTooManyModules = errors.New("too many modules in one pull")
+	ErrTooManyItems   = errors.New("too many items in one push")
+)
+
+// Keys of stored records the app has no use for
+var internalKeys = []string{"_id", "tenant_id", "entity", "deleted", "_approval"}
+
+type MobileSyncService interface {
+	RegisterDevice(ctx context.Context, req DeviceRequest, userID primitive.ObjectID) (*Device, error)
+	ListDevices(ctx context.Context, userID primitive.ObjectID) ([]Device, error)
+	// RevokeDevice stops the device from syncing until it is registered again
+	RevokeDevice(ctx context.Context, deviceID string, userID primitive.ObjectID) error
+	// Pull returns a page of each module's changes since the device's cursors
+	Pull(ctx context.Context, req PullRequest, userID primitive.ObjectID) (*PullResponse, error)
+	// Push applies offline edits in order and reports each one's outcome
+	Push(ctx context.Context, req PushRequest, userID primitive.ObjectID) (*PushResponse, error)
+}
+
+type MobileSyncServiceImpl struct {
+	Repo          DeviceRepository
+	RecordService record.RecordService
+	ModuleService module.ModuleService
+}
+
+func NewMobileSyncService(repo DeviceRepository, recordService record.RecordService, moduleService module.ModuleService) MobileSyncService {
+	return &MobileSyncServiceImpl{
+		Repo:          repo,
+		RecordService: recordService,
+		ModuleService: moduleService,
+	}
+}
+
+func (s *MobileSyncServiceImpl) RegisterDevice(ctx context.Context, req DeviceRequest, userID primitive.ObjectID) (*Device, error) {
+	deviceID := strings.TrimSpace(req.DeviceID)
+	if deviceID == "" {
+		return nil, ErrDeviceRequired
+	}
+	device, err := s.Repo.Register(ctx, &Device{
+		UserID:     userID,
+		DeviceID:   deviceID,
+		Name:       strings.TrimSpace(req.Name),
+		Platform:   strings.ToLower(strings.TrimSpace(req.Platform)),
+		AppVersion: strings.TrimSpace(req.AppVersion),
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Registering again is how a revoked device starts over
+	if device.Revoked {
+		if err := s.Repo.SetRevoked(ctx, userID, deviceID, false); err != nil {
+			return nil, err
+		}
+		device.Revoked = false
+	}
+	return device, nil
+}
+
+func (s *MobileSyncServiceImpl) ListDevices(ctx context.Context, userID primitive.ObjectID) ([]Device, error) {
+	return s.Repo.List(ctx, userID)
+}
+
+func (s *MobileSyncServiceImpl) RevokeDevice(ctx context.Context, deviceID string, userID primitive.ObjectID) error {
+	if deviceID == "" {
+		return ErrDeviceRequired
+	}
+	return s.Repo.SetRevoked(ctx, userID, deviceID, true)
+}
+
+// device returns the user's registered device if it may sync
+func (s *MobileSyncServiceImpl) device(ctx context.Context, deviceID string, userID primitive.ObjectID) (*Device, error) {
+	if deviceID == "" {
+		return nil, ErrDeviceRequired
+	}
+	device, err := s.Repo.Get(ctx, userID, deviceID)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrDeviceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if device.Revoked {
+		return nil, ErrDeviceRevoked
+	}
+	return device, nil
+}
+
+func (s *MobileSyncServiceImpl) Pull(ctx context.Context, req PullRequest, userID primitive.ObjectID) (*PullResponse, error) {
+	if len(req.Modules) == 0 {
+		return nil, ErrNoModules
+	}
+	if len(req.Modules) > maxPullModules {
+		return nil, ErrTooManyModules
+	}
+	device, err := s.device(ctx, req.DeviceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	limit := req.Limit
+	if limit < 1 {
+		limit = defaultPullLimit
+	}
+	limit = min(limit, maxPullLimit)
+
+	now := time.Now()
+	resp := &PullResponse{ServerTime: now.UTC(), Modules: []PulledModule{}}
+	states := map[string]ModuleState{}
+	for _, pm := range req.Modules {
+		pulled := PulledModule{Module: pm.Module, Changes: []PulledChange{}}
+		// One module failing, e.g. on access, does not hold back the others
+		mod, err := s.ModuleService.GetModuleByName(ctx, pm.Module, userID)
+		if err != nil {
+			pulled.Error = err.Error()
+			resp.Modules = append(resp.Modules, pulled)
+			continue
+		}
+		page, err := s.RecordService.ListChanges(ctx, pm.Module, pm.Since, limit, true, userID)
+		if err != nil {
+			pulled.Error = err.Error()
+			resp.Modules = append(resp.Modules, pulled)
+			continue
+		}
+
+		fileFields := fileFieldNames(mod)
+		for _, c := range page.Changes {
+			data, files := compactRecord(c.Data, pm.Fields, fileFields)
+			pulled.Changes = append(pulled.Changes, PulledChange{ID: c.ID, Op: c.Op, At: c.At, Data: data, Files: files})
+		}
+		pulled.Cursor = page.Cursor
+		pulled.HasMore = page.HasMore
+		resp.Modules = append(resp.Modules, pulled)
+		states[pm.Module] = ModuleState{Checkpoint: pm.Since, Served: page.Cursor, PulledAt: now}
+	}
+
+	if err := s.Repo.RecordPull(ctx, device.ID, states, now); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func fileFieldNames(mod *common_models.Entity) []string {
+	var names []string
+	for _, f := range mod.Fields {
+		if f.Type == common_models.FieldTypeFile || f.Type == common_models.FieldTypeImage {
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
+
+// compactRecord drops internal keys and empty values, keeps only fields when
+// given, and moves file fields out into references downloaded on demand
+func compactRecord(rec map[string]any, fields, fileFields []string) (map[string]any, []FileRef) {
+	if rec == nil {
+		return nil, nil
+	}
+	data := map[string]any{}
+	var files []FileRef
+	for k, v := range rec {
+		if slices.Contains(internalKeys, k) || isEmpty(v) {
+			continue
+		}
+		if len(fields) > 0 && !slices.Contains(fields, k) {
+			continue
+		}
+		if slices.Contains(fileFields, k) {
+			if id := fileID(v); id != "" {
+				files = append(files, FileRef{Field: k, FileID: id})
+			}
+			continue
+		}
+		data[k] = v
+	}
+	slices.SortFunc(files, func(a, b FileRef) int { return strings.Compare(a.Field, b.Field) })
+	return data, files
+}
+
+func isEmpty(v any) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case string:
+		return val == ""
+	case []any:
+		return len(val) == 0
+	case primitive.A:
+		return len(val) == 0
+	}
+	return false
+}
+
+func fileID(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case primitive.ObjectID:
+		return val.Hex()
+	case map[string]any:
+		return fileID(val["id"])
+	case primitive.M:
+		return fileID(val["id"])
+	}
+	return ""
+}
+
+func (s *MobileSyncServiceImpl) Push(ctx context.Context, req PushRequest, userID primitive.ObjectID) (*PushResponse, error) {
+	if len(req.Items) > maxPushItems {
+		return nil, ErrTooManyItems
+	}
+	device, err := s.device(ctx, req.DeviceID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &PushResponse{Results: make([]PushResult, 0, len(req.Items))}
+	// Temporary IDs of records created in this batch, or replayed from an earlier one
+	tempIDs := map[string]string{}
+	var pushed, conflicts int64
+	for _, item := range req.Items {
+		if item.ClientID != "" {
+			entry, err := s.Repo.FindPush(ctx, device.DeviceID, item.ClientID)
+			if err == nil {
+				if entry.TempID != "" {
+					tempIDs[entry.TempID] = entry.Result.RecordID
+				}
+				entry.Result.Replayed = true
+				resp.Results = append(resp.Results, entry.Result)
+				continue
+			}
+			if err != mongo.ErrNoDocuments {
+				return nil, err
+			}
+		}
+
+		result := s.apply(ctx, item, tempIDs, userID)
+		switch result.Status {
+		case StatusAccepted:
+			pushed++
+			tempID := ""
+			if item.Op == OpCreate && item.RecordID != "" {
+				tempID = item.RecordID
+				tempIDs[tempID] = result.RecordID
+			}
+			// Only applied edits are remembered; a conflict or rejection is
+			// decided again when the app retries it
+			if item.ClientID != "" {
+				entry := &pushLog{DeviceID: device.DeviceID, ClientID: item.ClientID, TempID: tempID, Result: result}
+				if err := s.Repo.SavePush(ctx, entry); err != nil && !mongo.IsDuplicateKeyError(err) {
+					return nil, err
+				}
+			}
+		case StatusConflict:
+			conflicts++
+		}
+		resp.Results = append(resp.Results, result)
+	}
+
+	if len(req.Items) > 0 {
+		if err := s.Repo.RecordPush(ctx, device.ID, pushed, conflicts, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// apply runs one push item against the record service
+func (s *MobileSyncServiceImpl) apply(ctx context.Context, item PushItem, tempIDs map[string]string, userID primitive.ObjectID) PushResult {
+	result := PushResult{ClientID: item.ClientID}
+	reject := func(err error) PushResult {
+		result.Status = StatusRejected
+		result.Error = err.Error()
+		return result
+	}
+	if item.Module == "" {
+		return reject(errors.New("module is required"))
+	}
+
+	data := map[string]any{}
+	for k, v := range item.Data {
+		if str, ok := v.(string); ok && strings.HasPrefix(str, LocalFilePrefix) {
+			result.Deferred = append(result.Deferred, k)
+			continue
+		}
+		data[k] = swapTempIDs(v, tempIDs)
+	}
+	slices.Sort(result.Deferred)
+
+	if item.Op == OpCreate {
+		created, err := s.RecordService.CreateRecord(ctx, item.Module, data, userID)
+		if err != nil {
+			return reject(err)
+		}
+		oid, ok := created.(primitive.ObjectID)
+		if !ok {
+			return reject(errors.New("record created without an id"))
+		}
+		result.Status = StatusAccepted
+		result.RecordID = oid.Hex()
+		return result
+	}
+	if item.Op != OpUpdate && item.Op != OpDelete {
+		return reject(errors.New("op must be create, update or delete"))
+	}
+
+	id := item.RecordID
+	if serverID, ok := tempIDs[id]; ok {
+		id = serverID
+	}
+	if id == "" {
+		return reject(errors.New("record_id is required"))
+	}
+	result.RecordID = id
+
+	current, err := s.RecordService.GetRecord(ctx, item.Module, id, userID)
+	if errors.Is(err, record.ErrRecordNotFound) {
+		if item.Op == OpDelete {
+			// Already gone is what the app asked for
+			result.Status = StatusAccepted
+			return result
+		}
+		result.Status = StatusConflict
+		result.Error = err.Error()
+		return result
+	}
+	if err != nil {
+		return reject(err)
+	}
+
+	if !item.Force {
+		if changed := conflictingFields(item, data, current); len(changed) > 0 {
+			result.Status = StatusConflict
+			result.Conflicts = changed
+			result.Server = map[string]any{"updated_at": current["updated_at"]}
+			for _, f := range changed {
+				if v, ok := current[f]; ok {
+					result.Server[f] = v
+				}
+			}
+			return result
+		}
+	}
+
+	if item.Op == OpDelete {
+		err = s.RecordService.DeleteRecord(ctx, item.Module, id, userID)
+	} else if len(data) > 0 {
+		err = s.RecordService.UpdateRecord(ctx, item.Module, id, data, userID)
+	}
+	if err != nil {
+		return reject(err)
+	}
+	result.Status = StatusAccepted
+	return result
+}
+
+// conflictingFields lists the fields changed on the server since the app
+// last saw the record. With Base, a field conflicts when its server value
+// moved away from the base and differs from the app's; otherwise every field
+// of the edit conflicts once the record changed after BaseUpdatedAt.
+func conflictingFields(item PushItem, data, current map[string]any) []string {
+	var changed []string
+	if len(item.Base) > 0 {
+		for f, base := range item.Base {
+			server := current[f]
+			if sameValue(server, base) {
+				continue
+			}
+			if v, ok := data[f]; ok && sameValue(server, v) {
+				// Both sides made the same change
+				continue
+			}
+			changed = append(changed, f)
+		}
+		slices.Sort(changed)
+		return changed
+	}
+
+	if item.BaseUpdatedAt == nil {
+		return nil
+	}
+	updatedAt, ok := timeValue(current["updated_at"])
+	if !ok || !updatedAt.After(*item.BaseUpdatedAt) {
+		return nil
+	}
+	for f, v := range data {
+		if !sameValue(current[f], v) {
+			changed = append(changed, f)
+		}
+	}
+	if item.Op == OpDelete || len(changed) == 0 {
+		// A delete, or an edit the server already matches, conflicts on the record as a whole
+		changed = []string{"updated_at"}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+// sameValue compares a stored value with one decoded from JSON. Populated
+// references compare by ID and numbers by value.
+func sameValue(a, b any) bool {
+	if isEmpty(a) && isEmpty(b) {
+		return true
+	}
+	ja, errA := json.Marshal(normalize(a))
+	jb, errB := json.Marshal(normalize(b))
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+func normalize(v any) any {
+	switch val := v.(type) {
+	case primitive.ObjectID:
+		return val.Hex()
+	case primitive.DateTime:
+		return val.Time().UTC()
+	case time.Time:
+		return val.UTC()
+	case map[string]any:
+		if id, ok := val["id"]; ok {
+			return normalize(id)
+		}
+		if id, ok := val["_id"]; ok {
+			return normalize(id)
+		}
+	case primitive.M:
+		return normalize(map[string]any(val))
+	case primitive.A:
+		return normalize([]any(val))
+	case []any:
+		out := make([]any, len(val))
+		for i, e := range val {
+			out[i] = normalize(e)
+		}
+		return out
+	}
+	return v
+}
+
+func timeValue(v any) (time.Time, bool) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, true
+	case primitive.DateTime:
+		return val.Time(), true
+	}
+	return time.Time{}, false
+}
+
+// swapTempIDs replaces temporary record IDs in a field value with the IDs
+// the server gave those records
+func swapTempIDs(v any, tempIDs map[string]string) any {
+	switch val := v.(type) {
+	case string:
+		if id, ok := tempIDs[val]; ok {
+			return id
+		}
+	case []any:
+		out := make([]any, len(val))
+		for i, e := range val {
+			out[i] = swapTempIDs(e, tempIDs)
+		}
+		return out
+	}
+	return v
+}
@@ -31,9 +31,21 @@ func (h *ModuleApi) Setup(app *fiber.App) {
 	// Module routes group with auth middleware
 	modules := app.Group("/api/modules", middleware.AuthMiddleware(h.config.SkipAuth))
 
-	modules.Post("/", h.moduleController.CreateModule)
+	// Schema changes need the modules admin scope; module admins only reach their own modules
+	manageAny := middleware.RequireAdminScope(h.roleService, role.AdminScopeModules, "")
+	manage := middleware.RequireAdminScope(h.roleService, role.AdminScopeModules, "name")
+
+	modules.Post("/", manageAny, h.moduleController.CreateModule)
 	modules.Get("/", h.moduleController.ListModules)
 	modules.Get("/:name", h.moduleController.GetModule)
-	modules.Put("/:name", h.moduleController.UpdateModule)
-	modules.Delete("/:name", h.moduleController.DeleteModule)
+	modules.Put("/:name", manage, h.moduleController.UpdateModule)
+	modules.Delete("/:name", manage, h.moduleController.DeleteModule)
+
+	// Label translations; changes require module update permission
+	modules.Get("/:name/translations", h.moduleController.GetTranslations)
+	modules.Put("/:name/translations/:lang", manage, h.moduleController.SetTranslation)
+	modules.Delete("/:name/translations/:lang", manage, h.moduleController.DeleteTranslation)
+
+	// Fields whose changes are kept in the audit log
+	modules.Put("/:name/history-tracking", manage, h.moduleController.SetHistoryTracking)
 }
@@ -2,10 +2,14 @@ package module
 
 import (
 	"context"
+	"errors"
+
+	common_api "go-crm/internal/common/api"
 	"go-crm/internal/common/models"
+	"go-crm/internal/common/session"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type ModuleController struct {
@@ -31,20 +35,13 @@ func NewModuleController(service ModuleService) *ModuleController {
 func (ctrl *ModuleController) CreateModule(c *fiber.Ctx) error {
 	var m models.Entity
 	if err := c.BodyParser(&m); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return common_api.InvalidBody(c, err)
 	}
 
-	var userID primitive.ObjectID
-	if idStr, ok := c.Locals("user_id").(string); ok && idStr != "" {
-		userID, _ = primitive.ObjectIDFromHex(idStr)
-	}
+	userID, _ := session.UserID(c.UserContext())
 
 	if err := ctrl.Service.CreateModule(c.UserContext(), &m, userID); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return common_api.Fail(c, failStatus(err), err)
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
@@ -59,14 +56,13 @@ func (ctrl *ModuleController) CreateModule(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param X-Rich-Product header string true "Product filter (e.g., crm, erp, analytics)"
+// @Param Accept-Language header string false "Language for labels; the user's profile locale takes precedence"
+// @Param translate query bool false "Set to false to return the default labels for editing"
 // @Success 200 {array} Module "List of modules"
 // @Failure 500 {object} map[string]string "Failed to fetch modules"
 // @Router /api/modules [get]
 func (ctrl *ModuleController) ListModules(c *fiber.Ctx) error {
-	var userID primitive.ObjectID
-	if idStr, ok := c.Locals("user_id").(string); ok && idStr != "" {
-		userID, _ = primitive.ObjectIDFromHex(idStr)
-	}
+	userID, _ := session.UserID(c.UserContext())
 
 	// Get product from header and add to context
 	ctx := c.UserContext()
@@ -82,6 +78,13 @@ func (ctrl *ModuleController) ListModules(c *fiber.Ctx) error {
 		})
 	}
 
+	if c.QueryBool("translate", true) {
+		langs := ctrl.Service.PreferredLanguages(ctx, userID, c.Get(fiber.HeaderAcceptLanguage))
+		for i := range modules {
+			Localize(&modules[i], langs)
+		}
+	}
+
 	return c.JSON(modules)
 }
 
@@ -92,16 +95,15 @@ func (ctrl *ModuleController) ListModules(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param name path string true "Module Name"
+// @Param Accept-Language header string false "Language for labels; the user's profile locale takes precedence"
+// @Param translate query bool false "Set to false to return the default labels for editing"
 // @Success 200 {object} Module "Module details"
 // @Failure 404 {object} map[string]string "Module not found"
 // @Router /api/modules/{name} [get]
 func (ctrl *ModuleController) GetModule(c *fiber.Ctx) error {
 	name := c.Params("name")
 
-	var userID primitive.ObjectID
-	if idStr, ok := c.Locals("user_id").(string); ok && idStr != "" {
-		userID, _ = primitive.ObjectIDFromHex(idStr)
-	}
+	userID, _ := session.UserID(c.UserContext())
 
 	m, err := ctrl.Service.GetModuleByName(c.UserContext(), name, userID)
 	if err != nil {
@@ -110,6 +112,10 @@ func (ctrl *ModuleController) GetModule(c *fiber.Ctx) error {
 		})
 	}
 
+	if c.QueryBool("translate", true) {
+		Localize(m, ctrl.Service.PreferredLanguages(c.UserContext(), userID, c.Get(fiber.HeaderAcceptLanguage)))
+	}
+
 	return c.JSON(m)
 }
 
@@ -130,21 +136,14 @@ func (ctrl *ModuleController) UpdateModule(c *fiber.Ctx) error {
 
 	var m models.Entity
 	if err := c.BodyParser(&m); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return common_api.InvalidBody(c, err)
 	}
 	m.Name = name // Ensure name matches path
 
-	var userID primitive.ObjectID
-	if idStr, ok := c.Locals("user_id").(string); ok && idStr != "" {
-		userID, _ = primitive.ObjectIDFromHex(idStr)
-	}
+	userID, _ := session.UserID(c.UserContext())
 
 	if err := ctrl.Service.UpdateModule(c.UserContext(), &m, userID); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return common_api.Fail(c, failStatus(err), err)
 	}
 	return c.JSON(fiber.Map{
 		"message": "Module updated successfully",
@@ -164,18 +163,127 @@ func (ctrl *ModuleController) UpdateModule(c *fiber.Ctx) error {
 func (ctrl *ModuleController) DeleteModule(c *fiber.Ctx) error {
 	name := c.Params("name")
 
-	var userID primitive.ObjectID
-	if idStr, ok := c.Locals("user_id").(string); ok && idStr != "" {
-		userID, _ = primitive.ObjectIDFromHex(idStr)
-	}
+	userID, _ := session.UserID(c.UserContext())
 
 	if err := ctrl.Service.DeleteModule(c.UserContext(), name, userID); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		return common_api.Fail(c, failStatus(err), err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Module deleted successfully",
+	})
+}
+
+// GetTranslations godoc
+// @Summary Get module translations
+// @Description List the translated module, field and option labels, keyed by language code
+// @Tags modules
+// @Produce json
+// @Param name path string true "Module Name"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string "Module not found"
+// @Router /api/modules/{name}/translations [get]
+func (ctrl *ModuleController) GetTranslations(c *fiber.Ctx) error {
+	translations, err := ctrl.Service.GetTranslations(c.UserContext(), c.Params("name"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Module not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{"data": translations})
+}
+
+// SetTranslation godoc
+// @Summary Set module translations for a language
+// @Description Replace one language's module, field and option labels; omitted labels fall back to the default
+// @Tags modules
+// @Accept json
+// @Produce json
+// @Param name path string true "Module Name"
+// @Param lang path string true "Language code, e.g. de or pt-BR"
+// @Param translation body ModuleTranslation true "Translated labels"
+// @Success 200 {object} map[string]string "Translations saved"
+// @Failure 400 {object} map[string]string "Invalid request body or unknown field"
+// @Router /api/modules/{name}/translations/{lang} [put]
+func (ctrl *ModuleController) SetTranslation(c *fiber.Ctx) error {
+	var t ModuleTranslation
+	if err := c.BodyParser(&t); err != nil {
+		return common_api.InvalidBody(c, err)
+	}
+
+	userID, _ := session.UserID(c.UserContext())
+
+	if err := ctrl.Service.SetTranslation(c.UserContext(), c.Params("name"), c.Params("lang"), t, userID); err != nil {
+		return common_api.Fail(c, failStatus(err), err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Translations saved successfully",
+	})
+}
+
+// DeleteTranslation godoc
+// @Summary Delete module translations for a language
+// @Description Remove every label of a language so the default labels are shown
+// @Tags modules
+// @Produce json
+// @Param name path string true "Module Name"
+// @Param lang path string true "Language code"
+// @Success 200 {object} map[string]string "Translations deleted"
+// @Failure 400 {object} map[string]string "Error"
+// @Router /api/modules/{name}/translations/{lang} [delete]
+func (ctrl *ModuleController) DeleteTranslation(c *fiber.Ctx) error {
+	userID, _ := session.UserID(c.UserContext())
+
+	if err := ctrl.Service.DeleteTranslation(c.UserContext(), c.Params("name"), c.Params("lang"), userID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
 	return c.JSON(fiber.Map{
-		"message": "Module deleted successfully",
+		"message": "Translations deleted successfully",
+	})
+}
+
+// SetHistoryTracking godoc
+// @Summary Set field history tracking
+// @Description Choose which fields of the module's records keep a change history: all (the default), selected (the listed fields) or none. Records still log that they changed, for the changes feed.
+// @Tags modules
+// @Accept json
+// @Produce json
+// @Param name path string true "Module Name"
+// @Param tracking body models.HistoryTracking true "Tracking mode and fields"
+// @Success 200 {object} map[string]string "History tracking saved"
+// @Failure 400 {object} map[string]string "Invalid request body"
+// @Failure 422 {object} map[string]interface{} "Invalid mode or unknown field"
+// @Router /api/modules/{name}/history-tracking [put]
+func (ctrl *ModuleController) SetHistoryTracking(c *fiber.Ctx) error {
+	var tracking models.HistoryTracking
+	if err := c.BodyParser(&tracking); err != nil {
+		return common_api.InvalidBody(c, err)
+	}
+
+	userID, _ := session.UserID(c.UserContext())
+
+	if err := ctrl.Service.SetHistoryTracking(c.UserContext(), c.Params("name"), tracking, userID); err != nil {
+		return common_api.Fail(c, failStatus(err), err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "History tracking saved successfully",
 	})
 }
+
+// failStatus is the status for a rejected module change. Validation errors
+// are sent as 422 by common_api.Fail regardless.
+func failStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrAccessDenied):
+		return fiber.StatusForbidden
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return fiber.StatusNotFound
+	}
+	return fiber.StatusBadRequest
+}
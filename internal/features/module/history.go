@@ -0,0 +1,70 @@
+package module
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/common/validation"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SetHistoryTracking replaces the field history configuration of a module.
+// It applies to changes made from now on; entries already written stay.
+func (s *ModuleServiceImpl) SetHistoryTracking(ctx context.Context, name string, tracking common_models.HistoryTracking, userID primitive.ObjectID) error {
+	m, err := s.Repo.FindByName(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := s.checkModuleUpdate(ctx, m, userID); err != nil {
+		return ErrAccessDenied
+	}
+
+	old := m.HistoryTracking
+	m.HistoryTracking = &tracking
+	var errs validation.Errors
+	validateHistoryTracking(m, &errs)
+	if err := errs.Err(); err != nil {
+		return err
+	}
+
+	m.UpdatedAt = time.Now()
+	if err := s.Repo.Update(ctx, m); err != nil {
+		return err
+	}
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionUpdate, "module", m.ID.Hex(), map[string]common_models.Change{
+		"history_tracking": {Old: old, New: m.HistoryTracking},
+	})
+	return nil
+}
+
+// validateHistoryTracking checks the mode and that selected fields exist
+func validateHistoryTracking(m *common_models.Entity, errs *validation.Errors) {
+	t := m.HistoryTracking
+	if t == nil {
+		return
+	}
+	switch t.Mode {
+	case common_models.HistoryTrackAll, common_models.HistoryTrackNone:
+		t.Fields = nil
+		return
+	case common_models.HistoryTrackSelected:
+	default:
+		errs.Add("history_tracking.mode", validation.CodeInvalid, "history_tracking.mode must be all, selected or none")
+		return
+	}
+
+	seen := make(map[string]bool, len(t.Fields))
+	for i, name := range t.Fields {
+		path := fmt.Sprintf("history_tracking.fields.%d", i)
+		switch {
+		case findField(m, name) == nil:
+			errs.Add(path, validation.CodeInvalid, fmt.Sprintf("unknown field '%s'", name))
+		case seen[name]:
+			errs.Add(path, validation.CodeDuplicate, fmt.Sprintf("duplicate field '%s'", name))
+		}
+		seen[name] = true
+	}
+}
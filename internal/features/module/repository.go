@@ -156,19 +156,7 @@ func (r *ModuleRepositoryImpl) FindUsingLookup(ctx context.Context, targetModule
 		return nil, err
 	}
 
-	// Find modules that have at least one field where field.lookup.module == targetModule
-	filter := bson.M{
-		"tenant_id":  oid,
-		"deleted_at": bson.M{"$exists": false},
-		"fields": bson.M{
-			"$elemMatch": bson.M{
-				"type":          "lookup",
-				"lookup.module": targetModule,
-			},
-		},
-	}
-
-	cursor, err := r.Collection.Find(ctx, filter)
+	cursor, err := r.Collection.Find(ctx, usingLookupFilter(oid, targetModule))
 	if err != nil {
 		return nil, err
 	}
@@ -181,6 +169,21 @@ func (r *ModuleRepositoryImpl) FindUsingLookup(ctx context.Context, targetModule
 	return modules, nil
 }
 
+// usingLookupFilter matches the tenant's modules with at least one lookup
+// field whose lookup_module is targetModule
+func usingLookupFilter(tenantID primitive.ObjectID, targetModule string) bson.M {
+	return bson.M{
+		"tenant_id":  tenantID,
+		"deleted_at": bson.M{"$exists": false},
+		"fields": bson.M{
+			"$elemMatch": bson.M{
+				"type":                 "lookup",
+				"lookup.lookup_module": targetModule,
+			},
+		},
+	}
+}
+
 func (r *ModuleRepositoryImpl) EnsureIndexes(ctx context.Context) error {
 	indexes := []mongo.IndexModel{
 		{
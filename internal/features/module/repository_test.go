@@ -0,0 +1,68 @@
+package module
+
+import (
+	"strings"
+	"testing"
+
+	"go-crm/internal/common/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fieldValue resolves a dotted path within a decoded document
+func fieldValue(doc bson.M, path string) (any, bool) {
+	var value any = doc
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(bson.M)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// matchesUsingLookup applies the filter's field conditions to a stored field
+func matchesUsingLookup(t *testing.T, field models.ModuleField, targetModule string) bool {
+	t.Helper()
+	raw, err := bson.Marshal(field)
+	if err != nil {
+		t.Fatalf("Failed to marshal field: %v", err)
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("Failed to unmarshal field: %v", err)
+	}
+
+	filter := usingLookupFilter(primitive.NewObjectID(), targetModule)
+	conditions := filter["fields"].(bson.M)["$elemMatch"].(bson.M)
+	for path, want := range conditions {
+		got, ok := fieldValue(doc, path)
+		if !ok || got != want {
+			return false
+		}
+	}
+	return true
+}
+
+func TestUsingLookupFilterMatchesStoredLookupFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		field models.ModuleField
+		want  bool
+	}{
+		{"lookup to the module", models.ModuleField{Name: "account", Type: models.FieldTypeLookup, Lookup: &models.LookupDef{LookupModule: "accounts"}}, true},
+		{"lookup to another module", models.ModuleField{Name: "owner", Type: models.FieldTypeLookup, Lookup: &models.LookupDef{LookupModule: "users"}}, false},
+		{"text field", models.ModuleField{Name: "accounts", Type: models.FieldTypeText}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesUsingLookup(t, tt.field, "accounts"); got != tt.want {
+				t.Errorf("Expected match %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
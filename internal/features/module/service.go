@@ -4,26 +4,38 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
 	common_models "go-crm/internal/common/models"
+	"go-crm/internal/common/validation"
 	"go-crm/internal/features/audit"
 	"go-crm/internal/features/role"
+	"go-crm/internal/features/user"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// ErrAccessDenied is returned when the user may not change or read a module
+var ErrAccessDenied = errors.New("access denied")
+
 type ModuleService interface {
 	CreateModule(ctx context.Context, module *common_models.Entity, userID primitive.ObjectID) error
 	GetModuleByName(ctx context.Context, name string, userID primitive.ObjectID) (*common_models.Entity, error)
 	ListModules(ctx context.Context, userID primitive.ObjectID) ([]common_models.Entity, error)
 	UpdateModule(ctx context.Context, module *common_models.Entity, userID primitive.ObjectID) error
 	DeleteModule(ctx context.Context, name string, userID primitive.ObjectID) error
+	PreferredLanguages(ctx context.Context, userID primitive.ObjectID, acceptLanguage string) []string
+	GetTranslations(ctx context.Context, name string) (ModuleTranslations, error)
+	SetTranslation(ctx context.Context, name, language string, t ModuleTranslation, userID primitive.ObjectID) error
+	DeleteTranslation(ctx context.Context, name, language string, userID primitive.ObjectID) error
+	SetHistoryTracking(ctx context.Context, name string, tracking common_models.HistoryTracking, userID primitive.ObjectID) error
 }
 
 type ModuleServiceImpl struct {
 	Repo            ModuleRepository
+	UserRepo        user.UserRepository
 	RoleService     role.RoleService
 	AuditService    audit.AuditService
 	ResourceService interface {
@@ -32,12 +44,13 @@ type ModuleServiceImpl struct {
 	}
 }
 
-func NewModuleService(repo ModuleRepository, roleService role.RoleService, auditService audit.AuditService, resourceService interface {
+func NewModuleService(repo ModuleRepository, userRepo user.UserRepository, roleService role.RoleService, auditService audit.AuditService, resourceService interface {
 	CreateResource(ctx context.Context, resource interface{}) error
 	DeleteResource(ctx context.Context, resourceID string, userID string) error
 }) ModuleService {
 	return &ModuleServiceImpl{
 		Repo:            repo,
+		UserRepo:        userRepo,
 		RoleService:     roleService,
 		AuditService:    auditService,
 		ResourceService: resourceService,
@@ -49,18 +62,20 @@ func (s *ModuleServiceImpl) CreateModule(ctx context.Context, m *common_models.E
 	if !userID.IsZero() {
 		allowed, err := s.RoleService.CheckPermission(ctx, userID, "modules", "create")
 		if err != nil || !allowed {
-			return errors.New("access denied")
+			return ErrAccessDenied
 		}
 	}
 
-	// Basic Validation
-	if m.Name == "" || m.Label == "" {
-		return errors.New("module name and label are required")
+	if err := validateSchema(m, true); err != nil {
+		return err
+	}
+	if err := s.validateRelatedLists(ctx, m); err != nil {
+		return err
 	}
 
 	// Check if already exists
 	if _, err := s.Repo.FindByName(ctx, m.Name); err == nil {
-		return errors.New("module with this name already exists")
+		return validation.New("name", validation.CodeDuplicate, "module with this name already exists")
 	}
 
 	m.ID = primitive.NewObjectID()
@@ -129,7 +144,7 @@ func (s *ModuleServiceImpl) GetModuleByName(ctx context.Context, name string, us
 			resourceID := fmt.Sprintf("%s.%s", m.Product, m.Name)
 			allowedSpecific, errSpec := s.RoleService.CheckPermission(ctx, userID, resourceID, "read")
 			if errSpec != nil || !allowedSpecific {
-				return nil, errors.New("access denied")
+				return nil, ErrAccessDenied
 			}
 		}
 
@@ -236,11 +251,18 @@ func (s *ModuleServiceImpl) UpdateModule(ctx context.Context, m *common_models.E
 			resourceID := fmt.Sprintf("%s.%s", existingModule.Product, existingModule.Name)
 			allowedSpecific, errSpec := s.RoleService.CheckPermission(ctx, userID, resourceID, "update")
 			if errSpec != nil || !allowedSpecific {
-				return errors.New("access denied")
+				return ErrAccessDenied
 			}
 		}
 	}
 
+	if err := validateSchema(m, false); err != nil {
+		return err
+	}
+	if err := s.validateRelatedLists(ctx, m); err != nil {
+		return err
+	}
+
 	// Identify removed fields
 	existingFieldsMap := make(map[string]common_models.ModuleField)
 	for _, f := range existingModule.Fields {
@@ -294,6 +316,13 @@ func (s *ModuleServiceImpl) UpdateModule(ctx context.Context, m *common_models.E
 	m.Slug = existingModule.Slug
 	m.Indexes = existingModule.Indexes
 	m.IsSystem = existingModule.IsSystem
+	if m.Actions == nil {
+		m.Actions = existingModule.Actions
+	}
+	if m.HistoryTracking == nil {
+		m.HistoryTracking = existingModule.HistoryTracking
+	}
+	keepTranslations(m, existingModule)
 	m.CreatedAt = existingModule.CreatedAt
 	m.UpdatedAt = time.Now()
 	// In real app, we might check if module exists first or validate schema changes
@@ -305,6 +334,110 @@ func (s *ModuleServiceImpl) UpdateModule(ctx context.Context, m *common_models.E
 	return err
 }
 
+var actionNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// validateSchema checks a submitted module definition and reports every
+// invalid field at once. Updates may leave the label out to keep the current one.
+func validateSchema(m *common_models.Entity, creating bool) error {
+	var errs validation.Errors
+	if m.Name == "" {
+		errs.Add("name", validation.CodeRequired, "module name is required")
+	}
+	if creating && m.Label == "" {
+		errs.Add("label", validation.CodeRequired, "module label is required")
+	}
+
+	seen := make(map[string]bool, len(m.Fields))
+	for i, f := range m.Fields {
+		path := fmt.Sprintf("fields.%d", i)
+		switch {
+		case f.Name == "":
+			errs.Add(path+".name", validation.CodeRequired, fmt.Sprintf("field %d needs a name", i+1))
+		case seen[f.Name]:
+			errs.Add(path+".name", validation.CodeDuplicate, fmt.Sprintf("duplicate field '%s'", f.Name))
+		}
+		seen[f.Name] = true
+		if f.Type == common_models.FieldTypeLookup && (f.Lookup == nil || f.Lookup.LookupModule == "") {
+			errs.Add(path+".lookup.lookup_module", validation.CodeRequired, fmt.Sprintf("lookup field '%s' needs lookup.lookup_module", f.Name))
+		}
+	}
+
+	validateActions(m.Actions, &errs)
+	validateHistoryTracking(m, &errs)
+	return errs.Err()
+}
+
+// validateRelatedLists checks each related list names a lookup of another
+// module to m
+func (s *ModuleServiceImpl) validateRelatedLists(ctx context.Context, m *common_models.Entity) error {
+	var errs validation.Errors
+	seen := make(map[string]bool, len(m.RelatedLists))
+	for i, rl := range m.RelatedLists {
+		path := fmt.Sprintf("related_lists.%d", i)
+		if !actionNamePattern.MatchString(rl.Name) {
+			errs.Add(path+".name", validation.CodeInvalid, fmt.Sprintf("invalid related list name '%s': use lowercase letters, digits and underscores", rl.Name))
+		} else if seen[rl.Name] {
+			errs.Add(path+".name", validation.CodeDuplicate, fmt.Sprintf("duplicate related list '%s'", rl.Name))
+		}
+		seen[rl.Name] = true
+		switch rl.SortOrder {
+		case "", "asc", "desc":
+		default:
+			errs.Add(path+".sort_order", validation.CodeInvalid, "sort_order must be asc or desc")
+		}
+
+		child, err := s.Repo.FindByName(ctx, rl.Module)
+		if err != nil || child == nil {
+			errs.Add(path+".module", validation.CodeInvalid, fmt.Sprintf("module '%s' not found", rl.Module))
+			continue
+		}
+		var lookup *common_models.ModuleField
+		for j := range child.Fields {
+			if child.Fields[j].Name == rl.Field {
+				lookup = &child.Fields[j]
+			}
+		}
+		if lookup == nil || lookup.Type != common_models.FieldTypeLookup || lookup.Lookup == nil || lookup.Lookup.LookupModule != m.Name {
+			errs.Add(path+".field", validation.CodeInvalid, fmt.Sprintf("'%s.%s' must be a lookup to %s", rl.Module, rl.Field, m.Name))
+		}
+	}
+	return errs.Err()
+}
+
+// validateActions checks custom action definitions. Automation rules are
+// resolved when the action runs.
+func validateActions(actions []common_models.CustomAction, errs *validation.Errors) {
+	seen := make(map[string]bool, len(actions))
+	for i, a := range actions {
+		path := fmt.Sprintf("actions.%d", i)
+		if !actionNamePattern.MatchString(a.Name) {
+			errs.Add(path+".name", validation.CodeInvalid, fmt.Sprintf("invalid action name '%s': use lowercase letters, digits and underscores", a.Name))
+		} else if seen[a.Name] {
+			errs.Add(path+".name", validation.CodeDuplicate, fmt.Sprintf("duplicate action '%s'", a.Name))
+		}
+		seen[a.Name] = true
+		if a.Label == "" {
+			errs.Add(path+".label", validation.CodeRequired, fmt.Sprintf("action '%s' needs a label", a.Name))
+		}
+		switch a.Target {
+		case common_models.CustomActionAutomation:
+			if _, err := primitive.ObjectIDFromHex(a.AutomationRuleID); err != nil {
+				errs.Add(path+".automation_rule_id", validation.CodeInvalid, fmt.Sprintf("action '%s' needs a valid automation_rule_id", a.Name))
+			}
+		case common_models.CustomActionScript:
+			if script, _ := a.Config["script"].(string); script == "" {
+				errs.Add(path+".config.script", validation.CodeRequired, fmt.Sprintf("action '%s' needs config.script", a.Name))
+			}
+		case common_models.CustomActionWebhook:
+			if url, _ := a.Config["url"].(string); !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+				errs.Add(path+".config.url", validation.CodeInvalid, fmt.Sprintf("action '%s' needs an http(s) config.url", a.Name))
+			}
+		default:
+			errs.Add(path+".target", validation.CodeNotAllowed, fmt.Sprintf("action '%s' has unknown target '%s'", a.Name, a.Target))
+		}
+	}
+}
+
 func (s *ModuleServiceImpl) DeleteModule(ctx context.Context, name string, userID primitive.ObjectID) error {
 	// 1. Check if System Module
 	m, err := s.Repo.FindByName(ctx, name)
@@ -319,7 +452,7 @@ func (s *ModuleServiceImpl) DeleteModule(ctx context.Context, name string, userI
 			resourceID := fmt.Sprintf("%s.%s", m.Product, m.Name)
 			allowedSpecific, errSpec := s.RoleService.CheckPermission(ctx, userID, resourceID, "delete")
 			if errSpec != nil || !allowedSpecific {
-				return errors.New("access denied")
+				return ErrAccessDenied
 			}
 		}
 	}
@@ -0,0 +1,218 @@
+package module
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/pkg/locale"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ModuleTranslation holds one language's labels of a module. Empty labels
+// remove the translation so the default label is shown again.
+type ModuleTranslation struct {
+	Label  string                      `json:"label"`
+	Fields map[string]FieldTranslation `json:"fields,omitempty"`
+}
+
+type FieldTranslation struct {
+	Label   string            `json:"label"`
+	Options map[string]string `json:"options,omitempty"` // Option value -> label
+}
+
+// ModuleTranslations is keyed by language code
+type ModuleTranslations map[string]ModuleTranslation
+
+// PreferredLanguages orders the languages labels are resolved in: the user's
+// profile locale first, then the Accept-Language header
+func (s *ModuleServiceImpl) PreferredLanguages(ctx context.Context, userID primitive.ObjectID, acceptLanguage string) []string {
+	var langs []string
+	if !userID.IsZero() && s.UserRepo != nil {
+		if u, err := s.UserRepo.FindByID(ctx, userID.Hex()); err == nil && u.Locale != nil && u.Locale.Locale != "" {
+			langs = append(langs, locale.NormalizeLanguage(u.Locale.Locale))
+		}
+	}
+	return append(langs, locale.ParseAcceptLanguage(acceptLanguage)...)
+}
+
+// Localize replaces module, field and option labels with their translations.
+// The translation maps are left in place for editors.
+func Localize(m *common_models.Entity, languages []string) {
+	if len(languages) == 0 {
+		return
+	}
+	m.Label = locale.Translate(m.Translations, m.Label, languages)
+	for i := range m.Fields {
+		f := &m.Fields[i]
+		f.Label = locale.Translate(f.Translations, f.Label, languages)
+		for j := range f.Options {
+			o := &f.Options[j]
+			o.Label = locale.Translate(o.Translations, o.Label, languages)
+		}
+	}
+}
+
+func (s *ModuleServiceImpl) GetTranslations(ctx context.Context, name string) (ModuleTranslations, error) {
+	m, err := s.Repo.FindByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	out := ModuleTranslations{}
+	entry := func(lang string) ModuleTranslation {
+		t, ok := out[lang]
+		if !ok {
+			t.Fields = map[string]FieldTranslation{}
+		}
+		return t
+	}
+	for lang, label := range m.Translations {
+		t := entry(lang)
+		t.Label = label
+		out[lang] = t
+	}
+	for _, f := range m.Fields {
+		for lang, label := range f.Translations {
+			t := entry(lang)
+			ft := t.Fields[f.Name]
+			ft.Label = label
+			t.Fields[f.Name] = ft
+			out[lang] = t
+		}
+		for _, o := range f.Options {
+			for lang, label := range o.Translations {
+				t := entry(lang)
+				ft := t.Fields[f.Name]
+				if ft.Options == nil {
+					ft.Options = map[string]string{}
+				}
+				ft.Options[o.Value] = label
+				t.Fields[f.Name] = ft
+				out[lang] = t
+			}
+		}
+	}
+	return out, nil
+}
+
+// SetTranslation replaces one language's labels on a module
+func (s *ModuleServiceImpl) SetTranslation(ctx context.Context, name, language string, t ModuleTranslation, userID primitive.ObjectID) error {
+	language = locale.NormalizeLanguage(language)
+	if language == "" {
+		return errors.New("language is required")
+	}
+
+	m, err := s.Repo.FindByName(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := s.checkModuleUpdate(ctx, m, userID); err != nil {
+		return err
+	}
+
+	for fieldName := range t.Fields {
+		if findField(m, fieldName) == nil {
+			return fmt.Errorf("unknown field '%s'", fieldName)
+		}
+	}
+
+	m.Translations = setTranslation(m.Translations, language, t.Label)
+	for i := range m.Fields {
+		f := &m.Fields[i]
+		ft := t.Fields[f.Name]
+		f.Translations = setTranslation(f.Translations, language, ft.Label)
+		for j := range f.Options {
+			o := &f.Options[j]
+			o.Translations = setTranslation(o.Translations, language, ft.Options[o.Value])
+		}
+	}
+	return s.saveTranslations(ctx, m, language)
+}
+
+// DeleteTranslation removes every label of a language from a module
+func (s *ModuleServiceImpl) DeleteTranslation(ctx context.Context, name, language string, userID primitive.ObjectID) error {
+	return s.SetTranslation(ctx, name, language, ModuleTranslation{}, userID)
+}
+
+func (s *ModuleServiceImpl) saveTranslations(ctx context.Context, m *common_models.Entity, language string) error {
+	m.UpdatedAt = time.Now()
+	err := s.Repo.Update(ctx, m)
+	if err == nil {
+		_ = s.AuditService.LogChange(ctx, common_models.AuditActionUpdate, "module", m.ID.Hex(), map[string]common_models.Change{
+			"translations": {New: language},
+		})
+	}
+	return err
+}
+
+func (s *ModuleServiceImpl) checkModuleUpdate(ctx context.Context, m *common_models.Entity, userID primitive.ObjectID) error {
+	if userID.IsZero() {
+		return nil
+	}
+	allowedGlobal, err := s.RoleService.CheckPermission(ctx, userID, "modules", "update")
+	if err != nil || !allowedGlobal {
+		resourceID := fmt.Sprintf("%s.%s", m.Product, m.Name)
+		allowedSpecific, errSpec := s.RoleService.CheckPermission(ctx, userID, resourceID, "update")
+		if errSpec != nil || !allowedSpecific {
+			return errors.New("access denied")
+		}
+	}
+	return nil
+}
+
+func setTranslation(translations map[string]string, language, label string) map[string]string {
+	if label == "" {
+		delete(translations, language)
+		if len(translations) == 0 {
+			return nil
+		}
+		return translations
+	}
+	if translations == nil {
+		translations = map[string]string{}
+	}
+	translations[language] = label
+	return translations
+}
+
+// keepTranslations carries translations over when a module is saved by a
+// client that does not send them
+func keepTranslations(m, existing *common_models.Entity) {
+	if m.Translations == nil {
+		m.Translations = existing.Translations
+	}
+	for i := range m.Fields {
+		f := &m.Fields[i]
+		old := findField(existing, f.Name)
+		if old == nil {
+			continue
+		}
+		if f.Translations == nil {
+			f.Translations = old.Translations
+		}
+		for j := range f.Options {
+			o := &f.Options[j]
+			if o.Translations != nil {
+				continue
+			}
+			for _, oldOpt := range old.Options {
+				if oldOpt.Value == o.Value {
+					o.Translations = oldOpt.Translations
+				}
+			}
+		}
+	}
+}
+
+func findField(m *common_models.Entity, name string) *common_models.ModuleField {
+	for i := range m.Fields {
+		if m.Fields[i].Name == name {
+			return &m.Fields[i]
+		}
+	}
+	return nil
+}
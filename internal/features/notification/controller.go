@@ -3,8 +3,9 @@ package notification
 import (
 	"strconv"
 
+	"go-crm/internal/common/session"
+
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type NotificationController struct {
@@ -30,9 +31,8 @@ func NewNotificationController(service NotificationService) *NotificationControl
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/notifications [get]
 func (c *NotificationController) List(ctx *fiber.Ctx) error {
-	userIDStr := ctx.Locals("user_id").(string)
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user ID"})
 	}
 
@@ -63,9 +63,8 @@ func (c *NotificationController) List(ctx *fiber.Ctx) error {
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/notifications/unread-count [get]
 func (c *NotificationController) GetUnreadCount(ctx *fiber.Ctx) error {
-	userIDStr := ctx.Locals("user_id").(string)
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user ID"})
 	}
 
@@ -89,9 +88,8 @@ func (c *NotificationController) GetUnreadCount(ctx *fiber.Ctx) error {
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/notifications/{id}/read [put]
 func (c *NotificationController) MarkAsRead(ctx *fiber.Ctx) error {
-	userIDStr := ctx.Locals("user_id").(string)
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user ID"})
 	}
 
@@ -114,9 +112,8 @@ func (c *NotificationController) MarkAsRead(ctx *fiber.Ctx) error {
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/notifications/read-all [put]
 func (c *NotificationController) MarkAllAsRead(ctx *fiber.Ctx) error {
-	userIDStr := ctx.Locals("user_id").(string)
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user ID"})
 	}
 
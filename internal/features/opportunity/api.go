@@ -0,0 +1,28 @@
+package opportunity
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type OpportunityApi struct {
+	controller *OpportunityController
+	config     *config.Config
+}
+
+func NewOpportunityApi(controller *OpportunityController, config *config.Config) *OpportunityApi {
+	return &OpportunityApi{
+		controller: controller,
+		config:     config,
+	}
+}
+
+func (h *OpportunityApi) Setup(app *fiber.App) {
+	group := app.Group("/api/opportunities", middleware.AuthMiddleware(h.config.SkipAuth))
+	group.Get("/:id/contact-roles", h.controller.GetContactRoles)
+	group.Put("/:id/contact-roles", h.controller.SetContactRoles)
+	group.Get("/:id/team", h.controller.GetTeam)
+	group.Put("/:id/team", h.controller.SetTeam)
+}
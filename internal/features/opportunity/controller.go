@@ -0,0 +1,128 @@
+package opportunity
+
+import (
+	common_api "go-crm/internal/common/api"
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type OpportunityController struct {
+	Service OpportunityService
+}
+
+func NewOpportunityController(service OpportunityService) *OpportunityController {
+	return &OpportunityController{Service: service}
+}
+
+// SetContactRolesRequest replaces the contacts involved in an opportunity
+type SetContactRolesRequest struct {
+	ContactRoles []ContactRole `json:"contact_roles"`
+}
+
+// SetTeamRequest replaces the deal team of an opportunity
+type SetTeamRequest struct {
+	Members []TeamMember `json:"members"`
+}
+
+// GetContactRoles godoc
+// @Summary List opportunity contact roles
+// @Description The contacts involved in an opportunity and the role each plays: decision_maker, influencer or champion. Names are given for the contacts the user may read.
+// @Tags opportunities
+// @Produce json
+// @Param id path string true "Opportunity record ID"
+// @Success 200 {array} ContactRole
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/opportunities/{id}/contact-roles [get]
+func (c *OpportunityController) GetContactRoles(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	roles, err := c.Service.GetContactRoles(ctx.UserContext(), ctx.Params("id"), userID)
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+	return ctx.JSON(roles)
+}
+
+// SetContactRoles godoc
+// @Summary Set opportunity contact roles
+// @Description Replace the contacts involved in an opportunity. Each contact is listed once, and at most one is primary.
+// @Tags opportunities
+// @Accept json
+// @Produce json
+// @Param id path string true "Opportunity record ID"
+// @Param roles body SetContactRolesRequest true "Contact roles"
+// @Success 200 {array} ContactRole
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{} "Unknown contact, role or duplicate"
+// @Router /api/opportunities/{id}/contact-roles [put]
+func (c *OpportunityController) SetContactRoles(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var req SetContactRolesRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return common_api.InvalidBody(ctx, err)
+	}
+	roles, err := c.Service.SetContactRoles(ctx.UserContext(), ctx.Params("id"), req.ContactRoles, userID)
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+	return ctx.JSON(roles)
+}
+
+// GetTeam godoc
+// @Summary Get opportunity deal team
+// @Description The users working an opportunity besides its owner, with their role (co_owner or member) and split percentage. owner_split is what the members' splits leave to the owner.
+// @Tags opportunities
+// @Produce json
+// @Param id path string true "Opportunity record ID"
+// @Success 200 {object} DealTeam
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/opportunities/{id}/team [get]
+func (c *OpportunityController) GetTeam(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	team, err := c.Service.GetTeam(ctx.UserContext(), ctx.Params("id"), userID)
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+	return ctx.JSON(team)
+}
+
+// SetTeam godoc
+// @Summary Set opportunity deal team
+// @Description Replace the deal team of an opportunity. Splits are between 0 and 100 and may not add up to more than 100. Team members can read the opportunity even when their role's conditions would not let them.
+// @Tags opportunities
+// @Accept json
+// @Produce json
+// @Param id path string true "Opportunity record ID"
+// @Param team body SetTeamRequest true "Deal team members"
+// @Success 200 {object} DealTeam
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{} "Unknown user, role or invalid split"
+// @Router /api/opportunities/{id}/team [put]
+func (c *OpportunityController) SetTeam(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var req SetTeamRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return common_api.InvalidBody(ctx, err)
+	}
+	team, err := c.Service.SetTeam(ctx.UserContext(), ctx.Params("id"), req.Members, userID)
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+	return ctx.JSON(team)
+}
@@ -0,0 +1,65 @@
+package opportunity
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Modules the opportunity endpoints read; see internal/migration/data/modules.json
+const (
+	ModuleName     = "opportunities"
+	ContactsModule = "contacts"
+)
+
+// Record fields holding the contact roles and the deal team of an
+// opportunity. Team members are matched by role.DealTeamMembersPath.
+const (
+	ContactRolesField = "contact_roles"
+	TeamField         = "deal_team"
+)
+
+// Roles a contact plays in an opportunity
+const (
+	ContactRoleDecisionMaker = "decision_maker"
+	ContactRoleInfluencer    = "influencer"
+	ContactRoleChampion      = "champion"
+)
+
+var contactRoles = map[string]bool{
+	ContactRoleDecisionMaker: true,
+	ContactRoleInfluencer:    true,
+	ContactRoleChampion:      true,
+}
+
+// Roles a user plays on a deal team
+const (
+	TeamRoleCoOwner = "co_owner"
+	TeamRoleMember  = "member"
+)
+
+var teamRoles = map[string]bool{
+	TeamRoleCoOwner: true,
+	TeamRoleMember:  true,
+}
+
+// ContactRole is a contact involved in an opportunity. At most one contact
+// is primary.
+type ContactRole struct {
+	ContactID   primitive.ObjectID `bson:"contact_id" json:"contact_id"`
+	Role        string             `bson:"role" json:"role"` // decision_maker, influencer or champion
+	Primary     bool               `bson:"primary,omitempty" json:"primary,omitempty"`
+	ContactName string             `bson:"-" json:"contact_name,omitempty"` // Filled on read
+}
+
+// TeamMember is a user working an opportunity besides its owner. Split is
+// the member's percentage of the deal's credit.
+type TeamMember struct {
+	UserID string  `bson:"user_id" json:"user_id"`
+	Role   string  `bson:"role" json:"role"` // co_owner or member
+	Split  float64 `bson:"split" json:"split"`
+	Name   string  `bson:"-" json:"name,omitempty"` // Filled on read
+}
+
+// DealTeam is the internal team of an opportunity. The owner keeps the
+// credit the members' splits leave.
+type DealTeam struct {
+	Members    []TeamMember `json:"members"`
+	OwnerSplit float64      `json:"owner_split"`
+}
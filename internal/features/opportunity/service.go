@@ -0,0 +1,361 @@
+package opportunity
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/common/validation"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/role"
+	"go-crm/internal/features/user"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrOpportunityNotFound = apperr.NotFound("opportunity not found")
+
+type OpportunityService interface {
+	// GetContactRoles returns the contacts involved in an opportunity
+	GetContactRoles(ctx context.Context, id string, userID primitive.ObjectID) ([]ContactRole, error)
+	// SetContactRoles replaces the contacts involved in an opportunity
+	SetContactRoles(ctx context.Context, id string, roles []ContactRole, userID primitive.ObjectID) ([]ContactRole, error)
+	// GetTeam returns the deal team of an opportunity
+	GetTeam(ctx context.Context, id string, userID primitive.ObjectID) (*DealTeam, error)
+	// SetTeam replaces the deal team of an opportunity
+	SetTeam(ctx context.Context, id string, members []TeamMember, userID primitive.ObjectID) (*DealTeam, error)
+}
+
+type OpportunityServiceImpl struct {
+	RecordService record.RecordService
+	RecordRepo    record.RecordRepository
+	RoleService   role.RoleService
+	UserRepo      user.UserRepository
+	AuditService  audit.AuditService
+}
+
+func NewOpportunityService(
+	recordService record.RecordService,
+	recordRepo record.RecordRepository,
+	roleService role.RoleService,
+	userRepo user.UserRepository,
+	auditService audit.AuditService,
+) OpportunityService {
+	return &OpportunityServiceImpl{
+		RecordService: recordService,
+		RecordRepo:    recordRepo,
+		RoleService:   roleService,
+		UserRepo:      userRepo,
+		AuditService:  auditService,
+	}
+}
+
+// GetContactRoles reads the opportunity with the user's access and names the
+// contacts the user may read
+func (s *OpportunityServiceImpl) GetContactRoles(ctx context.Context, id string, userID primitive.ObjectID) ([]ContactRole, error) {
+	opp, err := s.RecordService.GetRecord(ctx, ModuleName, id, userID)
+	if err != nil {
+		return nil, ErrOpportunityNotFound
+	}
+	var roles []ContactRole
+	if err := decodeList(opp[ContactRolesField], &roles); err != nil {
+		return nil, err
+	}
+	return s.nameContacts(ctx, roles, userID)
+}
+
+// SetContactRoles validates the roles against the tenant's contacts and
+// stores them on the opportunity, which the user must be able to update
+func (s *OpportunityServiceImpl) SetContactRoles(ctx context.Context, id string, roles []ContactRole, userID primitive.ObjectID) ([]ContactRole, error) {
+	if err := s.checkUpdate(ctx, id, ContactRolesField, userID); err != nil {
+		return nil, err
+	}
+	if roles == nil {
+		roles = []ContactRole{}
+	}
+
+	var errs validation.Errors
+	seen := make(map[primitive.ObjectID]bool, len(roles))
+	ids := make([]primitive.ObjectID, 0, len(roles))
+	primary := false
+	for i, r := range roles {
+		path := fmt.Sprintf("contact_roles.%d", i)
+		if !contactRoles[r.Role] {
+			errs.Add(path+".role", validation.CodeNotAllowed, fmt.Sprintf("role must be one of %s, %s or %s", ContactRoleDecisionMaker, ContactRoleInfluencer, ContactRoleChampion))
+		}
+		if r.Primary {
+			if primary {
+				errs.Add(path+".primary", validation.CodeDuplicate, "only one contact can be primary")
+			}
+			primary = true
+		}
+		switch {
+		case r.ContactID.IsZero():
+			errs.Add(path+".contact_id", validation.CodeRequired, "contact_id is required")
+		case seen[r.ContactID]:
+			errs.Add(path+".contact_id", validation.CodeDuplicate, "contact is listed more than once")
+		default:
+			seen[r.ContactID] = true
+			ids = append(ids, r.ContactID)
+		}
+	}
+	if len(ids) > 0 {
+		recs, err := s.RecordRepo.List(ctx, ContactsModule, map[string]any{"_id": bson.M{"$in": ids}}, nil, int64(len(ids)), 0, "_id", 1)
+		if err != nil {
+			return nil, err
+		}
+		found := make(map[primitive.ObjectID]bool, len(recs))
+		for _, rec := range recs {
+			if oid, ok := rec["_id"].(primitive.ObjectID); ok {
+				found[oid] = true
+			}
+		}
+		for i, r := range roles {
+			if seen[r.ContactID] && !found[r.ContactID] {
+				errs.Add(fmt.Sprintf("contact_roles.%d.contact_id", i), validation.CodeInvalid, "contact not found")
+			}
+		}
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.store(ctx, id, ContactRolesField, roles); err != nil {
+		return nil, err
+	}
+	return s.nameContacts(ctx, roles, userID)
+}
+
+// GetTeam reads the opportunity with the user's access and names its members
+func (s *OpportunityServiceImpl) GetTeam(ctx context.Context, id string, userID primitive.ObjectID) (*DealTeam, error) {
+	opp, err := s.RecordService.GetRecord(ctx, ModuleName, id, userID)
+	if err != nil {
+		return nil, ErrOpportunityNotFound
+	}
+	var members []TeamMember
+	if err := decodeList(opp[TeamField], &members); err != nil {
+		return nil, err
+	}
+	return s.team(ctx, members)
+}
+
+// SetTeam validates the members against the tenant's users and stores them
+// on the opportunity, which the user must be able to update. The splits may
+// not add up to more than 100.
+func (s *OpportunityServiceImpl) SetTeam(ctx context.Context, id string, members []TeamMember, userID primitive.ObjectID) (*DealTeam, error) {
+	if err := s.checkUpdate(ctx, id, TeamField, userID); err != nil {
+		return nil, err
+	}
+	if members == nil {
+		members = []TeamMember{}
+	}
+
+	var errs validation.Errors
+	seen := make(map[string]bool, len(members))
+	ids := make([]string, 0, len(members))
+	total := 0.0
+	for i, m := range members {
+		path := fmt.Sprintf("members.%d", i)
+		if !teamRoles[m.Role] {
+			errs.Add(path+".role", validation.CodeNotAllowed, fmt.Sprintf("role must be %s or %s", TeamRoleCoOwner, TeamRoleMember))
+		}
+		if m.Split < 0 || m.Split > 100 || math.IsNaN(m.Split) {
+			errs.Add(path+".split", validation.CodeInvalid, "split must be between 0 and 100")
+		} else {
+			total += m.Split
+		}
+		switch {
+		case m.UserID == "":
+			errs.Add(path+".user_id", validation.CodeRequired, "user_id is required")
+		case !primitive.IsValidObjectID(m.UserID):
+			errs.Add(path+".user_id", validation.CodeInvalid, "user not found")
+		case seen[m.UserID]:
+			errs.Add(path+".user_id", validation.CodeDuplicate, "user is listed more than once")
+		default:
+			seen[m.UserID] = true
+			ids = append(ids, m.UserID)
+		}
+	}
+	if total > 100 {
+		errs.Add("members", validation.CodeInvalid, fmt.Sprintf("splits add up to %g, more than 100", total))
+	}
+	if len(ids) > 0 {
+		users, err := s.users(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for i, m := range members {
+			if _, ok := users[m.UserID]; seen[m.UserID] && !ok {
+				errs.Add(fmt.Sprintf("members.%d.user_id", i), validation.CodeInvalid, "user not found")
+			}
+		}
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.store(ctx, id, TeamField, members); err != nil {
+		return nil, err
+	}
+	return s.team(ctx, members)
+}
+
+// checkUpdate applies the user's update access to the opportunity and the
+// field being set
+func (s *OpportunityServiceImpl) checkUpdate(ctx context.Context, id, field string, userID primitive.ObjectID) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrOpportunityNotFound
+	}
+	accessFilter, err := s.RoleService.GetAccessFilter(ctx, userID, ModuleName, "update")
+	if err != nil {
+		return ErrOpportunityNotFound
+	}
+	if v, denied := accessFilter["_id"]; denied && v == -1 {
+		return record.ErrAccessDenied
+	}
+	count, err := s.RecordRepo.Count(ctx, ModuleName, map[string]any{"_id": oid}, accessFilter)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrOpportunityNotFound
+	}
+
+	perms, err := s.RoleService.GetFieldPermissions(ctx, userID, ModuleName)
+	if err == nil && perms != nil {
+		if p := perms[field]; p == role.FieldPermReadOnly || p == role.FieldPermNone {
+			return fmt.Errorf("%w: field '%s' is read-only or hidden", record.ErrAccessDenied, field)
+		}
+	}
+	return nil
+}
+
+// store replaces the field on the opportunity and logs the change
+func (s *OpportunityServiceImpl) store(ctx context.Context, id, field string, value any) error {
+	var old any
+	if opp, err := s.RecordRepo.Get(ctx, ModuleName, id); err == nil {
+		old = opp[field]
+	}
+	if err := s.RecordRepo.Update(ctx, ModuleName, id, map[string]any{field: value}); err != nil {
+		return err
+	}
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionUpdate, ModuleName, id, map[string]common_models.Change{
+		field: {Old: old, New: value},
+	})
+	return nil
+}
+
+// nameContacts fills in the names of the contacts the user may read
+func (s *OpportunityServiceImpl) nameContacts(ctx context.Context, roles []ContactRole, userID primitive.ObjectID) ([]ContactRole, error) {
+	if roles == nil {
+		roles = []ContactRole{}
+	}
+	if len(roles) == 0 {
+		return roles, nil
+	}
+	accessFilter, err := s.RoleService.GetAccessFilter(ctx, userID, ContactsModule, "read")
+	if err != nil {
+		return roles, nil
+	}
+	ids := make([]primitive.ObjectID, 0, len(roles))
+	for _, r := range roles {
+		ids = append(ids, r.ContactID)
+	}
+	recs, err := s.RecordRepo.List(ctx, ContactsModule, map[string]any{"_id": bson.M{"$in": ids}}, accessFilter, int64(len(ids)), 0, "_id", 1)
+	if err != nil {
+		return nil, err
+	}
+	perms, _ := s.RoleService.GetFieldPermissions(ctx, userID, ContactsModule)
+	names := make(map[primitive.ObjectID]string, len(recs))
+	for _, rec := range recs {
+		oid, _ := rec["_id"].(primitive.ObjectID)
+		var first, last string
+		if perms["first_name"] != role.FieldPermNone {
+			first, _ = rec["first_name"].(string)
+		}
+		if perms["last_name"] != role.FieldPermNone {
+			last, _ = rec["last_name"].(string)
+		}
+		switch {
+		case first != "" && last != "":
+			names[oid] = first + " " + last
+		default:
+			names[oid] = first + last
+		}
+	}
+	for i := range roles {
+		roles[i].ContactName = names[roles[i].ContactID]
+	}
+	return roles, nil
+}
+
+// team names the members and works out the owner's split
+func (s *OpportunityServiceImpl) team(ctx context.Context, members []TeamMember) (*DealTeam, error) {
+	if members == nil {
+		members = []TeamMember{}
+	}
+	ids := make([]string, 0, len(members))
+	for _, m := range members {
+		ids = append(ids, m.UserID)
+	}
+	users, err := s.users(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	team := &DealTeam{Members: members, OwnerSplit: 100}
+	for i, m := range members {
+		if u, ok := users[m.UserID]; ok {
+			members[i].Name = displayName(u)
+		}
+		team.OwnerSplit -= m.Split
+	}
+	if team.OwnerSplit < 0 {
+		team.OwnerSplit = 0
+	}
+	return team, nil
+}
+
+// users returns the users of the caller's tenant among ids, by hex ID
+func (s *OpportunityServiceImpl) users(ctx context.Context, ids []string) (map[string]common_models.User, error) {
+	found, err := s.UserRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	tenantID, _ := ctx.Value(common_models.TenantIDKey).(string)
+	users := make(map[string]common_models.User, len(found))
+	for _, u := range found {
+		if tenantID != "" && u.TenantID.Hex() != tenantID {
+			continue
+		}
+		users[u.ID.Hex()] = u
+	}
+	return users, nil
+}
+
+func displayName(u common_models.User) string {
+	switch {
+	case u.FirstName != "" && u.LastName != "":
+		return u.FirstName + " " + u.LastName
+	case u.FirstName != "" || u.LastName != "":
+		return u.FirstName + u.LastName
+	}
+	return u.Username
+}
+
+// decodeList decodes a list stored on a record into out
+func decodeList(raw any, out any) error {
+	if raw == nil {
+		return nil
+	}
+	data, err := bson.Marshal(bson.M{"list": raw})
+	if err != nil {
+		return err
+	}
+	var doc bson.Raw = data
+	return doc.Lookup("list").Unmarshal(out)
+}
@@ -8,6 +8,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type OrganizationRepository interface {
@@ -15,6 +16,9 @@ type OrganizationRepository interface {
 	FindByID(ctx context.Context, id string) (*models.Organization, error)
 	FindByName(ctx context.Context, name string) (*models.Organization, error)
 	Update(ctx context.Context, org *models.Organization) error
+	ListSandboxes(ctx context.Context, sourceID primitive.ObjectID) ([]models.Organization, error)
+	// ListIDs returns the ID of every organization, for jobs run per tenant
+	ListIDs(ctx context.Context) ([]primitive.ObjectID, error)
 }
 
 type OrganizationRepositoryImpl struct {
@@ -61,3 +65,37 @@ func (r *OrganizationRepositoryImpl) Update(ctx context.Context, org *models.Org
 	_, err := r.Collection.UpdateOne(ctx, filter, update)
 	return err
 }
+
+func (r *OrganizationRepositoryImpl) ListSandboxes(ctx context.Context, sourceID primitive.ObjectID) ([]models.Organization, error) {
+	cursor, err := r.Collection.Find(ctx, bson.M{"sandbox_of": sourceID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	orgs := []models.Organization{}
+	if err := cursor.All(ctx, &orgs); err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+func (r *OrganizationRepositoryImpl) ListIDs(ctx context.Context) ([]primitive.ObjectID, error) {
+	cursor, err := r.Collection.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	return ids, nil
+}
@@ -2,7 +2,8 @@ package permission
 
 import (
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"go-crm/internal/common/session"
 )
 
 type PermissionController struct {
@@ -217,18 +218,12 @@ func (ctrl *PermissionController) RevokeResourceFromRole(c *fiber.Ctx) error {
 // @Failure      500  {string} string "Failed to get permissions"
 // @Router       /api/permissions/user/{userId}/effective [get]
 func (ctrl *PermissionController) GetUserEffectivePermissions(c *fiber.Ctx) error {
-	userIDStr, ok := c.Locals("user_id").(string)
-	if !ok || userIDStr == "" {
+	userId, ok := session.UserID(c.UserContext())
+	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "User context missing",
 		})
 	}
-	userId, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID format",
-		})
-	}
 
 	permissions, err := ctrl.PermissionService.GetUserEffectivePermissions(c.UserContext(), userId)
 	if err != nil {
@@ -7,6 +7,7 @@ import (
 
 	common_models "go-crm/internal/common/models"
 	"go-crm/internal/features/audit"
+	"go-crm/internal/features/authz"
 	"go-crm/internal/features/user"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -28,20 +29,38 @@ type PermissionServiceImpl struct {
 	PermissionRepo PermissionRepository
 	UserRepo       user.UserRepository
 	AuditService   audit.AuditService
+	Versions       authz.VersionService
 }
 
 func NewPermissionService(
 	permissionRepo PermissionRepository,
 	userRepo user.UserRepository,
 	auditService audit.AuditService,
+	versions authz.VersionService,
 ) PermissionService {
 	return &PermissionServiceImpl{
 		PermissionRepo: permissionRepo,
 		UserRepo:       userRepo,
 		AuditService:   auditService,
+		Versions:       versions,
 	}
 }
 
+// bumpVersion refreshes the tokens and cached decisions of the role's users
+// after one of its grants changes
+func (s *PermissionServiceImpl) bumpVersion(ctx context.Context, roleIDs ...primitive.ObjectID) {
+	if s.Versions == nil {
+		return
+	}
+	ids := make([]string, 0, len(roleIDs))
+	for _, id := range roleIDs {
+		if !id.IsZero() {
+			ids = append(ids, id.Hex())
+		}
+	}
+	s.Versions.Bump(ctx, ids...)
+}
+
 func (s *PermissionServiceImpl) CreatePermission(ctx context.Context, permission *Permission) (*Permission, error) {
 	permission.ID = primitive.NewObjectID()
 	permission.CreatedAt = time.Now()
@@ -59,6 +78,7 @@ func (s *PermissionServiceImpl) CreatePermission(ctx context.Context, permission
 		"role_id":  {New: permission.RoleID.Hex()},
 		"resource": {New: permission.Resource},
 	})
+	s.bumpVersion(ctx, permission.RoleID)
 
 	return permission, nil
 }
@@ -76,6 +96,10 @@ func (s *PermissionServiceImpl) GetPermissionsByResource(ctx context.Context, re
 }
 
 func (s *PermissionServiceImpl) UpdatePermission(ctx context.Context, id string, permission *Permission) error {
+	existing, err := s.PermissionRepo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
 	permission.UpdatedAt = time.Now()
 
 	if err := s.PermissionRepo.Update(ctx, id, permission); err != nil {
@@ -85,6 +109,7 @@ func (s *PermissionServiceImpl) UpdatePermission(ctx context.Context, id string,
 	_ = s.AuditService.LogChange(ctx, common_models.AuditActionUpdate, "permission", id, map[string]common_models.Change{
 		"actions": {New: permission.Actions},
 	})
+	s.bumpVersion(ctx, existing.RoleID, permission.RoleID)
 
 	return nil
 }
@@ -102,6 +127,7 @@ func (s *PermissionServiceImpl) DeletePermission(ctx context.Context, id string)
 	_ = s.AuditService.LogChange(ctx, common_models.AuditActionDelete, "permission", id, map[string]common_models.Change{
 		"resource": {Old: perm.Resource},
 	})
+	s.bumpVersion(ctx, perm.RoleID)
 
 	return nil
 }
@@ -133,7 +159,11 @@ func (s *PermissionServiceImpl) AssignResourceToRole(ctx context.Context, req As
 		existing.Actions = req.Actions
 		existing.FieldRules = req.FieldRules
 		existing.UpdatedAt = time.Now()
-		return s.PermissionRepo.Update(ctx, existing.ID.Hex(), existing)
+		if err := s.PermissionRepo.Update(ctx, existing.ID.Hex(), existing); err != nil {
+			return err
+		}
+		s.bumpVersion(ctx, roleID)
+		return nil
 	}
 
 	// Create new permission
@@ -151,7 +181,11 @@ func (s *PermissionServiceImpl) AssignResourceToRole(ctx context.Context, req As
 		UpdatedAt:  time.Now(),
 	}
 
-	return s.PermissionRepo.Create(ctx, permission)
+	if err := s.PermissionRepo.Create(ctx, permission); err != nil {
+		return err
+	}
+	s.bumpVersion(ctx, roleID)
+	return nil
 }
 
 func (s *PermissionServiceImpl) RevokeResourceFromRole(ctx context.Context, req RevokeResourceRequest) error {
@@ -164,7 +198,11 @@ func (s *PermissionServiceImpl) RevokeResourceFromRole(ctx context.Context, req
 		return fmt.Errorf("permission not found")
 	}
 
-	return s.PermissionRepo.Delete(ctx, existing.ID.Hex())
+	if err := s.PermissionRepo.Delete(ctx, existing.ID.Hex()); err != nil {
+		return err
+	}
+	s.bumpVersion(ctx, existing.RoleID)
+	return nil
 }
 
 func (s *PermissionServiceImpl) GetUserEffectivePermissions(ctx context.Context, userID primitive.ObjectID) (map[string]*Permission, error) {
@@ -0,0 +1,34 @@
+package plugin
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PluginApi struct {
+	controller  *PluginController
+	config      *config.Config
+	roleService middleware.RoleService
+}
+
+func NewPluginApi(controller *PluginController, config *config.Config, roleService middleware.RoleService) *PluginApi {
+	return &PluginApi{
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *PluginApi) Setup(app *fiber.App) {
+	group := app.Group("/api/plugins", middleware.AuthMiddleware(h.config.SkipAuth))
+
+	group.Get("/", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListPlugins)
+	group.Post("/", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.CreatePlugin)
+	group.Get("/:id", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.GetPlugin)
+	group.Put("/:id", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.UpdatePlugin)
+	group.Delete("/:id", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.DeletePlugin)
+	group.Post("/:id/rotate-secret", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.RotateSecret)
+	group.Get("/:id/invocations", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListInvocations)
+}
@@ -0,0 +1,140 @@
+package plugin
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"go-crm/internal/common/session"
+)
+
+type PluginController struct {
+	Service PluginService
+}
+
+func NewPluginController(service PluginService) *PluginController {
+	return &PluginController{Service: service}
+}
+
+// CreatePlugin godoc
+// @Summary Register plugin
+// @Description Register an HTTP endpoint for record lifecycle hooks. The signing secret is only returned in this response.
+// @Tags plugins
+// @Accept json
+// @Produce json
+// @Param plugin body Plugin true "Plugin"
+// @Success 201 {object} Plugin
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/plugins [post]
+func (c *PluginController) CreatePlugin(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var p Plugin
+	if err := ctx.BodyParser(&p); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := c.Service.CreatePlugin(ctx.UserContext(), &p, userID); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{"data": p})
+}
+
+// ListPlugins godoc
+// @Summary List plugins
+// @Tags plugins
+// @Produce json
+// @Success 200 {array} Plugin
+// @Router /api/plugins [get]
+func (c *PluginController) ListPlugins(ctx *fiber.Ctx) error {
+	plugins, err := c.Service.ListPlugins(ctx.UserContext())
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": plugins})
+}
+
+// GetPlugin godoc
+// @Summary Get plugin
+// @Tags plugins
+// @Produce json
+// @Param id path string true "Plugin ID"
+// @Success 200 {object} Plugin
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/plugins/{id} [get]
+func (c *PluginController) GetPlugin(ctx *fiber.Ctx) error {
+	p, err := c.Service.GetPlugin(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Plugin not found"})
+	}
+	return ctx.JSON(fiber.Map{"data": p})
+}
+
+// UpdatePlugin godoc
+// @Summary Update plugin
+// @Description The secret is not changed; use the rotate-secret endpoint
+// @Tags plugins
+// @Accept json
+// @Produce json
+// @Param id path string true "Plugin ID"
+// @Param plugin body Plugin true "Plugin"
+// @Success 200 {object} Plugin
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/plugins/{id} [put]
+func (c *PluginController) UpdatePlugin(ctx *fiber.Ctx) error {
+	var in Plugin
+	if err := ctx.BodyParser(&in); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	p, err := c.Service.UpdatePlugin(ctx.UserContext(), ctx.Params("id"), &in)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": p})
+}
+
+// DeletePlugin godoc
+// @Summary Delete plugin
+// @Tags plugins
+// @Param id path string true "Plugin ID"
+// @Success 204
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/plugins/{id} [delete]
+func (c *PluginController) DeletePlugin(ctx *fiber.Ctx) error {
+	if err := c.Service.DeletePlugin(ctx.UserContext(), ctx.Params("id")); err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// RotateSecret godoc
+// @Summary Rotate plugin secret
+// @Description Issue a new signing secret; the old one stops working immediately
+// @Tags plugins
+// @Produce json
+// @Param id path string true "Plugin ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/plugins/{id}/rotate-secret [post]
+func (c *PluginController) RotateSecret(ctx *fiber.Ctx) error {
+	secret, err := c.Service.RotateSecret(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": fiber.Map{"secret": secret}})
+}
+
+// ListInvocations godoc
+// @Summary List plugin invocations
+// @Tags plugins
+// @Produce json
+// @Param id path string true "Plugin ID"
+// @Param limit query int false "Limit"
+// @Success 200 {array} Invocation
+// @Router /api/plugins/{id}/invocations [get]
+func (c *PluginController) ListInvocations(ctx *fiber.Ctx) error {
+	invocations, err := c.Service.ListInvocations(ctx.UserContext(), ctx.Params("id"), int64(ctx.QueryInt("limit", 50)))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": invocations})
+}
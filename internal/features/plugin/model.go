@@ -0,0 +1,98 @@
+package plugin
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type FailurePolicy string
+
+const (
+	// FailOpen lets the write continue when the plugin times out or errors
+	FailOpen FailurePolicy = "fail_open"
+	// FailClosed rejects the write instead. Only applies to before hooks.
+	FailClosed FailurePolicy = "fail_closed"
+)
+
+const (
+	DefaultTimeoutMs = 3000
+	MaxTimeoutMs     = 10000
+)
+
+// Invocation outcomes
+const (
+	OutcomeAllowed  = "allowed"
+	OutcomeModified = "modified"
+	OutcomeRejected = "rejected"
+	OutcomeFailed   = "failed"
+)
+
+// Plugin is an external HTTP endpoint that intercepts record lifecycle
+// hooks. Each call is a POST of a HookRequest signed with the plugin secret:
+// X-CRM-Signature is "sha256=" + hex HMAC-SHA256 of X-CRM-Timestamp + "." + body.
+type Plugin struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID    primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	Name        string             `json:"name" bson:"name"`
+	Description string             `json:"description,omitempty" bson:"description,omitempty"`
+	URL         string             `json:"url" bson:"url"`
+	Secret      string             `json:"secret,omitempty" bson:"secret"` // Only returned on create and rotation
+	Headers     map[string]string  `json:"headers,omitempty" bson:"headers,omitempty"`
+
+	// Events are record hook names: before_create, before_update, after_create, after_update
+	Events []string `json:"events" bson:"events"`
+	// Modules limits the plugin to these modules; empty means all
+	Modules []string `json:"modules,omitempty" bson:"modules,omitempty"`
+	// Priority orders plugins on the same hook, lowest first
+	Priority      int           `json:"priority" bson:"priority"`
+	TimeoutMs     int           `json:"timeout_ms" bson:"timeout_ms"`
+	FailurePolicy FailurePolicy `json:"failure_policy" bson:"failure_policy"`
+	IsActive      bool          `json:"is_active" bson:"is_active"`
+
+	LastError   string     `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty" bson:"last_error_at,omitempty"`
+
+	CreatedBy primitive.ObjectID `json:"created_by" bson:"created_by"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// HookRequest is the body sent to plugins
+type HookRequest struct {
+	Event     string                 `json:"event"`
+	Module    string                 `json:"module"`
+	RecordID  string                 `json:"record_id,omitempty"`
+	Data      map[string]interface{} `json:"data"`
+	Previous  map[string]interface{} `json:"previous,omitempty"`
+	UserID    string                 `json:"user_id,omitempty"`
+	TenantID  string                 `json:"tenant_id"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// HookResponse is what plugins reply with. An empty 2xx body allows the
+// write unchanged.
+type HookResponse struct {
+	// Allow false rejects a before hook write with Message and Errors
+	Allow   *bool             `json:"allow,omitempty"`
+	Message string            `json:"message,omitempty"`
+	Errors  map[string]string `json:"errors,omitempty"` // field -> message
+	// Data overrides submitted fields on before hooks and is written back
+	// onto the record on after hooks
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+// Invocation logs one plugin call
+type Invocation struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID   primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	PluginID   primitive.ObjectID `json:"plugin_id" bson:"plugin_id"`
+	Event      string             `json:"event" bson:"event"`
+	Module     string             `json:"module" bson:"module"`
+	RecordID   string             `json:"record_id,omitempty" bson:"record_id,omitempty"`
+	Outcome    string             `json:"outcome" bson:"outcome"`
+	StatusCode int                `json:"status_code" bson:"status_code"`
+	Error      string             `json:"error,omitempty" bson:"error,omitempty"`
+	Duration   int64              `json:"duration" bson:"duration"` // milliseconds
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+}
@@ -0,0 +1,215 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+type PluginRepository interface {
+	Create(ctx context.Context, p *Plugin) error
+	Get(ctx context.Context, id string) (*Plugin, error)
+	List(ctx context.Context) ([]Plugin, error)
+	// ListForHook returns the active plugins bound to an event and module, in priority order
+	ListForHook(ctx context.Context, event, moduleName string) ([]Plugin, error)
+	Update(ctx context.Context, p *Plugin) error
+	SetLastError(ctx context.Context, id primitive.ObjectID, message string) error
+	Delete(ctx context.Context, id string) error
+}
+
+type PluginRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewPluginRepository(db *database.MongodbDB) PluginRepository {
+	return &PluginRepositoryImpl{
+		collection: db.DB.Collection("plugins"),
+	}
+}
+
+func (r *PluginRepositoryImpl) Create(ctx context.Context, p *Plugin) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	p.ID = primitive.NewObjectID()
+	p.TenantID = tenantID
+	p.CreatedAt = time.Now()
+	p.UpdatedAt = p.CreatedAt
+
+	_, err = r.collection.InsertOne(ctx, p)
+	return err
+}
+
+func (r *PluginRepositoryImpl) Get(ctx context.Context, id string) (*Plugin, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Plugin
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID}).Decode(&p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *PluginRepositoryImpl) List(ctx context.Context) ([]Plugin, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "priority", Value: 1}, {Key: "name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"tenant_id": tenantID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	plugins := []Plugin{}
+	if err := cursor.All(ctx, &plugins); err != nil {
+		return nil, err
+	}
+	return plugins, nil
+}
+
+func (r *PluginRepositoryImpl) ListForHook(ctx context.Context, event, moduleName string) ([]Plugin, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{
+		"tenant_id": tenantID,
+		"is_active": true,
+		"events":    event,
+		"$or": []bson.M{
+			{"modules": bson.M{"$exists": false}},
+			{"modules": bson.M{"$size": 0}},
+			{"modules": moduleName},
+		},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "priority", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var plugins []Plugin
+	if err := cursor.All(ctx, &plugins); err != nil {
+		return nil, err
+	}
+	return plugins, nil
+}
+
+func (r *PluginRepositoryImpl) Update(ctx context.Context, p *Plugin) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	p.UpdatedAt = time.Now()
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": p.ID, "tenant_id": tenantID}, p)
+	return err
+}
+
+func (r *PluginRepositoryImpl) SetLastError(ctx context.Context, id primitive.ObjectID, message string) error {
+	now := time.Now()
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"last_error":    message,
+		"last_error_at": now,
+	}})
+	return err
+}
+
+func (r *PluginRepositoryImpl) Delete(ctx context.Context, id string) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID})
+	return err
+}
+
+type InvocationRepository interface {
+	Create(ctx context.Context, inv *Invocation) error
+	ListByPlugin(ctx context.Context, pluginID primitive.ObjectID, limit int64) ([]Invocation, error)
+	DeleteByPlugin(ctx context.Context, pluginID primitive.ObjectID) error
+}
+
+type InvocationRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewInvocationRepository(db *database.MongodbDB) InvocationRepository {
+	return &InvocationRepositoryImpl{
+		collection: db.DB.Collection("plugin_invocations"),
+	}
+}
+
+func (r *InvocationRepositoryImpl) Create(ctx context.Context, inv *Invocation) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	inv.ID = primitive.NewObjectID()
+	inv.TenantID = tenantID
+	inv.CreatedAt = time.Now()
+
+	_, err = r.collection.InsertOne(ctx, inv)
+	return err
+}
+
+func (r *InvocationRepositoryImpl) ListByPlugin(ctx context.Context, pluginID primitive.ObjectID, limit int64) ([]Invocation, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, bson.M{"tenant_id": tenantID, "plugin_id": pluginID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	invocations := []Invocation{}
+	if err := cursor.All(ctx, &invocations); err != nil {
+		return nil, err
+	}
+	return invocations, nil
+}
+
+func (r *InvocationRepositoryImpl) DeleteByPlugin(ctx context.Context, pluginID primitive.ObjectID) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.DeleteMany(ctx, bson.M{"tenant_id": tenantID, "plugin_id": pluginID})
+	return err
+}
@@ -0,0 +1,368 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/record"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const maxResponseBytes = 1 << 20
+
+// RejectedError is returned when a before hook plugin refuses a write
+type RejectedError struct {
+	Plugin  string
+	Message string
+	Errors  map[string]string
+}
+
+func (e *RejectedError) Error() string {
+	msg := e.Message
+	if msg == "" && len(e.Errors) > 0 {
+		fields := make([]string, 0, len(e.Errors))
+		for f, m := range e.Errors {
+			fields = append(fields, f+": "+m)
+		}
+		sort.Strings(fields)
+		msg = strings.Join(fields, "; ")
+	}
+	if msg == "" {
+		msg = "write rejected"
+	}
+	return fmt.Sprintf("%s: %s", e.Plugin, msg)
+}
+
+type PluginService interface {
+	CreatePlugin(ctx context.Context, p *Plugin, userID primitive.ObjectID) error
+	GetPlugin(ctx context.Context, id string) (*Plugin, error)
+	ListPlugins(ctx context.Context) ([]Plugin, error)
+	UpdatePlugin(ctx context.Context, id string, p *Plugin) (*Plugin, error)
+	DeletePlugin(ctx context.Context, id string) error
+	// RotateSecret issues a new signing secret and returns it once
+	RotateSecret(ctx context.Context, id string) (string, error)
+	ListInvocations(ctx context.Context, id string, limit int64) ([]Invocation, error)
+
+	// RunHook calls the plugins bound to a record hook; see record.RecordHooks
+	RunHook(ctx context.Context, hook record.RecordHook) (map[string]interface{}, error)
+}
+
+type PluginServiceImpl struct {
+	Repo           PluginRepository
+	InvocationRepo InvocationRepository
+	AuditService   audit.AuditService
+	HttpClient     *http.Client
+}
+
+func NewPluginService(repo PluginRepository, invocationRepo InvocationRepository, auditService audit.AuditService) PluginService {
+	return &PluginServiceImpl{
+		Repo:           repo,
+		InvocationRepo: invocationRepo,
+		AuditService:   auditService,
+		// Per-call timeouts come from the plugin; this is only a ceiling
+		HttpClient: &http.Client{Timeout: MaxTimeoutMs * time.Millisecond},
+	}
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func validate(p *Plugin) error {
+	if p.Name == "" || p.URL == "" {
+		return errors.New("name and url are required")
+	}
+	u, err := url.Parse(p.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	if len(p.Events) == 0 {
+		return errors.New("at least one event is required")
+	}
+	for _, e := range p.Events {
+		switch e {
+		case record.HookBeforeCreate, record.HookBeforeUpdate, record.HookAfterCreate, record.HookAfterUpdate:
+		default:
+			return fmt.Errorf("unknown event: %s", e)
+		}
+	}
+	if p.TimeoutMs == 0 {
+		p.TimeoutMs = DefaultTimeoutMs
+	}
+	if p.TimeoutMs < 100 || p.TimeoutMs > MaxTimeoutMs {
+		return fmt.Errorf("timeout_ms must be between 100 and %d", MaxTimeoutMs)
+	}
+	switch p.FailurePolicy {
+	case "":
+		p.FailurePolicy = FailOpen
+	case FailOpen, FailClosed:
+	default:
+		return errors.New("failure_policy must be fail_open or fail_closed")
+	}
+	return nil
+}
+
+func (s *PluginServiceImpl) CreatePlugin(ctx context.Context, p *Plugin, userID primitive.ObjectID) error {
+	if err := validate(p); err != nil {
+		return err
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return err
+	}
+	p.Secret = secret
+	p.CreatedBy = userID
+	p.LastError = ""
+	p.LastErrorAt = nil
+	if err := s.Repo.Create(ctx, p); err != nil {
+		return err
+	}
+
+	logged := *p
+	logged.Secret = ""
+	_ = s.AuditService.LogChange(ctx, models.AuditActionSettings, "plugins", p.Name, map[string]models.Change{
+		"plugin": {New: logged},
+	})
+	return nil
+}
+
+func (s *PluginServiceImpl) GetPlugin(ctx context.Context, id string) (*Plugin, error) {
+	p, err := s.Repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	p.Secret = ""
+	return p, nil
+}
+
+func (s *PluginServiceImpl) ListPlugins(ctx context.Context) ([]Plugin, error) {
+	plugins, err := s.Repo.List(ctx)
+	for i := range plugins {
+		plugins[i].Secret = ""
+	}
+	return plugins, err
+}
+
+func (s *PluginServiceImpl) UpdatePlugin(ctx context.Context, id string, in *Plugin) (*Plugin, error) {
+	existing, err := s.Repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	old := *existing
+	old.Secret = ""
+
+	existing.Name = in.Name
+	existing.Description = in.Description
+	existing.URL = in.URL
+	existing.Headers = in.Headers
+	existing.Events = in.Events
+	existing.Modules = in.Modules
+	existing.Priority = in.Priority
+	existing.TimeoutMs = in.TimeoutMs
+	existing.FailurePolicy = in.FailurePolicy
+	existing.IsActive = in.IsActive
+	if err := validate(existing); err != nil {
+		return nil, err
+	}
+	if err := s.Repo.Update(ctx, existing); err != nil {
+		return nil, err
+	}
+	existing.Secret = ""
+
+	_ = s.AuditService.LogChange(ctx, models.AuditActionSettings, "plugins", existing.Name, map[string]models.Change{
+		"plugin": {Old: old, New: existing},
+	})
+	return existing, nil
+}
+
+func (s *PluginServiceImpl) DeletePlugin(ctx context.Context, id string) error {
+	p, err := s.Repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.Repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	_ = s.InvocationRepo.DeleteByPlugin(ctx, p.ID)
+
+	_ = s.AuditService.LogChange(ctx, models.AuditActionSettings, "plugins", p.Name, map[string]models.Change{
+		"plugin": {Old: p.URL, New: "DELETED"},
+	})
+	return nil
+}
+
+func (s *PluginServiceImpl) RotateSecret(ctx context.Context, id string) (string, error) {
+	p, err := s.Repo.Get(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return "", err
+	}
+	p.Secret = secret
+	if err := s.Repo.Update(ctx, p); err != nil {
+		return "", err
+	}
+
+	_ = s.AuditService.LogChange(ctx, models.AuditActionSettings, "plugins", p.Name, map[string]models.Change{
+		"secret": {New: "ROTATED"},
+	})
+	return secret, nil
+}
+
+func (s *PluginServiceImpl) ListInvocations(ctx context.Context, id string, limit int64) ([]Invocation, error) {
+	p, err := s.Repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+	return s.InvocationRepo.ListByPlugin(ctx, p.ID, limit)
+}
+
+// RunHook calls matching plugins in priority order. On before hooks each
+// plugin sees the data as overridden by the plugins before it, and the first
+// rejection stops the write. Failures follow the plugin's failure policy;
+// after hooks never fail the write.
+func (s *PluginServiceImpl) RunHook(ctx context.Context, hook record.RecordHook) (map[string]interface{}, error) {
+	plugins, err := s.Repo.ListForHook(ctx, hook.Event, hook.ModuleName)
+	if err != nil || len(plugins) == 0 {
+		// No tenant (system writes) or no plugins bound
+		return nil, nil
+	}
+	before := strings.HasPrefix(hook.Event, "before_")
+	tenantID, _ := tenantFromContext(ctx)
+
+	data := make(map[string]interface{}, len(hook.Data))
+	for k, v := range hook.Data {
+		data[k] = v
+	}
+	overrides := map[string]interface{}{}
+
+	for _, p := range plugins {
+		req := HookRequest{
+			Event:     hook.Event,
+			Module:    hook.ModuleName,
+			RecordID:  hook.RecordID,
+			Data:      data,
+			Previous:  hook.Previous,
+			TenantID:  tenantID.Hex(),
+			Timestamp: time.Now(),
+		}
+		if !hook.ActorID.IsZero() {
+			req.UserID = hook.ActorID.Hex()
+		}
+
+		inv := &Invocation{PluginID: p.ID, Event: hook.Event, Module: hook.ModuleName, RecordID: hook.RecordID}
+		started := time.Now()
+		resp, status, callErr := s.call(ctx, &p, req)
+		inv.StatusCode = status
+		inv.Duration = time.Since(started).Milliseconds()
+
+		switch {
+		case callErr != nil:
+			inv.Outcome = OutcomeFailed
+			inv.Error = callErr.Error()
+			_ = s.Repo.SetLastError(ctx, p.ID, callErr.Error())
+		case before && resp.Allow != nil && !*resp.Allow:
+			inv.Outcome = OutcomeRejected
+			inv.Error = resp.Message
+		case len(resp.Data) > 0:
+			inv.Outcome = OutcomeModified
+		default:
+			inv.Outcome = OutcomeAllowed
+		}
+		_ = s.InvocationRepo.Create(ctx, inv)
+
+		if callErr != nil {
+			if before && p.FailurePolicy == FailClosed {
+				return nil, fmt.Errorf("%s is unavailable: %w", p.Name, callErr)
+			}
+			continue
+		}
+		if inv.Outcome == OutcomeRejected {
+			return nil, &RejectedError{Plugin: p.Name, Message: resp.Message, Errors: resp.Errors}
+		}
+		for k, v := range resp.Data {
+			data[k] = v
+			overrides[k] = v
+		}
+	}
+	return overrides, nil
+}
+
+func (s *PluginServiceImpl) call(ctx context.Context, p *Plugin, payload HookRequest) (*HookResponse, int, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(p.TimeoutMs)*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(p.Secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Go-CRM-Plugin")
+	req.Header.Set("X-CRM-Event", payload.Event)
+	req.Header.Set("X-CRM-Timestamp", timestamp)
+	req.Header.Set("X-CRM-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	for k, v := range p.Headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := s.HttpClient.Do(req)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, 0, fmt.Errorf("timed out after %dms", p.TimeoutMs)
+		}
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(res.Body, maxResponseBytes))
+	if err != nil {
+		return nil, res.StatusCode, err
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, res.StatusCode, fmt.Errorf("plugin returned HTTP %d", res.StatusCode)
+	}
+
+	var resp HookResponse
+	if len(bytes.TrimSpace(raw)) > 0 {
+		if err := json.Unmarshal(raw, &resp); err != nil {
+			return nil, res.StatusCode, fmt.Errorf("invalid plugin response: %w", err)
+		}
+	}
+	return &resp, res.StatusCode, nil
+}
@@ -0,0 +1,48 @@
+package print_template
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/features/role"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PrintTemplateApi struct {
+	controller  *PrintTemplateController
+	documents   *DocumentTemplateController
+	config      *config.Config
+	roleService role.RoleService
+}
+
+func NewPrintTemplateApi(controller *PrintTemplateController, documents *DocumentTemplateController, config *config.Config, roleService role.RoleService) *PrintTemplateApi {
+	return &PrintTemplateApi{
+		controller:  controller,
+		documents:   documents,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *PrintTemplateApi) Setup(app *fiber.App) {
+	templates := app.Group("/api/print-templates", middleware.AuthMiddleware(h.config.SkipAuth))
+	templates.Get("/", h.controller.List)
+	templates.Get("/:id", h.controller.Get)
+	templates.Post("/", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.Create)
+	templates.Put("/:id", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.Update)
+	templates.Delete("/:id", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.Delete)
+
+	documents := app.Group("/api/document-templates", middleware.AuthMiddleware(h.config.SkipAuth))
+	documents.Get("/", h.documents.List)
+	documents.Get("/:id", h.documents.Get)
+	documents.Get("/:id/file", h.documents.Download)
+	documents.Post("/", middleware.RequirePermission(h.roleService, "settings", "update"), h.documents.Create)
+	documents.Put("/:id", middleware.RequirePermission(h.roleService, "settings", "update"), h.documents.Update)
+	documents.Delete("/:id", middleware.RequirePermission(h.roleService, "settings", "update"), h.documents.Delete)
+
+	// Record read access is enforced by RecordService when the record is loaded
+	records := app.Group("/api/modules", middleware.AuthMiddleware(h.config.SkipAuth))
+	records.Get("/:module/records/:id/pdf", h.controller.RenderPDF)
+	records.Post("/:module/records/:id/pdf/email", h.controller.EmailPDF)
+	records.Get("/:module/records/:id/documents/:templateId", h.documents.Render)
+}
@@ -0,0 +1,191 @@
+package print_template
+
+import (
+	"fmt"
+
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PrintTemplateController struct {
+	Service PrintTemplateService
+}
+
+func NewPrintTemplateController(service PrintTemplateService) *PrintTemplateController {
+	return &PrintTemplateController{Service: service}
+}
+
+// Create godoc
+// @Summary Create print template
+// @Description Create a PDF/print template for a module. Sections are fields, text (with {{field}} placeholders) or related lists.
+// @Tags print_templates
+// @Accept json
+// @Produce json
+// @Param template body PrintTemplate true "Print Template"
+// @Success 201 {object} PrintTemplate
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/print-templates [post]
+func (c *PrintTemplateController) Create(ctx *fiber.Ctx) error {
+	var template PrintTemplate
+	if err := ctx.BodyParser(&template); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if err := c.Service.CreateTemplate(ctx.UserContext(), &template, userID); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(template)
+}
+
+// List godoc
+// @Summary List print templates
+// @Description List print templates, optionally filtered by module
+// @Tags print_templates
+// @Produce json
+// @Param module query string false "Filter by module"
+// @Success 200 {array} PrintTemplate
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/print-templates [get]
+func (c *PrintTemplateController) List(ctx *fiber.Ctx) error {
+	templates, err := c.Service.ListTemplates(ctx.UserContext(), ctx.Query("module"))
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(templates)
+}
+
+// Get godoc
+// @Summary Get print template
+// @Description Get a print template by ID
+// @Tags print_templates
+// @Produce json
+// @Param id path string true "Template ID"
+// @Success 200 {object} PrintTemplate
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/print-templates/{id} [get]
+func (c *PrintTemplateController) Get(ctx *fiber.Ctx) error {
+	template, err := c.Service.GetTemplate(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Template not found"})
+	}
+
+	return ctx.JSON(template)
+}
+
+// Update godoc
+// @Summary Update print template
+// @Description Update an existing print template
+// @Tags print_templates
+// @Accept json
+// @Produce json
+// @Param id path string true "Template ID"
+// @Param template body PrintTemplate true "Print Template"
+// @Success 200 {object} PrintTemplate
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/print-templates/{id} [put]
+func (c *PrintTemplateController) Update(ctx *fiber.Ctx) error {
+	var template PrintTemplate
+	if err := ctx.BodyParser(&template); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	oid, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID format"})
+	}
+	template.ID = oid
+
+	if err := c.Service.UpdateTemplate(ctx.UserContext(), &template); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(template)
+}
+
+// Delete godoc
+// @Summary Delete print template
+// @Description Delete a print template by ID
+// @Tags print_templates
+// @Param id path string true "Template ID"
+// @Success 204 {object} nil
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/print-templates/{id} [delete]
+func (c *PrintTemplateController) Delete(ctx *fiber.Ctx) error {
+	if err := c.Service.DeleteTemplate(ctx.UserContext(), ctx.Params("id")); err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// RenderPDF godoc
+// @Summary Download record PDF
+// @Description Render a single record to PDF. Uses template_id, else the module's default template, else a generated layout of all visible fields.
+// @Tags print_templates
+// @Produce application/pdf
+// @Param module path string true "Module Name"
+// @Param id path string true "Record ID"
+// @Param template_id query string false "Print Template ID"
+// @Param disposition query string false "inline (print preview) or attachment (default)"
+// @Success 200 {file} file "PDF document"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/modules/{module}/records/{id}/pdf [get]
+func (c *PrintTemplateController) RenderPDF(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	data, filename, err := c.Service.RenderRecordPDF(ctx.UserContext(), ctx.Params("module"), ctx.Params("id"), ctx.Query("template_id"), userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	disposition := "attachment"
+	if ctx.Query("disposition") == "inline" {
+		disposition = "inline"
+	}
+	ctx.Set(fiber.HeaderContentType, "application/pdf")
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf("%s; filename=%q", disposition, filename))
+	return ctx.Send(data)
+}
+
+// EmailPDF godoc
+// @Summary Email record PDF
+// @Description Render a record to PDF and email it as an attachment
+// @Tags print_templates
+// @Accept json
+// @Produce json
+// @Param module path string true "Module Name"
+// @Param id path string true "Record ID"
+// @Param request body EmailPDFRequest true "Recipients and message"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/modules/{module}/records/{id}/pdf/email [post]
+func (c *PrintTemplateController) EmailPDF(ctx *fiber.Ctx) error {
+	var req EmailPDFRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if err := c.Service.EmailRecordPDF(ctx.UserContext(), ctx.Params("module"), ctx.Params("id"), req, userID); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Email sent successfully"})
+}
@@ -0,0 +1,231 @@
+package print_template
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+
+	common_api "go-crm/internal/common/api"
+	"go-crm/internal/common/apperr"
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type DocumentTemplateController struct {
+	Service DocumentTemplateService
+}
+
+func NewDocumentTemplateController(service DocumentTemplateService) *DocumentTemplateController {
+	return &DocumentTemplateController{Service: service}
+}
+
+// uploadFromForm reads the multipart fields of a template upload. The file is
+// optional when required is false.
+func uploadFromForm(ctx *fiber.Ctx, required bool) (DocumentTemplateUpload, error) {
+	upload := DocumentTemplateUpload{
+		Name:        ctx.FormValue("name"),
+		ModuleName:  ctx.FormValue("module_name"),
+		Description: ctx.FormValue("description"),
+	}
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		if required {
+			return upload, apperr.Validation("file is required")
+		}
+		return upload, nil
+	}
+	if fileHeader.Size > maxDocumentTemplateSize {
+		return upload, apperr.Validation("template files are limited to %d MB", maxDocumentTemplateSize>>20)
+	}
+	upload.FileName = fileHeader.Filename
+	upload.Content, err = readFormFile(fileHeader)
+	return upload, err
+}
+
+func readFormFile(fileHeader *multipart.FileHeader) ([]byte, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(io.LimitReader(file, maxDocumentTemplateSize+1))
+}
+
+// Create godoc
+// @Summary Upload document template
+// @Description Upload a DOCX or HTML template for a module. Merge fields are {{field}}, {{lookup_field.field}}, {{today}} and {{now}}; {{#related module.lookup_field}} ... {{/related}} repeats for each related record (a table row in DOCX, or the enclosed paragraphs).
+// @Tags document_templates
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "DOCX or HTML template"
+// @Param name formData string true "Template name"
+// @Param module_name formData string true "Module Name"
+// @Param description formData string false "Description"
+// @Success 201 {object} DocumentTemplate
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/document-templates [post]
+func (c *DocumentTemplateController) Create(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	upload, err := uploadFromForm(ctx, true)
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+
+	tmpl, err := c.Service.CreateDocumentTemplate(ctx.UserContext(), upload, userID)
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(tmpl)
+}
+
+// List godoc
+// @Summary List document templates
+// @Description List uploaded document templates, optionally filtered by module
+// @Tags document_templates
+// @Produce json
+// @Param module query string false "Filter by module"
+// @Success 200 {array} DocumentTemplate
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/document-templates [get]
+func (c *DocumentTemplateController) List(ctx *fiber.Ctx) error {
+	templates, err := c.Service.ListDocumentTemplates(ctx.UserContext(), ctx.Query("module"))
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+
+	return ctx.JSON(templates)
+}
+
+// Get godoc
+// @Summary Get document template
+// @Description Get a document template and the merge fields it uses
+// @Tags document_templates
+// @Produce json
+// @Param id path string true "Template ID"
+// @Success 200 {object} DocumentTemplate
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/document-templates/{id} [get]
+func (c *DocumentTemplateController) Get(ctx *fiber.Ctx) error {
+	tmpl, err := c.Service.GetDocumentTemplate(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+
+	return ctx.JSON(tmpl)
+}
+
+// Download godoc
+// @Summary Download document template file
+// @Description Download the uploaded template file, with its merge fields unfilled
+// @Tags document_templates
+// @Produce application/octet-stream
+// @Param id path string true "Template ID"
+// @Success 200 {file} file "Template file"
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/document-templates/{id}/file [get]
+func (c *DocumentTemplateController) Download(ctx *fiber.Ctx) error {
+	tmpl, err := c.Service.GetDocumentTemplate(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+
+	contentType := "text/html; charset=utf-8"
+	if tmpl.Format == DocumentDOCX {
+		contentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	}
+	ctx.Set(fiber.HeaderContentType, contentType)
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", tmpl.FileName))
+	return ctx.Send(tmpl.Content)
+}
+
+// Update godoc
+// @Summary Update document template
+// @Description Rename, move or replace the file of a document template. Without a file the stored one is kept and checked against the module again.
+// @Tags document_templates
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Template ID"
+// @Param file formData file false "DOCX or HTML template"
+// @Param name formData string false "Template name"
+// @Param module_name formData string false "Module Name"
+// @Param description formData string false "Description"
+// @Success 200 {object} DocumentTemplate
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/document-templates/{id} [put]
+func (c *DocumentTemplateController) Update(ctx *fiber.Ctx) error {
+	upload, err := uploadFromForm(ctx, false)
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+
+	tmpl, err := c.Service.UpdateDocumentTemplate(ctx.UserContext(), ctx.Params("id"), upload)
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+
+	return ctx.JSON(tmpl)
+}
+
+// Delete godoc
+// @Summary Delete document template
+// @Description Delete a document template by ID
+// @Tags document_templates
+// @Param id path string true "Template ID"
+// @Success 204 {object} nil
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/document-templates/{id} [delete]
+func (c *DocumentTemplateController) Delete(ctx *fiber.Ctx) error {
+	if err := c.Service.DeleteDocumentTemplate(ctx.UserContext(), ctx.Params("id")); err != nil {
+		return common_api.Error(ctx, err)
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// Render godoc
+// @Summary Generate record document
+// @Description Fill a document template with a record, its lookups and related records, as the user may see them. DOCX templates render to docx (default) or pdf; HTML templates to pdf (default) or html.
+// @Tags document_templates
+// @Produce application/octet-stream
+// @Param module path string true "Module Name"
+// @Param id path string true "Record ID"
+// @Param templateId path string true "Document Template ID"
+// @Param format query string false "docx, html or pdf"
+// @Param disposition query string false "inline or attachment (default)"
+// @Success 200 {file} file "Generated document"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/modules/{module}/records/{id}/documents/{templateId} [get]
+func (c *DocumentTemplateController) Render(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	format := DocumentFormat(ctx.Query("format"))
+	switch format {
+	case "", DocumentDOCX, DocumentHTML, DocumentPDF:
+	default:
+		return common_api.Error(ctx, apperr.BadRequest("format must be docx, html or pdf"))
+	}
+
+	data, filename, contentType, err := c.Service.RenderDocument(ctx.UserContext(), ctx.Params("module"), ctx.Params("id"), ctx.Params("templateId"), format, userID)
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+
+	disposition := "attachment"
+	if ctx.Query("disposition") == "inline" {
+		disposition = "inline"
+	}
+	ctx.Set(fiber.HeaderContentType, contentType)
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf("%s; filename=%q", disposition, filename))
+	return ctx.Send(data)
+}
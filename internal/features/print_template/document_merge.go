@@ -0,0 +1,396 @@
+package print_template
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/pkg/locale"
+)
+
+// Merge tags are {{path}} fields and {{#related module.field}} ...
+// {{/related}} blocks, which do not nest
+var (
+	mergeTagPattern   = regexp.MustCompile(`\{\{\s*[#/]?\s*[A-Za-z0-9_.]*(?:\s+[A-Za-z0-9_.]+)?\s*\}\}`)
+	blockOpenPattern  = regexp.MustCompile(`\{\{\s*#related\s+([A-Za-z0-9_]+)\.([A-Za-z0-9_]+)\s*\}\}`)
+	blockClosePattern = regexp.MustCompile(`\{\{\s*/related\s*\}\}`)
+	xmlTextEscaper    = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	wordTextPattern   = regexp.MustCompile(`(<w:t(?:\s[^>]*)?>)([^<]*)(</w:t>)`)
+	wordPartPattern   = regexp.MustCompile(`^word/(document|header[0-9]*|footer[0-9]*|footnotes|endnotes)\.xml$`)
+)
+
+// mergeScope resolves fields against a record or, inside a related block,
+// against one of its children
+type mergeScope struct {
+	mod *common_models.Entity
+	rec map[string]any
+	lf  *locale.Formatter
+	now time.Time
+}
+
+func (sc mergeScope) value(path string) string {
+	switch path {
+	case "today":
+		return sc.lf.Date(sc.now)
+	case "now":
+		return sc.lf.DateTime(sc.now)
+	}
+	return placeholderValue(path, sc.mod, sc.rec, sc.lf)
+}
+
+// relatedSet holds the children of each related block, keyed module.field
+type relatedSet map[string]relatedRecords
+
+type relatedRecords struct {
+	mod     *common_models.Entity
+	records []map[string]any
+}
+
+func (rs relatedSet) scopes(key string, parent mergeScope) []mergeScope {
+	rel := rs[key]
+	scopes := make([]mergeScope, len(rel.records))
+	for i, rec := range rel.records {
+		scopes[i] = mergeScope{mod: rel.mod, rec: rec, lf: parent.lf, now: parent.now}
+	}
+	return scopes
+}
+
+// mergeFields replaces the {{path}} fields of text, escaping values for
+// the output. Block tags left over from an unbalanced block are dropped.
+func mergeFields(text string, scope mergeScope, escape func(string) string) string {
+	return mergeTagPattern.ReplaceAllStringFunc(text, func(tag string) string {
+		path := tagBody(tag)
+		if path == "" || strings.ContainsAny(path[:1], "#/") {
+			return ""
+		}
+		return escape(scope.value(path))
+	})
+}
+
+// tagBody is a tag without its braces and surrounding spaces
+func tagBody(tag string) string {
+	return strings.TrimSpace(tag[2 : len(tag)-2])
+}
+
+// expandBlocks replaces each related block of text, from its open tag to the
+// next close tag or the end of text, with fn of its key and inner text
+func expandBlocks(text string, fn func(key, inner string) string) string {
+	var b strings.Builder
+	for {
+		open := blockOpenPattern.FindStringSubmatchIndex(text)
+		if open == nil {
+			b.WriteString(text)
+			return b.String()
+		}
+		key := text[open[2]:open[3]] + "." + text[open[4]:open[5]]
+		rest := text[open[1]:]
+		inner, after := rest, ""
+		if close := blockClosePattern.FindStringIndex(rest); close != nil {
+			inner, after = rest[:close[0]], rest[close[1]:]
+		}
+		b.WriteString(text[:open[0]])
+		b.WriteString(fn(key, inner))
+		text = after
+	}
+}
+
+func mergeHTML(src string, scope mergeScope, related relatedSet) string {
+	merged := expandBlocks(src, func(key, inner string) string {
+		var b strings.Builder
+		for _, child := range related.scopes(key, scope) {
+			b.WriteString(mergeFields(inner, child, escapeHTML))
+		}
+		return b.String()
+	})
+	return mergeFields(merged, scope, escapeHTML)
+}
+
+func escapeHTML(s string) string {
+	return strings.ReplaceAll(html.EscapeString(s), "\n", "<br>")
+}
+
+// mergeDOCX fills the body, headers and footers of a DOCX file. Other parts
+// are copied as they are.
+func mergeDOCX(src []byte, scope mergeScope, related relatedSet) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(src), int64(len(src)))
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	zw := zip.NewWriter(&out)
+	for _, f := range zr.File {
+		if !wordPartPattern.MatchString(f.Name) {
+			if err := zw.Copy(f); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		part, err := readZipFile(f)
+		if err != nil {
+			return nil, err
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.Name, Method: zip.Deflate, Modified: f.Modified})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(w, mergeWordPart(string(part), scope, related)); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(io.LimitReader(rc, maxDocumentPartSize))
+}
+
+// mergeWordPart fills one WordprocessingML part. A related block opened in
+// a table row repeats the row; one opened elsewhere repeats the paragraphs
+// up to its close tag, leaving out paragraphs holding only a tag.
+func mergeWordPart(xml string, scope mergeScope, related relatedSet) string {
+	xml = joinSplitTags(xml)
+	for {
+		open := blockOpenPattern.FindStringSubmatchIndex(xml)
+		if open == nil {
+			break
+		}
+		key := xml[open[2]:open[3]] + "." + xml[open[4]:open[5]]
+		children := related.scopes(key, scope)
+
+		if row, ok := enclosingElement(xml, "w:tr", open[0]); ok {
+			unit := stripBlockTags(xml[row[0]:row[1]])
+			var b strings.Builder
+			for _, child := range children {
+				b.WriteString(mergeWordText(unit, child))
+			}
+			xml = xml[:row[0]] + b.String() + xml[row[1]:]
+			continue
+		}
+
+		first, ok := enclosingElement(xml, "w:p", open[0])
+		if !ok {
+			xml = xml[:open[0]] + xml[open[1]:]
+			continue
+		}
+		last := first
+		if close := blockClosePattern.FindStringIndex(xml[open[1]:]); close != nil {
+			if p, ok := enclosingElement(xml, "w:p", open[1]+close[0]); ok && balanced(xml[first[0]:p[1]]) {
+				last = p
+			}
+		}
+		start, end := first[0], last[1]
+		if onlyTag(xml[first[0]:first[1]]) {
+			start = first[1]
+		}
+		if last != first && onlyTag(xml[last[0]:last[1]]) {
+			end = last[0]
+		}
+		unit := ""
+		if start < end {
+			unit = stripBlockTags(xml[start:end])
+		}
+		var b strings.Builder
+		for _, child := range children {
+			b.WriteString(mergeWordText(unit, child))
+		}
+		xml = xml[:first[0]] + b.String() + xml[last[1]:]
+	}
+	return mergeWordText(xml, scope)
+}
+
+// mergeWordText fills the fields of WordprocessingML. Line breaks in values
+// become breaks within the run.
+func mergeWordText(xml string, scope mergeScope) string {
+	merged := mergeFields(xml, scope, func(s string) string {
+		var b strings.Builder
+		for i, line := range strings.Split(s, "\n") {
+			if i > 0 {
+				b.WriteString(`</w:t><w:br/><w:t xml:space="preserve">`)
+			}
+			b.WriteString(escapeXML(line))
+		}
+		return b.String()
+	})
+	// Values may start or end with spaces Word would otherwise drop
+	return strings.ReplaceAll(merged, "<w:t>", `<w:t xml:space="preserve">`)
+}
+
+func escapeXML(s string) string {
+	return xmlTextEscaper.Replace(s)
+}
+
+// joinSplitTags moves each merge tag Word split across runs, as it does
+// around spelling marks and formatting changes, into the run it starts in
+func joinSplitTags(xml string) string {
+	texts := wordTextPattern.FindAllStringSubmatchIndex(xml, -1)
+	if len(texts) < 2 {
+		return xml
+	}
+
+	var full strings.Builder
+	owner := make([]int, 0, len(xml)/8) // owner[i] is the run of byte i of full
+	for i, m := range texts {
+		full.WriteString(xml[m[4]:m[5]])
+		for j := m[4]; j < m[5]; j++ {
+			owner = append(owner, i)
+		}
+	}
+	joined := full.String()
+	tags := mergeTagPattern.FindAllStringIndex(joined, -1)
+	split := false
+	for _, t := range tags {
+		if owner[t[0]] != owner[t[1]-1] {
+			split = true
+			for j := t[0]; j < t[1]; j++ {
+				owner[j] = owner[t[0]]
+			}
+		}
+	}
+	if !split {
+		return xml
+	}
+
+	contents := make([]strings.Builder, len(texts))
+	for j := 0; j < len(joined); j++ {
+		contents[owner[j]].WriteByte(joined[j])
+	}
+	var b strings.Builder
+	prev := 0
+	for i, m := range texts {
+		b.WriteString(xml[prev:m[4]])
+		b.WriteString(contents[i].String())
+		prev = m[5]
+	}
+	b.WriteString(xml[prev:])
+	return b.String()
+}
+
+// enclosingElement finds the innermost tag element around pos, such as the
+// w:p or w:tr a merge tag sits in
+func enclosingElement(xml, tag string, pos int) ([2]int, bool) {
+	openTag, closeTag := "<"+tag, "</"+tag+">"
+	var stack []int
+	best := [2]int{-1, -1}
+	for i := 0; i < len(xml); {
+		switch {
+		case strings.HasPrefix(xml[i:], closeTag):
+			if n := len(stack); n > 0 {
+				start, end := stack[n-1], i+len(closeTag)
+				stack = stack[:n-1]
+				if start <= pos && pos < end && (best[0] < 0 || start > best[0]) {
+					best = [2]int{start, end}
+				}
+			}
+			i += len(closeTag)
+		case strings.HasPrefix(xml[i:], openTag) && i+len(openTag) < len(xml) && (xml[i+len(openTag)] == '>' || xml[i+len(openTag)] == ' '):
+			if gt := strings.IndexByte(xml[i:], '>'); gt > 0 && xml[i+gt-1] == '/' {
+				i += gt + 1 // empty element
+				continue
+			}
+			stack = append(stack, i)
+			i += len(openTag)
+		default:
+			if i > pos && len(stack) == 0 {
+				return best, best[0] >= 0
+			}
+			i++
+		}
+	}
+	return best, best[0] >= 0
+}
+
+// onlyTag reports whether the text of a paragraph is a single block tag
+func onlyTag(paragraph string) bool {
+	text := strings.TrimSpace(wordText(paragraph))
+	return blockOpenPattern.MatchString(text) && blockOpenPattern.FindString(text) == text ||
+		blockClosePattern.MatchString(text) && blockClosePattern.FindString(text) == text
+}
+
+// balanced reports whether a run of paragraphs opens and closes the same
+// tables and cells, so it can be repeated as it is
+func balanced(xml string) bool {
+	for _, tag := range []string{"w:tbl", "w:tc"} {
+		if strings.Count(xml, "<"+tag+">")+strings.Count(xml, "<"+tag+" ") != strings.Count(xml, "</"+tag+">") {
+			return false
+		}
+	}
+	return true
+}
+
+func stripBlockTags(s string) string {
+	return blockClosePattern.ReplaceAllString(blockOpenPattern.ReplaceAllString(s, ""), "")
+}
+
+// wordText is the text of WordprocessingML, still XML escaped
+func wordText(xml string) string {
+	var b strings.Builder
+	for _, m := range wordTextPattern.FindAllStringSubmatch(xml, -1) {
+		b.WriteString(m[2])
+	}
+	return b.String()
+}
+
+// templateTags lists the distinct merge tags of a template's text in order
+func templateTags(text string) []string {
+	seen := map[string]bool{}
+	var tags []string
+	for _, tag := range mergeTagPattern.FindAllString(text, -1) {
+		body := strings.Join(strings.Fields(tagBody(tag)), " ")
+		if body != "" && !seen[body] {
+			seen[body] = true
+			tags = append(tags, body)
+		}
+	}
+	return tags
+}
+
+// docxText joins the text of the parts merged in a DOCX file, unescaped
+// enough for its tags to be read
+func docxText(src []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(src), int64(len(src)))
+	if err != nil {
+		return "", errors.New("not a valid DOCX file")
+	}
+	var total uint64
+	found := false
+	var b strings.Builder
+	for _, f := range zr.File {
+		total += f.UncompressedSize64
+		if total > maxDocumentUnpacked {
+			return "", fmt.Errorf("DOCX content exceeds %d MB", maxDocumentUnpacked>>20)
+		}
+		if strings.HasSuffix(f.Name, "vbaProject.bin") {
+			return "", errors.New("macro-enabled documents are not accepted")
+		}
+		if !wordPartPattern.MatchString(f.Name) {
+			continue
+		}
+		found = found || f.Name == "word/document.xml"
+		part, err := readZipFile(f)
+		if err != nil {
+			return "", errors.New("not a valid DOCX file")
+		}
+		b.WriteString(wordText(joinSplitTags(string(part))))
+		b.WriteByte('\n')
+	}
+	if !found {
+		return "", errors.New("not a valid DOCX file: word/document.xml is missing")
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,288 @@
+package print_template
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Filled documents convert to PDF as their text structure: headings,
+// paragraphs, list items and tables. Fonts, colours and images of the
+// original are not carried over.
+
+type blockKind int
+
+const (
+	blockTitle blockKind = iota
+	blockHeading
+	blockParagraph
+	blockTable
+)
+
+type docBlock struct {
+	kind blockKind
+	text string
+	rows [][]string
+}
+
+func renderBlocksPDF(title string, blocks []docBlock) ([]byte, error) {
+	doc := newPDFDocument("A4", title)
+	layout := newPDFLayout(doc, "")
+	for _, b := range blocks {
+		switch b.kind {
+		case blockTitle:
+			layout.title(b.text)
+		case blockHeading:
+			layout.heading(b.text)
+		case blockParagraph:
+			layout.paragraph(b.text)
+		case blockTable:
+			if len(b.rows) == 0 {
+				continue
+			}
+			width := 0
+			for _, row := range b.rows {
+				width = max(width, len(row))
+			}
+			headers := append(b.rows[0], make([]string, width-len(b.rows[0]))...)
+			layout.table(headers, b.rows[1:])
+		}
+	}
+	layout.finish()
+	return doc.bytes()
+}
+
+// htmlBlocks reads the text structure of an HTML document
+func htmlBlocks(src string) ([]docBlock, error) {
+	root, err := nethtml.Parse(strings.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []docBlock
+	var inline strings.Builder
+	flush := func() {
+		if text := collapseSpace(inline.String()); text != "" {
+			blocks = append(blocks, docBlock{kind: blockParagraph, text: text})
+		}
+		inline.Reset()
+	}
+
+	var walk func(n *nethtml.Node)
+	walk = func(n *nethtml.Node) {
+		switch n.Type {
+		case nethtml.TextNode:
+			inline.WriteString(n.Data)
+			return
+		case nethtml.ElementNode:
+			switch n.DataAtom {
+			case atom.Head, atom.Script, atom.Style, atom.Template:
+				return
+			case atom.Br:
+				flush()
+				return
+			case atom.H1:
+				flush()
+				blocks = append(blocks, docBlock{kind: blockTitle, text: collapseSpace(nodeText(n))})
+				return
+			case atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+				flush()
+				blocks = append(blocks, docBlock{kind: blockHeading, text: collapseSpace(nodeText(n))})
+				return
+			case atom.Li:
+				flush()
+				blocks = append(blocks, docBlock{kind: blockParagraph, text: "• " + collapseSpace(nodeText(n))})
+				return
+			case atom.Table:
+				flush()
+				blocks = append(blocks, docBlock{kind: blockTable, rows: htmlRows(n)})
+				return
+			case atom.P, atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer,
+				atom.Blockquote, atom.Pre, atom.Address, atom.Ul, atom.Ol, atom.Hr:
+				flush()
+				defer flush()
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	flush()
+	return blocks, nil
+}
+
+func htmlRows(table *nethtml.Node) [][]string {
+	var rows [][]string
+	var walk func(n *nethtml.Node)
+	walk = func(n *nethtml.Node) {
+		if n.Type == nethtml.ElementNode && n.DataAtom == atom.Tr {
+			var row []string
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == nethtml.ElementNode && (c.DataAtom == atom.Td || c.DataAtom == atom.Th) {
+					row = append(row, collapseSpace(nodeText(c)))
+				}
+			}
+			if len(row) > 0 {
+				rows = append(rows, row)
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(table)
+	return rows
+}
+
+func nodeText(n *nethtml.Node) string {
+	var b strings.Builder
+	var walk func(n *nethtml.Node)
+	walk = func(n *nethtml.Node) {
+		switch {
+		case n.Type == nethtml.TextNode:
+			b.WriteString(n.Data)
+		case n.Type == nethtml.ElementNode && n.DataAtom == atom.Br:
+			b.WriteString(" ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// docxBlocks reads the text structure of a DOCX body. Paragraph styles named
+// Title and Heading mark headings; text of nested tables joins its cell.
+func docxBlocks(src []byte) ([]docBlock, error) {
+	zr, err := zip.NewReader(bytes.NewReader(src), int64(len(src)))
+	if err != nil {
+		return nil, err
+	}
+	var body []byte
+	for _, f := range zr.File {
+		if f.Name == "word/document.xml" {
+			if body, err = readZipFile(f); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if body == nil {
+		return nil, errors.New("word/document.xml is missing")
+	}
+
+	var (
+		blocks   []docBlock
+		depth    int // table nesting
+		rows     [][]string
+		row      []string
+		cell     strings.Builder
+		para     strings.Builder
+		style    string
+		listItem bool
+		inText   bool
+	)
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "tbl":
+				depth++
+				if depth == 1 {
+					rows = nil
+				}
+			case "tr":
+				if depth == 1 {
+					row = nil
+				}
+			case "tc":
+				if depth == 1 {
+					cell.Reset()
+				}
+			case "p":
+				para.Reset()
+				style, listItem = "", false
+			case "pStyle":
+				for _, a := range t.Attr {
+					if a.Name.Local == "val" {
+						style = a.Value
+					}
+				}
+			case "numPr":
+				listItem = true
+			case "t":
+				inText = true
+			case "tab":
+				para.WriteString(" ")
+			case "br", "cr":
+				para.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText {
+				para.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text := strings.TrimSpace(para.String())
+				if depth > 0 {
+					if text != "" {
+						if cell.Len() > 0 {
+							cell.WriteString(" ")
+						}
+						cell.WriteString(collapseSpace(text))
+					}
+					continue
+				}
+				lower := strings.ToLower(style)
+				switch {
+				case text == "":
+				case lower == "title":
+					blocks = append(blocks, docBlock{kind: blockTitle, text: text})
+				case strings.HasPrefix(lower, "heading"):
+					blocks = append(blocks, docBlock{kind: blockHeading, text: text})
+				case listItem:
+					blocks = append(blocks, docBlock{kind: blockParagraph, text: "• " + text})
+				default:
+					blocks = append(blocks, docBlock{kind: blockParagraph, text: text})
+				}
+			case "tc":
+				if depth == 1 {
+					row = append(row, cell.String())
+				}
+			case "tr":
+				if depth == 1 && len(row) > 0 {
+					rows = append(rows, row)
+				}
+			case "tbl":
+				depth--
+				if depth == 0 {
+					blocks = append(blocks, docBlock{kind: blockTable, rows: rows})
+				}
+			}
+		}
+	}
+	return blocks, nil
+}
@@ -0,0 +1,116 @@
+package print_template
+
+import (
+	"context"
+	"time"
+
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type DocumentTemplateRepository interface {
+	Create(ctx context.Context, template *DocumentTemplate) error
+	// Get loads a template with its file content
+	Get(ctx context.Context, id string) (*DocumentTemplate, error)
+	// List returns templates without their file content
+	List(ctx context.Context, moduleName string) ([]DocumentTemplate, error)
+	Update(ctx context.Context, template *DocumentTemplate) error
+	Delete(ctx context.Context, id string) error
+}
+
+type DocumentTemplateRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewDocumentTemplateRepository(db *database.MongodbDB) DocumentTemplateRepository {
+	return &DocumentTemplateRepositoryImpl{
+		collection: db.DB.Collection("document_templates"),
+	}
+}
+
+func (r *DocumentTemplateRepositoryImpl) Create(ctx context.Context, template *DocumentTemplate) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if template.ID.IsZero() {
+		template.ID = primitive.NewObjectID()
+	}
+	template.TenantID = tenantID
+	template.CreatedAt = time.Now()
+	template.UpdatedAt = time.Now()
+
+	_, err = r.collection.InsertOne(ctx, template)
+	return err
+}
+
+func (r *DocumentTemplateRepositoryImpl) Get(ctx context.Context, id string) (*DocumentTemplate, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var template DocumentTemplate
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID}).Decode(&template); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *DocumentTemplateRepositoryImpl) List(ctx context.Context, moduleName string) ([]DocumentTemplate, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{"tenant_id": tenantID}
+	if moduleName != "" {
+		filter["module_name"] = moduleName
+	}
+
+	opts := options.Find().SetSort(bson.M{"name": 1}).SetProjection(bson.M{"content": 0})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	templates := []DocumentTemplate{}
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (r *DocumentTemplateRepositoryImpl) Update(ctx context.Context, template *DocumentTemplate) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	template.TenantID = tenantID
+	template.UpdatedAt = time.Now()
+
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": template.ID, "tenant_id": tenantID}, template)
+	return err
+}
+
+func (r *DocumentTemplateRepositoryImpl) Delete(ctx context.Context, id string) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID})
+	return err
+}
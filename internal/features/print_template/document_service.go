@@ -0,0 +1,387 @@
+package print_template
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/settings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	maxDocumentTemplateSize = 5 << 20
+	maxDocumentUnpacked     = 50 << 20 // all parts of a DOCX, against zip bombs
+	maxDocumentPartSize     = 20 << 20
+)
+
+// systemMergeFields are record attributes and values outside module.Fields
+// that templates may use
+var systemMergeFields = map[string]bool{
+	"_id": true, "id": true, "created_at": true, "updated_at": true,
+	"created_by": true, "owner": true, "today": true, "now": true,
+	record.AgeDaysField: true,
+}
+
+var (
+	ErrDocumentTemplateNotFound = apperr.NotFound("document template not found")
+	ErrModuleNotFound           = apperr.NotFound("module not found")
+)
+
+type DocumentTemplateService interface {
+	CreateDocumentTemplate(ctx context.Context, upload DocumentTemplateUpload, userID primitive.ObjectID) (*DocumentTemplate, error)
+	// GetDocumentTemplate returns a template with its file content
+	GetDocumentTemplate(ctx context.Context, id string) (*DocumentTemplate, error)
+	ListDocumentTemplates(ctx context.Context, moduleName string) ([]DocumentTemplate, error)
+	UpdateDocumentTemplate(ctx context.Context, id string, upload DocumentTemplateUpload) (*DocumentTemplate, error)
+	DeleteDocumentTemplate(ctx context.Context, id string) error
+	// RenderDocument fills a template with one record and its related records
+	// as the user sees them. DOCX templates render to docx or pdf, HTML
+	// templates to html or pdf; an empty format is the template's own.
+	RenderDocument(ctx context.Context, moduleName, recordID, templateID string, format DocumentFormat, userID primitive.ObjectID) (data []byte, filename, contentType string, err error)
+}
+
+type DocumentTemplateServiceImpl struct {
+	Repo            DocumentTemplateRepository
+	ModuleRepo      module.ModuleRepository
+	RecordService   record.RecordService
+	AuditService    audit.AuditService
+	SettingsService settings.SettingsService
+}
+
+func NewDocumentTemplateService(
+	repo DocumentTemplateRepository,
+	moduleRepo module.ModuleRepository,
+	recordService record.RecordService,
+	auditService audit.AuditService,
+	settingsService settings.SettingsService,
+) DocumentTemplateService {
+	return &DocumentTemplateServiceImpl{
+		Repo:            repo,
+		ModuleRepo:      moduleRepo,
+		RecordService:   recordService,
+		AuditService:    auditService,
+		SettingsService: settingsService,
+	}
+}
+
+func (s *DocumentTemplateServiceImpl) CreateDocumentTemplate(ctx context.Context, upload DocumentTemplateUpload, userID primitive.ObjectID) (*DocumentTemplate, error) {
+	if len(upload.Content) == 0 {
+		return nil, apperr.Validation("a template file is required")
+	}
+	tmpl := &DocumentTemplate{
+		Name:        strings.TrimSpace(upload.Name),
+		ModuleName:  upload.ModuleName,
+		Description: upload.Description,
+		CreatedBy:   userID,
+	}
+	if err := s.applyUpload(ctx, tmpl, upload); err != nil {
+		return nil, err
+	}
+	if err := s.Repo.Create(ctx, tmpl); err != nil {
+		return nil, err
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionTemplate, "document_templates", tmpl.ID.Hex(), map[string]common_models.Change{
+		"name":      {New: tmpl.Name},
+		"module":    {New: tmpl.ModuleName},
+		"file_name": {New: tmpl.FileName},
+	})
+	return tmpl, nil
+}
+
+func (s *DocumentTemplateServiceImpl) GetDocumentTemplate(ctx context.Context, id string) (*DocumentTemplate, error) {
+	tmpl, err := s.Repo.Get(ctx, id)
+	if err != nil {
+		return nil, ErrDocumentTemplateNotFound
+	}
+	return tmpl, nil
+}
+
+func (s *DocumentTemplateServiceImpl) ListDocumentTemplates(ctx context.Context, moduleName string) ([]DocumentTemplate, error) {
+	return s.Repo.List(ctx, moduleName)
+}
+
+func (s *DocumentTemplateServiceImpl) UpdateDocumentTemplate(ctx context.Context, id string, upload DocumentTemplateUpload) (*DocumentTemplate, error) {
+	tmpl, err := s.Repo.Get(ctx, id)
+	if err != nil {
+		return nil, ErrDocumentTemplateNotFound
+	}
+	old := *tmpl
+
+	if name := strings.TrimSpace(upload.Name); name != "" {
+		tmpl.Name = name
+	}
+	if upload.ModuleName != "" {
+		tmpl.ModuleName = upload.ModuleName
+	}
+	tmpl.Description = upload.Description
+	if len(upload.Content) == 0 {
+		// The stored file is checked again in case the module changed
+		upload.FileName, upload.Content = tmpl.FileName, tmpl.Content
+	}
+	if err := s.applyUpload(ctx, tmpl, upload); err != nil {
+		return nil, err
+	}
+	if err := s.Repo.Update(ctx, tmpl); err != nil {
+		return nil, err
+	}
+
+	changes := map[string]common_models.Change{}
+	if old.Name != tmpl.Name {
+		changes["name"] = common_models.Change{Old: old.Name, New: tmpl.Name}
+	}
+	if old.ModuleName != tmpl.ModuleName {
+		changes["module"] = common_models.Change{Old: old.ModuleName, New: tmpl.ModuleName}
+	}
+	if old.Size != tmpl.Size || old.FileName != tmpl.FileName {
+		changes["file_name"] = common_models.Change{Old: old.FileName, New: tmpl.FileName}
+	}
+	if len(changes) > 0 {
+		_ = s.AuditService.LogChange(ctx, common_models.AuditActionTemplate, "document_templates", tmpl.ID.Hex(), changes)
+	}
+	return tmpl, nil
+}
+
+func (s *DocumentTemplateServiceImpl) DeleteDocumentTemplate(ctx context.Context, id string) error {
+	old, err := s.Repo.Get(ctx, id)
+	if err != nil {
+		return ErrDocumentTemplateNotFound
+	}
+	if err := s.Repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionTemplate, "document_templates", old.ID.Hex(), map[string]common_models.Change{
+		"name": {Old: old.Name, New: "DELETED"},
+	})
+	return nil
+}
+
+// applyUpload checks the file of upload against the template's module and
+// stores it on tmpl with the merge fields it uses
+func (s *DocumentTemplateServiceImpl) applyUpload(ctx context.Context, tmpl *DocumentTemplate, upload DocumentTemplateUpload) error {
+	if tmpl.Name == "" {
+		return apperr.Validation("template name is required")
+	}
+	mod, err := s.ModuleRepo.FindByName(ctx, tmpl.ModuleName)
+	if err != nil || mod == nil {
+		return apperr.Validation("invalid module name specified")
+	}
+	if len(upload.Content) > maxDocumentTemplateSize {
+		return apperr.Validation("template files are limited to %d MB", maxDocumentTemplateSize>>20)
+	}
+
+	var text string
+	switch strings.ToLower(filepath.Ext(upload.FileName)) {
+	case ".docx":
+		tmpl.Format = DocumentDOCX
+		if text, err = docxText(upload.Content); err != nil {
+			return apperr.Validation("%v", err)
+		}
+	case ".html", ".htm":
+		tmpl.Format = DocumentHTML
+		if !utf8.Valid(upload.Content) {
+			return apperr.Validation("HTML templates must be UTF-8 encoded")
+		}
+		text = string(upload.Content)
+	default:
+		return apperr.Validation("templates must be .docx or .html files")
+	}
+
+	if err := s.checkMergeFields(ctx, mod, text); err != nil {
+		return err
+	}
+	tmpl.FileName = filepath.Base(upload.FileName)
+	tmpl.Content = upload.Content
+	tmpl.Size = int64(len(upload.Content))
+	tmpl.MergeFields = templateTags(text)
+	return nil
+}
+
+// checkMergeFields rejects fields the module does not have, related blocks
+// that do not point at the module and unbalanced blocks
+func (s *DocumentTemplateServiceImpl) checkMergeFields(ctx context.Context, mod *common_models.Entity, text string) error {
+	scope := mod
+	inBlock := false
+	for _, tag := range mergeTagPattern.FindAllString(text, -1) {
+		body := tagBody(tag)
+		switch {
+		case blockOpenPattern.MatchString(tag):
+			if inBlock {
+				return apperr.Validation("related blocks cannot nest: close the block before '%s'", body)
+			}
+			m := blockOpenPattern.FindStringSubmatch(tag)
+			related, err := s.ModuleRepo.FindByName(ctx, m[1])
+			if err != nil || related == nil {
+				return apperr.Validation("unknown related module '%s' in '%s'", m[1], body)
+			}
+			ref := findField(related, m[2])
+			if ref == nil || ref.Type != common_models.FieldTypeLookup || ref.Lookup == nil || ref.Lookup.LookupModule != mod.Name {
+				return apperr.Validation("'%s.%s' must be a lookup to %s", m[1], m[2], mod.Name)
+			}
+			scope, inBlock = related, true
+		case blockClosePattern.MatchString(tag):
+			if !inBlock {
+				return apperr.Validation("'%s' has no matching #related", body)
+			}
+			scope, inBlock = mod, false
+		case strings.HasPrefix(body, "#") || strings.HasPrefix(body, "/"):
+			return apperr.Validation("unknown block '%s'; blocks are #related module.field and /related", body)
+		default:
+			if err := s.checkMergeField(ctx, scope, body); err != nil {
+				return err
+			}
+		}
+	}
+	if inBlock {
+		return apperr.Validation("a #related block is not closed with {{/related}}")
+	}
+	return nil
+}
+
+func (s *DocumentTemplateServiceImpl) checkMergeField(ctx context.Context, mod *common_models.Entity, path string) error {
+	parts := strings.Split(path, ".")
+	if systemMergeFields[parts[0]] && len(parts) == 1 {
+		return nil
+	}
+	field := findField(mod, parts[0])
+	if field == nil {
+		return apperr.Validation("unknown merge field '%s' for %s", path, mod.Name)
+	}
+	if len(parts) == 1 {
+		return nil
+	}
+	if field.Type != common_models.FieldTypeLookup || field.Lookup == nil {
+		return apperr.Validation("merge field '%s': %s is not a lookup", path, parts[0])
+	}
+	target, err := s.ModuleRepo.FindByName(ctx, field.Lookup.LookupModule)
+	if err != nil || target == nil || (findField(target, parts[1]) == nil && !systemMergeFields[parts[1]]) {
+		return apperr.Validation("unknown merge field '%s'", path)
+	}
+	return nil
+}
+
+func (s *DocumentTemplateServiceImpl) RenderDocument(ctx context.Context, moduleName, recordID, templateID string, format DocumentFormat, userID primitive.ObjectID) ([]byte, string, string, error) {
+	mod, err := s.ModuleRepo.FindByName(ctx, moduleName)
+	if err != nil || mod == nil {
+		return nil, "", "", ErrModuleNotFound
+	}
+	tmpl, err := s.Repo.Get(ctx, templateID)
+	if err != nil {
+		return nil, "", "", ErrDocumentTemplateNotFound
+	}
+	if tmpl.ModuleName != mod.Name {
+		return nil, "", "", apperr.BadRequest("template belongs to module %s", tmpl.ModuleName)
+	}
+	if format == "" {
+		format = tmpl.Format
+	}
+	if format != tmpl.Format && format != DocumentPDF {
+		return nil, "", "", apperr.BadRequest("%s templates render to %s or pdf", tmpl.Format, tmpl.Format)
+	}
+
+	// GetRecord and ListRecords apply record access, field permissions and
+	// lookup population
+	rec, err := s.RecordService.GetRecord(ctx, moduleName, recordID, userID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	scope := mergeScope{mod: mod, rec: rec, lf: s.SettingsService.Formatter(ctx, userID.Hex()), now: time.Now()}
+
+	var source string
+	if tmpl.Format == DocumentDOCX {
+		if source, err = docxText(tmpl.Content); err != nil {
+			return nil, "", "", err
+		}
+	} else {
+		source = string(tmpl.Content)
+	}
+	related, err := s.relatedRecords(ctx, source, recordID, userID)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	var blocks []docBlock
+	base := documentFilename(tmpl.Name, recordID)
+	switch tmpl.Format {
+	case DocumentDOCX:
+		filled, err := mergeDOCX(tmpl.Content, scope, related)
+		if err != nil {
+			return nil, "", "", err
+		}
+		if format == DocumentDOCX {
+			return filled, base + ".docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.document", nil
+		}
+		if blocks, err = docxBlocks(filled); err != nil {
+			return nil, "", "", err
+		}
+	default:
+		filled := mergeHTML(source, scope, related)
+		if format == DocumentHTML {
+			return []byte(filled), base + ".html", "text/html; charset=utf-8", nil
+		}
+		if blocks, err = htmlBlocks(filled); err != nil {
+			return nil, "", "", err
+		}
+	}
+
+	data, err := renderBlocksPDF(tmpl.Name, blocks)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return data, base + ".pdf", "application/pdf", nil
+}
+
+// relatedRecords loads the children of each related block of a template
+func (s *DocumentTemplateServiceImpl) relatedRecords(ctx context.Context, source, recordID string, userID primitive.ObjectID) (relatedSet, error) {
+	related := relatedSet{}
+	for _, m := range blockOpenPattern.FindAllStringSubmatch(source, -1) {
+		key := m[1] + "." + m[2]
+		if _, ok := related[key]; ok {
+			continue
+		}
+		mod, err := s.ModuleRepo.FindByName(ctx, m[1])
+		if err != nil || mod == nil {
+			return nil, fmt.Errorf("related module %s not found", m[1])
+		}
+		filters := []common_models.Filter{{Field: m[2], Operator: "eq", Value: recordID}}
+		records, _, err := s.RecordService.ListRecords(ctx, m[1], filters, 1, maxRelatedLimit, "created_at", "asc", userID)
+		if err != nil {
+			return nil, err
+		}
+		related[key] = relatedRecords{mod: mod, records: records}
+	}
+	return related, nil
+}
+
+// documentFilename is the template name made safe for a file name, with the
+// record ID
+func documentFilename(name, recordID string) string {
+	slug := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		}
+		return '_'
+	}, strings.TrimSpace(name))
+	for strings.Contains(slug, "__") {
+		slug = strings.ReplaceAll(slug, "__", "_")
+	}
+	slug = strings.Trim(slug, "_")
+	if slug == "" {
+		slug = "document"
+	}
+	return slug + "_" + recordID
+}
@@ -0,0 +1,182 @@
+package print_template
+
+import (
+	"fmt"
+	"image"
+	"strings"
+)
+
+const (
+	pageMargin   = 48.0
+	footerHeight = 24.0
+	bodySize     = 10.0
+	lineGap      = 1.35
+)
+
+// pdfLayout flows content top to bottom, starting new pages as needed
+type pdfLayout struct {
+	doc    *pdfDocument
+	y      float64
+	footer string
+}
+
+func newPDFLayout(doc *pdfDocument, footer string) *pdfLayout {
+	l := &pdfLayout{doc: doc, footer: footer}
+	l.newPage()
+	return l
+}
+
+func (l *pdfLayout) width() float64 {
+	return l.doc.width - 2*pageMargin
+}
+
+func (l *pdfLayout) newPage() {
+	l.doc.addPage()
+	l.y = pageMargin
+}
+
+// ensure starts a new page unless h points of content still fit
+func (l *pdfLayout) ensure(h float64) {
+	if l.y+h > l.doc.height-pageMargin-footerHeight {
+		l.newPage()
+	}
+}
+
+func (l *pdfLayout) title(s string) {
+	for _, line := range wrapText(s, fontBold, 18, l.width()) {
+		l.ensure(24)
+		l.y += 18
+		l.doc.text(pageMargin, l.y, fontBold, 18, line)
+		l.y += 6
+	}
+	l.y += 8
+}
+
+func (l *pdfLayout) heading(s string) {
+	l.ensure(40)
+	l.y += 12
+	l.doc.text(pageMargin, l.y, fontBold, 12, s)
+	l.y += 5
+	l.doc.line(pageMargin, l.y, pageMargin+l.width(), l.y, 0.6)
+	l.y += 8
+}
+
+func (l *pdfLayout) paragraph(s string) {
+	lh := bodySize * lineGap
+	for _, line := range wrapText(s, fontRegular, bodySize, l.width()) {
+		l.ensure(lh)
+		l.y += lh
+		l.doc.text(pageMargin, l.y, fontRegular, bodySize, line)
+	}
+	l.y += 6
+}
+
+// keyValues renders label/value pairs as a two-column list
+func (l *pdfLayout) keyValues(pairs [][2]string) {
+	lh := bodySize * lineGap
+	labelW := l.width() * 0.32
+	valueX := pageMargin + labelW + 8
+	valueW := l.width() - labelW - 8
+
+	for _, kv := range pairs {
+		labels := wrapText(kv[0], fontBold, bodySize, labelW)
+		values := wrapText(kv[1], fontRegular, bodySize, valueW)
+		rows := max(len(labels), len(values))
+		l.ensure(float64(min(rows, 3)) * lh)
+
+		for i := 0; i < rows; i++ {
+			l.ensure(lh)
+			l.y += lh
+			if i < len(labels) {
+				l.doc.text(pageMargin, l.y, fontBold, bodySize, labels[i])
+			}
+			if i < len(values) {
+				l.doc.text(valueX, l.y, fontRegular, bodySize, values[i])
+			}
+		}
+		l.y += 3
+	}
+	l.y += 4
+}
+
+// table renders rows with equal-width columns; the header repeats on each page
+func (l *pdfLayout) table(headers []string, rows [][]string) {
+	if len(headers) == 0 {
+		return
+	}
+	const size = 9.0
+	const pad = 3.0
+	lh := size * lineGap
+	colW := l.width() / float64(len(headers))
+
+	drawRow := func(cells []string, font string, shade bool) {
+		wrapped := make([][]string, len(headers))
+		lines := 1
+		for i := range headers {
+			if i < len(cells) {
+				wrapped[i] = wrapText(cells[i], font, size, colW-2*pad)
+			}
+			lines = max(lines, len(wrapped[i]))
+		}
+		h := float64(lines)*lh + 2*pad
+		if shade {
+			l.doc.fillRect(pageMargin, l.y, l.width(), h, 0.92)
+		}
+		for i, cell := range wrapped {
+			for j, line := range cell {
+				l.doc.text(pageMargin+float64(i)*colW+pad, l.y+pad+float64(j+1)*lh-2, font, size, line)
+			}
+		}
+		l.y += h
+		l.doc.line(pageMargin, l.y, pageMargin+l.width(), l.y, 0.8)
+	}
+
+	l.ensure(2 * (lh + 2*pad))
+	drawRow(headers, fontBold, true)
+	for _, row := range rows {
+		before := len(l.doc.pages)
+		l.ensure(lh + 2*pad)
+		if len(l.doc.pages) != before {
+			drawRow(headers, fontBold, true)
+		}
+		drawRow(row, fontRegular, false)
+	}
+	l.y += 10
+}
+
+// figure draws img at the content width, or smaller to fit a page, under
+// its caption
+func (l *pdfLayout) figure(caption string, img image.Image) {
+	b := img.Bounds()
+	if b.Dx() == 0 || b.Dy() == 0 {
+		return
+	}
+	w := l.width()
+	h := w * float64(b.Dy()) / float64(b.Dx())
+	if room := l.doc.height - 2*pageMargin - footerHeight - 24; h > room {
+		w, h = w*room/h, room
+	}
+	l.ensure(h + 24)
+	if caption != "" {
+		l.y += 12
+		l.doc.text(pageMargin, l.y, fontBold, 11, caption)
+		l.y += 6
+	}
+	l.doc.image(pageMargin, l.y, w, h, img)
+	l.y += h + 12
+}
+
+// finish stamps the footer and page numbers once the page count is known
+func (l *pdfLayout) finish() {
+	total := len(l.doc.pages)
+	y := l.doc.height - pageMargin + 12
+	for i, page := range l.doc.pages {
+		label := fmt.Sprintf("Page %d of %d", i+1, total)
+		fmt.Fprintf(page, "BT /%s 8 Tf %.2f %.2f Td (%s) Tj ET\n", fontRegular,
+			pageMargin+l.width()-textWidth(label, fontRegular, 8), l.doc.height-y, pdfEscape(label))
+		if footer := strings.TrimSpace(l.footer); footer != "" {
+			fmt.Fprintf(page, "BT /%s 8 Tf %.2f %.2f Td (%s) Tj ET\n", fontRegular,
+				pageMargin, l.doc.height-y, pdfEscape(footer))
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package print_template
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SectionType string
+
+const (
+	SectionFields  SectionType = "fields"  // label/value list of record fields
+	SectionText    SectionType = "text"    // free text with {{field}} placeholders
+	SectionRelated SectionType = "related" // table of child records pointing at this record
+)
+
+type PrintSection struct {
+	Type    SectionType `json:"type" bson:"type"`
+	Heading string      `json:"heading,omitempty" bson:"heading,omitempty"`
+	// Fields lists the fields to print; empty prints every visible field
+	Fields []string `json:"fields,omitempty" bson:"fields,omitempty"`
+	Text   string   `json:"text,omitempty" bson:"text,omitempty"`
+	// RelatedModule/RelatedField select children whose lookup RelatedField references the record
+	RelatedModule string   `json:"related_module,omitempty" bson:"related_module,omitempty"`
+	RelatedField  string   `json:"related_field,omitempty" bson:"related_field,omitempty"`
+	Columns       []string `json:"columns,omitempty" bson:"columns,omitempty"`
+	Limit         int64    `json:"limit,omitempty" bson:"limit,omitempty"`
+}
+
+type PrintTemplate struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID   primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	Name       string             `json:"name" bson:"name"`
+	ModuleName string             `json:"module_name" bson:"module_name"`
+	Title      string             `json:"title" bson:"title"`   // supports {{field}} placeholders
+	Footer     string             `json:"footer" bson:"footer"` // supports {{field}} placeholders
+	PageSize   string             `json:"page_size" bson:"page_size"`
+	Sections   []PrintSection     `json:"sections" bson:"sections"`
+	IsDefault  bool               `json:"is_default" bson:"is_default"`
+	CreatedBy  primitive.ObjectID `json:"created_by" bson:"created_by"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// EmailPDFRequest emails a rendered record snapshot as an attachment
+type EmailPDFRequest struct {
+	TemplateID string   `json:"template_id"`
+	To         []string `json:"to"`
+	Subject    string   `json:"subject"`
+	Body       string   `json:"body"`
+}
+
+type DocumentFormat string
+
+const (
+	DocumentDOCX DocumentFormat = "docx"
+	DocumentHTML DocumentFormat = "html"
+	DocumentPDF  DocumentFormat = "pdf"
+)
+
+// DocumentTemplate is an uploaded DOCX or HTML file with merge fields:
+// {{field}}, {{lookup.field}} and {{#related module.field}}...{{/related}}
+// blocks repeated for each child record whose lookup field points at the
+// record. In DOCX a block opened in a table row repeats that row.
+type DocumentTemplate struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID    primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	Name        string             `json:"name" bson:"name"`
+	ModuleName  string             `json:"module_name" bson:"module_name"`
+	Description string             `json:"description,omitempty" bson:"description,omitempty"`
+	Format      DocumentFormat     `json:"format" bson:"format"`
+	FileName    string             `json:"file_name" bson:"file_name"`
+	Size        int64              `json:"size" bson:"size"`
+	Content     []byte             `json:"-" bson:"content,omitempty"`
+	// MergeFields lists the placeholders found in the file, for the editor
+	MergeFields []string           `json:"merge_fields" bson:"merge_fields"`
+	CreatedBy   primitive.ObjectID `json:"created_by" bson:"created_by"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// DocumentTemplateUpload is a template create or update as submitted. An
+// update without Content keeps the stored file.
+type DocumentTemplateUpload struct {
+	Name        string
+	ModuleName  string
+	Description string
+	FileName    string
+	Content     []byte
+}
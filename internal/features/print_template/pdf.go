@@ -0,0 +1,404 @@
+package print_template
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"image"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A deliberately small PDF 1.4 writer: built-in Helvetica fonts with
+// WinAnsiEncoding, text, rules, filled rectangles and RGB images. That covers
+// record snapshots without pulling a PDF dependency into the build.
+
+const (
+	fontRegular = "F1"
+	fontBold    = "F2"
+)
+
+var pageSizes = map[string][2]float64{
+	"A4":     {595.28, 841.89},
+	"LETTER": {612, 792},
+}
+
+type pdfDocument struct {
+	width, height float64
+	title         string
+	pages         []*bytes.Buffer
+	images        []pdfImage
+}
+
+// pdfImage is an image XObject and the page that draws it
+type pdfImage struct {
+	page          int
+	width, height int
+	rgb           []byte
+}
+
+func newPDFDocument(pageSize, title string) *pdfDocument {
+	size, ok := pageSizes[strings.ToUpper(pageSize)]
+	if !ok {
+		size = pageSizes["A4"]
+	}
+	return &pdfDocument{width: size[0], height: size[1], title: title}
+}
+
+func (d *pdfDocument) addPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+func (d *pdfDocument) page() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		d.addPage()
+	}
+	return d.pages[len(d.pages)-1]
+}
+
+// text draws s with its baseline at (x, y), measured from the top-left corner
+func (d *pdfDocument) text(x, y float64, font string, size float64, s string) {
+	fmt.Fprintf(d.page(), "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, d.height-y, pdfEscape(s))
+}
+
+func (d *pdfDocument) line(x1, y1, x2, y2, gray float64) {
+	fmt.Fprintf(d.page(), "%.2f G 0.5 w %.2f %.2f m %.2f %.2f l S 0 G\n", gray, x1, d.height-y1, x2, d.height-y2)
+}
+
+func (d *pdfDocument) fillRect(x, y, w, h, gray float64) {
+	fmt.Fprintf(d.page(), "%.2f g %.2f %.2f %.2f %.2f re f 0 g\n", gray, x, d.height-y-h, w, h)
+}
+
+// image draws img scaled into the w by h box whose top-left corner is (x, y).
+// Transparency is dropped.
+func (d *pdfDocument) image(x, y, w, h float64, img image.Image) {
+	b := img.Bounds()
+	rgb := make([]byte, 0, b.Dx()*b.Dy()*3)
+	for py := b.Min.Y; py < b.Max.Y; py++ {
+		for px := b.Min.X; px < b.Max.X; px++ {
+			r, g, bl, _ := img.At(px, py).RGBA()
+			rgb = append(rgb, byte(r>>8), byte(g>>8), byte(bl>>8))
+		}
+	}
+	d.page()
+	d.images = append(d.images, pdfImage{page: len(d.pages) - 1, width: b.Dx(), height: b.Dy(), rgb: rgb})
+	fmt.Fprintf(d.page(), "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", w, h, x, d.height-y-h, len(d.images))
+}
+
+func deflate(data []byte) ([]byte, error) {
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return z.Bytes(), nil
+}
+
+// bytes serializes the document with Flate-compressed page streams
+func (d *pdfDocument) bytes() ([]byte, error) {
+	if len(d.pages) == 0 {
+		d.addPage()
+	}
+
+	var out bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Fixed objects: 1 catalog, 2 page tree, 3-4 fonts, 5 info; pages follow
+	// in pairs, then the images
+	const firstPage = 6
+	firstImage := firstPage + len(d.pages)*2
+	kids := make([]string, len(d.pages))
+	xobjects := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+i*2)
+	}
+	for i, img := range d.images {
+		xobjects[img.page] += fmt.Sprintf(" /Im%d %d 0 R", i+1, firstImage+i)
+	}
+
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	obj(fmt.Sprintf("<< /Title (%s) /Producer (go-crm) /CreationDate (D:%s) >>", pdfEscape(d.title), time.Now().UTC().Format("20060102150405Z")))
+
+	for i, content := range d.pages {
+		resources := "/Font << /F1 3 0 R /F2 4 0 R >>"
+		if xobjects[i] != "" {
+			resources += " /XObject <<" + xobjects[i] + " >>"
+		}
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << %s >> /Contents %d 0 R >>",
+			d.width, d.height, resources, firstPage+i*2+1))
+
+		z, err := deflate(content.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", len(offsets), len(z))
+		out.Write(z)
+		out.WriteString("\nendstream\nendobj\n")
+	}
+
+	for _, img := range d.images {
+		z, err := deflate(img.rgb)
+		if err != nil {
+			return nil, err
+		}
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Length %d /Filter /FlateDecode >>\nstream\n",
+			len(offsets), img.width, img.height, len(z))
+		out.Write(z)
+		out.WriteString("\nendstream\nendobj\n")
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.Bytes(), nil
+}
+
+// pdfEscape converts UTF-8 to WinAnsi bytes and escapes string delimiters.
+// Characters outside WinAnsi are replaced with '?'.
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		c, ok := winAnsi(r)
+		if !ok {
+			c = '?'
+		}
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n', '\r', '\t':
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+var winAnsiSpecials = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91,
+	'’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98,
+	'™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+func winAnsi(r rune) (byte, bool) {
+	switch {
+	case r >= 0x20 && r < 0x7F, r >= 0xA0 && r <= 0xFF:
+		return byte(r), true
+	case r == '\n' || r == '\r' || r == '\t':
+		return byte(r), true
+	}
+	c, ok := winAnsiSpecials[r]
+	return c, ok
+}
+
+// Advance widths (1/1000 em) for ASCII 32-126, from the standard Helvetica AFMs
+var (
+	helveticaWidths = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	helveticaBoldWidths = [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+func textWidth(s, font string, size float64) float64 {
+	widths := &helveticaWidths
+	if font == fontBold {
+		widths = &helveticaBoldWidths
+	}
+	total := 0
+	for _, r := range s {
+		if r >= 32 && r <= 126 {
+			total += widths[r-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// wrapText breaks s into lines no wider than width, splitting long words if needed
+func wrapText(s, font string, size, width float64) []string {
+	var lines []string
+	for _, para := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		words := strings.Fields(para)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+		cur := ""
+		for _, w := range words {
+			for textWidth(w, font, size) > width {
+				// Hard-break words that cannot fit on a line by themselves
+				cut := len([]rune(w))
+				for cut > 1 && textWidth(string([]rune(w)[:cut]), font, size) > width {
+					cut--
+				}
+				if cur != "" {
+					lines = append(lines, cur)
+					cur = ""
+				}
+				lines = append(lines, string([]rune(w)[:cut]))
+				w = string([]rune(w)[cut:])
+			}
+			if cur == "" {
+				cur = w
+			} else if textWidth(cur+" "+w, font, size) <= width {
+				cur += " " + w
+			} else {
+				lines = append(lines, cur)
+				cur = w
+			}
+		}
+		if cur != "" {
+			lines = append(lines, cur)
+		}
+	}
+	return lines
+}
+
+// SummarySection is a heading with label/value rows, used for appended pages
+type SummarySection struct {
+	Heading string
+	Pairs   [][2]string
+}
+
+// Figure is a captioned image, such as a rendered chart
+type Figure struct {
+	Caption string
+	Image   image.Image
+}
+
+// RenderSummaryPDF lays out label/value sections followed by figures, for
+// features that mail PDFs without a print template of their own
+func RenderSummaryPDF(title, footer string, sections []SummarySection, figures []Figure) ([]byte, error) {
+	doc := newPDFDocument("A4", title)
+	layout := newPDFLayout(doc, footer)
+	layout.title(title)
+	for _, section := range sections {
+		if section.Heading != "" {
+			layout.heading(section.Heading)
+		}
+		layout.keyValues(section.Pairs)
+	}
+	for _, f := range figures {
+		layout.figure(f.Caption, f.Image)
+	}
+	layout.finish()
+	return doc.bytes()
+}
+
+var (
+	pagesObjPattern = regexp.MustCompile(`2 0 obj\n<< /Type /Pages /Kids \[([0-9 R]*)\] /Count (\d+) >>`)
+	mediaBoxPattern = regexp.MustCompile(`/MediaBox \[0 0 ([0-9.]+) ([0-9.]+)\]`)
+	sizePattern     = regexp.MustCompile(`/Size (\d+)`)
+	startxrefRegexp = regexp.MustCompile(`startxref\n(\d+)\n`)
+)
+
+// AppendSummaryPage adds pages to a PDF produced by this package using an
+// incremental update. The original bytes are kept verbatim, so a hash taken
+// before the update still verifies the first part of the file.
+func AppendSummaryPage(original []byte, title string, sections []SummarySection) ([]byte, error) {
+	pagesMatches := pagesObjPattern.FindAllSubmatch(original, -1)
+	sizeMatches := sizePattern.FindAllSubmatch(original, -1)
+	xrefMatches := startxrefRegexp.FindAllSubmatch(original, -1)
+	box := mediaBoxPattern.FindSubmatch(original)
+	if len(pagesMatches) == 0 || len(sizeMatches) == 0 || len(xrefMatches) == 0 || box == nil {
+		return nil, errors.New("unsupported PDF: not generated by print templates")
+	}
+	kids := string(pagesMatches[len(pagesMatches)-1][1])
+	count, _ := strconv.Atoi(string(pagesMatches[len(pagesMatches)-1][2]))
+	size, _ := strconv.Atoi(string(sizeMatches[len(sizeMatches)-1][1]))
+	prevXref := string(xrefMatches[len(xrefMatches)-1][1])
+	width, _ := strconv.ParseFloat(string(box[1]), 64)
+	height, _ := strconv.ParseFloat(string(box[2]), 64)
+
+	doc := &pdfDocument{width: width, height: height}
+	layout := &pdfLayout{doc: doc}
+	layout.newPage()
+	layout.title(title)
+	for _, section := range sections {
+		if section.Heading != "" {
+			layout.heading(section.Heading)
+		}
+		layout.keyValues(section.Pairs)
+	}
+
+	var out bytes.Buffer
+	out.Write(original)
+	if !bytes.HasSuffix(original, []byte("\n")) {
+		out.WriteByte('\n')
+	}
+
+	type entry struct{ num, off int }
+	var entries []entry
+	next := size
+	for _, content := range doc.pages {
+		pageNum, contentNum := next, next+1
+		next += 2
+		kids += fmt.Sprintf(" %d 0 R", pageNum)
+		count++
+
+		entries = append(entries, entry{pageNum, out.Len()})
+		fmt.Fprintf(&out, "%d 0 obj\n<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>\nendobj\n",
+			pageNum, width, height, contentNum)
+
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		if _, err := zw.Write(content.Bytes()); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{contentNum, out.Len()})
+		fmt.Fprintf(&out, "%d 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", contentNum, z.Len())
+		out.Write(z.Bytes())
+		out.WriteString("\nendstream\nendobj\n")
+	}
+
+	pagesOff := out.Len()
+	fmt.Fprintf(&out, "2 0 obj\n<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.TrimSpace(kids), count)
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n2 1\n%010d 00000 n \n%d %d\n", pagesOff, size, len(entries))
+	for _, e := range entries {
+		fmt.Fprintf(&out, "%010d 00000 n \n", e.off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R /Prev %s >>\nstartxref\n%d\n%%%%EOF\n", next, prevXref, xref)
+
+	return out.Bytes(), nil
+}
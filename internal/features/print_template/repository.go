@@ -0,0 +1,152 @@
+package print_template
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type PrintTemplateRepository interface {
+	Create(ctx context.Context, template *PrintTemplate) error
+	Get(ctx context.Context, id string) (*PrintTemplate, error)
+	GetDefault(ctx context.Context, moduleName string) (*PrintTemplate, error)
+	List(ctx context.Context, moduleName string) ([]PrintTemplate, error)
+	Update(ctx context.Context, template *PrintTemplate) error
+	Delete(ctx context.Context, id string) error
+	ClearDefault(ctx context.Context, moduleName string, except primitive.ObjectID) error
+}
+
+type PrintTemplateRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewPrintTemplateRepository(db *database.MongodbDB) PrintTemplateRepository {
+	return &PrintTemplateRepositoryImpl{
+		collection: db.DB.Collection("print_templates"),
+	}
+}
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+func (r *PrintTemplateRepositoryImpl) Create(ctx context.Context, template *PrintTemplate) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if template.ID.IsZero() {
+		template.ID = primitive.NewObjectID()
+	}
+	template.TenantID = tenantID
+	template.CreatedAt = time.Now()
+	template.UpdatedAt = time.Now()
+
+	_, err = r.collection.InsertOne(ctx, template)
+	return err
+}
+
+func (r *PrintTemplateRepositoryImpl) Get(ctx context.Context, id string) (*PrintTemplate, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var template PrintTemplate
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID}).Decode(&template); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *PrintTemplateRepositoryImpl) GetDefault(ctx context.Context, moduleName string) (*PrintTemplate, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var template PrintTemplate
+	err = r.collection.FindOne(ctx, bson.M{"tenant_id": tenantID, "module_name": moduleName, "is_default": true}).Decode(&template)
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *PrintTemplateRepositoryImpl) List(ctx context.Context, moduleName string) ([]PrintTemplate, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{"tenant_id": tenantID}
+	if moduleName != "" {
+		filter["module_name"] = moduleName
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	templates := []PrintTemplate{}
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (r *PrintTemplateRepositoryImpl) Update(ctx context.Context, template *PrintTemplate) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	template.TenantID = tenantID
+	template.UpdatedAt = time.Now()
+
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": template.ID, "tenant_id": tenantID}, template)
+	return err
+}
+
+func (r *PrintTemplateRepositoryImpl) Delete(ctx context.Context, id string) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID})
+	return err
+}
+
+func (r *PrintTemplateRepositoryImpl) ClearDefault(ctx context.Context, moduleName string, except primitive.ObjectID) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.UpdateMany(ctx,
+		bson.M{"tenant_id": tenantID, "module_name": moduleName, "_id": bson.M{"$ne": except}},
+		bson.M{"$set": bson.M{"is_default": false}},
+	)
+	return err
+}
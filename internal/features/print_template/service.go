@@ -0,0 +1,426 @@
+package print_template
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/email"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/settings"
+	"go-crm/pkg/locale"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultRelatedLimit = 50
+	maxRelatedLimit     = 100
+)
+
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.]+)\s*\}\}`)
+
+type PrintTemplateService interface {
+	CreateTemplate(ctx context.Context, template *PrintTemplate, userID primitive.ObjectID) error
+	GetTemplate(ctx context.Context, id string) (*PrintTemplate, error)
+	ListTemplates(ctx context.Context, moduleName string) ([]PrintTemplate, error)
+	UpdateTemplate(ctx context.Context, template *PrintTemplate) error
+	DeleteTemplate(ctx context.Context, id string) error
+	// RenderRecordPDF renders one record with the given template, the module's
+	// default template, or a generated layout listing every visible field
+	RenderRecordPDF(ctx context.Context, moduleName, recordID, templateID string, userID primitive.ObjectID) ([]byte, string, error)
+	EmailRecordPDF(ctx context.Context, moduleName, recordID string, req EmailPDFRequest, userID primitive.ObjectID) error
+}
+
+type PrintTemplateServiceImpl struct {
+	Repo            PrintTemplateRepository
+	ModuleRepo      module.ModuleRepository
+	RecordService   record.RecordService
+	AuditService    audit.AuditService
+	EmailService    email.EmailService
+	SettingsService settings.SettingsService
+}
+
+func NewPrintTemplateService(
+	repo PrintTemplateRepository,
+	moduleRepo module.ModuleRepository,
+	recordService record.RecordService,
+	auditService audit.AuditService,
+	emailService email.EmailService,
+	settingsService settings.SettingsService,
+) PrintTemplateService {
+	return &PrintTemplateServiceImpl{
+		Repo:            repo,
+		ModuleRepo:      moduleRepo,
+		RecordService:   recordService,
+		AuditService:    auditService,
+		EmailService:    emailService,
+		SettingsService: settingsService,
+	}
+}
+
+func (s *PrintTemplateServiceImpl) CreateTemplate(ctx context.Context, template *PrintTemplate, userID primitive.ObjectID) error {
+	if err := s.validate(ctx, template); err != nil {
+		return err
+	}
+	template.ID = primitive.NilObjectID
+	template.CreatedBy = userID
+
+	if err := s.Repo.Create(ctx, template); err != nil {
+		return err
+	}
+	if template.IsDefault {
+		_ = s.Repo.ClearDefault(ctx, template.ModuleName, template.ID)
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionTemplate, "print_templates", template.ID.Hex(), map[string]common_models.Change{
+		"template": {New: template},
+	})
+	return nil
+}
+
+func (s *PrintTemplateServiceImpl) GetTemplate(ctx context.Context, id string) (*PrintTemplate, error) {
+	return s.Repo.Get(ctx, id)
+}
+
+func (s *PrintTemplateServiceImpl) ListTemplates(ctx context.Context, moduleName string) ([]PrintTemplate, error) {
+	return s.Repo.List(ctx, moduleName)
+}
+
+func (s *PrintTemplateServiceImpl) UpdateTemplate(ctx context.Context, template *PrintTemplate) error {
+	old, err := s.Repo.Get(ctx, template.ID.Hex())
+	if err != nil {
+		return errors.New("template not found")
+	}
+	if err := s.validate(ctx, template); err != nil {
+		return err
+	}
+	template.CreatedBy = old.CreatedBy
+	template.CreatedAt = old.CreatedAt
+
+	if err := s.Repo.Update(ctx, template); err != nil {
+		return err
+	}
+	if template.IsDefault {
+		_ = s.Repo.ClearDefault(ctx, template.ModuleName, template.ID)
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionTemplate, "print_templates", template.ID.Hex(), map[string]common_models.Change{
+		"template": {Old: old, New: template},
+	})
+	return nil
+}
+
+func (s *PrintTemplateServiceImpl) DeleteTemplate(ctx context.Context, id string) error {
+	old, err := s.Repo.Get(ctx, id)
+	if err != nil {
+		return errors.New("template not found")
+	}
+	if err := s.Repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionTemplate, "print_templates", old.Name, map[string]common_models.Change{
+		"template": {Old: old, New: "DELETED"},
+	})
+	return nil
+}
+
+func (s *PrintTemplateServiceImpl) validate(ctx context.Context, template *PrintTemplate) error {
+	if template.Name == "" {
+		return errors.New("template name is required")
+	}
+	if template.PageSize == "" {
+		template.PageSize = "A4"
+	}
+	if _, ok := pageSizes[strings.ToUpper(template.PageSize)]; !ok {
+		return fmt.Errorf("unsupported page size: %s", template.PageSize)
+	}
+
+	mod, err := s.ModuleRepo.FindByName(ctx, template.ModuleName)
+	if err != nil || mod == nil {
+		return errors.New("invalid module name specified")
+	}
+
+	for i, section := range template.Sections {
+		switch section.Type {
+		case SectionFields:
+			for _, f := range section.Fields {
+				if findField(mod, f) == nil {
+					return fmt.Errorf("section %d: unknown field '%s'", i+1, f)
+				}
+			}
+		case SectionText:
+			if section.Text == "" {
+				return fmt.Errorf("section %d: text is required", i+1)
+			}
+		case SectionRelated:
+			related, err := s.ModuleRepo.FindByName(ctx, section.RelatedModule)
+			if err != nil || related == nil {
+				return fmt.Errorf("section %d: unknown related module '%s'", i+1, section.RelatedModule)
+			}
+			ref := findField(related, section.RelatedField)
+			if ref == nil || ref.Type != common_models.FieldTypeLookup || ref.Lookup == nil || ref.Lookup.LookupModule != mod.Name {
+				return fmt.Errorf("section %d: '%s.%s' must be a lookup to %s", i+1, section.RelatedModule, section.RelatedField, mod.Name)
+			}
+			for _, c := range section.Columns {
+				if findField(related, c) == nil {
+					return fmt.Errorf("section %d: unknown column '%s'", i+1, c)
+				}
+			}
+			if section.Limit < 0 || section.Limit > maxRelatedLimit {
+				return fmt.Errorf("section %d: limit must be at most %d", i+1, maxRelatedLimit)
+			}
+		default:
+			return fmt.Errorf("section %d: invalid section type '%s'", i+1, section.Type)
+		}
+	}
+	return nil
+}
+
+func (s *PrintTemplateServiceImpl) RenderRecordPDF(ctx context.Context, moduleName, recordID, templateID string, userID primitive.ObjectID) ([]byte, string, error) {
+	mod, err := s.ModuleRepo.FindByName(ctx, moduleName)
+	if err != nil || mod == nil {
+		return nil, "", errors.New("module not found")
+	}
+
+	tmpl, err := s.resolveTemplate(ctx, mod, templateID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// GetRecord applies record access, field permissions and lookup/file population
+	rec, err := s.RecordService.GetRecord(ctx, moduleName, recordID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Values print in the requesting user's locale
+	lf := s.SettingsService.Formatter(ctx, userID.Hex())
+
+	title := renderPlaceholders(tmpl.Title, mod, rec, lf)
+	if strings.TrimSpace(title) == "" {
+		title = fmt.Sprintf("%s %s", mod.Label, recordTitle(rec, lf))
+	}
+
+	doc := newPDFDocument(tmpl.PageSize, title)
+	layout := newPDFLayout(doc, renderPlaceholders(tmpl.Footer, mod, rec, lf))
+	layout.title(title)
+
+	for _, section := range tmpl.Sections {
+		if section.Heading != "" {
+			layout.heading(renderPlaceholders(section.Heading, mod, rec, lf))
+		}
+
+		switch section.Type {
+		case SectionFields:
+			layout.keyValues(fieldPairs(mod, rec, section.Fields, lf))
+		case SectionText:
+			layout.paragraph(renderPlaceholders(section.Text, mod, rec, lf))
+		case SectionRelated:
+			headers, rows, err := s.relatedRows(ctx, section, recordID, userID, lf)
+			if err != nil {
+				layout.paragraph("Related records unavailable: " + err.Error())
+				continue
+			}
+			if len(rows) == 0 {
+				layout.paragraph("No related records.")
+				continue
+			}
+			layout.table(headers, rows)
+		}
+	}
+
+	layout.finish()
+	data, err := doc.bytes()
+	if err != nil {
+		return nil, "", err
+	}
+
+	filename := fmt.Sprintf("%s_%s.pdf", moduleName, recordID)
+	return data, filename, nil
+}
+
+func (s *PrintTemplateServiceImpl) EmailRecordPDF(ctx context.Context, moduleName, recordID string, req EmailPDFRequest, userID primitive.ObjectID) error {
+	if len(req.To) == 0 {
+		return errors.New("at least one recipient is required")
+	}
+
+	data, filename, err := s.RenderRecordPDF(ctx, moduleName, recordID, req.TemplateID, userID)
+	if err != nil {
+		return err
+	}
+
+	subject := req.Subject
+	if subject == "" {
+		subject = fmt.Sprintf("%s record %s", moduleName, recordID)
+	}
+	body := req.Body
+	if body == "" {
+		body = "Please find the attached record snapshot."
+	}
+
+	return s.EmailService.SendEmailWithAttachment(ctx, req.To, subject, body, filename, data)
+}
+
+func (s *PrintTemplateServiceImpl) resolveTemplate(ctx context.Context, mod *common_models.Entity, templateID string) (*PrintTemplate, error) {
+	if templateID != "" {
+		tmpl, err := s.Repo.Get(ctx, templateID)
+		if err != nil {
+			return nil, errors.New("template not found")
+		}
+		if tmpl.ModuleName != mod.Name {
+			return nil, fmt.Errorf("template belongs to module %s", tmpl.ModuleName)
+		}
+		return tmpl, nil
+	}
+
+	if tmpl, err := s.Repo.GetDefault(ctx, mod.Name); err == nil {
+		return tmpl, nil
+	}
+
+	return &PrintTemplate{
+		ModuleName: mod.Name,
+		PageSize:   "A4",
+		Sections:   []PrintSection{{Type: SectionFields, Heading: "Details"}},
+	}, nil
+}
+
+func (s *PrintTemplateServiceImpl) relatedRows(ctx context.Context, section PrintSection, recordID string, userID primitive.ObjectID, lf *locale.Formatter) ([]string, [][]string, error) {
+	related, err := s.ModuleRepo.FindByName(ctx, section.RelatedModule)
+	if err != nil || related == nil {
+		return nil, nil, errors.New("related module not found")
+	}
+
+	columns := section.Columns
+	if len(columns) == 0 {
+		for _, f := range related.Fields {
+			if !f.Hidden && f.Name != section.RelatedField && len(columns) < 5 {
+				columns = append(columns, f.Name)
+			}
+		}
+	}
+
+	limit := section.Limit
+	if limit <= 0 {
+		limit = defaultRelatedLimit
+	}
+
+	filters := []common_models.Filter{{Field: section.RelatedField, Operator: "eq", Value: recordID}}
+	records, _, err := s.RecordService.ListRecords(ctx, section.RelatedModule, filters, 1, limit, "created_at", "asc", userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	headers := make([]string, len(columns))
+	for i, c := range columns {
+		headers[i] = fieldLabel(related, c)
+	}
+
+	rows := make([][]string, 0, len(records))
+	for _, rec := range records {
+		row := make([]string, len(columns))
+		for i, c := range columns {
+			row[i] = lf.Field(fieldType(related, c), rec[c])
+		}
+		rows = append(rows, row)
+	}
+	return headers, rows, nil
+}
+
+// fieldPairs lists label/value pairs in schema order. Fields stripped by field
+// permissions are absent from rec and therefore never printed.
+func fieldPairs(mod *common_models.Entity, rec map[string]any, names []string, lf *locale.Formatter) [][2]string {
+	if len(names) == 0 {
+		for _, f := range mod.Fields {
+			if !f.Hidden {
+				names = append(names, f.Name)
+			}
+		}
+	}
+
+	pairs := make([][2]string, 0, len(names))
+	for _, name := range names {
+		val, ok := rec[name]
+		if !ok {
+			continue
+		}
+		pairs = append(pairs, [2]string{fieldLabel(mod, name), lf.Field(fieldType(mod, name), val)})
+	}
+	return pairs
+}
+
+func recordTitle(rec map[string]any, lf *locale.Formatter) string {
+	for _, key := range []string{"name", "title", "subject"} {
+		if v, ok := rec[key]; ok {
+			if s := lf.Value(v); s != "" {
+				return s
+			}
+		}
+	}
+	if id, ok := rec["_id"]; ok {
+		return lf.Value(id)
+	}
+	return ""
+}
+
+func findField(mod *common_models.Entity, name string) *common_models.ModuleField {
+	for i := range mod.Fields {
+		if mod.Fields[i].Name == name {
+			return &mod.Fields[i]
+		}
+	}
+	return nil
+}
+
+func fieldLabel(mod *common_models.Entity, name string) string {
+	if f := findField(mod, name); f != nil && f.Label != "" {
+		return f.Label
+	}
+	return name
+}
+
+func fieldType(mod *common_models.Entity, name string) string {
+	if f := findField(mod, name); f != nil {
+		return string(f.Type)
+	}
+	return ""
+}
+
+// renderPlaceholders substitutes {{field}} and {{lookup.field}} with display values;
+// unknown placeholders render empty so permission-stripped fields do not leak their names
+func renderPlaceholders(text string, mod *common_models.Entity, rec map[string]any, lf *locale.Formatter) string {
+	return placeholderPattern.ReplaceAllStringFunc(text, func(m string) string {
+		return placeholderValue(placeholderPattern.FindStringSubmatch(m)[1], mod, rec, lf)
+	})
+}
+
+// placeholderValue is the display value of a field or lookup.field path of rec
+func placeholderValue(path string, mod *common_models.Entity, rec map[string]any, lf *locale.Formatter) string {
+	parts := strings.Split(path, ".")
+	var cur any = rec
+	for _, p := range parts {
+		var obj map[string]any
+		switch v := cur.(type) {
+		case map[string]any:
+			obj = v
+		case primitive.M:
+			obj = v
+		default:
+			return ""
+		}
+		var ok bool
+		if cur, ok = obj[p]; !ok {
+			return ""
+		}
+	}
+	if len(parts) == 1 {
+		return lf.Field(fieldType(mod, parts[0]), cur)
+	}
+	return lf.Value(cur)
+}
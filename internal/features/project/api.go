@@ -0,0 +1,40 @@
+package project
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ProjectApi struct {
+	controller  *ProjectController
+	config      *config.Config
+	roleService middleware.RoleService
+}
+
+func NewProjectApi(controller *ProjectController, config *config.Config, roleService middleware.RoleService) *ProjectApi {
+	return &ProjectApi{
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *ProjectApi) Setup(app *fiber.App) {
+	templates := app.Group("/api/project-templates", middleware.AuthMiddleware(h.config.SkipAuth))
+
+	templates.Get("/", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListTemplates)
+	templates.Post("/", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.CreateTemplate)
+	templates.Get("/:id", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.GetTemplate)
+	templates.Put("/:id", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.UpdateTemplate)
+	templates.Delete("/:id", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.DeleteTemplate)
+
+	projects := app.Group("/api/projects", middleware.AuthMiddleware(h.config.SkipAuth))
+
+	projects.Post("/from-template", middleware.RequirePermission(h.roleService, ModuleName, "create"), h.controller.CreateFromTemplate)
+	projects.Get("/:id/gantt", h.controller.GetGantt)
+	projects.Post("/:id/rollup", h.controller.Rollup)
+	projects.Post("/:id/dependencies", middleware.RequirePermission(h.roleService, ModuleName, "update"), h.controller.AddDependency)
+	projects.Delete("/:id/dependencies/:dependencyId", middleware.RequirePermission(h.roleService, ModuleName, "update"), h.controller.RemoveDependency)
+}
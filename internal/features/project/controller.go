@@ -0,0 +1,233 @@
+package project
+
+import (
+	"errors"
+
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ProjectController struct {
+	Service ProjectService
+}
+
+func NewProjectController(service ProjectService) *ProjectController {
+	return &ProjectController{Service: service}
+}
+
+func errorStatus(err error) int {
+	if errors.Is(err, ErrProjectNotFound) || errors.Is(err, ErrTemplateNotFound) || errors.Is(err, ErrDependencyNotFound) {
+		return fiber.StatusNotFound
+	}
+	return fiber.StatusBadRequest
+}
+
+// CreateTemplate godoc
+// @Summary Create project template
+// @Description Milestones and tasks are scheduled in days from the project start. Set auto_on_closed_won to create the plan whenever an opportunity is won.
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param template body Template true "Template"
+// @Success 201 {object} Template
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/project-templates [post]
+func (c *ProjectController) CreateTemplate(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var t Template
+	if err := ctx.BodyParser(&t); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := c.Service.CreateTemplate(ctx.UserContext(), &t, userID); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{"data": t})
+}
+
+// ListTemplates godoc
+// @Summary List project templates
+// @Tags projects
+// @Produce json
+// @Success 200 {array} Template
+// @Router /api/project-templates [get]
+func (c *ProjectController) ListTemplates(ctx *fiber.Ctx) error {
+	templates, err := c.Service.ListTemplates(ctx.UserContext())
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": templates})
+}
+
+// GetTemplate godoc
+// @Summary Get project template
+// @Tags projects
+// @Produce json
+// @Param id path string true "Template ID"
+// @Success 200 {object} Template
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/project-templates/{id} [get]
+func (c *ProjectController) GetTemplate(ctx *fiber.Ctx) error {
+	t, err := c.Service.GetTemplate(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": t})
+}
+
+// UpdateTemplate godoc
+// @Summary Update project template
+// @Description Projects already created from the template are not changed
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Template ID"
+// @Param template body Template true "Template"
+// @Success 200 {object} Template
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/project-templates/{id} [put]
+func (c *ProjectController) UpdateTemplate(ctx *fiber.Ctx) error {
+	var in Template
+	if err := ctx.BodyParser(&in); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	t, err := c.Service.UpdateTemplate(ctx.UserContext(), ctx.Params("id"), &in)
+	if err != nil {
+		return ctx.Status(errorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": t})
+}
+
+// DeleteTemplate godoc
+// @Summary Delete project template
+// @Tags projects
+// @Param id path string true "Template ID"
+// @Success 204
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/project-templates/{id} [delete]
+func (c *ProjectController) DeleteTemplate(ctx *fiber.Ctx) error {
+	if err := c.Service.DeleteTemplate(ctx.UserContext(), ctx.Params("id")); err != nil {
+		return ctx.Status(errorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// CreateFromTemplate godoc
+// @Summary Create project from template
+// @Description Creates the project with its milestones, tasks and dependencies. With opportunity_id the project is linked to the opportunity and its account.
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param request body FromTemplateRequest true "Request"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/projects/from-template [post]
+func (c *ProjectController) CreateFromTemplate(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var req FromTemplateRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	id, err := c.Service.CreateFromTemplate(ctx.UserContext(), req, userID)
+	if err != nil {
+		return ctx.Status(errorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{"data": fiber.Map{"id": id}})
+}
+
+// GetGantt godoc
+// @Summary Project Gantt data
+// @Description Milestones, tasks with start/end and progress, and finish-to-start links. Tasks are flagged blocked while a task they depend on is unfinished.
+// @Tags projects
+// @Produce json
+// @Param id path string true "Project ID"
+// @Success 200 {object} Gantt
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/projects/{id}/gantt [get]
+func (c *ProjectController) GetGantt(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	g, err := c.Service.GetGantt(ctx.UserContext(), ctx.Params("id"), userID)
+	if err != nil {
+		return ctx.Status(errorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": g})
+}
+
+// AddDependency godoc
+// @Summary Add task dependency
+// @Description task_id cannot start until depends_on_id is finished. Both tasks must belong to the project; cycles are rejected.
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param dependency body DependencyRequest true "Dependency"
+// @Success 201 {object} Dependency
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/projects/{id}/dependencies [post]
+func (c *ProjectController) AddDependency(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var req DependencyRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	dep, err := c.Service.AddDependency(ctx.UserContext(), ctx.Params("id"), req, userID)
+	if err != nil {
+		return ctx.Status(errorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{"data": dep})
+}
+
+// RemoveDependency godoc
+// @Summary Remove task dependency
+// @Tags projects
+// @Param id path string true "Project ID"
+// @Param dependencyId path string true "Dependency ID"
+// @Success 204
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/projects/{id}/dependencies/{dependencyId} [delete]
+func (c *ProjectController) RemoveDependency(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	if err := c.Service.RemoveDependency(ctx.UserContext(), ctx.Params("id"), ctx.Params("dependencyId"), userID); err != nil {
+		return ctx.Status(errorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// Rollup godoc
+// @Summary Recalculate project progress
+// @Description Progress is rolled up automatically when tasks change; this forces a recalculation
+// @Tags projects
+// @Produce json
+// @Param id path string true "Project ID"
+// @Success 200 {object} Rollup
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/projects/{id}/rollup [post]
+func (c *ProjectController) Rollup(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	r, err := c.Service.Rollup(ctx.UserContext(), ctx.Params("id"), userID)
+	if err != nil {
+		return ctx.Status(errorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": r})
+}
@@ -0,0 +1,134 @@
+package project
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ModuleName          = "projects"
+	MilestonesModule    = "project_milestones"
+	TasksModule         = "tasks"
+	OpportunitiesModule = "opportunities"
+)
+
+// DependencyFinishToStart means the task cannot start before the task it
+// depends on is finished. It is the only dependency type.
+const DependencyFinishToStart = "finish_to_start"
+
+// Dependency links two tasks of a project: TaskID waits on DependsOnID
+type Dependency struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID    primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	ProjectID   primitive.ObjectID `json:"project_id" bson:"project_id"`
+	TaskID      primitive.ObjectID `json:"task_id" bson:"task_id"`
+	DependsOnID primitive.ObjectID `json:"depends_on_id" bson:"depends_on_id"`
+	Type        string             `json:"type" bson:"type"`
+	CreatedBy   primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// TemplateMilestone is due OffsetDays after the project start
+type TemplateMilestone struct {
+	Name       string `json:"name" bson:"name"`
+	OffsetDays int    `json:"offset_days" bson:"offset_days"`
+}
+
+// TemplateTask starts OffsetDays after the project start and runs for
+// DurationDays. Milestone is a milestone name; DependsOn lists task keys.
+type TemplateTask struct {
+	Key          string   `json:"key" bson:"key"`
+	Subject      string   `json:"subject" bson:"subject"`
+	Description  string   `json:"description,omitempty" bson:"description,omitempty"`
+	Milestone    string   `json:"milestone,omitempty" bson:"milestone,omitempty"`
+	OffsetDays   int      `json:"offset_days" bson:"offset_days"`
+	DurationDays int      `json:"duration_days" bson:"duration_days"`
+	DependsOn    []string `json:"depends_on,omitempty" bson:"depends_on,omitempty"`
+}
+
+// Template is a standard project plan
+type Template struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID    primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	Name        string             `json:"name" bson:"name"`
+	Description string             `json:"description,omitempty" bson:"description,omitempty"`
+	// AutoOnClosedWon spins the plan up when an opportunity moves to
+	// Closed Won. At most one template per tenant has it set.
+	AutoOnClosedWon bool                `json:"auto_on_closed_won" bson:"auto_on_closed_won"`
+	Milestones      []TemplateMilestone `json:"milestones" bson:"milestones"`
+	Tasks           []TemplateTask      `json:"tasks" bson:"tasks"`
+	CreatedBy       primitive.ObjectID  `json:"created_by" bson:"created_by"`
+	CreatedAt       time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+// FromTemplateRequest creates a project from a template
+type FromTemplateRequest struct {
+	TemplateID    string `json:"template_id"`
+	Name          string `json:"name"`
+	StartDate     string `json:"start_date,omitempty"` // YYYY-MM-DD or RFC3339, default today
+	OpportunityID string `json:"opportunity_id,omitempty"`
+	AccountID     string `json:"account_id,omitempty"`
+}
+
+// DependencyRequest adds a finish-to-start dependency
+type DependencyRequest struct {
+	TaskID      string `json:"task_id"`
+	DependsOnID string `json:"depends_on_id"`
+}
+
+type GanttTask struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Start      *time.Time `json:"start,omitempty"`
+	End        *time.Time `json:"end,omitempty"`
+	Status     string     `json:"status"`
+	Progress   float64    `json:"progress"`
+	Milestone  string     `json:"milestone,omitempty"`
+	AssignedTo string     `json:"assigned_to,omitempty"`
+	// Dependencies are the IDs of the tasks this one waits on
+	Dependencies []string `json:"dependencies"`
+	// Blocked is set while a task it depends on is unfinished
+	Blocked bool `json:"blocked"`
+	// Conflict is set when the task is scheduled to start before a task
+	// it depends on is due
+	Conflict bool `json:"conflict"`
+}
+
+type GanttMilestone struct {
+	ID              string     `json:"id"`
+	Name            string     `json:"name"`
+	Date            *time.Time `json:"date,omitempty"`
+	Status          string     `json:"status"`
+	PercentComplete float64    `json:"percent_complete"`
+}
+
+type GanttLink struct {
+	ID     string `json:"id"`
+	Source string `json:"source"` // the task depended on
+	Target string `json:"target"`
+	Type   string `json:"type"`
+}
+
+// Gantt is a project laid out for timeline rendering
+type Gantt struct {
+	ProjectID       string           `json:"project_id"`
+	Name            string           `json:"name"`
+	Status          string           `json:"status"`
+	Start           *time.Time       `json:"start,omitempty"`
+	End             *time.Time       `json:"end,omitempty"`
+	PercentComplete float64          `json:"percent_complete"`
+	Milestones      []GanttMilestone `json:"milestones"`
+	Tasks           []GanttTask      `json:"tasks"`
+	Links           []GanttLink      `json:"links"`
+}
+
+// Rollup is the result of recomputing percent complete
+type Rollup struct {
+	ProjectID       string             `json:"project_id"`
+	Tasks           int                `json:"tasks"`
+	Completed       int                `json:"completed"`
+	PercentComplete float64            `json:"percent_complete"`
+	Milestones      map[string]float64 `json:"milestones"` // milestone ID -> percent
+}
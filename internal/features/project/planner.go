@@ -0,0 +1,321 @@
+package project
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/record"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxTasks bounds the tasks read for one project
+const maxTasks = 2000
+
+// Planner creates project plans and keeps percent complete rolled up. It
+// works from the record repository rather than RecordService because it is
+// itself a record.ChangeListener.
+type Planner struct {
+	TemplateRepo   TemplateRepository
+	DependencyRepo DependencyRepository
+	RecordRepo     record.RecordRepository
+	ModuleRepo     module.ModuleRepository
+}
+
+func NewPlanner(
+	templateRepo TemplateRepository,
+	dependencyRepo DependencyRepository,
+	recordRepo record.RecordRepository,
+	moduleRepo module.ModuleRepository,
+) *Planner {
+	return &Planner{
+		TemplateRepo:   templateRepo,
+		DependencyRepo: dependencyRepo,
+		RecordRepo:     recordRepo,
+		ModuleRepo:     moduleRepo,
+	}
+}
+
+func isComplete(status interface{}) bool {
+	s, _ := status.(string)
+	return s == "completed" || s == "done"
+}
+
+func isClosedWon(stage interface{}) bool {
+	s, _ := stage.(string)
+	return strings.EqualFold(strings.ReplaceAll(s, "_", " "), "closed won")
+}
+
+func objectID(v interface{}) (primitive.ObjectID, bool) {
+	switch t := v.(type) {
+	case primitive.ObjectID:
+		return t, !t.IsZero()
+	case string:
+		oid, err := primitive.ObjectIDFromHex(t)
+		return oid, err == nil
+	case map[string]interface{}:
+		return objectID(t["id"])
+	}
+	return primitive.NilObjectID, false
+}
+
+func dateValue(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case primitive.DateTime:
+		return t.Time(), true
+	}
+	return time.Time{}, false
+}
+
+func percent(done, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(done)*1000/float64(total)) / 10
+}
+
+func (p *Planner) product(ctx context.Context, moduleName string) common_models.Product {
+	if m, err := p.ModuleRepo.FindByName(ctx, moduleName); err == nil && m.Product != "" {
+		return m.Product
+	}
+	return common_models.ProductCRM
+}
+
+// Instantiate creates the project, its milestones, tasks and dependencies
+// from a template, with dates counted from start. Tasks are assigned to owner.
+func (p *Planner) Instantiate(ctx context.Context, tpl *Template, name string, start time.Time, opportunity, account, owner primitive.ObjectID) (primitive.ObjectID, error) {
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	end := start
+	for _, m := range tpl.Milestones {
+		if due := start.AddDate(0, 0, m.OffsetDays); due.After(end) {
+			end = due
+		}
+	}
+	for _, t := range tpl.Tasks {
+		if due := start.AddDate(0, 0, t.OffsetDays+t.DurationDays); due.After(end) {
+			end = due
+		}
+	}
+
+	now := time.Now()
+	data := map[string]interface{}{
+		"name":             name,
+		"status":           "planned",
+		"start_date":       start,
+		"end_date":         end,
+		"percent_complete": 0.0,
+		"description":      tpl.Description,
+		"created_at":       now,
+	}
+	if !opportunity.IsZero() {
+		data["opportunity"] = opportunity
+	}
+	if !account.IsZero() {
+		data["account"] = account
+	}
+	if !owner.IsZero() {
+		data["owner"] = owner
+	}
+	created, err := p.RecordRepo.Create(ctx, ModuleName, p.product(ctx, ModuleName), data)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	projectID, _ := created.(primitive.ObjectID)
+
+	milestones := make(map[string]primitive.ObjectID, len(tpl.Milestones))
+	msProduct := p.product(ctx, MilestonesModule)
+	for _, m := range tpl.Milestones {
+		created, err := p.RecordRepo.Create(ctx, MilestonesModule, msProduct, map[string]interface{}{
+			"name":             m.Name,
+			"project":          projectID,
+			"due_date":         start.AddDate(0, 0, m.OffsetDays),
+			"status":           "open",
+			"percent_complete": 0.0,
+			"created_at":       now,
+		})
+		if err != nil {
+			return projectID, err
+		}
+		milestones[m.Name], _ = created.(primitive.ObjectID)
+	}
+
+	tasks := make(map[string]primitive.ObjectID, len(tpl.Tasks))
+	taskProduct := p.product(ctx, TasksModule)
+	for _, t := range tpl.Tasks {
+		taskStart := start.AddDate(0, 0, t.OffsetDays)
+		task := map[string]interface{}{
+			"subject":        t.Subject,
+			"description":    t.Description,
+			"status":         "pending",
+			"start_date":     taskStart,
+			"due_date":       taskStart.AddDate(0, 0, t.DurationDays),
+			"project":        projectID,
+			"related_module": ModuleName,
+			"related_id":     projectID.Hex(),
+			"created_at":     now,
+		}
+		if msID, ok := milestones[t.Milestone]; ok {
+			task["milestone"] = msID
+		}
+		if !owner.IsZero() {
+			task["assigned_to"] = owner.Hex()
+		}
+		created, err := p.RecordRepo.Create(ctx, TasksModule, taskProduct, task)
+		if err != nil {
+			return projectID, err
+		}
+		tasks[t.Key], _ = created.(primitive.ObjectID)
+	}
+
+	for _, t := range tpl.Tasks {
+		for _, key := range t.DependsOn {
+			dep := &Dependency{ProjectID: projectID, TaskID: tasks[t.Key], DependsOnID: tasks[key], CreatedBy: owner}
+			if err := p.DependencyRepo.Create(ctx, dep); err != nil {
+				return projectID, err
+			}
+		}
+	}
+	return projectID, nil
+}
+
+// Rollup recomputes percent complete on every milestone of the project and
+// on the project itself from the share of its tasks that are completed
+func (p *Planner) Rollup(ctx context.Context, projectID primitive.ObjectID) (*Rollup, error) {
+	tasks, err := p.RecordRepo.List(ctx, TasksModule, bson.M{"project": projectID}, nil, maxTasks, 0, "_id", 1)
+	if err != nil {
+		return nil, err
+	}
+	milestones, err := p.RecordRepo.List(ctx, MilestonesModule, bson.M{"project": projectID}, nil, maxTasks, 0, "_id", 1)
+	if err != nil {
+		return nil, err
+	}
+
+	rollup := &Rollup{ProjectID: projectID.Hex(), Milestones: map[string]float64{}}
+	type counts struct{ done, total int }
+	byMilestone := map[primitive.ObjectID]*counts{}
+	for _, t := range tasks {
+		rollup.Tasks++
+		done := isComplete(t["status"])
+		if done {
+			rollup.Completed++
+		}
+		if msID, ok := objectID(t["milestone"]); ok {
+			c := byMilestone[msID]
+			if c == nil {
+				c = &counts{}
+				byMilestone[msID] = c
+			}
+			c.total++
+			if done {
+				c.done++
+			}
+		}
+	}
+	rollup.PercentComplete = percent(rollup.Completed, rollup.Tasks)
+
+	for _, m := range milestones {
+		msID, _ := m["_id"].(primitive.ObjectID)
+		c := byMilestone[msID]
+		if c == nil {
+			c = &counts{}
+		}
+		pct := percent(c.done, c.total)
+		rollup.Milestones[msID.Hex()] = pct
+
+		update := map[string]interface{}{"percent_complete": pct}
+		if c.total > 0 && c.done == c.total {
+			update["status"] = "completed"
+		} else {
+			update["status"] = "open"
+		}
+		if m["percent_complete"] != pct || m["status"] != update["status"] {
+			if err := p.RecordRepo.Update(ctx, MilestonesModule, msID.Hex(), update); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := p.RecordRepo.Update(ctx, ModuleName, projectID.Hex(), map[string]interface{}{"percent_complete": rollup.PercentComplete}); err != nil {
+		return nil, err
+	}
+	return rollup, nil
+}
+
+// RecordChanged rolls projects up when their tasks change and spins up the
+// closed-won template when an opportunity is won
+func (p *Planner) RecordChanged(ctx context.Context, change record.RecordChange) {
+	switch change.ModuleName {
+	case TasksModule:
+		p.taskChanged(ctx, change)
+	case OpportunitiesModule:
+		if stage, ok := change.Changes["stage"]; ok && isClosedWon(stage.New) && !isClosedWon(stage.Old) {
+			if err := p.opportunityWon(ctx, change); err != nil {
+				log.Printf("project: closed-won plan for %s failed: %v", change.RecordID, err)
+			}
+		}
+	}
+}
+
+func (p *Planner) taskChanged(ctx context.Context, change record.RecordChange) {
+	var projects []primitive.ObjectID
+	if c, ok := change.Changes["project"]; ok {
+		if old, ok := objectID(c.Old); ok {
+			projects = append(projects, old)
+		}
+	}
+	_, statusChanged := change.Changes["status"]
+	_, projectChanged := change.Changes["project"]
+	_, milestoneChanged := change.Changes["milestone"]
+	if change.Created || statusChanged || projectChanged || milestoneChanged {
+		if current, ok := objectID(change.Record["project"]); ok {
+			projects = append(projects, current)
+		}
+	}
+	for _, id := range projects {
+		if _, err := p.Rollup(ctx, id); err != nil {
+			log.Printf("project: rollup for %s failed: %v", id.Hex(), err)
+		}
+	}
+}
+
+func (p *Planner) opportunityWon(ctx context.Context, change record.RecordChange) error {
+	tpl, err := p.TemplateRepo.FindAutoOnClosedWon(ctx)
+	if err != nil || tpl == nil {
+		return err
+	}
+	oppID, err := primitive.ObjectIDFromHex(change.RecordID)
+	if err != nil {
+		return err
+	}
+	existing, err := p.RecordRepo.List(ctx, ModuleName, bson.M{"opportunity": oppID}, nil, 1, 0, "", 0)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		// Reopened and won again; keep the first plan
+		return nil
+	}
+
+	name, _ := change.Record["name"].(string)
+	if name == "" {
+		name = tpl.Name
+	} else {
+		name = fmt.Sprintf("%s - %s", name, tpl.Name)
+	}
+	account, _ := objectID(change.Record["account"])
+	owner, ok := objectID(change.Record["owner"])
+	if !ok {
+		owner = change.ActorID
+	}
+	_, err = p.Instantiate(ctx, tpl, name, time.Now(), oppID, account, owner)
+	return err
+}
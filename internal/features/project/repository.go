@@ -0,0 +1,219 @@
+package project
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+type TemplateRepository interface {
+	Create(ctx context.Context, t *Template) error
+	Get(ctx context.Context, id string) (*Template, error)
+	List(ctx context.Context) ([]Template, error)
+	// FindAutoOnClosedWon returns the template flagged for closed-won
+	// opportunities, or nil
+	FindAutoOnClosedWon(ctx context.Context) (*Template, error)
+	Update(ctx context.Context, t *Template) error
+	Delete(ctx context.Context, id string) error
+	// ClearAutoOnClosedWon unsets the flag on every template but keepID
+	ClearAutoOnClosedWon(ctx context.Context, keepID primitive.ObjectID) error
+}
+
+type TemplateRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewTemplateRepository(db *database.MongodbDB) TemplateRepository {
+	return &TemplateRepositoryImpl{
+		collection: db.DB.Collection("project_templates"),
+	}
+}
+
+func (r *TemplateRepositoryImpl) Create(ctx context.Context, t *Template) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	t.ID = primitive.NewObjectID()
+	t.TenantID = tenantID
+	t.CreatedAt = time.Now()
+	t.UpdatedAt = t.CreatedAt
+
+	_, err = r.collection.InsertOne(ctx, t)
+	return err
+}
+
+func (r *TemplateRepositoryImpl) Get(ctx context.Context, id string) (*Template, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var t Template
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID}).Decode(&t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *TemplateRepositoryImpl) List(ctx context.Context) ([]Template, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.M{"name": 1})
+	cursor, err := r.collection.Find(ctx, bson.M{"tenant_id": tenantID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	templates := []Template{}
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (r *TemplateRepositoryImpl) FindAutoOnClosedWon(ctx context.Context) (*Template, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var t Template
+	err = r.collection.FindOne(ctx, bson.M{"tenant_id": tenantID, "auto_on_closed_won": true}).Decode(&t)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *TemplateRepositoryImpl) Update(ctx context.Context, t *Template) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	t.UpdatedAt = time.Now()
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": t.ID, "tenant_id": tenantID}, t)
+	return err
+}
+
+func (r *TemplateRepositoryImpl) Delete(ctx context.Context, id string) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID})
+	if err == nil && res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return err
+}
+
+func (r *TemplateRepositoryImpl) ClearAutoOnClosedWon(ctx context.Context, keepID primitive.ObjectID) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.UpdateMany(ctx,
+		bson.M{"tenant_id": tenantID, "auto_on_closed_won": true, "_id": bson.M{"$ne": keepID}},
+		bson.M{"$set": bson.M{"auto_on_closed_won": false, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+type DependencyRepository interface {
+	Create(ctx context.Context, d *Dependency) error
+	ListByProject(ctx context.Context, projectID primitive.ObjectID) ([]Dependency, error)
+	Delete(ctx context.Context, projectID primitive.ObjectID, id string) error
+}
+
+type DependencyRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewDependencyRepository(db *database.MongodbDB) DependencyRepository {
+	return &DependencyRepositoryImpl{
+		collection: db.DB.Collection("task_dependencies"),
+	}
+}
+
+func (r *DependencyRepositoryImpl) Create(ctx context.Context, d *Dependency) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	d.ID = primitive.NewObjectID()
+	d.TenantID = tenantID
+	d.CreatedAt = time.Now()
+	if d.Type == "" {
+		d.Type = DependencyFinishToStart
+	}
+
+	_, err = r.collection.InsertOne(ctx, d)
+	return err
+}
+
+func (r *DependencyRepositoryImpl) ListByProject(ctx context.Context, projectID primitive.ObjectID) ([]Dependency, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"tenant_id": tenantID, "project_id": projectID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	deps := []Dependency{}
+	if err := cursor.All(ctx, &deps); err != nil {
+		return nil, err
+	}
+	return deps, nil
+}
+
+func (r *DependencyRepositoryImpl) Delete(ctx context.Context, projectID primitive.ObjectID, id string) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID, "project_id": projectID})
+	if err == nil && res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return err
+}
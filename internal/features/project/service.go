@@ -0,0 +1,458 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/record"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrProjectNotFound    = apperr.NotFound("project not found")
+	ErrTemplateNotFound   = apperr.NotFound("template not found")
+	ErrDependencyNotFound = apperr.NotFound("dependency not found")
+)
+
+type ProjectService interface {
+	CreateTemplate(ctx context.Context, t *Template, userID primitive.ObjectID) error
+	ListTemplates(ctx context.Context) ([]Template, error)
+	GetTemplate(ctx context.Context, id string) (*Template, error)
+	UpdateTemplate(ctx context.Context, id string, t *Template) (*Template, error)
+	DeleteTemplate(ctx context.Context, id string) error
+
+	// CreateFromTemplate spins up a project plan and returns the project ID
+	CreateFromTemplate(ctx context.Context, req FromTemplateRequest, userID primitive.ObjectID) (string, error)
+	GetGantt(ctx context.Context, projectID string, userID primitive.ObjectID) (*Gantt, error)
+	AddDependency(ctx context.Context, projectID string, req DependencyRequest, userID primitive.ObjectID) (*Dependency, error)
+	RemoveDependency(ctx context.Context, projectID, dependencyID string, userID primitive.ObjectID) error
+	Rollup(ctx context.Context, projectID string, userID primitive.ObjectID) (*Rollup, error)
+}
+
+type ProjectServiceImpl struct {
+	Planner        *Planner
+	TemplateRepo   TemplateRepository
+	DependencyRepo DependencyRepository
+	RecordService  record.RecordService
+	AuditService   audit.AuditService
+}
+
+func NewProjectService(
+	planner *Planner,
+	templateRepo TemplateRepository,
+	dependencyRepo DependencyRepository,
+	recordService record.RecordService,
+	auditService audit.AuditService,
+) ProjectService {
+	return &ProjectServiceImpl{
+		Planner:        planner,
+		TemplateRepo:   templateRepo,
+		DependencyRepo: dependencyRepo,
+		RecordService:  recordService,
+		AuditService:   auditService,
+	}
+}
+
+// hasPath reports whether `to` is reachable from `from` along edges
+func hasPath(edges map[string][]string, from, to string) bool {
+	seen := map[string]bool{}
+	stack := []string{from}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if n == to {
+			return true
+		}
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		stack = append(stack, edges[n]...)
+	}
+	return false
+}
+
+func validateTemplate(t *Template) error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(t.Tasks) == 0 {
+		return errors.New("at least one task is required")
+	}
+
+	milestones := map[string]bool{}
+	for _, m := range t.Milestones {
+		if m.Name == "" || milestones[m.Name] {
+			return fmt.Errorf("milestone names must be unique and not empty")
+		}
+		if m.OffsetDays < 0 {
+			return fmt.Errorf("milestone %s: offset_days cannot be negative", m.Name)
+		}
+		milestones[m.Name] = true
+	}
+
+	keys := map[string]bool{}
+	for i := range t.Tasks {
+		task := &t.Tasks[i]
+		if task.Key == "" {
+			task.Key = fmt.Sprintf("task_%d", i+1)
+		}
+		if keys[task.Key] {
+			return fmt.Errorf("duplicate task key %s", task.Key)
+		}
+		if task.Subject == "" {
+			return fmt.Errorf("task %s: subject is required", task.Key)
+		}
+		if task.OffsetDays < 0 || task.DurationDays < 0 {
+			return fmt.Errorf("task %s: offset_days and duration_days cannot be negative", task.Key)
+		}
+		if task.Milestone != "" && !milestones[task.Milestone] {
+			return fmt.Errorf("task %s: unknown milestone %s", task.Key, task.Milestone)
+		}
+		keys[task.Key] = true
+	}
+
+	// edges run from the task depended on to the task waiting on it
+	edges := map[string][]string{}
+	for _, task := range t.Tasks {
+		for _, dep := range task.DependsOn {
+			if !keys[dep] {
+				return fmt.Errorf("task %s: unknown dependency %s", task.Key, dep)
+			}
+			if dep == task.Key || hasPath(edges, task.Key, dep) {
+				return fmt.Errorf("task %s: dependencies form a cycle", task.Key)
+			}
+			edges[dep] = append(edges[dep], task.Key)
+		}
+	}
+	return nil
+}
+
+func (s *ProjectServiceImpl) saveTemplate(ctx context.Context, t *Template) error {
+	if !t.AutoOnClosedWon {
+		return nil
+	}
+	return s.TemplateRepo.ClearAutoOnClosedWon(ctx, t.ID)
+}
+
+func (s *ProjectServiceImpl) CreateTemplate(ctx context.Context, t *Template, userID primitive.ObjectID) error {
+	if err := validateTemplate(t); err != nil {
+		return err
+	}
+	t.CreatedBy = userID
+	if err := s.TemplateRepo.Create(ctx, t); err != nil {
+		return err
+	}
+	if err := s.saveTemplate(ctx, t); err != nil {
+		return err
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionTemplate, "project_templates", t.Name, map[string]common_models.Change{
+		"template": {New: t},
+	})
+	return nil
+}
+
+func (s *ProjectServiceImpl) ListTemplates(ctx context.Context) ([]Template, error) {
+	return s.TemplateRepo.List(ctx)
+}
+
+func (s *ProjectServiceImpl) GetTemplate(ctx context.Context, id string) (*Template, error) {
+	t, err := s.TemplateRepo.Get(ctx, id)
+	if err != nil {
+		return nil, ErrTemplateNotFound
+	}
+	return t, nil
+}
+
+func (s *ProjectServiceImpl) UpdateTemplate(ctx context.Context, id string, in *Template) (*Template, error) {
+	existing, err := s.TemplateRepo.Get(ctx, id)
+	if err != nil {
+		return nil, ErrTemplateNotFound
+	}
+	old := *existing
+
+	existing.Name = in.Name
+	existing.Description = in.Description
+	existing.AutoOnClosedWon = in.AutoOnClosedWon
+	existing.Milestones = in.Milestones
+	existing.Tasks = in.Tasks
+	if err := validateTemplate(existing); err != nil {
+		return nil, err
+	}
+	if err := s.TemplateRepo.Update(ctx, existing); err != nil {
+		return nil, err
+	}
+	if err := s.saveTemplate(ctx, existing); err != nil {
+		return nil, err
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionTemplate, "project_templates", existing.Name, map[string]common_models.Change{
+		"template": {Old: old, New: existing},
+	})
+	return existing, nil
+}
+
+func (s *ProjectServiceImpl) DeleteTemplate(ctx context.Context, id string) error {
+	t, err := s.TemplateRepo.Get(ctx, id)
+	if err != nil {
+		return ErrTemplateNotFound
+	}
+	if err := s.TemplateRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionTemplate, "project_templates", t.Name, map[string]common_models.Change{
+		"template": {Old: t.Name, New: "DELETED"},
+	})
+	return nil
+}
+
+func (s *ProjectServiceImpl) CreateFromTemplate(ctx context.Context, req FromTemplateRequest, userID primitive.ObjectID) (string, error) {
+	tpl, err := s.TemplateRepo.Get(ctx, req.TemplateID)
+	if err != nil {
+		return "", ErrTemplateNotFound
+	}
+
+	start := time.Now()
+	if req.StartDate != "" {
+		if start, err = time.Parse(time.RFC3339, req.StartDate); err != nil {
+			if start, err = time.Parse("2006-01-02", req.StartDate); err != nil {
+				return "", errors.New("start_date must be YYYY-MM-DD or RFC3339")
+			}
+		}
+	}
+
+	var opportunity, account primitive.ObjectID
+	if req.OpportunityID != "" {
+		opp, err := s.RecordService.GetRecord(ctx, OpportunitiesModule, req.OpportunityID, userID)
+		if err != nil {
+			return "", errors.New("opportunity not found")
+		}
+		opportunity, _ = objectID(opp["_id"])
+		account, _ = objectID(opp["account"])
+		if req.Name == "" {
+			name, _ := opp["name"].(string)
+			req.Name = fmt.Sprintf("%s - %s", name, tpl.Name)
+		}
+	}
+	if req.AccountID != "" {
+		if _, err := s.RecordService.GetRecord(ctx, "accounts", req.AccountID, userID); err != nil {
+			return "", errors.New("account not found")
+		}
+		account, _ = objectID(req.AccountID)
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		req.Name = tpl.Name
+	}
+
+	projectID, err := s.Planner.Instantiate(ctx, tpl, req.Name, start, opportunity, account, userID)
+	if err != nil {
+		return projectID.Hex(), err
+	}
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionCreate, ModuleName, projectID.Hex(), map[string]common_models.Change{
+		"template": {New: tpl.Name},
+	})
+	return projectID.Hex(), nil
+}
+
+// projectRecords returns the records of module linked to the project that the
+// user can read
+func (s *ProjectServiceImpl) projectRecords(ctx context.Context, module, projectID string, userID primitive.ObjectID) ([]map[string]any, error) {
+	var out []map[string]any
+	err := s.RecordService.StreamRecords(ctx, module, []common_models.Filter{{Field: "project", Operator: "eq", Value: projectID}}, nil, 500, userID, func(batch []map[string]any) error {
+		out = append(out, batch...)
+		if len(out) > maxTasks {
+			return fmt.Errorf("project has more than %d %s", maxTasks, module)
+		}
+		return nil
+	})
+	return out, err
+}
+
+func datePtr(v interface{}) *time.Time {
+	if t, ok := dateValue(v); ok {
+		return &t
+	}
+	return nil
+}
+
+func (s *ProjectServiceImpl) getProject(ctx context.Context, projectID string, userID primitive.ObjectID) (map[string]any, primitive.ObjectID, error) {
+	proj, err := s.RecordService.GetRecord(ctx, ModuleName, projectID, userID)
+	if err != nil {
+		return nil, primitive.NilObjectID, ErrProjectNotFound
+	}
+	oid, _ := objectID(proj["_id"])
+	return proj, oid, nil
+}
+
+func (s *ProjectServiceImpl) GetGantt(ctx context.Context, projectID string, userID primitive.ObjectID) (*Gantt, error) {
+	proj, oid, err := s.getProject(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.projectRecords(ctx, TasksModule, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	milestones, err := s.projectRecords(ctx, MilestonesModule, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	deps, err := s.DependencyRepo.ListByProject(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+
+	name, _ := proj["name"].(string)
+	status, _ := proj["status"].(string)
+	pct, _ := proj["percent_complete"].(float64)
+	g := &Gantt{
+		ProjectID:       projectID,
+		Name:            name,
+		Status:          status,
+		Start:           datePtr(proj["start_date"]),
+		End:             datePtr(proj["end_date"]),
+		PercentComplete: pct,
+		Milestones:      []GanttMilestone{},
+		Tasks:           []GanttTask{},
+		Links:           []GanttLink{},
+	}
+
+	for _, m := range milestones {
+		id, _ := objectID(m["_id"])
+		name, _ := m["name"].(string)
+		status, _ := m["status"].(string)
+		pct, _ := m["percent_complete"].(float64)
+		g.Milestones = append(g.Milestones, GanttMilestone{ID: id.Hex(), Name: name, Date: datePtr(m["due_date"]), Status: status, PercentComplete: pct})
+	}
+	sort.SliceStable(g.Milestones, func(i, j int) bool {
+		a, b := g.Milestones[i].Date, g.Milestones[j].Date
+		return a != nil && (b == nil || a.Before(*b))
+	})
+
+	index := make(map[string]int, len(tasks))
+	for _, t := range tasks {
+		id, _ := objectID(t["_id"])
+		subject, _ := t["subject"].(string)
+		status, _ := t["status"].(string)
+		assigned, _ := t["assigned_to"].(string)
+		task := GanttTask{
+			ID:           id.Hex(),
+			Name:         subject,
+			Start:        datePtr(t["start_date"]),
+			End:          datePtr(t["due_date"]),
+			Status:       status,
+			AssignedTo:   assigned,
+			Dependencies: []string{},
+		}
+		if task.Start == nil {
+			task.Start = datePtr(t["created_at"])
+		}
+		if isComplete(status) {
+			task.Progress = 100
+		}
+		if ms, ok := objectID(t["milestone"]); ok {
+			task.Milestone = ms.Hex()
+		}
+		index[task.ID] = len(g.Tasks)
+		g.Tasks = append(g.Tasks, task)
+	}
+
+	for _, d := range deps {
+		ti, ok1 := index[d.TaskID.Hex()]
+		pi, ok2 := index[d.DependsOnID.Hex()]
+		if !ok1 || !ok2 {
+			// One side was deleted or is hidden from this user
+			continue
+		}
+		task, pred := &g.Tasks[ti], g.Tasks[pi]
+		task.Dependencies = append(task.Dependencies, pred.ID)
+		if !isComplete(pred.Status) && !isComplete(task.Status) {
+			task.Blocked = true
+		}
+		if task.Start != nil && pred.End != nil && task.Start.Before(*pred.End) {
+			task.Conflict = true
+		}
+		g.Links = append(g.Links, GanttLink{ID: d.ID.Hex(), Source: pred.ID, Target: task.ID, Type: d.Type})
+	}
+
+	sort.SliceStable(g.Tasks, func(i, j int) bool {
+		a, b := g.Tasks[i].Start, g.Tasks[j].Start
+		return a != nil && (b == nil || a.Before(*b))
+	})
+	return g, nil
+}
+
+func (s *ProjectServiceImpl) AddDependency(ctx context.Context, projectID string, req DependencyRequest, userID primitive.ObjectID) (*Dependency, error) {
+	_, oid, err := s.getProject(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.TaskID == "" || req.DependsOnID == "" {
+		return nil, errors.New("task_id and depends_on_id are required")
+	}
+	if req.TaskID == req.DependsOnID {
+		return nil, errors.New("a task cannot depend on itself")
+	}
+
+	var ids [2]primitive.ObjectID
+	for i, id := range []string{req.TaskID, req.DependsOnID} {
+		task, err := s.RecordService.GetRecord(ctx, TasksModule, id, userID)
+		if err != nil {
+			return nil, fmt.Errorf("task %s not found", id)
+		}
+		if p, _ := objectID(task["project"]); p != oid {
+			return nil, fmt.Errorf("task %s is not part of this project", id)
+		}
+		ids[i], _ = objectID(task["_id"])
+	}
+
+	deps, err := s.DependencyRepo.ListByProject(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+	edges := map[string][]string{}
+	for _, d := range deps {
+		if d.TaskID == ids[0] && d.DependsOnID == ids[1] {
+			return &d, nil
+		}
+		edges[d.DependsOnID.Hex()] = append(edges[d.DependsOnID.Hex()], d.TaskID.Hex())
+	}
+	if hasPath(edges, ids[0].Hex(), ids[1].Hex()) {
+		return nil, errors.New("dependency would create a cycle")
+	}
+
+	dep := &Dependency{ProjectID: oid, TaskID: ids[0], DependsOnID: ids[1], Type: DependencyFinishToStart, CreatedBy: userID}
+	if err := s.DependencyRepo.Create(ctx, dep); err != nil {
+		return nil, err
+	}
+	return dep, nil
+}
+
+func (s *ProjectServiceImpl) RemoveDependency(ctx context.Context, projectID, dependencyID string, userID primitive.ObjectID) error {
+	_, oid, err := s.getProject(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.DependencyRepo.Delete(ctx, oid, dependencyID); err != nil {
+		return ErrDependencyNotFound
+	}
+	return nil
+}
+
+func (s *ProjectServiceImpl) Rollup(ctx context.Context, projectID string, userID primitive.ObjectID) (*Rollup, error) {
+	_, oid, err := s.getProject(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Planner.Rollup(ctx, oid)
+}
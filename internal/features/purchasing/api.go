@@ -0,0 +1,30 @@
+package purchasing
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PurchasingApi struct {
+	controller *PurchasingController
+	config     *config.Config
+}
+
+func NewPurchasingApi(controller *PurchasingController, config *config.Config) *PurchasingApi {
+	return &PurchasingApi{
+		controller: controller,
+		config:     config,
+	}
+}
+
+// Setup registers purchasing routes. Module permissions are checked by the
+// record service on each read and write.
+func (h *PurchasingApi) Setup(app *fiber.App) {
+	group := app.Group("/api/purchasing", middleware.AuthMiddleware(h.config.SkipAuth))
+
+	group.Get("/purchase-orders/export", h.controller.ExportApproved)
+	group.Post("/purchase-orders/:id/recalculate", h.controller.RecalculateTotals)
+	group.Post("/:module/:id/submit", h.controller.Submit)
+}
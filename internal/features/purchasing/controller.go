@@ -0,0 +1,124 @@
+package purchasing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PurchasingController struct {
+	Service PurchasingService
+}
+
+func NewPurchasingController(service PurchasingService) *PurchasingController {
+	return &PurchasingController{Service: service}
+}
+
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return fiber.StatusNotFound
+	case errors.Is(err, ErrAlreadySubmitted):
+		return fiber.StatusConflict
+	}
+	return fiber.StatusBadRequest
+}
+
+// RecalculateTotals godoc
+// @Summary Recalculate purchase order totals
+// @Description Sum the order's line items into total_value, total_tax and net_amount
+// @Tags purchasing
+// @Produce json
+// @Param id path string true "Purchase order ID"
+// @Success 200 {object} Totals
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/purchasing/purchase-orders/{id}/recalculate [post]
+func (c *PurchasingController) RecalculateTotals(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	totals, err := c.Service.RecalculateTotals(ctx.UserContext(), ctx.Params("id"), userID)
+	if err != nil {
+		return ctx.Status(errorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": totals})
+}
+
+// Submit godoc
+// @Summary Submit for approval
+// @Description Finalise totals and route a purchase order or expense into its module's approval workflow. Workflow and step criteria on net_amount set amount thresholds, e.g. a VP step with net_amount gt 10000.
+// @Tags purchasing
+// @Produce json
+// @Param module path string true "purchase_orders or expenses"
+// @Param id path string true "Record ID"
+// @Success 200 {object} SubmitResult
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/purchasing/{module}/{id}/submit [post]
+func (c *PurchasingController) Submit(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	result, err := c.Service.Submit(ctx.UserContext(), ctx.Params("module"), ctx.Params("id"), userID)
+	if err != nil {
+		return ctx.Status(errorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": result})
+}
+
+// ExportApproved godoc
+// @Summary Export approved purchase orders
+// @Description CSV of approved purchase orders, one row per line item, for import into accounting. Orders are stamped exported_at and left out of later exports unless include_exported is set.
+// @Tags purchasing
+// @Produce text/csv
+// @Param start_date query string false "Order date from (YYYY-MM-DD or RFC3339)"
+// @Param end_date query string false "Order date to"
+// @Param include_exported query bool false "Include orders already exported"
+// @Success 200 {file} file "CSV file"
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/purchasing/purchase-orders/export [get]
+func (c *PurchasingController) ExportApproved(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	start, err := parseDate(ctx.Query("start_date"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid start_date"})
+	}
+	end, err := parseDate(ctx.Query("end_date"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid end_date"})
+	}
+
+	data, filename, err := c.Service.ExportApprovedCSV(ctx.UserContext(), ExportQuery{
+		Start:           start,
+		End:             end,
+		IncludeExported: ctx.QueryBool("include_exported", false),
+	}, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	ctx.Set("Content-Type", "text/csv")
+	ctx.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	return ctx.Send(data)
+}
@@ -0,0 +1,40 @@
+package purchasing
+
+import "time"
+
+const (
+	PurchaseOrderModule = "purchase_orders"
+	POItemsModule       = "purchase_order_items"
+	ExpenseModule       = "expenses"
+)
+
+// StatusSubmitted is set on the document's status field on submit. The
+// approval outcome lives in the record's _approval state.
+const StatusSubmitted = "submitted"
+
+// Totals are the amounts written onto a purchase order or expense on submit
+type Totals struct {
+	TotalValue float64 `json:"total_value"`
+	TotalTax   float64 `json:"total_tax"`
+	NetAmount  float64 `json:"net_amount"`
+	Lines      int     `json:"lines,omitempty"`
+}
+
+// SubmitResult says where the document went after submit. Status is the
+// approval status: pending, or approved straight away when no approval
+// workflow or step applies.
+type SubmitResult struct {
+	Totals      Totals `json:"totals"`
+	Status      string `json:"status"`
+	WorkflowID  string `json:"workflow_id,omitempty"`
+	CurrentStep int    `json:"current_step"`
+}
+
+// ExportQuery selects approved purchase orders for the accounting export
+type ExportQuery struct {
+	Start time.Time
+	End   time.Time
+	// IncludeExported also returns orders already exported; otherwise each
+	// order is exported once
+	IncludeExported bool
+}
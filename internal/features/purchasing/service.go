@@ -0,0 +1,319 @@
+package purchasing
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/approval"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/record"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const batchSize = 500
+
+var (
+	ErrNotFound         = apperr.NotFound("record not found")
+	ErrAlreadySubmitted = apperr.Conflict("already submitted for approval")
+	ErrUnsupported      = errors.New("only purchase_orders and expenses can be submitted")
+)
+
+type PurchasingService interface {
+	// RecalculateTotals sums a purchase order's line items onto the order
+	RecalculateTotals(ctx context.Context, id string, userID primitive.ObjectID) (*Totals, error)
+	// Submit finalises totals and routes a purchase order or expense into
+	// the approval workflow matching its module
+	Submit(ctx context.Context, moduleName, id string, userID primitive.ObjectID) (*SubmitResult, error)
+	// ExportApprovedCSV returns approved purchase orders as CSV for
+	// accounting and stamps them as exported
+	ExportApprovedCSV(ctx context.Context, q ExportQuery, userID primitive.ObjectID) ([]byte, string, error)
+}
+
+type PurchasingServiceImpl struct {
+	RecordService   record.RecordService
+	RecordRepo      record.RecordRepository
+	ApprovalService approval.ApprovalService
+	AuditService    audit.AuditService
+}
+
+func NewPurchasingService(
+	recordService record.RecordService,
+	recordRepo record.RecordRepository,
+	approvalService approval.ApprovalService,
+	auditService audit.AuditService,
+) PurchasingService {
+	return &PurchasingServiceImpl{
+		RecordService:   recordService,
+		RecordRepo:      recordRepo,
+		ApprovalService: approvalService,
+		AuditService:    auditService,
+	}
+}
+
+func number(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case int:
+		return float64(n)
+	case string:
+		f, _ := strconv.ParseFloat(n, 64)
+		return f
+	}
+	return 0
+}
+
+func round2(v float64) float64 { return math.Round(v*100) / 100 }
+
+func approvalState(rec map[string]interface{}) *common_models.ApprovalRecordState {
+	val, ok := rec["_approval"]
+	if !ok || val == nil {
+		return nil
+	}
+	var state common_models.ApprovalRecordState
+	raw, err := bson.Marshal(val)
+	if err != nil || bson.Unmarshal(raw, &state) != nil {
+		return nil
+	}
+	return &state
+}
+
+// lineTotals sums purchase order items. Lines without taxable_value are
+// priced as qty * unit_price, and tax_rate is used when tax_amount is unset.
+func (s *PurchasingServiceImpl) lineTotals(ctx context.Context, id string, userID primitive.ObjectID) (Totals, error) {
+	var t Totals
+	err := s.RecordService.StreamRecords(ctx, POItemsModule, []common_models.Filter{{Field: "purchase_order_id", Operator: "eq", Value: id}}, nil, batchSize, userID, func(batch []map[string]any) error {
+		for _, item := range batch {
+			taxable := number(item["taxable_value"])
+			if taxable == 0 {
+				taxable = number(item["qty"]) * number(item["unit_price"])
+			}
+			tax := number(item["tax_amount"])
+			if tax == 0 {
+				tax = taxable * number(item["tax_rate"]) / 100
+			}
+			t.TotalValue += taxable
+			t.TotalTax += tax
+			t.Lines++
+		}
+		return nil
+	})
+	t.TotalValue = round2(t.TotalValue)
+	t.TotalTax = round2(t.TotalTax)
+	t.NetAmount = round2(t.TotalValue + t.TotalTax)
+	return t, err
+}
+
+func (t Totals) fields() map[string]interface{} {
+	return map[string]interface{}{
+		"total_value": t.TotalValue,
+		"total_tax":   t.TotalTax,
+		"net_amount":  t.NetAmount,
+	}
+}
+
+func (s *PurchasingServiceImpl) RecalculateTotals(ctx context.Context, id string, userID primitive.ObjectID) (*Totals, error) {
+	if _, err := s.RecordService.GetRecord(ctx, PurchaseOrderModule, id, userID); err != nil {
+		return nil, ErrNotFound
+	}
+	totals, err := s.lineTotals(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.RecordService.UpdateRecord(ctx, PurchaseOrderModule, id, totals.fields(), userID); err != nil {
+		return nil, err
+	}
+	return &totals, nil
+}
+
+func (s *PurchasingServiceImpl) Submit(ctx context.Context, moduleName, id string, userID primitive.ObjectID) (*SubmitResult, error) {
+	if moduleName != PurchaseOrderModule && moduleName != ExpenseModule {
+		return nil, ErrUnsupported
+	}
+	rec, err := s.RecordService.GetRecord(ctx, moduleName, id, userID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if state := approvalState(rec); state != nil && (state.Status == common_models.ApprovalStatusPending || state.Status == common_models.ApprovalStatusApproved) {
+		return nil, ErrAlreadySubmitted
+	}
+
+	var totals Totals
+	if moduleName == PurchaseOrderModule {
+		if totals, err = s.lineTotals(ctx, id, userID); err != nil {
+			return nil, err
+		}
+		if totals.Lines == 0 {
+			return nil, errors.New("purchase order has no line items")
+		}
+	} else {
+		totals.TotalValue = round2(number(rec["amount"]))
+		totals.TotalTax = round2(number(rec["tax"]))
+		totals.NetAmount = round2(totals.TotalValue + totals.TotalTax)
+	}
+
+	update := totals.fields()
+	if moduleName == ExpenseModule {
+		delete(update, "total_value")
+		delete(update, "total_tax")
+	}
+	update["status"] = StatusSubmitted
+	if err := s.RecordService.UpdateRecord(ctx, moduleName, id, update, userID); err != nil {
+		return nil, err
+	}
+	for k, v := range update {
+		rec[k] = v
+	}
+
+	// Route on the submitted amounts; workflow and step criteria such as
+	// net_amount gt 10000 decide who has to sign off
+	state, err := s.ApprovalService.InitializeApproval(ctx, moduleName, rec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check approval workflow: %v", err)
+	}
+	result := &SubmitResult{Totals: totals, Status: string(common_models.ApprovalStatusPending)}
+	if state == nil {
+		result.Status = string(common_models.ApprovalStatusApproved)
+		state = &common_models.ApprovalRecordState{
+			Status:  common_models.ApprovalStatusApproved,
+			History: []common_models.ApprovalHistory{},
+		}
+	} else {
+		result.WorkflowID = state.WorkflowID
+		result.CurrentStep = state.CurrentStep
+	}
+	if err := s.RecordRepo.Update(ctx, moduleName, id, map[string]interface{}{"_approval": state}); err != nil {
+		return nil, err
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionApproval, moduleName, id, map[string]common_models.Change{
+		"_approval":  {Old: nil, New: string(state.Status)},
+		"net_amount": {New: totals.NetAmount},
+	})
+	return result, nil
+}
+
+func label(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case map[string]interface{}:
+		return fmt.Sprint(t["name"])
+	case primitive.ObjectID:
+		return t.Hex()
+	case time.Time:
+		return t.Format("2006-01-02")
+	case primitive.DateTime:
+		return t.Time().UTC().Format("2006-01-02")
+	case float64:
+		return strconv.FormatFloat(t, 'f', 2, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+var exportHeader = []string{
+	"po_number", "date", "vendor", "expected_date", "net_amount", "total_value", "total_tax",
+	"line", "product", "qty", "unit_price", "line_taxable_value", "line_tax", "line_total",
+	"approved_at", "approved_by",
+}
+
+// ExportApprovedCSV writes one row per purchase order line, repeating the
+// order columns, so the file can be imported as bills with their lines
+func (s *PurchasingServiceImpl) ExportApprovedCSV(ctx context.Context, q ExportQuery, userID primitive.ObjectID) ([]byte, string, error) {
+	filters := []common_models.Filter{{Field: "_approval.status", Operator: "eq", Value: string(common_models.ApprovalStatusApproved)}}
+	if !q.Start.IsZero() || !q.End.IsZero() {
+		end := q.End
+		if end.IsZero() {
+			end = time.Now()
+		}
+		filters = append(filters, common_models.Filter{Field: "date", Operator: "between", Value: q.Start.Format(time.RFC3339) + "," + end.Format(time.RFC3339)})
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(exportHeader); err != nil {
+		return nil, "", err
+	}
+
+	var exported []string
+	err := s.RecordService.StreamRecords(ctx, PurchaseOrderModule, filters, nil, batchSize, userID, func(batch []map[string]any) error {
+		for _, po := range batch {
+			if !q.IncludeExported && po["exported_at"] != nil {
+				continue
+			}
+			id := label(po["_id"])
+			head := []string{
+				label(po["po_number"]), label(po["date"]), label(po["vendor_id"]), label(po["expected_date"]),
+				label(number(po["net_amount"])), label(number(po["total_value"])), label(number(po["total_tax"])),
+			}
+			var approvedAt, approvedBy string
+			if state := approvalState(po); state != nil && len(state.History) > 0 {
+				last := state.History[len(state.History)-1]
+				approvedAt = last.Timestamp.UTC().Format(time.RFC3339)
+				approvedBy = last.ActorID
+			}
+
+			lines := 0
+			err := s.RecordService.StreamRecords(ctx, POItemsModule, []common_models.Filter{{Field: "purchase_order_id", Operator: "eq", Value: id}}, nil, batchSize, userID, func(items []map[string]any) error {
+				for _, item := range items {
+					lines++
+					taxable := number(item["taxable_value"])
+					if taxable == 0 {
+						taxable = number(item["qty"]) * number(item["unit_price"])
+					}
+					row := append(append([]string{}, head...),
+						strconv.Itoa(lines), label(item["item_id"]), label(number(item["qty"])), label(number(item["unit_price"])),
+						label(taxable), label(number(item["tax_amount"])), label(number(item["total_line_amount"])),
+						approvedAt, approvedBy)
+					if err := w.Write(row); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if lines == 0 {
+				row := append(append([]string{}, head...), "", "", "", "", "", "", "", approvedAt, approvedBy)
+				if err := w.Write(row); err != nil {
+					return err
+				}
+			}
+			exported = append(exported, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	for _, id := range exported {
+		_ = s.RecordRepo.Update(ctx, PurchaseOrderModule, id, map[string]interface{}{"exported_at": now})
+	}
+	if len(exported) > 0 {
+		_ = s.AuditService.LogChange(ctx, common_models.AuditActionSync, PurchaseOrderModule, "accounting_csv", map[string]common_models.Change{
+			"exported": {New: len(exported)},
+		})
+	}
+	return buf.Bytes(), fmt.Sprintf("purchase_orders_%s.csv", now.Format("20060102_150405")), nil
+}
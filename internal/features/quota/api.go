@@ -0,0 +1,29 @@
+package quota
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type QuotaApi struct {
+	controller  *QuotaController
+	config      *config.Config
+	roleService middleware.RoleService
+}
+
+func NewQuotaApi(controller *QuotaController, config *config.Config, roleService middleware.RoleService) *QuotaApi {
+	return &QuotaApi{
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *QuotaApi) Setup(app *fiber.App) {
+	group := app.Group("/api/billing", middleware.AuthMiddleware(h.config.SkipAuth))
+
+	group.Get("/usage", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.GetUsage)
+	group.Get("/plans", h.controller.ListPlans)
+}
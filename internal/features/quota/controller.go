@@ -0,0 +1,41 @@
+package quota
+
+import (
+	common_api "go-crm/internal/common/api"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type QuotaController struct {
+	Service QuotaService
+}
+
+func NewQuotaController(service QuotaService) *QuotaController {
+	return &QuotaController{Service: service}
+}
+
+// GetUsage godoc
+// @Summary Get plan usage
+// @Description The tenant's consumption against its plan limits: records per module, users, automation rules and file storage. near_limit names the resources past 80% of their limit. A limit of 0 means unlimited.
+// @Tags billing
+// @Produce json
+// @Success 200 {object} Usage
+// @Router /api/billing/usage [get]
+func (c *QuotaController) GetUsage(ctx *fiber.Ctx) error {
+	usage, err := c.Service.Usage(ctx.UserContext())
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+	return ctx.JSON(fiber.Map{"data": usage})
+}
+
+// ListPlans godoc
+// @Summary List plans
+// @Description The limits of each plan, for comparing upgrades. A limit of 0 means unlimited.
+// @Tags billing
+// @Produce json
+// @Success 200 {object} map[string]Limits
+// @Router /api/billing/plans [get]
+func (c *QuotaController) ListPlans(ctx *fiber.Ctx) error {
+	return ctx.JSON(fiber.Map{"data": Plans})
+}
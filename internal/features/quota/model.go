@@ -0,0 +1,71 @@
+package quota
+
+// Resources a plan limits, as named in Usage.NearLimit
+const (
+	ResourceRecords     = "records"
+	ResourceUsers       = "users"
+	ResourceAutomations = "automations"
+	ResourceStorage     = "storage"
+)
+
+// UpsellThreshold is the share of a limit past which a resource is reported
+// as near its limit
+const UpsellThreshold = 0.8
+
+// Limits caps what a tenant may hold. Zero means unlimited.
+type Limits struct {
+	MaxRecordsPerModule int64 `json:"max_records_per_module"`
+	MaxUsers            int64 `json:"max_users"`
+	MaxAutomations      int64 `json:"max_automations"`
+	MaxStorageBytes     int64 `json:"max_storage_bytes"`
+}
+
+// Plans holds the limits of each plan an organization can be on.
+// Organizations without a plan, or on one not listed, are unlimited.
+var Plans = map[string]Limits{
+	"free": {
+		MaxRecordsPerModule: 1000,
+		MaxUsers:            3,
+		MaxAutomations:      5,
+		MaxStorageBytes:     1 << 30,
+	},
+	"starter": {
+		MaxRecordsPerModule: 10000,
+		MaxUsers:            10,
+		MaxAutomations:      25,
+		MaxStorageBytes:     10 << 30,
+	},
+	"professional": {
+		MaxRecordsPerModule: 100000,
+		MaxUsers:            50,
+		MaxAutomations:      100,
+		MaxStorageBytes:     100 << 30,
+	},
+	"enterprise": {},
+}
+
+// Meter is the consumption of one resource against its limit
+type Meter struct {
+	Used    int64   `json:"used"`
+	Limit   int64   `json:"limit"`   // 0 when unlimited
+	Percent float64 `json:"percent"` // Share of the limit used, 0 when unlimited
+}
+
+// ModuleMeter is the record count of one module against the per-module limit
+type ModuleMeter struct {
+	Module string `json:"module"`
+	Meter
+}
+
+// Usage is a tenant's consumption against its plan
+type Usage struct {
+	Plan        string        `json:"plan"`
+	Limits      Limits        `json:"limits"`
+	Records     []ModuleMeter `json:"records"` // Modules holding records, fullest first
+	Users       Meter         `json:"users"`
+	Automations Meter         `json:"automations"`
+	Storage     Meter         `json:"storage"` // Bytes
+	// NearLimit names the resources past UpsellThreshold of their limit, for
+	// plan upgrade prompts
+	NearLimit []string `json:"near_limit"`
+}
@@ -0,0 +1,125 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+// UsageRepository counts what the tenant holds. It reads the collections of
+// the features it meters rather than going through their services, which
+// check their quotas here.
+type UsageRepository interface {
+	CountRecords(ctx context.Context, moduleName string) (int64, error)
+	// CountRecordsByModule returns the record count of each module holding records
+	CountRecordsByModule(ctx context.Context) (map[string]int64, error)
+	CountUsers(ctx context.Context) (int64, error)
+	CountAutomations(ctx context.Context) (int64, error)
+	// StorageBytes sums the size of the tenant's stored files
+	StorageBytes(ctx context.Context) (int64, error)
+}
+
+type UsageRepositoryImpl struct {
+	records     *mongo.Collection
+	users       *mongo.Collection
+	automations *mongo.Collection
+	files       *mongo.Collection
+}
+
+func NewUsageRepository(db *database.MongodbDB) UsageRepository {
+	return &UsageRepositoryImpl{
+		records:     db.DB.Collection("entity_records"),
+		users:       db.DB.Collection("users"),
+		automations: db.DB.Collection("automation_rules"),
+		files:       db.DB.Collection("files"),
+	}
+}
+
+func (r *UsageRepositoryImpl) CountRecords(ctx context.Context, moduleName string) (int64, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return r.records.CountDocuments(ctx, bson.M{"tenant_id": tenantID, "entity": moduleName, "deleted": bson.M{"$ne": true}})
+}
+
+func (r *UsageRepositoryImpl) CountRecordsByModule(ctx context.Context) (map[string]int64, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := r.records.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"tenant_id": tenantID, "deleted": bson.M{"$ne": true}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$entity", "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Module string `bson:"_id"`
+		Count  int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Module] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *UsageRepositoryImpl) CountUsers(ctx context.Context) (int64, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return r.users.CountDocuments(ctx, bson.M{"tenant_id": tenantID})
+}
+
+func (r *UsageRepositoryImpl) CountAutomations(ctx context.Context) (int64, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return r.automations.CountDocuments(ctx, bson.M{"tenant_id": tenantID})
+}
+
+func (r *UsageRepositoryImpl) StorageBytes(ctx context.Context) (int64, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	cursor, err := r.files.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"tenant_id": tenantID}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "bytes": bson.M{"$sum": "$size"}}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Bytes int64 `bson:"bytes"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil || len(rows) == 0 {
+		return 0, err
+	}
+	return rows[0].Bytes, nil
+}
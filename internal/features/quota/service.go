@@ -0,0 +1,202 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go-crm/internal/common/apperr"
+	"go-crm/internal/common/models"
+	"go-crm/internal/features/organization"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// QuotaService enforces the limits of the tenant's plan. Checks run before a
+// create and count what the tenant holds, so concurrent creates may overshoot
+// a limit by the few that pass together. Contexts without a tenant, such as
+// system jobs, are not limited.
+type QuotaService interface {
+	// CheckRecord fails when the module holds as many records as the plan allows
+	CheckRecord(ctx context.Context, moduleName string) error
+	CheckUser(ctx context.Context) error
+	CheckAutomation(ctx context.Context) error
+	// CheckStorage fails when size more bytes would not fit the plan's storage
+	CheckStorage(ctx context.Context, size int64) error
+	// Usage reports the tenant's consumption against its plan
+	Usage(ctx context.Context) (*Usage, error)
+}
+
+type QuotaServiceImpl struct {
+	Repo             UsageRepository
+	OrganizationRepo organization.OrganizationRepository
+}
+
+func NewQuotaService(repo UsageRepository, orgRepo organization.OrganizationRepository) QuotaService {
+	return &QuotaServiceImpl{
+		Repo:             repo,
+		OrganizationRepo: orgRepo,
+	}
+}
+
+// plan returns the tenant's plan and its limits
+func (s *QuotaServiceImpl) plan(ctx context.Context) (string, Limits, error) {
+	tenantID, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantID == "" {
+		return "", Limits{}, nil
+	}
+	org, err := s.OrganizationRepo.FindByID(ctx, tenantID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", Limits{}, nil
+	}
+	if err != nil {
+		return "", Limits{}, err
+	}
+	name := strings.ToLower(org.Plan)
+	return name, Plans[name], nil
+}
+
+func (s *QuotaServiceImpl) CheckRecord(ctx context.Context, moduleName string) error {
+	plan, limits, err := s.plan(ctx)
+	if err != nil || limits.MaxRecordsPerModule == 0 {
+		return err
+	}
+	count, err := s.Repo.CountRecords(ctx, moduleName)
+	if err != nil {
+		return err
+	}
+	if count >= limits.MaxRecordsPerModule {
+		return apperr.QuotaExceeded("record limit reached: the %s plan allows %d records per module and %s has %d", plan, limits.MaxRecordsPerModule, moduleName, count)
+	}
+	return nil
+}
+
+func (s *QuotaServiceImpl) CheckUser(ctx context.Context) error {
+	plan, limits, err := s.plan(ctx)
+	if err != nil || limits.MaxUsers == 0 {
+		return err
+	}
+	count, err := s.Repo.CountUsers(ctx)
+	if err != nil {
+		return err
+	}
+	if count >= limits.MaxUsers {
+		return apperr.QuotaExceeded("user limit reached: the %s plan allows %d users", plan, limits.MaxUsers)
+	}
+	return nil
+}
+
+func (s *QuotaServiceImpl) CheckAutomation(ctx context.Context) error {
+	plan, limits, err := s.plan(ctx)
+	if err != nil || limits.MaxAutomations == 0 {
+		return err
+	}
+	count, err := s.Repo.CountAutomations(ctx)
+	if err != nil {
+		return err
+	}
+	if count >= limits.MaxAutomations {
+		return apperr.QuotaExceeded("automation limit reached: the %s plan allows %d automation rules", plan, limits.MaxAutomations)
+	}
+	return nil
+}
+
+func (s *QuotaServiceImpl) CheckStorage(ctx context.Context, size int64) error {
+	plan, limits, err := s.plan(ctx)
+	if err != nil || limits.MaxStorageBytes == 0 {
+		return err
+	}
+	used, err := s.Repo.StorageBytes(ctx)
+	if err != nil {
+		return err
+	}
+	if used+size > limits.MaxStorageBytes {
+		return apperr.QuotaExceeded("storage limit reached: the %s plan allows %s and %s is in use", plan, formatBytes(limits.MaxStorageBytes), formatBytes(used))
+	}
+	return nil
+}
+
+func (s *QuotaServiceImpl) Usage(ctx context.Context) (*Usage, error) {
+	plan, limits, err := s.plan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	usage := &Usage{Plan: plan, Limits: limits, Records: []ModuleMeter{}, NearLimit: []string{}}
+
+	counts, err := s.Repo.CountRecordsByModule(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nearRecords := false
+	for module, count := range counts {
+		m := ModuleMeter{Module: module, Meter: meter(count, limits.MaxRecordsPerModule)}
+		nearRecords = nearRecords || near(m.Meter)
+		usage.Records = append(usage.Records, m)
+	}
+	sort.Slice(usage.Records, func(i, j int) bool {
+		if usage.Records[i].Used != usage.Records[j].Used {
+			return usage.Records[i].Used > usage.Records[j].Used
+		}
+		return usage.Records[i].Module < usage.Records[j].Module
+	})
+	if nearRecords {
+		usage.NearLimit = append(usage.NearLimit, ResourceRecords)
+	}
+
+	users, err := s.Repo.CountUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	automations, err := s.Repo.CountAutomations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	storage, err := s.Repo.StorageBytes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	usage.Users = meter(users, limits.MaxUsers)
+	usage.Automations = meter(automations, limits.MaxAutomations)
+	usage.Storage = meter(storage, limits.MaxStorageBytes)
+	for _, r := range []struct {
+		name  string
+		meter Meter
+	}{
+		{ResourceUsers, usage.Users},
+		{ResourceAutomations, usage.Automations},
+		{ResourceStorage, usage.Storage},
+	} {
+		if near(r.meter) {
+			usage.NearLimit = append(usage.NearLimit, r.name)
+		}
+	}
+	return usage, nil
+}
+
+func meter(used, limit int64) Meter {
+	m := Meter{Used: used, Limit: limit}
+	if limit > 0 {
+		m.Percent = float64(used) / float64(limit) * 100
+	}
+	return m
+}
+
+func near(m Meter) bool {
+	return m.Limit > 0 && m.Percent >= UpsellThreshold*100
+}
+
+// formatBytes renders a size in binary units, e.g. 1.5 GB
+func formatBytes(n int64) string {
+	const unit = 1 << 10
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
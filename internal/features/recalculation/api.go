@@ -0,0 +1,32 @@
+package recalculation
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type RecalculationApi struct {
+	controller  *RecalculationController
+	config      *config.Config
+	roleService middleware.RoleService
+}
+
+func NewRecalculationApi(controller *RecalculationController, config *config.Config, roleService middleware.RoleService) *RecalculationApi {
+	return &RecalculationApi{
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *RecalculationApi) Setup(app *fiber.App) {
+	group := app.Group("/api/admin/recalculations", middleware.AuthMiddleware(h.config.SkipAuth))
+
+	group.Post("/", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.StartJob)
+	group.Get("/", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListJobs)
+	group.Get("/:id", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.GetJob)
+	group.Post("/:id/resume", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.ResumeJob)
+	group.Post("/:id/cancel", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.CancelJob)
+}
@@ -0,0 +1,109 @@
+package recalculation
+
+import (
+	common_api "go-crm/internal/common/api"
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type RecalculationController struct {
+	Service RecalculationService
+}
+
+func NewRecalculationController(service RecalculationService) *RecalculationController {
+	return &RecalculationController{Service: service}
+}
+
+// StartJob godoc
+// @Summary Start a recalculation job
+// @Description Rebuild derived data in the background: phone_index re-indexes phone numbers for the module's records matching the filters, counters recounts its list counts, data_quality re-evaluates its rules sla recomputes the due dates of open tickets and deal_scores retrains the win-probability model and rescores open opportunities. lead_scores, rollups, formulas and search_index are rejected: leads are not scored, rollups are computed on read, formula fields do not exist and there is no search index. Poll the returned job for progress.
+// @Tags recalculation
+// @Accept json
+// @Produce json
+// @Param request body JobRequest true "Module, filters and targets"
+// @Success 202 {object} Job
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{} "A job is already queued or running for the module"
+// @Failure 422 {object} map[string]interface{} "Unknown or unsupported target, or missing module"
+// @Router /api/admin/recalculations [post]
+func (c *RecalculationController) StartJob(ctx *fiber.Ctx) error {
+	var req JobRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return common_api.InvalidBody(ctx, err)
+	}
+	userID, _ := session.UserID(ctx.UserContext())
+	job, err := c.Service.Start(ctx.UserContext(), req, userID)
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+	return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{"data": job})
+}
+
+// ListJobs godoc
+// @Summary List recalculation jobs
+// @Description Jobs, newest first, with their progress
+// @Tags recalculation
+// @Produce json
+// @Param limit query int false "Jobs to return (default 20, max 100)"
+// @Success 200 {array} Job
+// @Router /api/admin/recalculations [get]
+func (c *RecalculationController) ListJobs(ctx *fiber.Ctx) error {
+	jobs, err := c.Service.ListJobs(ctx.UserContext(), int64(ctx.QueryInt("limit")))
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+	return ctx.JSON(fiber.Map{"data": jobs})
+}
+
+// GetJob godoc
+// @Summary Get recalculation job
+// @Description A job's status, overall progress and each step's counts
+// @Tags recalculation
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} Job
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/admin/recalculations/{id} [get]
+func (c *RecalculationController) GetJob(ctx *fiber.Ctx) error {
+	job, err := c.Service.GetJob(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+	return ctx.JSON(fiber.Map{"data": job})
+}
+
+// ResumeJob godoc
+// @Summary Resume recalculation job
+// @Description Continue a failed or cancelled job after the last batch it finished
+// @Tags recalculation
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 202 {object} Job
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{} "The job is not failed or cancelled, or another job is active"
+// @Router /api/admin/recalculations/{id}/resume [post]
+func (c *RecalculationController) ResumeJob(ctx *fiber.Ctx) error {
+	job, err := c.Service.Resume(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return common_api.Error(ctx, err)
+	}
+	return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{"data": job})
+}
+
+// CancelJob godoc
+// @Summary Cancel recalculation job
+// @Description Stop a queued or running job after its current batch; it can be resumed later
+// @Tags recalculation
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{} "The job has already finished"
+// @Router /api/admin/recalculations/{id}/cancel [post]
+func (c *RecalculationController) CancelJob(ctx *fiber.Ctx) error {
+	if err := c.Service.Cancel(ctx.UserContext(), ctx.Params("id")); err != nil {
+		return common_api.Error(ctx, err)
+	}
+	return ctx.JSON(fiber.Map{"message": "Cancellation requested"})
+}
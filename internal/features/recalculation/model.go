@@ -0,0 +1,99 @@
+package recalculation
+
+import (
+	"time"
+
+	"go-crm/internal/common/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Targets are the derived data a job can rebuild
+const (
+	// TargetPhoneIndex re-indexes the E.164 forms phone filters and caller-ID
+	// lookups search, for the module's records matching the job's filters
+	TargetPhoneIndex = "phone_index"
+	// TargetCounters recounts the module's list counts
+	TargetCounters = "counters"
+	// TargetDataQuality re-evaluates the module's data quality rules,
+	// refreshing its score and violations
+	TargetDataQuality = "data_quality"
+	// TargetSLA recomputes the SLA due dates, and so the breach flags, of
+	// open tickets from the policies now in place
+	TargetSLA = "sla"
+	// TargetDealScores retrains the tenant's win-probability model and
+	// rescores its open opportunities
+	TargetDealScores = "deal_scores"
+)
+
+// Targets lists every target in the order a job runs them
+var Targets = []string{TargetPhoneIndex, TargetCounters, TargetDataQuality, TargetSLA, TargetDealScores}
+
+// unsupportedTargets is derived data a job cannot rebuild, with the reason a
+// request naming it is rejected
+var unsupportedTargets = map[string]string{
+	"lead_scores":  "leads are not scored; only opportunities have scores, rebuilt by deal_scores",
+	"rollups":      "rollups are aggregated when they are read and are not stored",
+	"formulas":     "formula fields are not supported",
+	"search_index": "records are searched with database queries, so there is no search index to rebuild",
+}
+
+// moduleTargets need the job's module; the others ignore it
+var moduleTargets = map[string]bool{
+	TargetPhoneIndex:  true,
+	TargetCounters:    true,
+	TargetDataQuality: true,
+}
+
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobCompleted JobStatus = "completed"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// Step is a job's progress through one target
+type Step struct {
+	Target string    `json:"target" bson:"target"`
+	Status JobStatus `json:"status" bson:"status"`
+	// Total is the number of records the step goes through, when known
+	Total     int64  `json:"total,omitempty" bson:"total,omitempty"`
+	Processed int    `json:"processed" bson:"processed"`
+	Updated   int    `json:"updated" bson:"updated"`
+	Error     string `json:"error,omitempty" bson:"error,omitempty"`
+	// Cursor is the ID of the last record done; a resumed step continues after it
+	Cursor      primitive.ObjectID `json:"-" bson:"cursor,omitempty"`
+	CompletedAt *time.Time         `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+}
+
+// Job rebuilds derived data in the background, one target after another
+type Job struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	Module   string             `json:"module,omitempty" bson:"module,omitempty"`
+	// Filters narrow record targets to a subset of the module
+	Filters []models.Filter `json:"filters,omitempty" bson:"filters,omitempty"`
+	Steps   []Step          `json:"steps" bson:"steps"`
+	Status  JobStatus       `json:"status" bson:"status"`
+	Error   string          `json:"error,omitempty" bson:"error,omitempty"`
+	// Progress is the share of steps done, counting partly processed record
+	// steps by their records, as a percentage
+	Progress        float64            `json:"progress" bson:"-"`
+	CancelRequested bool               `json:"cancel_requested,omitempty" bson:"cancel_requested,omitempty"`
+	StartedBy       primitive.ObjectID `json:"started_by" bson:"started_by"`
+	CreatedAt       time.Time          `json:"created_at" bson:"created_at"`
+	// UpdatedAt moves with every batch, so a job nothing updates for a while
+	// was left behind by a stopped server
+	UpdatedAt   time.Time  `json:"updated_at" bson:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+}
+
+// JobRequest starts a job
+type JobRequest struct {
+	Module  string          `json:"module"`
+	Filters []models.Filter `json:"filters"`
+	Targets []string        `json:"targets"`
+}
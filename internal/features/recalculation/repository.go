@@ -0,0 +1,179 @@
+package recalculation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+var activeStatuses = []JobStatus{JobQueued, JobRunning}
+
+type JobRepository interface {
+	Create(ctx context.Context, job *Job) error
+	// Save writes the job's progress. A cancel request made meanwhile is kept.
+	Save(ctx context.Context, job *Job) error
+	// Requeue saves a job about to be resumed, clearing its cancel request
+	Requeue(ctx context.Context, job *Job) error
+	Get(ctx context.Context, id string) (*Job, error)
+	// List returns the tenant's jobs, newest first
+	List(ctx context.Context, limit int64) ([]Job, error)
+	// FindActive returns the tenant's queued or running job on the module, if any
+	FindActive(ctx context.Context, module string) (*Job, error)
+	// RequestCancel flags a queued or running job; it reports whether one was
+	RequestCancel(ctx context.Context, id string) (bool, error)
+	// ClaimStale takes over a job of any tenant left running with no
+	// progress since before, or returns nil when there is none
+	ClaimStale(ctx context.Context, before time.Time) (*Job, error)
+}
+
+type JobRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewJobRepository(db *database.MongodbDB) JobRepository {
+	return &JobRepositoryImpl{
+		collection: db.DB.Collection("recalculation_jobs"),
+	}
+}
+
+func (r *JobRepositoryImpl) Create(ctx context.Context, job *Job) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	job.ID = primitive.NewObjectID()
+	job.TenantID = tenantID
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	_, err = r.collection.InsertOne(ctx, job)
+	return err
+}
+
+func (r *JobRepositoryImpl) Save(ctx context.Context, job *Job) error {
+	job.UpdatedAt = time.Now()
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": job.ID, "tenant_id": job.TenantID}, bson.M{"$set": bson.M{
+		"steps":        job.Steps,
+		"status":       job.Status,
+		"error":        job.Error,
+		"updated_at":   job.UpdatedAt,
+		"completed_at": job.CompletedAt,
+	}})
+	return err
+}
+
+func (r *JobRepositoryImpl) Requeue(ctx context.Context, job *Job) error {
+	job.UpdatedAt = time.Now()
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": job.ID, "tenant_id": job.TenantID}, bson.M{
+		"$set": bson.M{
+			"steps":        job.Steps,
+			"status":       job.Status,
+			"error":        job.Error,
+			"updated_at":   job.UpdatedAt,
+			"completed_at": job.CompletedAt,
+		},
+		"$unset": bson.M{"cancel_requested": ""},
+	})
+	return err
+}
+
+func (r *JobRepositoryImpl) Get(ctx context.Context, id string) (*Job, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, mongo.ErrNoDocuments
+	}
+	var job Job
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID}).Decode(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *JobRepositoryImpl) List(ctx context.Context, limit int64) ([]Job, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, bson.M{"tenant_id": tenantID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	jobs := []Job{}
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (r *JobRepositoryImpl) FindActive(ctx context.Context, module string) (*Job, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var job Job
+	err = r.collection.FindOne(ctx, bson.M{"tenant_id": tenantID, "module": module, "status": bson.M{"$in": activeStatuses}}).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *JobRepositoryImpl) RequestCancel(ctx context.Context, id string) (bool, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+	res, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": oid, "tenant_id": tenantID, "status": bson.M{"$in": activeStatuses}},
+		bson.M{"$set": bson.M{"cancel_requested": true}})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (r *JobRepositoryImpl) ClaimStale(ctx context.Context, before time.Time) (*Job, error) {
+	var job Job
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"status": bson.M{"$in": activeStatuses}, "updated_at": bson.M{"$lt": before}},
+		bson.M{"$set": bson.M{"updated_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
@@ -0,0 +1,412 @@
+package recalculation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/common/validation"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/data_quality"
+	"go-crm/internal/features/forecast"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/ticket"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// batchSize is how many records a step handles between progress saves
+	batchSize = 200
+	// staleAfter is how long a running job goes without progress before
+	// ResumeInterrupted takes it over
+	staleAfter = 10 * time.Minute
+
+	defaultJobList = 20
+	maxJobList     = 100
+)
+
+var (
+	ErrJobNotFound = apperr.NotFound("recalculation job not found")
+	ErrJobActive   = apperr.Conflict("a recalculation job is already queued or running for this module")
+	ErrJobFinished = apperr.Conflict("only failed or cancelled jobs can be resumed")
+)
+
+type RecalculationService interface {
+	// Start queues a job and runs it in the background
+	Start(ctx context.Context, req JobRequest, userID primitive.ObjectID) (*Job, error)
+	// Resume continues a failed or cancelled job from where it stopped
+	Resume(ctx context.Context, id string) (*Job, error)
+	// Cancel stops a queued or running job after its current batch
+	Cancel(ctx context.Context, id string) error
+	GetJob(ctx context.Context, id string) (*Job, error)
+	ListJobs(ctx context.Context, limit int64) ([]Job, error)
+	// ResumeInterrupted picks up the jobs of every tenant a stopped server
+	// left running; called on startup
+	ResumeInterrupted(ctx context.Context)
+}
+
+type RecalculationServiceImpl struct {
+	Repo          JobRepository
+	RecordService record.RecordService
+	DataQuality   data_quality.DataQualityService
+	TicketService ticket.TicketService
+	Forecast      forecast.ForecastService
+	AuditService  audit.AuditService
+
+	running sync.Map // job ID -> struct{}
+}
+
+func NewRecalculationService(
+	repo JobRepository,
+	recordService record.RecordService,
+	dataQuality data_quality.DataQualityService,
+	ticketService ticket.TicketService,
+	forecastService forecast.ForecastService,
+	auditService audit.AuditService,
+) RecalculationService {
+	return &RecalculationServiceImpl{
+		Repo:          repo,
+		RecordService: recordService,
+		DataQuality:   dataQuality,
+		TicketService: ticketService,
+		Forecast:      forecastService,
+		AuditService:  auditService,
+	}
+}
+
+func (s *RecalculationServiceImpl) Start(ctx context.Context, req JobRequest, userID primitive.ObjectID) (*Job, error) {
+	steps, err := s.plan(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	active, err := s.Repo.FindActive(ctx, req.Module)
+	if err != nil {
+		return nil, err
+	}
+	if active != nil {
+		return nil, ErrJobActive
+	}
+
+	job := &Job{
+		Module:    req.Module,
+		Filters:   req.Filters,
+		Steps:     steps,
+		Status:    JobQueued,
+		StartedBy: userID,
+	}
+	if err := s.Repo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+	targets := make([]string, len(steps))
+	for i, step := range steps {
+		targets[i] = step.Target
+	}
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "recalculation", job.ID.Hex(), map[string]common_models.Change{
+		"module":  {New: req.Module},
+		"targets": {New: targets},
+	})
+
+	s.launch(job)
+	withProgress(job)
+	return job, nil
+}
+
+// plan checks the request and returns the job's steps, in Targets order
+func (s *RecalculationServiceImpl) plan(ctx context.Context, req JobRequest) ([]Step, error) {
+	var errs validation.Errors
+	requested := map[string]bool{}
+	for i, target := range req.Targets {
+		known := false
+		for _, t := range Targets {
+			known = known || t == target
+		}
+		if reason, ok := unsupportedTargets[target]; ok {
+			errs.Add(fmt.Sprintf("targets.%d", i), validation.CodeNotAllowed, fmt.Sprintf("target '%s' cannot be recalculated: %s", target, reason))
+			continue
+		}
+		if !known {
+			errs.Add(fmt.Sprintf("targets.%d", i), validation.CodeInvalid, fmt.Sprintf("unknown target '%s'; expected one of %v", target, Targets))
+			continue
+		}
+		requested[target] = true
+	}
+	if len(req.Targets) == 0 {
+		errs.Add("targets", validation.CodeRequired, "at least one target is required")
+	}
+
+	needsModule := false
+	for target := range requested {
+		needsModule = needsModule || moduleTargets[target]
+	}
+	if needsModule && req.Module == "" {
+		errs.Add("module", validation.CodeRequired, "module is required for phone_index, counters and data_quality")
+	}
+	if len(req.Filters) > 0 && !requested[TargetPhoneIndex] {
+		errs.Add("filters", validation.CodeNotAllowed, "filters only apply to phone_index")
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	var steps []Step
+	for _, target := range Targets {
+		if !requested[target] {
+			continue
+		}
+		step := Step{Target: target, Status: JobQueued}
+		if target == TargetPhoneIndex {
+			// Sizes the step and checks the module and filters up front
+			total, err := s.RecordService.CountMatching(ctx, req.Module, req.Filters)
+			if err != nil {
+				return nil, err
+			}
+			step.Total = total
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func (s *RecalculationServiceImpl) Resume(ctx context.Context, id string) (*Job, error) {
+	job, err := s.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != JobFailed && job.Status != JobCancelled {
+		return nil, ErrJobFinished
+	}
+	active, err := s.Repo.FindActive(ctx, job.Module)
+	if err != nil {
+		return nil, err
+	}
+	if active != nil {
+		return nil, ErrJobActive
+	}
+
+	job.Status = JobQueued
+	job.Error = ""
+	job.CompletedAt = nil
+	job.CancelRequested = false
+	for i := range job.Steps {
+		if job.Steps[i].Status != JobCompleted {
+			job.Steps[i].Status = JobQueued
+			job.Steps[i].Error = ""
+		}
+	}
+	if err := s.Repo.Requeue(ctx, job); err != nil {
+		return nil, err
+	}
+	s.launch(job)
+	withProgress(job)
+	return job, nil
+}
+
+func (s *RecalculationServiceImpl) Cancel(ctx context.Context, id string) error {
+	flagged, err := s.Repo.RequestCancel(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !flagged {
+		if _, err := s.GetJob(ctx, id); err != nil {
+			return err
+		}
+		return apperr.Conflict("the job has already finished")
+	}
+	return nil
+}
+
+func (s *RecalculationServiceImpl) GetJob(ctx context.Context, id string) (*Job, error) {
+	job, err := s.Repo.Get(ctx, id)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return withProgress(job), nil
+}
+
+func (s *RecalculationServiceImpl) ListJobs(ctx context.Context, limit int64) ([]Job, error) {
+	if limit <= 0 {
+		limit = defaultJobList
+	}
+	if limit > maxJobList {
+		limit = maxJobList
+	}
+	jobs, err := s.Repo.List(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range jobs {
+		withProgress(&jobs[i])
+	}
+	return jobs, nil
+}
+
+func (s *RecalculationServiceImpl) ResumeInterrupted(ctx context.Context) {
+	for {
+		job, err := s.Repo.ClaimStale(ctx, time.Now().Add(-staleAfter))
+		if err != nil {
+			log.Printf("recalculation: failed to claim interrupted jobs: %v", err)
+			return
+		}
+		if job == nil {
+			return
+		}
+		log.Printf("recalculation: resuming job %s of tenant %s", job.ID.Hex(), job.TenantID.Hex())
+		// Runs in turn, so a restart does not start every tenant's job at once
+		s.execute(job)
+	}
+}
+
+// launch runs the job in the background, once per process
+func (s *RecalculationServiceImpl) launch(job *Job) {
+	copied := *job
+	copied.Steps = append([]Step(nil), job.Steps...)
+	go s.execute(&copied)
+}
+
+func (s *RecalculationServiceImpl) execute(job *Job) {
+	if _, busy := s.running.LoadOrStore(job.ID.Hex(), struct{}{}); busy {
+		return
+	}
+	defer s.running.Delete(job.ID.Hex())
+
+	// Background run keeps the tenant so record reads stay scoped
+	ctx := context.WithValue(context.Background(), common_models.TenantIDKey, job.TenantID.Hex())
+	job.Status = JobRunning
+	s.save(ctx, job)
+
+	for i := range job.Steps {
+		step := &job.Steps[i]
+		if step.Status == JobCompleted {
+			continue
+		}
+		step.Status = JobRunning
+		s.save(ctx, job)
+
+		if err := s.runStep(ctx, job, step); err != nil {
+			if errors.Is(err, errCancelled) {
+				step.Status = JobCancelled
+				s.finish(ctx, job, JobCancelled, "")
+				return
+			}
+			step.Status = JobFailed
+			step.Error = err.Error()
+			s.finish(ctx, job, JobFailed, fmt.Sprintf("%s: %v", step.Target, err))
+			return
+		}
+		now := time.Now()
+		step.Status = JobCompleted
+		step.CompletedAt = &now
+		s.save(ctx, job)
+	}
+	s.finish(ctx, job, JobCompleted, "")
+}
+
+var errCancelled = errors.New("cancelled")
+
+// runStep works through one target. Record targets save their cursor after
+// every batch and stop between batches when the job is cancelled.
+func (s *RecalculationServiceImpl) runStep(ctx context.Context, job *Job, step *Step) error {
+	switch step.Target {
+	case TargetCounters:
+		if s.cancelled(ctx, job) {
+			return errCancelled
+		}
+		return s.RecordService.RebuildCounter(ctx, job.Module)
+	case TargetDataQuality:
+		if s.cancelled(ctx, job) {
+			return errCancelled
+		}
+		score, err := s.DataQuality.Evaluate(ctx, job.Module)
+		if err != nil {
+			return err
+		}
+		step.Processed = score.RecordsChecked
+		return nil
+	case TargetDealScores:
+		if s.cancelled(ctx, job) {
+			return errCancelled
+		}
+		_, err := s.Forecast.RefreshDealScores(ctx)
+		return err
+	}
+
+	for {
+		if s.cancelled(ctx, job) {
+			return errCancelled
+		}
+		var processed, updated int
+		var last primitive.ObjectID
+		var done bool
+		switch step.Target {
+		case TargetPhoneIndex:
+			batch, err := s.RecordService.RecalculateRecords(ctx, job.Module, job.Filters, step.Cursor, batchSize)
+			if err != nil {
+				return err
+			}
+			processed, updated, last, done = batch.Processed, batch.Updated, batch.Last, batch.Done
+		case TargetSLA:
+			batch, err := s.TicketService.RecalculateSLA(ctx, step.Cursor, batchSize)
+			if err != nil {
+				return err
+			}
+			processed, updated, last, done = batch.Processed, batch.Updated, batch.Last, batch.Done
+		default:
+			return fmt.Errorf("unknown target '%s'", step.Target)
+		}
+		step.Processed += processed
+		step.Updated += updated
+		step.Cursor = last
+		if done {
+			return nil
+		}
+		s.save(ctx, job)
+	}
+}
+
+// cancelled reports whether a cancel was requested since the job started
+func (s *RecalculationServiceImpl) cancelled(ctx context.Context, job *Job) bool {
+	current, err := s.Repo.Get(ctx, job.ID.Hex())
+	return err == nil && current.CancelRequested
+}
+
+func (s *RecalculationServiceImpl) finish(ctx context.Context, job *Job, status JobStatus, message string) {
+	now := time.Now()
+	job.Status = status
+	job.Error = message
+	job.CompletedAt = &now
+	s.save(ctx, job)
+}
+
+func (s *RecalculationServiceImpl) save(ctx context.Context, job *Job) {
+	if err := s.Repo.Save(ctx, job); err != nil {
+		log.Printf("recalculation: failed to save job %s: %v", job.ID.Hex(), err)
+	}
+}
+
+// withProgress fills in the job's progress
+func withProgress(job *Job) *Job {
+	if len(job.Steps) == 0 {
+		return job
+	}
+	var done float64
+	for _, step := range job.Steps {
+		switch {
+		case step.Status == JobCompleted:
+			done++
+		case step.Total > 0:
+			done += min(float64(step.Processed)/float64(step.Total), 1)
+		}
+	}
+	job.Progress = done / float64(len(job.Steps)) * 100
+	return job
+}
@@ -0,0 +1,51 @@
+package recalculation
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go-crm/internal/common/validation"
+)
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       JobRequest
+		wantSteps []string
+		wantField string
+		wantCode  string
+	}{
+		{name: "deal scores need no module", req: JobRequest{Targets: []string{TargetDealScores, TargetSLA}}, wantSteps: []string{TargetSLA, TargetDealScores}},
+		{name: "counters need a module", req: JobRequest{Targets: []string{TargetCounters}}, wantField: "module", wantCode: validation.CodeRequired},
+		{name: "lead scores", req: JobRequest{Targets: []string{TargetSLA, "lead_scores"}}, wantField: "targets.1", wantCode: validation.CodeNotAllowed},
+		{name: "rollups", req: JobRequest{Targets: []string{"rollups"}}, wantField: "targets.0", wantCode: validation.CodeNotAllowed},
+		{name: "formulas", req: JobRequest{Targets: []string{"formulas"}}, wantField: "targets.0", wantCode: validation.CodeNotAllowed},
+		{name: "search index", req: JobRequest{Targets: []string{"search_index"}}, wantField: "targets.0", wantCode: validation.CodeNotAllowed},
+		{name: "unknown target", req: JobRequest{Targets: []string{"everything"}}, wantField: "targets.0", wantCode: validation.CodeInvalid},
+		{name: "no targets", req: JobRequest{}, wantField: "targets", wantCode: validation.CodeRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps, err := (&RecalculationServiceImpl{}).plan(context.Background(), tt.req)
+			if tt.wantCode != "" {
+				errs, ok := validation.As(err)
+				if !ok || len(errs) != 1 || errs[0].Field != tt.wantField || errs[0].Code != tt.wantCode {
+					t.Fatalf("err = %v, want %s on %s", err, tt.wantCode, tt.wantField)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, step := range steps {
+				got = append(got, step.Target)
+			}
+			if !reflect.DeepEqual(got, tt.wantSteps) {
+				t.Errorf("steps = %v, want %v", got, tt.wantSteps)
+			}
+		})
+	}
+}
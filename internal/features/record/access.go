@@ -0,0 +1,52 @@
+package record
+
+import (
+	"context"
+
+	"go-crm/internal/common/apperr"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrRecordNotFound is returned for records that do not exist and for records
+// outside the caller's access scope, so IDs cannot be probed
+var ErrRecordNotFound = apperr.NotFound("record not found")
+
+// ErrAccessDenied is returned when the caller lacks a module permission an
+// operation needs beyond the one checked for its route
+var ErrAccessDenied = apperr.PermissionDenied("access denied")
+
+var ErrModuleNotFound = apperr.NotFound("module not found")
+
+// checkRecordAccess applies the caller's access filter for action to a single
+// record. System callers without a user, and services built without a role
+// service, are not restricted.
+func (s *RecordServiceImpl) checkRecordAccess(ctx context.Context, moduleName, id string, userID primitive.ObjectID, action string) error {
+	if s.RoleService == nil || userID.IsZero() {
+		return nil
+	}
+
+	accessFilter, err := s.RoleService.GetAccessFilter(ctx, userID, moduleName, action)
+	if err != nil {
+		return ErrRecordNotFound
+	}
+	if len(accessFilter) == 0 {
+		return nil
+	}
+	if v, denied := accessFilter["_id"]; denied && v == -1 {
+		return ErrRecordNotFound
+	}
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrRecordNotFound
+	}
+	count, err := s.RecordRepo.Count(ctx, moduleName, map[string]any{"_id": oid}, accessFilter)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
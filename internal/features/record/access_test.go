@@ -0,0 +1,174 @@
+package record
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/role"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// accessRoleService only answers GetAccessFilter; other calls are not expected
+type accessRoleService struct {
+	role.RoleService
+	filter bson.M
+	err    error
+}
+
+func (s *accessRoleService) GetAccessFilter(ctx context.Context, userID primitive.ObjectID, moduleName string, action string) (bson.M, error) {
+	return s.filter, s.err
+}
+
+func (s *accessRoleService) GetFieldPermissions(ctx context.Context, userID primitive.ObjectID, moduleName string) (map[string]string, error) {
+	return nil, nil
+}
+
+// accessModuleRepo returns an empty schema for any module
+type accessModuleRepo struct {
+	module.ModuleRepository
+}
+
+func (r *accessModuleRepo) FindByName(ctx context.Context, name string) (*common_models.Entity, error) {
+	return &common_models.Entity{Name: name}, nil
+}
+
+// accessRecordRepo reports whether the record matches the access filter
+type accessRecordRepo struct {
+	MockRecordRepo
+	matches        bool
+	capturedFilter map[string]any
+	capturedAccess map[string]any
+	updateCalled   bool
+	countCalled    bool
+}
+
+func (r *accessRecordRepo) Count(ctx context.Context, moduleName string, filter map[string]any, accessFilter map[string]any) (int64, error) {
+	r.countCalled = true
+	r.capturedFilter = filter
+	r.capturedAccess = accessFilter
+	if r.matches {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (r *accessRecordRepo) Update(ctx context.Context, moduleName, id string, data map[string]any) error {
+	r.updateCalled = true
+	return nil
+}
+
+func newAccessService(repo *accessRecordRepo, roles *accessRoleService) *RecordServiceImpl {
+	return &RecordServiceImpl{
+		RecordRepo:   repo,
+		ModuleRepo:   &accessModuleRepo{},
+		RoleService:  roles,
+		AuditService: &MockAuditService{},
+	}
+}
+
+func TestGetRecordOutsideScopeIsNotFound(t *testing.T) {
+	repo := &accessRecordRepo{matches: false}
+	ownerFilter := bson.M{"owner": primitive.NewObjectID()}
+	service := newAccessService(repo, &accessRoleService{filter: ownerFilter})
+
+	id := primitive.NewObjectID()
+	_, err := service.GetRecord(context.Background(), "contacts", id.Hex(), primitive.NewObjectID())
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Fatalf("Expected ErrRecordNotFound, got %v", err)
+	}
+	if repo.capturedFilter["_id"] != id {
+		t.Errorf("Expected access check on %s, got %v", id.Hex(), repo.capturedFilter["_id"])
+	}
+	if repo.capturedAccess["owner"] != ownerFilter["owner"] {
+		t.Errorf("Expected the role access filter to be applied, got %v", repo.capturedAccess)
+	}
+}
+
+func TestGetRecordInsideScope(t *testing.T) {
+	repo := &accessRecordRepo{matches: true}
+	service := newAccessService(repo, &accessRoleService{filter: bson.M{"owner": primitive.NewObjectID()}})
+
+	rec, err := service.GetRecord(context.Background(), "contacts", primitive.NewObjectID().Hex(), primitive.NewObjectID())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rec == nil {
+		t.Error("Expected the record to be returned")
+	}
+}
+
+func TestUpdateRecordOutsideScopeIsRejected(t *testing.T) {
+	repo := &accessRecordRepo{matches: false}
+	service := newAccessService(repo, &accessRoleService{filter: bson.M{"owner": primitive.NewObjectID()}})
+
+	err := service.UpdateRecord(context.Background(), "contacts", primitive.NewObjectID().Hex(), map[string]interface{}{"name": "x"}, primitive.NewObjectID())
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Fatalf("Expected ErrRecordNotFound, got %v", err)
+	}
+	if repo.updateCalled {
+		t.Error("Update must not reach the repository for a record outside the caller's scope")
+	}
+}
+
+func TestDeleteRecordDeniedByRole(t *testing.T) {
+	repo := &accessRecordRepo{matches: true}
+	service := newAccessService(repo, &accessRoleService{filter: bson.M{"_id": -1}})
+
+	err := service.DeleteRecord(context.Background(), "contacts", primitive.NewObjectID().Hex(), primitive.NewObjectID())
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Fatalf("Expected ErrRecordNotFound, got %v", err)
+	}
+	if repo.CapturedDeleteID != "" {
+		t.Error("Delete must not reach the repository when the role denies delete")
+	}
+	if repo.countCalled {
+		t.Error("A deny-all filter should not need a count query")
+	}
+}
+
+func TestDeleteRecordAccessFilterError(t *testing.T) {
+	repo := &accessRecordRepo{matches: true}
+	service := newAccessService(repo, &accessRoleService{err: errors.New("user not found")})
+
+	err := service.DeleteRecord(context.Background(), "contacts", primitive.NewObjectID().Hex(), primitive.NewObjectID())
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Fatalf("Expected ErrRecordNotFound, got %v", err)
+	}
+	if repo.CapturedDeleteID != "" {
+		t.Error("Delete must not reach the repository when the access filter cannot be resolved")
+	}
+}
+
+func TestDeleteRecordFullAccessSkipsCount(t *testing.T) {
+	repo := &accessRecordRepo{matches: false}
+	service := newAccessService(repo, &accessRoleService{filter: bson.M{}})
+
+	id := primitive.NewObjectID().Hex()
+	if err := service.DeleteRecord(context.Background(), "contacts", id, primitive.NewObjectID()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if repo.countCalled {
+		t.Error("Full access should not need a count query")
+	}
+	if repo.CapturedDeleteID != id {
+		t.Errorf("Expected delete ID %s, got %s", id, repo.CapturedDeleteID)
+	}
+}
+
+func TestSystemCallerSkipsAccessCheck(t *testing.T) {
+	repo := &accessRecordRepo{matches: false}
+	service := newAccessService(repo, &accessRoleService{filter: bson.M{"_id": -1}})
+
+	id := primitive.NewObjectID().Hex()
+	if err := service.DeleteRecord(context.Background(), "contacts", id, primitive.NilObjectID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if repo.CapturedDeleteID != id {
+		t.Errorf("Expected delete ID %s, got %s", id, repo.CapturedDeleteID)
+	}
+}
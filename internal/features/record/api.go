@@ -1,6 +1,7 @@
 package record
 
 import (
+	common_api "go-crm/internal/common/api"
 	"go-crm/internal/config"
 	"go-crm/internal/features/role"
 	"go-crm/internal/middleware"
@@ -28,16 +29,52 @@ func NewRecordApi(
 
 // Setup registers record-related routes
 func (h *RecordApi) Setup(app *fiber.App) {
-	// Group is same as module Schema, but handles records
-	modules := app.Group("/api/modules", middleware.AuthMiddleware(h.config.SkipAuth))
+	// Group is same as module Schema, but handles records. The unversioned
+	// groups carry the headers of the version they negotiate.
+	modules := app.Group("/api/modules", common_api.NegotiatedVersionMiddleware(), middleware.AuthMiddleware(h.config.SkipAuth))
 
 	// Separate group for generic record queries (Prompt requested /api/records/query)
-	records := app.Group("/api/records", middleware.AuthMiddleware(h.config.SkipAuth))
-	records.Post("/query", h.recordController.QueryRecords)
-
-	modules.Get("/:name/records", h.recordController.ListRecords)
-	modules.Post("/:name/records", h.recordController.CreateRecord)
-	modules.Get("/:name/records/:id", h.recordController.GetRecord)
-	modules.Put("/:name/records/:id", h.recordController.UpdateRecord)
-	modules.Delete("/:name/records/:id", h.recordController.DeleteRecord)
+	records := app.Group("/api/records", common_api.NegotiatedVersionMiddleware(), middleware.AuthMiddleware(h.config.SkipAuth))
+
+	h.registerQueryRoutes(records)
+	h.registerModuleRoutes(modules)
+
+	// One-off data migration, run per tenant by an administrator
+	records.Post("/migrations/utc-dates", middleware.RequirePermission(h.roleService, "settings", "update"), h.recordController.MigrateDatesToUTC)
+	records.Post("/migrations/phone-numbers", middleware.RequirePermission(h.roleService, "settings", "update"), h.recordController.MigratePhoneNumbers)
+
+	// Versioned routes (/api/v1, /api/v2); the unversioned routes above behave like v1
+	versions := []common_api.Version{common_api.V1, common_api.V2}
+	common_api.VersionedGroup(app, "/records", versions, func(r fiber.Router, v common_api.Version) {
+		h.registerQueryRoutes(r)
+	}, middleware.AuthMiddleware(h.config.SkipAuth))
+	common_api.VersionedGroup(app, "/modules", versions, func(r fiber.Router, v common_api.Version) {
+		h.registerModuleRoutes(r)
+	}, middleware.AuthMiddleware(h.config.SkipAuth))
+}
+
+func (h *RecordApi) registerQueryRoutes(records fiber.Router) {
+	// Both only read records: the query of the body's resource, and the
+	// phone lookup of every module it searches
+	records.Post("/query", middleware.RequireModuleAction(h.roleService, middleware.ModuleBody("resource"), "read"), h.recordController.QueryRecords)
+	records.Get("/phone-lookup", middleware.RequireModuleAction(h.roleService, middleware.ModuleQuery("module"), "read"), h.recordController.FindByPhone)
+}
+
+func (h *RecordApi) registerModuleRoutes(modules fiber.Router) {
+	// The HTTP verb decides which module permission is required
+	crud := middleware.RequireModulePermission(h.roleService, "name")
+
+	modules.Get("/:name/records", crud, h.recordController.ListRecords)
+	modules.Get("/:name/records/counts", crud, h.recordController.CountRecords)
+	modules.Post("/:name/records", crud, h.recordController.CreateRecord)
+	// Upsert inserts when nothing matches, so it needs create as well as update
+	modules.Put("/:name/records/upsert", crud, middleware.RequireModuleAction(h.roleService, middleware.ModuleParam("name"), "create"), h.recordController.UpsertRecord)
+	modules.Get("/:name/records/:id", crud, h.recordController.GetRecord)
+	modules.Put("/:name/records/:id", crud, h.recordController.UpdateRecord)
+	modules.Delete("/:name/records/:id", crud, h.recordController.DeleteRecord)
+	modules.Post("/:name/records/:id/clone", crud, h.recordController.CloneRecord)
+	modules.Get("/:name/records/:id/related/:relation", crud, h.recordController.ListRelated)
+	modules.Get("/:name/records/:id/history/:field", crud, h.recordController.GetFieldHistory)
+	modules.Get("/:name/lookup", crud, h.recordController.SearchLookup)
+	modules.Get("/:name/changes", crud, h.recordController.ListChanges)
 }
@@ -0,0 +1,147 @@
+package record
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/role"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// These measure the service's own work on the record list path, with the
+// database replaced by in-memory stand-ins. The same path against MongoDB is
+// benchmarked in internal/integration.
+
+var benchSchema = &common_models.Entity{
+	Name: "deals",
+	Fields: []common_models.ModuleField{
+		{Name: "name", Label: "Name", Type: common_models.FieldTypeText, Filterable: true},
+		{Name: "amount", Label: "Amount", Type: common_models.FieldTypeNumber, Filterable: true},
+		{Name: "region", Label: "Region", Type: common_models.FieldTypeSelect, Filterable: true},
+		{Name: "close_date", Label: "Close Date", Type: common_models.FieldTypeDate, Filterable: true},
+		{Name: "margin", Label: "Margin", Type: common_models.FieldTypeNumber},
+		{Name: "account", Label: "Account", Type: common_models.FieldTypeLookup, Lookup: &common_models.LookupDef{LookupModule: "accounts", LookupLabel: "name"}},
+	},
+}
+
+type benchModuleRepo struct {
+	module.ModuleRepository
+}
+
+func (r *benchModuleRepo) FindByName(ctx context.Context, name string) (*common_models.Entity, error) {
+	return benchSchema, nil
+}
+
+// benchRecordRepo serves a fixed page of deals and the accounts they reference
+type benchRecordRepo struct {
+	MockRecordRepo
+	page     []map[string]any
+	accounts map[string]map[string]any
+}
+
+func newBenchRecordRepo(pageSize int) *benchRecordRepo {
+	r := &benchRecordRepo{accounts: map[string]map[string]any{}}
+	for i := 0; i < 20; i++ {
+		id := primitive.NewObjectID()
+		r.accounts[id.Hex()] = map[string]any{"_id": id, "name": fmt.Sprintf("Account %d", i)}
+	}
+	ids := make([]primitive.ObjectID, 0, len(r.accounts))
+	for _, a := range r.accounts {
+		ids = append(ids, a["_id"].(primitive.ObjectID))
+	}
+	for i := 0; i < pageSize; i++ {
+		r.page = append(r.page, map[string]any{
+			"_id":     primitive.NewObjectID(),
+			"name":    fmt.Sprintf("Deal %d", i),
+			"amount":  float64(i * 100),
+			"region":  "west",
+			"margin":  0.3,
+			"account": ids[i%len(ids)],
+		})
+	}
+	return r
+}
+
+// List hands out copies, as each query decodes fresh documents
+func (r *benchRecordRepo) List(ctx context.Context, moduleName string, filter map[string]any, accessFilter map[string]any, limit, offset int64, sortBy string, sortOrder int) ([]map[string]any, error) {
+	out := make([]map[string]any, len(r.page))
+	for i, rec := range r.page {
+		cp := make(map[string]any, len(rec))
+		for k, v := range rec {
+			cp[k] = v
+		}
+		out[i] = cp
+	}
+	return out, nil
+}
+
+func (r *benchRecordRepo) Get(ctx context.Context, moduleName, id string) (map[string]any, error) {
+	return r.accounts[id], nil
+}
+
+func (r *benchRecordRepo) Count(ctx context.Context, moduleName string, filter map[string]any, accessFilter map[string]any) (int64, error) {
+	return 500, nil
+}
+
+// benchRoleService limits reads to the west region and hides the margin
+type benchRoleService struct {
+	role.RoleService
+}
+
+func (s *benchRoleService) GetAccessFilter(ctx context.Context, userID primitive.ObjectID, moduleName string, action string) (bson.M, error) {
+	return bson.M{"$and": []bson.M{{"data.region": bson.M{"$eq": "west"}}}}, nil
+}
+
+func (s *benchRoleService) GetFieldPermissions(ctx context.Context, userID primitive.ObjectID, moduleName string) (map[string]string, error) {
+	return map[string]string{"margin": role.FieldPermNone}, nil
+}
+
+func BenchmarkListRecordsWithLookups(b *testing.B) {
+	service := &RecordServiceImpl{
+		ModuleRepo:  &benchModuleRepo{},
+		RecordRepo:  newBenchRecordRepo(50),
+		RoleService: &benchRoleService{},
+	}
+	filters := []common_models.Filter{
+		{Field: "amount", Operator: "gte", Value: 1000.0},
+		{Field: "name", Operator: "contains", Value: "Deal"},
+	}
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		records, _, err := service.ListRecords(ctx, "deals", filters, 1, 50, "amount", "desc", userID)
+		if err != nil {
+			b.Fatalf("ListRecords: %v", err)
+		}
+		if len(records) != 50 {
+			b.Fatalf("listed %d records, want 50", len(records))
+		}
+	}
+}
+
+func BenchmarkPrepareFilters(b *testing.B) {
+	service := &RecordServiceImpl{}
+	filters := []common_models.Filter{
+		{Field: "name", Operator: "contains", Value: "Acme"},
+		{Field: "amount", Operator: "between", Value: []interface{}{1000.0, 5000.0}},
+		{Field: "region", Operator: "in", Value: []interface{}{"west", "north"}},
+		{Field: "close_date", Operator: "gte", Value: "2024-01-01"},
+	}
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := service.prepareFilters(ctx, benchSchema, filters); err != nil {
+			b.Fatalf("prepareFilters: %v", err)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package record
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/config"
+	"go-crm/internal/database"
+	"go-crm/internal/features/webhook"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// streamLeaseTTL bounds how long a crashed instance holds the stream;
+	// the holder renews it every streamLeaseRenew
+	streamLeaseTTL   = time.Minute
+	streamLeaseRenew = 20 * time.Second
+	// streamRetry is the wait before taking the lease or watching again
+	streamRetry = 30 * time.Second
+	// changeStreamKey names the entity_records stream in change_streams
+	changeStreamKey = "entity_records"
+)
+
+// Server error codes the stream handles
+const (
+	codeNotReplicaSet = 40573
+	codeHistoryLost   = 286
+)
+
+type dispatchKey struct{}
+
+// withDispatch marks writes whose events the record service dispatches
+// itself. The repository stamps them with an event ID.
+func withDispatch(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dispatchKey{}, true)
+}
+
+func dispatched(ctx context.Context) bool {
+	marked, _ := ctx.Value(dispatchKey{}).(bool)
+	return marked
+}
+
+// streamState is the stored resume token and lease of a change stream
+type streamState struct {
+	ID         string    `bson:"_id"`
+	Token      bson.Raw  `bson:"token,omitempty"`
+	Owner      string    `bson:"owner"`
+	LeaseUntil time.Time `bson:"lease_until"`
+	UpdatedAt  time.Time `bson:"updated_at"`
+}
+
+// recordEvent is the part of a change event the stream reads
+type recordEvent struct {
+	ID                bson.Raw             `bson:"_id"`
+	OperationType     string               `bson:"operationType"`
+	FullDocument      *models.EntityRecord `bson:"fullDocument"`
+	UpdateDescription struct {
+		UpdatedFields bson.M `bson:"updatedFields"`
+	} `bson:"updateDescription"`
+}
+
+// ChangeStream watches entity_records and dispatches record creates and
+// updates that did not go through the record service, e.g. seeders and
+// manual fixes, to the change listeners and webhooks. One instance holds
+// the stream at a time; the resume token is stored after every event, so
+// a restart or a new holder continues where the last one stopped.
+type ChangeStream struct {
+	Records  *mongo.Collection
+	State    *mongo.Collection
+	Listener ChangeListener
+	Webhooks webhook.WebhookService
+	Enabled  bool
+
+	owner string
+}
+
+func NewChangeStream(mongodb *database.MongodbDB, cfg *config.Config, listener ChangeListener, webhooks webhook.WebhookService) *ChangeStream {
+	host, _ := os.Hostname()
+	return &ChangeStream{
+		Records:  mongodb.DB.Collection("entity_records"),
+		State:    mongodb.DB.Collection("change_streams"),
+		Listener: listener,
+		Webhooks: webhooks,
+		Enabled:  cfg.ChangeStreams,
+		owner:    host + ":" + primitive.NewObjectID().Hex(),
+	}
+}
+
+// Run watches until ctx is done. It returns at once when change streams
+// are disabled, and stops when the deployment does not support them.
+func (cs *ChangeStream) Run(ctx context.Context) {
+	if !cs.Enabled {
+		return
+	}
+	for {
+		held, err := cs.acquire(ctx)
+		if err != nil {
+			log.Printf("change stream: failed to take lease: %v", err)
+		}
+		if held {
+			err = cs.watch(ctx)
+			cs.release()
+			if hasErrorCode(err, codeNotReplicaSet) {
+				log.Printf("change stream: not supported by this deployment, stopping: %v", err)
+				return
+			}
+			if err != nil && ctx.Err() == nil {
+				log.Printf("change stream: %v", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(streamRetry):
+		}
+	}
+}
+
+// watch follows the stream from the stored token while renewing the lease
+func (cs *ChangeStream) watch(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go cs.renew(ctx, cancel)
+
+	var state streamState
+	if err := cs.State.FindOne(ctx, bson.M{"_id": changeStreamKey}).Decode(&state); err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{"operationType": bson.M{"$in": []string{"insert", "update"}}}}}}
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if len(state.Token) > 0 {
+		opts.SetResumeAfter(state.Token)
+	}
+	stream, err := cs.Records.Watch(ctx, pipeline, opts)
+	if hasErrorCode(err, codeHistoryLost) {
+		// The token fell off the oplog; changes in between are lost
+		log.Printf("change stream: resume token expired, continuing from now")
+		stream, err = cs.Records.Watch(ctx, pipeline, opts.SetResumeAfter(nil))
+	}
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		var event recordEvent
+		if err := stream.Decode(&event); err != nil {
+			log.Printf("change stream: failed to decode event: %v", err)
+		} else {
+			cs.dispatch(ctx, event)
+		}
+		if err := cs.saveToken(ctx, stream.ResumeToken()); err != nil {
+			log.Printf("change stream: failed to store resume token: %v", err)
+		}
+	}
+	return stream.Err()
+}
+
+// dispatch hands one event to the pipeline unless the record service
+// already did
+func (cs *ChangeStream) dispatch(ctx context.Context, event recordEvent) {
+	doc := event.FullDocument
+	if doc == nil || doc.Deleted {
+		return
+	}
+
+	change := RecordChange{
+		ModuleName: doc.Entity,
+		RecordID:   doc.ID.Hex(),
+		Record:     streamRecord(doc),
+		Changes:    map[string]models.Change{},
+	}
+	switch event.OperationType {
+	case "insert":
+		if !doc.EventID.IsZero() {
+			return
+		}
+		change.Created = true
+		for k, v := range doc.Data {
+			change.Changes[k] = models.Change{New: v}
+		}
+	case "update":
+		if _, ok := event.UpdateDescription.UpdatedFields["event_id"]; ok {
+			return
+		}
+		for k, v := range event.UpdateDescription.UpdatedFields {
+			if field, ok := strings.CutPrefix(k, "data."); ok {
+				change.Changes[field] = models.Change{New: v}
+			}
+			// A write replacing data as a whole
+			if data, ok := v.(bson.M); ok && k == "data" {
+				for field, fv := range data {
+					change.Changes[field] = models.Change{New: fv}
+				}
+			}
+		}
+		// Soft deletes and bookkeeping writes change no field
+		if len(change.Changes) == 0 {
+			return
+		}
+	default:
+		return
+	}
+	if oid, err := primitive.ObjectIDFromHex(doc.UpdatedBy); err == nil {
+		change.ActorID = oid
+	}
+
+	tenantCtx := context.WithValue(context.WithoutCancel(ctx), models.TenantIDKey, doc.TenantID.Hex())
+	if cs.Listener != nil {
+		cs.Listener.RecordChanged(tenantCtx, change)
+	}
+	if cs.Webhooks != nil {
+		name := "record.updated"
+		if change.Created {
+			name = "record.created"
+		}
+		cs.Webhooks.Trigger(tenantCtx, "record.updated", models.WebhookPayload{
+			Event:     name,
+			Module:    change.ModuleName,
+			RecordID:  change.RecordID,
+			Data:      change.Record,
+			Timestamp: time.Now(),
+		})
+	}
+}
+
+// streamRecord flattens a streamed document like RecordRepository.Get
+func streamRecord(doc *models.EntityRecord) map[string]interface{} {
+	record := make(map[string]interface{}, len(doc.Data)+6)
+	for k, v := range doc.Data {
+		record[k] = v
+	}
+	record["_id"] = doc.ID
+	record["id"] = doc.ID
+	record["created_at"] = doc.CreatedAt
+	record["updated_at"] = doc.UpdatedAt
+	record["created_by"] = doc.CreatedBy
+	record["updated_by"] = doc.UpdatedBy
+	return record
+}
+
+// acquire takes the stream lease when it is free, lapsed or already ours
+func (cs *ChangeStream) acquire(ctx context.Context) (bool, error) {
+	now := time.Now()
+	_, err := cs.State.UpdateOne(ctx,
+		bson.M{"_id": changeStreamKey, "$or": []bson.M{{"owner": cs.owner}, {"lease_until": bson.M{"$lt": now}}}},
+		bson.M{"$set": bson.M{"owner": cs.owner, "lease_until": now.Add(streamLeaseTTL), "updated_at": now}},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		// Another instance holds it
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// renew keeps the lease while watching; losing it stops the watch
+func (cs *ChangeStream) renew(ctx context.Context, cancel context.CancelFunc) {
+	ticker := time.NewTicker(streamLeaseRenew)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now()
+			res, err := cs.State.UpdateOne(ctx,
+				bson.M{"_id": changeStreamKey, "owner": cs.owner},
+				bson.M{"$set": bson.M{"lease_until": now.Add(streamLeaseTTL), "updated_at": now}},
+			)
+			if err == nil && res.MatchedCount == 0 {
+				log.Printf("change stream: lease lost")
+				cancel()
+				return
+			}
+			if err != nil {
+				log.Printf("change stream: failed to renew lease: %v", err)
+			}
+		}
+	}
+}
+
+func (cs *ChangeStream) release() {
+	_, err := cs.State.UpdateOne(context.Background(),
+		bson.M{"_id": changeStreamKey, "owner": cs.owner},
+		bson.M{"$set": bson.M{"lease_until": time.Time{}}},
+	)
+	if err != nil {
+		log.Printf("change stream: failed to release lease: %v", err)
+	}
+}
+
+func hasErrorCode(err error, code int) bool {
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) && serverErr.HasErrorCode(code)
+}
+
+func (cs *ChangeStream) saveToken(ctx context.Context, token bson.Raw) error {
+	if len(token) == 0 {
+		return nil
+	}
+	_, err := cs.State.UpdateOne(ctx,
+		bson.M{"_id": changeStreamKey, "owner": cs.owner},
+		bson.M{"$set": bson.M{"token": token, "updated_at": time.Now()}},
+	)
+	return err
+}
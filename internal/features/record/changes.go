@@ -0,0 +1,212 @@
+package record
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/common/validation"
+	"go-crm/internal/features/role"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Operations reported by the changes feed
+const (
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+	ChangeDeleted = "deleted"
+)
+
+const (
+	// maxChangesPage caps the audit entries read per changes request
+	maxChangesPage = 1000
+	// changesSettle holds back the newest entries. Entry IDs are made by the
+	// writing process, so one written concurrently can land just before an
+	// ID already handed out as a cursor; waiting a moment keeps it reachable.
+	changesSettle = 5 * time.Second
+)
+
+// feedActions are the audit actions that change a module's records
+var feedActions = []common_models.AuditAction{
+	common_models.AuditActionCreate,
+	common_models.AuditActionUpdate,
+	common_models.AuditActionDelete,
+	common_models.AuditActionMerge,
+	common_models.AuditActionArchive,
+}
+
+// FeedChange is the latest change of one record within a page
+type FeedChange struct {
+	ID   string                 `json:"id"`
+	Op   string                 `json:"op"`
+	At   time.Time              `json:"at"`
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+// ChangesPage is one page of the changes feed. Cursor is passed as since
+// for the next page; HasMore says that page is available right away.
+type ChangesPage struct {
+	Changes []FeedChange `json:"changes"`
+	Cursor  string       `json:"cursor"`
+	HasMore bool         `json:"has_more"`
+}
+
+// ListChanges returns the records of a module created, updated or deleted
+// after since, which is a cursor from an earlier page, an RFC 3339 time or
+// empty for the start of the audit log. Several changes to one record in a
+// page are reported once, with its latest state. Created and updated
+// records the caller cannot read are left out; deleted ones are listed by ID
+// only. Records moved out by an archive policy are not reported.
+func (s *RecordServiceImpl) ListChanges(ctx context.Context, moduleName, since string, limit int64, includeData bool, userID primitive.ObjectID) (*ChangesPage, error) {
+	if limit < 1 {
+		limit = 100
+	}
+	if limit > maxChangesPage {
+		limit = maxChangesPage
+	}
+	after, err := parseChangesCursor(since)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.ModuleRepo.FindByName(ctx, moduleName); err != nil {
+		return nil, errors.New("module not found")
+	}
+
+	until := time.Now().Add(-changesSettle)
+	logs, err := s.AuditService.ListSince(ctx, moduleName, feedActions, after, until, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &ChangesPage{Changes: []FeedChange{}, HasMore: int64(len(logs)) > limit}
+	if page.HasMore {
+		logs = logs[:limit]
+		page.Cursor = logs[len(logs)-1].ID.Hex()
+	} else {
+		// Everything written before until has been read
+		cursor := primitive.NewObjectIDFromTimestamp(until)
+		if bytes.Compare(cursor[:], after[:]) < 0 {
+			cursor = after
+		}
+		page.Cursor = cursor.Hex()
+	}
+
+	changes := collapseChanges(logs)
+	if len(changes) == 0 {
+		return page, nil
+	}
+	visible, err := s.visibleRecords(ctx, moduleName, changes, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range changes {
+		if c.Op != ChangeDeleted {
+			record, ok := visible[c.ID]
+			if !ok {
+				continue
+			}
+			if includeData {
+				c.Data = record
+			}
+		}
+		page.Changes = append(page.Changes, c)
+	}
+	return page, nil
+}
+
+// parseChangesCursor reads the since parameter of the changes feed
+func parseChangesCursor(since string) (primitive.ObjectID, error) {
+	if since == "" {
+		return primitive.NilObjectID, nil
+	}
+	if oid, err := primitive.ObjectIDFromHex(since); err == nil {
+		return oid, nil
+	}
+	if t, err := time.Parse(time.RFC3339, since); err == nil {
+		// Entries of that second come after an ID with a zero suffix
+		return primitive.NewObjectIDFromTimestamp(t), nil
+	}
+	return primitive.NilObjectID, validation.New("since", validation.CodeInvalid, "since must be a cursor or an RFC 3339 time")
+}
+
+// collapseChanges keeps one change per record, in the order records first
+// changed. A record created within the page stays created until deleted.
+func collapseChanges(logs []common_models.AuditLog) []FeedChange {
+	var changes []FeedChange
+	index := map[string]int{}
+	for _, l := range logs {
+		op := ""
+		switch l.Action {
+		case common_models.AuditActionCreate:
+			op = ChangeCreated
+		case common_models.AuditActionUpdate, common_models.AuditActionMerge:
+			op = ChangeUpdated
+		case common_models.AuditActionDelete:
+			op = ChangeDeleted
+		case common_models.AuditActionArchive:
+			// Policy runs are logged against the policy; restores against the record
+			if _, restored := l.Changes["restored"]; restored {
+				op = ChangeUpdated
+			}
+		}
+		if op == "" || l.RecordID == "" {
+			continue
+		}
+
+		i, seen := index[l.RecordID]
+		if !seen {
+			index[l.RecordID] = len(changes)
+			changes = append(changes, FeedChange{ID: l.RecordID, Op: op, At: l.Timestamp})
+			continue
+		}
+		if changes[i].Op != ChangeCreated || op == ChangeDeleted {
+			changes[i].Op = op
+		}
+		changes[i].At = l.Timestamp
+	}
+	return changes
+}
+
+// visibleRecords loads the changed records that still exist and the caller
+// may read, keyed by ID, with field permissions applied
+func (s *RecordServiceImpl) visibleRecords(ctx context.Context, moduleName string, changes []FeedChange, userID primitive.ObjectID) (map[string]map[string]interface{}, error) {
+	var ids []primitive.ObjectID
+	for _, c := range changes {
+		if c.Op == ChangeDeleted {
+			continue
+		}
+		if oid, err := primitive.ObjectIDFromHex(c.ID); err == nil {
+			ids = append(ids, oid)
+		}
+	}
+	visible := make(map[string]map[string]interface{}, len(ids))
+	if len(ids) == 0 {
+		return visible, nil
+	}
+
+	accessFilter, err := s.RoleService.GetAccessFilter(ctx, userID, moduleName, "read")
+	if err != nil {
+		return nil, err
+	}
+	records, err := s.RecordRepo.List(ctx, moduleName, map[string]any{"_id": bson.M{"$in": ids}}, accessFilter, int64(len(ids)), 0, "_id", 1)
+	if err != nil {
+		return nil, err
+	}
+	perms, _ := s.RoleService.GetFieldPermissions(ctx, userID, moduleName)
+	for _, record := range records {
+		oid, ok := record["_id"].(primitive.ObjectID)
+		if !ok {
+			continue
+		}
+		for field, p := range perms {
+			if p == role.FieldPermNone {
+				delete(record, field)
+			}
+		}
+		visible[oid.Hex()] = record
+	}
+	return visible, nil
+}
@@ -0,0 +1,249 @@
+package record
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/common/validation"
+	"go-crm/internal/features/role"
+	"go-crm/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxCloneChildren caps the child records cloned per relation
+const maxCloneChildren = 1000
+
+// CloneOptions selects what a clone copies besides the record itself
+type CloneOptions struct {
+	// Overrides replaces field values of the cloned record
+	Overrides map[string]interface{} `json:"overrides,omitempty"`
+	// Children lists child modules whose records pointing at the source are
+	// cloned to point at the copy
+	Children []CloneChild `json:"children,omitempty"`
+}
+
+// CloneChild names a child module and its lookup field to the parent. Field
+// may be left empty when the module has a single lookup to the parent.
+type CloneChild struct {
+	Module string `json:"module"`
+	Field  string `json:"field,omitempty"`
+}
+
+// CloneResult reports the new record and the children cloned per module
+type CloneResult struct {
+	ID       string         `json:"id"`
+	Children map[string]int `json:"children,omitempty"`
+}
+
+// CloneRecord copies a record, and optionally its children, as new records.
+// The copies get fresh IDs, timestamps, owner and approval state; system
+// and unique fields, which hold numbers and codes, are not copied. Either
+// everything is cloned or nothing is kept.
+func (s *RecordServiceImpl) CloneRecord(ctx context.Context, moduleName, id string, opts CloneOptions, userID primitive.ObjectID) (*CloneResult, error) {
+	if err := s.checkRecordAccess(ctx, moduleName, id, userID, "read"); err != nil {
+		return nil, err
+	}
+	source, err := s.RecordRepo.Get(ctx, moduleName, id)
+	if err != nil {
+		return nil, ErrRecordNotFound
+	}
+	m, err := s.ModuleRepo.FindByName(ctx, moduleName)
+	if err != nil {
+		return nil, errors.New("module not found")
+	}
+
+	plans, err := s.planChildren(ctx, m, id, opts.Children, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := s.cloneData(ctx, m, source, userID)
+	for k, v := range opts.Overrides {
+		data[k] = v
+	}
+	res, err := s.CreateRecord(ctx, moduleName, data, userID)
+	if err != nil {
+		return nil, err
+	}
+	newID, ok := res.(primitive.ObjectID)
+	if !ok {
+		return nil, errors.New("failed to clone record")
+	}
+
+	result := &CloneResult{ID: newID.Hex(), Children: map[string]int{}}
+	created := []clonedRecord{{module: moduleName, id: newID.Hex()}}
+	for _, plan := range plans {
+		for _, child := range plan.records {
+			childData := s.cloneData(ctx, plan.module, child, userID)
+			childData[plan.field] = newID.Hex()
+			res, err := s.CreateRecord(ctx, plan.module.Name, childData, userID)
+			if err != nil {
+				s.discardClones(ctx, created, userID)
+				return nil, fmt.Errorf("failed to clone %s record %v: %w", plan.module.Name, child["_id"], err)
+			}
+			if oid, ok := res.(primitive.ObjectID); ok {
+				created = append(created, clonedRecord{module: plan.module.Name, id: oid.Hex()})
+			}
+			result.Children[plan.module.Name]++
+		}
+	}
+	return result, nil
+}
+
+type clonedRecord struct {
+	module string
+	id     string
+}
+
+type childPlan struct {
+	module  *models.Entity
+	field   string
+	records []map[string]interface{}
+}
+
+// planChildren loads the children to clone before anything is written, so
+// a bad option or a missing permission fails the clone up front
+func (s *RecordServiceImpl) planChildren(ctx context.Context, parent *models.Entity, parentID string, children []CloneChild, userID primitive.ObjectID) ([]childPlan, error) {
+	parentOID, err := primitive.ObjectIDFromHex(parentID)
+	if err != nil {
+		return nil, ErrRecordNotFound
+	}
+
+	var plans []childPlan
+	for i, c := range children {
+		path := fmt.Sprintf("children.%d", i)
+		child, err := s.ModuleRepo.FindByName(ctx, c.Module)
+		if err != nil || child == nil {
+			return nil, validation.New(path+".module", validation.CodeInvalid, fmt.Sprintf("module '%s' not found", c.Module))
+		}
+		field, err := parentLookup(child, parent.Name, c.Field)
+		if err != nil {
+			return nil, validation.New(path+".field", validation.CodeInvalid, err.Error())
+		}
+		if !s.hasModulePermission(ctx, child.Name, "create") {
+			return nil, fmt.Errorf("%w: missing create permission on %s", ErrAccessDenied, child.Name)
+		}
+
+		accessFilter, err := s.RoleService.GetAccessFilter(ctx, userID, child.Name, "read")
+		if err != nil {
+			return nil, err
+		}
+		filter := map[string]any{field: parentOID}
+		count, err := s.RecordRepo.Count(ctx, child.Name, filter, accessFilter)
+		if err != nil {
+			return nil, err
+		}
+		if count > maxCloneChildren {
+			return nil, fmt.Errorf("%s has %d records to clone, at most %d are supported", child.Name, count, maxCloneChildren)
+		}
+		records, err := s.RecordRepo.List(ctx, child.Name, filter, accessFilter, maxCloneChildren, 0, "_id", 1)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, childPlan{module: child, field: field, records: records})
+	}
+	return plans, nil
+}
+
+// parentLookup returns the child's lookup field to the parent module
+func parentLookup(child *models.Entity, parentModule, name string) (string, error) {
+	var found []string
+	for _, f := range child.Fields {
+		if f.Type != models.FieldTypeLookup || f.Lookup == nil || f.Lookup.LookupModule != parentModule {
+			continue
+		}
+		if name == "" || f.Name == name {
+			found = append(found, f.Name)
+		}
+	}
+	switch {
+	case len(found) == 1:
+		return found[0], nil
+	case name != "":
+		return "", fmt.Errorf("'%s.%s' is not a lookup to %s", child.Name, name, parentModule)
+	case len(found) == 0:
+		return "", fmt.Errorf("%s has no lookup to %s", child.Name, parentModule)
+	default:
+		return "", fmt.Errorf("%s has several lookups to %s, name one in field", child.Name, parentModule)
+	}
+}
+
+// cloneData copies the fields of a stored record the caller may write.
+// Values are turned back into the types CreateRecord accepts.
+func (s *RecordServiceImpl) cloneData(ctx context.Context, m *models.Entity, record map[string]interface{}, userID primitive.ObjectID) map[string]interface{} {
+	perms, _ := s.RoleService.GetFieldPermissions(ctx, userID, m.Name)
+	data := map[string]interface{}{}
+	for _, f := range m.Fields {
+		if f.IsSystem || f.Unique {
+			continue
+		}
+		if p, ok := perms[f.Name]; ok && (p == role.FieldPermReadOnly || p == role.FieldPermNone) {
+			continue
+		}
+		val, ok := record[f.Name]
+		if !ok || val == nil {
+			continue
+		}
+		data[f.Name] = cloneValue(val)
+	}
+	return data
+}
+
+// cloneValue converts a value decoded from the database into its input form
+func cloneValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case primitive.DateTime:
+		return val.Time()
+	case int32:
+		return int64(val)
+	case primitive.A:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = cloneValue(item)
+		}
+		return out
+	case primitive.D:
+		out := make(map[string]interface{}, len(val))
+		for _, e := range val {
+			out[e.Key] = cloneValue(e.Value)
+		}
+		return out
+	case primitive.M:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = cloneValue(item)
+		}
+		return out
+	}
+	return v
+}
+
+// hasModulePermission checks the caller's roles for a permission on a
+// module the route did not check. Calls without user claims come from the
+// system and are allowed.
+func (s *RecordServiceImpl) hasModulePermission(ctx context.Context, moduleName, action string) bool {
+	claims, ok := ctx.Value(utils.UserClaimsKey).(*utils.UserClaims)
+	if !ok {
+		return true
+	}
+	allowed, err := s.RoleService.CheckModulePermission(ctx, claims.Roles, moduleName, action)
+	return err == nil && allowed
+}
+
+// discardClones removes the records of a clone that failed part way. They
+// are deleted directly, as a pending approval would block DeleteRecord.
+func (s *RecordServiceImpl) discardClones(ctx context.Context, created []clonedRecord, userID primitive.ObjectID) {
+	for i := len(created) - 1; i >= 0; i-- {
+		c := created[i]
+		record, err := s.RecordRepo.Get(ctx, c.module, c.id)
+		if err != nil {
+			continue
+		}
+		if err := s.RecordRepo.Delete(ctx, c.module, c.id, userID); err == nil {
+			s.updateCounters(ctx, c.module, record, nil)
+		}
+	}
+}
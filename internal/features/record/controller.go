@@ -2,12 +2,15 @@ package record
 
 import (
 	"encoding/json"
+	"errors"
 	"strings"
 
+	common_api "go-crm/internal/common/api"
 	common_models "go-crm/internal/common/models"
+	"go-crm/internal/common/session"
+	"go-crm/internal/common/validation"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type RecordController struct {
@@ -21,9 +24,9 @@ func NewRecordController(service RecordService) *RecordController {
 // CreateRecord godoc
 // CreateRecord godoc
 // @Summary Create record
-// @Description Create a new record in a module
+// @Description Create a new record in a module. A multipart/form-data body carries the record as JSON in "data" and files for file and image fields in parts named after the field.
 // @Tags records
-// @Accept json
+// @Accept json,mpfd
 // @Produce json
 // @Param name path string true "Module Name"
 // @Param record body map[string]interface{} true "Record Data"
@@ -32,23 +35,16 @@ func NewRecordController(service RecordService) *RecordController {
 // @Router /api/records/{name} [post]
 func (ctrl *RecordController) CreateRecord(c *fiber.Ctx) error {
 	moduleName := c.Params("name")
-	var data map[string]interface{}
-	if err := c.BodyParser(&data); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	data, uploads, err := recordBody(c)
+	if err != nil {
+		return common_api.InvalidBody(c, err)
 	}
 
-	var userID primitive.ObjectID
-	if idStr, ok := c.Locals("user_id").(string); ok && idStr != "" {
-		userID, _ = primitive.ObjectIDFromHex(idStr)
-	}
+	userID, _ := session.UserID(c.UserContext())
 
-	res, err := ctrl.Service.CreateRecord(c.UserContext(), moduleName, data, userID)
+	res, err := ctrl.Service.CreateRecordWithFiles(c.UserContext(), moduleName, data, uploads, userID)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return writeError(c, fiber.StatusBadRequest, err)
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(res)
@@ -69,10 +65,7 @@ func (ctrl *RecordController) GetRecord(c *fiber.Ctx) error {
 	moduleName := c.Params("name")
 	id := c.Params("id")
 
-	var userID primitive.ObjectID
-	if idStr, ok := c.Locals("user_id").(string); ok && idStr != "" {
-		userID, _ = primitive.ObjectIDFromHex(idStr)
-	}
+	userID, _ := session.UserID(c.UserContext())
 
 	record, err := ctrl.Service.GetRecord(c.UserContext(), moduleName, id, userID)
 	if err != nil {
@@ -87,7 +80,7 @@ func (ctrl *RecordController) GetRecord(c *fiber.Ctx) error {
 // ListRecords godoc
 // ListRecords godoc
 // @Summary List records
-// @Description List records in a module with filtering, sorting, and pagination
+// @Description List records in a module with filtering, sorting, and pagination. Records carry age_days and, for each select field, days_in_<field> (e.g. days_in_stage), the whole days since it took its current value; both filter and sort like numbers, e.g. stage=Negotiation&days_in_stage__gt=30. Lookup fields take a list of record IDs with in and nin, e.g. account__in=<id>,<id>.
 // @Tags records
 // @Produce json
 // @Param name path string true "Module Name"
@@ -95,7 +88,9 @@ func (ctrl *RecordController) GetRecord(c *fiber.Ctx) error {
 // @Param limit query int false "Items per page"
 // @Param sort_by query string false "Sort field"
 // @Param sort_order query string false "Sort order (asc/desc)"
+// @Param $filter query string false "OData-style filter, e.g. amount ge 1000 and (stage eq 'Won' or not contains(name,'test'))"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/records/{name} [get]
 func (ctrl *RecordController) ListRecords(c *fiber.Ctx) error {
@@ -112,8 +107,90 @@ func (ctrl *RecordController) ListRecords(c *fiber.Ctx) error {
 		sortOrder = c.Query("order", "desc")
 	}
 
-	// Filtering
-	// Filtering
+	filters, expr, err := listFilters(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userID, _ := session.UserID(c.UserContext())
+
+	records, total, err := ctrl.Service.ListRecordsWithExpression(c.UserContext(), moduleName, filters, expr, page, limit, sortBy, sortOrder, userID)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, ErrInvalidFilter) {
+			status = fiber.StatusBadRequest
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// v2 nests pagination under "meta", matching the other list endpoints
+	if common_api.RequestVersion(c) == common_api.V2 {
+		return c.JSON(fiber.Map{
+			"data": records,
+			"meta": fiber.Map{
+				"total": total,
+				"page":  page,
+				"limit": limit,
+			},
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  records,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// CountRecords godoc
+// @Summary Count records
+// @Description Count a module's records for list badges, optionally grouped by a field. Unfiltered counts, a single equality filter on owner or status, and grouping by owner or status are served from maintained counters; other filters are counted exactly.
+// @Tags records
+// @Produce json
+// @Param name path string true "Module Name"
+// @Param group_by query string false "Field to count per value"
+// @Param $filter query string false "OData-style filter"
+// @Success 200 {object} RecordCounts
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/modules/{name}/records/counts [get]
+func (ctrl *RecordController) CountRecords(c *fiber.Ctx) error {
+	filters, expr, err := listFilters(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userID, _ := session.UserID(c.UserContext())
+
+	counts, err := ctrl.Service.CountRecords(c.UserContext(), c.Params("name"), filters, expr, c.Query("group_by"), userID)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, ErrInvalidFilter) {
+			status = fiber.StatusBadRequest
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{"data": counts})
+}
+
+// listParams are the list query parameters that are not field filters
+var listParams = map[string]bool{
+	"page": true, "limit": true, "sort_by": true, "sort_order": true, "order": true,
+	"filters": true, "$filter": true, "filter": true, "group_by": true,
+}
+
+// listFilters reads the record filters of a list or counts request: the JSON
+// "filters" param, a $filter expression and field__operator query params
+func listFilters(c *fiber.Ctx) ([]common_models.Filter, *FilterExpr, error) {
 	var filters []common_models.Filter
 
 	// Check if "filters" query param exists (JSON encoded)
@@ -121,9 +198,23 @@ func (ctrl *RecordController) ListRecords(c *fiber.Ctx) error {
 		_ = json.Unmarshal([]byte(filtersStr), &filters)
 	}
 
+	// Structured expression ($filter, or "filter" for clients that cannot send "$")
+	var expr *FilterExpr
+	filterStr := c.Query("$filter")
+	if filterStr == "" {
+		filterStr = c.Query("filter")
+	}
+	if filterStr != "" {
+		parsed, err := ParseFilterExpression(filterStr)
+		if err != nil {
+			return nil, nil, err
+		}
+		expr = parsed
+	}
+
 	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
 		k := string(key)
-		if k != "page" && k != "limit" && k != "sort_by" && k != "sort_order" && k != "order" && k != "filters" {
+		if !listParams[k] {
 			v := string(value)
 			// Parse field__operator
 			fieldName := k
@@ -150,32 +241,15 @@ func (ctrl *RecordController) ListRecords(c *fiber.Ctx) error {
 		}
 	})
 
-	var userID primitive.ObjectID
-	if idStr, ok := c.Locals("user_id").(string); ok && idStr != "" {
-		userID, _ = primitive.ObjectIDFromHex(idStr)
-	}
-
-	records, total, err := ctrl.Service.ListRecords(c.UserContext(), moduleName, filters, page, limit, sortBy, sortOrder, userID)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
-		})
-	}
-
-	return c.JSON(fiber.Map{
-		"data":  records,
-		"total": total,
-		"page":  page,
-		"limit": limit,
-	})
+	return filters, expr, nil
 }
 
 // UpdateRecord godoc
 // UpdateRecord godoc
 // @Summary Update record
-// @Description Update an existing record
+// @Description Update an existing record. Accepts the same multipart/form-data body as create.
 // @Tags records
-// @Accept json
+// @Accept json,mpfd
 // @Produce json
 // @Param name path string true "Module Name"
 // @Param id path string true "Record ID"
@@ -187,22 +261,18 @@ func (ctrl *RecordController) UpdateRecord(c *fiber.Ctx) error {
 	moduleName := c.Params("name")
 	id := c.Params("id")
 
-	var data map[string]interface{}
-	if err := c.BodyParser(&data); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	data, uploads, err := recordBody(c)
+	if err != nil {
+		return common_api.InvalidBody(c, err)
 	}
 
-	var userID primitive.ObjectID
-	if idStr, ok := c.Locals("user_id").(string); ok && idStr != "" {
-		userID, _ = primitive.ObjectIDFromHex(idStr)
-	}
+	userID, _ := session.UserID(c.UserContext())
 
-	if err := ctrl.Service.UpdateRecord(c.UserContext(), moduleName, id, data, userID); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+	if err := ctrl.Service.UpdateRecordWithFiles(c.UserContext(), moduleName, id, data, uploads, userID); err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return writeError(c, fiber.StatusNotFound, err)
+		}
+		return writeError(c, fiber.StatusBadRequest, err)
 	}
 
 	return c.JSON(fiber.Map{
@@ -224,13 +294,14 @@ func (ctrl *RecordController) DeleteRecord(c *fiber.Ctx) error {
 	moduleName := c.Params("name")
 	id := c.Params("id")
 
-	var userID primitive.ObjectID
-	if idStr, ok := c.Locals("user_id").(string); ok && idStr != "" {
-		userID, _ = primitive.ObjectIDFromHex(idStr)
-	}
+	userID, _ := session.UserID(c.UserContext())
 
 	if err := ctrl.Service.DeleteRecord(c.UserContext(), moduleName, id, userID); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		status := fiber.StatusBadRequest
+		if errors.Is(err, ErrRecordNotFound) {
+			status = fiber.StatusNotFound
+		}
+		return c.Status(status).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
@@ -240,6 +311,228 @@ func (ctrl *RecordController) DeleteRecord(c *fiber.Ctx) error {
 	})
 }
 
+// CloneRecord godoc
+// @Summary Clone record
+// @Description Copy a record as a new one, optionally with the child records of the given modules pointing at it. System and unique fields are not copied.
+// @Tags records
+// @Accept json
+// @Produce json
+// @Param name path string true "Module Name"
+// @Param id path string true "Record ID"
+// @Param options body CloneOptions false "Overrides and child modules to clone"
+// @Success 201 {object} CloneResult
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/modules/{name}/records/{id}/clone [post]
+func (ctrl *RecordController) CloneRecord(c *fiber.Ctx) error {
+	var opts CloneOptions
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&opts); err != nil {
+			return common_api.InvalidBody(c, err)
+		}
+	}
+
+	userID, _ := session.UserID(c.UserContext())
+
+	res, err := ctrl.Service.CloneRecord(c.UserContext(), c.Params("name"), c.Params("id"), opts, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRecordNotFound):
+			return writeError(c, fiber.StatusNotFound, err)
+		case errors.Is(err, ErrAccessDenied):
+			return writeError(c, fiber.StatusForbidden, err)
+		}
+		return writeError(c, fiber.StatusBadRequest, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"data": res})
+}
+
+// ListRelated godoc
+// @Summary List related records
+// @Description List the child records of a related list declared on the module, with the child module's permissions applied
+// @Tags records
+// @Produce json
+// @Param name path string true "Module Name"
+// @Param id path string true "Record ID"
+// @Param relation path string true "Related list name"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/modules/{name}/records/{id}/related/{relation} [get]
+func (ctrl *RecordController) ListRelated(c *fiber.Ctx) error {
+	page := ParseInt64(c.Query("page", "1"), 1)
+	limit := ParseInt64(c.Query("limit", "10"), 10)
+
+	userID, _ := session.UserID(c.UserContext())
+
+	res, err := ctrl.Service.ListRelated(c.UserContext(), c.Params("name"), c.Params("id"), c.Params("relation"), page, limit, userID)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrRecordNotFound), errors.Is(err, ErrUnknownRelation):
+			status = fiber.StatusNotFound
+		case errors.Is(err, ErrAccessDenied):
+			status = fiber.StatusForbidden
+		}
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":     res.Records,
+		"relation": res.Relation,
+		"total":    res.Total,
+		"page":     page,
+		"limit":    limit,
+	})
+}
+
+// GetFieldHistory godoc
+// @Summary Get field history
+// @Description The values one field of a record took over time, oldest first, for charting. Only changes made while the module tracked the field are kept.
+// @Tags records
+// @Produce json
+// @Param name path string true "Module Name"
+// @Param id path string true "Record ID"
+// @Param field path string true "Field name"
+// @Success 200 {object} FieldHistory
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/modules/{name}/records/{id}/history/{field} [get]
+func (ctrl *RecordController) GetFieldHistory(c *fiber.Ctx) error {
+	userID, _ := session.UserID(c.UserContext())
+
+	history, err := ctrl.Service.GetFieldHistory(c.UserContext(), c.Params("name"), c.Params("id"), c.Params("field"), userID)
+	if err != nil {
+		return common_api.Error(c, err)
+	}
+
+	return c.JSON(fiber.Map{"data": history})
+}
+
+// SearchLookup godoc
+// @Summary Search lookup values
+// @Description Typeahead for lookup pickers and filters. With field, searches the module the lookup field points at on its lookup label; without, searches this module by name. Labels starting with q come first; the searched module's permissions apply. Pass the picked IDs to a field__in or field__nin filter.
+// @Tags records
+// @Produce json
+// @Param name path string true "Module Name"
+// @Param field query string false "Lookup field of the module"
+// @Param q query string false "Text to search for"
+// @Param limit query int false "Maximum results (default 10, max 50)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/modules/{name}/lookup [get]
+func (ctrl *RecordController) SearchLookup(c *fiber.Ctx) error {
+	limit := ParseInt64(c.Query("limit", "10"), 10)
+
+	userID, _ := session.UserID(c.UserContext())
+
+	options, err := ctrl.Service.SearchLookup(c.UserContext(), c.Params("name"), c.Query("field"), c.Query("q"), limit, userID)
+	if err != nil {
+		return common_api.Error(c, err)
+	}
+
+	return c.JSON(fiber.Map{"data": options})
+}
+
+// FindByPhone godoc
+// @Summary Look up records by phone number
+// @Description Caller-ID lookup across modules: records the user may read with a phone field holding the number, however it was formatted. National numbers are read in the user's country. Every module searched needs read permission.
+// @Tags records
+// @Produce json
+// @Param number query string true "Phone number, e.g. +1 415 555 0123"
+// @Param module query string true "Comma-separated modules to search, e.g. contacts,leads"
+// @Success 200 {array} PhoneMatch
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/records/phone-lookup [get]
+func (ctrl *RecordController) FindByPhone(c *fiber.Ctx) error {
+	userID, _ := session.UserID(c.UserContext())
+
+	modules := strings.Split(c.Query("module"), ",")
+	matches, err := ctrl.Service.FindByPhone(c.UserContext(), c.Query("number"), modules, userID)
+	if err != nil {
+		return common_api.Error(c, err)
+	}
+
+	return c.JSON(fiber.Map{"data": matches})
+}
+
+// UpsertRecord godoc
+// @Summary Upsert record by external ID
+// @Description Create the record a source system knows by external_id, or update it when it was upserted before. Safe to retry: one external ID maps to one record.
+// @Tags records
+// @Accept json
+// @Produce json
+// @Param name path string true "Module Name"
+// @Param request body UpsertRequest true "Source, external ID and record data"
+// @Success 200 {object} UpsertResult
+// @Success 201 {object} UpsertResult
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/modules/{name}/records/upsert [put]
+func (ctrl *RecordController) UpsertRecord(c *fiber.Ctx) error {
+	var req UpsertRequest
+	if err := c.BodyParser(&req); err != nil {
+		return common_api.InvalidBody(c, err)
+	}
+
+	userID, _ := session.UserID(c.UserContext())
+
+	res, err := ctrl.Service.UpsertRecord(c.UserContext(), c.Params("name"), req, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrExternalIDClaimed):
+			return writeError(c, fiber.StatusConflict, err)
+		case errors.Is(err, ErrRecordNotFound):
+			return writeError(c, fiber.StatusNotFound, err)
+		case errors.Is(err, ErrAccessDenied):
+			return writeError(c, fiber.StatusForbidden, err)
+		}
+		return writeError(c, fiber.StatusBadRequest, err)
+	}
+
+	status := fiber.StatusOK
+	if res.Created {
+		status = fiber.StatusCreated
+	}
+	return c.Status(status).JSON(fiber.Map{"data": res})
+}
+
+// ListChanges godoc
+// @Summary List record changes
+// @Description Poll the records of a module created, updated or deleted since a cursor, for incremental sync. Pass the returned cursor as since on the next call.
+// @Tags records
+// @Produce json
+// @Param name path string true "Module Name"
+// @Param since query string false "Cursor from the previous page or an RFC 3339 time; empty starts from the oldest change"
+// @Param limit query int false "Changes per page (max 1000)"
+// @Param include_data query bool false "Include the current record of created and updated changes"
+// @Success 200 {object} ChangesPage
+// @Failure 400 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/modules/{name}/changes [get]
+func (ctrl *RecordController) ListChanges(c *fiber.Ctx) error {
+	limit := ParseInt64(c.Query("limit", "100"), 100)
+	includeData := c.QueryBool("include_data", false)
+
+	userID, _ := session.UserID(c.UserContext())
+
+	res, err := ctrl.Service.ListChanges(c.UserContext(), c.Params("name"), c.Query("since"), limit, includeData, userID)
+	if err != nil {
+		return writeError(c, fiber.StatusBadRequest, err)
+	}
+	return c.JSON(fiber.Map{"data": res})
+}
+
 // QueryRecords godoc
 // @Summary Query records with strict permission checks
 // @Description Query records based on resource, action, and filters
@@ -311,25 +604,11 @@ func (ctrl *RecordController) QueryRecords(c *fiber.Ctx) error {
 		})
 	}
 
-	var userID primitive.ObjectID
-	if idStr, ok := c.Locals("user_id").(string); ok && idStr != "" {
-		userID, _ = primitive.ObjectIDFromHex(idStr)
-	}
+	userID, _ := session.UserID(c.UserContext())
 
 	records, total, err := ctrl.Service.QueryRecords(c.UserContext(), req.Resource, req.Action, filters, req.Page, req.Limit, req.SortBy, req.SortOrder, userID)
 	if err != nil {
-		status := fiber.StatusInternalServerError
-		if strings.Contains(err.Error(), "permission denied") || strings.Contains(err.Error(), "not allowed") {
-			status = fiber.StatusForbidden
-		} else if strings.Contains(err.Error(), "not found") {
-			status = fiber.StatusNotFound
-		} else if strings.Contains(err.Error(), "invalid") {
-			status = fiber.StatusBadRequest
-		}
-
-		return c.Status(status).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return common_api.Error(c, err)
 	}
 
 	return c.JSON(fiber.Map{
@@ -339,3 +618,90 @@ func (ctrl *RecordController) QueryRecords(c *fiber.Ctx) error {
 		"limit": req.Limit,
 	})
 }
+
+// writeError answers a rejected create or update. Stage gate rejections
+// carry the missing requirements under "details"; field validation errors
+// are sent as 422 with one entry per field.
+func writeError(c *fiber.Ctx, status int, err error) error {
+	var stageErr *StageTransitionError
+	if errors.As(err, &stageErr) {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error(), "details": stageErr})
+	}
+	return common_api.Fail(c, status, err)
+}
+
+// recordBody reads the record of a create or update. A multipart body holds
+// the record as JSON in "data", plain form values for single fields and one
+// file per file or image field, in a part named after the field.
+func recordBody(c *fiber.Ctx) (map[string]interface{}, []RecordUpload, error) {
+	data := map[string]interface{}{}
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		err := c.BodyParser(&data)
+		return data, nil, err
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, nil, err
+	}
+	if values := form.Value["data"]; len(values) > 0 {
+		if err := json.Unmarshal([]byte(values[0]), &data); err != nil {
+			return nil, nil, err
+		}
+	}
+	for key, values := range form.Value {
+		// Plain values override the JSON part
+		if key != "data" && len(values) > 0 {
+			data[key] = values[0]
+		}
+	}
+
+	var uploads []RecordUpload
+	for field, headers := range form.File {
+		switch len(headers) {
+		case 0:
+			continue
+		case 1:
+			uploads = append(uploads, RecordUpload{Field: field, Header: headers[0]})
+		default:
+			return nil, nil, validation.New(field, validation.CodeInvalid, "only one file per field")
+		}
+	}
+	return data, uploads, nil
+}
+
+// MigrateDatesToUTC godoc
+// @Summary Convert text dates to UTC
+// @Description Rewrite date fields stored as text into UTC datetimes, reading values without an offset in the tenant's timezone
+// @Tags records
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/records/migrations/utc-dates [post]
+func (ctrl *RecordController) MigrateDatesToUTC(c *fiber.Ctx) error {
+	results, err := ctrl.Service.MigrateDatesToUTC(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{"data": results})
+}
+
+// MigratePhoneNumbers godoc
+// @Summary Normalize phone numbers
+// @Description Store the E.164 form of phone values written before numbers were normalized, reading national numbers in the tenant's country. Values that are not a recognisable number are counted as skipped.
+// @Tags records
+// @Produce json
+// @Success 200 {array} PhoneMigrationResult
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/records/migrations/phone-numbers [post]
+func (ctrl *RecordController) MigratePhoneNumbers(c *fiber.Ctx) error {
+	results, err := ctrl.Service.MigratePhoneNumbers(c.UserContext())
+	if err != nil {
+		return common_api.Error(c, err)
+	}
+
+	return c.JSON(fiber.Map{"data": results})
+}
@@ -0,0 +1,241 @@
+package record
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RecordCounter is one maintained count of a module's live records. The
+// module total has an empty Field; it is written only by a rebuild, so its
+// presence marks the module as seeded.
+type RecordCounter struct {
+	TenantID  primitive.ObjectID `bson:"tenant_id"`
+	Module    string             `bson:"module"`
+	Field     string             `bson:"field"`
+	Value     string             `bson:"value"`
+	Count     int64              `bson:"count"`
+	RebuiltAt time.Time          `bson:"rebuilt_at,omitempty"`
+}
+
+// counterBucket names the counter of records whose Field holds Value
+type counterBucket struct {
+	Field string
+	Value string
+}
+
+type CounterRepository interface {
+	// Get returns the counter of records whose field holds value, or the
+	// module total when field is empty. ok is false while the module is not
+	// seeded.
+	Get(ctx context.Context, moduleName, field, value string) (count int64, ok bool, err error)
+	// Groups returns the module total and every bucket of one counted field
+	Groups(ctx context.Context, moduleName, field string) (total int64, groups map[string]int64, ok bool, err error)
+	// Apply adds total to the module total and each delta to its bucket.
+	// Modules that are not seeded are left alone.
+	Apply(ctx context.Context, moduleName string, total int64, deltas map[counterBucket]int64) error
+	// Rebuild recounts the module from its records and replaces its counters
+	Rebuild(ctx context.Context, moduleName string) error
+
+	// ListSeeded returns the module totals of every tenant for the nightly rebuild
+	ListSeeded(ctx context.Context) ([]RecordCounter, error)
+}
+
+type CounterRepositoryImpl struct {
+	counters *mongo.Collection
+	records  *mongo.Collection
+}
+
+func NewCounterRepository(mongodb *database.MongodbDB) CounterRepository {
+	return &CounterRepositoryImpl{
+		counters: mongodb.DB.Collection("record_counters"),
+		records:  mongodb.DB.Collection("entity_records"),
+	}
+}
+
+func tenantObjectID(ctx context.Context) (primitive.ObjectID, error) {
+	tenantID, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantID == "" {
+		return primitive.NilObjectID, fmt.Errorf("organization context missing")
+	}
+	return primitive.ObjectIDFromHex(tenantID)
+}
+
+func (r *CounterRepositoryImpl) Get(ctx context.Context, moduleName, field, value string) (int64, bool, error) {
+	tenantID, err := tenantObjectID(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	cursor, err := r.counters.Find(ctx, bson.M{
+		"tenant_id": tenantID,
+		"module":    moduleName,
+		"$or": bson.A{
+			bson.M{"field": ""},
+			bson.M{"field": field, "value": value},
+		},
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	var found []RecordCounter
+	if err := cursor.All(ctx, &found); err != nil {
+		return 0, false, err
+	}
+
+	var count int64
+	seeded := false
+	for _, c := range found {
+		if c.Field == "" {
+			seeded = true
+		}
+		if c.Field == field && c.Value == value {
+			count = c.Count
+		}
+	}
+	return count, seeded, nil
+}
+
+func (r *CounterRepositoryImpl) Groups(ctx context.Context, moduleName, field string) (int64, map[string]int64, bool, error) {
+	tenantID, err := tenantObjectID(ctx)
+	if err != nil {
+		return 0, nil, false, err
+	}
+	cursor, err := r.counters.Find(ctx, bson.M{
+		"tenant_id": tenantID,
+		"module":    moduleName,
+		"field":     bson.M{"$in": bson.A{"", field}},
+	})
+	if err != nil {
+		return 0, nil, false, err
+	}
+	var found []RecordCounter
+	if err := cursor.All(ctx, &found); err != nil {
+		return 0, nil, false, err
+	}
+
+	var total int64
+	seeded := false
+	groups := map[string]int64{}
+	for _, c := range found {
+		if c.Field == "" {
+			total = c.Count
+			seeded = true
+			continue
+		}
+		if c.Count > 0 {
+			groups[c.Value] = c.Count
+		}
+	}
+	return total, groups, seeded, nil
+}
+
+func (r *CounterRepositoryImpl) Apply(ctx context.Context, moduleName string, total int64, deltas map[counterBucket]int64) error {
+	tenantID, err := tenantObjectID(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Incrementing the total, even by zero, tells whether the module is seeded
+	res, err := r.counters.UpdateOne(ctx,
+		bson.M{"tenant_id": tenantID, "module": moduleName, "field": ""},
+		bson.M{"$inc": bson.M{"count": total}},
+	)
+	if err != nil || res.MatchedCount == 0 {
+		return err
+	}
+
+	var writes []mongo.WriteModel
+	for bucket, delta := range deltas {
+		if delta == 0 {
+			continue
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"tenant_id": tenantID, "module": moduleName, "field": bucket.Field, "value": bucket.Value}).
+			SetUpdate(bson.M{"$inc": bson.M{"count": delta}}).
+			SetUpsert(true))
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+	_, err = r.counters.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+func (r *CounterRepositoryImpl) Rebuild(ctx context.Context, moduleName string) error {
+	tenantID, err := tenantObjectID(ctx)
+	if err != nil {
+		return err
+	}
+
+	facets := bson.M{"_total": bson.A{bson.M{"$count": "n"}}}
+	for _, field := range counterFields {
+		facets[field] = bson.A{bson.M{"$group": bson.M{"_id": "$data." + field, "n": bson.M{"$sum": 1}}}}
+	}
+	cursor, err := r.records.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"tenant_id": tenantID, "entity": moduleName, "deleted": bson.M{"$ne": true}}}},
+		{{Key: "$facet", Value: facets}},
+	})
+	if err != nil {
+		return err
+	}
+	var results []map[string][]struct {
+		ID any   `bson:"_id"`
+		N  int64 `bson:"n"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	total := RecordCounter{TenantID: tenantID, Module: moduleName, RebuiltAt: now}
+	var buckets []interface{}
+	if len(results) > 0 {
+		if rows := results[0]["_total"]; len(rows) > 0 {
+			total.Count = rows[0].N
+		}
+		for _, field := range counterFields {
+			for _, row := range results[0][field] {
+				value, ok := counterKey(row.ID)
+				if !ok {
+					continue
+				}
+				buckets = append(buckets, RecordCounter{TenantID: tenantID, Module: moduleName, Field: field, Value: value, Count: row.N, RebuiltAt: now})
+			}
+		}
+	}
+
+	// The total is written last, so a first rebuild seeds the module only
+	// once its buckets exist
+	scope := bson.M{"tenant_id": tenantID, "module": moduleName}
+	if _, err := r.counters.DeleteMany(ctx, bson.M{"tenant_id": tenantID, "module": moduleName, "field": bson.M{"$ne": ""}}); err != nil {
+		return err
+	}
+	if len(buckets) > 0 {
+		if _, err := r.counters.InsertMany(ctx, buckets); err != nil {
+			return err
+		}
+	}
+	scope["field"] = ""
+	_, err = r.counters.ReplaceOne(ctx, scope, total, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (r *CounterRepositoryImpl) ListSeeded(ctx context.Context) ([]RecordCounter, error) {
+	cursor, err := r.counters.Find(ctx, bson.M{"field": ""}, options.Find().SetSort(bson.D{{Key: "tenant_id", Value: 1}, {Key: "module", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var totals []RecordCounter
+	if err := cursor.All(ctx, &totals); err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
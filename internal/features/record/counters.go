@@ -0,0 +1,267 @@
+package record
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	common_models "go-crm/internal/common/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CounterRebuildSchedule recounts every seeded module nightly, correcting
+// drift from writes that bypass the record service (imports, archival)
+const CounterRebuildSchedule = "15 2 * * *"
+
+// counterFields get a bucket per value next to the module total
+var counterFields = []string{"owner", "status"}
+
+// Count sources reported by CountRecords
+const (
+	CountSourceCounter = "counter"
+	CountSourceQuery   = "query"
+)
+
+// RecordCounts is the answer to a counts request. Source says whether it
+// came from the maintained counters or from counting the records.
+type RecordCounts struct {
+	Total  int64            `json:"total"`
+	Groups map[string]int64 `json:"groups,omitempty"`
+	Source string           `json:"source"`
+}
+
+func isCounterField(name string) bool {
+	for _, f := range counterFields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// counterKey is the bucket a stored value is counted under. Empty and
+// composite values are not counted.
+func counterKey(v interface{}) (string, bool) {
+	switch val := v.(type) {
+	case primitive.ObjectID:
+		return val.Hex(), !val.IsZero()
+	case string:
+		return val, val != ""
+	case bool, int, int32, int64, float64:
+		return fmt.Sprint(val), true
+	}
+	return "", false
+}
+
+// counterDeltas lists the bucket changes of a record going from before to
+// after. A nil before is a create and a nil after is a delete.
+func counterDeltas(before, after map[string]interface{}) map[counterBucket]int64 {
+	deltas := map[counterBucket]int64{}
+	for _, field := range counterFields {
+		if before != nil {
+			if key, ok := counterKey(before[field]); ok {
+				deltas[counterBucket{Field: field, Value: key}]--
+			}
+		}
+		if after != nil {
+			if key, ok := counterKey(after[field]); ok {
+				deltas[counterBucket{Field: field, Value: key}]++
+			}
+		}
+	}
+	return deltas
+}
+
+// mergeRecord is the stored record after an update of changed fields
+func mergeRecord(record, changed map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(record)+len(changed))
+	for k, v := range record {
+		merged[k] = v
+	}
+	for k, v := range changed {
+		merged[k] = v
+	}
+	return merged
+}
+
+// updateCounters moves the maintained counters for one write. Failures are
+// logged only; the nightly rebuild corrects what they miss.
+func (s *RecordServiceImpl) updateCounters(ctx context.Context, moduleName string, before, after map[string]interface{}) {
+	if s.CounterRepo == nil {
+		return
+	}
+	var total int64
+	switch {
+	case before == nil:
+		total = 1
+	case after == nil:
+		total = -1
+	}
+	deltas := counterDeltas(before, after)
+	if total == 0 {
+		changed := false
+		for _, d := range deltas {
+			changed = changed || d != 0
+		}
+		if !changed {
+			return
+		}
+	}
+	if err := s.CounterRepo.Apply(ctx, moduleName, total, deltas); err != nil {
+		log.Printf("record counters: failed to update %s: %v", moduleName, err)
+	}
+}
+
+// fromCounters answers a count from the maintained counters. It applies to
+// users who see every record, with no filter or a single equality filter on
+// a counted field, and to grouping by a counted field without filters. The
+// first request for a module that is not seeded yet starts seeding it.
+func (s *RecordServiceImpl) fromCounters(ctx context.Context, moduleName string, filters []common_models.Filter, expr *FilterExpr, groupBy string, accessFilter bson.M) (*RecordCounts, bool) {
+	if s.CounterRepo == nil || expr != nil || len(accessFilter) > 0 || len(filters) > 1 {
+		return nil, false
+	}
+	if groupBy != "" && (!isCounterField(groupBy) || len(filters) > 0) {
+		return nil, false
+	}
+
+	var (
+		counts RecordCounts
+		seeded bool
+		err    error
+	)
+	switch {
+	case groupBy != "":
+		counts.Total, counts.Groups, seeded, err = s.CounterRepo.Groups(ctx, moduleName, groupBy)
+	case len(filters) == 1:
+		f := filters[0]
+		value, ok := f.Value.(string)
+		if !isCounterField(f.Field) || (f.Operator != "" && f.Operator != "eq") || !ok || value == "" {
+			return nil, false
+		}
+		counts.Total, seeded, err = s.CounterRepo.Get(ctx, moduleName, f.Field, value)
+	default:
+		counts.Total, seeded, err = s.CounterRepo.Get(ctx, moduleName, "", "")
+	}
+	if err != nil {
+		return nil, false
+	}
+	if !seeded {
+		s.seedCounters(ctx, moduleName)
+		return nil, false
+	}
+	counts.Source = CountSourceCounter
+	return &counts, true
+}
+
+// seedCounters builds a module's counters in the background, once at a time
+func (s *RecordServiceImpl) seedCounters(ctx context.Context, moduleName string) {
+	tenantID, _ := ctx.Value(common_models.TenantIDKey).(string)
+	key := tenantID + ":" + moduleName
+	if _, running := s.seeding.LoadOrStore(key, true); running {
+		return
+	}
+	seedCtx := context.WithoutCancel(ctx)
+	go func() {
+		defer s.seeding.Delete(key)
+		if err := s.CounterRepo.Rebuild(seedCtx, moduleName); err != nil {
+			log.Printf("record counters: failed to seed %s for tenant %s: %v", moduleName, tenantID, err)
+		}
+	}()
+}
+
+// CountRecords counts a module's records, optionally grouped by a field,
+// from the maintained counters when the filters allow and by counting the
+// matching records otherwise
+func (s *RecordServiceImpl) CountRecords(ctx context.Context, moduleName string, filters []common_models.Filter, expr *FilterExpr, groupBy string, userID primitive.ObjectID) (*RecordCounts, error) {
+	m, err := s.ModuleRepo.FindByName(ctx, moduleName)
+	if err != nil {
+		return nil, fmt.Errorf("module not found")
+	}
+	if groupBy != "" && !isCounterField(groupBy) {
+		known := false
+		for _, f := range m.Fields {
+			known = known || f.Name == groupBy
+		}
+		if !known {
+			return nil, fmt.Errorf("%w: unknown field '%s'", ErrInvalidFilter, groupBy)
+		}
+	}
+
+	accessFilter, err := s.RoleService.GetAccessFilter(ctx, userID, moduleName, "read")
+	if err != nil {
+		return nil, err
+	}
+	if counts, ok := s.fromCounters(ctx, moduleName, filters, expr, groupBy, accessFilter); ok {
+		return counts, nil
+	}
+
+	ctx = s.withUserLocation(ctx, userID)
+	typedFilters, err := s.prepareFilters(ctx, m, filters)
+	if err != nil {
+		return nil, err
+	}
+	if expr != nil {
+		exprFilter, err := s.compileFilterExpr(ctx, m, expr)
+		if err != nil {
+			return nil, err
+		}
+		andFilter(typedFilters, exprFilter)
+	}
+
+	counts := &RecordCounts{Source: CountSourceQuery}
+	counts.Total, err = s.RecordRepo.Count(ctx, moduleName, typedFilters, accessFilter)
+	if err != nil {
+		return nil, err
+	}
+	if groupBy == "" {
+		return counts, nil
+	}
+
+	match := []bson.M{toDataQuery(typedFilters)}
+	if len(accessFilter) > 0 {
+		match = append(match, accessFilter)
+	}
+	rows, err := s.RecordRepo.Aggregate(ctx, moduleName, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"$and": match}}},
+		{{Key: "$group", Value: bson.M{"_id": "$data." + groupBy, "n": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	counts.Groups = map[string]int64{}
+	for _, row := range rows {
+		key, ok := counterKey(row["_id"])
+		if !ok {
+			continue
+		}
+		switch n := row["n"].(type) {
+		case int32:
+			counts.Groups[key] = int64(n)
+		case int64:
+			counts.Groups[key] = n
+		}
+	}
+	return counts, nil
+}
+
+// RebuildCounters recounts every seeded module of every tenant; registered
+// as a system job
+func (s *RecordServiceImpl) RebuildCounters(ctx context.Context) error {
+	if s.CounterRepo == nil {
+		return nil
+	}
+	totals, err := s.CounterRepo.ListSeeded(ctx)
+	if err != nil {
+		return err
+	}
+	for _, t := range totals {
+		tenantCtx := context.WithValue(ctx, common_models.TenantIDKey, t.TenantID.Hex())
+		if err := s.CounterRepo.Rebuild(tenantCtx, t.Module); err != nil {
+			log.Printf("record counters: failed to rebuild %s for tenant %s: %v", t.Module, t.TenantID.Hex(), err)
+		}
+	}
+	return nil
+}
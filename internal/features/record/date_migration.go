@@ -0,0 +1,114 @@
+package record
+
+import (
+	"context"
+	"time"
+
+	models "go-crm/internal/common/models"
+	"go-crm/internal/database"
+	"go-crm/pkg/locale"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DateMigrationResult counts the date values one module had rewritten
+type DateMigrationResult struct {
+	Module    string `json:"module"`
+	Converted int    `json:"converted"`
+	Skipped   int    `json:"skipped"` // Values that are not a recognisable date
+}
+
+// MigrateDatesToUTC rewrites date fields that older imports and integrations
+// stored as text into UTC datetimes. Values without an offset are read in the
+// tenant's timezone. Running it again only touches values still stored as text.
+func (s *RecordServiceImpl) MigrateDatesToUTC(ctx context.Context) ([]DateMigrationResult, error) {
+	loc := time.UTC
+	if s.Timezones != nil {
+		loc = s.Timezones.Location(ctx, "")
+	}
+
+	modules, err := s.ModuleRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []DateMigrationResult{}
+	for _, m := range modules {
+		var dateFields []string
+		for _, f := range m.Fields {
+			if f.Type == models.FieldTypeDate {
+				dateFields = append(dateFields, f.Name)
+			}
+		}
+		if len(dateFields) == 0 {
+			continue
+		}
+
+		result := DateMigrationResult{Module: m.Name}
+		for _, field := range dateFields {
+			filter := map[string]any{field: bson.M{"$type": "string"}}
+			err := s.eachMigrationBatch(ctx, m.Name, filter, func(records []map[string]any) error {
+				updates := make(map[primitive.ObjectID]map[string]any, len(records))
+				for _, rec := range records {
+					raw, _ := rec[field].(string)
+					id, _ := rec["_id"].(primitive.ObjectID)
+					var value any
+					if raw != "" {
+						t, _, err := locale.ParseDateTime(raw, loc)
+						if err != nil {
+							result.Skipped++
+							continue
+						}
+						value = t
+					}
+					updates[id] = map[string]any{field: value}
+				}
+				if err := s.RecordRepo.BulkUpdate(ctx, m.Name, updates); err != nil {
+					return err
+				}
+				result.Converted += len(updates)
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		if result.Converted > 0 || result.Skipped > 0 {
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// migrationBatchSize bounds how many records a data migration holds at once
+const migrationBatchSize = 500
+
+// eachMigrationBatch hands the records of a module matching filter to fn in
+// _id order, one batch at a time. Paging on _id rather than an offset keeps
+// every query cheap and is not thrown off when fn rewrites the records it got.
+// Batches are read from the primary so a rewrite is never read back stale.
+func (s *RecordServiceImpl) eachMigrationBatch(ctx context.Context, moduleName string, filter map[string]any, fn func([]map[string]any) error) error {
+	ctx = database.WithReadMode(ctx, database.ReadPrimary)
+	after := primitive.NilObjectID
+	for {
+		page := map[string]any{"_id": bson.M{"$gt": after}}
+		for k, v := range filter {
+			page[k] = v
+		}
+		records, err := s.RecordRepo.List(ctx, moduleName, page, nil, migrationBatchSize, 0, "_id", 1)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		if err := fn(records); err != nil {
+			return err
+		}
+		if len(records) < migrationBatchSize {
+			return nil
+		}
+		after, _ = records[len(records)-1]["_id"].(primitive.ObjectID)
+	}
+}
@@ -0,0 +1,105 @@
+package record
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/module"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type migrationModuleRepo struct {
+	module.ModuleRepository
+	modules []common_models.Entity
+}
+
+func (r *migrationModuleRepo) List(ctx context.Context) ([]common_models.Entity, error) {
+	return r.modules, nil
+}
+
+// pagedRecordRepo serves records in _id order, honouring the _id cursor,
+// the $type filter and the limit, and applies bulk updates in memory
+type pagedRecordRepo struct {
+	RecordRepository
+	records []map[string]any
+	limits  []int64
+	batches []int
+}
+
+func (r *pagedRecordRepo) List(ctx context.Context, moduleName string, filter map[string]any, accessFilter map[string]any, limit, offset int64, sortBy string, sortOrder int) ([]map[string]any, error) {
+	r.limits = append(r.limits, limit)
+	after := filter["_id"].(bson.M)["$gt"].(primitive.ObjectID)
+	var out []map[string]any
+	for _, rec := range r.records {
+		if rec["_id"].(primitive.ObjectID).Hex() <= after.Hex() {
+			continue
+		}
+		if _, ok := rec["closed_on"].(string); !ok {
+			continue
+		}
+		out = append(out, rec)
+		if int64(len(out)) == limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (r *pagedRecordRepo) BulkUpdate(ctx context.Context, moduleName string, updates map[primitive.ObjectID]map[string]any) error {
+	r.batches = append(r.batches, len(updates))
+	for _, rec := range r.records {
+		for k, v := range updates[rec["_id"].(primitive.ObjectID)] {
+			rec[k] = v
+		}
+	}
+	return nil
+}
+
+func TestMigrateDatesToUTCPagesThroughRecords(t *testing.T) {
+	repo := &pagedRecordRepo{}
+	for i := 0; i < 2*migrationBatchSize+10; i++ {
+		value := "2024-03-01"
+		if i == 3 {
+			value = "next tuesday"
+		}
+		repo.records = append(repo.records, map[string]any{"_id": primitive.NewObjectID(), "closed_on": value})
+	}
+	// Already migrated: not matched by the $type filter
+	repo.records = append(repo.records, map[string]any{"_id": primitive.NewObjectID(), "closed_on": time.Now()})
+
+	s := &RecordServiceImpl{
+		ModuleRepo: &migrationModuleRepo{modules: []common_models.Entity{
+			{Name: "deals", Fields: []common_models.ModuleField{{Name: "closed_on", Type: common_models.FieldTypeDate}}},
+			{Name: "notes", Fields: []common_models.ModuleField{{Name: "body", Type: common_models.FieldTypeText}}},
+		}},
+		RecordRepo: repo,
+	}
+
+	results, err := s.MigrateDatesToUTC(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Converted != 2*migrationBatchSize+9 || results[0].Skipped != 1 {
+		t.Fatalf("results = %+v", results)
+	}
+	for _, limit := range repo.limits {
+		if limit != migrationBatchSize {
+			t.Errorf("listed with limit %d, want %d", limit, migrationBatchSize)
+		}
+	}
+	if len(repo.limits) != 3 || len(repo.batches) != 3 {
+		t.Errorf("pages = %d, bulk writes = %v, want 3 of each", len(repo.limits), repo.batches)
+	}
+
+	want := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	if got := repo.records[0]["closed_on"]; got != want {
+		t.Errorf("closed_on = %v, want %v", got, want)
+	}
+	if got := repo.records[3]["closed_on"]; got != "next tuesday" {
+		t.Errorf("unparseable value rewritten to %v", got)
+	}
+}
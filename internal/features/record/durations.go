@@ -0,0 +1,254 @@
+package record
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/role"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Duration metrics are whole days computed on read. age_days counts from
+// created_at; days_in_<field> counts from when a select field, such as an
+// opportunity's stage, took its current value. Both filter and sort like
+// number fields, so "deals over 30 days in Negotiation" is
+// stage=Negotiation&days_in_stage__gt=30.
+const (
+	AgeDaysField  = "age_days"
+	daysInPrefix  = "days_in_"
+	stageEntryKey = "_stage_changed_at"
+)
+
+const day = 24 * time.Hour
+
+// durationSource names the timestamp a duration pseudo-field counts from.
+// Select fields track theirs under stageEntryKey; records written before that
+// have none, and count from created_at instead.
+func durationSource(m *common_models.Entity, name string) (path string, tracked bool, ok bool) {
+	if name == AgeDaysField {
+		return "created_at", false, true
+	}
+	field, found := strings.CutPrefix(name, daysInPrefix)
+	if !found {
+		return "", false, false
+	}
+	for _, f := range m.Fields {
+		if f.Name == field && f.Type == common_models.FieldTypeSelect {
+			return stageEntryKey + "." + field, true, true
+		}
+	}
+	return "", false, false
+}
+
+// isDurationField reports whether name is a duration pseudo-field of m
+func isDurationField(m *common_models.Entity, name string) bool {
+	_, _, ok := durationSource(m, name)
+	return ok
+}
+
+// durationFilter turns a filter on a duration pseudo-field into a range on
+// the timestamp it counts from
+func durationFilter(m *common_models.Entity, f common_models.Filter, now time.Time) (bson.M, error) {
+	path, tracked, _ := durationSource(m, f.Field)
+
+	var cond bson.M
+	switch f.Operator {
+	case "", "eq":
+		n, err := durationDays(f)
+		if err != nil {
+			return nil, err
+		}
+		cond = daysRange(now, &n, &n)
+	case "gt", "gte", "lt", "lte":
+		n, err := durationDays(f)
+		if err != nil {
+			return nil, err
+		}
+		switch f.Operator {
+		case "gt":
+			n = math.Floor(n) + 1
+			cond = daysRange(now, &n, nil)
+		case "gte":
+			cond = daysRange(now, &n, nil)
+		case "lt":
+			n = math.Ceil(n) - 1
+			cond = daysRange(now, nil, &n)
+		case "lte":
+			cond = daysRange(now, nil, &n)
+		}
+	case "between":
+		lo, hi, err := durationBounds(f)
+		if err != nil {
+			return nil, err
+		}
+		cond = daysRange(now, &lo, &hi)
+	default:
+		return nil, apperr.BadRequest("operator '%s' is not supported for '%s'", f.Operator, f.Field)
+	}
+
+	if !tracked {
+		return bson.M{path: cond}, nil
+	}
+	return bson.M{"$or": []bson.M{
+		{path: cond},
+		{path: bson.M{"$exists": false}, "created_at": cond},
+	}}, nil
+}
+
+// daysRange matches timestamps whose whole days before now lie in [min, max];
+// either bound may be open
+func daysRange(now time.Time, min, max *float64) bson.M {
+	cond := bson.M{}
+	if min != nil {
+		cond["$lte"] = now.Add(-time.Duration(math.Ceil(*min)) * day)
+	}
+	if max != nil {
+		cond["$gt"] = now.Add(-time.Duration(math.Floor(*max)+1) * day)
+	}
+	return cond
+}
+
+func durationDays(f common_models.Filter) (float64, error) {
+	switch v := f.Value.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		if n, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			return n, nil
+		}
+	}
+	return 0, apperr.BadRequest("invalid filter value for '%s': expected a number of days", f.Field)
+}
+
+func durationBounds(f common_models.Filter) (float64, float64, error) {
+	var parts []interface{}
+	switch v := f.Value.(type) {
+	case string:
+		for _, p := range strings.Split(v, ",") {
+			parts = append(parts, p)
+		}
+	case []interface{}:
+		parts = v
+	}
+	if len(parts) != 2 {
+		return 0, 0, apperr.BadRequest("invalid range values for '%s'", f.Field)
+	}
+	lo, err := durationDays(common_models.Filter{Field: f.Field, Value: parts[0]})
+	if err != nil {
+		return 0, 0, err
+	}
+	hi, err := durationDays(common_models.Filter{Field: f.Field, Value: parts[1]})
+	if err != nil {
+		return 0, 0, err
+	}
+	return lo, hi, nil
+}
+
+// durationSort maps a sort on a duration pseudo-field to its timestamp, in
+// the opposite order: the longest in stage entered it first
+func durationSort(m *common_models.Entity, sortBy string, order int) (string, int) {
+	path, _, ok := durationSource(m, sortBy)
+	if !ok {
+		return sortBy, order
+	}
+	return path, -order
+}
+
+// stageEntries returns the select fields whose value data sets for the first
+// time or changes from old, stamped with now. old is nil on create.
+func stageEntries(m *common_models.Entity, old, data map[string]interface{}, now time.Time) map[string]interface{} {
+	entries := map[string]interface{}{}
+	for _, f := range m.Fields {
+		if f.Type != common_models.FieldTypeSelect {
+			continue
+		}
+		val, ok := data[f.Name]
+		if !ok || (old == nil && (val == nil || val == "")) {
+			continue
+		}
+		if old != nil && fmt.Sprint(old[f.Name]) == fmt.Sprint(val) {
+			continue
+		}
+		entries[f.Name] = now
+	}
+	return entries
+}
+
+// withDurations adds the duration pseudo-fields to a listed record. Those of
+// hidden fields are left out, along with the field's entry timestamp.
+func withDurations(m *common_models.Entity, record map[string]any, perms map[string]string, now time.Time) {
+	created, ok := record["created_at"].(time.Time)
+	if !ok {
+		return
+	}
+	record[AgeDaysField] = wholeDays(now.Sub(created))
+
+	entries := entryMap(record[stageEntryKey])
+	for _, f := range m.Fields {
+		if f.Type != common_models.FieldTypeSelect {
+			continue
+		}
+		if perms[f.Name] == role.FieldPermNone {
+			delete(entries, f.Name)
+			continue
+		}
+		if _, ok := record[f.Name]; !ok {
+			continue
+		}
+		since := created
+		if t, ok := entryTime(entries[f.Name]); ok {
+			since = t
+		}
+		record[daysInPrefix+f.Name] = wholeDays(now.Sub(since))
+	}
+	if entries != nil {
+		record[stageEntryKey] = entries
+	}
+}
+
+// entryMap reads the stored entry timestamps, decoded as a map or a document
+func entryMap(v interface{}) map[string]interface{} {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m
+	case primitive.M:
+		return m
+	case primitive.D:
+		return m.Map()
+	}
+	return nil
+}
+
+func entryTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case primitive.DateTime:
+		return t.Time(), true
+	}
+	return time.Time{}, false
+}
+
+func wholeDays(d time.Duration) int {
+	if d < 0 {
+		return 0
+	}
+	return int(d / day)
+}
+
+// andFilter adds clause to the $and of filter
+func andFilter(filter bson.M, clause bson.M) {
+	and, _ := filter["$and"].([]bson.M)
+	filter["$and"] = append(and, clause)
+}
@@ -0,0 +1,135 @@
+package record
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrExternalIDClaimed is returned when another upsert holds the external ID
+// and has not created its record yet
+var ErrExternalIDClaimed = errors.New("external ID is being created by another request")
+
+// ExternalID maps a record to its ID in a source system. RecordID is nil
+// while the upsert that claimed the mapping is creating the record.
+type ExternalID struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty"`
+	TenantID   primitive.ObjectID  `bson:"tenant_id"`
+	Module     string              `bson:"module"`
+	Source     string              `bson:"source"`
+	ExternalID string              `bson:"external_id"`
+	RecordID   *primitive.ObjectID `bson:"record_id"`
+	CreatedAt  time.Time           `bson:"created_at"`
+	UpdatedAt  time.Time           `bson:"updated_at"`
+}
+
+type ExternalIDRepository interface {
+	// Find returns the mapping of an external ID, or nil when there is none
+	Find(ctx context.Context, moduleName, source, externalID string) (*ExternalID, error)
+	// Claim inserts an empty mapping. It returns false when the external ID is
+	// already mapped or claimed.
+	Claim(ctx context.Context, moduleName, source, externalID string) (bool, error)
+	// Assign points the mapping at a record
+	Assign(ctx context.Context, moduleName, source, externalID string, recordID primitive.ObjectID) error
+	// Release drops a claim whose record could not be created
+	Release(ctx context.Context, moduleName, source, externalID string) error
+	EnsureIndexes(ctx context.Context) error
+}
+
+type ExternalIDRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewExternalIDRepository(mongodb *database.MongodbDB) ExternalIDRepository {
+	return &ExternalIDRepositoryImpl{
+		collection: mongodb.DB.Collection("record_external_ids"),
+	}
+}
+
+func (r *ExternalIDRepositoryImpl) key(ctx context.Context, moduleName, source, externalID string) (bson.M, error) {
+	tenantID, err := tenantObjectID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{"tenant_id": tenantID, "module": moduleName, "source": source, "external_id": externalID}, nil
+}
+
+func (r *ExternalIDRepositoryImpl) Find(ctx context.Context, moduleName, source, externalID string) (*ExternalID, error) {
+	filter, err := r.key(ctx, moduleName, source, externalID)
+	if err != nil {
+		return nil, err
+	}
+	var mapping ExternalID
+	err = r.collection.FindOne(ctx, filter).Decode(&mapping)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &mapping, nil
+}
+
+func (r *ExternalIDRepositoryImpl) Claim(ctx context.Context, moduleName, source, externalID string) (bool, error) {
+	tenantID, err := tenantObjectID(ctx)
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	_, err = r.collection.InsertOne(ctx, ExternalID{
+		TenantID:   tenantID,
+		Module:     moduleName,
+		Source:     source,
+		ExternalID: externalID,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *ExternalIDRepositoryImpl) Assign(ctx context.Context, moduleName, source, externalID string, recordID primitive.ObjectID) error {
+	filter, err := r.key(ctx, moduleName, source, externalID)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.UpdateOne(ctx, filter,
+		bson.M{"$set": bson.M{"record_id": recordID, "updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (r *ExternalIDRepositoryImpl) Release(ctx context.Context, moduleName, source, externalID string) error {
+	filter, err := r.key(ctx, moduleName, source, externalID)
+	if err != nil {
+		return err
+	}
+	filter["record_id"] = nil
+	_, err = r.collection.DeleteOne(ctx, filter)
+	return err
+}
+
+func (r *ExternalIDRepositoryImpl) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "module", Value: 1},
+				{Key: "source", Value: 1},
+				{Key: "external_id", Value: 1},
+			},
+			Options: options.Index().SetName("idx_external_id").SetUnique(true),
+		},
+	})
+	return err
+}
@@ -124,3 +124,52 @@ func TestPrepareFilters_IDTypes(t *testing.T) {
 		t.Errorf("Expected 2 IDs, got %d", len(inFilter))
 	}
 }
+
+func TestPrepareFilters_LookupInNin(t *testing.T) {
+	service := &RecordServiceImpl{} // IDs are not checked against the lookup module
+	ctx := context.Background()
+	schema := &common_models.Entity{
+		Fields: []common_models.ModuleField{
+			{Name: "account", Label: "Account", Type: common_models.FieldTypeLookup, Lookup: &common_models.LookupDef{LookupModule: "accounts"}},
+		},
+	}
+
+	oid1 := primitive.NewObjectID()
+	oid2 := primitive.NewObjectID()
+
+	for _, op := range []string{"in", "nin"} {
+		// Query params arrive split on commas
+		filters := []common_models.Filter{
+			{Field: "account", Operator: op, Value: []string{oid1.Hex(), " " + oid2.Hex()}},
+		}
+		res, err := service.prepareFilters(ctx, schema, filters)
+		if err != nil {
+			t.Fatalf("%s: prepareFilters failed: %v", op, err)
+		}
+		cond, ok := res["account"].(bson.M)
+		if !ok {
+			t.Fatalf("%s: account filter missing or not bson.M", op)
+		}
+		ids, ok := cond["$"+op].([]primitive.ObjectID)
+		if !ok || len(ids) != 2 || ids[0] != oid1 || ids[1] != oid2 {
+			t.Errorf("%s: expected both IDs as ObjectIDs, got %v", op, cond["$"+op])
+		}
+	}
+
+	// Populated lookup values from a JSON body
+	filters := []common_models.Filter{
+		{Field: "account", Operator: "in", Value: []interface{}{map[string]interface{}{"id": oid1.Hex(), "name": "Acme"}}},
+	}
+	res, err := service.prepareFilters(ctx, schema, filters)
+	if err != nil {
+		t.Fatalf("prepareFilters failed: %v", err)
+	}
+	if ids := res["account"].(bson.M)["$in"].([]primitive.ObjectID); len(ids) != 1 || ids[0] != oid1 {
+		t.Errorf("expected populated value to filter by its ID, got %v", ids)
+	}
+
+	bad := []common_models.Filter{{Field: "account", Operator: "in", Value: "not-an-id"}}
+	if _, err := service.prepareFilters(ctx, schema, bad); err == nil {
+		t.Error("expected an error for an invalid record ID")
+	}
+}
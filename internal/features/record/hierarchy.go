@@ -0,0 +1,65 @@
+package record
+
+import (
+	"context"
+	"fmt"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/common/validation"
+)
+
+// MaxHierarchyDepth bounds the parent chains of lookups pointing at their own
+// module, such as the parent_account of accounts
+const MaxHierarchyDepth = 20
+
+// checkHierarchies rejects self-referencing lookup values that would make a
+// record its own ancestor or nest it deeper than MaxHierarchyDepth. id is
+// empty on create.
+func (s *RecordServiceImpl) checkHierarchies(ctx context.Context, m *common_models.Entity, id string, data map[string]interface{}) error {
+	var errs validation.Errors
+	for _, field := range m.Fields {
+		if field.Type != common_models.FieldTypeLookup || field.Lookup == nil || field.Lookup.LookupModule != m.Name {
+			continue
+		}
+		val, ok := data[field.Name]
+		if !ok || val == nil {
+			continue
+		}
+		ids, err := lookupIDs(val)
+		if err != nil || len(ids) == 0 {
+			continue
+		}
+		if err := s.checkAncestry(ctx, m.Name, field, id, ids[0].Hex()); err != nil {
+			errs.Add(field.Name, validation.CodeInvalid, err.Error())
+		}
+	}
+	return errs.Err()
+}
+
+// checkAncestry follows the parent chain up from parent. A cycle already
+// above the record is left alone; it is not the write's doing.
+func (s *RecordServiceImpl) checkAncestry(ctx context.Context, moduleName string, field common_models.ModuleField, id, parent string) error {
+	seen := make(map[string]bool)
+	for depth := 1; parent != ""; depth++ {
+		if parent == id {
+			return fmt.Errorf("'%s' cannot be the record itself or one of its descendants", field.Label)
+		}
+		if seen[parent] {
+			return nil
+		}
+		if depth >= MaxHierarchyDepth {
+			return fmt.Errorf("'%s' would nest the record more than %d levels deep", field.Label, MaxHierarchyDepth)
+		}
+		seen[parent] = true
+
+		rec, err := s.RecordRepo.Get(ctx, moduleName, parent)
+		if err != nil {
+			return nil
+		}
+		parent = ""
+		if ids, err := lookupIDs(rec[field.Name]); err == nil && len(ids) > 0 {
+			parent = ids[0].Hex()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package record
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/role"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const maxFieldHistory = 500
+
+// FieldHistoryPoint is one change of a field, New being the value it took
+type FieldHistoryPoint struct {
+	At        time.Time                 `json:"at"`
+	Action    common_models.AuditAction `json:"action"`
+	Old       interface{}               `json:"old,omitempty"`
+	New       interface{}               `json:"new"`
+	ActorID   string                    `json:"actor_id"`
+	ActorName string                    `json:"actor_name,omitempty"`
+}
+
+// FieldHistory is the change series of one field of a record, oldest first.
+// Tracked says whether the module keeps the field's history now; points
+// from before a change of configuration remain.
+type FieldHistory struct {
+	Field   string              `json:"field"`
+	Tracked bool                `json:"tracked"`
+	Points  []FieldHistoryPoint `json:"points"`
+}
+
+// auditedChanges leaves out the fields the module's history tracking does not
+// keep. Keys that are not module fields, such as updated_at, stay, so every
+// write is still logged for the changes feed.
+func auditedChanges(m *common_models.Entity, changes map[string]common_models.Change) map[string]common_models.Change {
+	if m.HistoryTracking == nil {
+		return changes
+	}
+	isField := make(map[string]bool, len(m.Fields))
+	for _, f := range m.Fields {
+		isField[f.Name] = true
+	}
+	kept := make(map[string]common_models.Change, len(changes))
+	for k, c := range changes {
+		if !isField[k] || m.TracksHistory(k) {
+			kept[k] = c
+		}
+	}
+	return kept
+}
+
+// GetFieldHistory returns the values one field of a record took, from its
+// creation on, for charting. The caller needs read access to the record and
+// must be allowed to see the field.
+func (s *RecordServiceImpl) GetFieldHistory(ctx context.Context, moduleName, id, field string, userID primitive.ObjectID) (*FieldHistory, error) {
+	m, err := s.ModuleRepo.FindByName(ctx, moduleName)
+	if err != nil {
+		return nil, ErrModuleNotFound
+	}
+	known := false
+	for _, f := range m.Fields {
+		if f.Name == field {
+			known = true
+		}
+	}
+	if !known {
+		return nil, apperr.NotFound("unknown field '%s'", field)
+	}
+
+	if err := s.checkRecordAccess(ctx, moduleName, id, userID, "read"); err != nil {
+		return nil, err
+	}
+	if _, err := s.RecordRepo.Get(ctx, moduleName, id); err != nil {
+		return nil, ErrRecordNotFound
+	}
+	if perms, err := s.RoleService.GetFieldPermissions(ctx, userID, moduleName); err == nil && perms[field] == role.FieldPermNone {
+		return nil, fmt.Errorf("%w: field '%s' is hidden", ErrAccessDenied, field)
+	}
+
+	// Newest first, so the cap keeps the latest changes
+	logs, err := s.AuditService.ListLogs(ctx, map[string]interface{}{
+		"module":           moduleName,
+		"record_id":        id,
+		"action":           bson.M{"$in": []common_models.AuditAction{common_models.AuditActionCreate, common_models.AuditActionUpdate}},
+		"changes." + field: bson.M{"$exists": true},
+	}, 1, maxFieldHistory)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &FieldHistory{Field: field, Tracked: m.TracksHistory(field), Points: make([]FieldHistoryPoint, 0, len(logs))}
+	for i := len(logs) - 1; i >= 0; i-- {
+		l := logs[i]
+		c := l.Changes[field]
+		history.Points = append(history.Points, FieldHistoryPoint{
+			At:        l.Timestamp,
+			Action:    l.Action,
+			Old:       c.Old,
+			New:       c.New,
+			ActorID:   l.ActorID,
+			ActorName: l.ActorName,
+		})
+	}
+	return history, nil
+}
@@ -0,0 +1,157 @@
+package record
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/role"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultLookupLimit = 10
+	maxLookupLimit     = 50
+)
+
+// LookupOption is one match of a lookup search, shaped like a populated
+// lookup value
+type LookupOption struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// SearchLookup is the typeahead behind lookup pickers. With field, it
+// searches the module that lookup field of moduleName points at, on the
+// field's lookup label; without, it searches moduleName by name. Matches at
+// the start of the label come first. The searched module's read permission,
+// access filter and field permissions apply.
+func (s *RecordServiceImpl) SearchLookup(ctx context.Context, moduleName, field, query string, limit int64, userID primitive.ObjectID) ([]LookupOption, error) {
+	m, err := s.ModuleRepo.FindByName(ctx, moduleName)
+	if err != nil {
+		return nil, ErrModuleNotFound
+	}
+	if limit < 1 {
+		limit = defaultLookupLimit
+	}
+	if limit > maxLookupLimit {
+		limit = maxLookupLimit
+	}
+
+	target, label := m, "name"
+	if field != "" {
+		var def *common_models.ModuleField
+		for i := range m.Fields {
+			if m.Fields[i].Name == field {
+				def = &m.Fields[i]
+			}
+		}
+		if def == nil || def.Type != common_models.FieldTypeLookup || def.Lookup == nil || def.Lookup.LookupModule == "" {
+			return nil, apperr.Validation("'%s' is not a lookup field of %s", field, moduleName)
+		}
+		if perms, err := s.RoleService.GetFieldPermissions(ctx, userID, moduleName); err == nil && perms[field] == role.FieldPermNone {
+			return nil, fmt.Errorf("%w: field '%s' is hidden", ErrAccessDenied, field)
+		}
+		if def.Lookup.LookupLabel != "" {
+			label = def.Lookup.LookupLabel
+		}
+		if target, err = s.ModuleRepo.FindByName(ctx, def.Lookup.LookupModule); err != nil {
+			return nil, ErrModuleNotFound
+		}
+		if !s.hasModulePermission(ctx, target.Name, "read") {
+			return nil, fmt.Errorf("%w: missing read permission on %s", ErrAccessDenied, target.Name)
+		}
+	}
+	if perms, err := s.RoleService.GetFieldPermissions(ctx, userID, target.Name); err == nil && perms[label] == role.FieldPermNone {
+		return nil, fmt.Errorf("%w: field '%s' is hidden", ErrAccessDenied, label)
+	}
+
+	accessFilter, err := s.RoleService.GetAccessFilter(ctx, userID, target.Name, "read")
+	if err != nil {
+		return nil, err
+	}
+
+	quoted := regexp.QuoteMeta(strings.TrimSpace(query))
+	var options []LookupOption
+	var seen []primitive.ObjectID
+	for _, pattern := range []string{"^" + quoted, quoted} {
+		filter := bson.M{label: bson.M{"$regex": primitive.Regex{Pattern: pattern, Options: "i"}}}
+		if len(seen) > 0 {
+			filter["_id"] = bson.M{"$nin": seen}
+		}
+		records, err := s.RecordRepo.List(ctx, target.Name, filter, accessFilter, limit-int64(len(options)), 0, label, 1)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			oid, ok := record["_id"].(primitive.ObjectID)
+			if !ok {
+				continue
+			}
+			seen = append(seen, oid)
+			options = append(options, LookupOption{ID: oid.Hex(), Name: fmt.Sprint(record[label])})
+		}
+		if quoted == "" || int64(len(options)) >= limit {
+			break
+		}
+	}
+	if options == nil {
+		options = []LookupOption{}
+	}
+	return options, nil
+}
+
+// lookupIDs reads the record IDs of an in or nin filter on a lookup field:
+// a comma separated string or an array of IDs or populated lookup values.
+// Unlike lookup values being saved, the records are not looked up.
+func lookupIDs(val interface{}) ([]primitive.ObjectID, error) {
+	var items []interface{}
+	switch v := val.(type) {
+	case string:
+		for _, p := range strings.Split(v, ",") {
+			items = append(items, p)
+		}
+	case []string:
+		for _, p := range v {
+			items = append(items, p)
+		}
+	case []interface{}:
+		items = v
+	case primitive.A:
+		items = v
+	default:
+		items = []interface{}{v}
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(items))
+	for _, item := range items {
+		switch v := item.(type) {
+		case primitive.ObjectID:
+			ids = append(ids, v)
+			continue
+		case map[string]interface{}:
+			item = v["id"]
+		case primitive.M:
+			item = v["id"]
+		}
+		if oid, ok := item.(primitive.ObjectID); ok {
+			ids = append(ids, oid)
+			continue
+		}
+		str, _ := item.(string)
+		if str = strings.TrimSpace(str); str == "" {
+			continue
+		}
+		oid, err := primitive.ObjectIDFromHex(str)
+		if err != nil {
+			return nil, fmt.Errorf("invalid record ID '%s'", str)
+		}
+		ids = append(ids, oid)
+	}
+	return ids, nil
+}
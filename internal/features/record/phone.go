@@ -0,0 +1,284 @@
+package record
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/role"
+	"go-crm/pkg/locale"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Phone fields keep the number as it was written. Its E.164 form is stored
+// under phoneKey, by field, so filters and caller-ID lookups match however
+// the number was formatted.
+const phoneKey = "_phone_e164"
+
+// phoneLookupLimit bounds the records one module contributes to a lookup
+const phoneLookupLimit = 20
+
+// PhoneMatch is a record with a phone field holding the number looked up
+type PhoneMatch struct {
+	Module string         `json:"module"`
+	Field  string         `json:"field"`
+	Record map[string]any `json:"record"`
+}
+
+// PhoneMigrationResult counts the phone values of one module given their
+// E.164 form
+type PhoneMigrationResult struct {
+	Module     string `json:"module"`
+	Normalized int    `json:"normalized"`
+	Skipped    int    `json:"skipped"` // Values that are not a recognisable number
+}
+
+// phoneNumbers returns the E.164 form of each phone field written in data,
+// nil for cleared ones. Values were validated on the way in.
+func phoneNumbers(ctx context.Context, m *common_models.Entity, data map[string]any) map[string]any {
+	var numbers map[string]any
+	for _, f := range m.Fields {
+		if f.Type != common_models.FieldTypePhone {
+			continue
+		}
+		val, ok := data[f.Name]
+		if !ok {
+			continue
+		}
+		if numbers == nil {
+			numbers = make(map[string]any)
+		}
+		raw, _ := val.(string)
+		if e164, err := locale.ParsePhone(raw, locale.PhoneRegionFrom(ctx)); err == nil {
+			numbers[f.Name] = e164
+		} else {
+			numbers[f.Name] = nil
+		}
+	}
+	return numbers
+}
+
+// phoneFilter matches a phone field as written or in E.164 form. Numbers in
+// the filter are read like those written; partial numbers match the digits
+// of the E.164 form.
+func phoneFilter(ctx context.Context, field, operator string, val any) bson.M {
+	normalized := phoneKey + "." + field
+	region := locale.PhoneRegionFrom(ctx)
+
+	switch operator {
+	case "in", "nin":
+		var values []string
+		switch v := val.(type) {
+		case string:
+			for _, p := range strings.Split(v, ",") {
+				values = append(values, strings.TrimSpace(p))
+			}
+		case []string:
+			values = v
+		case []interface{}:
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					values = append(values, s)
+				}
+			}
+		}
+		numbers := make([]string, 0, len(values))
+		for _, v := range values {
+			if e164, err := locale.ParsePhone(v, region); err == nil {
+				numbers = append(numbers, e164)
+			}
+		}
+		cond := bson.M{"$or": []bson.M{{field: bson.M{"$in": values}}, {normalized: bson.M{"$in": numbers}}}}
+		if operator == "nin" {
+			return bson.M{"$nor": []bson.M{cond}}
+		}
+		return cond
+	}
+
+	str, _ := val.(string)
+	switch operator {
+	case "", "eq", "ne":
+		cond := bson.M{field: str}
+		if e164, err := locale.ParsePhone(str, region); err == nil {
+			cond = bson.M{"$or": []bson.M{cond, {normalized: e164}}}
+		}
+		if operator == "ne" {
+			return bson.M{"$nor": []bson.M{cond}}
+		}
+		return cond
+	case "contains", "starts_with", "ends_with":
+		pattern := str
+		digits := phoneDigits.ReplaceAllString(str, "")
+		switch operator {
+		case "starts_with":
+			pattern = "^" + pattern
+			if strings.HasPrefix(strings.TrimLeft(str, `\`), "+") {
+				digits = `^\+` + digits
+			} else {
+				digits = ""
+			}
+		case "ends_with":
+			pattern += "$"
+			digits += "$"
+		}
+		cond := bson.M{field: bson.M{"$regex": primitive.Regex{Pattern: pattern, Options: "i"}}}
+		if strings.Trim(digits, `^\+$`) == "" {
+			return cond
+		}
+		return bson.M{"$or": []bson.M{cond, {normalized: bson.M{"$regex": primitive.Regex{Pattern: digits}}}}}
+	}
+	return bson.M{field: val}
+}
+
+var phoneDigits = regexp.MustCompile(`[^0-9]`)
+
+// FindByPhone returns the records the user may read, in the given modules,
+// with a phone field holding the number, for caller-ID lookups. National
+// numbers are read in the user's country. Phone fields hidden from the user
+// are not searched.
+func (s *RecordServiceImpl) FindByPhone(ctx context.Context, number string, moduleNames []string, userID primitive.ObjectID) ([]PhoneMatch, error) {
+	ctx = s.withUserLocation(ctx, userID)
+	number = strings.TrimSpace(number)
+	e164, err := locale.ParsePhone(number, locale.PhoneRegionFrom(ctx))
+	if err != nil {
+		return nil, apperr.BadRequest("invalid phone number '%s': %v", number, err)
+	}
+
+	modules, err := s.ModuleRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(moduleNames))
+	for _, name := range moduleNames {
+		wanted[name] = true
+	}
+	matches := []PhoneMatch{}
+	for i := range modules {
+		m := &modules[i]
+		if !wanted[m.Name] {
+			continue
+		}
+		var fields []string
+		for _, f := range m.Fields {
+			if f.Type == common_models.FieldTypePhone {
+				fields = append(fields, f.Name)
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		accessFilter, err := s.RoleService.GetAccessFilter(ctx, userID, m.Name, "read")
+		if err != nil {
+			continue
+		}
+		if v, denied := accessFilter["_id"]; denied && v == -1 {
+			continue
+		}
+		perms, _ := s.RoleService.GetFieldPermissions(ctx, userID, m.Name)
+
+		var or []bson.M
+		searched := fields[:0:0]
+		for _, field := range fields {
+			if perms[field] == role.FieldPermNone {
+				continue
+			}
+			searched = append(searched, field)
+			// Records written before numbers were normalized match as written
+			or = append(or, bson.M{phoneKey + "." + field: e164}, bson.M{field: number})
+		}
+		if len(or) == 0 {
+			continue
+		}
+
+		recs, err := s.RecordRepo.List(ctx, m.Name, map[string]any{"$or": or}, accessFilter, phoneLookupLimit, 0, "updated_at", -1)
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range recs {
+			field := matchedPhoneField(rec, searched, e164, number)
+			for name, p := range perms {
+				if p == role.FieldPermNone {
+					delete(rec, name)
+				}
+			}
+			matches = append(matches, PhoneMatch{Module: m.Name, Field: field, Record: rec})
+		}
+	}
+	return matches, nil
+}
+
+// matchedPhoneField returns the first of fields holding the number
+func matchedPhoneField(rec map[string]any, fields []string, e164, number string) string {
+	var stored map[string]any
+	switch v := rec[phoneKey].(type) {
+	case map[string]any:
+		stored = v
+	case bson.M:
+		stored = v
+	case bson.D:
+		stored = v.Map()
+	}
+	for _, field := range fields {
+		if stored[field] == e164 || rec[field] == number {
+			return field
+		}
+	}
+	return ""
+}
+
+// MigratePhoneNumbers stores the E.164 form of phone values written before
+// numbers were normalized, reading national numbers in the tenant's
+// country. Running it again only touches values still without one.
+func (s *RecordServiceImpl) MigratePhoneNumbers(ctx context.Context) ([]PhoneMigrationResult, error) {
+	ctx = s.withUserLocation(ctx, primitive.NilObjectID)
+	region := locale.PhoneRegionFrom(ctx)
+
+	modules, err := s.ModuleRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []PhoneMigrationResult{}
+	for _, m := range modules {
+		result := PhoneMigrationResult{Module: m.Name}
+		for _, f := range m.Fields {
+			if f.Type != common_models.FieldTypePhone {
+				continue
+			}
+			key := phoneKey + "." + f.Name
+			filter := map[string]any{
+				f.Name: bson.M{"$type": "string", "$ne": ""},
+				key:    bson.M{"$exists": false},
+			}
+			err := s.eachMigrationBatch(ctx, m.Name, filter, func(records []map[string]any) error {
+				updates := make(map[primitive.ObjectID]map[string]any, len(records))
+				for _, rec := range records {
+					raw, _ := rec[f.Name].(string)
+					id, _ := rec["_id"].(primitive.ObjectID)
+					e164, err := locale.ParsePhone(raw, region)
+					if err != nil {
+						// Stored as null so the next run skips it too
+						updates[id] = map[string]any{key: nil}
+						result.Skipped++
+						continue
+					}
+					updates[id] = map[string]any{key: e164}
+					result.Normalized++
+				}
+				return s.RecordRepo.BulkUpdate(ctx, m.Name, updates)
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		if result.Normalized > 0 || result.Skipped > 0 {
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
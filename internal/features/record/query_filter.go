@@ -0,0 +1,518 @@
+package record
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ErrInvalidFilter wraps syntax and schema validation errors in $filter expressions
+var ErrInvalidFilter = errors.New("invalid filter")
+
+type FilterExprOp string
+
+const (
+	FilterExprAnd  FilterExprOp = "and"
+	FilterExprOr   FilterExprOp = "or"
+	FilterExprNot  FilterExprOp = "not"
+	FilterExprLeaf FilterExprOp = "leaf"
+)
+
+const (
+	maxFilterDepth      = 10
+	maxFilterConditions = 50
+)
+
+// FilterExpr is the boolean tree parsed from an OData-style $filter, e.g.
+//
+//	stage eq 'Closed Won' and (amount ge 1000 or not startswith(name, 'Test'))
+//
+// Leaves carry a regular Filter so they run through prepareFilters.
+type FilterExpr struct {
+	Op       FilterExprOp
+	Children []*FilterExpr
+	Filter   *common_models.Filter
+}
+
+// odataOperators maps $filter comparison operators to prepareFilters operators
+var odataOperators = map[string]string{
+	"eq": "eq",
+	"ne": "ne",
+	"gt": "gt",
+	"ge": "gte",
+	"lt": "lt",
+	"le": "lte",
+}
+
+var odataFunctions = map[string]string{
+	"contains":   "contains",
+	"startswith": "starts_with",
+	"endswith":   "ends_with",
+}
+
+// ParseFilterExpression parses the supported $filter subset: eq, ne, gt, ge,
+// lt, le, in (...), contains(), startswith(), endswith(), and/or/not and
+// parentheses. Literals are 'quoted strings' (double a quote to escape it), numbers,
+// true/false, null and bare dates (2024-01-31 or RFC3339).
+func ParseFilterExpression(input string) (*FilterExpr, error) {
+	tokens, err := tokenizeFilter(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%w: expression is empty", ErrInvalidFilter)
+	}
+
+	p := &filterParser{tokens: tokens}
+	expr, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %q at position %d", ErrInvalidFilter, p.tokens[p.pos].text, p.tokens[p.pos].pos)
+	}
+	if p.conditions > maxFilterConditions {
+		return nil, fmt.Errorf("%w: too many conditions (max %d)", ErrInvalidFilter, maxFilterConditions)
+	}
+	return expr, nil
+}
+
+type filterTokenKind int
+
+const (
+	filterTokIdent filterTokenKind = iota
+	filterTokString
+	filterTokLiteral // numbers and bare dates
+	filterTokLParen
+	filterTokRParen
+	filterTokComma
+)
+
+type filterToken struct {
+	kind filterTokenKind
+	text string
+	pos  int
+}
+
+func tokenizeFilter(input string) ([]filterToken, error) {
+	var tokens []filterToken
+	i := 0
+	for i < len(input) {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, filterToken{kind: filterTokLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, filterToken{kind: filterTokRParen, text: ")", pos: i})
+			i++
+		case c == ',':
+			tokens = append(tokens, filterToken{kind: filterTokComma, text: ",", pos: i})
+			i++
+		case c == '\'':
+			start := i
+			i++
+			var b strings.Builder
+			closed := false
+			for i < len(input) {
+				if input[i] == '\'' {
+					if i+1 < len(input) && input[i+1] == '\'' {
+						b.WriteByte('\'')
+						i += 2
+						continue
+					}
+					i++
+					closed = true
+					break
+				}
+				b.WriteByte(input[i])
+				i++
+			}
+			if !closed {
+				return nil, fmt.Errorf("%w: unterminated string at position %d", ErrInvalidFilter, start)
+			}
+			tokens = append(tokens, filterToken{kind: filterTokString, text: b.String(), pos: start})
+		case c == '-' || (c >= '0' && c <= '9'):
+			start := i
+			for i < len(input) && strings.IndexByte("0123456789-+.:TZtz", input[i]) >= 0 {
+				i++
+			}
+			tokens = append(tokens, filterToken{kind: filterTokLiteral, text: input[start:i], pos: start})
+		case c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			start := i
+			for i < len(input) && (input[i] == '_' || input[i] == '$' || input[i] == '.' ||
+				(input[i] >= 'a' && input[i] <= 'z') || (input[i] >= 'A' && input[i] <= 'Z') || (input[i] >= '0' && input[i] <= '9')) {
+				i++
+			}
+			tokens = append(tokens, filterToken{kind: filterTokIdent, text: input[start:i], pos: start})
+		default:
+			return nil, fmt.Errorf("%w: unexpected character %q at position %d", ErrInvalidFilter, c, i)
+		}
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens     []filterToken
+	pos        int
+	conditions int
+}
+
+func (p *filterParser) peek() *filterToken {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *filterParser) peekKeyword(word string) bool {
+	t := p.peek()
+	return t != nil && t.kind == filterTokIdent && strings.EqualFold(t.text, word)
+}
+
+func (p *filterParser) expect(kind filterTokenKind, what string) (*filterToken, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, fmt.Errorf("%w: expected %s at end of expression", ErrInvalidFilter, what)
+	}
+	if t.kind != kind {
+		return nil, fmt.Errorf("%w: expected %s at position %d, got %q", ErrInvalidFilter, what, t.pos, t.text)
+	}
+	p.pos++
+	return t, nil
+}
+
+func (p *filterParser) parseOr(depth int) (*FilterExpr, error) {
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	if !p.peekKeyword("or") {
+		return left, nil
+	}
+	node := &FilterExpr{Op: FilterExprOr, Children: []*FilterExpr{left}}
+	for p.peekKeyword("or") {
+		p.pos++
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, right)
+	}
+	return node, nil
+}
+
+func (p *filterParser) parseAnd(depth int) (*FilterExpr, error) {
+	left, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+	if !p.peekKeyword("and") {
+		return left, nil
+	}
+	node := &FilterExpr{Op: FilterExprAnd, Children: []*FilterExpr{left}}
+	for p.peekKeyword("and") {
+		p.pos++
+		right, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, right)
+	}
+	return node, nil
+}
+
+func (p *filterParser) parseUnary(depth int) (*FilterExpr, error) {
+	if depth > maxFilterDepth {
+		return nil, fmt.Errorf("%w: expression is nested too deeply (max %d)", ErrInvalidFilter, maxFilterDepth)
+	}
+	if p.peekKeyword("not") {
+		p.pos++
+		child, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &FilterExpr{Op: FilterExprNot, Children: []*FilterExpr{child}}, nil
+	}
+	return p.parsePrimary(depth)
+}
+
+func (p *filterParser) parsePrimary(depth int) (*FilterExpr, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, fmt.Errorf("%w: unexpected end of expression", ErrInvalidFilter)
+	}
+
+	if t.kind == filterTokLParen {
+		p.pos++
+		expr, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(filterTokRParen, "')'"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+
+	ident, err := p.expect(filterTokIdent, "field name")
+	if err != nil {
+		return nil, err
+	}
+
+	// Function call: contains(field, 'value')
+	if op, ok := odataFunctions[strings.ToLower(ident.text)]; ok {
+		if next := p.peek(); next != nil && next.kind == filterTokLParen {
+			p.pos++
+			field, err := p.expect(filterTokIdent, "field name")
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(filterTokComma, "','"); err != nil {
+				return nil, err
+			}
+			value, err := p.parseLiteral()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(filterTokRParen, "')'"); err != nil {
+				return nil, err
+			}
+			return p.leaf(field.text, op, value), nil
+		}
+	}
+
+	opTok, err := p.expect(filterTokIdent, "operator")
+	if err != nil {
+		return nil, err
+	}
+	opName := strings.ToLower(opTok.text)
+
+	if opName == "in" {
+		if _, err := p.expect(filterTokLParen, "'('"); err != nil {
+			return nil, err
+		}
+		values := []interface{}{}
+		for {
+			v, err := p.parseLiteral()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+			if next := p.peek(); next != nil && next.kind == filterTokComma {
+				p.pos++
+				continue
+			}
+			break
+		}
+		if _, err := p.expect(filterTokRParen, "')'"); err != nil {
+			return nil, err
+		}
+		return p.leaf(ident.text, "in", values), nil
+	}
+
+	op, ok := odataOperators[opName]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown operator %q at position %d", ErrInvalidFilter, opTok.text, opTok.pos)
+	}
+	value, err := p.parseLiteral()
+	if err != nil {
+		return nil, err
+	}
+	return p.leaf(ident.text, op, value), nil
+}
+
+func (p *filterParser) leaf(field, operator string, value interface{}) *FilterExpr {
+	p.conditions++
+	return &FilterExpr{Op: FilterExprLeaf, Filter: &common_models.Filter{Field: field, Operator: operator, Value: value}}
+}
+
+func (p *filterParser) parseLiteral() (interface{}, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, fmt.Errorf("%w: expected value at end of expression", ErrInvalidFilter)
+	}
+	p.pos++
+	switch t.kind {
+	case filterTokString:
+		return t.text, nil
+	case filterTokLiteral:
+		if f, err := strconv.ParseFloat(t.text, 64); err == nil {
+			return f, nil
+		}
+		// Bare dates stay strings; validateAndConvert parses them against the field type
+		return t.text, nil
+	case filterTokIdent:
+		switch strings.ToLower(t.text) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: expected value at position %d, got %q", ErrInvalidFilter, t.pos, t.text)
+}
+
+// systemFilterFields are record attributes outside module.Fields that may be filtered on
+var systemFilterFields = map[string]common_models.FieldType{
+	"id":         common_models.FieldTypeText,
+	"_id":        common_models.FieldTypeText,
+	"created_at": common_models.FieldTypeDate,
+	"updated_at": common_models.FieldTypeDate,
+	"created_by": common_models.FieldTypeText,
+}
+
+// compileFilterExpr validates the expression against the module schema and
+// builds the Mongo query. Each leaf goes through prepareFilters so type
+// coercion matches the field__op query parameters.
+func (s *RecordServiceImpl) compileFilterExpr(ctx context.Context, m *common_models.Entity, expr *FilterExpr) (bson.M, error) {
+	switch expr.Op {
+	case FilterExprAnd, FilterExprOr:
+		parts := make([]bson.M, 0, len(expr.Children))
+		for _, child := range expr.Children {
+			part, err := s.compileFilterExpr(ctx, m, child)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
+		}
+		return bson.M{"$" + string(expr.Op): parts}, nil
+	case FilterExprNot:
+		child, err := s.compileFilterExpr(ctx, m, expr.Children[0])
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"$nor": []bson.M{child}}, nil
+	case FilterExprLeaf:
+		return s.compileFilterLeaf(ctx, m, *expr.Filter)
+	}
+	return nil, fmt.Errorf("%w: unsupported expression", ErrInvalidFilter)
+}
+
+func (s *RecordServiceImpl) compileFilterLeaf(ctx context.Context, m *common_models.Entity, f common_models.Filter) (bson.M, error) {
+	if isDurationField(m, f.Field) {
+		cond, err := durationFilter(m, f, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+		}
+		return cond, nil
+	}
+
+	var field *common_models.ModuleField
+	for i := range m.Fields {
+		if m.Fields[i].Name == f.Field {
+			field = &m.Fields[i]
+			break
+		}
+	}
+	if field == nil {
+		if t, ok := systemFilterFields[f.Field]; ok {
+			field = &common_models.ModuleField{Name: f.Field, Label: f.Field, Type: t}
+		}
+	}
+	if field == nil {
+		return nil, fmt.Errorf("%w: unknown field '%s'", ErrInvalidFilter, f.Field)
+	}
+
+	if err := validateFilterOperator(field, f.Operator); err != nil {
+		return nil, err
+	}
+
+	isID := field.Name == "id" || field.Name == "_id"
+	if isID {
+		f.Field = "_id"
+	}
+
+	if f.Value == nil {
+		switch f.Operator {
+		case "eq":
+			return bson.M{f.Field: nil}, nil
+		case "ne":
+			return bson.M{f.Field: bson.M{"$ne": nil}}, nil
+		}
+		return nil, fmt.Errorf("%w: null is only supported with eq and ne on '%s'", ErrInvalidFilter, field.Name)
+	}
+
+	switch f.Operator {
+	case "contains", "starts_with", "ends_with":
+		str, ok := f.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s on '%s' expects a string", ErrInvalidFilter, f.Operator, field.Name)
+		}
+		// Match literally; prepareFilters builds the pattern unescaped
+		f.Value = regexp.QuoteMeta(str)
+	case "in":
+		if field.Type == common_models.FieldTypePhone {
+			// Matched as written or in E.164 form by prepareFilters
+			break
+		}
+		values, _ := f.Value.([]interface{})
+		if field.Type == common_models.FieldTypeLookup {
+			ids, err := lookupIDs(values)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid value for '%s': %v", ErrInvalidFilter, field.Label, err)
+			}
+			return bson.M{f.Field: bson.M{"$in": ids}}, nil
+		}
+		if !isID {
+			converted := make([]interface{}, 0, len(values))
+			for _, v := range values {
+				typed, err := s.validateAndConvert(ctx, *field, v)
+				if err != nil {
+					return nil, fmt.Errorf("%w: invalid value for '%s': %v", ErrInvalidFilter, field.Label, err)
+				}
+				converted = append(converted, typed)
+			}
+			return bson.M{f.Field: bson.M{"$in": converted}}, nil
+		}
+	}
+
+	// Evaluate against just this field so system fields get typed like module fields
+	scoped := &common_models.Entity{Fields: []common_models.ModuleField{*field}}
+	if isID {
+		scoped = m
+	}
+	cond, err := s.prepareFilters(ctx, scoped, []common_models.Filter{f})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	if len(cond) == 0 {
+		return nil, fmt.Errorf("%w: invalid value for '%s'", ErrInvalidFilter, field.Name)
+	}
+	return cond, nil
+}
+
+func validateFilterOperator(field *common_models.ModuleField, op string) error {
+	switch op {
+	case "eq", "ne", "in":
+		if field.Type == common_models.FieldTypeFile || field.Type == common_models.FieldTypeImage {
+			return fmt.Errorf("%w: field '%s' cannot be filtered", ErrInvalidFilter, field.Name)
+		}
+		return nil
+	case "gt", "gte", "lt", "lte":
+		switch field.Type {
+		case common_models.FieldTypeNumber, common_models.FieldTypeCurrency, common_models.FieldTypeDate:
+			return nil
+		}
+	case "contains", "starts_with", "ends_with":
+		switch field.Type {
+		case common_models.FieldTypeText, common_models.FieldTypeTextArea, common_models.FieldTypeEmail,
+			common_models.FieldTypePhone, common_models.FieldTypeURL, common_models.FieldTypeSelect,
+			common_models.FieldTypeMultiSelect:
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: operator '%s' is not supported for %s field '%s'", ErrInvalidFilter, op, field.Type, field.Name)
+}
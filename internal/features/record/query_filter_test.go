@@ -0,0 +1,222 @@
+package record
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	common_models "go-crm/internal/common/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// exprString renders a parsed expression compactly, e.g. and(eq(a,1),not(…))
+func exprString(e *FilterExpr) string {
+	if e.Op == FilterExprLeaf {
+		return fmt.Sprintf("%s(%s,%#v)", e.Filter.Operator, e.Filter.Field, e.Filter.Value)
+	}
+	parts := make([]string, len(e.Children))
+	for i, c := range e.Children {
+		parts[i] = exprString(c)
+	}
+	return string(e.Op) + "(" + strings.Join(parts, ",") + ")"
+}
+
+func TestTokenizeFilter(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []string
+		wantErr string
+	}{
+		{input: "name eq 'Ada'", want: []string{"ident:name", "ident:eq", "string:Ada"}},
+		{input: "amount ge -10.5", want: []string{"ident:amount", "ident:ge", "literal:-10.5"}},
+		{input: "closed lt 2024-01-31T10:00:00Z", want: []string{"ident:closed", "ident:lt", "literal:2024-01-31T10:00:00Z"}},
+		{input: "name eq 'O''Brien'", want: []string{"ident:name", "ident:eq", "string:O'Brien"}},
+		{input: "stage in ('a',\t'b')", want: []string{"ident:stage", "ident:in", "(", "string:a", ",", "string:b", ")"}},
+		{input: "address.city eq ''", want: []string{"ident:address.city", "ident:eq", "string:"}},
+		{input: "   ", want: nil},
+		{input: "name eq 'Ada", wantErr: "unterminated string at position 8"},
+		{input: "name == 'Ada'", wantErr: "unexpected character '=' at position 5"},
+	}
+
+	kinds := map[filterTokenKind]string{filterTokIdent: "ident", filterTokString: "string", filterTokLiteral: "literal"}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			tokens, err := tokenizeFilter(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !errors.Is(err, ErrInvalidFilter) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, tok := range tokens {
+				if kind, ok := kinds[tok.kind]; ok {
+					got = append(got, kind+":"+tok.text)
+				} else {
+					got = append(got, tok.text)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokens = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilterExpression(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr string
+	}{
+		{name: "comparison", input: "amount ge 1000", want: "gte(amount,1000)"},
+		{name: "and binds tighter than or", input: "a eq 1 or b eq 2 and c eq 3", want: "or(eq(a,1),and(eq(b,2),eq(c,3)))"},
+		{name: "parentheses", input: "(a eq 1 or b eq 2) and c eq 3", want: "and(or(eq(a,1),eq(b,2)),eq(c,3))"},
+		{name: "chained or", input: "a eq 1 or b eq 2 or c eq 3", want: "or(eq(a,1),eq(b,2),eq(c,3))"},
+		{name: "not binds tighter than and", input: "not a eq 1 and b eq 2", want: "and(not(eq(a,1)),eq(b,2))"},
+		{name: "not of a group", input: "not (a eq 1 or b eq 2)", want: "not(or(eq(a,1),eq(b,2)))"},
+		{name: "double not", input: "not not a eq 1", want: "not(not(eq(a,1)))"},
+		{name: "keywords ignore case", input: "a EQ 1 AND NOT b Ne 2", want: "and(eq(a,1),not(ne(b,2)))"},
+		{name: "null", input: "closed_at eq null", want: "eq(closed_at,<nil>)"},
+		{name: "booleans", input: "active eq true or archived ne FALSE", want: "or(eq(active,true),ne(archived,false))"},
+		{name: "bare date stays a string", input: "created_at lt 2024-01-31", want: `lt(created_at,"2024-01-31")`},
+		{name: "in", input: "stage in ('Won', 'Lost', 3)", want: `in(stage,[]interface {}{"Won", "Lost", 3})`},
+		{name: "functions", input: "contains(name, 'Acme') or startswith(name, 'A') or endswith(name, 'Ltd')", want: `or(contains(name,"Acme"),starts_with(name,"A"),ends_with(name,"Ltd"))`},
+		{name: "function name as a field", input: "contains eq 'x'", want: `eq(contains,"x")`},
+		{name: "empty", input: "  ", wantErr: "expression is empty"},
+		{name: "unknown operator", input: "a like 'x'", wantErr: `unknown operator "like" at position 2`},
+		{name: "missing value", input: "a eq", wantErr: "expected value at end of expression"},
+		{name: "operator as value", input: "a eq and", wantErr: `expected value at position 5, got "and"`},
+		{name: "unclosed group", input: "(a eq 1", wantErr: "expected ')' at end of expression"},
+		{name: "trailing tokens", input: "a eq 1 b eq 2", wantErr: `unexpected "b" at position 7`},
+		{name: "dangling and", input: "a eq 1 and", wantErr: "unexpected end of expression"},
+		{name: "in without list", input: "a in 'x'", wantErr: "expected '('"},
+		{name: "function without comma", input: "contains(name 'x')", wantErr: "expected ','"},
+		{name: "depth at the limit", input: strings.Repeat("(", 10) + "a eq 1" + strings.Repeat(")", 10), want: "eq(a,1)"},
+		{name: "depth over the limit", input: strings.Repeat("(", 11) + "a eq 1" + strings.Repeat(")", 11), wantErr: "nested too deeply (max 10)"},
+		{name: "not counts towards depth", input: strings.Repeat("not ", 11) + "a eq 1", wantErr: "nested too deeply"},
+		{name: "conditions at the limit", input: strings.TrimSuffix(strings.Repeat("a eq 1 or ", 50), " or "), want: "or(" + strings.TrimSuffix(strings.Repeat("eq(a,1),", 50), ",") + ")"},
+		{name: "too many conditions", input: strings.TrimSuffix(strings.Repeat("a eq 1 or ", 51), " or "), wantErr: "too many conditions (max 50)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := ParseFilterExpression(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !errors.Is(err, ErrInvalidFilter) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := exprString(expr); got != tt.want {
+				t.Errorf("parsed %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileFilterExpr(t *testing.T) {
+	schema := &common_models.Entity{
+		Fields: []common_models.ModuleField{
+			{Name: "name", Label: "Name", Type: common_models.FieldTypeText},
+			{Name: "amount", Label: "Amount", Type: common_models.FieldTypeNumber},
+			{Name: "active", Label: "Active", Type: common_models.FieldTypeBoolean},
+			{Name: "contract", Label: "Contract", Type: common_models.FieldTypeFile},
+		},
+	}
+	service := &RecordServiceImpl{}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    bson.M
+		wantErr string
+	}{
+		{
+			name:  "precedence",
+			input: "name eq 'Ada' or amount gt 10 and active eq true",
+			want: bson.M{"$or": []bson.M{
+				{"name": "Ada"},
+				{"$and": []bson.M{{"amount": bson.M{"$gt": 10.0}}, {"active": true}}},
+			}},
+		},
+		{
+			name:  "not",
+			input: "not (name eq 'Ada' or name eq 'Alan')",
+			want:  bson.M{"$nor": []bson.M{{"$or": []bson.M{{"name": "Ada"}, {"name": "Alan"}}}}},
+		},
+		{name: "eq null", input: "name eq null", want: bson.M{"name": nil}},
+		{name: "ne null", input: "name ne null", want: bson.M{"name": bson.M{"$ne": nil}}},
+		{name: "in converts values", input: "amount in (1, '2')", want: bson.M{"amount": bson.M{"$in": []interface{}{1.0, 2.0}}}},
+		{name: "system field", input: "created_by eq 'u1'", want: bson.M{"created_by": "u1"}},
+		{name: "null with a range operator", input: "amount gt null", wantErr: "null is only supported with eq and ne on 'amount'"},
+		{name: "unknown field", input: "color eq 'red'", wantErr: "unknown field 'color'"},
+		{name: "unknown field inside not", input: "not color eq 'red'", wantErr: "unknown field 'color'"},
+		{name: "range on text", input: "name gt 'A'", wantErr: "operator 'gt' is not supported for text field 'name'"},
+		{name: "contains on number", input: "contains(amount, '1')", wantErr: "operator 'contains' is not supported for number field 'amount'"},
+		{name: "contains with a number", input: "contains(name, 1)", wantErr: "contains on 'name' expects a string"},
+		{name: "string for a number", input: "amount eq 'lots'", wantErr: "invalid filter value for 'Amount': expected number"},
+		{name: "string for a boolean in a list", input: "active in ('maybe')", wantErr: "invalid value for 'Active'"},
+		{name: "file field", input: "contract eq 'x'", wantErr: "field 'contract' cannot be filtered"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := ParseFilterExpression(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := service.compileFilterExpr(context.Background(), schema, expr)
+			if tt.wantErr != "" {
+				if err == nil || !errors.Is(err, ErrInvalidFilter) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("compiled %#v\nwant     %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToDataQueryPrefixesNestedFields(t *testing.T) {
+	filter := map[string]any{
+		"_id":  "x",
+		"name": "Ada",
+		"$or": []bson.M{
+			{"amount": bson.M{"$gt": 10.0}},
+			{"$and": []bson.M{{"created_at": "t"}, {"stage": "Won"}}},
+		},
+		"$nor": []any{map[string]any{"active": false}, bson.M{"created_by": "u1"}},
+		"$and": []map[string]any{{"$nor": []bson.M{{"owner": "u2"}}}},
+	}
+
+	want := bson.M{
+		"_id":       "x",
+		"data.name": "Ada",
+		"$or": []bson.M{
+			{"data.amount": bson.M{"$gt": 10.0}},
+			{"$and": []bson.M{{"created_at": "t"}, {"data.stage": "Won"}}},
+		},
+		"$nor": []bson.M{{"data.active": false}, {"created_by": "u1"}},
+		"$and": []bson.M{{"$nor": []bson.M{{"data.owner": "u2"}}}},
+	}
+	if got := toDataQuery(filter); !reflect.DeepEqual(got, want) {
+		t.Errorf("query = %#v\nwant    %#v", got, want)
+	}
+}
@@ -0,0 +1,92 @@
+package record
+
+import (
+	"context"
+	"reflect"
+
+	common_models "go-crm/internal/common/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RecalculationBatch reports one batch of RecalculateRecords
+type RecalculationBatch struct {
+	Processed int                `json:"processed"`
+	Updated   int                `json:"updated"`
+	Last      primitive.ObjectID `json:"last"` // Where the next batch starts
+	Done      bool               `json:"done"`
+}
+
+// RecalculateRecords recomputes the values records derive from their
+// fields, the E.164 form of phone numbers, for records of the module
+// matching filters. It covers the records after ID after, a batch of limit
+// at a time in ID order, so a run can stop and resume between batches.
+// National numbers are read in the tenant's country.
+func (s *RecordServiceImpl) RecalculateRecords(ctx context.Context, moduleName string, filters []common_models.Filter, after primitive.ObjectID, limit int64) (*RecalculationBatch, error) {
+	ctx = s.withUserLocation(ctx, primitive.NilObjectID)
+	m, err := s.ModuleRepo.FindByName(ctx, moduleName)
+	if err != nil {
+		return nil, ErrModuleNotFound
+	}
+	filter, err := s.prepareFilters(ctx, m, filters)
+	if err != nil {
+		return nil, err
+	}
+	if !after.IsZero() {
+		andFilter(filter, bson.M{"_id": bson.M{"$gt": after}})
+	}
+	records, err := s.RecordRepo.List(ctx, moduleName, filter, nil, limit, 0, "_id", 1)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &RecalculationBatch{Last: after, Done: int64(len(records)) < limit}
+	for _, rec := range records {
+		id, _ := rec["_id"].(primitive.ObjectID)
+		batch.Processed++
+		batch.Last = id
+
+		update := map[string]any{}
+		stored := entryMap(rec[phoneKey])
+		for field, e164 := range phoneNumbers(ctx, m, rec) {
+			if current, ok := stored[field]; !ok || !reflect.DeepEqual(current, e164) {
+				update[phoneKey+"."+field] = e164
+			}
+		}
+		if len(update) == 0 {
+			continue
+		}
+		if err := s.RecordRepo.Update(ctx, moduleName, id.Hex(), update); err != nil {
+			return nil, err
+		}
+		batch.Updated++
+	}
+	return batch, nil
+}
+
+// CountMatching counts the records of the module matching filters, without
+// access control, for system jobs sizing their work
+func (s *RecordServiceImpl) CountMatching(ctx context.Context, moduleName string, filters []common_models.Filter) (int64, error) {
+	ctx = s.withUserLocation(ctx, primitive.NilObjectID)
+	m, err := s.ModuleRepo.FindByName(ctx, moduleName)
+	if err != nil {
+		return 0, ErrModuleNotFound
+	}
+	filter, err := s.prepareFilters(ctx, m, filters)
+	if err != nil {
+		return 0, err
+	}
+	return s.RecordRepo.Count(ctx, moduleName, filter, nil)
+}
+
+// RebuildCounter recounts the module's list counts for the tenant
+func (s *RecordServiceImpl) RebuildCounter(ctx context.Context, moduleName string) error {
+	if s.CounterRepo == nil {
+		return nil
+	}
+	if _, err := s.ModuleRepo.FindByName(ctx, moduleName); err != nil {
+		return ErrModuleNotFound
+	}
+	return s.CounterRepo.Rebuild(ctx, moduleName)
+}
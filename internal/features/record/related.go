@@ -0,0 +1,77 @@
+package record
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	common_models "go-crm/internal/common/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrUnknownRelation is returned for a relation the module does not declare
+var ErrUnknownRelation = errors.New("related list not found")
+
+// RelatedPage is one page of a record's related list
+type RelatedPage struct {
+	Relation common_models.RelatedList `json:"relation"`
+	Records  []map[string]any          `json:"records"`
+	Total    int64                     `json:"total"`
+}
+
+// ListRelated lists the child records of a related list declared on the
+// module. The caller needs read access to the parent record and read
+// permission on the child module, whose access filter and field permissions
+// apply to the records.
+func (s *RecordServiceImpl) ListRelated(ctx context.Context, moduleName, id, relation string, page, limit int64, userID primitive.ObjectID) (*RelatedPage, error) {
+	m, err := s.ModuleRepo.FindByName(ctx, moduleName)
+	if err != nil {
+		return nil, errors.New("module not found")
+	}
+	var rl *common_models.RelatedList
+	for i := range m.RelatedLists {
+		if m.RelatedLists[i].Name == relation {
+			rl = &m.RelatedLists[i]
+		}
+	}
+	if rl == nil {
+		return nil, ErrUnknownRelation
+	}
+
+	if err := s.checkRecordAccess(ctx, moduleName, id, userID, "read"); err != nil {
+		return nil, err
+	}
+	if _, err := s.RecordRepo.Get(ctx, moduleName, id); err != nil {
+		return nil, ErrRecordNotFound
+	}
+	if !s.hasModulePermission(ctx, rl.Module, "read") {
+		return nil, fmt.Errorf("%w: missing read permission on %s", ErrAccessDenied, rl.Module)
+	}
+
+	sortBy, sortOrder := rl.SortBy, rl.SortOrder
+	if sortBy == "" {
+		sortBy = "created_at"
+	}
+	if sortOrder == "" {
+		sortOrder = "desc"
+	}
+	filters := []common_models.Filter{{Field: rl.Field, Operator: "eq", Value: id}}
+	records, total, err := s.ListRecordsWithExpression(ctx, rl.Module, filters, nil, page, limit, sortBy, sortOrder, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rl.Columns) > 0 {
+		for i, record := range records {
+			trimmed := map[string]any{"_id": record["_id"], "id": record["id"]}
+			for _, col := range rl.Columns {
+				if v, ok := record[col]; ok {
+					trimmed[col] = v
+				}
+			}
+			records[i] = trimmed
+		}
+	}
+	return &RelatedPage{Relation: *rl, Records: records, Total: total}, nil
+}
@@ -19,17 +19,26 @@ type RecordRepository interface {
 	List(ctx context.Context, moduleName string, filter map[string]any, accessFilter map[string]any, limit, offset int64, sortBy string, sortOrder int) ([]map[string]any, error)
 	Count(ctx context.Context, moduleName string, filter map[string]any, accessFilter map[string]any) (int64, error)
 	Update(ctx context.Context, moduleName, id string, data map[string]any) error
+	// BulkUpdate sets fields on many records in one unordered write
+	BulkUpdate(ctx context.Context, moduleName string, updates map[primitive.ObjectID]map[string]any) error
 	Delete(ctx context.Context, moduleName, id string, userID primitive.ObjectID) error
 	Aggregate(ctx context.Context, moduleName string, pipeline mongo.Pipeline) ([]map[string]any, error)
 }
 
 type RecordRepositoryImpl struct {
 	Collection *mongo.Collection
+
+	// collections routes List, Count and Aggregate to secondaries when read
+	// offloading is configured; Get stays on the primary so a record reads
+	// back right after it is written
+	collections database.Collections
 }
 
 func NewRecordRepository(mongodb *database.MongodbDB) RecordRepository {
+	collections := mongodb.Collections("entity_records")
 	return &RecordRepositoryImpl{
-		Collection: mongodb.DB.Collection("entity_records"),
+		Collection:  collections.Primary,
+		collections: collections,
 	}
 }
 
@@ -53,6 +62,9 @@ func (r *RecordRepositoryImpl) Create(ctx context.Context, moduleName string, pr
 		UpdatedAt: time.Now(),
 		Deleted:   false,
 	}
+	if dispatched(ctx) {
+		record.EventID = primitive.NewObjectID()
+	}
 
 	// Capture CreatedBy from context if available (assuming generic UserID key)
 	if userID, ok := ctx.Value("user_id").(string); ok {
@@ -83,7 +95,7 @@ func (r *RecordRepositoryImpl) Get(ctx context.Context, moduleName, id string) (
 	}
 
 	var record models.EntityRecord
-	err = r.Collection.FindOne(ctx, bson.M{"_id": recordID, "tenant_id": oid, "entity": moduleName, "deleted": bson.M{"$ne": true}}).Decode(&record)
+	err = r.collections.Read(ctx, false).FindOne(ctx, bson.M{"_id": recordID, "tenant_id": oid, "entity": moduleName, "deleted": bson.M{"$ne": true}}).Decode(&record)
 	if err != nil {
 		return nil, err
 	}
@@ -109,15 +121,7 @@ func (r *RecordRepositoryImpl) List(ctx context.Context, moduleName string, filt
 	}
 
 	// User Filters (need to map fields to data.field)
-	userQuery := bson.M{}
-	for k, v := range filter {
-		// If key is system field, use as is, else prepend data.
-		if k == "_id" || k == "created_at" || k == "updated_at" || k == "created_by" {
-			userQuery[k] = v
-		} else {
-			userQuery["data."+k] = v
-		}
-	}
+	userQuery := toDataQuery(filter)
 
 	// Combine: Base AND (UserQuery AND AccessFilter)
 	// But UserQuery might be empty, AccessFilter might be empty
@@ -151,7 +155,7 @@ func (r *RecordRepositoryImpl) List(ctx context.Context, moduleName string, filt
 
 	findOptions.SetSort(bson.D{{Key: sortKey, Value: sortOrder}})
 
-	cursor, err := r.Collection.Find(ctx, finalQuery, findOptions)
+	cursor, err := r.collections.Read(ctx, true).Find(ctx, finalQuery, findOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -192,11 +196,45 @@ func (r *RecordRepositoryImpl) Update(ctx context.Context, moduleName, id string
 		updateSet["data."+k] = v
 	}
 	// TODO: Handle UpdatedBy
+	if dispatched(ctx) {
+		updateSet["event_id"] = primitive.NewObjectID()
+	}
 
 	_, err = r.Collection.UpdateOne(ctx, bson.M{"_id": recordID, "tenant_id": oid, "entity": moduleName}, bson.M{"$set": updateSet})
 	return err
 }
 
+func (r *RecordRepositoryImpl) BulkUpdate(ctx context.Context, moduleName string, updates map[primitive.ObjectID]map[string]any) error {
+	if len(updates) == 0 {
+		return nil
+	}
+	tenantID, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantID == "" {
+		return fmt.Errorf("organization context missing")
+	}
+	oid, err := primitive.ObjectIDFromHex(tenantID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	writes := make([]mongo.WriteModel, 0, len(updates))
+	for recordID, data := range updates {
+		updateSet := bson.M{"updated_at": now}
+		for k, v := range data {
+			updateSet["data."+k] = v
+		}
+		if dispatched(ctx) {
+			updateSet["event_id"] = primitive.NewObjectID()
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": recordID, "tenant_id": oid, "entity": moduleName}).
+			SetUpdate(bson.M{"$set": updateSet}))
+	}
+	_, err = r.Collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
 func (r *RecordRepositoryImpl) Delete(ctx context.Context, moduleName, id string, userID primitive.ObjectID) error {
 	tenantID, ok := ctx.Value(models.TenantIDKey).(string)
 	if !ok || tenantID == "" {
@@ -240,14 +278,7 @@ func (r *RecordRepositoryImpl) Count(ctx context.Context, moduleName string, fil
 		"deleted":   bson.M{"$ne": true},
 	}
 
-	userQuery := bson.M{}
-	for k, v := range filter {
-		if k == "_id" || k == "created_at" || k == "updated_at" {
-			userQuery[k] = v
-		} else {
-			userQuery["data."+k] = v
-		}
-	}
+	userQuery := toDataQuery(filter)
 
 	andConditions := []bson.M{baseQuery}
 	if len(userQuery) > 0 {
@@ -258,18 +289,78 @@ func (r *RecordRepositoryImpl) Count(ctx context.Context, moduleName string, fil
 	}
 	finalQuery := bson.M{"$and": andConditions}
 
-	return r.Collection.CountDocuments(ctx, finalQuery)
+	return r.collections.Read(ctx, true).CountDocuments(ctx, finalQuery)
 }
 
 func (r *RecordRepositoryImpl) Aggregate(ctx context.Context, moduleName string, pipeline mongo.Pipeline) ([]map[string]any, error) {
-	// Aggregation is tricky because of data nesting. Caller likely sends pipeline for flat structure.
-	// For now, assume pipeline is adjusted or basic support.
-	// We should probably inject a $match stage for tenant_id and entity at the start.
+	tenantID, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantID == "" {
+		return nil, fmt.Errorf("organization context missing")
+	}
+	oid, err := primitive.ObjectIDFromHex(tenantID)
+	if err != nil {
+		return nil, err
+	}
 
-	// This is a placeholder as full aggregation support on nested data requires rewriting the pipeline
-	// which is complex. For basic use cases, we might encourage List usage.
+	// Records live in a unified collection, so every pipeline is scoped to the
+	// tenant and entity first. Callers reference record fields as "data.<field>".
+	scoped := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"tenant_id": oid,
+			"entity":    moduleName,
+			"deleted":   bson.M{"$ne": true},
+		}}},
+	}
+	scoped = append(scoped, pipeline...)
 
-	return nil, fmt.Errorf("aggregation not yet supported on unified collection")
+	cursor, err := r.collections.Read(ctx, true).Aggregate(ctx, scoped)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []map[string]any
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// toDataQuery prefixes user fields with "data." so filters written against the
+// flattened record match the stored document. Logical operators ($and, $or,
+// $nor) from $filter expressions are rewritten recursively.
+func toDataQuery(filter map[string]any) bson.M {
+	query := bson.M{}
+	for k, v := range filter {
+		switch k {
+		case "$and", "$or", "$nor":
+			var parts []bson.M
+			switch list := v.(type) {
+			case []bson.M:
+				for _, part := range list {
+					parts = append(parts, toDataQuery(part))
+				}
+			case []map[string]any:
+				for _, part := range list {
+					parts = append(parts, toDataQuery(part))
+				}
+			case []any:
+				for _, part := range list {
+					if m, ok := part.(map[string]any); ok {
+						parts = append(parts, toDataQuery(m))
+					} else if m, ok := part.(bson.M); ok {
+						parts = append(parts, toDataQuery(m))
+					}
+				}
+			}
+			query[k] = parts
+		case "_id", "created_at", "updated_at", "created_by":
+			query[k] = v
+		default:
+			query["data."+k] = v
+		}
+	}
+	return query
 }
 
 func (r *RecordRepositoryImpl) flattenRecord(rec *models.EntityRecord) map[string]any {
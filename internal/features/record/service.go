@@ -7,18 +7,23 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"go-crm/internal/common/apperr"
 	"go-crm/internal/common/models"
 	common_models "go-crm/internal/common/models"
+	"go-crm/internal/common/validation"
 	"go-crm/internal/features/audit"
 	"go-crm/internal/features/file"
 	"go-crm/internal/features/module"
 	"go-crm/internal/features/permission"
+	"go-crm/internal/features/quota"
 	"go-crm/internal/features/role"
 	"go-crm/internal/features/user"
 	"go-crm/internal/features/webhook"
 	"go-crm/pkg/condition"
+	"go-crm/pkg/locale"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -29,9 +34,27 @@ type RecordService interface {
 	CreateRecord(ctx context.Context, moduleName string, data map[string]interface{}, userID primitive.ObjectID) (interface{}, error)
 	GetRecord(ctx context.Context, moduleName, id string, userID primitive.ObjectID) (map[string]any, error)
 	ListRecords(ctx context.Context, moduleName string, filters []common_models.Filter, page, limit int64, sortBy string, sortOrder string, userID primitive.ObjectID) ([]map[string]any, int64, error)
+	ListRecordsWithExpression(ctx context.Context, moduleName string, filters []common_models.Filter, expr *FilterExpr, page, limit int64, sortBy string, sortOrder string, userID primitive.ObjectID) ([]map[string]any, int64, error)
+	StreamRecords(ctx context.Context, moduleName string, filters []common_models.Filter, expr *FilterExpr, batchSize int64, userID primitive.ObjectID, fn func(batch []map[string]any) error) error
 	QueryRecords(ctx context.Context, moduleName string, action string, filters []common_models.Filter, page, limit int64, sortBy string, sortOrder string, userID primitive.ObjectID) ([]map[string]any, int64, error)
 	UpdateRecord(ctx context.Context, moduleName, id string, data map[string]interface{}, userID primitive.ObjectID) error
+	CreateRecordWithFiles(ctx context.Context, moduleName string, data map[string]interface{}, uploads []RecordUpload, userID primitive.ObjectID) (interface{}, error)
+	UpdateRecordWithFiles(ctx context.Context, moduleName, id string, data map[string]interface{}, uploads []RecordUpload, userID primitive.ObjectID) error
+	CloneRecord(ctx context.Context, moduleName, id string, opts CloneOptions, userID primitive.ObjectID) (*CloneResult, error)
+	ListRelated(ctx context.Context, moduleName, id, relation string, page, limit int64, userID primitive.ObjectID) (*RelatedPage, error)
+	SearchLookup(ctx context.Context, moduleName, field, query string, limit int64, userID primitive.ObjectID) ([]LookupOption, error)
+	GetFieldHistory(ctx context.Context, moduleName, id, field string, userID primitive.ObjectID) (*FieldHistory, error)
+	UpsertRecord(ctx context.Context, moduleName string, req UpsertRequest, userID primitive.ObjectID) (*UpsertResult, error)
+	ListChanges(ctx context.Context, moduleName, since string, limit int64, includeData bool, userID primitive.ObjectID) (*ChangesPage, error)
 	DeleteRecord(ctx context.Context, moduleName, id string, userID primitive.ObjectID) error
+	MigrateDatesToUTC(ctx context.Context) ([]DateMigrationResult, error)
+	MigratePhoneNumbers(ctx context.Context) ([]PhoneMigrationResult, error)
+	FindByPhone(ctx context.Context, number string, moduleNames []string, userID primitive.ObjectID) ([]PhoneMatch, error)
+	CountRecords(ctx context.Context, moduleName string, filters []common_models.Filter, expr *FilterExpr, groupBy string, userID primitive.ObjectID) (*RecordCounts, error)
+	RebuildCounters(ctx context.Context) error
+	RebuildCounter(ctx context.Context, moduleName string) error
+	RecalculateRecords(ctx context.Context, moduleName string, filters []common_models.Filter, after primitive.ObjectID, limit int64) (*RecalculationBatch, error)
+	CountMatching(ctx context.Context, moduleName string, filters []common_models.Filter) (int64, error)
 }
 
 // Internal interfaces to break circular dependencies
@@ -39,6 +62,145 @@ type AutomationTrigger interface {
 	ExecuteFromTrigger(ctx context.Context, moduleName string, record map[string]interface{}, triggerType string) error
 }
 
+type previousRecordKey struct{}
+
+// WithPrevious attaches the record as it was before an update, so update
+// triggers can evaluate changed-from/changed-to conditions
+func WithPrevious(ctx context.Context, previous map[string]interface{}) context.Context {
+	return context.WithValue(ctx, previousRecordKey{}, previous)
+}
+
+// PreviousRecord returns the pre-update record attached by WithPrevious
+func PreviousRecord(ctx context.Context) (map[string]interface{}, bool) {
+	previous, ok := ctx.Value(previousRecordKey{}).(map[string]interface{})
+	return previous, ok
+}
+
+type automationDepthKey struct{}
+
+// WithAutomationDepth marks writes made by automation actions, so the
+// triggers they cause know how deep the chain already is
+func WithAutomationDepth(ctx context.Context, depth int) context.Context {
+	return context.WithValue(ctx, automationDepthKey{}, depth)
+}
+
+// AutomationDepth is the number of automation runs that led to ctx
+func AutomationDepth(ctx context.Context) int {
+	depth, _ := ctx.Value(automationDepthKey{}).(int)
+	return depth
+}
+
+// triggerContext carries the automation depth of the write into the
+// detached context triggers run with
+func triggerContext(ctx context.Context) context.Context {
+	return WithAutomationDepth(context.Background(), AutomationDepth(ctx))
+}
+
+// RecordChange describes a committed create or update. On create, Changes
+// holds every stored field.
+type RecordChange struct {
+	ModuleName string
+	RecordID   string
+	Created    bool
+	Record     map[string]interface{}
+	Changes    map[string]common_models.Change
+	ActorID    primitive.ObjectID
+}
+
+// ChangeListener is notified asynchronously after records are created or updated
+type ChangeListener interface {
+	RecordChanged(ctx context.Context, change RecordChange)
+}
+
+// ChangeListeners fans a change out to several listeners in order
+type ChangeListeners []ChangeListener
+
+func (l ChangeListeners) RecordChanged(ctx context.Context, change RecordChange) {
+	for _, listener := range l {
+		listener.RecordChanged(ctx, change)
+	}
+}
+
+// Record lifecycle points that RecordHooks can intercept
+const (
+	HookBeforeCreate = "before_create"
+	HookBeforeUpdate = "before_update"
+	HookAfterCreate  = "after_create"
+	HookAfterUpdate  = "after_update"
+)
+
+// RecordHook is one lifecycle event. Data is the submitted data for before
+// hooks and the committed record for after hooks; Previous is the stored
+// record on updates.
+type RecordHook struct {
+	Event      string
+	ModuleName string
+	RecordID   string
+	Data       map[string]interface{}
+	Previous   map[string]interface{}
+	ActorID    primitive.ObjectID
+}
+
+// RecordHooks lets extensions intercept record writes. Before hooks run
+// synchronously and may reject the write or return fields that override the
+// submitted data; after hooks return fields to write back onto the record.
+type RecordHooks interface {
+	RunHook(ctx context.Context, hook RecordHook) (map[string]interface{}, error)
+}
+
+// StageValidator rejects writes that move a record into a stage it does not
+// qualify for. previous is nil on create; next is the record as it would be
+// saved. Rejections are returned as *StageTransitionError.
+type StageValidator interface {
+	ValidateStage(ctx context.Context, moduleName string, previous, next map[string]interface{}) error
+}
+
+// TransitionGuard enforces state machines on select fields. previous is nil
+// on create and input is the submitted data. Rejections are returned as
+// *StageTransitionError.
+type TransitionGuard interface {
+	CheckTransition(ctx context.Context, moduleName string, previous, input map[string]interface{}, userID primitive.ObjectID) error
+}
+
+// TimezoneResolver returns the timezone a user's naive dates are read in,
+// and the country their national phone numbers are
+type TimezoneResolver interface {
+	Location(ctx context.Context, userID string) *time.Location
+	PhoneRegion(ctx context.Context, userID string) string
+}
+
+// StageTransitionError explains why a record cannot enter a stage
+type StageTransitionError struct {
+	Field string `json:"field"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to"`
+	// Allowed is set when the transition itself is not allowed
+	Allowed []string `json:"allowed,omitempty"`
+	// Missing lists the labels of required fields that are empty
+	Missing []string `json:"missing,omitempty"`
+	// Unmet explains each failed condition
+	Unmet []string `json:"unmet,omitempty"`
+}
+
+func (e *StageTransitionError) Error() string {
+	if e.Allowed != nil {
+		allowed := "none"
+		if len(e.Allowed) > 0 {
+			allowed = strings.Join(e.Allowed, ", ")
+		}
+		if e.From == "" {
+			return fmt.Sprintf("cannot start in %s; allowed stages: %s", e.To, allowed)
+		}
+		return fmt.Sprintf("cannot move from %s to %s; allowed next stages: %s", e.From, e.To, allowed)
+	}
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing "+strings.Join(e.Missing, ", "))
+	}
+	parts = append(parts, e.Unmet...)
+	return fmt.Sprintf("cannot move to %s: %s", e.To, strings.Join(parts, "; "))
+}
+
 type ApprovalTrigger interface {
 	InitializeApproval(ctx context.Context, moduleName string, record map[string]interface{}) (*common_models.ApprovalRecordState, error)
 }
@@ -47,6 +209,7 @@ type RecordServiceImpl struct {
 	ModuleRepo        module.ModuleRepository
 	RecordRepo        RecordRepository
 	FileRepo          file.FileRepository
+	FileService       file.FileService
 	UserRepo          user.UserRepository
 	RoleRepo          role.RoleRepository
 	RoleService       role.RoleService
@@ -55,12 +218,28 @@ type RecordServiceImpl struct {
 	AutomationService AutomationTrigger
 	WebhookService    webhook.WebhookService
 	PermissionService permission.PermissionService
+	ChangeListener    ChangeListener
+	Hooks             RecordHooks
+	StageGates        StageValidator
+	Transitions       TransitionGuard
+	Timezones         TimezoneResolver
+
+	// CounterRepo maintains list counts; nil counts every request exactly
+	CounterRepo CounterRepository
+	seeding     sync.Map
+
+	// ExternalIDRepo maps records to their IDs in source systems for upserts
+	ExternalIDRepo ExternalIDRepository
+
+	// Quotas caps the records per module of the tenant's plan; nil is unlimited
+	Quotas quota.QuotaService
 }
 
 func NewRecordService(
 	moduleRepo module.ModuleRepository,
 	recordRepo RecordRepository,
 	fileRepo file.FileRepository,
+	fileService file.FileService,
 	userRepo user.UserRepository,
 	roleRepo role.RoleRepository,
 	roleService role.RoleService,
@@ -69,11 +248,20 @@ func NewRecordService(
 	automationService AutomationTrigger,
 	webhookService webhook.WebhookService,
 	permissionService permission.PermissionService,
+	changeListener ChangeListener,
+	hooks RecordHooks,
+	stageGates StageValidator,
+	transitions TransitionGuard,
+	timezones TimezoneResolver,
+	counterRepo CounterRepository,
+	externalIDRepo ExternalIDRepository,
+	quotas quota.QuotaService,
 ) RecordService {
 	return &RecordServiceImpl{
 		ModuleRepo:        moduleRepo,
 		RecordRepo:        recordRepo,
 		FileRepo:          fileRepo,
+		FileService:       fileService,
 		UserRepo:          userRepo,
 		RoleRepo:          roleRepo,
 		RoleService:       roleService,
@@ -82,14 +270,47 @@ func NewRecordService(
 		AutomationService: automationService,
 		WebhookService:    webhookService,
 		PermissionService: permissionService,
+		ChangeListener:    changeListener,
+		Hooks:             hooks,
+		StageGates:        stageGates,
+		Transitions:       transitions,
+		Timezones:         timezones,
+		CounterRepo:       counterRepo,
+		ExternalIDRepo:    externalIDRepo,
+		Quotas:            quotas,
 	}
 }
 
+// withUserLocation makes date values without an offset read in the user's
+// timezone, and national phone numbers in their country. System writes read
+// phone numbers in the tenant's.
+func (s *RecordServiceImpl) withUserLocation(ctx context.Context, userID primitive.ObjectID) context.Context {
+	if s.Timezones == nil {
+		return ctx
+	}
+	if userID.IsZero() {
+		return locale.WithPhoneRegion(ctx, s.Timezones.PhoneRegion(ctx, ""))
+	}
+	ctx = locale.WithPhoneRegion(ctx, s.Timezones.PhoneRegion(ctx, userID.Hex()))
+	return locale.WithLocation(ctx, s.Timezones.Location(ctx, userID.Hex()))
+}
+
 func (s *RecordServiceImpl) CreateRecord(ctx context.Context, moduleName string, data map[string]interface{}, userID primitive.ObjectID) (interface{}, error) {
+	ctx = s.withUserLocation(ctx, userID)
 	// 1. Fetch Schema
 	m, err := s.ModuleRepo.FindByName(ctx, moduleName)
 	if err != nil {
-		return nil, errors.New("module not found")
+		return nil, ErrModuleNotFound
+	}
+	if s.Quotas != nil {
+		if err := s.Quotas.CheckRecord(ctx, moduleName); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err = s.runBeforeHook(ctx, RecordHook{Event: HookBeforeCreate, ModuleName: moduleName, Data: data, ActorID: userID})
+	if err != nil {
+		return nil, err
 	}
 
 	// 2. Validate Data
@@ -103,12 +324,15 @@ func (s *RecordServiceImpl) CreateRecord(ctx context.Context, moduleName string,
 	// Fetch Field Permissions
 	perms, _ := s.RoleService.GetFieldPermissions(ctx, userID, moduleName)
 
+	// Every field is checked so the client can flag all of them at once
+	var fieldErrs validation.Errors
 	for _, field := range m.Fields {
 		val, exists := data[field.Name]
 
 		// Check Required
 		if field.Required && (!exists || val == nil || val == "") {
-			return nil, fmt.Errorf("field '%s' is required", field.Label)
+			fieldErrs.Add(field.Name, validation.CodeRequired, fmt.Sprintf("field '%s' is required", field.Label))
+			continue
 		}
 
 		if !exists {
@@ -119,7 +343,8 @@ func (s *RecordServiceImpl) CreateRecord(ctx context.Context, moduleName string,
 		if perms != nil {
 			if p, ok := perms[field.Name]; ok {
 				if p == role.FieldPermReadOnly || p == role.FieldPermNone {
-					return nil, fmt.Errorf("field '%s' is read-only or hidden", field.Label)
+					fieldErrs.Add(field.Name, validation.CodeReadOnly, fmt.Sprintf("field '%s' is read-only or hidden", field.Label))
+					continue
 				}
 			}
 		}
@@ -127,10 +352,35 @@ func (s *RecordServiceImpl) CreateRecord(ctx context.Context, moduleName string,
 		// Validate Type
 		cleanVal, err := s.validateAndConvert(ctx, field, val)
 		if err != nil {
-			return nil, fmt.Errorf("invalid value for field '%s': %v", field.Label, err)
+			fieldErrs.Add(field.Name, validation.CodeInvalid, fmt.Sprintf("invalid value for field '%s': %v", field.Label, err))
+			continue
 		}
 		validatedData[field.Name] = cleanVal
 	}
+	if err := fieldErrs.Err(); err != nil {
+		return nil, err
+	}
+	if err := s.checkHierarchies(ctx, m, "", validatedData); err != nil {
+		return nil, err
+	}
+
+	if s.StageGates != nil {
+		if err := s.StageGates.ValidateStage(ctx, moduleName, nil, validatedData); err != nil {
+			return nil, err
+		}
+	}
+	if s.Transitions != nil {
+		if err := s.Transitions.CheckTransition(ctx, moduleName, nil, validatedData, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if entries := stageEntries(m, nil, validatedData, validatedData["created_at"].(time.Time)); len(entries) > 0 {
+		validatedData[stageEntryKey] = entries
+	}
+	if numbers := phoneNumbers(ctx, m, validatedData); len(numbers) > 0 {
+		validatedData[phoneKey] = numbers
+	}
 
 	// 3. Initialize Approval Workflow
 	approvalState, err := s.ApprovalService.InitializeApproval(ctx, moduleName, validatedData)
@@ -142,10 +392,15 @@ func (s *RecordServiceImpl) CreateRecord(ctx context.Context, moduleName string,
 	}
 
 	// 4. Insert
-	res, err := s.RecordRepo.Create(ctx, moduleName, m.Product, validatedData)
+	res, err := s.RecordRepo.Create(withDispatch(ctx), moduleName, m.Product, validatedData)
 	if err != nil {
 		return nil, err
 	}
+	// The repository assigns the stored ID; audit, hooks and automations must
+	// refer to that record
+	if id, ok := res.(primitive.ObjectID); ok {
+		validatedData["_id"] = id
+	}
 
 	// 4. Audit Log
 	if oid, ok := validatedData["_id"].(primitive.ObjectID); ok {
@@ -153,25 +408,44 @@ func (s *RecordServiceImpl) CreateRecord(ctx context.Context, moduleName string,
 		for k, v := range validatedData {
 			changes[k] = common_models.Change{New: v}
 		}
-		_ = s.AuditService.LogChange(ctx, common_models.AuditActionCreate, moduleName, oid.Hex(), changes)
+		_ = s.AuditService.LogChange(ctx, common_models.AuditActionCreate, moduleName, oid.Hex(), auditedChanges(m, changes))
+		s.updateCounters(ctx, moduleName, nil, validatedData)
 
 		// 5. Automation Trigger
+		listenerCtx := context.WithoutCancel(ctx)
+		batch := triggerBatchFrom(ctx)
+		batch.begin()
 		go func() {
+			defer batch.done()
 			mergedRecord := make(map[string]interface{})
 			for k, v := range validatedData {
 				mergedRecord[k] = v
 			}
+			s.runAfterHook(listenerCtx, m, RecordHook{Event: HookAfterCreate, ModuleName: moduleName, RecordID: oid.Hex(), Data: mergedRecord, ActorID: userID})
 
-			_ = s.AutomationService.ExecuteFromTrigger(context.Background(), moduleName, validatedData, "create")
+			if !batch.suppressAutomations() {
+				_ = s.AutomationService.ExecuteFromTrigger(triggerContext(listenerCtx), moduleName, validatedData, "create")
+			}
 
 			// Webhook
-			s.WebhookService.Trigger(context.Background(), "record.updated", common_models.WebhookPayload{
+			batch.webhook(context.Background(), s.WebhookService, "record.updated", common_models.WebhookPayload{
 				Event:     "record.created",
 				Module:    moduleName,
 				RecordID:  validatedData["_id"].(primitive.ObjectID).Hex(),
 				Data:      mergedRecord,
 				Timestamp: time.Now(),
 			})
+
+			if s.ChangeListener != nil {
+				s.ChangeListener.RecordChanged(listenerCtx, RecordChange{
+					ModuleName: moduleName,
+					RecordID:   oid.Hex(),
+					Created:    true,
+					Record:     mergedRecord,
+					Changes:    changes,
+					ActorID:    userID,
+				})
+			}
 		}()
 	}
 
@@ -179,6 +453,10 @@ func (s *RecordServiceImpl) CreateRecord(ctx context.Context, moduleName string,
 }
 
 func (s *RecordServiceImpl) GetRecord(ctx context.Context, moduleName, id string, userID primitive.ObjectID) (map[string]any, error) {
+	if err := s.checkRecordAccess(ctx, moduleName, id, userID, "read"); err != nil {
+		return nil, err
+	}
+
 	record, err := s.RecordRepo.Get(ctx, moduleName, id)
 	if err != nil {
 		return nil, err
@@ -187,7 +465,7 @@ func (s *RecordServiceImpl) GetRecord(ctx context.Context, moduleName, id string
 	// Fetch Schema to identify file fields
 	m, err := s.ModuleRepo.FindByName(ctx, moduleName)
 	if err != nil {
-		return nil, errors.New("module not found")
+		return nil, ErrModuleNotFound
 	}
 
 	// Populate Files
@@ -209,11 +487,19 @@ func (s *RecordServiceImpl) GetRecord(ctx context.Context, moduleName, id string
 			}
 		}
 	}
+	withDurations(m, record, perms, time.Now())
 
 	return record, nil
 }
 
 func (s *RecordServiceImpl) ListRecords(ctx context.Context, moduleName string, filters []common_models.Filter, page, limit int64, sortBy string, sortOrder string, userID primitive.ObjectID) ([]map[string]any, int64, error) {
+	return s.ListRecordsWithExpression(ctx, moduleName, filters, nil, page, limit, sortBy, sortOrder, userID)
+}
+
+// ListRecordsWithExpression is ListRecords with an additional $filter expression
+// ANDed with the plain filters. Invalid expressions return an ErrInvalidFilter error.
+func (s *RecordServiceImpl) ListRecordsWithExpression(ctx context.Context, moduleName string, filters []common_models.Filter, expr *FilterExpr, page, limit int64, sortBy string, sortOrder string, userID primitive.ObjectID) ([]map[string]any, int64, error) {
+	ctx = s.withUserLocation(ctx, userID)
 	if page < 1 {
 		page = 1
 	}
@@ -228,7 +514,7 @@ func (s *RecordServiceImpl) ListRecords(ctx context.Context, moduleName string,
 	// 1. Fetch Schema to handle type conversion for filters
 	m, err := s.ModuleRepo.FindByName(ctx, moduleName)
 	if err != nil {
-		return nil, 0, errors.New("module not found")
+		return nil, 0, ErrModuleNotFound
 	}
 
 	// 2. Prepare Filters
@@ -237,6 +523,14 @@ func (s *RecordServiceImpl) ListRecords(ctx context.Context, moduleName string,
 		return nil, 0, err
 	}
 
+	if expr != nil {
+		exprFilter, err := s.compileFilterExpr(ctx, m, expr)
+		if err != nil {
+			return nil, 0, err
+		}
+		andFilter(typedFilters, exprFilter)
+	}
+
 	sortOrderInt := -1
 	if strings.ToLower(sortOrder) == "asc" {
 		sortOrderInt = 1
@@ -248,6 +542,7 @@ func (s *RecordServiceImpl) ListRecords(ctx context.Context, moduleName string,
 		return nil, 0, err
 	}
 
+	sortBy, sortOrderInt = durationSort(m, sortBy, sortOrderInt)
 	records, err := s.RecordRepo.List(ctx, moduleName, typedFilters, accessFilter, limit, offset, sortBy, sortOrderInt)
 	if err != nil {
 		return nil, 0, err
@@ -258,8 +553,10 @@ func (s *RecordServiceImpl) ListRecords(ctx context.Context, moduleName string,
 		_ = s.populateLookups(ctx, m.Fields, record)
 	}
 
-	totalCount, err := s.RecordRepo.Count(ctx, moduleName, typedFilters, accessFilter)
-	if err != nil {
+	var totalCount int64
+	if counts, ok := s.fromCounters(ctx, moduleName, filters, expr, "", accessFilter); ok {
+		totalCount = counts.Total
+	} else if totalCount, err = s.RecordRepo.Count(ctx, moduleName, typedFilters, accessFilter); err != nil {
 		return nil, 0, err
 	}
 
@@ -273,11 +570,92 @@ func (s *RecordServiceImpl) ListRecords(ctx context.Context, moduleName string,
 			}
 		}
 	}
+	now := time.Now()
+	for _, record := range records {
+		withDurations(m, record, perms, now)
+	}
 
 	return records, totalCount, nil
 }
 
+// StreamRecords walks every matching record in _id order, handing batches to fn.
+// It pages with an _id cursor instead of skip/limit so large exports neither
+// slow down on deep offsets nor hold the full result set in memory. Access and
+// field permissions are applied exactly as in ListRecords.
+func (s *RecordServiceImpl) StreamRecords(ctx context.Context, moduleName string, filters []common_models.Filter, expr *FilterExpr, batchSize int64, userID primitive.ObjectID, fn func(batch []map[string]any) error) error {
+	ctx = s.withUserLocation(ctx, userID)
+	if batchSize < 1 {
+		batchSize = 500
+	}
+
+	m, err := s.ModuleRepo.FindByName(ctx, moduleName)
+	if err != nil {
+		return ErrModuleNotFound
+	}
+
+	typedFilters, err := s.prepareFilters(ctx, m, filters)
+	if err != nil {
+		return err
+	}
+	if expr != nil {
+		exprFilter, err := s.compileFilterExpr(ctx, m, expr)
+		if err != nil {
+			return err
+		}
+		andFilter(typedFilters, exprFilter)
+	}
+
+	accessFilter, err := s.RoleService.GetAccessFilter(ctx, userID, moduleName, "read")
+	if err != nil {
+		return err
+	}
+
+	perms, err := s.RoleService.GetFieldPermissions(ctx, userID, moduleName)
+	if err != nil {
+		perms = nil
+	}
+
+	var lastID primitive.ObjectID
+	for {
+		pageFilter := typedFilters
+		if !lastID.IsZero() {
+			pageFilter = bson.M{"$and": []bson.M{typedFilters, {"_id": bson.M{"$gt": lastID}}}}
+		}
+
+		records, err := s.RecordRepo.List(ctx, moduleName, pageFilter, accessFilter, batchSize, 0, "_id", 1)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		if oid, ok := records[len(records)-1]["_id"].(primitive.ObjectID); ok {
+			lastID = oid
+		}
+
+		now := time.Now()
+		for _, record := range records {
+			_ = s.populateFiles(ctx, m.Fields, record)
+			_ = s.populateLookups(ctx, m.Fields, record)
+			for field, p := range perms {
+				if p == role.FieldPermNone {
+					delete(record, field)
+				}
+			}
+			withDurations(m, record, perms, now)
+		}
+
+		if err := fn(records); err != nil {
+			return err
+		}
+		if int64(len(records)) < batchSize || lastID.IsZero() {
+			return nil
+		}
+	}
+}
+
 func (s *RecordServiceImpl) QueryRecords(ctx context.Context, moduleName string, action string, filters []common_models.Filter, page, limit int64, sortBy string, sortOrder string, userID primitive.ObjectID) ([]map[string]any, int64, error) {
+	ctx = s.withUserLocation(ctx, userID)
 	if page < 1 {
 		page = 1
 	}
@@ -292,7 +670,7 @@ func (s *RecordServiceImpl) QueryRecords(ctx context.Context, moduleName string,
 	// 1. Fetch Schema
 	m, err := s.ModuleRepo.FindByName(ctx, moduleName)
 	if err != nil {
-		return nil, 0, errors.New("module not found")
+		return nil, 0, ErrModuleNotFound
 	}
 
 	// 2. Fetch User & Permissions
@@ -338,7 +716,7 @@ func (s *RecordServiceImpl) QueryRecords(ctx context.Context, moduleName string,
 	}
 
 	if actionPerm == nil || !actionPerm.Allowed {
-		return nil, 0, errors.New("permission denied")
+		return nil, 0, ErrAccessDenied
 	}
 
 	// 4. Validate Requested Filters
@@ -358,7 +736,7 @@ func (s *RecordServiceImpl) QueryRecords(ctx context.Context, moduleName string,
 			if !allowedFiltersMap[f.Field] {
 				// Allow if system ID? or just strict?
 				// Strict adherence to requirement implies blocking.
-				return nil, 0, fmt.Errorf("filter on field '%s' is not allowed", f.Field)
+				return nil, 0, apperr.PermissionDenied("filter on field '%s' is not allowed", f.Field)
 			}
 		} else {
 			// If ui.filters is missing/empty, NO filters allowed?
@@ -367,7 +745,7 @@ func (s *RecordServiceImpl) QueryRecords(ctx context.Context, moduleName string,
 			// "effectiveFilters = availableFilters ∩ permission.actions[action].ui.filters"
 			// "If permission does not define ui.filters, show none."
 			// So yes, strictly none allowed if not defined.
-			return nil, 0, fmt.Errorf("filtering is not allowed for this action")
+			return nil, 0, apperr.PermissionDenied("filtering is not allowed for this action")
 		}
 	}
 
@@ -483,9 +861,24 @@ func (s *RecordServiceImpl) QueryRecords(ctx context.Context, moduleName string,
 }
 
 func (s *RecordServiceImpl) UpdateRecord(ctx context.Context, moduleName, id string, data map[string]interface{}, userID primitive.ObjectID) error {
+	ctx = s.withUserLocation(ctx, userID)
 	m, err := s.ModuleRepo.FindByName(ctx, moduleName)
 	if err != nil {
-		return errors.New("module not found")
+		return ErrModuleNotFound
+	}
+
+	if err := s.checkRecordAccess(ctx, moduleName, id, userID, "update"); err != nil {
+		return err
+	}
+
+	oldRecord, err := s.RecordRepo.Get(ctx, moduleName, id)
+	if err != nil {
+		return err
+	}
+
+	data, err = s.runBeforeHook(ctx, RecordHook{Event: HookBeforeUpdate, ModuleName: moduleName, RecordID: id, Data: data, Previous: oldRecord, ActorID: userID})
+	if err != nil {
+		return err
 	}
 
 	validatedData := make(map[string]interface{})
@@ -501,6 +894,7 @@ func (s *RecordServiceImpl) UpdateRecord(ctx context.Context, moduleName, id str
 
 	perms, _ := s.RoleService.GetFieldPermissions(ctx, userID, moduleName)
 
+	var fieldErrs validation.Errors
 	for _, field := range m.Fields {
 		val, exists := data[field.Name]
 		if !exists {
@@ -510,20 +904,23 @@ func (s *RecordServiceImpl) UpdateRecord(ctx context.Context, moduleName, id str
 		if perms != nil {
 			if p, ok := perms[field.Name]; ok {
 				if p == role.FieldPermReadOnly || p == role.FieldPermNone {
-					return fmt.Errorf("field '%s' is read-only or hidden", field.Label)
+					fieldErrs.Add(field.Name, validation.CodeReadOnly, fmt.Sprintf("field '%s' is read-only or hidden", field.Label))
+					continue
 				}
 			}
 		}
 
 		cleanVal, err := s.validateAndConvert(ctx, field, val)
 		if err != nil {
-			return fmt.Errorf("invalid value for field '%s': %v", field.Label, err)
+			fieldErrs.Add(field.Name, validation.CodeInvalid, fmt.Sprintf("invalid value for field '%s': %v", field.Label, err))
+			continue
 		}
 		validatedData[field.Name] = cleanVal
 	}
-
-	oldRecord, err := s.RecordRepo.Get(ctx, moduleName, id)
-	if err != nil {
+	if err := fieldErrs.Err(); err != nil {
+		return err
+	}
+	if err := s.checkHierarchies(ctx, m, id, validatedData); err != nil {
 		return err
 	}
 
@@ -539,7 +936,42 @@ func (s *RecordServiceImpl) UpdateRecord(ctx context.Context, moduleName, id str
 		}
 	}
 
-	err = s.RecordRepo.Update(ctx, moduleName, id, validatedData)
+	if s.StageGates != nil {
+		next := make(map[string]interface{}, len(oldRecord)+len(validatedData))
+		for k, v := range oldRecord {
+			next[k] = v
+		}
+		for k, v := range validatedData {
+			next[k] = v
+		}
+		if err := s.StageGates.ValidateStage(ctx, moduleName, oldRecord, next); err != nil {
+			return err
+		}
+	}
+	if s.Transitions != nil {
+		if err := s.Transitions.CheckTransition(ctx, moduleName, oldRecord, validatedData, userID); err != nil {
+			return err
+		}
+	}
+
+	// Entry times and phone numbers are set by path, keeping the other
+	// fields' values, and stay out of the audited changes
+	update := validatedData
+	entries := stageEntries(m, oldRecord, validatedData, validatedData["updated_at"].(time.Time))
+	numbers := phoneNumbers(ctx, m, validatedData)
+	if len(entries) > 0 || len(numbers) > 0 {
+		update = make(map[string]interface{}, len(validatedData)+len(entries)+len(numbers))
+		for k, v := range validatedData {
+			update[k] = v
+		}
+		for field, at := range entries {
+			update[stageEntryKey+"."+field] = at
+		}
+		for field, e164 := range numbers {
+			update[phoneKey+"."+field] = e164
+		}
+	}
+	err = s.RecordRepo.Update(withDispatch(ctx), moduleName, id, update)
 	if err != nil {
 		return err
 	}
@@ -552,9 +984,14 @@ func (s *RecordServiceImpl) UpdateRecord(ctx context.Context, moduleName, id str
 		}
 	}
 	if len(changes) > 0 {
-		_ = s.AuditService.LogChange(ctx, common_models.AuditActionUpdate, moduleName, id, changes)
+		_ = s.AuditService.LogChange(ctx, common_models.AuditActionUpdate, moduleName, id, auditedChanges(m, changes))
+		s.updateCounters(ctx, moduleName, oldRecord, mergeRecord(oldRecord, validatedData))
 
+		listenerCtx := context.WithoutCancel(ctx)
+		batch := triggerBatchFrom(ctx)
+		batch.begin()
 		go func() {
+			defer batch.done()
 			mergedRecord := make(map[string]interface{})
 			for k, v := range oldRecord {
 				mergedRecord[k] = v
@@ -562,22 +999,99 @@ func (s *RecordServiceImpl) UpdateRecord(ctx context.Context, moduleName, id str
 			for k, v := range validatedData {
 				mergedRecord[k] = v
 			}
+			s.runAfterHook(listenerCtx, m, RecordHook{Event: HookAfterUpdate, ModuleName: moduleName, RecordID: id, Data: mergedRecord, Previous: oldRecord, ActorID: userID})
 
-			_ = s.AutomationService.ExecuteFromTrigger(context.Background(), moduleName, mergedRecord, "update")
+			if !batch.suppressAutomations() {
+				_ = s.AutomationService.ExecuteFromTrigger(WithPrevious(triggerContext(listenerCtx), oldRecord), moduleName, mergedRecord, "update")
+			}
 
-			s.WebhookService.Trigger(context.Background(), "record.updated", common_models.WebhookPayload{
+			batch.webhook(context.Background(), s.WebhookService, "record.updated", common_models.WebhookPayload{
 				Event:     "record.updated",
 				Module:    moduleName,
 				RecordID:  id,
 				Data:      mergedRecord,
 				Timestamp: time.Now(),
 			})
+
+			if s.ChangeListener != nil {
+				s.ChangeListener.RecordChanged(listenerCtx, RecordChange{
+					ModuleName: moduleName,
+					RecordID:   id,
+					Record:     mergedRecord,
+					Changes:    changes,
+					ActorID:    userID,
+				})
+			}
 		}()
 	}
 	return nil
 }
 
+// runBeforeHook returns the data to validate: the submitted data with any
+// fields the hooks override
+func (s *RecordServiceImpl) runBeforeHook(ctx context.Context, hook RecordHook) (map[string]interface{}, error) {
+	if s.Hooks == nil {
+		return hook.Data, nil
+	}
+	overrides, err := s.Hooks.RunHook(ctx, hook)
+	if err != nil || len(overrides) == 0 {
+		return hook.Data, err
+	}
+	merged := make(map[string]interface{}, len(hook.Data)+len(overrides))
+	for k, v := range hook.Data {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		merged[k] = v
+	}
+	return merged, nil
+}
+
+// runAfterHook writes the fields returned by after hooks straight to the
+// repository, so enrichment does not trigger the hooks again. Unknown fields
+// and invalid values are dropped. record is updated in place.
+func (s *RecordServiceImpl) runAfterHook(ctx context.Context, m *common_models.Entity, hook RecordHook) {
+	if s.Hooks == nil {
+		return
+	}
+	fields, err := s.Hooks.RunHook(ctx, hook)
+	if err != nil || len(fields) == 0 {
+		return
+	}
+
+	enriched := make(map[string]interface{})
+	for _, field := range m.Fields {
+		val, ok := fields[field.Name]
+		if !ok {
+			continue
+		}
+		cleanVal, err := s.validateAndConvert(ctx, field, val)
+		if err != nil {
+			continue
+		}
+		enriched[field.Name] = cleanVal
+	}
+	if len(enriched) == 0 {
+		return
+	}
+	enriched["updated_at"] = time.Now()
+	if err := s.RecordRepo.Update(withDispatch(ctx), hook.ModuleName, hook.RecordID, enriched); err != nil {
+		return
+	}
+
+	changes := make(map[string]common_models.Change, len(enriched))
+	for k, v := range enriched {
+		changes[k] = common_models.Change{Old: hook.Data[k], New: v}
+		hook.Data[k] = v
+	}
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionUpdate, hook.ModuleName, hook.RecordID, auditedChanges(m, changes))
+}
+
 func (s *RecordServiceImpl) DeleteRecord(ctx context.Context, moduleName, id string, userID primitive.ObjectID) error {
+	if err := s.checkRecordAccess(ctx, moduleName, id, userID, "delete"); err != nil {
+		return err
+	}
+
 	oldRecord, err := s.RecordRepo.Get(ctx, moduleName, id)
 	if err != nil {
 		return err
@@ -598,6 +1112,7 @@ func (s *RecordServiceImpl) DeleteRecord(ctx context.Context, moduleName, id str
 	err = s.RecordRepo.Delete(ctx, moduleName, id, userID)
 	if err == nil {
 		_ = s.AuditService.LogChange(ctx, common_models.AuditActionDelete, moduleName, id, nil)
+		s.updateCounters(ctx, moduleName, oldRecord, nil)
 	}
 	return err
 }
@@ -616,11 +1131,33 @@ func (s *RecordServiceImpl) populateFiles(ctx context.Context, fields []models.M
 				if idStr != "" {
 					file, err := s.FileRepo.Get(ctx, idStr)
 					if err == nil {
-						record[field.Name] = map[string]interface{}{
+						url := file.URL
+						var thumbnails map[string]string
+						if s.FileService != nil {
+							if signed, err := s.FileService.DownloadURL(ctx, file); err == nil {
+								url = signed
+							}
+							if field.Type == models.FieldTypeImage && len(file.Thumbnails) > 0 {
+								thumbnails, _ = s.FileService.ThumbnailURLs(ctx, file)
+							}
+						}
+						populated := map[string]interface{}{
 							"id":                file.ID,
 							"original_filename": file.OriginalFilename,
-							"url":               file.URL,
+							"url":               url,
+						}
+						// thumbnail_url is the smallest rendition, for list views
+						if len(thumbnails) > 0 {
+							smallest := -1
+							for px, u := range thumbnails {
+								if n, err := strconv.Atoi(px); err == nil && (smallest < 0 || n < smallest) {
+									smallest = n
+									populated["thumbnail_url"] = u
+								}
+							}
+							populated["thumbnails"] = thumbnails
 						}
+						record[field.Name] = populated
 					}
 				}
 			}
@@ -709,6 +1246,10 @@ func (s *RecordServiceImpl) validateAndConvert(ctx context.Context, field models
 		}
 		return nil, errors.New("expected boolean")
 	case models.FieldTypeDate:
+		// Hooks and imports may hand over parsed times
+		if t, ok := val.(time.Time); ok {
+			return t.UTC(), nil
+		}
 		strVal, ok := val.(string)
 		if !ok {
 			return nil, errors.New("expected date string")
@@ -716,12 +1257,9 @@ func (s *RecordServiceImpl) validateAndConvert(ctx context.Context, field models
 		if strVal == "" {
 			return nil, nil
 		}
-		t, err := time.Parse(time.RFC3339, strVal)
+		t, _, err := locale.ParseDateTime(strVal, locale.LocationFrom(ctx))
 		if err != nil {
-			t, err = time.Parse("2006-01-02", strVal)
-			if err != nil {
-				return nil, errors.New("invalid date format (use RFC3339 or YYYY-MM-DD)")
-			}
+			return nil, err
 		}
 		return t, nil
 	case models.FieldTypeEmail:
@@ -736,6 +1274,20 @@ func (s *RecordServiceImpl) validateAndConvert(ctx context.Context, field models
 			return nil, errors.New("invalid email format")
 		}
 		return strVal, nil
+	case models.FieldTypePhone:
+		strVal, ok := val.(string)
+		if !ok {
+			return nil, errors.New("expected string")
+		}
+		strVal = strings.TrimSpace(strVal)
+		if strVal == "" {
+			return nil, nil
+		}
+		// Stored as written; phoneNumbers derives the E.164 form
+		if _, err := locale.ParsePhone(strVal, locale.PhoneRegionFrom(ctx)); err != nil {
+			return nil, err
+		}
+		return strVal, nil
 	case models.FieldTypeLookup:
 		var idStr string
 		switch v := val.(type) {
@@ -844,6 +1396,23 @@ func (s *RecordServiceImpl) validateAndConvert(ctx context.Context, field models
 		default:
 			return nil, errors.New("expected string or populated object for image")
 		}
+
+		if idStr == "" {
+			return nil, nil
+		}
+
+		if _, err := primitive.ObjectIDFromHex(idStr); err == nil {
+			f, err := s.FileRepo.Get(ctx, idStr)
+			if err != nil {
+				if err == mongo.ErrNoDocuments {
+					return nil, errors.New("referenced file not found")
+				}
+				return nil, fmt.Errorf("failed to verify file reference: %v", err)
+			}
+			if !file.IsImageMime(f.MimeType) {
+				return nil, fmt.Errorf("field '%s' requires an image file", field.Name)
+			}
+		}
 		return idStr, nil
 	default:
 		return val, nil
@@ -853,11 +1422,21 @@ func (s *RecordServiceImpl) validateAndConvert(ctx context.Context, field models
 func (s *RecordServiceImpl) prepareFilters(ctx context.Context, m *common_models.Entity, filters []common_models.Filter) (bson.M, error) {
 	typedFilters := bson.M{}
 
+	now := time.Now()
 	for _, f := range filters {
 		fieldName := f.Field
 		operator := f.Operator
 		val := f.Value
 
+		if isDurationField(m, fieldName) {
+			cond, err := durationFilter(m, f, now)
+			if err != nil {
+				return nil, err
+			}
+			andFilter(typedFilters, cond)
+			continue
+		}
+
 		// Handle Special ID fields
 		if fieldName == "id" || fieldName == "_id" {
 			switch operator {
@@ -936,14 +1515,12 @@ func (s *RecordServiceImpl) prepareFilters(ctx context.Context, m *common_models
 					startStr := strings.TrimSpace(parts[0])
 					endStr := strings.TrimSpace(parts[1])
 
-					startTime, err1 := time.Parse("2006-01-02", startStr)
-					endTime, err2 := time.Parse("2006-01-02", endStr)
-
-					if err1 != nil {
-						startTime, err1 = time.Parse(time.RFC3339, startStr)
-					}
-					if err2 != nil {
-						endTime, err2 = time.Parse(time.RFC3339, endStr)
+					loc := locale.LocationFrom(ctx)
+					startTime, _, err1 := locale.ParseDateTime(startStr, loc)
+					endTime, endDateOnly, err2 := locale.ParseDateTime(endStr, loc)
+					if err2 == nil && endDateOnly {
+						// A date-only end covers the whole day
+						endTime = endTime.In(loc).AddDate(0, 0, 1).Add(-time.Nanosecond).UTC()
 					}
 
 					if err1 == nil && err2 == nil {
@@ -960,15 +1537,24 @@ func (s *RecordServiceImpl) prepareFilters(ctx context.Context, m *common_models
 								"$lte": endFloat,
 							}
 						} else {
-							return nil, fmt.Errorf("invalid range values for field '%s'", field.Label)
+							return nil, apperr.BadRequest("invalid range values for field '%s'", field.Label)
 						}
 					}
 				}
 			}
+		} else if field.Type == common_models.FieldTypeLookup && (operator == "in" || operator == "nin") {
+			ids, err := lookupIDs(val)
+			if err != nil {
+				return nil, apperr.BadRequest("invalid filter value for '%s': %v", field.Label, err)
+			}
+			typedFilters[fieldName] = bson.M{"$" + operator: ids}
+		} else if field.Type == common_models.FieldTypePhone {
+			conds, _ := typedFilters["$and"].([]bson.M)
+			typedFilters["$and"] = append(conds, phoneFilter(ctx, fieldName, operator, val))
 		} else {
 			typedVal, err := s.validateAndConvert(ctx, *field, val)
 			if err != nil {
-				return nil, fmt.Errorf("invalid filter value for '%s': %v", field.Label, err)
+				return nil, apperr.BadRequest("invalid filter value for '%s': %v", field.Label, err)
 			}
 
 			switch operator {
@@ -3,6 +3,7 @@ package record
 import (
 	"context"
 	"testing"
+	"time"
 
 	common_models "go-crm/internal/common/models"
 
@@ -46,6 +47,9 @@ func (m *MockRecordRepo) Count(ctx context.Context, moduleName string, filter ma
 func (m *MockRecordRepo) Update(ctx context.Context, moduleName, id string, data map[string]any) error {
 	return nil
 }
+func (m *MockRecordRepo) BulkUpdate(ctx context.Context, moduleName string, updates map[primitive.ObjectID]map[string]any) error {
+	return nil
+}
 func (m *MockRecordRepo) Delete(ctx context.Context, moduleName, id string, userID primitive.ObjectID) error {
 	m.CapturedDeleteID = id
 	m.CapturedUserID = userID
@@ -66,6 +70,10 @@ func (m *MockAuditService) ListLogs(ctx context.Context, filters map[string]inte
 	return []common_models.AuditLog{}, nil
 }
 
+func (m *MockAuditService) ListSince(ctx context.Context, module string, actions []common_models.AuditAction, after primitive.ObjectID, until time.Time, limit int64) ([]common_models.AuditLog, error) {
+	return nil, nil
+}
+
 func TestServiceSoftDeletePassesUserID(t *testing.T) {
 	mockRepo := &MockRecordRepo{}
 	mockAudit := &MockAuditService{}
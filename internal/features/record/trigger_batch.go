@@ -0,0 +1,140 @@
+package record
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/webhook"
+)
+
+// maxWebhookBatch is the number of record events sent in one batched payload
+const maxWebhookBatch = 100
+
+// TriggerOptions choose how the writes of a mass update or import job reach
+// automations and webhooks. The zero value runs both for every record.
+type TriggerOptions struct {
+	// SuppressAutomations skips automation rules for the job's records
+	SuppressAutomations bool `json:"suppress_automations,omitempty" bson:"suppress_automations,omitempty"`
+	// BatchWebhooks sends the job's record events in payloads of up to 100
+	// records instead of one request per record
+	BatchWebhooks bool `json:"batch_webhooks,omitempty" bson:"batch_webhooks,omitempty"`
+}
+
+// BatchedEvent is one record event within a batched webhook payload
+type BatchedEvent struct {
+	Event     string      `json:"event"`
+	RecordID  string      `json:"record_id"`
+	Data      interface{} `json:"data"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+type triggerBatchKey struct{}
+
+// webhookGroup is the events sent to the subscribers of one event for one
+// module
+type webhookGroup struct {
+	subscription string
+	event        string
+	module       string
+}
+
+// TriggerBatch collects the webhook events of a job's writes. Record events
+// are dispatched after each write returns, so Flush waits for those still
+// running before sending what is left.
+type TriggerBatch struct {
+	opts    TriggerOptions
+	jobID   string
+	pending sync.WaitGroup
+
+	mu       sync.Mutex
+	webhooks webhook.WebhookService
+	events   map[webhookGroup][]BatchedEvent
+}
+
+// WithTriggerBatch applies opts to the record writes made with the returned
+// context. Call Flush on the batch once the job has written its records.
+func WithTriggerBatch(ctx context.Context, opts TriggerOptions, jobID string) (context.Context, *TriggerBatch) {
+	b := &TriggerBatch{opts: opts, jobID: jobID, events: map[webhookGroup][]BatchedEvent{}}
+	return context.WithValue(ctx, triggerBatchKey{}, b), b
+}
+
+func triggerBatchFrom(ctx context.Context) *TriggerBatch {
+	b, _ := ctx.Value(triggerBatchKey{}).(*TriggerBatch)
+	return b
+}
+
+// begin counts a record event about to be dispatched; nil batches do nothing
+func (b *TriggerBatch) begin() {
+	if b != nil {
+		b.pending.Add(1)
+	}
+}
+
+func (b *TriggerBatch) done() {
+	if b != nil {
+		b.pending.Done()
+	}
+}
+
+func (b *TriggerBatch) suppressAutomations() bool {
+	return b != nil && b.opts.SuppressAutomations
+}
+
+// webhook sends a record event straight away, or holds it for a batched
+// payload when the job batches webhooks
+func (b *TriggerBatch) webhook(ctx context.Context, service webhook.WebhookService, subscription string, payload common_models.WebhookPayload) {
+	if b == nil || !b.opts.BatchWebhooks {
+		service.Trigger(ctx, subscription, payload)
+		return
+	}
+
+	group := webhookGroup{subscription: subscription, event: payload.Event, module: payload.Module}
+	b.mu.Lock()
+	b.webhooks = service
+	b.events[group] = append(b.events[group], BatchedEvent{Event: payload.Event, RecordID: payload.RecordID, Data: payload.Data, Timestamp: payload.Timestamp})
+	var full []BatchedEvent
+	if len(b.events[group]) >= maxWebhookBatch {
+		full = b.events[group]
+		delete(b.events, group)
+	}
+	b.mu.Unlock()
+
+	if full != nil {
+		b.send(ctx, group, full)
+	}
+}
+
+// Flush waits for the job's record events and sends the batched webhooks not
+// sent yet
+func (b *TriggerBatch) Flush(ctx context.Context) {
+	b.pending.Wait()
+
+	b.mu.Lock()
+	events := b.events
+	b.events = map[webhookGroup][]BatchedEvent{}
+	b.mu.Unlock()
+
+	for group, batch := range events {
+		b.send(ctx, group, batch)
+	}
+}
+
+func (b *TriggerBatch) send(ctx context.Context, group webhookGroup, events []BatchedEvent) {
+	b.mu.Lock()
+	service := b.webhooks
+	b.mu.Unlock()
+
+	service.Trigger(ctx, group.subscription, common_models.WebhookPayload{
+		Event:     group.event,
+		Module:    group.module,
+		Data:      events,
+		Timestamp: time.Now(),
+		Extra: map[string]any{
+			"batch":  true,
+			"count":  len(events),
+			"job_id": b.jobID,
+		},
+	})
+}
@@ -0,0 +1,136 @@
+package record
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"path/filepath"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/common/validation"
+	"go-crm/internal/features/file"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RecordUpload is a file sent inline with a record create or update for
+// one of the module's file or image fields
+type RecordUpload struct {
+	Field  string
+	Header *multipart.FileHeader
+}
+
+// CreateRecordWithFiles stores the uploads, points their fields at the
+// stored files and creates the record. Uploads are removed again when the
+// record is rejected.
+func (s *RecordServiceImpl) CreateRecordWithFiles(ctx context.Context, moduleName string, data map[string]interface{}, uploads []RecordUpload, userID primitive.ObjectID) (interface{}, error) {
+	stored, err := s.storeUploads(ctx, moduleName, "", data, uploads, userID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.CreateRecord(ctx, moduleName, data, userID)
+	if err != nil {
+		s.discardUploads(ctx, stored, userID)
+		return nil, err
+	}
+
+	// The record ID is known only now
+	if oid, ok := res.(primitive.ObjectID); ok {
+		for _, f := range stored {
+			f.RecordID = oid.Hex()
+			if err := s.FileService.SaveFile(ctx, f); err != nil {
+				log.Printf("record uploads: failed to link file %s to %s: %v", f.ID.Hex(), oid.Hex(), err)
+			}
+		}
+	}
+	return res, nil
+}
+
+// UpdateRecordWithFiles is CreateRecordWithFiles for an existing record
+func (s *RecordServiceImpl) UpdateRecordWithFiles(ctx context.Context, moduleName, id string, data map[string]interface{}, uploads []RecordUpload, userID primitive.ObjectID) error {
+	stored, err := s.storeUploads(ctx, moduleName, id, data, uploads, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.UpdateRecord(ctx, moduleName, id, data, userID); err != nil {
+		s.discardUploads(ctx, stored, userID)
+		return err
+	}
+	return nil
+}
+
+// storeUploads checks every upload targets a file or image field, stores
+// them and sets data[field] to the new file IDs. Nothing stays stored when
+// one of them fails.
+func (s *RecordServiceImpl) storeUploads(ctx context.Context, moduleName, recordID string, data map[string]interface{}, uploads []RecordUpload, userID primitive.ObjectID) ([]*file.File, error) {
+	if len(uploads) == 0 {
+		return nil, nil
+	}
+	if s.FileService == nil {
+		return nil, errors.New("file uploads are not available")
+	}
+	m, err := s.ModuleRepo.FindByName(ctx, moduleName)
+	if err != nil {
+		return nil, errors.New("module not found")
+	}
+
+	fields := make(map[string]models.FieldType, len(m.Fields))
+	for _, f := range m.Fields {
+		fields[f.Name] = f.Type
+	}
+	var errs validation.Errors
+	for _, u := range uploads {
+		fieldType, ok := fields[u.Field]
+		switch {
+		case !ok:
+			errs.Add(u.Field, validation.CodeNotAllowed, fmt.Sprintf("unknown field '%s'", u.Field))
+		case fieldType != models.FieldTypeFile && fieldType != models.FieldTypeImage:
+			errs.Add(u.Field, validation.CodeInvalid, fmt.Sprintf("field '%s' does not take files", u.Field))
+		}
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	stored := make([]*file.File, 0, len(uploads))
+	for _, u := range uploads {
+		f, err := s.storeUpload(ctx, moduleName, recordID, u, userID)
+		if err != nil {
+			s.discardUploads(ctx, stored, userID)
+			return nil, validation.New(u.Field, validation.CodeInvalid, err.Error())
+		}
+		stored = append(stored, f)
+		data[u.Field] = f.ID.Hex()
+	}
+	return stored, nil
+}
+
+func (s *RecordServiceImpl) storeUpload(ctx context.Context, moduleName, recordID string, u RecordUpload, userID primitive.ObjectID) (*file.File, error) {
+	src, err := u.Header.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", u.Header.Filename, err)
+	}
+	defer src.Close()
+
+	f := &file.File{
+		OriginalFilename: filepath.Base(u.Header.Filename),
+		MimeType:         u.Header.Header.Get("Content-Type"),
+		ModuleName:       moduleName,
+		RecordID:         recordID,
+		UploadedBy:       userID,
+	}
+	if err := s.FileService.Upload(ctx, src, u.Header.Size, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (s *RecordServiceImpl) discardUploads(ctx context.Context, stored []*file.File, userID primitive.ObjectID) {
+	for _, f := range stored {
+		if err := s.FileService.DeleteFile(ctx, f.ID.Hex(), userID); err != nil {
+			log.Printf("record uploads: failed to remove file %s: %v", f.ID.Hex(), err)
+		}
+	}
+}
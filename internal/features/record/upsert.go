@@ -0,0 +1,119 @@
+package record
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"go-crm/internal/common/validation"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// claimTimeout is how long an unassigned claim blocks other upserts of
+// the same external ID before it is taken over as abandoned
+const claimTimeout = time.Minute
+
+var sourcePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_\-]*$`)
+
+// UpsertRequest creates or updates the record a source system knows by
+// ExternalID
+type UpsertRequest struct {
+	Source     string                 `json:"source"`
+	ExternalID string                 `json:"external_id"`
+	Data       map[string]interface{} `json:"data"`
+}
+
+// UpsertResult names the written record and whether it was created
+type UpsertResult struct {
+	ID      string `json:"id"`
+	Created bool   `json:"created"`
+}
+
+// UpsertRecord updates the record mapped to (source, external_id), or
+// creates it and records the mapping. Repeating a request is safe: the
+// mapping is claimed before the record is created, so concurrent requests
+// for one external ID cannot create two records.
+func (s *RecordServiceImpl) UpsertRecord(ctx context.Context, moduleName string, req UpsertRequest, userID primitive.ObjectID) (*UpsertResult, error) {
+	var errs validation.Errors
+	if !sourcePattern.MatchString(req.Source) {
+		errs.Add("source", validation.CodeInvalid, "source is required: use lowercase letters, digits, '-' and '_'")
+	}
+	if req.ExternalID == "" {
+		errs.Add("external_id", validation.CodeRequired, "external_id is required")
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	if s.ExternalIDRepo == nil {
+		return nil, errors.New("upserts are not available")
+	}
+	if req.Data == nil {
+		req.Data = map[string]interface{}{}
+	}
+
+	mapping, err := s.ExternalIDRepo.Find(ctx, moduleName, req.Source, req.ExternalID)
+	if err != nil {
+		return nil, err
+	}
+	if mapping == nil {
+		claimed, err := s.ExternalIDRepo.Claim(ctx, moduleName, req.Source, req.ExternalID)
+		if err != nil {
+			return nil, err
+		}
+		if claimed {
+			return s.upsertCreate(ctx, moduleName, req, userID, true)
+		}
+		// Another request mapped or claimed it in between
+		if mapping, err = s.ExternalIDRepo.Find(ctx, moduleName, req.Source, req.ExternalID); err != nil || mapping == nil {
+			return nil, ErrExternalIDClaimed
+		}
+	}
+
+	if mapping.RecordID == nil {
+		if time.Since(mapping.CreatedAt) < claimTimeout {
+			return nil, ErrExternalIDClaimed
+		}
+		return s.upsertCreate(ctx, moduleName, req, userID, false)
+	}
+	id := mapping.RecordID.Hex()
+	if _, err := s.RecordRepo.Get(ctx, moduleName, id); err != nil {
+		// The mapped record was deleted; the source's record starts over
+		return s.upsertCreate(ctx, moduleName, req, userID, false)
+	}
+	if err := s.UpdateRecord(ctx, moduleName, id, req.Data, userID); err != nil {
+		return nil, err
+	}
+	return &UpsertResult{ID: id}, nil
+}
+
+// upsertCreate creates the record of an upsert and maps it. claimed says
+// the mapping is this request's fresh claim, to be dropped on failure.
+func (s *RecordServiceImpl) upsertCreate(ctx context.Context, moduleName string, req UpsertRequest, userID primitive.ObjectID, claimed bool) (*UpsertResult, error) {
+	release := func() {
+		if claimed {
+			_ = s.ExternalIDRepo.Release(ctx, moduleName, req.Source, req.ExternalID)
+		}
+	}
+	if !s.hasModulePermission(ctx, moduleName, "create") {
+		release()
+		return nil, fmt.Errorf("%w: missing create permission on %s", ErrAccessDenied, moduleName)
+	}
+
+	res, err := s.CreateRecord(ctx, moduleName, req.Data, userID)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	oid, ok := res.(primitive.ObjectID)
+	if !ok {
+		release()
+		return nil, errors.New("failed to create record")
+	}
+	if err := s.ExternalIDRepo.Assign(ctx, moduleName, req.Source, req.ExternalID, oid); err != nil {
+		return nil, fmt.Errorf("record %s created but its external ID was not saved: %w", oid.Hex(), err)
+	}
+	return &UpsertResult{ID: oid.Hex(), Created: true}, nil
+}
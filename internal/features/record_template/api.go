@@ -0,0 +1,38 @@
+package record_template
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/features/role"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type RecordTemplateApi struct {
+	controller  *RecordTemplateController
+	config      *config.Config
+	roleService role.RoleService
+}
+
+func NewRecordTemplateApi(controller *RecordTemplateController, config *config.Config, roleService role.RoleService) *RecordTemplateApi {
+	return &RecordTemplateApi{
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *RecordTemplateApi) Setup(app *fiber.App) {
+	templates := app.Group("/api/record-templates", middleware.AuthMiddleware(h.config.SkipAuth))
+	templates.Get("/", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.List)
+	templates.Get("/:id", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.Get)
+	templates.Post("/", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.Create)
+	templates.Put("/:id", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.Update)
+	templates.Delete("/:id", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.Delete)
+
+	// GET needs read and POST create permission on the module
+	crud := middleware.RequireModulePermission(h.roleService, "name")
+	records := app.Group("/api/modules", middleware.AuthMiddleware(h.config.SkipAuth))
+	records.Get("/:name/record-templates", crud, h.controller.Available)
+	records.Post("/:name/records/quick-create", crud, h.controller.QuickCreate)
+}
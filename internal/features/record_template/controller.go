@@ -0,0 +1,184 @@
+package record_template
+
+import (
+	"errors"
+
+	common_api "go-crm/internal/common/api"
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RecordTemplateController struct {
+	Service RecordTemplateService
+}
+
+func NewRecordTemplateController(service RecordTemplateService) *RecordTemplateController {
+	return &RecordTemplateController{Service: service}
+}
+
+// Create godoc
+// @Summary Create record template
+// @Description Create a named preset of default field values for a module, optionally restricted to roles
+// @Tags record_templates
+// @Accept json
+// @Produce json
+// @Param template body RecordTemplate true "Record Template"
+// @Success 201 {object} RecordTemplate
+// @Failure 400 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/record-templates [post]
+func (c *RecordTemplateController) Create(ctx *fiber.Ctx) error {
+	var template RecordTemplate
+	if err := ctx.BodyParser(&template); err != nil {
+		return common_api.InvalidBody(ctx, err)
+	}
+
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if err := c.Service.CreateTemplate(ctx.UserContext(), &template, userID); err != nil {
+		return common_api.Fail(ctx, fiber.StatusBadRequest, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(template)
+}
+
+// List godoc
+// @Summary List record templates
+// @Description List every record template, optionally filtered by module
+// @Tags record_templates
+// @Produce json
+// @Param module query string false "Filter by module"
+// @Success 200 {array} RecordTemplate
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/record-templates [get]
+func (c *RecordTemplateController) List(ctx *fiber.Ctx) error {
+	templates, err := c.Service.ListTemplates(ctx.UserContext(), ctx.Query("module"))
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(templates)
+}
+
+// Get godoc
+// @Summary Get record template
+// @Description Get a record template by ID
+// @Tags record_templates
+// @Produce json
+// @Param id path string true "Template ID"
+// @Success 200 {object} RecordTemplate
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/record-templates/{id} [get]
+func (c *RecordTemplateController) Get(ctx *fiber.Ctx) error {
+	template, err := c.Service.GetTemplate(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Template not found"})
+	}
+
+	return ctx.JSON(template)
+}
+
+// Update godoc
+// @Summary Update record template
+// @Description Update an existing record template
+// @Tags record_templates
+// @Accept json
+// @Produce json
+// @Param id path string true "Template ID"
+// @Param template body RecordTemplate true "Record Template"
+// @Success 200 {object} RecordTemplate
+// @Failure 400 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/record-templates/{id} [put]
+func (c *RecordTemplateController) Update(ctx *fiber.Ctx) error {
+	var template RecordTemplate
+	if err := ctx.BodyParser(&template); err != nil {
+		return common_api.InvalidBody(ctx, err)
+	}
+
+	oid, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID format"})
+	}
+	template.ID = oid
+
+	if err := c.Service.UpdateTemplate(ctx.UserContext(), &template); err != nil {
+		return common_api.Fail(ctx, fiber.StatusBadRequest, err)
+	}
+
+	return ctx.JSON(template)
+}
+
+// Delete godoc
+// @Summary Delete record template
+// @Description Delete a record template by ID
+// @Tags record_templates
+// @Param id path string true "Template ID"
+// @Success 204 {object} nil
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/record-templates/{id} [delete]
+func (c *RecordTemplateController) Delete(ctx *fiber.Ctx) error {
+	if err := c.Service.DeleteTemplate(ctx.UserContext(), ctx.Params("id")); err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// Available godoc
+// @Summary List usable record templates
+// @Description List the module's record templates available to the caller's roles
+// @Tags record_templates
+// @Produce json
+// @Param name path string true "Module Name"
+// @Success 200 {array} RecordTemplate
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/modules/{name}/record-templates [get]
+func (c *RecordTemplateController) Available(ctx *fiber.Ctx) error {
+	templates, err := c.Service.AvailableTemplates(ctx.UserContext(), ctx.Params("name"))
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(templates)
+}
+
+// QuickCreate godoc
+// @Summary Quick-create record from template
+// @Description Create a record from a template's default values; fields in data override the defaults
+// @Tags record_templates
+// @Accept json
+// @Produce json
+// @Param name path string true "Module Name"
+// @Param request body QuickCreateRequest true "Template and overrides"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/modules/{name}/records/quick-create [post]
+func (c *RecordTemplateController) QuickCreate(ctx *fiber.Ctx) error {
+	var req QuickCreateRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return common_api.InvalidBody(ctx, err)
+	}
+
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	res, err := c.Service.QuickCreate(ctx.UserContext(), ctx.Params("name"), req, userID)
+	if err != nil {
+		if errors.Is(err, ErrTemplateNotAllowed) {
+			return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
+		return common_api.Fail(ctx, fiber.StatusBadRequest, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(res)
+}
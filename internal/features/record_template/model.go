@@ -0,0 +1,31 @@
+package record_template
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RecordTemplate is a named preset of field values for creating records of
+// one module, e.g. a standard ticket or a typical opportunity setup
+type RecordTemplate struct {
+	ID          primitive.ObjectID     `json:"id" bson:"_id,omitempty"`
+	TenantID    primitive.ObjectID     `json:"tenant_id" bson:"tenant_id"`
+	Name        string                 `json:"name" bson:"name"`
+	Description string                 `json:"description,omitempty" bson:"description,omitempty"`
+	ModuleName  string                 `json:"module_name" bson:"module_name"`
+	Defaults    map[string]interface{} `json:"defaults" bson:"defaults"`
+	// RoleIDs restricts the template to these roles; empty allows everyone
+	// who can create records in the module
+	RoleIDs   []string           `json:"role_ids,omitempty" bson:"role_ids,omitempty"`
+	CreatedBy primitive.ObjectID `json:"created_by" bson:"created_by"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// QuickCreateRequest creates a record from a template. Data overrides the
+// template's defaults field by field.
+type QuickCreateRequest struct {
+	TemplateID string                 `json:"template_id"`
+	Data       map[string]interface{} `json:"data"`
+}
@@ -0,0 +1,122 @@
+package record_template
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RecordTemplateRepository interface {
+	Create(ctx context.Context, template *RecordTemplate) error
+	Get(ctx context.Context, id string) (*RecordTemplate, error)
+	// List returns the tenant's templates, optionally for one module
+	List(ctx context.Context, moduleName string) ([]RecordTemplate, error)
+	Update(ctx context.Context, template *RecordTemplate) error
+	Delete(ctx context.Context, id string) error
+}
+
+type RecordTemplateRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewRecordTemplateRepository(db *database.MongodbDB) RecordTemplateRepository {
+	return &RecordTemplateRepositoryImpl{
+		collection: db.DB.Collection("record_templates"),
+	}
+}
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+func (r *RecordTemplateRepositoryImpl) Create(ctx context.Context, template *RecordTemplate) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	template.ID = primitive.NewObjectID()
+	template.TenantID = tenantID
+	template.CreatedAt = time.Now()
+	template.UpdatedAt = template.CreatedAt
+
+	_, err = r.collection.InsertOne(ctx, template)
+	return err
+}
+
+func (r *RecordTemplateRepositoryImpl) Get(ctx context.Context, id string) (*RecordTemplate, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var template RecordTemplate
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID}).Decode(&template); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *RecordTemplateRepositoryImpl) List(ctx context.Context, moduleName string) ([]RecordTemplate, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{"tenant_id": tenantID}
+	if moduleName != "" {
+		filter["module_name"] = moduleName
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	templates := []RecordTemplate{}
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (r *RecordTemplateRepositoryImpl) Update(ctx context.Context, template *RecordTemplate) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	template.TenantID = tenantID
+	template.UpdatedAt = time.Now()
+
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": template.ID, "tenant_id": tenantID}, template)
+	return err
+}
+
+func (r *RecordTemplateRepositoryImpl) Delete(ctx context.Context, id string) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID})
+	return err
+}
@@ -0,0 +1,200 @@
+package record_template
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/common/validation"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/record"
+	"go-crm/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrTemplateNotAllowed is returned when the caller's roles may not use a template
+var ErrTemplateNotAllowed = errors.New("template is not available to your role")
+
+type RecordTemplateService interface {
+	CreateTemplate(ctx context.Context, template *RecordTemplate, userID primitive.ObjectID) error
+	GetTemplate(ctx context.Context, id string) (*RecordTemplate, error)
+	ListTemplates(ctx context.Context, moduleName string) ([]RecordTemplate, error)
+	UpdateTemplate(ctx context.Context, template *RecordTemplate) error
+	DeleteTemplate(ctx context.Context, id string) error
+
+	// AvailableTemplates lists the module's templates the caller's roles may use
+	AvailableTemplates(ctx context.Context, moduleName string) ([]RecordTemplate, error)
+	// QuickCreate creates a record from the template's defaults merged with
+	// the request's data
+	QuickCreate(ctx context.Context, moduleName string, req QuickCreateRequest, userID primitive.ObjectID) (interface{}, error)
+}
+
+type RecordTemplateServiceImpl struct {
+	Repo          RecordTemplateRepository
+	ModuleRepo    module.ModuleRepository
+	RecordService record.RecordService
+	AuditService  audit.AuditService
+}
+
+func NewRecordTemplateService(
+	repo RecordTemplateRepository,
+	moduleRepo module.ModuleRepository,
+	recordService record.RecordService,
+	auditService audit.AuditService,
+) RecordTemplateService {
+	return &RecordTemplateServiceImpl{
+		Repo:          repo,
+		ModuleRepo:    moduleRepo,
+		RecordService: recordService,
+		AuditService:  auditService,
+	}
+}
+
+func (s *RecordTemplateServiceImpl) CreateTemplate(ctx context.Context, template *RecordTemplate, userID primitive.ObjectID) error {
+	if err := s.validate(ctx, template); err != nil {
+		return err
+	}
+	template.CreatedBy = userID
+
+	if err := s.Repo.Create(ctx, template); err != nil {
+		return err
+	}
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionTemplate, "record_templates", template.ID.Hex(), map[string]common_models.Change{
+		"template": {New: template},
+	})
+	return nil
+}
+
+func (s *RecordTemplateServiceImpl) GetTemplate(ctx context.Context, id string) (*RecordTemplate, error) {
+	return s.Repo.Get(ctx, id)
+}
+
+func (s *RecordTemplateServiceImpl) ListTemplates(ctx context.Context, moduleName string) ([]RecordTemplate, error) {
+	return s.Repo.List(ctx, moduleName)
+}
+
+func (s *RecordTemplateServiceImpl) UpdateTemplate(ctx context.Context, template *RecordTemplate) error {
+	old, err := s.Repo.Get(ctx, template.ID.Hex())
+	if err != nil {
+		return errors.New("template not found")
+	}
+	if err := s.validate(ctx, template); err != nil {
+		return err
+	}
+	template.CreatedBy = old.CreatedBy
+	template.CreatedAt = old.CreatedAt
+
+	if err := s.Repo.Update(ctx, template); err != nil {
+		return err
+	}
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionTemplate, "record_templates", template.ID.Hex(), map[string]common_models.Change{
+		"template": {Old: old, New: template},
+	})
+	return nil
+}
+
+func (s *RecordTemplateServiceImpl) DeleteTemplate(ctx context.Context, id string) error {
+	old, err := s.Repo.Get(ctx, id)
+	if err != nil {
+		return errors.New("template not found")
+	}
+	if err := s.Repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionTemplate, "record_templates", id, map[string]common_models.Change{
+		"template": {Old: old, New: "DELETED"},
+	})
+	return nil
+}
+
+// validate checks the template against its module. Default values are
+// type-checked when a record is created from them, as the schema may change
+// in between.
+func (s *RecordTemplateServiceImpl) validate(ctx context.Context, template *RecordTemplate) error {
+	var errs validation.Errors
+	if template.Name == "" {
+		errs.Add("name", validation.CodeRequired, "name is required")
+	}
+	mod, err := s.ModuleRepo.FindByName(ctx, template.ModuleName)
+	if err != nil || mod == nil {
+		errs.Add("module_name", validation.CodeInvalid, fmt.Sprintf("module '%s' not found", template.ModuleName))
+		return errs.Err()
+	}
+
+	known := make(map[string]bool, len(mod.Fields))
+	for _, f := range mod.Fields {
+		known[f.Name] = true
+	}
+	for name := range template.Defaults {
+		if !known[name] {
+			errs.Add("defaults."+name, validation.CodeNotAllowed, fmt.Sprintf("unknown field '%s'", name))
+		}
+	}
+	for i, id := range template.RoleIDs {
+		if _, err := primitive.ObjectIDFromHex(id); err != nil {
+			errs.Add(fmt.Sprintf("role_ids.%d", i), validation.CodeInvalid, "invalid role ID")
+		}
+	}
+	if template.Defaults == nil {
+		template.Defaults = map[string]interface{}{}
+	}
+	return errs.Err()
+}
+
+// allowed reports whether the caller's roles may use the template
+func allowed(ctx context.Context, template *RecordTemplate) bool {
+	if len(template.RoleIDs) == 0 {
+		return true
+	}
+	claims, ok := ctx.Value(utils.UserClaimsKey).(*utils.UserClaims)
+	if !ok {
+		return false
+	}
+	for _, roleID := range claims.RoleIDs {
+		for _, allowedID := range template.RoleIDs {
+			if roleID == allowedID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *RecordTemplateServiceImpl) AvailableTemplates(ctx context.Context, moduleName string) ([]RecordTemplate, error) {
+	templates, err := s.Repo.List(ctx, moduleName)
+	if err != nil {
+		return nil, err
+	}
+	available := []RecordTemplate{}
+	for i := range templates {
+		if allowed(ctx, &templates[i]) {
+			available = append(available, templates[i])
+		}
+	}
+	return available, nil
+}
+
+func (s *RecordTemplateServiceImpl) QuickCreate(ctx context.Context, moduleName string, req QuickCreateRequest, userID primitive.ObjectID) (interface{}, error) {
+	if req.TemplateID == "" {
+		return nil, validation.New("template_id", validation.CodeRequired, "template_id is required")
+	}
+	template, err := s.Repo.Get(ctx, req.TemplateID)
+	if err != nil || template.ModuleName != moduleName {
+		return nil, errors.New("template not found")
+	}
+	if !allowed(ctx, template) {
+		return nil, ErrTemplateNotAllowed
+	}
+
+	data := make(map[string]interface{}, len(template.Defaults)+len(req.Data))
+	for k, v := range template.Defaults {
+		data[k] = v
+	}
+	for k, v := range req.Data {
+		data[k] = v
+	}
+	return s.RecordService.CreateRecord(ctx, moduleName, data, userID)
+}
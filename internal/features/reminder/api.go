@@ -0,0 +1,31 @@
+package reminder
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ReminderApi struct {
+	controller *ReminderController
+	config     *config.Config
+}
+
+func NewReminderApi(controller *ReminderController, config *config.Config) *ReminderApi {
+	return &ReminderApi{
+		controller: controller,
+		config:     config,
+	}
+}
+
+func (h *ReminderApi) Setup(app *fiber.App) {
+	reminders := app.Group("/api/reminders", middleware.AuthMiddleware(h.config.SkipAuth))
+	reminders.Get("/", h.controller.ListMyReminders)
+	reminders.Put("/:id", h.controller.UpdateReminder)
+	reminders.Delete("/:id", h.controller.CancelReminder)
+
+	records := app.Group("/api/modules", middleware.AuthMiddleware(h.config.SkipAuth))
+	records.Get("/:module/records/:id/reminders", h.controller.ListRecordReminders)
+	records.Post("/:module/records/:id/reminders", h.controller.CreateReminder)
+}
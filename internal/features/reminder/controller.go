@@ -0,0 +1,142 @@
+package reminder
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"go-crm/internal/common/session"
+)
+
+type ReminderController struct {
+	Service ReminderService
+}
+
+func NewReminderController(service ReminderService) *ReminderController {
+	return &ReminderController{Service: service}
+}
+
+// CreateReminder godoc
+// @Summary Create reminder
+// @Description Remind the current user about a record at a fixed time (remind_at) or relative to a date field (relative, e.g. 2 days before close_date)
+// @Tags reminders
+// @Accept json
+// @Produce json
+// @Param module path string true "Module Name"
+// @Param id path string true "Record ID"
+// @Param request body ReminderRequest true "Reminder"
+// @Success 201 {object} Reminder
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/modules/{module}/records/{id}/reminders [post]
+func (c *ReminderController) CreateReminder(ctx *fiber.Ctx) error {
+	var req ReminderRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	rem, err := c.Service.CreateReminder(ctx.UserContext(), ctx.Params("module"), ctx.Params("id"), req, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(rem)
+}
+
+// ListRecordReminders godoc
+// @Summary List record reminders
+// @Description The current user's reminders on a record
+// @Tags reminders
+// @Produce json
+// @Param module path string true "Module Name"
+// @Param id path string true "Record ID"
+// @Success 200 {array} Reminder
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/modules/{module}/records/{id}/reminders [get]
+func (c *ReminderController) ListRecordReminders(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	reminders, err := c.Service.ListForRecord(ctx.UserContext(), ctx.Params("module"), ctx.Params("id"), userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(reminders)
+}
+
+// ListMyReminders godoc
+// @Summary List my reminders
+// @Description All reminders of the current user, optionally filtered by status
+// @Tags reminders
+// @Produce json
+// @Param status query string false "pending, waiting, sent, failed or cancelled"
+// @Success 200 {array} Reminder
+// @Router /api/reminders [get]
+func (c *ReminderController) ListMyReminders(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	reminders, err := c.Service.ListMine(ctx.UserContext(), Status(ctx.Query("status")), userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(reminders)
+}
+
+// UpdateReminder godoc
+// @Summary Update reminder
+// @Description Change the note, trigger or email delivery of an unsent reminder
+// @Tags reminders
+// @Accept json
+// @Produce json
+// @Param id path string true "Reminder ID"
+// @Param request body ReminderRequest true "Reminder"
+// @Success 200 {object} Reminder
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/reminders/{id} [put]
+func (c *ReminderController) UpdateReminder(ctx *fiber.Ctx) error {
+	var req ReminderRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	rem, err := c.Service.UpdateReminder(ctx.UserContext(), ctx.Params("id"), req, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(rem)
+}
+
+// CancelReminder godoc
+// @Summary Cancel reminder
+// @Tags reminders
+// @Param id path string true "Reminder ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/reminders/{id} [delete]
+func (c *ReminderController) CancelReminder(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if err := c.Service.CancelReminder(ctx.UserContext(), ctx.Params("id"), userID); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Reminder cancelled"})
+}
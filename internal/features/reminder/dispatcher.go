@@ -0,0 +1,203 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/email"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/notification"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/user"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DispatchSchedule is the cron schedule the dispatcher runs on
+const DispatchSchedule = "* * * * *"
+
+// maxPerRun bounds one tick so a backlog cannot starve the scheduler
+const maxPerRun = 500
+
+// Dispatcher delivers due reminders and keeps relative reminders in step with
+// their date fields. It works from the record repository rather than
+// RecordService because it is itself a record.ChangeListener.
+type Dispatcher struct {
+	Repo                ReminderRepository
+	RecordRepo          record.RecordRepository
+	ModuleRepo          module.ModuleRepository
+	UserRepo            user.UserRepository
+	NotificationService notification.NotificationService
+	EmailService        email.EmailService
+}
+
+func NewDispatcher(
+	repo ReminderRepository,
+	recordRepo record.RecordRepository,
+	moduleRepo module.ModuleRepository,
+	userRepo user.UserRepository,
+	notificationService notification.NotificationService,
+	emailService email.EmailService,
+) *Dispatcher {
+	return &Dispatcher{
+		Repo:                repo,
+		RecordRepo:          recordRepo,
+		ModuleRepo:          moduleRepo,
+		UserRepo:            userRepo,
+		NotificationService: notificationService,
+		EmailService:        emailService,
+	}
+}
+
+// Run sends every reminder that is due. Claiming is atomic, so several API
+// instances can run it concurrently without double delivery.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	for i := 0; i < maxPerRun; i++ {
+		rem, err := d.Repo.ClaimDue(ctx, time.Now())
+		if err != nil {
+			return err
+		}
+		if rem == nil {
+			return nil
+		}
+		tenantCtx := context.WithValue(ctx, common_models.TenantIDKey, rem.TenantID.Hex())
+		if err := d.deliver(tenantCtx, rem); err != nil {
+			log.Printf("reminder: delivery failed for %s: %v", rem.ID.Hex(), err)
+			_ = d.Repo.MarkFailed(ctx, rem.ID, err.Error())
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, rem *Reminder) error {
+	rec, err := d.RecordRepo.Get(ctx, rem.ModuleName, rem.RecordID)
+	if err != nil {
+		return errors.New("record no longer exists")
+	}
+
+	label := rem.ModuleName
+	mod, _ := d.ModuleRepo.FindByName(ctx, rem.ModuleName)
+	if mod != nil && mod.Label != "" {
+		label = mod.Label
+	}
+
+	title := fmt.Sprintf("Reminder: %s", recordName(rec, rem.RecordID))
+	message := rem.Note
+	if message == "" {
+		message = fmt.Sprintf("Reminder for %s %s", label, recordName(rec, rem.RecordID))
+	}
+	if rem.Relative != nil && mod != nil {
+		if when, ok := dateValue(rec[rem.Relative.Field]); ok {
+			message += fmt.Sprintf(" (%s: %s)", fieldLabel(mod, rem.Relative.Field), when.Format("2006-01-02 15:04"))
+		}
+	}
+	link := fmt.Sprintf("/dashboard/modules/%s/%s", rem.ModuleName, rem.RecordID)
+
+	if err := d.NotificationService.CreateNotification(ctx, rem.UserID, title, message, notification.NotificationTypeTask, link); err != nil {
+		return err
+	}
+
+	if rem.NotifyEmail {
+		u, err := d.UserRepo.FindByID(ctx, rem.UserID.Hex())
+		if err != nil || u == nil || u.Email == "" {
+			return errors.New("no email address for reminder recipient")
+		}
+		if err := d.EmailService.SendEmail(ctx, []string{u.Email}, title, message); err != nil {
+			return fmt.Errorf("email: %w", err)
+		}
+	}
+	return nil
+}
+
+// RecordChanged moves relative reminders when their date field changes
+func (d *Dispatcher) RecordChanged(ctx context.Context, change record.RecordChange) {
+	if change.Created {
+		return
+	}
+	reminders, err := d.Repo.ListOpenRelative(ctx, change.ModuleName, change.RecordID)
+	if err != nil || len(reminders) == 0 {
+		return
+	}
+	for i := range reminders {
+		rem := &reminders[i]
+		if _, touched := change.Changes[rem.Relative.Field]; !touched {
+			continue
+		}
+		applyDue(rem, change.Record)
+		if err := d.Repo.Update(ctx, rem); err != nil {
+			log.Printf("reminder: failed to reschedule %s: %v", rem.ID.Hex(), err)
+		}
+	}
+}
+
+// applyDue recomputes DueAt and the pending/waiting status for a relative reminder
+func applyDue(rem *Reminder, rec map[string]interface{}) {
+	base, ok := dateValue(rec[rem.Relative.Field])
+	if !ok {
+		rem.DueAt = nil
+		rem.Status = StatusWaiting
+		return
+	}
+	due := offsetTime(base, rem.Relative)
+	rem.DueAt = &due
+	rem.Status = StatusPending
+}
+
+func offsetTime(base time.Time, t *RelativeTrigger) time.Time {
+	var d time.Duration
+	switch t.Unit {
+	case "minutes":
+		d = time.Duration(t.Offset) * time.Minute
+	case "hours":
+		d = time.Duration(t.Offset) * time.Hour
+	default:
+		return base.AddDate(0, 0, sign(t)*t.Offset)
+	}
+	return base.Add(time.Duration(sign(t)) * d)
+}
+
+func sign(t *RelativeTrigger) int {
+	if t.Direction == "after" {
+		return 1
+	}
+	return -1
+}
+
+func dateValue(v interface{}) (time.Time, bool) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, !val.IsZero()
+	case primitive.DateTime:
+		return val.Time(), true
+	case string:
+		for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+			if t, err := time.Parse(layout, val); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func fieldLabel(mod *common_models.Entity, name string) string {
+	for _, f := range mod.Fields {
+		if f.Name == name && f.Label != "" {
+			return f.Label
+		}
+	}
+	return name
+}
+
+func recordName(rec map[string]interface{}, id string) string {
+	for _, key := range []string{"name", "title", "subject"} {
+		if s, ok := rec[key].(string); ok && strings.TrimSpace(s) != "" {
+			return s
+		}
+	}
+	return id
+}
@@ -0,0 +1,55 @@
+package reminder
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusWaiting   Status = "waiting" // relative reminder whose date field is empty
+	StatusSent      Status = "sent"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// RelativeTrigger places a reminder relative to a date field on the record,
+// e.g. 2 days before close_date
+type RelativeTrigger struct {
+	Field     string `json:"field" bson:"field"`
+	Offset    int    `json:"offset" bson:"offset"`
+	Unit      string `json:"unit" bson:"unit"`           // minutes, hours, days
+	Direction string `json:"direction" bson:"direction"` // before, after
+}
+
+type Reminder struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID   primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	UserID     primitive.ObjectID `json:"user_id" bson:"user_id"`
+	ModuleName string             `json:"module_name" bson:"module_name"`
+	RecordID   string             `json:"record_id" bson:"record_id"`
+	Note       string             `json:"note" bson:"note"`
+
+	// Exactly one of RemindAt and Relative is set
+	RemindAt *time.Time       `json:"remind_at,omitempty" bson:"remind_at,omitempty"`
+	Relative *RelativeTrigger `json:"relative,omitempty" bson:"relative,omitempty"`
+
+	// DueAt is when the reminder fires; relative reminders follow their date field
+	DueAt       *time.Time `json:"due_at,omitempty" bson:"due_at,omitempty"`
+	NotifyEmail bool       `json:"notify_email" bson:"notify_email"`
+	Status      Status     `json:"status" bson:"status"`
+	SentAt      *time.Time `json:"sent_at,omitempty" bson:"sent_at,omitempty"`
+	Error       string     `json:"error,omitempty" bson:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" bson:"updated_at"`
+}
+
+type ReminderRequest struct {
+	Note        string           `json:"note"`
+	RemindAt    *time.Time       `json:"remind_at"`
+	Relative    *RelativeTrigger `json:"relative"`
+	NotifyEmail bool             `json:"notify_email"`
+}
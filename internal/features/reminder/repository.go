@@ -0,0 +1,175 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ReminderRepository interface {
+	Create(ctx context.Context, reminder *Reminder) error
+	Get(ctx context.Context, id string) (*Reminder, error)
+	Update(ctx context.Context, reminder *Reminder) error
+	ListByUser(ctx context.Context, userID primitive.ObjectID, status Status) ([]Reminder, error)
+	ListByRecord(ctx context.Context, moduleName, recordID string, userID primitive.ObjectID) ([]Reminder, error)
+	// ListOpenRelative returns unsent relative reminders on a record
+	ListOpenRelative(ctx context.Context, moduleName, recordID string) ([]Reminder, error)
+
+	// ClaimDue atomically marks the next due reminder across all tenants as
+	// sent and returns it, or nil when nothing is due. Used by the scheduler.
+	ClaimDue(ctx context.Context, now time.Time) (*Reminder, error)
+	MarkFailed(ctx context.Context, id primitive.ObjectID, reason string) error
+}
+
+type ReminderRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewReminderRepository(db *database.MongodbDB) ReminderRepository {
+	return &ReminderRepositoryImpl{
+		collection: db.DB.Collection("reminders"),
+	}
+}
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+func (r *ReminderRepositoryImpl) Create(ctx context.Context, reminder *Reminder) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if reminder.ID.IsZero() {
+		reminder.ID = primitive.NewObjectID()
+	}
+	reminder.TenantID = tenantID
+	reminder.CreatedAt = time.Now()
+	reminder.UpdatedAt = time.Now()
+
+	_, err = r.collection.InsertOne(ctx, reminder)
+	return err
+}
+
+func (r *ReminderRepositoryImpl) Get(ctx context.Context, id string) (*Reminder, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var reminder Reminder
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID}).Decode(&reminder); err != nil {
+		return nil, err
+	}
+	return &reminder, nil
+}
+
+func (r *ReminderRepositoryImpl) Update(ctx context.Context, reminder *Reminder) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	reminder.TenantID = tenantID
+	reminder.UpdatedAt = time.Now()
+
+	res, err := r.collection.ReplaceOne(ctx, bson.M{"_id": reminder.ID, "tenant_id": tenantID}, reminder)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("reminder not found")
+	}
+	return nil
+}
+
+func (r *ReminderRepositoryImpl) ListByUser(ctx context.Context, userID primitive.ObjectID, status Status) ([]Reminder, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{"tenant_id": tenantID, "user_id": userID}
+	if status != "" {
+		filter["status"] = status
+	}
+	return r.find(ctx, filter)
+}
+
+func (r *ReminderRepositoryImpl) ListByRecord(ctx context.Context, moduleName, recordID string, userID primitive.ObjectID) ([]Reminder, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.find(ctx, bson.M{"tenant_id": tenantID, "module_name": moduleName, "record_id": recordID, "user_id": userID})
+}
+
+func (r *ReminderRepositoryImpl) ListOpenRelative(ctx context.Context, moduleName, recordID string) ([]Reminder, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.find(ctx, bson.M{
+		"tenant_id":   tenantID,
+		"module_name": moduleName,
+		"record_id":   recordID,
+		"relative":    bson.M{"$exists": true},
+		"status":      bson.M{"$in": []Status{StatusPending, StatusWaiting}},
+	})
+}
+
+func (r *ReminderRepositoryImpl) find(ctx context.Context, filter bson.M) ([]Reminder, error) {
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "due_at", Value: 1}, {Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	reminders := []Reminder{}
+	if err := cursor.All(ctx, &reminders); err != nil {
+		return nil, err
+	}
+	return reminders, nil
+}
+
+func (r *ReminderRepositoryImpl) ClaimDue(ctx context.Context, now time.Time) (*Reminder, error) {
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.M{"due_at": 1}).
+		SetReturnDocument(options.After)
+
+	var reminder Reminder
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"status": StatusPending, "due_at": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"status": StatusSent, "sent_at": now, "updated_at": now}},
+		opts,
+	).Decode(&reminder)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &reminder, nil
+}
+
+func (r *ReminderRepositoryImpl) MarkFailed(ctx context.Context, id primitive.ObjectID, reason string) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{"status": StatusFailed, "error": reason, "updated_at": time.Now()},
+	})
+	return err
+}
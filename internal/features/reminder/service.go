@@ -0,0 +1,184 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/record"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const maxNoteLength = 1000
+
+type ReminderService interface {
+	CreateReminder(ctx context.Context, moduleName, recordID string, req ReminderRequest, userID primitive.ObjectID) (*Reminder, error)
+	ListForRecord(ctx context.Context, moduleName, recordID string, userID primitive.ObjectID) ([]Reminder, error)
+	ListMine(ctx context.Context, status Status, userID primitive.ObjectID) ([]Reminder, error)
+	UpdateReminder(ctx context.Context, id string, req ReminderRequest, userID primitive.ObjectID) (*Reminder, error)
+	CancelReminder(ctx context.Context, id string, userID primitive.ObjectID) error
+}
+
+type ReminderServiceImpl struct {
+	Repo          ReminderRepository
+	ModuleRepo    module.ModuleRepository
+	RecordService record.RecordService
+}
+
+func NewReminderService(repo ReminderRepository, moduleRepo module.ModuleRepository, recordService record.RecordService) ReminderService {
+	return &ReminderServiceImpl{
+		Repo:          repo,
+		ModuleRepo:    moduleRepo,
+		RecordService: recordService,
+	}
+}
+
+func (s *ReminderServiceImpl) CreateReminder(ctx context.Context, moduleName, recordID string, req ReminderRequest, userID primitive.ObjectID) (*Reminder, error) {
+	rec, err := s.RecordService.GetRecord(ctx, moduleName, recordID, userID)
+	if err != nil {
+		return nil, errors.New("record not found")
+	}
+
+	rem := &Reminder{
+		UserID:     userID,
+		ModuleName: moduleName,
+		RecordID:   recordID,
+	}
+	if err := s.apply(ctx, rem, req, rec); err != nil {
+		return nil, err
+	}
+	if err := s.Repo.Create(ctx, rem); err != nil {
+		return nil, err
+	}
+	return rem, nil
+}
+
+func (s *ReminderServiceImpl) ListForRecord(ctx context.Context, moduleName, recordID string, userID primitive.ObjectID) ([]Reminder, error) {
+	if _, err := s.RecordService.GetRecord(ctx, moduleName, recordID, userID); err != nil {
+		return nil, errors.New("record not found")
+	}
+	return s.Repo.ListByRecord(ctx, moduleName, recordID, userID)
+}
+
+func (s *ReminderServiceImpl) ListMine(ctx context.Context, status Status, userID primitive.ObjectID) ([]Reminder, error) {
+	return s.Repo.ListByUser(ctx, userID, status)
+}
+
+func (s *ReminderServiceImpl) UpdateReminder(ctx context.Context, id string, req ReminderRequest, userID primitive.ObjectID) (*Reminder, error) {
+	rem, err := s.owned(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if rem.Status != StatusPending && rem.Status != StatusWaiting {
+		return nil, fmt.Errorf("reminder is already %s", rem.Status)
+	}
+
+	rec, err := s.RecordService.GetRecord(ctx, rem.ModuleName, rem.RecordID, userID)
+	if err != nil {
+		return nil, errors.New("record not found")
+	}
+	if err := s.apply(ctx, rem, req, rec); err != nil {
+		return nil, err
+	}
+	if err := s.Repo.Update(ctx, rem); err != nil {
+		return nil, err
+	}
+	return rem, nil
+}
+
+func (s *ReminderServiceImpl) CancelReminder(ctx context.Context, id string, userID primitive.ObjectID) error {
+	rem, err := s.owned(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if rem.Status == StatusCancelled {
+		return nil
+	}
+	if rem.Status != StatusPending && rem.Status != StatusWaiting {
+		return fmt.Errorf("reminder is already %s", rem.Status)
+	}
+	rem.Status = StatusCancelled
+	return s.Repo.Update(ctx, rem)
+}
+
+func (s *ReminderServiceImpl) owned(ctx context.Context, id string, userID primitive.ObjectID) (*Reminder, error) {
+	rem, err := s.Repo.Get(ctx, id)
+	if err != nil || rem.UserID != userID {
+		return nil, errors.New("reminder not found")
+	}
+	return rem, nil
+}
+
+// apply validates the request and sets the trigger and due time on rem
+func (s *ReminderServiceImpl) apply(ctx context.Context, rem *Reminder, req ReminderRequest, rec map[string]interface{}) error {
+	note := strings.TrimSpace(req.Note)
+	if len(note) > maxNoteLength {
+		return fmt.Errorf("note exceeds %d characters", maxNoteLength)
+	}
+	if (req.RemindAt == nil) == (req.Relative == nil) {
+		return errors.New("set exactly one of remind_at or relative")
+	}
+
+	rem.Note = note
+	rem.NotifyEmail = req.NotifyEmail
+	rem.Error = ""
+
+	if req.RemindAt != nil {
+		if !req.RemindAt.After(time.Now()) {
+			return errors.New("remind_at must be in the future")
+		}
+		at := *req.RemindAt
+		rem.RemindAt = &at
+		rem.Relative = nil
+		rem.DueAt = &at
+		rem.Status = StatusPending
+		return nil
+	}
+
+	trig := *req.Relative
+	if trig.Direction == "" {
+		trig.Direction = "before"
+	}
+	if trig.Unit == "" {
+		trig.Unit = "days"
+	}
+	if trig.Direction != "before" && trig.Direction != "after" {
+		return errors.New("relative.direction must be before or after")
+	}
+	if trig.Unit != "minutes" && trig.Unit != "hours" && trig.Unit != "days" {
+		return errors.New("relative.unit must be minutes, hours or days")
+	}
+	if trig.Offset < 0 {
+		return errors.New("relative.offset cannot be negative")
+	}
+
+	mod, err := s.ModuleRepo.FindByName(ctx, rem.ModuleName)
+	if err != nil || mod == nil {
+		return errors.New("module not found")
+	}
+	if !hasDateField(mod, trig.Field) {
+		return fmt.Errorf("relative.field '%s' is not a date field", trig.Field)
+	}
+
+	rem.RemindAt = nil
+	rem.Relative = &trig
+	applyDue(rem, rec)
+	if rem.DueAt != nil && !rem.DueAt.After(time.Now()) {
+		return errors.New("the computed reminder time is already in the past")
+	}
+	return nil
+}
+
+func hasDateField(mod *common_models.Entity, name string) bool {
+	for _, f := range mod.Fields {
+		if f.Name == name {
+			return f.Type == common_models.FieldTypeDate
+		}
+	}
+	return false
+}
@@ -34,6 +34,7 @@ func (api *ReportApi) Setup(app *fiber.App) {
 
 	// Advanced reporting endpoints
 	group.Post("/pivot", middleware.RequirePermission(api.RoleService, "reports", "read"), api.ReportController.RunPivot)
+	group.Post("/query", middleware.RequirePermission(api.RoleService, "reports", "read"), api.ReportController.Query)
 	group.Post("/cross-module", middleware.RequirePermission(api.RoleService, "reports", "read"), api.ReportController.RunCrossModule)
 	group.Post("/export-excel", middleware.RequirePermission(api.RoleService, "reports", "read"), api.ReportController.ExportExcel)
 }
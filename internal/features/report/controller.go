@@ -1,8 +1,12 @@
 package report
 
 import (
+	"errors"
 	"fmt"
 
+	"go-crm/internal/common/session"
+	"go-crm/internal/features/module"
+
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -133,14 +137,10 @@ func (c *ReportController) Delete(ctx *fiber.Ctx) error {
 func (c *ReportController) Run(ctx *fiber.Ctx) error {
 	id := ctx.Params("id")
 
-	userIDStr, ok := ctx.Locals("user_id").(string)
+	userID, ok := session.UserID(ctx.UserContext())
 	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User ID not found"})
 	}
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
-	}
 
 	data, err := c.ReportService.RunReport(ctx.UserContext(), id, userID)
 	if err != nil {
@@ -152,7 +152,7 @@ func (c *ReportController) Run(ctx *fiber.Ctx) error {
 // Export godoc
 // Export godoc
 // @Summary Export report
-// @Description Export report results to a file (CSV)
+// @Description Export report results to a file (CSV). Columns of fields hidden from the user are left out, and the first line is a watermark naming the exporter and the time.
 // @Tags reports
 // @Produce text/csv
 // @Param id path string true "Report ID"
@@ -164,14 +164,10 @@ func (c *ReportController) Export(ctx *fiber.Ctx) error {
 	id := ctx.Params("id")
 	format := ctx.Query("format", "csv")
 
-	userIDStr, ok := ctx.Locals("user_id").(string)
+	userID, ok := session.UserID(ctx.UserContext())
 	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User ID not found"})
 	}
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
-	}
 
 	data, filename, err := c.ReportService.ExportReport(ctx.UserContext(), id, format, userID)
 	if err != nil {
@@ -207,14 +203,10 @@ func (c *ReportController) RunPivot(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "module query parameter is required"})
 	}
 
-	userIDStr, ok := ctx.Locals("user_id").(string)
+	userID, ok := session.UserID(ctx.UserContext())
 	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User ID not found"})
 	}
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
-	}
 
 	filters := make(map[string]any)
 	result, err := c.ReportService.RunPivotReport(ctx.UserContext(), &config, moduleName, filters, userID)
@@ -225,6 +217,45 @@ func (c *ReportController) RunPivot(ctx *fiber.Ctx) error {
 	return ctx.JSON(result)
 }
 
+// Query godoc
+// @Summary Ask a report question
+// @Description Translate a natural-language question into a report spec limited to the user's modules and fields, run it, and return the spec with the results. Send a spec instead of a question to run a refined one.
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param request body QueryRequest true "Question or spec"
+// @Success 200 {object} QueryResult
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/reports/query [post]
+func (c *ReportController) Query(ctx *fiber.Ctx) error {
+	var req QueryRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User ID not found"})
+	}
+
+	result, err := c.ReportService.Query(ctx.UserContext(), req, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrQueryEmpty), errors.Is(err, ErrQueryBadSpec), errors.Is(err, ErrQueryBadPeriod):
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, ErrQueryNoModule):
+			return ctx.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, module.ErrAccessDenied):
+			return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(result)
+}
+
 // RunCrossModule godoc
 // RunCrossModule godoc
 // @Summary Run cross-module report
@@ -243,14 +274,10 @@ func (c *ReportController) RunCrossModule(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
 	}
 
-	userIDStr, ok := ctx.Locals("user_id").(string)
+	userID, ok := session.UserID(ctx.UserContext())
 	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User ID not found"})
 	}
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
-	}
 
 	filters := make(map[string]any)
 	result, err := c.ReportService.RunCrossModuleReport(ctx.UserContext(), &config, filters, userID)
@@ -264,11 +291,11 @@ func (c *ReportController) RunCrossModule(ctx *fiber.Ctx) error {
 // ExportExcel godoc
 // ExportExcel godoc
 // @Summary Export to Excel
-// @Description Export raw data to an Excel file
+// @Description Export raw data to an Excel file. The first row is a watermark naming the exporter and the time; with module set, columns of fields hidden from the user are left out.
 // @Tags reports
 // @Accept json
 // @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
-// @Param request body map[string]interface{} true "Excel Export Request (data, columns)"
+// @Param request body map[string]interface{} true "Excel Export Request (data, columns, filename, module)"
 // @Success 200 {file} file "Excel file"
 // @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
@@ -278,6 +305,7 @@ func (c *ReportController) ExportExcel(ctx *fiber.Ctx) error {
 		Data     []map[string]any `json:"data"`
 		Columns  []string         `json:"columns"`
 		Filename string           `json:"filename"`
+		Module   string           `json:"module"` // Drops columns hidden from the user
 	}
 
 	if err := ctx.BodyParser(&request); err != nil {
@@ -288,7 +316,8 @@ func (c *ReportController) ExportExcel(ctx *fiber.Ctx) error {
 		request.Filename = fmt.Sprintf("export_%d", int64(primitive.NewObjectID().Timestamp().Unix()))
 	}
 
-	data, filename, err := c.ReportService.ExportToExcel(ctx.UserContext(), request.Data, request.Columns, request.Filename)
+	userID, _ := session.UserID(ctx.UserContext())
+	data, filename, err := c.ReportService.ExportToExcel(ctx.UserContext(), request.Module, request.Data, request.Columns, request.Filename, userID)
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -0,0 +1,802 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	common_models "go-crm/internal/common/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// maxQueryRecords caps how many records a question aggregates over
+	maxQueryRecords = 10000
+	// queryPageSize matches the record service's page cap
+	queryPageSize  = 100
+	maxQueryGroups = 500
+)
+
+var (
+	ErrQueryEmpty     = errors.New("question or spec is required")
+	ErrQueryNoModule  = errors.New("could not tell which module the question is about")
+	ErrQueryBadSpec   = errors.New("invalid query spec")
+	ErrQueryBadPeriod = errors.New("unknown date period")
+)
+
+// QuerySpec is the report a question translates to. It is returned with the
+// results so clients can adjust it and run it again in place of the question.
+type QuerySpec struct {
+	Module      string `json:"module"`
+	Aggregation string `json:"aggregation"` // count, sum, avg, min, max
+	ValueField  string `json:"value_field,omitempty"`
+	// GroupBy holds field names; date fields may add a bucket, e.g. "close_date:month"
+	GroupBy []string `json:"group_by,omitempty"`
+	// Filters use the saved report syntax: field or field__operator keys
+	Filters   map[string]any  `json:"filters,omitempty"`
+	DateRange *QueryDateRange `json:"date_range,omitempty"`
+	Limit     int             `json:"limit,omitempty"`
+}
+
+// QueryDateRange restricts a date field to a named period (this_quarter,
+// last_30_days, ...) or to explicit bounds when Period is empty
+type QueryDateRange struct {
+	Field  string    `json:"field"`
+	Period string    `json:"period,omitempty"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+}
+
+type QueryRequest struct {
+	Question string     `json:"question"`
+	Spec     *QuerySpec `json:"spec,omitempty"`
+}
+
+type QueryRow struct {
+	Group map[string]string `json:"group,omitempty"`
+	Value float64           `json:"value"`
+	Count int               `json:"count"`
+}
+
+type QueryResult struct {
+	Question string     `json:"question,omitempty"`
+	Spec     *QuerySpec `json:"spec"`
+	// Notes explain which parts of the question were interpreted and how
+	Notes     []string   `json:"notes,omitempty"`
+	Rows      []QueryRow `json:"rows"`
+	Total     float64    `json:"total"`
+	Matched   int        `json:"matched"`
+	Truncated bool       `json:"truncated"`
+}
+
+var (
+	queryAggregations = map[string]bool{"count": true, "sum": true, "avg": true, "min": true, "max": true}
+	dateBuckets       = map[string]bool{"day": true, "week": true, "month": true, "quarter": true, "year": true}
+
+	// moduleSynonyms maps everyday words to the modules they usually mean
+	moduleSynonyms = map[string]string{
+		"pipeline": "opportunities",
+		"deal":     "opportunities",
+		"deals":    "opportunities",
+		"revenue":  "opportunities",
+		"customer": "accounts",
+		"company":  "accounts",
+		"people":   "contacts",
+	}
+
+	aggregationWords = []struct {
+		phrase string
+		agg    string
+	}{
+		{"how many", "count"},
+		{"number of", "count"},
+		{"count", "count"},
+		{"average", "avg"},
+		{"avg", "avg"},
+		{"mean", "avg"},
+		{"total", "sum"},
+		{"sum", "sum"},
+		{"highest", "max"},
+		{"largest", "max"},
+		{"biggest", "max"},
+		{"maximum", "max"},
+		{"lowest", "min"},
+		{"smallest", "min"},
+		{"minimum", "min"},
+	}
+
+	periodPattern = regexp.MustCompile(`\b(this|current|last|previous|next) (week|month|quarter|year)\b`)
+	daysPattern   = regexp.MustCompile(`\b(last|past|next) (\d+) days?\b`)
+	topPattern    = regexp.MustCompile(`\b(top|first) (\d+)\b`)
+)
+
+// Query answers a question, or runs a spec the client refined, over the
+// modules and fields the user can read
+func (s *ReportServiceImpl) Query(ctx context.Context, req QueryRequest, userID primitive.ObjectID) (*QueryResult, error) {
+	ctx = reportContext(ctx)
+	now := time.Now().In(s.location(ctx, userID))
+
+	result := &QueryResult{Question: req.Question}
+	spec := req.Spec
+	if spec == nil {
+		if strings.TrimSpace(req.Question) == "" {
+			return nil, ErrQueryEmpty
+		}
+		modules, err := s.ModuleService.ListModules(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		spec, result.Notes, err = parseQuestion(req.Question, modules, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	mod, err := s.ModuleService.GetModuleByName(ctx, spec.Module, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSpec(spec, mod, now); err != nil {
+		return nil, err
+	}
+	result.Spec = spec
+
+	records, truncated, err := s.listQueryRecords(ctx, spec, userID)
+	if err != nil {
+		return nil, err
+	}
+	result.Matched = len(records)
+	result.Truncated = truncated
+	result.Rows, result.Total = aggregateQuery(spec, records, now.Location())
+	return result, nil
+}
+
+func (s *ReportServiceImpl) location(ctx context.Context, userID primitive.ObjectID) *time.Location {
+	if s.SettingsService == nil || userID.IsZero() {
+		return time.UTC
+	}
+	return s.SettingsService.Location(ctx, userID.Hex())
+}
+
+// listQueryRecords pages through the permission-filtered records matching the spec
+func (s *ReportServiceImpl) listQueryRecords(ctx context.Context, spec *QuerySpec, userID primitive.ObjectID) ([]map[string]any, bool, error) {
+	filters := s.convertFilters(spec.Filters)
+	if dr := spec.DateRange; dr != nil {
+		if dr.Field != "created_at" && dr.Field != "updated_at" {
+			filters = append(filters, common_models.Filter{
+				Field:    dr.Field,
+				Operator: "between",
+				Value:    dr.From.Format(time.RFC3339) + "," + dr.To.Format(time.RFC3339),
+			})
+		} else {
+			// Record timestamps are not in the stored schema, so the range goes through as is
+			filters = append(filters, common_models.Filter{
+				Field: dr.Field,
+				Value: bson.M{"$gte": dr.From.UTC(), "$lte": dr.To.UTC()},
+			})
+		}
+	}
+
+	var records []map[string]any
+	for page := int64(1); len(records) < maxQueryRecords; page++ {
+		batch, total, err := s.RecordService.ListRecords(ctx, spec.Module, filters, page, queryPageSize, "created_at", "desc", userID)
+		if err != nil {
+			return nil, false, err
+		}
+		records = append(records, batch...)
+		if len(batch) < queryPageSize || int64(len(records)) >= total {
+			return records, false, nil
+		}
+	}
+	return records, true, nil
+}
+
+// validateSpec checks a spec only names fields of the module the user can see
+// and fills in defaults
+func validateSpec(spec *QuerySpec, mod *common_models.Entity, now time.Time) error {
+	spec.Module = mod.Name
+	if spec.Aggregation == "" {
+		spec.Aggregation = "count"
+	}
+	if !queryAggregations[spec.Aggregation] {
+		return fmt.Errorf("%w: unknown aggregation %q", ErrQueryBadSpec, spec.Aggregation)
+	}
+	if spec.Aggregation != "count" {
+		f := findField(mod, spec.ValueField)
+		if f == nil || !isNumericField(f) {
+			return fmt.Errorf("%w: %s needs a numeric value_field", ErrQueryBadSpec, spec.Aggregation)
+		}
+	}
+	for _, g := range spec.GroupBy {
+		name, bucket, _ := strings.Cut(g, ":")
+		f := findField(mod, name)
+		if f == nil && !isUserField(name) {
+			return fmt.Errorf("%w: unknown field %q", ErrQueryBadSpec, name)
+		}
+		if bucket != "" && (f == nil || f.Type != common_models.FieldTypeDate || !dateBuckets[bucket]) {
+			return fmt.Errorf("%w: cannot group %q by %q", ErrQueryBadSpec, name, bucket)
+		}
+	}
+	for key := range spec.Filters {
+		name, _, _ := strings.Cut(key, "__")
+		if findField(mod, name) == nil && !isUserField(name) {
+			return fmt.Errorf("%w: unknown filter field %q", ErrQueryBadSpec, name)
+		}
+	}
+	if dr := spec.DateRange; dr != nil {
+		if f := findField(mod, dr.Field); f == nil || f.Type != common_models.FieldTypeDate {
+			return fmt.Errorf("%w: %q is not a date field", ErrQueryBadSpec, dr.Field)
+		}
+		if dr.Period != "" {
+			from, to, ok := periodRange(dr.Period, now)
+			if !ok {
+				return fmt.Errorf("%w: %q", ErrQueryBadPeriod, dr.Period)
+			}
+			dr.From, dr.To = from, to
+		} else if dr.From.IsZero() || dr.To.IsZero() || dr.To.Before(dr.From) {
+			return fmt.Errorf("%w: date_range needs a period or from/to", ErrQueryBadSpec)
+		}
+	}
+	if spec.Limit < 0 || spec.Limit > maxQueryGroups {
+		spec.Limit = maxQueryGroups
+	}
+	return nil
+}
+
+// parseQuestion translates a question into a spec over the given modules,
+// which must already be restricted to what the user can read
+func parseQuestion(question string, modules []common_models.Entity, now time.Time) (*QuerySpec, []string, error) {
+	text := normalizeQuestion(question)
+	var notes []string
+
+	mod := matchModule(text, modules)
+	if mod == nil {
+		return nil, nil, ErrQueryNoModule
+	}
+	spec := &QuerySpec{Module: mod.Name, Aggregation: "count", Filters: map[string]any{}}
+	notes = append(notes, fmt.Sprintf("module: %s", mod.Name))
+
+	// Group by: "by owner", "per stage", "by month"
+	for _, f := range mod.Fields {
+		for _, p := range fieldPhrases(f) {
+			if containsPhrase(text, "by "+p) || containsPhrase(text, "per "+p) {
+				spec.GroupBy = append(spec.GroupBy, f.Name)
+				break
+			}
+		}
+	}
+	if !hasGroup(spec, "owner") && (containsPhrase(text, "by owner") || containsPhrase(text, "per owner") || containsPhrase(text, "by rep")) {
+		spec.GroupBy = append(spec.GroupBy, "owner")
+	}
+
+	for _, w := range aggregationWords {
+		if containsPhrase(text, w.phrase) {
+			spec.Aggregation = w.agg
+			break
+		}
+	}
+	pipeline := containsPhrase(text, "pipeline") || containsPhrase(text, "revenue")
+	if pipeline && spec.Aggregation == "count" && !containsPhrase(text, "how many") && !containsPhrase(text, "count") && !containsPhrase(text, "number of") {
+		spec.Aggregation = "sum"
+	}
+
+	// Value field: a numeric field the question names, else the first amount-like one
+	if spec.Aggregation != "count" {
+		var value *common_models.ModuleField
+		for i := range mod.Fields {
+			f := &mod.Fields[i]
+			if isNumericField(f) && !hasGroup(spec, f.Name) && matchesAny(text, fieldPhrases(*f)) {
+				value = f
+				break
+			}
+		}
+		if value == nil {
+			value = firstField(mod, common_models.FieldTypeCurrency)
+		}
+		if value == nil {
+			value = firstField(mod, common_models.FieldTypeNumber)
+		}
+		if value == nil {
+			notes = append(notes, fmt.Sprintf("no numeric field to %s, counting records instead", spec.Aggregation))
+			spec.Aggregation = "count"
+		} else {
+			spec.ValueField = value.Name
+		}
+	}
+	if spec.ValueField != "" {
+		notes = append(notes, fmt.Sprintf("%s of %s", spec.Aggregation, spec.ValueField))
+	} else {
+		notes = append(notes, "count of records")
+	}
+
+	// Date range over the field the question implies
+	if period := matchPeriod(text); period != "" {
+		field := dateFieldFor(text, mod)
+		from, to, _ := periodRange(period, now)
+		spec.DateRange = &QueryDateRange{Field: field, Period: period, From: from, To: to}
+		notes = append(notes, fmt.Sprintf("%s in %s", field, strings.ReplaceAll(period, "_", " ")))
+	}
+	for _, bucket := range []string{"day", "week", "month", "quarter", "year"} {
+		if containsPhrase(text, "by "+bucket) || containsPhrase(text, "per "+bucket) {
+			field := dateFieldFor(text, mod)
+			if spec.DateRange != nil {
+				field = spec.DateRange.Field
+			}
+			spec.GroupBy = append(spec.GroupBy, field+":"+bucket)
+			break
+		}
+	}
+	if len(spec.GroupBy) > 0 {
+		notes = append(notes, fmt.Sprintf("grouped by %s", strings.Join(spec.GroupBy, ", ")))
+	}
+
+	// Option values the question names filter their field ("closed won", "hot")
+	for _, f := range mod.Fields {
+		if f.Type != common_models.FieldTypeSelect || hasGroup(spec, f.Name) {
+			continue
+		}
+		var values []string
+		for _, o := range f.Options {
+			label := normalizeQuestion(o.Label)
+			if len(label) > 2 && containsPhrase(text, label) {
+				values = append(values, o.Value)
+			}
+		}
+		switch len(values) {
+		case 0:
+		case 1:
+			spec.Filters[f.Name] = values[0]
+		default:
+			spec.Filters[f.Name+"__in"] = values
+		}
+		if len(values) > 0 {
+			notes = append(notes, fmt.Sprintf("%s is %s", f.Name, strings.Join(values, " or ")))
+		}
+	}
+	// "pipeline" and "open" leave out closed stages
+	if pipeline || containsPhrase(text, "open") {
+		if f := stageField(mod); f != nil && !hasFilter(spec, f.Name) {
+			var closed []string
+			for _, o := range f.Options {
+				if strings.Contains(strings.ToLower(o.Value), "closed") {
+					closed = append(closed, o.Value)
+				}
+			}
+			if len(closed) > 0 {
+				spec.Filters[f.Name+"__nin"] = closed
+				notes = append(notes, fmt.Sprintf("excluding %s %s", f.Name, strings.Join(closed, ", ")))
+			}
+		}
+	}
+	if len(spec.Filters) == 0 {
+		spec.Filters = nil
+	}
+
+	if m := topPattern.FindStringSubmatch(text); m != nil {
+		spec.Limit, _ = strconv.Atoi(m[2])
+	}
+	return spec, notes, nil
+}
+
+// aggregateQuery groups records and sorts the groups by value, or
+// chronologically when grouped by a date bucket
+func aggregateQuery(spec *QuerySpec, records []map[string]any, loc *time.Location) ([]QueryRow, float64) {
+	type acc struct {
+		group map[string]string
+		key   string
+		sum   float64
+		n     int // records with a numeric value
+		count int
+		set   bool
+		value float64
+	}
+	groups := map[string]*acc{}
+	var order []*acc
+	var total float64
+	totalN := 0
+
+	for _, rec := range records {
+		group := map[string]string{}
+		parts := make([]string, 0, len(spec.GroupBy))
+		for _, g := range spec.GroupBy {
+			name, bucket, _ := strings.Cut(g, ":")
+			v := groupValue(rec[name], bucket, loc)
+			group[g] = v
+			parts = append(parts, v)
+		}
+		key := strings.Join(parts, "|")
+		a := groups[key]
+		if a == nil {
+			a = &acc{group: group, key: key}
+			groups[key] = a
+			order = append(order, a)
+		}
+		a.count++
+
+		if spec.Aggregation == "count" {
+			continue
+		}
+		num, ok := toNumber(rec[spec.ValueField])
+		if !ok {
+			continue
+		}
+		a.sum += num
+		a.n++
+		total += num
+		totalN++
+		switch {
+		case !a.set:
+			a.value, a.set = num, true
+		case spec.Aggregation == "min" && num < a.value:
+			a.value = num
+		case spec.Aggregation == "max" && num > a.value:
+			a.value = num
+		}
+	}
+
+	rows := make([]QueryRow, 0, len(order))
+	for _, a := range order {
+		row := QueryRow{Count: a.count}
+		if len(spec.GroupBy) > 0 {
+			row.Group = a.group
+		}
+		switch spec.Aggregation {
+		case "count":
+			row.Value = float64(a.count)
+		case "sum":
+			row.Value = a.sum
+		case "avg":
+			if a.n > 0 {
+				row.Value = a.sum / float64(a.n)
+			}
+		default:
+			row.Value = a.value
+		}
+		rows = append(rows, row)
+	}
+
+	chronological := false
+	for _, g := range spec.GroupBy {
+		if strings.Contains(g, ":") {
+			chronological = true
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if chronological {
+			return groupKey(spec, rows[i]) < groupKey(spec, rows[j])
+		}
+		return rows[i].Value > rows[j].Value
+	})
+	if spec.Limit > 0 && len(rows) > spec.Limit {
+		rows = rows[:spec.Limit]
+	}
+
+	switch spec.Aggregation {
+	case "count":
+		total = float64(len(records))
+	case "avg":
+		if totalN > 0 {
+			total /= float64(totalN)
+		}
+	case "min", "max":
+		total = 0
+		for i, r := range rows {
+			if i == 0 || (spec.Aggregation == "min" && r.Value < total) || (spec.Aggregation == "max" && r.Value > total) {
+				total = r.Value
+			}
+		}
+	}
+	return rows, total
+}
+
+func groupKey(spec *QuerySpec, row QueryRow) string {
+	parts := make([]string, len(spec.GroupBy))
+	for i, g := range spec.GroupBy {
+		parts[i] = row.Group[g]
+	}
+	return strings.Join(parts, "|")
+}
+
+func groupValue(v any, bucket string, loc *time.Location) string {
+	if bucket != "" {
+		t, ok := toTime(v)
+		if !ok {
+			return "N/A"
+		}
+		t = t.In(loc)
+		switch bucket {
+		case "day":
+			return t.Format("2006-01-02")
+		case "week":
+			y, w := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", y, w)
+		case "month":
+			return t.Format("2006-01")
+		case "quarter":
+			return fmt.Sprintf("%d-Q%d", t.Year(), (int(t.Month())-1)/3+1)
+		default:
+			return strconv.Itoa(t.Year())
+		}
+	}
+	switch val := v.(type) {
+	case nil:
+		return "N/A"
+	case primitive.ObjectID:
+		return val.Hex()
+	case string:
+		if val == "" {
+			return "N/A"
+		}
+		return val
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// periodRange resolves a named period to inclusive bounds in now's location
+func periodRange(period string, now time.Time) (time.Time, time.Time, bool) {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	end := func(t time.Time) time.Time { return t.Add(-time.Nanosecond) }
+
+	if rel, n, ok := strings.Cut(period, "_"); ok && strings.HasSuffix(n, "_days") {
+		days, err := strconv.Atoi(strings.TrimSuffix(n, "_days"))
+		if err != nil || days < 1 {
+			return time.Time{}, time.Time{}, false
+		}
+		switch rel {
+		case "last":
+			return day.AddDate(0, 0, -days+1), end(day.AddDate(0, 0, 1)), true
+		case "next":
+			return day, end(day.AddDate(0, 0, days)), true
+		}
+		return time.Time{}, time.Time{}, false
+	}
+
+	switch period {
+	case "today":
+		return day, end(day.AddDate(0, 0, 1)), true
+	case "yesterday":
+		return day.AddDate(0, 0, -1), end(day), true
+	case "year_to_date":
+		return time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location()), end(day.AddDate(0, 0, 1)), true
+	}
+
+	rel, unit, ok := strings.Cut(period, "_")
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	var start time.Time
+	var step func(t time.Time, n int) time.Time
+	switch unit {
+	case "week":
+		start = day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		step = func(t time.Time, n int) time.Time { return t.AddDate(0, 0, 7*n) }
+	case "month":
+		start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		step = func(t time.Time, n int) time.Time { return t.AddDate(0, n, 0) }
+	case "quarter":
+		start = time.Date(now.Year(), time.Month((int(now.Month())-1)/3*3+1), 1, 0, 0, 0, 0, now.Location())
+		step = func(t time.Time, n int) time.Time { return t.AddDate(0, 3*n, 0) }
+	case "year":
+		start = time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+		step = func(t time.Time, n int) time.Time { return t.AddDate(n, 0, 0) }
+	default:
+		return time.Time{}, time.Time{}, false
+	}
+	switch rel {
+	case "this":
+	case "last":
+		start = step(start, -1)
+	case "next":
+		start = step(start, 1)
+	default:
+		return time.Time{}, time.Time{}, false
+	}
+	return start, end(step(start, 1)), true
+}
+
+func matchPeriod(text string) string {
+	switch {
+	case containsPhrase(text, "year to date") || containsPhrase(text, "ytd"):
+		return "year_to_date"
+	case containsPhrase(text, "today"):
+		return "today"
+	case containsPhrase(text, "yesterday"):
+		return "yesterday"
+	}
+	if m := daysPattern.FindStringSubmatch(text); m != nil {
+		rel := "last"
+		if m[1] == "next" {
+			rel = "next"
+		}
+		return rel + "_" + m[2] + "_days"
+	}
+	if m := periodPattern.FindStringSubmatch(text); m != nil {
+		rel := m[1]
+		switch rel {
+		case "current":
+			rel = "this"
+		case "previous":
+			rel = "last"
+		}
+		return rel + "_" + m[2]
+	}
+	return ""
+}
+
+// dateFieldFor picks the date field a question refers to: a named one,
+// the close date for "closing", else when the record was created
+func dateFieldFor(text string, mod *common_models.Entity) string {
+	hints := []struct{ word, field string }{
+		{"closing", "close"}, {"close", "close"}, {"closed", "close"}, {"due", "due"},
+		{"created", "created_at"}, {"updated", "updated_at"}, {"modified", "updated_at"},
+	}
+	for _, h := range hints {
+		if !containsPhrase(text, h.word) {
+			continue
+		}
+		for _, f := range mod.Fields {
+			if f.Type == common_models.FieldTypeDate && strings.Contains(f.Name, h.field) {
+				return f.Name
+			}
+		}
+	}
+	for _, f := range mod.Fields {
+		if f.Type == common_models.FieldTypeDate && !f.IsSystem && matchesAny(text, fieldPhrases(f)) {
+			return f.Name
+		}
+	}
+	return "created_at"
+}
+
+func matchModule(text string, modules []common_models.Entity) *common_models.Entity {
+	var best *common_models.Entity
+	bestLen := 0
+	for i := range modules {
+		m := &modules[i]
+		phrases := append(phraseVariants(m.Name), phraseVariants(m.Label)...)
+		for word, target := range moduleSynonyms {
+			if target == m.Name {
+				phrases = append(phrases, word)
+			}
+		}
+		for _, p := range phrases {
+			if len(p) > bestLen && containsPhrase(text, p) {
+				best, bestLen = m, len(p)
+			}
+		}
+	}
+	return best
+}
+
+func fieldPhrases(f common_models.ModuleField) []string {
+	return append(phraseVariants(f.Name), phraseVariants(f.Label)...)
+}
+
+// phraseVariants returns the normalized singular and plural forms of a name
+func phraseVariants(s string) []string {
+	p := normalizeQuestion(strings.ReplaceAll(s, "_", " "))
+	if p == "" {
+		return nil
+	}
+	variants := []string{p}
+	switch {
+	case strings.HasSuffix(p, "ies"):
+		variants = append(variants, strings.TrimSuffix(p, "ies")+"y")
+	case strings.HasSuffix(p, "s"):
+		variants = append(variants, strings.TrimSuffix(p, "s"))
+	case strings.HasSuffix(p, "y"):
+		variants = append(variants, strings.TrimSuffix(p, "y")+"ies")
+	default:
+		variants = append(variants, p+"s")
+	}
+	return variants
+}
+
+func normalizeQuestion(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+func containsPhrase(text, phrase string) bool {
+	return phrase != "" && strings.Contains(" "+text+" ", " "+phrase+" ")
+}
+
+func matchesAny(text string, phrases []string) bool {
+	for _, p := range phrases {
+		if containsPhrase(text, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func findField(mod *common_models.Entity, name string) *common_models.ModuleField {
+	for i := range mod.Fields {
+		if mod.Fields[i].Name == name {
+			return &mod.Fields[i]
+		}
+	}
+	return nil
+}
+
+func firstField(mod *common_models.Entity, t common_models.FieldType) *common_models.ModuleField {
+	for i := range mod.Fields {
+		if mod.Fields[i].Type == t {
+			return &mod.Fields[i]
+		}
+	}
+	return nil
+}
+
+// stageField is the select field that tracks open vs closed records
+func stageField(mod *common_models.Entity) *common_models.ModuleField {
+	for _, name := range []string{"stage", "status"} {
+		if f := findField(mod, name); f != nil && f.Type == common_models.FieldTypeSelect {
+			return f
+		}
+	}
+	return nil
+}
+
+func isNumericField(f *common_models.ModuleField) bool {
+	return f.Type == common_models.FieldTypeNumber || f.Type == common_models.FieldTypeCurrency
+}
+
+// isUserField reports whether name is a record's user reference that modules
+// do not declare
+func isUserField(name string) bool {
+	return name == "owner" || name == "created_by"
+}
+
+func hasGroup(spec *QuerySpec, name string) bool {
+	for _, g := range spec.GroupBy {
+		if g == name || strings.HasPrefix(g, name+":") {
+			return true
+		}
+	}
+	return false
+}
+
+func hasFilter(spec *QuerySpec, name string) bool {
+	for key := range spec.Filters {
+		if key == name || strings.HasPrefix(key, name+"__") {
+			return true
+		}
+	}
+	return false
+}
+
+func toNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func toTime(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case primitive.DateTime:
+		return t.Time(), true
+	}
+	return time.Time{}, false
+}
@@ -9,9 +9,15 @@ import (
 	"time"
 
 	common_models "go-crm/internal/common/models"
+	"go-crm/internal/common/watermark"
+	"go-crm/internal/database"
 	"go-crm/internal/features/audit"
 	"go-crm/internal/features/module"
 	"go-crm/internal/features/record"
+	"go-crm/internal/features/role"
+	"go-crm/internal/features/settings"
+	"go-crm/internal/features/user"
+	"go-crm/pkg/locale"
 
 	"github.com/xuri/excelize/v2"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -27,25 +33,69 @@ type ReportService interface {
 	RunPivotReport(ctx context.Context, config *PivotConfig, moduleName string, filters map[string]any, userID primitive.ObjectID) (interface{}, error)
 	RunCrossModuleReport(ctx context.Context, config *CrossModuleConfig, filters map[string]any, userID primitive.ObjectID) ([]map[string]any, error)
 	ExportReport(ctx context.Context, id string, format string, userID primitive.ObjectID) ([]byte, string, error)
-	ExportToExcel(ctx context.Context, data []map[string]any, columns []string, filename string) ([]byte, string, error)
+	// ExportToExcel writes rows the client already holds. With moduleName set,
+	// columns of fields hidden from the user are left out.
+	ExportToExcel(ctx context.Context, moduleName string, data []map[string]any, columns []string, filename string, userID primitive.ObjectID) ([]byte, string, error)
+	Query(ctx context.Context, req QueryRequest, userID primitive.ObjectID) (*QueryResult, error)
 }
 
 type ReportServiceImpl struct {
-	ReportRepo    ReportRepository
-	RecordService record.RecordService
-	ModuleService module.ModuleService
-	AuditService  audit.AuditService
+	ReportRepo      ReportRepository
+	RecordService   record.RecordService
+	ModuleService   module.ModuleService
+	AuditService    audit.AuditService
+	SettingsService settings.SettingsService
+	RoleService     role.RoleService
+	UserRepo        user.UserRepository
 }
 
-func NewReportService(reportRepo ReportRepository, recordService record.RecordService, moduleService module.ModuleService, auditService audit.AuditService) ReportService {
+func NewReportService(reportRepo ReportRepository, recordService record.RecordService, moduleService module.ModuleService, auditService audit.AuditService, settingsService settings.SettingsService, roleService role.RoleService, userRepo user.UserRepository) ReportService {
 	return &ReportServiceImpl{
-		ReportRepo:    reportRepo,
-		RecordService: recordService,
-		ModuleService: moduleService,
-		AuditService:  auditService,
+		ReportRepo:      reportRepo,
+		RecordService:   recordService,
+		ModuleService:   moduleService,
+		AuditService:    auditService,
+		SettingsService: settingsService,
+		RoleService:     roleService,
+		UserRepo:        userRepo,
 	}
 }
 
+// formatter returns the user's locale formatter, or en-US when settings are unavailable
+func (s *ReportServiceImpl) formatter(ctx context.Context, userID primitive.ObjectID) *locale.Formatter {
+	if s.SettingsService == nil {
+		return locale.Default()
+	}
+	id := ""
+	if !userID.IsZero() {
+		id = userID.Hex()
+	}
+	return s.SettingsService.Formatter(ctx, id)
+}
+
+// visibleColumns drops the columns of fields hidden from the user. Records
+// come back without those values already; this keeps the empty columns, and
+// with them the field names, out of the file.
+func (s *ReportServiceImpl) visibleColumns(ctx context.Context, moduleName string, columns []string, userID primitive.ObjectID) ([]string, error) {
+	if s.RoleService == nil {
+		return columns, nil
+	}
+	perms, err := s.RoleService.GetFieldPermissions(ctx, userID, moduleName)
+	if err != nil {
+		return nil, err
+	}
+	return role.VisibleColumns(perms, columns), nil
+}
+
+// watermark names the exporting user; a user that cannot be loaded is named by ID
+func (s *ReportServiceImpl) watermark(ctx context.Context, userID primitive.ObjectID) watermark.Mark {
+	var u *common_models.User
+	if s.UserRepo != nil {
+		u, _ = s.UserRepo.FindByID(ctx, userID.Hex())
+	}
+	return watermark.New(ctx, userID.Hex(), u)
+}
+
 func (s *ReportServiceImpl) CreateReport(ctx context.Context, report *Report) error {
 	if report.ID.IsZero() {
 		report.ID = primitive.NewObjectID()
@@ -93,7 +143,15 @@ func (s *ReportServiceImpl) DeleteReport(ctx context.Context, id string) error {
 	return err
 }
 
+// reportContext runs a report's queries with the configured read
+// preference even when a write request starts it; reports tolerate a little
+// replication lag
+func reportContext(ctx context.Context) context.Context {
+	return database.WithReadMode(ctx, database.ReadSecondary)
+}
+
 func (s *ReportServiceImpl) RunReport(ctx context.Context, id string, userID primitive.ObjectID) ([]map[string]any, error) {
+	ctx = reportContext(ctx)
 	report, err := s.GetReport(ctx, id)
 	if err != nil {
 		return nil, err
@@ -125,6 +183,7 @@ func (s *ReportServiceImpl) RunReport(ctx context.Context, id string, userID pri
 }
 
 func (s *ReportServiceImpl) ExportReport(ctx context.Context, id string, format string, userID primitive.ObjectID) ([]byte, string, error) {
+	ctx = reportContext(ctx)
 	if format != "csv" {
 		return nil, "", fmt.Errorf("unsupported format: %s", format)
 	}
@@ -150,27 +209,29 @@ func (s *ReportServiceImpl) ExportReport(ctx context.Context, id string, format
 			}
 		}
 	}
+	headers, err = s.visibleColumns(ctx, report.ModuleID, headers, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := writer.Write([]string{"# " + s.watermark(ctx, userID).String()}); err != nil {
+		return nil, "", err
+	}
 	if err := writer.Write(headers); err != nil {
 		return nil, "", err
 	}
 
+	fieldTypes := map[string]string{}
+	if mod, err := s.ModuleService.GetModuleByName(ctx, report.ModuleID, userID); err == nil {
+		for _, f := range mod.Fields {
+			fieldTypes[f.Name] = string(f.Type)
+		}
+	}
+	lf := s.formatter(ctx, userID)
+
 	for _, rec := range records {
 		var row []string
 		for _, col := range headers {
-			val := rec[col]
-			strVal := fmt.Sprintf("%v", val)
-			if mapVal, ok := val.(map[string]interface{}); ok {
-				if name, ok := mapVal["name"]; ok {
-					strVal = fmt.Sprintf("%v", name)
-				} else if originalName, ok := mapVal["original_filename"]; ok {
-					strVal = fmt.Sprintf("%v", originalName)
-				}
-			} else if tVal, ok := val.(time.Time); ok {
-				strVal = tVal.Format("2006-01-02 15:04:05")
-			} else if oid, ok := val.(primitive.ObjectID); ok {
-				strVal = oid.Hex()
-			}
-			row = append(row, strVal)
+			row = append(row, lf.Field(fieldTypes[col], rec[col]))
 		}
 		if err := writer.Write(row); err != nil {
 			return nil, "", err
@@ -187,6 +248,7 @@ func (s *ReportServiceImpl) ExportReport(ctx context.Context, id string, format
 }
 
 func (s *ReportServiceImpl) RunPivotReport(ctx context.Context, config *PivotConfig, moduleName string, filters map[string]any, userID primitive.ObjectID) (interface{}, error) {
+	ctx = reportContext(ctx)
 	records, _, err := s.RecordService.ListRecords(ctx, moduleName, s.convertFilters(filters), 1, 10000, "created_at", "desc", userID)
 	if err != nil {
 		return nil, err
@@ -226,6 +288,7 @@ func (s *ReportServiceImpl) RunPivotReport(ctx context.Context, config *PivotCon
 }
 
 func (s *ReportServiceImpl) RunCrossModuleReport(ctx context.Context, config *CrossModuleConfig, filters map[string]any, userID primitive.ObjectID) ([]map[string]any, error) {
+	ctx = reportContext(ctx)
 	if config.BaseModule == "" {
 		return nil, fmt.Errorf("base module is required")
 	}
@@ -292,10 +355,19 @@ func (s *ReportServiceImpl) RunCrossModuleReport(ctx context.Context, config *Cr
 	return result, nil
 }
 
-func (s *ReportServiceImpl) ExportToExcel(ctx context.Context, data []map[string]any, columns []string, filename string) ([]byte, string, error) {
+func (s *ReportServiceImpl) ExportToExcel(ctx context.Context, moduleName string, data []map[string]any, columns []string, filename string, userID primitive.ObjectID) ([]byte, string, error) {
 	f := excelize.NewFile()
 	defer f.Close()
 
+	mark := s.watermark(ctx, userID)
+	if err := f.SetDocProps(&excelize.DocProperties{
+		Creator:     mark.Author(),
+		Created:     mark.At.Format(time.RFC3339),
+		Description: mark.String(),
+	}); err != nil {
+		return nil, "", err
+	}
+
 	sheetName := "Report"
 	index, err := f.NewSheet(sheetName)
 	if err != nil {
@@ -308,37 +380,37 @@ func (s *ReportServiceImpl) ExportToExcel(ctx context.Context, data []map[string
 			columns = append(columns, k)
 		}
 	}
+	if moduleName != "" {
+		var err error
+		if columns, err = s.visibleColumns(ctx, moduleName, columns, userID); err != nil {
+			return nil, "", err
+		}
+	}
+
+	lf := s.formatter(ctx, userID)
 
 	headerStyle, _ := f.NewStyle(&excelize.Style{
 		Font: &excelize.Font{Bold: true},
 		Fill: excelize.Fill{Type: "pattern", Color: []string{"#E0E0E0"}, Pattern: 1},
 	})
 
+	// The watermark takes the first row, above the column names
+	f.SetCellValue(sheetName, "A1", mark.String())
 	for i, col := range columns {
-		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		cell, _ := excelize.CoordinatesToCellName(i+1, 2)
 		f.SetCellValue(sheetName, cell, col)
 		f.SetCellStyle(sheetName, cell, cell, headerStyle)
 	}
 
 	for rowIdx, record := range data {
 		for colIdx, col := range columns {
-			cell, _ := excelize.CoordinatesToCellName(colIdx+1, rowIdx+2)
-			val := record[col]
-			switch v := val.(type) {
-			case time.Time:
-				f.SetCellValue(sheetName, cell, v.Format("2006-01-02 15:04:05"))
-			case primitive.ObjectID:
-				f.SetCellValue(sheetName, cell, v.Hex())
-			case map[string]interface{}:
-				if name, ok := v["name"]; ok {
-					f.SetCellValue(sheetName, cell, fmt.Sprintf("%v", name))
-				} else if fname, ok := v["original_filename"]; ok {
-					f.SetCellValue(sheetName, cell, fmt.Sprintf("%v", fname))
-				} else {
-					f.SetCellValue(sheetName, cell, fmt.Sprintf("%v", v))
-				}
-			default:
+			cell, _ := excelize.CoordinatesToCellName(colIdx+1, rowIdx+3)
+			// Numbers stay numeric so spreadsheets can sum them
+			switch v := record[col].(type) {
+			case float64, int, int32, int64, bool:
 				f.SetCellValue(sheetName, cell, v)
+			default:
+				f.SetCellValue(sheetName, cell, lf.Value(v))
 			}
 		}
 	}
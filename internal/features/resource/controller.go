@@ -3,6 +3,8 @@ package resource
 import (
 	"context"
 
+	"go-crm/internal/common/session"
+
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -84,13 +86,12 @@ func (c *ResourceController) GetResourceMetadata(ctx *fiber.Ctx) error {
 	resourceName := ctx.Params("resource")
 	action := ctx.Query("action", "read")
 
-	// Get User ID from Locals (set by AuthMiddleware)
-	userID, ok := ctx.Locals("user_id").(string)
-	if !ok || userID == "" {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
 		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
-	metadata, err := c.service.GetResourceMetadata(ctx.UserContext(), resourceName, action, userID)
+	metadata, err := c.service.GetResourceMetadata(ctx.UserContext(), resourceName, action, userID.Hex())
 	if err != nil {
 		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -29,6 +29,9 @@ func (h *RoleApi) Setup(app *fiber.App) {
 	// Role CRUD - require "roles" module permissions
 	roles.Get("/", middleware.RequirePermission(h.roleService, "roles", "read"), h.controller.ListRoles)
 	roles.Post("/", middleware.RequirePermission(h.roleService, "roles", "create"), h.controller.CreateRole)
+	roles.Get("/templates", middleware.RequirePermission(h.roleService, "roles", "read"), h.controller.ListTemplates)
+	roles.Post("/templates/:key", middleware.RequirePermission(h.roleService, "roles", "create"), h.controller.InstantiateTemplate)
+	roles.Get("/:id/effective", middleware.RequirePermission(h.roleService, "roles", "read"), h.controller.GetEffectivePermissions)
 	roles.Get("/:id", middleware.RequirePermission(h.roleService, "roles", "read"), h.controller.GetRole)
 	roles.Put("/:id", middleware.RequirePermission(h.roleService, "roles", "update"), h.controller.UpdateRole)
 	roles.Delete("/:id", middleware.RequirePermission(h.roleService, "roles", "delete"), h.controller.DeleteRole)
@@ -0,0 +1,82 @@
+package role
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go-crm/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// decisionTTL bounds how long a cached decision can miss changes that do not
+// bump the permissions version, such as edits to a user's ABAC attributes
+const decisionTTL = 5 * time.Minute
+
+// maxDecisions triggers a sweep of expired entries
+const maxDecisions = 10000
+
+type decision struct {
+	value   any
+	version int64
+	expires time.Time
+}
+
+// decisionCache memoises authorization decisions per user permissions
+// version, so most requests skip the user, role and permission lookups
+type decisionCache struct {
+	mu      sync.RWMutex
+	entries map[string]decision
+}
+
+func (c *decisionCache) get(key string, version int64) (any, bool) {
+	c.mu.RLock()
+	d, ok := c.entries[key]
+	c.mu.RUnlock()
+	if !ok || d.version != version || time.Now().After(d.expires) {
+		return nil, false
+	}
+	return d.value, true
+}
+
+func (c *decisionCache) put(key string, version int64, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]decision{}
+	}
+	if len(c.entries) >= maxDecisions {
+		now := time.Now()
+		for k, d := range c.entries {
+			if now.After(d.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	c.entries[key] = decision{value: value, version: version, expires: time.Now().Add(decisionTTL)}
+}
+
+// decisionKey builds the cache key of a decision about the calling user and
+// returns the permissions version of the user and their roles. Decisions
+// about anyone else, or without a version service, are not cached.
+func (s *RoleServiceImpl) decisionKey(ctx context.Context, userID string, parts ...string) (string, int64, bool) {
+	if s.Versions == nil {
+		return "", 0, false
+	}
+	claims, ok := ctx.Value(utils.UserClaimsKey).(*utils.UserClaims)
+	if !ok || claims == nil || claims.UserID == "" || claims.UserID != userID {
+		return "", 0, false
+	}
+	key := claims.UserID + "|" + strings.Join(claims.RoleIDs, ",") + "|" + strings.Join(parts, "|")
+	return key, s.Versions.Current(ctx, append([]string{claims.UserID}, claims.RoleIDs...)...), true
+}
+
+// bumpVersion invalidates issued tokens and cached decisions of the role's
+// users, and those of the roles extending it
+func (s *RoleServiceImpl) bumpVersion(ctx context.Context, id primitive.ObjectID) {
+	if s.Versions != nil {
+		s.Versions.Bump(ctx, id.Hex())
+	}
+}
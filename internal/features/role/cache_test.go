@@ -0,0 +1,172 @@
+package role
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/authz"
+	"go-crm/internal/features/permission"
+	"go-crm/internal/features/user"
+	"go-crm/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// lookupDelay stands in for one database round trip
+const lookupDelay = 200 * time.Microsecond
+
+type stubUserRepo struct {
+	user.UserRepository
+	user  *common_models.User
+	calls int
+}
+
+func (r *stubUserRepo) FindByID(ctx context.Context, id string) (*common_models.User, error) {
+	r.calls++
+	time.Sleep(lookupDelay)
+	return r.user, nil
+}
+
+type stubRoleRepo struct {
+	RoleRepository
+	role *Role
+}
+
+func (r *stubRoleRepo) FindByID(ctx context.Context, id string) (*Role, error) {
+	time.Sleep(lookupDelay)
+	return r.role, nil
+}
+
+type stubPermissionService struct {
+	permission.PermissionService
+	perms []permission.Permission
+}
+
+func (s *stubPermissionService) GetPermissionsByRole(ctx context.Context, roleID string) ([]permission.Permission, error) {
+	time.Sleep(lookupDelay)
+	return s.perms, nil
+}
+
+type stubVersions struct {
+	version int64
+}
+
+func (v *stubVersions) Current(ctx context.Context, subjectIDs ...string) int64 { return v.version }
+func (v *stubVersions) Bump(ctx context.Context, subjectIDs ...string)          { v.version++ }
+func (v *stubVersions) SetDependents(fn authz.DependentsFunc)                   {}
+
+// newOwnerScopedService builds a role service whose only role reads leads the
+// user owns, the shape of filter the record list endpoint asks for
+func newOwnerScopedService(versions *stubVersions) (*RoleServiceImpl, *stubUserRepo, primitive.ObjectID) {
+	userID := primitive.NewObjectID()
+	roleID := primitive.NewObjectID()
+	users := &stubUserRepo{user: &common_models.User{ID: userID, TenantID: primitive.NewObjectID(), Roles: []primitive.ObjectID{roleID}}}
+	owned := &common_models.PermissionGroup{
+		Operator: "AND",
+		Rules: []common_models.PermissionRule{
+			{Field: "owner", Operator: "eq", Type: common_models.RuleTypeVariable, Value: "$user.id"},
+		},
+	}
+	perms := &stubPermissionService{perms: []permission.Permission{{
+		RoleID:   roleID,
+		Resource: permission.ResourceRef{Type: "module", ID: "leads"},
+		Actions:  map[string]common_models.ActionPermission{"read": {Allowed: true, Conditions: owned}},
+	}}}
+
+	svc := &RoleServiceImpl{
+		RoleRepo:          &stubRoleRepo{role: &Role{ID: roleID, Name: "Sales Rep"}},
+		UserRepo:          users,
+		PermissionService: perms,
+	}
+	if versions != nil {
+		svc.Versions = versions
+	}
+	return svc, users, userID
+}
+
+// callerContext authenticates the request as the user
+func callerContext(userID primitive.ObjectID) context.Context {
+	ctx := context.WithValue(context.Background(), common_models.TenantIDKey, primitive.NewObjectID().Hex())
+	return context.WithValue(ctx, utils.UserClaimsKey, &utils.UserClaims{UserID: userID.Hex()})
+}
+
+func TestGetAccessFilterCachedUntilVersionBump(t *testing.T) {
+	versions := &stubVersions{version: 1}
+	svc, users, userID := newOwnerScopedService(versions)
+	ctx := callerContext(userID)
+
+	first, err := svc.GetAccessFilter(ctx, userID, "leads", "read")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := svc.GetAccessFilter(ctx, userID, "leads", "read"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if users.calls != 1 {
+		t.Errorf("Expected one user lookup while the version is unchanged, got %d", users.calls)
+	}
+	if len(first) == 0 {
+		t.Error("Expected an owner-scoped filter")
+	}
+
+	versions.Bump(ctx)
+	if _, err := svc.GetAccessFilter(ctx, userID, "leads", "read"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if users.calls != 2 {
+		t.Errorf("Expected the filter to be rebuilt after a version bump, got %d lookups", users.calls)
+	}
+}
+
+func TestGetAccessFilterWithoutVersionsIsNotCached(t *testing.T) {
+	svc, users, userID := newOwnerScopedService(nil)
+	ctx := callerContext(userID)
+
+	for i := 0; i < 2; i++ {
+		if _, err := svc.GetAccessFilter(ctx, userID, "leads", "read"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if users.calls != 2 {
+		t.Errorf("Expected a lookup per call without a version service, got %d", users.calls)
+	}
+}
+
+func TestGetAccessFilterForAnotherUserIsNotCached(t *testing.T) {
+	svc, users, userID := newOwnerScopedService(&stubVersions{version: 1})
+	ctx := callerContext(primitive.NewObjectID())
+
+	for i := 0; i < 2; i++ {
+		if _, err := svc.GetAccessFilter(ctx, userID, "leads", "read"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if users.calls != 2 {
+		t.Errorf("Expected a lookup per call for a user other than the caller, got %d", users.calls)
+	}
+}
+
+// BenchmarkGetAccessFilter compares the per-request authorization cost of a
+// record list call with and without cached decisions:
+//
+//	go test ./internal/features/role -bench GetAccessFilter
+func BenchmarkGetAccessFilter(b *testing.B) {
+	b.Run("uncached", func(b *testing.B) {
+		svc, _, userID := newOwnerScopedService(nil)
+		ctx := callerContext(userID)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _ = svc.GetAccessFilter(ctx, userID, "leads", "read")
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		svc, _, userID := newOwnerScopedService(&stubVersions{version: 1})
+		ctx := callerContext(userID)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _ = svc.GetAccessFilter(ctx, userID, "leads", "read")
+		}
+	})
+}
@@ -1,6 +1,11 @@
 package role
 
 import (
+	"errors"
+
+	common_api "go-crm/internal/common/api"
+	"go-crm/internal/common/validation"
+
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -60,17 +65,19 @@ func (c *RoleController) GetRole(ctx *fiber.Ctx) error {
 // @Param        role  body      Role  true  "Role data"
 // @Success      201   {object}  Role
 // @Failure      400   {string}  string
+// @Failure      422   {object}  map[string]interface{}
 // @Failure      500   {string}  string
 // @Router       /roles [post]
 func (c *RoleController) CreateRole(ctx *fiber.Ctx) error {
 	var role Role
 	if err := ctx.BodyParser(&role); err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return common_api.InvalidBody(ctx, err)
 	}
 
 	createdRole, err := c.Service.CreateRole(ctx.UserContext(), &role)
+	if _, invalid := validation.As(err); invalid {
+		return common_api.Fail(ctx, fiber.StatusBadRequest, err)
+	}
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create role",
@@ -91,6 +98,7 @@ func (c *RoleController) CreateRole(ctx *fiber.Ctx) error {
 // @Success      200   {object}  Role
 // @Failure      400   {string}  string
 // @Failure      404   {string}  string
+// @Failure      422   {object}  map[string]interface{}
 // @Failure      500   {string}  string
 // @Router       /roles/{id} [put]
 func (c *RoleController) UpdateRole(ctx *fiber.Ctx) error {
@@ -98,12 +106,13 @@ func (c *RoleController) UpdateRole(ctx *fiber.Ctx) error {
 
 	var role Role
 	if err := ctx.BodyParser(&role); err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return common_api.InvalidBody(ctx, err)
 	}
 
 	if err := c.Service.UpdateRole(ctx.UserContext(), id, &role); err != nil {
+		if _, invalid := validation.As(err); invalid {
+			return common_api.Fail(ctx, fiber.StatusBadRequest, err)
+		}
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update role",
 		})
@@ -136,3 +145,89 @@ func (c *RoleController) DeleteRole(ctx *fiber.Ctx) error {
 		"message": "Role deleted successfully",
 	})
 }
+
+// ListTemplates godoc
+// @Summary      List role templates
+// @Description  Roles shipped with the product, such as Sales Rep, Support Agent and Read-Only Auditor, with the permissions a role made from them starts with
+// @Tags         roles
+// @Produce      json
+// @Success      200  {array}   RoleTemplate
+// @Router       /roles/templates [get]
+func (c *RoleController) ListTemplates(ctx *fiber.Ctx) error {
+	return ctx.JSON(c.Service.ListTemplates())
+}
+
+// InstantiateTemplate godoc
+// @Summary      Create a role from a template
+// @Description  Create a role and its permissions from a template. The role can then be customized like any other; with base_role_id it extends an existing role instead of standing alone.
+// @Tags         roles
+// @Accept       json
+// @Produce      json
+// @Param        key      path      string                      true  "Template key"
+// @Param        request  body      InstantiateTemplateRequest  false "Name and base role"
+// @Success      201      {object}  Role
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {string}  string
+// @Router       /roles/templates/{key} [post]
+func (c *RoleController) InstantiateTemplate(ctx *fiber.Ctx) error {
+	var req InstantiateTemplateRequest
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&req); err != nil {
+			return common_api.InvalidBody(ctx, err)
+		}
+	}
+
+	role, err := c.Service.InstantiateTemplate(ctx.UserContext(), ctx.Params("key"), req)
+	if errors.Is(err, ErrTemplateNotFound) {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if _, invalid := validation.As(err); invalid {
+		return common_api.Fail(ctx, fiber.StatusBadRequest, err)
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create role",
+		})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(role)
+}
+
+// GetEffectivePermissions godoc
+// @Summary      Get a role's effective permissions
+// @Description  The role's module and field permissions with those of the roles it extends applied
+// @Tags         roles
+// @Produce      json
+// @Param        id   path      string  true  "Role ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      404  {string}  string
+// @Failure      500  {string}  string
+// @Router       /roles/{id}/effective [get]
+func (c *RoleController) GetEffectivePermissions(ctx *fiber.Ctx) error {
+	role, err := c.Service.GetRoleByID(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Role not found",
+		})
+	}
+	permissions, err := c.Service.EffectivePermissions(ctx.UserContext(), role)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	fieldPermissions, err := c.Service.EffectiveFieldPermissions(ctx.UserContext(), role)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"role":              role,
+		"permissions":       permissions,
+		"field_permissions": fieldPermissions,
+	})
+}
@@ -0,0 +1,100 @@
+package role
+
+import (
+	"context"
+	"fmt"
+
+	common_models "go-crm/internal/common/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Grant sources
+const (
+	GrantSourceAdmin = "admin"
+	GrantSourceRole  = "role"
+	GrantSourceGroup = "group"
+)
+
+// AccessGrant is one permission that gives a user an action on a module's
+// records. Filter is the grant's conditions translated for the user; it is
+// nil for an unconditional grant.
+type AccessGrant struct {
+	Source     string                         `json:"source"`
+	Name       string                         `json:"name"` // Role or group name
+	Resource   string                         `json:"resource"`
+	Conditions *common_models.PermissionGroup `json:"conditions,omitempty"`
+	Filter     bson.M                         `json:"filter,omitempty"`
+	Error      string                         `json:"error,omitempty"` // Conditions that could not be translated grant nothing
+}
+
+// ExplainAccess lists the grants GetAccessFilter combines for the user, in
+// the same order and with the same matching rules, so each can be checked
+// against a record on its own
+func (s *RoleServiceImpl) ExplainAccess(ctx context.Context, userID primitive.ObjectID, moduleName string, action string) ([]AccessGrant, error) {
+	user, err := s.UserRepo.FindByID(ctx, userID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	userGroups := user.Groups
+	if userGroups == nil {
+		userGroups = []string{}
+	}
+	groups := s.memberGroups(ctx, userID)
+	contextData := groupContextData(PrepareContextData(userID, user.TenantID, userGroups), userID, userGroups, groups)
+
+	grant := func(source, name, resource string, p common_models.ActionPermission) AccessGrant {
+		g := AccessGrant{Source: source, Name: name, Resource: resource, Conditions: p.Conditions}
+		if p.Conditions != nil {
+			filter, err := TranslateConditions(p.Conditions, contextData)
+			if err != nil {
+				g.Error = err.Error()
+			} else {
+				g.Filter = filter
+			}
+		}
+		return g
+	}
+
+	var grants []AccessGrant
+	for _, roleID := range user.Roles {
+		role, err := s.RoleRepo.FindByID(ctx, roleID.Hex())
+		if err != nil {
+			role = nil
+		}
+		if role != nil && IsAdminRole(role.Name) {
+			return []AccessGrant{{Source: GrantSourceAdmin, Name: role.Name, Resource: "*"}}, nil
+		}
+
+		perms, err := s.rolePermissions(ctx, roleID, role)
+		if err != nil {
+			continue
+		}
+		name := roleID.Hex()
+		if role != nil {
+			name = role.Name
+		}
+		for _, p := range perms {
+			if !matchesModule(p.Resource.ID, moduleName) {
+				continue
+			}
+			if actionPerm, ok := p.Actions[action]; ok && actionPerm.Allowed {
+				grants = append(grants, grant(GrantSourceRole, name, p.Resource.ID, actionPerm))
+			}
+		}
+	}
+
+	for _, g := range groups {
+		for _, key := range []string{"*", moduleName, "crm." + moduleName} {
+			if p, ok := g.Permissions[key][action]; ok && p.Allowed {
+				grants = append(grants, grant(GrantSourceGroup, g.Name, key, p))
+			}
+		}
+	}
+	return grants, nil
+}
@@ -0,0 +1,121 @@
+package role
+
+import (
+	"context"
+
+	"go-crm/internal/common/apperr"
+	"go-crm/internal/features/permission"
+)
+
+// IsAdminRole reports whether a role name is one of the built-in full admin
+// roles, which bypass permission checks
+func IsAdminRole(name string) bool {
+	return name == "admin" || name == "Super Admin"
+}
+
+// HasAdminRole reports whether any of the role names is a full admin role
+func HasAdminRole(roleNames []string) bool {
+	for _, name := range roleNames {
+		if IsAdminRole(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *RoleServiceImpl) IsAdmin(roleNames []string) bool {
+	return HasAdminRole(roleNames)
+}
+
+// CheckGrantable makes sure the granter, holding the named roles, has every
+// module permission and admin scope of the roles being granted. Full admins
+// may grant any role; delegated admins may only hand out what they have.
+func (s *RoleServiceImpl) CheckGrantable(ctx context.Context, granterRoles []string, roleIDs []string) error {
+	if s.IsAdmin(granterRoles) {
+		return nil
+	}
+
+	var held []*Role
+	var heldPerms []permission.Permission
+	for _, name := range granterRoles {
+		r, err := s.RoleRepo.FindByName(ctx, name)
+		if err != nil || r == nil {
+			continue
+		}
+		perms, err := s.EffectivePermissions(ctx, r)
+		if err != nil {
+			return err
+		}
+		held = append(held, r)
+		heldPerms = append(heldPerms, perms...)
+	}
+
+	for _, id := range roleIDs {
+		r, err := s.RoleRepo.FindByID(ctx, id)
+		if err != nil || r == nil {
+			return apperr.NotFound("role %s not found", id)
+		}
+		if IsAdminRole(r.Name) {
+			return apperr.PermissionDenied("only admins can grant the %s role", r.Name)
+		}
+		if scope := uncoveredScope(r.Admin, held); scope != "" {
+			return apperr.PermissionDenied("role %s grants the %s admin scope, which you do not have", r.Name, scope)
+		}
+		perms, err := s.EffectivePermissions(ctx, r)
+		if err != nil {
+			return err
+		}
+		for _, p := range perms {
+			for action, ap := range p.Actions {
+				if ap.Allowed && !holdsAction(heldPerms, p.Resource.ID, action, ap.Conditions == nil) {
+					return apperr.PermissionDenied("role %s grants %s on %s, which you do not have", r.Name, action, p.Resource.ID)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// uncoveredScope returns the first of a role's delegated admin scopes that
+// none of the held roles has, or "" when all are covered
+func uncoveredScope(a AdminPermissions, held []*Role) string {
+	allows := func(scope, moduleName string) bool {
+		for _, r := range held {
+			if r.Admin.Allows(scope, moduleName) {
+				return true
+			}
+		}
+		return false
+	}
+	if a.Users && !allows(AdminScopeUsers, "") {
+		return AdminScopeUsers
+	}
+	if a.Roles && !allows(AdminScopeRoles, "") {
+		return AdminScopeRoles
+	}
+	for _, m := range a.Modules {
+		if !allows(AdminScopeModules, m) {
+			return AdminScopeModules
+		}
+	}
+	for _, m := range a.Automations {
+		if !allows(AdminScopeAutomations, m) {
+			return AdminScopeAutomations
+		}
+	}
+	return ""
+}
+
+// holdsAction reports whether the permissions allow the action on the
+// resource, unconditionally when the grant being checked is unconditional
+func holdsAction(perms []permission.Permission, resourceID, action string, unconditional bool) bool {
+	for _, p := range perms {
+		if p.Resource.ID != resourceID && p.Resource.ID != "*" {
+			continue
+		}
+		if ap, ok := p.Actions[action]; ok && ap.Allowed && (!unconditional || ap.Conditions == nil) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package role
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/permission"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type grantRoleRepo struct {
+	RoleRepository
+	roles []*Role
+}
+
+func (r *grantRoleRepo) FindByID(ctx context.Context, id string) (*Role, error) {
+	for _, role := range r.roles {
+		if role.ID.Hex() == id {
+			return role, nil
+		}
+	}
+	return nil, errors.New("role not found")
+}
+
+func (r *grantRoleRepo) FindByName(ctx context.Context, name string) (*Role, error) {
+	for _, role := range r.roles {
+		if role.Name == name {
+			return role, nil
+		}
+	}
+	return nil, errors.New("role not found")
+}
+
+type grantPermissions struct {
+	permission.PermissionService
+	byRole map[string][]permission.Permission
+}
+
+func (s *grantPermissions) GetPermissionsByRole(ctx context.Context, roleID string) ([]permission.Permission, error) {
+	return s.byRole[roleID], nil
+}
+
+func modulePermission(module string, actions map[string]common_models.ActionPermission) []permission.Permission {
+	return []permission.Permission{{Resource: permission.ResourceRef{Type: "module", ID: module}, Actions: actions}}
+}
+
+func TestCheckGrantable(t *testing.T) {
+	owned := &common_models.PermissionGroup{Operator: "AND", Rules: []common_models.PermissionRule{
+		{Field: "owner", Operator: "eq", Type: common_models.RuleTypeVariable, Value: "$user.id"},
+	}}
+	userAdmin := &Role{ID: primitive.NewObjectID(), Name: "User Admin", Admin: AdminPermissions{Users: true, Modules: []string{"leads"}}}
+	admin := &Role{ID: primitive.NewObjectID(), Name: "admin"}
+	readLeads := &Role{ID: primitive.NewObjectID(), Name: "Lead Reader"}
+	ownLeads := &Role{ID: primitive.NewObjectID(), Name: "Own Leads"}
+	allLeads := &Role{ID: primitive.NewObjectID(), Name: "All Leads"}
+	deleteLeads := &Role{ID: primitive.NewObjectID(), Name: "Lead Deleter"}
+	roleAdmin := &Role{ID: primitive.NewObjectID(), Name: "Role Admin", Admin: AdminPermissions{Roles: true}}
+	leadsAdmin := &Role{ID: primitive.NewObjectID(), Name: "Leads Admin", Admin: AdminPermissions{Modules: []string{"leads"}}}
+	modulesAdmin := &Role{ID: primitive.NewObjectID(), Name: "Modules Admin", Admin: AdminPermissions{Modules: []string{"*"}}}
+	extendsDeleter := &Role{ID: primitive.NewObjectID(), Name: "Extends Deleter", BaseRoleID: &deleteLeads.ID}
+
+	svc := &RoleServiceImpl{
+		RoleRepo: &grantRoleRepo{roles: []*Role{userAdmin, admin, readLeads, ownLeads, allLeads, deleteLeads, roleAdmin, leadsAdmin, modulesAdmin, extendsDeleter}},
+		PermissionService: &grantPermissions{byRole: map[string][]permission.Permission{
+			userAdmin.ID.Hex(): modulePermission("leads", map[string]common_models.ActionPermission{
+				"read":   {Allowed: true},
+				"update": {Allowed: true, Conditions: owned},
+			}),
+			readLeads.ID.Hex():   modulePermission("leads", map[string]common_models.ActionPermission{"read": {Allowed: true}}),
+			ownLeads.ID.Hex():    modulePermission("leads", map[string]common_models.ActionPermission{"update": {Allowed: true, Conditions: owned}}),
+			allLeads.ID.Hex():    modulePermission("leads", map[string]common_models.ActionPermission{"update": {Allowed: true}}),
+			deleteLeads.ID.Hex(): modulePermission("leads", map[string]common_models.ActionPermission{"delete": {Allowed: true}}),
+		}},
+	}
+
+	tests := []struct {
+		name    string
+		granter []string
+		roles   []*Role
+		allowed bool
+	}{
+		{"admin grants anything", []string{"admin"}, []*Role{admin, deleteLeads, modulesAdmin}, true},
+		{"held permissions", []string{"User Admin"}, []*Role{readLeads, ownLeads}, true},
+		{"held admin scopes", []string{"User Admin"}, []*Role{leadsAdmin}, true},
+		{"admin role", []string{"User Admin"}, []*Role{admin}, false},
+		{"action not held", []string{"User Admin"}, []*Role{deleteLeads}, false},
+		{"inherited action not held", []string{"User Admin"}, []*Role{extendsDeleter}, false},
+		{"unconditional over conditional", []string{"User Admin"}, []*Role{allLeads}, false},
+		{"roles scope not held", []string{"User Admin"}, []*Role{roleAdmin}, false},
+		{"all modules over one", []string{"User Admin"}, []*Role{modulesAdmin}, false},
+		{"unknown granter role", []string{"Nobody"}, []*Role{readLeads}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []string
+			for _, r := range tt.roles {
+				ids = append(ids, r.ID.Hex())
+			}
+			err := svc.CheckGrantable(context.Background(), tt.granter, ids)
+			if tt.allowed && err != nil {
+				t.Errorf("Expected the roles to be grantable, got %v", err)
+			}
+			if !tt.allowed && apperr.CodeOf(err) != apperr.CodePermissionDenied {
+				t.Errorf("Expected a permission denied error, got %v", err)
+			}
+		})
+	}
+}
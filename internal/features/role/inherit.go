@@ -0,0 +1,165 @@
+package role
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/common/validation"
+	"go-crm/internal/features/permission"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxRoleDepth bounds how many base roles a role can stack
+const maxRoleDepth = 5
+
+var ErrRoleCycle = errors.New("a role cannot extend itself or one of the roles extending it")
+
+// roleChain returns the role followed by its base roles, nearest first
+func (s *RoleServiceImpl) roleChain(ctx context.Context, role *Role) ([]*Role, error) {
+	chain := []*Role{role}
+	seen := map[primitive.ObjectID]bool{role.ID: true}
+	for current := role; current.BaseRoleID != nil; {
+		if seen[*current.BaseRoleID] {
+			return nil, ErrRoleCycle
+		}
+		if len(chain) > maxRoleDepth {
+			return nil, fmt.Errorf("role %s extends more than %d roles", role.Name, maxRoleDepth)
+		}
+		base, err := s.RoleRepo.FindByID(ctx, current.BaseRoleID.Hex())
+		if err != nil || base == nil {
+			return nil, fmt.Errorf("base role of %s not found", current.Name)
+		}
+		seen[base.ID] = true
+		chain = append(chain, base)
+		current = base
+	}
+	return chain, nil
+}
+
+// EffectivePermissions returns the permissions a role holds once its base
+// roles are applied. For each resource the role's own actions and field
+// rules replace those of its base, so an extending role can also take an
+// action away by setting it to not allowed.
+func (s *RoleServiceImpl) EffectivePermissions(ctx context.Context, role *Role) ([]permission.Permission, error) {
+	if role.BaseRoleID == nil {
+		return s.PermissionService.GetPermissionsByRole(ctx, role.ID.Hex())
+	}
+	chain, err := s.roleChain(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+
+	var order []string
+	merged := map[string]*permission.Permission{}
+	for i := len(chain) - 1; i >= 0; i-- {
+		perms, err := s.PermissionService.GetPermissionsByRole(ctx, chain[i].ID.Hex())
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range perms {
+			existing, ok := merged[p.Resource.ID]
+			if !ok {
+				copied := p
+				copied.RoleID = role.ID
+				copied.Actions = map[string]common_models.ActionPermission{}
+				copied.FieldRules = map[string]string{}
+				merged[p.Resource.ID] = &copied
+				order = append(order, p.Resource.ID)
+				existing = &copied
+			}
+			for action, ap := range p.Actions {
+				existing.Actions[action] = ap
+			}
+			for field, rule := range p.FieldRules {
+				existing.FieldRules[field] = rule
+			}
+		}
+	}
+
+	result := make([]permission.Permission, 0, len(order))
+	for _, resource := range order {
+		result = append(result, *merged[resource])
+	}
+	return result, nil
+}
+
+// EffectiveFieldPermissions returns the role's field permissions by module
+// with those of its base roles filled in
+func (s *RoleServiceImpl) EffectiveFieldPermissions(ctx context.Context, role *Role) (map[string]map[string]string, error) {
+	if role.BaseRoleID == nil {
+		return role.FieldPermissions, nil
+	}
+	chain, err := s.roleChain(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := map[string]map[string]string{}
+	for i := len(chain) - 1; i >= 0; i-- {
+		for module, fields := range chain[i].FieldPermissions {
+			if merged[module] == nil {
+				merged[module] = map[string]string{}
+			}
+			for field, rule := range fields {
+				merged[module][field] = rule
+			}
+		}
+	}
+	return merged, nil
+}
+
+// rolePermissions returns the effective permissions of a user's role, or the
+// permissions stored under its ID when the role itself cannot be read
+func (s *RoleServiceImpl) rolePermissions(ctx context.Context, roleID primitive.ObjectID, role *Role) ([]permission.Permission, error) {
+	if role == nil {
+		return s.PermissionService.GetPermissionsByRole(ctx, roleID.Hex())
+	}
+	return s.EffectivePermissions(ctx, role)
+}
+
+// validateBase checks that the base role exists and that extending it
+// neither forms a cycle nor stacks too many roles
+func (s *RoleServiceImpl) validateBase(ctx context.Context, role *Role) error {
+	if role.BaseRoleID == nil {
+		return nil
+	}
+	var errs validation.Errors
+	if base, err := s.RoleRepo.FindByID(ctx, role.BaseRoleID.Hex()); err != nil || base == nil {
+		errs.Add("base_role_id", validation.CodeInvalid, "base role not found")
+	} else if _, err := s.roleChain(ctx, role); err != nil {
+		errs.Add("base_role_id", validation.CodeNotAllowed, err.Error())
+	}
+	return errs.Err()
+}
+
+func (s *RoleServiceImpl) ExtendingRoleIDs(ctx context.Context, id string) []string {
+	roles, err := s.RoleRepo.List(ctx)
+	if err != nil {
+		return nil
+	}
+	var ids []string
+	for _, r := range roles {
+		if r.BaseRoleID != nil && r.BaseRoleID.Hex() == id {
+			ids = append(ids, r.ID.Hex())
+		}
+	}
+	return ids
+}
+
+// extendingRoles lists the roles that name id as their base
+func (s *RoleServiceImpl) extendingRoles(ctx context.Context, id primitive.ObjectID) ([]string, error) {
+	roles, err := s.RoleRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, r := range roles {
+		if r.BaseRoleID != nil && *r.BaseRoleID == id {
+			names = append(names, r.Name)
+		}
+	}
+	return names, nil
+}
@@ -22,6 +22,62 @@ const (
 	FieldPermNone      = "none"
 )
 
+// DealTeamMembersPath is where records list the users on their deal team. A
+// user allowed to read some records of a module also reads those they are on.
+const DealTeamMembersPath = "data.deal_team.user_id"
+
+// VisibleColumns returns columns without the fields perms hide. Read-only
+// fields are readable and stay, as they do in the record API; perms is the
+// result of GetFieldPermissions and may be nil.
+func VisibleColumns(perms map[string]string, columns []string) []string {
+	visible := make([]string, 0, len(columns))
+	for _, col := range columns {
+		if perms[col] != FieldPermNone {
+			visible = append(visible, col)
+		}
+	}
+	return visible
+}
+
+// Admin scopes that can be delegated to non-admin roles
+const (
+	AdminScopeUsers       = "users"
+	AdminScopeRoles       = "roles"
+	AdminScopeModules     = "modules"
+	AdminScopeAutomations = "automations"
+)
+
+// AdminPermissions delegates parts of administration to a role. Module lists
+// hold module names; "*" covers every module, including new ones.
+type AdminPermissions struct {
+	Users       bool     `json:"users" bson:"users"` // Manage users and their role assignments, not roles
+	Roles       bool     `json:"roles" bson:"roles"`
+	Modules     []string `json:"modules,omitempty" bson:"modules,omitempty"`         // Fields, layouts and translations
+	Automations []string `json:"automations,omitempty" bson:"automations,omitempty"` // Automation rules
+}
+
+// Allows reports whether the flags cover scope. An empty module name stands
+// for module-less actions such as creating a module, which need "*".
+func (a AdminPermissions) Allows(scope, moduleName string) bool {
+	var modules []string
+	switch scope {
+	case AdminScopeUsers:
+		return a.Users
+	case AdminScopeRoles:
+		return a.Roles
+	case AdminScopeModules:
+		modules = a.Modules
+	case AdminScopeAutomations:
+		modules = a.Automations
+	}
+	for _, m := range modules {
+		if m == "*" || (moduleName != "" && m == moduleName) {
+			return true
+		}
+	}
+	return false
+}
+
 // Role represents a user role with module-level permissions
 type Role struct {
 	ID          primitive.ObjectID                            `json:"id" bson:"_id,omitempty"`
@@ -34,9 +90,14 @@ type Role struct {
 	// For backward compat in code, "ModulePermissions" name removal implies updating all references.
 
 	FieldPermissions map[string]map[string]string `json:"field_permissions" bson:"field_permissions"` // Module -> Field -> "read_write" | "read_only" | "none"
-	IsSystem         bool                         `json:"is_system" bson:"is_system"`                 // Prevent deletion of system roles
-	CreatedAt        time.Time                    `json:"created_at" bson:"created_at"`
-	UpdatedAt        time.Time                    `json:"updated_at" bson:"updated_at"`
+	Admin            AdminPermissions             `json:"admin" bson:"admin"`                         // Delegated administration
+	// BaseRoleID is the role this one extends: it gets the base role's module
+	// and field permissions, with its own taking precedence
+	BaseRoleID *primitive.ObjectID `json:"base_role_id,omitempty" bson:"base_role_id,omitempty"`
+	Template   string              `json:"template,omitempty" bson:"template,omitempty"` // Key of the template the role was created from
+	IsSystem   bool                `json:"is_system" bson:"is_system"`                   // Prevent deletion of system roles
+	CreatedAt  time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at" bson:"updated_at"`
 }
 
 type Permission struct {
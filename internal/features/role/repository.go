@@ -133,6 +133,7 @@ func (r *RoleRepositoryImpl) Update(ctx context.Context, id string, role *Role)
 			"description":       role.Description,
 			"permissions":       role.Permissions,
 			"field_permissions": role.FieldPermissions,
+			"base_role_id":      role.BaseRoleID,
 			"updated_at":        role.UpdatedAt,
 		},
 	}
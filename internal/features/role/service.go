@@ -7,9 +7,13 @@ import (
 	"time"
 
 	common_models "go-crm/internal/common/models"
+	"go-crm/internal/common/validation"
 	"go-crm/internal/features/audit"
+	"go-crm/internal/features/authz"
+	"go-crm/internal/features/group"
 	"go-crm/internal/features/permission"
 	"go-crm/internal/features/user"
+	"go-crm/pkg/utils"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -27,6 +31,22 @@ type RoleService interface {
 	GetFieldPermissions(ctx context.Context, userID primitive.ObjectID, moduleName string) (map[string]string, error)
 	GetAccessFilter(ctx context.Context, userID primitive.ObjectID, moduleName string, action string) (bson.M, error)
 	CheckPermission(ctx context.Context, userID primitive.ObjectID, resourceID string, action string) (bool, error)
+	CheckAdminScope(ctx context.Context, roleNames []string, scope string, moduleName string) (bool, error)
+	// ExtendingRoleIDs lists the IDs of the roles naming id as their base
+	ExtendingRoleIDs(ctx context.Context, id string) []string
+	// IsAdmin reports whether any of the role names is a full admin role
+	IsAdmin(roleNames []string) bool
+	// CheckGrantable rejects roles granting more than the granter's roles hold
+	CheckGrantable(ctx context.Context, granterRoles []string, roleIDs []string) error
+	// EffectivePermissions and EffectiveFieldPermissions resolve a role's
+	// grants with those of the roles it extends
+	EffectivePermissions(ctx context.Context, role *Role) ([]permission.Permission, error)
+	EffectiveFieldPermissions(ctx context.Context, role *Role) (map[string]map[string]string, error)
+	ListTemplates() []RoleTemplate
+	// InstantiateTemplate creates a role, with its permissions, from a template
+	InstantiateTemplate(ctx context.Context, key string, req InstantiateTemplateRequest) (*Role, error)
+	// ExplainAccess lists the grants behind a user's access filter
+	ExplainAccess(ctx context.Context, userID primitive.ObjectID, moduleName string, action string) ([]AccessGrant, error)
 }
 
 type RoleServiceImpl struct {
@@ -34,6 +54,10 @@ type RoleServiceImpl struct {
 	UserRepo          user.UserRepository
 	AuditService      audit.AuditService
 	PermissionService permission.PermissionService
+	GroupRepo         group.GroupRepository
+	Versions          authz.VersionService
+
+	cache decisionCache
 }
 
 func NewRoleService(
@@ -41,17 +65,56 @@ func NewRoleService(
 	userRepo user.UserRepository,
 	auditService audit.AuditService,
 	permissionService permission.PermissionService,
+	groupRepo group.GroupRepository,
+	versions authz.VersionService,
 ) RoleService {
 	return &RoleServiceImpl{
 		RoleRepo:          roleRepo,
 		UserRepo:          userRepo,
 		AuditService:      auditService,
 		PermissionService: permissionService,
+		GroupRepo:         groupRepo,
+		Versions:          versions,
+	}
+}
+
+// validateRole checks a submitted role and reports every invalid entry
+func validateRole(role *Role) error {
+	var errs validation.Errors
+	if strings.TrimSpace(role.Name) == "" {
+		errs.Add("name", validation.CodeRequired, "role name is required")
+	}
+	for resource, actions := range role.Permissions {
+		if resource == "" {
+			errs.Add("permissions", validation.CodeInvalid, "permission resource must not be empty")
+		}
+		for action := range actions {
+			if action == "" {
+				errs.Add("permissions."+resource, validation.CodeInvalid, fmt.Sprintf("empty action for resource '%s'", resource))
+			}
+		}
+	}
+	for module, fields := range role.FieldPermissions {
+		for field, perm := range fields {
+			switch perm {
+			case FieldPermReadWrite, FieldPermReadOnly, FieldPermNone:
+			default:
+				errs.Add("field_permissions."+module+"."+field, validation.CodeNotAllowed,
+					fmt.Sprintf("field permission for '%s.%s' must be %s, %s or %s", module, field, FieldPermReadWrite, FieldPermReadOnly, FieldPermNone))
+			}
+		}
 	}
+	return errs.Err()
 }
 
 func (s *RoleServiceImpl) CreateRole(ctx context.Context, role *Role) (*Role, error) {
+	if err := validateRole(role); err != nil {
+		return nil, err
+	}
 	role.ID = primitive.NewObjectID()
+	if err := s.validateBase(ctx, role); err != nil {
+		return nil, err
+	}
 	role.CreatedAt = time.Now()
 	role.UpdatedAt = time.Now()
 
@@ -83,6 +146,15 @@ func (s *RoleServiceImpl) ListRoles(ctx context.Context) ([]Role, error) {
 }
 
 func (s *RoleServiceImpl) UpdateRole(ctx context.Context, id string, role *Role) error {
+	if err := validateRole(role); err != nil {
+		return err
+	}
+	if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+		role.ID = oid
+	}
+	if err := s.validateBase(ctx, role); err != nil {
+		return err
+	}
 	role.UpdatedAt = time.Now()
 
 	if err := s.RoleRepo.Update(ctx, id, role); err != nil {
@@ -90,8 +162,10 @@ func (s *RoleServiceImpl) UpdateRole(ctx context.Context, id string, role *Role)
 	}
 
 	_ = s.AuditService.LogChange(ctx, common_models.AuditActionUpdate, "role", id, map[string]common_models.Change{
-		"permissions": {New: role.Permissions},
+		"permissions":  {New: role.Permissions},
+		"base_role_id": {New: role.BaseRoleID},
 	})
+	s.bumpVersion(ctx, role.ID)
 
 	return nil
 }
@@ -108,6 +182,13 @@ func (s *RoleServiceImpl) DeleteRole(ctx context.Context, id string) error {
 	if role.IsSystem {
 		return fmt.Errorf("cannot delete system role")
 	}
+	extending, err := s.extendingRoles(ctx, role.ID)
+	if err != nil {
+		return err
+	}
+	if len(extending) > 0 {
+		return fmt.Errorf("cannot delete a role other roles extend: %s", strings.Join(extending, ", "))
+	}
 
 	if err := s.RoleRepo.Delete(ctx, id); err != nil {
 		return err
@@ -116,6 +197,7 @@ func (s *RoleServiceImpl) DeleteRole(ctx context.Context, id string) error {
 	_ = s.AuditService.LogChange(ctx, common_models.AuditActionDelete, "role", id, map[string]common_models.Change{
 		"name": {Old: role.Name},
 	})
+	s.bumpVersion(ctx, role.ID)
 
 	return nil
 }
@@ -137,6 +219,25 @@ func (s *RoleServiceImpl) GetPermissionsForRoles(ctx context.Context, roleIDHexe
 }
 
 func (s *RoleServiceImpl) CheckModulePermission(ctx context.Context, roleNames []string, moduleName string, permission string) (bool, error) {
+	// Group grants depend on the user, so the caller is part of the key
+	caller := ""
+	if claims, ok := ctx.Value(utils.UserClaimsKey).(*utils.UserClaims); ok && claims != nil {
+		caller = claims.UserID
+	}
+	key, version, cacheable := s.decisionKey(ctx, caller, "module", strings.Join(roleNames, ","), moduleName, permission)
+	if cacheable {
+		if v, ok := s.cache.get(key, version); ok {
+			return v.(bool), nil
+		}
+	}
+	allowed, err := s.checkModulePermission(ctx, roleNames, moduleName, permission)
+	if err == nil && cacheable {
+		s.cache.put(key, version, allowed)
+	}
+	return allowed, err
+}
+
+func (s *RoleServiceImpl) checkModulePermission(ctx context.Context, roleNames []string, moduleName string, permission string) (bool, error) {
 	// Legacy method relied on roleNames, but new system relies on UserID for effective permissions
 	// Extract userID from context if available (AuthMiddleware usually puts "user_id" in Locals, need fiber context?)
 	// But this is service layer, relying on context values passed from controller/middleware.
@@ -152,7 +253,12 @@ func (s *RoleServiceImpl) CheckModulePermission(ctx context.Context, roleNames [
 		if err != nil {
 			return false, err
 		}
-		perms, err := s.PermissionService.GetPermissionsByRole(ctx, role.ID.Hex())
+		// Delegated user admins can see roles in order to assign them
+		if (moduleName == AdminScopeUsers && role.Admin.Users) ||
+			(moduleName == AdminScopeRoles && (role.Admin.Roles || (role.Admin.Users && permission == "read"))) {
+			return true, nil
+		}
+		perms, err := s.EffectivePermissions(ctx, role)
 		if err != nil {
 			return false, err
 		}
@@ -175,7 +281,7 @@ func (s *RoleServiceImpl) CheckModulePermission(ctx context.Context, roleNames [
 
 	for _, name := range roleNames {
 		// Check for Super Admin bypass in role name
-		if name == "Super Admin" || name == "admin" {
+		if IsAdminRole(name) {
 			return true, nil
 		}
 		allowed, err := checkRole(name)
@@ -184,7 +290,7 @@ func (s *RoleServiceImpl) CheckModulePermission(ctx context.Context, roleNames [
 		}
 	}
 
-	return false, nil
+	return s.groupsAllow(ctx, strings.TrimPrefix(moduleName, "crm."), permission), nil
 }
 
 // ... GetFieldPermissions ...
@@ -216,9 +322,13 @@ func (s *RoleServiceImpl) GetFieldPermissions(ctx context.Context, userID primit
 		if err != nil || role == nil {
 			continue
 		}
+		fieldPermissions, err := s.EffectiveFieldPermissions(ctx, role)
+		if err != nil {
+			continue
+		}
 
-		if role.FieldPermissions != nil {
-			if modPerms, ok := role.FieldPermissions[moduleName]; ok {
+		if fieldPermissions != nil {
+			if modPerms, ok := fieldPermissions[moduleName]; ok {
 				hasFieldRules = true
 				for field, p := range modPerms {
 					current, exists := finalPerms[field]
@@ -238,7 +348,7 @@ func (s *RoleServiceImpl) GetFieldPermissions(ctx context.Context, userID primit
 			}
 		}
 
-		if role.FieldPermissions == nil || role.FieldPermissions[moduleName] == nil {
+		if fieldPermissions == nil || fieldPermissions[moduleName] == nil {
 			return nil, nil // Full access
 		}
 	}
@@ -250,7 +360,46 @@ func (s *RoleServiceImpl) GetFieldPermissions(ctx context.Context, userID primit
 	return finalPerms, nil
 }
 
+// CheckAdminScope reports whether any of the roles is a full admin or has been
+// delegated the given admin scope for the module
+func (s *RoleServiceImpl) CheckAdminScope(ctx context.Context, roleNames []string, scope string, moduleName string) (bool, error) {
+	for _, name := range roleNames {
+		if IsAdminRole(name) {
+			return true, nil
+		}
+		role, err := s.RoleRepo.FindByName(ctx, name)
+		if err != nil {
+			continue
+		}
+		if role.Admin.Allows(scope, moduleName) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (s *RoleServiceImpl) GetAccessFilter(ctx context.Context, userID primitive.ObjectID, moduleName string, action string) (primitive.M, error) {
+	key, version, cacheable := s.decisionKey(ctx, userID.Hex(), "filter", moduleName, action)
+	if cacheable {
+		if v, ok := s.cache.get(key, version); ok {
+			return v.(primitive.M), nil
+		}
+	}
+	filter, err := s.accessFilter(ctx, userID, moduleName, action)
+	if err == nil && cacheable {
+		s.cache.put(key, version, filter)
+	}
+	return filter, err
+}
+
+// matchesModule reports whether a permission's resource covers a module's
+// records: the wildcard, the module name, or its "crm."-prefixed resource ID
+// as the seeded permissions and role templates use
+func matchesModule(resourceID, moduleName string) bool {
+	return resourceID == "*" || resourceID == moduleName || resourceID == "crm."+moduleName
+}
+
+func (s *RoleServiceImpl) accessFilter(ctx context.Context, userID primitive.ObjectID, moduleName string, action string) (primitive.M, error) {
 	// 1. Get User
 	user, err := s.UserRepo.FindByID(ctx, userID.Hex())
 	if err != nil {
@@ -269,24 +418,28 @@ func (s *RoleServiceImpl) GetAccessFilter(ctx context.Context, userID primitive.
 	if userGroups == nil {
 		userGroups = []string{}
 	}
-	contextData := PrepareContextData(userID, orgID, userGroups)
+	groups := s.memberGroups(ctx, userID)
+	contextData := groupContextData(PrepareContextData(userID, orgID, userGroups), userID, userGroups, groups)
 
 	for _, roleID := range user.Roles {
 		// Check Admin Bypass (Optional, but safe)
 		role, err := s.RoleRepo.FindByID(ctx, roleID.Hex())
-		if err == nil && (role.Name == "admin" || role.Name == "Super Admin") {
+		if err == nil && IsAdminRole(role.Name) {
 			return primitive.M{}, nil // Full Access
 		}
+		if err != nil {
+			role = nil
+		}
 
-		// Fetch Permissions from Service (Source of Truth)
-		perms, err := s.PermissionService.GetPermissionsByRole(ctx, roleID.Hex())
+		// Fetch Permissions from Service (Source of Truth), with base roles applied
+		perms, err := s.rolePermissions(ctx, roleID, role)
 		if err != nil {
 			continue
 		}
 
 		for _, p := range perms {
 			// Check Wildcard or Specific Resource
-			if p.Resource.ID == "*" || p.Resource.ID == moduleName {
+			if matchesModule(p.Resource.ID, moduleName) {
 				if actionPerm, ok := p.Actions[action]; ok && actionPerm.Allowed {
 					if actionPerm.Conditions == nil {
 						hasFullAccess = true
@@ -301,6 +454,18 @@ func (s *RoleServiceImpl) GetAccessFilter(ctx context.Context, userID primitive.
 		}
 	}
 
+	// Permissions granted to the user's groups add to those of the roles
+	for _, p := range groupPermission(groups, moduleName, action) {
+		if p.Conditions == nil {
+			hasFullAccess = true
+			continue
+		}
+		cond, err := TranslateConditions(p.Conditions, contextData)
+		if err == nil {
+			orConditions = append(orConditions, cond)
+		}
+	}
+
 	if hasFullAccess {
 		return primitive.M{}, nil
 	}
@@ -309,6 +474,11 @@ func (s *RoleServiceImpl) GetAccessFilter(ctx context.Context, userID primitive.
 		return primitive.M{"_id": -1}, nil
 	}
 
+	// Deal team members read the records they are on beyond their conditions
+	if action == "read" {
+		orConditions = append(orConditions, primitive.M{DealTeamMembersPath: userID.Hex()})
+	}
+
 	if len(orConditions) == 1 {
 		return orConditions[0], nil
 	}
@@ -317,25 +487,35 @@ func (s *RoleServiceImpl) GetAccessFilter(ctx context.Context, userID primitive.
 }
 
 func (s *RoleServiceImpl) CheckPermission(ctx context.Context, userID primitive.ObjectID, resourceID string, action string) (bool, error) {
-	// Use PermissionService to get effective permissions (RBAC/ABAC source of truth)
-	effectivePerms, err := s.PermissionService.GetUserEffectivePermissions(ctx, userID)
+	user, err := s.UserRepo.FindByID(ctx, userID.Hex())
 	if err != nil {
 		return false, err
 	}
-
-	// 1. Check Wildcard Resource "*"
-	if wildPerm, ok := effectivePerms["*"]; ok {
-		if p, ok := wildPerm.Actions[action]; ok && p.Allowed {
-			return true, nil
-		}
+	if user == nil {
+		return false, fmt.Errorf("user not found")
 	}
 
-	// 2. Check Specific Resource
-	if resPerm, ok := effectivePerms[resourceID]; ok {
-		if p, ok := resPerm.Actions[action]; ok && p.Allowed {
-			return true, nil
+	// 1. Check the Wildcard Resource "*" or the Specific Resource on each
+	// role, with its base roles applied
+	for _, roleID := range user.Roles {
+		role, err := s.RoleRepo.FindByID(ctx, roleID.Hex())
+		if err != nil {
+			role = nil
+		}
+		perms, err := s.rolePermissions(ctx, roleID, role)
+		if err != nil {
+			continue
+		}
+		for _, perm := range perms {
+			if perm.Resource.ID != "*" && perm.Resource.ID != resourceID {
+				continue
+			}
+			if p, ok := perm.Actions[action]; ok && p.Allowed {
+				return true, nil
+			}
 		}
 	}
 
-	return false, nil
+	// 2. Check Group Grants
+	return len(groupPermission(s.memberGroups(ctx, userID), resourceID, action)) > 0, nil
 }
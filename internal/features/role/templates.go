@@ -0,0 +1,125 @@
+package role
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/common/validation"
+	"go-crm/internal/features/permission"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//go:embed data/templates.json
+var templateFS embed.FS
+
+var ErrTemplateNotFound = errors.New("role template not found")
+
+// TemplatePermission is a grant a template gives the roles made from it
+type TemplatePermission struct {
+	Resource permission.ResourceRef                    `json:"resource"`
+	Actions  map[string]common_models.ActionPermission `json:"actions"`
+}
+
+// RoleTemplate is a role shipped with the product that tenants instantiate
+// and then customize like any other role
+type RoleTemplate struct {
+	Key              string                       `json:"key"`
+	Name             string                       `json:"name"`
+	Description      string                       `json:"description"`
+	Permissions      []TemplatePermission         `json:"permissions"`
+	FieldPermissions map[string]map[string]string `json:"field_permissions,omitempty"`
+}
+
+type InstantiateTemplateRequest struct {
+	Name        string `json:"name"` // Defaults to the template name
+	Description string `json:"description"`
+	// BaseRoleID makes the new role extend an existing one; the template's
+	// grants then override the base role's
+	BaseRoleID *primitive.ObjectID `json:"base_role_id,omitempty"`
+}
+
+var roleTemplates = mustTemplates()
+
+func mustTemplates() []RoleTemplate {
+	b, err := templateFS.ReadFile("data/templates.json")
+	if err != nil {
+		panic(err)
+	}
+	var templates []RoleTemplate
+	if err := json.Unmarshal(b, &templates); err != nil {
+		panic(err)
+	}
+	return templates
+}
+
+func (s *RoleServiceImpl) ListTemplates() []RoleTemplate {
+	return roleTemplates
+}
+
+func (s *RoleServiceImpl) InstantiateTemplate(ctx context.Context, key string, req InstantiateTemplateRequest) (*Role, error) {
+	var tmpl *RoleTemplate
+	for i := range roleTemplates {
+		if roleTemplates[i].Key == key {
+			tmpl = &roleTemplates[i]
+		}
+	}
+	if tmpl == nil {
+		return nil, ErrTemplateNotFound
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = tmpl.Name
+	}
+	description := req.Description
+	if description == "" {
+		description = tmpl.Description
+	}
+	if existing, err := s.RoleRepo.FindByName(ctx, name); err == nil && existing != nil {
+		var errs validation.Errors
+		errs.Add("name", validation.CodeDuplicate, "a role named '"+name+"' already exists")
+		return nil, errs.Err()
+	}
+
+	role := &Role{
+		Name:             name,
+		Description:      description,
+		FieldPermissions: tmpl.FieldPermissions,
+		BaseRoleID:       req.BaseRoleID,
+		Template:         tmpl.Key,
+	}
+	created, err := s.CreateRole(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+
+	// A role left without its grants would look instantiated, so a failed
+	// grant removes the role again
+	var granted []string
+	for _, p := range tmpl.Permissions {
+		actions := make(map[string]common_models.ActionPermission, len(p.Actions))
+		for action, ap := range p.Actions {
+			actions[action] = ap
+		}
+		perm, err := s.PermissionService.CreatePermission(ctx, &permission.Permission{
+			TenantID: created.TenantID,
+			RoleID:   created.ID,
+			Resource: p.Resource,
+			Actions:  actions,
+		})
+		if err != nil {
+			for _, id := range granted {
+				_ = s.PermissionService.DeletePermission(ctx, id)
+			}
+			_ = s.RoleRepo.Delete(ctx, created.ID.Hex())
+			return nil, err
+		}
+		granted = append(granted, perm.ID.Hex())
+	}
+	return created, nil
+}
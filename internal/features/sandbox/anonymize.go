@@ -0,0 +1,168 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go-crm/internal/common/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// anonymizeBatchSize is how many records a job reads and rewrites at a time
+const anonymizeBatchSize = 500
+
+func (s *SandboxServiceImpl) AnonymizeSandbox(ctx context.Context, sandboxID string) (*AnonymizeJob, error) {
+	claims, err := requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prodID, err := currentTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sandbox, err := s.OrganizationRepo.FindByID(ctx, sandboxID)
+	if err != nil || sandbox.SandboxOf == nil || *sandbox.SandboxOf != prodID {
+		return nil, errors.New("sandbox not found")
+	}
+
+	job := &AnonymizeJob{
+		TenantID:    prodID,
+		SandboxID:   sandbox.ID,
+		Status:      AnonymizeQueued,
+		Records:     map[string]int{},
+		RequestedBy: claims.UserID,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.Repo.CreateAnonymizeJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	_ = s.AuditService.LogChange(ctx, models.AuditActionUpdate, "sandbox", sandbox.ID.Hex(), map[string]models.Change{
+		"anonymize": {New: job.ID.Hex()},
+	})
+
+	go func() {
+		if err := s.runAnonymizeJob(context.Background(), job); err != nil {
+			log.Printf("anonymize sandbox %s: %v", sandbox.ID.Hex(), err)
+		}
+	}()
+	return job, nil
+}
+
+func (s *SandboxServiceImpl) GetAnonymizeJob(ctx context.Context, sandboxID, jobID string) (*AnonymizeJob, error) {
+	prodID, err := currentTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		return nil, err
+	}
+	job, err := s.Repo.GetAnonymizeJob(ctx, prodID, oid)
+	if err != nil {
+		return nil, err
+	}
+	if job.SandboxID.Hex() != sandboxID {
+		return nil, errors.New("anonymize job not found")
+	}
+	return job, nil
+}
+
+func (s *SandboxServiceImpl) RunAnonymize(ctx context.Context, sandboxID primitive.ObjectID, requestedBy string) (*AnonymizeJob, error) {
+	sandbox, err := s.OrganizationRepo.FindByID(ctx, sandboxID.Hex())
+	if err != nil {
+		return nil, errors.New("organization not found")
+	}
+	if sandbox.SandboxOf == nil {
+		return nil, fmt.Errorf("%s is not a sandbox", sandbox.Name)
+	}
+
+	job := &AnonymizeJob{
+		TenantID:    *sandbox.SandboxOf,
+		SandboxID:   sandbox.ID,
+		Status:      AnonymizeQueued,
+		Records:     map[string]int{},
+		RequestedBy: requestedBy,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.Repo.CreateAnonymizeJob(ctx, job); err != nil {
+		return nil, err
+	}
+	err = s.runAnonymizeJob(ctx, job)
+	return job, err
+}
+
+// runAnonymizeJob rewrites every record of the sandbox with one anonymizer,
+// so a value repeated across records and modules keeps a single fake. The
+// job's progress is saved after each module.
+func (s *SandboxServiceImpl) runAnonymizeJob(ctx context.Context, job *AnonymizeJob) error {
+	started := time.Now()
+	job.Status = AnonymizeRunning
+	job.StartedAt = &started
+	_ = s.Repo.UpdateAnonymizeJob(ctx, job)
+
+	err := s.anonymizeRecords(ctx, job)
+	completed := time.Now()
+	job.CompletedAt = &completed
+	job.Status = AnonymizeCompleted
+	if err != nil {
+		job.Status = AnonymizeFailed
+		job.Error = err.Error()
+	}
+	if saveErr := s.Repo.UpdateAnonymizeJob(ctx, job); saveErr != nil && err == nil {
+		err = saveErr
+	}
+	return err
+}
+
+func (s *SandboxServiceImpl) anonymizeRecords(ctx context.Context, job *AnonymizeJob) error {
+	entities, err := s.Repo.FindByTenant(ctx, "entities", job.SandboxID)
+	if err != nil {
+		return fmt.Errorf("read entities: %w", err)
+	}
+
+	anonymizer := NewAnonymizer(nil)
+	for _, entity := range entities {
+		moduleName, _ := entity["name"].(string)
+		fields := moduleFields(entity)
+
+		var after primitive.ObjectID
+		for {
+			records, err := s.Repo.RecordsAfter(ctx, job.SandboxID, moduleName, after, anonymizeBatchSize)
+			if err != nil {
+				return fmt.Errorf("read %s: %w", moduleName, err)
+			}
+			if len(records) == 0 {
+				break
+			}
+
+			updates := make(map[primitive.ObjectID]bson.M, len(records))
+			for _, rec := range records {
+				id, _ := rec["_id"].(primitive.ObjectID)
+				after = id
+				data, ok := rec["data"].(bson.M)
+				if !ok {
+					continue
+				}
+				anonymizer.Record(data, fields, moduleName)
+				updates[id] = data
+			}
+			if err := s.Repo.SetRecordData(ctx, job.SandboxID, updates); err != nil {
+				return fmt.Errorf("write %s: %w", moduleName, err)
+			}
+			job.Records[moduleName] += len(updates)
+			job.Processed += len(updates)
+
+			if len(records) < anonymizeBatchSize {
+				break
+			}
+		}
+		_ = s.Repo.UpdateAnonymizeJob(ctx, job)
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+package sandbox
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"go-crm/internal/common/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Anonymizer replaces personal values in records with realistic fakes. A
+// value always gets the same fake within one run, so duplicates, values
+// repeated across modules and email domains shared by a company's contacts
+// stay consistent, and lookups, numbers, dates and picklists are left as
+// they are. Each run uses a fresh salt so fakes cannot be reversed by
+// hashing guesses.
+type Anonymizer struct {
+	salt []byte
+}
+
+// NewAnonymizer returns an anonymizer keyed by salt; a nil salt is random
+func NewAnonymizer(salt []byte) *Anonymizer {
+	if salt == nil {
+		salt = make([]byte, 32)
+		rand.Read(salt)
+	}
+	return &Anonymizer{salt: salt}
+}
+
+var (
+	fakeFirstNames = []string{
+		"James", "Mary", "Robert", "Patricia", "John", "Jennifer", "Michael", "Linda", "David", "Elizabeth",
+		"William", "Barbara", "Richard", "Susan", "Joseph", "Jessica", "Thomas", "Sarah", "Carlos", "Karen",
+		"Daniel", "Lisa", "Matthew", "Nancy", "Anthony", "Sandra", "Mark", "Ashley", "Priya", "Emily",
+		"Wei", "Aisha", "Kenji", "Sofia", "Omar", "Elena", "Raj", "Fatima", "Lucas", "Mei",
+	}
+	fakeLastNames = []string{
+		"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis", "Rodriguez", "Martinez",
+		"Hernandez", "Lopez", "Gonzalez", "Wilson", "Anderson", "Thomas", "Taylor", "Moore", "Jackson", "Martin",
+		"Lee", "Perez", "Thompson", "White", "Harris", "Sanchez", "Clark", "Ramirez", "Lewis", "Robinson",
+		"Walker", "Young", "Allen", "King", "Wright", "Scott", "Nguyen", "Patel", "Kim", "Chen",
+	}
+	companyWords = []string{
+		"Apex", "Blue", "Cedar", "Delta", "Echo", "Falcon", "Granite", "Harbor", "Iron", "Juniper",
+		"Keystone", "Lumen", "Maple", "Nova", "Orbit", "Pioneer", "Quartz", "Ridge", "Summit", "Terra",
+		"Union", "Vertex", "Willow", "Zenith", "Bright", "Crest", "North", "Silver", "Oak", "Pacific",
+	}
+	companyKinds    = []string{"Systems", "Labs", "Group", "Partners", "Industries", "Solutions", "Works", "Logistics", "Health", "Foods"}
+	companySuffixes = []string{"Inc", "LLC", "Ltd", "Co", "Corp"}
+	fakeStreets     = []string{"Main", "Oak", "Pine", "Maple", "Cedar", "Elm", "Washington", "Lake", "Hill", "Park", "River", "Sunset", "Highland", "Church", "Mill"}
+	streetSuffixes  = []string{"St", "Ave", "Rd", "Blvd", "Ln", "Dr", "Way", "Ct"}
+	fakeCities      = []string{"Springfield", "Riverside", "Fairview", "Franklin", "Greenville", "Bristol", "Clinton", "Georgetown", "Salem", "Madison", "Ashland", "Milton", "Oxford", "Arlington", "Burlington"}
+	loremWords      = []string{"lorem", "ipsum", "dolor", "sit", "amet", "consectetur", "adipiscing", "elit", "sed", "do", "eiusmod", "tempor", "incididunt", "ut", "labore", "et", "dolore", "magna", "aliqua", "enim"}
+)
+
+// publicMailDomains keep their provider name, under the reserved .example
+// TLD, so the share of free-mail contacts survives
+var publicMailDomains = []string{"gmail.com", "googlemail.com", "yahoo.com", "outlook.com", "hotmail.com", "live.com", "icloud.com", "aol.com", "proton.me", "protonmail.com", "gmx.com", "mail.com"}
+
+// maxFakeWords caps the text written for a long free-text value
+const maxFakeWords = 200
+
+type personalKind int
+
+const (
+	kindNone personalKind = iota
+	kindFirstName
+	kindLastName
+	kindFullName
+	kindCompany
+	kindStreet
+	kindCity
+	kindPostalCode
+)
+
+// textKind guesses what a text field holds from its name. Name fields of
+// account-like modules hold company names.
+func textKind(moduleName, field string) personalKind {
+	lower := strings.ToLower(field)
+	switch {
+	case strings.Contains(lower, "first"):
+		return kindFirstName
+	case strings.Contains(lower, "last") || strings.Contains(lower, "surname"):
+		return kindLastName
+	case strings.Contains(lower, "company") || strings.Contains(lower, "account") || strings.Contains(lower, "organization"):
+		return kindCompany
+	case strings.Contains(lower, "street") || strings.Contains(lower, "address"):
+		return kindStreet
+	case strings.Contains(lower, "city"):
+		return kindCity
+	case strings.Contains(lower, "zip") || strings.Contains(lower, "postal"):
+		return kindPostalCode
+	case strings.Contains(lower, "name"):
+		if lower == "name" && (moduleName == "accounts" || moduleName == "companies" || moduleName == "vendors") {
+			return kindCompany
+		}
+		return kindFullName
+	}
+	return kindNone
+}
+
+// hash maps a value to a stable number for the kind of fake it becomes
+func (a *Anonymizer) hash(kind, value string) uint64 {
+	mac := hmac.New(sha256.New, a.salt)
+	mac.Write([]byte(kind))
+	mac.Write([]byte{0})
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(value))))
+	return binary.BigEndian.Uint64(mac.Sum(nil))
+}
+
+func pick(list []string, h uint64) string {
+	return list[h%uint64(len(list))]
+}
+
+func (a *Anonymizer) FirstName(v string) string { return pick(fakeFirstNames, a.hash("first", v)) }
+func (a *Anonymizer) LastName(v string) string  { return pick(fakeLastNames, a.hash("last", v)) }
+
+func (a *Anonymizer) FullName(v string) string {
+	h := a.hash("name", v)
+	return pick(fakeFirstNames, h) + " " + pick(fakeLastNames, h>>16)
+}
+
+func (a *Anonymizer) Company(v string) string {
+	h := a.hash("company", v)
+	return pick(companyWords, h) + " " + pick(companyKinds, h>>16) + " " + pick(companySuffixes, h>>32)
+}
+
+// Domain maps a domain to a fake one under .example. A domain maps the same
+// way in emails and URLs, keeping contacts tied to their company's website.
+func (a *Anonymizer) Domain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	for _, public := range publicMailDomains {
+		if domain == public {
+			return strings.SplitN(domain, ".", 2)[0] + ".example"
+		}
+	}
+	h := a.hash("domain", domain)
+	return strings.ToLower(pick(companyWords, h)+pick(companyKinds, h>>16)) + fmt.Sprintf("%d", h>>48%100) + ".example"
+}
+
+func (a *Anonymizer) Email(v string) string {
+	local, domain, ok := strings.Cut(strings.TrimSpace(v), "@")
+	if !ok {
+		return a.FirstName(v) + "@" + a.Domain("")
+	}
+	h := a.hash("email", local+"@"+domain)
+	return fmt.Sprintf("%s.%s%d@%s", strings.ToLower(pick(fakeFirstNames, h)), strings.ToLower(pick(fakeLastNames, h>>16)), h>>40%100, a.Domain(domain))
+}
+
+// Phone keeps the number's format, length and country code and replaces
+// the other digits
+func (a *Anonymizer) Phone(v string) string {
+	h := a.hash("phone", v)
+	keep := 0
+	if strings.HasPrefix(strings.TrimSpace(v), "+") {
+		keep = 2
+	}
+	var b strings.Builder
+	digits := 0
+	for _, r := range v {
+		if !unicode.IsDigit(r) {
+			b.WriteRune(r)
+			continue
+		}
+		if digits < keep {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte(byte('0' + h%10))
+			h = h/10 + uint64(digits)*2654435761
+		}
+		digits++
+	}
+	return b.String()
+}
+
+func (a *Anonymizer) URL(v string) string {
+	u, err := url.Parse(strings.TrimSpace(v))
+	if err != nil || u.Host == "" {
+		return "https://" + a.Domain(v)
+	}
+	return u.Scheme + "://" + a.Domain(strings.TrimPrefix(u.Hostname(), "www."))
+}
+
+func (a *Anonymizer) Street(v string) string {
+	h := a.hash("street", v)
+	return fmt.Sprintf("%d %s %s", 1+h%9899, pick(fakeStreets, h>>16), pick(streetSuffixes, h>>32))
+}
+
+func (a *Anonymizer) City(v string) string { return pick(fakeCities, a.hash("city", v)) }
+
+// PostalCode keeps the code's shape, a digit for a digit and a letter for a letter
+func (a *Anonymizer) PostalCode(v string) string {
+	h := a.hash("postal", v)
+	var b strings.Builder
+	for i, r := range v {
+		n := h>>(uint(i%12)*5) + uint64(i)
+		switch {
+		case unicode.IsDigit(r):
+			b.WriteByte(byte('0' + n%10))
+		case unicode.IsLetter(r):
+			b.WriteByte(byte('A' + n%26))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Text replaces free text with filler of the same number of words
+func (a *Anonymizer) Text(v string) string {
+	n := min(len(strings.Fields(v)), maxFakeWords)
+	if n == 0 {
+		return ""
+	}
+	h := a.hash("text", v)
+	words := make([]string, n)
+	for i := range words {
+		words[i] = loremWords[(h+uint64(i)*7)%uint64(len(loremWords))]
+	}
+	return strings.Join(words, " ")
+}
+
+func (a *Anonymizer) text(kind personalKind, v string) string {
+	switch kind {
+	case kindFirstName:
+		return a.FirstName(v)
+	case kindLastName:
+		return a.LastName(v)
+	case kindFullName:
+		return a.FullName(v)
+	case kindCompany:
+		return a.Company(v)
+	case kindStreet:
+		return a.Street(v)
+	case kindCity:
+		return a.City(v)
+	case kindPostalCode:
+		return a.PostalCode(v)
+	}
+	return v
+}
+
+// Record anonymizes a record's data in place. A full name is rebuilt from
+// the record's fake first and last names when it has both.
+func (a *Anonymizer) Record(data bson.M, fields map[string]fieldInfo, moduleName string) {
+	var first, last string
+	var fullNames []string
+	for name, value := range data {
+		f, ok := fields[name]
+		if !ok || value == nil || value == "" {
+			continue
+		}
+		if models.FieldType(f.Type) == models.FieldTypeFile || models.FieldType(f.Type) == models.FieldTypeImage {
+			delete(data, name)
+			continue
+		}
+		str, ok := value.(string)
+		if !ok {
+			continue
+		}
+		switch models.FieldType(f.Type) {
+		case models.FieldTypeEmail:
+			data[name] = a.Email(str)
+		case models.FieldTypePhone:
+			data[name] = a.Phone(str)
+		case models.FieldTypeURL:
+			data[name] = a.URL(str)
+		case models.FieldTypeTextArea:
+			data[name] = a.Text(str)
+		case models.FieldTypeText:
+			kind := textKind(moduleName, name)
+			data[name] = a.text(kind, str)
+			switch kind {
+			case kindFirstName:
+				first = data[name].(string)
+			case kindLastName:
+				last = data[name].(string)
+			case kindFullName:
+				fullNames = append(fullNames, name)
+			}
+		}
+	}
+	if first != "" && last != "" {
+		for _, name := range fullNames {
+			data[name] = first + " " + last
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package sandbox
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/features/role"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type SandboxApi struct {
+	controller  *SandboxController
+	config      *config.Config
+	roleService role.RoleService
+}
+
+func NewSandboxApi(controller *SandboxController, config *config.Config, roleService role.RoleService) *SandboxApi {
+	return &SandboxApi{
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *SandboxApi) Setup(app *fiber.App) {
+	group := app.Group("/api/sandboxes", middleware.AuthMiddleware(h.config.SkipAuth))
+	group.Get("/", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListSandboxes)
+	group.Post("/", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.CloneTenant)
+	group.Post("/:id/anonymize", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.AnonymizeSandbox)
+	group.Get("/:id/anonymize/:jobId", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.GetAnonymizeJob)
+
+	changeSets := app.Group("/api/change-sets", middleware.AuthMiddleware(h.config.SkipAuth))
+	changeSets.Get("/", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListChangeSets)
+	changeSets.Post("/", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.CaptureChangeSet)
+	changeSets.Get("/:id", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.GetChangeSet)
+	changeSets.Post("/:id/apply", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.ApplyChangeSet)
+	changeSets.Post("/:id/rollback", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.RollbackChangeSet)
+}
@@ -0,0 +1,378 @@
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"go-crm/internal/common/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// deployableCollections can be moved between environments, in apply order.
+// Documents are matched by name; permissions by role name and resource.
+var deployableCollections = []string{"entities", "roles", "permissions", "automation_rules", "reports"}
+
+// volatileFields differ between tenants without being a configuration change
+var volatileFields = []string{"_id", "tenant_id", "created_at", "updated_at", "created_by", "updated_by"}
+
+// tenantConfig is one tenant's deployable documents keyed by collection, then
+// by the name they are matched on
+type tenantConfig map[string]map[string]bson.M
+
+func (s *SandboxServiceImpl) loadConfig(ctx context.Context, tenantID primitive.ObjectID) (tenantConfig, error) {
+	config := tenantConfig{}
+	roleNames := map[primitive.ObjectID]string{}
+	for _, collection := range deployableCollections {
+		docs, err := s.Repo.FindByTenant(ctx, collection, tenantID)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", collection, err)
+		}
+		config[collection] = map[string]bson.M{}
+		for _, doc := range docs {
+			key := documentKey(collection, doc, roleNames)
+			if key == "" {
+				continue
+			}
+			if collection == "roles" {
+				if id, ok := doc["_id"].(primitive.ObjectID); ok {
+					roleNames[id] = key
+				}
+			}
+			config[collection][key] = doc
+		}
+	}
+	return config, nil
+}
+
+func documentKey(collection string, doc bson.M, roleNames map[primitive.ObjectID]string) string {
+	if collection == "permissions" {
+		roleID, _ := doc["role_id"].(primitive.ObjectID)
+		resource, _ := doc["resource"].(bson.M)
+		resourceID, _ := resource["id"].(string)
+		if roleNames[roleID] == "" || resourceID == "" {
+			return ""
+		}
+		return roleNames[roleID] + ":" + resourceID
+	}
+	name, _ := doc["name"].(string)
+	return name
+}
+
+func (s *SandboxServiceImpl) CaptureChangeSet(ctx context.Context, req CaptureRequest) (*ChangeSet, error) {
+	claims, err := requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prodID, err := currentTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sandbox, err := s.OrganizationRepo.FindByID(ctx, req.SandboxID)
+	if err != nil || sandbox.SandboxOf == nil || *sandbox.SandboxOf != prodID {
+		return nil, errors.New("sandbox not found")
+	}
+
+	collections := deployableCollections
+	if len(req.Collections) > 0 {
+		collections = nil
+		for _, c := range deployableCollections {
+			if slices.Contains(req.Collections, c) {
+				collections = append(collections, c)
+			}
+		}
+		if len(collections) == 0 {
+			return nil, fmt.Errorf("collections must be among %s", strings.Join(deployableCollections, ", "))
+		}
+	}
+
+	source, err := s.loadConfig(ctx, sandbox.ID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.loadConfig(ctx, prodID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Point sandbox IDs at their production counterparts, or at new IDs for
+	// documents production does not have yet, so references between
+	// documents (permission -> role, rule -> module) survive the move
+	ids := newIDMap()
+	ids.set(sandbox.ID, prodID)
+	for _, collection := range deployableCollections {
+		for key, doc := range source[collection] {
+			id, ok := doc["_id"].(primitive.ObjectID)
+			if !ok {
+				continue
+			}
+			if existing, found := target[collection][key]; found {
+				ids.set(id, existing["_id"].(primitive.ObjectID))
+			} else {
+				ids.add(id)
+			}
+		}
+	}
+
+	items := []ChangeItem{}
+	for _, collection := range collections {
+		for _, key := range sortedKeys(source[collection]) {
+			after := ids.remap(source[collection][key]).(bson.M)
+			after["tenant_id"] = prodID
+			before, found := target[collection][key]
+			if !found {
+				items = append(items, ChangeItem{Collection: collection, Key: key, Action: ChangeActionCreate, After: after})
+				continue
+			}
+			if fields := changedFields(before, after); len(fields) > 0 {
+				items = append(items, ChangeItem{Collection: collection, Key: key, Action: ChangeActionUpdate, Fields: fields, Before: before, After: after})
+			}
+		}
+		if req.IncludeDeletes {
+			for _, key := range sortedKeys(target[collection]) {
+				if _, found := source[collection][key]; !found {
+					items = append(items, ChangeItem{Collection: collection, Key: key, Action: ChangeActionDelete, Before: target[collection][key]})
+				}
+			}
+		}
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = fmt.Sprintf("%s changes %s", sandbox.Name, time.Now().Format("2006-01-02 15:04"))
+	}
+	cs := &ChangeSet{
+		ID:        primitive.NewObjectID(),
+		TenantID:  prodID,
+		SandboxID: sandbox.ID,
+		Name:      name,
+		Status:    ChangeSetPending,
+		Items:     items,
+		CreatedBy: claims.UserID,
+		CreatedAt: time.Now(),
+	}
+	if err := s.Repo.CreateChangeSet(ctx, cs); err != nil {
+		return nil, err
+	}
+
+	_ = s.AuditService.LogChange(ctx, models.AuditActionCreate, "change_set", cs.ID.Hex(), map[string]models.Change{
+		"sandbox_id": {New: sandbox.ID.Hex()},
+		"items":      {New: len(items)},
+	})
+	return cs, nil
+}
+
+func (s *SandboxServiceImpl) GetChangeSet(ctx context.Context, id string) (*ChangeSet, error) {
+	tenantID, err := currentTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Repo.GetChangeSet(ctx, tenantID, id)
+}
+
+func (s *SandboxServiceImpl) ListChangeSets(ctx context.Context) ([]ChangeSet, error) {
+	tenantID, err := currentTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Repo.ListChangeSets(ctx, tenantID)
+}
+
+func (s *SandboxServiceImpl) ApplyChangeSet(ctx context.Context, id string) (*ChangeSet, error) {
+	claims, err := requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cs, err := s.GetChangeSet(ctx, id)
+	if err != nil {
+		return nil, errors.New("change set not found")
+	}
+	if cs.Status != ChangeSetPending {
+		return nil, fmt.Errorf("change set is %s", cs.Status)
+	}
+
+	now := time.Now()
+	err = s.Repo.WithTransaction(ctx, func(ctx context.Context) error {
+		for _, item := range cs.Items {
+			if item.Action != ChangeActionCreate {
+				if err := s.checkUnchanged(ctx, cs.TenantID, item); err != nil {
+					return err
+				}
+			}
+			switch item.Action {
+			case ChangeActionCreate:
+				doc := item.After
+				doc["created_at"] = now
+				doc["updated_at"] = now
+				if err := s.Repo.InsertDocument(ctx, item.Collection, doc); err != nil {
+					return fmt.Errorf("%s %q: %w", item.Collection, item.Key, err)
+				}
+			case ChangeActionUpdate:
+				doc := item.After
+				doc["created_at"] = item.Before["created_at"]
+				doc["updated_at"] = now
+				if err := s.Repo.ReplaceDocument(ctx, item.Collection, cs.TenantID, item.Before["_id"].(primitive.ObjectID), doc); err != nil {
+					return fmt.Errorf("%s %q: %w", item.Collection, item.Key, err)
+				}
+			case ChangeActionDelete:
+				if err := s.Repo.DeleteDocument(ctx, item.Collection, cs.TenantID, item.Before["_id"].(primitive.ObjectID)); err != nil {
+					return fmt.Errorf("%s %q: %w", item.Collection, item.Key, err)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		cs.Error = err.Error()
+		_ = s.Repo.UpdateChangeSet(ctx, cs)
+		return nil, err
+	}
+
+	cs.Status = ChangeSetApplied
+	cs.Error = ""
+	cs.AppliedBy = claims.UserID
+	cs.AppliedAt = &now
+	if err := s.Repo.UpdateChangeSet(ctx, cs); err != nil {
+		return nil, err
+	}
+
+	_ = s.AuditService.LogChange(ctx, models.AuditActionSettings, "change_set", cs.ID.Hex(), map[string]models.Change{
+		"status": {Old: ChangeSetPending, New: ChangeSetApplied},
+	})
+	return cs, nil
+}
+
+// checkUnchanged aborts an apply when a production document was edited after
+// the change set captured it
+func (s *SandboxServiceImpl) checkUnchanged(ctx context.Context, tenantID primitive.ObjectID, item ChangeItem) error {
+	id, _ := item.Before["_id"].(primitive.ObjectID)
+	current, err := s.Repo.FindDocument(ctx, item.Collection, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if current == nil || len(changedFields(item.Before, current)) > 0 {
+		return fmt.Errorf("%s %q changed in production after the change set was captured; capture a new one", item.Collection, item.Key)
+	}
+	return nil
+}
+
+func (s *SandboxServiceImpl) RollbackChangeSet(ctx context.Context, id string) (*ChangeSet, error) {
+	if _, err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	cs, err := s.GetChangeSet(ctx, id)
+	if err != nil {
+		return nil, errors.New("change set not found")
+	}
+	if cs.Status != ChangeSetApplied {
+		return nil, errors.New("only applied change sets can be rolled back")
+	}
+
+	// Undo in reverse order. Edits made to these documents since the apply
+	// are overwritten by the captured production versions.
+	err = s.Repo.WithTransaction(ctx, func(ctx context.Context) error {
+		for i := len(cs.Items) - 1; i >= 0; i-- {
+			item := cs.Items[i]
+			var err error
+			switch item.Action {
+			case ChangeActionCreate:
+				err = s.Repo.DeleteDocument(ctx, item.Collection, cs.TenantID, item.After["_id"].(primitive.ObjectID))
+			case ChangeActionUpdate:
+				err = s.Repo.ReplaceDocument(ctx, item.Collection, cs.TenantID, item.Before["_id"].(primitive.ObjectID), item.Before)
+			case ChangeActionDelete:
+				err = s.Repo.InsertDocument(ctx, item.Collection, item.Before)
+			}
+			if err != nil {
+				return fmt.Errorf("%s %q: %w", item.Collection, item.Key, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	cs.Status = ChangeSetRolledBack
+	cs.RolledBackAt = &now
+	if err := s.Repo.UpdateChangeSet(ctx, cs); err != nil {
+		return nil, err
+	}
+
+	_ = s.AuditService.LogChange(ctx, models.AuditActionSettings, "change_set", cs.ID.Hex(), map[string]models.Change{
+		"status": {Old: ChangeSetApplied, New: ChangeSetRolledBack},
+	})
+	return cs, nil
+}
+
+// changedFields lists the top-level fields whose values differ, ignoring
+// IDs, tenant and timestamps. Values are compared as JSON so documents read
+// back from a stored change set compare equal to freshly loaded ones.
+func changedFields(a, b bson.M) []string {
+	keys := map[string]bool{}
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+
+	var fields []string
+	for k := range keys {
+		if slices.Contains(volatileFields, k) {
+			continue
+		}
+		if canonical(a[k]) != canonical(b[k]) {
+			fields = append(fields, k)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func canonical(v interface{}) string {
+	raw, _ := json.Marshal(normalize(v))
+	return string(raw)
+}
+
+// normalize turns ordered documents into maps so key order does not count
+// as a change
+func normalize(v interface{}) interface{} {
+	switch val := v.(type) {
+	case bson.D:
+		m := map[string]interface{}{}
+		for _, e := range val {
+			m[e.Key] = normalize(e.Value)
+		}
+		return m
+	case bson.M:
+		m := map[string]interface{}{}
+		for k, item := range val {
+			m[k] = normalize(item)
+		}
+		return m
+	case bson.A:
+		out := make([]interface{}, len(val))
+		for i := range val {
+			out[i] = normalize(val[i])
+		}
+		return out
+	}
+	return v
+}
+
+func sortedKeys(m map[string]bson.M) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
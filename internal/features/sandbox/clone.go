@@ -0,0 +1,93 @@
+package sandbox
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// idMap gives every cloned document a new ID and rewrites references to it,
+// whether stored as an ObjectID or as its hex string (role IDs on users,
+// module IDs on automation rules, lookup values on records)
+type idMap struct {
+	ids map[primitive.ObjectID]primitive.ObjectID
+	hex map[string]string
+}
+
+func newIDMap() *idMap {
+	return &idMap{
+		ids: map[primitive.ObjectID]primitive.ObjectID{},
+		hex: map[string]string{},
+	}
+}
+
+func (m *idMap) add(old primitive.ObjectID) primitive.ObjectID {
+	if id, ok := m.ids[old]; ok {
+		return id
+	}
+	id := primitive.NewObjectID()
+	m.ids[old] = id
+	m.hex[old.Hex()] = id.Hex()
+	return id
+}
+
+// set maps old to an existing document, e.g. the production module a
+// sandbox module corresponds to
+func (m *idMap) set(old, id primitive.ObjectID) {
+	m.ids[old] = id
+	m.hex[old.Hex()] = id.Hex()
+}
+
+func (m *idMap) remap(v interface{}) interface{} {
+	switch val := v.(type) {
+	case primitive.ObjectID:
+		if id, ok := m.ids[val]; ok {
+			return id
+		}
+		return val
+	case string:
+		if len(val) == 24 {
+			if id, ok := m.hex[val]; ok {
+				return id
+			}
+		}
+		return val
+	case bson.M:
+		for k, item := range val {
+			val[k] = m.remap(item)
+		}
+		return val
+	case bson.D:
+		for i := range val {
+			val[i].Value = m.remap(val[i].Value)
+		}
+		return val
+	case bson.A:
+		for i := range val {
+			val[i] = m.remap(val[i])
+		}
+		return val
+	}
+	return v
+}
+
+type fieldInfo struct {
+	Type  string
+	Label string
+}
+
+// moduleFields reads field names and types from a raw module document
+func moduleFields(entity bson.M) map[string]fieldInfo {
+	fields := map[string]fieldInfo{}
+	list, _ := entity["fields"].(bson.A)
+	for _, item := range list {
+		f, ok := item.(bson.M)
+		if !ok {
+			continue
+		}
+		name, _ := f["name"].(string)
+		fieldType, _ := f["type"].(string)
+		label, _ := f["label"].(string)
+		fields[name] = fieldInfo{Type: fieldType, Label: label}
+	}
+	return fields
+}
@@ -0,0 +1,200 @@
+package sandbox
+
+import (
+	"errors"
+
+	"go-crm/internal/database"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type SandboxController struct {
+	Service SandboxService
+}
+
+func NewSandboxController(service SandboxService) *SandboxController {
+	return &SandboxController{Service: service}
+}
+
+// CloneTenant godoc
+// @Summary Create sandbox
+// @Description Clone the organization's modules, roles, automations, workflows and templates into a new sandbox tenant, optionally with a sampled and anonymized subset of records. Integrations and email settings are not copied.
+// @Tags sandboxes
+// @Accept json
+// @Produce json
+// @Param request body CloneRequest true "Sandbox options"
+// @Success 201 {object} CloneResult
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/sandboxes [post]
+func (c *SandboxController) CloneTenant(ctx *fiber.Ctx) error {
+	var req CloneRequest
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&req); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+
+	result, err := c.Service.CloneTenant(ctx.UserContext(), req)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{"data": result})
+}
+
+// ListSandboxes godoc
+// @Summary List sandboxes
+// @Description List the sandbox tenants cloned from this organization
+// @Tags sandboxes
+// @Produce json
+// @Success 200 {array} models.Organization
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/sandboxes [get]
+func (c *SandboxController) ListSandboxes(ctx *fiber.Ctx) error {
+	sandboxes, err := c.Service.ListSandboxes(ctx.UserContext())
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"data": sandboxes})
+}
+
+// AnonymizeSandbox godoc
+// @Summary Anonymize sandbox
+// @Description Start a background job that replaces names, emails, phones, addresses and free text in every record of a sandbox with realistic fakes. A value gets the same fake wherever it appears; lookups, numbers, dates and picklists are kept.
+// @Tags sandboxes
+// @Produce json
+// @Param id path string true "Sandbox ID"
+// @Success 202 {object} AnonymizeJob
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/sandboxes/{id}/anonymize [post]
+func (c *SandboxController) AnonymizeSandbox(ctx *fiber.Ctx) error {
+	job, err := c.Service.AnonymizeSandbox(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{"data": job})
+}
+
+// GetAnonymizeJob godoc
+// @Summary Get anonymize job
+// @Description Get the status and per-module progress of a sandbox anonymize job
+// @Tags sandboxes
+// @Produce json
+// @Param id path string true "Sandbox ID"
+// @Param jobId path string true "Job ID"
+// @Success 200 {object} AnonymizeJob
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/sandboxes/{id}/anonymize/{jobId} [get]
+func (c *SandboxController) GetAnonymizeJob(ctx *fiber.Ctx) error {
+	job, err := c.Service.GetAnonymizeJob(ctx.UserContext(), ctx.Params("id"), ctx.Params("jobId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Anonymize job not found"})
+	}
+
+	return ctx.JSON(fiber.Map{"data": job})
+}
+
+// CaptureChangeSet godoc
+// @Summary Capture change set
+// @Description Diff a sandbox's modules, roles, permissions, automation rules and reports against this organization and store the differences as a reviewable manifest
+// @Tags change-sets
+// @Accept json
+// @Produce json
+// @Param request body CaptureRequest true "Sandbox and collections"
+// @Success 201 {object} ChangeSet
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/change-sets [post]
+func (c *SandboxController) CaptureChangeSet(ctx *fiber.Ctx) error {
+	var req CaptureRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	cs, err := c.Service.CaptureChangeSet(ctx.UserContext(), req)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{"data": cs})
+}
+
+// ListChangeSets godoc
+// @Summary List change sets
+// @Description List captured change sets without their item documents, newest first
+// @Tags change-sets
+// @Produce json
+// @Success 200 {array} ChangeSet
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/change-sets [get]
+func (c *SandboxController) ListChangeSets(ctx *fiber.Ctx) error {
+	sets, err := c.Service.ListChangeSets(ctx.UserContext())
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"data": sets})
+}
+
+// GetChangeSet godoc
+// @Summary Get change set
+// @Description Get a change set's manifest with the production (before) and sandbox (after) version of every document
+// @Tags change-sets
+// @Produce json
+// @Param id path string true "Change Set ID"
+// @Success 200 {object} ChangeSet
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/change-sets/{id} [get]
+func (c *SandboxController) GetChangeSet(ctx *fiber.Ctx) error {
+	cs, err := c.Service.GetChangeSet(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Change set not found"})
+	}
+
+	return ctx.JSON(fiber.Map{"data": cs})
+}
+
+// ApplyChangeSet godoc
+// @Summary Apply change set
+// @Description Apply every change in one transaction. Nothing is applied when a production document changed since capture.
+// @Tags change-sets
+// @Produce json
+// @Param id path string true "Change Set ID"
+// @Success 200 {object} ChangeSet
+// @Failure 409 {object} map[string]interface{}
+// @Failure 501 {object} map[string]interface{} "MongoDB is not running as a replica set"
+// @Router /api/change-sets/{id}/apply [post]
+func (c *SandboxController) ApplyChangeSet(ctx *fiber.Ctx) error {
+	cs, err := c.Service.ApplyChangeSet(ctx.UserContext(), ctx.Params("id"))
+	if errors.Is(err, database.ErrTransactionsUnsupported) {
+		return ctx.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"data": cs})
+}
+
+// RollbackChangeSet godoc
+// @Summary Roll back change set
+// @Description Restore the production documents an applied change set created, updated or deleted
+// @Tags change-sets
+// @Produce json
+// @Param id path string true "Change Set ID"
+// @Success 200 {object} ChangeSet
+// @Failure 409 {object} map[string]interface{}
+// @Failure 501 {object} map[string]interface{} "MongoDB is not running as a replica set"
+// @Router /api/change-sets/{id}/rollback [post]
+func (c *SandboxController) RollbackChangeSet(ctx *fiber.Ctx) error {
+	cs, err := c.Service.RollbackChangeSet(ctx.UserContext(), ctx.Params("id"))
+	if errors.Is(err, database.ErrTransactionsUnsupported) {
+		return ctx.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"data": cs})
+}
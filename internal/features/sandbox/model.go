@@ -0,0 +1,133 @@
+package sandbox
+
+import (
+	"time"
+
+	"go-crm/internal/common/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// configCollections hold a tenant's configuration and are copied whole into
+// the sandbox. Integrations (webhooks, cron jobs, accounting, marketing, sync
+// and SFTP exchanges) are left out so a sandbox never calls external systems
+// with production credentials. Email templates and groups are not
+// tenant-scoped and are already visible to the sandbox.
+var configCollections = []string{
+	"entities",
+	"roles",
+	"permissions",
+	"resources",
+	"automation_rules",
+	"approval_workflows",
+	"blueprints",
+	"stage_gates",
+	"print_templates",
+	"project_templates",
+	"reports",
+	"charts",
+	"dashboards",
+	"data_quality_rules",
+	"sla_policies",
+	"escalation_rules",
+	"ticket_settings",
+	"comment_settings",
+	"settings",
+}
+
+// CloneRequest describes the sandbox to create from the current tenant
+type CloneRequest struct {
+	Name           string `json:"name"` // Defaults to "<organization> Sandbox"
+	IncludeRecords bool   `json:"include_records"`
+	SampleSize     int    `json:"sample_size"` // Records sampled per module, default 50, max 1000
+	Anonymize      bool   `json:"anonymize"`   // Replace emails, phones, names, addresses and free text in sampled records with consistent fakes
+}
+
+// CloneResult reports what was copied. The admin signs in to the sandbox
+// with Username and their current password.
+type CloneResult struct {
+	Tenant      *models.Organization `json:"tenant"`
+	Username    string               `json:"username"`
+	Collections map[string]int       `json:"collections"` // Documents copied per collection
+	Records     map[string]int       `json:"records,omitempty"`
+}
+
+// ChangeAction is what applying a change item does to the production document
+type ChangeAction string
+
+const (
+	ChangeActionCreate ChangeAction = "create"
+	ChangeActionUpdate ChangeAction = "update"
+	ChangeActionDelete ChangeAction = "delete"
+)
+
+type ChangeSetStatus string
+
+const (
+	ChangeSetPending    ChangeSetStatus = "pending"
+	ChangeSetApplied    ChangeSetStatus = "applied"
+	ChangeSetRolledBack ChangeSetStatus = "rolled_back"
+)
+
+// ChangeItem is one configuration document that differs between the sandbox
+// and production. Before is the production document when the set was
+// captured and is restored on rollback; After is the sandbox document with
+// its references rewritten to production IDs.
+type ChangeItem struct {
+	Collection string       `json:"collection" bson:"collection"`
+	Key        string       `json:"key" bson:"key"` // Name the document is matched by, e.g. the module name
+	Action     ChangeAction `json:"action" bson:"action"`
+	Fields     []string     `json:"fields,omitempty" bson:"fields,omitempty"` // Top-level fields that changed
+	Before     bson.M       `json:"before,omitempty" bson:"before,omitempty"`
+	After      bson.M       `json:"after,omitempty" bson:"after,omitempty"`
+}
+
+// ChangeSet is a reviewable manifest of configuration changes captured from
+// a sandbox and applied to the production tenant it was cloned from
+type ChangeSet struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID     primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	SandboxID    primitive.ObjectID `json:"sandbox_id" bson:"sandbox_id"`
+	Name         string             `json:"name" bson:"name"`
+	Status       ChangeSetStatus    `json:"status" bson:"status"`
+	Items        []ChangeItem       `json:"items" bson:"items"`
+	Error        string             `json:"error,omitempty" bson:"error,omitempty"` // Why the last apply was aborted
+	CreatedBy    string             `json:"created_by" bson:"created_by"`
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+	AppliedBy    string             `json:"applied_by,omitempty" bson:"applied_by,omitempty"`
+	AppliedAt    *time.Time         `json:"applied_at,omitempty" bson:"applied_at,omitempty"`
+	RolledBackAt *time.Time         `json:"rolled_back_at,omitempty" bson:"rolled_back_at,omitempty"`
+}
+
+type CaptureRequest struct {
+	SandboxID      string   `json:"sandbox_id"`
+	Name           string   `json:"name"`
+	Collections    []string `json:"collections"`     // Subset of entities, roles, permissions, automation_rules and reports; empty for all
+	IncludeDeletes bool     `json:"include_deletes"` // Delete production documents missing from the sandbox
+}
+
+type AnonymizeStatus string
+
+const (
+	AnonymizeQueued    AnonymizeStatus = "queued"
+	AnonymizeRunning   AnonymizeStatus = "running"
+	AnonymizeCompleted AnonymizeStatus = "completed"
+	AnonymizeFailed    AnonymizeStatus = "failed"
+)
+
+// AnonymizeJob rewrites the personal data of every record in a sandbox, e.g.
+// after a staging refresh copied production records into it
+type AnonymizeJob struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID    primitive.ObjectID `json:"tenant_id" bson:"tenant_id"` // Production tenant that requested the job
+	SandboxID   primitive.ObjectID `json:"sandbox_id" bson:"sandbox_id"`
+	Status      AnonymizeStatus    `json:"status" bson:"status"`
+	Records     map[string]int     `json:"records" bson:"records"` // Records rewritten per module
+	Processed   int                `json:"processed" bson:"processed"`
+	Error       string             `json:"error,omitempty" bson:"error,omitempty"`
+	RequestedBy string             `json:"requested_by" bson:"requested_by"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	StartedAt   *time.Time         `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	CompletedAt *time.Time         `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+}
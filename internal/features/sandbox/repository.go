@@ -0,0 +1,220 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SandboxRepository reads and writes raw documents so any collection can be
+// cloned without knowing its model
+type SandboxRepository interface {
+	FindByTenant(ctx context.Context, collection string, tenantID primitive.ObjectID) ([]bson.M, error)
+	SampleRecords(ctx context.Context, tenantID primitive.ObjectID, moduleName string, size int) ([]bson.M, error)
+	InsertMany(ctx context.Context, collection string, docs []bson.M) error
+
+	FindDocument(ctx context.Context, collection string, tenantID, id primitive.ObjectID) (bson.M, error)
+	InsertDocument(ctx context.Context, collection string, doc bson.M) error
+	ReplaceDocument(ctx context.Context, collection string, tenantID, id primitive.ObjectID, doc bson.M) error
+	DeleteDocument(ctx context.Context, collection string, tenantID, id primitive.ObjectID) error
+	// WithTransaction runs fn in a transaction; the document methods join it
+	// when called with the context fn receives. It fails with
+	// database.ErrTransactionsUnsupported on a standalone mongod.
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+
+	CreateChangeSet(ctx context.Context, cs *ChangeSet) error
+	GetChangeSet(ctx context.Context, tenantID primitive.ObjectID, id string) (*ChangeSet, error)
+	ListChangeSets(ctx context.Context, tenantID primitive.ObjectID) ([]ChangeSet, error)
+	UpdateChangeSet(ctx context.Context, cs *ChangeSet) error
+
+	// RecordsAfter pages a module's records, including deleted ones, in _id order
+	RecordsAfter(ctx context.Context, tenantID primitive.ObjectID, moduleName string, after primitive.ObjectID, limit int64) ([]bson.M, error)
+	// SetRecordData replaces the data of records by ID
+	SetRecordData(ctx context.Context, tenantID primitive.ObjectID, data map[primitive.ObjectID]bson.M) error
+
+	CreateAnonymizeJob(ctx context.Context, job *AnonymizeJob) error
+	GetAnonymizeJob(ctx context.Context, tenantID, id primitive.ObjectID) (*AnonymizeJob, error)
+	UpdateAnonymizeJob(ctx context.Context, job *AnonymizeJob) error
+}
+
+type SandboxRepositoryImpl struct {
+	db *mongo.Database
+}
+
+func NewSandboxRepository(db *database.MongodbDB) SandboxRepository {
+	return &SandboxRepositoryImpl{db: db.DB}
+}
+
+func (r *SandboxRepositoryImpl) FindByTenant(ctx context.Context, collection string, tenantID primitive.ObjectID) ([]bson.M, error) {
+	cursor, err := r.db.Collection(collection).Find(ctx, bson.M{"tenant_id": tenantID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	docs := []bson.M{}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+func (r *SandboxRepositoryImpl) SampleRecords(ctx context.Context, tenantID primitive.ObjectID, moduleName string, size int) ([]bson.M, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"tenant_id": tenantID, "entity": moduleName, "deleted": bson.M{"$ne": true}}}},
+		{{Key: "$sample", Value: bson.M{"size": size}}},
+	}
+	cursor, err := r.db.Collection("entity_records").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	docs := []bson.M{}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+func (r *SandboxRepositoryImpl) InsertMany(ctx context.Context, collection string, docs []bson.M) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	batch := make([]interface{}, len(docs))
+	for i, d := range docs {
+		batch[i] = d
+	}
+	_, err := r.db.Collection(collection).InsertMany(ctx, batch)
+	return err
+}
+
+func (r *SandboxRepositoryImpl) FindDocument(ctx context.Context, collection string, tenantID, id primitive.ObjectID) (bson.M, error) {
+	var doc bson.M
+	err := r.db.Collection(collection).FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	return doc, err
+}
+
+func (r *SandboxRepositoryImpl) InsertDocument(ctx context.Context, collection string, doc bson.M) error {
+	_, err := r.db.Collection(collection).InsertOne(ctx, doc)
+	return err
+}
+
+func (r *SandboxRepositoryImpl) ReplaceDocument(ctx context.Context, collection string, tenantID, id primitive.ObjectID, doc bson.M) error {
+	_, err := r.db.Collection(collection).ReplaceOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}, doc)
+	return err
+}
+
+func (r *SandboxRepositoryImpl) DeleteDocument(ctx context.Context, collection string, tenantID, id primitive.ObjectID) error {
+	_, err := r.db.Collection(collection).DeleteOne(ctx, bson.M{"_id": id, "tenant_id": tenantID})
+	return err
+}
+
+func (r *SandboxRepositoryImpl) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return database.WithTransaction(ctx, r.db, fn)
+}
+
+func (r *SandboxRepositoryImpl) CreateChangeSet(ctx context.Context, cs *ChangeSet) error {
+	if cs.ID.IsZero() {
+		cs.ID = primitive.NewObjectID()
+	}
+	_, err := r.db.Collection("change_sets").InsertOne(ctx, cs)
+	return err
+}
+
+func (r *SandboxRepositoryImpl) GetChangeSet(ctx context.Context, tenantID primitive.ObjectID, id string) (*ChangeSet, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var cs ChangeSet
+	if err := r.db.Collection("change_sets").FindOne(ctx, bson.M{"_id": oid, "tenant_id": tenantID}).Decode(&cs); err != nil {
+		return nil, err
+	}
+	return &cs, nil
+}
+
+func (r *SandboxRepositoryImpl) ListChangeSets(ctx context.Context, tenantID primitive.ObjectID) ([]ChangeSet, error) {
+	// The manifests can be large; the list only shows headers
+	opts := options.Find().SetSort(bson.M{"created_at": -1}).SetProjection(bson.M{"items.before": 0, "items.after": 0})
+	cursor, err := r.db.Collection("change_sets").Find(ctx, bson.M{"tenant_id": tenantID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	sets := []ChangeSet{}
+	if err := cursor.All(ctx, &sets); err != nil {
+		return nil, err
+	}
+	return sets, nil
+}
+
+func (r *SandboxRepositoryImpl) UpdateChangeSet(ctx context.Context, cs *ChangeSet) error {
+	_, err := r.db.Collection("change_sets").ReplaceOne(ctx, bson.M{"_id": cs.ID, "tenant_id": cs.TenantID}, cs)
+	return err
+}
+
+func (r *SandboxRepositoryImpl) RecordsAfter(ctx context.Context, tenantID primitive.ObjectID, moduleName string, after primitive.ObjectID, limit int64) ([]bson.M, error) {
+	filter := bson.M{"tenant_id": tenantID, "entity": moduleName}
+	if !after.IsZero() {
+		filter["_id"] = bson.M{"$gt": after}
+	}
+	opts := options.Find().SetSort(bson.M{"_id": 1}).SetLimit(limit).SetProjection(bson.M{"data": 1})
+	cursor, err := r.db.Collection("entity_records").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	docs := []bson.M{}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+func (r *SandboxRepositoryImpl) SetRecordData(ctx context.Context, tenantID primitive.ObjectID, data map[primitive.ObjectID]bson.M) error {
+	if len(data) == 0 {
+		return nil
+	}
+	writes := make([]mongo.WriteModel, 0, len(data))
+	for id, d := range data {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id, "tenant_id": tenantID}).
+			SetUpdate(bson.M{"$set": bson.M{"data": d}}))
+	}
+	_, err := r.db.Collection("entity_records").BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+func (r *SandboxRepositoryImpl) CreateAnonymizeJob(ctx context.Context, job *AnonymizeJob) error {
+	if job.ID.IsZero() {
+		job.ID = primitive.NewObjectID()
+	}
+	_, err := r.db.Collection("anonymize_jobs").InsertOne(ctx, job)
+	return err
+}
+
+func (r *SandboxRepositoryImpl) GetAnonymizeJob(ctx context.Context, tenantID, id primitive.ObjectID) (*AnonymizeJob, error) {
+	var job AnonymizeJob
+	if err := r.db.Collection("anonymize_jobs").FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}).Decode(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *SandboxRepositoryImpl) UpdateAnonymizeJob(ctx context.Context, job *AnonymizeJob) error {
+	_, err := r.db.Collection("anonymize_jobs").ReplaceOne(ctx, bson.M{"_id": job.ID, "tenant_id": job.TenantID}, job)
+	return err
+}
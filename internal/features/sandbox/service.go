@@ -0,0 +1,239 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/organization"
+	"go-crm/internal/features/role"
+	"go-crm/internal/features/user"
+	"go-crm/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultSampleSize = 50
+	maxSampleSize     = 1000
+)
+
+type SandboxService interface {
+	// CloneTenant copies the current tenant's configuration, and optionally a
+	// sample of its records, into a new sandbox tenant
+	CloneTenant(ctx context.Context, req CloneRequest) (*CloneResult, error)
+	ListSandboxes(ctx context.Context) ([]models.Organization, error)
+	// AnonymizeSandbox starts a background job that replaces the personal
+	// data in every record of a sandbox with consistent fakes
+	AnonymizeSandbox(ctx context.Context, sandboxID string) (*AnonymizeJob, error)
+	GetAnonymizeJob(ctx context.Context, sandboxID, jobID string) (*AnonymizeJob, error)
+	// RunAnonymize anonymizes a sandbox tenant synchronously, e.g. from a
+	// staging refresh script; it refuses tenants that are not sandboxes
+	RunAnonymize(ctx context.Context, sandboxID primitive.ObjectID, requestedBy string) (*AnonymizeJob, error)
+
+	// CaptureChangeSet diffs a sandbox's configuration against the current
+	// (production) tenant and stores the result for review
+	CaptureChangeSet(ctx context.Context, req CaptureRequest) (*ChangeSet, error)
+	GetChangeSet(ctx context.Context, id string) (*ChangeSet, error)
+	ListChangeSets(ctx context.Context) ([]ChangeSet, error)
+	// ApplyChangeSet applies every item in one transaction. It aborts when a
+	// production document changed since the set was captured.
+	ApplyChangeSet(ctx context.Context, id string) (*ChangeSet, error)
+	// RollbackChangeSet restores the production documents an applied set changed
+	RollbackChangeSet(ctx context.Context, id string) (*ChangeSet, error)
+}
+
+type SandboxServiceImpl struct {
+	Repo             SandboxRepository
+	OrganizationRepo organization.OrganizationRepository
+	UserRepo         user.UserRepository
+	AuditService     audit.AuditService
+}
+
+func NewSandboxService(repo SandboxRepository, orgRepo organization.OrganizationRepository, userRepo user.UserRepository, auditService audit.AuditService) SandboxService {
+	return &SandboxServiceImpl{
+		Repo:             repo,
+		OrganizationRepo: orgRepo,
+		UserRepo:         userRepo,
+		AuditService:     auditService,
+	}
+}
+
+// requireAdmin returns the caller's claims when they are an admin acting as
+// themselves; sandboxes and deployments are not available while impersonating
+func requireAdmin(ctx context.Context) (*utils.UserClaims, error) {
+	claims, ok := ctx.Value(utils.UserClaimsKey).(*utils.UserClaims)
+	if !ok {
+		return nil, errors.New("unauthorized")
+	}
+	if claims.ImpersonationID == "" && role.HasAdminRole(claims.Roles) {
+		return claims, nil
+	}
+	return nil, errors.New("only admins can manage sandboxes")
+}
+
+func currentTenant(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+func (s *SandboxServiceImpl) CloneTenant(ctx context.Context, req CloneRequest) (*CloneResult, error) {
+	claims, err := requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceID, err := currentTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	source, err := s.OrganizationRepo.FindByID(ctx, sourceID.Hex())
+	if err != nil {
+		return nil, errors.New("organization not found")
+	}
+	admin, err := s.UserRepo.FindByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	sampleSize := req.SampleSize
+	if sampleSize <= 0 {
+		sampleSize = defaultSampleSize
+	}
+	if sampleSize > maxSampleSize {
+		sampleSize = maxSampleSize
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = source.Name + " Sandbox"
+	}
+
+	ids := newIDMap()
+	tenantID := ids.add(sourceID)
+
+	docs := map[string][]bson.M{}
+	result := &CloneResult{Collections: map[string]int{}}
+	for _, collection := range configCollections {
+		found, err := s.Repo.FindByTenant(ctx, collection, sourceID)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", collection, err)
+		}
+		for _, doc := range found {
+			// Keep production SMTP credentials out of the sandbox
+			if collection == "settings" && doc["type"] == "email" {
+				continue
+			}
+			if id, ok := doc["_id"].(primitive.ObjectID); ok {
+				ids.add(id)
+			}
+			docs[collection] = append(docs[collection], doc)
+		}
+	}
+
+	// The requesting admin is the sandbox's only user. Usernames are global,
+	// so theirs gets the sandbox slug appended.
+	slug := utils.Slugify(name) + "-" + tenantID.Hex()[:4]
+	userID := ids.add(admin.ID)
+	userDoc, err := toDocument(admin)
+	if err != nil {
+		return nil, err
+	}
+	userDoc["username"] = admin.Username + "." + slug
+	delete(userDoc, "reports_to")
+	delete(userDoc, "last_login")
+	docs["users"] = []bson.M{userDoc}
+
+	if req.IncludeRecords {
+		result.Records = map[string]int{}
+		anonymizer := NewAnonymizer(nil)
+		for _, entity := range docs["entities"] {
+			moduleName, _ := entity["name"].(string)
+			records, err := s.Repo.SampleRecords(ctx, sourceID, moduleName, sampleSize)
+			if err != nil {
+				return nil, fmt.Errorf("sample %s: %w", moduleName, err)
+			}
+			fields := moduleFields(entity)
+			for _, rec := range records {
+				if id, ok := rec["_id"].(primitive.ObjectID); ok {
+					ids.add(id)
+				}
+				if req.Anonymize {
+					if data, ok := rec["data"].(bson.M); ok {
+						anonymizer.Record(data, fields, moduleName)
+					}
+				}
+			}
+			docs["entity_records"] = append(docs["entity_records"], records...)
+			result.Records[moduleName] = len(records)
+		}
+	}
+
+	// Insert in a fixed order; the organization goes last so a failed clone
+	// never shows up as a usable tenant
+	collections := append(append([]string{}, configCollections...), "users", "entity_records")
+	for _, collection := range collections {
+		batch := docs[collection]
+		for i := range batch {
+			batch[i] = ids.remap(batch[i]).(bson.M)
+			batch[i]["tenant_id"] = tenantID
+		}
+		if err := s.Repo.InsertMany(ctx, collection, batch); err != nil {
+			return nil, fmt.Errorf("write %s: %w", collection, err)
+		}
+		if collection != "entity_records" {
+			result.Collections[collection] = len(batch)
+		}
+	}
+
+	now := time.Now()
+	sandbox := &models.Organization{
+		ID:              tenantID,
+		Name:            name,
+		Slug:            slug,
+		Plan:            source.Plan,
+		EnabledProducts: source.EnabledProducts,
+		OwnerID:         userID,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		SandboxOf:       &sourceID,
+	}
+	if err := s.OrganizationRepo.Create(ctx, sandbox); err != nil {
+		return nil, err
+	}
+	result.Tenant = sandbox
+	result.Username = userDoc["username"].(string)
+
+	_ = s.AuditService.LogChange(ctx, models.AuditActionCreate, "sandbox", tenantID.Hex(), map[string]models.Change{
+		"name":            {New: name},
+		"include_records": {New: req.IncludeRecords},
+		"anonymize":       {New: req.Anonymize},
+	})
+
+	return result, nil
+}
+
+func (s *SandboxServiceImpl) ListSandboxes(ctx context.Context) ([]models.Organization, error) {
+	sourceID, err := currentTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.OrganizationRepo.ListSandboxes(ctx, sourceID)
+}
+
+func toDocument(v interface{}) (bson.M, error) {
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.M
+	err = bson.Unmarshal(raw, &doc)
+	return doc, err
+}
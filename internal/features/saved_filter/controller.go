@@ -3,6 +3,8 @@ package saved_filter
 import (
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"go-crm/internal/common/session"
 )
 
 type SavedFilterController struct {
@@ -33,11 +35,10 @@ func (c *SavedFilterController) CreateFilter(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
 	}
 
-	userIDStr, ok := ctx.Locals("user_id").(string)
+	userID, ok := session.UserID(ctx.UserContext())
 	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
-	userID, _ := primitive.ObjectIDFromHex(userIDStr)
 	filter.UserID = userID
 
 	if err := c.FilterService.CreateFilter(ctx.UserContext(), &filter); err != nil {
@@ -109,11 +110,10 @@ func (c *SavedFilterController) UpdateFilter(ctx *fiber.Ctx) error {
 func (c *SavedFilterController) DeleteFilter(ctx *fiber.Ctx) error {
 	id := ctx.Params("id")
 
-	userIDStr, ok := ctx.Locals("user_id").(string)
+	userID, ok := session.UserID(ctx.UserContext())
 	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
-	userID, _ := primitive.ObjectIDFromHex(userIDStr)
 
 	if err := c.FilterService.DeleteFilter(ctx.UserContext(), id, userID); err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
@@ -139,11 +139,10 @@ func (c *SavedFilterController) ListUserFilters(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "module parameter required"})
 	}
 
-	userIDStr, ok := ctx.Locals("user_id").(string)
+	userID, ok := session.UserID(ctx.UserContext())
 	if !ok {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
-	userID, _ := primitive.ObjectIDFromHex(userIDStr)
 
 	filters, err := c.FilterService.GetUserFilters(ctx.UserContext(), userID, moduleName)
 	if err != nil {
@@ -2,7 +2,8 @@ package search
 
 import (
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"go-crm/internal/common/session"
 )
 
 type SearchController struct {
@@ -30,18 +31,12 @@ func NewSearchController(service SearchService) *SearchController {
 func (ctrl *SearchController) GlobalSearch(c *fiber.Ctx) error {
 	query := c.Query("q")
 
-	userIDStr, ok := c.Locals("user_id").(string)
+	userID, ok := session.UserID(c.UserContext())
 	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "User ID not found in context",
 		})
 	}
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
-	}
 
 	results, err := ctrl.Service.GlobalSearch(c.UserContext(), query, userID)
 	if err != nil {
@@ -33,4 +33,15 @@ func (a *SettingsApi) Setup(app *fiber.App) {
 	// File Sharing Settings
 	group.Get("/file-sharing", middleware.RequirePermission(a.RoleService, "settings", "read"), a.Controller.GetFileSharingConfig)
 	group.Put("/file-sharing", middleware.RequirePermission(a.RoleService, "settings", "update"), a.Controller.UpdateFileSharingConfig)
+
+	// Locale: tenant defaults, and per-user overrides any user may set
+	group.Get("/locale", middleware.RequirePermission(a.RoleService, "settings", "read"), a.Controller.GetLocaleConfig)
+	group.Put("/locale", middleware.RequirePermission(a.RoleService, "settings", "update"), a.Controller.UpdateLocaleConfig)
+	group.Get("/locale/me", a.Controller.GetMyLocale)
+	group.Put("/locale/me", a.Controller.UpdateMyLocale)
+
+	group.Get("/impersonation", middleware.RequirePermission(a.RoleService, "settings", "read"), a.Controller.GetImpersonationConfig)
+	group.Put("/impersonation", middleware.RequirePermission(a.RoleService, "settings", "update"), a.Controller.UpdateImpersonationConfig)
+	group.Get("/ai", middleware.RequirePermission(a.RoleService, "settings", "read"), a.Controller.GetAIConfig)
+	group.Put("/ai", middleware.RequirePermission(a.RoleService, "settings", "update"), a.Controller.UpdateAIConfig)
 }
@@ -1,6 +1,11 @@
 package settings
 
 import (
+	"sort"
+
+	"go-crm/internal/common/session"
+	"go-crm/pkg/locale"
+
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -157,3 +162,214 @@ func (ctrl *SettingsController) UpdateFileSharingConfig(c *fiber.Ctx) error {
 		"message": "File sharing settings updated successfully",
 	})
 }
+
+// GetLocaleConfig godoc
+// @Summary Get locale configuration
+// @Description Get the tenant's number, currency and date formatting preferences
+// @Tags settings
+// @Produce json
+// @Success 200 {object} locale.Settings
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/settings/locale [get]
+func (ctrl *SettingsController) GetLocaleConfig(c *fiber.Ctx) error {
+	config, err := ctrl.Service.GetLocaleConfig(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error retrieving locale settings",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"settings":     config,
+		"locales":      locale.Locales(),
+		"date_formats": dateFormatNames(),
+	})
+}
+
+// UpdateLocaleConfig godoc
+// @Summary Update locale configuration
+// @Description Update the tenant's number, currency and date formatting preferences
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Param config body locale.Settings true "Locale Configuration"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/settings/locale [put]
+func (ctrl *SettingsController) UpdateLocaleConfig(c *fiber.Ctx) error {
+	var config locale.Settings
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := ctrl.Service.UpdateLocaleConfig(c.UserContext(), config); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Locale settings updated successfully",
+	})
+}
+
+// GetMyLocale godoc
+// @Summary Get my locale preferences
+// @Description Get the current user's formatting overrides and the resolved settings
+// @Tags settings
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/settings/locale/me [get]
+func (ctrl *SettingsController) GetMyLocale(c *fiber.Ctx) error {
+	userID, _ := session.UserID(c.UserContext())
+	config, err := ctrl.Service.GetUserLocale(c.UserContext(), userID.Hex())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error retrieving locale preferences",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"settings": config,
+		"resolved": ctrl.Service.Formatter(c.UserContext(), userID.Hex()).Settings(),
+	})
+}
+
+// UpdateMyLocale godoc
+// @Summary Update my locale preferences
+// @Description Override the tenant's formatting preferences for the current user; empty fields inherit
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Param config body locale.Settings true "Locale Preferences"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/settings/locale/me [put]
+func (ctrl *SettingsController) UpdateMyLocale(c *fiber.Ctx) error {
+	var config locale.Settings
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	userID, _ := session.UserID(c.UserContext())
+	if err := ctrl.Service.UpdateUserLocale(c.UserContext(), userID.Hex(), config); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Locale preferences updated successfully",
+	})
+}
+
+// GetImpersonationConfig godoc
+// @Summary Get impersonation configuration
+// @Description Get whether admins may impersonate users and the longest session allowed
+// @Tags settings
+// @Produce json
+// @Success 200 {object} ImpersonationConfig
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/settings/impersonation [get]
+func (ctrl *SettingsController) GetImpersonationConfig(c *fiber.Ctx) error {
+	config, err := ctrl.Service.GetImpersonationConfig(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error retrieving impersonation settings",
+		})
+	}
+
+	return c.JSON(config)
+}
+
+// UpdateImpersonationConfig godoc
+// @Summary Update impersonation configuration
+// @Description Allow or disallow admin impersonation for the tenant
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Param config body ImpersonationConfig true "Impersonation Configuration"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/settings/impersonation [put]
+func (ctrl *SettingsController) UpdateImpersonationConfig(c *fiber.Ctx) error {
+	var config ImpersonationConfig
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := ctrl.Service.UpdateImpersonationConfig(c.UserContext(), config); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Impersonation settings updated successfully",
+	})
+}
+
+// GetAIConfig godoc
+// @Summary Get AI configuration
+// @Description Get the tenant's AI provider and model. The API key is not returned.
+// @Tags settings
+// @Produce json
+// @Success 200 {object} AIConfig
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/settings/ai [get]
+func (ctrl *SettingsController) GetAIConfig(c *fiber.Ctx) error {
+	config, err := ctrl.Service.GetAIConfig(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error retrieving AI settings",
+		})
+	}
+
+	config.APIKey = ""
+	return c.JSON(config)
+}
+
+// UpdateAIConfig godoc
+// @Summary Update AI configuration
+// @Description Set the tenant's AI provider, model and API key. An empty api_key keeps the stored one.
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Param config body AIConfig true "AI Configuration"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/settings/ai [put]
+func (ctrl *SettingsController) UpdateAIConfig(c *fiber.Ctx) error {
+	var config AIConfig
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := ctrl.Service.UpdateAIConfig(c.UserContext(), config); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "AI settings updated successfully",
+	})
+}
+
+func dateFormatNames() []string {
+	names := make([]string, 0, len(locale.DateFormats))
+	for name := range locale.DateFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
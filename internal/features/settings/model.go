@@ -3,6 +3,8 @@ package settings
 import (
 	"time"
 
+	"go-crm/pkg/locale"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -12,6 +14,10 @@ const (
 	SettingsTypeEmail       SettingsType = "email"
 	SettingsTypeGeneral     SettingsType = "general"
 	SettingsTypeFileSharing SettingsType = "file_sharing"
+	SettingsTypeLocale      SettingsType = "locale"
+
+	SettingsTypeImpersonation SettingsType = "impersonation"
+	SettingsTypeAI            SettingsType = "ai"
 )
 
 type EmailConfig struct {
@@ -22,6 +28,22 @@ type EmailConfig struct {
 	FromEmail    string `json:"from_email" bson:"from_email"`
 	FromName     string `json:"from_name" bson:"from_name"`
 	Secure       bool   `json:"secure" bson:"secure"` // TLS/SSL
+
+	// Provider is the primary delivery provider: smtp (default), sendgrid or
+	// ses. FallbackProviders are tried in order when it fails.
+	Provider          string   `json:"provider,omitempty" bson:"provider,omitempty"`
+	FallbackProviders []string `json:"fallback_providers,omitempty" bson:"fallback_providers,omitempty"`
+	SendGridAPIKey    string   `json:"sendgrid_api_key,omitempty" bson:"sendgrid_api_key,omitempty"`
+	SESRegion         string   `json:"ses_region,omitempty" bson:"ses_region,omitempty"`
+	SESAccessKeyID    string   `json:"ses_access_key_id,omitempty" bson:"ses_access_key_id,omitempty"`
+	SESSecretKey      string   `json:"ses_secret_key,omitempty" bson:"ses_secret_key,omitempty"`
+
+	// SendingDomain is the tenant's authenticated domain; the from address
+	// must belong to it. SMTP mail is DKIM-signed when a selector and PEM
+	// private key are set (API providers sign with their domain setup).
+	SendingDomain  string `json:"sending_domain,omitempty" bson:"sending_domain,omitempty"`
+	DKIMSelector   string `json:"dkim_selector,omitempty" bson:"dkim_selector,omitempty"`
+	DKIMPrivateKey string `json:"dkim_private_key,omitempty" bson:"dkim_private_key,omitempty"`
 }
 
 type GeneralConfig struct {
@@ -42,6 +64,23 @@ type FileSharingConfig struct {
 	AllowSharedDocuments bool     `json:"allow_shared_documents" bson:"allow_shared_documents"`
 }
 
+// ImpersonationConfig controls whether admins may act as other users
+type ImpersonationConfig struct {
+	Enabled    bool `json:"enabled" bson:"enabled"`
+	MaxMinutes int  `json:"max_minutes" bson:"max_minutes"` // Longest session an admin can start
+}
+
+// AIConfig selects the tenant's AI provider and key. The key is never
+// returned; APIKeySet reports whether one is stored.
+type AIConfig struct {
+	Enabled   bool   `json:"enabled" bson:"enabled"`
+	Provider  string `json:"provider" bson:"provider"` // openai or anthropic
+	Model     string `json:"model,omitempty" bson:"model,omitempty"`
+	APIKey    string `json:"api_key,omitempty" bson:"api_key,omitempty"`
+	BaseURL   string `json:"base_url,omitempty" bson:"base_url,omitempty"` // Optional, for compatible gateways
+	APIKeySet bool   `json:"api_key_set" bson:"-"`
+}
+
 type Settings struct {
 	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	TenantID    primitive.ObjectID `json:"tenant_id" bson:"tenant_id,omitempty"`
@@ -49,6 +88,10 @@ type Settings struct {
 	Email       *EmailConfig       `json:"email,omitempty" bson:"email,omitempty"`
 	General     *GeneralConfig     `json:"general,omitempty" bson:"general,omitempty"`
 	FileSharing *FileSharingConfig `json:"file_sharing,omitempty" bson:"file_sharing,omitempty"`
+	Locale      *locale.Settings   `json:"locale,omitempty" bson:"locale,omitempty"`
 	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+
+	Impersonation *ImpersonationConfig `json:"impersonation,omitempty" bson:"impersonation,omitempty"`
+	AI            *AIConfig            `json:"ai,omitempty" bson:"ai,omitempty"`
 }
@@ -2,10 +2,13 @@ package settings
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	common_models "go-crm/internal/common/models"
 	"go-crm/internal/features/audit"
+	"go-crm/internal/features/user"
+	"go-crm/pkg/locale"
 )
 
 type SettingsService interface {
@@ -15,16 +18,39 @@ type SettingsService interface {
 	UpdateGeneralConfig(ctx context.Context, config GeneralConfig) error
 	GetFileSharingConfig(ctx context.Context) (*FileSharingConfig, error)
 	UpdateFileSharingConfig(ctx context.Context, config FileSharingConfig) error
+	GetLocaleConfig(ctx context.Context) (*locale.Settings, error)
+	UpdateLocaleConfig(ctx context.Context, config locale.Settings) error
+	GetUserLocale(ctx context.Context, userID string) (*locale.Settings, error)
+	UpdateUserLocale(ctx context.Context, userID string, config locale.Settings) error
+	// Formatter resolves the user's locale over the tenant's; an empty
+	// userID formats with the tenant settings only
+	Formatter(ctx context.Context, userID string) *locale.Formatter
+	// ResolveLocale is the user's locale settings merged over the tenant's
+	ResolveLocale(ctx context.Context, userID string) locale.Settings
+	GetImpersonationConfig(ctx context.Context) (*ImpersonationConfig, error)
+	UpdateImpersonationConfig(ctx context.Context, config ImpersonationConfig) error
+	// GetAIConfig returns the stored AI configuration, key included, for
+	// server-side use; the API strips the key
+	GetAIConfig(ctx context.Context) (*AIConfig, error)
+	// UpdateAIConfig keeps the stored key when config has none
+	UpdateAIConfig(ctx context.Context, config AIConfig) error
+	// Location is the user's timezone, falling back to the tenant's, then UTC
+	Location(ctx context.Context, userID string) *time.Location
+	// PhoneRegion is the country the user's national phone numbers are read
+	// in, falling back to the tenant's; see locale.Settings.PhoneRegion
+	PhoneRegion(ctx context.Context, userID string) string
 }
 
 type SettingsServiceImpl struct {
 	Repo         SettingsRepository
+	UserRepo     user.UserRepository
 	AuditService audit.AuditService
 }
 
-func NewSettingsService(repo SettingsRepository, auditService audit.AuditService) SettingsService {
+func NewSettingsService(repo SettingsRepository, userRepo user.UserRepository, auditService audit.AuditService) SettingsService {
 	return &SettingsServiceImpl{
 		Repo:         repo,
+		UserRepo:     userRepo,
 		AuditService: auditService,
 	}
 }
@@ -130,3 +156,172 @@ func (s *SettingsServiceImpl) UpdateFileSharingConfig(ctx context.Context, confi
 	}
 	return err
 }
+
+func (s *SettingsServiceImpl) GetLocaleConfig(ctx context.Context) (*locale.Settings, error) {
+	settings, err := s.Repo.GetByType(ctx, SettingsTypeLocale)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil || settings.Locale == nil {
+		return &locale.Settings{
+			Locale:   locale.DefaultLocale,
+			Currency: locale.DefaultCurrency,
+		}, nil
+	}
+	return settings.Locale, nil
+}
+
+func (s *SettingsServiceImpl) UpdateLocaleConfig(ctx context.Context, config locale.Settings) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	oldConfig, _ := s.GetLocaleConfig(ctx)
+
+	settings := &Settings{
+		Type:      SettingsTypeLocale,
+		Locale:    &config,
+		UpdatedAt: time.Now(),
+	}
+	err := s.Repo.Upsert(ctx, settings)
+	if err == nil {
+		_ = s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "settings", "locale_config", map[string]common_models.Change{
+			"locale_config": {
+				Old: oldConfig,
+				New: config,
+			},
+		})
+	}
+	return err
+}
+
+func (s *SettingsServiceImpl) GetUserLocale(ctx context.Context, userID string) (*locale.Settings, error) {
+	u, err := s.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.Locale == nil {
+		return &locale.Settings{}, nil
+	}
+	return u.Locale, nil
+}
+
+func (s *SettingsServiceImpl) UpdateUserLocale(ctx context.Context, userID string, config locale.Settings) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	u, err := s.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	u.Locale = &config
+	u.UpdatedAt = time.Now()
+	return s.UserRepo.Update(ctx, userID, u)
+}
+
+func (s *SettingsServiceImpl) ResolveLocale(ctx context.Context, userID string) locale.Settings {
+	var resolved locale.Settings
+	if tenant, err := s.GetLocaleConfig(ctx); err == nil && tenant != nil {
+		resolved = *tenant
+	}
+	if userID != "" && s.UserRepo != nil {
+		if u, err := s.UserRepo.FindByID(ctx, userID); err == nil && u.Locale != nil {
+			resolved = u.Locale.Merge(resolved)
+		}
+	}
+	return resolved
+}
+
+func (s *SettingsServiceImpl) Formatter(ctx context.Context, userID string) *locale.Formatter {
+	return locale.New(s.ResolveLocale(ctx, userID))
+}
+
+func (s *SettingsServiceImpl) Location(ctx context.Context, userID string) *time.Location {
+	return s.Formatter(ctx, userID).Location()
+}
+
+func (s *SettingsServiceImpl) PhoneRegion(ctx context.Context, userID string) string {
+	return s.Formatter(ctx, userID).Settings().PhoneRegion()
+}
+
+func (s *SettingsServiceImpl) GetImpersonationConfig(ctx context.Context) (*ImpersonationConfig, error) {
+	settings, err := s.Repo.GetByType(ctx, SettingsTypeImpersonation)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil || settings.Impersonation == nil {
+		return &ImpersonationConfig{
+			Enabled:    true,
+			MaxMinutes: 60,
+		}, nil
+	}
+	return settings.Impersonation, nil
+}
+
+func (s *SettingsServiceImpl) UpdateImpersonationConfig(ctx context.Context, config ImpersonationConfig) error {
+	if config.MaxMinutes < 1 || config.MaxMinutes > 24*60 {
+		return errors.New("max_minutes must be between 1 and 1440")
+	}
+	oldConfig, _ := s.GetImpersonationConfig(ctx)
+
+	settings := &Settings{
+		Type:          SettingsTypeImpersonation,
+		Impersonation: &config,
+		UpdatedAt:     time.Now(),
+	}
+	err := s.Repo.Upsert(ctx, settings)
+	if err == nil {
+		_ = s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "settings", "impersonation_config", map[string]common_models.Change{
+			"impersonation_config": {
+				Old: oldConfig,
+				New: config,
+			},
+		})
+	}
+	return err
+}
+
+func (s *SettingsServiceImpl) GetAIConfig(ctx context.Context) (*AIConfig, error) {
+	settings, err := s.Repo.GetByType(ctx, SettingsTypeAI)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil || settings.AI == nil {
+		return &AIConfig{}, nil
+	}
+	settings.AI.APIKeySet = settings.AI.APIKey != ""
+	return settings.AI, nil
+}
+
+func (s *SettingsServiceImpl) UpdateAIConfig(ctx context.Context, config AIConfig) error {
+	oldConfig, err := s.GetAIConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if config.APIKey == "" {
+		config.APIKey = oldConfig.APIKey
+	}
+	if config.Enabled && (config.Provider == "" || config.APIKey == "") {
+		return errors.New("provider and api_key are required to enable AI")
+	}
+
+	settings := &Settings{
+		Type:      SettingsTypeAI,
+		AI:        &config,
+		UpdatedAt: time.Now(),
+	}
+	err = s.Repo.Upsert(ctx, settings)
+	if err == nil {
+		// Keys stay out of the audit log
+		redact := func(c AIConfig) AIConfig {
+			c.APIKeySet, c.APIKey = c.APIKey != "", ""
+			return c
+		}
+		_ = s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "settings", "ai_config", map[string]common_models.Change{
+			"ai_config": {
+				Old: redact(*oldConfig),
+				New: redact(config),
+			},
+		})
+	}
+	return err
+}
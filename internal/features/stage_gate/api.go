@@ -0,0 +1,31 @@
+package stage_gate
+
+import (
+	"go-crm/internal/config"
+	"go-crm/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type StageGateApi struct {
+	controller  *StageGateController
+	config      *config.Config
+	roleService middleware.RoleService
+}
+
+func NewStageGateApi(controller *StageGateController, config *config.Config, roleService middleware.RoleService) *StageGateApi {
+	return &StageGateApi{
+		controller:  controller,
+		config:      config,
+		roleService: roleService,
+	}
+}
+
+func (h *StageGateApi) Setup(app *fiber.App) {
+	group := app.Group("/api/stage-gates", middleware.AuthMiddleware(h.config.SkipAuth))
+
+	group.Get("/", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.ListGates)
+	group.Get("/:module", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.GetGate)
+	group.Put("/:module", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.SaveGate)
+	group.Delete("/:module", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.DeleteGate)
+}
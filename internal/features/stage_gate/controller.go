@@ -0,0 +1,91 @@
+package stage_gate
+
+import (
+	"errors"
+
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type StageGateController struct {
+	Service StageGateService
+}
+
+func NewStageGateController(service StageGateService) *StageGateController {
+	return &StageGateController{Service: service}
+}
+
+// ListGates godoc
+// @Summary List stage gates
+// @Tags stage-gates
+// @Produce json
+// @Success 200 {array} StageGate
+// @Router /api/stage-gates [get]
+func (c *StageGateController) ListGates(ctx *fiber.Ctx) error {
+	gates, err := c.Service.ListGates(ctx.UserContext())
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": gates})
+}
+
+// GetGate godoc
+// @Summary Get a module's stage gate
+// @Tags stage-gates
+// @Produce json
+// @Param module path string true "Module name, or tickets"
+// @Success 200 {object} StageGate
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/stage-gates/{module} [get]
+func (c *StageGateController) GetGate(ctx *fiber.Ctx) error {
+	g, err := c.Service.GetGate(ctx.UserContext(), ctx.Params("module"))
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, ErrGateNotFound) {
+			status = fiber.StatusNotFound
+		}
+		return ctx.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": g})
+}
+
+// SaveGate godoc
+// @Summary Save a module's stage gate
+// @Description Required fields and conditions per stage, and the stages each stage may move to. Writes that break the gate are rejected with the missing requirements.
+// @Tags stage-gates
+// @Accept json
+// @Produce json
+// @Param module path string true "Module name, or tickets"
+// @Param gate body StageGate true "Stage gate"
+// @Success 200 {object} StageGate
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/stage-gates/{module} [put]
+func (c *StageGateController) SaveGate(ctx *fiber.Ctx) error {
+	userID, ok := session.UserID(ctx.UserContext())
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var g StageGate
+	if err := ctx.BodyParser(&g); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := c.Service.SaveGate(ctx.UserContext(), ctx.Params("module"), &g, userID); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": g})
+}
+
+// DeleteGate godoc
+// @Summary Delete a module's stage gate
+// @Tags stage-gates
+// @Param module path string true "Module name, or tickets"
+// @Success 204
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/stage-gates/{module} [delete]
+func (c *StageGateController) DeleteGate(ctx *fiber.Ctx) error {
+	if err := c.Service.DeleteGate(ctx.UserContext(), ctx.Params("module")); err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,48 @@
+package stage_gate
+
+import (
+	"time"
+
+	"go-crm/internal/features/ticket"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TicketsModule is the module name stage gates use for tickets, which are
+// not module records
+const TicketsModule = ticket.StageGateModuleName
+
+// Condition must hold on the record for it to enter the stage. Operators
+// are eq, ne, gt, gte, lt, lte, in and nin; in and nin take a list.
+type Condition struct {
+	Field    string      `json:"field" bson:"field"`
+	Operator string      `json:"operator" bson:"operator"`
+	Value    interface{} `json:"value" bson:"value"`
+	// Message replaces the generated explanation when the condition fails
+	Message string `json:"message,omitempty" bson:"message,omitempty"`
+}
+
+// StageRule lists what a record needs before it can enter Stage
+type StageRule struct {
+	Stage          string      `json:"stage" bson:"stage"`
+	RequiredFields []string    `json:"required_fields,omitempty" bson:"required_fields,omitempty"`
+	Conditions     []Condition `json:"conditions,omitempty" bson:"conditions,omitempty"`
+}
+
+// StageGate holds the transition rules of one module's stage field
+type StageGate struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID   primitive.ObjectID `json:"tenant_id" bson:"tenant_id"`
+	ModuleName string             `json:"module_name" bson:"module_name"`
+	// Field is the select field holding the stage: stage for opportunities,
+	// status for leads and tickets
+	Field  string `json:"field" bson:"field"`
+	Active bool   `json:"active" bson:"active"`
+	// Transitions maps a stage to the stages it may move to. Stages without
+	// an entry may move to any stage.
+	Transitions map[string][]string `json:"transitions,omitempty" bson:"transitions,omitempty"`
+	Rules       []StageRule         `json:"rules" bson:"rules"`
+	UpdatedBy   primitive.ObjectID  `json:"updated_by" bson:"updated_by"`
+	CreatedAt   time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at" bson:"updated_at"`
+}
@@ -0,0 +1,116 @@
+package stage_gate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-crm/internal/common/models"
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func tenantFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	tenantIDStr, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantIDStr == "" {
+		return primitive.NilObjectID, fmt.Errorf("tenant ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(tenantIDStr)
+}
+
+type StageGateRepository interface {
+	// FindByModule returns the module's gate, or nil when it has none
+	FindByModule(ctx context.Context, moduleName string) (*StageGate, error)
+	List(ctx context.Context) ([]StageGate, error)
+	// Upsert replaces the module's gate
+	Upsert(ctx context.Context, g *StageGate) error
+	Delete(ctx context.Context, moduleName string) error
+}
+
+type StageGateRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewStageGateRepository(db *database.MongodbDB) StageGateRepository {
+	return &StageGateRepositoryImpl{
+		collection: db.DB.Collection("stage_gates"),
+	}
+}
+
+func (r *StageGateRepositoryImpl) FindByModule(ctx context.Context, moduleName string) (*StageGate, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var g StageGate
+	err = r.collection.FindOne(ctx, bson.M{"tenant_id": tenantID, "module_name": moduleName}).Decode(&g)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+func (r *StageGateRepositoryImpl) List(ctx context.Context) ([]StageGate, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.M{"module_name": 1})
+	cursor, err := r.collection.Find(ctx, bson.M{"tenant_id": tenantID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	gates := []StageGate{}
+	if err := cursor.All(ctx, &gates); err != nil {
+		return nil, err
+	}
+	return gates, nil
+}
+
+func (r *StageGateRepositoryImpl) Upsert(ctx context.Context, g *StageGate) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	g.TenantID = tenantID
+	g.UpdatedAt = now
+
+	filter := bson.M{"tenant_id": tenantID, "module_name": g.ModuleName}
+	update := bson.M{
+		"$set": bson.M{
+			"field":       g.Field,
+			"active":      g.Active,
+			"transitions": g.Transitions,
+			"rules":       g.Rules,
+			"updated_by":  g.UpdatedBy,
+			"updated_at":  now,
+		},
+		"$setOnInsert": bson.M{"created_at": now},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	return r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(g)
+}
+
+func (r *StageGateRepositoryImpl) Delete(ctx context.Context, moduleName string) error {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	res, err := r.collection.DeleteOne(ctx, bson.M{"tenant_id": tenantID, "module_name": moduleName})
+	if err == nil && res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return err
+}
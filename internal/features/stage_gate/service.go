@@ -0,0 +1,383 @@
+package stage_gate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/ticket"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrGateNotFound = errors.New("stage gate not found")
+
+var ticketStatuses = []string{
+	string(ticket.TicketStatusNew),
+	string(ticket.TicketStatusOpen),
+	string(ticket.TicketStatusPending),
+	string(ticket.TicketStatusResolved),
+	string(ticket.TicketStatusClosed),
+}
+
+type StageGateService interface {
+	ListGates(ctx context.Context) ([]StageGate, error)
+	GetGate(ctx context.Context, moduleName string) (*StageGate, error)
+	SaveGate(ctx context.Context, moduleName string, g *StageGate, userID primitive.ObjectID) error
+	DeleteGate(ctx context.Context, moduleName string) error
+
+	// ValidateStage checks a write against the module's gate and returns a
+	// *record.StageTransitionError when the record cannot enter its new stage. previous
+	// is nil on create; next is the record as it would be saved.
+	ValidateStage(ctx context.Context, moduleName string, previous, next map[string]interface{}) error
+}
+
+type StageGateServiceImpl struct {
+	Repo         StageGateRepository
+	ModuleRepo   module.ModuleRepository
+	AuditService audit.AuditService
+}
+
+func NewStageGateService(repo StageGateRepository, moduleRepo module.ModuleRepository, auditService audit.AuditService) StageGateService {
+	return &StageGateServiceImpl{
+		Repo:         repo,
+		ModuleRepo:   moduleRepo,
+		AuditService: auditService,
+	}
+}
+
+// stageOptions returns the allowed values of the gate's stage field
+func (s *StageGateServiceImpl) stageOptions(ctx context.Context, moduleName, field string) ([]string, error) {
+	if moduleName == TicketsModule {
+		if field != "status" {
+			return nil, errors.New("ticket stage gates apply to the status field")
+		}
+		return ticketStatuses, nil
+	}
+	m, err := s.ModuleRepo.FindByName(ctx, moduleName)
+	if err != nil {
+		return nil, errors.New("module not found")
+	}
+	for _, f := range m.Fields {
+		if f.Name != field {
+			continue
+		}
+		if f.Type != common_models.FieldTypeSelect {
+			return nil, fmt.Errorf("field %s is not a select field", field)
+		}
+		options := make([]string, 0, len(f.Options))
+		for _, o := range f.Options {
+			options = append(options, o.Value)
+		}
+		return options, nil
+	}
+	return nil, fmt.Errorf("field %s not found in %s", field, moduleName)
+}
+
+func validateGate(g *StageGate, stages []string) error {
+	known := func(stage string) error {
+		if !slices.Contains(stages, stage) {
+			return fmt.Errorf("unknown stage %q; expected one of %s", stage, strings.Join(stages, ", "))
+		}
+		return nil
+	}
+	for from, tos := range g.Transitions {
+		if err := known(from); err != nil {
+			return err
+		}
+		for _, to := range tos {
+			if err := known(to); err != nil {
+				return err
+			}
+		}
+	}
+	seen := map[string]bool{}
+	for _, rule := range g.Rules {
+		if err := known(rule.Stage); err != nil {
+			return err
+		}
+		if seen[rule.Stage] {
+			return fmt.Errorf("stage %s has more than one rule", rule.Stage)
+		}
+		seen[rule.Stage] = true
+		for _, c := range rule.Conditions {
+			if c.Field == "" {
+				return fmt.Errorf("stage %s: condition field is required", rule.Stage)
+			}
+			switch c.Operator {
+			case "eq", "ne", "gt", "gte", "lt", "lte", "in", "nin":
+			default:
+				return fmt.Errorf("stage %s: unknown operator %q", rule.Stage, c.Operator)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *StageGateServiceImpl) ListGates(ctx context.Context) ([]StageGate, error) {
+	return s.Repo.List(ctx)
+}
+
+func (s *StageGateServiceImpl) GetGate(ctx context.Context, moduleName string) (*StageGate, error) {
+	g, err := s.Repo.FindByModule(ctx, moduleName)
+	if err != nil {
+		return nil, err
+	}
+	if g == nil {
+		return nil, ErrGateNotFound
+	}
+	return g, nil
+}
+
+func (s *StageGateServiceImpl) SaveGate(ctx context.Context, moduleName string, g *StageGate, userID primitive.ObjectID) error {
+	g.ModuleName = moduleName
+	if g.Field == "" {
+		g.Field = "stage"
+		if moduleName == TicketsModule {
+			g.Field = "status"
+		}
+	}
+	stages, err := s.stageOptions(ctx, moduleName, g.Field)
+	if err != nil {
+		return err
+	}
+	if err := validateGate(g, stages); err != nil {
+		return err
+	}
+
+	old, _ := s.Repo.FindByModule(ctx, moduleName)
+	g.UpdatedBy = userID
+	if err := s.Repo.Upsert(ctx, g); err != nil {
+		return err
+	}
+
+	change := common_models.Change{New: g}
+	if old != nil {
+		change.Old = old
+	}
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "stage_gates", moduleName, map[string]common_models.Change{
+		"stage_gate": change,
+	})
+	return nil
+}
+
+func (s *StageGateServiceImpl) DeleteGate(ctx context.Context, moduleName string) error {
+	if err := s.Repo.Delete(ctx, moduleName); err != nil {
+		return ErrGateNotFound
+	}
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "stage_gates", moduleName, map[string]common_models.Change{
+		"stage_gate": {Old: moduleName, New: "DELETED"},
+	})
+	return nil
+}
+
+func stringValue(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case primitive.ObjectID:
+		return t.Hex()
+	case map[string]interface{}:
+		// Populated lookup
+		return fmt.Sprint(t["id"])
+	}
+	return fmt.Sprint(v)
+}
+
+func isEmpty(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(t) == ""
+	case primitive.ObjectID:
+		return t.IsZero()
+	case []interface{}:
+		return len(t) == 0
+	case primitive.A:
+		return len(t) == 0
+	case []string:
+		return len(t) == 0
+	}
+	return false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func listValues(v interface{}) []string {
+	var out []string
+	switch t := v.(type) {
+	case []interface{}:
+		for _, x := range t {
+			out = append(out, stringValue(x))
+		}
+	case primitive.A:
+		for _, x := range t {
+			out = append(out, stringValue(x))
+		}
+	case []string:
+		out = t
+	case string:
+		for _, x := range strings.Split(t, ",") {
+			out = append(out, strings.TrimSpace(x))
+		}
+	}
+	return out
+}
+
+var operatorText = map[string]string{
+	"eq":  "must be",
+	"ne":  "must not be",
+	"gt":  "must be greater than",
+	"gte": "must be at least",
+	"lt":  "must be less than",
+	"lte": "must be at most",
+	"in":  "must be one of",
+	"nin": "must not be one of",
+}
+
+// holds evaluates a condition; a missing field only satisfies ne and nin
+func holds(c Condition, rec map[string]interface{}) bool {
+	val := rec[c.Field]
+	switch c.Operator {
+	case "eq":
+		return !isEmpty(val) && stringValue(val) == stringValue(c.Value)
+	case "ne":
+		return stringValue(val) != stringValue(c.Value)
+	case "in":
+		return !isEmpty(val) && slices.Contains(listValues(c.Value), stringValue(val))
+	case "nin":
+		return !slices.Contains(listValues(c.Value), stringValue(val))
+	}
+	a, ok1 := toFloat(val)
+	b, ok2 := toFloat(c.Value)
+	if !ok1 || !ok2 {
+		return false
+	}
+	switch c.Operator {
+	case "gt":
+		return a > b
+	case "gte":
+		return a >= b
+	case "lt":
+		return a < b
+	case "lte":
+		return a <= b
+	}
+	return false
+}
+
+func humanize(field string) string {
+	words := strings.Split(field, "_")
+	for i, w := range words {
+		if w != "" {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func (s *StageGateServiceImpl) labels(ctx context.Context, moduleName string) map[string]string {
+	labels := map[string]string{}
+	if moduleName == TicketsModule {
+		return labels
+	}
+	if m, err := s.ModuleRepo.FindByName(ctx, moduleName); err == nil {
+		for _, f := range m.Fields {
+			labels[f.Name] = f.Label
+		}
+	}
+	return labels
+}
+
+func (s *StageGateServiceImpl) ValidateStage(ctx context.Context, moduleName string, previous, next map[string]interface{}) error {
+	if _, ok := ctx.Value(common_models.TenantIDKey).(string); !ok {
+		// System writes without a tenant are not gated
+		return nil
+	}
+	g, err := s.Repo.FindByModule(ctx, moduleName)
+	if err != nil {
+		return err
+	}
+	if g == nil || !g.Active {
+		return nil
+	}
+
+	to := stringValue(next[g.Field])
+	from := stringValue(previous[g.Field])
+	if to == "" || (previous != nil && to == from) {
+		return nil
+	}
+
+	if previous != nil && from != "" {
+		if allowed, ok := g.Transitions[from]; ok && !slices.Contains(allowed, to) {
+			if allowed == nil {
+				allowed = []string{}
+			}
+			return &record.StageTransitionError{Field: g.Field, From: from, To: to, Allowed: allowed}
+		}
+	}
+
+	i := slices.IndexFunc(g.Rules, func(r StageRule) bool { return r.Stage == to })
+	if i < 0 {
+		return nil
+	}
+	rule := g.Rules[i]
+	labels := s.labels(ctx, moduleName)
+	label := func(field string) string {
+		if l := labels[field]; l != "" {
+			return l
+		}
+		return humanize(field)
+	}
+
+	terr := &record.StageTransitionError{Field: g.Field, From: from, To: to}
+	for _, field := range rule.RequiredFields {
+		if isEmpty(next[field]) {
+			terr.Missing = append(terr.Missing, label(field))
+		}
+	}
+	for _, c := range rule.Conditions {
+		if holds(c, next) {
+			continue
+		}
+		msg := c.Message
+		if msg == "" {
+			msg = fmt.Sprintf("%s %s %s", label(c.Field), operatorText[c.Operator], strings.Join(listValues(c.Value), ", "))
+			if c.Operator != "in" && c.Operator != "nin" {
+				msg = fmt.Sprintf("%s %s %v", label(c.Field), operatorText[c.Operator], c.Value)
+			}
+		}
+		terr.Unmet = append(terr.Unmet, msg)
+	}
+	if len(terr.Missing) > 0 || len(terr.Unmet) > 0 {
+		return terr
+	}
+	return nil
+}
@@ -0,0 +1,507 @@
+package sync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxLoggedErrors caps the per-record errors stored on one sync log
+const maxLoggedErrors = 200
+
+const recordPageSize = int64(1000)
+
+// withDefaults fills in the optional parts of a module config
+func (c ModuleSyncConfig) withDefaults() ModuleSyncConfig {
+	if c.Direction == "" {
+		c.Direction = DirectionPush
+	}
+	if c.ConflictStrategy == "" {
+		c.ConflictStrategy = ConflictLastWriteWins
+	}
+	if c.TargetTable == "" {
+		c.TargetTable = c.ModuleName
+	}
+	if c.TargetKey == "" {
+		c.TargetKey = "id"
+	}
+	if c.TargetUpdatedAt == "" {
+		c.TargetUpdatedAt = "updated_at"
+	}
+	return c
+}
+
+func (c ModuleSyncConfig) pushes() bool {
+	return c.Direction == DirectionPush || c.Direction == DirectionBidirectional
+}
+
+func (c ModuleSyncConfig) pulls() bool {
+	return c.Direction == DirectionPull || c.Direction == DirectionBidirectional
+}
+
+func validateModuleConfig(c ModuleSyncConfig) error {
+	if c.ModuleName == "" {
+		return errors.New("module_name is required")
+	}
+	if len(c.Mapping) == 0 {
+		return fmt.Errorf("%s: mapping is required", c.ModuleName)
+	}
+	c = c.withDefaults()
+	switch c.Direction {
+	case DirectionPush, DirectionPull, DirectionBidirectional:
+	default:
+		return fmt.Errorf("%s: direction must be push, pull or bidirectional", c.ModuleName)
+	}
+	switch c.ConflictStrategy {
+	case ConflictLastWriteWins, ConflictCRMWins, ConflictTargetWins:
+	default:
+		return fmt.Errorf("%s: conflict_strategy must be last_write_wins, crm_wins or target_wins", c.ModuleName)
+	}
+	idents := []string{c.TargetTable, c.TargetKey, c.TargetUpdatedAt}
+	if c.TargetRowID != "" {
+		idents = append(idents, c.TargetRowID)
+	}
+	if c.TargetDeletedFlag != "" {
+		idents = append(idents, c.TargetDeletedFlag)
+	}
+	for _, col := range c.Mapping {
+		idents = append(idents, col)
+	}
+	for _, ident := range idents {
+		if !validIdent(ident) {
+			return fmt.Errorf("%s: invalid table or column name %q", c.ModuleName, ident)
+		}
+	}
+	return nil
+}
+
+// syncRun accumulates the outcome of one run into its log
+type syncRun struct {
+	setting *SyncSetting
+	log     *SyncLog
+	target  syncTarget
+}
+
+func (r *syncRun) fail(module, recordID, direction, operation string, err error) {
+	r.log.Failed++
+	if len(r.log.Errors) < maxLoggedErrors {
+		r.log.Errors = append(r.log.Errors, SyncRecordError{
+			Module:    module,
+			RecordID:  recordID,
+			Direction: direction,
+			Operation: operation,
+			Error:     err.Error(),
+		})
+	}
+}
+
+// syncModule runs one module: collect changes on both sides since the last
+// run, resolve conflicts, then push, pull and propagate deletes
+func (s *SyncServiceImpl) syncModule(ctx context.Context, run *syncRun, cfg ModuleSyncConfig) error {
+	cfg = cfg.withDefaults()
+	since := run.setting.LastSyncAt
+
+	crmChanges := map[string]map[string]any{}
+	if cfg.pushes() {
+		records, err := s.changedRecords(ctx, cfg.ModuleName, since)
+		if err != nil {
+			return err
+		}
+		for _, rec := range records {
+			crmChanges[idString(rec["_id"])] = rec
+		}
+	}
+
+	targetChanges := map[string]map[string]any{}
+	var targetCreates []map[string]any
+	if cfg.pulls() {
+		rows, err := run.target.Changed(ctx, cfg.TargetTable, cfg.TargetUpdatedAt, since)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if id := idString(row[cfg.TargetKey]); id != "" {
+				targetChanges[id] = row
+			} else {
+				targetCreates = append(targetCreates, row)
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(crmChanges)+len(targetChanges))
+	for id := range crmChanges {
+		ids = append(ids, id)
+	}
+	for id := range targetChanges {
+		if _, dup := crmChanges[id]; !dup {
+			ids = append(ids, id)
+		}
+	}
+	states, err := s.StateRepo.GetMany(ctx, run.setting.ID, cfg.ModuleName, ids)
+	if err != nil {
+		return err
+	}
+
+	// Records changed on both sides
+	for id, rec := range crmChanges {
+		row, both := targetChanges[id]
+		if !both {
+			continue
+		}
+		crmHash := rowHash(toTargetRow(rec, cfg), cfg)
+		targetHash := rowHash(row, cfg)
+		state, known := states[id]
+
+		switch {
+		case crmHash == targetHash:
+			// Already identical; record the state and touch neither side
+			run.log.Skipped++
+			s.saveState(ctx, run, cfg, id, crmHash)
+			delete(crmChanges, id)
+			delete(targetChanges, id)
+		case known && state.Hash == crmHash:
+			delete(crmChanges, id) // only the target really changed
+		case known && state.Hash == targetHash:
+			delete(targetChanges, id) // only the CRM really changed
+		default:
+			run.log.Conflicts++
+			if crmWins(cfg, rec, row) {
+				delete(targetChanges, id)
+			} else {
+				delete(crmChanges, id)
+			}
+		}
+	}
+
+	if cfg.pushes() {
+		s.push(ctx, run, cfg, crmChanges, states)
+		if cfg.SyncDeletes {
+			if err := s.pushDeletes(ctx, run, cfg, since); err != nil {
+				return err
+			}
+		}
+	}
+	if cfg.pulls() {
+		s.pull(ctx, run, cfg, targetChanges, states)
+		s.pullCreates(ctx, run, cfg, targetCreates)
+	}
+	return nil
+}
+
+func (s *SyncServiceImpl) changedRecords(ctx context.Context, moduleName string, since time.Time) ([]map[string]any, error) {
+	filters := bson.M{
+		"updated_at": bson.M{"$gt": since},
+	}
+
+	var all []map[string]any
+	for page := int64(0); ; page++ {
+		records, err := s.RecordRepo.List(ctx, moduleName, filters, nil, recordPageSize, page*recordPageSize, "updated_at", 1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch records for %s on page %d: %v", moduleName, page+1, err)
+		}
+		all = append(all, records...)
+		if len(records) < int(recordPageSize) {
+			return all, nil
+		}
+	}
+}
+
+func (s *SyncServiceImpl) push(ctx context.Context, run *syncRun, cfg ModuleSyncConfig, changes map[string]map[string]any, states map[string]SyncRecordState) {
+	ids := []string{}
+	rows := []map[string]any{}
+	hashes := []string{}
+	for id, rec := range changes {
+		row := toTargetRow(rec, cfg)
+		hash := rowHash(row, cfg)
+		if state, ok := states[id]; ok && state.Hash == hash {
+			run.log.Skipped++
+			continue
+		}
+		ids = append(ids, id)
+		rows = append(rows, row)
+		hashes = append(hashes, hash)
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	errs, err := run.target.Upsert(ctx, cfg.TargetTable, cfg.TargetKey, rows)
+	if err != nil {
+		for _, id := range ids {
+			run.fail(cfg.ModuleName, id, DirectionPush, "upsert", err)
+		}
+		return
+	}
+	for i, id := range ids {
+		if errs[i] != nil {
+			run.fail(cfg.ModuleName, id, DirectionPush, "upsert", errs[i])
+			continue
+		}
+		run.log.Pushed++
+		s.saveState(ctx, run, cfg, id, hashes[i])
+	}
+}
+
+// pushDeletes propagates CRM deletions recorded in the audit log
+func (s *SyncServiceImpl) pushDeletes(ctx context.Context, run *syncRun, cfg ModuleSyncConfig, since time.Time) error {
+	filters := map[string]interface{}{
+		"action":    common_models.AuditActionDelete,
+		"module":    cfg.ModuleName,
+		"timestamp": map[string]interface{}{"$gt": since},
+	}
+
+	limit := int64(1000)
+	for page := int64(1); ; page++ {
+		logs, err := s.AuditService.ListLogs(ctx, filters, page, limit)
+		if err != nil {
+			return fmt.Errorf("failed to fetch delete logs for %s on page %d: %v", cfg.ModuleName, page, err)
+		}
+		if len(logs) == 0 {
+			return nil
+		}
+
+		ids := make([]string, 0, len(logs))
+		for _, l := range logs {
+			ids = append(ids, l.RecordID)
+		}
+		deleted, err := run.target.Delete(ctx, cfg.TargetTable, cfg.TargetKey, ids)
+		if err != nil {
+			for _, id := range ids {
+				run.fail(cfg.ModuleName, id, DirectionPush, "delete", err)
+			}
+		} else {
+			run.log.Deleted += deleted
+			_ = s.StateRepo.Delete(ctx, run.setting.ID, cfg.ModuleName, ids)
+		}
+
+		if len(logs) < int(limit) {
+			return nil
+		}
+	}
+}
+
+func (s *SyncServiceImpl) pull(ctx context.Context, run *syncRun, cfg ModuleSyncConfig, changes map[string]map[string]any, states map[string]SyncRecordState) {
+	for id, row := range changes {
+		if cfg.TargetDeletedFlag != "" && truthy(row[cfg.TargetDeletedFlag]) {
+			if !cfg.SyncDeletes {
+				run.log.Skipped++
+				continue
+			}
+			if err := s.RecordRepo.Delete(ctx, cfg.ModuleName, id, primitive.NilObjectID); err != nil {
+				run.fail(cfg.ModuleName, id, DirectionPull, "delete", err)
+				continue
+			}
+			run.log.Deleted++
+			_ = s.StateRepo.Delete(ctx, run.setting.ID, cfg.ModuleName, []string{id})
+			continue
+		}
+
+		hash := rowHash(row, cfg)
+		if state, ok := states[id]; ok && state.Hash == hash {
+			run.log.Skipped++
+			continue
+		}
+		if _, err := s.RecordRepo.Get(ctx, cfg.ModuleName, id); err != nil {
+			run.fail(cfg.ModuleName, id, DirectionPull, "upsert", errors.New("record not found in CRM"))
+			continue
+		}
+		if err := s.RecordRepo.Update(ctx, cfg.ModuleName, id, fromTargetRow(row, cfg)); err != nil {
+			run.fail(cfg.ModuleName, id, DirectionPull, "upsert", err)
+			continue
+		}
+		run.log.Pulled++
+		s.saveState(ctx, run, cfg, id, hash)
+	}
+}
+
+// pullCreates imports target rows without a CRM ID and stamps the new ID back
+func (s *SyncServiceImpl) pullCreates(ctx context.Context, run *syncRun, cfg ModuleSyncConfig, rows []map[string]any) {
+	if len(rows) == 0 {
+		return
+	}
+	if cfg.TargetRowID == "" {
+		for range rows {
+			run.fail(cfg.ModuleName, "", DirectionPull, "create", errors.New("row has no CRM ID and target_row_id is not configured"))
+		}
+		return
+	}
+
+	product := common_models.ProductCRM
+	if mod, err := s.ModuleRepo.FindByName(ctx, cfg.ModuleName); err == nil && mod != nil && mod.Product != "" {
+		product = mod.Product
+	}
+
+	for _, row := range rows {
+		rowID := row[cfg.TargetRowID]
+		label := fmt.Sprintf("%s=%v", cfg.TargetRowID, rowID)
+		if rowID == nil {
+			run.fail(cfg.ModuleName, "", DirectionPull, "create", fmt.Errorf("row has no %s", cfg.TargetRowID))
+			continue
+		}
+		if cfg.TargetDeletedFlag != "" && truthy(row[cfg.TargetDeletedFlag]) {
+			run.log.Skipped++
+			continue
+		}
+
+		created, err := s.RecordRepo.Create(ctx, cfg.ModuleName, product, fromTargetRow(row, cfg))
+		if err != nil {
+			run.fail(cfg.ModuleName, label, DirectionPull, "create", err)
+			continue
+		}
+		id := idString(created)
+		if err := run.target.SetKey(ctx, cfg.TargetTable, cfg.TargetRowID, rowID, cfg.TargetKey, id); err != nil {
+			run.fail(cfg.ModuleName, id, DirectionPull, "create", fmt.Errorf("created in CRM but failed to stamp target row %s: %v", label, err))
+			continue
+		}
+		row[cfg.TargetKey] = id
+		run.log.Pulled++
+		s.saveState(ctx, run, cfg, id, rowHash(row, cfg))
+	}
+}
+
+func (s *SyncServiceImpl) saveState(ctx context.Context, run *syncRun, cfg ModuleSyncConfig, recordID, hash string) {
+	_ = s.StateRepo.Save(ctx, SyncRecordState{
+		SyncSettingID: run.setting.ID,
+		ModuleName:    cfg.ModuleName,
+		RecordID:      recordID,
+		Hash:          hash,
+		SyncedAt:      time.Now(),
+	})
+}
+
+// crmWins resolves a conflict according to the module's strategy
+func crmWins(cfg ModuleSyncConfig, rec, row map[string]any) bool {
+	switch cfg.ConflictStrategy {
+	case ConflictCRMWins:
+		return true
+	case ConflictTargetWins:
+		return false
+	}
+	crmTime, _ := timeValue(rec["updated_at"])
+	targetTime, ok := timeValue(row[cfg.TargetUpdatedAt])
+	return !ok || !targetTime.After(crmTime)
+}
+
+// toTargetRow maps a CRM record to target columns; the key always carries the CRM ID
+func toTargetRow(rec map[string]any, cfg ModuleSyncConfig) map[string]any {
+	row := map[string]any{}
+	for crmField, col := range cfg.Mapping {
+		val, ok := rec[crmField]
+		if !ok && crmField == "id" {
+			val = rec["_id"]
+		}
+		row[col] = writeValue(val)
+	}
+	row[cfg.TargetKey] = idString(rec["_id"])
+	return row
+}
+
+// fromTargetRow maps target columns back to CRM fields, leaving IDs alone
+func fromTargetRow(row map[string]any, cfg ModuleSyncConfig) map[string]any {
+	data := map[string]any{}
+	for crmField, col := range cfg.Mapping {
+		if crmField == "id" || crmField == "_id" || col == cfg.TargetKey {
+			continue
+		}
+		if val, ok := row[col]; ok {
+			data[crmField] = val
+		}
+	}
+	return data
+}
+
+// rowHash fingerprints the mapped columns of a row so the same content hashes
+// the same whichever side it was read from
+func rowHash(row map[string]any, cfg ModuleSyncConfig) string {
+	normalized := map[string]any{}
+	for _, col := range cfg.Mapping {
+		if col == cfg.TargetKey {
+			continue
+		}
+		normalized[col] = hashValue(row[col])
+	}
+	b, _ := json.Marshal(normalized)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func writeValue(v any) any {
+	switch val := v.(type) {
+	case primitive.ObjectID:
+		return val.Hex()
+	case primitive.DateTime:
+		return val.Time().UTC()
+	}
+	return v
+}
+
+func hashValue(v any) any {
+	if t, ok := timeValue(v); ok {
+		return t.UTC().Truncate(time.Second).Format(time.RFC3339)
+	}
+	switch val := v.(type) {
+	case primitive.ObjectID:
+		return val.Hex()
+	case []byte:
+		return string(val)
+	case int:
+		return float64(val)
+	case int32:
+		return float64(val)
+	case int64:
+		return float64(val)
+	}
+	return v
+}
+
+func timeValue(v any) (time.Time, bool) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, !val.IsZero()
+	case primitive.DateTime:
+		return val.Time(), true
+	}
+	return time.Time{}, false
+}
+
+func idString(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case primitive.ObjectID:
+		return val.Hex()
+	case string:
+		return val
+	case []byte:
+		return string(val)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func truthy(v any) bool {
+	switch val := v.(type) {
+	case bool:
+		return val
+	case int64:
+		return val != 0
+	case int32:
+		return val != 0
+	case int:
+		return val != 0
+	case string:
+		return val == "1" || val == "true" || val == "t"
+	case []byte:
+		s := string(val)
+		return s == "1" || s == "true" || s == "t"
+	}
+	return false
+}
@@ -0,0 +1,419 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/record"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memRecords is a CRM module kept in memory, keyed by hex ID
+type memRecords struct {
+	record.RecordRepository
+	records map[string]map[string]any
+}
+
+func (m *memRecords) List(ctx context.Context, moduleName string, filter map[string]any, accessFilter map[string]any, limit, offset int64, sortBy string, sortOrder int) ([]map[string]any, error) {
+	since := filter["updated_at"].(bson.M)["$gt"].(time.Time)
+	var list []map[string]any
+	for _, rec := range m.records {
+		if rec["updated_at"].(time.Time).After(since) {
+			list = append(list, copyRow(rec))
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i]["updated_at"].(time.Time).Before(list[j]["updated_at"].(time.Time))
+	})
+	if offset > 0 {
+		return nil, nil
+	}
+	return list, nil
+}
+
+func (m *memRecords) Get(ctx context.Context, moduleName, id string) (map[string]any, error) {
+	rec, ok := m.records[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return copyRow(rec), nil
+}
+
+func (m *memRecords) Update(ctx context.Context, moduleName, id string, data map[string]any) error {
+	for k, v := range data {
+		m.records[id][k] = v
+	}
+	m.records[id]["updated_at"] = time.Now()
+	return nil
+}
+
+// memTarget is an external table kept in memory, keyed by the CRM ID
+type memTarget struct {
+	rows     map[string]map[string]any
+	upserts  int
+	onUpsert func()
+	fail     error
+}
+
+func (t *memTarget) Upsert(ctx context.Context, table, key string, rows []map[string]any) ([]error, error) {
+	if t.fail != nil {
+		return nil, t.fail
+	}
+	for _, row := range rows {
+		t.rows[row[key].(string)] = copyRow(row)
+	}
+	t.upserts++
+	if t.onUpsert != nil {
+		t.onUpsert()
+	}
+	return make([]error, len(rows)), nil
+}
+
+func (t *memTarget) Delete(ctx context.Context, table, key string, ids []string) (int, error) {
+	n := 0
+	for _, id := range ids {
+		if _, ok := t.rows[id]; ok {
+			delete(t.rows, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (t *memTarget) Changed(ctx context.Context, table, updatedCol string, since time.Time) ([]map[string]any, error) {
+	var rows []map[string]any
+	for _, row := range t.rows {
+		if at, ok := row[updatedCol].(time.Time); ok && at.After(since) {
+			rows = append(rows, copyRow(row))
+		}
+	}
+	return rows, nil
+}
+
+func (t *memTarget) SetKey(ctx context.Context, table, rowIDCol string, rowID any, key, crmID string) error {
+	return errors.New("not supported")
+}
+
+func (t *memTarget) Close() {}
+
+type memStates struct {
+	states map[string]SyncRecordState
+}
+
+func (m *memStates) GetMany(ctx context.Context, settingID primitive.ObjectID, moduleName string, recordIDs []string) (map[string]SyncRecordState, error) {
+	found := map[string]SyncRecordState{}
+	for _, id := range recordIDs {
+		if st, ok := m.states[id]; ok {
+			found[id] = st
+		}
+	}
+	return found, nil
+}
+
+func (m *memStates) Save(ctx context.Context, state SyncRecordState) error {
+	m.states[state.RecordID] = state
+	return nil
+}
+
+func (m *memStates) Delete(ctx context.Context, settingID primitive.ObjectID, moduleName string, recordIDs []string) error {
+	for _, id := range recordIDs {
+		delete(m.states, id)
+	}
+	return nil
+}
+
+func (m *memStates) DeleteBySetting(ctx context.Context, settingID primitive.ObjectID) error {
+	m.states = map[string]SyncRecordState{}
+	return nil
+}
+
+// settingStore holds one setting and applies cursor updates to it
+type settingStore struct {
+	SyncSettingRepository
+	setting *SyncSetting
+}
+
+func (s *settingStore) Update(ctx context.Context, id string, updates map[string]interface{}) error {
+	if at, ok := updates["last_sync_at"].(time.Time); ok {
+		s.setting.LastSyncAt = at
+	}
+	return nil
+}
+
+type discardLogs struct{ SyncLogRepository }
+
+func (discardLogs) Create(ctx context.Context, log *SyncLog) error { return nil }
+func (discardLogs) Update(ctx context.Context, log *SyncLog) error { return nil }
+
+type discardAudit struct{ audit.AuditService }
+
+func (discardAudit) LogChange(ctx context.Context, action common_models.AuditAction, module string, recordID string, changes map[string]common_models.Change) error {
+	return nil
+}
+
+func copyRow(row map[string]any) map[string]any {
+	c := make(map[string]any, len(row))
+	for k, v := range row {
+		c[k] = v
+	}
+	return c
+}
+
+var testMapping = map[string]string{"name": "name", "amount": "amount"}
+
+func TestSyncModuleConflictStrategies(t *testing.T) {
+	since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	earlier, later := since.Add(time.Hour), since.Add(2*time.Hour)
+
+	tests := []struct {
+		name       string
+		strategy   string
+		crmAt      time.Time
+		targetAt   time.Time
+		targetName string
+		state      string // side the stored hash matches, if any
+		want       string // name both sides end up with
+		conflicts  int
+		pushed     int
+		pulled     int
+		skipped    int
+	}{
+		{name: "last write wins, CRM newer", strategy: ConflictLastWriteWins, crmAt: later, targetAt: earlier, want: "from crm", conflicts: 1, pushed: 1},
+		{name: "last write wins, target newer", strategy: ConflictLastWriteWins, crmAt: earlier, targetAt: later, want: "from target", conflicts: 1, pulled: 1},
+		{name: "last write wins, same time goes to the CRM", strategy: ConflictLastWriteWins, crmAt: later, targetAt: later, want: "from crm", conflicts: 1, pushed: 1},
+		{name: "crm wins over a newer target", strategy: ConflictCRMWins, crmAt: earlier, targetAt: later, want: "from crm", conflicts: 1, pushed: 1},
+		{name: "target wins over a newer CRM", strategy: ConflictTargetWins, crmAt: later, targetAt: earlier, want: "from target", conflicts: 1, pulled: 1},
+		{name: "default strategy is last write wins", crmAt: earlier, targetAt: later, want: "from target", conflicts: 1, pulled: 1},
+		{name: "same content on both sides", strategy: ConflictTargetWins, crmAt: later, targetAt: earlier, targetName: "from crm", want: "from crm", skipped: 1},
+		{name: "only the target really changed", strategy: ConflictCRMWins, crmAt: later, targetAt: earlier, state: "crm", want: "from target", pulled: 1},
+		{name: "only the CRM really changed", strategy: ConflictTargetWins, crmAt: earlier, targetAt: later, state: "target", want: "from crm", pushed: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := ModuleSyncConfig{ModuleName: "deals", Mapping: testMapping, Direction: DirectionBidirectional, ConflictStrategy: tt.strategy}.withDefaults()
+			id := primitive.NewObjectID()
+			if tt.targetName == "" {
+				tt.targetName = "from target"
+			}
+
+			records := &memRecords{records: map[string]map[string]any{
+				id.Hex(): {"_id": id, "name": "from crm", "amount": 10, "updated_at": tt.crmAt},
+			}}
+			target := &memTarget{rows: map[string]map[string]any{
+				id.Hex(): {"id": id.Hex(), "name": tt.targetName, "amount": int64(10), "updated_at": tt.targetAt},
+			}}
+			states := &memStates{states: map[string]SyncRecordState{}}
+			switch tt.state {
+			case "crm":
+				states.states[id.Hex()] = SyncRecordState{Hash: rowHash(toTargetRow(records.records[id.Hex()], cfg), cfg)}
+			case "target":
+				states.states[id.Hex()] = SyncRecordState{Hash: rowHash(target.rows[id.Hex()], cfg)}
+			}
+
+			s := &SyncServiceImpl{RecordRepo: records, StateRepo: states}
+			run := &syncRun{setting: &SyncSetting{LastSyncAt: since}, log: &SyncLog{}, target: target}
+			if err := s.syncModule(context.Background(), run, cfg); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := records.records[id.Hex()]["name"]; got != tt.want {
+				t.Errorf("CRM name = %v, want %q", got, tt.want)
+			}
+			if got := target.rows[id.Hex()]["name"]; got != tt.want {
+				t.Errorf("target name = %v, want %q", got, tt.want)
+			}
+			l := run.log
+			if l.Conflicts != tt.conflicts || l.Pushed != tt.pushed || l.Pulled != tt.pulled || l.Skipped != tt.skipped || l.Failed != 0 {
+				t.Errorf("log = conflicts %d, pushed %d, pulled %d, skipped %d, failed %d; want %d, %d, %d, %d, 0",
+					l.Conflicts, l.Pushed, l.Pulled, l.Skipped, l.Failed, tt.conflicts, tt.pushed, tt.pulled, tt.skipped)
+			}
+			if want := rowHash(target.rows[id.Hex()], cfg); states.states[id.Hex()].Hash != want {
+				t.Errorf("stored hash does not match the synced row")
+			}
+		})
+	}
+}
+
+func TestRowHashIgnoresKeyOrder(t *testing.T) {
+	values := map[string]any{
+		"a": "text", "b": 1.5, "c": true, "d": nil,
+		"e": time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), "f": int64(7), "g": "", "h": []any{"x", "y"},
+	}
+	cols := make([]string, 0, len(values))
+	for col := range values {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+
+	// Maps filled in a different order each time, for the mapping as well as the row
+	build := func(r *rand.Rand) (map[string]any, ModuleSyncConfig) {
+		row := map[string]any{"id": "rec-1"}
+		mapping := map[string]string{}
+		for _, i := range r.Perm(len(cols)) {
+			row[cols[i]] = values[cols[i]]
+			mapping["crm_"+cols[i]] = cols[i]
+		}
+		return row, ModuleSyncConfig{ModuleName: "deals", Mapping: mapping}.withDefaults()
+	}
+
+	r := rand.New(rand.NewSource(1))
+	want := rowHash(build(r))
+	for i := 0; i < 50; i++ {
+		if got := rowHash(build(r)); got != want {
+			t.Fatalf("hash %d = %s, want %s", i, got, want)
+		}
+	}
+}
+
+func TestRowHashAcrossSides(t *testing.T) {
+	cfg := ModuleSyncConfig{ModuleName: "deals", Mapping: map[string]string{"value": "value"}}.withDefaults()
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	oid := primitive.NewObjectID()
+
+	tests := []struct {
+		name   string
+		crm    any
+		target any
+		same   bool
+	}{
+		{name: "int and int64", crm: 5, target: int64(5), same: true},
+		{name: "int32 and float64", crm: int32(5), target: 5.0, same: true},
+		{name: "stored datetime and a time in another zone", crm: primitive.NewDateTimeFromTime(at), target: at.In(time.FixedZone("X", 3*60*60)), same: true},
+		{name: "sub-second precision", crm: at, target: at.Add(300 * time.Millisecond), same: true},
+		{name: "object ID and its hex", crm: oid, target: oid.Hex(), same: true},
+		{name: "bytes and text", crm: "abc", target: []byte("abc"), same: true},
+		{name: "nil and a missing column", crm: nil, target: nil, same: true},
+		{name: "different text", crm: "abc", target: "abd"},
+		{name: "a second apart", crm: at, target: at.Add(time.Second)},
+		{name: "different numbers", crm: 5, target: int64(6)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := map[string]any{"_id": primitive.NewObjectID()}
+			if tt.crm != nil {
+				rec["value"] = tt.crm
+			}
+			row := map[string]any{"id": "another-id"}
+			if tt.target != nil {
+				row["value"] = tt.target
+			}
+			crmHash := rowHash(toTargetRow(rec, cfg), cfg)
+			if same := crmHash == rowHash(row, cfg); same != tt.same {
+				t.Errorf("hashes equal = %v, want %v", same, tt.same)
+			}
+		})
+	}
+}
+
+func TestRunCursorPicksUpChangesMadeDuringRun(t *testing.T) {
+	id := primitive.NewObjectID()
+	records := &memRecords{records: map[string]map[string]any{
+		id.Hex(): {"_id": id, "name": "original", "amount": 10, "updated_at": time.Now().Add(-time.Hour)},
+	}}
+	target := &memTarget{rows: map[string]map[string]any{}}
+	setting := &SyncSetting{
+		ID:      primitive.NewObjectID(),
+		Name:    "warehouse",
+		Modules: []ModuleSyncConfig{{ModuleName: "deals", Mapping: testMapping}},
+	}
+	s := &SyncServiceImpl{
+		SyncRepo:     &settingStore{setting: setting},
+		LogRepo:      discardLogs{},
+		StateRepo:    &memStates{states: map[string]SyncRecordState{}},
+		RecordRepo:   records,
+		AuditService: discardAudit{},
+		openTarget: func(ctx context.Context, dbType string, cfg map[string]string) (syncTarget, error) {
+			return target, nil
+		},
+	}
+
+	// The record is edited while the first run is writing it out
+	var editedAt time.Time
+	target.onUpsert = func() {
+		target.onUpsert = nil
+		editedAt = time.Now()
+		records.records[id.Hex()]["name"] = "edited during run"
+		records.records[id.Hex()]["updated_at"] = editedAt
+	}
+	runStart := time.Now()
+	if err := s.executeSync(context.Background(), setting); err != nil {
+		t.Fatal(err)
+	}
+	if got := target.rows[id.Hex()]["name"]; got != "original" {
+		t.Fatalf("first run pushed %v, want the original", got)
+	}
+	cursor := setting.LastSyncAt
+	if cursor.Before(runStart) || !cursor.Before(editedAt) {
+		t.Fatalf("cursor %v should be the run start, before the edit at %v", cursor, editedAt)
+	}
+
+	if err := s.executeSync(context.Background(), setting); err != nil {
+		t.Fatal(err)
+	}
+	if got := target.rows[id.Hex()]["name"]; got != "edited during run" {
+		t.Fatalf("second run left %v, want the edit made during the first run", got)
+	}
+	if !setting.LastSyncAt.After(editedAt) {
+		t.Fatalf("cursor %v did not move past the edit at %v", setting.LastSyncAt, editedAt)
+	}
+
+	upserts := target.upserts
+	if err := s.executeSync(context.Background(), setting); err != nil {
+		t.Fatal(err)
+	}
+	if target.upserts != upserts {
+		t.Errorf("third run wrote %d batches, want none", target.upserts-upserts)
+	}
+}
+
+func TestRunKeepsCursorAfterFailures(t *testing.T) {
+	id := primitive.NewObjectID()
+	records := &memRecords{records: map[string]map[string]any{
+		id.Hex(): {"_id": id, "name": "original", "amount": 10, "updated_at": time.Now().Add(-time.Hour)},
+	}}
+	target := &memTarget{rows: map[string]map[string]any{}, fail: errors.New("connection reset")}
+	since := time.Now().Add(-24 * time.Hour)
+	setting := &SyncSetting{
+		ID:         primitive.NewObjectID(),
+		Modules:    []ModuleSyncConfig{{ModuleName: "deals", Mapping: testMapping}},
+		LastSyncAt: since,
+	}
+	s := &SyncServiceImpl{
+		SyncRepo:     &settingStore{setting: setting},
+		LogRepo:      discardLogs{},
+		StateRepo:    &memStates{states: map[string]SyncRecordState{}},
+		RecordRepo:   records,
+		AuditService: discardAudit{},
+		openTarget: func(ctx context.Context, dbType string, cfg map[string]string) (syncTarget, error) {
+			return target, nil
+		},
+	}
+
+	if err := s.executeSync(context.Background(), setting); err != nil {
+		t.Fatal(err)
+	}
+	if !setting.LastSyncAt.Equal(since) {
+		t.Fatalf("cursor moved to %v after a failed record", setting.LastSyncAt)
+	}
+
+	target.fail = nil
+	if err := s.executeSync(context.Background(), setting); err != nil {
+		t.Fatal(err)
+	}
+	if got := target.rows[id.Hex()]["name"]; got != "original" {
+		t.Errorf("retry pushed %v, want the record that failed", got)
+	}
+	if !setting.LastSyncAt.After(since) {
+		t.Errorf("cursor did not advance after a clean run")
+	}
+}
@@ -6,14 +6,43 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Sync directions
+const (
+	DirectionPush          = "push"          // CRM -> target (default)
+	DirectionPull          = "pull"          // target -> CRM
+	DirectionBidirectional = "bidirectional" // both, with conflict resolution
+)
+
+// Conflict strategies, applied when a record changed on both sides since the
+// last run
+const (
+	ConflictLastWriteWins = "last_write_wins" // newer updated_at wins (default)
+	ConflictCRMWins       = "crm_wins"        // CRM is the source of truth
+	ConflictTargetWins    = "target_wins"     // target is the source of truth
+)
+
 type ModuleSyncConfig struct {
 	ModuleName  string            `json:"module_name" bson:"module_name"`
 	Mapping     map[string]string `json:"mapping" bson:"mapping"` // CRM Field -> Target DB Column
 	SyncDeletes bool              `json:"sync_deletes" bson:"sync_deletes"`
+
+	Direction        string `json:"direction,omitempty" bson:"direction,omitempty"`
+	ConflictStrategy string `json:"conflict_strategy,omitempty" bson:"conflict_strategy,omitempty"`
+
+	// Target layout; defaults are the module name, "id" and "updated_at"
+	TargetTable     string `json:"target_table,omitempty" bson:"target_table,omitempty"`
+	TargetKey       string `json:"target_key,omitempty" bson:"target_key,omitempty"`               // Column holding the CRM record ID
+	TargetUpdatedAt string `json:"target_updated_at,omitempty" bson:"target_updated_at,omitempty"` // Change tracking column for pulls
+	// Optional for pulls: the target's own primary key, so rows created in the
+	// target can be imported and stamped with their new CRM ID
+	TargetRowID string `json:"target_row_id,omitempty" bson:"target_row_id,omitempty"`
+	// Optional for pulls: a boolean column marking rows deleted in the target
+	TargetDeletedFlag string `json:"target_deleted_flag,omitempty" bson:"target_deleted_flag,omitempty"`
 }
 
 type SyncSetting struct {
 	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID       primitive.ObjectID `json:"tenant_id,omitempty" bson:"tenant_id,omitempty"`
 	Name           string             `json:"name" bson:"name"`
 	Modules        []ModuleSyncConfig `json:"modules" bson:"modules"`
 	TargetDBType   string             `json:"target_db_type" bson:"target_db_type"` // "postgres", "mysql", "sqlserver", "mongodb"
@@ -29,7 +58,36 @@ type SyncLog struct {
 	SyncSettingID  primitive.ObjectID `json:"sync_setting_id" bson:"sync_setting_id"`
 	StartTime      time.Time          `json:"start_time" bson:"start_time"`
 	EndTime        time.Time          `json:"end_time" bson:"end_time"`
-	Status         string             `json:"status" bson:"status"` // "success", "failed", "in_progress"
+	Status         string             `json:"status" bson:"status"` // "success", "partial", "failed", "in_progress"
 	ProcessedCount int                `json:"processed_count" bson:"processed_count"`
 	Error          string             `json:"error,omitempty" bson:"error,omitempty"`
+
+	Pushed    int               `json:"pushed" bson:"pushed"`
+	Pulled    int               `json:"pulled" bson:"pulled"`
+	Deleted   int               `json:"deleted" bson:"deleted"`
+	Skipped   int               `json:"skipped" bson:"skipped"` // unchanged since the last run
+	Conflicts int               `json:"conflicts" bson:"conflicts"`
+	Failed    int               `json:"failed" bson:"failed"`
+	Errors    []SyncRecordError `json:"errors,omitempty" bson:"errors,omitempty"` // capped at maxLoggedErrors
+}
+
+// SyncRecordError is a per-record failure; the run carries on without it
+type SyncRecordError struct {
+	Module    string `json:"module" bson:"module"`
+	RecordID  string `json:"record_id" bson:"record_id"`
+	Direction string `json:"direction" bson:"direction"`
+	Operation string `json:"operation" bson:"operation"` // upsert, create, delete
+	Error     string `json:"error" bson:"error"`
+}
+
+// SyncRecordState remembers what each side looked like after the last
+// successful sync of a record, so unchanged records are skipped and changes
+// on both sides are detected as conflicts
+type SyncRecordState struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	SyncSettingID primitive.ObjectID `bson:"sync_setting_id"`
+	ModuleName    string             `bson:"module_name"`
+	RecordID      string             `bson:"record_id"`
+	Hash          string             `bson:"hash"` // of the mapped values, in target column space
+	SyncedAt      time.Time          `bson:"synced_at"`
 }
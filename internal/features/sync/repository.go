@@ -187,3 +187,73 @@ func (r *SyncLogRepositoryImpl) Update(ctx context.Context, log *SyncLog) error
 	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": log.ID}, log)
 	return err
 }
+
+type SyncStateRepository interface {
+	// GetMany returns the stored states of the given records keyed by record ID
+	GetMany(ctx context.Context, settingID primitive.ObjectID, moduleName string, recordIDs []string) (map[string]SyncRecordState, error)
+	Save(ctx context.Context, state SyncRecordState) error
+	Delete(ctx context.Context, settingID primitive.ObjectID, moduleName string, recordIDs []string) error
+	DeleteBySetting(ctx context.Context, settingID primitive.ObjectID) error
+}
+
+type SyncStateRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewSyncStateRepository(db *database.MongodbDB) SyncStateRepository {
+	return &SyncStateRepositoryImpl{
+		collection: db.DB.Collection("sync_record_states"),
+	}
+}
+
+func (r *SyncStateRepositoryImpl) GetMany(ctx context.Context, settingID primitive.ObjectID, moduleName string, recordIDs []string) (map[string]SyncRecordState, error) {
+	states := map[string]SyncRecordState{}
+	if len(recordIDs) == 0 {
+		return states, nil
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"sync_setting_id": settingID,
+		"module_name":     moduleName,
+		"record_id":       bson.M{"$in": recordIDs},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var list []SyncRecordState
+	if err = cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+	for _, st := range list {
+		states[st.RecordID] = st
+	}
+	return states, nil
+}
+
+func (r *SyncStateRepositoryImpl) Save(ctx context.Context, state SyncRecordState) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"sync_setting_id": state.SyncSettingID, "module_name": state.ModuleName, "record_id": state.RecordID},
+		bson.M{"$set": bson.M{"hash": state.Hash, "synced_at": state.SyncedAt}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (r *SyncStateRepositoryImpl) Delete(ctx context.Context, settingID primitive.ObjectID, moduleName string, recordIDs []string) error {
+	if len(recordIDs) == 0 {
+		return nil
+	}
+	_, err := r.collection.DeleteMany(ctx, bson.M{
+		"sync_setting_id": settingID,
+		"module_name":     moduleName,
+		"record_id":       bson.M{"$in": recordIDs},
+	})
+	return err
+}
+
+func (r *SyncStateRepositoryImpl) DeleteBySetting(ctx context.Context, settingID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"sync_setting_id": settingID})
+	return err
+}
@@ -0,0 +1,300 @@
+package sync
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// syncTarget is the external database side of a sync run. One connection is
+// opened per run and shared by all modules.
+type syncTarget interface {
+	// Upsert writes rows keyed by key and returns one error slot per row
+	Upsert(ctx context.Context, table, key string, rows []map[string]any) ([]error, error)
+	Delete(ctx context.Context, table, key string, ids []string) (int, error)
+	// Changed returns rows whose updatedCol is after since
+	Changed(ctx context.Context, table, updatedCol string, since time.Time) ([]map[string]any, error)
+	// SetKey stamps the CRM ID on a row identified by the target's own primary key
+	SetKey(ctx context.Context, table, rowIDCol string, rowID any, key, crmID string) error
+	Close()
+}
+
+func openTarget(ctx context.Context, dbType string, cfg map[string]string) (syncTarget, error) {
+	switch dbType {
+	case "postgres", "mysql":
+		return openSQLTarget(ctx, dbType, cfg)
+	case "mongodb":
+		return openMongoTarget(ctx, cfg)
+	}
+	return nil, fmt.Errorf("unsupported target DB type: %s", dbType)
+}
+
+var identPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validIdent reports whether a configured table or column name is safe to
+// interpolate into SQL
+func validIdent(name string) bool {
+	return identPattern.MatchString(name)
+}
+
+type sqlTarget struct {
+	db      *sql.DB
+	dialect string
+}
+
+func openSQLTarget(ctx context.Context, dialect string, cfg map[string]string) (*sqlTarget, error) {
+	var connStr string
+	if dialect == "mysql" {
+		connStr = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true",
+			cfg["user"], cfg["password"], cfg["host"], cfg["port"], cfg["database"])
+	} else {
+		connStr = fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+			cfg["host"], cfg["port"], cfg["user"], cfg["password"], cfg["database"])
+	}
+
+	db, err := sql.Open(dialect, connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %v", dialect, err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping %s: %v", dialect, err)
+	}
+	return &sqlTarget{db: db, dialect: dialect}, nil
+}
+
+func (t *sqlTarget) quote(ident string) string {
+	if t.dialect == "mysql" {
+		return "`" + ident + "`"
+	}
+	return `"` + ident + `"`
+}
+
+func (t *sqlTarget) placeholder(n int) string {
+	if t.dialect == "mysql" {
+		return "?"
+	}
+	return fmt.Sprintf("$%d", n)
+}
+
+func (t *sqlTarget) Upsert(ctx context.Context, table, key string, rows []map[string]any) ([]error, error) {
+	errs := make([]error, len(rows))
+	for i, row := range rows {
+		columns := []string{}
+		placeholders := []string{}
+		updates := []string{}
+		values := []interface{}{}
+
+		for col, val := range row {
+			if !validIdent(col) {
+				errs[i] = fmt.Errorf("invalid column name %q", col)
+				break
+			}
+			values = append(values, val)
+			columns = append(columns, t.quote(col))
+			placeholders = append(placeholders, t.placeholder(len(values)))
+			if col == key {
+				continue
+			}
+			if t.dialect == "mysql" {
+				updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", t.quote(col), t.quote(col)))
+			} else {
+				updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", t.quote(col), t.quote(col)))
+			}
+		}
+		if errs[i] != nil {
+			continue
+		}
+
+		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+			t.quote(table), strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+		switch {
+		case t.dialect == "mysql" && len(updates) > 0:
+			query += " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+		case t.dialect == "mysql":
+			query = strings.Replace(query, "INSERT", "INSERT IGNORE", 1)
+		case len(updates) > 0:
+			query += fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", t.quote(key), strings.Join(updates, ", "))
+		default:
+			query += fmt.Sprintf(" ON CONFLICT (%s) DO NOTHING", t.quote(key))
+		}
+
+		if _, err := t.db.ExecContext(ctx, query, values...); err != nil {
+			errs[i] = err
+		}
+	}
+	return errs, nil
+}
+
+func (t *sqlTarget) Delete(ctx context.Context, table, key string, ids []string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = t.placeholder(i + 1)
+		args[i] = id
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s IN (%s)", t.quote(table), t.quote(key), strings.Join(placeholders, ","))
+	res, err := t.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete from %s: %v", t.dialect, err)
+	}
+	count, _ := res.RowsAffected()
+	return int(count), nil
+}
+
+func (t *sqlTarget) Changed(ctx context.Context, table, updatedCol string, since time.Time) ([]map[string]any, error) {
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s > %s ORDER BY %s",
+		t.quote(table), t.quote(updatedCol), t.placeholder(1), t.quote(updatedCol))
+	rows, err := t.db.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read changes from %s: %v", t.dialect, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []map[string]any
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]any, len(columns))
+		for i, col := range columns {
+			if b, ok := values[i].([]byte); ok {
+				row[col] = string(b)
+			} else {
+				row[col] = values[i]
+			}
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+func (t *sqlTarget) SetKey(ctx context.Context, table, rowIDCol string, rowID any, key, crmID string) error {
+	query := fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s = %s",
+		t.quote(table), t.quote(key), t.placeholder(1), t.quote(rowIDCol), t.placeholder(2))
+	_, err := t.db.ExecContext(ctx, query, crmID, rowID)
+	return err
+}
+
+func (t *sqlTarget) Close() {
+	t.db.Close()
+}
+
+type mongoTarget struct {
+	client *mongo.Client
+	db     *mongo.Database
+}
+
+func openMongoTarget(ctx context.Context, cfg map[string]string) (*mongoTarget, error) {
+	uri := cfg["uri"]
+	if uri == "" {
+		uri = fmt.Sprintf("mongodb://%s:%s@%s:%s/%s",
+			cfg["user"], cfg["password"], cfg["host"], cfg["port"], cfg["database"])
+		if cfg["user"] == "" {
+			uri = fmt.Sprintf("mongodb://%s:%s/%s", cfg["host"], cfg["port"], cfg["database"])
+		}
+	}
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to external mongodb: %v", err)
+	}
+	return &mongoTarget{client: client, db: client.Database(cfg["database"])}, nil
+}
+
+func (t *mongoTarget) Upsert(ctx context.Context, table, key string, rows []map[string]any) ([]error, error) {
+	errs := make([]error, len(rows))
+	if len(rows) == 0 {
+		return errs, nil
+	}
+
+	models := make([]mongo.WriteModel, 0, len(rows))
+	for _, row := range rows {
+		set := bson.M{}
+		for k, v := range row {
+			set[k] = v
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{key: row[key]}).
+			SetUpdate(bson.M{"$set": set}).
+			SetUpsert(true))
+	}
+
+	_, err := t.db.Collection(table).BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) {
+		for _, we := range bulkErr.WriteErrors {
+			if we.Index < len(errs) {
+				errs[we.Index] = errors.New(we.Message)
+			}
+		}
+		return errs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to bulk write to mongodb: %v", err)
+	}
+	return errs, nil
+}
+
+func (t *mongoTarget) Delete(ctx context.Context, table, key string, ids []string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	res, err := t.db.Collection(table).DeleteMany(ctx, bson.M{key: bson.M{"$in": ids}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete from mongodb: %v", err)
+	}
+	return int(res.DeletedCount), nil
+}
+
+func (t *mongoTarget) Changed(ctx context.Context, table, updatedCol string, since time.Time) ([]map[string]any, error) {
+	opts := options.Find().SetSort(bson.D{{Key: updatedCol, Value: 1}})
+	cursor, err := t.db.Collection(table).Find(ctx, bson.M{updatedCol: bson.M{"$gt": since}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read changes from mongodb: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []bson.M
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	result := make([]map[string]any, 0, len(docs))
+	for _, d := range docs {
+		result = append(result, map[string]any(d))
+	}
+	return result, nil
+}
+
+func (t *mongoTarget) SetKey(ctx context.Context, table, rowIDCol string, rowID any, key, crmID string) error {
+	_, err := t.db.Collection(table).UpdateOne(ctx, bson.M{rowIDCol: rowID}, bson.M{"$set": bson.M{key: crmID}})
+	return err
+}
+
+func (t *mongoTarget) Close() {
+	_ = t.client.Disconnect(context.Background())
+}
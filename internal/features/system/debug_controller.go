@@ -2,6 +2,8 @@ package system
 
 import (
 	"github.com/gofiber/fiber/v2"
+
+	"go-crm/internal/common/session"
 )
 
 type DebugController struct{}
@@ -18,12 +20,14 @@ func NewDebugController() *DebugController {
 // @Success      200  {object}  map[string]interface{}
 // @Router       /debug/user [get]
 func (c *DebugController) GetCurrentUser(ctx *fiber.Ctx) error {
-	userID := ctx.Locals("user_id")
-	roles := ctx.Locals("roles")
+	rc := session.From(ctx.UserContext())
+	if rc == nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
 
 	return ctx.JSON(fiber.Map{
-		"user_id": userID,
-		"roles":   roles,
+		"user_id": rc.UserID.Hex(),
+		"roles":   rc.Roles,
 		"message": "This is your current JWT token data",
 	})
 }
@@ -1,6 +1,7 @@
 package ticket
 
 import (
+	common_api "go-crm/internal/common/api"
 	"go-crm/internal/config"
 	"go-crm/internal/middleware"
 
@@ -8,43 +9,39 @@ import (
 )
 
 type TicketApi struct {
-	controller        *TicketController
-	metricsController *SLAMetricsController
-	config            *config.Config
+	controller         *TicketController
+	metricsController  *SLAMetricsController
+	workloadController *WorkloadController
+	config             *config.Config
+	roleService        middleware.RoleService
 }
 
-func NewTicketApi(controller *TicketController, metricsController *SLAMetricsController, config *config.Config) *TicketApi {
+func NewTicketApi(controller *TicketController, metricsController *SLAMetricsController, workloadController *WorkloadController, config *config.Config, roleService middleware.RoleService) *TicketApi {
 	return &TicketApi{
-		controller:        controller,
-		metricsController: metricsController,
-		config:            config,
+		controller:         controller,
+		metricsController:  metricsController,
+		workloadController: workloadController,
+		config:             config,
+		roleService:        roleService,
 	}
 }
 
 // Setup registers all ticket-related routes
 func (h *TicketApi) Setup(app *fiber.App) {
-	// Ticket routes
-	tickets := app.Group("/api/tickets", middleware.AuthMiddleware(h.config.SkipAuth))
+	// Ticket routes; unversioned, so they carry the headers of the version they negotiate
+	tickets := app.Group("/api/tickets", common_api.NegotiatedVersionMiddleware(), middleware.AuthMiddleware(h.config.SkipAuth))
 
-	// Ticket CRUD
-	tickets.Post("/", h.controller.CreateTicket)
-	tickets.Get("/", h.controller.ListTickets)
-	tickets.Get("/my", h.controller.GetMyTickets)
-	tickets.Get("/customer/:customerId", h.controller.GetCustomerTickets)
-	tickets.Get("/:id", h.controller.GetTicket)
-	tickets.Put("/:id", h.controller.UpdateTicket)
-	tickets.Delete("/:id", h.controller.DeleteTicket)
-
-	// Ticket actions
-	tickets.Patch("/:id/status", h.controller.UpdateStatus)
-	tickets.Patch("/:id/assign", h.controller.AssignTicket)
+	h.registerTicketRoutes(tickets)
 
-	// Ticket SLA status
-	tickets.Get("/:id/sla-status", h.metricsController.GetTicketSLAStatus)
+	// Versioned routes (/api/v1, /api/v2); the unversioned routes above behave like v1
+	common_api.VersionedGroup(app, "/tickets", []common_api.Version{common_api.V1, common_api.V2}, func(r fiber.Router, v common_api.Version) {
+		h.registerTicketRoutes(r)
+	}, middleware.AuthMiddleware(h.config.SkipAuth))
 
-	// Comments
-	tickets.Post("/:id/comments", h.controller.AddComment)
-	tickets.Get("/:id/comments", h.controller.ListComments)
+	// Ticket lifecycle settings
+	ticketSettings := app.Group("/api/ticket-settings", middleware.AuthMiddleware(h.config.SkipAuth))
+	ticketSettings.Get("/", middleware.RequirePermission(h.roleService, "settings", "read"), h.controller.GetSettings)
+	ticketSettings.Put("/", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.UpdateSettings)
 
 	// SLA Policy routes
 	slaPolicies := app.Group("/api/sla-policies", middleware.AuthMiddleware(h.config.SkipAuth))
@@ -59,6 +56,19 @@ func (h *TicketApi) Setup(app *fiber.App) {
 	slaMetrics.Get("/overview", h.metricsController.GetOverview)
 	slaMetrics.Get("/violations", h.metricsController.GetViolations)
 	slaMetrics.Get("/trends", h.metricsController.GetTrends)
+	slaMetrics.Get("/report", h.metricsController.GetReport)
+	slaMetrics.Get("/sentiment", h.metricsController.GetSentimentTrends)
+	slaMetrics.Get("/first-contact-resolution", h.metricsController.GetFirstContactResolution)
+	slaMetrics.Get("/response-templates", h.metricsController.GetResponseTemplates)
+
+	// Agent workload and availability routes
+	workload := app.Group("/api/agent-workload", middleware.AuthMiddleware(h.config.SkipAuth))
+	workload.Get("/agents", h.workloadController.GetAgentWorkload)
+	workload.Get("/teams", h.workloadController.GetTeamWorkload)
+	workload.Get("/suggest", h.workloadController.SuggestAssignee)
+	workload.Get("/availability", h.workloadController.GetMyAvailability)
+	workload.Put("/availability", h.workloadController.SetMyAvailability)
+	workload.Put("/agents/:userId/availability", middleware.RequirePermission(h.roleService, "users", "update"), h.workloadController.SetAgentAvailability)
 
 	// Escalation Rule routes
 	escalationRules := app.Group("/api/escalation-rules", middleware.AuthMiddleware(h.config.SkipAuth))
@@ -68,3 +78,41 @@ func (h *TicketApi) Setup(app *fiber.App) {
 	escalationRules.Put("/:id", h.controller.UpdateEscalationRule)
 	escalationRules.Delete("/:id", h.controller.DeleteEscalationRule)
 }
+
+func (h *TicketApi) registerTicketRoutes(tickets fiber.Router) {
+	// Ticket CRUD
+	tickets.Post("/", h.controller.CreateTicket)
+	tickets.Post("/inbound-email", h.controller.IngestEmail)
+	tickets.Get("/quarantine", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.ListQuarantined)
+	tickets.Get("/", h.controller.ListTickets)
+	tickets.Get("/my", h.controller.GetMyTickets)
+	tickets.Get("/customer/:customerId", h.controller.GetCustomerTickets)
+	tickets.Get("/:id", h.controller.GetTicket)
+	tickets.Put("/:id", h.controller.UpdateTicket)
+	tickets.Delete("/:id", h.controller.DeleteTicket)
+
+	// Ticket actions
+	tickets.Patch("/:id/status", h.controller.UpdateStatus)
+	tickets.Patch("/:id/assign", h.controller.AssignTicket)
+
+	// Quarantine review
+	tickets.Post("/:id/release", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.ReleaseTicket)
+	tickets.Post("/:id/reject", middleware.RequirePermission(h.roleService, "settings", "update"), h.controller.RejectTicket)
+
+	// Ticket SLA status
+	tickets.Get("/:id/sla-status", h.metricsController.GetTicketSLAStatus)
+
+	// Escalation history
+	tickets.Get("/:id/escalations", h.controller.GetEscalationHistory)
+
+	// Comments
+	tickets.Post("/:id/comments", h.controller.AddComment)
+	tickets.Get("/:id/comments", h.controller.ListComments)
+
+	// Customer satisfaction
+	tickets.Post("/:id/satisfaction", h.controller.RateSatisfaction)
+
+	// Watchers; the service checks who may change other users' watching
+	tickets.Post("/:id/watchers", h.controller.AddWatcher)
+	tickets.Delete("/:id/watchers/:userId", h.controller.RemoveWatcher)
+}
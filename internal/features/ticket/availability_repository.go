@@ -0,0 +1,68 @@
+package ticket
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AgentAvailabilityRepository stores agent availability, one document per user
+type AgentAvailabilityRepository interface {
+	Upsert(ctx context.Context, availability *AgentAvailability) error
+	FindByUser(ctx context.Context, userID primitive.ObjectID) (*AgentAvailability, error)
+	FindAll(ctx context.Context) ([]AgentAvailability, error)
+}
+
+// AgentAvailabilityRepositoryImpl implements AgentAvailabilityRepository
+type AgentAvailabilityRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+// NewAgentAvailabilityRepository creates a new agent availability repository
+func NewAgentAvailabilityRepository(db *database.MongodbDB) AgentAvailabilityRepository {
+	return &AgentAvailabilityRepositoryImpl{
+		collection: db.DB.Collection("agent_availability"),
+	}
+}
+
+// Upsert replaces the availability of availability.UserID
+func (r *AgentAvailabilityRepositoryImpl) Upsert(ctx context.Context, availability *AgentAvailability) error {
+	availability.UpdatedAt = time.Now()
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": availability.UserID}, availability, options.Replace().SetUpsert(true))
+	return err
+}
+
+// FindByUser returns the availability of a user, or offline when none is set
+func (r *AgentAvailabilityRepositoryImpl) FindByUser(ctx context.Context, userID primitive.ObjectID) (*AgentAvailability, error) {
+	var availability AgentAvailability
+	err := r.collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&availability)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return &AgentAvailability{UserID: userID, Status: AgentStatusOffline}, nil
+		}
+		return nil, err
+	}
+	return &availability, nil
+}
+
+// FindAll returns every stored availability
+func (r *AgentAvailabilityRepositoryImpl) FindAll(ctx context.Context) ([]AgentAvailability, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var availability []AgentAvailability
+	if err := cursor.All(ctx, &availability); err != nil {
+		return nil, err
+	}
+	return availability, nil
+}
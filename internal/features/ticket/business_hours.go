@@ -0,0 +1,84 @@
+package ticket
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// maxBusinessDays bounds the search for working time so a misconfigured
+// window cannot loop forever
+const maxBusinessDays = 3 * 366
+
+func parseClock(value, fallback string) (int, error) {
+	if value == "" {
+		value = fallback
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid business hours time %q (use HH:MM)", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Validate checks the window's timezone, days and clock times
+func (b *BusinessHours) Validate() error {
+	if b.Timezone != "" {
+		if _, err := time.LoadLocation(b.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", b.Timezone)
+		}
+	}
+	for _, d := range b.Days {
+		if d < 0 || d > 6 {
+			return fmt.Errorf("invalid business day %d (use 0 = Sunday to 6 = Saturday)", d)
+		}
+	}
+	start, err := parseClock(b.Start, "09:00")
+	if err != nil {
+		return err
+	}
+	end, err := parseClock(b.End, "17:00")
+	if err != nil {
+		return err
+	}
+	if end <= start {
+		return fmt.Errorf("business hours must end after they start")
+	}
+	return nil
+}
+
+// addBusinessMinutes returns the instant minutes of working time after from.
+// Days and clock times are read in loc, so DST changes shift the UTC result.
+func addBusinessMinutes(from time.Time, minutes int, b BusinessHours, loc *time.Location) time.Time {
+	start, errStart := parseClock(b.Start, "09:00")
+	end, errEnd := parseClock(b.End, "17:00")
+	if errStart != nil || errEnd != nil || end <= start {
+		return from.Add(time.Duration(minutes) * time.Minute)
+	}
+	days := b.Days
+	if len(days) == 0 {
+		days = []int{1, 2, 3, 4, 5}
+	}
+
+	remaining := time.Duration(minutes) * time.Minute
+	cursor := from.In(loc)
+	for i := 0; i < maxBusinessDays; i++ {
+		y, m, d := cursor.Date()
+		if slices.Contains(days, int(cursor.Weekday())) {
+			open := time.Date(y, m, d, start/60, start%60, 0, 0, loc)
+			closed := time.Date(y, m, d, end/60, end%60, 0, 0, loc)
+			if cursor.Before(open) {
+				cursor = open
+			}
+			if cursor.Before(closed) {
+				available := closed.Sub(cursor)
+				if remaining <= available {
+					return cursor.Add(remaining).UTC()
+				}
+				remaining -= available
+			}
+		}
+		cursor = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+	}
+	return from.Add(time.Duration(minutes) * time.Minute)
+}
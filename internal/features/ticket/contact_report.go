@@ -0,0 +1,339 @@
+package ticket
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ContactMetrics are the resolution and satisfaction outcomes of a set of
+// tickets. A ticket is resolved on first contact when it was resolved after
+// a single public agent reply and never reopened. Agent replies are counted
+// since reply tracking began, so older tickets do not qualify.
+type ContactMetrics struct {
+	Tickets              int      `json:"tickets" bson:"tickets"`
+	Resolved             int      `json:"resolved" bson:"resolved"`
+	ResolvedWithResponse int      `json:"resolved_with_response" bson:"resolved_with_response"`
+	FirstContactResolved int      `json:"first_contact_resolved" bson:"first_contact_resolved"`
+	FCRRate              float64  `json:"fcr_rate" bson:"-"` // of resolved tickets with an agent reply
+	AvgResolutionMinutes *float64 `json:"avg_resolution_minutes" bson:"avg_resolution_minutes"`
+
+	Rated           int      `json:"rated" bson:"rated"`
+	Satisfied       int      `json:"satisfied" bson:"satisfied"` // ratings of 4 or 5
+	CSAT            float64  `json:"csat" bson:"-"`              // satisfied share of ratings
+	AvgSatisfaction *float64 `json:"avg_satisfaction" bson:"avg_satisfaction"`
+	// Average ratings of tickets resolved on first contact and of the other
+	// resolved tickets
+	AvgSatisfactionFirstContact *float64 `json:"avg_satisfaction_first_contact" bson:"avg_satisfaction_first_contact"`
+	AvgSatisfactionOther        *float64 `json:"avg_satisfaction_other" bson:"avg_satisfaction_other"`
+}
+
+// FirstContactRow holds the metrics of one group and period
+type FirstContactRow struct {
+	Key    string     `json:"key"` // priority, agent ID or team; empty when unassigned or ungrouped
+	Label  string     `json:"label,omitempty"`
+	Period *time.Time `json:"period,omitempty"`
+	ContactMetrics
+}
+
+type FirstContactReport struct {
+	Start    time.Time         `json:"start"`
+	End      time.Time         `json:"end"`
+	GroupBy  string            `json:"group_by,omitempty"`
+	Interval string            `json:"interval,omitempty"`
+	Timezone string            `json:"timezone"`
+	Rows     []FirstContactRow `json:"rows"`
+	Totals   FirstContactRow   `json:"totals"`
+}
+
+// ResponseTemplateRow holds the outcomes of the tickets answered with one
+// template. Deltas compare it with tickets answered without a template and
+// are nil when either side has no data.
+type ResponseTemplateRow struct {
+	TemplateID string `json:"template_id,omitempty"`
+	Name       string `json:"name,omitempty"` // empty once the template is deleted
+	Uses       int    `json:"uses"`           // replies sent with the template
+	ContactMetrics
+
+	FCRRateDelta           *float64 `json:"fcr_rate_delta"`
+	SatisfactionDelta      *float64 `json:"satisfaction_delta"`
+	ResolutionMinutesDelta *float64 `json:"resolution_minutes_delta"`
+}
+
+type ResponseTemplateReport struct {
+	Start     time.Time             `json:"start"`
+	End       time.Time             `json:"end"`
+	Templates []ResponseTemplateRow `json:"templates"` // most used first
+	// WithoutTemplate covers tickets whose agent replies used no template
+	WithoutTemplate ResponseTemplateRow `json:"without_template"`
+}
+
+// contactFields adds the per-ticket values contactAccumulators sum up
+func contactFields() bson.M {
+	responses := bson.M{"$ifNull": bson.A{"$agent_responses", 0}}
+	fields := bson.M{
+		"resolved":           present("$_resolved_at"),
+		"responded":          bson.M{"$gte": bson.A{responses, 1}},
+		"resolution_minutes": minutesBetween("$created_at", "$_resolved_at"),
+		"score":              bson.M{"$ifNull": bson.A{"$satisfaction.score", nil}},
+	}
+	fields["first_contact"] = bson.M{"$and": bson.A{
+		fields["resolved"],
+		bson.M{"$eq": bson.A{responses, 1}},
+		bson.M{"$eq": bson.A{"$_reopens.n", 0}},
+	}}
+	return fields
+}
+
+func contactAccumulators() bson.M {
+	return bson.M{
+		"tickets":                bson.M{"$sum": 1},
+		"resolved":               sumIf("$resolved"),
+		"resolved_with_response": sumIf(bson.M{"$and": bson.A{"$resolved", "$responded"}}),
+		"first_contact_resolved": sumIf("$first_contact"),
+		"avg_resolution_minutes": bson.M{"$avg": "$resolution_minutes"},
+		"rated":                  sumIf(bson.M{"$ne": bson.A{"$score", nil}}),
+		"satisfied":              sumIf(bson.M{"$gte": bson.A{"$score", 4}}),
+		"avg_satisfaction":       bson.M{"$avg": "$score"},
+		"avg_satisfaction_first_contact": bson.M{"$avg": bson.M{"$cond": bson.A{
+			"$first_contact", "$score", nil,
+		}}},
+		"avg_satisfaction_other": bson.M{"$avg": bson.M{"$cond": bson.A{
+			bson.M{"$and": bson.A{"$resolved", bson.M{"$not": bson.A{"$first_contact"}}}}, "$score", nil,
+		}}},
+	}
+}
+
+// contactStages resolve each matched ticket and add its contactFields
+func contactStages(match bson.M, extra bson.M) mongo.Pipeline {
+	fields := contactFields()
+	for k, v := range extra {
+		fields[k] = v
+	}
+	return mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$addFields", Value: bson.M{
+			"_resolved_at": bson.M{"$ifNull": bson.A{"$resolved_at", "$closed_at"}},
+			"_reopens":     reopenMoves(),
+		}}},
+		{{Key: "$addFields", Value: fields}},
+	}
+}
+
+// FirstContactReport aggregates first-contact resolution and CSAT per group
+// and period
+func (r *TicketRepositoryImpl) FirstContactReport(ctx context.Context, q SLAReportQuery) ([]FirstContactRow, error) {
+	key, period := reportGroup(q)
+	group := contactAccumulators()
+	group["_id"] = bson.M{"key": key, "period": period}
+
+	pipeline := append(contactStages(reportMatch(q), nil), bson.D{{Key: "$group", Value: group}})
+	if q.GroupBy == SLAReportByAgent {
+		pipeline = append(pipeline, agentLookup)
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$sort", Value: bson.D{{Key: "_id.key", Value: 1}, {Key: "_id.period", Value: 1}}}})
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var buckets []struct {
+		ID struct {
+			Key    interface{} `bson:"key"`
+			Period *time.Time  `bson:"period"`
+		} `bson:"_id"`
+		ContactMetrics `bson:",inline"`
+		Agent          []reportAgent `bson:"agent"`
+	}
+	if err := cursor.All(ctx, &buckets); err != nil {
+		return nil, err
+	}
+
+	rows := make([]FirstContactRow, 0, len(buckets))
+	for _, b := range buckets {
+		rows = append(rows, FirstContactRow{
+			Key:            reportKey(b.ID.Key),
+			Label:          reportLabel(b.Agent),
+			Period:         b.ID.Period,
+			ContactMetrics: b.ContactMetrics,
+		})
+	}
+	return rows, nil
+}
+
+// ResponseTemplateReport aggregates the outcomes of tickets with agent
+// replies per reply template used; tickets using several templates count
+// for each. The row without a template ID covers tickets answered without
+// any.
+func (r *TicketRepositoryImpl) ResponseTemplateReport(ctx context.Context, q SLAReportQuery) ([]ResponseTemplateRow, error) {
+	match := reportMatch(q)
+	match["agent_responses"] = bson.M{"$gte": 1}
+
+	templates := bson.M{"$ifNull": bson.A{"$response_template_ids", bson.A{}}}
+	// One entry per distinct template, with the replies that used it
+	used := bson.M{"$map": bson.M{
+		"input": bson.M{"$setUnion": bson.A{templates, bson.A{}}},
+		"as":    "t",
+		"in": bson.M{
+			"id":   "$$t",
+			"uses": bson.M{"$size": bson.M{"$filter": bson.M{"input": templates, "cond": bson.M{"$eq": bson.A{"$$this", "$$t"}}}}},
+		},
+	}}
+	group := contactAccumulators()
+	group["_id"] = "$_used.id"
+	group["uses"] = bson.M{"$sum": "$_used.uses"}
+
+	pipeline := append(contactStages(match, bson.M{"_used": used}),
+		bson.D{{Key: "$addFields", Value: bson.M{"_used": bson.M{"$cond": bson.A{
+			bson.M{"$gt": bson.A{bson.M{"$size": "$_used"}, 0}},
+			"$_used",
+			bson.A{bson.M{"id": nil, "uses": 0}},
+		}}}}},
+		bson.D{{Key: "$unwind", Value: "$_used"}},
+		bson.D{{Key: "$group", Value: group}},
+		bson.D{{Key: "$lookup", Value: bson.M{
+			"from":         "email_templates",
+			"localField":   "_id",
+			"foreignField": "_id",
+			"as":           "template",
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "uses", Value: -1}, {Key: "_id", Value: 1}}}},
+	)
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var buckets []struct {
+		ID             interface{} `bson:"_id"`
+		Uses           int         `bson:"uses"`
+		ContactMetrics `bson:",inline"`
+		Template       []struct {
+			Name string `bson:"name"`
+		} `bson:"template"`
+	}
+	if err := cursor.All(ctx, &buckets); err != nil {
+		return nil, err
+	}
+
+	rows := make([]ResponseTemplateRow, 0, len(buckets))
+	for _, b := range buckets {
+		row := ResponseTemplateRow{
+			TemplateID:     reportKey(b.ID),
+			Uses:           b.Uses,
+			ContactMetrics: b.ContactMetrics,
+		}
+		if len(b.Template) > 0 {
+			row.Name = b.Template[0].Name
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// GetFirstContactReport runs the report, and once more ungrouped for the
+// totals when it is grouped or split into periods
+func (s *SLAServiceImpl) GetFirstContactReport(ctx context.Context, q SLAReportQuery) (*FirstContactReport, error) {
+	if err := normalizeReportQuery(&q); err != nil {
+		return nil, err
+	}
+	rows, err := s.TicketRepo.FirstContactReport(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &FirstContactReport{
+		Start:    q.Start,
+		End:      q.End,
+		GroupBy:  q.GroupBy,
+		Interval: q.Interval,
+		Timezone: q.Timezone,
+		Rows:     rows,
+	}
+	totals := rows
+	if q.GroupBy != "" || q.Interval != "" {
+		overall := q
+		overall.GroupBy, overall.Interval = "", ""
+		if totals, err = s.TicketRepo.FirstContactReport(ctx, overall); err != nil {
+			return nil, err
+		}
+	}
+	if len(totals) > 0 {
+		report.Totals.ContactMetrics = totals[0].ContactMetrics
+	}
+
+	for i := range report.Rows {
+		finishContactMetrics(&report.Rows[i].ContactMetrics)
+	}
+	finishContactMetrics(&report.Totals.ContactMetrics)
+	return report, nil
+}
+
+func (s *SLAServiceImpl) GetResponseTemplateReport(ctx context.Context, q SLAReportQuery) (*ResponseTemplateReport, error) {
+	q.GroupBy, q.Interval = "", ""
+	if err := normalizeReportQuery(&q); err != nil {
+		return nil, err
+	}
+	rows, err := s.TicketRepo.ResponseTemplateReport(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ResponseTemplateReport{Start: q.Start, End: q.End, Templates: []ResponseTemplateRow{}}
+	for _, row := range rows {
+		finishContactMetrics(&row.ContactMetrics)
+		if row.TemplateID == "" {
+			report.WithoutTemplate = row
+			continue
+		}
+		report.Templates = append(report.Templates, row)
+	}
+
+	base := report.WithoutTemplate.ContactMetrics
+	for i := range report.Templates {
+		row := &report.Templates[i]
+		if row.ResolvedWithResponse > 0 && base.ResolvedWithResponse > 0 {
+			row.FCRRateDelta = roundedDelta(row.FCRRate, base.FCRRate)
+		}
+		if row.AvgSatisfaction != nil && base.AvgSatisfaction != nil {
+			row.SatisfactionDelta = roundedDelta(*row.AvgSatisfaction, *base.AvgSatisfaction)
+		}
+		if row.AvgResolutionMinutes != nil && base.AvgResolutionMinutes != nil {
+			row.ResolutionMinutesDelta = roundedDelta(*row.AvgResolutionMinutes, *base.AvgResolutionMinutes)
+		}
+	}
+	return report, nil
+}
+
+// finishContactMetrics derives the rates and rounds the averages
+func finishContactMetrics(m *ContactMetrics) {
+	round := func(v *float64, places float64) *float64 {
+		if v == nil {
+			return nil
+		}
+		r := math.Round(*v*places) / places
+		return &r
+	}
+	m.AvgResolutionMinutes = round(m.AvgResolutionMinutes, 10)
+	m.AvgSatisfaction = round(m.AvgSatisfaction, 100)
+	m.AvgSatisfactionFirstContact = round(m.AvgSatisfactionFirstContact, 100)
+	m.AvgSatisfactionOther = round(m.AvgSatisfactionOther, 100)
+	if m.ResolvedWithResponse > 0 {
+		m.FCRRate = math.Round(float64(m.FirstContactResolved)/float64(m.ResolvedWithResponse)*1000) / 10
+	}
+	if m.Rated > 0 {
+		m.CSAT = math.Round(float64(m.Satisfied)/float64(m.Rated)*1000) / 10
+	}
+}
+
+func roundedDelta(v, base float64) *float64 {
+	d := math.Round((v-base)*100) / 100
+	return &d
+}
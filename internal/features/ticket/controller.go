@@ -1,8 +1,15 @@
 package ticket
 
 import (
+	"bytes"
+	"errors"
 	"strconv"
 
+	common_api "go-crm/internal/common/api"
+	"go-crm/internal/common/session"
+	"go-crm/internal/common/validation"
+	"go-crm/internal/features/record"
+
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -40,38 +47,165 @@ func NewTicketController(
 func (ctrl *TicketController) CreateTicket(c *fiber.Ctx) error {
 	var ticket Ticket
 	if err := c.BodyParser(&ticket); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
+		return common_api.InvalidBody(c, err)
+	}
+
+	userID, ok := session.UserID(c.UserContext())
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User ID not found in context",
 		})
 	}
 
-	// Get user ID from context (set by auth middleware)
-	userIDStr, ok := c.Locals("user_id").(string)
+	if err := validateTicket(&ticket); err != nil {
+		return common_api.Fail(c, fiber.StatusBadRequest, err)
+	}
+	if err := ctrl.TicketService.CreateTicket(c.UserContext(), &ticket, userID); err != nil {
+		return common_api.Fail(c, fiber.StatusBadRequest, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Ticket created successfully",
+		"data":    ticket,
+	})
+}
+
+// IngestEmail godoc
+// @Summary Create ticket from email
+// @Description Create a ticket from a raw RFC 5322 message; attachments and inline images are stored and linked
+// @Tags tickets
+// @Accept message/rfc822
+// @Produce json
+// @Success 201 {object} map[string]interface{}
+// @Success 202 {object} map[string]interface{} "Auto-reply, bounce or blocked sender; no ticket created"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/tickets/inbound-email [post]
+func (ctrl *TicketController) IngestEmail(c *fiber.Ctx) error {
+	userID, ok := session.UserID(c.UserContext())
 	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "User ID not found in context",
 		})
 	}
 
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
+	ticket, err := ctrl.TicketService.IngestEmail(c.UserContext(), bytes.NewReader(c.Body()), userID)
 	if err != nil {
+		var ignored *IgnoredEmailError
+		if errors.As(err, &ignored) {
+			return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+				"message": "Email ignored",
+				"ignored": true,
+				"reason":  ignored.Reason,
+			})
+		}
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
+			"error": err.Error(),
 		})
 	}
 
-	if err := ctrl.TicketService.CreateTicket(c.UserContext(), &ticket, userID); err != nil {
+	message := "Ticket created successfully"
+	if ticket.Status == TicketStatusQuarantined {
+		message = "Ticket quarantined as suspected spam"
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": message,
+		"data":    ticket,
+	})
+}
+
+// ListQuarantined godoc
+// @Summary List quarantined tickets
+// @Description List email tickets held as suspected spam
+// @Tags tickets
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/tickets/quarantine [get]
+func (ctrl *TicketController) ListQuarantined(c *fiber.Ctx) error {
+	page, _ := strconv.ParseInt(c.Query("page", "1"), 10, 64)
+	limit, _ := strconv.ParseInt(c.Query("limit", "10"), 10, 64)
+
+	tickets, totalCount, err := ctrl.TicketService.ListQuarantined(c.UserContext(), page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": tickets,
+		"meta": fiber.Map{
+			"total": totalCount,
+			"page":  page,
+			"limit": limit,
+		},
+	})
+}
+
+// ReleaseTicket godoc
+// @Summary Release quarantined ticket
+// @Description Move a quarantined ticket into the queue as new and start its SLA
+// @Tags tickets
+// @Produce json
+// @Param id path string true "Ticket ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/tickets/{id}/release [post]
+func (ctrl *TicketController) ReleaseTicket(c *fiber.Ctx) error {
+	userID, ok := session.UserID(c.UserContext())
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User ID not found in context",
+		})
+	}
+
+	ticket, err := ctrl.TicketService.ReleaseTicket(c.UserContext(), c.Params("id"), userID)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"message": "Ticket created successfully",
+	return c.JSON(fiber.Map{
+		"message": "Ticket released",
 		"data":    ticket,
 	})
 }
 
+// RejectTicket godoc
+// @Summary Reject quarantined ticket
+// @Description Delete a quarantined ticket and its attachments, optionally blocklisting the sender
+// @Tags tickets
+// @Produce json
+// @Param id path string true "Ticket ID"
+// @Param block_sender query bool false "Add the sender to the email blocklist"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/tickets/{id}/reject [post]
+func (ctrl *TicketController) RejectTicket(c *fiber.Ctx) error {
+	userID, ok := session.UserID(c.UserContext())
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User ID not found in context",
+		})
+	}
+
+	if err := ctrl.TicketService.RejectTicket(c.UserContext(), c.Params("id"), userID, c.QueryBool("block_sender")); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Ticket rejected",
+	})
+}
+
 // ListTickets godoc
 // ListTickets godoc
 // @Summary List tickets
@@ -85,7 +219,13 @@ func (ctrl *TicketController) CreateTicket(c *fiber.Ctx) error {
 // @Param channel query string false "Filter by channel"
 // @Param assigned_to query string false "Filter by assignee"
 // @Param search query string false "Search query"
+// @Param min_age_days query int false "Only tickets at least this many whole days old"
+// @Param max_age_days query int false "Only tickets at most this many whole days old"
+// @Param min_days_in_status query int false "Only tickets in their status for at least this many whole days"
+// @Param max_days_in_status query int false "Only tickets in their status for at most this many whole days"
+// @Param sort_by query string false "Sort field; age_days and days_in_status sort by the computed day counts"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/tickets [get]
 func (ctrl *TicketController) ListTickets(c *fiber.Ctx) error {
@@ -114,12 +254,15 @@ func (ctrl *TicketController) ListTickets(c *fiber.Ctx) error {
 	if search := c.Query("search"); search != "" {
 		filters["search"] = search
 	}
+	for _, key := range []string{"min_age_days", "max_age_days", "min_days_in_status", "max_days_in_status"} {
+		if v := c.Query(key); v != "" {
+			filters[key] = v
+		}
+	}
 
 	tickets, totalCount, err := ctrl.TicketService.ListTickets(c.UserContext(), filters, page, limit, sortBy, sortOrder)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return common_api.Error(c, err)
 	}
 
 	return c.JSON(fiber.Map{
@@ -172,29 +315,18 @@ func (ctrl *TicketController) UpdateTicket(c *fiber.Ctx) error {
 
 	var updates map[string]interface{}
 	if err := c.BodyParser(&updates); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return common_api.InvalidBody(c, err)
 	}
 
-	userIDStr, ok := c.Locals("user_id").(string)
+	userID, ok := session.UserID(c.UserContext())
 	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "User ID not found in context",
 		})
 	}
 
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
-	}
-
 	if err := ctrl.TicketService.UpdateTicket(c.UserContext(), id, updates, userID); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return common_api.Fail(c, fiber.StatusBadRequest, err)
 	}
 
 	return c.JSON(fiber.Map{
@@ -214,20 +346,13 @@ func (ctrl *TicketController) UpdateTicket(c *fiber.Ctx) error {
 func (ctrl *TicketController) DeleteTicket(c *fiber.Ctx) error {
 	id := c.Params("id")
 
-	userIDStr, ok := c.Locals("user_id").(string)
+	userID, ok := session.UserID(c.UserContext())
 	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "User ID not found in context",
 		})
 	}
 
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
-	}
-
 	if err := ctrl.TicketService.DeleteTicket(c.UserContext(), id, userID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -259,30 +384,23 @@ func (ctrl *TicketController) UpdateStatus(c *fiber.Ctx) error {
 		Comment string `json:"comment"`
 	}
 	if err := c.BodyParser(&input); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return common_api.InvalidBody(c, err)
 	}
 
-	userIDStr, ok := c.Locals("user_id").(string)
+	userID, ok := session.UserID(c.UserContext())
 	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "User ID not found in context",
 		})
 	}
 
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
-	}
-
 	status := TicketStatus(input.Status)
 	if err := ctrl.TicketService.UpdateStatus(c.UserContext(), id, status, input.Comment, userID); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		var gateErr *record.StageTransitionError
+		if errors.As(err, &gateErr) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error(), "details": gateErr})
+		}
+		return common_api.Fail(c, fiber.StatusBadRequest, err)
 	}
 
 	return c.JSON(fiber.Map{
@@ -309,32 +427,21 @@ func (ctrl *TicketController) AssignTicket(c *fiber.Ctx) error {
 		AssignedTo string `json:"assigned_to"`
 	}
 	if err := c.BodyParser(&input); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return common_api.InvalidBody(c, err)
 	}
 
 	assignedTo, err := primitive.ObjectIDFromHex(input.AssignedTo)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
+		return common_api.Fail(c, fiber.StatusBadRequest, validation.New("assigned_to", validation.CodeInvalid, "Invalid user ID"))
 	}
 
-	userIDStr, ok := c.Locals("user_id").(string)
+	userID, ok := session.UserID(c.UserContext())
 	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "User ID not found in context",
 		})
 	}
 
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
-	}
-
 	if err := ctrl.TicketService.AssignTicket(c.UserContext(), id, assignedTo, userID); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
@@ -354,44 +461,33 @@ func (ctrl *TicketController) AssignTicket(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param id path string true "Ticket ID"
-// @Param comment body TicketComment true "Comment Details"
+// @Param comment body TicketCommentRequest true "Comment Details; template_id replies with a ticket email template"
 // @Success 201 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
 // @Router /api/tickets/{id}/comments [post]
 func (ctrl *TicketController) AddComment(c *fiber.Ctx) error {
 	id := c.Params("id")
 
-	var comment TicketComment
-	if err := c.BodyParser(&comment); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	var req TicketCommentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return common_api.InvalidBody(c, err)
 	}
 
-	userIDStr, ok := c.Locals("user_id").(string)
+	userID, ok := session.UserID(c.UserContext())
 	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "User ID not found in context",
 		})
 	}
 
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
+	created, err := ctrl.TicketService.AddComment(c.UserContext(), id, req, userID)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
-	}
-	comment.CreatedBy = userID
-
-	if err := ctrl.TicketService.AddComment(c.UserContext(), id, &comment); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return common_api.Fail(c, fiber.StatusBadRequest, err)
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"message": "Comment added successfully",
-		"data":    comment,
+		"data":    created,
 	})
 }
 
@@ -408,7 +504,14 @@ func (ctrl *TicketController) AddComment(c *fiber.Ctx) error {
 func (ctrl *TicketController) ListComments(c *fiber.Ctx) error {
 	id := c.Params("id")
 
-	comments, err := ctrl.TicketService.ListComments(c.UserContext(), id)
+	userID, ok := session.UserID(c.UserContext())
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User ID not found in context",
+		})
+	}
+
+	comments, err := ctrl.TicketService.ListComments(c.UserContext(), id, userID)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
@@ -420,6 +523,118 @@ func (ctrl *TicketController) ListComments(c *fiber.Ctx) error {
 	})
 }
 
+// RateSatisfaction godoc
+// @Summary Rate ticket satisfaction
+// @Description Store a CSAT score from 1 to 5 for a resolved or closed ticket, from the customer or recorded by an agent. A new rating replaces the previous one.
+// @Tags tickets
+// @Accept json
+// @Produce json
+// @Param id path string true "Ticket ID"
+// @Param rating body object true "score (1-5) and optional comment"
+// @Success 200 {object} SatisfactionRating
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{} "Ticket not resolved or closed"
+// @Router /api/tickets/{id}/satisfaction [post]
+func (ctrl *TicketController) RateSatisfaction(c *fiber.Ctx) error {
+	var req struct {
+		Score   int    `json:"score"`
+		Comment string `json:"comment"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return common_api.InvalidBody(c, err)
+	}
+
+	userID, ok := session.UserID(c.UserContext())
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User ID not found in context",
+		})
+	}
+
+	rating, err := ctrl.TicketService.RateSatisfaction(c.UserContext(), c.Params("id"), req.Score, req.Comment, userID)
+	if err != nil {
+		return common_api.Fail(c, fiber.StatusBadRequest, err)
+	}
+	return c.JSON(fiber.Map{"data": rating})
+}
+
+// AddWatcher godoc
+// AddWatcher godoc
+// @Summary Watch ticket
+// @Description Add a user, by default the current one, to the ticket's watchers. Adding someone else takes being the assignee or holding ticket update permission.
+// @Tags tickets
+// @Accept json
+// @Produce json
+// @Param id path string true "Ticket ID"
+// @Param request body map[string]string false "Watcher user_id"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/tickets/{id}/watchers [post]
+func (ctrl *TicketController) AddWatcher(c *fiber.Ctx) error {
+	var req struct {
+		UserID string `json:"user_id"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return common_api.InvalidBody(c, err)
+		}
+	}
+
+	userID, ok := session.UserID(c.UserContext())
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User ID not found in context",
+		})
+	}
+	if req.UserID != "" {
+		var err error
+		if userID, err = primitive.ObjectIDFromHex(req.UserID); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid user ID",
+			})
+		}
+	}
+
+	if err := ctrl.TicketService.AddWatcher(c.UserContext(), c.Params("id"), userID); err != nil {
+		return common_api.Fail(c, fiber.StatusBadRequest, err)
+	}
+	return c.JSON(fiber.Map{
+		"message": "Watcher added successfully",
+	})
+}
+
+// RemoveWatcher godoc
+// RemoveWatcher godoc
+// @Summary Unwatch ticket
+// @Description Remove a user from the ticket's watchers. Removing someone else takes being the assignee or holding ticket update permission.
+// @Tags tickets
+// @Produce json
+// @Param id path string true "Ticket ID"
+// @Param userId path string true "User ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/tickets/{id}/watchers/{userId} [delete]
+func (ctrl *TicketController) RemoveWatcher(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Params("userId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	if err := ctrl.TicketService.RemoveWatcher(c.UserContext(), c.Params("id"), userID); err != nil {
+		return common_api.Fail(c, fiber.StatusBadRequest, err)
+	}
+	return c.JSON(fiber.Map{
+		"message": "Watcher removed successfully",
+	})
+}
+
 // GetMyTickets godoc
 // GetMyTickets godoc
 // @Summary Get my tickets
@@ -435,20 +650,13 @@ func (ctrl *TicketController) GetMyTickets(c *fiber.Ctx) error {
 	page, _ := strconv.ParseInt(c.Query("page", "1"), 10, 64)
 	limit, _ := strconv.ParseInt(c.Query("limit", "10"), 10, 64)
 
-	userIDStr, ok := c.Locals("user_id").(string)
+	userID, ok := session.UserID(c.UserContext())
 	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "User ID not found in context",
 		})
 	}
 
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
-	}
-
 	tickets, totalCount, err := ctrl.TicketService.GetMyTickets(c.UserContext(), userID, page, limit)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -524,9 +732,7 @@ func (ctrl *TicketController) GetCustomerTickets(c *fiber.Ctx) error {
 func (ctrl *TicketController) CreateSLAPolicy(c *fiber.Ctx) error {
 	var policy SLAPolicy
 	if err := c.BodyParser(&policy); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return common_api.InvalidBody(c, err)
 	}
 
 	if err := ctrl.SLAService.CreatePolicy(c.UserContext(), &policy); err != nil {
@@ -603,9 +809,7 @@ func (ctrl *TicketController) UpdateSLAPolicy(c *fiber.Ctx) error {
 
 	var updates map[string]interface{}
 	if err := c.BodyParser(&updates); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return common_api.InvalidBody(c, err)
 	}
 
 	if err := ctrl.SLAService.UpdatePolicy(c.UserContext(), id, updates); err != nil {
@@ -658,15 +862,11 @@ func (ctrl *TicketController) DeleteSLAPolicy(c *fiber.Ctx) error {
 func (ctrl *TicketController) CreateEscalationRule(c *fiber.Ctx) error {
 	var rule EscalationRule
 	if err := c.BodyParser(&rule); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return common_api.InvalidBody(c, err)
 	}
 
 	if err := ctrl.EscalationService.CreateRule(c.UserContext(), &rule); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return common_api.Fail(c, fiber.StatusBadRequest, err)
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
@@ -737,15 +937,11 @@ func (ctrl *TicketController) UpdateEscalationRule(c *fiber.Ctx) error {
 
 	var updates map[string]interface{}
 	if err := c.BodyParser(&updates); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return common_api.InvalidBody(c, err)
 	}
 
 	if err := ctrl.EscalationService.UpdateRule(c.UserContext(), id, updates); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return common_api.Fail(c, fiber.StatusBadRequest, err)
 	}
 
 	return c.JSON(fiber.Map{
@@ -775,3 +971,64 @@ func (ctrl *TicketController) DeleteEscalationRule(c *fiber.Ctx) error {
 		"message": "Escalation rule deleted successfully",
 	})
 }
+
+// GetSettings godoc
+// @Summary Get ticket settings
+// @Description Auto-close, reopen-on-reply and required close fields
+// @Tags tickets
+// @Produce json
+// @Success 200 {object} TicketSettings
+// @Router /api/ticket-settings [get]
+func (ctrl *TicketController) GetSettings(c *fiber.Ctx) error {
+	settings, err := ctrl.TicketService.GetSettings(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"data": settings})
+}
+
+// UpdateSettings godoc
+// @Summary Update ticket settings
+// @Tags tickets
+// @Accept json
+// @Produce json
+// @Param settings body TicketSettings true "Ticket settings"
+// @Success 200 {object} TicketSettings
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/ticket-settings [put]
+func (ctrl *TicketController) UpdateSettings(c *fiber.Ctx) error {
+	var input TicketSettings
+	if err := c.BodyParser(&input); err != nil {
+		return common_api.InvalidBody(c, err)
+	}
+	settings, err := ctrl.TicketService.UpdateSettings(c.UserContext(), &input)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"data": settings})
+}
+
+// GetEscalationHistory godoc
+// GetEscalationHistory godoc
+// @Summary Get ticket escalation history
+// @Description List a ticket's escalations, oldest first, with the rule and chain level that raised each
+// @Tags escalation
+// @Produce json
+// @Param id path string true "Ticket ID"
+// @Success 200 {array} EscalationHistoryItem
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/tickets/{id}/escalations [get]
+func (ctrl *TicketController) GetEscalationHistory(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	history, err := ctrl.EscalationService.GetEscalationHistory(c.UserContext(), id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": history,
+	})
+}
@@ -0,0 +1,106 @@
+package ticket
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Overall SLA states of a customer's open tickets
+const (
+	SLAStateBreached = "breached"
+	SLAStateAtRisk   = "at_risk"
+	SLAStateOnTime   = "on_time"
+	SLAStateNoSLA    = "no_sla"
+)
+
+// CustomerTicketSummary counts the open tickets of a set of customers. An
+// open ticket is at risk when less than a quarter of the time between its
+// creation and a due date remains, as CalculateSLAStatus judges with the
+// policy at hand.
+type CustomerTicketSummary struct {
+	Open      int    `json:"open"`
+	WithSLA   int    `json:"with_sla"`
+	Breached  int    `json:"breached"`
+	AtRisk    int    `json:"at_risk"`
+	SLAStatus string `json:"sla_status"` // breached, at_risk, on_time or no_sla
+}
+
+// CustomerSummary summarises the open tickets filed from the emails
+func (r *TicketRepositoryImpl) CustomerSummary(ctx context.Context, emails []string, now time.Time) (*CustomerTicketSummary, error) {
+	summary := &CustomerTicketSummary{SLAStatus: SLAStateNoSLA}
+	if len(emails) == 0 {
+		return summary, nil
+	}
+
+	responsePending := bson.M{"$and": bson.A{
+		bson.M{"$ne": bson.A{bson.M{"$ifNull": bson.A{"$response_due_date", nil}}, nil}},
+		bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$first_response_at", nil}}, nil}},
+	}}
+	resolutionPending := bson.M{"$ne": bson.A{bson.M{"$ifNull": bson.A{"$due_date", nil}}, nil}}
+	// A due date is near when less than a quarter of its window remains
+	near := func(due string) bson.M {
+		return bson.M{"$lt": bson.A{
+			bson.M{"$subtract": bson.A{due, now}},
+			bson.M{"$multiply": bson.A{bson.M{"$subtract": bson.A{due, "$created_at"}}, 0.25}},
+		}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"customer_email": bson.M{"$in": emails},
+			"status":         bson.M{"$nin": bson.A{TicketStatusResolved, TicketStatusClosed, TicketStatusQuarantined}},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"has_sla": bson.M{"$or": bson.A{responsePending, resolutionPending}},
+			"breached": bson.M{"$or": bson.A{
+				bson.M{"$and": bson.A{responsePending, bson.M{"$lt": bson.A{"$response_due_date", now}}}},
+				bson.M{"$and": bson.A{resolutionPending, bson.M{"$lt": bson.A{"$due_date", now}}}},
+			}},
+			"near": bson.M{"$or": bson.A{
+				bson.M{"$and": bson.A{responsePending, near("$response_due_date")}},
+				bson.M{"$and": bson.A{resolutionPending, near("$due_date")}},
+			}},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      nil,
+			"open":     bson.M{"$sum": 1},
+			"with_sla": sumIf("$has_sla"),
+			"breached": sumIf("$breached"),
+			"at_risk":  sumIf(bson.M{"$and": bson.A{"$near", bson.M{"$not": bson.A{"$breached"}}}}),
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Open     int `bson:"open"`
+		WithSLA  int `bson:"with_sla"`
+		Breached int `bson:"breached"`
+		AtRisk   int `bson:"at_risk"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return summary, nil
+	}
+
+	row := rows[0]
+	summary.Open, summary.WithSLA, summary.Breached, summary.AtRisk = row.Open, row.WithSLA, row.Breached, row.AtRisk
+	switch {
+	case row.Breached > 0:
+		summary.SLAStatus = SLAStateBreached
+	case row.AtRisk > 0:
+		summary.SLAStatus = SLAStateAtRisk
+	case row.WithSLA > 0:
+		summary.SLAStatus = SLAStateOnTime
+	}
+	return summary, nil
+}
@@ -0,0 +1,137 @@
+package ticket
+
+import (
+	"strconv"
+	"time"
+
+	"go-crm/internal/common/apperr"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Ticket age and time in the current status are whole days computed when
+// tickets are read. They filter with min_/max_ bounds and sort by their
+// names, like stored fields.
+const (
+	AgeDaysField      = "age_days"
+	DaysInStatusField = "days_in_status"
+)
+
+const day = 24 * time.Hour
+
+// statusSince is when the ticket took its current status. Tickets stored
+// before StatusChangedAt was kept fall back to their last history entry.
+func (t *Ticket) statusSince() time.Time {
+	if t.StatusChangedAt != nil {
+		return *t.StatusChangedAt
+	}
+	if n := len(t.StatusHistory); n > 0 {
+		return t.StatusHistory[n-1].ChangedAt
+	}
+	return t.CreatedAt
+}
+
+// setDurations fills the computed day counts as of now
+func (t *Ticket) setDurations(now time.Time) {
+	t.AgeDays = wholeDays(now.Sub(t.CreatedAt))
+	t.DaysInStatus = wholeDays(now.Sub(t.statusSince()))
+}
+
+func wholeDays(d time.Duration) int {
+	if d < 0 {
+		return 0
+	}
+	return int(d / day)
+}
+
+// statusSinceExpr is statusSince as an aggregation expression
+var statusSinceExpr = bson.M{"$ifNull": bson.A{
+	"$status_changed_at",
+	bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$status_history.changed_at", -1}}, "$created_at"}},
+}}
+
+// addDurationFilters reads the min_age_days, max_age_days,
+// min_days_in_status and max_days_in_status filters, inclusive whole days
+func addDurationFilters(filter bson.M, filters map[string]interface{}, now time.Time) error {
+	var exprs bson.A
+	for _, metric := range []string{AgeDaysField, DaysInStatusField} {
+		lo, err := dayBound(filters, "min_"+metric)
+		if err != nil {
+			return err
+		}
+		hi, err := dayBound(filters, "max_"+metric)
+		if err != nil {
+			return err
+		}
+		if lo == nil && hi == nil {
+			continue
+		}
+		// d whole days ago means a timestamp in (now-(d+1) days, now-d days]
+		if metric == AgeDaysField {
+			cond := bson.M{}
+			if lo != nil {
+				cond["$lte"] = now.Add(-time.Duration(*lo) * day)
+			}
+			if hi != nil {
+				cond["$gt"] = now.Add(-time.Duration(*hi+1) * day)
+			}
+			filter["created_at"] = cond
+			continue
+		}
+		if lo != nil {
+			exprs = append(exprs, bson.M{"$lte": bson.A{statusSinceExpr, now.Add(-time.Duration(*lo) * day)}})
+		}
+		if hi != nil {
+			exprs = append(exprs, bson.M{"$gt": bson.A{statusSinceExpr, now.Add(-time.Duration(*hi+1) * day)}})
+		}
+	}
+	if len(exprs) > 0 {
+		filter["$expr"] = bson.M{"$and": exprs}
+	}
+	return nil
+}
+
+func dayBound(filters map[string]interface{}, key string) (*int, error) {
+	raw, ok := filters[key]
+	if !ok {
+		return nil, nil
+	}
+	var n int
+	switch v := raw.(type) {
+	case int:
+		n = v
+	case float64:
+		n = int(v)
+	case string:
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, apperr.BadRequest("%s must be a whole number of days", key)
+		}
+		n = parsed
+	default:
+		return nil, apperr.BadRequest("%s must be a whole number of days", key)
+	}
+	if n < 0 {
+		return nil, apperr.BadRequest("%s must not be negative", key)
+	}
+	return &n, nil
+}
+
+// durationSort maps a sort on a computed day count to the timestamp it counts
+// from, in the opposite order. Tickets without status_changed_at sort as the
+// longest in their status.
+func durationSort(sortBy, sortOrder string) (string, string) {
+	var field string
+	switch sortBy {
+	case AgeDaysField:
+		field = "created_at"
+	case DaysInStatusField:
+		field = "status_changed_at"
+	default:
+		return sortBy, sortOrder
+	}
+	if sortOrder == "desc" {
+		return field, "asc"
+	}
+	return field, "desc"
+}
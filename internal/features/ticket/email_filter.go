@@ -0,0 +1,215 @@
+package ticket
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// IgnoredEmailError reports an inbound email that was deliberately not
+// turned into a ticket
+type IgnoredEmailError struct {
+	Reason string
+}
+
+func (e *IgnoredEmailError) Error() string {
+	return "email ignored: " + e.Reason
+}
+
+type emailVerdict int
+
+const (
+	emailAccept emailVerdict = iota
+	emailDrop
+	emailQuarantine
+)
+
+// Senders whose mail is a delivery report rather than a customer message
+var bounceSenders = []string{"mailer-daemon@", "postmaster@"}
+
+// screen decides what happens to an inbound email. Blocked senders,
+// auto-replies and bounces are dropped; allowlisted senders skip the spam
+// checks; suspected spam is quarantined or dropped per QuarantineSpam.
+func (f EmailFilter) screen(email *InboundEmail) (emailVerdict, string) {
+	if senderListed(f.Blocklist, email.From) {
+		return emailDrop, "blocked sender"
+	}
+	if reason := autoReplyReason(email); reason != "" {
+		return emailDrop, reason
+	}
+	if senderListed(f.Allowlist, email.From) {
+		return emailAccept, ""
+	}
+	if reason := f.spamReason(email); reason != "" {
+		if f.QuarantineSpam {
+			return emailQuarantine, reason
+		}
+		return emailDrop, reason
+	}
+	return emailAccept, ""
+}
+
+// autoReplyReason recognises out-of-office replies, list traffic and
+// bounces from the headers RFC 3834 and common mailers set
+func autoReplyReason(email *InboundEmail) string {
+	h := email.Header
+	if h == nil {
+		h = mail.Header{}
+	}
+	if v := strings.ToLower(strings.TrimSpace(h.Get("Auto-Submitted"))); v != "" && v != "no" {
+		return "auto-submitted"
+	}
+	if h.Get("X-Autoreply") != "" || h.Get("X-Autorespond") != "" {
+		return "auto-reply"
+	}
+	switch strings.ToLower(strings.TrimSpace(h.Get("Precedence"))) {
+	case "bulk", "junk", "list", "auto_reply":
+		return "bulk mail"
+	}
+	if strings.TrimSpace(h.Get("Return-Path")) == "<>" {
+		return "bounce"
+	}
+	if strings.HasPrefix(strings.ToLower(h.Get("Content-Type")), "multipart/report") {
+		return "bounce"
+	}
+	from := strings.ToLower(email.From)
+	for _, prefix := range bounceSenders {
+		if strings.HasPrefix(from, prefix) {
+			return "bounce"
+		}
+	}
+	return ""
+}
+
+// spamReason checks spam filter verdicts added upstream and the configured
+// subject keywords
+func (f EmailFilter) spamReason(email *InboundEmail) string {
+	h := email.Header
+	if h == nil {
+		h = mail.Header{}
+	}
+	if strings.EqualFold(strings.TrimSpace(h.Get("X-Spam-Flag")), "yes") {
+		return "flagged as spam"
+	}
+	if status := strings.ToLower(strings.TrimSpace(h.Get("X-Spam-Status"))); strings.HasPrefix(status, "yes") {
+		return "flagged as spam"
+	}
+	subject := strings.ToLower(email.Subject)
+	for _, keyword := range f.SpamKeywords {
+		if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" && strings.Contains(subject, keyword) {
+			return "spam keyword: " + keyword
+		}
+	}
+	return ""
+}
+
+// senderListed matches an address against full addresses and bare domains
+func senderListed(list []string, address string) bool {
+	address = strings.ToLower(strings.TrimSpace(address))
+	domain := address[strings.LastIndex(address, "@")+1:]
+	for _, entry := range list {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == address || strings.TrimPrefix(entry, "@") == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// ListQuarantined returns email tickets held as suspected spam
+func (s *TicketServiceImpl) ListQuarantined(ctx context.Context, page, limit int64) ([]Ticket, int64, error) {
+	return s.TicketRepo.FindAll(ctx, bson.M{"status": TicketStatusQuarantined}, page, limit, "created_at", "desc")
+}
+
+// ReleaseTicket moves a quarantined ticket into the queue as new, starting
+// its SLA clock from the release
+func (s *TicketServiceImpl) ReleaseTicket(ctx context.Context, id string, releasedBy primitive.ObjectID) (*Ticket, error) {
+	t, err := s.quarantined(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Status = TicketStatusNew
+	if err := s.CalculateDueDates(ctx, t); err != nil {
+		return nil, err
+	}
+	entry := StatusHistoryEntry{
+		Status:    TicketStatusNew,
+		ChangedBy: releasedBy,
+		ChangedAt: time.Now(),
+		Comment:   "Released from quarantine",
+	}
+	if err := s.TicketRepo.UpdateStatus(ctx, t.ID, TicketStatusNew, entry); err != nil {
+		return nil, err
+	}
+	t.StatusHistory = append(t.StatusHistory, entry)
+	if t.SLAPolicyID != nil {
+		if err := s.TicketRepo.Update(ctx, t.ID, bson.M{
+			"sla_policy_id":     t.SLAPolicyID,
+			"response_due_date": t.ResponseDueDate,
+			"due_date":          t.DueDate,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionUpdate, "tickets", t.ID.Hex(), map[string]common_models.Change{
+		"status": {Old: TicketStatusQuarantined, New: TicketStatusNew},
+	})
+	s.recordSentiment(ctx, t, nil, t.Description)
+	s.notify(ctx, t, EventTicketCreated, releasedBy)
+	return t, nil
+}
+
+// RejectTicket deletes a quarantined ticket and its attachments. With
+// blockSender the sender is added to the email blocklist.
+func (s *TicketServiceImpl) RejectTicket(ctx context.Context, id string, rejectedBy primitive.ObjectID, blockSender bool) error {
+	t, err := s.quarantined(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if s.Files != nil {
+		for _, fileID := range t.AttachmentIDs {
+			_ = s.Files.DeleteFile(ctx, fileID.Hex(), rejectedBy)
+		}
+	}
+	if err := s.DeleteTicket(ctx, id, rejectedBy); err != nil {
+		return err
+	}
+
+	if blockSender && t.CustomerEmail != "" {
+		settings, err := s.SettingsRepo.Get(ctx)
+		if err != nil {
+			return err
+		}
+		if !senderListed(settings.EmailFilter.Blocklist, t.CustomerEmail) {
+			settings.EmailFilter.Blocklist = append(settings.EmailFilter.Blocklist, strings.ToLower(t.CustomerEmail))
+			if _, err := s.UpdateSettings(ctx, settings); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *TicketServiceImpl) quarantined(ctx context.Context, id string) (*Ticket, error) {
+	t, err := s.GetTicket(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if t.Status != TicketStatusQuarantined {
+		return nil, errors.New("ticket is not quarantined")
+	}
+	return t, nil
+}
@@ -0,0 +1,109 @@
+package ticket
+
+import (
+	"bytes"
+	"context"
+	"html"
+	"io"
+	"strings"
+
+	"go-crm/internal/features/file"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// IngestEmail turns a raw customer email into a ticket. Attachments and
+// inline images are stored as files linked to the ticket, and cid:
+// references in the HTML body are rewritten to the stored files so the
+// description renders as it did in the mail client.
+func (s *TicketServiceImpl) IngestEmail(ctx context.Context, raw io.Reader, createdBy primitive.ObjectID) (*Ticket, error) {
+	email, err := ParseInboundEmail(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := s.SettingsRepo.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	verdict, reason := settings.EmailFilter.screen(email)
+	if verdict == emailDrop {
+		return nil, &IgnoredEmailError{Reason: reason}
+	}
+
+	description := email.HTML
+	if description == "" {
+		description = html.EscapeString(strings.TrimSpace(email.Text))
+	}
+	subject := email.Subject
+	if subject == "" {
+		subject = "(no subject)"
+	}
+
+	t := &Ticket{
+		Subject:     subject,
+		Description: description,
+		Channel:     TicketChannelEmail,
+		ChannelMetadata: map[string]interface{}{
+			"message_id":  email.MessageID,
+			"in_reply_to": email.InReplyTo,
+			"to":          email.To,
+		},
+		CustomerEmail: email.From,
+		CustomerName:  email.FromName,
+		Priority:      TicketPriorityMedium,
+		Status:        TicketStatusNew,
+	}
+	if verdict == emailQuarantine {
+		t.Status = TicketStatusQuarantined
+		t.ChannelMetadata["quarantine_reason"] = reason
+	}
+	if err := s.CreateTicket(ctx, t, createdBy); err != nil {
+		return nil, err
+	}
+	if len(email.Attachments) == 0 || s.Files == nil {
+		return t, nil
+	}
+
+	// Attachments are stored after the ticket exists so they can be linked to
+	// it; one that fails validation is reported in the metadata, not fatal
+	urls := map[string]string{}
+	var rejected []string
+	for _, att := range email.Attachments {
+		f := &file.File{
+			OriginalFilename: att.Filename,
+			MimeType:         att.ContentType,
+			ModuleName:       CommentModuleName,
+			RecordID:         t.ID.Hex(),
+			UploadedBy:       createdBy,
+		}
+		if err := s.Files.Upload(ctx, bytes.NewReader(att.Data), int64(len(att.Data)), f); err != nil {
+			rejected = append(rejected, att.Filename+": "+err.Error())
+			continue
+		}
+		t.AttachmentIDs = append(t.AttachmentIDs, f.ID)
+		if att.ContentID != "" {
+			urls[att.ContentID] = f.URL
+		}
+	}
+
+	updates := bson.M{}
+	if len(t.AttachmentIDs) > 0 {
+		updates["attachment_ids"] = t.AttachmentIDs
+	}
+	if email.HTML != "" && len(urls) > 0 {
+		t.Description = rewriteContentIDs(email.HTML, urls)
+		updates["description"] = t.Description
+	}
+	if len(rejected) > 0 {
+		t.ChannelMetadata["rejected_attachments"] = rejected
+		updates["channel_metadata"] = t.ChannelMetadata
+	}
+	if len(updates) > 0 {
+		if err := s.TicketRepo.Update(ctx, t.ID, updates); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
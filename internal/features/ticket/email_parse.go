@@ -0,0 +1,192 @@
+package ticket
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+)
+
+// maxInboundEmailBytes caps the size of a raw message accepted for ingestion
+const maxInboundEmailBytes = 25 << 20
+
+// InboundEmail is a parsed customer email
+type InboundEmail struct {
+	MessageID   string
+	InReplyTo   string
+	From        string
+	FromName    string
+	To          string
+	Subject     string
+	Text        string
+	HTML        string
+	Attachments []InboundAttachment
+
+	// Header is the top-level message header, kept for filtering
+	Header mail.Header
+}
+
+// InboundAttachment is one attached file or inline image. Inline parts carry
+// the Content-ID the HTML body refers to as "cid:<id>".
+type InboundAttachment struct {
+	Filename    string
+	ContentType string
+	ContentID   string
+	Inline      bool
+	Data        []byte
+}
+
+var headerDecoder = mime.WordDecoder{}
+
+// ParseInboundEmail reads an RFC 5322 message, walking nested multipart
+// bodies to collect the text and HTML bodies and every attachment
+func ParseInboundEmail(r io.Reader) (*InboundEmail, error) {
+	msg, err := mail.ReadMessage(io.LimitReader(r, maxInboundEmailBytes))
+	if err != nil {
+		return nil, fmt.Errorf("invalid email: %w", err)
+	}
+
+	email := &InboundEmail{
+		MessageID: strings.Trim(msg.Header.Get("Message-Id"), "<> "),
+		InReplyTo: strings.Trim(msg.Header.Get("In-Reply-To"), "<> "),
+		To:        msg.Header.Get("To"),
+		Subject:   decodeHeader(msg.Header.Get("Subject")),
+		Header:    msg.Header,
+	}
+	if from, err := mail.ParseAddress(msg.Header.Get("From")); err == nil {
+		email.From = from.Address
+		email.FromName = from.Name
+	} else {
+		email.From = msg.Header.Get("From")
+	}
+	if email.From == "" {
+		return nil, errors.New("invalid email: missing sender")
+	}
+
+	header := partHeader{
+		contentType: msg.Header.Get("Content-Type"),
+		encoding:    msg.Header.Get("Content-Transfer-Encoding"),
+		disposition: msg.Header.Get("Content-Disposition"),
+		contentID:   msg.Header.Get("Content-Id"),
+	}
+	if err := email.walk(header, msg.Body); err != nil {
+		return nil, err
+	}
+	return email, nil
+}
+
+type partHeader struct {
+	contentType string
+	encoding    string
+	disposition string
+	contentID   string
+}
+
+func (e *InboundEmail) walk(h partHeader, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(h.contentType)
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("invalid email part: %w", err)
+			}
+			// NextPart decodes quoted-printable itself and drops the header
+			child := partHeader{
+				contentType: part.Header.Get("Content-Type"),
+				encoding:    part.Header.Get("Content-Transfer-Encoding"),
+				disposition: part.Header.Get("Content-Disposition"),
+				contentID:   part.Header.Get("Content-Id"),
+			}
+			if err := e.walk(child, part); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(decodeTransfer(h.encoding, body))
+	if err != nil {
+		return fmt.Errorf("invalid email part: %w", err)
+	}
+
+	disposition, dispParams, _ := mime.ParseMediaType(h.disposition)
+	filename := decodeHeader(dispParams["filename"])
+	if filename == "" {
+		filename = decodeHeader(params["name"])
+	}
+	contentID := strings.Trim(h.contentID, "<> ")
+
+	isBody := disposition != "attachment" && filename == "" && contentID == ""
+	switch {
+	case isBody && mediaType == "text/plain" && e.Text == "":
+		e.Text = string(data)
+	case isBody && mediaType == "text/html" && e.HTML == "":
+		e.HTML = string(data)
+	case len(data) > 0:
+		if filename == "" {
+			filename = defaultAttachmentName(contentID, mediaType)
+		}
+		e.Attachments = append(e.Attachments, InboundAttachment{
+			Filename:    filename,
+			ContentType: mediaType,
+			ContentID:   contentID,
+			Inline:      disposition == "inline" || (disposition == "" && contentID != ""),
+			Data:        data,
+		})
+	}
+	return nil
+}
+
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	default:
+		return r
+	}
+}
+
+func decodeHeader(value string) string {
+	decoded, err := headerDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+func defaultAttachmentName(contentID, mediaType string) string {
+	name := "attachment"
+	if contentID != "" {
+		name = strings.SplitN(contentID, "@", 2)[0]
+	}
+	if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+		name += exts[0]
+	}
+	return name
+}
+
+// rewriteContentIDs points cid: references in an HTML body at stored files
+func rewriteContentIDs(html string, urls map[string]string) string {
+	if len(urls) == 0 {
+		return html
+	}
+	pairs := make([]string, 0, len(urls)*2)
+	for cid, url := range urls {
+		pairs = append(pairs, "cid:"+cid, url)
+	}
+	return strings.NewReplacer(pairs...).Replace(html)
+}
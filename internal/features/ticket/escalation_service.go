@@ -2,28 +2,50 @@ package ticket
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
+	"strconv"
+	"strings"
 	"time"
 
 	common_models "go-crm/internal/common/models"
+	"go-crm/internal/common/validation"
 	"go-crm/internal/features/audit"
+	"go-crm/internal/features/email"
 	"go-crm/internal/features/notification"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/user"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// EscalationSchedule runs the escalation rules every five minutes
+const EscalationSchedule = "*/5 * * * *"
+
 // EscalationService defines the interface for escalation management
 type EscalationService interface {
+	// ProcessEscalations is the system job running the rules on open tickets
 	ProcessEscalations(ctx context.Context) error
+	// EvaluateRules returns the rules with a chain level due on the ticket
 	EvaluateRules(ctx context.Context, ticket *Ticket) ([]EscalationRule, error)
+	// ExecuteEscalation escalates the ticket to the rule's next due level
 	ExecuteEscalation(ctx context.Context, ticket *Ticket, rule *EscalationRule) error
 	CreateRule(ctx context.Context, rule *EscalationRule) error
 	GetRule(ctx context.Context, id string) (*EscalationRule, error)
 	ListRules(ctx context.Context) ([]EscalationRule, error)
 	UpdateRule(ctx context.Context, id string, updates map[string]interface{}) error
 	DeleteRule(ctx context.Context, id string) error
+	// GetEscalationHistory lists the ticket's escalations, oldest first
+	GetEscalationHistory(ctx context.Context, ticketID string) ([]EscalationHistoryItem, error)
+}
+
+// EscalationHistoryItem is an escalation with the name of its rule
+type EscalationHistoryItem struct {
+	EscalationHistoryEntry
+	RuleName string `json:"rule_name,omitempty"`
 }
 
 // EscalationServiceImpl implements EscalationService
@@ -32,6 +54,9 @@ type EscalationServiceImpl struct {
 	TicketRepo          TicketRepository
 	AuditService        audit.AuditService
 	NotificationService notification.NotificationService
+	UserRepo            user.UserRepository
+	EmailService        email.EmailService
+	Timezones           record.TimezoneResolver
 }
 
 // NewEscalationService creates a new escalation service
@@ -40,12 +65,18 @@ func NewEscalationService(
 	ticketRepo TicketRepository,
 	auditService audit.AuditService,
 	notificationService notification.NotificationService,
+	userRepo user.UserRepository,
+	emailService email.EmailService,
+	timezones record.TimezoneResolver,
 ) EscalationService {
 	return &EscalationServiceImpl{
 		EscalationRuleRepo:  escalationRuleRepo,
 		TicketRepo:          ticketRepo,
 		AuditService:        auditService,
 		NotificationService: notificationService,
+		UserRepo:            userRepo,
+		EmailService:        emailService,
+		Timezones:           timezones,
 	}
 }
 
@@ -53,7 +84,7 @@ func NewEscalationService(
 func (s *EscalationServiceImpl) ProcessEscalations(ctx context.Context) error {
 	// Get all open tickets
 	tickets, _, err := s.TicketRepo.FindAll(ctx, bson.M{
-		"status": bson.M{"$nin": []TicketStatus{TicketStatusResolved, TicketStatusClosed}},
+		"status": bson.M{"$nin": []TicketStatus{TicketStatusResolved, TicketStatusClosed, TicketStatusQuarantined}},
 	}, 1, 1000, "created_at", "asc")
 	if err != nil {
 		return err
@@ -67,7 +98,9 @@ func (s *EscalationServiceImpl) ProcessEscalations(ctx context.Context) error {
 		}
 
 		for _, rule := range applicableRules {
-			_ = s.ExecuteEscalation(ctx, &ticket, &rule)
+			if err := s.ExecuteEscalation(ctx, &ticket, &rule); err != nil {
+				log.Printf("escalation: rule %s on ticket %s: %v", rule.Name, ticket.TicketNumber, err)
+			}
 		}
 	}
 
@@ -83,84 +116,241 @@ func (s *EscalationServiceImpl) EvaluateRules(ctx context.Context, ticket *Ticke
 
 	var applicableRules []EscalationRule
 	now := time.Now()
-
 	for _, rule := range rules {
-		// Check priority match
-		if rule.Priority != nil && *rule.Priority != ticket.Priority {
-			continue
+		if s.dueLevel(ctx, ticket, &rule, now) >= 0 {
+			applicableRules = append(applicableRules, rule)
 		}
+	}
+	return applicableRules, nil
+}
 
-		// Check status match
-		if rule.Status != nil && *rule.Status != ticket.Status {
-			continue
-		}
+// chainLevels is the rule's escalation chain; a rule without levels has one
+func chainLevels(rule *EscalationRule) []EscalationLevel {
+	if len(rule.Levels) > 0 {
+		return rule.Levels
+	}
+	return []EscalationLevel{{EscalateTo: rule.EscalateTo, EscalateToType: EscalateToUser}}
+}
 
-		// Check time condition
-		var referenceTime time.Time
-		switch rule.ConditionType {
-		case "sla_breach":
-			if ticket.DueDate != nil && now.After(*ticket.DueDate) {
-				applicableRules = append(applicableRules, rule)
-			}
-		case "no_response":
-			if ticket.FirstResponseAt == nil {
-				referenceTime = ticket.CreatedAt
-				if now.Sub(referenceTime) > time.Duration(rule.EscalateAfter)*time.Minute {
-					applicableRules = append(applicableRules, rule)
-				}
-			}
-		case "no_update":
-			referenceTime = ticket.UpdatedAt
-			if now.Sub(referenceTime) > time.Duration(rule.EscalateAfter)*time.Minute {
-				applicableRules = append(applicableRules, rule)
+// dueLevel returns the index of the chain level due on the ticket, or -1.
+// The first level is due once the rule's condition holds; each further
+// level its own After minutes after the previous one, unless the rule is
+// suppressed by an update since.
+func (s *EscalationServiceImpl) dueLevel(ctx context.Context, ticket *Ticket, rule *EscalationRule, now time.Time) int {
+	// Check priority match
+	if rule.Priority != nil && *rule.Priority != ticket.Priority {
+		return -1
+	}
+	// Check status match
+	if rule.Status != nil && *rule.Status != ticket.Status {
+		return -1
+	}
+
+	since, applies := s.conditionStart(ctx, ticket, rule, now)
+	if !applies {
+		return -1
+	}
+	fired := escalationsSince(ticket, rule.ID, since)
+	levels := chainLevels(rule)
+	switch {
+	case len(fired) >= len(levels):
+		return -1
+	case len(fired) == 0:
+		return 0
+	}
+
+	last := fired[len(fired)-1].EscalatedAt
+	if rule.SuppressOnUpdate && ticket.UpdatedAt.After(last) {
+		return -1
+	}
+	if now.Before(s.deadline(ctx, rule, last, levels[len(fired)].After)) {
+		return -1
+	}
+	return len(fired)
+}
+
+// conditionStart reports whether the rule's condition holds, and since when
+// it has: escalations before then belong to an earlier occurrence
+func (s *EscalationServiceImpl) conditionStart(ctx context.Context, ticket *Ticket, rule *EscalationRule, now time.Time) (time.Time, bool) {
+	switch rule.ConditionType {
+	case "sla_breach":
+		if ticket.DueDate != nil && now.After(*ticket.DueDate) {
+			return *ticket.DueDate, true
+		}
+	case "no_response":
+		if ticket.FirstResponseAt == nil && now.After(s.deadline(ctx, rule, ticket.CreatedAt, rule.EscalateAfter)) {
+			return ticket.CreatedAt, true
+		}
+	case "no_update":
+		if now.After(s.deadline(ctx, rule, ticket.UpdatedAt, rule.EscalateAfter)) {
+			return ticket.UpdatedAt, true
+		}
+	case EscalateOnNegativeSentiment:
+		// EscalateAfter counts very negative messages; the chain runs once per streak
+		sentiment := ticket.Sentiment
+		if sentiment != nil && sentiment.NegativeStreak >= max(rule.EscalateAfter, 1) {
+			if sentiment.StreakStartedAt != nil {
+				return *sentiment.StreakStartedAt, true
 			}
+			return time.Time{}, true
 		}
 	}
+	return time.Time{}, false
+}
 
-	return applicableRules, nil
+// deadline is minutes after from, in working time for business-hours rules
+func (s *EscalationServiceImpl) deadline(ctx context.Context, rule *EscalationRule, from time.Time, minutes int) time.Time {
+	if !rule.BusinessHoursOnly {
+		return from.Add(time.Duration(minutes) * time.Minute)
+	}
+	hours := BusinessHours{}
+	if rule.BusinessHours != nil {
+		hours = *rule.BusinessHours
+	}
+	return addBusinessMinutes(from, minutes, hours, windowLocation(ctx, &hours, s.Timezones))
+}
+
+// escalationsSince returns the rule's escalations of the ticket at or after since
+func escalationsSince(ticket *Ticket, ruleID primitive.ObjectID, since time.Time) []EscalationHistoryEntry {
+	var entries []EscalationHistoryEntry
+	for _, entry := range ticket.EscalationHistory {
+		if entry.RuleID == ruleID && !entry.EscalatedAt.Before(since) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
 }
 
 // ExecuteEscalation executes an escalation action
 func (s *EscalationServiceImpl) ExecuteEscalation(ctx context.Context, ticket *Ticket, rule *EscalationRule) error {
+	now := time.Now()
+	index := s.dueLevel(ctx, ticket, rule, now)
+	if index < 0 {
+		return nil
+	}
+	levels := chainLevels(rule)
+
+	// The manager level goes up from the previous target, else the assignee
+	previous := ticket.AssignedTo
+	since, _ := s.conditionStart(ctx, ticket, rule, now)
+	if fired := escalationsSince(ticket, rule.ID, since); len(fired) > 0 {
+		previous = &fired[len(fired)-1].EscalatedTo
+	}
+	target, err := s.levelTarget(ctx, levels[index], previous)
+	if err != nil {
+		return err
+	}
+
 	// Create escalation history entry
+	reason := fmt.Sprintf("Escalated by rule: %s", rule.Name)
+	if len(levels) > 1 {
+		reason = fmt.Sprintf("Escalated by rule: %s (level %d of %d)", rule.Name, index+1, len(levels))
+	}
 	escalationEntry := EscalationHistoryEntry{
 		Level:       ticket.EscalationLevel + 1,
-		EscalatedTo: rule.EscalateTo,
-		EscalatedAt: time.Now(),
-		Reason:      fmt.Sprintf("Escalated by rule: %s", rule.Name),
+		EscalatedTo: target,
+		EscalatedAt: now,
+		Reason:      reason,
 		RuleID:      rule.ID,
+		ChainLevel:  index + 1,
 	}
-
-	// Update ticket
-	updates := bson.M{
-		"escalation_level": ticket.EscalationLevel + 1,
-		"escalated_to":     rule.EscalateTo,
-	}
-
-	if err := s.TicketRepo.Update(ctx, ticket.ID, updates); err != nil {
+	if err := s.TicketRepo.AddEscalation(ctx, ticket.ID, escalationEntry); err != nil {
 		return err
 	}
 
-	// Add to escalation history
-	_ = s.TicketRepo.Update(ctx, ticket.ID, bson.M{
-		"$push": bson.M{"escalation_history": escalationEntry},
-	})
-
 	// Audit log
+	var oldTarget interface{}
+	if ticket.EscalatedTo != nil {
+		oldTarget = ticket.EscalatedTo.Hex()
+	}
 	changes := map[string]common_models.Change{
 		"escalation_level": {Old: ticket.EscalationLevel, New: ticket.EscalationLevel + 1},
-		"escalated_to":     {Old: nil, New: rule.EscalateTo.Hex()},
+		"escalated_to":     {Old: oldTarget, New: target.Hex()},
 	}
 	_ = s.AuditService.LogChange(ctx, common_models.AuditActionUpdate, "tickets", ticket.ID.Hex(), changes)
 
-	// Send notifications to escalated_to user
-	_ = s.NotificationService.CreateNotification(ctx, rule.EscalateTo, "Ticket Escalated", fmt.Sprintf("Ticket %s has been escalated to you due to rule: %s", ticket.TicketNumber, rule.Name), notification.NotificationTypeSLA, fmt.Sprintf("/dashboard/modules/tickets/%s", ticket.ID.Hex()))
+	// Notify the escalated user and the rule's addresses
+	title, message := escalationNotification(ticket, rule, index+1)
+	_ = s.NotificationService.CreateNotification(ctx, target, title, message, notification.NotificationTypeSLA, fmt.Sprintf("/dashboard/modules/tickets/%s", ticket.ID.Hex()))
+	if len(rule.NotifyEmails) > 0 && s.EmailService != nil {
+		_ = s.EmailService.SendEmail(ctx, rule.NotifyEmails, title, message)
+	}
 
+	ticket.EscalationLevel++
+	ticket.EscalatedTo = &target
+	ticket.EscalationHistory = append(ticket.EscalationHistory, escalationEntry)
 	return nil
 }
 
+// levelTarget is the user a chain level escalates to
+func (s *EscalationServiceImpl) levelTarget(ctx context.Context, level EscalationLevel, previous *primitive.ObjectID) (primitive.ObjectID, error) {
+	if level.EscalateToType != EscalateToManager {
+		return level.EscalateTo, nil
+	}
+	if previous == nil || previous.IsZero() {
+		return primitive.NilObjectID, errors.New("no assignee to find the manager of")
+	}
+	if s.UserRepo == nil {
+		return primitive.NilObjectID, errors.New("managers cannot be resolved")
+	}
+	u, err := s.UserRepo.FindByID(ctx, previous.Hex())
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	if u.ReportsTo == nil {
+		return primitive.NilObjectID, fmt.Errorf("user %s has no manager", previous.Hex())
+	}
+	return *u.ReportsTo, nil
+}
+
+// escalationNotification renders the rule's notification, or the default one
+func escalationNotification(ticket *Ticket, rule *EscalationRule, level int) (string, string) {
+	title := "Ticket Escalated"
+	message := fmt.Sprintf("Ticket %s has been escalated to you due to rule: %s", ticket.TicketNumber, rule.Name)
+	if rule.Notification == nil {
+		return title, message
+	}
+	placeholders := ticketPlaceholders(ticket, "{{rule}}", rule.Name, "{{level}}", strconv.Itoa(level))
+	if rule.Notification.Title != "" {
+		title = placeholders.Replace(rule.Notification.Title)
+	}
+	if rule.Notification.Message != "" {
+		message = placeholders.Replace(rule.Notification.Message)
+	}
+	return title, message
+}
+
+// GetEscalationHistory returns the ticket's escalation history with rule names
+func (s *EscalationServiceImpl) GetEscalationHistory(ctx context.Context, ticketID string) ([]EscalationHistoryItem, error) {
+	objID, err := primitive.ObjectIDFromHex(ticketID)
+	if err != nil {
+		return nil, errors.New("invalid ticket ID")
+	}
+	ticket, err := s.TicketRepo.FindByID(ctx, objID)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := s.EscalationRuleRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[primitive.ObjectID]string, len(rules))
+	for _, rule := range rules {
+		names[rule.ID] = rule.Name
+	}
+
+	items := make([]EscalationHistoryItem, 0, len(ticket.EscalationHistory))
+	for _, entry := range ticket.EscalationHistory {
+		items = append(items, EscalationHistoryItem{EscalationHistoryEntry: entry, RuleName: names[entry.RuleID]})
+	}
+	return items, nil
+}
+
 // CreateRule creates a new escalation rule
 func (s *EscalationServiceImpl) CreateRule(ctx context.Context, rule *EscalationRule) error {
+	if err := validateEscalationRule(rule); err != nil {
+		return err
+	}
 	return s.EscalationRuleRepo.Create(ctx, rule)
 }
 
@@ -186,9 +376,52 @@ func (s *EscalationServiceImpl) UpdateRule(ctx context.Context, id string, updat
 		return errors.New("invalid rule ID")
 	}
 
-	bsonUpdates := bson.M{}
+	rule, err := s.EscalationRuleRepo.FindByID(ctx, objID)
+	if err != nil {
+		return err
+	}
+
+	// Apply the updates to the rule so they are checked, and stored, typed
+	merged, err := json.Marshal(rule)
+	if err != nil {
+		return err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(merged, &fields); err != nil {
+		return err
+	}
 	for k, v := range updates {
-		bsonUpdates[k] = v
+		fields[k] = v
+	}
+	if merged, err = json.Marshal(fields); err != nil {
+		return err
+	}
+	var updated EscalationRule
+	if err := json.Unmarshal(merged, &updated); err != nil {
+		return fmt.Errorf("invalid escalation rule: %w", err)
+	}
+	if err := validateEscalationRule(&updated); err != nil {
+		return err
+	}
+
+	raw, err := bson.Marshal(updated)
+	if err != nil {
+		return err
+	}
+	var stored bson.M
+	if err := bson.Unmarshal(raw, &stored); err != nil {
+		return err
+	}
+	bsonUpdates := bson.M{}
+	for k := range updates {
+		if !updatableRuleFields[k] {
+			continue
+		}
+		// Fields left out by omitempty were cleared
+		bsonUpdates[k] = stored[k]
+	}
+	if len(bsonUpdates) == 0 {
+		return nil
 	}
 
 	return s.EscalationRuleRepo.Update(ctx, objID, bsonUpdates)
@@ -203,3 +436,58 @@ func (s *EscalationServiceImpl) DeleteRule(ctx context.Context, id string) error
 
 	return s.EscalationRuleRepo.Delete(ctx, objID)
 }
+
+// escalationConditions are the condition types a rule can escalate on
+var escalationConditions = map[string]bool{
+	"sla_breach":                true,
+	"no_response":               true,
+	"no_update":                 true,
+	EscalateOnNegativeSentiment: true,
+}
+
+// updatableRuleFields are the rule fields UpdateRule writes
+var updatableRuleFields = map[string]bool{
+	"name": true, "description": true, "priority": true, "status": true,
+	"escalate_after": true, "condition_type": true, "escalate_to": true,
+	"escalate_to_type": true, "notify_emails": true, "levels": true,
+	"business_hours_only": true, "business_hours": true,
+	"suppress_on_update": true, "notification": true, "is_active": true,
+}
+
+// validateEscalationRule checks a rule's condition and escalation chain
+func validateEscalationRule(rule *EscalationRule) error {
+	var errs validation.Errors
+	if strings.TrimSpace(rule.Name) == "" {
+		errs.Add("name", validation.CodeRequired, "name is required")
+	}
+	if !escalationConditions[rule.ConditionType] {
+		errs.Add("condition_type", validation.CodeInvalid, "condition_type must be sla_breach, no_response, no_update or negative_sentiment")
+	}
+	if rule.EscalateAfter < 0 {
+		errs.Add("escalate_after", validation.CodeInvalid, "escalate_after cannot be negative")
+	}
+	if len(rule.Levels) == 0 && rule.EscalateTo.IsZero() {
+		errs.Add("levels", validation.CodeRequired, "levels or escalate_to is required")
+	}
+	for i, level := range rule.Levels {
+		field := fmt.Sprintf("levels.%d", i)
+		switch level.EscalateToType {
+		case EscalateToUser, "":
+			if level.EscalateTo.IsZero() {
+				errs.Add(field+".escalate_to", validation.CodeRequired, "escalate_to is required for user levels")
+			}
+		case EscalateToManager:
+		default:
+			errs.Add(field+".escalate_to_type", validation.CodeInvalid, "escalate_to_type must be user or manager")
+		}
+		if level.After < 0 {
+			errs.Add(field+".after", validation.CodeInvalid, "after cannot be negative")
+		}
+	}
+	if rule.BusinessHours != nil {
+		if err := rule.BusinessHours.Validate(); err != nil {
+			errs.Add("business_hours", validation.CodeInvalid, err.Error())
+		}
+	}
+	return errs.Err()
+}
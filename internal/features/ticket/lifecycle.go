@@ -0,0 +1,164 @@
+package ticket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/notification"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AutoCloseSchedule runs the auto-close job hourly
+const AutoCloseSchedule = "15 * * * *"
+
+// GetSettings returns the ticket lifecycle settings
+func (s *TicketServiceImpl) GetSettings(ctx context.Context) (*TicketSettings, error) {
+	return s.SettingsRepo.Get(ctx)
+}
+
+// UpdateSettings validates and saves the ticket lifecycle settings
+func (s *TicketServiceImpl) UpdateSettings(ctx context.Context, settings *TicketSettings) (*TicketSettings, error) {
+	if settings.AutoCloseAfterDays < 0 || settings.AutoCloseAfterDays > 365 {
+		return nil, errors.New("auto_close_after_days must be between 0 and 365")
+	}
+	if settings.RequiredOnClose == nil {
+		settings.RequiredOnClose = []string{}
+	}
+	for _, field := range settings.RequiredOnClose {
+		if !closeRequirableFields[field] {
+			return nil, fmt.Errorf("%s cannot be required on close", field)
+		}
+	}
+
+	if settings.Notifications == nil {
+		settings.Notifications = DefaultNotificationTriggers()
+	}
+	if err := validateNotificationTriggers(settings.Notifications); err != nil {
+		return nil, err
+	}
+
+	for _, list := range []*[]string{&settings.EmailFilter.Blocklist, &settings.EmailFilter.Allowlist, &settings.EmailFilter.SpamKeywords} {
+		if *list == nil {
+			*list = []string{}
+		}
+	}
+
+	old, err := s.SettingsRepo.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.SettingsRepo.Save(ctx, settings); err != nil {
+		return nil, err
+	}
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionSettings, "tickets", "ticket_settings", map[string]common_models.Change{
+		"settings": {Old: old, New: settings},
+	})
+	return settings, nil
+}
+
+// missingCloseFields returns the required-on-close fields the ticket lacks
+func missingCloseFields(t *Ticket, required []string) []string {
+	var missing []string
+	for _, field := range required {
+		var value string
+		switch field {
+		case "resolution_code":
+			value = t.ResolutionCode
+		case "root_cause":
+			value = t.RootCause
+		case "category":
+			value = t.Category
+		}
+		if strings.TrimSpace(value) == "" {
+			missing = append(missing, field)
+		}
+	}
+	return missing
+}
+
+// isCustomerReply is true for public comments by the ticket's portal customer
+func isCustomerReply(t *Ticket, userID primitive.ObjectID, internal bool) bool {
+	return !internal && t.CustomerID != nil && *t.CustomerID == userID
+}
+
+// customerReplied records a customer reply and reopens the ticket when it
+// was resolved
+func (s *TicketServiceImpl) customerReplied(ctx context.Context, t *Ticket, userID primitive.ObjectID) {
+	s.notify(ctx, t, EventCustomerReplied, userID)
+
+	now := time.Now()
+	if t.Status != TicketStatusResolved {
+		_ = s.TicketRepo.Update(ctx, t.ID, bson.M{"last_customer_reply_at": now})
+		return
+	}
+
+	settings, err := s.SettingsRepo.Get(ctx)
+	if err != nil || !settings.ReopenOnCustomerReply {
+		_ = s.TicketRepo.Update(ctx, t.ID, bson.M{"last_customer_reply_at": now})
+		return
+	}
+	reopened, err := s.TicketRepo.ReopenResolved(ctx, t.ID, StatusHistoryEntry{
+		Status:    TicketStatusOpen,
+		ChangedBy: userID,
+		ChangedAt: now,
+		Comment:   "Reopened by customer reply",
+	})
+	if err != nil || !reopened {
+		return
+	}
+
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionUpdate, "tickets", t.ID.Hex(), map[string]common_models.Change{
+		"status":       {Old: t.Status, New: TicketStatusOpen},
+		"reopen_count": {Old: t.ReopenCount, New: t.ReopenCount + 1},
+	})
+	if t.AssignedTo != nil {
+		_ = s.NotificationService.CreateNotification(ctx, *t.AssignedTo, "Ticket Reopened", fmt.Sprintf("The customer replied to resolved ticket %s: %s", t.TicketNumber, t.Subject), notification.NotificationTypeTask, fmt.Sprintf("/dashboard/modules/tickets/%s", t.ID.Hex()))
+	}
+}
+
+// AutoCloseResolved closes tickets that stayed resolved without a customer
+// reply for the configured number of days. Tickets missing required close
+// fields stay resolved.
+func (s *TicketServiceImpl) AutoCloseResolved(ctx context.Context) error {
+	settings, err := s.SettingsRepo.Get(ctx)
+	if err != nil {
+		return err
+	}
+	if settings.AutoCloseAfterDays == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	closed, err := s.TicketRepo.AutoCloseResolved(ctx, now.AddDate(0, 0, -settings.AutoCloseAfterDays), settings.RequiredOnClose, StatusHistoryEntry{
+		Status:    TicketStatusClosed,
+		ChangedAt: now,
+		Comment:   fmt.Sprintf("Closed automatically after %d days without a customer response", settings.AutoCloseAfterDays),
+	})
+	if err != nil {
+		return err
+	}
+	if closed > 0 {
+		log.Printf("tickets: auto-closed %d resolved tickets", closed)
+	}
+	return nil
+}
+
+// ticketFields flattens a ticket into the field map stage gates check
+func ticketFields(t *Ticket) (map[string]interface{}, error) {
+	raw, err := bson.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := bson.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
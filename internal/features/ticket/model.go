@@ -15,6 +15,10 @@ const (
 	TicketStatusPending  TicketStatus = "pending"
 	TicketStatusResolved TicketStatus = "resolved"
 	TicketStatusClosed   TicketStatus = "closed"
+	// TicketStatusQuarantined holds suspected spam from email until an admin
+	// releases or rejects it; quarantined tickets have no SLA and are hidden
+	// from ticket lists
+	TicketStatusQuarantined TicketStatus = "quarantined"
 )
 
 // TicketPriority represents the priority level of a ticket
@@ -53,6 +57,8 @@ type EscalationHistoryEntry struct {
 	Reason      string             `json:"reason" bson:"reason"`
 	EscalatedBy primitive.ObjectID `json:"escalated_by,omitempty" bson:"escalated_by,omitempty"`
 	RuleID      primitive.ObjectID `json:"rule_id,omitempty" bson:"rule_id,omitempty"`
+	// ChainLevel is the step of the rule's escalation chain, from 1
+	ChainLevel int `json:"chain_level,omitempty" bson:"chain_level,omitempty"`
 }
 
 // Ticket represents a customer support ticket
@@ -74,8 +80,13 @@ type Ticket struct {
 	FirstResponseAt *time.Time          `json:"first_response_at,omitempty" bson:"first_response_at,omitempty"`
 
 	// Status Workflow
-	Status        TicketStatus         `json:"status" bson:"status"`
-	StatusHistory []StatusHistoryEntry `json:"status_history,omitempty" bson:"status_history,omitempty"`
+	Status          TicketStatus         `json:"status" bson:"status"`
+	StatusChangedAt *time.Time           `json:"status_changed_at,omitempty" bson:"status_changed_at,omitempty"`
+	StatusHistory   []StatusHistoryEntry `json:"status_history,omitempty" bson:"status_history,omitempty"`
+
+	// Whole days since creation and since the last status change, computed on read
+	AgeDays      int `json:"age_days" bson:"-"`
+	DaysInStatus int `json:"days_in_status" bson:"-"`
 
 	// Assignment
 	AssignedTo    *primitive.ObjectID `json:"assigned_to,omitempty" bson:"assigned_to,omitempty"`
@@ -91,10 +102,43 @@ type Ticket struct {
 	EscalatedTo       *primitive.ObjectID      `json:"escalated_to,omitempty" bson:"escalated_to,omitempty"`
 	EscalationHistory []EscalationHistoryEntry `json:"escalation_history,omitempty" bson:"escalation_history,omitempty"`
 
+	// Assets are records of the assets module the ticket was filed against
+	AssetIDs []primitive.ObjectID `json:"asset_ids,omitempty" bson:"asset_ids,omitempty"`
+
+	// Files received with the ticket, such as email attachments and inline images
+	AttachmentIDs []primitive.ObjectID `json:"attachment_ids,omitempty" bson:"attachment_ids,omitempty"`
+
 	// Tags and Categories
 	Tags     []string `json:"tags,omitempty" bson:"tags,omitempty"`
 	Category string   `json:"category,omitempty" bson:"category,omitempty"`
 
+	// Closure
+	ResolutionCode string `json:"resolution_code,omitempty" bson:"resolution_code,omitempty"`
+	RootCause      string `json:"root_cause,omitempty" bson:"root_cause,omitempty"`
+	// ReopenCount counts customer replies that reopened the ticket after it was resolved
+	ReopenCount         int        `json:"reopen_count" bson:"reopen_count"`
+	LastCustomerReplyAt *time.Time `json:"last_customer_reply_at,omitempty" bson:"last_customer_reply_at,omitempty"`
+	AutoClosed          bool       `json:"auto_closed,omitempty" bson:"auto_closed,omitempty"`
+
+	// Sentiment of the customer's latest message
+	Sentiment *TicketSentiment `json:"sentiment,omitempty" bson:"sentiment,omitempty"`
+
+	// AgentResponses counts public agent comments; a ticket resolved after
+	// one and never reopened was resolved on first contact
+	AgentResponses int `json:"agent_responses" bson:"agent_responses"`
+	// ResponseTemplateIDs are the ticket email templates agents replied
+	// with, once per reply
+	ResponseTemplateIDs []primitive.ObjectID `json:"response_template_ids,omitempty" bson:"response_template_ids,omitempty"`
+	// Satisfaction is the customer's CSAT rating once the ticket is resolved
+	Satisfaction *SatisfactionRating `json:"satisfaction,omitempty" bson:"satisfaction,omitempty"`
+
+	// Watchers are users notified of the ticket's events alongside the assignee
+	Watchers []primitive.ObjectID `json:"watchers,omitempty" bson:"watchers,omitempty"`
+	// The due dates the SLA warning and breach notifications were sent for;
+	// a recalculated due date is notified again
+	SLAWarningSentFor *time.Time `json:"-" bson:"sla_warning_sent_for,omitempty"`
+	SLABreachSentFor  *time.Time `json:"-" bson:"sla_breach_sent_for,omitempty"`
+
 	// Timestamps
 	CreatedAt  time.Time  `json:"created_at" bson:"created_at"`
 	UpdatedAt  time.Time  `json:"updated_at" bson:"updated_at"`
@@ -102,6 +146,14 @@ type Ticket struct {
 	ClosedAt   *time.Time `json:"closed_at,omitempty" bson:"closed_at,omitempty"`
 }
 
+// SatisfactionRating is a customer satisfaction (CSAT) score from 1 to 5
+type SatisfactionRating struct {
+	Score   int                `json:"score" bson:"score"`
+	Comment string             `json:"comment,omitempty" bson:"comment,omitempty"`
+	RatedBy primitive.ObjectID `json:"rated_by" bson:"rated_by"`
+	RatedAt time.Time          `json:"rated_at" bson:"rated_at"`
+}
+
 // SLAPolicy represents a Service Level Agreement policy
 type SLAPolicy struct {
 	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
@@ -116,8 +168,8 @@ type SLAPolicy struct {
 	ResolutionTime int `json:"resolution_time" bson:"resolution_time"`
 
 	// Business Hours
-	IsBusinessHoursOnly bool                   `json:"is_business_hours_only" bson:"is_business_hours_only"`
-	BusinessHours       map[string]interface{} `json:"business_hours,omitempty" bson:"business_hours,omitempty"`
+	IsBusinessHoursOnly bool           `json:"is_business_hours_only" bson:"is_business_hours_only"`
+	BusinessHours       *BusinessHours `json:"business_hours,omitempty" bson:"business_hours,omitempty"`
 
 	// Status
 	IsActive  bool      `json:"is_active" bson:"is_active"`
@@ -125,7 +177,25 @@ type SLAPolicy struct {
 	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
 }
 
-// TicketComment represents a comment or note on a ticket
+// BusinessHours is the working window SLA clocks run in
+type BusinessHours struct {
+	Timezone string `json:"timezone,omitempty" bson:"timezone,omitempty"` // IANA zone; empty uses the tenant's
+	Days     []int  `json:"days,omitempty" bson:"days,omitempty"`         // 0 = Sunday; empty is Monday to Friday
+	Start    string `json:"start,omitempty" bson:"start,omitempty"`       // HH:MM, default 09:00
+	End      string `json:"end,omitempty" bson:"end,omitempty"`           // HH:MM, default 17:00
+}
+
+// CommentModuleName is the module name ticket threads use in the comments store
+const CommentModuleName = "tickets"
+
+// StageGateModuleName is the module name stage gates use for tickets
+const StageGateModuleName = "tickets"
+
+// PermissionModuleName is the module role permissions name for tickets
+const PermissionModuleName = "tickets"
+
+// TicketComment is a comment written before ticket threads moved to the
+// generic comments feature. The ticket_comments collection is only read now.
 type TicketComment struct {
 	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	TicketID   primitive.ObjectID `json:"ticket_id" bson:"ticket_id"`
@@ -153,15 +223,150 @@ type EscalationRule struct {
 	Priority      *TicketPriority `json:"priority,omitempty" bson:"priority,omitempty"`
 	Status        *TicketStatus   `json:"status,omitempty" bson:"status,omitempty"`
 	EscalateAfter int             `json:"escalate_after" bson:"escalate_after"`
-	ConditionType string          `json:"condition_type" bson:"condition_type"`
+	ConditionType string          `json:"condition_type" bson:"condition_type"` // sla_breach, no_response, no_update or negative_sentiment
 
 	// Escalation Action
 	EscalateTo     primitive.ObjectID `json:"escalate_to" bson:"escalate_to"`
 	EscalateToType string             `json:"escalate_to_type" bson:"escalate_to_type"`
 	NotifyEmails   []string           `json:"notify_emails,omitempty" bson:"notify_emails,omitempty"`
+	// Levels escalate one after another, each on its own timer. Rules
+	// without levels escalate once, to EscalateTo.
+	Levels []EscalationLevel `json:"levels,omitempty" bson:"levels,omitempty"`
+
+	// BusinessHoursOnly counts EscalateAfter and level timers in working
+	// time: BusinessHours, by default Monday to Friday 09:00-17:00 in the
+	// tenant's timezone
+	BusinessHoursOnly bool           `json:"business_hours_only" bson:"business_hours_only"`
+	BusinessHours     *BusinessHours `json:"business_hours,omitempty" bson:"business_hours,omitempty"`
+	// SuppressOnUpdate stops the chain once the ticket is updated after an
+	// escalation, such as by an agent reply
+	SuppressOnUpdate bool `json:"suppress_on_update" bson:"suppress_on_update"`
+	// Notification replaces the default escalation notification
+	Notification *EscalationNotification `json:"notification,omitempty" bson:"notification,omitempty"`
 
 	// Status
 	IsActive  bool      `json:"is_active" bson:"is_active"`
 	CreatedAt time.Time `json:"created_at" bson:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
 }
+
+// Escalation targets of a chain level
+const (
+	EscalateToUser = "user"
+	// EscalateToManager escalates to the manager of whoever the previous
+	// level escalated to, or of the assignee at the first level
+	EscalateToManager = "manager"
+)
+
+// EscalationLevel is one step of an escalation chain
+type EscalationLevel struct {
+	EscalateTo     primitive.ObjectID `json:"escalate_to,omitempty" bson:"escalate_to,omitempty"`
+	EscalateToType string             `json:"escalate_to_type,omitempty" bson:"escalate_to_type,omitempty"` // user (default) or manager
+	// After is the minutes since the previous level before this one
+	// escalates; the first level escalates as soon as the rule applies
+	After int `json:"after" bson:"after"`
+}
+
+// EscalationNotification is sent to the escalated user and NotifyEmails. Title
+// and Message may use the ticket placeholders of NotificationTrigger along
+// with {{rule}} and {{level}}.
+type EscalationNotification struct {
+	Title   string `json:"title" bson:"title"`
+	Message string `json:"message" bson:"message"`
+}
+
+// Fields that TicketSettings.RequiredOnClose may list
+var closeRequirableFields = map[string]bool{
+	"resolution_code": true,
+	"root_cause":      true,
+	"category":        true,
+}
+
+// TicketSettings configures the ticket lifecycle. There is one document for
+// the whole ticket store; defaults apply until it is saved.
+type TicketSettings struct {
+	// AutoCloseAfterDays closes resolved tickets without a customer reply
+	// for this many days; 0 disables auto-close
+	AutoCloseAfterDays int `json:"auto_close_after_days" bson:"auto_close_after_days"`
+	// ReopenOnCustomerReply moves resolved tickets back to open when the
+	// customer comments on them
+	ReopenOnCustomerReply bool `json:"reopen_on_customer_reply" bson:"reopen_on_customer_reply"`
+	// RequiredOnClose lists ticket fields that must be set before closing:
+	// resolution_code, root_cause, category
+	RequiredOnClose []string  `json:"required_on_close" bson:"required_on_close"`
+	UpdatedAt       time.Time `json:"updated_at" bson:"updated_at"`
+
+	// EmailFilter screens email-created tickets
+	EmailFilter EmailFilter `json:"email_filter" bson:"email_filter"`
+
+	// Notifications configure who each ticket event notifies. Events left
+	// out use their default trigger.
+	Notifications []NotificationTrigger `json:"notifications" bson:"notifications"`
+}
+
+// EmailFilter decides which inbound emails become tickets. Entries in the
+// lists are full addresses or bare domains ("example.com").
+type EmailFilter struct {
+	// Blocklist drops email from these senders
+	Blocklist []string `json:"blocklist" bson:"blocklist"`
+	// Allowlist senders skip the spam checks
+	Allowlist []string `json:"allowlist" bson:"allowlist"`
+	// SpamKeywords mark an email as spam when found in its subject
+	SpamKeywords []string `json:"spam_keywords" bson:"spam_keywords"`
+	// QuarantineSpam keeps suspected spam as quarantined tickets for review;
+	// when false it is dropped
+	QuarantineSpam bool `json:"quarantine_spam" bson:"quarantine_spam"`
+}
+
+// NotificationTrigger sends a notification on a ticket event
+type NotificationTrigger struct {
+	Event   TicketEvent `json:"event" bson:"event"`
+	Enabled bool        `json:"enabled" bson:"enabled"`
+	// Recipients are assignee, watchers, team_lead (the assignee's manager)
+	// and customer, who is emailed; the user causing the event is left out
+	Recipients []string `json:"recipients" bson:"recipients"`
+	// Title and Message replace the event's default text. They may use
+	// {{ticket_number}}, {{subject}}, {{priority}}, {{status}},
+	// {{customer_name}} and {{due_date}}.
+	Title   string `json:"title,omitempty" bson:"title,omitempty"`
+	Message string `json:"message,omitempty" bson:"message,omitempty"`
+}
+
+// DefaultTicketSettings is used until settings are saved
+func DefaultTicketSettings() *TicketSettings {
+	return &TicketSettings{
+		AutoCloseAfterDays:    7,
+		ReopenOnCustomerReply: true,
+		RequiredOnClose:       []string{},
+		EmailFilter: EmailFilter{
+			Blocklist:      []string{},
+			Allowlist:      []string{},
+			SpamKeywords:   []string{},
+			QuarantineSpam: true,
+		},
+		Notifications: DefaultNotificationTriggers(),
+	}
+}
+
+// AgentStatus is an agent's self-reported availability
+type AgentStatus string
+
+const (
+	AgentStatusAvailable AgentStatus = "available"
+	AgentStatusBusy      AgentStatus = "busy"
+	AgentStatusAway      AgentStatus = "away"
+	AgentStatusOffline   AgentStatus = "offline"
+)
+
+// AgentAvailability is the current availability of one agent. Agents without
+// a document are treated as offline.
+type AgentAvailability struct {
+	UserID primitive.ObjectID `json:"user_id" bson:"_id"`
+	Status AgentStatus        `json:"status" bson:"status"`
+	// Teams are the assigned_group values the agent takes tickets for
+	Teams []string `json:"teams,omitempty" bson:"teams,omitempty"`
+	// MaxOpen caps the open tickets the agent should be assigned; 0 means no cap
+	MaxOpen   int                `json:"max_open" bson:"max_open"`
+	UpdatedBy primitive.ObjectID `json:"updated_by" bson:"updated_by"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
@@ -0,0 +1,309 @@
+package ticket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go-crm/internal/common/apperr"
+	"go-crm/internal/common/validation"
+	"go-crm/internal/features/notification"
+	"go-crm/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SLANotificationSchedule checks for SLA warnings and breaches every five minutes
+const SLANotificationSchedule = "*/5 * * * *"
+
+// slaWarningShare is the share of the resolution time after which the SLA
+// warning is sent
+const slaWarningShare = 0.75
+
+// TicketEvent is a ticket lifecycle event that can notify people
+type TicketEvent string
+
+const (
+	EventTicketCreated   TicketEvent = "created"
+	EventTicketAssigned  TicketEvent = "assigned"
+	EventCustomerReplied TicketEvent = "customer_replied"
+	EventInternalNote    TicketEvent = "internal_note"
+	// EventSLAWarning fires once 75% of the time to the resolution due date,
+	// counted from creation, has passed
+	EventSLAWarning     TicketEvent = "sla_warning"
+	EventSLABreached    TicketEvent = "sla_breached"
+	EventTicketResolved TicketEvent = "resolved"
+)
+
+// Recipients of a notification trigger
+const (
+	RecipientAssignee = "assignee"
+	RecipientWatchers = "watchers"
+	RecipientTeamLead = "team_lead"
+	RecipientCustomer = "customer"
+)
+
+var notificationRecipients = map[string]bool{
+	RecipientAssignee: true,
+	RecipientWatchers: true,
+	RecipientTeamLead: true,
+	RecipientCustomer: true,
+}
+
+// eventDefaults is the title and message of each event without a template
+var eventDefaults = map[TicketEvent][2]string{
+	EventTicketCreated:   {"New Ticket", "Ticket {{ticket_number}} was created: {{subject}}"},
+	EventTicketAssigned:  {"Ticket Assigned", "Ticket {{ticket_number}} has been assigned: {{subject}}"},
+	EventCustomerReplied: {"Customer Replied", "{{customer_name}} replied to ticket {{ticket_number}}: {{subject}}"},
+	EventInternalNote:    {"Internal Note Added", "An internal note was added to ticket {{ticket_number}}: {{subject}}"},
+	EventSLAWarning:      {"SLA Warning", "Ticket {{ticket_number}} is due {{due_date}}: {{subject}}"},
+	EventSLABreached:     {"SLA Breached", "Ticket {{ticket_number}} was due {{due_date}}: {{subject}}"},
+	EventTicketResolved:  {"Ticket Resolved", "Ticket {{ticket_number}} has been resolved: {{subject}}"},
+}
+
+// DefaultNotificationTriggers lists every event; only assignment notifies
+// until configured otherwise
+func DefaultNotificationTriggers() []NotificationTrigger {
+	return []NotificationTrigger{
+		{Event: EventTicketCreated, Recipients: []string{RecipientAssignee}},
+		{Event: EventTicketAssigned, Enabled: true, Recipients: []string{RecipientAssignee}},
+		{Event: EventCustomerReplied, Recipients: []string{RecipientAssignee, RecipientWatchers}},
+		{Event: EventInternalNote, Recipients: []string{RecipientAssignee, RecipientWatchers}},
+		{Event: EventSLAWarning, Recipients: []string{RecipientAssignee}},
+		{Event: EventSLABreached, Recipients: []string{RecipientAssignee, RecipientTeamLead}},
+		{Event: EventTicketResolved, Recipients: []string{RecipientCustomer}},
+	}
+}
+
+// trigger returns the event's configured trigger, else its default
+func (settings *TicketSettings) trigger(event TicketEvent) NotificationTrigger {
+	for _, t := range settings.Notifications {
+		if t.Event == event {
+			return t
+		}
+	}
+	for _, t := range DefaultNotificationTriggers() {
+		if t.Event == event {
+			return t
+		}
+	}
+	return NotificationTrigger{Event: event}
+}
+
+// validateNotificationTriggers checks the events and recipients of the triggers
+func validateNotificationTriggers(triggers []NotificationTrigger) error {
+	var errs validation.Errors
+	seen := map[TicketEvent]bool{}
+	for i, t := range triggers {
+		field := fmt.Sprintf("notifications.%d", i)
+		if _, ok := eventDefaults[t.Event]; !ok {
+			errs.Add(field+".event", validation.CodeInvalid, fmt.Sprintf("unknown ticket event '%s'", t.Event))
+		} else if seen[t.Event] {
+			errs.Add(field+".event", validation.CodeDuplicate, fmt.Sprintf("event '%s' is configured twice", t.Event))
+		}
+		seen[t.Event] = true
+		for j, r := range t.Recipients {
+			if !notificationRecipients[r] {
+				errs.Add(fmt.Sprintf("%s.recipients.%d", field, j), validation.CodeInvalid, "recipients must be assignee, watchers, team_lead or customer")
+			}
+		}
+	}
+	return errs.Err()
+}
+
+// ticketPlaceholders fills in the ticket placeholders of notification
+// templates, along with the extra old/new pairs
+func ticketPlaceholders(t *Ticket, extra ...string) *strings.Replacer {
+	due := ""
+	if t.DueDate != nil {
+		due = t.DueDate.UTC().Format("2006-01-02 15:04 MST")
+	}
+	pairs := []string{
+		"{{ticket_number}}", t.TicketNumber,
+		"{{subject}}", t.Subject,
+		"{{priority}}", string(t.Priority),
+		"{{status}}", string(t.Status),
+		"{{customer_name}}", t.CustomerName,
+		"{{due_date}}", due,
+	}
+	return strings.NewReplacer(append(pairs, extra...)...)
+}
+
+// notify sends the event's notification, when enabled, to its recipients
+// other than actor. Delivery failures are ignored like other notifications.
+func (s *TicketServiceImpl) notify(ctx context.Context, t *Ticket, event TicketEvent, actor primitive.ObjectID) {
+	settings, err := s.SettingsRepo.Get(ctx)
+	if err != nil {
+		return
+	}
+	trigger := settings.trigger(event)
+	if !trigger.Enabled {
+		return
+	}
+
+	title, message := eventDefaults[event][0], eventDefaults[event][1]
+	if trigger.Title != "" {
+		title = trigger.Title
+	}
+	if trigger.Message != "" {
+		message = trigger.Message
+	}
+	placeholders := ticketPlaceholders(t)
+	title, message = placeholders.Replace(title), placeholders.Replace(message)
+
+	var users []primitive.ObjectID
+	seen := map[primitive.ObjectID]bool{actor: true, primitive.NilObjectID: true}
+	add := func(id primitive.ObjectID) {
+		if !seen[id] {
+			seen[id] = true
+			users = append(users, id)
+		}
+	}
+	emailCustomer := false
+	for _, recipient := range trigger.Recipients {
+		switch recipient {
+		case RecipientAssignee:
+			if t.AssignedTo != nil {
+				add(*t.AssignedTo)
+			}
+		case RecipientWatchers:
+			for _, id := range t.Watchers {
+				add(id)
+			}
+		case RecipientTeamLead:
+			if lead := s.teamLead(ctx, t); lead != nil {
+				add(*lead)
+			}
+		case RecipientCustomer:
+			emailCustomer = t.CustomerID == nil || *t.CustomerID != actor
+		}
+	}
+
+	kind := notification.NotificationTypeTask
+	if event == EventSLAWarning || event == EventSLABreached {
+		kind = notification.NotificationTypeSLA
+	}
+	link := fmt.Sprintf("/dashboard/modules/tickets/%s", t.ID.Hex())
+	for _, id := range users {
+		_ = s.NotificationService.CreateNotification(ctx, id, title, message, kind, link)
+	}
+	if emailCustomer && t.CustomerEmail != "" && s.EmailService != nil {
+		_ = s.EmailService.SendEmail(ctx, []string{t.CustomerEmail}, title, message)
+	}
+}
+
+// teamLead is the manager of the ticket's assignee
+func (s *TicketServiceImpl) teamLead(ctx context.Context, t *Ticket) *primitive.ObjectID {
+	if t.AssignedTo == nil || s.UserRepo == nil {
+		return nil
+	}
+	u, err := s.UserRepo.FindByID(ctx, t.AssignedTo.Hex())
+	if err != nil {
+		return nil
+	}
+	return u.ReportsTo
+}
+
+// NotifySLA sends the SLA warning and breach notifications of open tickets,
+// once per resolution due date
+func (s *TicketServiceImpl) NotifySLA(ctx context.Context) error {
+	const batch = 500
+	now := time.Now()
+	filter := bson.M{
+		"status":   bson.M{"$nin": []TicketStatus{TicketStatusResolved, TicketStatusClosed, TicketStatusQuarantined}},
+		"due_date": bson.M{"$ne": nil},
+		"$expr":    bson.M{"$ne": bson.A{"$sla_breach_sent_for", "$due_date"}},
+	}
+	for {
+		tickets, _, err := s.TicketRepo.FindAll(ctx, filter, 1, batch, "_id", "asc")
+		if err != nil {
+			return err
+		}
+		for i := range tickets {
+			s.notifySLA(ctx, &tickets[i], now)
+		}
+		if len(tickets) < batch {
+			return nil
+		}
+		filter["_id"] = bson.M{"$gt": tickets[len(tickets)-1].ID}
+	}
+}
+
+// notifySLA sends the ticket's due SLA notification, if any
+func (s *TicketServiceImpl) notifySLA(ctx context.Context, t *Ticket, now time.Time) {
+	due := *t.DueDate
+	field, event := "sla_breach_sent_for", EventSLABreached
+	if now.Before(due) {
+		warnAt := t.CreatedAt.Add(time.Duration(float64(due.Sub(t.CreatedAt)) * slaWarningShare))
+		if now.Before(warnAt) || sameTime(t.SLAWarningSentFor, &due) {
+			return
+		}
+		field, event = "sla_warning_sent_for", EventSLAWarning
+	}
+
+	// Marking first keeps concurrent runs from notifying twice
+	marked, err := s.TicketRepo.MarkSLANotified(ctx, t.ID, field, due)
+	if err != nil {
+		log.Printf("ticket: failed to mark the SLA notification of %s: %v", t.TicketNumber, err)
+		return
+	}
+	if marked {
+		s.notify(ctx, t, event, primitive.NilObjectID)
+	}
+}
+
+// AddWatcher adds an existing user to the ticket's watchers. Users may add
+// themselves; adding someone else takes being the assignee or holding ticket
+// update permission.
+func (s *TicketServiceImpl) AddWatcher(ctx context.Context, id string, userID primitive.ObjectID) error {
+	if userID.IsZero() {
+		return validation.New("user_id", validation.CodeRequired, "user_id is required")
+	}
+	t, err := s.watchedTicket(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.UserRepo.FindByID(ctx, userID.Hex()); err != nil {
+		return validation.New("user_id", validation.CodeInvalid, "user not found")
+	}
+	return s.TicketRepo.AddWatcher(ctx, t.ID, userID)
+}
+
+// RemoveWatcher removes a user from the ticket's watchers, with the same
+// rules as AddWatcher
+func (s *TicketServiceImpl) RemoveWatcher(ctx context.Context, id string, userID primitive.ObjectID) error {
+	t, err := s.watchedTicket(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	return s.TicketRepo.RemoveWatcher(ctx, t.ID, userID)
+}
+
+// watchedTicket loads the ticket whose watchers the caller changes for
+// userID. Calls without user claims come from the system and are allowed.
+func (s *TicketServiceImpl) watchedTicket(ctx context.Context, id string, userID primitive.ObjectID) (*Ticket, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid ticket ID")
+	}
+	t, err := s.TicketRepo.FindByID(ctx, objID)
+	if err != nil {
+		return nil, apperr.NotFound("ticket not found")
+	}
+
+	claims, ok := ctx.Value(utils.UserClaimsKey).(*utils.UserClaims)
+	if !ok || claims.UserID == userID.Hex() {
+		return t, nil
+	}
+	if t.AssignedTo != nil && t.AssignedTo.Hex() == claims.UserID {
+		return t, nil
+	}
+	if allowed, err := s.RoleService.CheckModulePermission(ctx, claims.Roles, PermissionModuleName, "update"); err == nil && allowed {
+		return t, nil
+	}
+	return nil, apperr.PermissionDenied("only the assignee or users who can update tickets may change other users' watching")
+}
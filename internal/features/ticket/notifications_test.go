@@ -0,0 +1,280 @@
+package ticket
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-crm/internal/common/apperr"
+	"go-crm/internal/common/models"
+	"go-crm/internal/features/notification"
+	"go-crm/internal/features/role"
+	"go-crm/internal/features/user"
+	"go-crm/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// stubTicketRepo serves tickets from memory and marks SLA notifications
+// once per ticket, field and due date, as the repository's update does
+type stubTicketRepo struct {
+	TicketRepository
+	tickets  []Ticket
+	marked   map[string]bool
+	markErr  error
+	watchers map[primitive.ObjectID][]primitive.ObjectID
+}
+
+func (r *stubTicketRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*Ticket, error) {
+	for i := range r.tickets {
+		if r.tickets[i].ID == id {
+			return &r.tickets[i], nil
+		}
+	}
+	return nil, errors.New("ticket not found")
+}
+
+func (r *stubTicketRepo) FindAll(ctx context.Context, filter bson.M, page, limit int64, sortBy string, sortOrder string) ([]Ticket, int64, error) {
+	var out []Ticket
+	for _, t := range r.tickets {
+		if after, ok := filter["_id"].(bson.M); ok && t.ID.Hex() <= after["$gt"].(primitive.ObjectID).Hex() {
+			continue
+		}
+		out = append(out, t)
+		if int64(len(out)) == limit {
+			break
+		}
+	}
+	return out, int64(len(out)), nil
+}
+
+func (r *stubTicketRepo) MarkSLANotified(ctx context.Context, id primitive.ObjectID, field string, due time.Time) (bool, error) {
+	if r.markErr != nil {
+		return false, r.markErr
+	}
+	key := id.Hex() + field + due.String()
+	if r.marked[key] {
+		return false, nil
+	}
+	r.marked[key] = true
+	return true, nil
+}
+
+func (r *stubTicketRepo) AddWatcher(ctx context.Context, id, userID primitive.ObjectID) error {
+	r.watchers[id] = append(r.watchers[id], userID)
+	return nil
+}
+
+func (r *stubTicketRepo) RemoveWatcher(ctx context.Context, id, userID primitive.ObjectID) error {
+	r.watchers[id] = nil
+	return nil
+}
+
+// slaSettingsRepo enables the SLA notifications for the assignee
+type slaSettingsRepo struct {
+	TicketSettingsRepository
+}
+
+func (r *slaSettingsRepo) Get(ctx context.Context) (*TicketSettings, error) {
+	return &TicketSettings{Notifications: []NotificationTrigger{
+		{Event: EventSLAWarning, Enabled: true, Recipients: []string{RecipientAssignee}},
+		{Event: EventSLABreached, Enabled: true, Recipients: []string{RecipientAssignee}},
+	}}, nil
+}
+
+type sentNotification struct {
+	userID primitive.ObjectID
+	title  string
+	kind   notification.NotificationType
+}
+
+type recordingNotifications struct {
+	notification.NotificationService
+	sent []sentNotification
+}
+
+func (n *recordingNotifications) CreateNotification(ctx context.Context, userID primitive.ObjectID, title, message string, notifType notification.NotificationType, link string) error {
+	n.sent = append(n.sent, sentNotification{userID: userID, title: title, kind: notifType})
+	return nil
+}
+
+func slaService(tickets ...Ticket) (*TicketServiceImpl, *stubTicketRepo, *recordingNotifications) {
+	repo := &stubTicketRepo{tickets: tickets, marked: map[string]bool{}, watchers: map[primitive.ObjectID][]primitive.ObjectID{}}
+	notifications := &recordingNotifications{}
+	return &TicketServiceImpl{
+		TicketRepo:          repo,
+		SettingsRepo:        &slaSettingsRepo{},
+		NotificationService: notifications,
+	}, repo, notifications
+}
+
+func TestNotifySLA(t *testing.T) {
+	now := time.Now()
+	created := now.Add(-8 * time.Hour)
+	day := func(h int) *time.Time {
+		due := created.Add(time.Duration(h) * time.Hour)
+		return &due
+	}
+
+	tests := []struct {
+		name      string
+		due       *time.Time
+		warned    bool
+		markErr   error
+		wantTitle string
+	}{
+		// 8 of 20 hours passed: before the 75% warning point
+		{name: "not yet due for a warning", due: day(20)},
+		// 8 of 10 hours passed
+		{name: "warning", due: day(10), wantTitle: "SLA Warning"},
+		{name: "warning already sent for the due date", due: day(10), warned: true},
+		{name: "breach", due: day(4), wantTitle: "SLA Breached"},
+		{name: "breach after the warning", due: day(4), warned: true, wantTitle: "SLA Breached"},
+		{name: "marking fails", due: day(4), markErr: errors.New("write failed")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assignee := primitive.NewObjectID()
+			ticket := Ticket{ID: primitive.NewObjectID(), TicketNumber: "T-1", CreatedAt: created, DueDate: tt.due, AssignedTo: &assignee}
+			if tt.warned {
+				ticket.SLAWarningSentFor = tt.due
+			}
+			s, repo, notifications := slaService(ticket)
+			repo.markErr = tt.markErr
+
+			s.notifySLA(context.Background(), &ticket, now)
+
+			if tt.wantTitle == "" {
+				if len(notifications.sent) != 0 {
+					t.Fatalf("sent %+v, want nothing", notifications.sent)
+				}
+				return
+			}
+			if len(notifications.sent) != 1 {
+				t.Fatalf("sent %d notifications, want 1", len(notifications.sent))
+			}
+			got := notifications.sent[0]
+			if got.title != tt.wantTitle || got.userID != assignee || got.kind != notification.NotificationTypeSLA {
+				t.Errorf("sent %+v, want %q to the assignee", got, tt.wantTitle)
+			}
+		})
+	}
+}
+
+func TestNotifySLASendsOncePerDueDate(t *testing.T) {
+	now := time.Now()
+	due := now.Add(-time.Hour)
+	assignee := primitive.NewObjectID()
+	breached := Ticket{ID: primitive.NewObjectID(), CreatedAt: now.Add(-5 * time.Hour), DueDate: &due, AssignedTo: &assignee}
+	s, repo, notifications := slaService(breached)
+
+	// Overlapping runs: the stub ignores the $expr filter, so both see the ticket
+	for i := 0; i < 2; i++ {
+		if err := s.NotifySLA(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(notifications.sent) != 1 {
+		t.Fatalf("sent %d notifications, want 1", len(notifications.sent))
+	}
+
+	// A new due date, as after a priority change, is notified again
+	later := now.Add(-30 * time.Minute)
+	repo.tickets[0].DueDate = &later
+	if err := s.NotifySLA(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(notifications.sent) != 2 {
+		t.Errorf("sent %d notifications, want 2 after the due date moved", len(notifications.sent))
+	}
+}
+
+// watcherUserRepo knows the listed users
+type watcherUserRepo struct {
+	user.UserRepository
+	known map[string]bool
+}
+
+func (r *watcherUserRepo) FindByID(ctx context.Context, id string) (*models.User, error) {
+	if !r.known[id] {
+		return nil, errors.New("user not found")
+	}
+	return &models.User{}, nil
+}
+
+// ticketUpdaters grants ticket update to the "support_lead" role
+type ticketUpdaters struct {
+	role.RoleService
+}
+
+func (s *ticketUpdaters) CheckModulePermission(ctx context.Context, roleNames []string, moduleName string, permission string) (bool, error) {
+	for _, r := range roleNames {
+		if r == "support_lead" && moduleName == PermissionModuleName && permission == "update" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestAddWatcher(t *testing.T) {
+	assignee, caller, other, ghost := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	tests := []struct {
+		name     string
+		callerID primitive.ObjectID
+		roles    []string
+		watcher  primitive.ObjectID
+		wantCode string
+	}{
+		{name: "self", callerID: caller, watcher: caller},
+		{name: "assignee adds someone", callerID: assignee, watcher: other},
+		{name: "ticket updater adds someone", callerID: caller, roles: []string{"support_lead"}, watcher: other},
+		{name: "other user adds someone", callerID: caller, roles: []string{"agent"}, watcher: other, wantCode: apperr.CodePermissionDenied},
+		{name: "unknown user", callerID: assignee, watcher: ghost, wantCode: apperr.CodeValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ticket := Ticket{ID: primitive.NewObjectID(), AssignedTo: &assignee}
+			s, repo, _ := slaService(ticket)
+			s.UserRepo = &watcherUserRepo{known: map[string]bool{assignee.Hex(): true, caller.Hex(): true, other.Hex(): true}}
+			s.RoleService = &ticketUpdaters{}
+			ctx := context.WithValue(context.Background(), utils.UserClaimsKey, &utils.UserClaims{UserID: tt.callerID.Hex(), Roles: tt.roles})
+
+			err := s.AddWatcher(ctx, ticket.ID.Hex(), tt.watcher)
+			if tt.wantCode != "" {
+				if apperr.CodeOf(err) != tt.wantCode {
+					t.Fatalf("err = %v (%s), want %s", err, apperr.CodeOf(err), tt.wantCode)
+				}
+				if len(repo.watchers[ticket.ID]) != 0 {
+					t.Errorf("watchers = %v, want none", repo.watchers[ticket.ID])
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w := repo.watchers[ticket.ID]; len(w) != 1 || w[0] != tt.watcher {
+				t.Errorf("watchers = %v, want %v", w, tt.watcher)
+			}
+		})
+	}
+}
+
+func TestRemoveWatcherOfAnotherUserIsDenied(t *testing.T) {
+	assignee, caller, other := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	ticket := Ticket{ID: primitive.NewObjectID(), AssignedTo: &assignee, Watchers: []primitive.ObjectID{other}}
+	s, _, _ := slaService(ticket)
+	s.RoleService = &ticketUpdaters{}
+	ctx := context.WithValue(context.Background(), utils.UserClaimsKey, &utils.UserClaims{UserID: caller.Hex()})
+
+	if err := s.RemoveWatcher(ctx, ticket.ID.Hex(), other); apperr.CodeOf(err) != apperr.CodePermissionDenied {
+		t.Errorf("err = %v, want permission denied", err)
+	}
+	if err := s.RemoveWatcher(ctx, ticket.ID.Hex(), caller); err != nil {
+		t.Errorf("removing oneself: %v", err)
+	}
+}
@@ -25,7 +25,26 @@ type TicketRepository interface {
 	FindByAssignee(ctx context.Context, userID primitive.ObjectID, page, limit int64) ([]Ticket, int64, error)
 	FindOverdueSLA(ctx context.Context) ([]Ticket, error)
 	UpdateStatus(ctx context.Context, id primitive.ObjectID, status TicketStatus, historyEntry StatusHistoryEntry) error
+	// AddEscalation records an escalation without touching updated_at
+	AddEscalation(ctx context.Context, id primitive.ObjectID, entry EscalationHistoryEntry) error
+	// AddAgentResponse counts a public agent reply and the template it used, if any
+	AddAgentResponse(ctx context.Context, id primitive.ObjectID, templateID *primitive.ObjectID) error
+	// MarkSLANotified sets field, sla_warning_sent_for or sla_breach_sent_for,
+	// to due; it reports false when it already was
+	MarkSLANotified(ctx context.Context, id primitive.ObjectID, field string, due time.Time) (bool, error)
+	AddWatcher(ctx context.Context, id, userID primitive.ObjectID) error
+	RemoveWatcher(ctx context.Context, id, userID primitive.ObjectID) error
 	GetNextTicketNumber(ctx context.Context) (string, error)
+	AddAsset(ctx context.Context, id, assetID primitive.ObjectID) error
+	RemoveAsset(ctx context.Context, id, assetID primitive.ObjectID) error
+	FindByAsset(ctx context.Context, assetID primitive.ObjectID) ([]Ticket, error)
+	ReopenResolved(ctx context.Context, id primitive.ObjectID, historyEntry StatusHistoryEntry) (bool, error)
+	AutoCloseResolved(ctx context.Context, resolvedBefore time.Time, required []string, historyEntry StatusHistoryEntry) (int64, error)
+	SLAReport(ctx context.Context, q SLAReportQuery, now time.Time) ([]SLAReportRow, error)
+	FirstContactReport(ctx context.Context, q SLAReportQuery) ([]FirstContactRow, error)
+	ResponseTemplateReport(ctx context.Context, q SLAReportQuery) ([]ResponseTemplateRow, error)
+	Workload(ctx context.Context, q WorkloadQuery, now time.Time) ([]WorkloadRow, error)
+	CustomerSummary(ctx context.Context, emails []string, now time.Time) (*CustomerTicketSummary, error)
 }
 
 // TicketRepositoryImpl implements TicketRepository
@@ -183,8 +202,9 @@ func (r *TicketRepositoryImpl) FindOverdueSLA(ctx context.Context) ([]Ticket, er
 // UpdateStatus updates the ticket status and adds to history
 func (r *TicketRepositoryImpl) UpdateStatus(ctx context.Context, id primitive.ObjectID, status TicketStatus, historyEntry StatusHistoryEntry) error {
 	updates := bson.M{
-		"status":     status,
-		"updated_at": time.Now(),
+		"status":            status,
+		"status_changed_at": historyEntry.ChangedAt,
+		"updated_at":        time.Now(),
 	}
 
 	// Add resolved/closed timestamps
@@ -213,6 +233,185 @@ func (r *TicketRepositoryImpl) UpdateStatus(ctx context.Context, id primitive.Ob
 	return nil
 }
 
+// AddEscalation appends an entry to the ticket's escalation history and
+// moves the ticket to its level. updated_at is kept, as escalation rules
+// watch it for updates by people.
+func (r *TicketRepositoryImpl) AddEscalation(ctx context.Context, id primitive.ObjectID, entry EscalationHistoryEntry) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$push": bson.M{"escalation_history": entry},
+		"$set":  bson.M{"escalation_level": entry.Level, "escalated_to": entry.EscalatedTo},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("ticket not found")
+	}
+	return nil
+}
+
+// AddAgentResponse increments the agent reply count and records the template
+func (r *TicketRepositoryImpl) AddAgentResponse(ctx context.Context, id primitive.ObjectID, templateID *primitive.ObjectID) error {
+	update := bson.M{
+		"$inc": bson.M{"agent_responses": 1},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+	if templateID != nil {
+		update["$push"] = bson.M{"response_template_ids": *templateID}
+	}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("ticket not found")
+	}
+	return nil
+}
+
+// MarkSLANotified records the due date an SLA notification was sent for,
+// without touching updated_at
+func (r *TicketRepositoryImpl) MarkSLANotified(ctx context.Context, id primitive.ObjectID, field string, due time.Time) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, field: bson.M{"$ne": due}},
+		bson.M{"$set": bson.M{field: due}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// AddWatcher adds a watcher to a ticket. Watching is not an update of the
+// ticket, so updated_at is kept.
+func (r *TicketRepositoryImpl) AddWatcher(ctx context.Context, id, userID primitive.ObjectID) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$addToSet": bson.M{"watchers": userID},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("ticket not found")
+	}
+	return nil
+}
+
+// RemoveWatcher removes a watcher from a ticket
+func (r *TicketRepositoryImpl) RemoveWatcher(ctx context.Context, id, userID primitive.ObjectID) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$pull": bson.M{"watchers": userID},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("ticket not found")
+	}
+	return nil
+}
+
+// AddAsset links an asset to a ticket
+func (r *TicketRepositoryImpl) AddAsset(ctx context.Context, id, assetID primitive.ObjectID) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$addToSet": bson.M{"asset_ids": assetID},
+		"$set":      bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("ticket not found")
+	}
+	return nil
+}
+
+// RemoveAsset unlinks an asset from a ticket
+func (r *TicketRepositoryImpl) RemoveAsset(ctx context.Context, id, assetID primitive.ObjectID) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$pull": bson.M{"asset_ids": assetID},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("ticket not found")
+	}
+	return nil
+}
+
+// FindByAsset returns the tickets linked to an asset, newest first
+func (r *TicketRepositoryImpl) FindByAsset(ctx context.Context, assetID primitive.ObjectID) ([]Ticket, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"asset_ids": assetID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tickets := []Ticket{}
+	if err := cursor.All(ctx, &tickets); err != nil {
+		return nil, err
+	}
+	return tickets, nil
+}
+
+// ReopenResolved moves a resolved ticket back to open after a customer reply
+// and counts the reopen. It reports false when the ticket was not resolved.
+func (r *TicketRepositoryImpl) ReopenResolved(ctx context.Context, id primitive.ObjectID, historyEntry StatusHistoryEntry) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "status": TicketStatusResolved},
+		bson.M{
+			"$set": bson.M{
+				"status":                 TicketStatusOpen,
+				"status_changed_at":      historyEntry.ChangedAt,
+				"last_customer_reply_at": historyEntry.ChangedAt,
+				"updated_at":             time.Now(),
+			},
+			"$unset": bson.M{"resolved_at": ""},
+			"$inc":   bson.M{"reopen_count": 1},
+			"$push":  bson.M{"status_history": historyEntry},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// AutoCloseResolved closes tickets resolved before resolvedBefore whose
+// required fields are all set, and returns how many were closed
+func (r *TicketRepositoryImpl) AutoCloseResolved(ctx context.Context, resolvedBefore time.Time, required []string, historyEntry StatusHistoryEntry) (int64, error) {
+	filter := bson.M{
+		"status":      TicketStatusResolved,
+		"resolved_at": bson.M{"$lte": resolvedBefore},
+	}
+	for _, field := range required {
+		filter[field] = bson.M{"$nin": bson.A{nil, ""}}
+	}
+
+	result, err := r.collection.UpdateMany(
+		ctx,
+		filter,
+		bson.M{
+			"$set": bson.M{
+				"status":            TicketStatusClosed,
+				"status_changed_at": historyEntry.ChangedAt,
+				"closed_at":         historyEntry.ChangedAt,
+				"auto_closed":       true,
+				"updated_at":        time.Now(),
+			},
+			"$push": bson.M{"status_history": historyEntry},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
 // GetNextTicketNumber generates the next ticket number
 func (r *TicketRepositoryImpl) GetNextTicketNumber(ctx context.Context) (string, error) {
 	// Find the latest ticket
@@ -0,0 +1,85 @@
+package ticket
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/common/validation"
+	"go-crm/internal/features/comment"
+	"go-crm/internal/features/email_template"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TicketCommentRequest is a ticket comment, optionally written from a reply
+// template
+type TicketCommentRequest struct {
+	comment.CreateCommentRequest
+	// TemplateID is a ticket email template the reply uses. An empty
+	// content is filled with the template's body; edited content still
+	// counts as a use of the template.
+	TemplateID string `json:"template_id"`
+}
+
+// replyTemplate returns an active ticket email template
+func (s *TicketServiceImpl) replyTemplate(ctx context.Context, id string) (*email_template.EmailTemplate, error) {
+	if s.Templates == nil {
+		return nil, errors.New("reply templates are not available")
+	}
+	tpl, err := s.Templates.GetTemplate(ctx, id)
+	if err != nil || tpl == nil {
+		return nil, validation.New("template_id", validation.CodeInvalid, "template not found")
+	}
+	if tpl.ModuleName != CommentModuleName {
+		return nil, validation.New("template_id", validation.CodeNotAllowed, "template is not a ticket template")
+	}
+	if !tpl.IsActive {
+		return nil, validation.New("template_id", validation.CodeNotAllowed, "template is inactive")
+	}
+	return tpl, nil
+}
+
+// RateSatisfaction stores the customer's CSAT score, 1 to 5, for a resolved
+// or closed ticket. Agents may record a rating given over another channel;
+// a new rating replaces the previous one.
+func (s *TicketServiceImpl) RateSatisfaction(ctx context.Context, id string, score int, comment string, ratedBy primitive.ObjectID) (*SatisfactionRating, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid ticket ID")
+	}
+	if score < 1 || score > 5 {
+		return nil, validation.New("score", validation.CodeInvalid, "score must be between 1 and 5")
+	}
+
+	t, err := s.TicketRepo.FindByID(ctx, objID)
+	if err != nil {
+		return nil, err
+	}
+	if t.Status != TicketStatusResolved && t.Status != TicketStatusClosed {
+		return nil, apperr.Conflict("only resolved or closed tickets can be rated")
+	}
+
+	rating := &SatisfactionRating{
+		Score:   score,
+		Comment: strings.TrimSpace(comment),
+		RatedBy: ratedBy,
+		RatedAt: time.Now(),
+	}
+	if err := s.TicketRepo.Update(ctx, objID, bson.M{"satisfaction": rating}); err != nil {
+		return nil, err
+	}
+
+	var old interface{}
+	if t.Satisfaction != nil {
+		old = t.Satisfaction.Score
+	}
+	_ = s.AuditService.LogChange(ctx, common_models.AuditActionUpdate, "tickets", objID.Hex(), map[string]common_models.Change{
+		"satisfaction": {Old: old, New: score},
+	})
+	return rating, nil
+}
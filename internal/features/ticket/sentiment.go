@@ -0,0 +1,320 @@
+package ticket
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SentimentLabel buckets a sentiment score
+type SentimentLabel string
+
+const (
+	SentimentVeryNegative SentimentLabel = "very_negative"
+	SentimentNegative     SentimentLabel = "negative"
+	SentimentNeutral      SentimentLabel = "neutral"
+	SentimentPositive     SentimentLabel = "positive"
+	SentimentVeryPositive SentimentLabel = "very_positive"
+)
+
+// TriggerSentimentScored is the automation trigger fired on the tickets
+// module after each scored customer message. The record carries
+// sentiment_score, sentiment_label and negative_streak, so a rule can match
+// e.g. negative_streak gte 2.
+const TriggerSentimentScored = "sentiment_scored"
+
+// EscalateOnNegativeSentiment is the escalation rule condition matching
+// tickets whose last EscalateAfter customer messages were very negative
+const EscalateOnNegativeSentiment = "negative_sentiment"
+
+// TicketSentiment is the sentiment of the customer's latest message
+type TicketSentiment struct {
+	Score float64        `json:"score" bson:"score"` // -1 to 1
+	Label SentimentLabel `json:"label" bson:"label"`
+	// NegativeStreak counts the customer's consecutive very negative
+	// messages, up to and including the latest
+	NegativeStreak  int        `json:"negative_streak" bson:"negative_streak"`
+	StreakStartedAt *time.Time `json:"streak_started_at,omitempty" bson:"streak_started_at,omitempty"`
+	Messages        int        `json:"messages" bson:"messages"`
+	UpdatedAt       time.Time  `json:"updated_at" bson:"updated_at"`
+}
+
+// SentimentEntry is one scored customer message, kept for trends
+type SentimentEntry struct {
+	ID        primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	TicketID  primitive.ObjectID  `json:"ticket_id" bson:"ticket_id"`
+	CommentID *primitive.ObjectID `json:"comment_id,omitempty" bson:"comment_id,omitempty"` // Unset for the ticket description
+	// AccountID is the account of the contact with the customer's email
+	AccountID     string         `json:"account_id,omitempty" bson:"account_id,omitempty"`
+	CustomerEmail string         `json:"customer_email,omitempty" bson:"customer_email,omitempty"`
+	Score         float64        `json:"score" bson:"score"`
+	Label         SentimentLabel `json:"label" bson:"label"`
+	CreatedAt     time.Time      `json:"created_at" bson:"created_at"`
+}
+
+// sentimentLexicon weighs words by polarity, from -3 to 3
+var sentimentLexicon = map[string]float64{
+	// Negative
+	"angry": -3, "furious": -3, "outraged": -3, "unacceptable": -3, "terrible": -3, "horrible": -3,
+	"awful": -3, "worst": -3, "disgusted": -3, "ridiculous": -2.5, "useless": -2.5, "scam": -3,
+	"hate": -3, "pathetic": -3, "incompetent": -3, "lawyer": -2, "lawsuit": -3, "refund": -1,
+	"cancel": -1.5, "cancelling": -1.5, "canceling": -1.5, "disappointed": -2, "disappointing": -2,
+	"frustrated": -2, "frustrating": -2, "annoyed": -2, "annoying": -2, "upset": -2, "unhappy": -2,
+	"poor": -1.5, "bad": -1.5, "broken": -1.5, "fail": -1.5, "failed": -1.5, "failing": -1.5,
+	"failure": -1.5, "error": -1, "errors": -1, "bug": -1, "issue": -0.5, "problem": -1,
+	"problems": -1, "slow": -1, "waiting": -1, "waited": -1, "still": -0.5, "again": -0.5,
+	"nobody": -1.5, "ignored": -2, "ignoring": -2, "wrong": -1.5, "worse": -2,
+	"crash": -1.5, "crashed": -1.5, "crashes": -1.5, "crashing": -1.5, "lost": -1.5, "missing": -1, "confusing": -1,
+	"complaint": -2, "complain": -1.5, "urgent": -1, "impossible": -2, "sucks": -2.5,
+	// Positive
+	"thanks": 1.5, "thank": 1.5, "thx": 1, "appreciate": 2, "appreciated": 2, "great": 2.5,
+	"excellent": 3, "amazing": 3, "awesome": 3, "fantastic": 3, "perfect": 3, "love": 3,
+	"wonderful": 3, "helpful": 2, "happy": 2, "glad": 2, "pleased": 2, "good": 1.5, "nice": 1.5,
+	"works": 1, "working": 0.5, "fixed": 2, "resolved": 2, "solved": 2, "quick": 1.5, "fast": 1.5,
+	"easy": 1.5, "satisfied": 2, "brilliant": 3, "kind": 1.5, "friendly": 2, "impressed": 2.5,
+}
+
+// sentimentNegators flip the polarity of the next few words
+var sentimentNegators = map[string]bool{
+	"not": true, "no": true, "never": true, "without": true, "hardly": true,
+	"dont": true, "doesnt": true, "didnt": true, "isnt": true, "wasnt": true, "cant": true,
+	"cannot": true, "wont": true, "havent": true, "hasnt": true, "arent": true,
+}
+
+// sentimentBoosters strengthen the next word
+var sentimentBoosters = map[string]float64{
+	"very": 0.3, "really": 0.3, "extremely": 0.5, "so": 0.2, "totally": 0.3, "completely": 0.4,
+	"absolutely": 0.5, "incredibly": 0.5, "super": 0.3, "utterly": 0.5,
+}
+
+var htmlTagPattern = regexp.MustCompile(`(?s)<[^>]*>`)
+
+// AnalyzeSentiment scores text from -1 (very negative) to 1 (very
+// positive) with a word lexicon. Negations flip the following words,
+// intensifiers, shouting and exclamation marks strengthen them, and quoted
+// reply lines are ignored.
+func AnalyzeSentiment(text string) (float64, SentimentLabel) {
+	text = html.UnescapeString(htmlTagPattern.ReplaceAllString(text, " "))
+
+	var sum float64
+	exclaims := 0
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, ">") {
+			continue
+		}
+		exclaims += strings.Count(line, "!")
+
+		negateFor := 0
+		boost := 0.0
+		words := strings.FieldsFunc(line, func(r rune) bool {
+			return !unicode.IsLetter(r) && r != '\''
+		})
+		for _, raw := range words {
+			word := strings.ToLower(strings.ReplaceAll(raw, "'", ""))
+			if sentimentNegators[word] {
+				negateFor = 3
+				continue
+			}
+			if b, ok := sentimentBoosters[word]; ok {
+				boost += b
+				continue
+			}
+			weight, ok := sentimentLexicon[word]
+			if ok {
+				weight *= 1 + boost
+				if len(raw) > 2 && raw == strings.ToUpper(raw) {
+					weight *= 1.3
+				}
+				if negateFor > 0 {
+					weight *= -0.75
+				}
+				sum += weight
+			}
+			boost = 0
+			if negateFor > 0 {
+				negateFor--
+			}
+		}
+	}
+	if sum != 0 {
+		sum *= 1 + 0.1*math.Min(float64(exclaims), 4)
+	}
+
+	// Squash into [-1, 1]; a couple of strong words already read as very
+	score := sum / math.Sqrt(sum*sum+15)
+	return math.Round(score*1000) / 1000, sentimentLabel(score)
+}
+
+func sentimentLabel(score float64) SentimentLabel {
+	switch {
+	case score <= -0.6:
+		return SentimentVeryNegative
+	case score <= -0.2:
+		return SentimentNegative
+	case score >= 0.6:
+		return SentimentVeryPositive
+	case score >= 0.2:
+		return SentimentPositive
+	}
+	return SentimentNeutral
+}
+
+// recordSentiment scores a customer message, updates the ticket's sentiment
+// and fires the sentiment automations. Failures are logged; they never fail
+// the write that brought the message in.
+func (s *TicketServiceImpl) recordSentiment(ctx context.Context, t *Ticket, commentID *primitive.ObjectID, text string) {
+	if s.SentimentRepo == nil || strings.TrimSpace(text) == "" {
+		return
+	}
+	score, label := AnalyzeSentiment(text)
+	now := time.Now()
+
+	sentiment := TicketSentiment{Score: score, Label: label, UpdatedAt: now, Messages: 1}
+	if t.Sentiment != nil {
+		sentiment.Messages = t.Sentiment.Messages + 1
+	}
+	if label == SentimentVeryNegative {
+		sentiment.NegativeStreak = 1
+		sentiment.StreakStartedAt = &now
+		if t.Sentiment != nil && t.Sentiment.NegativeStreak > 0 {
+			sentiment.NegativeStreak = t.Sentiment.NegativeStreak + 1
+			sentiment.StreakStartedAt = t.Sentiment.StreakStartedAt
+		}
+	}
+	if err := s.TicketRepo.Update(ctx, t.ID, bson.M{"sentiment": sentiment}); err != nil {
+		log.Printf("tickets: failed to store sentiment of %s: %v", t.TicketNumber, err)
+		return
+	}
+	t.Sentiment = &sentiment
+
+	entry := &SentimentEntry{
+		TicketID:      t.ID,
+		CommentID:     commentID,
+		AccountID:     s.customerAccount(ctx, t.CustomerEmail),
+		CustomerEmail: t.CustomerEmail,
+		Score:         score,
+		Label:         label,
+		CreatedAt:     now,
+	}
+	if err := s.SentimentRepo.Create(ctx, entry); err != nil {
+		log.Printf("tickets: failed to store sentiment entry of %s: %v", t.TicketNumber, err)
+	}
+
+	if s.Automations != nil {
+		if err := s.Automations.ExecuteFromTrigger(ctx, CommentModuleName, sentimentRecord(t, entry.AccountID), TriggerSentimentScored); err != nil {
+			log.Printf("tickets: sentiment automations for %s failed: %v", t.TicketNumber, err)
+		}
+	}
+}
+
+// customerAccount returns the account of the contact with the email, if any
+func (s *TicketServiceImpl) customerAccount(ctx context.Context, email string) string {
+	if s.Contacts == nil || email == "" {
+		return ""
+	}
+	contacts, err := s.Contacts.List(ctx, "contacts", map[string]any{"email": email}, nil, 1, 0, "created_at", 1)
+	if err != nil || len(contacts) == 0 {
+		return ""
+	}
+	switch account := contacts[0]["account"].(type) {
+	case string:
+		return account
+	case primitive.ObjectID:
+		return account.Hex()
+	}
+	return ""
+}
+
+// sentimentRecord is the ticket as automation conditions see it
+func sentimentRecord(t *Ticket, accountID string) map[string]interface{} {
+	rec := map[string]interface{}{
+		"_id":             t.ID,
+		"id":              t.ID,
+		"ticket_number":   t.TicketNumber,
+		"subject":         t.Subject,
+		"status":          string(t.Status),
+		"priority":        string(t.Priority),
+		"channel":         string(t.Channel),
+		"customer_email":  t.CustomerEmail,
+		"customer_name":   t.CustomerName,
+		"assigned_group":  t.AssignedGroup,
+		"account":         accountID,
+		"sentiment_score": t.Sentiment.Score,
+		"sentiment_label": string(t.Sentiment.Label),
+		"negative_streak": t.Sentiment.NegativeStreak,
+	}
+	if t.AssignedTo != nil {
+		rec["assigned_to"] = t.AssignedTo.Hex()
+	}
+	return rec
+}
+
+// SentimentTrendQuery selects the customer messages scored in [Start, End),
+// split into day, week or month periods in Timezone
+type SentimentTrendQuery struct {
+	Start     time.Time
+	End       time.Time
+	Interval  string
+	Timezone  string
+	AccountID string // Only this account
+}
+
+// SentimentTrendRow is the sentiment of one account over one period
+type SentimentTrendRow struct {
+	AccountID    string    `json:"account_id" bson:"account_id"` // Empty for customers without an account
+	Period       time.Time `json:"period" bson:"period"`
+	Messages     int       `json:"messages" bson:"messages"`
+	Tickets      int       `json:"tickets" bson:"tickets"`
+	AvgScore     float64   `json:"avg_score" bson:"avg_score"`
+	Negative     int       `json:"negative" bson:"negative"` // Negative and very negative messages
+	VeryNegative int       `json:"very_negative" bson:"very_negative"`
+	Positive     int       `json:"positive" bson:"positive"` // Positive and very positive messages
+}
+
+type SentimentTrends struct {
+	Start    time.Time           `json:"start"`
+	End      time.Time           `json:"end"`
+	Interval string              `json:"interval"`
+	Timezone string              `json:"timezone"`
+	Rows     []SentimentTrendRow `json:"rows"`
+}
+
+func (s *TicketServiceImpl) GetSentimentTrends(ctx context.Context, q SentimentTrendQuery) (*SentimentTrends, error) {
+	if q.End.IsZero() {
+		q.End = time.Now()
+	}
+	if q.Start.IsZero() {
+		q.Start = q.End.AddDate(0, -3, 0)
+	}
+	if !q.End.After(q.Start) {
+		return nil, fmt.Errorf("end must be after start")
+	}
+	if q.Interval == "" {
+		q.Interval = "week"
+	}
+	if q.Interval != "day" && q.Interval != "week" && q.Interval != "month" {
+		return nil, fmt.Errorf("interval must be day, week or month")
+	}
+	if q.Timezone == "" {
+		q.Timezone = "UTC"
+	}
+
+	rows, err := s.SentimentRepo.Trends(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		rows[i].AvgScore = math.Round(rows[i].AvgScore*1000) / 1000
+	}
+	return &SentimentTrends{Start: q.Start, End: q.End, Interval: q.Interval, Timezone: q.Timezone, Rows: rows}, nil
+}
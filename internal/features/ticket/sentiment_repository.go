@@ -0,0 +1,95 @@
+package ticket
+
+import (
+	"context"
+	"time"
+
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// TicketSentimentRepository stores scored customer messages
+type TicketSentimentRepository interface {
+	Create(ctx context.Context, entry *SentimentEntry) error
+	// Trends aggregates the entries per account and period
+	Trends(ctx context.Context, q SentimentTrendQuery) ([]SentimentTrendRow, error)
+}
+
+// TicketSentimentRepositoryImpl implements TicketSentimentRepository
+type TicketSentimentRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+// NewTicketSentimentRepository creates a new ticket sentiment repository
+func NewTicketSentimentRepository(db *database.MongodbDB) TicketSentimentRepository {
+	return &TicketSentimentRepositoryImpl{
+		collection: db.DB.Collection("ticket_sentiments"),
+	}
+}
+
+// Create stores a scored message
+func (r *TicketSentimentRepositoryImpl) Create(ctx context.Context, entry *SentimentEntry) error {
+	entry.ID = primitive.NewObjectID()
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	_, err := r.collection.InsertOne(ctx, entry)
+	return err
+}
+
+// Trends groups the entries by account and period, oldest period first
+func (r *TicketSentimentRepositoryImpl) Trends(ctx context.Context, q SentimentTrendQuery) ([]SentimentTrendRow, error) {
+	match := bson.M{"created_at": bson.M{"$gte": q.Start, "$lt": q.End}}
+	if q.AccountID != "" {
+		match["account_id"] = q.AccountID
+	}
+	trunc := bson.M{"date": "$created_at", "unit": q.Interval, "timezone": q.Timezone}
+	if q.Interval == "week" {
+		trunc["startOfWeek"] = "monday"
+	}
+	negative := bson.A{SentimentNegative, SentimentVeryNegative}
+	positive := bson.A{SentimentPositive, SentimentVeryPositive}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"account": bson.M{"$ifNull": bson.A{"$account_id", ""}},
+				"period":  bson.M{"$dateTrunc": trunc},
+			},
+			"messages":      bson.M{"$sum": 1},
+			"tickets":       bson.M{"$addToSet": "$ticket_id"},
+			"avg_score":     bson.M{"$avg": "$score"},
+			"negative":      sumIf(bson.M{"$in": bson.A{"$label", negative}}),
+			"very_negative": sumIf(bson.M{"$eq": bson.A{"$label", SentimentVeryNegative}}),
+			"positive":      sumIf(bson.M{"$in": bson.A{"$label", positive}}),
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":           0,
+			"account_id":    "$_id.account",
+			"period":        "$_id.period",
+			"messages":      1,
+			"tickets":       bson.M{"$size": "$tickets"},
+			"avg_score":     1,
+			"negative":      1,
+			"very_negative": 1,
+			"positive":      1,
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "period", Value: 1}, {Key: "account_id", Value: 1}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rows := []SentimentTrendRow{}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
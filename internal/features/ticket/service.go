@@ -4,11 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"sort"
+	"strings"
 	"time"
 
 	common_models "go-crm/internal/common/models"
 	"go-crm/internal/features/audit"
+	"go-crm/internal/features/comment"
+	"go-crm/internal/features/email"
+	"go-crm/internal/features/email_template"
+	"go-crm/internal/features/file"
 	"go-crm/internal/features/notification"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/role"
+	"go-crm/internal/features/user"
+	"go-crm/pkg/locale"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -33,18 +44,53 @@ type TicketService interface {
 	GetCustomerTickets(ctx context.Context, customerID primitive.ObjectID, page, limit int64) ([]Ticket, int64, error)
 
 	// Comments
-	AddComment(ctx context.Context, ticketID string, comment *TicketComment) error
-	ListComments(ctx context.Context, ticketID string) ([]TicketComment, error)
+	AddComment(ctx context.Context, ticketID string, req TicketCommentRequest, userID primitive.ObjectID) (*comment.Comment, error)
+	ListComments(ctx context.Context, ticketID string, userID primitive.ObjectID) ([]comment.Comment, error)
 
 	// SLA Management
 	CalculateDueDates(ctx context.Context, ticket *Ticket) error
 	CheckSLABreach(ctx context.Context, ticketID string) (bool, error)
 	GetOverdueSLATickets(ctx context.Context) ([]Ticket, error)
+	// RecalculateSLA recomputes the due dates of open tickets after ID from
+	// the policies now in place, a batch at a time in ID order
+	RecalculateSLA(ctx context.Context, after primitive.ObjectID, limit int64) (*SLARecalculation, error)
 
 	// Multi-Channel
 	CreateTicketFromEmail(ctx context.Context, subject, description, customerEmail, customerName string, metadata map[string]interface{}) error
+	// IngestEmail creates a ticket from a raw email, storing its attachments.
+	// Filtered emails return an *IgnoredEmailError.
+	IngestEmail(ctx context.Context, raw io.Reader, createdBy primitive.ObjectID) (*Ticket, error)
+	// Quarantine review for suspected spam
+	ListQuarantined(ctx context.Context, page, limit int64) ([]Ticket, int64, error)
+	ReleaseTicket(ctx context.Context, id string, releasedBy primitive.ObjectID) (*Ticket, error)
+	RejectTicket(ctx context.Context, id string, rejectedBy primitive.ObjectID, blockSender bool) error
 	CreateTicketFromChat(ctx context.Context, subject, description, customerEmail, customerName string, metadata map[string]interface{}) error
 	CreateTicketFromPortal(ctx context.Context, ticket *Ticket, createdBy primitive.ObjectID) error
+
+	// Lifecycle
+	GetSettings(ctx context.Context) (*TicketSettings, error)
+	UpdateSettings(ctx context.Context, settings *TicketSettings) (*TicketSettings, error)
+	// AutoCloseResolved is the system job closing stale resolved tickets
+	AutoCloseResolved(ctx context.Context) error
+
+	// Sentiment
+	GetSentimentTrends(ctx context.Context, q SentimentTrendQuery) (*SentimentTrends, error)
+
+	// Satisfaction
+	RateSatisfaction(ctx context.Context, id string, score int, comment string, ratedBy primitive.ObjectID) (*SatisfactionRating, error)
+
+	// Notifications
+	// NotifySLA is the system job sending SLA warning and breach notifications
+	NotifySLA(ctx context.Context) error
+	AddWatcher(ctx context.Context, id string, userID primitive.ObjectID) error
+	RemoveWatcher(ctx context.Context, id string, userID primitive.ObjectID) error
+}
+
+// StatusMachine checks status changes against the tickets blueprint and runs
+// its transition actions once a change is saved
+type StatusMachine interface {
+	record.TransitionGuard
+	record.ChangeListener
 }
 
 // TicketServiceImpl implements TicketService
@@ -52,8 +98,21 @@ type TicketServiceImpl struct {
 	TicketRepo          TicketRepository
 	SLAPolicyRepo       SLAPolicyRepository
 	CommentRepo         TicketCommentRepository
+	SettingsRepo        TicketSettingsRepository
+	CommentService      comment.CommentService
 	AuditService        audit.AuditService
 	NotificationService notification.NotificationService
+	StageGates          record.StageValidator
+	StatusMachine       StatusMachine
+	Timezones           record.TimezoneResolver
+	Files               file.FileService
+	SentimentRepo       TicketSentimentRepository
+	Contacts            record.RecordRepository
+	Automations         record.AutomationTrigger
+	Templates           email_template.EmailTemplateService
+	UserRepo            user.UserRepository
+	EmailService        email.EmailService
+	RoleService         role.RoleService
 }
 
 // NewTicketService creates a new ticket service
@@ -61,15 +120,52 @@ func NewTicketService(
 	ticketRepo TicketRepository,
 	slaPolicyRepo SLAPolicyRepository,
 	commentRepo TicketCommentRepository,
+	settingsRepo TicketSettingsRepository,
+	commentService comment.CommentService,
 	auditService audit.AuditService,
 	notificationService notification.NotificationService,
+	stageGates record.StageValidator,
+	statusMachine StatusMachine,
+	timezones record.TimezoneResolver,
+	files file.FileService,
+	sentimentRepo TicketSentimentRepository,
+	contacts record.RecordRepository,
+	automations record.AutomationTrigger,
+	templates email_template.EmailTemplateService,
+	userRepo user.UserRepository,
+	emailService email.EmailService,
+	roleService role.RoleService,
 ) TicketService {
+	// Ticket threads live in the generic comments store; tickets are not module
+	// records, so tell it how to resolve them
+	commentService.RegisterRecordResolver(CommentModuleName, func(ctx context.Context, recordID string, userID primitive.ObjectID) error {
+		objID, err := primitive.ObjectIDFromHex(recordID)
+		if err != nil {
+			return errors.New("invalid ticket ID")
+		}
+		_, err = ticketRepo.FindByID(ctx, objID)
+		return err
+	})
+
 	return &TicketServiceImpl{
 		TicketRepo:          ticketRepo,
 		SLAPolicyRepo:       slaPolicyRepo,
 		CommentRepo:         commentRepo,
+		SettingsRepo:        settingsRepo,
+		CommentService:      commentService,
 		AuditService:        auditService,
 		NotificationService: notificationService,
+		StageGates:          stageGates,
+		StatusMachine:       statusMachine,
+		Timezones:           timezones,
+		Files:               files,
+		SentimentRepo:       sentimentRepo,
+		Contacts:            contacts,
+		Automations:         automations,
+		Templates:           templates,
+		UserRepo:            userRepo,
+		EmailService:        emailService,
+		RoleService:         roleService,
 	}
 }
 
@@ -88,18 +184,22 @@ func (s *TicketServiceImpl) CreateTicket(ctx context.Context, t *Ticket, created
 	}
 
 	// Initialize status history
+	now := time.Now()
+	t.StatusChangedAt = &now
 	t.StatusHistory = []StatusHistoryEntry{
 		{
 			Status:    t.Status,
 			ChangedBy: createdBy,
-			ChangedAt: time.Now(),
+			ChangedAt: now,
 			Comment:   "Ticket created",
 		},
 	}
 
-	// Calculate SLA due dates
-	if err := s.CalculateDueDates(ctx, t); err != nil {
-		return err
+	// Calculate SLA due dates; quarantined tickets start the clock on release
+	if t.Status != TicketStatusQuarantined {
+		if err := s.CalculateDueDates(ctx, t); err != nil {
+			return err
+		}
 	}
 
 	// Create ticket
@@ -116,6 +216,12 @@ func (s *TicketServiceImpl) CreateTicket(ctx context.Context, t *Ticket, created
 	}
 	_ = s.AuditService.LogChange(ctx, common_models.AuditActionCreate, "tickets", t.ID.Hex(), changes)
 
+	// The description is the customer's first message
+	if t.Status != TicketStatusQuarantined {
+		s.recordSentiment(ctx, t, nil, t.Description)
+		s.notify(ctx, t, EventTicketCreated, createdBy)
+	}
+
 	return nil
 }
 
@@ -126,7 +232,12 @@ func (s *TicketServiceImpl) GetTicket(ctx context.Context, id string) (*Ticket,
 		return nil, errors.New("invalid ticket ID")
 	}
 
-	return s.TicketRepo.FindByID(ctx, objID)
+	t, err := s.TicketRepo.FindByID(ctx, objID)
+	if err != nil {
+		return nil, err
+	}
+	t.setDurations(time.Now())
+	return t, nil
 }
 
 // ListTickets retrieves tickets with filtering and pagination
@@ -136,6 +247,8 @@ func (s *TicketServiceImpl) ListTickets(ctx context.Context, filters map[string]
 
 	if status, ok := filters["status"].(string); ok && status != "" {
 		filter["status"] = status
+	} else {
+		filter["status"] = bson.M{"$ne": TicketStatusQuarantined}
 	}
 
 	if priority, ok := filters["priority"].(string); ok && priority != "" {
@@ -169,7 +282,20 @@ func (s *TicketServiceImpl) ListTickets(ctx context.Context, filters map[string]
 		}
 	}
 
-	return s.TicketRepo.FindAll(ctx, filter, page, limit, sortBy, sortOrder)
+	now := time.Now()
+	if err := addDurationFilters(filter, filters, now); err != nil {
+		return nil, 0, err
+	}
+	sortBy, sortOrder = durationSort(sortBy, sortOrder)
+
+	tickets, total, err := s.TicketRepo.FindAll(ctx, filter, page, limit, sortBy, sortOrder)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range tickets {
+		tickets[i].setDurations(now)
+	}
+	return tickets, total, nil
 }
 
 // UpdateTicket updates a ticket
@@ -178,6 +304,9 @@ func (s *TicketServiceImpl) UpdateTicket(ctx context.Context, id string, updates
 	if err != nil {
 		return errors.New("invalid ticket ID")
 	}
+	if err := validateTicketUpdates(updates); err != nil {
+		return err
+	}
 
 	// Get existing ticket for audit
 	oldTicket, err := s.TicketRepo.FindByID(ctx, objID)
@@ -207,6 +336,12 @@ func (s *TicketServiceImpl) UpdateTicket(ctx context.Context, id string, updates
 	if priority, ok := updates["priority"]; ok {
 		changes["priority"] = common_models.Change{Old: oldTicket.Priority, New: priority}
 	}
+	if code, ok := updates["resolution_code"]; ok {
+		changes["resolution_code"] = common_models.Change{Old: oldTicket.ResolutionCode, New: code}
+	}
+	if cause, ok := updates["root_cause"]; ok {
+		changes["root_cause"] = common_models.Change{Old: oldTicket.RootCause, New: cause}
+	}
 
 	if len(changes) > 0 {
 		_ = s.AuditService.LogChange(ctx, common_models.AuditActionUpdate, "tickets", objID.Hex(), changes)
@@ -256,17 +391,39 @@ func (s *TicketServiceImpl) UpdateStatus(ctx context.Context, id string, status
 		return err
 	}
 
-	// Validate status transition (basic validation)
-	validStatuses := map[TicketStatus]bool{
-		TicketStatusNew:      true,
-		TicketStatusOpen:     true,
-		TicketStatusPending:  true,
-		TicketStatusResolved: true,
-		TicketStatusClosed:   true,
+	if err := validateStatus(status); err != nil {
+		return err
+	}
+	previous, err := ticketFields(oldTicket)
+	if err != nil {
+		return err
+	}
+	if s.StatusMachine != nil {
+		input := map[string]interface{}{"status": string(status), "comment": comment}
+		if err := s.StatusMachine.CheckTransition(ctx, StageGateModuleName, previous, input, changedBy); err != nil {
+			return err
+		}
+	}
+
+	if status == TicketStatusClosed && oldTicket.Status != TicketStatusClosed {
+		settings, err := s.SettingsRepo.Get(ctx)
+		if err != nil {
+			return err
+		}
+		if missing := missingCloseFields(oldTicket, settings.RequiredOnClose); len(missing) > 0 {
+			return fmt.Errorf("cannot close ticket without %s", strings.Join(missing, ", "))
+		}
 	}
 
-	if !validStatuses[status] {
-		return errors.New("invalid status")
+	next := make(map[string]interface{}, len(previous))
+	for k, v := range previous {
+		next[k] = v
+	}
+	next["status"] = string(status)
+	if s.StageGates != nil && status != oldTicket.Status {
+		if err := s.StageGates.ValidateStage(ctx, StageGateModuleName, previous, next); err != nil {
+			return err
+		}
 	}
 
 	// Create history entry
@@ -288,6 +445,22 @@ func (s *TicketServiceImpl) UpdateStatus(ctx context.Context, id string, status
 	}
 	_ = s.AuditService.LogChange(ctx, common_models.AuditActionUpdate, "tickets", objID.Hex(), changes)
 
+	if status == TicketStatusResolved && oldTicket.Status != TicketStatusResolved {
+		resolved := *oldTicket
+		resolved.Status = status
+		s.notify(ctx, &resolved, EventTicketResolved, changedBy)
+	}
+
+	if s.StatusMachine != nil && status != oldTicket.Status {
+		go s.StatusMachine.RecordChanged(context.WithoutCancel(ctx), record.RecordChange{
+			ModuleName: StageGateModuleName,
+			RecordID:   objID.Hex(),
+			Record:     next,
+			Changes:    changes,
+			ActorID:    changedBy,
+		})
+	}
+
 	return nil
 }
 
@@ -332,8 +505,9 @@ func (s *TicketServiceImpl) AssignTicket(ctx context.Context, id string, assigne
 	}
 	_ = s.AuditService.LogChange(ctx, common_models.AuditActionUpdate, "tickets", objID.Hex(), changes)
 
-	// Send notification to assignee
-	_ = s.NotificationService.CreateNotification(ctx, assignedTo, "Ticket Assigned", fmt.Sprintf("You have been assigned ticket %s: %s", oldTicket.TicketNumber, oldTicket.Subject), notification.NotificationTypeTask, fmt.Sprintf("/dashboard/modules/tickets/%s", id))
+	assigned := *oldTicket
+	assigned.AssignedTo = &assignedTo
+	s.notify(ctx, &assigned, EventTicketAssigned, assignedBy)
 
 	return nil
 }
@@ -383,48 +557,96 @@ func (s *TicketServiceImpl) GetCustomerTickets(ctx context.Context, customerID p
 }
 
 // AddComment adds a comment to a ticket
-func (s *TicketServiceImpl) AddComment(ctx context.Context, ticketID string, tComment *TicketComment) error {
+func (s *TicketServiceImpl) AddComment(ctx context.Context, ticketID string, req TicketCommentRequest, userID primitive.ObjectID) (*comment.Comment, error) {
 	objID, err := primitive.ObjectIDFromHex(ticketID)
 	if err != nil {
-		return errors.New("invalid ticket ID")
+		return nil, errors.New("invalid ticket ID")
 	}
 
 	// Verify ticket exists
-	_, err = s.TicketRepo.FindByID(ctx, objID)
+	t, err := s.TicketRepo.FindByID(ctx, objID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	tComment.TicketID = objID
+	var templateID *primitive.ObjectID
+	if req.TemplateID != "" {
+		tpl, err := s.replyTemplate(ctx, req.TemplateID)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(req.Content) == "" {
+			fields, err := ticketFields(t)
+			if err != nil {
+				return nil, err
+			}
+			req.Content = s.Templates.RenderText(ctx, "", tpl.Body, fields)
+		}
+		templateID = &tpl.ID
+	}
 
-	if err := s.CommentRepo.Create(ctx, tComment); err != nil {
-		return err
+	c, err := s.CommentService.AddComment(ctx, CommentModuleName, ticketID, req.CreateCommentRequest, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	// Update first response time if this is the first response
-	t, _ := s.TicketRepo.FindByID(ctx, objID)
-	if t != nil && t.FirstResponseAt == nil && !tComment.IsInternal {
-		now := time.Now()
-		_ = s.TicketRepo.Update(ctx, objID, bson.M{"first_response_at": now})
+	if isCustomerReply(t, userID, c.IsInternal) {
+		s.customerReplied(ctx, t, userID)
+		s.recordSentiment(ctx, t, &c.ID, c.Content)
+		return c, nil
 	}
 
-	// Audit log
-	changes := map[string]common_models.Change{
-		"comment_added": {Old: nil, New: tComment.ID.Hex()},
+	if c.IsInternal {
+		s.notify(ctx, t, EventInternalNote, userID)
+		return c, nil
 	}
-	_ = s.AuditService.LogChange(ctx, common_models.AuditActionUpdate, "tickets", objID.Hex(), changes)
+	// Update first response time if this is the first response
+	now := time.Now()
+	if t.FirstResponseAt == nil {
+		_ = s.TicketRepo.Update(ctx, objID, bson.M{"first_response_at": now})
+	}
+	_ = s.TicketRepo.AddAgentResponse(ctx, objID, templateID)
 
-	return nil
+	return c, nil
 }
 
-// ListComments retrieves all comments for a ticket
-func (s *TicketServiceImpl) ListComments(ctx context.Context, ticketID string) ([]TicketComment, error) {
+// ListComments retrieves all comments for a ticket, including ones written
+// before ticket comments moved to the generic comments store
+func (s *TicketServiceImpl) ListComments(ctx context.Context, ticketID string, userID primitive.ObjectID) ([]comment.Comment, error) {
 	objID, err := primitive.ObjectIDFromHex(ticketID)
 	if err != nil {
 		return nil, errors.New("invalid ticket ID")
 	}
 
-	return s.CommentRepo.FindByTicketID(ctx, objID)
+	comments, err := s.CommentService.ListCommentsFlat(ctx, CommentModuleName, ticketID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	legacy, err := s.CommentRepo.FindByTicketID(ctx, objID)
+	if err != nil {
+		return nil, err
+	}
+	if len(legacy) == 0 {
+		return comments, nil
+	}
+	for _, lc := range legacy {
+		comments = append(comments, comment.Comment{
+			ID:          lc.ID,
+			ModuleName:  CommentModuleName,
+			RecordID:    ticketID,
+			Content:     lc.Content,
+			IsInternal:  lc.IsInternal,
+			Attachments: lc.Attachments,
+			CreatedBy:   lc.CreatedBy,
+			CreatedAt:   lc.CreatedAt,
+			UpdatedAt:   lc.UpdatedAt,
+		})
+	}
+	sort.SliceStable(comments, func(i, j int) bool {
+		return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+	})
+	return comments, nil
 }
 
 // CalculateDueDates calculates SLA due dates for a ticket
@@ -440,19 +662,44 @@ func (s *TicketServiceImpl) CalculateDueDates(ctx context.Context, t *Ticket) er
 		return nil
 	}
 
+	s.applyPolicy(ctx, t, policy, time.Now().UTC())
+	return nil
+}
+
+// applyPolicy sets the ticket's SLA due dates, counted from start
+func (s *TicketServiceImpl) applyPolicy(ctx context.Context, t *Ticket, policy *SLAPolicy, start time.Time) {
 	t.SLAPolicyID = &policy.ID
 
-	now := time.Now()
+	due := func(minutes int) time.Time {
+		if !policy.IsBusinessHoursOnly || policy.BusinessHours == nil {
+			return start.Add(time.Duration(minutes) * time.Minute)
+		}
+		return addBusinessMinutes(start, minutes, *policy.BusinessHours, s.businessLocation(ctx, policy.BusinessHours))
+	}
 
 	// Calculate response due date
-	responseDue := now.Add(time.Duration(policy.ResponseTime) * time.Minute)
+	responseDue := due(policy.ResponseTime)
 	t.ResponseDueDate = &responseDue
 
 	// Calculate resolution due date
-	resolutionDue := now.Add(time.Duration(policy.ResolutionTime) * time.Minute)
+	resolutionDue := due(policy.ResolutionTime)
 	t.DueDate = &resolutionDue
+}
 
-	return nil
+// businessLocation is the policy's timezone, else the tenant's
+func (s *TicketServiceImpl) businessLocation(ctx context.Context, hours *BusinessHours) *time.Location {
+	return windowLocation(ctx, hours, s.Timezones)
+}
+
+// windowLocation is the window's timezone, else the tenant's
+func windowLocation(ctx context.Context, hours *BusinessHours, timezones record.TimezoneResolver) *time.Location {
+	if hours.Timezone != "" {
+		return locale.LoadLocation(hours.Timezone)
+	}
+	if timezones != nil {
+		return timezones.Location(ctx, "")
+	}
+	return time.UTC
 }
 
 // CheckSLABreach checks if a ticket has breached its SLA
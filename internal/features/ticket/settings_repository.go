@@ -0,0 +1,53 @@
+package ticket
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const ticketSettingsID = "default"
+
+// TicketSettingsRepository stores the ticket lifecycle settings
+type TicketSettingsRepository interface {
+	Get(ctx context.Context) (*TicketSettings, error)
+	Save(ctx context.Context, settings *TicketSettings) error
+}
+
+// TicketSettingsRepositoryImpl implements TicketSettingsRepository
+type TicketSettingsRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+// NewTicketSettingsRepository creates a new ticket settings repository
+func NewTicketSettingsRepository(db *database.MongodbDB) TicketSettingsRepository {
+	return &TicketSettingsRepositoryImpl{
+		collection: db.DB.Collection("ticket_settings"),
+	}
+}
+
+// Get returns the saved settings, or the defaults when none are saved
+func (r *TicketSettingsRepositoryImpl) Get(ctx context.Context) (*TicketSettings, error) {
+	var settings TicketSettings
+	err := r.collection.FindOne(ctx, bson.M{"_id": ticketSettingsID}).Decode(&settings)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return DefaultTicketSettings(), nil
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// Save replaces the settings
+func (r *TicketSettingsRepositoryImpl) Save(ctx context.Context, settings *TicketSettings) error {
+	settings.UpdatedAt = time.Now()
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": ticketSettingsID}, bson.M{"$set": settings}, options.Update().SetUpsert(true))
+	return err
+}
@@ -1,9 +1,11 @@
 package ticket
 
 import (
+	"errors"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type SLAMetricsController struct {
@@ -88,3 +90,184 @@ func (ctrl *SLAMetricsController) GetTicketSLAStatus(c *fiber.Ctx) error {
 	status := ctrl.SLAService.CalculateSLAStatus(c.UserContext(), ticket, policy)
 	return c.JSON(status)
 }
+
+// GetReport godoc
+// @Summary SLA report
+// @Description First response and resolution times, breach percentage, reopen rate, customer reopens and auto-closed counts for tickets created in the range, grouped by priority, agent or team
+// @Tags sla-metrics
+// @Produce json
+// @Param group_by query string false "priority, agent or team; omit for one overall row"
+// @Param start_date query string false "Start date (YYYY-MM-DD, default 30 days ago)"
+// @Param end_date query string false "End date (YYYY-MM-DD, inclusive, default today)"
+// @Param interval query string false "Split into day, week or month periods"
+// @Param timezone query string false "IANA timezone for dates and periods (default UTC)"
+// @Param priority query string false "Only this priority"
+// @Param assigned_to query string false "Only this agent"
+// @Param assigned_group query string false "Only this team"
+// @Param channel query string false "Only this channel"
+// @Success 200 {object} SLAReport
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/sla-metrics/report [get]
+func (ctrl *SLAMetricsController) GetReport(c *fiber.Ctx) error {
+	q, err := reportQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	report, err := ctrl.SLAService.GetSLAReport(c.UserContext(), q)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(report)
+}
+
+// GetFirstContactResolution godoc
+// @Summary First-contact resolution report
+// @Description Share of resolved tickets closed after a single agent reply without reopening, resolution time and CSAT for tickets created in the range, grouped by priority, agent or team. Average ratings are split between tickets resolved on first contact and the others.
+// @Tags sla-metrics
+// @Produce json
+// @Param group_by query string false "priority, agent or team; omit for one overall row"
+// @Param start_date query string false "Start date (YYYY-MM-DD, default 30 days ago)"
+// @Param end_date query string false "End date (YYYY-MM-DD, inclusive, default today)"
+// @Param interval query string false "Split into day, week or month periods"
+// @Param timezone query string false "IANA timezone for dates and periods (default UTC)"
+// @Param priority query string false "Only this priority"
+// @Param assigned_to query string false "Only this agent"
+// @Param assigned_group query string false "Only this team"
+// @Param channel query string false "Only this channel"
+// @Success 200 {object} FirstContactReport
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/sla-metrics/first-contact-resolution [get]
+func (ctrl *SLAMetricsController) GetFirstContactResolution(c *fiber.Ctx) error {
+	q, err := reportQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	report, err := ctrl.SLAService.GetFirstContactReport(c.UserContext(), q)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(report)
+}
+
+// GetResponseTemplates godoc
+// @Summary Reply template effectiveness report
+// @Description Per ticket email template: replies sent with it, first-contact resolution, resolution time and CSAT of the tickets it was used on, and their difference from tickets answered without a template
+// @Tags sla-metrics
+// @Produce json
+// @Param start_date query string false "Start date (YYYY-MM-DD, default 30 days ago)"
+// @Param end_date query string false "End date (YYYY-MM-DD, inclusive, default today)"
+// @Param timezone query string false "IANA timezone for dates (default UTC)"
+// @Param priority query string false "Only this priority"
+// @Param assigned_to query string false "Only this agent"
+// @Param assigned_group query string false "Only this team"
+// @Param channel query string false "Only this channel"
+// @Success 200 {object} ResponseTemplateReport
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/sla-metrics/response-templates [get]
+func (ctrl *SLAMetricsController) GetResponseTemplates(c *fiber.Ctx) error {
+	q, err := reportQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	report, err := ctrl.SLAService.GetResponseTemplateReport(c.UserContext(), q)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(report)
+}
+
+// reportQuery reads the range, grouping and filters shared by the ticket reports
+func reportQuery(c *fiber.Ctx) (SLAReportQuery, error) {
+	q := SLAReportQuery{
+		GroupBy:       c.Query("group_by"),
+		Interval:      c.Query("interval"),
+		Timezone:      c.Query("timezone"),
+		Priority:      TicketPriority(c.Query("priority")),
+		AssignedGroup: c.Query("assigned_group"),
+		Channel:       TicketChannel(c.Query("channel")),
+	}
+
+	loc := time.UTC
+	if q.Timezone != "" {
+		l, err := time.LoadLocation(q.Timezone)
+		if err != nil {
+			return q, errors.New("Unknown timezone")
+		}
+		loc = l
+	}
+	if v := c.Query("start_date"); v != "" {
+		start, err := time.ParseInLocation("2006-01-02", v, loc)
+		if err != nil {
+			return q, errors.New("Invalid start_date (YYYY-MM-DD)")
+		}
+		q.Start = start
+	}
+	if v := c.Query("end_date"); v != "" {
+		end, err := time.ParseInLocation("2006-01-02", v, loc)
+		if err != nil {
+			return q, errors.New("Invalid end_date (YYYY-MM-DD)")
+		}
+		q.End = end.AddDate(0, 0, 1)
+	}
+	if v := c.Query("assigned_to"); v != "" {
+		oid, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return q, errors.New("Invalid assigned_to")
+		}
+		q.AssignedTo = &oid
+	}
+	return q, nil
+}
+
+// GetSentimentTrends godoc
+// @Summary Customer sentiment trends
+// @Description Average sentiment and negative message counts of customer ticket messages per account and period
+// @Tags sla-metrics
+// @Produce json
+// @Param start_date query string false "Start date (YYYY-MM-DD, default 3 months ago)"
+// @Param end_date query string false "End date (YYYY-MM-DD, inclusive, default today)"
+// @Param interval query string false "day, week or month (default week)"
+// @Param timezone query string false "IANA timezone for dates and periods (default UTC)"
+// @Param account_id query string false "Only this account"
+// @Success 200 {object} SentimentTrends
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/sla-metrics/sentiment [get]
+func (ctrl *SLAMetricsController) GetSentimentTrends(c *fiber.Ctx) error {
+	q := SentimentTrendQuery{
+		Interval:  c.Query("interval"),
+		Timezone:  c.Query("timezone"),
+		AccountID: c.Query("account_id"),
+	}
+
+	loc := time.UTC
+	if q.Timezone != "" {
+		l, err := time.LoadLocation(q.Timezone)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown timezone"})
+		}
+		loc = l
+	}
+	if v := c.Query("start_date"); v != "" {
+		start, err := time.ParseInLocation("2006-01-02", v, loc)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid start_date (YYYY-MM-DD)"})
+		}
+		q.Start = start
+	}
+	if v := c.Query("end_date"); v != "" {
+		end, err := time.ParseInLocation("2006-01-02", v, loc)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid end_date (YYYY-MM-DD)"})
+		}
+		q.End = end.AddDate(0, 0, 1)
+	}
+
+	trends, err := ctrl.TicketService.GetSentimentTrends(c.UserContext(), q)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(trends)
+}
@@ -0,0 +1,79 @@
+package ticket
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SLARecalculation reports one batch of RecalculateSLA
+type SLARecalculation struct {
+	Processed int                `json:"processed"`
+	Updated   int                `json:"updated"`
+	Last      primitive.ObjectID `json:"last"` // Where the next batch starts
+	Done      bool               `json:"done"`
+}
+
+// RecalculateSLA counts due dates from each ticket's creation, so tickets
+// keep the time they already had under the policy. Tickets whose priority
+// no longer has a policy lose their due dates.
+func (s *TicketServiceImpl) RecalculateSLA(ctx context.Context, after primitive.ObjectID, limit int64) (*SLARecalculation, error) {
+	filter := bson.M{"status": bson.M{"$nin": []TicketStatus{TicketStatusResolved, TicketStatusClosed, TicketStatusQuarantined}}}
+	if !after.IsZero() {
+		filter["_id"] = bson.M{"$gt": after}
+	}
+	tickets, _, err := s.TicketRepo.FindAll(ctx, filter, 1, limit, "_id", "asc")
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &SLARecalculation{Last: after, Done: int64(len(tickets)) < limit}
+	policies := map[TicketPriority]*SLAPolicy{}
+	for i := range tickets {
+		t := &tickets[i]
+		batch.Processed++
+		batch.Last = t.ID
+
+		policy, cached := policies[t.Priority]
+		if !cached {
+			if policy, err = s.SLAPolicyRepo.FindByPriority(ctx, t.Priority); err != nil {
+				return nil, err
+			}
+			policies[t.Priority] = policy
+		}
+
+		recalculated := Ticket{}
+		if policy != nil {
+			s.applyPolicy(ctx, &recalculated, policy, t.CreatedAt.UTC())
+		}
+		if sameID(t.SLAPolicyID, recalculated.SLAPolicyID) && sameTime(t.ResponseDueDate, recalculated.ResponseDueDate) && sameTime(t.DueDate, recalculated.DueDate) {
+			continue
+		}
+		if err := s.TicketRepo.Update(ctx, t.ID, bson.M{
+			"sla_policy_id":     recalculated.SLAPolicyID,
+			"response_due_date": recalculated.ResponseDueDate,
+			"due_date":          recalculated.DueDate,
+		}); err != nil {
+			return nil, err
+		}
+		batch.Updated++
+	}
+	return batch, nil
+}
+
+func sameID(a, b *primitive.ObjectID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// sameTime compares at the millisecond precision times are stored with
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Truncate(time.Millisecond).Equal(b.Truncate(time.Millisecond))
+}
@@ -0,0 +1,299 @@
+package ticket
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SLA report dimensions
+const (
+	SLAReportByPriority = "priority"
+	SLAReportByAgent    = "agent"
+	SLAReportByTeam     = "team"
+)
+
+// SLAReportQuery selects the tickets created in [Start, End) and how they are
+// grouped. Interval additionally splits each group into day, week or month
+// periods in Timezone.
+type SLAReportQuery struct {
+	Start    time.Time
+	End      time.Time
+	GroupBy  string // priority, agent, team; empty for one overall row
+	Interval string // day, week, month; empty for the whole range
+	Timezone string
+
+	Priority      TicketPriority
+	AssignedTo    *primitive.ObjectID
+	AssignedGroup string
+	Channel       TicketChannel
+}
+
+// SLAReportRow holds the metrics of one group and period. Times are in
+// minutes and averages are nil when no ticket qualifies.
+type SLAReportRow struct {
+	Key    string     `json:"key"` // priority, agent ID or team; empty when unassigned or ungrouped
+	Label  string     `json:"label,omitempty"`
+	Period *time.Time `json:"period,omitempty"`
+
+	Tickets                 int      `json:"tickets"`
+	Responded               int      `json:"responded"`
+	Resolved                int      `json:"resolved"`
+	AvgFirstResponseMinutes *float64 `json:"avg_first_response_minutes"`
+	AvgResolutionMinutes    *float64 `json:"avg_resolution_minutes"`
+
+	WithSLA            int     `json:"with_sla"`
+	ResponseBreached   int     `json:"response_breached"`
+	ResolutionBreached int     `json:"resolution_breached"`
+	Breached           int     `json:"breached"`
+	BreachPercentage   float64 `json:"breach_percentage"` // of tickets with an SLA
+
+	Reopened        int     `json:"reopened"`
+	Reopens         int     `json:"reopens"`
+	CustomerReopens int     `json:"customer_reopens"` // reopens caused by customer replies
+	ReopenRate      float64 `json:"reopen_rate"`      // of resolved tickets
+	AutoClosed      int     `json:"auto_closed"`
+}
+
+type SLAReport struct {
+	Start    time.Time      `json:"start"`
+	End      time.Time      `json:"end"`
+	GroupBy  string         `json:"group_by,omitempty"`
+	Interval string         `json:"interval,omitempty"`
+	Timezone string         `json:"timezone"`
+	Rows     []SLAReportRow `json:"rows"`
+	Totals   SLAReportRow   `json:"totals"`
+}
+
+var terminalStatuses = bson.A{TicketStatusResolved, TicketStatusClosed}
+
+// slaReportBucket is one $group result of SLAReport
+type slaReportBucket struct {
+	ID struct {
+		Key    interface{} `bson:"key"`
+		Period *time.Time  `bson:"period"`
+	} `bson:"_id"`
+	Tickets                 int           `bson:"tickets"`
+	Responded               int           `bson:"responded"`
+	Resolved                int           `bson:"resolved"`
+	AvgFirstResponseMinutes *float64      `bson:"avg_first_response_minutes"`
+	AvgResolutionMinutes    *float64      `bson:"avg_resolution_minutes"`
+	WithSLA                 int           `bson:"with_sla"`
+	ResponseBreached        int           `bson:"response_breached"`
+	ResolutionBreached      int           `bson:"resolution_breached"`
+	Breached                int           `bson:"breached"`
+	Reopened                int           `bson:"reopened"`
+	Reopens                 int           `bson:"reopens"`
+	CustomerReopens         int           `bson:"customer_reopens"`
+	AutoClosed              int           `bson:"auto_closed"`
+	Agent                   []reportAgent `bson:"agent"`
+}
+
+// present is true for set, non-null fields
+func present(field string) bson.M {
+	return bson.M{"$ifNull": bson.A{field, false}}
+}
+
+func minutesBetween(from, to string) bson.M {
+	return bson.M{"$cond": bson.A{
+		present(to),
+		bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{to, from}}, 60000}},
+		nil,
+	}}
+}
+
+func sumIf(cond interface{}) bson.M {
+	return bson.M{"$sum": bson.M{"$cond": bson.A{cond, 1, 0}}}
+}
+
+// reopenMoves counts the moves out of resolved or closed in the status history
+func reopenMoves() bson.M {
+	return bson.M{"$reduce": bson.M{
+		"input":        bson.M{"$ifNull": bson.A{"$status_history", bson.A{}}},
+		"initialValue": bson.M{"prev": "", "n": 0},
+		"in": bson.M{
+			"prev": "$$this.status",
+			"n": bson.M{"$add": bson.A{"$$value.n", bson.M{"$cond": bson.A{
+				bson.M{"$and": bson.A{
+					bson.M{"$in": bson.A{"$$value.prev", terminalStatuses}},
+					bson.M{"$not": bson.A{bson.M{"$in": bson.A{"$$this.status", terminalStatuses}}}},
+				}},
+				1, 0,
+			}}}},
+		},
+	}}
+}
+
+// reportMatch selects the tickets of a report query
+func reportMatch(q SLAReportQuery) bson.M {
+	match := bson.M{"created_at": bson.M{"$gte": q.Start, "$lt": q.End}}
+	if q.Priority != "" {
+		match["priority"] = q.Priority
+	}
+	if q.AssignedTo != nil {
+		match["assigned_to"] = *q.AssignedTo
+	}
+	if q.AssignedGroup != "" {
+		match["assigned_group"] = q.AssignedGroup
+	}
+	if q.Channel != "" {
+		match["channel"] = q.Channel
+	}
+	return match
+}
+
+// reportGroup returns the group key and period expressions of a report query
+func reportGroup(q SLAReportQuery) (key, period interface{}) {
+	key = bson.M{"$literal": nil}
+	switch q.GroupBy {
+	case SLAReportByPriority:
+		key = "$priority"
+	case SLAReportByAgent:
+		key = "$assigned_to"
+	case SLAReportByTeam:
+		key = "$assigned_group"
+	}
+	period = bson.M{"$literal": nil}
+	if q.Interval != "" {
+		trunc := bson.M{"date": "$created_at", "unit": q.Interval, "timezone": q.Timezone}
+		if q.Interval == "week" {
+			trunc["startOfWeek"] = "monday"
+		}
+		period = bson.M{"$dateTrunc": trunc}
+	}
+	return key, period
+}
+
+// agentLookup joins the users an agent-grouped report's keys refer to
+var agentLookup = bson.D{{Key: "$lookup", Value: bson.M{
+	"from":         "users",
+	"localField":   "_id.key",
+	"foreignField": "_id",
+	"as":           "agent",
+}}}
+
+type reportAgent struct {
+	Username  string `bson:"username"`
+	FirstName string `bson:"first_name"`
+	LastName  string `bson:"last_name"`
+}
+
+// reportKey and reportLabel format a group key and its joined agent
+func reportKey(key interface{}) string {
+	switch k := key.(type) {
+	case primitive.ObjectID:
+		return k.Hex()
+	case string:
+		return k
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(k)
+	}
+}
+
+func reportLabel(agent []reportAgent) string {
+	if len(agent) == 0 {
+		return ""
+	}
+	a := agent[0]
+	if label := strings.TrimSpace(a.FirstName + " " + a.LastName); label != "" {
+		return label
+	}
+	return a.Username
+}
+
+// SLAReport aggregates first response, resolution, breach and reopen metrics.
+// Open tickets count as breached once their due date is before now.
+func (r *TicketRepositoryImpl) SLAReport(ctx context.Context, q SLAReportQuery, now time.Time) ([]SLAReportRow, error) {
+	key, period := reportGroup(q)
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: reportMatch(q)}},
+		{{Key: "$addFields", Value: bson.M{
+			"_resolved_at": bson.M{"$ifNull": bson.A{"$resolved_at", "$closed_at"}},
+			// Reopens are moves out of resolved or closed in the status history
+			"_reopens": reopenMoves(),
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"key":                key,
+			"period":             period,
+			"response_minutes":   minutesBetween("$created_at", "$first_response_at"),
+			"resolution_minutes": minutesBetween("$created_at", "$_resolved_at"),
+			"resolved":           present("$_resolved_at"),
+			"has_sla":            bson.M{"$or": bson.A{present("$response_due_date"), present("$due_date")}},
+			"response_breached": bson.M{"$cond": bson.A{
+				present("$response_due_date"),
+				bson.M{"$gt": bson.A{bson.M{"$ifNull": bson.A{"$first_response_at", now}}, "$response_due_date"}},
+				false,
+			}},
+			"resolution_breached": bson.M{"$cond": bson.A{
+				present("$due_date"),
+				bson.M{"$gt": bson.A{bson.M{"$ifNull": bson.A{"$_resolved_at", now}}, "$due_date"}},
+				false,
+			}},
+			"reopens":          "$_reopens.n",
+			"customer_reopens": bson.M{"$ifNull": bson.A{"$reopen_count", 0}},
+			"auto_closed":      present("$auto_closed"),
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":                        bson.M{"key": "$key", "period": "$period"},
+			"tickets":                    bson.M{"$sum": 1},
+			"responded":                  sumIf(bson.M{"$ne": bson.A{"$response_minutes", nil}}),
+			"resolved":                   sumIf("$resolved"),
+			"avg_first_response_minutes": bson.M{"$avg": "$response_minutes"},
+			"avg_resolution_minutes":     bson.M{"$avg": "$resolution_minutes"},
+			"with_sla":                   sumIf("$has_sla"),
+			"response_breached":          sumIf("$response_breached"),
+			"resolution_breached":        sumIf("$resolution_breached"),
+			"breached":                   sumIf(bson.M{"$or": bson.A{"$response_breached", "$resolution_breached"}}),
+			"reopened":                   sumIf(bson.M{"$gt": bson.A{"$reopens", 0}}),
+			"reopens":                    bson.M{"$sum": "$reopens"},
+			"customer_reopens":           bson.M{"$sum": "$customer_reopens"},
+			"auto_closed":                sumIf("$auto_closed"),
+		}}},
+	}
+	if q.GroupBy == SLAReportByAgent {
+		pipeline = append(pipeline, agentLookup)
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$sort", Value: bson.D{{Key: "_id.key", Value: 1}, {Key: "_id.period", Value: 1}}}})
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var buckets []slaReportBucket
+	if err := cursor.All(ctx, &buckets); err != nil {
+		return nil, err
+	}
+
+	rows := make([]SLAReportRow, 0, len(buckets))
+	for _, b := range buckets {
+		row := SLAReportRow{
+			Period:                  b.ID.Period,
+			Tickets:                 b.Tickets,
+			Responded:               b.Responded,
+			Resolved:                b.Resolved,
+			AvgFirstResponseMinutes: b.AvgFirstResponseMinutes,
+			AvgResolutionMinutes:    b.AvgResolutionMinutes,
+			WithSLA:                 b.WithSLA,
+			ResponseBreached:        b.ResponseBreached,
+			ResolutionBreached:      b.ResolutionBreached,
+			Breached:                b.Breached,
+			Reopened:                b.Reopened,
+			Reopens:                 b.Reopens,
+			CustomerReopens:         b.CustomerReopens,
+			AutoClosed:              b.AutoClosed,
+			Key:                     reportKey(b.ID.Key),
+			Label:                   reportLabel(b.Agent),
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
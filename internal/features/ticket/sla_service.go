@@ -2,7 +2,10 @@ package ticket
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -62,6 +65,13 @@ type SLAService interface {
 	GetSLAMetrics(ctx context.Context, startDate, endDate time.Time) (*SLAMetrics, error)
 	GetSLAViolations(ctx context.Context) ([]SLAViolation, error)
 	GetSLATrends(ctx context.Context, days int) ([]SLATrend, error)
+	GetSLAReport(ctx context.Context, q SLAReportQuery) (*SLAReport, error)
+	// GetFirstContactReport computes first-contact resolution and CSAT per
+	// priority, agent or team
+	GetFirstContactReport(ctx context.Context, q SLAReportQuery) (*FirstContactReport, error)
+	// GetResponseTemplateReport compares the outcomes of tickets answered
+	// with each reply template against tickets answered without one
+	GetResponseTemplateReport(ctx context.Context, q SLAReportQuery) (*ResponseTemplateReport, error)
 }
 
 // SLAServiceImpl implements SLAService
@@ -80,6 +90,11 @@ func NewSLAService(slaPolicyRepo SLAPolicyRepository, ticketRepo TicketRepositor
 
 // CreatePolicy creates a new SLA policy
 func (s *SLAServiceImpl) CreatePolicy(ctx context.Context, policy *SLAPolicy) error {
+	if policy.BusinessHours != nil {
+		if err := policy.BusinessHours.Validate(); err != nil {
+			return err
+		}
+	}
 	return s.SLAPolicyRepo.Create(ctx, policy)
 }
 
@@ -109,6 +124,20 @@ func (s *SLAServiceImpl) UpdatePolicy(ctx context.Context, id string, updates ma
 	for k, v := range updates {
 		bsonUpdates[k] = v
 	}
+	if raw, ok := updates["business_hours"]; ok && raw != nil {
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return err
+		}
+		var hours BusinessHours
+		if err := json.Unmarshal(data, &hours); err != nil {
+			return errors.New("invalid business hours")
+		}
+		if err := hours.Validate(); err != nil {
+			return err
+		}
+		bsonUpdates["business_hours"] = hours
+	}
 
 	return s.SLAPolicyRepo.Update(ctx, objID, bsonUpdates)
 }
@@ -397,3 +426,113 @@ func (s *SLAServiceImpl) GetSLATrends(ctx context.Context, days int) ([]SLATrend
 
 	return trends, nil
 }
+
+// maxSLAReportDays bounds a report range
+const maxSLAReportDays = 366
+
+// GetSLAReport computes SLA metrics per priority, agent or team. The
+// aggregation runs in MongoDB; only percentages and totals are derived here.
+func (s *SLAServiceImpl) GetSLAReport(ctx context.Context, q SLAReportQuery) (*SLAReport, error) {
+	if err := normalizeReportQuery(&q); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.TicketRepo.SLAReport(ctx, q, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SLAReport{
+		Start:    q.Start,
+		End:      q.End,
+		GroupBy:  q.GroupBy,
+		Interval: q.Interval,
+		Timezone: q.Timezone,
+		Rows:     rows,
+	}
+	var responseTotal, resolutionTotal float64
+	for i := range report.Rows {
+		row := &report.Rows[i]
+		t := &report.Totals
+		t.Tickets += row.Tickets
+		t.Responded += row.Responded
+		t.Resolved += row.Resolved
+		t.WithSLA += row.WithSLA
+		t.ResponseBreached += row.ResponseBreached
+		t.ResolutionBreached += row.ResolutionBreached
+		t.Breached += row.Breached
+		t.Reopened += row.Reopened
+		t.Reopens += row.Reopens
+		t.CustomerReopens += row.CustomerReopens
+		t.AutoClosed += row.AutoClosed
+		if row.AvgFirstResponseMinutes != nil {
+			responseTotal += *row.AvgFirstResponseMinutes * float64(row.Responded)
+		}
+		if row.AvgResolutionMinutes != nil {
+			resolutionTotal += *row.AvgResolutionMinutes * float64(row.Resolved)
+		}
+		finishSLAReportRow(row)
+	}
+	if report.Totals.Responded > 0 {
+		avg := responseTotal / float64(report.Totals.Responded)
+		report.Totals.AvgFirstResponseMinutes = &avg
+	}
+	if report.Totals.Resolved > 0 {
+		avg := resolutionTotal / float64(report.Totals.Resolved)
+		report.Totals.AvgResolutionMinutes = &avg
+	}
+	finishSLAReportRow(&report.Totals)
+	return report, nil
+}
+
+func finishSLAReportRow(row *SLAReportRow) {
+	round := func(v float64) float64 { return math.Round(v*10) / 10 }
+	if row.AvgFirstResponseMinutes != nil {
+		v := round(*row.AvgFirstResponseMinutes)
+		row.AvgFirstResponseMinutes = &v
+	}
+	if row.AvgResolutionMinutes != nil {
+		v := round(*row.AvgResolutionMinutes)
+		row.AvgResolutionMinutes = &v
+	}
+	if row.WithSLA > 0 {
+		row.BreachPercentage = round(float64(row.Breached) / float64(row.WithSLA) * 100)
+	}
+	if row.Resolved > 0 {
+		row.ReopenRate = round(float64(row.Reopened) / float64(row.Resolved) * 100)
+	}
+}
+
+// normalizeReportQuery checks a report query and fills in its defaults: UTC
+// and the last 30 days
+func normalizeReportQuery(q *SLAReportQuery) error {
+	switch q.GroupBy {
+	case "", SLAReportByPriority, SLAReportByAgent, SLAReportByTeam:
+	default:
+		return errors.New("group_by must be priority, agent or team")
+	}
+	switch q.Interval {
+	case "", "day", "week", "month":
+	default:
+		return errors.New("interval must be day, week or month")
+	}
+	if q.Timezone == "" {
+		q.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(q.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone '%s'", q.Timezone)
+	}
+	if q.End.IsZero() {
+		q.End = time.Now()
+	}
+	if q.Start.IsZero() {
+		q.Start = q.End.AddDate(0, 0, -30)
+	}
+	if !q.End.After(q.Start) {
+		return errors.New("end must be after start")
+	}
+	if q.End.Sub(q.Start) > maxSLAReportDays*24*time.Hour {
+		return fmt.Errorf("range cannot exceed %d days", maxSLAReportDays)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package ticket
+
+import (
+	"fmt"
+	"net/mail"
+
+	"go-crm/internal/common/validation"
+)
+
+var (
+	validPriorities = map[TicketPriority]bool{
+		TicketPriorityLow: true, TicketPriorityMedium: true, TicketPriorityHigh: true, TicketPriorityUrgent: true,
+	}
+	validStatuses = map[TicketStatus]bool{
+		TicketStatusNew: true, TicketStatusOpen: true, TicketStatusPending: true,
+		TicketStatusResolved: true, TicketStatusClosed: true, TicketStatusQuarantined: true,
+	}
+	validChannels = map[TicketChannel]bool{
+		TicketChannelEmail: true, TicketChannelChat: true, TicketChannelPortal: true, TicketChannelPhone: true,
+	}
+)
+
+// protectedTicketFields are maintained by the service and cannot be set
+// through an update
+var protectedTicketFields = map[string]bool{
+	"_id": true, "id": true, "tenant_id": true, "ticket_number": true,
+	"status": true, "status_changed_at": true, "status_history": true, "escalation_history": true,
+	"created_at": true, "updated_at": true, "sentiment": true,
+	"agent_responses": true, "response_template_ids": true, "satisfaction": true,
+	"watchers": true, "sla_warning_sent_for": true, "sla_breach_sent_for": true,
+}
+
+// validateTicket checks a ticket submitted through the API
+func validateTicket(t *Ticket) error {
+	var errs validation.Errors
+	if t.Subject == "" {
+		errs.Add("subject", validation.CodeRequired, "subject is required")
+	}
+	if t.Priority != "" && !validPriorities[t.Priority] {
+		errs.Add("priority", validation.CodeNotAllowed, fmt.Sprintf("unknown priority '%s'", t.Priority))
+	}
+	if t.Status != "" && !validStatuses[t.Status] {
+		errs.Add("status", validation.CodeNotAllowed, fmt.Sprintf("unknown status '%s'", t.Status))
+	}
+	if t.Channel != "" && !validChannels[t.Channel] {
+		errs.Add("channel", validation.CodeNotAllowed, fmt.Sprintf("unknown channel '%s'", t.Channel))
+	}
+	if t.CustomerEmail != "" {
+		if _, err := mail.ParseAddress(t.CustomerEmail); err != nil {
+			errs.Add("customer_email", validation.CodeInvalid, "invalid email format")
+		}
+	}
+	return errs.Err()
+}
+
+// validateTicketUpdates checks the fields of a partial update
+func validateTicketUpdates(updates map[string]interface{}) error {
+	var errs validation.Errors
+	for field, value := range updates {
+		if protectedTicketFields[field] {
+			message := fmt.Sprintf("'%s' cannot be updated", field)
+			if field == "status" {
+				message = "use the status endpoint to change the status"
+			}
+			errs.Add(field, validation.CodeReadOnly, message)
+			continue
+		}
+		switch field {
+		case "subject":
+			if s, ok := value.(string); !ok || s == "" {
+				errs.Add(field, validation.CodeRequired, "subject is required")
+			}
+		case "priority":
+			if s, ok := value.(string); !ok || !validPriorities[TicketPriority(s)] {
+				errs.Add(field, validation.CodeNotAllowed, fmt.Sprintf("unknown priority '%v'", value))
+			}
+		case "customer_email":
+			s, ok := value.(string)
+			if !ok {
+				errs.Add(field, validation.CodeInvalid, "invalid email format")
+			} else if s != "" {
+				if _, err := mail.ParseAddress(s); err != nil {
+					errs.Add(field, validation.CodeInvalid, "invalid email format")
+				}
+			}
+		}
+	}
+	return errs.Err()
+}
+
+// validateStatus checks a requested status change
+func validateStatus(status TicketStatus) error {
+	if status == "" {
+		return validation.New("status", validation.CodeRequired, "status is required")
+	}
+	if !validStatuses[status] {
+		return validation.New("status", validation.CodeNotAllowed, fmt.Sprintf("unknown status '%s'", status))
+	}
+	return nil
+}
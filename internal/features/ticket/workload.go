@@ -0,0 +1,161 @@
+package ticket
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Workload dimensions
+const (
+	WorkloadByAgent = "agent"
+	WorkloadByTeam  = "team"
+)
+
+// WorkloadQuery selects the open tickets counted per agent or team. Tickets
+// resolved or closed since DayStart count towards ResolvedToday.
+type WorkloadQuery struct {
+	GroupBy       string // agent or team
+	AssignedGroup string
+	DayStart      time.Time
+}
+
+// WorkloadRow is the open ticket load of one agent or team. Ages are in
+// minutes and AvgAgeMinutes is nil without open tickets.
+type WorkloadRow struct {
+	Key           string                 // agent ID or team; empty for tickets without a team
+	Open          int                    // open tickets (not resolved or closed)
+	ByPriority    map[TicketPriority]int // open tickets per priority
+	ByStatus      map[TicketStatus]int   // open tickets per status
+	AvgAgeMinutes *float64
+	ResolvedToday int
+	Unassigned    int                  // open tickets without an agent; teams only
+	Agents        []primitive.ObjectID // agents holding open tickets; teams only
+}
+
+// workloadBucket is one result of the second $group in Workload
+type workloadBucket struct {
+	Key           interface{}     `bson:"_id"`
+	Open          int             `bson:"open"`
+	AgeSum        float64         `bson:"age_sum"`
+	ResolvedToday int             `bson:"resolved_today"`
+	Unassigned    int             `bson:"unassigned"`
+	Agents        [][]interface{} `bson:"agents"`
+	Breakdown     []struct {
+		Status   TicketStatus   `bson:"status"`
+		Priority TicketPriority `bson:"priority"`
+		Open     int            `bson:"open"`
+	} `bson:"breakdown"`
+}
+
+// Workload counts open tickets by priority and status per agent or team.
+// Unassigned tickets are left out of agent rows.
+func (r *TicketRepositoryImpl) Workload(ctx context.Context, q WorkloadQuery, now time.Time) ([]WorkloadRow, error) {
+	match := bson.M{"$or": bson.A{
+		bson.M{"status": bson.M{"$nin": terminalStatuses}},
+		bson.M{"resolved_at": bson.M{"$gte": q.DayStart}},
+		bson.M{"closed_at": bson.M{"$gte": q.DayStart}},
+	}}
+	if q.AssignedGroup != "" {
+		match["assigned_group"] = q.AssignedGroup
+	}
+	key := "$assigned_group"
+	if q.GroupBy == WorkloadByAgent {
+		key = "$assigned_to"
+		match["assigned_to"] = bson.M{"$ne": nil}
+	}
+
+	open := bson.M{"$not": bson.A{bson.M{"$in": bson.A{"$status", terminalStatuses}}}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$project", Value: bson.M{
+			"key":         bson.M{"$ifNull": bson.A{key, nil}},
+			"assigned_to": bson.M{"$ifNull": bson.A{"$assigned_to", nil}},
+			"status":      "$status",
+			"priority":    "$priority",
+			"open":        open,
+			"age_minutes": bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{now, "$created_at"}}, 60000}},
+			"resolved_today": bson.M{"$and": bson.A{
+				bson.M{"$in": bson.A{"$status", terminalStatuses}},
+				bson.M{"$gte": bson.A{bson.M{"$ifNull": bson.A{"$resolved_at", "$closed_at"}}, q.DayStart}},
+			}},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":            bson.M{"key": "$key", "status": "$status", "priority": "$priority"},
+			"open":           sumIf("$open"),
+			"age_sum":        bson.M{"$sum": bson.M{"$cond": bson.A{"$open", "$age_minutes", 0}}},
+			"resolved_today": sumIf("$resolved_today"),
+			"unassigned":     sumIf(bson.M{"$and": bson.A{"$open", bson.M{"$eq": bson.A{"$assigned_to", nil}}}}),
+			"agents":         bson.M{"$addToSet": bson.M{"$cond": bson.A{"$open", "$assigned_to", nil}}},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":            "$_id.key",
+			"open":           bson.M{"$sum": "$open"},
+			"age_sum":        bson.M{"$sum": "$age_sum"},
+			"resolved_today": bson.M{"$sum": "$resolved_today"},
+			"unassigned":     bson.M{"$sum": "$unassigned"},
+			"agents":         bson.M{"$push": "$agents"},
+			"breakdown":      bson.M{"$push": bson.M{"status": "$_id.status", "priority": "$_id.priority", "open": "$open"}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var buckets []workloadBucket
+	if err := cursor.All(ctx, &buckets); err != nil {
+		return nil, err
+	}
+
+	rows := make([]WorkloadRow, 0, len(buckets))
+	for _, b := range buckets {
+		row := WorkloadRow{
+			Open:          b.Open,
+			ByPriority:    map[TicketPriority]int{},
+			ByStatus:      map[TicketStatus]int{},
+			ResolvedToday: b.ResolvedToday,
+		}
+		switch k := b.Key.(type) {
+		case primitive.ObjectID:
+			row.Key = k.Hex()
+		case string:
+			row.Key = k
+		case nil:
+		default:
+			row.Key = fmt.Sprint(k)
+		}
+		for _, p := range b.Breakdown {
+			if p.Open == 0 {
+				continue
+			}
+			row.ByPriority[p.Priority] += p.Open
+			row.ByStatus[p.Status] += p.Open
+		}
+		if b.Open > 0 {
+			avg := b.AgeSum / float64(b.Open)
+			row.AvgAgeMinutes = &avg
+		}
+		if q.GroupBy == WorkloadByTeam {
+			row.Unassigned = b.Unassigned
+			seen := map[primitive.ObjectID]bool{}
+			for _, set := range b.Agents {
+				for _, v := range set {
+					if oid, ok := v.(primitive.ObjectID); ok && !seen[oid] {
+						seen[oid] = true
+						row.Agents = append(row.Agents, oid)
+					}
+				}
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
@@ -0,0 +1,143 @@
+package ticket
+
+import (
+	"errors"
+
+	"go-crm/internal/common/session"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WorkloadController struct {
+	WorkloadService WorkloadService
+}
+
+func NewWorkloadController(workloadService WorkloadService) *WorkloadController {
+	return &WorkloadController{WorkloadService: workloadService}
+}
+
+// GetAgentWorkload godoc
+// @Summary Agent workload
+// @Description Open tickets per agent by priority and status, average open ticket age, tickets resolved today and current availability
+// @Tags agent-workload
+// @Produce json
+// @Param team query string false "Only tickets of this assigned_group and agents taking them"
+// @Param timezone query string false "IANA timezone where today starts (default UTC)"
+// @Success 200 {array} AgentWorkload
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/agent-workload/agents [get]
+func (ctrl *WorkloadController) GetAgentWorkload(c *fiber.Ctx) error {
+	agents, err := ctrl.WorkloadService.GetAgentWorkload(c.UserContext(), c.Query("team"), c.Query("timezone"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"data": agents})
+}
+
+// GetTeamWorkload godoc
+// @Summary Team workload
+// @Description Open and unassigned tickets per assigned_group with agent availability
+// @Tags agent-workload
+// @Produce json
+// @Param timezone query string false "IANA timezone where today starts (default UTC)"
+// @Success 200 {array} TeamWorkload
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/agent-workload/teams [get]
+func (ctrl *WorkloadController) GetTeamWorkload(c *fiber.Ctx) error {
+	teams, err := ctrl.WorkloadService.GetTeamWorkload(c.UserContext(), c.Query("timezone"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"data": teams})
+}
+
+// SuggestAssignee godoc
+// @Summary Suggest assignee
+// @Description The available agent under capacity with the fewest open tickets
+// @Tags agent-workload
+// @Produce json
+// @Param team query string false "Only agents taking this assigned_group"
+// @Success 200 {object} AgentWorkload
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/agent-workload/suggest [get]
+func (ctrl *WorkloadController) SuggestAssignee(c *fiber.Ctx) error {
+	agent, err := ctrl.WorkloadService.SuggestAssignee(c.UserContext(), c.Query("team"))
+	if err != nil {
+		if errors.Is(err, ErrNoAvailableAgent) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"data": agent})
+}
+
+// GetMyAvailability godoc
+// @Summary Get my availability
+// @Tags agent-workload
+// @Produce json
+// @Success 200 {object} AgentAvailability
+// @Router /api/agent-workload/availability [get]
+func (ctrl *WorkloadController) GetMyAvailability(c *fiber.Ctx) error {
+	userID, ok := session.UserID(c.UserContext())
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	availability, err := ctrl.WorkloadService.GetAvailability(c.UserContext(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"data": availability})
+}
+
+// SetMyAvailability godoc
+// @Summary Set my availability
+// @Tags agent-workload
+// @Accept json
+// @Produce json
+// @Param availability body AgentAvailability true "Status, teams and max_open"
+// @Success 200 {object} AgentAvailability
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/agent-workload/availability [put]
+func (ctrl *WorkloadController) SetMyAvailability(c *fiber.Ctx) error {
+	userID, ok := session.UserID(c.UserContext())
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	return ctrl.setAvailability(c, userID, userID)
+}
+
+// SetAgentAvailability godoc
+// @Summary Set an agent's availability
+// @Description For supervisors
+// @Tags agent-workload
+// @Accept json
+// @Produce json
+// @Param userId path string true "User ID"
+// @Param availability body AgentAvailability true "Status, teams and max_open"
+// @Success 200 {object} AgentAvailability
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/agent-workload/agents/{userId}/availability [put]
+func (ctrl *WorkloadController) SetAgentAvailability(c *fiber.Ctx) error {
+	updatedBy, ok := session.UserID(c.UserContext())
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	userID, err := primitive.ObjectIDFromHex(c.Params("userId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	return ctrl.setAvailability(c, userID, updatedBy)
+}
+
+func (ctrl *WorkloadController) setAvailability(c *fiber.Ctx, userID, updatedBy primitive.ObjectID) error {
+	var input AgentAvailability
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	availability, err := ctrl.WorkloadService.SetAvailability(c.UserContext(), userID, &input, updatedBy)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"data": availability})
+}
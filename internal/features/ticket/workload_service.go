@@ -0,0 +1,295 @@
+package ticket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/user"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrNoAvailableAgent is returned by SuggestAssignee when every matching
+// agent is unavailable or at capacity
+var ErrNoAvailableAgent = errors.New("no available agent")
+
+// AgentWorkload is the load and availability of one agent
+type AgentWorkload struct {
+	UserID        string                 `json:"user_id"`
+	Name          string                 `json:"name"`
+	Status        AgentStatus            `json:"status"`
+	Teams         []string               `json:"teams,omitempty"`
+	MaxOpen       int                    `json:"max_open"`
+	Open          int                    `json:"open"`
+	ByPriority    map[TicketPriority]int `json:"by_priority"`
+	ByStatus      map[TicketStatus]int   `json:"by_status"`
+	AvgAgeMinutes *float64               `json:"avg_age_minutes"`
+	ResolvedToday int                    `json:"resolved_today"`
+	AtCapacity    bool                   `json:"at_capacity"`
+}
+
+// TeamWorkload summarises the open tickets of one assigned_group
+type TeamWorkload struct {
+	Team            string                 `json:"team"` // empty for tickets without a team
+	Open            int                    `json:"open"`
+	Unassigned      int                    `json:"unassigned"`
+	ByPriority      map[TicketPriority]int `json:"by_priority"`
+	ByStatus        map[TicketStatus]int   `json:"by_status"`
+	AvgAgeMinutes   *float64               `json:"avg_age_minutes"`
+	ResolvedToday   int                    `json:"resolved_today"`
+	Agents          int                    `json:"agents"`           // agents with open tickets or who take the team's tickets
+	AvailableAgents int                    `json:"available_agents"` // of those, currently available
+}
+
+// WorkloadService reports agent and team load and picks assignees for new
+// tickets. Timezone decides where "today" starts; empty means UTC.
+type WorkloadService interface {
+	GetAgentWorkload(ctx context.Context, team, timezone string) ([]AgentWorkload, error)
+	GetTeamWorkload(ctx context.Context, timezone string) ([]TeamWorkload, error)
+	GetAvailability(ctx context.Context, userID primitive.ObjectID) (*AgentAvailability, error)
+	SetAvailability(ctx context.Context, userID primitive.ObjectID, availability *AgentAvailability, updatedBy primitive.ObjectID) (*AgentAvailability, error)
+	// SuggestAssignee returns the available agent with the fewest open
+	// tickets among those taking tickets for team (any team when empty)
+	SuggestAssignee(ctx context.Context, team string) (*AgentWorkload, error)
+}
+
+// WorkloadServiceImpl implements WorkloadService
+type WorkloadServiceImpl struct {
+	TicketRepo       TicketRepository
+	AvailabilityRepo AgentAvailabilityRepository
+	UserRepo         user.UserRepository
+}
+
+// NewWorkloadService creates a new workload service
+func NewWorkloadService(ticketRepo TicketRepository, availabilityRepo AgentAvailabilityRepository, userRepo user.UserRepository) WorkloadService {
+	return &WorkloadServiceImpl{
+		TicketRepo:       ticketRepo,
+		AvailabilityRepo: availabilityRepo,
+		UserRepo:         userRepo,
+	}
+}
+
+func startOfDay(now time.Time, timezone string) (time.Time, error) {
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unknown timezone '%s'", timezone)
+	}
+	local := now.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc), nil
+}
+
+func roundMinutes(v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	r := math.Round(*v*10) / 10
+	return &r
+}
+
+func hasTeam(teams []string, team string) bool {
+	for _, t := range teams {
+		if t == team {
+			return true
+		}
+	}
+	return false
+}
+
+// availabilityByUser returns the stored availability of the current tenant's
+// agents, keyed by user ID
+func (s *WorkloadServiceImpl) availabilityByUser(ctx context.Context) (map[string]*AgentAvailability, error) {
+	all, err := s.AvailabilityRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]*AgentAvailability, len(all))
+	for i := range all {
+		result[all[i].UserID.Hex()] = &all[i]
+	}
+	return result, nil
+}
+
+// GetAgentWorkload lists agents with open tickets in team (all teams when
+// empty) together with available agents taking that team's tickets
+func (s *WorkloadServiceImpl) GetAgentWorkload(ctx context.Context, team, timezone string) ([]AgentWorkload, error) {
+	now := time.Now()
+	dayStart, err := startOfDay(now, timezone)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.TicketRepo.Workload(ctx, WorkloadQuery{GroupBy: WorkloadByAgent, AssignedGroup: team, DayStart: dayStart}, now)
+	if err != nil {
+		return nil, err
+	}
+	availability, err := s.availabilityByUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byUser := map[string]*AgentWorkload{}
+	var ids []string
+	for _, row := range rows {
+		byUser[row.Key] = &AgentWorkload{
+			UserID:        row.Key,
+			Status:        AgentStatusOffline,
+			Open:          row.Open,
+			ByPriority:    row.ByPriority,
+			ByStatus:      row.ByStatus,
+			AvgAgeMinutes: roundMinutes(row.AvgAgeMinutes),
+			ResolvedToday: row.ResolvedToday,
+		}
+		ids = append(ids, row.Key)
+	}
+	for id, a := range availability {
+		if _, ok := byUser[id]; ok || a.Status == AgentStatusOffline || (team != "" && !hasTeam(a.Teams, team)) {
+			continue
+		}
+		byUser[id] = &AgentWorkload{
+			UserID:     id,
+			ByPriority: map[TicketPriority]int{},
+			ByStatus:   map[TicketStatus]int{},
+		}
+		ids = append(ids, id)
+	}
+
+	users, err := s.UserRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	tenantID, _ := ctx.Value(common_models.TenantIDKey).(string)
+	result := make([]AgentWorkload, 0, len(users))
+	for _, u := range users {
+		if tenantID != "" && u.TenantID.Hex() != tenantID {
+			continue
+		}
+		w := byUser[u.ID.Hex()]
+		w.Name = strings.TrimSpace(u.FirstName + " " + u.LastName)
+		if w.Name == "" {
+			w.Name = u.Username
+		}
+		if a, ok := availability[w.UserID]; ok {
+			w.Status = a.Status
+			w.Teams = a.Teams
+			w.MaxOpen = a.MaxOpen
+		}
+		w.AtCapacity = w.MaxOpen > 0 && w.Open >= w.MaxOpen
+		result = append(result, *w)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Open != result[j].Open {
+			return result[i].Open > result[j].Open
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// GetTeamWorkload summarises open tickets per assigned_group
+func (s *WorkloadServiceImpl) GetTeamWorkload(ctx context.Context, timezone string) ([]TeamWorkload, error) {
+	now := time.Now()
+	dayStart, err := startOfDay(now, timezone)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.TicketRepo.Workload(ctx, WorkloadQuery{GroupBy: WorkloadByTeam, DayStart: dayStart}, now)
+	if err != nil {
+		return nil, err
+	}
+	availability, err := s.availabilityByUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]TeamWorkload, 0, len(rows))
+	for _, row := range rows {
+		t := TeamWorkload{
+			Team:          row.Key,
+			Open:          row.Open,
+			Unassigned:    row.Unassigned,
+			ByPriority:    row.ByPriority,
+			ByStatus:      row.ByStatus,
+			AvgAgeMinutes: roundMinutes(row.AvgAgeMinutes),
+			ResolvedToday: row.ResolvedToday,
+		}
+		agents := map[string]bool{}
+		for _, id := range row.Agents {
+			agents[id.Hex()] = true
+		}
+		if row.Key != "" {
+			for id, a := range availability {
+				if hasTeam(a.Teams, row.Key) {
+					agents[id] = true
+				}
+			}
+		}
+		t.Agents = len(agents)
+		for id := range agents {
+			if a, ok := availability[id]; ok && a.Status == AgentStatusAvailable {
+				t.AvailableAgents++
+			}
+		}
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+// GetAvailability returns a user's availability, offline when never set
+func (s *WorkloadServiceImpl) GetAvailability(ctx context.Context, userID primitive.ObjectID) (*AgentAvailability, error) {
+	return s.AvailabilityRepo.FindByUser(ctx, userID)
+}
+
+// SetAvailability replaces a user's availability
+func (s *WorkloadServiceImpl) SetAvailability(ctx context.Context, userID primitive.ObjectID, availability *AgentAvailability, updatedBy primitive.ObjectID) (*AgentAvailability, error) {
+	switch availability.Status {
+	case AgentStatusAvailable, AgentStatusBusy, AgentStatusAway, AgentStatusOffline:
+	default:
+		return nil, errors.New("status must be available, busy, away or offline")
+	}
+	if availability.MaxOpen < 0 {
+		return nil, errors.New("max_open cannot be negative")
+	}
+	if _, err := s.UserRepo.FindByID(ctx, userID.Hex()); err != nil {
+		return nil, errors.New("user not found")
+	}
+	availability.UserID = userID
+	availability.UpdatedBy = updatedBy
+	if err := s.AvailabilityRepo.Upsert(ctx, availability); err != nil {
+		return nil, err
+	}
+	return availability, nil
+}
+
+// SuggestAssignee picks the least loaded available agent. Load counts open
+// tickets across all teams; ties go to the agent who resolved fewer today.
+func (s *WorkloadServiceImpl) SuggestAssignee(ctx context.Context, team string) (*AgentWorkload, error) {
+	agents, err := s.GetAgentWorkload(ctx, "", "")
+	if err != nil {
+		return nil, err
+	}
+	var best *AgentWorkload
+	for i := range agents {
+		a := &agents[i]
+		if a.Status != AgentStatusAvailable || a.AtCapacity {
+			continue
+		}
+		if team != "" && !hasTeam(a.Teams, team) {
+			continue
+		}
+		if best == nil || a.Open < best.Open || (a.Open == best.Open && a.ResolvedToday < best.ResolvedToday) {
+			best = a
+		}
+	}
+	if best == nil {
+		return nil, ErrNoAvailableAgent
+	}
+	return best, nil
+}
@@ -3,6 +3,8 @@ package user
 import (
 	"strconv"
 
+	common_api "go-crm/internal/common/api"
+	"go-crm/internal/common/apperr"
 	"go-crm/internal/common/models"
 
 	"github.com/gofiber/fiber/v2"
@@ -161,6 +163,9 @@ func (ctrl *UserController) CreateUser(c *fiber.Ctx) error {
 	}
 
 	if err := ctrl.UserService.CreateUser(c.UserContext(), user); err != nil {
+		if apperr.CodeOf(err) != apperr.CodeInternal {
+			return common_api.Error(c, err)
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create user: " + err.Error(),
 		})
@@ -231,6 +236,9 @@ func (ctrl *UserController) UpdateUser(c *fiber.Ctx) error {
 	}
 
 	if err := ctrl.UserService.UpdateUser(c.UserContext(), id, updates); err != nil {
+		if apperr.CodeOf(err) != apperr.CodeInternal {
+			return common_api.Error(c, err)
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update user: " + err.Error(),
 		})
@@ -251,6 +259,7 @@ func (ctrl *UserController) UpdateUser(c *fiber.Ctx) error {
 // @Param        input body UpdateUserRolesRequest true "Update User Roles Input"
 // @Success      200  {object} map[string]string
 // @Failure      400  {string} string "Invalid request body"
+// @Failure      403  {string} string "Role grants more than the caller holds"
 // @Failure      500  {string} string "Failed to update user roles"
 // @Router       /users/{id}/roles [put]
 func (ctrl *UserController) UpdateUserRoles(c *fiber.Ctx) error {
@@ -264,6 +273,9 @@ func (ctrl *UserController) UpdateUserRoles(c *fiber.Ctx) error {
 	}
 
 	if err := ctrl.UserService.UpdateUserRoles(c.UserContext(), id, req.RoleIDs); err != nil {
+		if apperr.CodeOf(err) != apperr.CodeInternal {
+			return common_api.Error(c, err)
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update user roles: " + err.Error(),
 		})
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"go-crm/internal/common/models"
 	"go-crm/internal/database"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -15,6 +16,7 @@ import (
 type UserRepository interface {
 	Create(ctx context.Context, user *models.User) error
 	FindByUsername(ctx context.Context, username string) (*models.User, error)
+	UpdatePassword(ctx context.Context, id string, password string) error
 	FindByUsernameGlobal(ctx context.Context, username string) (*models.User, error)
 	FindByID(ctx context.Context, id string) (*models.User, error)
 	FindByEmail(ctx context.Context, email string) (*models.User, error)
@@ -189,6 +191,9 @@ func (r *UserRepositoryImpl) Update(ctx context.Context, id string, user *models
 	if user.LastLogin != nil {
 		update["$set"].(bson.M)["last_login"] = user.LastLogin
 	}
+	if user.Locale != nil {
+		update["$set"].(bson.M)["locale"] = user.Locale
+	}
 
 	_, err = r.Collection.UpdateOne(ctx, bson.M{"_id": objectID, "tenant_id": oid}, update)
 	return err
@@ -236,3 +241,30 @@ func (r *UserRepositoryImpl) FindByIDs(ctx context.Context, ids []string) ([]mod
 	}
 	return users, nil
 }
+
+func (r *UserRepositoryImpl) UpdatePassword(ctx context.Context, id string, password string) error {
+	tenantID, ok := ctx.Value(models.TenantIDKey).(string)
+	if !ok || tenantID == "" {
+		return fmt.Errorf("tenant context missing")
+	}
+	oid, err := primitive.ObjectIDFromHex(tenantID)
+	if err != nil {
+		return err
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.Collection.UpdateOne(ctx, bson.M{"_id": objectID, "tenant_id": oid}, bson.M{
+		"$set": bson.M{"password": password, "updated_at": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
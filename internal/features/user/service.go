@@ -7,6 +7,9 @@ import (
 
 	"go-crm/internal/common/models"
 	"go-crm/internal/features/audit"
+	"go-crm/internal/features/authz"
+	"go-crm/internal/features/quota"
+	"go-crm/pkg/utils"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -14,25 +17,71 @@ import (
 type UserService interface {
 	ListUsers(ctx context.Context, filter map[string]interface{}, page, limit int64) ([]models.User, int64, error)
 	GetUserByID(ctx context.Context, id string) (*models.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
 	CreateUser(ctx context.Context, user *models.User) error
 	UpdateUser(ctx context.Context, id string, updates map[string]interface{}) error
 	UpdateUserRoles(ctx context.Context, id string, roleIDs []string) error
 	UpdateUserStatus(ctx context.Context, id string, status string) error
 	DeleteUser(ctx context.Context, id string) error
+	ResetPassword(ctx context.Context, id string, password string) error
+}
+
+// RoleGrants checks that a caller may hand out roles; the role service
+// implements it
+type RoleGrants interface {
+	CheckGrantable(ctx context.Context, granterRoles []string, roleIDs []string) error
 }
 
 type UserServiceImpl struct {
 	UserRepo     UserRepository
 	AuditService audit.AuditService
+	Versions     authz.VersionService
+	Quotas       quota.QuotaService
+	Grants       RoleGrants
 }
 
-func NewUserService(userRepo UserRepository, auditService audit.AuditService) UserService {
+func NewUserService(userRepo UserRepository, auditService audit.AuditService, versions authz.VersionService, quotas quota.QuotaService, grants RoleGrants) UserService {
 	return &UserServiceImpl{
 		UserRepo:     userRepo,
 		AuditService: auditService,
+		Versions:     versions,
+		Quotas:       quotas,
+		Grants:       grants,
+	}
+}
+
+// bumpVersion makes the user's tokens issued before a change to their roles,
+// groups or status be refreshed
+func (s *UserServiceImpl) bumpVersion(ctx context.Context, id string) {
+	if s.Versions != nil {
+		s.Versions.Bump(ctx, id)
 	}
 }
 
+// checkGrantable makes sure the caller holds everything the roles a user is
+// gaining grant, so delegated user admins cannot escalate. Calls without a
+// caller, such as the admin CLI and migrations, are trusted.
+func (s *UserServiceImpl) checkGrantable(ctx context.Context, current, next []primitive.ObjectID) error {
+	claims, ok := ctx.Value(utils.UserClaimsKey).(*utils.UserClaims)
+	if !ok || s.Grants == nil {
+		return nil
+	}
+	had := make(map[primitive.ObjectID]bool, len(current))
+	for _, id := range current {
+		had[id] = true
+	}
+	var gained []string
+	for _, id := range next {
+		if !had[id] {
+			gained = append(gained, id.Hex())
+		}
+	}
+	if len(gained) == 0 {
+		return nil
+	}
+	return s.Grants.CheckGrantable(ctx, claims.Roles, gained)
+}
+
 func (s *UserServiceImpl) ListUsers(ctx context.Context, filter map[string]interface{}, page, limit int64) ([]models.User, int64, error) {
 	if filter == nil {
 		filter = make(map[string]interface{})
@@ -51,7 +100,21 @@ func (s *UserServiceImpl) GetUserByID(ctx context.Context, id string) (*models.U
 	return s.UserRepo.FindByID(ctx, id)
 }
 
+func (s *UserServiceImpl) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	return s.UserRepo.FindByUsername(ctx, username)
+}
+
 func (s *UserServiceImpl) CreateUser(ctx context.Context, user *models.User) error {
+	if s.Quotas != nil {
+		if err := s.Quotas.CheckUser(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := s.checkGrantable(ctx, nil, user.Roles); err != nil {
+		return err
+	}
+
 	// Initialize default fields if missing
 	if user.ID.IsZero() {
 		user.ID = primitive.NewObjectID()
@@ -128,6 +191,9 @@ func (s *UserServiceImpl) UpdateUser(ctx context.Context, id string, updates map
 		user.Groups = newGroups
 	}
 	if roles, ok := updates["roles"].([]primitive.ObjectID); ok {
+		if err := s.checkGrantable(ctx, user.Roles, roles); err != nil {
+			return err
+		}
 		changes["roles"] = models.Change{Old: user.Roles, New: roles}
 		user.Roles = roles
 	}
@@ -143,6 +209,12 @@ func (s *UserServiceImpl) UpdateUser(ctx context.Context, id string, updates map
 	if len(changes) > 0 {
 		_ = s.AuditService.LogChange(ctx, models.AuditActionUpdate, "user", id, changes)
 	}
+	for _, field := range []string{"roles", "groups", "status"} {
+		if _, changed := changes[field]; changed {
+			s.bumpVersion(ctx, id)
+			break
+		}
+	}
 
 	return nil
 }
@@ -162,6 +234,9 @@ func (s *UserServiceImpl) UpdateUserRoles(ctx context.Context, id string, roleID
 		}
 		objectIDs = append(objectIDs, oid)
 	}
+	if err := s.checkGrantable(ctx, user.Roles, objectIDs); err != nil {
+		return err
+	}
 
 	// Track change
 	changes := map[string]models.Change{
@@ -177,6 +252,7 @@ func (s *UserServiceImpl) UpdateUserRoles(ctx context.Context, id string, roleID
 
 	// Audit log
 	_ = s.AuditService.LogChange(ctx, models.AuditActionUpdate, "user", id, changes)
+	s.bumpVersion(ctx, id)
 
 	return nil
 }
@@ -214,6 +290,7 @@ func (s *UserServiceImpl) UpdateUserStatus(ctx context.Context, id string, statu
 
 	// Audit log
 	_ = s.AuditService.LogChange(ctx, models.AuditActionUpdate, "user", id, changes)
+	s.bumpVersion(ctx, id)
 
 	return nil
 }
@@ -236,6 +313,22 @@ func (s *UserServiceImpl) DeleteUser(ctx context.Context, id string) error {
 		"username": {Old: user.Username, New: ""},
 	}
 	_ = s.AuditService.LogChange(ctx, models.AuditActionDelete, "user", id, changes)
+	s.bumpVersion(ctx, id)
+
+	return nil
+}
+
+func (s *UserServiceImpl) ResetPassword(ctx context.Context, id string, password string) error {
+	if len(password) < 8 {
+		return errors.New("password must be at least 8 characters")
+	}
+	// hash password placeholder (TODO: use bcrypt), matching Login
+	if err := s.UserRepo.UpdatePassword(ctx, id, password); err != nil {
+		return err
+	}
 
+	_ = s.AuditService.LogChange(ctx, models.AuditActionUpdate, "user", id, map[string]models.Change{
+		"password": {New: "reset"},
+	})
 	return nil
 }
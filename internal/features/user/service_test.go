@@ -0,0 +1,92 @@
+package user
+
+import (
+	"context"
+	"testing"
+
+	"go-crm/internal/common/apperr"
+	"go-crm/internal/common/models"
+	"go-crm/internal/features/audit"
+	"go-crm/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type stubUserRepo struct {
+	UserRepository
+	user    *models.User
+	updated bool
+}
+
+func (r *stubUserRepo) FindByID(ctx context.Context, id string) (*models.User, error) {
+	return r.user, nil
+}
+
+func (r *stubUserRepo) Update(ctx context.Context, id string, user *models.User) error {
+	r.updated = true
+	return nil
+}
+
+type stubAudit struct {
+	audit.AuditService
+}
+
+func (stubAudit) LogChange(ctx context.Context, action models.AuditAction, module string, recordID string, changes map[string]models.Change) error {
+	return nil
+}
+
+// stubGrants allows only the listed roles and records what it was asked
+type stubGrants struct {
+	allowed map[string]bool
+	asked   []string
+}
+
+func (g *stubGrants) CheckGrantable(ctx context.Context, granterRoles []string, roleIDs []string) error {
+	g.asked = append(g.asked, roleIDs...)
+	for _, id := range roleIDs {
+		if !g.allowed[id] {
+			return apperr.PermissionDenied("role %s grants more than you have", id)
+		}
+	}
+	return nil
+}
+
+func TestUpdateUserRolesChecksGainedRoles(t *testing.T) {
+	held, granted, escalating := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	callerCtx := context.WithValue(context.Background(), utils.UserClaimsKey, &utils.UserClaims{Roles: []string{"User Admin"}})
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		roles   []primitive.ObjectID
+		asked   int
+		allowed bool
+	}{
+		{"grantable role", callerCtx, []primitive.ObjectID{held, granted}, 1, true},
+		{"role beyond the caller", callerCtx, []primitive.ObjectID{held, escalating}, 1, false},
+		{"kept roles are not rechecked", callerCtx, []primitive.ObjectID{held}, 0, true},
+		{"no caller", context.Background(), []primitive.ObjectID{escalating}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &stubUserRepo{user: &models.User{Roles: []primitive.ObjectID{held}}}
+			grants := &stubGrants{allowed: map[string]bool{granted.Hex(): true}}
+			svc := NewUserService(repo, stubAudit{}, nil, nil, grants)
+
+			var ids []string
+			for _, id := range tt.roles {
+				ids = append(ids, id.Hex())
+			}
+			err := svc.UpdateUserRoles(tt.ctx, primitive.NewObjectID().Hex(), ids)
+			if len(grants.asked) != tt.asked {
+				t.Errorf("Expected %d roles to be checked, got %v", tt.asked, grants.asked)
+			}
+			if tt.allowed && (err != nil || !repo.updated) {
+				t.Errorf("Expected the roles to be updated, got %v", err)
+			}
+			if !tt.allowed && (apperr.CodeOf(err) != apperr.CodePermissionDenied || repo.updated) {
+				t.Errorf("Expected a permission denied error without an update, got %v", err)
+			}
+		})
+	}
+}
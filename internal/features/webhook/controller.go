@@ -2,7 +2,8 @@ package webhook
 
 import (
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"go-crm/internal/common/session"
 )
 
 type WebhookController struct {
@@ -34,12 +35,7 @@ func (ctrl *WebhookController) CreateWebhook(c *fiber.Ctx) error {
 		})
 	}
 
-	userIDStr, ok := c.Locals("user_id").(string)
-	if ok {
-		if oid, err := primitive.ObjectIDFromHex(userIDStr); err == nil {
-			webhook.CreatedBy = oid
-		}
-	}
+	webhook.CreatedBy, _ = session.UserID(c.UserContext())
 
 	if err := ctrl.Service.CreateWebhook(c.UserContext(), &webhook); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -0,0 +1,57 @@
+package integration
+
+import (
+	"testing"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/automation"
+)
+
+func TestCreateTriggerUpdatesRecord(t *testing.T) {
+	e := newEnv(t)
+	e.module("leads").
+		required("name", common_models.FieldTypeText).
+		field("amount", common_models.FieldTypeNumber).
+		field("priority", common_models.FieldTypeText).
+		create()
+	ctx := e.ctx()
+
+	rule := &automation.AutomationRule{
+		Name:        "Flag large leads",
+		ModuleID:    "leads",
+		TriggerType: "create",
+		Active:      true,
+		Conditions:  []automation.RuleCondition{{Field: "amount", Operator: automation.OperatorGreaterThan, Value: 1000}},
+		Actions:     []automation.RuleAction{{Type: automation.ActionUpdateField, Config: map[string]any{"field": "priority", "value": "high"}}},
+	}
+	if err := e.Automations.CreateRule(ctx, rule); err != nil {
+		t.Fatalf("CreateRule: %v", err)
+	}
+
+	large := e.record("leads", e.admin, map[string]any{"name": "Acme", "amount": 5000})
+	eventually(t, "the rule to flag the large lead", func() bool {
+		rec, err := e.Records.Get(ctx, "leads", large)
+		return err == nil && rec["priority"] == "high"
+	})
+
+	// Triggers run the same rules synchronously; a record outside the
+	// conditions is left alone
+	smallID, err := e.Records.Create(ctx, "leads", common_models.ProductCRM, map[string]any{"name": "Initech", "amount": 10})
+	if err != nil {
+		t.Fatalf("create small lead: %v", err)
+	}
+	small, err := e.Records.Get(ctx, "leads", hex(smallID))
+	if err != nil {
+		t.Fatalf("get small lead: %v", err)
+	}
+	if err := e.Automations.ExecuteFromTrigger(ctx, "leads", small, "create"); err != nil {
+		t.Fatalf("ExecuteFromTrigger: %v", err)
+	}
+	small, err = e.Records.Get(ctx, "leads", hex(smallID))
+	if err != nil {
+		t.Fatalf("get small lead: %v", err)
+	}
+	if _, flagged := small["priority"]; flagged {
+		t.Fatalf("small lead was flagged: %v", small)
+	}
+}
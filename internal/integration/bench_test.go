@@ -0,0 +1,139 @@
+package integration
+
+import (
+	"fmt"
+	"testing"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/automation"
+	"go-crm/internal/features/ticket"
+)
+
+// The benchmarks below cover the endpoints behind most of the traffic, at the
+// service layer and against a real database. Baselines and the regression
+// thresholds they are held to are in deployment/loadtest/README.md.
+
+const benchPageSize = 50
+
+// BenchmarkRecordListWithLookups lists a page of deals, each resolving a
+// lookup to its account, as a rep whose role limits the rows they see
+func BenchmarkRecordListWithLookups(b *testing.B) {
+	e := newEnv(b)
+	e.module("accounts").required("name", common_models.FieldTypeText).create()
+	e.module("deals").
+		required("name", common_models.FieldTypeText).
+		field("amount", common_models.FieldTypeNumber).
+		field("region", common_models.FieldTypeText).
+		lookup("account", "accounts").
+		create()
+
+	accounts := make([]string, 20)
+	for i := range accounts {
+		accounts[i] = e.record("accounts", e.admin, map[string]any{"name": fmt.Sprintf("Account %d", i)})
+	}
+	for i := 0; i < 500; i++ {
+		region := "west"
+		if i%2 == 1 {
+			region = "east"
+		}
+		e.record("deals", e.admin, map[string]any{
+			"name":    fmt.Sprintf("Deal %d", i),
+			"amount":  i * 100,
+			"region":  region,
+			"account": accounts[i%len(accounts)],
+		})
+	}
+	rep := e.user("rep", e.role("west reps").allow("deals", "read", where("region", "eq", "west")).create())
+	ctx := e.as(rep)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		records, _, err := e.RecordSvc.ListRecords(ctx, "deals", nil, 1, benchPageSize, "amount", "desc", rep.ID)
+		if err != nil {
+			b.Fatalf("ListRecords: %v", err)
+		}
+		if len(records) != benchPageSize {
+			b.Fatalf("listed %d deals, want %d", len(records), benchPageSize)
+		}
+	}
+}
+
+// BenchmarkRecordCreateWithAutomations creates leads that two create rules
+// match. Rules run off the request; the timing includes waiting for them so a
+// slower executor shows up here rather than as a growing backlog.
+func BenchmarkRecordCreateWithAutomations(b *testing.B) {
+	e := newEnv(b)
+	e.module("leads").
+		required("name", common_models.FieldTypeText).
+		field("amount", common_models.FieldTypeNumber).
+		field("priority", common_models.FieldTypeText).
+		field("stage", common_models.FieldTypeText).
+		create()
+	ctx := e.ctx()
+
+	for _, rule := range []*automation.AutomationRule{
+		{
+			Name:        "Flag large leads",
+			ModuleID:    "leads",
+			TriggerType: "create",
+			Active:      true,
+			Conditions:  []automation.RuleCondition{{Field: "amount", Operator: automation.OperatorGreaterThan, Value: 1000}},
+			Actions:     []automation.RuleAction{{Type: automation.ActionUpdateField, Config: map[string]any{"field": "priority", "value": "high"}}},
+		},
+		{
+			Name:        "Start new leads in qualification",
+			ModuleID:    "leads",
+			TriggerType: "create",
+			Active:      true,
+			Actions:     []automation.RuleAction{{Type: automation.ActionUpdateField, Config: map[string]any{"field": "stage", "value": "qualification"}}},
+		},
+	} {
+		if err := e.Automations.CreateRule(ctx, rule); err != nil {
+			b.Fatalf("CreateRule %s: %v", rule.Name, err)
+		}
+	}
+
+	var last string
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		last = e.record("leads", e.admin, map[string]any{"name": fmt.Sprintf("Lead %d", i), "amount": 5000})
+	}
+	eventually(b, "the rules to finish", func() bool {
+		rec, err := e.Records.Get(ctx, "leads", last)
+		return err == nil && rec["priority"] == "high" && rec["stage"] == "qualification"
+	})
+}
+
+// BenchmarkTicketList lists the first page of open tickets in a queue of a
+// few thousand, newest first, as the agent inbox does
+func BenchmarkTicketList(b *testing.B) {
+	e := newEnv(b)
+	ctx := e.ctx()
+
+	statuses := []ticket.TicketStatus{ticket.TicketStatusNew, ticket.TicketStatusOpen, ticket.TicketStatusPending, ticket.TicketStatusResolved}
+	priorities := []ticket.TicketPriority{ticket.TicketPriorityLow, ticket.TicketPriorityMedium, ticket.TicketPriorityHigh, ticket.TicketPriorityUrgent}
+	for i := 0; i < 2000; i++ {
+		tk := &ticket.Ticket{
+			TicketNumber: fmt.Sprintf("TKT-%05d", i),
+			Subject:      fmt.Sprintf("Ticket %d", i),
+			Status:       statuses[i%len(statuses)],
+			Priority:     priorities[i%len(priorities)],
+			Channel:      ticket.TicketChannelEmail,
+		}
+		if err := e.TicketRepo.Create(ctx, tk); err != nil {
+			b.Fatalf("create ticket: %v", err)
+		}
+	}
+	filters := map[string]interface{}{"status": string(ticket.TicketStatusOpen)}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tickets, _, err := e.Tickets.ListTickets(ctx, filters, 1, benchPageSize, "created_at", "desc")
+		if err != nil {
+			b.Fatalf("ListTickets: %v", err)
+		}
+		if len(tickets) != benchPageSize {
+			b.Fatalf("listed %d tickets, want %d", len(tickets), benchPageSize)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+// Package integration runs the services against a real MongoDB: records,
+// permissions, automations and ticket SLAs wired the way the API wires
+// them, with stand-ins only for outbound side effects. mongotest provides
+// the database, a single-node replica set from a local mongod or a Docker
+// container so transactions work; `make test-integration` fails when
+// neither is available.
+package integration
@@ -0,0 +1,282 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/common/session"
+	"go-crm/internal/config"
+	"go-crm/internal/database"
+	"go-crm/internal/features/audit"
+	"go-crm/internal/features/automation"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/permission"
+	"go-crm/internal/features/record"
+	"go-crm/internal/features/role"
+	"go-crm/internal/features/ticket"
+	"go-crm/internal/features/user"
+	"go-crm/internal/features/webhook"
+	"go-crm/internal/testutil/mongotest"
+	"go-crm/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMain(m *testing.M) {
+	mongotest.Main(m)
+}
+
+// automationRate is high enough that benchmarks creating records in a tight
+// loop are not throttled by the per-tenant automation cap
+const automationRate = 1_000_000
+
+// env is one tenant on its own database with the services wired over it
+type env struct {
+	t        testing.TB
+	db       *database.MongodbDB
+	tenantID primitive.ObjectID
+	admin    *common_models.User
+
+	Modules     module.ModuleRepository
+	Records     record.RecordRepository
+	Users       user.UserRepository
+	Roles       role.RoleRepository
+	Permissions permission.PermissionService
+	RoleService role.RoleService
+	Audit       audit.AuditService
+	Automations automation.AutomationService
+	RecordSvc   record.RecordService
+	Tickets     ticket.TicketService
+	TicketRepo  ticket.TicketRepository
+	SLA         ticket.SLAService
+}
+
+func newEnv(t testing.TB) *env {
+	t.Helper()
+	db := mongotest.New(t)
+	e := &env{t: t, db: db, tenantID: primitive.NewObjectID()}
+
+	e.Modules = module.NewModuleRepository(db)
+	e.Records = record.NewRecordRepository(db)
+	e.Users = user.NewUserRepository(db)
+	e.Roles = role.NewRoleRepository(db)
+	e.Audit = audit.NewAuditService(audit.NewAuditRepository(db), e.Users)
+	e.Permissions = permission.NewPermissionService(permission.NewPermissionRepository(db), e.Users, e.Audit, nil)
+	e.RoleService = role.NewRoleService(e.Roles, e.Users, e.Audit, e.Permissions, nil, nil)
+
+	executor := automation.NewActionExecutor(e.Modules, e.Records, nil, nil, e.Audit, nil)
+	e.Automations = automation.NewAutomationService(automation.NewAutomationRepository(db), executor, e.Audit, e.Modules, nil, nil, &config.Config{AutomationRatePerMinute: automationRate})
+
+	e.RecordSvc = &record.RecordServiceImpl{
+		ModuleRepo:        e.Modules,
+		RecordRepo:        e.Records,
+		UserRepo:          e.Users,
+		RoleRepo:          e.Roles,
+		RoleService:       e.RoleService,
+		AuditService:      e.Audit,
+		ApprovalService:   noApprovals{},
+		AutomationService: e.Automations,
+		WebhookService:    noWebhooks{},
+		PermissionService: e.Permissions,
+	}
+
+	e.TicketRepo = ticket.NewTicketRepository(db)
+	slaRepo := ticket.NewSLAPolicyRepository(db)
+	e.Tickets = &ticket.TicketServiceImpl{
+		TicketRepo:    e.TicketRepo,
+		SLAPolicyRepo: slaRepo,
+		AuditService:  e.Audit,
+	}
+	e.SLA = ticket.NewSLAService(slaRepo, e.TicketRepo)
+
+	adminRole := e.role("admin").create()
+	e.admin = e.user("admin", adminRole)
+	return e
+}
+
+// ctx acts as the tenant's admin
+func (e *env) ctx() context.Context {
+	return e.as(e.admin)
+}
+
+// as returns the context AuthMiddleware would hand the services for u
+func (e *env) as(u *common_models.User) context.Context {
+	claims := &utils.UserClaims{UserID: u.ID.Hex(), TenantID: e.tenantID.Hex()}
+	ctx := context.WithValue(context.Background(), common_models.TenantIDKey, claims.TenantID)
+	ctx = context.WithValue(ctx, utils.UserClaimsKey, claims)
+	return session.With(ctx, &session.RequestContext{UserID: u.ID, TenantID: e.tenantID})
+}
+
+// tenantCtx carries only the tenant, as for requests made before users exist
+func (e *env) tenantCtx() context.Context {
+	return context.WithValue(context.Background(), common_models.TenantIDKey, e.tenantID.Hex())
+}
+
+func (e *env) user(username string, roles ...*role.Role) *common_models.User {
+	e.t.Helper()
+	u := &common_models.User{
+		ID:        primitive.NewObjectID(),
+		Username:  username,
+		Email:     username + "@example.com",
+		Status:    "active",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	for _, r := range roles {
+		u.Roles = append(u.Roles, r.ID)
+	}
+	if err := e.Users.Create(e.tenantCtx(), u); err != nil {
+		e.t.Fatalf("create user %s: %v", username, err)
+	}
+	return u
+}
+
+// moduleBuilder describes a module schema to store
+type moduleBuilder struct {
+	e      *env
+	entity common_models.Entity
+}
+
+func (e *env) module(name string) *moduleBuilder {
+	return &moduleBuilder{e: e, entity: common_models.Entity{
+		ID:      primitive.NewObjectID(),
+		Name:    name,
+		Label:   name,
+		Slug:    name,
+		Product: common_models.ProductCRM,
+	}}
+}
+
+func (b *moduleBuilder) field(name string, fieldType common_models.FieldType) *moduleBuilder {
+	b.entity.Fields = append(b.entity.Fields, common_models.ModuleField{Name: name, Label: name, Type: fieldType, Filterable: true, Sortable: true})
+	return b
+}
+
+func (b *moduleBuilder) required(name string, fieldType common_models.FieldType) *moduleBuilder {
+	b.field(name, fieldType)
+	b.entity.Fields[len(b.entity.Fields)-1].Required = true
+	return b
+}
+
+// lookup adds a field referencing records of target, shown by their name
+func (b *moduleBuilder) lookup(name, target string) *moduleBuilder {
+	b.field(name, common_models.FieldTypeLookup)
+	b.entity.Fields[len(b.entity.Fields)-1].Lookup = &common_models.LookupDef{LookupModule: target, LookupLabel: "name"}
+	return b
+}
+
+func (b *moduleBuilder) create() *common_models.Entity {
+	b.e.t.Helper()
+	b.entity.CreatedAt = time.Now()
+	b.entity.UpdatedAt = time.Now()
+	if err := b.e.Modules.Create(b.e.tenantCtx(), &b.entity); err != nil {
+		b.e.t.Fatalf("create module %s: %v", b.entity.Name, err)
+	}
+	return &b.entity
+}
+
+// roleBuilder describes a role and the permissions stored for it
+type roleBuilder struct {
+	e     *env
+	role  role.Role
+	perms []permission.Permission
+}
+
+func (e *env) role(name string) *roleBuilder {
+	return &roleBuilder{e: e, role: role.Role{ID: primitive.NewObjectID(), Name: name}}
+}
+
+// allow grants action on the module's records, limited to those matching
+// conditions when given
+func (b *roleBuilder) allow(moduleName, action string, conditions *common_models.PermissionGroup) *roleBuilder {
+	for i := range b.perms {
+		if b.perms[i].Resource.ID == moduleName {
+			b.perms[i].Actions[action] = common_models.ActionPermission{Allowed: true, Conditions: conditions}
+			return b
+		}
+	}
+	b.perms = append(b.perms, permission.Permission{
+		Resource: permission.ResourceRef{Type: "module", ID: moduleName},
+		Actions:  map[string]common_models.ActionPermission{action: {Allowed: true, Conditions: conditions}},
+	})
+	return b
+}
+
+// extends makes the role inherit base's permissions
+func (b *roleBuilder) extends(base *role.Role) *roleBuilder {
+	b.role.BaseRoleID = &base.ID
+	return b
+}
+
+func (b *roleBuilder) create() *role.Role {
+	b.e.t.Helper()
+	ctx := b.e.tenantCtx()
+	b.role.CreatedAt = time.Now()
+	b.role.UpdatedAt = time.Now()
+	if err := b.e.Roles.Create(ctx, &b.role); err != nil {
+		b.e.t.Fatalf("create role %s: %v", b.role.Name, err)
+	}
+	for _, p := range b.perms {
+		p.RoleID = b.role.ID
+		if _, err := b.e.Permissions.CreatePermission(ctx, &p); err != nil {
+			b.e.t.Fatalf("grant %s on %s: %v", b.role.Name, p.Resource.ID, err)
+		}
+	}
+	return &b.role
+}
+
+// where is a permission condition comparing a record field to a fixed value
+func where(field, operator string, value any) *common_models.PermissionGroup {
+	return &common_models.PermissionGroup{
+		Operator: "AND",
+		Rules:    []common_models.PermissionRule{{Field: field, Operator: operator, Value: value, Type: common_models.RuleTypeStatic}},
+	}
+}
+
+// record creates a record through the record service as u
+func (e *env) record(moduleName string, u *common_models.User, data map[string]any) string {
+	e.t.Helper()
+	res, err := e.RecordSvc.CreateRecord(e.as(u), moduleName, data, u.ID)
+	if err != nil {
+		e.t.Fatalf("create %s record: %v", moduleName, err)
+	}
+	id, ok := res.(primitive.ObjectID)
+	if !ok {
+		e.t.Fatalf("create %s record returned %T", moduleName, res)
+	}
+	return id.Hex()
+}
+
+// eventually polls cond until it holds, for work the services hand to goroutines
+func eventually(t testing.TB, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// noApprovals starts no approval workflow
+type noApprovals struct{}
+
+func (noApprovals) InitializeApproval(ctx context.Context, moduleName string, record map[string]interface{}) (*common_models.ApprovalRecordState, error) {
+	return nil, nil
+}
+
+// noWebhooks drops outbound webhooks
+type noWebhooks struct {
+	webhook.WebhookService
+}
+
+func (noWebhooks) Trigger(ctx context.Context, event string, payload common_models.WebhookPayload) {}
+
+// hex is the ID a repository Create returned
+func hex(id any) string {
+	oid, _ := id.(primitive.ObjectID)
+	return oid.Hex()
+}
@@ -0,0 +1,75 @@
+package integration
+
+import (
+	"errors"
+	"testing"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/record"
+)
+
+func TestRecordAccessFollowsRoleConditions(t *testing.T) {
+	e := newEnv(t)
+	e.module("leads").
+		required("name", common_models.FieldTypeText).
+		field("region", common_models.FieldTypeText).
+		create()
+
+	westReps := e.role("west_reps").
+		allow("leads", "read", where("region", "eq", "west")).
+		allow("leads", "create", nil).
+		create()
+	alice := e.user("alice", westReps)
+
+	west := e.record("leads", e.admin, map[string]any{"name": "Acme", "region": "west"})
+	east := e.record("leads", e.admin, map[string]any{"name": "Globex", "region": "east"})
+	ctx := e.as(alice)
+
+	if _, err := e.RecordSvc.GetRecord(ctx, "leads", west, alice.ID); err != nil {
+		t.Fatalf("alice reading a west lead: %v", err)
+	}
+	if _, err := e.RecordSvc.GetRecord(ctx, "leads", east, alice.ID); !errors.Is(err, record.ErrRecordNotFound) {
+		t.Fatalf("alice reading an east lead: err = %v, want ErrRecordNotFound", err)
+	}
+
+	records, total, err := e.RecordSvc.ListRecords(ctx, "leads", nil, 1, 10, "", "", alice.ID)
+	if err != nil {
+		t.Fatalf("ListRecords: %v", err)
+	}
+	if total != 1 || len(records) != 1 || records[0]["name"] != "Acme" {
+		t.Fatalf("alice lists %d %v, want only Acme", total, records)
+	}
+
+	for action, want := range map[string]bool{"read": true, "create": true, "delete": false} {
+		got, err := e.RoleService.CheckPermission(ctx, alice.ID, "leads", action)
+		if err != nil {
+			t.Fatalf("CheckPermission %s: %v", action, err)
+		}
+		if got != want {
+			t.Errorf("CheckPermission leads %s = %v, want %v", action, got, want)
+		}
+	}
+}
+
+func TestExtendingRoleOverridesBaseConditions(t *testing.T) {
+	e := newEnv(t)
+	e.module("leads").
+		required("name", common_models.FieldTypeText).
+		field("region", common_models.FieldTypeText).
+		create()
+
+	westReps := e.role("west_reps").allow("leads", "read", where("region", "eq", "west")).create()
+	managers := e.role("sales_managers").extends(westReps).allow("leads", "read", nil).create()
+	bob := e.user("bob", managers)
+
+	e.record("leads", e.admin, map[string]any{"name": "Acme", "region": "west"})
+	e.record("leads", e.admin, map[string]any{"name": "Globex", "region": "east"})
+
+	_, total, err := e.RecordSvc.ListRecords(e.as(bob), "leads", nil, 1, 10, "", "", bob.ID)
+	if err != nil {
+		t.Fatalf("ListRecords: %v", err)
+	}
+	if total != 2 {
+		t.Fatalf("bob lists %d leads, want both", total)
+	}
+}
@@ -0,0 +1,98 @@
+package integration
+
+import (
+	"errors"
+	"testing"
+
+	"go-crm/internal/common/apperr"
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/common/validation"
+)
+
+func TestRecordLifecycle(t *testing.T) {
+	e := newEnv(t)
+	e.module("leads").
+		required("name", common_models.FieldTypeText).
+		field("region", common_models.FieldTypeText).
+		field("amount", common_models.FieldTypeNumber).
+		create()
+	ctx := e.ctx()
+
+	id := e.record("leads", e.admin, map[string]any{"name": "Acme", "region": "west", "amount": 1200})
+
+	got, err := e.RecordSvc.GetRecord(ctx, "leads", id, e.admin.ID)
+	if err != nil {
+		t.Fatalf("GetRecord: %v", err)
+	}
+	if got["name"] != "Acme" || got["amount"] != float64(1200) {
+		t.Fatalf("GetRecord = %v", got)
+	}
+
+	if err := e.RecordSvc.UpdateRecord(ctx, "leads", id, map[string]any{"amount": 1500}, e.admin.ID); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	got, err = e.RecordSvc.GetRecord(ctx, "leads", id, e.admin.ID)
+	if err != nil {
+		t.Fatalf("GetRecord after update: %v", err)
+	}
+	if got["amount"] != float64(1500) || got["name"] != "Acme" {
+		t.Fatalf("update did not apply: %v", got)
+	}
+
+	e.record("leads", e.admin, map[string]any{"name": "Globex", "region": "east"})
+	records, total, err := e.RecordSvc.ListRecords(ctx, "leads", []common_models.Filter{{Field: "region", Operator: "eq", Value: "west"}}, 1, 10, "", "", e.admin.ID)
+	if err != nil {
+		t.Fatalf("ListRecords: %v", err)
+	}
+	if total != 1 || len(records) != 1 || records[0]["name"] != "Acme" {
+		t.Fatalf("ListRecords region=west = %d %v", total, records)
+	}
+
+	logs, err := e.Audit.ListLogs(ctx, map[string]any{"module": "leads", "record_id": id}, 1, 10)
+	if err != nil {
+		t.Fatalf("ListLogs: %v", err)
+	}
+	actions := map[common_models.AuditAction]bool{}
+	for _, l := range logs {
+		actions[l.Action] = true
+		if l.ActorID != e.admin.ID.Hex() {
+			t.Errorf("audit actor = %s, want %s", l.ActorID, e.admin.ID.Hex())
+		}
+	}
+	if !actions[common_models.AuditActionCreate] || !actions[common_models.AuditActionUpdate] {
+		t.Fatalf("audit trail of %s = %v", id, logs)
+	}
+
+	if err := e.RecordSvc.DeleteRecord(ctx, "leads", id, e.admin.ID); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+	if _, err := e.RecordSvc.GetRecord(ctx, "leads", id, e.admin.ID); apperr.CodeOf(err) != apperr.CodeNotFound {
+		t.Fatalf("GetRecord after delete: err = %v, want not found", err)
+	}
+}
+
+func TestRecordValidation(t *testing.T) {
+	e := newEnv(t)
+	e.module("leads").
+		required("name", common_models.FieldTypeText).
+		field("amount", common_models.FieldTypeNumber).
+		create()
+
+	_, err := e.RecordSvc.CreateRecord(e.ctx(), "leads", map[string]any{"amount": "lots"}, e.admin.ID)
+	fieldErrs, ok := validation.As(err)
+	if !ok {
+		t.Fatalf("CreateRecord err = %v, want field errors", err)
+	}
+	codes := map[string]string{}
+	for _, fe := range fieldErrs {
+		codes[fe.Field] = fe.Code
+	}
+	if codes["name"] != validation.CodeRequired || codes["amount"] != validation.CodeInvalid {
+		t.Fatalf("field errors = %v", fieldErrs)
+	}
+
+	_, err = e.RecordSvc.CreateRecord(e.ctx(), "opportunities", map[string]any{"name": "x"}, e.admin.ID)
+	if !errors.Is(err, apperr.ErrNotFound) {
+		t.Fatalf("CreateRecord on a missing module: err = %v, want not found", err)
+	}
+}
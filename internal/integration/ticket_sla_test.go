@@ -0,0 +1,71 @@
+package integration
+
+import (
+	"testing"
+	"time"
+
+	"go-crm/internal/features/ticket"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestTicketSLALifecycle(t *testing.T) {
+	e := newEnv(t)
+	ctx := e.ctx()
+
+	policy := &ticket.SLAPolicy{
+		Name:           "High priority",
+		Priority:       ticket.TicketPriorityHigh,
+		ResponseTime:   60,
+		ResolutionTime: 480,
+		IsActive:       true,
+	}
+	if err := e.SLA.CreatePolicy(ctx, policy); err != nil {
+		t.Fatalf("CreatePolicy: %v", err)
+	}
+
+	before := time.Now()
+	tk := &ticket.Ticket{Subject: "Printer on fire", Description: "Smoke everywhere", Priority: ticket.TicketPriorityHigh, Channel: ticket.TicketChannelEmail}
+	if err := e.Tickets.CreateTicket(ctx, tk, e.admin.ID); err != nil {
+		t.Fatalf("CreateTicket: %v", err)
+	}
+	if tk.SLAPolicyID == nil || tk.ResponseDueDate == nil || tk.DueDate == nil {
+		t.Fatalf("SLA not applied: policy %v response %v resolution %v", tk.SLAPolicyID, tk.ResponseDueDate, tk.DueDate)
+	}
+	if d := tk.ResponseDueDate.Sub(before); d < time.Hour || d > time.Hour+time.Minute {
+		t.Errorf("response due in %s, want 1h", d)
+	}
+	if d := tk.DueDate.Sub(before); d < 8*time.Hour || d > 8*time.Hour+time.Minute {
+		t.Errorf("resolution due in %s, want 8h", d)
+	}
+
+	breached, err := e.Tickets.CheckSLABreach(ctx, tk.ID.Hex())
+	if err != nil || breached {
+		t.Fatalf("new ticket breached = %v, %v", breached, err)
+	}
+
+	// Let the resolution deadline pass
+	if err := e.TicketRepo.Update(ctx, tk.ID, bson.M{"due_date": time.Now().Add(-time.Minute), "first_response_at": time.Now()}); err != nil {
+		t.Fatalf("move due date: %v", err)
+	}
+	breached, err = e.Tickets.CheckSLABreach(ctx, tk.ID.Hex())
+	if err != nil || !breached {
+		t.Fatalf("overdue ticket breached = %v, %v", breached, err)
+	}
+	overdue, err := e.Tickets.GetOverdueSLATickets(ctx)
+	if err != nil {
+		t.Fatalf("GetOverdueSLATickets: %v", err)
+	}
+	if len(overdue) != 1 || overdue[0].ID != tk.ID {
+		t.Fatalf("overdue tickets = %v", overdue)
+	}
+
+	// Resolving stops the clock
+	if err := e.Tickets.UpdateStatus(ctx, tk.ID.Hex(), ticket.TicketStatusResolved, "Extinguished", e.admin.ID); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+	breached, err = e.Tickets.CheckSLABreach(ctx, tk.ID.Hex())
+	if err != nil || breached {
+		t.Fatalf("resolved ticket breached = %v, %v", breached, err)
+	}
+}
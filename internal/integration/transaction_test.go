@@ -0,0 +1,43 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-crm/internal/features/sandbox"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Change sets rely on transactions, which mongotest's replica set supports
+func TestSandboxTransactions(t *testing.T) {
+	e := newEnv(t)
+	repo := sandbox.NewSandboxRepository(e.db)
+	coll := e.db.DB.Collection("tx_probe")
+	ctx := context.Background()
+
+	stop := errors.New("stop")
+	err := repo.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := repo.InsertDocument(ctx, "tx_probe", bson.M{"n": 1}); err != nil {
+			return err
+		}
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("WithTransaction = %v, want the error fn returned", err)
+	}
+	if n, err := coll.CountDocuments(ctx, bson.M{}); err != nil || n != 0 {
+		t.Fatalf("rolled back transaction left %d documents (%v)", n, err)
+	}
+
+	err = repo.WithTransaction(ctx, func(ctx context.Context) error {
+		return repo.InsertDocument(ctx, "tx_probe", bson.M{"n": 2})
+	})
+	if err != nil {
+		t.Fatalf("WithTransaction: %v", err)
+	}
+	if n, err := coll.CountDocuments(ctx, bson.M{"n": 2}); err != nil || n != 1 {
+		t.Fatalf("committed transaction left %d documents (%v), want 1", n, err)
+	}
+}
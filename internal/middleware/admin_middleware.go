@@ -1,13 +1,18 @@
 package middleware
 
 import (
-	"strings"
+	"context"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// AdminChecker tells full admin roles apart; the role service implements it
+type AdminChecker interface {
+	IsAdmin(roleNames []string) bool
+}
+
 // AdminMiddleware checks if the user has admin role
-func AdminMiddleware() fiber.Handler {
+func AdminMiddleware(checker AdminChecker) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Get user from context (set by AuthMiddleware)
 		userID := c.Locals("user_id")
@@ -32,16 +37,7 @@ func AdminMiddleware() fiber.Handler {
 			})
 		}
 
-		// Check if user has admin role
-		isAdmin := false
-		for _, role := range roles {
-			if strings.ToLower(role) == "admin" {
-				isAdmin = true
-				break
-			}
-		}
-
-		if !isAdmin {
+		if !checker.IsAdmin(roles) {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 				"error": "Access denied: Admin role required",
 			})
@@ -50,3 +46,41 @@ func AdminMiddleware() fiber.Handler {
 		return c.Next()
 	}
 }
+
+// AdminScopeChecker resolves delegated admin scopes for a set of roles
+type AdminScopeChecker interface {
+	CheckAdminScope(ctx context.Context, roleNames []string, scope string, moduleName string) (bool, error)
+}
+
+// HasAdminScope reports whether the caller is an admin or holds the delegated
+// scope for the module; controllers use it when the module is in the body
+func HasAdminScope(c *fiber.Ctx, checker AdminScopeChecker, scope string, moduleName string) bool {
+	roles, _ := c.Locals("roles").([]string)
+	if len(roles) == 0 || checker == nil {
+		return false
+	}
+	allowed, err := checker.CheckAdminScope(c.UserContext(), roles, scope, moduleName)
+	return err == nil && allowed
+}
+
+// RequireAdminScope limits a route to admins and roles delegated the scope.
+// The module is read from the named route parameter; an empty param means
+// the action is not tied to one module.
+func RequireAdminScope(checker AdminScopeChecker, scope string, param string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		moduleName := ""
+		if param != "" {
+			moduleName = c.Params(param)
+		}
+		if !HasAdminScope(c, checker, scope, moduleName) {
+			msg := "Access denied: " + scope + " admin scope required"
+			if moduleName != "" {
+				msg += " for " + moduleName
+			}
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": msg,
+			})
+		}
+		return c.Next()
+	}
+}
@@ -1,8 +1,6 @@
 package middleware
 
 import (
-	"context"
-	"go-crm/internal/common/models"
 	"go-crm/pkg/utils"
 
 	"github.com/gofiber/fiber/v2"
@@ -20,15 +18,7 @@ func AuthMiddleware(skipAuth bool) fiber.Handler {
 				Roles:    []string{"admin"},
 				Groups:   []string{"admins", "managers"}, // For ABAC testing
 			}
-			c.Locals(utils.UserClaimsKey, dummyClaims)
-			c.Locals("user_id", dummyClaims.UserID)
-			c.Locals("tenant_id", dummyClaims.TenantID)
-			c.Locals("roles", dummyClaims.Roles)
-			c.Locals("groups", dummyClaims.Groups)
-
-			// Set organization context
-			ctx := context.WithValue(c.UserContext(), models.TenantIDKey, dummyClaims.TenantID)
-			c.SetUserContext(ctx)
+			bindSession(c, dummyClaims)
 
 			return c.Next()
 		}
@@ -55,15 +45,30 @@ func AuthMiddleware(skipAuth bool) fiber.Handler {
 			})
 		}
 
-		// Store claims and also set userID and roles for other middleware
-		c.Locals(utils.UserClaimsKey, claims)
-		c.Locals("user_id", claims.UserID)
-		c.Locals("tenant_id", claims.TenantID)
-		c.Locals("roles", claims.Roles)
-		c.Locals("groups", claims.Groups)
+		// Add-in tokens live long in client storage, so they only open the add-in endpoints
+		if claims.Scope == utils.ScopeAddIn && !isAddInPath(c) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Token is limited to add-in endpoints",
+				"code":  "token_scope",
+			})
+		}
+
+		// Store claims and the request context for other middleware and handlers
+		bindSession(c, claims)
 
-		ctx := context.WithValue(c.UserContext(), models.TenantIDKey, claims.TenantID)
-		c.SetUserContext(ctx)
+		if !impersonationActive(c, claims) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Impersonation session has ended",
+			})
+		}
+
+		// Roles or permissions changed since the token was issued
+		if !permissionsCurrent(c, claims) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Permissions have changed, refresh the token",
+				"code":  "permissions_changed",
+			})
+		}
 
 		return c.Next()
 	}
@@ -1,16 +1,70 @@
 package middleware
 
 import (
+	"log"
+	"slices"
+	"strings"
+
+	"go-crm/internal/config"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 )
 
-// CORSMiddleware returns Fiber's built-in CORS middleware with custom config
-func CORSMiddleware() fiber.Handler {
+// AddInPath prefixes the email add-in endpoints, which have a CORS policy
+// of their own and are the only routes add-in tokens may call
+const AddInPath = "/api/addin"
+
+// CORSMiddleware returns Fiber's built-in CORS middleware configured from
+// the environment. Origins such as https://*.example.com allow every
+// subdomain, so tenant frontends need no entry of their own.
+func CORSMiddleware(cfg *config.Config) fiber.Handler {
+	var refuseAll func(string) bool
+	if len(cfg.CORSAllowOrigins) == 0 {
+		// Without origins Fiber would allow every one
+		log.Println("CORS: no allowed origins configured, cross-origin requests will be refused")
+		refuseAll = func(string) bool { return false }
+	}
+	credentials := !slices.Contains(cfg.CORSAllowOrigins, "*")
+	if !credentials {
+		// Browsers refuse credentials for a wildcard origin and Fiber panics on it
+		log.Println("CORS: allowing every origin, credentials disabled")
+	}
 	return cors.New(cors.Config{
-		AllowOrigins:     "http://localhost:3000, http://localhost:3001, http://localhost:3002, http://localhost:8000",
-		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS, PATCH",
-		AllowHeaders:     "Content-Type,Authorization,X-Requested-With,X-Rich-Product",
-		AllowCredentials: true,
+		Next:             isAddInPath,
+		AllowOrigins:     strings.Join(cfg.CORSAllowOrigins, ","),
+		AllowOriginsFunc: refuseAll,
+		AllowMethods:     strings.Join(cfg.CORSAllowMethods, ","),
+		AllowHeaders:     strings.Join(cfg.CORSAllowHeaders, ","),
+		ExposeHeaders:    strings.Join(cfg.CORSExposeHeaders, ","),
+		AllowCredentials: credentials,
 	})
 }
+
+// AddInCORSMiddleware admits the add-in origins on top of the configured ones.
+// Add-ins send bearer tokens, never cookies, so credentials stay off, and
+// preflights are cached longer since task panes reload often.
+func AddInCORSMiddleware(cfg *config.Config) fiber.Handler {
+	origins := append(slices.Clone(cfg.CORSAllowOrigins), cfg.AddInAllowOrigins...)
+	var refuseAll func(string) bool
+	if len(origins) == 0 {
+		refuseAll = func(string) bool { return false }
+	}
+	allowOrigins := strings.Join(origins, ",")
+	if slices.Contains(origins, "*") {
+		allowOrigins = "*"
+	}
+	return cors.New(cors.Config{
+		AllowOrigins:     allowOrigins,
+		AllowOriginsFunc: refuseAll,
+		AllowMethods:     "GET,POST,OPTIONS",
+		AllowHeaders:     "Content-Type,Authorization,X-Requested-With",
+		ExposeHeaders:    strings.Join(cfg.CORSExposeHeaders, ","),
+		AllowCredentials: false,
+		MaxAge:           3600,
+	})
+}
+
+func isAddInPath(c *fiber.Ctx) bool {
+	return strings.HasPrefix(c.Path(), AddInPath)
+}
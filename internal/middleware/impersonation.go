@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+
+	"go-crm/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Response headers that flag requests made with an impersonation token
+const (
+	HeaderImpersonatedBy       = "X-Impersonated-By"
+	HeaderImpersonationSession = "X-Impersonation-Session"
+)
+
+// ImpersonationValidator reports whether an impersonation session is still
+// running; stopped sessions invalidate their tokens before they expire
+type ImpersonationValidator func(ctx context.Context, sessionID string) bool
+
+var (
+	impersonationMu        sync.RWMutex
+	impersonationValidator ImpersonationValidator
+)
+
+// SetImpersonationValidator installs the session check AuthMiddleware runs
+// for impersonation tokens
+func SetImpersonationValidator(fn ImpersonationValidator) {
+	impersonationMu.Lock()
+	defer impersonationMu.Unlock()
+	impersonationValidator = fn
+}
+
+// impersonationActive flags the response of impersonation tokens and reports
+// false when their session has ended. Ordinary tokens always pass.
+func impersonationActive(c *fiber.Ctx, claims *utils.UserClaims) bool {
+	if claims.ImpersonationID == "" {
+		return true
+	}
+
+	impersonationMu.RLock()
+	validate := impersonationValidator
+	impersonationMu.RUnlock()
+	if validate != nil && !validate(c.UserContext(), claims.ImpersonationID) {
+		return false
+	}
+
+	c.Set(HeaderImpersonatedBy, claims.ImpersonatorID)
+	c.Set(HeaderImpersonationSession, claims.ImpersonationID)
+	c.Locals("impersonator_id", claims.ImpersonatorID)
+	return true
+}
@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"context"
+	"encoding/json"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -44,3 +46,103 @@ func PermissionMiddleware(roleService RoleService, moduleName string, permission
 func RequirePermission(roleService RoleService, moduleName string, permission string) fiber.Handler {
 	return PermissionMiddleware(roleService, moduleName, permission)
 }
+
+// recordActions maps HTTP verbs on the record API to module permission actions
+var recordActions = map[string]string{
+	fiber.MethodGet:    "read",
+	fiber.MethodHead:   "read",
+	fiber.MethodPost:   "create",
+	fiber.MethodPut:    "update",
+	fiber.MethodPatch:  "update",
+	fiber.MethodDelete: "delete",
+}
+
+// ModuleFrom names the modules a request acts on
+type ModuleFrom func(c *fiber.Ctx) []string
+
+// ModuleParam reads the module from a route parameter
+func ModuleParam(param string) ModuleFrom {
+	return func(c *fiber.Ctx) []string {
+		return nonEmpty([]string{c.Params(param)})
+	}
+}
+
+// ModuleQuery reads a comma-separated list of modules from a query parameter
+func ModuleQuery(key string) ModuleFrom {
+	return func(c *fiber.Ctx) []string {
+		return nonEmpty(strings.Split(c.Query(key), ","))
+	}
+}
+
+// ModuleBody reads the module from a field of the JSON body
+func ModuleBody(field string) ModuleFrom {
+	return func(c *fiber.Ctx) []string {
+		var body map[string]any
+		if err := json.Unmarshal(c.Body(), &body); err != nil {
+			return nil
+		}
+		name, _ := body[field].(string)
+		return nonEmpty([]string{name})
+	}
+}
+
+func nonEmpty(names []string) []string {
+	out := names[:0]
+	for _, n := range names {
+		if n = strings.TrimSpace(n); n != "" {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// RequireModulePermission checks the module named by the route parameter
+// against the create/read/update/delete permission implied by the HTTP verb
+func RequireModulePermission(roleService RoleService, param string) fiber.Handler {
+	return RequireModuleAction(roleService, ModuleParam(param), "")
+}
+
+// RequireModuleAction checks every module named by from against action, or
+// the action implied by the HTTP verb when empty. Requests naming no module
+// are rejected rather than let through unchecked.
+func RequireModuleAction(roleService RoleService, from ModuleFrom, action string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		required := action
+		if required == "" {
+			verbAction, ok := recordActions[c.Method()]
+			if !ok {
+				return c.Status(fiber.StatusMethodNotAllowed).JSON(fiber.Map{
+					"error": "Method not allowed on module records",
+				})
+			}
+			required = verbAction
+		}
+
+		modules := from(c)
+		if len(modules) == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "module is required",
+			})
+		}
+
+		roles, _ := c.Locals("roles").([]string)
+		if len(roles) == 0 {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Access denied: No roles assigned",
+			})
+		}
+
+		for _, moduleName := range modules {
+			hasPermission, err := roleService.CheckModulePermission(c.UserContext(), roles, moduleName, required)
+			if err != nil || !hasPermission {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error":      "Access denied: missing " + required + " permission on " + moduleName,
+					"module":     moduleName,
+					"permission": required,
+				})
+			}
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// grantRoleService allows the listed module/action pairs and records checks
+type grantRoleService struct {
+	allowed map[string]bool
+	checked []string
+}
+
+func (s *grantRoleService) CheckModulePermission(ctx context.Context, roleNames []string, moduleName string, permission string) (bool, error) {
+	s.checked = append(s.checked, moduleName+":"+permission)
+	return s.allowed[moduleName+":"+permission], nil
+}
+
+func withRoles(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals("roles", roles)
+		return c.Next()
+	}
+}
+
+func ok(c *fiber.Ctx) error {
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func TestRequireModulePermission(t *testing.T) {
+	tests := []struct {
+		method     string
+		path       string
+		allowed    string
+		wantStatus int
+		wantCheck  string
+	}{
+		{method: fiber.MethodGet, path: "/modules/deals/records", allowed: "deals:read", wantStatus: fiber.StatusNoContent, wantCheck: "deals:read"},
+		{method: fiber.MethodHead, path: "/modules/deals/records", allowed: "deals:read", wantStatus: fiber.StatusNoContent, wantCheck: "deals:read"},
+		{method: fiber.MethodPost, path: "/modules/deals/records", allowed: "deals:create", wantStatus: fiber.StatusNoContent, wantCheck: "deals:create"},
+		{method: fiber.MethodPut, path: "/modules/deals/records", allowed: "deals:update", wantStatus: fiber.StatusNoContent, wantCheck: "deals:update"},
+		{method: fiber.MethodPatch, path: "/modules/deals/records", allowed: "deals:update", wantStatus: fiber.StatusNoContent, wantCheck: "deals:update"},
+		{method: fiber.MethodDelete, path: "/modules/deals/records", allowed: "deals:delete", wantStatus: fiber.StatusNoContent, wantCheck: "deals:delete"},
+		{method: fiber.MethodDelete, path: "/modules/deals/records", allowed: "deals:update", wantStatus: fiber.StatusForbidden, wantCheck: "deals:delete"},
+		{method: fiber.MethodPost, path: "/modules/deals/records", allowed: "leads:create", wantStatus: fiber.StatusForbidden, wantCheck: "deals:create"},
+		{method: fiber.MethodOptions, path: "/modules/deals/records", allowed: "deals:read", wantStatus: fiber.StatusMethodNotAllowed},
+		{method: fiber.MethodGet, path: "/records", allowed: "deals:read", wantStatus: fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path+" "+tt.allowed, func(t *testing.T) {
+			roles := &grantRoleService{allowed: map[string]bool{tt.allowed: true}}
+			app := fiber.New()
+			crud := RequireModulePermission(roles, "name")
+			app.All("/modules/:name/records", withRoles("sales"), crud, ok)
+			// A route without the parameter must not be let through unchecked
+			app.All("/records", withRoles("sales"), crud, ok)
+
+			resp, err := app.Test(httptest.NewRequest(tt.method, tt.path, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := strings.Join(roles.checked, ","); got != tt.wantCheck {
+				t.Errorf("checked = %q, want %q", got, tt.wantCheck)
+			}
+		})
+	}
+}
+
+func TestRequireModulePermissionWithoutRoles(t *testing.T) {
+	roles := &grantRoleService{allowed: map[string]bool{"deals:read": true}}
+	app := fiber.New()
+	app.Get("/modules/:name/records", RequireModulePermission(roles, "name"), ok)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/modules/deals/records", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("status = %d, want %d", resp.StatusCode, fiber.StatusForbidden)
+	}
+}
+
+func TestRequireModuleAction(t *testing.T) {
+	tests := []struct {
+		name       string
+		from       ModuleFrom
+		target     string
+		body       string
+		allowed    []string
+		wantStatus int
+		wantCheck  string
+	}{
+		{name: "body resource", from: ModuleBody("resource"), target: "/query", body: `{"resource":"deals","action":"update"}`, allowed: []string{"deals:read"}, wantStatus: fiber.StatusNoContent, wantCheck: "deals:read"},
+		{name: "body resource denied", from: ModuleBody("resource"), target: "/query", body: `{"resource":"deals"}`, wantStatus: fiber.StatusForbidden, wantCheck: "deals:read"},
+		{name: "body without resource", from: ModuleBody("resource"), target: "/query", body: `{"action":"read"}`, wantStatus: fiber.StatusBadRequest},
+		{name: "invalid body", from: ModuleBody("resource"), target: "/query", body: `{`, wantStatus: fiber.StatusBadRequest},
+		{name: "query modules", from: ModuleQuery("module"), target: "/query?module=contacts,%20leads", allowed: []string{"contacts:read", "leads:read"}, wantStatus: fiber.StatusNoContent, wantCheck: "contacts:read,leads:read"},
+		{name: "query module denied", from: ModuleQuery("module"), target: "/query?module=contacts,leads", allowed: []string{"contacts:read"}, wantStatus: fiber.StatusForbidden, wantCheck: "contacts:read,leads:read"},
+		{name: "query without module", from: ModuleQuery("module"), target: "/query?module=,", wantStatus: fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed := map[string]bool{}
+			for _, a := range tt.allowed {
+				allowed[a] = true
+			}
+			roles := &grantRoleService{allowed: allowed}
+			app := fiber.New()
+			app.Post("/query", withRoles("sales"), RequireModuleAction(roles, tt.from, "read"), ok)
+
+			req := httptest.NewRequest(fiber.MethodPost, tt.target, strings.NewReader(tt.body))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := strings.Join(roles.checked, ","); got != tt.wantCheck {
+				t.Errorf("checked = %q, want %q", got, tt.wantCheck)
+			}
+		})
+	}
+}
+
+func TestChainedModuleActions(t *testing.T) {
+	// As on the upsert route: PUT checks update, the chained check adds create
+	tests := []struct {
+		allowed    []string
+		wantStatus int
+	}{
+		{allowed: []string{"deals:update", "deals:create"}, wantStatus: fiber.StatusNoContent},
+		{allowed: []string{"deals:update"}, wantStatus: fiber.StatusForbidden},
+		{allowed: []string{"deals:create"}, wantStatus: fiber.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.allowed, ","), func(t *testing.T) {
+			allowed := map[string]bool{}
+			for _, a := range tt.allowed {
+				allowed[a] = true
+			}
+			roles := &grantRoleService{allowed: allowed}
+			app := fiber.New()
+			app.Put("/modules/:name/records/upsert", withRoles("sales"), RequireModulePermission(roles, "name"), RequireModuleAction(roles, ModuleParam("name"), "create"), ok)
+
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodPut, "/modules/deals/records/upsert", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+
+	"go-crm/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// PermissionsVersionSource returns the current permissions version of a user
+// and their roles
+type PermissionsVersionSource func(ctx context.Context, subjectIDs ...string) int64
+
+var (
+	permissionsVersionMu  sync.RWMutex
+	permissionsVersionSrc PermissionsVersionSource
+)
+
+// SetPermissionsVersionSource installs the version check AuthMiddleware runs
+// so tokens issued before a role or permission change are refreshed
+func SetPermissionsVersionSource(fn PermissionsVersionSource) {
+	permissionsVersionMu.Lock()
+	defer permissionsVersionMu.Unlock()
+	permissionsVersionSrc = fn
+}
+
+// permissionsCurrent reports false when the token predates a change to its
+// user or their roles
+func permissionsCurrent(c *fiber.Ctx, claims *utils.UserClaims) bool {
+	permissionsVersionMu.RLock()
+	current := permissionsVersionSrc
+	permissionsVersionMu.RUnlock()
+	if current == nil {
+		return true
+	}
+	return claims.PermissionsVersion >= current(c.UserContext(), append([]string{claims.UserID}, claims.RoleIDs...)...)
+}
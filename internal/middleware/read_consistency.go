@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"go-crm/internal/database"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ReadConsistencyMiddleware keeps the reads of write requests on the primary,
+// so validations and lookups made while writing see the latest data. GET
+// requests can ask for the same with "X-Read-Consistency: strong", e.g. a
+// list reloaded right after a save.
+func ReadConsistencyMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if (c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead) || c.Get("X-Read-Consistency") == "strong" {
+			c.SetUserContext(database.WithReadMode(c.UserContext(), database.ReadPrimary))
+		}
+		return c.Next()
+	}
+}
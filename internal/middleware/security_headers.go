@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"strconv"
+	"strings"
+
+	"go-crm/internal/config"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SecurityHeadersMiddleware sets the standard security headers on every
+// response. The portal gets its own content security policy; the Swagger UI
+// runs inline scripts and gets none.
+func SecurityHeadersMiddleware(cfg *config.Config) fiber.Handler {
+	hsts := ""
+	if cfg.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(cfg.HSTSMaxAge) + "; includeSubDomains"
+	}
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+		c.Set(fiber.HeaderXFrameOptions, "DENY")
+		c.Set(fiber.HeaderReferrerPolicy, "strict-origin-when-cross-origin")
+		if hsts != "" && c.Protocol() == "https" {
+			c.Set(fiber.HeaderStrictTransportSecurity, hsts)
+		}
+
+		path := c.Path()
+		csp := cfg.ContentSecurityPolicy
+		switch {
+		case cfg.PortalPath != "" && strings.HasPrefix(path, cfg.PortalPath):
+			csp = cfg.PortalCSP
+			// frame-ancestors in the portal policy decides who may embed it
+			c.Response().Header.Del(fiber.HeaderXFrameOptions)
+		case strings.HasPrefix(path, "/swagger"):
+			csp = ""
+		}
+		if csp != "" {
+			c.Set(fiber.HeaderContentSecurityPolicy, csp)
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,145 @@
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Kind separates the defaults every environment needs from demo data that
+// only development and sales environments load
+type Kind string
+
+const (
+	KindSystem Kind = "system"
+	KindDemo   Kind = "demo"
+)
+
+// Step is one versioned migration. Steps run in Version order and are
+// recorded once applied. Repeatable steps sync embedded data and run again
+// whenever that data changes, so every step must be safe to re-run.
+type Step struct {
+	Version    int
+	Name       string
+	Kind       Kind
+	Repeatable bool
+	Data       []byte // Hashed for repeatable steps
+	Run        func(ctx context.Context, opts Options) error
+}
+
+func (s Step) checksum() string {
+	sum := sha256.Sum256(s.Data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Record is a step's entry in the migrations collection
+type Record struct {
+	Version    int       `json:"version" bson:"_id"`
+	Name       string    `json:"name" bson:"name"`
+	Kind       Kind      `json:"kind" bson:"kind"`
+	Checksum   string    `json:"checksum,omitempty" bson:"checksum,omitempty"`
+	AppliedAt  time.Time `json:"applied_at" bson:"applied_at"`
+	DurationMs int64     `json:"duration_ms" bson:"duration_ms"`
+}
+
+type Options struct {
+	Demo bool // Also apply demo steps
+	// AdminPassword is the first admin's password; the admin step fails
+	// without it, so no install starts with a known credential
+	AdminPassword string
+}
+
+// Status is a step and whether it still has to run
+type Status struct {
+	Step    Step
+	Applied *Record
+	Pending bool
+}
+
+type Runner struct {
+	Repo   MigrationRepository
+	Steps  []Step
+	Logger *zap.Logger
+}
+
+func NewRunner(repo MigrationRepository, seeder *Seeder, logger *zap.Logger) *Runner {
+	return &Runner{
+		Repo:   repo,
+		Steps:  seeder.Steps(),
+		Logger: logger,
+	}
+}
+
+// Status lists the steps that apply under opts in version order
+func (r *Runner) Status(ctx context.Context, opts Options) ([]Status, error) {
+	steps := append([]Step{}, r.Steps...)
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].Version < steps[j].Version })
+	for i := 1; i < len(steps); i++ {
+		if steps[i].Version == steps[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d", steps[i].Version)
+		}
+	}
+
+	applied, err := r.Repo.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []Status
+	for _, step := range steps {
+		if step.Kind == KindDemo && !opts.Demo {
+			continue
+		}
+		status := Status{Step: step}
+		if rec, ok := applied[step.Version]; ok {
+			status.Applied = &rec
+			status.Pending = step.Repeatable && rec.Checksum != step.checksum()
+		} else {
+			status.Pending = true
+		}
+		out = append(out, status)
+	}
+	return out, nil
+}
+
+// Run applies pending steps in order and stops at the first failure; the
+// failed step and everything after it run again next time
+func (r *Runner) Run(ctx context.Context, opts Options) ([]Record, error) {
+	statuses, err := r.Status(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := []Record{}
+	for _, s := range statuses {
+		if !s.Pending {
+			continue
+		}
+		r.Logger.Info("Applying migration", zap.Int("version", s.Step.Version), zap.String("name", s.Step.Name))
+		started := time.Now()
+		if err := s.Step.Run(ctx, opts); err != nil {
+			return applied, fmt.Errorf("migration %d (%s): %w", s.Step.Version, s.Step.Name, err)
+		}
+
+		rec := Record{
+			Version:    s.Step.Version,
+			Name:       s.Step.Name,
+			Kind:       s.Step.Kind,
+			AppliedAt:  time.Now(),
+			DurationMs: time.Since(started).Milliseconds(),
+		}
+		if s.Step.Repeatable {
+			rec.Checksum = s.Step.checksum()
+		}
+		if err := r.Repo.Save(ctx, rec); err != nil {
+			return applied, err
+		}
+		applied = append(applied, rec)
+	}
+	return applied, nil
+}
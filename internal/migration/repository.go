@@ -0,0 +1,50 @@
+package migration
+
+import (
+	"context"
+
+	"go-crm/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MigrationRepository interface {
+	// Applied returns the recorded steps keyed by version
+	Applied(ctx context.Context) (map[int]Record, error)
+	Save(ctx context.Context, rec Record) error
+}
+
+type MigrationRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewMigrationRepository(db *database.MongodbDB) MigrationRepository {
+	return &MigrationRepositoryImpl{
+		collection: db.DB.Collection("migrations"),
+	}
+}
+
+func (r *MigrationRepositoryImpl) Applied(ctx context.Context) (map[int]Record, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []Record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	applied := make(map[int]Record, len(records))
+	for _, rec := range records {
+		applied[rec.Version] = rec
+	}
+	return applied, nil
+}
+
+func (r *MigrationRepositoryImpl) Save(ctx context.Context, rec Record) error {
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": rec.Version}, rec, options.Replace().SetUpsert(true))
+	return err
+}
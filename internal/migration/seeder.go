@@ -0,0 +1,402 @@
+package migration
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	common_models "go-crm/internal/common/models"
+	"go-crm/internal/features/module"
+	"go-crm/internal/features/organization"
+	"go-crm/internal/features/permission"
+	"go-crm/internal/features/resource"
+	"go-crm/internal/features/role"
+	"go-crm/internal/features/user"
+	"go-crm/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+//go:embed data/*.json
+var dataFS embed.FS
+
+func mustData(name string) []byte {
+	b, err := dataFS.ReadFile("data/" + name)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+const defaultOrganizationName = "Default Organization"
+
+// defaultOrganizationID is fixed so development databases agree on it
+var defaultOrganizationID, _ = primitive.ObjectIDFromHex("678e9a1b2c3d4e5f6a7b8c9e")
+
+// Seeder holds the steps that load system defaults and demo data into the
+// default organization
+type Seeder struct {
+	RoleRepo        role.RoleRepository
+	UserRepo        user.UserRepository
+	ModuleRepo      module.ModuleRepository
+	OrgRepo         organization.OrganizationRepository
+	ResourceService resource.ResourceService
+	PermissionRepo  permission.PermissionRepository
+	Logger          *zap.Logger
+}
+
+func NewSeeder(
+	roleRepo role.RoleRepository,
+	userRepo user.UserRepository,
+	moduleRepo module.ModuleRepository,
+	orgRepo organization.OrganizationRepository,
+	resourceService resource.ResourceService,
+	permissionRepo permission.PermissionRepository,
+	logger *zap.Logger,
+) *Seeder {
+	return &Seeder{
+		RoleRepo:        roleRepo,
+		UserRepo:        userRepo,
+		ModuleRepo:      moduleRepo,
+		OrgRepo:         orgRepo,
+		ResourceService: resourceService,
+		PermissionRepo:  permissionRepo,
+		Logger:          logger,
+	}
+}
+
+// Steps lists every migration. Add new steps with the next free version and
+// never renumber applied ones. Demo steps use versions from 1000.
+func (s *Seeder) Steps() []Step {
+	return []Step{
+		{Version: 1, Name: "default_organization", Kind: KindSystem, Run: s.seedOrganization},
+		{Version: 2, Name: "resources", Kind: KindSystem, Repeatable: true, Data: mustData("resources.json"), Run: s.seedResources},
+		{Version: 3, Name: "roles", Kind: KindSystem, Repeatable: true, Data: mustData("roles.json"), Run: s.seedRoles},
+		{Version: 4, Name: "permissions", Kind: KindSystem, Repeatable: true, Data: mustData("permissions.json"), Run: s.seedPermissions},
+		{Version: 5, Name: "modules", Kind: KindSystem, Repeatable: true, Data: mustData("modules.json"), Run: s.seedModules},
+		{Version: 6, Name: "admin_user", Kind: KindSystem, Data: mustData("admin.json"), Run: s.seedAdmin},
+		{Version: 1000, Name: "demo_users", Kind: KindDemo, Repeatable: true, Data: mustData("users.json"), Run: s.seedDemoUsers},
+	}
+}
+
+// tenantContext scopes repositories to the default organization
+func (s *Seeder) tenantContext(ctx context.Context) (context.Context, error) {
+	org, err := s.OrgRepo.FindByName(ctx, defaultOrganizationName)
+	if err != nil {
+		return nil, fmt.Errorf("default organization missing: %w", err)
+	}
+	return context.WithValue(ctx, common_models.TenantIDKey, org.ID.Hex()), nil
+}
+
+func (s *Seeder) seedOrganization(ctx context.Context, _ Options) error {
+	if _, err := s.OrgRepo.FindByName(ctx, defaultOrganizationName); err == nil {
+		s.Logger.Info("Organization exists, skipping", zap.String("organization", defaultOrganizationName))
+		return nil
+	}
+
+	org := common_models.Organization{
+		ID:        defaultOrganizationID,
+		Name:      defaultOrganizationName,
+		Slug:      utils.Slugify(defaultOrganizationName),
+		OwnerID:   primitive.NilObjectID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	return s.OrgRepo.Create(ctx, &org)
+}
+
+func (s *Seeder) seedResources(ctx context.Context, _ Options) error {
+	ctx, err := s.tenantContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var resources []resource.Resource
+	if err := json.Unmarshal(mustData("resources.json"), &resources); err != nil {
+		return err
+	}
+	if err := s.ResourceService.SyncResources(ctx, resources); err != nil {
+		return err
+	}
+	s.Logger.Info("Resources synced", zap.Int("count", len(resources)))
+	return nil
+}
+
+func (s *Seeder) seedRoles(ctx context.Context, _ Options) error {
+	ctx, err := s.tenantContext(ctx)
+	if err != nil {
+		return err
+	}
+	tenantID, _ := primitive.ObjectIDFromHex(ctx.Value(common_models.TenantIDKey).(string))
+
+	var roles []role.Role
+	if err := json.Unmarshal(mustData("roles.json"), &roles); err != nil {
+		return err
+	}
+
+	for _, r := range roles {
+		r.TenantID = tenantID
+		existing, err := s.RoleRepo.FindByName(ctx, r.Name)
+		if err == nil {
+			existing.Description = r.Description
+			existing.IsSystem = r.IsSystem
+			existing.UpdatedAt = time.Now()
+			if err := s.RoleRepo.Update(ctx, existing.ID.Hex(), existing); err != nil {
+				return fmt.Errorf("update role %s: %w", r.Name, err)
+			}
+			continue
+		}
+
+		r.ID = primitive.NewObjectID()
+		r.CreatedAt = time.Now()
+		r.UpdatedAt = time.Now()
+		if err := s.RoleRepo.Create(ctx, &r); err != nil {
+			return fmt.Errorf("create role %s: %w", r.Name, err)
+		}
+		s.Logger.Info("Role created", zap.String("role", r.Name))
+	}
+	return nil
+}
+
+func (s *Seeder) seedPermissions(ctx context.Context, _ Options) error {
+	ctx, err := s.tenantContext(ctx)
+	if err != nil {
+		return err
+	}
+	tenantID, _ := primitive.ObjectIDFromHex(ctx.Value(common_models.TenantIDKey).(string))
+
+	var permissionsData []struct {
+		RoleName   string                                    `json:"role_name"`
+		Resource   permission.ResourceRef                    `json:"resource"`
+		Actions    map[string]common_models.ActionPermission `json:"actions"`
+		FieldRules map[string]string                         `json:"field_rules"`
+	}
+	if err := json.Unmarshal(mustData("permissions.json"), &permissionsData); err != nil {
+		return err
+	}
+
+	for _, p := range permissionsData {
+		r, err := s.RoleRepo.FindByName(ctx, p.RoleName)
+		if err != nil {
+			return fmt.Errorf("role %s for permission on %s: %w", p.RoleName, p.Resource.ID, err)
+		}
+
+		existing, err := s.PermissionRepo.FindByRoleAndResource(ctx, r.ID.Hex(), p.Resource.ID)
+		if err == nil && existing != nil {
+			existing.Actions = p.Actions
+			existing.FieldRules = p.FieldRules
+			existing.UpdatedAt = time.Now()
+			if err := s.PermissionRepo.Update(ctx, existing.ID.Hex(), existing); err != nil {
+				return fmt.Errorf("update permission %s on %s: %w", p.RoleName, p.Resource.ID, err)
+			}
+			continue
+		}
+
+		perm := permission.Permission{
+			ID:         primitive.NewObjectID(),
+			TenantID:   tenantID,
+			RoleID:     r.ID,
+			Resource:   p.Resource,
+			Actions:    p.Actions,
+			FieldRules: p.FieldRules,
+			CreatedAt:  time.Now(),
+			UpdatedAt:  time.Now(),
+		}
+		if err := s.PermissionRepo.Create(ctx, &perm); err != nil {
+			return fmt.Errorf("create permission %s on %s: %w", p.RoleName, p.Resource.ID, err)
+		}
+	}
+	s.Logger.Info("Permissions synced", zap.Int("count", len(permissionsData)))
+	return nil
+}
+
+// Modules without a product in modules.json are assigned one here
+var erpModules = map[string]bool{
+	"products":               true,
+	"categories":             true,
+	"brands":                 true,
+	"tax_rates":              true,
+	"price_lists":            true,
+	"price_list_items":       true,
+	"customers":              true,
+	"vendors":                true,
+	"invoices":               true,
+	"invoice_items":          true,
+	"purchase_invoices":      true,
+	"purchase_invoice_items": true,
+	"purchase_orders":        true,
+	"purchase_order_items":   true,
+	"expenses":               true,
+}
+
+// seedModules creates missing modules and adds fields that are new in
+// modules.json. Fields admins changed or added are left alone.
+func (s *Seeder) seedModules(ctx context.Context, _ Options) error {
+	ctx, err := s.tenantContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var modules []common_models.Entity
+	if err := json.Unmarshal(mustData("modules.json"), &modules); err != nil {
+		return err
+	}
+
+	for _, m := range modules {
+		if m.Product == "" {
+			m.Product = common_models.ProductCRM
+			if erpModules[m.Name] {
+				m.Product = common_models.ProductERP
+			}
+		}
+
+		existing, err := s.ModuleRepo.FindByName(ctx, m.Name)
+		if err == nil {
+			updated := false
+			have := make(map[string]bool)
+			for _, f := range existing.Fields {
+				have[f.Name] = true
+			}
+			for _, f := range m.Fields {
+				if !have[f.Name] {
+					existing.Fields = append(existing.Fields, f)
+					s.Logger.Info("Adding field to module", zap.String("module", m.Name), zap.String("field", f.Name))
+					updated = true
+				}
+			}
+			if existing.Product == "" {
+				existing.Product = m.Product
+				updated = true
+			}
+			if updated {
+				existing.UpdatedAt = time.Now()
+				if err := s.ModuleRepo.Update(ctx, existing); err != nil {
+					return fmt.Errorf("update module %s: %w", m.Name, err)
+				}
+			}
+			continue
+		}
+
+		m.ID = primitive.NewObjectID()
+		m.CreatedAt = time.Now()
+		m.UpdatedAt = time.Now()
+		if err := s.ModuleRepo.Create(ctx, &m); err != nil {
+			return fmt.Errorf("create module %s: %w", m.Name, err)
+		}
+		s.Logger.Info("Module created", zap.String("module", m.Name), zap.String("product", string(m.Product)))
+	}
+	return nil
+}
+
+type seedUser struct {
+	Username  string   `json:"username"`
+	Password  string   `json:"password"`
+	Email     string   `json:"email"`
+	FirstName string   `json:"first_name"`
+	LastName  string   `json:"last_name"`
+	Status    string   `json:"status"`
+	RoleNames []string `json:"roles"`
+	Groups    []string `json:"groups"`
+}
+
+// seedAdmin creates the first admin, with the password given to the
+// migration, and makes them the organization's owner
+func (s *Seeder) seedAdmin(ctx context.Context, opts Options) error {
+	if opts.AdminPassword == "" {
+		return errors.New("the first admin's password is required: set ADMIN_PASSWORD or pass -admin-password")
+	}
+	ids, err := s.seedUsers(ctx, "admin.json", opts.AdminPassword)
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+
+	ctx, err = s.tenantContext(ctx)
+	if err != nil {
+		return err
+	}
+	org, err := s.OrgRepo.FindByID(ctx, ctx.Value(common_models.TenantIDKey).(string))
+	if err != nil {
+		return err
+	}
+	if org.OwnerID.IsZero() {
+		org.OwnerID = ids[0]
+		org.UpdatedAt = time.Now()
+		return s.OrgRepo.Update(ctx, org)
+	}
+	return nil
+}
+
+func (s *Seeder) seedDemoUsers(ctx context.Context, _ Options) error {
+	_, err := s.seedUsers(ctx, "users.json", "")
+	return err
+}
+
+// seedUsers creates the users in a data file, or resets the roles of those
+// that exist. New users get password when set, else the one in the file;
+// passwords of existing users are not touched.
+func (s *Seeder) seedUsers(ctx context.Context, file, password string) ([]primitive.ObjectID, error) {
+	ctx, err := s.tenantContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tenantID, _ := primitive.ObjectIDFromHex(ctx.Value(common_models.TenantIDKey).(string))
+
+	var users []seedUser
+	if err := json.Unmarshal(mustData(file), &users); err != nil {
+		return nil, err
+	}
+
+	var ids []primitive.ObjectID
+	for _, u := range users {
+		var roleIDs []primitive.ObjectID
+		for _, name := range u.RoleNames {
+			r, err := s.RoleRepo.FindByName(ctx, name)
+			if err != nil {
+				return nil, fmt.Errorf("role %s for user %s: %w", name, u.Username, err)
+			}
+			roleIDs = append(roleIDs, r.ID)
+		}
+
+		existing, err := s.UserRepo.FindByUsername(ctx, u.Username)
+		if err == nil {
+			existing.Roles = roleIDs
+			existing.UpdatedAt = time.Now()
+			if err := s.UserRepo.Update(ctx, existing.ID.Hex(), existing); err != nil {
+				return nil, fmt.Errorf("update user %s: %w", u.Username, err)
+			}
+			ids = append(ids, existing.ID)
+			continue
+		}
+
+		if password != "" {
+			u.Password = password
+		}
+		if u.Password == "" {
+			return nil, fmt.Errorf("user %s has no password", u.Username)
+		}
+		newUser := common_models.User{
+			ID:        primitive.NewObjectID(),
+			Username:  u.Username,
+			Password:  u.Password,
+			Email:     u.Email,
+			FirstName: u.FirstName,
+			LastName:  u.LastName,
+			Status:    u.Status,
+			Roles:     roleIDs,
+			Groups:    u.Groups,
+			TenantID:  tenantID,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+		if err := s.UserRepo.Create(ctx, &newUser); err != nil {
+			return nil, fmt.Errorf("create user %s: %w", u.Username, err)
+		}
+		s.Logger.Info("User created", zap.String("username", u.Username))
+		ids = append(ids, newUser.ID)
+	}
+	return ids, nil
+}
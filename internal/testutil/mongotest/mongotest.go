@@ -0,0 +1,296 @@
+// Package mongotest gives integration tests a MongoDB database of their own.
+//
+// Tests connect to CRM_TEST_MONGO_URI when it is set, which has to be a
+// replica set. Otherwise a throwaway single-node replica set is started once
+// per test binary, so transactions work as in production: mongod from PATH
+// with its data in a temporary directory, else a mongo container through
+// Docker. When none of these is available, or in short mode, the tests are
+// skipped with the reason so `go test ./...` stays usable without a
+// database. CRM_REQUIRE_INTEGRATION=1, as set in CI, turns every such skip
+// into a failure.
+package mongotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go-crm/internal/database"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnvURI names the variable pointing the tests at an existing server
+const EnvURI = "CRM_TEST_MONGO_URI"
+
+// EnvRequire names the variable that turns skipped integration tests into
+// failures
+const EnvRequire = "CRM_REQUIRE_INTEGRATION"
+
+// containerImage is the server started through Docker
+const containerImage = "mongo:7"
+
+// replicaSet names the single-node replica set of a started server
+const replicaSet = "rs0"
+
+const startTimeout = 30 * time.Second
+
+// containerTimeout also covers pulling the image
+const containerTimeout = 3 * time.Minute
+
+var (
+	once      sync.Once
+	client    *mongo.Client
+	mongod    *exec.Cmd
+	dataDir   string
+	container testcontainers.Container
+	startErr  error
+
+	skipMu  sync.Mutex
+	skipped = map[string]int{} // skip reasons and how many tests each skipped
+)
+
+// Main runs the package's tests and stops the mongod they started. Call it
+// from TestMain.
+func Main(m *testing.M) {
+	code := m.Run()
+	stop()
+	for reason, n := range skipped {
+		fmt.Fprintf(os.Stderr, "mongotest: skipped %d test(s): %s; set %s=1 to fail instead\n", n, reason, EnvRequire)
+	}
+	os.Exit(code)
+}
+
+// New returns an empty database dropped when the test ends. Repositories take
+// it as they take the application's.
+func New(t testing.TB) *database.MongodbDB {
+	t.Helper()
+	if testing.Short() {
+		skip(t, "short mode")
+	}
+	once.Do(start)
+	if startErr != nil {
+		skip(t, startErr.Error())
+	}
+
+	db := client.Database("crm_test_" + primitive.NewObjectID().Hex())
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = db.Drop(ctx)
+	})
+	return &database.MongodbDB{DB: db}
+}
+
+// skip skips the test with its reason, or fails it when EnvRequire is set
+func skip(t testing.TB, reason string) {
+	t.Helper()
+	if os.Getenv(EnvRequire) != "" {
+		t.Fatalf("mongotest: %s (%s is set)", reason, EnvRequire)
+	}
+	skipMu.Lock()
+	skipped[reason]++
+	skipMu.Unlock()
+	t.Skipf("mongotest: %s", reason)
+}
+
+func start() {
+	uri := os.Getenv(EnvURI)
+	if uri != "" {
+		if client, startErr = connect(uri); startErr == nil {
+			startErr = checkReplicaSet(client)
+		}
+		return
+	}
+
+	uri, member, err := startServer()
+	if err != nil {
+		startErr = err
+		return
+	}
+	if client, startErr = connect(uri); startErr == nil {
+		startErr = initiate(client, member)
+	}
+}
+
+// startServer prefers a local mongod, which starts faster than a container.
+// It returns the address to connect to and the member's address as the
+// server sees itself.
+func startServer() (uri, member string, err error) {
+	if bin, err := exec.LookPath("mongod"); err == nil {
+		return startMongod(bin)
+	}
+	uri, member, err = startContainer()
+	if err != nil {
+		return "", "", fmt.Errorf("set %s, put mongod on PATH or make Docker available: %w", EnvURI, err)
+	}
+	return uri, member, nil
+}
+
+// startMongod launches mongod on a free local port
+func startMongod(bin string) (string, string, error) {
+	port, err := freePort()
+	if err != nil {
+		return "", "", err
+	}
+	dataDir, err = os.MkdirTemp("", "crm-mongotest-")
+	if err != nil {
+		return "", "", err
+	}
+
+	mongod = exec.Command(bin,
+		"--dbpath", dataDir,
+		"--port", strconv.Itoa(port),
+		"--bind_ip", "127.0.0.1",
+		"--replSet", replicaSet,
+		"--quiet",
+	)
+	if err := mongod.Start(); err != nil {
+		_ = os.RemoveAll(dataDir)
+		return "", "", fmt.Errorf("start mongod: %w", err)
+	}
+	member := fmt.Sprintf("127.0.0.1:%d", port)
+	return "mongodb://" + member + "/?directConnection=true", member, nil
+}
+
+// startContainer runs the mongo image and returns its mapped address
+func startContainer() (uri, member string, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), containerTimeout)
+	defer cancel()
+
+	// testcontainers panics rather than failing when no Docker host is found
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("start %s: %v", containerImage, r)
+		}
+	}()
+
+	container, err = testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        containerImage,
+			Cmd:          []string{"--replSet", replicaSet, "--bind_ip_all"},
+			ExposedPorts: []string{"27017/tcp"},
+			WaitingFor:   wait.ForListeningPort("27017/tcp"),
+		},
+		Started: true,
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("start %s: %w", containerImage, err)
+	}
+	host, err := container.Host(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	port, err := container.MappedPort(ctx, "27017/tcp")
+	if err != nil {
+		return "", "", err
+	}
+	return fmt.Sprintf("mongodb://%s:%s/?directConnection=true", host, port.Port()), "localhost:27017", nil
+}
+
+// initiate makes a fresh server a replica set of itself and waits until it
+// is primary and takes writes
+func initiate(c *mongo.Client, member string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
+	defer cancel()
+
+	admin := c.Database("admin")
+	config := bson.M{"_id": replicaSet, "members": bson.A{bson.M{"_id": 0, "host": member}}}
+	if err := admin.RunCommand(ctx, bson.D{{Key: "replSetInitiate", Value: config}}).Err(); err != nil {
+		return fmt.Errorf("initiate replica set: %w", err)
+	}
+	for {
+		var hello struct {
+			IsWritablePrimary bool `bson:"isWritablePrimary"`
+		}
+		if err := admin.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err == nil && hello.IsWritablePrimary {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return errors.New("replica set did not elect a primary")
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// checkReplicaSet rejects a server given through CRM_TEST_MONGO_URI that
+// cannot run transactions
+func checkReplicaSet(c *mongo.Client) error {
+	ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
+	defer cancel()
+
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := c.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return err
+	}
+	if hello.SetName == "" && hello.Msg != "isdbgrid" {
+		return fmt.Errorf("%s points to a standalone server; transactions need a replica set, e.g. mongod --replSet %s", EnvURI, replicaSet)
+	}
+	return nil
+}
+
+// connect waits for the server to answer, as a fresh mongod takes a moment
+func connect(uri string) (*mongo.Client, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
+	defer cancel()
+
+	c, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, err
+	}
+	for {
+		err = c.Ping(ctx, nil)
+		if err == nil {
+			return c, nil
+		}
+		select {
+		case <-ctx.Done():
+			_ = c.Disconnect(context.Background())
+			return nil, errors.Join(errors.New("mongodb did not answer"), err)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+func stop() {
+	if client != nil {
+		_ = client.Disconnect(context.Background())
+	}
+	if mongod != nil && mongod.Process != nil {
+		_ = mongod.Process.Kill()
+		_ = mongod.Wait()
+	}
+	if dataDir != "" {
+		_ = os.RemoveAll(dataDir)
+	}
+	if container != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_ = container.Terminate(ctx)
+	}
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
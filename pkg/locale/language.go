@@ -0,0 +1,67 @@
+package locale
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NormalizeLanguage lowercases a language tag and uses a hyphen separator,
+// e.g. pt_BR becomes pt-br
+func NormalizeLanguage(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+}
+
+// ParseAcceptLanguage returns the languages of an Accept-Language header,
+// most preferred first. Wildcards and q=0 entries are dropped.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = NormalizeLanguage(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		tags = append(tags, weighted{tag, q})
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		out = append(out, t.tag)
+	}
+	return out
+}
+
+// Translate picks the label for the first language with a translation. A
+// regional tag falls back to its base language (fr-ca to fr); with no match
+// the default label is returned.
+func Translate(translations map[string]string, fallback string, languages []string) string {
+	if len(translations) == 0 {
+		return fallback
+	}
+	for _, lang := range languages {
+		if t := translations[lang]; t != "" {
+			return t
+		}
+		if base, _, ok := strings.Cut(lang, "-"); ok {
+			if t := translations[base]; t != "" {
+				return t
+			}
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,355 @@
+package locale
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DefaultLocale   = "en-US"
+	DefaultCurrency = "USD"
+)
+
+// Settings are the formatting preferences of a tenant or user. Empty fields
+// inherit: user settings fall back to the tenant's, the tenant's to the
+// locale defaults.
+type Settings struct {
+	Locale   string `json:"locale,omitempty" bson:"locale,omitempty"`     // e.g. en-US, de-DE
+	Currency string `json:"currency,omitempty" bson:"currency,omitempty"` // ISO 4217, e.g. EUR
+	// DateFormat is one of DateFormats, e.g. DD/MM/YYYY; empty uses the locale's
+	DateFormat string `json:"date_format,omitempty" bson:"date_format,omitempty"`
+	// TimeFormat is 12h or 24h; empty uses the locale's
+	TimeFormat string `json:"time_format,omitempty" bson:"time_format,omitempty"`
+	// Timezone is an IANA zone, e.g. Europe/Berlin; empty is UTC
+	Timezone string `json:"timezone,omitempty" bson:"timezone,omitempty"`
+	// Country is the ISO 3166 code national phone numbers are read in, e.g.
+	// GB; empty uses the locale's region
+	Country string `json:"country,omitempty" bson:"country,omitempty"`
+}
+
+// Merge returns s with empty fields taken from base
+func (s Settings) Merge(base Settings) Settings {
+	if s.Locale == "" {
+		s.Locale = base.Locale
+	}
+	if s.Currency == "" {
+		s.Currency = base.Currency
+	}
+	if s.DateFormat == "" {
+		s.DateFormat = base.DateFormat
+	}
+	if s.TimeFormat == "" {
+		s.TimeFormat = base.TimeFormat
+	}
+	if s.Timezone == "" {
+		s.Timezone = base.Timezone
+	}
+	if s.Country == "" {
+		s.Country = base.Country
+	}
+	return s
+}
+
+// Validate rejects locales and formats the formatter does not know
+func (s Settings) Validate() error {
+	if s.Locale != "" {
+		if _, ok := locales[s.Locale]; !ok {
+			return fmt.Errorf("unsupported locale %q; expected one of %s", s.Locale, strings.Join(Locales(), ", "))
+		}
+	}
+	if s.Currency != "" && (len(s.Currency) != 3 || strings.ToUpper(s.Currency) != s.Currency) {
+		return fmt.Errorf("currency must be a 3-letter ISO code, e.g. USD")
+	}
+	if s.DateFormat != "" {
+		if _, ok := DateFormats[s.DateFormat]; !ok {
+			return fmt.Errorf("unsupported date format %q", s.DateFormat)
+		}
+	}
+	if s.TimeFormat != "" && s.TimeFormat != "12h" && s.TimeFormat != "24h" {
+		return fmt.Errorf("time format must be 12h or 24h")
+	}
+	if s.Timezone != "" {
+		if _, err := time.LoadLocation(s.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", s.Timezone)
+		}
+	}
+	if s.Country != "" {
+		if err := validatePhoneRegion(s.Country); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DateFormats maps the supported date patterns to Go layouts
+var DateFormats = map[string]string{
+	"YYYY-MM-DD": "2006-01-02",
+	"YYYY/MM/DD": "2006/01/02",
+	"DD/MM/YYYY": "02/01/2006",
+	"MM/DD/YYYY": "01/02/2006",
+	"DD.MM.YYYY": "02.01.2006",
+	"DD-MM-YYYY": "02-01-2006",
+}
+
+type spec struct {
+	decimal string
+	group   string
+	// lakh groups digits as 12,34,567 after the first thousand
+	lakh bool
+	// symbolAfter writes 1.234,56 € instead of €1,234.56
+	symbolAfter bool
+	// symbolSpace writes € 1.234,56 instead of €1.234,56
+	symbolSpace bool
+	date        string
+	clock24     bool
+}
+
+var locales = map[string]spec{
+	"en-US": {decimal: ".", group: ",", date: "01/02/2006"},
+	"en-GB": {decimal: ".", group: ",", date: "02/01/2006", clock24: true},
+	"en-IN": {decimal: ".", group: ",", lakh: true, date: "02/01/2006"},
+	"en-AU": {decimal: ".", group: ",", date: "02/01/2006"},
+	"en-CA": {decimal: ".", group: ",", date: "2006-01-02"},
+	"de-DE": {decimal: ",", group: ".", symbolAfter: true, date: "02.01.2006", clock24: true},
+	"fr-FR": {decimal: ",", group: " ", symbolAfter: true, date: "02/01/2006", clock24: true},
+	"es-ES": {decimal: ",", group: ".", symbolAfter: true, date: "02/01/2006", clock24: true},
+	"it-IT": {decimal: ",", group: ".", symbolAfter: true, date: "02/01/2006", clock24: true},
+	"nl-NL": {decimal: ",", group: ".", symbolSpace: true, date: "02-01-2006", clock24: true},
+	"pt-BR": {decimal: ",", group: ".", symbolSpace: true, date: "02/01/2006", clock24: true},
+	"ja-JP": {decimal: ".", group: ",", date: "2006/01/02", clock24: true},
+	"zh-CN": {decimal: ".", group: ",", date: "2006-01-02", clock24: true},
+}
+
+// Locales lists the supported locale codes
+func Locales() []string {
+	codes := make([]string, 0, len(locales))
+	for code := range locales {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"INR": "₹",
+	"JPY": "¥",
+	"CNY": "¥",
+	"BRL": "R$",
+	"AUD": "A$",
+	"CAD": "CA$",
+}
+
+// zeroDecimalCurrencies have no minor unit
+var zeroDecimalCurrencies = map[string]bool{"JPY": true, "KRW": true, "VND": true}
+
+// Formatter renders values for people: exports, emails, documents and reports
+type Formatter struct {
+	settings   Settings
+	spec       spec
+	dateLayout string
+	timeLayout string
+	location   *time.Location
+}
+
+// New builds a formatter; unknown or empty settings use the en-US defaults
+func New(s Settings) *Formatter {
+	s = s.Merge(Settings{Locale: DefaultLocale, Currency: DefaultCurrency})
+	sp, ok := locales[s.Locale]
+	if !ok {
+		s.Locale = DefaultLocale
+		sp = locales[DefaultLocale]
+	}
+	f := &Formatter{settings: s, spec: sp, dateLayout: sp.date, timeLayout: "3:04 PM", location: LoadLocation(s.Timezone)}
+	if layout, ok := DateFormats[s.DateFormat]; ok {
+		f.dateLayout = layout
+	}
+	if s.TimeFormat == "24h" || (s.TimeFormat == "" && sp.clock24) {
+		f.timeLayout = "15:04"
+	}
+	return f
+}
+
+// Default is the en-US formatter
+func Default() *Formatter {
+	return New(Settings{})
+}
+
+// Settings returns the resolved settings
+func (f *Formatter) Settings() Settings {
+	return f.settings
+}
+
+// Number formats v with the given number of decimals and locale separators
+func (f *Formatter) Number(v float64, decimals int) string {
+	neg := v < 0
+	s := strconv.FormatFloat(math.Abs(v), 'f', decimals, 64)
+	intPart, frac, _ := strings.Cut(s, ".")
+
+	var b strings.Builder
+	if neg {
+		b.WriteByte('-')
+	}
+	b.WriteString(f.group(intPart))
+	if frac != "" {
+		b.WriteString(f.spec.decimal)
+		b.WriteString(frac)
+	}
+	return b.String()
+}
+
+func (f *Formatter) group(digits string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+	head, tail := digits[:len(digits)-3], digits[len(digits)-3:]
+	size := 3
+	if f.spec.lakh {
+		size = 2
+	}
+	var parts []string
+	for len(head) > size {
+		parts = append([]string{head[len(head)-size:]}, parts...)
+		head = head[:len(head)-size]
+	}
+	parts = append([]string{head}, parts...)
+	return strings.Join(append(parts, tail), f.spec.group)
+}
+
+// Currency formats an amount in the settings' currency
+func (f *Formatter) Currency(v float64) string {
+	code := f.settings.Currency
+	decimals := 2
+	if zeroDecimalCurrencies[code] {
+		decimals = 0
+	}
+	amount := f.Number(math.Abs(v), decimals)
+	symbol, ok := currencySymbols[code]
+	if !ok {
+		symbol = code
+	}
+
+	var s string
+	switch {
+	case f.spec.symbolAfter:
+		s = amount + " " + symbol
+	case !ok || f.spec.symbolSpace:
+		// Bare ISO codes always get a space: CHF 1,234.56
+		s = symbol + " " + amount
+	default:
+		s = symbol + amount
+	}
+	if v < 0 {
+		return "-" + s
+	}
+	return s
+}
+
+// Location is the timezone dates are shown in
+func (f *Formatter) Location() *time.Location {
+	return f.location
+}
+
+// Date formats the calendar date of t in the formatter's timezone
+func (f *Formatter) Date(t time.Time) string {
+	return t.In(f.location).Format(f.dateLayout)
+}
+
+// DateTime formats t with the date and clock layouts in the formatter's timezone
+func (f *Formatter) DateTime(t time.Time) string {
+	return t.In(f.location).Format(f.dateLayout + " " + f.timeLayout)
+}
+
+// Field formats a record value by its module field type (currency, number,
+// date, ...), falling back to Value for other types
+func (f *Formatter) Field(fieldType string, val any) string {
+	switch fieldType {
+	case "currency":
+		if n, ok := toFloat(val); ok {
+			return f.Currency(n)
+		}
+	case "date":
+		if t, ok := toTime(val); ok {
+			return f.Date(t)
+		}
+	}
+	return f.Value(val)
+}
+
+// Value formats a value without knowing its field type
+func (f *Formatter) Value(val any) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		if v {
+			return "Yes"
+		}
+		return "No"
+	case time.Time, primitive.DateTime:
+		t, _ := toTime(v)
+		return f.DateTime(t)
+	case primitive.ObjectID:
+		return v.Hex()
+	case map[string]any:
+		if name, ok := v["name"]; ok {
+			return f.Value(name)
+		}
+		if originalName, ok := v["original_filename"]; ok {
+			return f.Value(originalName)
+		}
+	case primitive.M:
+		return f.Value(map[string]any(v))
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, f.Value(item))
+		}
+		return strings.Join(parts, ", ")
+	case primitive.A:
+		return f.Value([]any(v))
+	}
+	if n, ok := toFloat(val); ok {
+		if n == math.Trunc(n) && math.Abs(n) < 1e15 {
+			return f.Number(n, 0)
+		}
+		return f.Number(n, 2)
+	}
+	return fmt.Sprintf("%v", val)
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func toTime(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case primitive.DateTime:
+		return t.Time(), true
+	}
+	return time.Time{}, false
+}
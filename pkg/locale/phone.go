@@ -0,0 +1,179 @@
+package locale
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// phoneRegion is how a country's numbers are dialled. nsnMin and nsnMax
+// bound the national significant number: the digits after the calling code,
+// without the trunk prefix dialled before them at home.
+type phoneRegion struct {
+	callingCode string
+	trunk       string
+	nsnMin      int
+	nsnMax      int
+}
+
+var phoneRegions = map[string]phoneRegion{
+	"US": {callingCode: "1", trunk: "1", nsnMin: 10, nsnMax: 10},
+	"CA": {callingCode: "1", trunk: "1", nsnMin: 10, nsnMax: 10},
+	"MX": {callingCode: "52", nsnMin: 10, nsnMax: 10},
+	"BR": {callingCode: "55", trunk: "0", nsnMin: 10, nsnMax: 11},
+	"GB": {callingCode: "44", trunk: "0", nsnMin: 9, nsnMax: 10},
+	"IE": {callingCode: "353", trunk: "0", nsnMin: 7, nsnMax: 9},
+	"DE": {callingCode: "49", trunk: "0", nsnMin: 6, nsnMax: 13},
+	"FR": {callingCode: "33", trunk: "0", nsnMin: 9, nsnMax: 9},
+	"ES": {callingCode: "34", nsnMin: 9, nsnMax: 9},
+	"IT": {callingCode: "39", nsnMin: 6, nsnMax: 11}, // The leading 0 of landlines is kept
+	"NL": {callingCode: "31", trunk: "0", nsnMin: 9, nsnMax: 9},
+	"CH": {callingCode: "41", trunk: "0", nsnMin: 9, nsnMax: 9},
+	"SE": {callingCode: "46", trunk: "0", nsnMin: 7, nsnMax: 9},
+	"ZA": {callingCode: "27", trunk: "0", nsnMin: 9, nsnMax: 9},
+	"AE": {callingCode: "971", trunk: "0", nsnMin: 8, nsnMax: 9},
+	"IN": {callingCode: "91", trunk: "0", nsnMin: 10, nsnMax: 10},
+	"SG": {callingCode: "65", nsnMin: 8, nsnMax: 8},
+	"AU": {callingCode: "61", trunk: "0", nsnMin: 9, nsnMax: 9},
+	"JP": {callingCode: "81", trunk: "0", nsnMin: 9, nsnMax: 10},
+	"CN": {callingCode: "86", trunk: "0", nsnMin: 10, nsnMax: 11},
+}
+
+// PhoneRegions lists the countries national phone numbers can be read for
+func PhoneRegions() []string {
+	codes := make([]string, 0, len(phoneRegions))
+	for code := range phoneRegions {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// PhoneRegion returns the country national phone numbers are read in: the
+// configured one, or else the region of the locale
+func (s Settings) PhoneRegion() string {
+	if s.Country != "" {
+		return s.Country
+	}
+	_, region, _ := strings.Cut(s.Locale, "-")
+	return region
+}
+
+var (
+	ErrInvalidPhone = errors.New("invalid phone number")
+	// ErrPhoneRegion is returned for national numbers when no supported
+	// country is known to read them in
+	ErrPhoneRegion = errors.New("phone number needs a country code, e.g. +1 555 123 4567")
+)
+
+var (
+	phoneExtension = regexp.MustCompile(`(?i)\s*(?:ext\.?|extension|x|#)\s*\d{1,6}$`)
+	phoneChars     = regexp.MustCompile(`^\+?[0-9\s\-.()/]+$`)
+)
+
+// ParsePhone returns the number in E.164 form, e.g. +14155550123. Numbers
+// written with a + or an international prefix (00, or 011 in North
+// America) carry their country; others are read as national numbers of
+// region, without the trunk prefix dialled before them. Extensions are
+// dropped.
+func ParsePhone(raw, region string) (string, error) {
+	number := strings.TrimSpace(phoneExtension.ReplaceAllString(strings.TrimSpace(raw), ""))
+	if number == "" || !phoneChars.MatchString(number) {
+		return "", ErrInvalidPhone
+	}
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, number)
+
+	home, known := phoneRegions[strings.ToUpper(region)]
+	switch {
+	case strings.HasPrefix(number, "+"):
+		return internationalPhone(digits)
+	case strings.HasPrefix(digits, "00"):
+		return internationalPhone(digits[2:])
+	case known && home.callingCode == "1" && strings.HasPrefix(digits, "011"):
+		return internationalPhone(digits[3:])
+	case !known:
+		return "", ErrPhoneRegion
+	}
+
+	nsn := home.withoutTrunk(digits)
+	if !home.accepts(nsn) {
+		return "", ErrInvalidPhone
+	}
+	return "+" + home.callingCode + nsn, nil
+}
+
+// internationalPhone checks digits that start with a calling code. Numbers
+// of countries listed in phoneRegions must also have a fitting length.
+func internationalPhone(digits string) (string, error) {
+	if len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+		return "", ErrInvalidPhone
+	}
+	matched := false
+	for _, r := range phoneRegions {
+		nsn, ok := strings.CutPrefix(digits, r.callingCode)
+		if !ok {
+			continue
+		}
+		matched = true
+		// A trunk prefix written after the code, as in +44 (0)20, is dropped
+		if nsn = r.withoutTrunk(nsn); r.accepts(nsn) {
+			return "+" + r.callingCode + nsn, nil
+		}
+	}
+	if matched {
+		return "", ErrInvalidPhone
+	}
+	return "+" + digits, nil
+}
+
+// withoutTrunk drops the trunk prefix from a national number. The North
+// American 1 is only a prefix on numbers one digit too long.
+func (r phoneRegion) withoutTrunk(digits string) string {
+	if r.trunk == "" || !strings.HasPrefix(digits, r.trunk) {
+		return digits
+	}
+	if r.trunk == "1" && len(digits) != r.nsnMax+1 {
+		return digits
+	}
+	return digits[len(r.trunk):]
+}
+
+func (r phoneRegion) accepts(nsn string) bool {
+	if len(nsn) < r.nsnMin || len(nsn) > r.nsnMax {
+		return false
+	}
+	// North American area codes and exchanges never start with 0 or 1
+	if r.callingCode == "1" && (nsn[0] < '2' || nsn[3] < '2') {
+		return false
+	}
+	return r.trunk != "0" || nsn[0] != '0'
+}
+
+type phoneRegionKey struct{}
+
+// WithPhoneRegion stores the country national numbers in this request are
+// read in
+func WithPhoneRegion(ctx context.Context, region string) context.Context {
+	return context.WithValue(ctx, phoneRegionKey{}, region)
+}
+
+// PhoneRegionFrom returns the request's phone region, or "" when none was set
+func PhoneRegionFrom(ctx context.Context) string {
+	region, _ := ctx.Value(phoneRegionKey{}).(string)
+	return region
+}
+
+func validatePhoneRegion(country string) error {
+	if _, ok := phoneRegions[country]; !ok {
+		return fmt.Errorf("unsupported country %q; expected one of %s", country, strings.Join(PhoneRegions(), ", "))
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package locale
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParsePhone(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		region  string
+		want    string
+		wantErr error
+	}{
+		// Country codes
+		{name: "plus prefix", raw: "+1 415 555 0123", want: "+14155550123"},
+		{name: "plus prefix ignores the region", raw: "+44 20 7946 0958", region: "US", want: "+442079460958"},
+		{name: "00 prefix", raw: "0044 20 7946 0958", region: "DE", want: "+442079460958"},
+		{name: "011 prefix in North America", raw: "011 44 20 7946 0958", region: "US", want: "+442079460958"},
+		{name: "011 is national outside North America", raw: "0114 496 0123", region: "GB", want: "+441144960123"},
+		{name: "trunk zero after the code", raw: "+44 (0)20 7946 0958", want: "+442079460958"},
+		{name: "three digit calling code", raw: "+353 1 234 5678", want: "+35312345678"},
+		{name: "unlisted country", raw: "+64 9 123 4567", want: "+6491234567"},
+		{name: "listed country with a wrong length", raw: "+33 1 23 45", wantErr: ErrInvalidPhone},
+
+		// National numbers
+		{name: "US national", raw: "(415) 555-0123", region: "US", want: "+14155550123"},
+		{name: "US with the leading 1", raw: "1-415-555-0123", region: "us", want: "+14155550123"},
+		{name: "US area code starting with 1", raw: "115 555 0123", region: "US", wantErr: ErrInvalidPhone},
+		{name: "GB with trunk zero", raw: "020 7946 0958", region: "GB", want: "+442079460958"},
+		{name: "FR with dots", raw: "01.23.45.67.89", region: "FR", want: "+33123456789"},
+		{name: "IT keeps the leading zero", raw: "06 1234 5678", region: "IT", want: "+390612345678"},
+		{name: "national number without a region", raw: "415 555 0123", wantErr: ErrPhoneRegion},
+		{name: "national number of an unknown region", raw: "415 555 0123", region: "XX", wantErr: ErrPhoneRegion},
+		{name: "too short", raw: "555 0123", region: "US", wantErr: ErrInvalidPhone},
+
+		// Extensions and punctuation
+		{name: "ext.", raw: "+1 415 555 0123 ext. 42", want: "+14155550123"},
+		{name: "x", raw: "(415) 555-0123 x42", region: "US", want: "+14155550123"},
+		{name: "hash", raw: "+1 415 555 0123 #9", want: "+14155550123"},
+		{name: "slashes", raw: "030/1234567", region: "DE", want: "+49301234567"},
+		{name: "surrounding spaces", raw: "  +1 415 555 0123  ", want: "+14155550123"},
+
+		// Empty and invalid
+		{name: "empty", raw: "", region: "US", wantErr: ErrInvalidPhone},
+		{name: "only spaces", raw: "   ", region: "US", wantErr: ErrInvalidPhone},
+		{name: "only an extension", raw: "ext 42", region: "US", wantErr: ErrInvalidPhone},
+		{name: "letters", raw: "1-800-FLOWERS", region: "US", wantErr: ErrInvalidPhone},
+		{name: "plus in the middle", raw: "415+555", region: "US", wantErr: ErrInvalidPhone},
+		{name: "too long", raw: "+1234567890123456", wantErr: ErrInvalidPhone},
+		{name: "calling code starting with zero", raw: "+0 415 555 0123", wantErr: ErrInvalidPhone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePhone(tt.raw, tt.region)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParsePhone(%q, %q) = %q, %v; want %v", tt.raw, tt.region, got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ParsePhone(%q, %q) = %q, %v; want %q", tt.raw, tt.region, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPhoneRegion(t *testing.T) {
+	tests := []struct {
+		settings Settings
+		want     string
+	}{
+		{settings: Settings{Country: "GB", Locale: "en-US"}, want: "GB"},
+		{settings: Settings{Locale: "de-CH"}, want: "CH"},
+		{settings: Settings{Locale: "fr"}, want: ""},
+	}
+	for _, tt := range tests {
+		if got := tt.settings.PhoneRegion(); got != tt.want {
+			t.Errorf("%+v.PhoneRegion() = %q, want %q", tt.settings, got, tt.want)
+		}
+	}
+}
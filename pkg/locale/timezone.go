@@ -0,0 +1,68 @@
+package locale
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type locationKey struct{}
+
+// LoadLocation resolves an IANA timezone, falling back to UTC for empty or
+// unknown names
+func LoadLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// WithLocation stores the timezone that naive dates in this request are read in
+func WithLocation(ctx context.Context, loc *time.Location) context.Context {
+	return context.WithValue(ctx, locationKey{}, loc)
+}
+
+// LocationFrom returns the request's timezone, or UTC when none was set
+func LocationFrom(ctx context.Context) *time.Location {
+	if loc, ok := ctx.Value(locationKey{}).(*time.Location); ok && loc != nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// offsetLayouts carry their own offset and are parsed as given
+var offsetLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04Z07:00"}
+
+// naiveLayouts have no offset and are read in the caller's timezone
+var naiveLayouts = []string{"2006-01-02T15:04:05.999999999", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+// ErrInvalidDateTime is returned for values ParseDateTime does not understand
+var ErrInvalidDateTime = errors.New("invalid date format (use ISO 8601, e.g. 2006-01-02 or 2006-01-02T15:04:05Z07:00)")
+
+// ParseDateTime reads an ISO 8601 date or timestamp and returns it in UTC.
+// Timestamps with an offset keep their instant; naive timestamps and
+// date-only values are taken as wall-clock time in loc, so 2024-03-01 is
+// midnight of that day where the user is.
+func ParseDateTime(value string, loc *time.Location) (t time.Time, dateOnly bool, err error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	for _, layout := range offsetLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), false, nil
+		}
+	}
+	for _, layout := range naiveLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t.UTC(), false, nil
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, loc); err == nil {
+		return t.UTC(), true, nil
+	}
+	return time.Time{}, false, ErrInvalidDateTime
+}
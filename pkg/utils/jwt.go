@@ -20,16 +20,27 @@ type UserClaims struct {
 	Roles    []string `json:"roles"`            // Role Names
 	Groups   []string `json:"groups,omitempty"` // User groups for ABAC
 	RoleIDs  []string `json:"role_ids"`         // Role IDs
+	// Set on impersonation tokens: the admin acting as UserID and their session
+	ImpersonatorID  string `json:"impersonator_id,omitempty"`
+	ImpersonationID string `json:"impersonation_id,omitempty"`
+	// Permissions version of the user and their roles when the token was issued
+	PermissionsVersion int64 `json:"pv,omitempty"`
+	// Scope limits a token to part of the API; empty for full access
+	Scope string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID primitive.ObjectID, tenantID primitive.ObjectID, roleNames []string, roleIDs []string, groups []string) (string, error) {
+// ScopeAddIn tokens only work on the email add-in endpoints
+const ScopeAddIn = "addin"
+
+func GenerateToken(userID primitive.ObjectID, tenantID primitive.ObjectID, roleNames []string, roleIDs []string, groups []string, permissionsVersion int64) (string, error) {
 	claims := UserClaims{
-		UserID:   userID.Hex(),
-		TenantID: tenantID.Hex(),
-		Roles:    roleNames,
-		RoleIDs:  roleIDs,
-		Groups:   groups,
+		UserID:             userID.Hex(),
+		TenantID:           tenantID.Hex(),
+		Roles:              roleNames,
+		RoleIDs:            roleIDs,
+		Groups:             groups,
+		PermissionsVersion: permissionsVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 72)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -40,6 +51,48 @@ func GenerateToken(userID primitive.ObjectID, tenantID primitive.ObjectID, roleN
 	return token.SignedString(jwtSecret)
 }
 
+// GenerateImpersonationToken issues a token acting as userID that expires at
+// expiresAt and names the impersonating admin and session
+func GenerateImpersonationToken(userID primitive.ObjectID, tenantID primitive.ObjectID, roleNames []string, roleIDs []string, groups []string, impersonatorID, sessionID string, expiresAt time.Time, permissionsVersion int64) (string, error) {
+	claims := UserClaims{
+		UserID:             userID.Hex(),
+		TenantID:           tenantID.Hex(),
+		Roles:              roleNames,
+		RoleIDs:            roleIDs,
+		Groups:             groups,
+		ImpersonatorID:     impersonatorID,
+		ImpersonationID:    sessionID,
+		PermissionsVersion: permissionsVersion,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtSecret)
+}
+
+// GenerateScopedToken issues a token limited to scope that expires at expiresAt
+func GenerateScopedToken(userID primitive.ObjectID, tenantID primitive.ObjectID, roleNames []string, roleIDs []string, groups []string, scope string, expiresAt time.Time, permissionsVersion int64) (string, error) {
+	claims := UserClaims{
+		UserID:             userID.Hex(),
+		TenantID:           tenantID.Hex(),
+		Roles:              roleNames,
+		RoleIDs:            roleIDs,
+		Groups:             groups,
+		PermissionsVersion: permissionsVersion,
+		Scope:              scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtSecret)
+}
+
 func ValidateToken(tokenString string) (*UserClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &UserClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {